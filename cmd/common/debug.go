@@ -0,0 +1,130 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/environment"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+)
+
+func NewDebugCmd(rt runtime.Runtime) *cobra.Command {
+	var containerName string
+	var reason string
+	var timeout time.Duration
+	var tty bool
+
+	runtimeParams := rt.ParamDescs().ToParams()
+
+	cmd := &cobra.Command{
+		Use:   "debug TARGET [-- COMMAND [ARG...]]",
+		Short: "Open an audited debug shell in a container",
+		Long: `Open a short-lived debug shell in the namespaces of a container, running /bin/sh or the given command
+of the container image.
+
+TARGET is the name of a pod on Kubernetes (use --container to select one of its containers) and the name of a
+container otherwise.
+
+The session is terminated after --timeout (the node can enforce a lower limit) and everything happening in it,
+including the input, is recorded in the audit log of the node. Debug shells must be enabled on the nodes.`,
+		SilenceUsage: true,
+		Args:         cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, ok := rt.(runtime.DebugShellProvider)
+			if !ok {
+				return fmt.Errorf("runtime doesn't support debug shells")
+			}
+			if strings.TrimSpace(reason) == "" {
+				return fmt.Errorf("--reason is required")
+			}
+
+			req := &api.DebugShellStartRequest{
+				Command: args[1:],
+				Reason:  reason,
+				Timeout: int64(timeout),
+			}
+			if environment.Environment == environment.Kubernetes {
+				req.Namespace, _ = rt.GetDefaultValue(gadgets.K8SNamespace)
+				req.PodName = args[0]
+				req.ContainerName = containerName
+			} else {
+				if containerName != "" {
+					return fmt.Errorf("--container is only supported on Kubernetes, use TARGET to select the container")
+				}
+				req.ContainerName = args[0]
+			}
+
+			stdinFd := int(os.Stdin.Fd())
+			if !cmd.Flags().Changed("tty") {
+				tty = term.IsTerminal(stdinFd)
+			}
+			req.Tty = tty
+			if tty {
+				if cols, rows, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+					req.Rows, req.Cols = uint32(rows), uint32(cols)
+				}
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+			defer cancel()
+
+			restore := func() {}
+			exit, err := provider.DebugShell(ctx, runtimeParams, req, os.Stdin, os.Stdout, func(started *api.DebugShellStarted) {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Debug session %s in %s on node %q, ends at %s; it is audited\r\n",
+					started.SessionID, started.Container, started.Node,
+					time.Unix(0, started.Deadline).Format(time.TimeOnly))
+				if tty && term.IsTerminal(stdinFd) {
+					if state, err := term.MakeRaw(stdinFd); err == nil {
+						restore = func() { term.Restore(stdinFd, state) }
+					}
+				}
+			})
+			restore()
+			if err != nil {
+				return err
+			}
+
+			if exit.Reason != "" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Debug session ended: %s\n", exit.Reason)
+			}
+			if exit.ExitCode != 0 {
+				return fmt.Errorf("command exited with code %d", exit.ExitCode)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&containerName, "container", "c", "", "Container of the pod to debug; can be omitted if the pod has a single container")
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason for debugging, recorded in the audit log (required)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Minute, "Maximum duration of the session")
+	cmd.Flags().BoolVarP(&tty, "tty", "t", false, "Allocate a terminal; enabled by default if stdin is a terminal")
+
+	// Only keep params that select the targets, like --node
+	AddOCIFlags(cmd, runtimeParams, []string{"!attach"}, rt)
+
+	return cmd
+}
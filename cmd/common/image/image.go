@@ -40,6 +40,7 @@ func NewImageCmd(r runtime.Runtime, addCommands []*cobra.Command) *cobra.Command
 	cmd.AddCommand(NewImportCmd())
 	cmd.AddCommand(NewPushCmd())
 	cmd.AddCommand(NewPullCmd())
+	cmd.AddCommand(NewPolicyCmd())
 	cmd.AddCommand(NewTagCmd())
 	cmd.AddCommand(NewListCmd())
 	cmd.AddCommand(NewInspectCmd(r))
@@ -0,0 +1,102 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+func NewPolicyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Manage gadget policies published as OCI artifacts",
+		Long: `Manage gadget policies published as OCI artifacts.
+
+A gadget policy lists the gadgets approved by an organization. Daemons configured
+with the gadget-policy parameter of the oci operator sync it periodically and
+deny the gadgets it doesn't allow.`,
+	}
+
+	cmd.AddCommand(newPolicyPushCmd())
+	cmd.AddCommand(newPolicyShowCmd())
+
+	return cmd
+}
+
+func newPolicyPushCmd() *cobra.Command {
+	var authOpts oci.AuthOptions
+	var policy oci.GadgetPolicy
+
+	cmd := &cobra.Command{
+		Use:   "push REFERENCE",
+		Short: "Publish a gadget policy to a remote registry",
+		Example: `  # Allow only the gadgets of an organization, except an old version
+  ig image policy push ghcr.io/myorg/gadget-policy:latest \
+    --allow 'ghcr.io/myorg/gadgets/*' --deny ghcr.io/myorg/gadgets/trace_exec:v1.0.0`,
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			digest, err := oci.PushGadgetPolicy(context.TODO(), args[0], &policy, &authOpts)
+			if err != nil {
+				return fmt.Errorf("pushing gadget policy: %w", err)
+			}
+			cmd.Printf("Successfully pushed %s@%s\n", args[0], digest)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&policy.Allowed, "allow", nil, "Allowed gadgets: image names, image names with digest or prefixes ending with '*'. All gadgets are allowed if empty")
+	cmd.Flags().StringSliceVar(&policy.Denied, "deny", nil, "Denied gadgets, even if they are allowed, using the same syntax as --allow")
+	utils.AddRegistryAuthVariablesAndFlags(cmd, &authOpts)
+	return cmd
+}
+
+func newPolicyShowCmd() *cobra.Command {
+	var authOpts oci.AuthOptions
+
+	cmd := &cobra.Command{
+		Use:          "show REFERENCE",
+		Short:        "Show a gadget policy published to a remote registry",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policy, err := oci.PullGadgetPolicy(context.TODO(), args[0], &authOpts, nil)
+			if err != nil {
+				return fmt.Errorf("pulling gadget policy: %w", err)
+			}
+
+			orAll := func(list []string, empty string) string {
+				if len(list) == 0 {
+					return empty
+				}
+				return strings.Join(list, ", ")
+			}
+			cmd.Printf("Digest:  %s\n", policy.Digest)
+			cmd.Printf("Allowed: %s\n", orAll(policy.Allowed, "all gadgets"))
+			cmd.Printf("Denied:  %s\n", orAll(policy.Denied, "none"))
+			return nil
+		},
+	}
+
+	utils.AddRegistryAuthVariablesAndFlags(cmd, &authOpts)
+	return cmd
+}
@@ -16,18 +16,26 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v3"
 
+	"github.com/inspektor-gadget/inspektor-gadget/internal/version"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns/ellipsis"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns/formatter/textcolumns"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	gadgetmanifest "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-manifest"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	grpcruntime "github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/grpc"
 )
 
@@ -36,9 +44,21 @@ type GadgetInfo struct {
 }
 
 type NodeInstanceState struct {
-	Node    string `yaml:"Node"`
-	Status  string `yaml:"Status"`
-	Message string `yaml:"Message"`
+	Node     string   `yaml:"Node"`
+	Status   string   `yaml:"Status"`
+	Message  string   `yaml:"Message"`
+	Warnings []string `yaml:"Warnings,omitempty"`
+
+	Resources *NodeInstanceResources `yaml:"Resources,omitempty"`
+}
+
+// NodeInstanceResources are the eBPF resources used by a gadget instance on a
+// node
+type NodeInstanceResources struct {
+	Programs     uint32 `yaml:"Programs"`
+	Maps         uint32 `yaml:"Maps"`
+	MapMemory    uint64 `yaml:"MapMemory"`
+	BufferMemory uint64 `yaml:"BufferMemory"`
 }
 
 type InstanceState struct {
@@ -46,6 +66,7 @@ type InstanceState struct {
 	Name          string              `yaml:"Name"`
 	Image         string              `yaml:"Image"`
 	TimeCreated   string              `yaml:"TimeCreated"`
+	ExpiresAt     string              `yaml:"ExpiresAt,omitempty"`
 	Params        map[string]string   `yaml:"Params"`
 	NodeInstances []NodeInstanceState `yaml:"NodeInstances"`
 }
@@ -56,6 +77,7 @@ func AddInstanceCommands(
 ) {
 	runtimeParams := runtime.ParamDescs().ToParams()
 
+	var listSelector []string
 	listCmd := &cobra.Command{
 		Use:          "list",
 		Aliases:      []string{"l", "ls", "ps"},
@@ -122,31 +144,46 @@ func AddInstanceCommands(
 				return toInstanceStatus(g.pg.State)
 			})
 
-			formatter := textcolumns.NewFormatter(cols.GetColumnMap())
-			fmt.Println(formatter.FormatHeader())
-
-			gadgets, err := runtime.GetGadgetInstances(context.Background(), runtimeParams)
+			res, err := runtime.ListGadgetInstances(context.Background(), runtimeParams, listSelector)
 			if err != nil {
 				return err
 			}
-			for _, gadget := range gadgets {
+			if unreachable := res.Unreachable(); len(unreachable) == len(res.Nodes) {
+				errs := make([]error, 0, len(unreachable))
+				for _, n := range unreachable {
+					errs = append(errs, n.Error)
+				}
+				return errors.Join(errs...)
+			}
+
+			formatter := textcolumns.NewFormatter(cols.GetColumnMap())
+			fmt.Println(formatter.FormatHeader())
+			for _, gadget := range res.Instances {
 				gi := &GadgetInfo{pg: gadget}
 				fmt.Println(formatter.FormatEntry(gi))
 			}
+
+			// Tell which nodes are missing from the list or might be
+			// reporting outdated information
+			for _, msg := range nodeReportWarnings(res.Nodes, version.Version().String()) {
+				log.Warn(msg)
+			}
 			return nil
 		},
 	}
+	addSelectorFlag(listCmd.Flags(), &listSelector)
 	AddFlags(listCmd, runtimeParams, nil, runtime)
 	rootCmd.AddCommand(listCmd)
 
+	var deleteSelector []string
 	deleteCmd := &cobra.Command{
 		Use:          "delete",
 		Aliases:      []string{"d", "del"},
 		Short:        "Delete one or more gadget instances",
 		SilenceUsage: true,
-		Args:         cobra.MinimumNArgs(1),
+		Args:         idsOrSelectorArgs(&deleteSelector),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			instances, ambiguous, notfound, err := findGadgetInstances(runtime, runtimeParams, args)
+			instances, ambiguous, notfound, err := findGadgetInstances(runtime, runtimeParams, args, deleteSelector)
 			if err != nil {
 				return fmt.Errorf("getting gadget instances: %w", err)
 			}
@@ -166,9 +203,74 @@ func AddInstanceCommands(
 			return nil
 		},
 	}
+	addSelectorFlag(deleteCmd.Flags(), &deleteSelector)
 	AddFlags(deleteCmd, runtimeParams, nil, runtime)
 	rootCmd.AddCommand(deleteCmd)
 
+	var pauseSelector []string
+	pauseCmd := &cobra.Command{
+		Use:          "pause",
+		Short:        "Pause one or more gadget instances, keeping their configuration until they are resumed",
+		SilenceUsage: true,
+		Args:         idsOrSelectorArgs(&pauseSelector),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return forEachGadgetInstance(runtime, runtimeParams, args, pauseSelector, "pause", runtime.PauseGadgetInstance)
+		},
+	}
+	addSelectorFlag(pauseCmd.Flags(), &pauseSelector)
+	AddFlags(pauseCmd, runtimeParams, nil, runtime)
+	rootCmd.AddCommand(pauseCmd)
+
+	var resumeSelector []string
+	resumeCmd := &cobra.Command{
+		Use:          "resume",
+		Short:        "Resume one or more paused gadget instances",
+		SilenceUsage: true,
+		Args:         idsOrSelectorArgs(&resumeSelector),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return forEachGadgetInstance(runtime, runtimeParams, args, resumeSelector, "resume", runtime.ResumeGadgetInstance)
+		},
+	}
+	addSelectorFlag(resumeCmd.Flags(), &resumeSelector)
+	AddFlags(resumeCmd, runtimeParams, nil, runtime)
+	rootCmd.AddCommand(resumeCmd)
+
+	var updateParams []string
+	updateCmd := &cobra.Command{
+		Use:          "update",
+		Short:        "Update the params of a gadget instance, keeping its ID and buffered events",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paramValues, err := parseParamValues(updateParams)
+			if err != nil {
+				return err
+			}
+			if len(paramValues) == 0 {
+				return fmt.Errorf("no params given, use --param KEY=VALUE")
+			}
+			instances, ambiguous, notfound, err := findGadgetInstances(runtime, runtimeParams, args, nil)
+			if err != nil {
+				return fmt.Errorf("getting gadget instances: %w", err)
+			}
+			if len(ambiguous) > 0 {
+				return fmt.Errorf("ambiguous names/ids: %s", strings.Join(ambiguous, ", "))
+			}
+			if len(notfound) > 0 {
+				return fmt.Errorf("instance %q not found", args[0])
+			}
+			err = runtime.UpdateGadgetInstance(context.Background(), runtimeParams, instances[0].Id, paramValues)
+			if err != nil {
+				return fmt.Errorf("updating gadget instance %q: %w", instances[0].Id, err)
+			}
+			fmt.Printf("%s\n", instances[0].Id)
+			return nil
+		},
+	}
+	updateCmd.Flags().StringArrayVar(&updateParams, "param", nil, "param to set as KEY=VALUE, using the keys shown by the show command; can be repeated")
+	AddFlags(updateCmd, runtimeParams, nil, runtime)
+	rootCmd.AddCommand(updateCmd)
+
 	showCmd := &cobra.Command{
 		Use:          "show",
 		Aliases:      []string{"s", "sh"},
@@ -176,7 +278,7 @@ func AddInstanceCommands(
 		SilenceUsage: true,
 		Args:         cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			instances, ambiguous, notfound, err := findGadgetInstances(runtime, runtimeParams, args)
+			instances, ambiguous, notfound, err := findGadgetInstances(runtime, runtimeParams, args, nil)
 			if err != nil {
 				return fmt.Errorf("getting gadget instances: %w", err)
 			}
@@ -193,11 +295,28 @@ func AddInstanceCommands(
 
 			var nodeInstances []NodeInstanceState
 			for _, ni := range nStates {
-				nodeInstances = append(nodeInstances, NodeInstanceState{
-					Node:    ni.Node,
-					Status:  toInstanceStatus(ni.State),
-					Message: ni.State.Message,
-				})
+				nodeInstance := NodeInstanceState{
+					Node:     ni.Node,
+					Status:   toInstanceStatus(ni.State),
+					Message:  ni.State.GetMessage(),
+					Warnings: ni.State.GetWarnings(),
+				}
+				if r := ni.State.GetResources(); r != nil {
+					nodeInstance.Resources = &NodeInstanceResources{
+						Programs:     r.Programs,
+						Maps:         r.Maps,
+						MapMemory:    r.MapMemory,
+						BufferMemory: r.BufferMemory,
+					}
+				}
+				switch {
+				case ni.Error != nil:
+					nodeInstance.Status = "Unreachable"
+					nodeInstance.Message = ni.Error.Error()
+				case ni.Missing:
+					nodeInstance.Status = "Missing"
+				}
+				nodeInstances = append(nodeInstances, nodeInstance)
 			}
 			state := InstanceState{
 				ID:            instances[0].Id,
@@ -207,6 +326,9 @@ func AddInstanceCommands(
 				Params:        instances[0].GadgetConfig.ParamValues,
 				NodeInstances: nodeInstances,
 			}
+			if expiresAt := instances[0].ExpiresAt(); !expiresAt.IsZero() {
+				state.ExpiresAt = expiresAt.Format(time.RFC3339)
+			}
 
 			out, err := yaml.Marshal(state)
 			if err != nil {
@@ -219,6 +341,164 @@ func AddInstanceCommands(
 	}
 	AddFlags(showCmd, runtimeParams, nil, runtime)
 	rootCmd.AddCommand(showCmd)
+
+	var exportSelector []string
+	var exportOutput string
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the definitions of gadget instances as YAML manifest",
+		Long: `Export the definitions of gadget instances as YAML manifest.
+
+The manifest holds the image, params, tags and nodes of each gadget instance and can be applied again with the import
+command, e.g. to create the same gadget instances on another cluster or to keep them in version control.`,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			specs, err := runtime.ExportGadgetInstances(context.Background(), runtimeParams, exportSelector)
+			if err != nil {
+				return err
+			}
+			if exportOutput == "" || exportOutput == "-" {
+				return gadgetmanifest.WriteInstanceSpecs(os.Stdout, specs)
+			}
+			f, err := os.Create(exportOutput)
+			if err != nil {
+				return fmt.Errorf("creating manifest file: %w", err)
+			}
+			if err := gadgetmanifest.WriteInstanceSpecs(f, specs); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		},
+	}
+	addSelectorFlag(exportCmd.Flags(), &exportSelector)
+	exportCmd.Flags().StringVar(&exportOutput, "output-file", "", "file to write the manifest to; stdout if empty")
+	AddFlags(exportCmd, runtimeParams, nil, runtime)
+	rootCmd.AddCommand(exportCmd)
+
+	importParams := runtime.ParamDescs().ToParams()
+	importCmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Create the gadget instances of a YAML manifest that don't exist yet",
+		Long: `Create the gadget instances of a YAML manifest that don't exist yet.
+
+Gadget instances of the manifest that have an ID already in use are skipped, so the same manifest can be applied
+repeatedly. FILE can also be an http(s) URL.`,
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			specs, err := readInstanceSpecs(args[0])
+			if err != nil {
+				return err
+			}
+
+			// Don't risk creating duplicates on nodes we can't see
+			existing, err := runtime.GetGadgetInstances(context.Background(), importParams, nil)
+			if err != nil {
+				return fmt.Errorf("getting gadget instances: %w", err)
+			}
+			specs = slices.DeleteFunc(specs, func(spec *gadgetmanifest.InstanceSpec) bool {
+				if spec.ID == "" || !slices.ContainsFunc(existing, func(gi *api.GadgetInstance) bool { return gi.Id == spec.ID }) {
+					return false
+				}
+				log.Infof("gadget instance %q (%s) already exists, skipping", spec.ID, spec.Name)
+				return true
+			})
+			if len(specs) == 0 {
+				return nil
+			}
+
+			if err := importParams.Set(grpcruntime.ParamDetach, "true"); err != nil {
+				return err
+			}
+			return runInstanceSpecsDetached(context.Background(), runtime, specs, importParams,
+				gadgetcontext.WithIsClient(runtime.IsClient()),
+				gadgetcontext.WithUseInstance(false),
+			)
+		},
+	}
+	AddFlags(importCmd, importParams, nil, runtime)
+	rootCmd.AddCommand(importCmd)
+}
+
+// parseParamValues turns a list of KEY=VALUE strings into a map
+func parseParamValues(kvs []string) (map[string]string, error) {
+	paramValues := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid param %q: expected KEY=VALUE", kv)
+		}
+		paramValues[k] = v
+	}
+	return paramValues, nil
+}
+
+// addSelectorFlag adds the --selector flag used to pick gadget instances by
+// their tags
+func addSelectorFlag(fs *pflag.FlagSet, selector *[]string) {
+	fs.StringSliceVar(selector, "selector", nil, "only consider gadget instances with all of the given tags, like team=netops,env=prod")
+}
+
+// idsOrSelectorArgs requires at least one gadget instance name or ID, unless a
+// selector is given
+func idsOrSelectorArgs(selector *[]string) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(*selector) > 0 {
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	}
+}
+
+// forEachGadgetInstance runs fn for the gadget instances matching the given
+// names or IDs and selector and prints the IDs of the ones it succeeded for
+func forEachGadgetInstance(
+	runtime *grpcruntime.Runtime,
+	runtimeParams *params.Params,
+	idOrNames []string,
+	selector []string,
+	action string,
+	fn func(ctx context.Context, runtimeParams *params.Params, id string) error,
+) error {
+	instances, ambiguous, notfound, err := findGadgetInstances(runtime, runtimeParams, idOrNames, selector)
+	if err != nil {
+		return fmt.Errorf("getting gadget instances: %w", err)
+	}
+	if len(ambiguous) > 0 {
+		fmt.Fprintf(os.Stderr, "ambiguous names/ids: %s\n", strings.Join(ambiguous, ", "))
+	}
+	if len(notfound) > 0 {
+		fmt.Fprintf(os.Stderr, "not found names/ids: %s\n", strings.Join(notfound, ", "))
+	}
+	for _, instance := range instances {
+		if err := fn(context.Background(), runtimeParams, instance.Id); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to %s gadget instance %q: %v\n", action, instance.Id, err)
+			continue
+		}
+		fmt.Printf("%s\n", instance.Id)
+	}
+	return nil
+}
+
+// nodeReportWarnings returns a message for every issue found in the node
+// reports of a gadget instance listing
+func nodeReportWarnings(nodes []*grpcruntime.NodeReport, clientVersion string) []string {
+	var msgs []string
+	for _, n := range nodes {
+		if !n.Reachable {
+			msgs = append(msgs, fmt.Sprintf("node %q is unreachable, its instances are missing: %v", n.Node, n.Error))
+			continue
+		}
+		if n.VersionSkew {
+			msgs = append(msgs, fmt.Sprintf("node %q runs v%s, which differs from the client (v%s)", n.Node, strings.TrimPrefix(n.ServerVersion, "v"), clientVersion))
+		}
+		if len(n.StaleInstances) > 0 {
+			msgs = append(msgs, fmt.Sprintf("node %q has no state yet for gadget instances: %s", n.Node, strings.Join(n.StaleInstances, ", ")))
+		}
+	}
+	return msgs
 }
 
 func toInstanceStatus(state *api.GadgetInstanceState) string {
@@ -230,6 +510,8 @@ func toInstanceStatus(state *api.GadgetInstanceState) string {
 		return "Running"
 	case api.GadgetInstanceStatus_StatusError:
 		return "Error"
+	case api.GadgetInstanceStatus_StatusPaused:
+		return "Paused"
 	default:
 		return "Unknown"
 	}
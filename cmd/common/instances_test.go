@@ -0,0 +1,75 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	grpcruntime "github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/grpc"
+)
+
+func TestParseParamValues(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		values   []string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{name: "empty", values: nil, expected: map[string]string{}},
+		{
+			name:     "multiple",
+			values:   []string{"operator.filter.filter=pid==1", "operator.oci.ebpf.paths=true"},
+			expected: map[string]string{"operator.filter.filter": "pid==1", "operator.oci.ebpf.paths": "true"},
+		},
+		{name: "empty_value", values: []string{"operator.filter.filter="}, expected: map[string]string{"operator.filter.filter": ""}},
+		{name: "missing_value", values: []string{"operator.filter.filter"}, wantErr: true},
+		{name: "missing_key", values: []string{"=pid==1"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseParamValues(test.values)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestNodeReportWarnings(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*grpcruntime.NodeReport{
+		{Node: "node1", Reachable: true, ServerVersion: "v0.40.0"},
+		{Node: "node2", Error: errors.New("connection refused")},
+		{Node: "node3", Reachable: true, ServerVersion: "v0.39.0", VersionSkew: true},
+		{Node: "node4", Reachable: true, StaleInstances: []string{"aaa", "bbb"}},
+	}
+	require.Equal(t, []string{
+		`node "node2" is unreachable, its instances are missing: connection refused`,
+		`node "node3" runs v0.39.0, which differs from the client (v0.40.0)`,
+		`node "node4" has no state yet for gadget instances: aaa, bbb`,
+	}, nodeReportWarnings(nodes, "0.40.0"))
+
+	require.Empty(t, nodeReportWarnings(nodes[:1], "0.40.0"))
+}
@@ -0,0 +1,164 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns/formatter/textcolumns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+)
+
+type NodeInfo struct {
+	Node                string          `json:"node" yaml:"node" column:"node"`
+	KernelRelease       string          `json:"kernelRelease" yaml:"kernelRelease" column:"kernel"`
+	BTF                 bool            `json:"btf" yaml:"btf" column:"btf"`
+	RingBuffer          bool            `json:"ringBuffer" yaml:"ringBuffer" column:"ringbuf"`
+	CgroupMode          string          `json:"cgroupMode" yaml:"cgroupMode" column:"cgroup"`
+	LSMBPF              bool            `json:"lsmBPF" yaml:"lsmBPF" column:"lsm-bpf"`
+	Lockdown            string          `json:"lockdown" yaml:"lockdown" column:"lockdown"`
+	TracepointCount     uint32          `json:"tracepointCount" yaml:"tracepointCount" column:"tracepoints"`
+	KprobeFunctionCount uint32          `json:"kprobeFunctionCount" yaml:"kprobeFunctionCount" column:"kprobes"`
+	Tracepoints         map[string]bool `json:"tracepoints,omitempty" yaml:"tracepoints,omitempty" column:"-"`
+	Kprobes             map[string]bool `json:"kprobes,omitempty" yaml:"kprobes,omitempty" column:"-"`
+}
+
+func NewNodeInfoCmd(rt runtime.Runtime) *cobra.Command {
+	var outputMode string
+	var tracepoints []string
+	var kprobes []string
+
+	outputModes := []string{utils.OutputModeColumns, utils.OutputModeJSON, utils.OutputModeJSONPretty, utils.OutputModeYAML}
+
+	runtimeParams := rt.ParamDescs().ToParams()
+
+	cmd := &cobra.Command{
+		Use:   "node-info",
+		Short: "Show the kernel features relevant to gadgets of the node(s)",
+		Long: `Show the kernel features relevant to gadgets of the node(s), like BTF presence, ring buffer support,
+cgroup mode, BPF LSM availability, the lockdown mode and the number of available tracepoints and kprobes.
+
+Use --tracepoint and --kprobe to check whether specific tracepoints or kernel functions are available.`,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, ok := rt.(runtime.NodeInfoProvider)
+			if !ok {
+				return fmt.Errorf("runtime doesn't support getting node information")
+			}
+
+			infos, err := provider.GetNodeInfo(context.Background(), runtimeParams, &api.NodeInfoRequest{
+				Tracepoints: tracepoints,
+				Kprobes:     kprobes,
+			})
+			if err != nil && len(infos) == 0 {
+				return fmt.Errorf("getting node information: %w", err)
+			}
+
+			nodeInfos := make([]*NodeInfo, 0, len(infos))
+			for _, node := range slices.Sorted(maps.Keys(infos)) {
+				info := infos[node]
+				nodeInfos = append(nodeInfos, &NodeInfo{
+					Node:                node,
+					KernelRelease:       info.KernelRelease,
+					BTF:                 info.BtfAvailable,
+					RingBuffer:          info.RingBufferSupported,
+					CgroupMode:          info.CgroupMode,
+					LSMBPF:              info.LsmBPFAvailable,
+					Lockdown:            info.Lockdown,
+					TracepointCount:     info.TracepointCount,
+					KprobeFunctionCount: info.KprobeFunctionCount,
+					Tracepoints:         info.Tracepoints,
+					Kprobes:             info.Kprobes,
+				})
+			}
+
+			switch outputMode {
+			case utils.OutputModeJSON:
+				bytes, err := json.Marshal(nodeInfos)
+				if err != nil {
+					return fmt.Errorf("marshalling node information to JSON: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(bytes))
+			case utils.OutputModeJSONPretty:
+				bytes, err := json.MarshalIndent(nodeInfos, "", "  ")
+				if err != nil {
+					return fmt.Errorf("marshalling node information to JSON: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(bytes))
+			case utils.OutputModeYAML:
+				bytes, err := yaml.Marshal(nodeInfos)
+				if err != nil {
+					return fmt.Errorf("marshalling node information to YAML: %w", err)
+				}
+				fmt.Fprint(cmd.OutOrStdout(), string(bytes))
+			case utils.OutputModeColumns:
+				cols := columns.MustCreateColumns[NodeInfo]()
+				formatter := textcolumns.NewFormatter(cols.GetColumnMap())
+				formatter.WriteTable(cmd.OutOrStdout(), nodeInfos)
+
+				for _, info := range nodeInfos {
+					if missing := unavailable(info.Tracepoints); len(missing) > 0 {
+						fmt.Fprintf(cmd.OutOrStdout(), "%s: missing tracepoints: %s\n", info.Node, strings.Join(missing, ", "))
+					}
+					if missing := unavailable(info.Kprobes); len(missing) > 0 {
+						fmt.Fprintf(cmd.OutOrStdout(), "%s: missing kprobes: %s\n", info.Node, strings.Join(missing, ", "))
+					}
+				}
+			default:
+				return fmt.Errorf("invalid output mode %q, valid values are: %s", outputMode, strings.Join(outputModes, ", "))
+			}
+
+			// Report partial failures after printing the nodes that could be reached
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&outputMode,
+		"output",
+		"o",
+		utils.OutputModeColumns,
+		fmt.Sprintf("Output mode, possible values are, %s", strings.Join(outputModes, ", ")),
+	)
+	cmd.Flags().StringSliceVar(&tracepoints, "tracepoint", nil, "Check whether the given tracepoints (category/name) are available")
+	cmd.Flags().StringSliceVar(&kprobes, "kprobe", nil, "Check whether the given kernel functions can be used with kprobes")
+
+	// Only keep params that select the targets, like --node
+	AddOCIFlags(cmd, runtimeParams, []string{"!attach"}, rt)
+
+	return cmd
+}
+
+func unavailable(checks map[string]bool) []string {
+	var res []string
+	for _, name := range slices.Sorted(maps.Keys(checks)) {
+		if !checks[name] {
+			res = append(res, name)
+		}
+	}
+	return res
+}
@@ -36,13 +36,18 @@ import (
 	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
 	clioperator "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/cli"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/clickhouse"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/combiner"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/file"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/generate_networkpolicy"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kafka"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/limiter"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/loki"
 	ocihandler "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/oci-handler"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/otel-logs"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/otel-metrics"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/sort"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/syslog"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ustack"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
@@ -64,32 +69,26 @@ var commandModesDescriptions = map[CommandMode]string{
 	CommandModeAttach: "Attach to a running gadget",
 }
 
-func findGadgetInstances(runtime *grpcruntime.Runtime, runtimeParams *params.Params, idOrNames []string) (instances []*api.GadgetInstance, ambiguous []string, notfound []string, retErr error) {
-	gadgetInstances, err := runtime.GetGadgetInstances(context.Background(), runtimeParams)
+// findGadgetInstances looks up the gadget instances with the given (partial)
+// IDs or names among the ones matching the selector; all instances matching
+// the selector are returned if no IDs or names are given
+func findGadgetInstances(runtime *grpcruntime.Runtime, runtimeParams *params.Params, idOrNames []string, selector []string) (instances []*api.GadgetInstance, ambiguous []string, notfound []string, retErr error) {
+	gadgetInstances, err := runtime.GetGadgetInstances(context.Background(), runtimeParams, selector)
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	if len(idOrNames) == 0 {
+		return gadgetInstances, nil, nil, nil
+	}
 
-nextName:
 	for _, idOrName := range idOrNames {
-		matches := 0
-		var instance *api.GadgetInstance
-		for _, tmpGadgetInstance := range gadgetInstances {
-			if idOrName == tmpGadgetInstance.Id {
-				instances = append(instances, tmpGadgetInstance)
-				break nextName
-			}
-			// match partial ID or full name
-			if tmpGadgetInstance.Name == idOrName || strings.HasPrefix(tmpGadgetInstance.Id, idOrName) {
-				instance = tmpGadgetInstance
-				matches++
-			}
-		}
-		if matches > 1 {
+		instance, err := grpcruntime.FindGadgetInstance(gadgetInstances, idOrName)
+		switch {
+		case errors.Is(err, grpcruntime.ErrAmbiguousGadgetInstance):
 			ambiguous = append(ambiguous, idOrName)
-		} else if matches == 0 {
+		case err != nil:
 			notfound = append(notfound, idOrName)
-		} else {
+		default:
 			instances = append(instances, instance)
 		}
 	}
@@ -115,10 +114,13 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 	var info *api.GadgetInfo
 	paramLookup := map[string]*params.Param{}
 
-	var timeoutSeconds int
+	var timeout time.Duration
+	var since string
+	var selector []string
 	var gadgetInstanceID string
 
 	var inFile string
+	var dryRun bool
 
 	var skipParams []string
 	if commandMode == CommandModeAttach {
@@ -167,7 +169,7 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 		// Before running the gadget, we need to get the gadget info to be able to set
 		// things (like params) up correctly
 		actualArgs := cmd.Flags().Args()
-		if len(actualArgs) == 0 {
+		if len(actualArgs) == 0 && len(selector) == 0 {
 			return cmd.ParseFlags(args)
 		}
 
@@ -188,9 +190,16 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 		ops = append(ops, clioperator.CLIOperator, combiner.CombinerOperator, generate_networkpolicy.GNPOperator)
 		initializedOperators = true
 
-		imageName := actualArgs[0]
+		var imageName string
+		if len(actualArgs) > 0 {
+			imageName = actualArgs[0]
+		}
 		if grpcrt, ok := runtime.(*grpcruntime.Runtime); ok && commandMode == CommandModeAttach {
-			instances, ambiguous, notfound, err := findGadgetInstances(grpcrt, runtimeParams, []string{imageName})
+			var idOrNames []string
+			if imageName != "" {
+				idOrNames = []string{imageName}
+			}
+			instances, ambiguous, notfound, err := findGadgetInstances(grpcrt, runtimeParams, idOrNames, selector)
 			if err != nil {
 				return fmt.Errorf("getting gadget instances: %w", err)
 			}
@@ -200,6 +209,9 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 			if len(ambiguous) > 0 {
 				return fmt.Errorf("gadget instance id or name are ambiguous")
 			}
+			if len(instances) > 1 {
+				return fmt.Errorf("selector matches %d gadget instances, only one can be attached to", len(instances))
+			}
 			imageName = instances[0].Id
 
 			if len(instances[0].Nodes) > 0 {
@@ -271,7 +283,7 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 
 		showHelp, _ := cmd.Flags().GetBool("help")
 
-		if len(args) == 0 && inFile == "" {
+		if len(args) == 0 && inFile == "" && len(selector) == 0 {
 			if showHelp {
 				additionalMessage := "Specify the gadget image to get more information about it"
 				cmd.Long = fmt.Sprintf("%s\n\n%s", cmd.Short, additionalMessage)
@@ -307,8 +319,6 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 		}
 		ops = append(ops, clioperator.CLIOperator, combiner.CombinerOperator, generate_networkpolicy.GNPOperator)
 
-		timeoutDuration := time.Duration(timeoutSeconds) * time.Second
-
 		var image string
 		if len(args) > 0 {
 			image = args[0]
@@ -317,34 +327,19 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 		paramValueMap := make(map[string]string)
 
 		if inFile != "" {
-			var f io.ReadCloser
-			var err error
-			if strings.HasPrefix(inFile, "http://") || strings.HasPrefix(inFile, "https://") {
-				f, err = utils.DownloadFile(inFile)
-				if err != nil {
-					return fmt.Errorf("downloading gadget runtime manifest file %s: %w", inFile, err)
-				}
-			} else {
-				f, err = os.Open(inFile)
-				if err != nil {
-					return fmt.Errorf("opening gadget runtime manifest file %s: %w", inFile, err)
-				}
-			}
-
-			specs, err := gadgetmanifest.InstanceSpecsFromReader(f)
-			f.Close()
+			specs, err := readInstanceSpecs(inFile)
 			if err != nil {
-				return fmt.Errorf("reading gadget runtime manifest file %s: %w", inFile, err)
+				return err
 			}
 
 			detachedParam := runtimeParams.Get("detach")
 			isDetach := detachedParam != nil && detachedParam.AsBool()
 
-			if isDetach {
+			if isDetach && !dryRun {
 				return runInstanceSpecsDetached(ctx, runtime, specs, runtimeParams,
 					gadgetcontext.WithIsClient(runtime.IsClient()),
 					gadgetcontext.WithDataOperators(ops...),
-					gadgetcontext.WithTimeout(timeoutDuration),
+					gadgetcontext.WithTimeout(timeout),
 					gadgetcontext.WithUseInstance(false),
 				)
 			}
@@ -377,7 +372,7 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 			ctx,
 			image,
 			gadgetcontext.WithDataOperators(ops...),
-			gadgetcontext.WithTimeout(timeoutDuration),
+			gadgetcontext.WithTimeout(timeout),
 			gadgetcontext.WithUseInstance(commandMode == CommandModeAttach),
 			gadgetcontext.WithIsClient(runtime.IsClient()),
 		)
@@ -392,6 +387,18 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 		// Also copy special oci params
 		ociParams.CopyToMap(paramValueMap, "operator.oci.")
 
+		if grpcrt, ok := runtime.(*grpcruntime.Runtime); ok && commandMode == CommandModeAttach {
+			sinceTime, err := parseSince(since, time.Now())
+			if err != nil {
+				return err
+			}
+			return grpcrt.AttachGadgetInstance(gadgetCtx, runtimeParams, sinceTime)
+		}
+
+		if dryRun {
+			return validateGadget(cmd.OutOrStdout(), runtime, gadgetCtx, runtimeParams, paramValueMap)
+		}
+
 		err := runtime.RunGadget(gadgetCtx, runtimeParams, paramValueMap)
 		if err != nil {
 			return err
@@ -418,19 +425,28 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 
 	if commandMode == CommandModeAttach {
 		cmd.Aliases = []string{"a"}
+		cmd.PersistentFlags().StringVar(
+			&since,
+			"since",
+			"",
+			"Only replay the events buffered since this time, either relative like 5m or an RFC3339 timestamp; all buffered events are replayed if empty",
+		)
+		addSelectorFlag(cmd.PersistentFlags(), &selector)
 	}
 
-	cmd.PersistentFlags().IntVarP(
-		&timeoutSeconds,
+	utils.DurationVarP(
+		cmd.PersistentFlags(),
+		&timeout,
 		"timeout",
 		"t",
 		0,
-		"Number of seconds that the gadget will run for, 0 to run indefinitely",
+		"Time the gadget will run for, like 30s or 5m; plain numbers are seconds. 0 to run indefinitely",
 	)
 
 	if commandMode != CommandModeAttach {
 		AddOCIFlags(cmd, ociParams, skipParams, runtime)
 		cmd.PersistentFlags().StringVarP(&inFile, "file", "f", "", "path or remote URL (prefixed with http:// or https://) to a gadget runtime manifest file")
+		cmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Check whether the gadget would run with the given params, without attaching any program")
 	}
 
 	AddOCIFlags(cmd, runtimeGlobalParams, skipParams, runtime)
@@ -443,6 +459,47 @@ func NewRunCommand(rootCmd *cobra.Command, runtime runtime.Runtime, hiddenColumn
 	return cmd
 }
 
+// parseSince parses the value of --since, which is either a duration before now
+// or an RFC3339 timestamp
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := params.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid value %q for --since: expected a duration like 5m or an RFC3339 timestamp", s)
+	}
+	return t, nil
+}
+
+// readInstanceSpecs reads the gadget instance specs of the manifest at the
+// given path or http(s) URL
+func readInstanceSpecs(inFile string) ([]*gadgetmanifest.InstanceSpec, error) {
+	var f io.ReadCloser
+	var err error
+	if strings.HasPrefix(inFile, "http://") || strings.HasPrefix(inFile, "https://") {
+		f, err = utils.DownloadFile(inFile)
+		if err != nil {
+			return nil, fmt.Errorf("downloading gadget runtime manifest file %s: %w", inFile, err)
+		}
+	} else {
+		f, err = os.Open(inFile)
+		if err != nil {
+			return nil, fmt.Errorf("opening gadget runtime manifest file %s: %w", inFile, err)
+		}
+	}
+
+	specs, err := gadgetmanifest.InstanceSpecsFromReader(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading gadget runtime manifest file %s: %w", inFile, err)
+	}
+	return specs, nil
+}
+
 func runInstanceSpecsDetached(
 	ctx context.Context,
 	runtime runtime.Runtime,
@@ -460,6 +517,19 @@ func runInstanceSpecsDetached(
 		runtimeParams.Set("tags", strings.Join(spec.Tags, ","))
 		runtimeParams.Set("node", strings.Join(spec.Nodes, ","))
 
+		ttl := spec.TTL
+		if ttl == "" {
+			ttl = "0"
+		}
+		if err := setSpecParams(runtimeParams, map[string]string{
+			"node-selector": spec.NodeSelector,
+			"tolerations":   spec.Tolerations,
+			"ttl":           ttl,
+		}); err != nil {
+			merr = errors.Join(merr, fmt.Errorf("running gadget from manifest file: %w", err))
+			continue
+		}
+
 		gadgetCtx := gadgetcontext.New(ctx, image, runOptions...)
 
 		err := runtime.RunGadget(gadgetCtx, runtimeParams, spec.ParamValues)
@@ -470,6 +540,17 @@ func runInstanceSpecsDetached(
 	return merr
 }
 
+// setSpecParams sets runtime params taken from a gadget instance spec; params
+// the runtime doesn't know about are skipped
+func setSpecParams(runtimeParams *params.Params, values map[string]string) error {
+	for k, v := range values {
+		if err := runtimeParams.Set(k, v); err != nil && !errors.Is(err, params.ErrNotFound) {
+			return fmt.Errorf("setting %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
 func AddOCIFlags(cmd *cobra.Command, params *params.Params, skipParams []string, runtime runtime.Runtime) {
 	defer func() {
 		if err := recover(); err != nil {
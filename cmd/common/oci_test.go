@@ -0,0 +1,53 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSince(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{name: "empty", value: "", expected: time.Time{}},
+		{name: "relative", value: "5m", expected: now.Add(-5 * time.Minute)},
+		{name: "relative_days", value: "1d", expected: now.Add(-24 * time.Hour)},
+		{name: "rfc3339", value: "2025-06-01T11:30:00Z", expected: time.Date(2025, 6, 1, 11, 30, 0, 0, time.UTC)},
+		{name: "invalid", value: "yesterday", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseSince(test.value, now)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, test.expected.Equal(got), "expected %s, got %s", test.expected, got)
+		})
+	}
+}
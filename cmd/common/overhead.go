@@ -0,0 +1,226 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns/formatter/textcolumns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+)
+
+type NodeOverhead struct {
+	Node              string              `json:"node" yaml:"node" column:"node"`
+	CPUPercent        float64             `json:"cpuPercent" yaml:"cpuPercent" column:"cpu%,precision:1"`
+	Memory            string              `json:"-" yaml:"-" column:"memory"`
+	MemoryBytes       uint64              `json:"memoryBytes" yaml:"memoryBytes" column:"-"`
+	BPFRunTimePercent float64             `json:"bpfRunTimePercent" yaml:"bpfRunTimePercent" column:"bpf-cpu%,precision:1"`
+	BPFPrograms       uint32              `json:"bpfPrograms" yaml:"bpfPrograms" column:"progs"`
+	BPFMaps           uint32              `json:"bpfMaps" yaml:"bpfMaps" column:"maps"`
+	BPFMapMemory      string              `json:"-" yaml:"-" column:"map-memory"`
+	BPFMapMemoryBytes uint64              `json:"bpfMapMemoryBytes" yaml:"bpfMapMemoryBytes" column:"-"`
+	EventsPerSecond   float64             `json:"eventsPerSecond" yaml:"eventsPerSecond" column:"events/s,precision:1"`
+	Instances         []*InstanceOverhead `json:"instances,omitempty" yaml:"instances,omitempty" column:"-"`
+}
+
+type InstanceOverhead struct {
+	Node            string  `json:"-" yaml:"-" column:"node"`
+	ID              string  `json:"id" yaml:"id" column:"id,width:12"`
+	Name            string  `json:"name" yaml:"name" column:"name"`
+	Image           string  `json:"image" yaml:"image" column:"image"`
+	EventsPerSecond float64 `json:"eventsPerSecond" yaml:"eventsPerSecond" column:"events/s,precision:1"`
+}
+
+func NewTopCmd(rt runtime.Runtime) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Show the resources used by Inspektor Gadget",
+	}
+	cmd.AddCommand(newTopNodeCmd(rt))
+	return cmd
+}
+
+func newTopNodeCmd(rt runtime.Runtime) *cobra.Command {
+	var outputMode string
+	var interval time.Duration
+	var watch bool
+
+	outputModes := []string{utils.OutputModeColumns, utils.OutputModeJSON, utils.OutputModeJSONPretty, utils.OutputModeYAML}
+
+	runtimeParams := rt.ParamDescs().ToParams()
+
+	cmd := &cobra.Command{
+		Use:   "node",
+		Short: "Show the overhead of Inspektor Gadget on the node(s)",
+		Long: `Show the overhead of Inspektor Gadget on the node(s): the CPU and memory used by its process, the CPU
+time spent running its eBPF programs, the memory of its eBPF maps and the rate of events of each gadget instance.
+
+CPU usage and rates are measured over --interval; 100% means one CPU fully used.`,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, ok := rt.(runtime.NodeOverheadProvider)
+			if !ok {
+				return fmt.Errorf("runtime doesn't support getting the overhead of nodes")
+			}
+			if !slices.Contains(outputModes, outputMode) {
+				return fmt.Errorf("invalid output mode %q, valid values are: %s", outputMode, strings.Join(outputModes, ", "))
+			}
+			if interval < time.Millisecond {
+				return fmt.Errorf("invalid interval %s", interval)
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			for {
+				overheads, err := provider.GetNodeOverhead(ctx, runtimeParams, &api.NodeOverheadRequest{
+					IntervalMs: uint32(interval.Milliseconds()),
+				})
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err != nil && len(overheads) == 0 {
+					return fmt.Errorf("getting node overhead: %w", err)
+				}
+
+				if watch && outputMode == utils.OutputModeColumns {
+					utils.ClearScreen()
+				}
+				if perr := printNodeOverheads(cmd, outputMode, toNodeOverheads(overheads)); perr != nil {
+					return perr
+				}
+
+				if !watch {
+					// Report partial failures after printing the nodes that could be reached
+					return err
+				}
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&outputMode,
+		"output",
+		"o",
+		utils.OutputModeColumns,
+		fmt.Sprintf("Output mode, possible values are, %s", strings.Join(outputModes, ", ")),
+	)
+	cmd.Flags().DurationVar(&interval, "interval", time.Second, "Interval over which CPU usage and event rates are measured")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Keep refreshing the overhead until interrupted")
+
+	// Only keep params that select the targets, like --node
+	AddOCIFlags(cmd, runtimeParams, []string{"!attach"}, rt)
+
+	return cmd
+}
+
+func toNodeOverheads(overheads map[string]*api.NodeOverhead) []*NodeOverhead {
+	res := make([]*NodeOverhead, 0, len(overheads))
+	for _, node := range slices.Sorted(maps.Keys(overheads)) {
+		o := overheads[node]
+		no := &NodeOverhead{
+			Node:              node,
+			CPUPercent:        o.CpuPercent,
+			Memory:            humanize.IBytes(o.MemoryBytes),
+			MemoryBytes:       o.MemoryBytes,
+			BPFRunTimePercent: o.BpfRunTimePercent,
+			BPFPrograms:       o.BpfProgramCount,
+			BPFMaps:           o.BpfMapCount,
+			BPFMapMemory:      humanize.IBytes(o.BpfMapMemoryBytes),
+			BPFMapMemoryBytes: o.BpfMapMemoryBytes,
+		}
+		for _, inst := range o.Instances {
+			no.EventsPerSecond += inst.EventsPerSecond
+			no.Instances = append(no.Instances, &InstanceOverhead{
+				Node:            node,
+				ID:              inst.Id,
+				Name:            inst.Name,
+				Image:           inst.ImageName,
+				EventsPerSecond: inst.EventsPerSecond,
+			})
+		}
+		// Busiest instances first
+		slices.SortStableFunc(no.Instances, func(a, b *InstanceOverhead) int {
+			switch {
+			case a.EventsPerSecond > b.EventsPerSecond:
+				return -1
+			case a.EventsPerSecond < b.EventsPerSecond:
+				return 1
+			}
+			return strings.Compare(a.ID, b.ID)
+		})
+		res = append(res, no)
+	}
+	return res
+}
+
+func printNodeOverheads(cmd *cobra.Command, outputMode string, overheads []*NodeOverhead) error {
+	switch outputMode {
+	case utils.OutputModeJSON:
+		bytes, err := json.Marshal(overheads)
+		if err != nil {
+			return fmt.Errorf("marshalling node overhead to JSON: %w", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(bytes))
+	case utils.OutputModeJSONPretty:
+		bytes, err := json.MarshalIndent(overheads, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshalling node overhead to JSON: %w", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(bytes))
+	case utils.OutputModeYAML:
+		bytes, err := yaml.Marshal(overheads)
+		if err != nil {
+			return fmt.Errorf("marshalling node overhead to YAML: %w", err)
+		}
+		fmt.Fprint(cmd.OutOrStdout(), string(bytes))
+	case utils.OutputModeColumns:
+		cols := columns.MustCreateColumns[NodeOverhead]()
+		formatter := textcolumns.NewFormatter(cols.GetColumnMap())
+		formatter.WriteTable(cmd.OutOrStdout(), overheads)
+
+		var instances []*InstanceOverhead
+		for _, o := range overheads {
+			instances = append(instances, o.Instances...)
+		}
+		if len(instances) > 0 {
+			fmt.Fprintln(cmd.OutOrStdout())
+			instCols := columns.MustCreateColumns[InstanceOverhead]()
+			instFormatter := textcolumns.NewFormatter(instCols.GetColumnMap())
+			instFormatter.WriteTable(cmd.OutOrStdout(), instances)
+		}
+	}
+	return nil
+}
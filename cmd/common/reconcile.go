@@ -0,0 +1,87 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	grpcruntime "github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/grpc"
+)
+
+func NewReconcileCmd(runtime *grpcruntime.Runtime) *cobra.Command {
+	var selector []string
+	var interval time.Duration
+	var once bool
+
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Keep gadget instances deployed on all nodes as nodes join and leave",
+		Long: `Keep gadget instances deployed on all nodes as nodes join and leave.
+
+Gadget instances created with --detach are only installed on the nodes known at that time. This command looks up the
+nodes again every --interval, using --remote-address-file to follow changes to the list of nodes, installs the
+gadget instances on the nodes that joined and removes them from the nodes that left.`,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rc, err := runtime.NewInstanceReconciler(runtime.ParamDescs().ToParams(), selector)
+			if err != nil {
+				return err
+			}
+
+			report := func(actions []*grpcruntime.ReconcileAction, err error) {
+				for _, action := range actions {
+					if action.Error != nil {
+						log.Warn(action.String())
+						continue
+					}
+					log.Info(action.String())
+				}
+				if err != nil {
+					log.Warnf("reconciling gadget instances: %v", err)
+				}
+			}
+
+			if once {
+				actions, err := rc.Reconcile(context.Background())
+				report(actions, nil)
+				if err != nil {
+					return fmt.Errorf("reconciling gadget instances: %w", err)
+				}
+				return nil
+			}
+
+			if interval <= 0 {
+				return fmt.Errorf("interval must be positive")
+			}
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+			rc.Run(ctx, interval, report)
+			return nil
+		},
+	}
+	addSelectorFlag(cmd.Flags(), &selector)
+	cmd.Flags().DurationVar(&interval, "interval", 30*time.Second, "Time between two reconciliations")
+	cmd.Flags().BoolVar(&once, "once", false, "Reconcile once and exit")
+	return cmd
+}
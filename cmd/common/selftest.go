@@ -0,0 +1,199 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/selftest"
+)
+
+// SelfTestActivity generates the activity the self-test expects events for
+type SelfTestActivity struct {
+	// EnrichmentFields are the fields the events need to have set
+	EnrichmentFields []string
+	// AddFlags adds the flags configuring the activity
+	AddFlags func(cmd *cobra.Command)
+	// Validate checks the flags before the gadget is run
+	Validate func(runtimeParams *params.Params) error
+	// Generate starts opening files whose path contains the marker until ctx
+	// is done. The returned function cleans up the resources created.
+	Generate func(ctx context.Context, marker string, runtimeParams *params.Params) (func(), error)
+}
+
+func NewSelfTestCmd(rt runtime.Runtime, activity SelfTestActivity) *cobra.Command {
+	var outputMode string
+	var timeout time.Duration
+	var maxLatency time.Duration
+
+	outputModes := []string{utils.OutputModeColumns, utils.OutputModeJSON, utils.OutputModeJSONPretty}
+
+	runtimeParams := rt.ParamDescs().ToParams()
+
+	cmd := &cobra.Command{
+		Use:   "selftest",
+		Short: "Verify that events flow through the whole pipeline on a node",
+		Long: `Verify that events flow through the whole pipeline on a node: the gadget built into the
+binaries is loaded, known activity is generated and the events it causes have to go through the
+enrichment and the other operators to the output within --max-latency.
+
+The command exits with a non-zero status if any check fails.`,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(outputModes, outputMode) {
+				return fmt.Errorf("invalid output mode %q, valid values are: %s", outputMode, strings.Join(outputModes, ", "))
+			}
+			if activity.Validate != nil {
+				if err := activity.Validate(runtimeParams); err != nil {
+					return err
+				}
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			report := runSelfTest(ctx, rt, runtimeParams, activity, timeout, maxLatency)
+
+			var err error
+			switch outputMode {
+			case utils.OutputModeJSON, utils.OutputModeJSONPretty:
+				var b []byte
+				if outputMode == utils.OutputModeJSONPretty {
+					b, err = json.MarshalIndent(report, "", "  ")
+				} else {
+					b, err = json.Marshal(report)
+				}
+				if err != nil {
+					return fmt.Errorf("marshaling report: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(b))
+			default:
+				report.Print(cmd.OutOrStdout())
+			}
+
+			if !report.Passed() {
+				return errors.New("self-test failed")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(
+		&outputMode,
+		"output",
+		"o",
+		utils.OutputModeColumns,
+		fmt.Sprintf("Output mode, possible values are, %s", strings.Join(outputModes, ", ")),
+	)
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Maximum time to wait for the gadget to start and the events to be received")
+	cmd.Flags().DurationVar(&maxLatency, "max-latency", 2*time.Second, "Maximum time between an event happening and being received")
+	if activity.AddFlags != nil {
+		activity.AddFlags(cmd)
+	}
+
+	// Only keep params that select the targets, like --node
+	AddOCIFlags(cmd, runtimeParams, []string{"!attach"}, rt)
+
+	return cmd
+}
+
+func runSelfTest(
+	ctx context.Context,
+	rt runtime.Runtime,
+	runtimeParams *params.Params,
+	activity SelfTestActivity,
+	timeout time.Duration,
+	maxLatency time.Duration,
+) *selftest.Report {
+	checker := selftest.NewChecker(selftest.Options{
+		Marker:           selftest.NewMarker(),
+		EnrichmentFields: activity.EnrichmentFields,
+		TimestampField:   selftest.ImageTimestampField,
+		BootTime:         true,
+		MaxLatency:       maxLatency,
+	})
+
+	ops := make([]operators.DataOperator, 0)
+	for _, op := range operators.GetDataOperators() {
+		if err := op.Init(apihelpers.ToParamDescs(op.GlobalParams()).ToParams()); err != nil {
+			log.Warnf("error initializing operator %s: %v", op.Name(), err)
+			continue
+		}
+		ops = append(ops, op)
+	}
+	ops = append(ops, checker.Operator())
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	gadgetCtx := gadgetcontext.New(
+		runCtx,
+		selftest.Image,
+		gadgetcontext.WithDataOperators(ops...),
+		gadgetcontext.WithIsClient(rt.IsClient()),
+	)
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- rt.RunGadget(gadgetCtx, runtimeParams, map[string]string{})
+	}()
+
+	var err error
+	select {
+	case <-checker.Loaded():
+	case err = <-runErr:
+		return checker.Report(err)
+	case <-runCtx.Done():
+		cancel()
+		return checker.Report(<-runErr)
+	}
+
+	cleanup, activityErr := activity.Generate(runCtx, checker.Marker(), runtimeParams)
+	if activityErr != nil {
+		checker.SetActivityError(activityErr)
+	} else {
+		defer cleanup()
+
+		select {
+		case <-checker.Found():
+		case err = <-runErr:
+			return checker.Report(err)
+		case <-runCtx.Done():
+		}
+	}
+
+	cancel()
+	<-runErr
+	return checker.Report(nil)
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -27,6 +28,7 @@ import (
 
 	runtimeclient "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/runtime-client"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 )
 
 const (
@@ -207,3 +209,32 @@ func CopyFlagSet(fs *pflag.FlagSet) *pflag.FlagSet {
 	flags.AddFlagSet(fs)
 	return flags
 }
+
+// durationValue is a pflag.Value parsing durations with params.ParseDuration,
+// so that plain numbers are interpreted as seconds
+type durationValue time.Duration
+
+func (d *durationValue) Set(s string) error {
+	v, err := params.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = durationValue(v)
+	return nil
+}
+
+func (d *durationValue) Type() string {
+	return "duration"
+}
+
+func (d *durationValue) String() string {
+	return time.Duration(*d).String()
+}
+
+// DurationVarP defines a duration flag like pflag.DurationVarP but that also
+// accepts days ("1d") and plain numbers as seconds, for flags that used to take
+// a number of seconds
+func DurationVarP(fs *pflag.FlagSet, p *time.Duration, name, shorthand string, value time.Duration, usage string) {
+	*p = value
+	fs.VarP((*durationValue)(p), name, shorthand, usage)
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns/formatter/textcolumns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+)
+
+type ValidationCheck struct {
+	Node    string `column:"node"`
+	Check   string `column:"check"`
+	Result  string `column:"result"`
+	Message string `column:"message"`
+}
+
+var validationResults = map[api.ValidationResult]string{
+	api.ValidationResult_ValidationPassed:  "ok",
+	api.ValidationResult_ValidationWarning: "warning",
+	api.ValidationResult_ValidationFailed:  "failed",
+}
+
+// validateGadget checks whether the gadget would run on the nodes of the
+// runtime, without starting it, and prints the result
+func validateGadget(
+	w io.Writer,
+	rt runtime.Runtime,
+	gadgetCtx runtime.GadgetContext,
+	runtimeParams *params.Params,
+	paramValues api.ParamValues,
+) error {
+	validator, ok := rt.(runtime.GadgetValidator)
+	if !ok {
+		return fmt.Errorf("runtime doesn't support validating gadgets")
+	}
+	results, err := validator.ValidateGadget(gadgetCtx, runtimeParams, paramValues)
+	if err != nil && len(results) == 0 {
+		return fmt.Errorf("validating gadget: %w", err)
+	}
+	if err := printValidation(w, results); err != nil {
+		return err
+	}
+
+	// Report the nodes that couldn't be reached after printing the others
+	return err
+}
+
+// printValidation writes the checks of all nodes as a table and returns an
+// error naming the nodes where at least one check failed
+func printValidation(w io.Writer, results map[string]*api.ValidateGadgetResponse) error {
+	var checks []*ValidationCheck
+	var failedNodes []string
+	for _, node := range slices.Sorted(maps.Keys(results)) {
+		nodeFailed := false
+		for _, check := range results[node].GetChecks() {
+			checks = append(checks, &ValidationCheck{
+				Node:    node,
+				Check:   check.Name,
+				Result:  validationResults[check.Result],
+				Message: check.Message,
+			})
+			if check.Result == api.ValidationResult_ValidationFailed {
+				nodeFailed = true
+			}
+		}
+		if nodeFailed {
+			failedNodes = append(failedNodes, node)
+		}
+	}
+
+	cols := columns.MustCreateColumns[ValidationCheck]()
+	formatter := textcolumns.NewFormatter(cols.GetColumnMap())
+	formatter.WriteTable(w, checks)
+
+	if len(failedNodes) > 0 {
+		return fmt.Errorf("gadget can't run on %s", strings.Join(failedNodes, ", "))
+	}
+	return nil
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/environment"
 	grpcruntime "github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/grpc"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/experimental"
 )
 
@@ -105,14 +106,21 @@ func main() {
 	hiddenColumnTags := []string{"kubernetes"}
 
 	common.AddInstanceCommands(rootCmd, runtime)
+	rootCmd.AddCommand(common.NewReconcileCmd(runtime))
 	rootCmd.AddCommand(common.NewRunCommand(rootCmd, runtime, hiddenColumnTags, common.CommandModeRun))
 	rootCmd.AddCommand(common.NewRunCommand(rootCmd, runtime, hiddenColumnTags, common.CommandModeAttach))
 	rootCmd.AddCommand(common.NewConfigCmd(runtime, rootFlags))
+	rootCmd.AddCommand(common.NewNodeInfoCmd(runtime))
+	rootCmd.AddCommand(common.NewTopCmd(runtime))
+	rootCmd.AddCommand(common.NewDebugCmd(runtime))
 	rootCmd.AddCommand(image.NewImageCmd(runtime, imgCommands))
 
+	shutdownTracing := tracing.Init("gadgetctl")
 	if err := rootCmd.Execute(); err != nil {
+		shutdownTracing()
 		os.Exit(1)
 	}
+	shutdownTracing()
 }
 
 func init() {
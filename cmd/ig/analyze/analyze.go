@@ -0,0 +1,284 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	commonutils "github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/analyze"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/histogram"
+)
+
+func NewAnalyzeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "analyze",
+		Short: "Analyze events captured with the JSON output mode",
+		Long: `Analyze events captured with the JSON output mode, like "ig run trace_tcp -o json > capture.json".
+
+CAPTURE is a file, compressed with gzip if its name ends with .gz, or a directory like an extracted support bundle,
+in which all the .json and .jsonl files are read. No access to the hosts or clusters the events were captured on is
+needed.`,
+	}
+
+	cmd.AddCommand(
+		newTopCmd(),
+		newHistogramCmd(),
+		newFilterCmd(),
+	)
+	return cmd
+}
+
+// load reads the events of all the captures and keeps the ones matching
+// filter
+func load(captures []string, filter string) ([]analyze.Event, error) {
+	f, err := analyze.ParseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []analyze.Event
+	for _, capture := range captures {
+		captureEvents, err := analyze.Load(capture)
+		if err != nil {
+			return nil, fmt.Errorf("loading capture: %w", err)
+		}
+		events = append(events, analyze.Select(captureEvents, f)...)
+	}
+	return events, nil
+}
+
+func addFilterFlag(cmd *cobra.Command, filter *string) {
+	cmd.Flags().StringVarP(filter, "filter", "F", "", `Only analyze the events matching the filter, using the syntax of the --filter flag of the gadgets, like "proc.comm==curl,dst.port>=1024"`)
+}
+
+func newTopCmd() *cobra.Command {
+	var filter string
+	var keys []string
+	var sum string
+	var maxRows int
+	var outputMode string
+
+	cmd := &cobra.Command{
+		Use:   "top CAPTURE...",
+		Short: "Show the most frequent values of fields, like the top talkers",
+		Example: `  # Destinations with the most connections
+  ig analyze top capture.json --key dst.addr --key dst.port
+
+  # Processes sending the most bytes
+  ig analyze top capture.json --key proc.comm --sum size`,
+		SilenceUsage: true,
+		Args:         cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			events, err := load(args, filter)
+			if err != nil {
+				return err
+			}
+
+			entries := analyze.Top(events, keys, sum, maxRows)
+
+			switch outputMode {
+			case commonutils.OutputModeJSON:
+				type jsonEntry struct {
+					Key   map[string]string `json:"key"`
+					Count uint64            `json:"count"`
+					Sum   *float64          `json:"sum,omitempty"`
+				}
+				out := make([]jsonEntry, 0, len(entries))
+				for _, e := range entries {
+					je := jsonEntry{Key: map[string]string{}, Count: e.Count}
+					for i, k := range keys {
+						je.Key[k] = e.Key[i]
+					}
+					if sum != "" {
+						je.Sum = &e.Sum
+					}
+					out = append(out, je)
+				}
+				return printJSON(cmd.OutOrStdout(), out)
+			case commonutils.OutputModeColumns:
+				headers := append([]string{}, keys...)
+				headers = append(headers, "COUNT")
+				if sum != "" {
+					headers = append(headers, "SUM("+sum+")")
+				}
+				rows := make([][]string, 0, len(entries))
+				for _, e := range entries {
+					row := append([]string{}, e.Key...)
+					row = append(row, strconv.FormatUint(e.Count, 10))
+					if sum != "" {
+						row = append(row, strconv.FormatFloat(e.Sum, 'f', -1, 64))
+					}
+					rows = append(rows, row)
+				}
+				printTable(cmd.OutOrStdout(), headers, rows)
+				return nil
+			default:
+				return fmt.Errorf("invalid output mode %q", outputMode)
+			}
+		},
+	}
+
+	addFilterFlag(cmd, &filter)
+	cmd.Flags().StringSliceVarP(&keys, "key", "k", nil, "Fields whose values identify a group of events, like dst.addr")
+	cmd.Flags().StringVar(&sum, "sum", "", "Numeric field to sum for each group and sort the groups by, instead of the number of events")
+	cmd.Flags().IntVarP(&maxRows, "max-rows", "m", 20, "Maximum number of groups to show; 0 shows all of them")
+	cmd.Flags().StringVarP(&outputMode, "output", "o", commonutils.OutputModeColumns,
+		fmt.Sprintf("Output mode, one of: %s, %s", commonutils.OutputModeColumns, commonutils.OutputModeJSON))
+	cmd.MarkFlagRequired("key")
+
+	return cmd
+}
+
+func newHistogramCmd() *cobra.Command {
+	var filter string
+	var field string
+	var unit string
+
+	cmd := &cobra.Command{
+		Use:   "histogram CAPTURE...",
+		Short: "Show the distribution of the values of a numeric field",
+		Example: `  # Distribution of the latencies of the slow file operations
+  ig analyze histogram capture.json --field latency_raw --unit ns`,
+		SilenceUsage: true,
+		Args:         cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			events, err := load(args, filter)
+			if err != nil {
+				return err
+			}
+
+			h, skipped := analyze.Histogram(events, field)
+			if skipped > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Skipped %d events without a positive number in %q\n", skipped, field)
+			}
+			h.Unit = histogram.Unit(unit)
+			fmt.Fprint(cmd.OutOrStdout(), h.String())
+			return nil
+		},
+	}
+
+	addFilterFlag(cmd, &filter)
+	cmd.Flags().StringVar(&field, "field", "", "Numeric field to show the distribution of")
+	cmd.Flags().StringVar(&unit, "unit", "", "Unit of the values, shown in the header")
+	cmd.MarkFlagRequired("field")
+
+	return cmd
+}
+
+func newFilterCmd() *cobra.Command {
+	var filter string
+	var fields []string
+	var outputMode string
+	var outputFile string
+
+	cmd := &cobra.Command{
+		Use:     "filter CAPTURE...",
+		Aliases: []string{"export"},
+		Short:   "Export the events matching a filter",
+		Example: `  # Keep the connections of curl with only some fields
+  ig analyze filter capture.json -F proc.comm==curl --fields timestamp,dst.addr,dst.port -O curl.json`,
+		SilenceUsage: true,
+		Args:         cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			events, err := load(args, filter)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if outputFile != "" {
+				f, err := os.Create(outputFile)
+				if err != nil {
+					return fmt.Errorf("creating output file: %w", err)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			for _, e := range events {
+				var b []byte
+				switch outputMode {
+				case commonutils.OutputModeJSON:
+					b, err = json.Marshal(analyze.Project(e, fields))
+				case commonutils.OutputModeJSONPretty:
+					b, err = json.MarshalIndent(analyze.Project(e, fields), "", "  ")
+				default:
+					return fmt.Errorf("invalid output mode %q", outputMode)
+				}
+				if err != nil {
+					return fmt.Errorf("marshalling event: %w", err)
+				}
+				if _, err := fmt.Fprintln(out, string(b)); err != nil {
+					return fmt.Errorf("writing event: %w", err)
+				}
+			}
+			return nil
+		},
+	}
+
+	addFilterFlag(cmd, &filter)
+	cmd.Flags().StringSliceVar(&fields, "fields", nil, "Fields to keep in the events; all of them if empty")
+	cmd.Flags().StringVarP(&outputMode, "output", "o", commonutils.OutputModeJSON,
+		fmt.Sprintf("Output mode, one of: %s, %s", commonutils.OutputModeJSON, commonutils.OutputModeJSONPretty))
+	cmd.Flags().StringVarP(&outputFile, "output-file", "O", "", "File to write the events to instead of the standard output")
+
+	return cmd
+}
+
+func printJSON(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshalling to JSON: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func printTable(w io.Writer, headers []string, rows [][]string) {
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = len(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	printRow := func(row []string) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, " "), " "))
+	}
+
+	upper := make([]string, len(headers))
+	for i, h := range headers {
+		upper[i] = strings.ToUpper(h)
+	}
+	printRow(upper)
+	for _, row := range rows {
+		printRow(row)
+	}
+}
@@ -15,13 +15,17 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -30,10 +34,20 @@ import (
 
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/common"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/debugshell"
 	gadgetservice "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/audit"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
 	instancemanager "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/instance-manager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/quota"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/resultcache"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store/etcd-store"
 	filestore "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store/file-store"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/integrity"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
 	gadgettls "github.com/inspektor-gadget/inspektor-gadget/pkg/utils/tls"
 )
@@ -52,6 +66,18 @@ func newDaemonCommand(runtime runtime.Runtime) *cobra.Command {
 	var serverKey string
 	var serverCert string
 	var clientCA string
+	var handoffFile string
+	var diskBuffer instancemanager.DiskBufferConfig
+	var checksumKeyFile string
+	var policyFile string
+	var auditLogFile string
+	var limits quota.Limits
+	var resourceLimits operators.ResourceLimits
+	var debugShell gadgetservice.DebugShellConfig
+	var gateway gadgetservice.GatewayConfig
+	var resultCacheTTL time.Duration
+	var metricsAddress string
+	var shutdownTimeout time.Duration
 
 	daemonCmd.PersistentFlags().StringVarP(
 		&group,
@@ -93,6 +119,164 @@ func newDaemonCommand(runtime runtime.Runtime) *cobra.Command {
 		"",
 		"Path to CA certificate for client validation")
 
+	daemonCmd.PersistentFlags().StringVar(
+		&checksumKeyFile,
+		"checksum-key-file",
+		"",
+		"Path to a key shared with the clients to sign the checksums of event batches")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&policyFile,
+		"authorization-policy-file",
+		"",
+		"Path to a policy file granting gadgets and operations to the clients, identified by bearer tokens or "+
+			"client certificates; all requests are allowed if not set")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&auditLogFile,
+		"audit-log-file",
+		"",
+		"Path to a file to append a JSON line to for each gadget run, attached to, created, changed or removed, "+
+			"holding the client, image, params and nodes")
+
+	daemonCmd.PersistentFlags().IntVar(
+		&limits.MaxRunningGadgets,
+		"quota-max-running-gadgets",
+		0,
+		"Maximum number of gadgets running at once, including gadget instances that are not paused; 0 for no limit")
+
+	daemonCmd.PersistentFlags().IntVar(
+		&limits.MaxRunningGadgetsPerClient,
+		"quota-max-running-gadgets-per-client",
+		0,
+		"Maximum number of gadgets each client runs at once; 0 for no limit")
+
+	daemonCmd.PersistentFlags().IntVar(
+		&limits.MaxInstancesPerNamespace,
+		"quota-max-instances-per-namespace",
+		0,
+		"Maximum number of gadget instances for the same namespace; 0 for no limit")
+
+	daemonCmd.PersistentFlags().IntVar(
+		&limits.MaxInstancesPerTag,
+		"quota-max-instances-per-tag",
+		0,
+		"Maximum number of gadget instances with the same tag; 0 for no limit")
+
+	daemonCmd.PersistentFlags().Float64Var(
+		&limits.RequestsPerSecond,
+		"quota-requests-per-second",
+		0,
+		"Maximum rate of requests of all clients together; 0 for no limit")
+
+	daemonCmd.PersistentFlags().IntVar(
+		&limits.RequestBurst,
+		"quota-request-burst",
+		0,
+		"Number of requests of all clients together allowed at once above --quota-requests-per-second")
+
+	daemonCmd.PersistentFlags().Float64Var(
+		&limits.ClientRequestsPerSecond,
+		"quota-client-requests-per-second",
+		0,
+		"Maximum rate of requests of each client; 0 for no limit")
+
+	daemonCmd.PersistentFlags().IntVar(
+		&limits.ClientRequestBurst,
+		"quota-client-request-burst",
+		0,
+		"Number of requests of each client allowed at once above --quota-client-requests-per-second")
+
+	daemonCmd.PersistentFlags().IntVar(
+		&resourceLimits.MaxPrograms,
+		"quota-gadget-max-programs",
+		0,
+		"Maximum number of eBPF programs of each gadget; 0 for no limit")
+
+	daemonCmd.PersistentFlags().Uint64Var(
+		&resourceLimits.MaxMapMemory,
+		"quota-gadget-max-map-memory",
+		0,
+		"Maximum memory in bytes of the eBPF maps of each gadget, ring buffers apart; 0 for no limit")
+
+	daemonCmd.PersistentFlags().Uint64Var(
+		&resourceLimits.MaxBufferMemory,
+		"quota-gadget-max-buffer-memory",
+		0,
+		"Maximum memory in bytes of the ring buffers and perf buffers of each gadget; 0 for no limit")
+
+	daemonCmd.PersistentFlags().BoolVar(
+		&debugShell.Enabled,
+		"enable-debug-shell",
+		false,
+		"Allow clients to open audited debug shells in containers")
+
+	daemonCmd.PersistentFlags().DurationVar(
+		&debugShell.MaxDuration,
+		"debug-shell-max-duration",
+		debugshell.DefaultMaxDuration,
+		"Maximum duration of debug shells")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&gateway.Address,
+		"gateway-address",
+		"",
+		"Address (ip:port) to serve the HTTP gateway at, which allows running gadgets with JSON requests and "+
+			"receiving their events as server-sent events or over WebSockets. Disabled if empty")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&gateway.AdvertiseAddress,
+		"gateway-advertise-address",
+		"",
+		"Address (host:port) at which the other replicas sharing the etcd store reach the HTTP gateway, to forward "+
+			"attach requests for gadget instances they don't run")
+
+	daemonCmd.PersistentFlags().DurationVar(
+		&resultCacheTTL,
+		"result-cache-ttl",
+		0,
+		"Time to cache the events of one-shot gadgets like snapshot_process for, so that clients requesting them "+
+			"with the same parameters don't run them again. Clients can bypass the cache with --no-cache. 0 = disabled")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&metricsAddress,
+		"metrics-address",
+		"",
+		"Address (ip:port) to serve the metrics of the daemon itself at /metrics in the Prometheus format, like "+
+			"running gadget instances, dropped events and the memory of eBPF maps. Disabled if empty")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&handoffFile,
+		"handoff-file",
+		filepath.Join(filestore.GadgetBaseDir, "handoff.json"),
+		"File to write the state of the running gadget instances to on shutdown and to restore it from on start. "+
+			"Set to empty to disable")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&diskBuffer.Dir,
+		"event-buffer-dir",
+		"",
+		"Directory to store the buffered events of the gadget instances in, so they can still be replayed when "+
+			"attaching after a restart. Set to empty to disable")
+
+	daemonCmd.PersistentFlags().Int64Var(
+		&diskBuffer.MaxBytes,
+		"event-buffer-max-bytes",
+		instancemanager.DefaultDiskBufferMaxBytes,
+		"Maximum size of the events stored on disk for each gadget instance; used with --event-buffer-dir")
+
+	daemonCmd.PersistentFlags().DurationVar(
+		&diskBuffer.MaxAge,
+		"event-buffer-max-age",
+		instancemanager.DefaultDiskBufferMaxAge,
+		"Maximum age of the events stored on disk, 0 = no limit; used with --event-buffer-dir")
+
+	daemonCmd.PersistentFlags().DurationVar(
+		&shutdownTimeout,
+		"shutdown-timeout",
+		10*time.Second,
+		"Time to wait for the gadget instances to stop on shutdown")
+
 	service := gadgetservice.NewService(log.StandardLogger())
 
 	for _, params := range service.GetOperatorMap() {
@@ -124,6 +308,53 @@ func newDaemonCommand(runtime runtime.Runtime) *cobra.Command {
 		log.Infof("starting Inspektor Gadget daemon at %q", socket)
 		service.SetEventBufferLength(eventBufferLength)
 
+		if checksumKeyFile != "" {
+			key, err := integrity.LoadKey(checksumKeyFile)
+			if err != nil {
+				return err
+			}
+			service.SetChecksumKey(key)
+		}
+
+		service.SetDebugShellConfig(debugShell)
+
+		if policyFile != "" {
+			policy, err := authz.LoadPolicyFile(policyFile)
+			if err != nil {
+				return err
+			}
+			var reviewer authz.TokenReviewer
+			if policy.TokenReview {
+				clientset, err := k8sutil.NewClientset("", "ig-daemon/authz")
+				if err != nil {
+					return fmt.Errorf("creating kubernetes client to review tokens: %w", err)
+				}
+				reviewer = authz.NewK8sTokenReviewer(clientset)
+			}
+			service.SetAuthorizer(authz.New(policy, reviewer))
+			log.Infof("authorizing requests using %q", policyFile)
+		}
+
+		if auditLogFile != "" {
+			auditor, err := audit.NewFileAuditor(auditLogFile)
+			if err != nil {
+				return err
+			}
+			defer auditor.Close()
+			service.SetAuditor(auditor)
+			log.Infof("recording audit log to %q", auditLogFile)
+		}
+
+		if resultCacheTTL > 0 {
+			service.SetResultCache(resultcache.New(resultCacheTTL, resultcache.DefaultMaxEntries))
+		}
+
+		service.SetMetricsAddress(metricsAddress)
+
+		if limits.Enabled() {
+			service.SetQuotaLimiter(quota.New(limits))
+		}
+
 		if err = config.Config.ReadInConfig(); err != nil {
 			log.Warnf("reading config: %v", err)
 		}
@@ -148,48 +379,103 @@ All these options should be set at the same time to enable TLS connection`,
 		var options []grpc.ServerOption
 
 		if tlsOptionsSet == 3 {
-			cert, err := gadgettls.LoadTLSCert(serverCert, serverKey)
+			tlsConfig, err := gadgettls.NewServerConfig(serverCert, serverKey, clientCA)
 			if err != nil {
-				return fmt.Errorf("creating TLS certificate: %w", err)
-			}
-
-			ca, err := gadgettls.LoadTLSCA(clientCA)
-			if err != nil {
-				return fmt.Errorf("creating TLS certificate authority: %w", err)
-			}
-
-			tlsConfig := &tls.Config{
-				ClientAuth:   tls.RequireAndVerifyClientCert,
-				Certificates: []tls.Certificate{cert},
-				ClientCAs:    ca,
+				return err
 			}
 
 			options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+			gateway.TLSConfig = tlsConfig
 
 			log.Debugf("TLS is enabled using %v, %v and %v", serverKey, serverCert, clientCA)
 		} else if !strings.HasPrefix(socketPath, "unix") {
 			log.Warnf("no TLS configuration provided, communication between daemon and CLI will not be encrypted")
 		}
 
-		mgr, err := instancemanager.New(runtime)
+		if gateway.Address != "" {
+			if gateway.TLSConfig == nil {
+				return errors.New("the HTTP gateway requires the --tls-* options to be set")
+			}
+			if policyFile == "" {
+				return errors.New("the HTTP gateway requires --authorization-policy-file to be set")
+			}
+			service.SetGatewayConfig(gateway)
+		}
+
+		if resourceLimits.Enabled() {
+			service.SetResourceLimits(resourceLimits)
+		}
+
+		mgr, err := instancemanager.New(runtime,
+			instancemanager.WithDiskBuffer(diskBuffer),
+			instancemanager.WithResourceLimits(resourceLimits),
+		)
 		if err != nil {
 			return fmt.Errorf("initializing manager: %w", err)
 		}
 
-		store, err := filestore.New(mgr)
+		store, err := store.New(mgr, config.Config, filestore.Name)
 		if err != nil {
 			return fmt.Errorf("initializing store: %w", err)
 		}
 
+		if handoffFile != "" {
+			restoreHandoff(mgr, handoffFile)
+		}
+
 		service.SetStore(store)
 		service.SetInstanceManager(mgr)
 
-		return service.Run(gadgetservice.RunConfig{
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			service.Close()
+		}()
+
+		err = service.Run(gadgetservice.RunConfig{
 			SocketType: socketType,
 			SocketPath: socketPath,
 			SocketGID:  gid,
 		}, options...)
+		if ctx.Err() == nil {
+			return err
+		}
+
+		log.Infof("shutting down Inspektor Gadget daemon")
+		if handoffFile != "" {
+			h := mgr.Handoff()
+			if err := instancemanager.WriteHandoffFile(handoffFile, h); err != nil {
+				log.Warnf("writing handoff file: %v", err)
+			} else {
+				log.Infof("handed off %d gadget instances to %q", len(h.Instances), handoffFile)
+			}
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := mgr.Shutdown(shutdownCtx); err != nil {
+			log.Warnf("stopping gadget instances: %v", err)
+		}
+		return nil
 	}
 
 	return daemonCmd
 }
+
+// restoreHandoff makes the gadget instances pick up the state handed off by
+// the previous daemon. The file is removed afterwards, so a stale state isn't
+// restored again after a crash.
+func restoreHandoff(mgr *instancemanager.Manager, handoffFile string) {
+	h, err := instancemanager.ReadHandoffFile(handoffFile)
+	if err != nil {
+		log.Warnf("reading handoff file: %v", err)
+	}
+	if h != nil {
+		log.Infof("restoring %d gadget instances handed off at %s", len(h.Instances), h.CreatedAt.Format(time.RFC3339))
+		mgr.SetHandoff(h)
+	}
+	if err := os.Remove(handoffFile); err != nil && !os.IsNotExist(err) {
+		log.Warnf("removing handoff file: %v", err)
+	}
+}
@@ -32,21 +32,38 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/common"
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/image"
 	commonutils "github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/ig/analyze"
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/ig/containers"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/local"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/experimental"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
 
 	// Another blank import for the used operator
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/aggregate"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ancestry"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/btfgen"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/dedup"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/env"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/filter"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/formatters"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/geoip"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/identity"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/join"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/localmanager"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/privacy"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/privileges"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/process"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/quota"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/redact"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/reversedns"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/routing"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/sample"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/socketenricher"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/sort"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/systemd"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/transform"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/uidgidresolver"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ustack"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/wasm"
@@ -72,6 +89,7 @@ func main() {
 
 	rootCmd.AddCommand(
 		containers.NewListContainersCmd(),
+		analyze.NewAnalyzeCmd(),
 		common.NewVersionCmd(),
 	)
 
@@ -109,6 +127,8 @@ func main() {
 	rootCmd.AddCommand(common.NewLogoutCmd())
 	rootCmd.AddCommand(common.NewRunCommand(rootCmd, runtime, hiddenColumnTags, common.CommandModeRun))
 	rootCmd.AddCommand(common.NewConfigCmd(runtime, rootFlags))
+	rootCmd.AddCommand(common.NewNodeInfoCmd(runtime))
+	rootCmd.AddCommand(newSelfTestCmd(runtime))
 
 	pprofAddr, _ := rootCmd.PersistentFlags().GetString("pprof-addr")
 	if pprofAddr != "" {
@@ -118,7 +138,10 @@ func main() {
 			}
 		}()
 	}
+	shutdownTracing := tracing.Init("ig")
 	if err := rootCmd.Execute(); err != nil {
+		shutdownTracing()
 		os.Exit(1)
 	}
+	shutdownTracing()
 }
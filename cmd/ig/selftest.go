@@ -0,0 +1,66 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/common"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+)
+
+func newSelfTestCmd(rt runtime.Runtime) *cobra.Command {
+	return common.NewSelfTestCmd(rt, common.SelfTestActivity{
+		EnrichmentFields: []string{"proc.comm", "proc.pid", "timestamp_raw"},
+		Generate:         generateLocalActivity,
+	})
+}
+
+// generateLocalActivity opens a file named after the marker on the host until
+// ctx is done, so events are generated even if the first ones are missed while
+// the gadget is attaching. The file doesn't exist: the gadget also traces
+// failed opens.
+func generateLocalActivity(ctx context.Context, marker string, _ *params.Params) (func(), error) {
+	path := filepath.Join(os.TempDir(), marker)
+	run := func() {
+		if f, err := os.Open(path); err == nil {
+			f.Close()
+		}
+	}
+	run()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				run()
+			}
+		}
+	}()
+	return func() { close(done) }, nil
+}
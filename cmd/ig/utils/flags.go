@@ -17,6 +17,7 @@ package utils
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/containerd/containerd/pkg/cri/constants"
 	securejoin "github.com/cyphar/filepath-securejoin"
@@ -59,8 +60,8 @@ type CommonFlags struct {
 	// with their specific socket path.
 	RuntimeConfigs []*containerutilsTypes.RuntimeConfig
 
-	// Number of seconds that the gadget will run for
-	Timeout int
+	// Time that the gadget will run for
+	Timeout time.Duration
 
 	// ContainerdNamespace is the namespace used by containerd
 	ContainerdNamespace string
@@ -174,11 +175,13 @@ func AddCommonFlags(command *cobra.Command, commonFlags *CommonFlags) {
 			strings.Join(containerutils.AvailableRuntimes, ", ")),
 	)
 
-	command.PersistentFlags().IntVar(
+	commonutils.DurationVarP(
+		command.PersistentFlags(),
 		&commonFlags.Timeout,
 		"timeout",
+		"",
 		0,
-		"Number of seconds that the gadget will run for",
+		"Time the gadget will run for, like 30s or 5m; plain numbers are seconds",
 	)
 
 	command.PersistentFlags().StringVar(
@@ -26,7 +26,7 @@ import (
 func WaitForEnd(f *CommonFlags) {
 	timeoutChannel := make(<-chan time.Time)
 	if f.Timeout != 0 {
-		timeoutChannel = time.After(time.Duration(f.Timeout) * time.Second)
+		timeoutChannel = time.After(f.Timeout)
 	}
 
 	stop := make(chan os.Signal, 1)
@@ -32,6 +32,7 @@ import (
 	igconfig "github.com/inspektor-gadget/inspektor-gadget/pkg/config"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
 	grpcruntime "github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/grpc"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/experimental"
 )
 
@@ -106,6 +107,7 @@ func main() {
 		log.Fatalf("Creating RESTConfig: %s", err)
 	}
 	grpcRuntime.SetRestConfig(config)
+	grpcRuntime.SetRestConfigLoader(utils.RestConfigForContext)
 
 	namespace, _ := utils.GetNamespace()
 	grpcRuntime.SetDefaultValue(gadgets.K8SNamespace, namespace)
@@ -159,9 +161,16 @@ func main() {
 	rootCmd.AddCommand(common.NewRunCommand(rootCmd, grpcRuntime, hiddenColumnTags, common.CommandModeRun))
 	rootCmd.AddCommand(common.NewRunCommand(rootCmd, grpcRuntime, hiddenColumnTags, common.CommandModeAttach))
 	rootCmd.AddCommand(common.NewConfigCmd(grpcRuntime, rootFlags))
+	rootCmd.AddCommand(common.NewNodeInfoCmd(grpcRuntime))
+	rootCmd.AddCommand(common.NewTopCmd(grpcRuntime))
+	rootCmd.AddCommand(common.NewDebugCmd(grpcRuntime))
+	rootCmd.AddCommand(newSelfTestCmd(grpcRuntime))
 	rootCmd.AddCommand(img.NewImageCmd(grpcRuntime, imgCommands))
 
+	shutdownTracing := tracing.Init("kubectl-gadget")
 	if err := rootCmd.Execute(); err != nil {
+		shutdownTracing()
 		os.Exit(1)
 	}
+	shutdownTracing()
 }
@@ -0,0 +1,103 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/common"
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
+	paramsPkg "github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	grpcruntime "github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/grpc"
+)
+
+func newSelfTestCmd(rt *grpcruntime.Runtime) *cobra.Command {
+	var podImage string
+
+	cmd := common.NewSelfTestCmd(rt, common.SelfTestActivity{
+		EnrichmentFields: []string{"proc.comm", "proc.pid", "timestamp_raw", "k8s.node", "k8s.namespace", "k8s.podName"},
+		AddFlags: func(cmd *cobra.Command) {
+			cmd.Flags().StringVar(&podImage, "pod-image", "busybox", "Image of the pod generating the activity on the node")
+		},
+		Validate: func(runtimeParams *paramsPkg.Params) error {
+			if len(runtimeParams.Get(grpcruntime.ParamNode).AsStringSlice()) != 1 {
+				return errors.New("exactly one node needs to be set with --node")
+			}
+			return nil
+		},
+		Generate: func(ctx context.Context, marker string, runtimeParams *paramsPkg.Params) (func(), error) {
+			node := runtimeParams.Get(grpcruntime.ParamNode).AsStringSlice()[0]
+			return generatePodActivity(ctx, node, podImage, marker)
+		},
+	})
+	cmd.Example = `  # Verify that events of a node are received and enriched
+  kubectl gadget selftest --node worker-1`
+	return cmd
+}
+
+// generatePodActivity creates a pod on the node opening a file named after the
+// marker in a loop. The pod is deleted by the returned function.
+func generatePodActivity(ctx context.Context, node, image, marker string) (func(), error) {
+	client, err := k8sutil.NewClientsetFromConfigFlags(utils.KubernetesConfigFlags)
+	if err != nil {
+		return nil, fmt.Errorf("creating k8s client: %w", err)
+	}
+	namespace, _ := utils.GetNamespace()
+
+	gracePeriod := int64(0)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   marker,
+			Labels: map[string]string{"app.kubernetes.io/name": "gadget-selftest"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:                      node,
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			TerminationGracePeriodSeconds: &gracePeriod,
+			Tolerations:                   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			Containers: []corev1.Container{
+				{
+					Name:    "selftest",
+					Image:   image,
+					Command: []string{"/bin/sh", "-c", `while true; do cat "/tmp/$0" 2>/dev/null; sleep 0.2; done`, marker},
+				},
+			},
+		},
+	}
+
+	pod, err = client.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("creating pod in namespace %q: %w", namespace, err)
+	}
+
+	return func() {
+		// ctx may be done already
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := client.CoreV1().Pods(namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+		if err != nil {
+			log.Warnf("deleting pod %s/%s: %v", namespace, pod.Name, err)
+		}
+	}, nil
+}
@@ -18,10 +18,12 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
 
 	commonutils "github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
@@ -33,6 +35,15 @@ func FlagInit(rootCmd *cobra.Command) {
 	KubernetesConfigFlags.AddFlags(rootCmd.PersistentFlags())
 }
 
+// RestConfigForContext returns the REST config of the given kubeconfig
+// context, loaded from the same kubeconfig as KubernetesConfigFlags
+func RestConfigForContext(kubeContext string) (*rest.Config, error) {
+	flags := genericclioptions.NewConfigFlags(false)
+	flags.KubeConfig = KubernetesConfigFlags.KubeConfig
+	flags.Context = &kubeContext
+	return flags.ToRESTConfig()
+}
+
 // CommonFlags contains CLI flags common to several gadgets
 type CommonFlags struct {
 	// OutputConfig describes the way output should be printed
@@ -67,8 +78,8 @@ type CommonFlags struct {
 	// Containername allows to filter containers by name
 	Containername string
 
-	// Number of seconds that the gadget will run for
-	Timeout int
+	// Time that the gadget will run for
+	Timeout time.Duration
 }
 
 // GetNamespace returns the namespace specified by '-n' or the default
@@ -180,10 +191,12 @@ func AddCommonFlags(command *cobra.Command, params *CommonFlags, gadgetNamespace
 		"Show data from pods in all namespaces",
 	)
 
-	command.PersistentFlags().IntVar(
+	commonutils.DurationVarP(
+		command.PersistentFlags(),
 		&params.Timeout,
 		"timeout",
+		"",
 		0,
-		"Number of seconds that the gadget will run for",
+		"Time the gadget will run for, like 30s or 5m; plain numbers are seconds",
 	)
 }
@@ -28,40 +28,72 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/inspektor-gadget/inspektor-gadget/internal/version"
 	// Import this early to set the environment variable before any other package is imported
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/environment/k8s"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/audit"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
 	instancemanager "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/instance-manager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/quota"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/resultcache"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store/etcd-store"
 	k8sconfigmapstore "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store/k8s-configmap-store"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/local"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/config/gadgettracermanagerconfig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/integrity"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
 	ocihandler "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/oci-handler"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/experimental"
+	gadgettls "github.com/inspektor-gadget/inspektor-gadget/pkg/utils/tls"
 
 	// import for gadgettracermanager entrypoint"
 	"github.com/inspektor-gadget/inspektor-gadget/gadget-container/entrypoint"
 	// Blank import for some operators
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/aggregate"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ancestry"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/btfgen"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/clickhouse"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/dedup"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/env"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/file"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/filter"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/formatters"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/geoip"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/identity"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/join"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kafka"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kubeipresolver"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kubemanager"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kubenameresolver"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/limiter"
-	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/otel-logs"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/loki"
+	otellogs "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/otel-logs"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/otel-metrics"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/privacy"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/privileges"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/process"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/quota"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/redact"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/reversedns"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/routing"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/sample"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/socketenricher"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/sort"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/syslog"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/systemd"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/transform"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/uidgidresolver"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ustack"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/wasm"
@@ -73,6 +105,7 @@ import (
 	gadgetservice "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 	kubemanagertypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kubemanager/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
 )
 
@@ -155,6 +188,8 @@ func main() {
 		log.Infof("Inspektor Gadget version: %s", version.Version().String())
 		log.Infof("Inspektor Gadget User Agent: %s", version.UserAgent())
 
+		shutdownTracing := tracing.Init("gadgettracermanager")
+
 		logLevel, err := log.ParseLevel(config.Config.GetString(gadgettracermanagerconfig.DaemonLogLevel))
 		if err != nil {
 			log.Fatalf("Parsing log level %q: %v", logLevel, err)
@@ -205,7 +240,51 @@ func main() {
 		service := gadgetservice.NewService(log.StandardLogger())
 		service.SetEventBufferLength(bufferLength)
 
-		mgr, err := instancemanager.New(local.New())
+		if keyFile := config.Config.GetString(gadgettracermanagerconfig.ChecksumKeyFile); keyFile != "" {
+			key, err := integrity.LoadKey(keyFile)
+			if err != nil {
+				log.Fatalf("Loading checksum key: %v", err)
+			}
+			service.SetChecksumKey(key)
+		}
+
+		debugShell := gadgetservice.DebugShellConfig{
+			Enabled:     config.Config.GetBool(gadgettracermanagerconfig.DebugShellEnabled),
+			MaxDuration: config.Config.GetDuration(gadgettracermanagerconfig.DebugShellMaxDuration),
+		}
+		if exporter := config.Config.GetString(gadgettracermanagerconfig.DebugShellAuditExporter); exporter != "" {
+			debugShell.AuditParamValues = api.ParamValues{
+				"operator.otel-logs." + otellogs.ParamOtelLogsExporter: exporter,
+			}
+		}
+		service.SetDebugShellConfig(debugShell)
+
+		diskBuffer := instancemanager.DiskBufferConfig{
+			Dir:      config.Config.GetString(gadgettracermanagerconfig.EventBufferDir),
+			MaxBytes: config.Config.GetInt64(gadgettracermanagerconfig.EventBufferMaxBytes),
+			MaxAge:   instancemanager.DefaultDiskBufferMaxAge,
+		}
+		if config.Config.IsSet(gadgettracermanagerconfig.EventBufferMaxAge) {
+			diskBuffer.MaxAge = config.Config.GetDuration(gadgettracermanagerconfig.EventBufferMaxAge)
+		}
+		if diskBuffer.Dir != "" {
+			log.Infof("Config: storing buffered events in %q", diskBuffer.Dir)
+		}
+
+		resourceLimits := operators.ResourceLimits{
+			MaxPrograms:     config.Config.GetInt(gadgettracermanagerconfig.QuotaGadgetMaxPrograms),
+			MaxMapMemory:    config.Config.GetUint64(gadgettracermanagerconfig.QuotaGadgetMaxMapMemory),
+			MaxBufferMemory: config.Config.GetUint64(gadgettracermanagerconfig.QuotaGadgetMaxBufferMemory),
+		}
+		if resourceLimits.Enabled() {
+			log.Infof("Config: eBPF resource limits of gadgets: %+v", resourceLimits)
+			service.SetResourceLimits(resourceLimits)
+		}
+
+		mgr, err := instancemanager.New(local.New(),
+			instancemanager.WithDiskBuffer(diskBuffer),
+			instancemanager.WithResourceLimits(resourceLimits),
+		)
 		if err != nil {
 			log.Fatalf("initializing manager: %v", err)
 		}
@@ -216,7 +295,7 @@ func main() {
 			log.Fatalf("gadget namespace must not be empty")
 		}
 
-		store, err := k8sconfigmapstore.New(mgr, gadgetNs)
+		store, err := store.New(mgr, config.Config, k8sconfigmapstore.Name)
 		if err != nil {
 			log.Fatalf("initializing store: %v", err)
 		}
@@ -224,15 +303,69 @@ func main() {
 		service.SetStore(store)
 		service.SetInstanceManager(mgr)
 
+		authorizer, err := newAuthorizer(gadgetNs)
+		if err != nil {
+			log.Fatalf("configuring authorization: %v", err)
+		}
+		if authorizer != nil {
+			service.SetAuthorizer(authorizer)
+		}
+
+		auditor, err := newAuditor(gadgetNs)
+		if err != nil {
+			log.Fatalf("configuring audit log: %v", err)
+		}
+		if auditor != nil {
+			service.SetAuditor(auditor)
+		}
+
+		limits := quota.Limits{
+			MaxRunningGadgets:          config.Config.GetInt(gadgettracermanagerconfig.QuotaMaxRunningGadgets),
+			MaxRunningGadgetsPerClient: config.Config.GetInt(gadgettracermanagerconfig.QuotaMaxRunningGadgetsPerClient),
+			MaxInstancesPerNamespace:   config.Config.GetInt(gadgettracermanagerconfig.QuotaMaxInstancesPerNamespace),
+			MaxInstancesPerTag:         config.Config.GetInt(gadgettracermanagerconfig.QuotaMaxInstancesPerTag),
+			RequestsPerSecond:          config.Config.GetFloat64(gadgettracermanagerconfig.QuotaRequestsPerSecond),
+			RequestBurst:               config.Config.GetInt(gadgettracermanagerconfig.QuotaRequestBurst),
+			ClientRequestsPerSecond:    config.Config.GetFloat64(gadgettracermanagerconfig.QuotaClientRequestsPerSecond),
+			ClientRequestBurst:         config.Config.GetInt(gadgettracermanagerconfig.QuotaClientRequestBurst),
+		}
+		if limits.Enabled() {
+			log.Infof("Config: quotas: %+v", limits)
+			service.SetQuotaLimiter(quota.New(limits))
+		}
+
+		if ttl := config.Config.GetDuration(gadgettracermanagerconfig.ResultCacheTTL); ttl > 0 {
+			log.Infof("Config: caching the events of one-shot gadgets for %s", ttl)
+			service.SetResultCache(resultcache.New(ttl, resultcache.DefaultMaxEntries))
+		}
+
+		if address := config.Config.GetString(gadgettracermanagerconfig.MetricsAddress); address != "" {
+			log.Infof("Config: serving daemon metrics at %q", address)
+			service.SetMetricsAddress(address)
+		}
+
 		socketType, socketPath, err := api.ParseSocketAddress(gadgetServiceHost)
 		if err != nil {
 			log.Fatalf("invalid service host: %v", err)
 		}
+		var serverOptions []grpc.ServerOption
+		tlsKey := config.Config.GetString(gadgettracermanagerconfig.TLSKeyFile)
+		tlsCert := config.Config.GetString(gadgettracermanagerconfig.TLSCertFile)
+		tlsClientCA := config.Config.GetString(gadgettracermanagerconfig.TLSClientCAFile)
+		if tlsKey != "" || tlsCert != "" || tlsClientCA != "" {
+			tlsConfig, err := gadgettls.NewServerConfig(tlsCert, tlsKey, tlsClientCA)
+			if err != nil {
+				log.Fatalf("configuring TLS: %v", err)
+			}
+			serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(tlsConfig)))
+			log.Infof("Config: TLS is enabled using %v, %v and %v", tlsKey, tlsCert, tlsClientCA)
+		}
+
 		go func() {
 			err := service.Run(gadgetservice.RunConfig{
 				SocketType: socketType,
 				SocketPath: socketPath,
-			})
+			}, serverOptions...)
 			if err != nil {
 				log.Fatalf("starting gadget service: %v", err)
 			}
@@ -243,5 +376,81 @@ func main() {
 		<-exitSignal
 
 		service.Close()
+		shutdownTracing()
+	}
+}
+
+// authorizationPolicyKey is the key of the policy in the ConfigMap given by
+// AuthorizationPolicyConfigMap
+const authorizationPolicyKey = "policy.yaml"
+
+// newAuthorizer returns the authorizer using the policy found in the configured
+// file or ConfigMap of the gadget namespace, or nil if none is configured
+func newAuthorizer(gadgetNs string) (*authz.Authorizer, error) {
+	policyFile := config.Config.GetString(gadgettracermanagerconfig.AuthorizationPolicyFile)
+	policyConfigMap := config.Config.GetString(gadgettracermanagerconfig.AuthorizationPolicyConfigMap)
+	if policyFile == "" && policyConfigMap == "" {
+		return nil, nil
+	}
+	if policyFile != "" && policyConfigMap != "" {
+		return nil, fmt.Errorf("%s and %s can't be used together",
+			gadgettracermanagerconfig.AuthorizationPolicyFile, gadgettracermanagerconfig.AuthorizationPolicyConfigMap)
+	}
+
+	clientset, err := k8sutil.NewClientset("", "gadgettracermanager/authz")
+	if err != nil {
+		return nil, fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	var policy *authz.Policy
+	if policyFile != "" {
+		log.Infof("Config: authorizing requests using policy file %q", policyFile)
+		policy, err = authz.LoadPolicyFile(policyFile)
+	} else {
+		log.Infof("Config: authorizing requests using policy from ConfigMap %s/%s", gadgetNs, policyConfigMap)
+		var cm *corev1.ConfigMap
+		cm, err = clientset.CoreV1().ConfigMaps(gadgetNs).Get(context.Background(), policyConfigMap, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("getting policy ConfigMap: %w", err)
+		}
+		data, ok := cm.Data[authorizationPolicyKey]
+		if !ok {
+			return nil, fmt.Errorf("ConfigMap %q has no %q key", policyConfigMap, authorizationPolicyKey)
+		}
+		policy, err = authz.ParsePolicy([]byte(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return authz.New(policy, authz.NewK8sTokenReviewer(clientset)), nil
+}
+
+// newAuditor returns the auditor recording the requests to the configured file
+// and/or as events of the gadget pod, or nil if none is configured
+func newAuditor(gadgetNs string) (audit.Auditor, error) {
+	var auditors audit.Auditors
+	if auditFile := config.Config.GetString(gadgettracermanagerconfig.AuditFile); auditFile != "" {
+		fileAuditor, err := audit.NewFileAuditor(auditFile)
+		if err != nil {
+			return nil, err
+		}
+		auditors = append(auditors, fileAuditor)
+		log.Infof("Config: recording audit log to %q", auditFile)
+	}
+	if config.Config.GetBool(gadgettracermanagerconfig.AuditK8sEvents) {
+		clientset, err := k8sutil.NewClientset("", "gadgettracermanager/audit")
+		if err != nil {
+			return nil, fmt.Errorf("creating kubernetes client: %w", err)
+		}
+		podName, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("getting pod name: %w", err)
+		}
+		auditors = append(auditors, audit.NewK8sEventAuditor(clientset, gadgetNs, podName, os.Getenv("GADGET_POD_UID"), log.StandardLogger()))
+		log.Infof("Config: recording audit log as events of pod %s/%s", gadgetNs, podName)
+	}
+	if len(auditors) == 0 {
+		return nil, nil
 	}
+	return auditors, nil
 }
@@ -0,0 +1,171 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auditmsg parses the records logged by the LSMs through the audit
+// subsystem, like the ones of AppArmor and SELinux.
+package auditmsg
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	LSMAppArmor = "apparmor"
+	LSMSELinux  = "selinux"
+
+	DecisionDenied  = "DENIED"
+	DecisionAllowed = "ALLOWED"
+)
+
+// Record is an audit record of an LSM
+type Record struct {
+	LSM string
+	// Decision is DENIED when the access was denied and ALLOWED when it would
+	// have been denied, but the policy is in complain (AppArmor) or permissive
+	// (SELinux) mode. Other AppArmor records have their own decision, like
+	// AUDIT or STATUS, and SELinux ones GRANTED.
+	Decision    string
+	Operation   string
+	Subject     string
+	Target      string
+	Class       string
+	Permissions string
+	Object      string
+
+	// Fields holds all the key=value fields of the record
+	Fields map[string]string
+}
+
+// untrusted are the fields that are hex encoded when they aren't quoted
+var untrusted = map[string]bool{
+	"comm":    true,
+	"exe":     true,
+	"name":    true,
+	"path":    true,
+	"peer":    true,
+	"profile": true,
+	"target":  true,
+}
+
+// Parse parses an audit record, with or without the audit(TIME:SERIAL) header
+// added by the kernel. It returns nil if the record isn't one of AppArmor or
+// SELinux.
+func Parse(msg string) *Record {
+	if strings.HasPrefix(msg, "audit(") {
+		if _, rest, ok := strings.Cut(msg, "): "); ok {
+			msg = rest
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(msg, "apparmor="):
+		return parseAppArmor(msg)
+	case strings.HasPrefix(msg, "avc:"):
+		return parseSELinux(msg)
+	}
+	return nil
+}
+
+func parseAppArmor(msg string) *Record {
+	fields := parseFields(msg)
+	r := &Record{
+		LSM:         LSMAppArmor,
+		Decision:    fields["apparmor"],
+		Operation:   fields["operation"],
+		Subject:     fields["profile"],
+		Target:      fields["peer"],
+		Class:       fields["class"],
+		Permissions: fields["denied_mask"],
+		Object:      first(fields, "name", "capname", "target", "family"),
+		Fields:      fields,
+	}
+	if r.Permissions == "" {
+		r.Permissions = fields["requested_mask"]
+	}
+	return r
+}
+
+// parseSELinux parses records like:
+// avc:  denied  { read write } for  pid=1 comm="cat" ... tclass=file permissive=0
+func parseSELinux(msg string) *Record {
+	msg = strings.TrimSpace(strings.TrimPrefix(msg, "avc:"))
+	decision, rest, _ := strings.Cut(msg, " ")
+
+	var perms string
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, "{") {
+		perms, rest, _ = strings.Cut(rest[1:], "}")
+		perms = strings.Join(strings.Fields(perms), ",")
+	}
+	rest = strings.TrimSpace(rest)
+	rest = strings.TrimPrefix(rest, "for ")
+
+	fields := parseFields(rest)
+	r := &Record{
+		LSM:         LSMSELinux,
+		Decision:    strings.ToUpper(decision),
+		Subject:     fields["scontext"],
+		Target:      fields["tcontext"],
+		Class:       fields["tclass"],
+		Permissions: perms,
+		Object:      first(fields, "path", "name", "capability"),
+		Fields:      fields,
+	}
+	if r.Decision == DecisionDenied && fields["permissive"] == "1" {
+		r.Decision = DecisionAllowed
+	}
+	return r
+}
+
+func first(fields map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := fields[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseFields parses space separated key=value fields, whose values can be
+// quoted
+func parseFields(s string) map[string]string {
+	fields := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return fields
+		}
+
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok || strings.Contains(key, " ") {
+			// Skip words that aren't fields
+			_, s, _ = strings.Cut(s, " ")
+			continue
+		}
+
+		var value string
+		if strings.HasPrefix(rest, "\"") {
+			value, s, _ = strings.Cut(rest[1:], "\"")
+		} else {
+			value, s, _ = strings.Cut(rest, " ")
+			if untrusted[key] {
+				if decoded, err := hex.DecodeString(value); err == nil {
+					value = string(decoded)
+				}
+			}
+		}
+		fields[key] = value
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditmsg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      string
+		expected *Record
+	}{
+		{
+			name: "apparmor_file",
+			msg: `apparmor="DENIED" operation="open" class="file" profile="docker-default" name="/etc/shadow" ` +
+				`pid=1234 comm="cat" requested_mask="r" denied_mask="r" fsuid=0 ouid=0`,
+			expected: &Record{
+				LSM:         LSMAppArmor,
+				Decision:    DecisionDenied,
+				Operation:   "open",
+				Subject:     "docker-default",
+				Class:       "file",
+				Permissions: "r",
+				Object:      "/etc/shadow",
+			},
+		},
+		{
+			name: "apparmor_signal",
+			msg: `apparmor="ALLOWED" operation="signal" class="signal" profile="demo" pid=1234 comm="kill" ` +
+				`requested_mask="send" denied_mask="send" signal=kill peer="unconfined"`,
+			expected: &Record{
+				LSM:         LSMAppArmor,
+				Decision:    DecisionAllowed,
+				Operation:   "signal",
+				Subject:     "demo",
+				Target:      "unconfined",
+				Class:       "signal",
+				Permissions: "send",
+			},
+		},
+		{
+			name: "apparmor_hex_name",
+			msg:  `apparmor="DENIED" operation="mkdir" profile="demo" name=2F746D702F61206220 pid=1 comm="mkdir"`,
+			expected: &Record{
+				LSM:       LSMAppArmor,
+				Decision:  DecisionDenied,
+				Operation: "mkdir",
+				Subject:   "demo",
+				Object:    "/tmp/a b ",
+			},
+		},
+		{
+			name: "selinux_file",
+			msg: `avc:  denied  { read write } for  pid=1234 comm="cat" name="shadow" dev="sda1" ino=123 ` +
+				`scontext=system_u:system_r:container_t:s0:c1,c2 tcontext=system_u:object_r:shadow_t:s0 tclass=file permissive=0`,
+			expected: &Record{
+				LSM:         LSMSELinux,
+				Decision:    DecisionDenied,
+				Subject:     "system_u:system_r:container_t:s0:c1,c2",
+				Target:      "system_u:object_r:shadow_t:s0",
+				Class:       "file",
+				Permissions: "read,write",
+				Object:      "shadow",
+			},
+		},
+		{
+			name: "selinux_permissive",
+			msg: `avc:  denied  { net_admin } for  pid=1 comm="ip" capability=12 ` +
+				`scontext=system_u:system_r:container_t:s0 tcontext=system_u:system_r:container_t:s0 tclass=capability permissive=1`,
+			expected: &Record{
+				LSM:         LSMSELinux,
+				Decision:    DecisionAllowed,
+				Subject:     "system_u:system_r:container_t:s0",
+				Target:      "system_u:system_r:container_t:s0",
+				Class:       "capability",
+				Permissions: "net_admin",
+				Object:      "12",
+			},
+		},
+		{
+			name: "header",
+			msg:  `audit(1700000000.123:456): apparmor="DENIED" operation="change_profile" info="label not found" error=-2 profile="docker-default" name="demo" pid=1 comm="sh"`,
+			expected: &Record{
+				LSM:       LSMAppArmor,
+				Decision:  DecisionDenied,
+				Operation: "change_profile",
+				Subject:   "docker-default",
+				Object:    "demo",
+			},
+		},
+		{
+			name: "other",
+			msg:  `lockdown_reason="unsigned module loading"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := Parse(test.msg)
+			if test.expected == nil {
+				assert.Nil(t, r)
+				return
+			}
+			require.NotNil(t, r)
+			r.Fields = nil
+			assert.Equal(t, test.expected, r)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/inspektor-gadget/inspektor-gadget/gadgets/audit_lsm/auditmsg"
+	api "github.com/inspektor-gadget/inspektor-gadget/wasmapi/go"
+)
+
+// Keep in sync with MESSAGE_LEN in program.bpf.c
+const messageLen = 1024
+
+//go:wasmexport gadgetInit
+func gadgetInit() int32 {
+	ds, err := api.GetDataSource("lsm")
+	if err != nil {
+		api.Warnf("failed to get datasource: %s", err)
+		return 1
+	}
+
+	messageF, err := ds.GetField("message")
+	if err != nil {
+		api.Warnf("failed to get field: %s", err)
+		return 1
+	}
+
+	fields := map[string]api.Field{}
+	for _, name := range []string{"lsm", "decision", "operation", "subject", "target", "class", "permissions", "object"} {
+		f, err := ds.AddField(name, api.Kind_String)
+		if err != nil {
+			api.Warnf("failed to add field: %s", err)
+			return 1
+		}
+		fields[name] = f
+	}
+
+	ds.Subscribe(func(source api.DataSource, data api.Data) {
+		msg, err := messageF.String(data, messageLen)
+		if err != nil {
+			api.Warnf("failed to get message: %s", err)
+			return
+		}
+
+		r := auditmsg.Parse(msg)
+		if r == nil {
+			return
+		}
+
+		fields["lsm"].SetString(data, r.LSM)
+		fields["decision"].SetString(data, r.Decision)
+		fields["operation"].SetString(data, r.Operation)
+		fields["subject"].SetString(data, r.Subject)
+		fields["target"].SetString(data, r.Target)
+		fields["class"].SetString(data, r.Class)
+		fields["permissions"].SetString(data, r.Permissions)
+		fields["object"].SetString(data, r.Object)
+	}, 0)
+
+	return 0
+}
+
+func main() {}
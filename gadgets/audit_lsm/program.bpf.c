@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include <gadget/buffer.h>
+#include <gadget/common.h>
+#include <gadget/filter.h>
+#include <gadget/macros.h>
+#include <gadget/types.h>
+
+#define MESSAGE_LEN 1024
+#define NLMSG_HDRLEN 16
+
+// Maximum length of the audit(TIME:SERIAL) header of the records
+#define HEADER_MAX_LEN 48
+
+// Type of the records logged by the LSMs through common_lsm_audit()
+#define AUDIT_AVC 1400
+
+#define APPARMOR_DENIED "apparmor=\"DENIED\""
+#define SELINUX_DENIED "avc:  denied"
+
+struct event {
+	gadget_timestamp timestamp_raw;
+	struct gadget_process proc;
+
+	// Audit record, parsed in user space
+	char message[MESSAGE_LEN];
+};
+
+const volatile bool denied_only = true;
+GADGET_PARAM(denied_only);
+
+GADGET_TRACER_MAP(events, 1024 * 256);
+GADGET_TRACER(lsm, events, event);
+
+static __always_inline bool has_prefix(const char *s, const char *prefix,
+				       int len)
+{
+	for (int i = 0; i < len; i++) {
+		if (s[i] != prefix[i])
+			return false;
+	}
+	return true;
+}
+
+// audit_log_end() is called when a record is complete, before it's sent to
+// auditd or the kernel log, and even if it's dropped because of the rate limit
+SEC("kprobe/audit_log_end")
+int BPF_KPROBE(ig_audit_log_end, struct audit_buffer *ab)
+{
+	struct nlmsghdr *nlh;
+	struct sk_buff *skb;
+	struct event *event;
+	int off = 0;
+
+	if (!ab)
+		return 0;
+
+	skb = BPF_CORE_READ(ab, skb);
+	nlh = (struct nlmsghdr *)BPF_CORE_READ(skb, data);
+	if (BPF_CORE_READ(nlh, nlmsg_type) != AUDIT_AVC)
+		return 0;
+
+	if (gadget_should_discard_data_current())
+		return 0;
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		return 0;
+
+	// The record is a string, formatted with vsnprintf()
+	if (bpf_probe_read_kernel_str(event->message, sizeof(event->message),
+				      (void *)nlh + NLMSG_HDRLEN) <= 0)
+		goto discard;
+
+	// Skip the audit(TIME:SERIAL) header added by audit_log_start()
+	for (int i = 0; i < HEADER_MAX_LEN; i++) {
+		if (event->message[i] == ')' && event->message[i + 1] == ':') {
+			off = i + 3;
+			break;
+		}
+	}
+
+	if (denied_only &&
+	    !has_prefix(event->message + off, APPARMOR_DENIED,
+			sizeof(APPARMOR_DENIED) - 1) &&
+	    !has_prefix(event->message + off, SELINUX_DENIED,
+			sizeof(SELINUX_DENIED) - 1))
+		goto discard;
+
+	event->timestamp_raw = bpf_ktime_get_boot_ns();
+	gadget_process_populate(&event->proc);
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+	return 0;
+
+discard:
+	gadget_discard_buf(event);
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
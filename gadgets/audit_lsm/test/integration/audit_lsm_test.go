@@ -0,0 +1,124 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/match"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+type auditLsmEvent struct {
+	utils.CommonData
+
+	Timestamp string        `json:"timestamp"`
+	Proc      utils.Process `json:"proc"`
+
+	Lsm       string `json:"lsm"`
+	Decision  string `json:"decision"`
+	Operation string `json:"operation"`
+	Subject   string `json:"subject"`
+	Object    string `json:"object"`
+}
+
+const apparmorEnabledPath = "/sys/module/apparmor/parameters/enabled"
+
+// targetProfile doesn't exist, so changing to it is always denied
+const targetProfile = "ig-test-audit-lsm"
+
+func TestAuditLsm(t *testing.T) {
+	gadgettesting.RequireEnvironmentVariables(t)
+	utils.InitTest(t)
+
+	// The LSM enabled on the nodes can't be checked from here
+	if utils.CurrentTestComponent != utils.IgLocalTestComponent {
+		t.Skipf("Skipping test as containers can't be checked for AppArmor")
+	}
+	if _, err := os.Stat(apparmorEnabledPath); err != nil {
+		t.Skipf("Skipping test as AppArmor isn't available: %s", err)
+	}
+	utils.RequireFileContains(t, apparmorEnabledPath, "Y")
+
+	containerFactory, err := containers.NewContainerFactory(utils.Runtime)
+	require.NoError(t, err, "new container factory")
+	containerName := "test-audit-lsm"
+	containerImage := gadgettesting.BusyBoxImage
+
+	// Newer kernels have a per-LSM attr directory, older ones only the
+	// shared files
+	cmd := fmt.Sprintf(
+		"while true; do echo -n 'changeprofile %[1]s' > /proc/self/attr/apparmor/current || echo -n 'changeprofile %[1]s' > /proc/self/attr/current; sleep 0.5; done",
+		targetProfile,
+	)
+	testContainer := containerFactory.NewContainer(containerName, cmd,
+		containers.WithContainerImage(containerImage),
+		containers.WithStartAndStop(),
+	)
+
+	commonDataOpts := []utils.CommonDataOption{
+		utils.WithContainerImageName(containerImage),
+		// The container is started after the tracer, its ID isn't known yet
+		utils.WithContainerID(utils.NormalizedStr),
+	}
+
+	runnerOpts := []igrunner.Option{
+		igrunner.WithFlags(fmt.Sprintf("-r=%s", utils.Runtime)),
+		igrunner.WithValidateOutput(
+			func(t *testing.T, output string) {
+				expectedEntries := []*auditLsmEvent{
+					{
+						CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+						Proc:       utils.BuildProc("sh", 0, 0),
+						Lsm:        "apparmor",
+						Decision:   "DENIED",
+						Operation:  "change_profile",
+						Object:     targetProfile,
+						// The profile of the container depends on the runtime
+						Subject:   utils.NormalizedStr,
+						Timestamp: utils.NormalizedStr,
+					},
+				}
+				normalize := func(e *auditLsmEvent) {
+					utils.NormalizeCommonData(&e.CommonData)
+					utils.NormalizeString(&e.Runtime.ContainerID)
+					utils.NormalizeString(&e.Timestamp)
+					utils.NormalizeProc(&e.Proc)
+					utils.NormalizeString(&e.Subject)
+				}
+				match.MatchEntries(t, match.JSONMultiObjectMode, output, normalize, expectedEntries...)
+			},
+		),
+		igrunner.WithStartAndStop(),
+	}
+	auditLsmCmd := igrunner.New("audit_lsm", runnerOpts...)
+
+	steps := []igtesting.TestStep{
+		auditLsmCmd,
+		// wait to ensure ig has started
+		utils.Sleep(10 * time.Second),
+		testContainer,
+	}
+	igtesting.RunTestSteps(steps, t)
+}
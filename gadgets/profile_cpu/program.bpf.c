@@ -32,7 +32,7 @@ const volatile bool include_idle = false;
 GADGET_PARAM(include_idle);
 
 struct values {
-	__u64 samples;
+	gadget_counter__u64 samples;
 };
 
 struct {
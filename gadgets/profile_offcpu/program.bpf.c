@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include <gadget/maps.bpf.h>
+#include <gadget/mntns_filter.h>
+#include <gadget/filter.h>
+#include <gadget/types.h>
+#include <gadget/macros.h>
+#include <gadget/common.h>
+#include <gadget/kernel_stack_map.h>
+#include <gadget/user_stack_map.h>
+
+#define MAX_ENTRIES 10240
+#define MAX_THREADS 10240
+
+struct key_t {
+	struct gadget_user_stack user_stack_raw;
+	gadget_kernel_stack kern_stack_raw;
+	struct gadget_process proc;
+};
+
+const volatile bool kernel_stacks_only = false;
+GADGET_PARAM(kernel_stacks_only);
+
+const volatile bool user_stacks_only = false;
+GADGET_PARAM(user_stacks_only);
+
+// Blocking periods shorter than min_block_us are ignored, to reduce the
+// overhead and the noise of very short sleeps
+const volatile __u64 min_block_us = 1;
+GADGET_PARAM(min_block_us);
+
+struct values {
+	gadget_duration off_cpu_raw;
+	gadget_counter__u64 switches;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__type(key, struct key_t);
+	__type(value, struct values);
+	__uint(max_entries, MAX_ENTRIES);
+} counts SEC(".maps");
+
+GADGET_MAPITER(samples, counts);
+
+// Threads switched out, with their stacks at that time. The key is the tid.
+struct start_t {
+	__u64 ts;
+	struct key_t key;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__type(key, __u32);
+	__type(value, struct start_t);
+	__uint(max_entries, MAX_THREADS);
+} starts SEC(".maps");
+
+// Too big for the stack
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, struct start_t);
+} tmp_start SEC(".maps");
+
+SEC("tp_btf/sched_switch")
+int BPF_PROG(ig_offcpu_switch, bool preempt, struct task_struct *prev,
+	     struct task_struct *next)
+{
+	static const struct values zero = {};
+	struct start_t *start;
+	struct values *valp;
+	__u32 zero_key = 0;
+	__u32 tid;
+	__u64 delta;
+
+	// The switched out thread is still the current one, its stacks are
+	// collected now and accounted once it's back on the CPU.
+	tid = BPF_CORE_READ(prev, pid);
+	if (tid != 0 && !gadget_should_discard_data_current()) {
+		start = bpf_map_lookup_elem(&tmp_start, &zero_key);
+		if (!start)
+			return 0; // it never happens
+
+		__builtin_memset(start, 0, sizeof(*start));
+		gadget_process_populate(&start->key.proc);
+
+		if (user_stacks_only)
+			start->key.kern_stack_raw = -1;
+		else
+			start->key.kern_stack_raw = gadget_get_kernel_stack(ctx);
+
+		if (!kernel_stacks_only)
+			gadget_get_user_stack(ctx, &start->key.user_stack_raw);
+
+		start->ts = bpf_ktime_get_ns();
+		bpf_map_update_elem(&starts, &tid, start, BPF_ANY);
+	}
+
+	tid = BPF_CORE_READ(next, pid);
+	start = bpf_map_lookup_elem(&starts, &tid);
+	if (!start)
+		return 0;
+
+	delta = bpf_ktime_get_ns() - start->ts;
+	if (delta >= min_block_us * 1000) {
+		valp = bpf_map_lookup_or_try_init(&counts, &start->key, &zero);
+		if (valp) {
+			__sync_fetch_and_add(&valp->off_cpu_raw, delta);
+			__sync_fetch_and_add(&valp->switches, 1);
+		}
+	}
+
+	bpf_map_delete_elem(&starts, &tid);
+
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
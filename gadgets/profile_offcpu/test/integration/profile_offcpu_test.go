@@ -0,0 +1,123 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/match"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+type profileOffcpuEntry struct {
+	utils.CommonData
+
+	Proc utils.Process `json:"proc"`
+
+	KernStack string `json:"kern_stack"`
+	OffCPURaw uint64 `json:"off_cpu_raw"`
+	Switches  uint64 `json:"switches"`
+}
+
+func TestProfileOffcpu(t *testing.T) {
+	gadgettesting.RequireEnvironmentVariables(t)
+	utils.InitTest(t)
+
+	containerFactory, err := containers.NewContainerFactory(utils.Runtime)
+	require.NoError(t, err, "new container factory")
+	containerName := "test-profile-offcpu"
+	containerImage := gadgettesting.BusyBoxImage
+
+	var ns string
+	containerOpts := []containers.ContainerOption{
+		containers.WithContainerImage(containerImage),
+	}
+
+	if utils.CurrentTestComponent == utils.KubectlGadgetTestComponent {
+		ns = utils.GenerateTestNamespaceName(t, "test-profile-offcpu")
+		containerOpts = append(containerOpts, containers.WithContainerNamespace(ns))
+	}
+
+	testContainer := containerFactory.NewContainer(
+		containerName,
+		"while true; do sleep 0.1; done",
+		containerOpts...,
+	)
+
+	testContainer.Start(t)
+	t.Cleanup(func() {
+		testContainer.Stop(t)
+	})
+
+	var runnerOpts []igrunner.Option
+	var testingOpts []igtesting.Option
+	commonDataOpts := []utils.CommonDataOption{
+		utils.WithContainerImageName(containerImage),
+		utils.WithContainerID(testContainer.ID()),
+	}
+
+	switch utils.CurrentTestComponent {
+	case utils.IgLocalTestComponent:
+		runnerOpts = append(runnerOpts,
+			igrunner.WithFlags(
+				fmt.Sprintf("-r=%s", utils.Runtime),
+				fmt.Sprintf("-c=%s", containerName),
+			),
+		)
+	case utils.KubectlGadgetTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-n=%s", ns)))
+		testingOpts = append(testingOpts, igtesting.WithCbBeforeCleanup(utils.PrintLogsFn(ns)))
+		commonDataOpts = append(commonDataOpts, utils.WithK8sNamespace(ns))
+	}
+
+	runnerOpts = append(runnerOpts,
+		igrunner.WithStartAndStop(),
+		igrunner.WithValidateOutput(
+			func(t *testing.T, output string) {
+				// sleep blocks in the kernel until the timer expires, the
+				// samples are only emitted when the gadget is stopped
+				expectedEntry := match.WithFields(
+					&profileOffcpuEntry{
+						CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+						Proc:       utils.BuildProc("sleep", 0, 0),
+					},
+					match.Fields{
+						"kern_stack":  match.Regexp("schedule"),
+						"off_cpu_raw": match.Range(50_000_000, 1e12),
+						"switches":    match.NonEmpty(),
+					},
+				)
+
+				normalize := func(e *profileOffcpuEntry) {
+					utils.NormalizeCommonData(&e.CommonData)
+					utils.NormalizeProc(&e.Proc)
+				}
+
+				match.MatchEntriesWithFields(t, match.JSONMultiArrayMode, output, normalize, expectedEntry)
+			},
+		),
+	)
+
+	profileOffcpuCmd := igrunner.New("profile_offcpu", runnerOpts...)
+
+	igtesting.RunTestSteps([]igtesting.TestStep{profileOffcpuCmd}, t, testingOpts...)
+}
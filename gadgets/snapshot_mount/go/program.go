@@ -0,0 +1,89 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/inspektor-gadget/inspektor-gadget/gadgets/snapshot_mount/mountinfo"
+	api "github.com/inspektor-gadget/inspektor-gadget/wasmapi/go"
+)
+
+// Keep in sync with program.bpf.c
+const (
+	fstypeLen = 16
+	pathLen   = 256
+)
+
+//go:wasmexport gadgetInit
+func gadgetInit() int32 {
+	ds, err := api.GetDataSource("mounts")
+	if err != nil {
+		api.Warnf("failed to get datasource: %s", err)
+		return 1
+	}
+
+	fields := map[string]api.Field{}
+	for _, name := range []string{"fstype", "root", "options_raw", "peer_group", "master_group", "writable"} {
+		f, err := ds.GetField(name)
+		if err != nil {
+			api.Warnf("failed to get field %q: %s", name, err)
+			return 1
+		}
+		fields[name] = f
+	}
+
+	optionsF, err := ds.AddField("options", api.Kind_String)
+	if err != nil {
+		api.Warnf("failed to add field: %s", err)
+		return 1
+	}
+	propagationF, err := ds.AddField("propagation", api.Kind_String)
+	if err != nil {
+		api.Warnf("failed to add field: %s", err)
+		return 1
+	}
+	hostPathF, err := ds.AddField("host_path", api.Kind_Bool)
+	if err != nil {
+		api.Warnf("failed to add field: %s", err)
+		return 1
+	}
+	sensitiveF, err := ds.AddField("sensitive", api.Kind_Bool)
+	if err != nil {
+		api.Warnf("failed to add field: %s", err)
+		return 1
+	}
+
+	ds.SubscribeArray(func(source api.DataSource, arr api.DataArray) error {
+		for i := 0; i < arr.Len(); i++ {
+			data := arr.Get(i)
+
+			fstype, _ := fields["fstype"].String(data, fstypeLen)
+			root, _ := fields["root"].String(data, pathLen)
+			flags, _ := fields["options_raw"].Uint32(data)
+			peerGroup, _ := fields["peer_group"].Uint32(data)
+			masterGroup, _ := fields["master_group"].Uint32(data)
+			writable, _ := fields["writable"].Bool(data)
+
+			optionsF.SetString(data, mountinfo.FormatOptions(flags))
+			propagationF.SetString(data, mountinfo.FormatPropagation(flags, peerGroup, masterGroup))
+			hostPathF.SetBool(data, mountinfo.IsHostPath(fstype, root))
+			sensitiveF.SetBool(data, mountinfo.IsSensitive(fstype, root, writable))
+		}
+		return nil
+	}, 0)
+
+	return 0
+}
+
+func main() {}
@@ -0,0 +1,165 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mountinfo formats the mounts reported by the snapshot_mount gadget
+// like /proc/<pid>/mountinfo does, and flags the ones exposing the host.
+package mountinfo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mount flags of the kernel (MNT_* in include/linux/mount.h)
+const (
+	mntNoSuid      = 0x01
+	mntNoDev       = 0x02
+	mntNoExec      = 0x04
+	mntNoAtime     = 0x08
+	mntNoDirAtime  = 0x10
+	mntRelAtime    = 0x20
+	mntReadOnly    = 0x40
+	mntNoSymFollow = 0x80
+	mntUnbindable  = 0x2000
+)
+
+var options = []struct {
+	flag uint32
+	name string
+}{
+	{mntNoSuid, "nosuid"},
+	{mntNoDev, "nodev"},
+	{mntNoExec, "noexec"},
+	{mntNoAtime, "noatime"},
+	{mntNoDirAtime, "nodiratime"},
+	{mntRelAtime, "relatime"},
+	{mntNoSymFollow, "nosymfollow"},
+}
+
+// pseudoFilesystems don't store data on a device, so mounting them doesn't
+// expose any file of the host
+var pseudoFilesystems = map[string]bool{
+	"overlay": true,
+	"proc":    true,
+	"sysfs":   true,
+	"cgroup":  true,
+	"cgroup2": true,
+	"devpts":  true,
+	"mqueue":  true,
+	"nsfs":    true,
+}
+
+// hostFilesystems give access to the host itself when they are mounted in a
+// container
+var hostFilesystems = map[string]bool{
+	"devtmpfs":   true,
+	"securityfs": true,
+	"debugfs":    true,
+	"tracefs":    true,
+	"bpf":        true,
+}
+
+// runtimeDirs are directories where the container runtimes and kubelet keep
+// the files they bind mount into containers, like /etc/hosts or volumes
+var runtimeDirs = []string{
+	"/kubelet/pods/",
+	"/containerd/io.containerd.",
+	"/docker/containers/",
+	"/containers/storage/",
+}
+
+// sensitivePaths are directories of the host that shouldn't be mounted in
+// containers
+var sensitivePaths = map[string]bool{
+	"/":                true,
+	"/boot":            true,
+	"/dev":             true,
+	"/etc":             true,
+	"/proc":            true,
+	"/root":            true,
+	"/run":             true,
+	"/sys":             true,
+	"/var/lib/kubelet": true,
+	"/var/log":         true,
+	"/var/run":         true,
+}
+
+// FormatOptions returns the mount options of flags, like "ro,nosuid,nodev"
+func FormatOptions(flags uint32) string {
+	opts := []string{"rw"}
+	if flags&mntReadOnly != 0 {
+		opts[0] = "ro"
+	}
+	for _, o := range options {
+		if flags&o.flag != 0 {
+			opts = append(opts, o.name)
+		}
+	}
+	return strings.Join(opts, ",")
+}
+
+// FormatPropagation returns the propagation type of a mount, like
+// "shared:1 master:2", or "private" if it doesn't propagate events.
+func FormatPropagation(flags, peerGroup, masterGroup uint32) string {
+	var props []string
+	if peerGroup != 0 {
+		props = append(props, fmt.Sprintf("shared:%d", peerGroup))
+	}
+	if masterGroup != 0 {
+		props = append(props, fmt.Sprintf("master:%d", masterGroup))
+	}
+	if flags&mntUnbindable != 0 {
+		props = append(props, "unbindable")
+	}
+	if len(props) == 0 {
+		return "private"
+	}
+	return strings.Join(props, " ")
+}
+
+// IsHostPath returns whether the mount exposes files of the host, like
+// hostPath volumes do. root is the path of the mounted directory inside its
+// filesystem. Files that the container runtime or kubelet bind mount, like
+// /etc/hosts or emptyDir volumes, aren't considered.
+func IsHostPath(fstype, root string) bool {
+	if pseudoFilesystems[fstype] || hostFilesystems[fstype] {
+		return false
+	}
+	// A tmpfs mounted at its root was created for the container
+	if fstype == "tmpfs" && root == "/" {
+		return false
+	}
+	for _, dir := range runtimeDirs {
+		if strings.Contains(root, dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSensitive returns whether the mount gives access to sensitive parts of
+// the host, like its root filesystem, the socket of the container runtime or
+// writable kernel interfaces.
+func IsSensitive(fstype, root string, writable bool) bool {
+	if hostFilesystems[fstype] {
+		return true
+	}
+	if fstype == "sysfs" && writable {
+		return true
+	}
+	if !IsHostPath(fstype, root) {
+		return false
+	}
+	return sensitivePaths[root] || strings.HasSuffix(root, ".sock")
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mountinfo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatOptions(t *testing.T) {
+	assert.Equal(t, "rw", FormatOptions(0))
+	assert.Equal(t, "rw,relatime", FormatOptions(mntRelAtime))
+	assert.Equal(t, "ro,nosuid,nodev,noexec", FormatOptions(mntReadOnly|mntNoSuid|mntNoDev|mntNoExec))
+	assert.Equal(t, "rw,nosuid,noatime", FormatOptions(mntNoSuid|mntNoAtime|mntUnbindable))
+}
+
+func TestFormatPropagation(t *testing.T) {
+	assert.Equal(t, "private", FormatPropagation(0, 0, 0))
+	assert.Equal(t, "shared:3", FormatPropagation(0, 3, 0))
+	assert.Equal(t, "master:1", FormatPropagation(0, 0, 1))
+	assert.Equal(t, "shared:3 master:1", FormatPropagation(0, 3, 1))
+	assert.Equal(t, "unbindable", FormatPropagation(mntUnbindable, 0, 0))
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		fstype    string
+		root      string
+		writable  bool
+		hostPath  bool
+		sensitive bool
+	}{
+		{
+			name:   "rootfs",
+			fstype: "overlay",
+			root:   "/",
+		},
+		{
+			name:   "proc",
+			fstype: "proc",
+			root:   "/",
+		},
+		{
+			name:     "empty_dir",
+			fstype:   "ext4",
+			root:     "/var/lib/kubelet/pods/0c6d/volumes/kubernetes.io~empty-dir/cache",
+			writable: true,
+		},
+		{
+			name:     "etc_hosts_separate_var",
+			fstype:   "xfs",
+			root:     "/lib/kubelet/pods/0c6d/etc-hosts",
+			writable: true,
+		},
+		{
+			name:     "projected_volume",
+			fstype:   "tmpfs",
+			root:     "/",
+			writable: false,
+		},
+		{
+			name:     "host_path",
+			fstype:   "ext4",
+			root:     "/data",
+			writable: true,
+			hostPath: true,
+		},
+		{
+			name:      "host_root",
+			fstype:    "ext4",
+			root:      "/",
+			hostPath:  true,
+			sensitive: true,
+		},
+		{
+			name:      "runtime_socket",
+			fstype:    "tmpfs",
+			root:      "/containerd/containerd.sock",
+			writable:  true,
+			hostPath:  true,
+			sensitive: true,
+		},
+		{
+			name:      "writable_sysfs",
+			fstype:    "sysfs",
+			root:      "/",
+			writable:  true,
+			sensitive: true,
+		},
+		{
+			name:   "readonly_sysfs",
+			fstype: "sysfs",
+			root:   "/",
+		},
+		{
+			name:      "host_devices",
+			fstype:    "devtmpfs",
+			root:      "/",
+			writable:  true,
+			sensitive: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.hostPath, IsHostPath(test.fstype, test.root))
+			assert.Equal(t, test.sensitive, IsSensitive(test.fstype, test.root, test.writable))
+		})
+	}
+}
@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+/* This BPF program uses the GPL-restricted function bpf_seq_write(). */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+
+#include <gadget/filesystem.h>
+#include <gadget/filter.h>
+#include <gadget/macros.h>
+#include <gadget/types.h>
+
+#define SOURCE_LEN 64
+#define FSTYPE_LEN 16
+#define PATH_LEN 256
+#define MAX_ENTRIES 10240
+
+// Everything written by a single invocation of the iterator has to fit in
+// the seq_file buffer (8 pages), so keep MAX_MOUNTS * sizeof(struct mount_entry)
+// below 32KB.
+#define MAX_MOUNTS 48
+#define MAX_DEPTH 32
+
+#ifndef SB_RDONLY
+#define SB_RDONLY 1
+#endif
+
+struct mount_entry {
+	gadget_mntns_id mntns_id;
+	__u32 mount_id;
+	__u32 parent_id;
+	char source[SOURCE_LEN];
+	char fstype[FSTYPE_LEN];
+	// Path of the mounted directory inside its filesystem, it's not "/" for
+	// bind mounts
+	char root[PATH_LEN];
+	char mountpoint[PATH_LEN];
+	__u32 options_raw;
+	__u32 peer_group;
+	__u32 master_group;
+	bool writable;
+};
+
+struct walk_ctx {
+	struct seq_file *seq;
+	struct mount *start;
+	struct mount *cur;
+	gadget_mntns_id mntns_id;
+};
+
+// Task reporting the mounts of each mount namespace. The iterator can show
+// the same task again when its output didn't fit in the seq_file buffer, so
+// the owner task is kept instead of a simple "seen" flag.
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, gadget_mntns_id);
+	__type(value, __u64);
+} mntns_owners SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, struct mount_entry);
+} entries SEC(".maps");
+
+GADGET_SNAPSHOTTER(mounts, mount_entry, ig_snap_mount);
+
+// read_mount_root writes the path of the root of the mount inside its
+// filesystem, like the 4th field of /proc/<pid>/mountinfo.
+static __always_inline void read_mount_root(struct mount *mnt, char *dst)
+{
+	struct dentry *dentry = BPF_CORE_READ(mnt, mnt.mnt_root);
+	u32 buf_off = (MAX_PERCPU_BUFSIZE >> 1);
+	struct dentry *d_parent;
+	struct qstr d_name;
+	unsigned int len;
+	unsigned int off;
+	int sz;
+
+	buf_t *string_p = get_buf(STRING_BUF_IDX);
+	if (string_p == NULL)
+		return;
+
+	for (int i = 0; i < MAX_PATH_COMPONENTS; i++) {
+		d_parent = BPF_CORE_READ(dentry, d_parent);
+		if (dentry == d_parent)
+			break;
+
+		d_name = BPF_CORE_READ(dentry, d_name);
+		len = (d_name.len + 1) & (PATH_MAX - 1);
+		off = buf_off - len;
+		if (off > buf_off)
+			break;
+
+		len = len & ((MAX_PERCPU_BUFSIZE >> 1) - 1);
+		sz = bpf_probe_read_kernel_str(
+			&string_p->buf[off & ((MAX_PERCPU_BUFSIZE >> 1) - 1)],
+			len, d_name.name);
+		if (sz <= 1)
+			break;
+
+		// Replace the null byte termination with a slash
+		buf_off -= 1;
+		string_p->buf[buf_off & (MAX_PERCPU_BUFSIZE - 1)] = '/';
+		buf_off -= sz - 1;
+		dentry = d_parent;
+	}
+
+	if (buf_off == (MAX_PERCPU_BUFSIZE >> 1)) {
+		dst[0] = '/';
+		dst[1] = '\0';
+		return;
+	}
+
+	buf_off -= 1;
+	string_p->buf[buf_off & (MAX_PERCPU_BUFSIZE - 1)] = '/';
+	string_p->buf[(MAX_PERCPU_BUFSIZE >> 1) - 1] = '\0';
+	bpf_probe_read_kernel_str(
+		dst, PATH_LEN, &string_p->buf[buf_off & (MAX_PERCPU_BUFSIZE - 1)]);
+}
+
+// next_mount returns the mount following mnt in a depth-first walk of the
+// mount tree under start, or NULL once the whole tree was walked.
+static __always_inline struct mount *next_mount(struct mount *start,
+						struct mount *mnt)
+{
+	struct list_head *next = BPF_CORE_READ(mnt, mnt_mounts.next);
+	struct mount *parent;
+
+	if (next != &mnt->mnt_mounts)
+		return container_of(next, struct mount, mnt_child);
+
+	for (int i = 0; i < MAX_DEPTH; i++) {
+		if (mnt == start)
+			return NULL;
+
+		parent = BPF_CORE_READ(mnt, mnt_parent);
+		next = BPF_CORE_READ(mnt, mnt_child.next);
+		if (next != &parent->mnt_mounts)
+			return container_of(next, struct mount, mnt_child);
+
+		mnt = parent;
+	}
+
+	return NULL;
+}
+
+static long emit_mount(__u32 index, void *c)
+{
+	struct walk_ctx *wctx = c;
+	struct mount *mnt = wctx->cur;
+	struct mount *parent, *master;
+	struct mount_entry *entry;
+	struct path mountpoint;
+	__u32 zero = 0;
+	int flags;
+	char *path;
+
+	if (mnt == NULL)
+		return 1;
+
+	entry = bpf_map_lookup_elem(&entries, &zero);
+	if (entry == NULL)
+		return 1;
+
+	parent = BPF_CORE_READ(mnt, mnt_parent);
+	flags = BPF_CORE_READ(mnt, mnt.mnt_flags);
+
+	entry->mntns_id = wctx->mntns_id;
+	entry->mount_id = BPF_CORE_READ(mnt, mnt_id);
+	entry->parent_id = BPF_CORE_READ(parent, mnt_id);
+	bpf_probe_read_kernel_str(entry->source, sizeof(entry->source),
+				  BPF_CORE_READ(mnt, mnt_devname));
+	bpf_probe_read_kernel_str(entry->fstype, sizeof(entry->fstype),
+				  BPF_CORE_READ(mnt, mnt.mnt_sb, s_type, name));
+
+	entry->options_raw = flags;
+	entry->writable = !(flags & MNT_READONLY) &&
+			  !(BPF_CORE_READ(mnt, mnt.mnt_sb, s_flags) & SB_RDONLY);
+
+	entry->peer_group = 0;
+	if (flags & MNT_SHARED)
+		entry->peer_group = BPF_CORE_READ(mnt, mnt_group_id);
+
+	entry->master_group = 0;
+	master = BPF_CORE_READ(mnt, mnt_master);
+	if (master != NULL)
+		entry->master_group = BPF_CORE_READ(master, mnt_group_id);
+
+	// Mountpoints are relative to the root of the task used to walk the tree
+	entry->mountpoint[0] = '/';
+	entry->mountpoint[1] = '\0';
+	if (mnt != wctx->start) {
+		mountpoint.mnt = &parent->mnt;
+		mountpoint.dentry = BPF_CORE_READ(mnt, mnt_mountpoint);
+		path = get_path_str(&mountpoint);
+		if (path != NULL)
+			bpf_probe_read_kernel_str(entry->mountpoint,
+						  sizeof(entry->mountpoint),
+						  path);
+	}
+
+	read_mount_root(mnt, entry->root);
+
+	bpf_seq_write(wctx->seq, entry, sizeof(*entry));
+
+	wctx->cur = next_mount(wctx->start, mnt);
+	return 0;
+}
+
+SEC("iter/task")
+int ig_snap_mount(struct bpf_iter__task *ctx)
+{
+	struct task_struct *task = ctx->task;
+	struct walk_ctx wctx = {};
+	gadget_mntns_id mntns_id;
+	struct vfsmount *root;
+	__u64 owner, *cur_owner;
+
+	if (task == NULL)
+		return 0;
+
+	// Threads share the mount namespace of their process
+	if (task->tgid != task->pid)
+		return 0;
+
+	// Exiting tasks don't have namespaces anymore
+	if (task->nsproxy == NULL)
+		return 0;
+
+	mntns_id = task->nsproxy->mnt_ns->ns.inum;
+
+	if (gadget_should_discard_data(mntns_id, task->tgid, task->pid,
+				       task->comm, task->cred->uid.val,
+				       task->cred->gid.val))
+		return 0;
+
+	// Only the first task found in each mount namespace reports its mounts
+	owner = (__u64)task;
+	bpf_map_update_elem(&mntns_owners, &mntns_id, &owner, BPF_NOEXIST);
+	cur_owner = bpf_map_lookup_elem(&mntns_owners, &mntns_id);
+	if (cur_owner == NULL || *cur_owner != owner)
+		return 0;
+
+	root = BPF_CORE_READ(task, fs, root.mnt);
+	if (root == NULL)
+		return 0;
+
+	wctx.seq = ctx->meta->seq;
+	wctx.start = real_mount(root);
+	wctx.cur = wctx.start;
+	wctx.mntns_id = mntns_id;
+
+	bpf_loop(MAX_MOUNTS, emit_mount, &wctx, 0);
+
+	return 0;
+}
+
+char _license[] SEC("license") = "GPL";
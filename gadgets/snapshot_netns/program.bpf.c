@@ -0,0 +1,449 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+/* This BPF program uses the GPL-restricted function bpf_seq_write(). */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+
+#include <gadget/filter.h>
+#include <gadget/macros.h>
+#include <gadget/types.h>
+
+#define AF_INET 2
+#define AF_INET6 10
+
+#define IFNAMSIZ 16
+#define KIND_LEN 16
+#define MAC_LEN 18
+#define ETH_ALEN 6
+
+#define IFF_UP 1
+#define IPV6_ADDR_SCOPE_GLOBAL 0
+
+// Everything written by a single invocation of the iterator has to fit in
+// the seq_file buffer (8 pages), so keep
+// MAX_INTERFACES * sizeof(struct interface_entry) below 32KB.
+#define MAX_INTERFACES 128
+#define MAX_ADDRESSES 8
+#define MAX_TRIE_DEPTH 32
+#define MAX_ENTRIES 10240
+
+// Size of the keys of the IPv4 FIB trie, fa_slen is KEYLENGTH - prefix length
+#define KEYLENGTH 32
+
+enum oper_state {
+	unknown,
+	notpresent,
+	down,
+	lowerlayerdown,
+	testing,
+	dormant,
+	up,
+};
+
+enum snapshotter {
+	SNAPSHOTTER_NETNS,
+	SNAPSHOTTER_INTERFACES,
+};
+
+struct netns_entry {
+	gadget_netns_id netns_id;
+
+	// Default routes of the main tables. The gateway is 0.0.0.0 or :: when
+	// there isn't any default route or when it doesn't have a gateway.
+	struct gadget_l3endpoint_t gateway;
+	char gateway_dev[IFNAMSIZ];
+	struct gadget_l3endpoint_t gateway6;
+	char gateway6_dev[IFNAMSIZ];
+
+	// net.ipv4.ip_forward and net.ipv6.conf.all.forwarding
+	bool ip_forward;
+	bool ipv6_forwarding;
+	// net.ipv4.conf.all.rp_filter
+	__u32 rp_filter;
+	// net.ipv4.ip_local_port_range
+	__u16 port_range_min;
+	__u16 port_range_max;
+	// net.ipv4.ip_unprivileged_port_start
+	__u32 unprivileged_port_start;
+	// net.core.somaxconn
+	__u32 somaxconn;
+	// net.ipv4.tcp_syncookies
+	__u32 tcp_syncookies;
+};
+
+struct interface_entry {
+	gadget_netns_id netns_id;
+	__u32 ifindex;
+	char name[IFNAMSIZ];
+	char kind[KIND_LEN];
+	char mac[MAC_LEN];
+	bool admin_up;
+	enum oper_state state_raw;
+	__u32 mtu;
+	struct gadget_l3endpoint_t ipv4;
+	__u8 ipv4_prefixlen;
+	struct gadget_l3endpoint_t ipv6;
+	__u8 ipv6_prefixlen;
+};
+
+struct owner_key {
+	gadget_netns_id netns_id;
+	enum snapshotter snapshotter;
+};
+
+struct walk_ctx {
+	struct seq_file *seq;
+	struct list_head *head;
+	struct list_head *cur;
+	gadget_netns_id netns_id;
+};
+
+// Task reporting each network namespace. The iterator can show the same task
+// again when its output didn't fit in the seq_file buffer, so the owner task
+// is kept instead of a simple "seen" flag.
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct owner_key);
+	__type(value, __u64);
+} netns_owners SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, struct interface_entry);
+} interfaces SEC(".maps");
+
+// ip_local_port_range was stored as two integers before being packed in a
+// single u32 in Linux 6.3
+struct local_ports___old {
+	int range[2];
+};
+
+GADGET_SNAPSHOTTER(netns, netns_entry, ig_snap_netns);
+GADGET_SNAPSHOTTER(interfaces, interface_entry, ig_snap_ifaces);
+
+// netns_of_task returns the network namespace of the task if it's the first
+// task of it seen by the given snapshotter, or NULL otherwise.
+static __always_inline struct net *netns_of_task(struct task_struct *task,
+						 enum snapshotter snapshotter)
+{
+	struct owner_key key = { .snapshotter = snapshotter };
+	__u64 owner, *cur_owner;
+	struct net *net;
+
+	if (task == NULL)
+		return NULL;
+
+	// Threads share the network namespace of their process
+	if (task->tgid != task->pid)
+		return NULL;
+
+	// Exiting tasks don't have namespaces anymore
+	if (task->nsproxy == NULL)
+		return NULL;
+
+	if (gadget_should_discard_data(task->nsproxy->mnt_ns->ns.inum,
+				       task->tgid, task->pid, task->comm,
+				       task->cred->uid.val,
+				       task->cred->gid.val))
+		return NULL;
+
+	net = task->nsproxy->net_ns;
+	key.netns_id = BPF_CORE_READ(net, ns.inum);
+
+	owner = (__u64)task;
+	bpf_map_update_elem(&netns_owners, &key, &owner, BPF_NOEXIST);
+	cur_owner = bpf_map_lookup_elem(&netns_owners, &key);
+	if (cur_owner == NULL || *cur_owner != owner)
+		return NULL;
+
+	return net;
+}
+
+// fib4_default_route returns the default route of the IPv4 main table. It
+// follows the path of the 0.0.0.0 key in the LC-trie, like fib_table_lookup()
+// does, since the default route is stored in the leaf of that key.
+static __always_inline struct fib_info *fib4_default_route(struct net *net)
+{
+	struct fib_table *tb = BPF_CORE_READ(net, ipv4.fib_main);
+	struct key_vector *n;
+	struct hlist_node *node;
+	struct fib_alias *fa;
+	struct trie *t;
+	t_key index;
+	__u8 bits;
+
+	if (tb == NULL)
+		return NULL;
+
+	t = (struct trie *)BPF_CORE_READ(tb, tb_data);
+	n = BPF_CORE_READ(t, kv[0].tnode[0]);
+
+	for (int i = 0; i < MAX_TRIE_DEPTH; i++) {
+		if (n == NULL)
+			return NULL;
+
+		bits = BPF_CORE_READ(n, bits);
+		if (bits == 0)
+			break;
+
+		// The key being looked up is 0, so the index of the child is
+		// given by the bits of the node's key
+		index = BPF_CORE_READ(n, key) >> BPF_CORE_READ(n, pos);
+		if (index >> bits)
+			return NULL;
+
+		bpf_probe_read_kernel(&n, sizeof(n),
+				      (void *)n +
+					      bpf_core_field_offset(n->tnode) +
+					      index * sizeof(n));
+	}
+
+	if (n == NULL || BPF_CORE_READ(n, bits) != 0 ||
+	    BPF_CORE_READ(n, key) != 0)
+		return NULL;
+
+	node = BPF_CORE_READ(n, leaf.first);
+	for (int i = 0; i < MAX_ADDRESSES; i++) {
+		if (node == NULL)
+			return NULL;
+
+		fa = container_of(node, struct fib_alias, fa_list);
+		if (BPF_CORE_READ(fa, fa_slen) == KEYLENGTH &&
+		    BPF_CORE_READ(fa, tb_id) == RT_TABLE_MAIN)
+			return BPF_CORE_READ(fa, fa_info);
+
+		node = BPF_CORE_READ(node, next);
+	}
+
+	return NULL;
+}
+
+// fib6_default_route returns the default route of the IPv6 main table, which
+// is the route of the root node of the table.
+static __always_inline struct fib6_info *fib6_default_route(struct net *net)
+{
+	struct fib6_table *tb = BPF_CORE_READ(net, ipv6.fib6_main_tbl);
+	struct fib6_info *rt;
+
+	// IPv6 is disabled
+	if (tb == NULL)
+		return NULL;
+
+	rt = BPF_CORE_READ(tb, tb6_root.leaf);
+	if (rt == NULL || rt == BPF_CORE_READ(net, ipv6.fib6_null_entry))
+		return NULL;
+
+	if (BPF_CORE_READ(rt, fib6_dst.plen) != 0)
+		return NULL;
+
+	return rt;
+}
+
+static __always_inline void fill_nexthop(struct fib_nh_common *nhc,
+					 struct gadget_l3endpoint_t *gw,
+					 char *dev)
+{
+	struct net_device *netdev = BPF_CORE_READ(nhc, nhc_dev);
+
+	switch (BPF_CORE_READ(nhc, nhc_gw_family)) {
+	case AF_INET:
+		gw->addr_raw.v4 = BPF_CORE_READ(nhc, nhc_gw.ipv4);
+		gw->version = 4;
+		break;
+	case AF_INET6:
+		BPF_CORE_READ_INTO(&gw->addr_raw.v6, nhc, nhc_gw.ipv6);
+		gw->version = 6;
+		break;
+	}
+
+	if (netdev != NULL)
+		BPF_CORE_READ_STR_INTO(dev, netdev, name);
+}
+
+SEC("iter/task")
+int ig_snap_netns(struct bpf_iter__task *ctx)
+{
+	struct netns_entry entry = {};
+	struct fib6_info *rt6;
+	struct fib_info *fi;
+	struct net *net;
+	__u32 range;
+
+	net = netns_of_task(ctx->task, SNAPSHOTTER_NETNS);
+	if (net == NULL)
+		return 0;
+
+	entry.netns_id = BPF_CORE_READ(net, ns.inum);
+
+	entry.gateway.version = 4;
+	fi = fib4_default_route(net);
+	// Routes using nexthop objects aren't supported
+	if (fi != NULL && BPF_CORE_READ(fi, nh) == NULL)
+		fill_nexthop(&fi->fib_nh[0].nh_common, &entry.gateway,
+			     entry.gateway_dev);
+
+	entry.gateway6.version = 6;
+	rt6 = fib6_default_route(net);
+	if (rt6 != NULL && BPF_CORE_READ(rt6, nh) == NULL)
+		fill_nexthop(&rt6->fib6_nh[0].nh_common, &entry.gateway6,
+			     entry.gateway6_dev);
+
+	entry.ip_forward = BPF_CORE_READ(
+		net, ipv4.devconf_all, data[IPV4_DEVCONF_FORWARDING - 1]);
+	entry.rp_filter = BPF_CORE_READ(net, ipv4.devconf_all,
+					data[IPV4_DEVCONF_RP_FILTER - 1]);
+	entry.ipv6_forwarding =
+		BPF_CORE_READ(net, ipv6.devconf_all, forwarding);
+
+	if (bpf_core_field_size(net->ipv4.ip_local_ports.range) ==
+	    sizeof(__u32)) {
+		range = BPF_CORE_READ(net, ipv4.ip_local_ports.range);
+		entry.port_range_min = range & 0xffff;
+		entry.port_range_max = range >> 16;
+	} else {
+		struct local_ports___old *ports =
+			(void *)&net->ipv4.ip_local_ports;
+		entry.port_range_min = BPF_CORE_READ(ports, range[0]);
+		entry.port_range_max = BPF_CORE_READ(ports, range[1]);
+	}
+
+	entry.unprivileged_port_start =
+		BPF_CORE_READ(net, ipv4.sysctl_ip_prot_sock);
+	entry.somaxconn = BPF_CORE_READ(net, core.sysctl_somaxconn);
+	entry.tcp_syncookies = BPF_CORE_READ(net, ipv4.sysctl_tcp_syncookies);
+
+	bpf_seq_write(ctx->meta->seq, &entry, sizeof(entry));
+
+	return 0;
+}
+
+static __always_inline void format_mac(struct net_device *dev, char *mac)
+{
+	static const char hex[] = "0123456789abcdef";
+	__u8 addr[ETH_ALEN];
+
+	if (BPF_CORE_READ(dev, addr_len) != ETH_ALEN)
+		return;
+
+	if (bpf_probe_read_kernel(addr, sizeof(addr),
+				  BPF_CORE_READ(dev, dev_addr)))
+		return;
+
+	for (int i = 0; i < ETH_ALEN; i++) {
+		mac[i * 3] = hex[addr[i] >> 4];
+		mac[i * 3 + 1] = hex[addr[i] & 0xf];
+		mac[i * 3 + 2] = i == ETH_ALEN - 1 ? '\0' : ':';
+	}
+}
+
+static __always_inline void fill_ipv4(struct net_device *dev,
+				      struct interface_entry *entry)
+{
+	struct in_ifaddr *ifa = BPF_CORE_READ(dev, ip_ptr, ifa_list);
+
+	if (ifa == NULL)
+		return;
+
+	entry->ipv4.addr_raw.v4 = BPF_CORE_READ(ifa, ifa_local);
+	entry->ipv4_prefixlen = BPF_CORE_READ(ifa, ifa_prefixlen);
+}
+
+// fill_ipv6 picks the first global address of the device, or its first
+// address if it doesn't have any global one.
+static __always_inline void fill_ipv6(struct net_device *dev,
+				      struct interface_entry *entry)
+{
+	struct inet6_dev *idev = BPF_CORE_READ(dev, ip6_ptr);
+	struct list_head *head, *cur;
+	struct inet6_ifaddr *ifa;
+	bool found = false;
+
+	if (idev == NULL)
+		return;
+
+	head = &idev->addr_list;
+	cur = BPF_CORE_READ(idev, addr_list.next);
+
+	for (int i = 0; i < MAX_ADDRESSES; i++) {
+		if (cur == NULL || cur == head)
+			break;
+
+		ifa = container_of(cur, struct inet6_ifaddr, if_list);
+		if (!found ||
+		    BPF_CORE_READ(ifa, scope) == IPV6_ADDR_SCOPE_GLOBAL) {
+			BPF_CORE_READ_INTO(&entry->ipv6.addr_raw.v6, ifa, addr);
+			entry->ipv6_prefixlen = BPF_CORE_READ(ifa, prefix_len);
+			found = true;
+		}
+		if (BPF_CORE_READ(ifa, scope) == IPV6_ADDR_SCOPE_GLOBAL)
+			break;
+
+		cur = BPF_CORE_READ(cur, next);
+	}
+}
+
+static long emit_interface(__u32 index, void *c)
+{
+	struct walk_ctx *wctx = c;
+	struct interface_entry *entry;
+	struct net_device *dev;
+	__u32 zero = 0;
+
+	if (wctx->cur == NULL || wctx->cur == wctx->head)
+		return 1;
+
+	entry = bpf_map_lookup_elem(&interfaces, &zero);
+	if (entry == NULL)
+		return 1;
+	__builtin_memset(entry, 0, sizeof(*entry));
+
+	dev = container_of(wctx->cur, struct net_device, dev_list);
+
+	entry->netns_id = wctx->netns_id;
+	entry->ifindex = BPF_CORE_READ(dev, ifindex);
+	BPF_CORE_READ_STR_INTO(&entry->name, dev, name);
+	bpf_probe_read_kernel_str(entry->kind, sizeof(entry->kind),
+				  BPF_CORE_READ(dev, rtnl_link_ops, kind));
+	format_mac(dev, entry->mac);
+	entry->admin_up = BPF_CORE_READ(dev, flags) & IFF_UP;
+	entry->state_raw = BPF_CORE_READ(dev, operstate);
+	entry->mtu = BPF_CORE_READ(dev, mtu);
+
+	entry->ipv4.version = 4;
+	fill_ipv4(dev, entry);
+	entry->ipv6.version = 6;
+	fill_ipv6(dev, entry);
+
+	bpf_seq_write(wctx->seq, entry, sizeof(*entry));
+
+	wctx->cur = BPF_CORE_READ(wctx->cur, next);
+	return 0;
+}
+
+SEC("iter/task")
+int ig_snap_ifaces(struct bpf_iter__task *ctx)
+{
+	struct walk_ctx wctx = {};
+	struct net *net;
+
+	net = netns_of_task(ctx->task, SNAPSHOTTER_INTERFACES);
+	if (net == NULL)
+		return 0;
+
+	wctx.seq = ctx->meta->seq;
+	wctx.head = &net->dev_base_head;
+	wctx.cur = BPF_CORE_READ(net, dev_base_head.next);
+	wctx.netns_id = BPF_CORE_READ(net, ns.inum);
+
+	bpf_loop(MAX_INTERFACES, emit_interface, &wctx, 0);
+
+	return 0;
+}
+
+char _license[] SEC("license") = "GPL";
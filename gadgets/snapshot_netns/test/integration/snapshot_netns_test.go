@@ -0,0 +1,166 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/match"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+type snapshotNetnsEntry struct {
+	utils.CommonData
+
+	NetNsID    uint64           `json:"netns_id"`
+	Gateway    utils.L3Endpoint `json:"gateway"`
+	GatewayDev string           `json:"gateway_dev"`
+}
+
+type snapshotInterfaceEntry struct {
+	utils.CommonData
+
+	NetNsID       uint64           `json:"netns_id"`
+	Ifindex       uint32           `json:"ifindex"`
+	Name          string           `json:"name"`
+	Kind          string           `json:"kind"`
+	State         string           `json:"state"`
+	Mtu           uint32           `json:"mtu"`
+	IPv4          utils.L3Endpoint `json:"ipv4"`
+	IPv4Prefixlen uint8            `json:"ipv4_prefixlen"`
+}
+
+func TestSnapshotNetns(t *testing.T) {
+	gadgettesting.RequireEnvironmentVariables(t)
+	utils.InitTest(t)
+
+	containerFactory, err := containers.NewContainerFactory(utils.Runtime)
+	require.NoError(t, err, "new container factory")
+	containerName := "test-snapshot-netns"
+	containerImage := gadgettesting.BusyBoxImage
+
+	var ns string
+	containerOpts := []containers.ContainerOption{containers.WithContainerImage(containerImage)}
+
+	if utils.CurrentTestComponent == utils.KubectlGadgetTestComponent {
+		ns = utils.GenerateTestNamespaceName(t, "test-snapshot-netns")
+		containerOpts = append(containerOpts, containers.WithContainerNamespace(ns))
+	}
+
+	testContainer := containerFactory.NewContainer(
+		containerName,
+		"sleep inf",
+		containerOpts...,
+	)
+
+	testContainer.Start(t)
+	t.Cleanup(func() {
+		testContainer.Stop(t)
+	})
+
+	var runnerOpts []igrunner.Option
+	var testingOpts []igtesting.Option
+	commonDataOpts := []utils.CommonDataOption{utils.WithContainerImageName(containerImage), utils.WithContainerID(testContainer.ID())}
+
+	// TODO: timeout shouldn't be required. We need to use something big like 5
+	// seconds to avoid the message being lost.
+	const timeoutParam = "--timeout=5"
+	switch utils.CurrentTestComponent {
+	case utils.IgLocalTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-r=%s", utils.Runtime), timeoutParam))
+	case utils.KubectlGadgetTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-n=%s", ns), timeoutParam))
+		testingOpts = append(testingOpts, igtesting.WithCbBeforeCleanup(utils.PrintLogsFn(ns)))
+		commonDataOpts = append(commonDataOpts, utils.WithK8sNamespace(ns))
+	}
+
+	// The containerd test utils don't configure the network of the
+	// containers, they only have the loopback interface
+	hasNetwork := utils.CurrentTestComponent != utils.IgLocalTestComponent || utils.Runtime != "containerd"
+
+	runnerOpts = append(runnerOpts, igrunner.WithValidateOutput(
+		func(t *testing.T, output string) {
+			expectedInterfaces := []*snapshotInterfaceEntry{
+				{
+					CommonData:    utils.BuildCommonData(containerName, commonDataOpts...),
+					NetNsID:       utils.NormalizedInt,
+					Ifindex:       1,
+					Name:          "lo",
+					Kind:          "",
+					State:         "unknown",
+					Mtu:           65536,
+					IPv4:          utils.L3Endpoint{Addr: "127.0.0.1", Version: 4},
+					IPv4Prefixlen: 8,
+				},
+			}
+			if hasNetwork {
+				expectedInterfaces = append(expectedInterfaces, &snapshotInterfaceEntry{
+					CommonData:    utils.BuildCommonData(containerName, commonDataOpts...),
+					NetNsID:       utils.NormalizedInt,
+					Ifindex:       utils.NormalizedInt,
+					Name:          "eth0",
+					Kind:          "veth",
+					State:         "up",
+					Mtu:           utils.NormalizedInt,
+					IPv4:          utils.L3Endpoint{Addr: utils.NormalizedStr, Version: 4},
+					IPv4Prefixlen: utils.NormalizedInt,
+				})
+			}
+
+			normalizeInterface := func(e *snapshotInterfaceEntry) {
+				utils.NormalizeCommonData(&e.CommonData)
+				utils.NormalizeInt(&e.NetNsID)
+				if e.Name != "lo" {
+					utils.NormalizeInt(&e.Ifindex)
+					utils.NormalizeInt(&e.Mtu)
+					utils.NormalizeString(&e.IPv4.Addr)
+					utils.NormalizeInt(&e.IPv4Prefixlen)
+				}
+			}
+
+			match.MatchEntries(t, match.JSONMultiArrayMode, output, normalizeInterface, expectedInterfaces...)
+
+			if !hasNetwork {
+				return
+			}
+
+			expectedNetns := &snapshotNetnsEntry{
+				CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+				NetNsID:    utils.NormalizedInt,
+				Gateway:    utils.L3Endpoint{Addr: utils.NormalizedStr, Version: 4},
+				GatewayDev: "eth0",
+			}
+
+			normalizeNetns := func(e *snapshotNetnsEntry) {
+				utils.NormalizeCommonData(&e.CommonData)
+				utils.NormalizeInt(&e.NetNsID)
+				utils.NormalizeString(&e.Gateway.Addr)
+			}
+
+			match.MatchEntries(t, match.JSONMultiArrayMode, output, normalizeNetns, expectedNetns)
+		},
+	))
+
+	snapshotNetnsCmd := igrunner.New("snapshot_netns", runnerOpts...)
+	igtesting.RunTestSteps([]igtesting.TestStep{utils.Sleep(5 * time.Second), snapshotNetnsCmd}, t, testingOpts...)
+}
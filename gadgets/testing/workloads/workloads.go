@@ -0,0 +1,261 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package workloads provides containers generating a known activity at a fixed
+// rate, to be traced by the integration tests of the gadgets.
+package workloads
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+)
+
+const (
+	// DefaultInterval is the time between two operations of a workload
+	DefaultInterval = time.Second
+
+	// DefaultDomain is the domain queried by the DNS workloads
+	DefaultDomain = "fake.test.com."
+)
+
+type Options struct {
+	// Interval is the time between two operations, DefaultInterval if zero
+	Interval time.Duration
+
+	// Count is the number of operations to perform; the workload performs
+	// them until it's stopped if zero. The containers keep running once
+	// they're done.
+	Count int
+
+	// ContainerOptions are added to the options of all the containers of the
+	// workload, like containers.WithContainerNamespace()
+	ContainerOptions []containers.ContainerOption
+}
+
+type containerSpec struct {
+	name  string
+	image string
+	// cmd returns the command of the container; it's called once the previous
+	// containers of the workload are started, so it can use their IPs
+	cmd  func(w *Workload) string
+	opts []containers.ContainerOption
+}
+
+// Workload is a set of containers started in order and stopped in the reverse
+// order
+type Workload struct {
+	factory containers.ContainerFactory
+	options Options
+	specs   []containerSpec
+
+	// Containers holds the started containers, in the order of the specs
+	Containers []*containers.TestContainer
+}
+
+func newWorkload(factory containers.ContainerFactory, options Options, specs ...containerSpec) *Workload {
+	return &Workload{
+		factory: factory,
+		options: options,
+		specs:   specs,
+	}
+}
+
+// Start starts the containers of the workload and stops them when the test
+// finishes
+func (w *Workload) Start(t *testing.T) {
+	t.Helper()
+
+	for _, spec := range w.specs {
+		opts := []containers.ContainerOption{containers.WithContainerImage(spec.image)}
+		opts = append(opts, w.options.ContainerOptions...)
+		opts = append(opts, spec.opts...)
+
+		c := w.factory.NewContainer(spec.name, spec.cmd(w), opts...)
+		c.Start(t)
+		t.Cleanup(func() {
+			c.Stop(t)
+		})
+		w.Containers = append(w.Containers, c)
+	}
+}
+
+// Container returns the started container with the given name
+func (w *Workload) Container(name string) *containers.TestContainer {
+	for i, spec := range w.specs {
+		if spec.name == name && i < len(w.Containers) {
+			return w.Containers[i]
+		}
+	}
+	return nil
+}
+
+// Loop returns a shell command running cmd according to options: every
+// Interval, Count times or forever.
+func Loop(cmd string, options Options) string {
+	interval := options.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	sleep := "sleep " + strconv.FormatFloat(interval.Seconds(), 'f', -1, 64)
+
+	if options.Count == 0 {
+		return fmt.Sprintf("while true; do %s; %s; done", cmd, sleep)
+	}
+	return fmt.Sprintf("i=0; while [ $i -lt %d ]; do %s; i=$((i+1)); %s; done; tail -f /dev/null",
+		options.Count, cmd, sleep)
+}
+
+// HTTP returns a workload made of an nginx server in the container
+// "<name>-server" and a client fetching its index page with wget in the
+// container "<name>-client"
+func HTTP(factory containers.ContainerFactory, name string, options Options) *Workload {
+	serverName := name + "-server"
+	return newWorkload(factory, options,
+		containerSpec{
+			name:  serverName,
+			image: gadgettesting.NginxImage,
+			cmd: func(*Workload) string {
+				return "nginx && tail -f /dev/null"
+			},
+		},
+		containerSpec{
+			name:  name + "-client",
+			image: gadgettesting.BusyBoxImage,
+			cmd: func(w *Workload) string {
+				url := fmt.Sprintf("http://%s/", w.Container(serverName).IP())
+				return Loop(fmt.Sprintf("wget -q -O /dev/null %s", url), options)
+			},
+		},
+	)
+}
+
+// HTTPLoopback returns a workload made of a single container running an
+// nginx server and fetching its index page on 127.0.0.1 with curl. The
+// container needs to be privileged on some runtimes for nginx to drop its
+// privileges.
+func HTTPLoopback(factory containers.ContainerFactory, name string, options Options) *Workload {
+	return newWorkload(factory, options, containerSpec{
+		name:  name,
+		image: gadgettesting.NginxImage,
+		cmd: func(*Workload) string {
+			return "nginx && " + Loop("curl -s -o /dev/null 127.0.0.1", options)
+		},
+	})
+}
+
+type DNSOptions struct {
+	Options
+
+	// Server is the address of the DNS server to query, the one of the
+	// container if empty
+	Server string
+
+	// Domain is the domain to query, DefaultDomain if empty
+	Domain string
+
+	// Types are the types of the records to query, "a" if empty
+	Types []string
+}
+
+// DNS returns a workload made of a container querying records with nslookup
+func DNS(factory containers.ContainerFactory, name string, options DNSOptions) *Workload {
+	domain := options.Domain
+	if domain == "" {
+		domain = DefaultDomain
+	}
+	types := options.Types
+	if len(types) == 0 {
+		types = []string{"a"}
+	}
+
+	cmd := ""
+	for i, typ := range types {
+		if i > 0 {
+			cmd += "; "
+		}
+		// nslookup fails if the domain doesn't exist; keep looping anyway
+		cmd += fmt.Sprintf("nslookup -type=%s %s %s || true", typ, domain, options.Server)
+	}
+
+	return newWorkload(factory, options.Options, containerSpec{
+		name:  name,
+		image: gadgettesting.BusyBoxImage,
+		cmd: func(*Workload) string {
+			return Loop(cmd, options.Options)
+		},
+	})
+}
+
+type FileOptions struct {
+	Options
+
+	// Path is the file to write, read and remove, /tmp/workload if empty
+	Path string
+
+	// Size is the number of bytes written, 4096 if zero
+	Size int
+}
+
+// Files returns a workload made of a container creating, reading and removing
+// a file
+func Files(factory containers.ContainerFactory, name string, options FileOptions) *Workload {
+	path := options.Path
+	if path == "" {
+		path = "/tmp/workload"
+	}
+	size := options.Size
+	if size == 0 {
+		size = 4096
+	}
+
+	cmd := fmt.Sprintf("head -c %d /dev/zero > %s; cat %s > /dev/null; rm %s", size, path, path, path)
+	return newWorkload(factory, options.Options, containerSpec{
+		name:  name,
+		image: gadgettesting.BusyBoxImage,
+		cmd: func(*Workload) string {
+			return Loop(cmd, options.Options)
+		},
+	})
+}
+
+type CrashOptions struct {
+	Options
+
+	// Signal is the name of the signal killing the processes, SEGV if empty
+	Signal string
+}
+
+// Crashes returns a workload made of a container whose child processes are
+// killed by a signal; the container itself keeps running so all the crashes
+// are attributed to it.
+func Crashes(factory containers.ContainerFactory, name string, options CrashOptions) *Workload {
+	signal := options.Signal
+	if signal == "" {
+		signal = "SEGV"
+	}
+
+	cmd := fmt.Sprintf("sh -c 'kill -%s $$' || true", signal)
+	return newWorkload(factory, options.Options, containerSpec{
+		name:  name,
+		image: gadgettesting.BusyBoxImage,
+		cmd: func(*Workload) string {
+			return Loop(cmd, options.Options)
+		},
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoop(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Options
+		expected string
+	}{
+		{
+			name:     "default",
+			expected: "while true; do true; sleep 1; done",
+		},
+		{
+			name:     "interval",
+			options:  Options{Interval: 100 * time.Millisecond},
+			expected: "while true; do true; sleep 0.1; done",
+		},
+		{
+			name:     "count",
+			options:  Options{Interval: 2 * time.Second, Count: 5},
+			expected: "i=0; while [ $i -lt 5 ]; do true; i=$((i+1)); sleep 2; done; tail -f /dev/null",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, Loop("true", test.options))
+		})
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		workload *Workload
+		expected []string
+	}{
+		{
+			name:     "http loopback",
+			workload: HTTPLoopback(nil, "test", Options{}),
+			expected: []string{"nginx && while true; do curl -s -o /dev/null 127.0.0.1; sleep 1; done"},
+		},
+		{
+			name: "dns",
+			workload: DNS(nil, "test", DNSOptions{
+				Server: "10.0.0.1",
+				Types:  []string{"a", "aaaa"},
+			}),
+			expected: []string{
+				"while true; do nslookup -type=a fake.test.com. 10.0.0.1 || true; " +
+					"nslookup -type=aaaa fake.test.com. 10.0.0.1 || true; sleep 1; done",
+			},
+		},
+		{
+			name:     "files",
+			workload: Files(nil, "test", FileOptions{Path: "/tmp/foo", Size: 10}),
+			expected: []string{
+				"while true; do head -c 10 /dev/zero > /tmp/foo; cat /tmp/foo > /dev/null; rm /tmp/foo; sleep 1; done",
+			},
+		},
+		{
+			name:     "crashes",
+			workload: Crashes(nil, "test", CrashOptions{Signal: "ABRT"}),
+			expected: []string{"while true; do sh -c 'kill -ABRT $$' || true; sleep 1; done"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Len(t, test.workload.specs, len(test.expected))
+			for i, spec := range test.workload.specs {
+				assert.Equal(t, test.expected[i], spec.cmd(test.workload))
+			}
+		})
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	w := HTTP(nil, "test", Options{})
+	require.Len(t, w.specs, 2)
+	assert.Equal(t, "test-server", w.specs[0].name)
+	assert.Equal(t, "test-client", w.specs[1].name)
+	assert.Nil(t, w.Container("test-server"), "containers aren't started")
+}
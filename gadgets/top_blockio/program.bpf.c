@@ -51,9 +51,9 @@ struct info_t {
 
 // the value of the output summary
 struct val_t {
-	__u64 bytes;
-	__u64 us;
-	__u32 io;
+	gadget_counter__u64 bytes;
+	gadget_counter__u64 us;
+	gadget_counter__u32 io;
 };
 
 struct {
@@ -34,9 +34,9 @@ struct file_id {
 
 struct file_stat {
 	struct gadget_process proc;
-	__u64 reads;
+	gadget_counter__u64 reads;
 	gadget_bytes rbytes_raw;
-	__u64 writes;
+	gadget_counter__u64 writes;
 	gadget_bytes wbytes_raw;
 	char file[GADGET_PATH_MAX];
 	enum type t_raw;
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"encoding/binary"
 	"fmt"
 	"net"
 	"strings"
@@ -111,8 +112,46 @@ func (r RCode) String() string {
 	return fmt.Sprintf("%d", r)
 }
 
+// Values of the transport_t enum of the eBPF program
+const (
+	transportUDP uint8 = 0
+	transportTCP uint8 = 1
+)
+
+// Keep in sync with the max entries of doh_endpoints in the eBPF program
+const maxDoHEndpoints = 256
+
 var payload []byte
 
+// unpackMessage parses a DNS message. If the message is partial, like the
+// first segment of a DNS over TCP message split across several segments,
+// only its header and its first question are parsed.
+func unpackMessage(buf []byte, partial bool) (dnsmessage.Message, error) {
+	msg := dnsmessage.Message{}
+	if !partial {
+		err := msg.Unpack(buf)
+		return msg, err
+	}
+
+	var p dnsmessage.Parser
+	header, err := p.Start(buf)
+	if err != nil {
+		return msg, err
+	}
+	msg.Header = header
+
+	question, err := p.Question()
+	if err == dnsmessage.ErrSectionDone {
+		return msg, nil
+	}
+	if err != nil {
+		return msg, err
+	}
+	msg.Questions = append(msg.Questions, question)
+
+	return msg, nil
+}
+
 //go:wasmexport gadgetInit
 func gadgetInit() int32 {
 	ds, err := api.GetDataSource("dns")
@@ -139,6 +178,12 @@ func gadgetInit() int32 {
 		return 1
 	}
 
+	transportRawF, err := ds.GetField("transport_raw")
+	if err != nil {
+		api.Warnf("failed to get field: %s", err)
+		return 1
+	}
+
 	idF, err := ds.AddField("id", api.Kind_String)
 	if err != nil {
 		api.Warnf("failed to add field: %s", err)
@@ -231,6 +276,16 @@ func gadgetInit() int32 {
 			api.Warnf("failed to get dns_off: %s", err)
 			return
 		}
+		transport, err := transportRawF.Uint8(data)
+		if err != nil {
+			api.Warnf("failed to get transport_raw: %s", err)
+			return
+		}
+
+		// Encrypted DNS messages can't be parsed
+		if transport != transportUDP && transport != transportTCP {
+			return
+		}
 
 		if payloadLen < uint32(dnsOff) {
 			api.Warnf("packet too short: dataLen: %d < dnsOff: %d", payloadLen, dnsOff)
@@ -248,8 +303,21 @@ func gadgetInit() int32 {
 			return
 		}
 
-		msg := dnsmessage.Message{}
-		if err := msg.Unpack(payload[dnsOff:n]); err != nil {
+		buf := payload[dnsOff:n]
+		partial := false
+		if transport == transportTCP {
+			// DNS over TCP messages are prefixed by their length. The eBPF
+			// program ensures it's in the packet.
+			msgLen := int(binary.BigEndian.Uint16(payload[dnsOff-2 : dnsOff]))
+			if len(buf) > msgLen {
+				buf = buf[:msgLen]
+			} else if len(buf) < msgLen {
+				partial = true
+			}
+		}
+
+		msg, err := unpackMessage(buf, partial)
+		if err != nil {
 			api.Warnf("failed to unpack dns message: %s", err)
 			return
 		}
@@ -303,4 +371,54 @@ func gadgetInit() int32 {
 	return 0
 }
 
+//go:wasmexport gadgetStart
+func gadgetStart() int32 {
+	rawString, err := api.GetParamValue("doh-endpoints", 4096)
+	if err != nil {
+		api.Errorf("failed to get param: %v", err)
+		return 1
+	}
+
+	dohEndpointsMapName := "doh_endpoints"
+	dohEndpointsMap, err := api.GetMap(dohEndpointsMapName)
+	if err != nil {
+		api.Errorf("no map named %s", dohEndpointsMapName)
+		return 1
+	}
+
+	var endpoints []string
+	if rawString != "" {
+		endpoints = strings.Split(rawString, ",")
+	}
+
+	if len(endpoints) > maxDoHEndpoints {
+		api.Errorf("Length of --doh-endpoints exceeded. No more than %d values can be added.", maxDoHEndpoints)
+		return 1
+	}
+	for _, endpoint := range endpoints {
+		ip := net.ParseIP(strings.TrimSpace(endpoint))
+		if ip == nil {
+			api.Errorf("invalid IP address %q in --doh-endpoints", endpoint)
+			return 1
+		}
+
+		// Same layout as union gadget_ip_addr_t: IPv4 addresses are stored in
+		// the first 4 bytes.
+		var key [16]byte
+		if ipv4 := ip.To4(); ipv4 != nil {
+			copy(key[:], ipv4)
+		} else {
+			copy(key[:], ip.To16())
+		}
+
+		err = dohEndpointsMap.Put(key, uint8(1))
+		if err != nil {
+			api.Errorf("Could not add %q to DoH endpoints map: %v", endpoint, err)
+			return 1
+		}
+	}
+
+	return 0
+}
+
 func main() {}
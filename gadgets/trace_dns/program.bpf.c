@@ -44,11 +44,18 @@ unsigned long long load_word(const void *skb,
 #define DNS_QR_QUERY 0
 #define DNS_QR_RESP 1
 
+// DNS over TLS (RFC 7858) and over QUIC (RFC 9250)
+#define DOT_PORT 853
+// DNS over HTTPS (RFC 8484)
+#define DOH_PORT 443
+
 #define MAX_PORTS 16
 const volatile __u16 ports[MAX_PORTS] = { 53, 5353 };
 const volatile __u16 ports_len = 2;
 const volatile bool paths = false;
+const volatile bool encrypted = false;
 GADGET_PARAM(paths);
+GADGET_PARAM(encrypted);
 
 static __always_inline bool is_dns_port(__u16 port)
 {
@@ -70,6 +77,14 @@ enum pkt_type_t : __u8 {
 	KERNEL,
 };
 
+enum transport_t : __u8 {
+	UDP,
+	TCP,
+	DoT,
+	DoQ,
+	DoH,
+};
+
 struct event_t {
 	gadget_timestamp timestamp_raw;
 	struct gadget_l4endpoint_t src;
@@ -81,6 +96,9 @@ struct event_t {
 	char exepath[TRACE_DNS_PATH_MAX];
 
 	enum pkt_type_t pkt_type_raw;
+	enum transport_t transport_raw;
+	// Set on TCP queries retrying a query whose UDP response was truncated
+	bool tcp_retry;
 	gadget_duration
 		latency_ns_raw; // Set only if the packet is a response and pkt_type is 0 (Host).
 
@@ -160,6 +178,22 @@ struct {
 	__uint(max_entries, 1024);
 } query_map SEC(".maps");
 
+// Queries whose UDP response was truncated, expected to be retried over TCP
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__type(key, struct query_key_t);
+	__type(value, __u8);
+	__uint(max_entries, 1024);
+} truncated_map SEC(".maps");
+
+// Addresses of known DNS over HTTPS resolvers. It's filled from user space.
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__type(key, union gadget_ip_addr_t);
+	__type(value, __u8);
+	__uint(max_entries, 256);
+} doh_endpoints SEC(".maps");
+
 static __always_inline unsigned int min(unsigned int a, unsigned int b)
 {
 	return a < b ? a : b;
@@ -170,7 +204,8 @@ int ig_trace_dns(struct __sk_buff *skb)
 {
 	struct event_t *event;
 	int zero = 0;
-	__u16 sport, dport, l4_off, dns_off, h_proto, id;
+	__u16 sport, dport, l4_off, dns_off, h_proto, id, msg_len;
+	enum transport_t transport;
 	__u8 proto;
 	int i;
 
@@ -253,7 +288,16 @@ int ig_trace_dns(struct __sk_buff *skb)
 		return 0;
 	}
 
-	if (!is_dns_port(sport) && !is_dns_port(dport))
+	if (is_dns_port(sport) || is_dns_port(dport))
+		transport = proto == IPPROTO_TCP ? TCP : UDP;
+	else if (!encrypted)
+		return 0;
+	else if (dport == DOT_PORT)
+		transport = proto == IPPROTO_TCP ? DoT : DoQ;
+	else if (dport == DOH_PORT && proto == IPPROTO_TCP)
+		// The destination is checked against the known resolvers below
+		transport = DoH;
+	else
 		return 0;
 
 	// Calculate the DNS offset in the packet
@@ -261,6 +305,14 @@ int ig_trace_dns(struct __sk_buff *skb)
 	switch (proto) {
 	case IPPROTO_UDP:
 		dns_off = l4_off + sizeof(struct udphdr);
+
+		// Encrypted messages can't be parsed, so only report the Initial
+		// packets of the QUIC connections: long header with the fixed bit
+		// and the Initial type.
+		if (transport == DoQ &&
+		    (skb->len <= dns_off ||
+		     (load_byte(skb, dns_off) & 0xf0) != 0xc0))
+			return 0;
 		break;
 	case IPPROTO_TCP:
 		// This is best effort, since we don't reassemble TCP segments.
@@ -270,12 +322,29 @@ int ig_trace_dns(struct __sk_buff *skb)
 		// The data offset field in the header is specified in 32-bit words. We
 		// have to multiply this value by 4 to get the TCP header length in bytes.
 		__u8 tcp_header_len = tcph.doff * 4;
+		dns_off = l4_off + tcp_header_len;
+
+		// Encrypted messages can't be parsed, so only report the connection
+		// requests
+		if (transport != TCP) {
+			if (!tcph.syn || tcph.ack)
+				return 0;
+			break;
+		}
 
 		// Skip if we don't have any data to avoid handling control segments
-		dns_off = l4_off + tcp_header_len;
 		if (skb->len <= dns_off)
 			return 0;
 
+		// Each DNS message is prefixed by its length (RFC 7766). Skip the
+		// segments that are too short to start with a message, like the ones
+		// carrying the end of a message split across several segments.
+		if (skb->len < dns_off + 2 + sizeof(struct dnshdr))
+			return 0;
+		msg_len = load_half(skb, dns_off);
+		if (msg_len < sizeof(struct dnshdr))
+			return 0;
+
 		// DNS is after the TCP header and the 2 bytes of the length of the DNS packet
 		dns_off += 2;
 		break;
@@ -300,6 +369,8 @@ int ig_trace_dns(struct __sk_buff *skb)
 	event->data_len = skb->len;
 	event->dns_off = dns_off;
 	event->pkt_type_raw = skb->pkt_type;
+	event->transport_raw = transport;
+	event->tcp_retry = false;
 	event->src.proto_raw = event->dst.proto_raw = proto;
 	event->src.port = sport;
 	event->dst.port = dport;
@@ -332,6 +403,17 @@ int ig_trace_dns(struct __sk_buff *skb)
 		break;
 	}
 
+	if (transport == DoH) {
+		union gadget_ip_addr_t addr = {};
+
+		if (event->dst.version == 4)
+			addr.v4 = event->dst.addr_raw.v4;
+		else
+			addr = event->dst.addr_raw;
+		if (!bpf_map_lookup_elem(&doh_endpoints, &addr))
+			return 0;
+	}
+
 	struct gadget_socket_value *skb_val = gadget_socket_lookup(skb);
 	if (gadget_should_discard_data_by_skb(skb_val))
 		return 0;
@@ -364,6 +446,14 @@ int ig_trace_dns(struct __sk_buff *skb)
 		}
 	}
 
+	// Connections to encrypted DNS resolvers are always reported from the
+	// client side
+	if (transport != UDP && transport != TCP) {
+		event->nameserver.version = event->dst.version;
+		event->nameserver.addr_raw = event->dst.addr_raw;
+		goto output;
+	}
+
 	// Handle nameserver
 	union dnsflags flags;
 	flags.flags = load_half(skb, dns_off + offsetof(struct dnshdr, flags));
@@ -406,6 +496,14 @@ int ig_trace_dns(struct __sk_buff *skb)
 		if (qr == DNS_QR_QUERY && event->pkt_type_raw == OUTGOING) {
 			bpf_map_update_elem(&query_map, &query_key,
 					    &event->timestamp_raw, BPF_NOEXIST);
+
+			// Resolvers retry the truncated queries over TCP with the
+			// same ID
+			if (transport == TCP &&
+			    bpf_map_lookup_elem(&truncated_map, &query_key)) {
+				event->tcp_retry = true;
+				bpf_map_delete_elem(&truncated_map, &query_key);
+			}
 		} else if (flags.qr == DNS_QR_RESP &&
 			   event->pkt_type_raw == HOST) {
 			__u64 *query_ts =
@@ -419,9 +517,16 @@ int ig_trace_dns(struct __sk_buff *skb)
 				}
 				bpf_map_delete_elem(&query_map, &query_key);
 			}
+
+			if (transport == UDP && flags.tc) {
+				__u8 one = 1;
+				bpf_map_update_elem(&truncated_map, &query_key,
+						    &one, BPF_ANY);
+			}
 		}
 	}
 
+output:;
 	__u64 skb_len = skb->len;
 	bpf_perf_event_output(skb, &events, skb_len << 32 | BPF_F_CURRENT_CPU,
 			      event, sizeof(*event));
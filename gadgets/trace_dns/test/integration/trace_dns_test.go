@@ -57,6 +57,7 @@ type traceDNSEvent struct {
 	Truncated          bool   `json:"tc"`
 	RecursionDesired   bool   `json:"rd"`
 	RecursionAvailable bool   `json:"ra"`
+	Transport          string `json:"transport"`
 }
 
 const (
@@ -168,13 +169,14 @@ func newTraceDNSStep(t *testing.T, tc testCase) (igtesting.TestStep, []igtesting
 						Addr:    serverIP,
 						Version: 4,
 					},
-					QrRaw:    false,
-					Qr:       "Q",
-					Name:     "fake.test.com.",
-					Qtype:    "A",
-					QtypeRaw: 1,
-					Rcode:    "",
-					PktType:  "OUTGOING",
+					QrRaw:     false,
+					Qr:        "Q",
+					Name:      "fake.test.com.",
+					Qtype:     "A",
+					QtypeRaw:  1,
+					Rcode:     "",
+					PktType:   "OUTGOING",
+					Transport: strings.ToUpper(tc.protocol),
 
 					// Check the existence of the following fields
 					NetNsID:            utils.NormalizedInt,
@@ -214,6 +216,7 @@ func newTraceDNSStep(t *testing.T, tc testCase) (igtesting.TestStep, []igtesting
 					Qtype:     "A",
 					Rcode:     "Success",
 					PktType:   "HOST",
+					Transport: strings.ToUpper(tc.protocol),
 					Addresses: "127.0.0.1",
 
 					// Check the existence of the following fields
@@ -247,13 +250,14 @@ func newTraceDNSStep(t *testing.T, tc testCase) (igtesting.TestStep, []igtesting
 						Addr:    serverIP,
 						Version: 4,
 					},
-					QrRaw:    false,
-					Qr:       "Q",
-					Name:     "fake.test.com.",
-					QtypeRaw: 28,
-					Qtype:    "AAAA",
-					Rcode:    "",
-					PktType:  "OUTGOING",
+					QrRaw:     false,
+					Qr:        "Q",
+					Name:      "fake.test.com.",
+					QtypeRaw:  28,
+					Qtype:     "AAAA",
+					Rcode:     "",
+					PktType:   "OUTGOING",
+					Transport: strings.ToUpper(tc.protocol),
 
 					// Check the existence of the following fields
 					NetNsID:            utils.NormalizedInt,
@@ -293,6 +297,7 @@ func newTraceDNSStep(t *testing.T, tc testCase) (igtesting.TestStep, []igtesting
 					Qtype:     "AAAA",
 					Rcode:     "Success",
 					PktType:   "HOST",
+					Transport: strings.ToUpper(tc.protocol),
 					Addresses: "::1",
 
 					// Check the existence of the following fields
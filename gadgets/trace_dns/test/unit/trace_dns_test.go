@@ -24,7 +24,8 @@ func TestTraceDns(t *testing.T) {
 	// TODO: This is a dummy test to check that the gadget runs without errors.
 	// It should be extended to check that the gadget produces the right events.
 	paramValues := map[string]string{
-		"operator.oci.ebpf.paths": "true",
+		"operator.oci.ebpf.paths":     "true",
+		"operator.oci.ebpf.encrypted": "true",
 	}
 	gadgettesting.DummyGadgetTest(t, "trace_dns", gadgettesting.WithParamValues(paramValues))
 }
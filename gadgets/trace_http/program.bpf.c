@@ -0,0 +1,377 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+#include <linux/bpf.h>
+#include <linux/if_ether.h>
+#include <linux/ip.h>
+#include <linux/ipv6.h>
+#include <linux/in.h>
+#include <linux/tcp.h>
+#include <linux/types.h>
+
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_endian.h>
+
+#define GADGET_NO_BUF_RESERVE
+#define GADGET_TYPE_NETWORKING
+#include <gadget/macros.h>
+#include <gadget/buffer.h>
+#include <gadget/types.h>
+#include <gadget/sockets-map.h>
+#include <gadget/filter.h>
+
+// Only the beginning of the segments is parsed, as TCP segments aren't
+// reassembled. It's enough for the request line and the usual headers.
+#define HTTP_MAX_PARSE_LEN 512
+#define HTTP_MAX_PATH_LEN 128
+#define HTTP_MAX_HOST_LEN 64
+#define MAX_ENTRIES 10240
+
+enum http_method : __u8 {
+	UNKNOWN,
+	GET,
+	HEAD,
+	POST,
+	PUT,
+	DELETE,
+	CONNECT,
+	OPTIONS,
+	TRACE,
+	PATCH,
+};
+
+struct event_t {
+	gadget_timestamp timestamp_raw;
+	gadget_netns_id netns_id;
+	struct gadget_process proc;
+
+	// src is the client and dst the server, for requests and responses
+	struct gadget_l4endpoint_t src;
+	struct gadget_l4endpoint_t dst;
+
+	enum http_method method_raw;
+	__u16 status;
+	gadget_duration latency_ns_raw;
+	char host[HTTP_MAX_HOST_LEN];
+	char path[HTTP_MAX_PATH_LEN];
+};
+
+GADGET_TRACER_MAP(events, 1024 * 256);
+GADGET_TRACER(http, events, event_t);
+
+// Requests waiting for their responses. Connections are identified by their
+// network namespace, as the same connection can be seen in the namespaces of
+// the client and the server when both are on the node.
+struct conn_key_t {
+	__u32 netns_id;
+	__u16 client_port;
+	__u16 server_port;
+	__u8 client_addr[16];
+	__u8 server_addr[16];
+};
+
+struct request_t {
+	gadget_timestamp timestamp;
+	enum http_method method;
+	char host[HTTP_MAX_HOST_LEN];
+	char path[HTTP_MAX_PATH_LEN];
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct conn_key_t);
+	__type(value, struct request_t);
+} requests SEC(".maps");
+
+// Too big for the stack
+struct scratch_t {
+	char payload[HTTP_MAX_PARSE_LEN];
+	struct request_t request;
+	struct event_t event;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, struct scratch_t);
+} scratch SEC(".maps");
+
+// has_prefix must be called with a constant prefix, for the loop to be
+// unrolled
+static __always_inline bool has_prefix(const char *buf, __u32 len,
+				       const char *prefix, __u32 prefix_len)
+{
+	if (len < prefix_len)
+		return false;
+
+#pragma unroll
+	for (int i = 0; i < prefix_len; i++) {
+		if (buf[i] != prefix[i])
+			return false;
+	}
+	return true;
+}
+
+#define HAS_PREFIX(buf, len, s) has_prefix(buf, len, s, sizeof(s) - 1)
+
+// parse_method returns the method of the request at the beginning of buf and
+// sets the length of the method and the following space
+static __always_inline enum http_method parse_method(const char *buf,
+						     __u32 len, __u32 *off)
+{
+#define METHOD(m)                                \
+	if (HAS_PREFIX(buf, len, #m " ")) {      \
+		*off = sizeof(#m);               \
+		return m;                        \
+	}
+
+	METHOD(GET)
+	METHOD(HEAD)
+	METHOD(POST)
+	METHOD(PUT)
+	METHOD(DELETE)
+	METHOD(CONNECT)
+	METHOD(OPTIONS)
+	METHOD(TRACE)
+	METHOD(PATCH)
+#undef METHOD
+
+	return UNKNOWN;
+}
+
+static __always_inline char to_lower(char c)
+{
+	if (c >= 'A' && c <= 'Z')
+		return c + ('a' - 'A');
+	return c;
+}
+
+// parse_request fills req with the request at the beginning of buf. It
+// returns false if buf doesn't contain the request line of a HTTP/1.x
+// request.
+static __always_inline bool parse_request(const char *buf, __u32 len,
+					  struct request_t *req)
+{
+	__u32 off = 0, host_off = 0;
+	int i;
+
+	req->method = parse_method(buf, len, &off);
+	if (req->method == UNKNOWN)
+		return false;
+
+	// The request target ends with a space, followed by the version
+	for (i = 0; i < HTTP_MAX_PATH_LEN - 1; i++) {
+		__u32 j = off + i;
+
+		if (j >= len || j >= HTTP_MAX_PARSE_LEN)
+			break;
+		if (buf[j] == ' ' || buf[j] == '\r')
+			break;
+		req->path[i] = buf[j];
+	}
+	req->path[i & (HTTP_MAX_PATH_LEN - 1)] = '\0';
+
+	// Long paths are truncated, the version isn't checked then
+	off += i;
+	if (i < HTTP_MAX_PATH_LEN - 1 &&
+	    (off > HTTP_MAX_PARSE_LEN - 8 ||
+	     !HAS_PREFIX(&buf[off], len - off, " HTTP/1.")))
+		return false;
+
+	// Look for the Host header, whose name is case-insensitive
+	for (i = off; i < HTTP_MAX_PARSE_LEN - 8; i++) {
+		if (i + 8 > len)
+			break;
+		if (buf[i] == '\r' && buf[i + 1] == '\n' &&
+		    to_lower(buf[i + 2]) == 'h' && to_lower(buf[i + 3]) == 'o' &&
+		    to_lower(buf[i + 4]) == 's' && to_lower(buf[i + 5]) == 't' &&
+		    buf[i + 6] == ':') {
+			host_off = i + 7;
+			break;
+		}
+	}
+
+	req->host[0] = '\0';
+	if (!host_off)
+		return true;
+
+	if (host_off < len && host_off < HTTP_MAX_PARSE_LEN &&
+	    buf[host_off] == ' ')
+		host_off++;
+	for (i = 0; i < HTTP_MAX_HOST_LEN - 1; i++) {
+		__u32 j = host_off + i;
+
+		if (j >= len || j >= HTTP_MAX_PARSE_LEN)
+			break;
+		if (buf[j] == '\r' || buf[j] == ' ')
+			break;
+		req->host[i] = buf[j];
+	}
+	req->host[i & (HTTP_MAX_HOST_LEN - 1)] = '\0';
+
+	return true;
+}
+
+// parse_status returns the status code of the response at the beginning of
+// buf, or 0 if it doesn't contain the status line of a HTTP/1.x response
+static __always_inline __u16 parse_status(const char *buf, __u32 len)
+{
+	__u16 status = 0;
+
+	// "HTTP/1.1 200 OK"
+	if (!HAS_PREFIX(buf, len, "HTTP/1.") || len < 13 || buf[8] != ' ')
+		return 0;
+
+#pragma unroll
+	for (int i = 9; i < 12; i++) {
+		if (buf[i] < '0' || buf[i] > '9')
+			return 0;
+		status = status * 10 + (buf[i] - '0');
+	}
+	return status;
+}
+
+SEC("socket1")
+int ig_trace_http(struct __sk_buff *skb)
+{
+	struct gadget_l4endpoint_t src = {}, dst = {};
+	struct conn_key_t key = {};
+	struct scratch_t *s;
+	struct request_t *req;
+	struct event_t *event;
+	__u32 zero = 0;
+	__u32 l4_off, payload_off, len;
+	__u16 h_proto, status;
+	__u8 proto;
+
+	if (bpf_skb_load_bytes(skb, offsetof(struct ethhdr, h_proto), &h_proto,
+			       sizeof(h_proto)))
+		return 0;
+
+	switch (bpf_ntohs(h_proto)) {
+	case ETH_P_IP: {
+		struct iphdr iph;
+
+		if (bpf_skb_load_bytes(skb, ETH_HLEN, &iph, sizeof(iph)))
+			return 0;
+		proto = iph.protocol;
+		// An IPv4 header doesn't have a fixed size. The IHL field of a packet
+		// represents the size of the IP header in 32-bit words, so we need to
+		// multiply this value by 4 to get the header size in bytes.
+		l4_off = ETH_HLEN + iph.ihl * 4;
+		src.version = dst.version = 4;
+		src.addr_raw.v4 = iph.saddr;
+		dst.addr_raw.v4 = iph.daddr;
+		break;
+	}
+	case ETH_P_IPV6: {
+		struct ipv6hdr ip6h;
+
+		if (bpf_skb_load_bytes(skb, ETH_HLEN, &ip6h, sizeof(ip6h)))
+			return 0;
+		// Extension headers aren't supported
+		proto = ip6h.nexthdr;
+		l4_off = ETH_HLEN + sizeof(ip6h);
+		src.version = dst.version = 6;
+		__builtin_memcpy(src.addr_raw.v6, &ip6h.saddr,
+				 sizeof(src.addr_raw.v6));
+		__builtin_memcpy(dst.addr_raw.v6, &ip6h.daddr,
+				 sizeof(dst.addr_raw.v6));
+		break;
+	}
+	default:
+		return 0;
+	}
+
+	if (proto != IPPROTO_TCP)
+		return 0;
+
+	struct tcphdr tcph;
+	if (bpf_skb_load_bytes(skb, l4_off, &tcph, sizeof(tcph)))
+		return 0;
+
+	// The data offset field in the header is specified in 32-bit words. We
+	// have to multiply this value by 4 to get the TCP header length in bytes.
+	payload_off = l4_off + tcph.doff * 4;
+	if (skb->len <= payload_off)
+		return 0;
+	len = skb->len - payload_off;
+	if (len > HTTP_MAX_PARSE_LEN)
+		len = HTTP_MAX_PARSE_LEN;
+
+	src.proto_raw = dst.proto_raw = IPPROTO_TCP;
+	src.port = bpf_ntohs(tcph.source);
+	dst.port = bpf_ntohs(tcph.dest);
+
+	s = bpf_map_lookup_elem(&scratch, &zero);
+	if (!s)
+		return 0; // it never happens
+
+	if (bpf_skb_load_bytes(skb, payload_off, s->payload, len))
+		return 0;
+
+	key.netns_id = skb->cb[0]; // cb[0] initialized by dispatcher.bpf.c
+
+	status = parse_status(s->payload, len);
+	if (!status) {
+		// Requests are only saved, the events are sent with the responses
+		if (!parse_request(s->payload, len, &s->request))
+			return 0;
+
+		struct gadget_socket_value *skb_val = gadget_socket_lookup(skb);
+		if (gadget_should_discard_data_by_skb(skb_val))
+			return 0;
+
+		key.client_port = src.port;
+		key.server_port = dst.port;
+		__builtin_memcpy(key.client_addr, &src.addr_raw,
+				 sizeof(key.client_addr));
+		__builtin_memcpy(key.server_addr, &dst.addr_raw,
+				 sizeof(key.server_addr));
+		s->request.timestamp = bpf_ktime_get_boot_ns();
+		bpf_map_update_elem(&requests, &key, &s->request, BPF_ANY);
+		return 0;
+	}
+
+	// The response goes from the server to the client
+	key.client_port = dst.port;
+	key.server_port = src.port;
+	__builtin_memcpy(key.client_addr, &dst.addr_raw,
+			 sizeof(key.client_addr));
+	__builtin_memcpy(key.server_addr, &src.addr_raw,
+			 sizeof(key.server_addr));
+
+	req = bpf_map_lookup_elem(&requests, &key);
+	if (!req)
+		return 0;
+
+	// Interim responses, like "100 Continue", are followed by the final one
+	if (status < 200)
+		return 0;
+
+	event = &s->event;
+	__builtin_memset(event, 0, sizeof(*event));
+	event->timestamp_raw = bpf_ktime_get_boot_ns();
+	event->netns_id = key.netns_id;
+	event->src = dst;
+	event->dst = src;
+	event->method_raw = req->method;
+	event->status = status;
+	event->latency_ns_raw = event->timestamp_raw - req->timestamp;
+	__builtin_memcpy(event->host, req->host, sizeof(event->host));
+	__builtin_memcpy(event->path, req->path, sizeof(event->path));
+
+	bpf_map_delete_elem(&requests, &key);
+
+	// Enrich event with process metadata
+	struct gadget_socket_value *skb_val = gadget_socket_lookup(skb);
+	gadget_process_populate_from_socket(skb_val, &event->proc);
+
+	gadget_output_buf(skb, &events, event, sizeof(*event));
+
+	return 0;
+}
+
+char _license[] SEC("license") = "GPL";
@@ -0,0 +1,141 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/match"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+type traceHTTPEvent struct {
+	utils.CommonData
+
+	Timestamp string `json:"timestamp"`
+	NetNs     uint64 `json:"netns_id"`
+
+	// The process is the client or the server depending on the copy of the
+	// loopback packets seen first, so it isn't checked
+	Src utils.L4Endpoint `json:"src"`
+	Dst utils.L4Endpoint `json:"dst"`
+
+	Method    string `json:"method"`
+	Host      string `json:"host"`
+	Path      string `json:"path"`
+	Status    uint16 `json:"status"`
+	LatencyNs uint64 `json:"latency_ns_raw"`
+}
+
+func TestTraceHTTP(t *testing.T) {
+	gadgettesting.RequireEnvironmentVariables(t)
+	utils.InitTest(t)
+
+	containerFactory, err := containers.NewContainerFactory(utils.Runtime)
+	require.NoError(t, err, "new container factory")
+	containerName := "test-trace-http"
+	containerImage := gadgettesting.NginxImage
+
+	var ns string
+	containerOpts := []containers.ContainerOption{containers.WithContainerImage(containerImage)}
+
+	if utils.CurrentTestComponent == utils.KubectlGadgetTestComponent {
+		ns = utils.GenerateTestNamespaceName(t, "test-trace-http")
+		containerOpts = append(containerOpts, containers.WithContainerNamespace(ns))
+	}
+
+	testContainer := containerFactory.NewContainer(
+		containerName,
+		"nginx && while true; do curl -s -o /dev/null http://127.0.0.1/; sleep 1; done",
+		containerOpts...,
+	)
+
+	testContainer.Start(t)
+	t.Cleanup(func() {
+		testContainer.Stop(t)
+	})
+
+	var runnerOpts []igrunner.Option
+	var testingOpts []igtesting.Option
+	commonDataOpts := []utils.CommonDataOption{utils.WithContainerImageName(containerImage), utils.WithContainerID(testContainer.ID())}
+
+	switch utils.CurrentTestComponent {
+	case utils.IgLocalTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-r=%s", utils.Runtime), fmt.Sprintf("-c=%s", containerName), "--timeout=5"))
+	case utils.KubectlGadgetTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-n=%s", ns), "--timeout=5"))
+		testingOpts = append(testingOpts, igtesting.WithCbBeforeCleanup(utils.PrintLogsFn(ns)))
+		commonDataOpts = append(commonDataOpts, utils.WithK8sNamespace(ns))
+	}
+
+	runnerOpts = append(runnerOpts, igrunner.WithValidateOutput(
+		func(t *testing.T, output string) {
+			expectedEntry := &traceHTTPEvent{
+				CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+				Src: utils.L4Endpoint{
+					Addr:    "127.0.0.1",
+					Version: 4,
+					Port:    utils.NormalizedInt,
+					Proto:   "TCP",
+				},
+				Dst: utils.L4Endpoint{
+					Addr:    "127.0.0.1",
+					Version: 4,
+					Port:    80,
+					Proto:   "TCP",
+				},
+				Method: "GET",
+				Host:   "127.0.0.1",
+				Path:   "/",
+				Status: 200,
+
+				// Check the existence of the following fields
+				Timestamp: utils.NormalizedStr,
+				NetNs:     utils.NormalizedInt,
+				LatencyNs: utils.NormalizedInt,
+			}
+
+			if utils.CurrentTestComponent == utils.KubectlGadgetTestComponent {
+				expectedEntry.Src.K8s = utils.K8s{
+					Kind: "raw",
+				}
+				expectedEntry.Dst.K8s = expectedEntry.Src.K8s
+			}
+
+			normalize := func(e *traceHTTPEvent) {
+				utils.NormalizeCommonData(&e.CommonData)
+				utils.NormalizeString(&e.Timestamp)
+				utils.NormalizeInt(&e.NetNs)
+				utils.NormalizeInt(&e.LatencyNs)
+				utils.NormalizeEndpoint(&e.Src)
+				utils.NormalizeEndpoint(&e.Dst)
+				utils.NormalizeInt(&e.Src.Port)
+			}
+
+			match.MatchEntries(t, match.JSONMultiObjectMode, output, normalize, expectedEntry)
+		},
+	))
+
+	traceHTTPCmd := igrunner.New("trace_http", runnerOpts...)
+
+	igtesting.RunTestSteps([]igtesting.TestStep{traceHTTPCmd}, t, testingOpts...)
+}
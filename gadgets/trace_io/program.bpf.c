@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include <gadget/bits.bpf.h>
+#include <gadget/core_fixes.bpf.h>
+#include <gadget/filter.h>
+#include <gadget/macros.h>
+#include <gadget/mntns.h>
+#include <gadget/types.h>
+
+#define MAX_SLOTS 27
+#define MAX_ENTRIES 10240
+#define DISK_NAME_LEN 32
+
+#define REQ_OP_BITS 8
+#define REQ_OP_MASK ((1 << REQ_OP_BITS) - 1)
+
+// Include the time the requests spend in the OS queue
+const volatile bool targ_queued = false;
+GADGET_PARAM(targ_queued);
+
+enum rw_type : u8 {
+	read,
+	write,
+};
+
+struct hist_key {
+	gadget_mntns_id mntns_id;
+	char disk[DISK_NAME_LEN];
+	__u32 major;
+	__u32 minor;
+	enum rw_type rw_raw;
+};
+
+struct hist_value {
+	gadget_histogram_slot__u32 latency[MAX_SLOTS];
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct hist_key);
+	__type(value, struct hist_value);
+} hists SEC(".maps");
+
+GADGET_MAPITER(iolatency, hists);
+
+// The completion of the requests runs in interrupt context, the container is
+// the one of the process that inserted or issued the request.
+struct start_t {
+	__u64 ts;
+	gadget_mntns_id mntns_id;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct request *);
+	__type(value, struct start_t);
+} starts SEC(".maps");
+
+static const struct hist_value initial_hist = {};
+
+static __always_inline int trace_rq_start(struct request *rq, bool issue)
+{
+	struct start_t *startp, start = {};
+
+	startp = bpf_map_lookup_elem(&starts, &rq);
+	if (startp) {
+		// The request was inserted in the queue before, keep its container
+		if (issue && !targ_queued)
+			startp->ts = bpf_ktime_get_ns();
+		return 0;
+	}
+
+	// Requests can be issued without being inserted in the queue before,
+	// when there isn't an I/O scheduler
+	if (gadget_should_discard_data_current())
+		return 0;
+
+	start.ts = bpf_ktime_get_ns();
+	start.mntns_id = gadget_get_current_mntns_id();
+	bpf_map_update_elem(&starts, &rq, &start, BPF_ANY);
+	return 0;
+}
+
+// See profile_blockio for the prototypes of the tracepoints before and after
+// https://github.com/torvalds/linux/commit/a54895fa057c67700270777f7661d8d3c7fda88a
+struct request___empty {};
+struct request_queue___empty {};
+
+typedef void (*btf_trace_block_rq_insert___new)(void *,
+						struct request___empty *);
+typedef void (*btf_trace_block_rq_insert___old)(void *,
+						struct request_queue___empty *q,
+						struct request___empty *);
+typedef void (*btf_trace_block_rq_issue___new)(void *,
+					       struct request___empty *);
+typedef void (*btf_trace_block_rq_issue___old)(void *,
+					       struct request_queue___empty *q,
+					       struct request___empty *);
+
+SEC("raw_tp/block_rq_insert")
+int ig_io_ins(u64 *ctx)
+{
+	if (bpf_core_type_matches(btf_trace_block_rq_insert___new)) {
+		// After commit a54895fa (v5.11-rc1)
+		return trace_rq_start((void *)ctx[0], false);
+	} else if (bpf_core_type_matches(btf_trace_block_rq_insert___old)) {
+		// Before commit a54895fa (v5.11-rc1)
+		return trace_rq_start((void *)ctx[1], false);
+	} else {
+		// Couldn't detect block/block_rq_insert tracepoint
+		bpf_core_unreachable();
+		return 0;
+	}
+}
+
+SEC("raw_tp/block_rq_issue")
+int ig_io_iss(u64 *ctx)
+{
+	if (bpf_core_type_matches(btf_trace_block_rq_issue___new)) {
+		// After commit a54895fa (v5.11-rc1)
+		return trace_rq_start((void *)ctx[0], true);
+	} else if (bpf_core_type_matches(btf_trace_block_rq_issue___old)) {
+		// Before commit a54895fa (v5.11-rc1)
+		return trace_rq_start((void *)ctx[1], true);
+	} else {
+		// Couldn't detect block/block_rq_issue tracepoint
+		bpf_core_unreachable();
+		return 0;
+	}
+}
+
+SEC("raw_tp/block_rq_complete")
+int BPF_PROG(ig_io_done, struct request *rq, int error, unsigned int nr_bytes)
+{
+	struct hist_key hkey = {};
+	struct hist_value *histp;
+	struct start_t *startp;
+	struct gendisk *disk;
+	unsigned int req_op;
+	u64 slot;
+	s64 delta;
+
+	startp = bpf_map_lookup_elem(&starts, &rq);
+	if (!startp)
+		return 0;
+
+	delta = (s64)(bpf_ktime_get_ns() - startp->ts);
+	if (delta < 0)
+		goto cleanup;
+
+	// Other operations, like flushes and discards, aren't reported
+	req_op = BPF_CORE_READ(rq, cmd_flags) & REQ_OP_MASK;
+	if (req_op == REQ_OP_READ)
+		hkey.rw_raw = read;
+	else if (req_op == REQ_OP_WRITE)
+		hkey.rw_raw = write;
+	else
+		goto cleanup;
+
+	hkey.mntns_id = startp->mntns_id;
+	disk = get_disk(rq);
+	if (disk) {
+		hkey.major = BPF_CORE_READ(disk, major);
+		hkey.minor = BPF_CORE_READ(disk, first_minor);
+		BPF_CORE_READ_STR_INTO(&hkey.disk, disk, disk_name);
+	}
+
+	histp = bpf_map_lookup_elem(&hists, &hkey);
+	if (!histp) {
+		bpf_map_update_elem(&hists, &hkey, &initial_hist, BPF_NOEXIST);
+		histp = bpf_map_lookup_elem(&hists, &hkey);
+		if (!histp)
+			goto cleanup;
+	}
+
+	slot = log2l(delta / 1000U);
+	if (slot >= MAX_SLOTS)
+		slot = MAX_SLOTS - 1;
+	__sync_fetch_and_add(&histp->latency[slot], 1);
+
+cleanup:
+	bpf_map_delete_elem(&starts, &rq);
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
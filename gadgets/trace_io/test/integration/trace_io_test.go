@@ -0,0 +1,86 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/match"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+// traceIoEntry is the histogram rendered by the otel-metrics operator
+type traceIoEntry struct {
+	Text string `json:"text"`
+}
+
+func TestTraceIo(t *testing.T) {
+	gadgettesting.RequireEnvironmentVariables(t)
+	utils.InitTest(t)
+
+	// The histograms are only printed when the gadget runs locally
+	if utils.CurrentTestComponent != utils.IgLocalTestComponent {
+		t.Skipf("Skipping test as histograms are only printed by ig")
+	}
+
+	containerFactory, err := containers.NewContainerFactory(utils.Runtime)
+	require.NoError(t, err, "new container factory")
+	containerName := "test-trace-io"
+	containerImage := gadgettesting.BusyBoxImage
+
+	// fsync() submits the writes from the context of dd, the writeback of
+	// the kernel threads isn't attributed to the container
+	testContainer := containerFactory.NewContainer(
+		containerName,
+		"while true; do dd if=/dev/zero of=/tmp/file bs=64k count=16 conv=fsync; sleep 0.5; done",
+		containers.WithContainerImage(containerImage),
+	)
+
+	testContainer.Start(t)
+	t.Cleanup(func() {
+		testContainer.Stop(t)
+	})
+
+	runnerOpts := []igrunner.Option{
+		igrunner.WithFlags(
+			fmt.Sprintf("-r=%s", utils.Runtime),
+			fmt.Sprintf("-c=%s", containerName),
+		),
+		igrunner.WithStartAndStop(),
+		igrunner.WithValidateOutput(
+			func(t *testing.T, output string) {
+				expectedEntry := match.WithFields(
+					&traceIoEntry{},
+					match.Fields{
+						"text": match.Regexp(`(?s)^latency\n.*\brw=write\b`),
+					},
+				)
+
+				match.MatchEntriesWithFields(t, match.JSONMultiObjectMode, output, nil, expectedEntry)
+			},
+		),
+	}
+
+	traceIoCmd := igrunner.New("trace_io", runnerOpts...)
+
+	igtesting.RunTestSteps([]igtesting.TestStep{traceIoCmd}, t)
+}
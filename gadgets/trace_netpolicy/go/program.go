@@ -0,0 +1,50 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	api "github.com/inspektor-gadget/inspektor-gadget/wasmapi/go"
+)
+
+// tables are the functions evaluating the chains, with the programs attached
+// to them. The modules providing them are only loaded when they are used.
+var tables = []struct {
+	symbol   string
+	programs []string
+}{
+	{"nft_do_chain", []string{"ig_nft_chain_e", "ig_nft_chain_x"}},
+	{"ipt_do_table", []string{"ig_ipt_table_e", "ig_ipt_table_x"}},
+	{"ip6t_do_table", []string{"ig_ip6t_table_e", "ig_ip6t_table_x"}},
+}
+
+//go:wasmexport gadgetPreStart
+func gadgetPreStart() int32 {
+	found := false
+	for _, t := range tables {
+		if api.KallsymsSymbolExists(t.symbol) {
+			found = true
+			continue
+		}
+		for _, p := range t.programs {
+			api.SetConfig("programs."+p+".attach_to", "gadget_program_disabled")
+		}
+	}
+	if !found {
+		api.Warnf("neither nf_tables nor ip_tables are loaded: drops by netfilter won't be attributed to a table")
+	}
+	return 0
+}
+
+func main() {}
@@ -0,0 +1,390 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+#include <bpf/bpf_endian.h>
+
+#include <gadget/buffer.h>
+#include <gadget/macros.h>
+#include <gadget/mntns_filter.h>
+#include <gadget/types.h>
+
+#define GADGET_TYPE_TRACING
+#include <gadget/sockets-map.h>
+
+#define TABLE_LEN 32
+#define CHAIN_LEN 64
+#define MAX_ENTRIES 10240
+
+#define NF_DROP 0
+#define NF_VERDICT_MASK 0x000000ff
+
+// Component that dropped the packet
+enum drop_source {
+	IPTABLES,
+	NFTABLES,
+	// Netfilter hook other than an iptables or nftables chain, like conntrack
+	NETFILTER,
+	BPF_CGROUP,
+	TC_INGRESS,
+	TC_EGRESS,
+};
+
+struct event {
+	gadget_timestamp timestamp_raw;
+	gadget_netns_id netns_id;
+
+	struct gadget_l4endpoint_t src;
+	struct gadget_l4endpoint_t dst;
+
+	// Process owning the local socket of the packet, if any
+	gadget_mntns_id mntns_id;
+	gadget_comm comm[TASK_COMM_LEN];
+	// user-space terminology for pid and tid
+	gadget_pid pid;
+	gadget_tid tid;
+	gadget_uid uid;
+	gadget_gid gid;
+
+	enum drop_source source_raw;
+	char table[TABLE_LEN];
+	char chain[CHAIN_LEN];
+};
+
+GADGET_TRACER_MAP(events, 1024 * 256);
+GADGET_TRACER(netpolicy, events, event);
+
+// The nftables types are defined in the nf_tables module, they aren't part
+// of vmlinux.h
+struct nft_pktinfo___ig {
+	struct sk_buff *skb;
+	const struct nf_hook_state *state;
+} __attribute__((preserve_access_index));
+
+struct nft_table___ig {
+	char *name;
+} __attribute__((preserve_access_index));
+
+struct nft_chain___ig {
+	struct nft_table___ig *table;
+	char *name;
+} __attribute__((preserve_access_index));
+
+struct nft_base_chain___ig {
+	struct nft_chain___ig chain;
+} __attribute__((preserve_access_index));
+
+struct verdict {
+	enum drop_source source;
+	char table[TABLE_LEN];
+	char chain[CHAIN_LEN];
+};
+
+struct call {
+	struct sk_buff *skb;
+	struct verdict verdict;
+};
+
+// Chains being evaluated, per thread
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, __u64);
+	__type(value, struct call);
+} calls SEC(".maps");
+
+// Chains that dropped the packets, until they are freed
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct sk_buff *);
+	__type(value, struct verdict);
+} verdicts SEC(".maps");
+
+extern int LINUX_KERNEL_VERSION __kconfig;
+
+// xt_hook_chain copies the name of the iptables built-in chain of a hook
+static __always_inline void xt_hook_chain(char *chain, u8 hook)
+{
+	switch (hook) {
+	case NF_INET_PRE_ROUTING:
+		__builtin_memcpy(chain, "PREROUTING", sizeof("PREROUTING"));
+		break;
+	case NF_INET_LOCAL_IN:
+		__builtin_memcpy(chain, "INPUT", sizeof("INPUT"));
+		break;
+	case NF_INET_FORWARD:
+		__builtin_memcpy(chain, "FORWARD", sizeof("FORWARD"));
+		break;
+	case NF_INET_LOCAL_OUT:
+		__builtin_memcpy(chain, "OUTPUT", sizeof("OUTPUT"));
+		break;
+	case NF_INET_POST_ROUTING:
+		__builtin_memcpy(chain, "POSTROUTING", sizeof("POSTROUTING"));
+		break;
+	}
+}
+
+static __always_inline int xt_do_table_enter(struct pt_regs *ctx)
+{
+	__u64 pid_tgid = bpf_get_current_pid_tgid();
+	const struct nf_hook_state *state;
+	struct xt_table *table;
+	struct call call = {};
+
+	// Linux 5.16 turned ipt_do_table and ip6t_do_table into hook functions,
+	// taking the table first
+	if (LINUX_KERNEL_VERSION >= KERNEL_VERSION(5, 16, 0)) {
+		table = (struct xt_table *)PT_REGS_PARM1(ctx);
+		call.skb = (struct sk_buff *)PT_REGS_PARM2(ctx);
+		state = (const struct nf_hook_state *)PT_REGS_PARM3(ctx);
+	} else {
+		call.skb = (struct sk_buff *)PT_REGS_PARM1(ctx);
+		state = (const struct nf_hook_state *)PT_REGS_PARM2(ctx);
+		table = (struct xt_table *)PT_REGS_PARM3(ctx);
+	}
+
+	call.verdict.source = IPTABLES;
+	bpf_probe_read_kernel_str(call.verdict.table,
+				  sizeof(call.verdict.table), table->name);
+	xt_hook_chain(call.verdict.chain, BPF_CORE_READ(state, hook));
+
+	bpf_map_update_elem(&calls, &pid_tgid, &call, BPF_ANY);
+	return 0;
+}
+
+static __always_inline int chain_exit(unsigned int ret)
+{
+	__u64 pid_tgid = bpf_get_current_pid_tgid();
+	struct call *call;
+
+	call = bpf_map_lookup_elem(&calls, &pid_tgid);
+	if (!call)
+		return 0;
+
+	if ((ret & NF_VERDICT_MASK) == NF_DROP)
+		bpf_map_update_elem(&verdicts, &call->skb, &call->verdict,
+				    BPF_ANY);
+
+	bpf_map_delete_elem(&calls, &pid_tgid);
+	return 0;
+}
+
+SEC("kprobe/nft_do_chain")
+int BPF_KPROBE(ig_nft_chain_e, struct nft_pktinfo___ig *pkt, void *priv)
+{
+	struct nft_base_chain___ig *basechain = priv;
+	__u64 pid_tgid = bpf_get_current_pid_tgid();
+	struct nft_chain___ig *chain;
+	struct call call = {};
+
+	// This program is loaded even if it's disabled because nf_tables isn't
+	if (!bpf_core_type_exists(struct nft_base_chain___ig))
+		return 0;
+
+	chain = &basechain->chain;
+	call.skb = BPF_CORE_READ(pkt, skb);
+	call.verdict.source = NFTABLES;
+	bpf_probe_read_kernel_str(call.verdict.table,
+				  sizeof(call.verdict.table),
+				  BPF_CORE_READ(chain, table, name));
+	bpf_probe_read_kernel_str(call.verdict.chain,
+				  sizeof(call.verdict.chain),
+				  BPF_CORE_READ(chain, name));
+
+	bpf_map_update_elem(&calls, &pid_tgid, &call, BPF_ANY);
+	return 0;
+}
+
+SEC("kretprobe/nft_do_chain")
+int BPF_KRETPROBE(ig_nft_chain_x, unsigned int ret)
+{
+	return chain_exit(ret);
+}
+
+SEC("kprobe/ipt_do_table")
+int BPF_KPROBE(ig_ipt_table_e)
+{
+	return xt_do_table_enter(ctx);
+}
+
+SEC("kretprobe/ipt_do_table")
+int BPF_KRETPROBE(ig_ipt_table_x, unsigned int ret)
+{
+	return chain_exit(ret);
+}
+
+SEC("kprobe/ip6t_do_table")
+int BPF_KPROBE(ig_ip6t_table_e)
+{
+	return xt_do_table_enter(ctx);
+}
+
+SEC("kretprobe/ip6t_do_table")
+int BPF_KRETPROBE(ig_ip6t_table_x, unsigned int ret)
+{
+	return chain_exit(ret);
+}
+
+// fill_endpoints reads the addresses and ports from the headers of the packet
+static __always_inline int fill_endpoints(struct event *event,
+					  struct sk_buff *skb)
+{
+	unsigned char *head = BPF_CORE_READ(skb, head);
+	__u16 offset = BPF_CORE_READ(skb, network_header);
+	struct ipv6hdr ip6h;
+	struct iphdr iph;
+	__be16 ports[2];
+	__u8 proto;
+
+	// The network header isn't set
+	if (offset == (__u16)~0U)
+		return -1;
+
+	if (bpf_probe_read_kernel(&iph, sizeof(iph), head + offset))
+		return -1;
+
+	switch (iph.version) {
+	case 4:
+		event->src.version = event->dst.version = 4;
+		event->src.addr_raw.v4 = iph.saddr;
+		event->dst.addr_raw.v4 = iph.daddr;
+		proto = iph.protocol;
+		offset += iph.ihl * 4;
+		break;
+	case 6:
+		if (bpf_probe_read_kernel(&ip6h, sizeof(ip6h), head + offset))
+			return -1;
+		event->src.version = event->dst.version = 6;
+		__builtin_memcpy(event->src.addr_raw.v6, &ip6h.saddr,
+				 sizeof(event->src.addr_raw.v6));
+		__builtin_memcpy(event->dst.addr_raw.v6, &ip6h.daddr,
+				 sizeof(event->dst.addr_raw.v6));
+		// Extension headers aren't parsed
+		proto = ip6h.nexthdr;
+		offset += sizeof(ip6h);
+		break;
+	default:
+		return -1;
+	}
+
+	event->src.proto_raw = event->dst.proto_raw = proto;
+
+	switch (proto) {
+	case IPPROTO_TCP:
+	case IPPROTO_UDP:
+	case IPPROTO_SCTP:
+		if (bpf_probe_read_kernel(ports, sizeof(ports), head + offset))
+			break;
+		event->src.port = bpf_ntohs(ports[0]);
+		event->dst.port = bpf_ntohs(ports[1]);
+		break;
+	}
+
+	return 0;
+}
+
+// drop_source_of returns whether the reason is a drop by a policy, and which
+// component dropped the packet
+static __always_inline bool drop_source_of(int reason,
+					   enum drop_source *source)
+{
+	if (reason == bpf_core_enum_value(enum skb_drop_reason,
+					  SKB_DROP_REASON_NETFILTER_DROP)) {
+		*source = NETFILTER;
+		return true;
+	}
+	if (bpf_core_enum_value_exists(enum skb_drop_reason,
+				       SKB_DROP_REASON_BPF_CGROUP_EGRESS) &&
+	    reason == bpf_core_enum_value(enum skb_drop_reason,
+					  SKB_DROP_REASON_BPF_CGROUP_EGRESS)) {
+		*source = BPF_CGROUP;
+		return true;
+	}
+	if (bpf_core_enum_value_exists(enum skb_drop_reason,
+				       SKB_DROP_REASON_TC_INGRESS) &&
+	    reason == bpf_core_enum_value(enum skb_drop_reason,
+					  SKB_DROP_REASON_TC_INGRESS)) {
+		*source = TC_INGRESS;
+		return true;
+	}
+	if (bpf_core_enum_value_exists(enum skb_drop_reason,
+				       SKB_DROP_REASON_TC_EGRESS) &&
+	    reason == bpf_core_enum_value(enum skb_drop_reason,
+					  SKB_DROP_REASON_TC_EGRESS)) {
+		*source = TC_EGRESS;
+		return true;
+	}
+	return false;
+}
+
+SEC("tracepoint/skb/kfree_skb")
+int ig_netpol_drop(struct trace_event_raw_kfree_skb *ctx)
+{
+	struct sk_buff *skb = ctx->skbaddr;
+	struct gadget_socket_value *skb_val = NULL;
+	struct verdict *verdict;
+	enum drop_source source;
+	struct event *event;
+	struct sock *sk;
+
+	if (!drop_source_of(ctx->reason, &source))
+		return 0;
+
+	verdict = bpf_map_lookup_elem(&verdicts, &skb);
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		goto cleanup;
+
+	if (fill_endpoints(event, skb))
+		goto discard;
+
+	sk = BPF_CORE_READ(skb, sk);
+	if (sk) {
+		event->netns_id =
+			BPF_CORE_READ(sk, __sk_common.skc_net.net, ns.inum);
+		skb_val = gadget_socket_lookup(sk, event->netns_id);
+	} else {
+		event->netns_id = BPF_CORE_READ(skb, dev, nd_net.net, ns.inum);
+	}
+
+	if (skb_val != NULL) {
+		// Use the mount namespace of the socket to filter by container
+		if (gadget_should_discard_mntns_id(skb_val->mntns))
+			goto discard;
+
+		event->mntns_id = skb_val->mntns;
+		event->pid = skb_val->pid_tgid >> 32;
+		event->tid = (__u32)skb_val->pid_tgid;
+		__builtin_memcpy(&event->comm, skb_val->task,
+				 sizeof(event->comm));
+		event->uid = (__u32)skb_val->uid_gid;
+		event->gid = (__u32)(skb_val->uid_gid >> 32);
+	}
+
+	event->timestamp_raw = bpf_ktime_get_boot_ns();
+	event->source_raw = source;
+	if (source == NETFILTER && verdict) {
+		event->source_raw = verdict->source;
+		__builtin_memcpy(event->table, verdict->table,
+				 sizeof(event->table));
+		__builtin_memcpy(event->chain, verdict->chain,
+				 sizeof(event->chain));
+	}
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+	goto cleanup;
+
+discard:
+	gadget_discard_buf(event);
+cleanup:
+	if (verdict)
+		bpf_map_delete_elem(&verdicts, &skb);
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,120 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/match"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+type traceNetpolicyEvent struct {
+	utils.CommonData
+
+	Timestamp string `json:"timestamp"`
+	NetNs     uint64 `json:"netns_id"`
+	Comm      string `json:"comm"`
+	Pid       uint32 `json:"pid"`
+
+	Src    utils.L4Endpoint `json:"src"`
+	Dst    utils.L4Endpoint `json:"dst"`
+	Source string           `json:"source"`
+	Table  string           `json:"table"`
+	Chain  string           `json:"chain"`
+}
+
+func TestTraceNetpolicy(t *testing.T) {
+	gadgettesting.RequireEnvironmentVariables(t)
+	utils.InitTest(t)
+
+	if utils.CurrentTestComponent == utils.KubectlGadgetTestComponent {
+		t.Skip("Skipping test as K8s doesn't support privileged containers")
+	} else if utils.CurrentTestComponent == utils.IgLocalTestComponent && utils.Runtime == "containerd" {
+		t.Skip("Skipping test as containerd test utils can't use the network")
+	}
+
+	containerFactory, err := containers.NewContainerFactory(utils.Runtime)
+	require.NoError(t, err, "new container factory")
+	containerName := "test-trace-netpolicy"
+	containerImage := gadgettesting.NetworkMultitoolImage
+
+	// run the container with privileged mode to be able to use tc command
+	containerOpts := []containers.ContainerOption{containers.WithContainerImage(containerImage), containers.WithPrivileged()}
+
+	// drop all the egress packets with tc, the connections never leave the
+	// container
+	cmds := "tc qdisc add dev eth0 clsact && tc filter add dev eth0 egress matchall action drop && while true; do wget -T 1 -q -O /dev/null http://1.1.1.1; sleep 0.1; done"
+	testContainer := containerFactory.NewContainer(
+		containerName,
+		cmds,
+		containerOpts...,
+	)
+	testContainer.Start(t)
+	t.Cleanup(func() {
+		testContainer.Stop(t)
+	})
+
+	commonDataOpts := []utils.CommonDataOption{utils.WithContainerImageName(containerImage), utils.WithContainerID(testContainer.ID())}
+
+	runnerOpts := []igrunner.Option{
+		igrunner.WithFlags(fmt.Sprintf("-r=%s", utils.Runtime), "--timeout=5"),
+		igrunner.WithValidateOutput(
+			func(t *testing.T, output string) {
+				expectedEntries := &traceNetpolicyEvent{
+					CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+					Comm:       "wget",
+					Pid:        utils.NormalizedInt,
+					Src: utils.L4Endpoint{
+						Addr:    utils.NormalizedStr,
+						Version: 4,
+						Port:    utils.NormalizedInt,
+						Proto:   "TCP",
+					},
+					Dst: utils.L4Endpoint{
+						Addr:    "1.1.1.1",
+						Version: 4,
+						Port:    80,
+						Proto:   "TCP",
+					},
+					Source: "TC_EGRESS",
+
+					Timestamp: utils.NormalizedStr,
+					NetNs:     utils.NormalizedInt,
+				}
+
+				normalize := func(e *traceNetpolicyEvent) {
+					utils.NormalizeCommonData(&e.CommonData)
+					utils.NormalizeString(&e.Timestamp)
+					utils.NormalizeInt(&e.NetNs)
+					utils.NormalizeInt(&e.Pid)
+					utils.NormalizeString(&e.Src.Addr)
+					utils.NormalizeInt(&e.Src.Port)
+				}
+				match.MatchEntries(t, match.JSONMultiObjectMode, output, normalize, expectedEntries)
+			},
+		),
+	}
+	traceNetpolicyCmd := igrunner.New("trace_netpolicy", runnerOpts...)
+
+	igtesting.RunTestSteps([]igtesting.TestStep{traceNetpolicyCmd}, t)
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	api "github.com/inspektor-gadget/inspektor-gadget/wasmapi/go"
+)
+
+type fsConf struct {
+	read  string
+	write string
+	open  string
+	fsync string
+	flush string
+}
+
+var fsConfMap = map[string]fsConf{
+	"nfs": {
+		read:  "nfs_file_read",
+		write: "nfs_file_write",
+		open:  "nfs_file_open",
+		fsync: "nfs_file_fsync",
+		flush: "nfs_file_flush",
+	},
+	// Functions used with the default cache=strict mount option
+	"cifs": {
+		read:  "cifs_strict_readv",
+		write: "cifs_strict_writev",
+		open:  "cifs_open",
+		fsync: "cifs_strict_fsync",
+		flush: "cifs_flush",
+	},
+}
+
+//go:wasmexport gadgetPreStart
+func gadgetPreStart() int32 {
+	value, err := api.GetParamValue("filesystem", 32)
+	if err != nil {
+		api.Errorf("failed to get param value: %s", err)
+		return 1
+	}
+
+	config, ok := fsConfMap[value]
+	if !ok {
+		api.Errorf("filesystem %s not supported", value)
+		return 1
+	}
+
+	api.SetConfig("programs.ig_nfs_read_e.attach_to", config.read)
+	api.SetConfig("programs.ig_nfs_read_x.attach_to", config.read)
+	api.SetConfig("programs.ig_nfs_wr_e.attach_to", config.write)
+	api.SetConfig("programs.ig_nfs_wr_x.attach_to", config.write)
+	api.SetConfig("programs.ig_nfs_open_e.attach_to", config.open)
+	api.SetConfig("programs.ig_nfs_open_x.attach_to", config.open)
+	api.SetConfig("programs.ig_nfs_sync_e.attach_to", config.fsync)
+	api.SetConfig("programs.ig_nfs_sync_x.attach_to", config.fsync)
+	api.SetConfig("programs.ig_nfs_flush_e.attach_to", config.flush)
+	api.SetConfig("programs.ig_nfs_flush_x.attach_to", config.flush)
+
+	return 0
+}
+
+func main() {}
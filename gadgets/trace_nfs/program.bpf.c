@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include <gadget/buffer.h>
+#include <gadget/common.h>
+#include <gadget/filesystem.h>
+#include <gadget/filter.h>
+#include <gadget/macros.h>
+#include <gadget/types.h>
+
+#define SERVER_LEN 128
+#define MAX_ENTRIES 8192
+
+enum nfs_op {
+	READ,
+	WRITE,
+	OPEN,
+	FSYNC,
+	FLUSH,
+};
+
+struct event {
+	gadget_timestamp timestamp_raw;
+	struct gadget_process proc;
+
+	enum nfs_op op_raw;
+	gadget_errno error_raw;
+	gadget_duration latency_raw;
+	__s64 offset;
+	gadget_bytes size_raw;
+	// Source of the mount, like "server:/export" for NFS or
+	// "//server/share" for CIFS
+	char server[SERVER_LEN];
+	char file[GADGET_PATH_MAX];
+};
+
+const volatile __u64 min_lat_ms = 0;
+GADGET_PARAM(min_lat_ms);
+
+GADGET_TRACER_MAP(events, 1024 * 256);
+GADGET_TRACER(nfs, events, event);
+
+struct data_key {
+	gadget_tid tid;
+	// Operations can be nested, like a flush during an open
+	enum nfs_op op;
+};
+
+struct data {
+	__u64 ts;
+	__s64 offset;
+	struct file *file;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct data_key);
+	__type(value, struct data);
+} starts SEC(".maps");
+
+static __always_inline int probe_entry(struct file *file, enum nfs_op op,
+				       __s64 offset)
+{
+	struct data_key key = {
+		.tid = (__u32)bpf_get_current_pid_tgid(),
+		.op = op,
+	};
+	struct data data = {};
+
+	if (!file)
+		return 0;
+
+	if (gadget_should_discard_data_current())
+		return 0;
+
+	data.ts = bpf_ktime_get_ns();
+	data.offset = offset;
+	data.file = file;
+	bpf_map_update_elem(&starts, &key, &data, BPF_ANY);
+	return 0;
+}
+
+static __always_inline int probe_exit(void *ctx, enum nfs_op op, __s64 ret)
+{
+	struct data_key key = {
+		.tid = (__u32)bpf_get_current_pid_tgid(),
+		.op = op,
+	};
+	struct data *datap;
+	struct event *event;
+	struct path f_path;
+	struct mount *mnt;
+	const char *devname;
+	char *c_path;
+	__u64 delta_ns;
+
+	datap = bpf_map_lookup_elem(&starts, &key);
+	if (!datap)
+		return 0;
+
+	delta_ns = bpf_ktime_get_ns() - datap->ts;
+	if (delta_ns < 1000 * 1000 * min_lat_ms)
+		goto cleanup;
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		goto cleanup;
+
+	event->timestamp_raw = bpf_ktime_get_boot_ns();
+	gadget_process_populate(&event->proc);
+	event->op_raw = op;
+	event->latency_raw = delta_ns;
+	event->offset = datap->offset;
+	if (ret < 0) {
+		event->error_raw = -ret;
+		event->size_raw = 0;
+	} else {
+		event->error_raw = 0;
+		event->size_raw = (op == READ || op == WRITE) ? ret : 0;
+	}
+
+	f_path = BPF_CORE_READ(datap->file, f_path);
+	mnt = real_mount(f_path.mnt);
+	devname = BPF_CORE_READ(mnt, mnt_devname);
+	bpf_probe_read_kernel_str(event->server, sizeof(event->server),
+				  devname);
+
+	c_path = get_path_str(&f_path);
+	bpf_probe_read_kernel_str(event->file, sizeof(event->file), c_path);
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+
+cleanup:
+	bpf_map_delete_elem(&starts, &key);
+	return 0;
+}
+
+// The programs are attached to the NFS functions by default, the wasm module
+// changes them according to the filesystem parameter.
+
+SEC("kprobe/nfs_file_read")
+int BPF_KPROBE(ig_nfs_read_e, struct kiocb *iocb)
+{
+	return probe_entry(BPF_CORE_READ(iocb, ki_filp), READ,
+			   BPF_CORE_READ(iocb, ki_pos));
+}
+
+SEC("kretprobe/nfs_file_read")
+int BPF_KRETPROBE(ig_nfs_read_x, ssize_t ret)
+{
+	return probe_exit(ctx, READ, ret);
+}
+
+SEC("kprobe/nfs_file_write")
+int BPF_KPROBE(ig_nfs_wr_e, struct kiocb *iocb)
+{
+	return probe_entry(BPF_CORE_READ(iocb, ki_filp), WRITE,
+			   BPF_CORE_READ(iocb, ki_pos));
+}
+
+SEC("kretprobe/nfs_file_write")
+int BPF_KRETPROBE(ig_nfs_wr_x, ssize_t ret)
+{
+	return probe_exit(ctx, WRITE, ret);
+}
+
+SEC("kprobe/nfs_file_open")
+int BPF_KPROBE(ig_nfs_open_e, struct inode *inode, struct file *file)
+{
+	return probe_entry(file, OPEN, 0);
+}
+
+SEC("kretprobe/nfs_file_open")
+int BPF_KRETPROBE(ig_nfs_open_x, int ret)
+{
+	return probe_exit(ctx, OPEN, ret);
+}
+
+SEC("kprobe/nfs_file_fsync")
+int BPF_KPROBE(ig_nfs_sync_e, struct file *file, loff_t start, loff_t end)
+{
+	return probe_entry(file, FSYNC, start);
+}
+
+SEC("kretprobe/nfs_file_fsync")
+int BPF_KRETPROBE(ig_nfs_sync_x, int ret)
+{
+	return probe_exit(ctx, FSYNC, ret);
+}
+
+// Called when a file descriptor is closed, it writes back the dirty pages
+SEC("kprobe/nfs_file_flush")
+int BPF_KPROBE(ig_nfs_flush_e, struct file *file)
+{
+	return probe_entry(file, FLUSH, 0);
+}
+
+SEC("kretprobe/nfs_file_flush")
+int BPF_KRETPROBE(ig_nfs_flush_x, int ret)
+{
+	return probe_exit(ctx, FLUSH, ret);
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,137 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/match"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+type traceNfsEvent struct {
+	utils.CommonData
+
+	Timestamp string        `json:"timestamp"`
+	Proc      utils.Process `json:"proc"`
+
+	Op         string `json:"op"`
+	Error      string `json:"error"`
+	LatencyRaw uint64 `json:"latency_raw"`
+	SizeRaw    uint64 `json:"size_raw"`
+	Server     string `json:"server"`
+	File       string `json:"file"`
+}
+
+const testFile = "/mnt/nfs/ig-test-trace-nfs"
+
+func TestTraceNfs(t *testing.T) {
+	gadgettesting.RequireEnvironmentVariables(t)
+	utils.InitTest(t)
+
+	// There isn't an NFS server in the test environment, one has to be
+	// provided as IP:/path, like 127.0.0.1:/srv/nfs
+	export := os.Getenv("TEST_NFS_EXPORT")
+	if export == "" {
+		t.Skip("Skipping test as TEST_NFS_EXPORT isn't set")
+	}
+	server, _, ok := strings.Cut(export, ":")
+	require.True(t, ok, "TEST_NFS_EXPORT must be IP:/path, got %q", export)
+
+	// Mounting the export requires a privileged container
+	if utils.CurrentTestComponent != utils.IgLocalTestComponent {
+		t.Skipf("Skipping test as the export is only mounted by ig tests")
+	}
+
+	containerFactory, err := containers.NewContainerFactory(utils.Runtime)
+	require.NoError(t, err, "new container factory")
+	containerName := "test-trace-nfs"
+	containerImage := gadgettesting.BusyBoxImage
+
+	// busybox mount passes the options as they are to the kernel, that
+	// requires the address of the server
+	cmd := fmt.Sprintf(
+		"mkdir -p /mnt/nfs && mount -t nfs4 -o vers=4,addr=%s %s /mnt/nfs && while true; do echo -n foo > %s; sleep 0.5; done",
+		server, export, testFile,
+	)
+	testContainer := containerFactory.NewContainer(containerName, cmd,
+		containers.WithContainerImage(containerImage),
+		containers.WithPrivileged(),
+		containers.WithStartAndStop(),
+	)
+
+	commonDataOpts := []utils.CommonDataOption{
+		utils.WithContainerImageName(containerImage),
+		// The container is started after the tracer, its ID isn't known yet
+		utils.WithContainerID(utils.NormalizedStr),
+	}
+
+	runnerOpts := []igrunner.Option{
+		igrunner.WithFlags(fmt.Sprintf("-r=%s", utils.Runtime)),
+		igrunner.WithValidateOutput(
+			func(t *testing.T, output string) {
+				expectedEntries := []*traceNfsEvent{
+					{
+						CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+						Proc:       utils.BuildProc("sh", 0, 0),
+						Op:         "OPEN",
+						Server:     export,
+						File:       testFile,
+						LatencyRaw: utils.NormalizedInt,
+						Timestamp:  utils.NormalizedStr,
+					},
+					{
+						CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+						Proc:       utils.BuildProc("sh", 0, 0),
+						Op:         "WRITE",
+						SizeRaw:    3,
+						Server:     export,
+						File:       testFile,
+						LatencyRaw: utils.NormalizedInt,
+						Timestamp:  utils.NormalizedStr,
+					},
+				}
+				normalize := func(e *traceNfsEvent) {
+					utils.NormalizeCommonData(&e.CommonData)
+					utils.NormalizeString(&e.Runtime.ContainerID)
+					utils.NormalizeString(&e.Timestamp)
+					utils.NormalizeProc(&e.Proc)
+					utils.NormalizeInt(&e.LatencyRaw)
+				}
+				match.MatchEntries(t, match.JSONMultiObjectMode, output, normalize, expectedEntries...)
+			},
+		),
+		igrunner.WithStartAndStop(),
+	}
+	traceNfsCmd := igrunner.New("trace_nfs", runnerOpts...)
+
+	steps := []igtesting.TestStep{
+		traceNfsCmd,
+		// wait to ensure ig has started
+		utils.Sleep(10 * time.Second),
+		testContainer,
+	}
+	igtesting.RunTestSteps(steps, t)
+}
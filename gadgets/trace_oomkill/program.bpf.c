@@ -13,6 +13,11 @@
 #include <gadget/types.h>
 
 #define TASK_COMM_LEN 16
+#define MEMCG_NAME_LEN 128
+
+#ifndef LONG_MAX
+#define LONG_MAX ((long)(~0UL >> 1))
+#endif
 
 struct event {
 	gadget_timestamp timestamp_raw;
@@ -28,15 +33,65 @@ struct event {
 	gadget_pid tpid;
 	gadget_comm tcomm[TASK_COMM_LEN];
 	__u64 pages;
+	enum oom_constraint constraint_raw;
+	// cgroup whose limit was reached, or the one of the killed process for
+	// the other constraints
+	char memcg[MEMCG_NAME_LEN];
+	gadget_bytes memory_current_raw;
+	// 0 when the cgroup has no limit
+	gadget_bytes memory_max_raw;
 };
 
 GADGET_TRACER_MAP(events, 1024 * 256);
 
 GADGET_TRACER(oomkill, events, event);
 
+static __always_inline __u64 page_size(void)
+{
+	if (bpf_core_enum_value_exists(enum page_size_enum, __PAGE_SIZE))
+		return bpf_core_enum_value(enum page_size_enum, __PAGE_SIZE);
+	return 4096;
+}
+
+static __always_inline struct mem_cgroup *task_memcg(struct task_struct *task)
+{
+	int id = bpf_core_enum_value(enum cgroup_subsys_id, memory_cgrp_id);
+	struct cgroup_subsys_state *css;
+
+	css = BPF_CORE_READ(task, cgroups, subsys[id]);
+	if (!css)
+		return NULL;
+	return container_of(css, struct mem_cgroup, css);
+}
+
+// fill_memcg fills the event with the name and the memory usage and limit of
+// the memory cgroup, like memory.current and memory.max of cgroup v2
+static __always_inline void fill_memcg(struct event *event,
+				       struct mem_cgroup *memcg)
+{
+	__u64 psize = page_size();
+	__u64 max;
+
+	event->memcg[0] = '\0';
+	event->memory_current_raw = 0;
+	event->memory_max_raw = 0;
+	if (!memcg)
+		return;
+
+	bpf_probe_read_kernel_str(event->memcg, sizeof(event->memcg),
+				  BPF_CORE_READ(memcg, css.cgroup, kn, name));
+	event->memory_current_raw =
+		BPF_CORE_READ(memcg, memory.usage.counter) * psize;
+	max = BPF_CORE_READ(memcg, memory.max);
+	// PAGE_COUNTER_MAX means no limit
+	if (max < LONG_MAX / psize)
+		event->memory_max_raw = max * psize;
+}
+
 SEC("kprobe/oom_kill_process")
 int BPF_KPROBE(ig_oom_kill, struct oom_control *oc, const char *message)
 {
+	struct mem_cgroup *memcg;
 	struct event *event;
 	u64 mntns_id;
 
@@ -55,6 +110,11 @@ int BPF_KPROBE(ig_oom_kill, struct oom_control *oc, const char *message)
 			      BPF_CORE_READ(oc, chosen, comm));
 	event->tmntns_id = mntns_id;
 	event->pages = BPF_CORE_READ(oc, totalpages);
+	event->constraint_raw = BPF_CORE_READ(oc, constraint);
+	memcg = BPF_CORE_READ(oc, memcg);
+	if (!memcg)
+		memcg = task_memcg(BPF_CORE_READ(oc, chosen));
+	fill_memcg(event, memcg);
 	event->timestamp_raw = bpf_ktime_get_boot_ns();
 
 	gadget_submit_buf(ctx, &events, event, sizeof(*event));
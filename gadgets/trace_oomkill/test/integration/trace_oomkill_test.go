@@ -36,6 +36,11 @@ type traceOomKillEvent struct {
 	Tpid      uint32        `json:"tpid"`
 	Pages     uint64        `json:"pages"`
 	Timestamp string        `json:"timestamp"`
+
+	Constraint    string `json:"constraint"`
+	Memcg         string `json:"memcg"`
+	MemoryCurrent uint64 `json:"memory_current_raw"`
+	MemoryMax     uint64 `json:"memory_max_raw"`
 }
 
 func TestTraceOomKill(t *testing.T) {
@@ -90,6 +95,13 @@ func TestTraceOomKill(t *testing.T) {
 				Tcomm:      "tail",
 				Tpid:       utils.NormalizedInt,
 				FProc:      utils.BuildProc(utils.NormalizedStr, 0, 0),
+
+				// The limit of the pod or the one of the container can be
+				// reached first, both are 128Mi
+				Constraint:    "CONSTRAINT_MEMCG",
+				Memcg:         utils.NormalizedStr,
+				MemoryCurrent: utils.NormalizedInt,
+				MemoryMax:     128 * 1024 * 1024,
 			}
 			expectedEntry.Runtime.ContainerID = utils.NormalizedStr
 
@@ -104,6 +116,8 @@ func TestTraceOomKill(t *testing.T) {
 				// Normalize from process command, as we do not have any guarantee on which
 				// process will trigger the OOM killer.
 				utils.NormalizeString(&e.FProc.Comm)
+				utils.NormalizeString(&e.Memcg)
+				utils.NormalizeInt(&e.MemoryCurrent)
 			}
 
 			match.MatchEntries(t, match.JSONMultiObjectMode, output, normalize, expectedEntry)
@@ -0,0 +1,260 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include <gadget/buffer.h>
+#include <gadget/common.h>
+#include <gadget/filter.h>
+#include <gadget/macros.h>
+#include <gadget/types.h>
+
+#define NAME_LEN 64
+#define MAX_ENTRIES 10240
+
+#define PTRACE_POKETEXT 4
+#define PTRACE_POKEDATA 5
+#define PTRACE_ATTACH 16
+#define PTRACE_SEIZE 0x4206
+
+// Prefix of the names of the files created by memfd_create()
+#define MEMFD_PREFIX "memfd:"
+#define MEMFD_PREFIX_LEN (sizeof(MEMFD_PREFIX) - 1)
+
+// mnt_ns of the internal mounts of the kernel, ERR_PTR(-EINVAL)
+#define MNT_NS_INTERNAL ((struct mnt_namespace *)-22)
+
+enum injection_type {
+	ATTACH,
+	SEIZE,
+	POKETEXT,
+	POKEDATA,
+	VM_WRITEV,
+	MEMFD_EXEC,
+};
+
+struct event {
+	gadget_timestamp timestamp_raw;
+	// Process doing the injection
+	struct gadget_process proc;
+	// Process being injected, for all types but MEMFD_EXEC
+	struct gadget_process tracee;
+
+	enum injection_type type_raw;
+	gadget_errno error_raw;
+
+	// Address written with POKETEXT and POKEDATA
+	__u64 addr;
+	// Bytes written with VM_WRITEV
+	__u64 size;
+	// Name of the file executed with MEMFD_EXEC
+	char file[NAME_LEN];
+};
+
+GADGET_TRACER_MAP(events, 1024 * 256);
+GADGET_TRACER(ptrace, events, event);
+
+struct call {
+	enum injection_type type;
+	// pid given to the syscall, used if the tracee isn't found
+	__u32 pid;
+	bool found;
+	__u64 addr;
+	struct gadget_process tracee;
+};
+
+// ptrace and process_vm_writev calls in progress, per thread
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, __u32);
+	__type(value, struct call);
+} calls SEC(".maps");
+
+static const struct call empty_call = {};
+
+static __always_inline int enter_call(enum injection_type type, __u32 pid)
+{
+	__u32 tid = (__u32)bpf_get_current_pid_tgid();
+	struct call *call;
+
+	if (bpf_map_update_elem(&calls, &tid, &empty_call, BPF_ANY))
+		return 0;
+
+	call = bpf_map_lookup_elem(&calls, &tid);
+	if (!call)
+		return 0;
+
+	call->type = type;
+	call->pid = pid;
+	return 0;
+}
+
+// found_tracee records the task targeted by the call of the current thread,
+// if any
+static __always_inline struct call *found_tracee(struct task_struct *task)
+{
+	__u32 tid = (__u32)bpf_get_current_pid_tgid();
+	struct call *call;
+
+	call = bpf_map_lookup_elem(&calls, &tid);
+	if (!call)
+		return NULL;
+
+	gadget_process_populate_task(&call->tracee, task);
+	call->found = true;
+	return call;
+}
+
+static __always_inline int exit_call(void *ctx, long ret)
+{
+	__u32 tid = (__u32)bpf_get_current_pid_tgid();
+	struct event *event;
+	struct call *call;
+
+	call = bpf_map_lookup_elem(&calls, &tid);
+	if (!call)
+		return 0;
+
+	// Report the injections from and into the selected processes
+	if (gadget_should_discard_data_current() &&
+	    (!call->found ||
+	     gadget_should_discard_mntns_id(call->tracee.mntns_id)))
+		goto cleanup;
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		goto cleanup;
+
+	event->timestamp_raw = bpf_ktime_get_boot_ns();
+	gadget_process_populate(&event->proc);
+	if (call->found)
+		event->tracee = call->tracee;
+	else
+		event->tracee.pid = call->pid;
+
+	event->type_raw = call->type;
+	event->addr = call->addr;
+	if (ret < 0)
+		event->error_raw = -ret;
+	else if (call->type == VM_WRITEV)
+		event->size = ret;
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+
+cleanup:
+	bpf_map_delete_elem(&calls, &tid);
+	return 0;
+}
+
+SEC("tracepoint/syscalls/sys_enter_ptrace")
+int ig_ptrace_e(struct syscall_trace_enter *ctx)
+{
+	long request = ctx->args[0];
+	__u32 pid = ctx->args[1];
+
+	switch (request) {
+	case PTRACE_ATTACH:
+		return enter_call(ATTACH, pid);
+	case PTRACE_SEIZE:
+		return enter_call(SEIZE, pid);
+	case PTRACE_POKETEXT:
+		return enter_call(POKETEXT, pid);
+	case PTRACE_POKEDATA:
+		return enter_call(POKEDATA, pid);
+	}
+	return 0;
+}
+
+SEC("tracepoint/syscalls/sys_exit_ptrace")
+int ig_ptrace_x(struct syscall_trace_exit *ctx)
+{
+	return exit_call(ctx, ctx->ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_process_vm_writev")
+int ig_vm_writev_e(struct syscall_trace_enter *ctx)
+{
+	return enter_call(VM_WRITEV, ctx->args[0]);
+}
+
+SEC("tracepoint/syscalls/sys_exit_process_vm_writev")
+int ig_vm_writev_x(struct syscall_trace_exit *ctx)
+{
+	return exit_call(ctx, ctx->ret);
+}
+
+// security_ptrace_access_check() is called with the tracee when attaching
+// to it or accessing its memory with process_vm_writev()
+SEC("kprobe/security_ptrace_access_check")
+int BPF_KPROBE(ig_ptrace_check, struct task_struct *child)
+{
+	found_tracee(child);
+	return 0;
+}
+
+// generic_ptrace_pokedata() is called with the tracee for POKETEXT and
+// POKEDATA, which don't check the access again
+SEC("kprobe/generic_ptrace_pokedata")
+int BPF_KPROBE(ig_ptrace_poke, struct task_struct *child, unsigned long addr)
+{
+	struct call *call;
+
+	call = found_tracee(child);
+	if (call)
+		call->addr = addr;
+	return 0;
+}
+
+// security_bprm_check() is called with the file being executed. memfd files
+// aren't linked anywhere, their name starts with MEMFD_PREFIX and they are
+// created on the internal mount of shmem or hugetlbfs, which isn't part of
+// any mount namespace. The latter tells them apart from unlinked files of
+// tmpfs mounts named like them.
+SEC("kprobe/security_bprm_check")
+int BPF_KPROBE(ig_memfd_exec, struct linux_binprm *bprm)
+{
+	char prefix[MEMFD_PREFIX_LEN];
+	struct event *event;
+	struct dentry *dentry;
+	struct vfsmount *vfsmnt;
+	struct mount *mnt;
+	const unsigned char *name;
+
+	if (gadget_should_discard_data_current())
+		return 0;
+
+	vfsmnt = BPF_CORE_READ(bprm, file, f_path.mnt);
+	mnt = container_of(vfsmnt, struct mount, mnt);
+	if (BPF_CORE_READ(mnt, mnt_ns) != MNT_NS_INTERNAL)
+		return 0;
+
+	dentry = BPF_CORE_READ(bprm, file, f_path.dentry);
+	if (BPF_CORE_READ(dentry, d_inode, i_nlink) != 0)
+		return 0;
+
+	name = BPF_CORE_READ(dentry, d_name.name);
+	if (bpf_probe_read_kernel(prefix, sizeof(prefix), name))
+		return 0;
+	for (int i = 0; i < MEMFD_PREFIX_LEN; i++) {
+		if (prefix[i] != MEMFD_PREFIX[i])
+			return 0;
+	}
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		return 0;
+
+	event->timestamp_raw = bpf_ktime_get_boot_ns();
+	gadget_process_populate(&event->proc);
+	event->type_raw = MEMFD_EXEC;
+	bpf_probe_read_kernel_str(event->file, sizeof(event->file), name);
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,200 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/match"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+type tracePtraceEvent struct {
+	utils.CommonData
+
+	Timestamp string        `json:"timestamp"`
+	Proc      utils.Process `json:"proc"`
+	Tracee    utils.Process `json:"tracee"`
+
+	Type  string `json:"type"`
+	Error string `json:"error"`
+	Addr  uint64 `json:"addr"`
+	Size  uint64 `json:"size"`
+	File  string `json:"file"`
+}
+
+// pokeAddr is the address the test program writes to in its child, keep in
+// sync with ADDR in injectProgram
+const pokeAddr = 0x10000000
+
+// injectProgram attaches to a child process, writes to its memory and then
+// executes /bin/true from a memfd file
+const injectProgram = `
+#define _GNU_SOURCE
+
+#include <fcntl.h>
+#include <signal.h>
+#include <stdio.h>
+#include <sys/mman.h>
+#include <sys/ptrace.h>
+#include <sys/sendfile.h>
+#include <sys/stat.h>
+#include <sys/wait.h>
+#include <unistd.h>
+
+#define ADDR ((void *)0x10000000)
+
+int main(void) {
+    if (mmap(ADDR, 4096, PROT_READ | PROT_WRITE, MAP_PRIVATE | MAP_ANONYMOUS | MAP_FIXED, -1, 0) == MAP_FAILED) {
+        perror("mmap");
+        return 1;
+    }
+
+    pid_t child = fork();
+    if (child == 0) {
+        for (;;)
+            pause();
+    }
+
+    if (ptrace(PTRACE_ATTACH, child, NULL, NULL) == -1) {
+        perror("PTRACE_ATTACH");
+        return 1;
+    }
+    waitpid(child, NULL, 0);
+    if (ptrace(PTRACE_POKEDATA, child, ADDR, (void *)42) == -1) {
+        perror("PTRACE_POKEDATA");
+        return 1;
+    }
+    ptrace(PTRACE_DETACH, child, NULL, NULL);
+    kill(child, SIGKILL);
+    waitpid(child, NULL, 0);
+
+    int in = open("/bin/true", O_RDONLY);
+    struct stat st;
+    if (in == -1 || fstat(in, &st) == -1) {
+        perror("/bin/true");
+        return 1;
+    }
+    int fd = memfd_create("payload", 0);
+    if (fd == -1 || sendfile(fd, in, NULL, st.st_size) != st.st_size) {
+        perror("memfd");
+        return 1;
+    }
+    char *args[] = {"true", NULL};
+    fexecve(fd, args, environ);
+    perror("fexecve");
+    return 1;
+}
+`
+
+func TestTracePtrace(t *testing.T) {
+	gadgettesting.RequireEnvironmentVariables(t)
+	utils.InitTest(t)
+
+	containerFactory, err := containers.NewContainerFactory(utils.Runtime)
+	require.NoError(t, err, "new container factory")
+	containerName := "test-trace-ptrace"
+	containerImage := gadgettesting.GccImage
+
+	var ns string
+	containerOpts := []containers.ContainerOption{
+		containers.WithContainerImage(containerImage),
+		containers.WithStartAndStop(),
+	}
+
+	if utils.CurrentTestComponent == utils.KubectlGadgetTestComponent {
+		ns = utils.GenerateTestNamespaceName(t, "test-trace-ptrace")
+		containerOpts = append(containerOpts, containers.WithContainerNamespace(ns))
+	}
+
+	progBase64 := base64.StdEncoding.EncodeToString([]byte(injectProgram))
+	cmd := fmt.Sprintf("echo %s | base64 -d > inject.c && gcc -Wall -o /bin/inject inject.c && while true; do inject; sleep 0.5; done", progBase64)
+	testContainer := containerFactory.NewContainer(containerName, cmd, containerOpts...)
+
+	var runnerOpts []igrunner.Option
+	var testingOpts []igtesting.Option
+	commonDataOpts := []utils.CommonDataOption{
+		utils.WithContainerImageName(containerImage),
+		// The container is started after the tracer, its ID isn't known yet
+		utils.WithContainerID(utils.NormalizedStr),
+	}
+
+	switch utils.CurrentTestComponent {
+	case utils.IgLocalTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-r=%s", utils.Runtime)))
+	case utils.KubectlGadgetTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-n=%s", ns)))
+		testingOpts = append(testingOpts, igtesting.WithCbBeforeCleanup(utils.PrintLogsFn(ns)))
+		commonDataOpts = append(commonDataOpts, utils.WithK8sNamespace(ns))
+	}
+
+	runnerOpts = append(runnerOpts,
+		igrunner.WithValidateOutput(
+			func(t *testing.T, output string) {
+				expectedEntries := []*tracePtraceEvent{
+					{
+						CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+						Proc:       utils.BuildProc("inject", 0, 0),
+						Tracee:     utils.BuildProc("inject", 0, 0),
+						Type:       "ATTACH",
+						Timestamp:  utils.NormalizedStr,
+					},
+					{
+						CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+						Proc:       utils.BuildProc("inject", 0, 0),
+						Tracee:     utils.BuildProc("inject", 0, 0),
+						Type:       "POKEDATA",
+						Addr:       pokeAddr,
+						Timestamp:  utils.NormalizedStr,
+					},
+					{
+						CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+						Proc:       utils.BuildProc("inject", 0, 0),
+						Type:       "MEMFD_EXEC",
+						File:       "memfd:payload",
+						Timestamp:  utils.NormalizedStr,
+					},
+				}
+				normalize := func(e *tracePtraceEvent) {
+					utils.NormalizeCommonData(&e.CommonData)
+					utils.NormalizeString(&e.Runtime.ContainerID)
+					utils.NormalizeString(&e.Timestamp)
+					utils.NormalizeProc(&e.Proc)
+					utils.NormalizeProc(&e.Tracee)
+				}
+				match.MatchEntries(t, match.JSONMultiObjectMode, output, normalize, expectedEntries...)
+			},
+		),
+		igrunner.WithStartAndStop(),
+	)
+	tracePtraceCmd := igrunner.New("trace_ptrace", runnerOpts...)
+
+	steps := []igtesting.TestStep{
+		tracePtraceCmd,
+		// wait to ensure ig or kubectl-gadget has started
+		utils.Sleep(10 * time.Second),
+		testContainer,
+	}
+	igtesting.RunTestSteps(steps, t, testingOpts...)
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/gadgets/testing/workloads"
 	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
 	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
@@ -53,7 +54,7 @@ func TestTraceTCP(t *testing.T) {
 	containerImage := gadgettesting.NginxImage
 
 	var ns string
-	containerOpts := []containers.ContainerOption{containers.WithContainerImage(containerImage)}
+	var containerOpts []containers.ContainerOption
 
 	switch utils.CurrentTestComponent {
 	case utils.KubectlGadgetTestComponent:
@@ -63,17 +64,11 @@ func TestTraceTCP(t *testing.T) {
 		containerOpts = append(containerOpts, containers.WithPrivileged())
 	}
 
-	// TODO: can't use setuidgid because it's not available on the nginx image
-	testContainer := containerFactory.NewContainer(
-		containerName,
-		"nginx && while true; do curl 127.0.0.1; sleep 1; done",
-		containerOpts...,
-	)
-
-	testContainer.Start(t)
-	t.Cleanup(func() {
-		testContainer.Stop(t)
+	workload := workloads.HTTPLoopback(containerFactory, containerName, workloads.Options{
+		ContainerOptions: containerOpts,
 	})
+	workload.Start(t)
+	testContainer := workload.Container(containerName)
 
 	var runnerOpts []igrunner.Option
 	var testingOpts []igtesting.Option
@@ -90,111 +85,76 @@ func TestTraceTCP(t *testing.T) {
 
 	runnerOpts = append(runnerOpts, igrunner.WithValidateOutput(
 		func(t *testing.T, output string) {
-			expectedEntries := []*traceTCPEvent{
-				{
+			endpoint := utils.L4Endpoint{
+				Addr:    "127.0.0.1",
+				Version: 4,
+				Proto:   "TCP",
+			}
+			if utils.CurrentTestComponent == utils.KubectlGadgetTestComponent {
+				endpoint.K8s = utils.K8s{
+					Kind: "raw",
+				}
+			}
+
+			// Checking the ports is a little bit complicated as successive
+			// calls to curl with --local-port fail because of
+			// https://github.com/curl/curl/issues/6288
+			commonFields := match.Fields{
+				"timestamp": match.NonEmpty(),
+				"netns_id":  match.NonEmpty(),
+				"src.port":  match.Range(1, 65535),
+				"dst.port":  match.Range(1, 65535),
+			}
+			withFields := func(fields match.Fields) match.Fields {
+				for path, matcher := range commonFields {
+					fields[path] = matcher
+				}
+				return fields
+			}
+
+			expectedEntries := []*match.ExpectedEntry[traceTCPEvent]{
+				match.WithFields(&traceTCPEvent{
 					CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
 					Proc:       utils.BuildProc("curl", 0, 0),
-					Src: utils.L4Endpoint{
-						Addr:    "127.0.0.1",
-						Version: 4,
-						Port:    utils.NormalizedInt,
-						Proto:   "TCP",
-					},
-					Dst: utils.L4Endpoint{
-						Addr:    "127.0.0.1",
-						Version: 4,
-						Port:    utils.NormalizedInt,
-						Proto:   "TCP",
-					},
-					Type:     "connect",
-					Error:    "",
-					AcceptFd: -1,
-
-					// Check only the existence of these fields
-					Timestamp: utils.NormalizedStr,
-					NetNsID:   utils.NormalizedInt,
-					Fd:        utils.NormalizedInt,
-				},
-				{
+					Src:        endpoint,
+					Dst:        endpoint,
+					Type:       "connect",
+					Error:      "",
+					AcceptFd:   -1,
+				}, withFields(match.Fields{
+					"fd": match.NonEmpty(),
+				})),
+				match.WithFields(&traceTCPEvent{
 					CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
 					Proc:       utils.BuildProc("nginx", 101, 101),
-					Src: utils.L4Endpoint{
-						Addr:    "127.0.0.1",
-						Version: 4,
-						Port:    utils.NormalizedInt,
-						Proto:   "TCP",
-					},
-					Dst: utils.L4Endpoint{
-						Addr:    "127.0.0.1",
-						Version: 4,
-						Port:    utils.NormalizedInt,
-						Proto:   "TCP",
-					},
-					Type:  "accept",
-					Error: "",
-
-					// Check only the existence of these fields
-					Timestamp: utils.NormalizedStr,
-					NetNsID:   utils.NormalizedInt,
-					Fd:        utils.NormalizedInt,
-					AcceptFd:  utils.NormalizedInt,
-				},
-				{
+					Src:        endpoint,
+					Dst:        endpoint,
+					Type:       "accept",
+					Error:      "",
+				}, withFields(match.Fields{
+					"fd":        match.NonEmpty(),
+					"accept_fd": match.NonEmpty(),
+				})),
+				match.WithFields(&traceTCPEvent{
 					CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
 					Proc:       utils.BuildProc("curl", 0, 0),
-					Src: utils.L4Endpoint{
-						Addr:    "127.0.0.1",
-						Version: 4,
-						Port:    utils.NormalizedInt,
-						Proto:   "TCP",
-					},
-					Dst: utils.L4Endpoint{
-						Addr:    "127.0.0.1",
-						Version: 4,
-						Port:    utils.NormalizedInt,
-						Proto:   "TCP",
-					},
-					Type:     "close",
-					Error:    "",
-					Fd:       -1,
-					AcceptFd: -1,
-
-					// Check only the existence of these fields
-					Timestamp: utils.NormalizedStr,
-					NetNsID:   utils.NormalizedInt,
-				},
-			}
-
-			if utils.CurrentTestComponent == utils.KubectlGadgetTestComponent {
-				for _, expectedEntry := range expectedEntries {
-					expectedEntry.Src.K8s = utils.K8s{
-						Kind: "raw",
-					}
-					expectedEntry.Dst.K8s = expectedEntry.Src.K8s
-				}
+					Src:        endpoint,
+					Dst:        endpoint,
+					Type:       "close",
+					Error:      "",
+					Fd:         -1,
+					AcceptFd:   -1,
+				}, withFields(match.Fields{})),
 			}
 
 			normalize := func(e *traceTCPEvent) {
 				utils.NormalizeCommonData(&e.CommonData)
 				utils.NormalizeEndpoint(&e.Src)
 				utils.NormalizeEndpoint(&e.Dst)
-				utils.NormalizeString(&e.Timestamp)
 				utils.NormalizeProc(&e.Proc)
-				utils.NormalizeInt(&e.NetNsID)
-				// Checking the ports is a little bit complicated as successive
-				// calls to curl with --local-port fail because of
-				// https://github.com/curl/curl/issues/6288
-				utils.NormalizeInt(&e.Src.Port)
-				utils.NormalizeInt(&e.Dst.Port)
-				if e.Type == "accept" {
-					utils.NormalizeInt(&e.AcceptFd)
-				}
-				if e.Type != "close" {
-					utils.NormalizeInt(&e.Fd)
-				}
 			}
 
-			match.MatchEntries(t, match.JSONMultiObjectMode, output, normalize, expectedEntries...)
+			match.MatchEntriesWithFields(t, match.JSONMultiObjectMode, output, normalize, expectedEntries...)
 		},
 	))
 
@@ -0,0 +1,721 @@
+// SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause)
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+//
+// The handshake functions of the TLS libraries are traced with uprobes. While
+// they run, the ClientHello and ServerHello messages read and written by the
+// same thread are parsed to get the SNI, the negotiated version, the cipher
+// suite and the ALPN protocols. This doesn't depend on the internal structures
+// of the libraries, which change between versions.
+//
+// Sockets switched to kTLS are traced with the setsockopt(SOL_TLS) syscall,
+// which provides the version and the cipher used by the kernel.
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+
+#include <gadget/buffer.h>
+#include <gadget/common.h>
+#include <gadget/filter.h>
+#include <gadget/macros.h>
+#include <gadget/types.h>
+
+#define MAX_ENTRIES 8192
+
+#define TLS_MAX_SNI_LEN 256
+#define TLS_MAX_ALPN_LEN 64
+#define TLS_MAX_EXTENSIONS 32
+
+#define TLS_RECORD_HANDSHAKE 0x16
+#define TLS_CLIENT_HELLO 1
+#define TLS_SERVER_HELLO 2
+
+#define TLS_EXT_SERVER_NAME 0
+#define TLS_EXT_ALPN 16
+#define TLS_EXT_SUPPORTED_VERSIONS 43
+
+/* from include/uapi/linux/tls.h and include/linux/socket.h */
+#define SOL_TLS 282
+#define TLS_TX 1
+#define TLS_RX 2
+#define TLS_CIPHER_AES_GCM_128 51
+#define TLS_CIPHER_AES_GCM_256 52
+#define TLS_CIPHER_AES_CCM_128 53
+#define TLS_CIPHER_CHACHA20_POLY1305 54
+
+/* openssl returns -1 and gnutls GNUTLS_E_AGAIN or GNUTLS_E_INTERRUPTED when
+ * the handshake of a non-blocking socket has to be resumed
+ */
+#define GNUTLS_E_AGAIN -28
+#define GNUTLS_E_INTERRUPTED -52
+
+enum tls_source : u8 {
+	OPENSSL,
+	GNUTLS,
+	KTLS_TX,
+	KTLS_RX,
+};
+
+enum tls_role : u8 {
+	TLS_ROLE_UNKNOWN,
+	TLS_CLIENT,
+	TLS_SERVER,
+};
+
+enum tls_version : u16 {
+	SSL_3_0 = 0x0300,
+	TLS_1_0 = 0x0301,
+	TLS_1_1 = 0x0302,
+	TLS_1_2 = 0x0303,
+	TLS_1_3 = 0x0304,
+};
+
+/* The most common cipher suites, from the IANA TLS Cipher Suites registry */
+enum tls_cipher : u16 {
+	TLS_RSA_WITH_RC4_128_MD5 = 0x0004,
+	TLS_RSA_WITH_RC4_128_SHA = 0x0005,
+	TLS_RSA_WITH_3DES_EDE_CBC_SHA = 0x000a,
+	TLS_RSA_WITH_AES_128_CBC_SHA = 0x002f,
+	TLS_DHE_RSA_WITH_AES_128_CBC_SHA = 0x0033,
+	TLS_RSA_WITH_AES_256_CBC_SHA = 0x0035,
+	TLS_DHE_RSA_WITH_AES_256_CBC_SHA = 0x0039,
+	TLS_RSA_WITH_AES_128_CBC_SHA256 = 0x003c,
+	TLS_RSA_WITH_AES_256_CBC_SHA256 = 0x003d,
+	TLS_RSA_WITH_AES_128_GCM_SHA256 = 0x009c,
+	TLS_RSA_WITH_AES_256_GCM_SHA384 = 0x009d,
+	TLS_DHE_RSA_WITH_AES_128_GCM_SHA256 = 0x009e,
+	TLS_DHE_RSA_WITH_AES_256_GCM_SHA384 = 0x009f,
+	TLS_AES_128_GCM_SHA256 = 0x1301,
+	TLS_AES_256_GCM_SHA384 = 0x1302,
+	TLS_CHACHA20_POLY1305_SHA256 = 0x1303,
+	TLS_AES_128_CCM_SHA256 = 0x1304,
+	TLS_AES_128_CCM_8_SHA256 = 0x1305,
+	TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA = 0xc009,
+	TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA = 0xc00a,
+	TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA = 0xc013,
+	TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA = 0xc014,
+	TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256 = 0xc023,
+	TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384 = 0xc024,
+	TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256 = 0xc027,
+	TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384 = 0xc028,
+	TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 = 0xc02b,
+	TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384 = 0xc02c,
+	TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 = 0xc02f,
+	TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 = 0xc030,
+	TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256 = 0xcca8,
+	TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256 = 0xcca9,
+	TLS_DHE_RSA_WITH_CHACHA20_POLY1305_SHA256 = 0xccaa,
+};
+
+/* what is known about a handshake, filled while it's running */
+struct handshake {
+	u64 start_time;
+	enum tls_source source_raw;
+	enum tls_role role_raw;
+	enum tls_version version_raw;
+	enum tls_cipher cipher_raw;
+	char sni[TLS_MAX_SNI_LEN];
+	char alpn[TLS_MAX_ALPN_LEN];
+	char alpn_offered[TLS_MAX_ALPN_LEN];
+};
+
+struct event {
+	gadget_timestamp timestamp_raw;
+	struct gadget_process proc;
+
+	enum tls_source source_raw;
+	enum tls_role role_raw;
+	enum tls_version version_raw;
+	enum tls_cipher cipher_raw;
+	gadget_duration latency_ns_raw;
+	char sni[TLS_MAX_SNI_LEN];
+	char alpn[TLS_MAX_ALPN_LEN];
+	char alpn_offered[TLS_MAX_ALPN_LEN];
+};
+
+GADGET_TRACER_MAP(events, 1024 * 256);
+GADGET_TRACER(tls, events, event);
+
+/* handshakes are identified by the SSL or gnutls_session_t pointer of their
+ * process, as non-blocking ones can span several calls and threads
+ */
+struct handshake_key {
+	u32 tgid;
+	u32 pad;
+	u64 session;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct handshake_key);
+	__type(value, struct handshake);
+} handshakes SEC(".maps");
+
+/* used for context between the uprobes and uretprobes of the handshake
+ * functions, and the syscalls made by the thread in between
+ */
+struct handshake_call {
+	struct handshake_key key;
+	const void *buf;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u32); // tid
+	__type(value, struct handshake_call);
+} handshake_calls SEC(".maps");
+
+/* used for context between the entry and the exit of setsockopt(SOL_TLS) */
+struct ktls_call {
+	enum tls_source source;
+	enum tls_version version;
+	u16 cipher_type;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, u32); // tid
+	__type(value, struct ktls_call);
+} ktls_calls SEC(".maps");
+
+static const struct handshake empty_handshake = {};
+
+/**
+ * clean up the maps when a thread terminates,
+ * because there may be residual data in the map
+ * if a userspace thread is killed between a uprobe and a uretprobe
+ */
+SEC("tracepoint/sched/sched_process_exit")
+int trace_sched_process_exit(void *ctx)
+{
+	u32 tid;
+
+	tid = (u32)bpf_get_current_pid_tgid();
+	bpf_map_delete_elem(&handshake_calls, &tid);
+	bpf_map_delete_elem(&ktls_calls, &tid);
+	return 0;
+}
+
+static __always_inline int read_u8(const void *buf, u32 len, u32 off, u8 *v)
+{
+	if (off + 1 > len)
+		return -1;
+	return bpf_probe_read_user(v, sizeof(*v), buf + off);
+}
+
+static __always_inline int read_be16(const void *buf, u32 len, u32 off,
+				     u16 *v)
+{
+	u8 b[2];
+
+	if (off + sizeof(b) > len)
+		return -1;
+	if (bpf_probe_read_user(b, sizeof(b), buf + off))
+		return -1;
+	*v = (u16)b[0] << 8 | b[1];
+	return 0;
+}
+
+/* read_alpn_list copies a list of ALPN protocols in the wire format, like
+ * "\x02h2\x08http/1.1", as a comma-separated string, like "h2,http/1.1"
+ */
+static __always_inline void read_alpn_list(char *dst, const void *buf,
+					   u32 len, u32 off, u16 list_len)
+{
+	u32 n = list_len;
+	u32 next;
+	int i;
+
+	if (n >= TLS_MAX_ALPN_LEN)
+		n = TLS_MAX_ALPN_LEN - 1;
+	if (n < 2 || off + n > len)
+		return;
+	if (bpf_probe_read_user(dst, n & (TLS_MAX_ALPN_LEN - 1), buf + off))
+		return;
+
+	/* shift the protocols by one byte, replacing the lengths by commas */
+	next = 1 + (u8)dst[0];
+	for (i = 0; i < TLS_MAX_ALPN_LEN - 1; i++) {
+		char c;
+
+		if (i + 1 >= n)
+			break;
+		c = dst[i + 1];
+		if (i + 1 == next) {
+			next = i + 2 + (u8)c;
+			c = ',';
+		}
+		dst[i] = c;
+	}
+	dst[(n - 1) & (TLS_MAX_ALPN_LEN - 1)] = '\0';
+}
+
+static __always_inline void read_sni(struct handshake *hs, const void *buf,
+				     u32 off, u32 ext_end)
+{
+	u16 name_len;
+	u8 name_type;
+
+	/* server_name_list length, then the first name */
+	off += 2;
+	if (read_u8(buf, ext_end, off, &name_type) || name_type != 0)
+		return;
+	if (read_be16(buf, ext_end, off + 1, &name_len))
+		return;
+	off += 3;
+	if (name_len >= TLS_MAX_SNI_LEN)
+		name_len = TLS_MAX_SNI_LEN - 1;
+	if (name_len == 0 || off + name_len > ext_end)
+		return;
+	if (bpf_probe_read_user(hs->sni, name_len & (TLS_MAX_SNI_LEN - 1),
+				buf + off))
+		return;
+	hs->sni[name_len & (TLS_MAX_SNI_LEN - 1)] = '\0';
+}
+
+/* parse_hello fills the handshake with the content of a ClientHello or
+ * ServerHello message at the beginning of buf. The message can be preceded by
+ * the header of its record or not, as some libraries read the header and the
+ * payload of records separately.
+ */
+static __always_inline void parse_hello(struct handshake *hs, const void *buf,
+					u32 len, bool outgoing)
+{
+	u32 off = 0, end, ext_end;
+	u16 legacy_version, cipher, ext_len;
+	u8 hdr[6];
+	u8 msg_type, sid_len, comp_len;
+	u16 cs_len;
+	int i;
+
+	if (len < sizeof(hdr))
+		return;
+	if (bpf_probe_read_user(hdr, sizeof(hdr), buf))
+		return;
+	if (hdr[0] == TLS_RECORD_HANDSHAKE && hdr[1] == 0x03) {
+		off = 5;
+		if (read_u8(buf, len, off, &msg_type))
+			return;
+	} else {
+		msg_type = hdr[0];
+		/* legacy_version must follow the handshake header */
+		if (hdr[4] != 0x03)
+			return;
+	}
+	if (msg_type != TLS_CLIENT_HELLO && msg_type != TLS_SERVER_HELLO)
+		return;
+
+	/* handshake type and 24-bit length */
+	{
+		u8 b[3];
+
+		if (off + 4 > len)
+			return;
+		if (bpf_probe_read_user(b, sizeof(b), buf + off + 1))
+			return;
+		end = off + 4 + ((u32)b[0] << 16 | (u32)b[1] << 8 | b[2]);
+		if (end > len)
+			end = len;
+	}
+	off += 4;
+
+	if (read_be16(buf, end, off, &legacy_version))
+		return;
+	/* legacy_version and random */
+	off += 2 + 32;
+	if (read_u8(buf, end, off, &sid_len))
+		return;
+	off += 1 + sid_len;
+
+	if (msg_type == TLS_CLIENT_HELLO) {
+		hs->role_raw = outgoing ? TLS_CLIENT : TLS_SERVER;
+		if (read_be16(buf, end, off, &cs_len))
+			return;
+		off += 2 + cs_len;
+		if (read_u8(buf, end, off, &comp_len))
+			return;
+		off += 1 + comp_len;
+	} else {
+		hs->role_raw = outgoing ? TLS_SERVER : TLS_CLIENT;
+		if (read_be16(buf, end, off, &cipher))
+			return;
+		hs->cipher_raw = cipher;
+		/* overridden by the supported_versions extension with TLS 1.3 */
+		hs->version_raw = legacy_version;
+		/* cipher suite and compression method */
+		off += 3;
+	}
+
+	if (read_be16(buf, end, off, &ext_len))
+		return;
+	off += 2;
+	ext_end = off + ext_len;
+	if (ext_end > end)
+		ext_end = end;
+
+	for (i = 0; i < TLS_MAX_EXTENSIONS; i++) {
+		u16 type, elen, v;
+
+		if (read_be16(buf, ext_end, off, &type) ||
+		    read_be16(buf, ext_end, off + 2, &elen))
+			break;
+		off += 4;
+
+		switch (type) {
+		case TLS_EXT_SERVER_NAME:
+			if (msg_type == TLS_CLIENT_HELLO)
+				read_sni(hs, buf, off, ext_end);
+			break;
+		case TLS_EXT_ALPN:
+			if (read_be16(buf, ext_end, off, &v))
+				break;
+			if (msg_type == TLS_CLIENT_HELLO)
+				read_alpn_list(hs->alpn_offered, buf, ext_end,
+					       off + 2, v);
+			else
+				read_alpn_list(hs->alpn, buf, ext_end, off + 2,
+					       v);
+			break;
+		case TLS_EXT_SUPPORTED_VERSIONS:
+			/* the ServerHello contains the selected version, the
+			 * ClientHello the list of the offered ones
+			 */
+			if (msg_type == TLS_SERVER_HELLO &&
+			    !read_be16(buf, ext_end, off, &v))
+				hs->version_raw = v;
+			break;
+		}
+
+		off += elen;
+	}
+}
+
+static __always_inline int handshake_enter(void *session,
+					   enum tls_source source)
+{
+	struct handshake_call call = {};
+	struct handshake *hs;
+	u64 pid_tgid;
+	u32 tid;
+
+	if (gadget_should_discard_data_current())
+		return 0;
+
+	pid_tgid = bpf_get_current_pid_tgid();
+	tid = (u32)pid_tgid;
+
+	/* SSL_connect and SSL_accept call SSL_do_handshake */
+	if (bpf_map_lookup_elem(&handshake_calls, &tid))
+		return 0;
+
+	call.key.tgid = pid_tgid >> 32;
+	call.key.session = (u64)session;
+
+	hs = bpf_map_lookup_elem(&handshakes, &call.key);
+	if (!hs) {
+		bpf_map_update_elem(&handshakes, &call.key, &empty_handshake,
+				    BPF_NOEXIST);
+		hs = bpf_map_lookup_elem(&handshakes, &call.key);
+		if (!hs)
+			return 0;
+		hs->start_time = bpf_ktime_get_boot_ns();
+		hs->source_raw = source;
+	}
+
+	bpf_map_update_elem(&handshake_calls, &tid, &call, BPF_ANY);
+	return 0;
+}
+
+static __always_inline int handshake_exit(struct pt_regs *ctx, bool done,
+					  bool failed)
+{
+	struct handshake_call *call;
+	struct handshake *hs;
+	struct event *event;
+	u32 tid;
+	u64 ts;
+
+	tid = (u32)bpf_get_current_pid_tgid();
+	call = bpf_map_lookup_elem(&handshake_calls, &tid);
+	if (!call)
+		return 0;
+
+	hs = bpf_map_lookup_elem(&handshakes, &call->key);
+	if (!hs || !done)
+		goto clean;
+
+	ts = bpf_ktime_get_boot_ns();
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		goto clean_handshake;
+
+	gadget_process_populate(&event->proc);
+	event->timestamp_raw = ts;
+	event->source_raw = hs->source_raw;
+	event->role_raw = hs->role_raw;
+	event->version_raw = hs->version_raw;
+	event->cipher_raw = hs->cipher_raw;
+	event->latency_ns_raw = ts - hs->start_time;
+	__builtin_memcpy(event->sni, hs->sni, sizeof(event->sni));
+	__builtin_memcpy(event->alpn, hs->alpn, sizeof(event->alpn));
+	__builtin_memcpy(event->alpn_offered, hs->alpn_offered,
+			 sizeof(event->alpn_offered));
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+
+clean_handshake:
+	bpf_map_delete_elem(&handshakes, &call->key);
+clean:
+	if (failed && hs)
+		bpf_map_delete_elem(&handshakes, &call->key);
+	bpf_map_delete_elem(&handshake_calls, &tid);
+	return 0;
+}
+
+/* uprobes for libssl */
+SEC("uprobe/libssl:SSL_do_handshake")
+int trace_uprobe_libssl_SSL_do_handshake(struct pt_regs *ctx)
+{
+	return handshake_enter((void *)PT_REGS_PARM1(ctx), OPENSSL);
+}
+
+SEC("uretprobe/libssl:SSL_do_handshake")
+int trace_uretprobe_libssl_SSL_do_handshake(struct pt_regs *ctx)
+{
+	int ret = PT_REGS_RC(ctx);
+
+	return handshake_exit(ctx, ret == 1, ret == 0);
+}
+
+SEC("uprobe/libssl:SSL_connect")
+int trace_uprobe_libssl_SSL_connect(struct pt_regs *ctx)
+{
+	return handshake_enter((void *)PT_REGS_PARM1(ctx), OPENSSL);
+}
+
+SEC("uretprobe/libssl:SSL_connect")
+int trace_uretprobe_libssl_SSL_connect(struct pt_regs *ctx)
+{
+	int ret = PT_REGS_RC(ctx);
+
+	return handshake_exit(ctx, ret == 1, ret == 0);
+}
+
+SEC("uprobe/libssl:SSL_accept")
+int trace_uprobe_libssl_SSL_accept(struct pt_regs *ctx)
+{
+	return handshake_enter((void *)PT_REGS_PARM1(ctx), OPENSSL);
+}
+
+SEC("uretprobe/libssl:SSL_accept")
+int trace_uretprobe_libssl_SSL_accept(struct pt_regs *ctx)
+{
+	int ret = PT_REGS_RC(ctx);
+
+	return handshake_exit(ctx, ret == 1, ret == 0);
+}
+
+/* uprobes for libgnutls */
+SEC("uprobe/libgnutls:gnutls_handshake")
+int trace_uprobe_libgnutls_gnutls_handshake(struct pt_regs *ctx)
+{
+	return handshake_enter((void *)PT_REGS_PARM1(ctx), GNUTLS);
+}
+
+SEC("uretprobe/libgnutls:gnutls_handshake")
+int trace_uretprobe_libgnutls_gnutls_handshake(struct pt_regs *ctx)
+{
+	int ret = PT_REGS_RC(ctx);
+
+	return handshake_exit(ctx, ret == 0,
+			      ret < 0 && ret != GNUTLS_E_AGAIN &&
+				      ret != GNUTLS_E_INTERRUPTED);
+}
+
+/* syscalls used by the libraries to send and receive the handshake messages */
+static __always_inline int trace_send(const void *buf, size_t count)
+{
+	struct handshake_call *call;
+	struct handshake *hs;
+	u32 tid;
+
+	tid = (u32)bpf_get_current_pid_tgid();
+	call = bpf_map_lookup_elem(&handshake_calls, &tid);
+	if (!call)
+		return 0;
+
+	hs = bpf_map_lookup_elem(&handshakes, &call->key);
+	if (!hs)
+		return 0;
+
+	parse_hello(hs, buf, count, true);
+	return 0;
+}
+
+static __always_inline int trace_recv_enter(const void *buf)
+{
+	struct handshake_call *call;
+	u32 tid;
+
+	tid = (u32)bpf_get_current_pid_tgid();
+	call = bpf_map_lookup_elem(&handshake_calls, &tid);
+	if (!call)
+		return 0;
+
+	call->buf = buf;
+	return 0;
+}
+
+static __always_inline int trace_recv_exit(long ret)
+{
+	struct handshake_call *call;
+	struct handshake *hs;
+	const void *buf;
+	u32 tid;
+
+	tid = (u32)bpf_get_current_pid_tgid();
+	call = bpf_map_lookup_elem(&handshake_calls, &tid);
+	if (!call || !call->buf)
+		return 0;
+
+	buf = call->buf;
+	call->buf = NULL;
+	if (ret <= 0)
+		return 0;
+
+	hs = bpf_map_lookup_elem(&handshakes, &call->key);
+	if (!hs)
+		return 0;
+
+	parse_hello(hs, buf, ret, false);
+	return 0;
+}
+
+SEC("tracepoint/syscalls/sys_enter_write")
+int ig_write_e(struct syscall_trace_enter *ctx)
+{
+	return trace_send((const void *)ctx->args[1], (size_t)ctx->args[2]);
+}
+
+SEC("tracepoint/syscalls/sys_enter_sendto")
+int ig_sendto_e(struct syscall_trace_enter *ctx)
+{
+	return trace_send((const void *)ctx->args[1], (size_t)ctx->args[2]);
+}
+
+SEC("tracepoint/syscalls/sys_enter_read")
+int ig_read_e(struct syscall_trace_enter *ctx)
+{
+	return trace_recv_enter((const void *)ctx->args[1]);
+}
+
+SEC("tracepoint/syscalls/sys_exit_read")
+int ig_read_x(struct syscall_trace_exit *ctx)
+{
+	return trace_recv_exit(ctx->ret);
+}
+
+SEC("tracepoint/syscalls/sys_enter_recvfrom")
+int ig_recvfrom_e(struct syscall_trace_enter *ctx)
+{
+	return trace_recv_enter((const void *)ctx->args[1]);
+}
+
+SEC("tracepoint/syscalls/sys_exit_recvfrom")
+int ig_recvfrom_x(struct syscall_trace_exit *ctx)
+{
+	return trace_recv_exit(ctx->ret);
+}
+
+/* kTLS */
+
+/* ktls_cipher returns the cipher suite of a kTLS cipher. It's only known with
+ * TLS 1.3, as the suites of the previous versions include the key exchange.
+ */
+static __always_inline enum tls_cipher ktls_cipher(enum tls_version version,
+						   u16 cipher_type)
+{
+	if (version != TLS_1_3)
+		return 0;
+
+	switch (cipher_type) {
+	case TLS_CIPHER_AES_GCM_128:
+		return TLS_AES_128_GCM_SHA256;
+	case TLS_CIPHER_AES_GCM_256:
+		return TLS_AES_256_GCM_SHA384;
+	case TLS_CIPHER_CHACHA20_POLY1305:
+		return TLS_CHACHA20_POLY1305_SHA256;
+	case TLS_CIPHER_AES_CCM_128:
+		return TLS_AES_128_CCM_SHA256;
+	default:
+		return 0;
+	}
+}
+
+SEC("tracepoint/syscalls/sys_enter_setsockopt")
+int ig_setsockopt_e(struct syscall_trace_enter *ctx)
+{
+	int level = (int)ctx->args[1];
+	int optname = (int)ctx->args[2];
+	const void *optval = (const void *)ctx->args[3];
+	struct ktls_call call = {};
+	/* struct tls_crypto_info */
+	u16 crypto_info[2];
+	u32 tid;
+
+	if (level != SOL_TLS || (optname != TLS_TX && optname != TLS_RX))
+		return 0;
+
+	if (gadget_should_discard_data_current())
+		return 0;
+
+	if (bpf_probe_read_user(crypto_info, sizeof(crypto_info), optval))
+		return 0;
+
+	call.source = optname == TLS_TX ? KTLS_TX : KTLS_RX;
+	call.version = crypto_info[0];
+	call.cipher_type = crypto_info[1];
+
+	tid = (u32)bpf_get_current_pid_tgid();
+	bpf_map_update_elem(&ktls_calls, &tid, &call, BPF_ANY);
+	return 0;
+}
+
+SEC("tracepoint/syscalls/sys_exit_setsockopt")
+int ig_setsockopt_x(struct syscall_trace_exit *ctx)
+{
+	struct ktls_call *call;
+	struct event *event;
+	u32 tid;
+
+	tid = (u32)bpf_get_current_pid_tgid();
+	call = bpf_map_lookup_elem(&ktls_calls, &tid);
+	if (!call)
+		return 0;
+
+	if (ctx->ret != 0)
+		goto clean;
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		goto clean;
+
+	__builtin_memset(event, 0, sizeof(*event));
+	gadget_process_populate(&event->proc);
+	event->timestamp_raw = bpf_ktime_get_boot_ns();
+	event->source_raw = call->source;
+	event->role_raw = TLS_ROLE_UNKNOWN;
+	event->version_raw = call->version;
+	event->cipher_raw = ktls_cipher(call->version, call->cipher_type);
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+
+clean:
+	bpf_map_delete_elem(&ktls_calls, &tid);
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
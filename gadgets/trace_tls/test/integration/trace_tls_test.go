@@ -0,0 +1,123 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/match"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+type traceTLSEvent struct {
+	utils.CommonData
+
+	Timestamp string        `json:"timestamp"`
+	Proc      utils.Process `json:"proc"`
+
+	Source      string `json:"source"`
+	Role        string `json:"role"`
+	Version     string `json:"version"`
+	Cipher      string `json:"cipher"`
+	Sni         string `json:"sni"`
+	Alpn        string `json:"alpn"`
+	AlpnOffered string `json:"alpn_offered"`
+}
+
+func TestTraceTLS(t *testing.T) {
+	gadgettesting.RequireEnvironmentVariables(t)
+	utils.InitTest(t)
+
+	if utils.CurrentTestComponent == utils.IgLocalTestComponent && utils.Runtime == "containerd" {
+		t.Skip("Skipping test as containerd test utils can't use the network")
+	}
+
+	containerFactory, err := containers.NewContainerFactory(utils.Runtime)
+	require.NoError(t, err, "new container factory")
+	containerName := "test-trace-tls"
+	// curl of this image uses OpenSSL
+	containerImage := gadgettesting.NetworkMultitoolImage
+
+	var ns string
+	containerOpts := []containers.ContainerOption{containers.WithContainerImage(containerImage)}
+
+	if utils.CurrentTestComponent == utils.KubectlGadgetTestComponent {
+		ns = utils.GenerateTestNamespaceName(t, "test-trace-tls")
+		containerOpts = append(containerOpts, containers.WithContainerNamespace(ns))
+	}
+
+	testContainer := containerFactory.NewContainer(
+		containerName,
+		"while true; do curl -s -k -m 2 -o /dev/null --http1.1 --tlsv1.2 --tls-max 1.2 https://inspektor-gadget.io; sleep 1; done",
+		containerOpts...,
+	)
+
+	testContainer.Start(t)
+	t.Cleanup(func() {
+		testContainer.Stop(t)
+	})
+
+	var runnerOpts []igrunner.Option
+	var testingOpts []igtesting.Option
+	commonDataOpts := []utils.CommonDataOption{utils.WithContainerImageName(containerImage), utils.WithContainerID(testContainer.ID())}
+
+	switch utils.CurrentTestComponent {
+	case utils.IgLocalTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-r=%s", utils.Runtime), "--timeout=5"))
+	case utils.KubectlGadgetTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-n=%s", ns), "--timeout=5"))
+		testingOpts = append(testingOpts, igtesting.WithCbBeforeCleanup(utils.PrintLogsFn(ns)))
+		commonDataOpts = append(commonDataOpts, utils.WithK8sNamespace(ns))
+	}
+
+	runnerOpts = append(runnerOpts, igrunner.WithValidateOutput(
+		func(t *testing.T, output string) {
+			expectedEntry := &traceTLSEvent{
+				CommonData:  utils.BuildCommonData(containerName, commonDataOpts...),
+				Proc:        utils.BuildProc("curl", 0, 0),
+				Source:      "OPENSSL",
+				Role:        "TLS_CLIENT",
+				Version:     "TLS_1_2",
+				Sni:         "inspektor-gadget.io",
+				Alpn:        "http/1.1",
+				AlpnOffered: "http/1.1",
+
+				// Check the existence of the following fields
+				Timestamp: utils.NormalizedStr,
+				Cipher:    utils.NormalizedStr,
+			}
+
+			normalize := func(e *traceTLSEvent) {
+				utils.NormalizeCommonData(&e.CommonData)
+				utils.NormalizeString(&e.Timestamp)
+				utils.NormalizeString(&e.Cipher)
+				utils.NormalizeProc(&e.Proc)
+			}
+
+			match.MatchEntries(t, match.JSONMultiObjectMode, output, normalize, expectedEntry)
+		},
+	))
+
+	traceTLSCmd := igrunner.New("trace_tls", runnerOpts...)
+
+	igtesting.RunTestSteps([]igtesting.TestStep{traceTLSCmd}, t, testingOpts...)
+}
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include <gadget/buffer.h>
+#include <gadget/macros.h>
+#include <gadget/mntns_filter.h>
+#include <gadget/types.h>
+
+#define CGROUP_NAME_LEN 64
+#define MAX_ENTRIES 10240
+// Maximum depth of nested task groups walked to find the running task
+#define MAX_GROUP_DEPTH 8
+// Weight of the last throttling in the running average, as 1/2^EWMA_SHIFT
+#define EWMA_SHIFT 3
+
+// The CFS bandwidth control fields are only available with
+// CONFIG_CFS_BANDWIDTH, they aren't part of vmlinux.h
+struct cfs_bandwidth___ig {
+	ktime_t period;
+	u64 quota;
+	int nr_periods;
+	int nr_throttled;
+	u64 throttled_time;
+} __attribute__((preserve_access_index));
+
+struct task_group___ig {
+	struct cgroup_subsys_state css;
+	struct cfs_bandwidth___ig cfs_bandwidth;
+} __attribute__((preserve_access_index));
+
+struct cfs_rq___ig {
+	struct rq *rq;
+	struct task_group___ig *tg;
+	struct sched_entity *curr;
+} __attribute__((preserve_access_index));
+
+struct event {
+	gadget_timestamp timestamp_raw;
+	// Mount namespace of a task of the throttled cgroup, to enrich the event
+	// with the container
+	gadget_mntns_id mntns_id;
+
+	char cgroup[CGROUP_NAME_LEN];
+	__u64 cgroup_id;
+	__u32 cpu;
+
+	// Time the cgroup was throttled on this CPU
+	gadget_duration throttled_raw;
+	// Running average of throttled_raw for this cgroup
+	gadget_duration avg_throttled_raw;
+	// Total time the cgroup was throttled
+	gadget_duration total_throttled_raw;
+
+	gadget_duration period_raw;
+	gadget_duration quota_raw;
+	__u32 nr_periods;
+	__u32 nr_throttled;
+	// Percentage of the periods the cgroup was throttled in
+	__u32 throttled_pct;
+};
+
+GADGET_TRACER_MAP(events, 1024 * 256);
+GADGET_TRACER(throttling, events, event);
+
+struct throttle_t {
+	__u64 ts;
+	gadget_mntns_id mntns_id;
+};
+
+// Throttled run queues, there is one per CPU and cgroup
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, struct cfs_rq___ig *);
+	__type(value, struct throttle_t);
+} throttles SEC(".maps");
+
+// Running averages of the throttled time, per cgroup id
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, __u64);
+	__type(value, __u64);
+} averages SEC(".maps");
+
+// cfs_rq_mntns_id returns the mount namespace of the task running on the run
+// queue, walking down the nested task groups, or 0 if there isn't any
+static __always_inline gadget_mntns_id
+cfs_rq_mntns_id(struct cfs_rq___ig *cfs_rq)
+{
+	struct sched_entity *se;
+	struct cfs_rq___ig *my_q;
+	struct task_struct *task;
+
+	for (int i = 0; i < MAX_GROUP_DEPTH; i++) {
+		se = BPF_CORE_READ(cfs_rq, curr);
+		if (!se)
+			return 0;
+
+		my_q = (struct cfs_rq___ig *)BPF_CORE_READ(se, my_q);
+		if (!my_q) {
+			task = container_of(se, struct task_struct, se);
+			return BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+		}
+		cfs_rq = my_q;
+	}
+	return 0;
+}
+
+SEC("kprobe/throttle_cfs_rq")
+int BPF_KPROBE(ig_throttle, struct cfs_rq___ig *cfs_rq)
+{
+	struct throttle_t throttle = {};
+
+	throttle.mntns_id = cfs_rq_mntns_id(cfs_rq);
+	if (gadget_should_discard_mntns_id(throttle.mntns_id))
+		return 0;
+
+	throttle.ts = bpf_ktime_get_ns();
+	bpf_map_update_elem(&throttles, &cfs_rq, &throttle, BPF_ANY);
+	return 0;
+}
+
+SEC("kprobe/unthrottle_cfs_rq")
+int BPF_KPROBE(ig_unthrottle, struct cfs_rq___ig *cfs_rq)
+{
+	struct throttle_t *throttle;
+	struct task_group___ig *tg;
+	struct cgroup *cgrp;
+	struct event *event;
+	__u64 *avgp, avg;
+	__u64 delta;
+	u32 nr_periods;
+
+	throttle = bpf_map_lookup_elem(&throttles, &cfs_rq);
+	if (!throttle)
+		return 0;
+
+	delta = bpf_ktime_get_ns() - throttle->ts;
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		goto cleanup;
+
+	tg = BPF_CORE_READ(cfs_rq, tg);
+	cgrp = BPF_CORE_READ(tg, css.cgroup);
+
+	event->timestamp_raw = bpf_ktime_get_boot_ns();
+	event->mntns_id = throttle->mntns_id;
+	event->cgroup_id = BPF_CORE_READ(cgrp, kn, id);
+	bpf_probe_read_kernel_str(event->cgroup, sizeof(event->cgroup),
+				  BPF_CORE_READ(cgrp, kn, name));
+	event->cpu = BPF_CORE_READ(cfs_rq, rq, cpu);
+	event->throttled_raw = delta;
+
+	avgp = bpf_map_lookup_elem(&averages, &event->cgroup_id);
+	if (avgp) {
+		avg = *avgp - (*avgp >> EWMA_SHIFT) + (delta >> EWMA_SHIFT);
+		*avgp = avg;
+	} else {
+		avg = delta;
+		bpf_map_update_elem(&averages, &event->cgroup_id, &avg,
+				    BPF_ANY);
+	}
+	event->avg_throttled_raw = avg;
+
+	event->period_raw = BPF_CORE_READ(tg, cfs_bandwidth.period);
+	event->quota_raw = BPF_CORE_READ(tg, cfs_bandwidth.quota);
+	event->total_throttled_raw =
+		BPF_CORE_READ(tg, cfs_bandwidth.throttled_time);
+	nr_periods = BPF_CORE_READ(tg, cfs_bandwidth.nr_periods);
+	event->nr_periods = nr_periods;
+	event->nr_throttled = BPF_CORE_READ(tg, cfs_bandwidth.nr_throttled);
+	event->throttled_pct =
+		nr_periods ? (__u64)event->nr_throttled * 100 / nr_periods : 0;
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+
+cleanup:
+	bpf_map_delete_elem(&throttles, &cfs_rq);
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
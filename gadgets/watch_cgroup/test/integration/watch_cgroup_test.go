@@ -0,0 +1,131 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	gadgettesting "github.com/inspektor-gadget/inspektor-gadget/gadgets/testing"
+	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
+	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/match"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+type watchCgroupEvent struct {
+	utils.CommonData
+
+	Timestamp string `json:"timestamp"`
+
+	Cgroup       string `json:"cgroup"`
+	CgroupID     uint64 `json:"cgroup_id"`
+	CPU          uint32 `json:"cpu"`
+	ThrottledRaw uint64 `json:"throttled_raw"`
+	PeriodRaw    uint64 `json:"period_raw"`
+	QuotaRaw     uint64 `json:"quota_raw"`
+}
+
+func TestWatchCgroup(t *testing.T) {
+	gadgettesting.RequireEnvironmentVariables(t)
+	utils.InitTest(t)
+
+	if utils.CurrentTestComponent != utils.KubectlGadgetTestComponent && utils.CurrentTestComponent != utils.IgK8sTestComponent {
+		// We have no general way to enforce CPU limits for all container runtimes
+		t.Skip("Test only runs for kubectl-gadget and ig-k8s")
+	}
+
+	containerFactory := &containers.K8sManager{}
+	containerName := "test-watch-cgroup"
+	containerImage := gadgettesting.BusyBoxImage
+
+	var ns string
+	containerOpts := []containers.ContainerOption{containers.WithContainerImage(containerImage)}
+
+	if utils.CurrentTestComponent == utils.KubectlGadgetTestComponent {
+		ns = utils.GenerateTestNamespaceName(t, "test-watch-cgroup")
+		containerOpts = append(containerOpts, containers.WithContainerNamespace(ns))
+	}
+
+	// 100m is a quota of 10ms every 100ms, the busy loop is throttled in
+	// every period
+	containerOpts = append(containerOpts, containers.WithLimits(map[string]string{"cpu": "100m"}), containers.WithStartAndStop())
+
+	testContainer := containerFactory.NewContainer(
+		containerName,
+		"while true; do :; done",
+		containerOpts...,
+	)
+
+	var runnerOpts []igrunner.Option
+	var testingOpts []igtesting.Option
+	commonDataOpts := []utils.CommonDataOption{utils.WithContainerImageName(containerImage)}
+
+	switch utils.CurrentTestComponent {
+	case utils.IgK8sTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-r=%s", utils.Runtime)), igrunner.WithStartAndStop())
+	case utils.KubectlGadgetTestComponent:
+		runnerOpts = append(runnerOpts, igrunner.WithFlags(fmt.Sprintf("-n=%s", ns)), igrunner.WithStartAndStop())
+		testingOpts = append(testingOpts, igtesting.WithCbBeforeCleanup(utils.PrintLogsFn(ns)))
+		commonDataOpts = append(commonDataOpts, utils.WithK8sNamespace(ns))
+	}
+
+	runnerOpts = append(runnerOpts, igrunner.WithValidateOutput(
+		func(t *testing.T, output string) {
+			expectedEntry := &watchCgroupEvent{
+				CommonData: utils.BuildCommonData(containerName, commonDataOpts...),
+				Timestamp:  utils.NormalizedStr,
+
+				// The quota of the pod or the one of the container can be
+				// reached first, both are the same
+				Cgroup:       utils.NormalizedStr,
+				CgroupID:     utils.NormalizedInt,
+				ThrottledRaw: utils.NormalizedInt,
+				PeriodRaw:    uint64(100 * time.Millisecond),
+				QuotaRaw:     uint64(10 * time.Millisecond),
+
+				// Manually normalize fields that might contain 0
+				CPU: 0,
+			}
+			expectedEntry.Runtime.ContainerID = utils.NormalizedStr
+
+			normalize := func(e *watchCgroupEvent) {
+				utils.NormalizeCommonData(&e.CommonData)
+				utils.NormalizeString(&e.Runtime.ContainerID)
+				utils.NormalizeString(&e.Timestamp)
+				utils.NormalizeString(&e.Cgroup)
+				utils.NormalizeInt(&e.CgroupID)
+				utils.NormalizeInt(&e.ThrottledRaw)
+
+				// Manually normalize fields that might contain 0
+				e.CPU = 0
+			}
+
+			match.MatchEntries(t, match.JSONMultiObjectMode, output, normalize, expectedEntry)
+		},
+	))
+
+	watchCgroupCmd := igrunner.New("watch_cgroup", runnerOpts...)
+
+	testSteps := []igtesting.TestStep{
+		watchCgroupCmd,
+		utils.Sleep(10 * time.Second),
+		testContainer,
+	}
+
+	igtesting.RunTestSteps(testSteps, t, testingOpts...)
+}
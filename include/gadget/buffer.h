@@ -18,6 +18,22 @@
 	} name SEC(".maps");                        \
 	const void *gadget_map_tracer_##name __attribute__((unused));
 
+// gadget_buf_lost and gadget_buf_sampling are used by Inspektor Gadget to
+// tune the buffers at runtime, never by the gadget itself. gadget_buf_lost
+// counts the events dropped because the ring buffers were full. When events
+// are dropped, gadget_buf_sampling can be set to N to keep only one event out
+// of N; 0 and 1 keep all of them. Events not kept are never sent, as if the
+// buffers were full, but they aren't counted as lost.
+volatile __u64 gadget_buf_lost = 0;
+volatile __u32 gadget_buf_sampling = 0;
+
+static __always_inline bool gadget_buf_sampled_out(void)
+{
+	__u32 sampling = gadget_buf_sampling;
+
+	return sampling > 1 && bpf_get_prandom_u32() % sampling != 0;
+}
+
 #ifndef GADGET_NO_BUF_RESERVE
 struct {
 	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
@@ -29,10 +45,18 @@ struct {
 static __always_inline void *gadget_reserve_buf(void *map, __u64 size)
 {
 	static const int zero = 0;
+	void *buf;
+
+	if (gadget_buf_sampled_out())
+		return NULL;
 
 	if (bpf_core_enum_value_exists(enum bpf_func_id,
-				       BPF_FUNC_ringbuf_reserve))
-		return bpf_ringbuf_reserve(map, size, 0);
+				       BPF_FUNC_ringbuf_reserve)) {
+		buf = bpf_ringbuf_reserve(map, size, 0);
+		if (!buf)
+			__sync_fetch_and_add(&gadget_buf_lost, 1);
+		return buf;
+	}
 
 	return bpf_map_lookup_elem(&gadget_heap, &zero);
 }
@@ -60,9 +84,13 @@ static __always_inline long gadget_submit_buf(void *ctx, void *map, void *buf,
 static __always_inline long gadget_output_buf(void *ctx, void *map, void *buf,
 					      __u64 size)
 {
+	if (gadget_buf_sampled_out())
+		return 0;
+
 	if (bpf_core_enum_value_exists(enum bpf_func_id,
 				       BPF_FUNC_ringbuf_output)) {
-		bpf_ringbuf_output(map, buf, size, 0);
+		if (bpf_ringbuf_output(map, buf, size, 0))
+			__sync_fetch_and_add(&gadget_buf_lost, 1);
 		return 0;
 	}
 
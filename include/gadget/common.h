@@ -34,4 +34,27 @@ void static __always_inline gadget_process_populate(struct gadget_process *p)
 	p->parent.pid = BPF_CORE_READ(parent, tgid);
 }
 
+// gadget_process_populate_task fills the given process struct with the
+// information of the given task, which doesn't need to be the current one.
+void static __always_inline
+gadget_process_populate_task(struct gadget_process *p, struct task_struct *task)
+{
+	p->pid = BPF_CORE_READ(task, tgid);
+	p->tid = BPF_CORE_READ(task, pid);
+	bpf_probe_read_kernel(&p->comm, sizeof(p->comm), task->comm);
+
+	p->creds.uid = BPF_CORE_READ(task, cred, uid.val);
+	p->creds.gid = BPF_CORE_READ(task, cred, gid.val);
+
+	p->mntns_id = BPF_CORE_READ(task, nsproxy, mnt_ns, ns.inum);
+
+	struct task_struct *parent = BPF_CORE_READ(task, real_parent);
+	if (parent == NULL)
+		return;
+
+	bpf_probe_read_kernel(&p->parent.comm, sizeof(p->parent.comm),
+			      parent->comm);
+	p->parent.pid = BPF_CORE_READ(parent, tgid);
+}
+
 #endif
@@ -0,0 +1,44 @@
+/* SPDX-License-Identifier: (GPL-2.0 WITH Linux-syscall-note) OR Apache-2.0 */
+
+// This file defines the helpers for gadgets that are able to block actions
+// using LSM programs. Those gadgets have to be marked with the
+// "ebpf.enforcement" annotation in their metadata.
+
+#ifndef ENFORCEMENT_H
+#define ENFORCEMENT_H
+
+#include <bpf/bpf_helpers.h>
+#include <gadget/types.h>
+
+// Keep in sync with verdictNames in pkg/operators/formatters/types.go
+#define GADGET_VERDICT_ALLOWED 0
+#define GADGET_VERDICT_BLOCKED 1
+#define GADGET_VERDICT_WOULD_BLOCK 2
+
+// gadget_enforce and gadget_enforce_dry_run are set by Inspektor Gadget,
+// never by the gadget itself. gadget_enforce is only true when the user
+// requested enforcement and the gadget is allowed to enforce by the
+// server-side policy. gadget_enforce_dry_run is true when the user requested
+// to only report the actions that would have been blocked.
+const volatile bool gadget_enforce = false;
+const volatile bool gadget_enforce_dry_run = false;
+
+// gadget_deny returns the value an LSM program has to return to deny an action
+// with the given error (e.g. -EPERM). When enforcement is disabled, the action
+// is allowed. If verdict isn't NULL, it's set to what happened to the action,
+// so it can be reported in the event together with the rule that matched.
+static __always_inline int gadget_deny(int err, gadget_verdict *verdict)
+{
+	if (gadget_enforce) {
+		if (verdict)
+			*verdict = GADGET_VERDICT_BLOCKED;
+		return err;
+	}
+
+	if (verdict)
+		*verdict = gadget_enforce_dry_run ? GADGET_VERDICT_WOULD_BLOCK :
+						    GADGET_VERDICT_ALLOWED;
+	return 0;
+}
+
+#endif /* ENFORCEMENT_H */
@@ -71,6 +71,11 @@ typedef __u32 gadget_file_mode;
 // The formatter operator adds a field with the string representation of the flags.
 typedef __u32 gadget_file_flags;
 
+// gadget_verdict is used by enforcement gadgets to report what happened to an
+// action, see gadget/enforcement.h.
+// The formatter operator adds a field with the string representation of the verdict.
+typedef __u32 gadget_verdict;
+
 typedef __u32 gadget_kernel_stack;
 
 struct gadget_user_stack {
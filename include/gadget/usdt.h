@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: (LGPL-2.1 OR BSD-2-Clause)
+// Copyright (c) 2025 The Inspektor Gadget authors
+
+#ifndef __USDT_H
+#define __USDT_H
+
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+// Arguments of USDT probes, like the ones of Python or PostgreSQL. The
+// locations of the arguments of each probe are read from its note by Inspektor
+// Gadget when attaching it, and stored in ig_usdt_specs with the BPF cookie of
+// the probe as key. Requires Linux 5.15 for bpf_get_attach_cookie().
+//
+// SEC("usdt/libc:libc:memory_malloc_retry")
+// int trace_malloc_retry(struct pt_regs *ctx)
+// {
+//	long bytes;
+//
+//	if (gadget_usdt_arg(ctx, 0, &bytes))
+//		return 0;
+//	...
+// }
+//
+// Keep in sync with pkg/uprobetracer/usdt_args.go
+
+#ifndef ENOENT
+#define ENOENT 2
+#endif
+#ifndef ESRCH
+#define ESRCH 3
+#endif
+#ifndef EINVAL
+#define EINVAL 22
+#endif
+
+#define GADGET_USDT_MAX_ARGS 12
+#define GADGET_USDT_MAX_SPECS 1024
+
+enum gadget_usdt_arg_type {
+	GADGET_USDT_ARG_CONST,
+	GADGET_USDT_ARG_REG,
+	GADGET_USDT_ARG_REG_DEREF,
+};
+
+struct gadget_usdt_arg_spec {
+	// Value of constants or offset of dereferences
+	__u64 val_off;
+	enum gadget_usdt_arg_type arg_type;
+	// Offset of the register in struct pt_regs
+	__s16 reg_off;
+	__u8 arg_signed;
+	// Shift to keep only the bits of the size of the argument
+	__s8 arg_bitshift;
+};
+
+struct gadget_usdt_spec {
+	struct gadget_usdt_arg_spec args[GADGET_USDT_MAX_ARGS];
+	__u64 arg_cnt;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, GADGET_USDT_MAX_SPECS);
+	__type(key, __u64);
+	__type(value, struct gadget_usdt_spec);
+} ig_usdt_specs SEC(".maps");
+
+/* Returns the number of arguments of the USDT probe, or a negative error */
+static __always_inline int gadget_usdt_arg_cnt(struct pt_regs *ctx)
+{
+	__u64 cookie = bpf_get_attach_cookie(ctx);
+	struct gadget_usdt_spec *spec;
+
+	spec = bpf_map_lookup_elem(&ig_usdt_specs, &cookie);
+	if (!spec)
+		return -ESRCH;
+	return spec->arg_cnt;
+}
+
+/* Reads the argument arg_num of the USDT probe, starting at 0, into res.
+ * Returns 0 on success, a negative error otherwise.
+ */
+static __always_inline int gadget_usdt_arg(struct pt_regs *ctx, __u64 arg_num,
+					   long *res)
+{
+	__u64 cookie = bpf_get_attach_cookie(ctx);
+	struct gadget_usdt_arg_spec *arg_spec;
+	struct gadget_usdt_spec *spec;
+	unsigned long val;
+	int err;
+
+	*res = 0;
+
+	spec = bpf_map_lookup_elem(&ig_usdt_specs, &cookie);
+	if (!spec)
+		return -ESRCH;
+	if (arg_num >= GADGET_USDT_MAX_ARGS || arg_num >= spec->arg_cnt)
+		return -ENOENT;
+
+	arg_spec = &spec->args[arg_num];
+	switch (arg_spec->arg_type) {
+	case GADGET_USDT_ARG_CONST:
+		val = arg_spec->val_off;
+		break;
+	case GADGET_USDT_ARG_REG:
+		err = bpf_probe_read_kernel(&val, sizeof(val),
+					    (void *)ctx + arg_spec->reg_off);
+		if (err)
+			return err;
+		break;
+	case GADGET_USDT_ARG_REG_DEREF:
+		err = bpf_probe_read_kernel(&val, sizeof(val),
+					    (void *)ctx + arg_spec->reg_off);
+		if (err)
+			return err;
+		err = bpf_probe_read_user(&val, sizeof(val),
+					  (void *)val + arg_spec->val_off);
+		if (err)
+			return err;
+#if __BYTE_ORDER__ == __ORDER_BIG_ENDIAN__
+		val >>= arg_spec->arg_bitshift;
+#endif
+		break;
+	default:
+		return -EINVAL;
+	}
+
+	// Keep only the bits of the size of the argument, extending the sign
+	val <<= arg_spec->arg_bitshift;
+	if (arg_spec->arg_signed)
+		val = ((long)val) >> arg_spec->arg_bitshift;
+	else
+		val = val >> arg_spec->arg_bitshift;
+	*res = val;
+	return 0;
+}
+
+#endif /* __USDT_H */
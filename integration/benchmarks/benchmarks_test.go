@@ -32,6 +32,7 @@ import (
 	igtesting "github.com/inspektor-gadget/inspektor-gadget/pkg/testing"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/containers"
 	igrunner "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/ig"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/latency"
 	statsrecorder "github.com/inspektor-gadget/inspektor-gadget/pkg/testing/stats"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
 )
@@ -222,20 +223,6 @@ func TestBenchmarks(t *testing.T) {
 	}
 }
 
-type linesCounter struct {
-	lines uint64
-}
-
-func (c *linesCounter) Write(p []byte) (n int, err error) {
-	// Count the number of lines in the input
-	for _, b := range p {
-		if b == '\n' {
-			c.lines++
-		}
-	}
-	return len(p), nil
-}
-
 // singleRunResult holds the results of a single run of a gadget test
 type RunResult struct {
 	TestName        string
@@ -247,16 +234,18 @@ type RunResult struct {
 	IgCpu           float64
 	IgMem           float64
 	Lost            uint64
+	LatencyP50      time.Duration
+	LatencyP99      time.Duration
 }
 
 func (r *RunResult) HeaderString() string {
 	// format as csv header line
-	return "TestName,TestCase,Run,EventsPerSecond,Cpu,Mem,IgCpu,IgMem,Lost"
+	return "TestName,TestCase,Run,EventsPerSecond,Cpu,Mem,IgCpu,IgMem,Lost,LatencyP50Ms,LatencyP99Ms"
 }
 
 func (r *RunResult) String() string {
 	// format as csv line
-	return fmt.Sprintf("%s,%s,%d,%d,%.2f,%.2f,%.2f,%.2f,%d",
+	return fmt.Sprintf("%s,%s,%d,%d,%.2f,%.2f,%.2f,%.2f,%d,%.3f,%.3f",
 		r.TestName,
 		r.TestCase,
 		r.Run,
@@ -266,6 +255,8 @@ func (r *RunResult) String() string {
 		r.IgCpu,
 		r.IgMem,
 		r.Lost,
+		float64(r.LatencyP50)/float64(time.Millisecond),
+		float64(r.LatencyP99)/float64(time.Millisecond),
 	)
 }
 
@@ -343,7 +334,7 @@ func testGadgetSingle(t *testing.T, bc *BenchmarkConfig, tc *TestConfig, eventsP
 	lost := uint64(0)
 
 	var gadgetCmd igtesting.TestStep
-	var lWriter *linesCounter
+	var lWriter *latency.Recorder
 	if usetracer {
 		// Count dropped events.
 		runnerOpts = append(runnerOpts, igrunner.WithValidateStderrOutput(func(t *testing.T, output string) {
@@ -366,8 +357,9 @@ func testGadgetSingle(t *testing.T, bc *BenchmarkConfig, tc *TestConfig, eventsP
 			}
 		}))
 
-		// check that the gadget captured data
-		lWriter = &linesCounter{}
+		// check that the gadget captured data and measure how long events take
+		// to reach ig
+		lWriter = latency.NewRecorder()
 		runnerOpts = append(runnerOpts, igrunner.WithStdOutWriter(lWriter))
 		gadgetCmd = igrunner.New(tc.GadgetName, runnerOpts...)
 	} else {
@@ -398,12 +390,16 @@ func testGadgetSingle(t *testing.T, bc *BenchmarkConfig, tc *TestConfig, eventsP
 
 	igtesting.RunTestSteps(steps, t, testingOpts...)
 
+	var latencyStats latency.Stats
 	if lWriter != nil {
-		require.Greater(t, lWriter.lines, uint64(0), "Gadget %s should have captured some data", tc.GadgetName)
+		require.Greater(t, lWriter.Lines(), uint64(0), "Gadget %s should have captured some data", tc.GadgetName)
 
 		// for tracers, check if they captured enough events
 		if strings.HasPrefix(tc.GadgetName, "trace_") {
-			require.Greater(t, float64(lWriter.lines), 0.95*float64(runDuration*eventsPerSecond))
+			require.Greater(t, float64(lWriter.Lines()), 0.95*float64(runDuration*eventsPerSecond))
+
+			// Thresholds are only enforced if set in the environment
+			latencyStats = latency.RequireLatency(t, lWriter, latency.Thresholds{})
 		}
 	}
 
@@ -413,6 +409,9 @@ func testGadgetSingle(t *testing.T, bc *BenchmarkConfig, tc *TestConfig, eventsP
 		Cpu:  cpuAndMemoryAvg.System.CPUPercentage,
 		Mem:  float64(cpuAndMemoryAvg.System.Memory) / (1024 * 1024),
 		Lost: lost / uint64(runDuration), // Convert lost events to per second
+
+		LatencyP50: latencyStats.P50,
+		LatencyP99: latencyStats.P99,
 	}
 
 	if usetracer {
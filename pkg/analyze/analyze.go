@@ -0,0 +1,442 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package analyze works on the events captured by running gadgets with the
+// JSON output mode, like "ig run trace_tcp -o json > capture.json", without
+// access to the environment they were captured in.
+package analyze
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/histogram"
+)
+
+// Event is a captured event, as decoded from JSON. Numbers are kept as
+// json.Number to not lose the precision of 64 bit integers.
+type Event map[string]any
+
+// Get returns the value of the field at path, the JSON names of the fields
+// separated by dots like "src.addr"
+func (e Event) Get(path string) (any, bool) {
+	m := map[string]any(e)
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		m = next
+	}
+	value, ok := m[parts[len(parts)-1]]
+	return value, ok
+}
+
+// GetString returns the value of the field at path formatted as a string; an
+// empty string if the field is missing
+func (e Event) GetString(path string) string {
+	value, ok := e.Get(path)
+	if !ok || value == nil {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// GetNumber returns the value of the field at path as a number
+func (e Event) GetNumber(path string) (float64, bool) {
+	value, ok := e.Get(path)
+	if !ok {
+		return 0, false
+	}
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil
+}
+
+// Read decodes the events of r: JSON objects, one per event, or JSON arrays of
+// events as printed by gadgets like snapshot_process
+func Read(r io.Reader) ([]Event, error) {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	decoder.UseNumber()
+
+	var events []Event
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return events, fmt.Errorf("decoding event %d: %w", len(events), err)
+		}
+
+		d := json.NewDecoder(strings.NewReader(string(raw)))
+		d.UseNumber()
+		if len(raw) > 0 && raw[0] == '[' {
+			var batch []Event
+			if err := d.Decode(&batch); err != nil {
+				return events, fmt.Errorf("decoding events %d: %w", len(events), err)
+			}
+			events = append(events, batch...)
+			continue
+		}
+		var event Event
+		if err := d.Decode(&event); err != nil {
+			return events, fmt.Errorf("decoding event %d: %w", len(events), err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Load reads the events of a capture: a file, compressed with gzip if its name
+// ends with ".gz", or a directory like an extracted support bundle in which
+// all the files ending with ".json", ".jsonl" or their ".gz" variants are read
+func Load(path string) ([]Event, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadFile(path)
+	}
+
+	var events []Event
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name := strings.TrimSuffix(d.Name(), ".gz")
+		if !strings.HasSuffix(name, ".json") && !strings.HasSuffix(name, ".jsonl") {
+			return nil
+		}
+		fileEvents, err := loadFile(p)
+		if err != nil {
+			return err
+		}
+		events = append(events, fileEvents...)
+		return nil
+	})
+	return events, err
+}
+
+func loadFile(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing %q: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	events, err := Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading %q: %w", path, err)
+	}
+	return events, nil
+}
+
+type comparison int
+
+const (
+	comparisonEqual comparison = iota
+	comparisonRegex
+	comparisonLt
+	comparisonLte
+	comparisonGt
+	comparisonGte
+)
+
+// Filter selects events; see ParseFilter
+type Filter func(Event) bool
+
+var operators = map[string]struct {
+	comparison comparison
+	negate     bool
+}{
+	"=":  {comparisonEqual, false},
+	"==": {comparisonEqual, false},
+	"!=": {comparisonEqual, true},
+	"~":  {comparisonRegex, false},
+	"!~": {comparisonRegex, true},
+	"<":  {comparisonLt, false},
+	"<=": {comparisonLte, false},
+	">":  {comparisonGt, false},
+	">=": {comparisonGte, false},
+}
+
+// ParseFilter parses a filter using the syntax of the --filter flag of the
+// gadgets: comma separated rules like "proc.comm==curl,dst.port>=1024" that
+// must all match. Values are compared as numbers if both sides are numbers and
+// as strings otherwise; "~" matches a regular expression.
+func ParseFilter(filter string) (Filter, error) {
+	var rules []Filter
+	for _, rule := range api.SplitStringWithEscape(filter, ',') {
+		if rule == "" {
+			continue
+		}
+		r, err := parseRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return func(e Event) bool {
+		for _, rule := range rules {
+			if !rule(e) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func parseRule(rule string) (Filter, error) {
+	const operatorChars = "!~<>="
+
+	start := strings.IndexAny(rule, operatorChars)
+	if start <= 0 {
+		return nil, fmt.Errorf("invalid filter rule %q", rule)
+	}
+	end := start
+	for end < len(rule) && strings.IndexByte(operatorChars, rule[end]) >= 0 {
+		end++
+	}
+
+	o, ok := operators[rule[start:end]]
+	if !ok {
+		return nil, fmt.Errorf("invalid operation %q in filter rule %q", rule[start:end], rule)
+	}
+	match, err := compareFunc(rule[:start], o.comparison, rule[end:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter rule %q: %w", rule, err)
+	}
+	if o.negate {
+		return func(e Event) bool { return !match(e) }, nil
+	}
+	return match, nil
+}
+
+func compareFunc(field string, c comparison, value string) (Filter, error) {
+	if c == comparisonRegex {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(e Event) bool {
+			return re.MatchString(e.GetString(field))
+		}, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	isNumber := err == nil
+	return func(e Event) bool {
+		var cmp int
+		if n, ok := e.GetNumber(field); ok && isNumber {
+			cmp = compareNumbers(n, number)
+		} else {
+			cmp = strings.Compare(e.GetString(field), value)
+		}
+		switch c {
+		case comparisonLt:
+			return cmp < 0
+		case comparisonLte:
+			return cmp <= 0
+		case comparisonGt:
+			return cmp > 0
+		case comparisonGte:
+			return cmp >= 0
+		default:
+			return cmp == 0
+		}
+	}, nil
+}
+
+func compareNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Select returns the events matching filter
+func Select(events []Event, filter Filter) []Event {
+	if filter == nil {
+		return events
+	}
+	var selected []Event
+	for _, e := range events {
+		if filter(e) {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+// Project returns an event containing only the given fields of e, keeping
+// their nesting
+func Project(e Event, fields []string) Event {
+	if len(fields) == 0 {
+		return e
+	}
+	out := Event{}
+	for _, field := range fields {
+		value, ok := e.Get(field)
+		if !ok {
+			continue
+		}
+		m := map[string]any(out)
+		parts := strings.Split(field, ".")
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := m[part].(map[string]any)
+			if !ok {
+				next = map[string]any{}
+				m[part] = next
+			}
+			m = next
+		}
+		m[parts[len(parts)-1]] = value
+	}
+	return out
+}
+
+// TopEntry is a distinct combination of the values of the key fields
+type TopEntry struct {
+	Key   []string
+	Count uint64
+	// Sum is the sum of the values of the summed field, if any
+	Sum float64
+}
+
+// Top groups events by the values of the keys fields and returns the
+// limit groups with the highest sum of the values of the sum field, or the
+// highest number of events if sum is empty. limit <= 0 returns all the groups.
+func Top(events []Event, keys []string, sum string, limit int) []TopEntry {
+	index := map[string]int{}
+	var entries []TopEntry
+
+	for _, e := range events {
+		key := make([]string, 0, len(keys))
+		for _, k := range keys {
+			key = append(key, e.GetString(k))
+		}
+		id := strings.Join(key, "\x00")
+
+		i, ok := index[id]
+		if !ok {
+			i = len(entries)
+			index[id] = i
+			entries = append(entries, TopEntry{Key: key})
+		}
+		entries[i].Count++
+		if sum != "" {
+			if n, ok := e.GetNumber(sum); ok {
+				entries[i].Sum += n
+			}
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a, b TopEntry) int {
+		if sum != "" && a.Sum != b.Sum {
+			return -compareNumbers(a.Sum, b.Sum)
+		}
+		if a.Count != b.Count {
+			if a.Count > b.Count {
+				return -1
+			}
+			return 1
+		}
+		return slices.Compare(a.Key, b.Key)
+	})
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
+
+// Histogram returns the distribution of the values of field in power-of-2
+// intervals, like the histograms of the profile gadgets. Negative values and
+// events without the field are skipped and counted in the returned number.
+func Histogram(events []Event, field string) (*histogram.Histogram, uint64) {
+	var slots []uint32
+	var skipped uint64
+
+	for _, e := range events {
+		n, ok := e.GetNumber(field)
+		if !ok || n < 0 || math.IsNaN(n) {
+			skipped++
+			continue
+		}
+
+		v := uint64(n)
+		if n >= math.MaxUint64 {
+			v = math.MaxUint64
+		}
+		// Slot i holds the values in [2^i, 2^(i+1)-1], slot 0 also holds 0
+		slot := 0
+		if v > 0 {
+			slot = bits.Len64(v) - 1
+		}
+		for len(slots) <= slot {
+			slots = append(slots, 0)
+		}
+		slots[slot]++
+	}
+
+	return &histogram.Histogram{
+		Intervals: histogram.NewIntervalsFromExp2Slots(slots),
+	}, skipped
+}
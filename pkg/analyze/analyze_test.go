@@ -0,0 +1,168 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/histogram"
+)
+
+const capture = `{"proc":{"comm":"curl","pid":10},"dst":{"addr":"10.0.0.1","port":80},"size":100}
+{"proc":{"comm":"curl","pid":10},"dst":{"addr":"10.0.0.1","port":80},"size":300}
+{"proc":{"comm":"wget","pid":11},"dst":{"addr":"10.0.0.2","port":443},"size":1000}
+[{"proc":{"comm":"nginx","pid":12},"dst":{"addr":"10.0.0.3","port":8080},"size":5}]
+`
+
+func readCapture(t *testing.T) []Event {
+	t.Helper()
+	events, err := Read(strings.NewReader(capture))
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	return events
+}
+
+func TestRead(t *testing.T) {
+	events := readCapture(t)
+	assert.Equal(t, "curl", events[0].GetString("proc.comm"))
+	assert.Equal(t, "80", events[0].GetString("dst.port"))
+	assert.Equal(t, "nginx", events[3].GetString("proc.comm"))
+	assert.Equal(t, "", events[0].GetString("missing.field"))
+
+	n, ok := events[2].GetNumber("size")
+	require.True(t, ok)
+	assert.Equal(t, 1000.0, n)
+
+	_, err := Read(strings.NewReader(`{"a": 1} {"a":`))
+	require.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(capture), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ignored.txt"), []byte("not json"), 0o600))
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "node1"), 0o700))
+	f, err := os.Create(filepath.Join(dir, "node1", "b.jsonl.gz"))
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write([]byte(capture))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+
+	events, err := Load(filepath.Join(dir, "a.json"))
+	require.NoError(t, err)
+	assert.Len(t, events, 4)
+
+	events, err = Load(dir)
+	require.NoError(t, err)
+	assert.Len(t, events, 8)
+
+	_, err = Load(filepath.Join(dir, "missing.json"))
+	require.Error(t, err)
+}
+
+func TestParseFilter(t *testing.T) {
+	events := readCapture(t)
+
+	tests := []struct {
+		filter   string
+		expected []string
+	}{
+		{"", []string{"curl", "curl", "wget", "nginx"}},
+		{"proc.comm==curl", []string{"curl", "curl"}},
+		{"proc.comm=curl", []string{"curl", "curl"}},
+		{"proc.comm!=curl", []string{"wget", "nginx"}},
+		{"proc.comm~^(w|n)", []string{"wget", "nginx"}},
+		{"proc.comm!~^c", []string{"wget", "nginx"}},
+		{"dst.port>80", []string{"wget", "nginx"}},
+		{"dst.port>=443,size<100", []string{"nginx"}},
+		{"size<=100", []string{"curl", "nginx"}},
+		{"dst.addr==10.0.0.2", []string{"wget"}},
+		{"missing==x", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			filter, err := ParseFilter(test.filter)
+			require.NoError(t, err)
+
+			var comms []string
+			for _, e := range Select(events, filter) {
+				comms = append(comms, e.GetString("proc.comm"))
+			}
+			assert.Equal(t, test.expected, comms)
+		})
+	}
+
+	for _, invalid := range []string{"comm", "==curl", "comm~(", "comm=!curl"} {
+		_, err := ParseFilter(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestProject(t *testing.T) {
+	events := readCapture(t)
+	assert.Equal(t, Event{
+		"proc": map[string]any{"comm": "curl"},
+		"size": events[0]["size"],
+	}, Project(events[0], []string{"proc.comm", "size", "missing"}))
+	assert.Equal(t, events[0], Project(events[0], nil))
+}
+
+func TestTop(t *testing.T) {
+	events := readCapture(t)
+
+	assert.Equal(t, []TopEntry{
+		{Key: []string{"curl", "10.0.0.1"}, Count: 2},
+		{Key: []string{"nginx", "10.0.0.3"}, Count: 1},
+		{Key: []string{"wget", "10.0.0.2"}, Count: 1},
+	}, Top(events, []string{"proc.comm", "dst.addr"}, "", 0))
+
+	assert.Equal(t, []TopEntry{
+		{Key: []string{"wget"}, Count: 1, Sum: 1000},
+		{Key: []string{"curl"}, Count: 2, Sum: 400},
+	}, Top(events, []string{"proc.comm"}, "size", 2))
+}
+
+func TestHistogram(t *testing.T) {
+	events := readCapture(t)
+
+	h, skipped := Histogram(events, "size")
+	assert.Equal(t, uint64(0), skipped)
+	// 5 -> [4, 7], 100 -> [64, 127], 300 -> [256, 511], 1000 -> [512, 1023]
+	expected := make([]histogram.Interval, 10)
+	for i := range expected {
+		start := uint64(1) << i
+		expected[i] = histogram.Interval{Start: start, End: 2*start - 1}
+	}
+	expected[0].Start = 0
+	expected[2].Count = 1
+	expected[6].Count = 1
+	expected[8].Count = 1
+	expected[9].Count = 1
+	assert.Equal(t, expected, h.Intervals)
+
+	_, skipped = Histogram(events, "proc.comm")
+	assert.Equal(t, uint64(4), skipped)
+}
@@ -116,7 +116,7 @@ func GetOSInfo() (*OsInfo, error) {
 
 		switch parts[0] {
 		case "ID":
-			osInfo.ID = parts[1]
+			osInfo.ID = strings.Trim(parts[1], "\"")
 		case "VERSION_ID":
 			osInfo.VersionID = strings.Trim(parts[1], "\"")
 		}
@@ -0,0 +1,165 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btfgen
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/ulikunitz/xz"
+)
+
+// maxBTFHubFileSize limits the size of the files downloaded from BTFHub, they
+// are a few MiB
+const maxBTFHubFileSize = 128 * 1024 * 1024
+
+// KernelHasBTF returns whether the running kernel exposes BTF information
+var KernelHasBTF = sync.OnceValue(func() bool {
+	_, err := btf.LoadKernelSpec()
+	return err == nil
+})
+
+// BTFHubPath returns the path of the BTF file of a kernel in BTFHub archives,
+// like "ubuntu/20.04/x86_64/5.4.0-91-generic.btf.tar.xz"
+func BTFHubPath(info *OsInfo) string {
+	return fmt.Sprintf("%s/%s/%s/%s.btf.tar.xz", info.ID, info.VersionID, info.Arch, info.Kernel)
+}
+
+// LoadBTFHubSpec loads the BTF of a kernel from a BTFHub archive. The file is
+// looked up in archiveDir first, a local copy of the archive, then downloaded
+// from baseURL, like https://github.com/aquasecurity/btfhub-archive/raw/main,
+// and stored in archiveDir to avoid downloading it again. Any of them can be
+// empty.
+func LoadBTFHubSpec(ctx context.Context, info *OsInfo, archiveDir, baseURL string) (*btf.Spec, error) {
+	if archiveDir == "" && baseURL == "" {
+		return nil, errors.New("no BTFHub archive configured")
+	}
+
+	filePath := BTFHubPath(info)
+	if archiveDir != "" {
+		f, err := os.Open(filepath.Join(archiveDir, filePath))
+		if err == nil {
+			defer f.Close()
+			return loadBTFHubFile(f)
+		}
+		if !errors.Is(err, os.ErrNotExist) || baseURL == "" {
+			return nil, fmt.Errorf("opening BTF file: %w", err)
+		}
+	}
+
+	b, err := download(ctx, strings.TrimSuffix(baseURL, "/")+"/"+filePath)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := loadBTFHubFile(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	if archiveDir != "" {
+		if err := store(filepath.Join(archiveDir, filePath), b); err != nil {
+			return nil, fmt.Errorf("storing BTF file: %w", err)
+		}
+	}
+	return spec, nil
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading BTF file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading BTF file %q: %s", url, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxBTFHubFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("downloading BTF file: %w", err)
+	}
+	if len(b) > maxBTFHubFileSize {
+		return nil, fmt.Errorf("BTF file %q is bigger than %d bytes", url, maxBTFHubFileSize)
+	}
+	return b, nil
+}
+
+// store writes a file atomically, so concurrent gadgets never read partial
+// files
+func store(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".btf-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// loadBTFHubFile loads the BTF spec of a .btf.tar.xz file of BTFHub
+func loadBTFHubFile(r io.Reader) (*btf.Spec, error) {
+	xzr, err := xz.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing BTF file: %w", err)
+	}
+
+	tr := tar.NewReader(xzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("BTF file not found in archive")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading tar: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasSuffix(hdr.Name, ".btf") {
+			continue
+		}
+
+		b, err := io.ReadAll(io.LimitReader(tr, maxBTFHubFileSize))
+		if err != nil {
+			return nil, fmt.Errorf("reading BTF: %w", err)
+		}
+		spec, err := btf.LoadSpecFromReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("loading BTF spec: %w", err)
+		}
+		return spec, nil
+	}
+}
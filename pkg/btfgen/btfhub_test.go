@@ -0,0 +1,122 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btfgen
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+var testOsInfo = &OsInfo{
+	ID:        "ubuntu",
+	VersionID: "20.04",
+	Arch:      "x86_64",
+	Kernel:    "5.4.0-91-generic",
+}
+
+// btfHubFile returns a .btf.tar.xz file like the ones of BTFHub, with a BTF
+// containing a single type
+func btfHubFile(t *testing.T) []byte {
+	t.Helper()
+
+	b, err := btf.NewBuilder([]btf.Type{&btf.Int{Name: "test_int", Size: 4}})
+	require.NoError(t, err)
+	raw, err := b.Marshal(nil, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	xzw, err := xz.NewWriter(&buf)
+	require.NoError(t, err)
+	tw := tar.NewWriter(xzw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{
+		Name:     testOsInfo.Kernel + ".btf",
+		Typeflag: tar.TypeReg,
+		Mode:     0o644,
+		Size:     int64(len(raw)),
+	}))
+	_, err = tw.Write(raw)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, xzw.Close())
+	return buf.Bytes()
+}
+
+func requireTestSpec(t *testing.T, spec *btf.Spec) {
+	t.Helper()
+
+	var typ *btf.Int
+	require.NoError(t, spec.TypeByName("test_int", &typ))
+}
+
+func TestBTFHubPath(t *testing.T) {
+	require.Equal(t, "ubuntu/20.04/x86_64/5.4.0-91-generic.btf.tar.xz", BTFHubPath(testOsInfo))
+}
+
+func TestLoadBTFHubSpecFromArchive(t *testing.T) {
+	archiveDir := t.TempDir()
+	path := filepath.Join(archiveDir, BTFHubPath(testOsInfo))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, btfHubFile(t), 0o644))
+
+	spec, err := LoadBTFHubSpec(context.Background(), testOsInfo, archiveDir, "")
+	require.NoError(t, err)
+	requireTestSpec(t, spec)
+
+	_, err = LoadBTFHubSpec(context.Background(), &OsInfo{ID: "debian"}, archiveDir, "")
+	require.Error(t, err)
+}
+
+func TestLoadBTFHubSpecDownload(t *testing.T) {
+	file := btfHubFile(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/archive/"+BTFHubPath(testOsInfo) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(file)
+	}))
+	defer server.Close()
+
+	archiveDir := t.TempDir()
+
+	spec, err := LoadBTFHubSpec(context.Background(), testOsInfo, archiveDir, server.URL+"/archive/")
+	require.NoError(t, err)
+	requireTestSpec(t, spec)
+	require.FileExists(t, filepath.Join(archiveDir, BTFHubPath(testOsInfo)))
+
+	// The stored file is used afterwards
+	spec, err = LoadBTFHubSpec(context.Background(), testOsInfo, archiveDir, server.URL+"/archive")
+	require.NoError(t, err)
+	requireTestSpec(t, spec)
+	require.Equal(t, 1, requests)
+
+	_, err = LoadBTFHubSpec(context.Background(), &OsInfo{ID: "debian"}, "", server.URL)
+	require.Error(t, err)
+
+	_, err = LoadBTFHubSpec(context.Background(), testOsInfo, "", "")
+	require.Error(t, err)
+}
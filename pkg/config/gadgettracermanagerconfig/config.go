@@ -30,6 +30,41 @@ const (
 	PodmanSocketPath      = "podman-socketpath"
 	GadgetNamespace       = "gadget-namespace"
 	DaemonLogLevel        = "daemon-log-level"
+	ChecksumKeyFile       = "checksum-key-file"
+
+	DebugShellEnabled       = "debug-shell.enabled"
+	DebugShellMaxDuration   = "debug-shell.max-duration"
+	DebugShellAuditExporter = "debug-shell.audit-exporter"
+
+	EventBufferDir      = "event-buffer.dir"
+	EventBufferMaxBytes = "event-buffer.max-bytes"
+	EventBufferMaxAge   = "event-buffer.max-age"
+
+	TLSKeyFile      = "tls.key-file"
+	TLSCertFile     = "tls.cert-file"
+	TLSClientCAFile = "tls.client-ca-file"
+
+	AuthorizationPolicyFile      = "authorization.policy-file"
+	AuthorizationPolicyConfigMap = "authorization.policy-configmap"
+
+	AuditFile      = "audit.file"
+	AuditK8sEvents = "audit.k8s-events"
+
+	QuotaMaxRunningGadgets          = "quota.max-running-gadgets"
+	QuotaMaxRunningGadgetsPerClient = "quota.max-running-gadgets-per-client"
+	QuotaMaxInstancesPerNamespace   = "quota.max-instances-per-namespace"
+	QuotaMaxInstancesPerTag         = "quota.max-instances-per-tag"
+	QuotaRequestsPerSecond          = "quota.requests-per-second"
+	QuotaRequestBurst               = "quota.request-burst"
+	QuotaClientRequestsPerSecond    = "quota.client-requests-per-second"
+	QuotaClientRequestBurst         = "quota.client-request-burst"
+	QuotaGadgetMaxPrograms          = "quota.gadget-max-programs"
+	QuotaGadgetMaxMapMemory         = "quota.gadget-max-map-memory"
+	QuotaGadgetMaxBufferMemory      = "quota.gadget-max-buffer-memory"
+
+	ResultCacheTTL = "result-cache-ttl"
+
+	MetricsAddress = "metrics-address"
 
 	VerifyImage        = "verify-image"
 	PublicKeys         = "public-keys"
@@ -77,7 +112,7 @@ func FullKeyPath(key string) string {
 func isRootKey(key string) bool {
 	switch key {
 	case EventsBufferLengthKey, ContainerdSocketPath, CrioSocketPath, DockerSocketPath,
-		PodmanSocketPath, GadgetNamespace, DaemonLogLevel:
+		PodmanSocketPath, GadgetNamespace, DaemonLogLevel, ChecksumKeyFile:
 		return true
 	default:
 		return false
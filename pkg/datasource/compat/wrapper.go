@@ -33,6 +33,11 @@ func init() {
 const (
 	MntNsIdType = "type:" + ebpftypes.MntNsTypeName
 	NetNsIdType = "type:" + ebpftypes.NetNsTypeName
+
+	// AnnotationEnrichContainer can be set to "true" on a mntns field other
+	// than the first one of a DataSource, to enrich it with its own container.
+	// The k8s and runtime fields are then added to the struct holding it.
+	AnnotationEnrichContainer = "enrichment.container"
 )
 
 type EventWrapperBase struct {
@@ -54,8 +59,14 @@ type EventWrapperBase struct {
 	podLabelsAccessor            datasource.FieldAccessor
 	ownerKindAccessor            datasource.FieldAccessor
 	ownerNameAccessor            datasource.FieldAccessor
+
+	// nested holds the wrappers of the other mntns fields enriched with
+	// AnnotationEnrichContainer
+	nested []*EventWrapperBase
 }
 
+type addFieldFunc func(name string, kind api.Kind, opts ...datasource.FieldOption) (datasource.FieldAccessor, error)
+
 type (
 	MntNsEnrichFunc func(event operators.ContainerInfoFromMountNSID) bool
 	NetNsEnrichFunc func(event operators.ContainerInfoFromNetNSID) bool
@@ -77,13 +88,32 @@ func GetEventWrappers(gadgetCtx operators.GadgetContext) (map[datasource.DataSou
 
 		var mntnsField datasource.FieldAccessor
 		var netnsField datasource.FieldAccessor
+		var nested []*EventWrapperBase
 
 		if len(mntnsFields) > 0 {
 			gadgetCtx.Logger().Debugf("using mntns enrichment")
 			mntnsField = mntnsFields[0]
 
-			// We only support one of those per DataSource for now
-			if len(mntnsFields) > 1 {
+			ignored := false
+			for _, f := range mntnsFields[1:] {
+				parent := f.Parent()
+				if f.Annotations()[AnnotationEnrichContainer] != "true" || parent == nil {
+					ignored = true
+					continue
+				}
+
+				gadgetCtx.Logger().Debugf("using mntns enrichment for %q", f.FullName())
+				n, err := wrapAccessors(parent.AddSubField, f, nil)
+				if err != nil {
+					return nil, fmt.Errorf("registering accessors for %q: %w", f.FullName(), err)
+				}
+				// The node is the same for all the containers of an event
+				n.nodeAccessor.SetHidden(true, false)
+				nested = append(nested, n)
+			}
+
+			// Other fields need to be explicitly enriched in their struct
+			if ignored {
 				gadgetCtx.Logger().Warnf("multiple fields of %q found in DataSource %q, only %q will be used",
 					MntNsIdType, ds.Name(), mntnsField.Name())
 			}
@@ -104,6 +134,7 @@ func GetEventWrappers(gadgetCtx operators.GadgetContext) (map[datasource.DataSou
 		if err != nil {
 			return nil, fmt.Errorf("registering accessors: %w", err)
 		}
+		accessors.nested = nested
 		res[ds] = accessors
 	}
 	return res, nil
@@ -134,19 +165,32 @@ func Subscribe(
 			if !enriched && wrapper.NetnsidAccessor != nil {
 				netNsEnrichFunc(&wr)
 			}
+			for _, n := range wrapper.nested {
+				mntNsEnrichFunc(&EventWrapper{EventWrapperBase: n, Data: data})
+			}
 			return nil
 		}, priority)
 	}
 }
 
 func WrapAccessors(source datasource.DataSource, mntnsidAccessor datasource.FieldAccessor, netnsidAccessor datasource.FieldAccessor) (*EventWrapperBase, error) {
+	ev, err := wrapAccessors(source.AddField, mntnsidAccessor, netnsidAccessor)
+	if err != nil {
+		return nil, err
+	}
+	ev.ds = source
+	return ev, nil
+}
+
+// wrapAccessors adds the k8s and runtime fields using addField, which adds
+// them either to the root of the DataSource or to a struct field
+func wrapAccessors(addField addFieldFunc, mntnsidAccessor datasource.FieldAccessor, netnsidAccessor datasource.FieldAccessor) (*EventWrapperBase, error) {
 	ev := &EventWrapperBase{
-		ds:              source,
 		MntnsidAccessor: mntnsidAccessor,
 		NetnsidAccessor: netnsidAccessor,
 	}
 
-	k8s, err := source.AddField("k8s", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+	k8s, err := addField("k8s", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
 	if err != nil {
 		return nil, err
 	}
@@ -248,7 +292,7 @@ func WrapAccessors(source datasource.DataSource, mntnsidAccessor datasource.Fiel
 		k8s.SetHidden(true, true)
 	}
 
-	runtime, err := source.AddField("runtime", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+	runtime, err := addField("runtime", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
 	if err != nil {
 		return nil, err
 	}
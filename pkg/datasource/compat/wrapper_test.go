@@ -0,0 +1,55 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func TestWrapAccessorsNested(t *testing.T) {
+	ds, err := datasource.New(datasource.TypeSingle, "test")
+	require.NoError(t, err)
+
+	mntns, err := ds.AddField("mntns_id", api.Kind_Uint64)
+	require.NoError(t, err)
+	tracee, err := ds.AddField("tracee", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+	require.NoError(t, err)
+	traceeMntns, err := tracee.AddSubField("mntns_id", api.Kind_Uint64)
+	require.NoError(t, err)
+
+	_, err = WrapAccessors(ds, mntns, nil)
+	require.NoError(t, err)
+	nested, err := wrapAccessors(tracee.AddSubField, traceeMntns, nil)
+	require.NoError(t, err)
+
+	for _, name := range []string{"k8s.podName", "runtime.containerName"} {
+		assert.NotNil(t, ds.GetField(name), name)
+		assert.NotNil(t, ds.GetField("tracee."+name), "tracee."+name)
+	}
+
+	data, err := ds.NewPacketSingle()
+	require.NoError(t, err)
+	require.NoError(t, mntns.PutUint64(data, 1))
+	require.NoError(t, traceeMntns.PutUint64(data, 2))
+
+	wr := &EventWrapper{EventWrapperBase: nested, Data: data}
+	assert.Equal(t, uint64(2), wr.GetMountNSID())
+}
@@ -227,6 +227,10 @@ func NewFromAPI(in *api.DataSource) (DataSource, error) {
 		if !FieldFlagUnreferenced.In(f.Flags) {
 			ds.fieldMap[f.FullName] = (*field)(f)
 		}
+		// Fields added later on must not reuse the payloads of existing ones
+		if !FieldFlagEmpty.In(f.Flags) {
+			ds.payloadCount = max(ds.payloadCount, f.PayloadIndex+1)
+		}
 	}
 	if in.Flags&api.DataSourceFlagsBigEndian != 0 {
 		ds.byteOrder = binary.BigEndian
@@ -750,6 +754,11 @@ func (ds *dataSource) CopyFieldsTo(out DataSource) error {
 		if !FieldFlagUnreferenced.In(f.Flags) {
 			outDs.fieldMap[nf.FullName] = (*field)(nf)
 		}
+		// Keep room for the payloads of the copied fields, so fields added
+		// later don't share them
+		if !FieldFlagEmpty.In(f.Flags) {
+			outDs.payloadCount = max(outDs.payloadCount, nf.PayloadIndex+1)
+		}
 	}
 
 	return nil
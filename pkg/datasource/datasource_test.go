@@ -797,3 +797,38 @@ func randBytes(n int) []byte {
 	rand.Read(ret)
 	return ret
 }
+
+func TestCopyFieldsToAddField(t *testing.T) {
+	t.Parallel()
+
+	ds, err := New(TypeSingle, "event")
+	require.NoError(t, err)
+	_, err = ds.AddField("comm", api.Kind_String)
+	require.NoError(t, err)
+	_, err = ds.AddField("pid", api.Kind_Uint32)
+	require.NoError(t, err)
+
+	out, err := New(TypeSingle, "copy")
+	require.NoError(t, err)
+	require.NoError(t, ds.CopyFieldsTo(out))
+
+	// Fields added to the copy get their own payload
+	count, err := out.AddField("count", api.Kind_Uint64)
+	require.NoError(t, err)
+
+	data, err := out.NewPacketSingle()
+	require.NoError(t, err)
+	require.NoError(t, out.GetField("comm").PutString(data, "curl"))
+	require.NoError(t, out.GetField("pid").PutUint32(data, 42))
+	require.NoError(t, count.PutUint64(data, 3))
+
+	comm, err := out.GetField("comm").String(data)
+	require.NoError(t, err)
+	assert.Equal(t, "curl", comm)
+	pid, err := out.GetField("pid").Uint32(data)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(42), pid)
+	c, err := count.Uint64(data)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), c)
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+)
+
+// rewriteDurations replaces the duration literals of an expression, like 10ms
+// or 1h30m, by their value in nanoseconds, so they can be compared with the
+// durations of the gadgets. Literals in strings are left untouched.
+func rewriteDurations(expression string) string {
+	var sb strings.Builder
+	sb.Grow(len(expression))
+
+	var quote rune
+	prev := rune(0)
+	for i := 0; i < len(expression); {
+		r, size := utf8.DecodeRuneInString(expression[i:])
+
+		if quote != 0 {
+			sb.WriteRune(r)
+			i += size
+			switch {
+			case r == '\\' && quote != '`' && i < len(expression):
+				// Keep the escaped character as is
+				r, size = utf8.DecodeRuneInString(expression[i:])
+				sb.WriteRune(r)
+				i += size
+			case r == quote:
+				quote = 0
+			}
+			prev = r
+			continue
+		}
+
+		switch {
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case unicode.IsDigit(r) && !isIdentRune(prev) && prev != '.':
+			end := i
+			for end < len(expression) {
+				r, size := utf8.DecodeRuneInString(expression[end:])
+				if !isIdentRune(r) && r != '.' && r != 'µ' {
+					break
+				}
+				end += size
+			}
+			token := expression[i:end]
+			if d, ok := parseDuration(token); ok {
+				sb.WriteString(strconv.FormatInt(d.Nanoseconds(), 10))
+			} else {
+				sb.WriteString(token)
+			}
+			lastRune, _ := utf8.DecodeLastRuneInString(token)
+			prev = lastRune
+			i = end
+			continue
+		}
+
+		sb.WriteRune(r)
+		prev = r
+		i += size
+	}
+	return sb.String()
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// parseDuration parses tokens like 10ms, but not plain numbers like 10 or 1e3
+func parseDuration(token string) (time.Duration, bool) {
+	last, _ := utf8.DecodeLastRuneInString(token)
+	if !unicode.IsLetter(last) {
+		return 0, false
+	}
+	d, err := time.ParseDuration(token)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// rawDurationField returns the field holding the nanoseconds of a duration
+// that was formatted as a string by the formatters operator, or nil if f isn't
+// one
+func rawDurationField(f datasource.FieldAccessor, siblings []datasource.FieldAccessor) datasource.FieldAccessor {
+	if f.Type() != api.Kind_String || f.Annotations()[metadatav1.TemplateAnnotation] != "duration" {
+		return nil
+	}
+	for _, sf := range siblings {
+		if sf.Name() == f.Name()+"_raw" && sf.Type() == api.Kind_Uint64 {
+			return sf
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+)
+
+func TestRewriteDurations(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"latency > 10ms", "latency > 10000000"},
+		{"latency>1h30m", "latency>5400000000000"},
+		{"latency < 1.5s && latency > 10µs", "latency < 1500000000 && latency > 10000"},
+		{"port == 443", "port == 443"},
+		{"value > 1e3 || value == 0x1f", "value > 1e3 || value == 0x1f"},
+		{"range(1..10)", "range(1..10)"},
+		{"name == '10ms'", "name == '10ms'"},
+		{`name == "a\"10s" || x > 1s`, `name == "a\"10s" || x > 1000000000`},
+		{"field10ms > 1", "field10ms > 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			assert.Equal(t, test.expected, rewriteDurations(test.expression))
+		})
+	}
+}
+
+func TestFilterDurations(t *testing.T) {
+	ds, err := datasource.New(datasource.TypeSingle, "tcp")
+	require.NoError(t, err)
+	comm, err := ds.AddField("comm", api.Kind_String)
+	require.NoError(t, err)
+	dst, err := ds.AddField("dst", api.Kind_Invalid)
+	require.NoError(t, err)
+	port, err := dst.AddSubField("port", api.Kind_Uint16)
+	require.NoError(t, err)
+	latencyRaw, err := ds.AddField("latency_raw", api.Kind_Uint64)
+	require.NoError(t, err)
+	// As added by the formatters operator
+	latency, err := ds.AddField("latency", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{metadatav1.TemplateAnnotation: "duration"}))
+	require.NoError(t, err)
+
+	data, err := ds.NewPacketSingle()
+	require.NoError(t, err)
+	require.NoError(t, comm.PutString(data, "curl"))
+	require.NoError(t, port.PutUint16(data, 443))
+	require.NoError(t, latencyRaw.PutUint64(data, uint64(25*time.Millisecond)))
+	require.NoError(t, latency.PutString(data, "25ms"))
+
+	tests := []struct {
+		expression string
+		match      bool
+	}{
+		{`dst.port == 443 && comm != "kubelet" && latency > 10ms`, true},
+		{`dst.port == 443 && comm != "kubelet" && latency > 100ms`, false},
+		{`latency_raw >= 25ms && latency_raw < 0.5s`, true},
+		{`comm == "kubelet" || latency <= 25ms`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			prog, err := CompileFilterProgram(ds, test.expression)
+			require.NoError(t, err)
+			res, err := Run(prog, data)
+			require.NoError(t, err)
+			assert.Equal(t, test.match, res)
+		})
+	}
+
+	// String expressions still see the formatted value
+	prog, err := CompileStringProgram(ds, `comm + " " + latency`)
+	require.NoError(t, err)
+	res, err := Run(prog, data)
+	require.NoError(t, err)
+	assert.Equal(t, "curl 25ms", res)
+}
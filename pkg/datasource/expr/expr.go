@@ -29,6 +29,10 @@ import (
 
 type dsPatcher struct {
 	ds datasource.DataSource
+
+	// rawDurations makes durations formatted as strings evaluate to their
+	// nanoseconds, so they can be compared with duration literals
+	rawDurations bool
 }
 
 func (dsp dsPatcher) Visit(node *ast.Node) {
@@ -54,11 +58,21 @@ func (dsp dsPatcher) Visit(node *ast.Node) {
 				break
 			}
 		}
-		replaceNode(node, f)
+		replaceNode(node, dsp.resolve(f, rf.SubFields()))
 	case *ast.IdentifierNode:
 		f := dsp.ds.GetField(nx.Value)
-		replaceNode(node, f)
+		replaceNode(node, dsp.resolve(f, dsp.ds.Accessors(true)))
+	}
+}
+
+func (dsp dsPatcher) resolve(f datasource.FieldAccessor, siblings []datasource.FieldAccessor) datasource.FieldAccessor {
+	if f == nil || !dsp.rawDurations {
+		return f
+	}
+	if raw := rawDurationField(f, siblings); raw != nil {
+		return raw
 	}
+	return f
 }
 
 func replaceNode(node *ast.Node, f datasource.FieldAccessor) {
@@ -245,14 +259,35 @@ func CompileStringProgram(ds datasource.DataSource, expression string) (*vm.Prog
 	return prog, nil
 }
 
-func CompileFilterProgram(ds datasource.DataSource, expression string) (*vm.Program, error) {
+// CompileProgram compiles an expression returning a value of any type, which
+// can be found with the Type() of the Node() of the program. It's unknown
+// (interface) if the type can't be determined before running it.
+func CompileProgram(ds datasource.DataSource, expression string) (*vm.Program, error) {
 	dsp := dsPatcher{
 		ds: ds,
 	}
 
-	options := append(getBuiltInExpressions(), expr.AsBool(), expr.Patch(dsp), expr.Env(datasource.Data(nil)))
+	options := append(getBuiltInExpressions(), expr.Patch(dsp), expr.Env(datasource.Data(nil)))
 
 	prog, err := expr.Compile(expression, options...)
+	if err != nil {
+		return nil, fmt.Errorf("compiling expression: %w", err)
+	}
+	return prog, nil
+}
+
+// CompileFilterProgram compiles an expression returning whether an event
+// matches. Duration literals like 10ms are evaluated to nanoseconds, as are the
+// fields holding durations.
+func CompileFilterProgram(ds datasource.DataSource, expression string) (*vm.Program, error) {
+	dsp := dsPatcher{
+		ds:           ds,
+		rawDurations: true,
+	}
+
+	options := append(getBuiltInExpressions(), expr.AsBool(), expr.Patch(dsp), expr.Env(datasource.Data(nil)))
+
+	prog, err := expr.Compile(rewriteDurations(expression), options...)
 	if err != nil {
 		return nil, fmt.Errorf("compiling filter expression: %w", err)
 	}
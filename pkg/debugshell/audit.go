@@ -0,0 +1,219 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debugshell
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+type RecordType string
+
+const (
+	RecordTypeStart  RecordType = "start"
+	RecordTypeInput  RecordType = "input"
+	RecordTypeOutput RecordType = "output"
+	RecordTypeEnd    RecordType = "end"
+)
+
+// AuditRecord describes something that happened in a session
+type AuditRecord struct {
+	Time      time.Time  `json:"time"`
+	SessionID string     `json:"sessionID"`
+	Type      RecordType `json:"type"`
+	Node      string     `json:"node,omitempty"`
+	User      string     `json:"user,omitempty"`
+	Container string     `json:"container,omitempty"`
+
+	// Set for start records
+	Command  []string  `json:"command,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Deadline time.Time `json:"deadline,omitzero"`
+
+	// Data holds the input or the output, as received or sent
+	Data string `json:"data,omitempty"`
+
+	// Set for end records
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// Error is set if the session could not be started or was terminated by
+	// the node
+	Error string `json:"error,omitempty"`
+}
+
+type Auditor interface {
+	Audit(*AuditRecord)
+}
+
+// LoggerAuditor writes the audit records as JSON to a logger
+type LoggerAuditor struct {
+	Logger logger.Logger
+}
+
+func (a *LoggerAuditor) Audit(r *AuditRecord) {
+	d, _ := json.Marshal(r)
+	a.Logger.Infof("debug shell audit: %s", d)
+}
+
+const (
+	// AuditDataSourceName is the name of the data source the audit records are
+	// emitted to by the PipelineAuditor
+	AuditDataSourceName = "debug_shell"
+
+	auditImageName = "debug-shell"
+)
+
+// PipelineAuditor emits the audit records as events of a data source, so they
+// go through data operators like the events of gadgets; the otel-logs operator
+// for example exports them to a logs backend.
+type PipelineAuditor struct {
+	ds        datasource.DataSource
+	timestamp datasource.FieldAccessor
+	sessionID datasource.FieldAccessor
+	typ       datasource.FieldAccessor
+	node      datasource.FieldAccessor
+	user      datasource.FieldAccessor
+	container datasource.FieldAccessor
+	command   datasource.FieldAccessor
+	reason    datasource.FieldAccessor
+	data      datasource.FieldAccessor
+	exitCode  datasource.FieldAccessor
+	error     datasource.FieldAccessor
+
+	cancel func()
+	done   chan error
+}
+
+// NewPipelineAuditor runs the given data operators with their instance
+// parameters in paramValues (prefixed with "operator.<name>.") until Close is
+// called
+func NewPipelineAuditor(ops []operators.DataOperator, paramValues api.ParamValues, log logger.Logger) (*PipelineAuditor, error) {
+	a := &PipelineAuditor{
+		done: make(chan error, 1),
+	}
+	started := make(chan struct{})
+
+	auditOp := simple.New("debug-shell-audit",
+		// Register the data source before the other operators look for them
+		simple.WithPriority(-10000),
+		simple.OnInit(func(gadgetCtx operators.GadgetContext) error {
+			return a.init(gadgetCtx)
+		}),
+		simple.OnStart(func(gadgetCtx operators.GadgetContext) error {
+			close(started)
+			return nil
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+
+	gadgetCtx := gadgetcontext.New(ctx, auditImageName,
+		gadgetcontext.WithLogger(log),
+		gadgetcontext.WithDataOperators(append(ops, auditOp)...),
+	)
+	go func() {
+		a.done <- gadgetCtx.Run(paramValues)
+	}()
+
+	select {
+	case <-started:
+		return a, nil
+	case err := <-a.done:
+		cancel()
+		if err == nil {
+			err = fmt.Errorf("stopped before starting")
+		}
+		return nil, fmt.Errorf("running audit pipeline: %w", err)
+	}
+}
+
+func (a *PipelineAuditor) init(gadgetCtx operators.GadgetContext) error {
+	ds, err := gadgetCtx.RegisterDataSource(datasource.TypeSingle, AuditDataSourceName)
+	if err != nil {
+		return fmt.Errorf("registering data source: %w", err)
+	}
+	ds.AddAnnotation("logs.name", auditImageName)
+	a.ds = ds
+
+	addField := func(name string, kind api.Kind) (datasource.FieldAccessor, error) {
+		return ds.AddField(name, kind, datasource.WithAnnotations(map[string]string{
+			"logs.name": name,
+		}))
+	}
+
+	fields := []struct {
+		acc  *datasource.FieldAccessor
+		name string
+		kind api.Kind
+	}{
+		{&a.timestamp, "timestamp", api.Kind_Int64},
+		{&a.sessionID, "session_id", api.Kind_String},
+		{&a.typ, "type", api.Kind_String},
+		{&a.node, "node", api.Kind_String},
+		{&a.user, "user", api.Kind_String},
+		{&a.container, "container", api.Kind_String},
+		{&a.command, "command", api.Kind_String},
+		{&a.reason, "reason", api.Kind_String},
+		{&a.data, "data", api.Kind_String},
+		{&a.exitCode, "exit_code", api.Kind_Int32},
+		{&a.error, "error", api.Kind_String},
+	}
+	for _, f := range fields {
+		*f.acc, err = addField(f.name, f.kind)
+		if err != nil {
+			return fmt.Errorf("adding field %q: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+func (a *PipelineAuditor) Audit(r *AuditRecord) {
+	p, err := a.ds.NewPacketSingle()
+	if err != nil {
+		return
+	}
+	// The timestamp field is interpreted by otel-logs in microseconds
+	a.timestamp.PutInt64(p, r.Time.UnixMicro())
+	a.sessionID.PutString(p, r.SessionID)
+	a.typ.PutString(p, string(r.Type))
+	a.node.PutString(p, r.Node)
+	a.user.PutString(p, r.User)
+	a.container.PutString(p, r.Container)
+	a.command.PutString(p, strings.Join(r.Command, " "))
+	a.reason.PutString(p, r.Reason)
+	a.data.PutString(p, r.Data)
+	if r.ExitCode != nil {
+		a.exitCode.PutInt32(p, *r.ExitCode)
+	}
+	a.error.PutString(p, r.Error)
+	a.ds.EmitAndRelease(p)
+}
+
+// Close stops the data operators
+func (a *PipelineAuditor) Close() error {
+	a.cancel()
+	return <-a.done
+}
@@ -0,0 +1,322 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package debugshell runs short-lived interactive debug sessions in the
+// namespaces of a container. Sessions are bounded in time and everything
+// happening in them, including the input typed by the user, is recorded to
+// auditors.
+package debugshell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/google/uuid"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+const (
+	// DefaultMaxDuration is the maximum duration of a session if the node
+	// doesn't configure it
+	DefaultMaxDuration = 15 * time.Minute
+
+	DefaultCommand = "/bin/sh"
+
+	// outputGracePeriod is the time given to read the remaining output once the
+	// command exited
+	outputGracePeriod = time.Second
+)
+
+// Target is the container the session is run in
+type Target struct {
+	// Pid is a process of the container; 0 runs the session on the host,
+	// which is only meant for testing
+	Pid uint32
+
+	// Name describes the container in the audit records
+	Name string
+}
+
+type Options struct {
+	// Node is the name of the node, recorded in the audit records
+	Node string
+
+	// User identifies who opened the session, recorded in the audit records
+	User string
+
+	// MaxDuration caps the duration requested by the client
+	MaxDuration time.Duration
+
+	Auditors []Auditor
+}
+
+type Session struct {
+	id       string
+	target   Target
+	command  []string
+	reason   string
+	tty      bool
+	size     *pty.Winsize
+	deadline time.Time
+	opts     Options
+
+	cmd        *exec.Cmd
+	out        io.Closer
+	outputDone chan struct{}
+	waitDone   chan error
+}
+
+// NewSession validates the request and prepares a session
+func NewSession(req *api.DebugShellStartRequest, target Target, opts Options) (*Session, error) {
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, errors.New("a reason is required to open a debug shell")
+	}
+
+	maxDuration := opts.MaxDuration
+	if maxDuration <= 0 {
+		maxDuration = DefaultMaxDuration
+	}
+	duration := time.Duration(req.Timeout)
+	if duration <= 0 || duration > maxDuration {
+		duration = maxDuration
+	}
+
+	command := req.Command
+	if len(command) == 0 {
+		command = []string{DefaultCommand}
+	}
+
+	s := &Session{
+		id:       uuid.New().String(),
+		target:   target,
+		command:  command,
+		reason:   req.Reason,
+		tty:      req.Tty,
+		deadline: time.Now().Add(duration),
+		opts:     opts,
+	}
+	if req.Tty && req.Rows > 0 && req.Cols > 0 {
+		s.size = &pty.Winsize{Rows: uint16(req.Rows), Cols: uint16(req.Cols)}
+	}
+	return s, nil
+}
+
+func (s *Session) ID() string {
+	return s.id
+}
+
+func (s *Session) Deadline() time.Time {
+	return s.deadline
+}
+
+func (s *Session) audit(typ RecordType, fn func(r *AuditRecord)) {
+	r := &AuditRecord{
+		Time:      time.Now(),
+		SessionID: s.id,
+		Type:      typ,
+		Node:      s.opts.Node,
+		User:      s.opts.User,
+		Container: s.target.Name,
+	}
+	if fn != nil {
+		fn(r)
+	}
+	for _, a := range s.opts.Auditors {
+		a.Audit(r)
+	}
+}
+
+// start starts the command in the container; it returns the input and output
+// of the command
+func (s *Session) start(cmd *exec.Cmd, creds *credentials) (io.WriteCloser, io.ReadCloser, error) {
+	if s.tty {
+		var ptmx *os.File
+		// The pty is allocated in the container
+		err := enterNamespaces(s.target.Pid, creds, func() error {
+			var err error
+			ptmx, err = pty.StartWithAttrs(cmd, s.size, cmd.SysProcAttr)
+			return err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return ptmx, ptmx, nil
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err = enterNamespaces(s.target.Pid, creds, cmd.Start)
+	w.Close()
+	if err != nil {
+		r.Close()
+		return nil, nil, err
+	}
+	return stdin, r, nil
+}
+
+// Start starts the command in the container. The data received from stdin is
+// sent to the command until the channel is closed; output is called with the
+// output of the command.
+func (s *Session) Start(stdin <-chan []byte, output func([]byte) error) error {
+	root := rootDir(s.target.Pid)
+	path, err := lookPath(root, s.command[0])
+	if err != nil {
+		return err
+	}
+
+	cmd := &exec.Cmd{
+		Path: path,
+		Args: s.command,
+		Env:  environ(s.target.Pid),
+		Dir:  "/",
+		// Pdeathsig can't be used: it's delivered when the thread that
+		// started the command exits, see enterNamespaces()
+		SysProcAttr: &syscall.SysProcAttr{},
+	}
+	var creds *credentials
+	if s.target.Pid != 0 {
+		// The command runs as the process of the container, not as the
+		// daemon
+		creds, err = containerCredentials(s.target.Pid)
+		if err != nil {
+			return err
+		}
+		cmd.SysProcAttr.Credential = &syscall.Credential{
+			Uid:    creds.uid,
+			Gid:    creds.gid,
+			Groups: creds.groups,
+		}
+		cmd.SysProcAttr.AmbientCaps = creds.ambientCaps()
+	}
+	if s.tty {
+		cmd.SysProcAttr.Setsid = true
+		cmd.SysProcAttr.Setctty = true
+		cmd.Env = append(cmd.Env, "TERM=xterm")
+	}
+
+	in, out, err := s.start(cmd, creds)
+	if err != nil {
+		s.audit(RecordTypeStart, func(r *AuditRecord) {
+			r.Command = s.command
+			r.Reason = s.reason
+			r.Error = err.Error()
+		})
+		return fmt.Errorf("starting %q: %w", s.command[0], err)
+	}
+
+	s.audit(RecordTypeStart, func(r *AuditRecord) {
+		r.Command = s.command
+		r.Reason = s.reason
+		r.Deadline = s.deadline
+	})
+
+	s.cmd = cmd
+	s.out = out
+	s.outputDone = make(chan struct{})
+	s.waitDone = make(chan error, 1)
+
+	go func() {
+		for data := range stdin {
+			s.audit(RecordTypeInput, func(r *AuditRecord) { r.Data = string(data) })
+			if _, err := in.Write(data); err != nil {
+				return
+			}
+		}
+		if !s.tty {
+			in.Close()
+		}
+	}()
+
+	go func() {
+		defer close(s.outputDone)
+		buf := make([]byte, 4096)
+		for {
+			n, err := out.Read(buf)
+			if n > 0 {
+				data := append([]byte(nil), buf[:n]...)
+				s.audit(RecordTypeOutput, func(r *AuditRecord) { r.Data = string(data) })
+				if output(data) != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		s.waitDone <- cmd.Wait()
+	}()
+	return nil
+}
+
+// Wait waits until the command exits, ctx is done or the deadline is reached
+func (s *Session) Wait(ctx context.Context) *api.DebugShellExit {
+	timer := time.NewTimer(time.Until(s.deadline))
+	defer timer.Stop()
+
+	exit := &api.DebugShellExit{}
+	var waitErr error
+	select {
+	case waitErr = <-s.waitDone:
+	case <-timer.C:
+		exit.Reason = "session timed out"
+		s.cmd.Process.Kill()
+		waitErr = <-s.waitDone
+	case <-ctx.Done():
+		exit.Reason = "session closed"
+		s.cmd.Process.Kill()
+		waitErr = <-s.waitDone
+	}
+
+	select {
+	case <-s.outputDone:
+	case <-time.After(outputGracePeriod):
+	}
+	s.out.Close()
+	<-s.outputDone
+
+	var exitErr *exec.ExitError
+	switch {
+	case waitErr == nil:
+	case errors.As(waitErr, &exitErr):
+		exit.ExitCode = int32(exitErr.ExitCode())
+	default:
+		exit.ExitCode = -1
+	}
+
+	s.audit(RecordTypeEnd, func(r *AuditRecord) {
+		r.ExitCode = &exit.ExitCode
+		r.Error = exit.Reason
+	})
+	return exit
+}
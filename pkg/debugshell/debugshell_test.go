@@ -0,0 +1,237 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debugshell
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	records []*AuditRecord
+}
+
+func (r *recorder) Audit(record *AuditRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, record)
+}
+
+func (r *recorder) types() []RecordType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var types []RecordType
+	for _, record := range r.records {
+		types = append(types, record.Type)
+	}
+	return types
+}
+
+func TestNewSession(t *testing.T) {
+	_, err := NewSession(&api.DebugShellStartRequest{Reason: " "}, Target{}, Options{})
+	require.ErrorContains(t, err, "reason is required")
+
+	s, err := NewSession(&api.DebugShellStartRequest{Reason: "debugging", Timeout: int64(time.Hour)}, Target{}, Options{
+		MaxDuration: time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{DefaultCommand}, s.command)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), s.Deadline(), 5*time.Second)
+
+	s, err = NewSession(&api.DebugShellStartRequest{Reason: "debugging", Timeout: int64(time.Second)}, Target{}, Options{})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Second), s.Deadline(), 500*time.Millisecond)
+}
+
+func runSession(t *testing.T, req *api.DebugShellStartRequest, input []string) (*api.DebugShellExit, string, *recorder) {
+	t.Helper()
+
+	rec := &recorder{}
+	s, err := NewSession(req, Target{Name: "test"}, Options{Node: "node1", User: "alice", Auditors: []Auditor{rec}})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var output bytes.Buffer
+	stdin := make(chan []byte)
+	require.NoError(t, s.Start(stdin, func(data []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		output.Write(data)
+		return nil
+	}))
+	for _, in := range input {
+		stdin <- []byte(in)
+	}
+	close(stdin)
+
+	exit := s.Wait(context.Background())
+	mu.Lock()
+	defer mu.Unlock()
+	return exit, output.String(), rec
+}
+
+func TestSession(t *testing.T) {
+	exit, output, rec := runSession(t, &api.DebugShellStartRequest{
+		Command: []string{"sh", "-c", "cat; exit 3"},
+		Reason:  "testing",
+	}, []string{"hello\n"})
+
+	assert.Equal(t, int32(3), exit.ExitCode)
+	assert.Empty(t, exit.Reason)
+	assert.Equal(t, "hello\n", output)
+
+	assert.Equal(t, []RecordType{RecordTypeStart, RecordTypeInput, RecordTypeOutput, RecordTypeEnd}, rec.types())
+	start := rec.records[0]
+	assert.Equal(t, "testing", start.Reason)
+	assert.Equal(t, "node1", start.Node)
+	assert.Equal(t, "alice", start.User)
+	assert.Equal(t, "test", start.Container)
+	assert.Equal(t, "hello\n", rec.records[1].Data)
+	assert.Equal(t, "hello\n", rec.records[2].Data)
+	require.NotNil(t, rec.records[3].ExitCode)
+	assert.Equal(t, int32(3), *rec.records[3].ExitCode)
+}
+
+func TestSessionTimeout(t *testing.T) {
+	exit, _, rec := runSession(t, &api.DebugShellStartRequest{
+		Command: []string{"sleep", "10"},
+		Reason:  "testing",
+		Timeout: int64(100 * time.Millisecond),
+	}, nil)
+
+	assert.Equal(t, "session timed out", exit.Reason)
+	assert.Equal(t, []RecordType{RecordTypeStart, RecordTypeEnd}, rec.types())
+	assert.Equal(t, "session timed out", rec.records[1].Error)
+}
+
+func TestSessionCommandNotFound(t *testing.T) {
+	s, err := NewSession(&api.DebugShellStartRequest{
+		Command: []string{"does-not-exist"},
+		Reason:  "testing",
+	}, Target{}, Options{})
+	require.NoError(t, err)
+	require.ErrorContains(t, s.Start(nil, nil), "not found")
+}
+
+func TestLookPath(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "usr/bin"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "opt"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "opt/tool"), nil, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "usr/bin/data"), nil, 0o644))
+
+	// Absolute symlinks are resolved in the root filesystem
+	require.NoError(t, os.Symlink("/opt/tool", filepath.Join(root, "usr/bin/tool")))
+	require.NoError(t, os.Symlink("/bin/sh", filepath.Join(root, "usr/bin/sh")))
+
+	path, err := lookPath(root, "tool")
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/bin/tool", path)
+
+	// /bin/sh only exists outside of root
+	_, err = lookPath(root, "sh")
+	require.Error(t, err)
+
+	_, err = lookPath(root, "data")
+	require.Error(t, err)
+
+	path, err = lookPath(root, "/does/not/exist")
+	require.NoError(t, err)
+	assert.Equal(t, "/does/not/exist", path)
+}
+
+func TestParseStatus(t *testing.T) {
+	status := `Name:	nginx
+Umask:	0022
+State:	S (sleeping)
+Uid:	0	101	101	101
+Gid:	0	102	102	102
+Groups:	102 1000
+NoNewPrivs:	1
+CapInh:	0000000000000000
+CapPrm:	00000000a80425fb
+CapEff:	00000000a80425fb
+CapBnd:	00000000a80425fb
+CapAmb:	0000000000000400
+`
+	creds, err := parseStatus([]byte(status))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(101), creds.uid)
+	assert.Equal(t, uint32(102), creds.gid)
+	assert.Equal(t, []uint32{102, 1000}, creds.groups)
+	assert.Equal(t, uint64(0xa80425fb), creds.capabilities)
+	assert.Equal(t, []uintptr{10}, creds.ambientCaps())
+	assert.True(t, creds.noNewPrivs)
+
+	_, err = parseStatus([]byte("Name:\tnginx\nUid:\t0\t0\t0\t0\n"))
+	require.ErrorContains(t, err, "not found")
+
+	_, err = parseStatus([]byte("Uid:\t0\t0\t0\t0\nGid:\t0\t0\t0\t0\nCapEff:\tzz\n"))
+	require.ErrorContains(t, err, "parsing CapEff")
+}
+
+func TestPipelineAuditor(t *testing.T) {
+	var mu sync.Mutex
+	var sessions, types []string
+
+	collector := simple.New("collector",
+		simple.OnPreStart(func(gadgetCtx operators.GadgetContext) error {
+			for _, ds := range gadgetCtx.GetDataSources() {
+				if ds.Name() != AuditDataSourceName {
+					continue
+				}
+				sessionID := ds.GetField("session_id")
+				typ := ds.GetField("type")
+				ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+					mu.Lock()
+					defer mu.Unlock()
+					s, _ := sessionID.String(data)
+					sessions = append(sessions, s)
+					s, _ = typ.String(data)
+					types = append(types, s)
+					return nil
+				}, 0)
+			}
+			return nil
+		}),
+	)
+
+	a, err := NewPipelineAuditor([]operators.DataOperator{collector}, nil, logger.DefaultLogger())
+	require.NoError(t, err)
+
+	a.Audit(&AuditRecord{Time: time.Now(), SessionID: "abc", Type: RecordTypeStart})
+	a.Audit(&AuditRecord{Time: time.Now(), SessionID: "abc", Type: RecordTypeEnd})
+	require.NoError(t, a.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"abc", "abc"}, sessions)
+	assert.Equal(t, []string{"start", "end"}, types)
+}
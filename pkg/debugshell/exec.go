@@ -0,0 +1,342 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debugshell
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"golang.org/x/sys/unix"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
+)
+
+// defaultPath is used to look up commands given without path in the container
+var defaultPath = []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"}
+
+// namespaces joined by the debug shell. The user namespace can't be joined by
+// a multi-threaded process: containers having their own one are handled by
+// containerCredentials instead.
+var namespaces = []struct {
+	name string
+	flag int
+}{
+	{"pid", unix.CLONE_NEWPID},
+	{"ipc", unix.CLONE_NEWIPC},
+	{"uts", unix.CLONE_NEWUTS},
+	{"net", unix.CLONE_NEWNET},
+	{"cgroup", unix.CLONE_NEWCGROUP},
+	{"mnt", unix.CLONE_NEWNS},
+}
+
+// credentials are the ones of a process of the container, which the commands
+// of the debug shell are run with
+type credentials struct {
+	uid    uint32
+	gid    uint32
+	groups []uint32
+
+	// capabilities is the effective capability set of the process; ambient
+	// is its ambient one
+	capabilities uint64
+	ambient      uint64
+	noNewPrivs   bool
+}
+
+// containerCredentials returns the credentials of the process pid, as seen
+// from the host. Capabilities only apply to the user namespace of the process,
+// so none are kept if it's not the one of the host.
+func containerCredentials(pid uint32) (*credentials, error) {
+	data, err := os.ReadFile(filepath.Join(host.HostProcFs, fmt.Sprint(pid), "status"))
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials of the container: %w", err)
+	}
+	creds, err := parseStatus(data)
+	if err != nil {
+		return nil, fmt.Errorf("reading credentials of the container: %w", err)
+	}
+
+	same, err := sameNamespace("/proc/self/ns/user", filepath.Join(host.HostProcFs, fmt.Sprint(pid), "ns", "user"))
+	if err != nil {
+		return nil, fmt.Errorf("checking user namespace of the container: %w", err)
+	}
+	if !same {
+		creds.capabilities = 0
+		creds.ambient = 0
+	}
+	return creds, nil
+}
+
+// parseStatus reads the credentials from the content of /proc/<pid>/status
+func parseStatus(data []byte) (*credentials, error) {
+	creds := &credentials{}
+	found := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		var err error
+		switch key {
+		case "Uid", "Gid":
+			// Real, effective, saved and filesystem IDs
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid %s line %q", key, line)
+			}
+			var id uint64
+			id, err = strconv.ParseUint(fields[1], 10, 32)
+			if key == "Uid" {
+				creds.uid = uint32(id)
+			} else {
+				creds.gid = uint32(id)
+			}
+		case "Groups":
+			for _, f := range fields {
+				var id uint64
+				id, err = strconv.ParseUint(f, 10, 32)
+				if err != nil {
+					break
+				}
+				creds.groups = append(creds.groups, uint32(id))
+			}
+		case "CapEff", "CapAmb":
+			if len(fields) != 1 {
+				return nil, fmt.Errorf("invalid %s line %q", key, line)
+			}
+			var caps uint64
+			caps, err = strconv.ParseUint(fields[0], 16, 64)
+			if key == "CapEff" {
+				creds.capabilities = caps
+			} else {
+				creds.ambient = caps
+			}
+		case "NoNewPrivs":
+			creds.noNewPrivs = len(fields) == 1 && fields[0] == "1"
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", key, err)
+		}
+		found[key] = true
+	}
+	for _, key := range []string{"Uid", "Gid", "CapEff"} {
+		if !found[key] {
+			return nil, fmt.Errorf("%s not found", key)
+		}
+	}
+	return creds, nil
+}
+
+// ambientCaps returns the ambient capabilities to give to the command
+func (c *credentials) ambientCaps() []uintptr {
+	var caps []uintptr
+	for i := range 64 {
+		if c.ambient&(1<<i) != 0 {
+			caps = append(caps, uintptr(i))
+		}
+	}
+	return caps
+}
+
+// restrictThread limits the capabilities that the processes started by the
+// calling thread can get to the ones of the credentials: the command is run as
+// the user of the container, which regains all the capabilities of the
+// bounding set if it's root, and the inheritable and ambient capabilities of
+// the thread would be passed to it otherwise
+func (c *credentials) restrictThread() error {
+	for i := range 64 {
+		if c.capabilities&(1<<i) != 0 {
+			continue
+		}
+		err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(i), 0, 0, 0)
+		if errors.Is(err, unix.EINVAL) {
+			// Not supported by the kernel, nor are the following ones
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("dropping capability %d: %w", i, err)
+		}
+	}
+
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return fmt.Errorf("getting capabilities: %w", err)
+	}
+	data[0].Inheritable = 0
+	data[1].Inheritable = 0
+	if err := unix.Capset(&hdr, &data[0]); err != nil {
+		return fmt.Errorf("clearing inheritable capabilities: %w", err)
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("clearing ambient capabilities: %w", err)
+	}
+
+	if c.noNewPrivs {
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			return fmt.Errorf("setting no_new_privs: %w", err)
+		}
+	}
+	return nil
+}
+
+// sameNamespace returns whether both namespace files refer to the same
+// namespace
+func sameNamespace(a, b string) (bool, error) {
+	var sta, stb unix.Stat_t
+	if err := unix.Stat(a, &sta); err != nil {
+		return false, err
+	}
+	if err := unix.Stat(b, &stb); err != nil {
+		return false, err
+	}
+	return sta.Dev == stb.Dev && sta.Ino == stb.Ino, nil
+}
+
+// rootDir returns the root filesystem of the process pid as seen from the host;
+// pid 0 means the host itself
+func rootDir(pid uint32) string {
+	if pid == 0 {
+		return "/"
+	}
+	return filepath.Join(host.HostProcFs, fmt.Sprint(pid), "root")
+}
+
+// lookPath looks for the command in the root filesystem and returns its path
+// relative to it
+func lookPath(root, name string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+	for _, dir := range defaultPath {
+		path := filepath.Join(dir, name)
+		var st unix.Stat_t
+		if err := statInRoot(root, path, &st); err == nil && st.Mode&unix.S_IFMT == unix.S_IFREG && st.Mode&0o111 != 0 {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("command %q not found in the container", name)
+}
+
+// statInRoot stats path with root as root directory: symlinks, even absolute
+// ones, are resolved in the root filesystem of the container rather than in
+// the one of the host
+func statInRoot(root, path string, st *unix.Stat_t) error {
+	rootFd, err := unix.Open(root, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(rootFd)
+
+	fd, err := unix.Openat2(rootFd, path, &unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_IN_ROOT | unix.RESOLVE_NO_MAGICLINKS,
+	})
+	if errors.Is(err, unix.ENOSYS) {
+		// openat2 was added in Linux 5.6
+		joined, err := securejoin.SecureJoin(root, path)
+		if err != nil {
+			return err
+		}
+		return unix.Stat(joined, st)
+	}
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	return unix.Fstat(fd, st)
+}
+
+// environ returns the environment of the process pid
+func environ(pid uint32) []string {
+	if pid == 0 {
+		return os.Environ()
+	}
+	data, err := os.ReadFile(filepath.Join(host.HostProcFs, fmt.Sprint(pid), "environ"))
+	if err != nil {
+		return nil
+	}
+	var env []string
+	for _, kv := range bytes.Split(data, []byte{0}) {
+		if len(kv) > 0 {
+			env = append(env, string(kv))
+		}
+	}
+	return env
+}
+
+// enterNamespaces joins the namespaces of the process pid, restricts the
+// capabilities to the ones of creds and calls start, which is expected to fork
+// the command; pid 0 means the host itself
+func enterNamespaces(pid uint32, creds *credentials, start func() error) error {
+	if pid == 0 {
+		return start()
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		// The namespaces and capabilities are only changed for this thread,
+		// which is never unlocked: the Go runtime terminates it when the
+		// goroutine exits instead of reusing it
+		runtime.LockOSThread()
+
+		// A thread sharing its root and working directories with other ones
+		// can't join a mount namespace
+		if err := unix.Unshare(unix.CLONE_FS); err != nil {
+			errc <- fmt.Errorf("unsharing filesystem attributes: %w", err)
+			return
+		}
+
+		// Open all the namespaces before joining them, their paths are
+		// resolved in the mount namespace of the host
+		fds := make([]int, 0, len(namespaces))
+		defer func() {
+			for _, fd := range fds {
+				unix.Close(fd)
+			}
+		}()
+		for _, ns := range namespaces {
+			path := filepath.Join(host.HostProcFs, fmt.Sprint(pid), "ns", ns.name)
+			fd, err := unix.Open(path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+			if err != nil {
+				errc <- fmt.Errorf("opening %s namespace: %w", ns.name, err)
+				return
+			}
+			fds = append(fds, fd)
+		}
+		for i, ns := range namespaces {
+			if err := unix.Setns(fds[i], ns.flag); err != nil {
+				errc <- fmt.Errorf("joining %s namespace: %w", ns.name, err)
+				return
+			}
+		}
+
+		if err := creds.restrictThread(); err != nil {
+			errc <- err
+			return
+		}
+		errc <- start()
+	}()
+	return <-errc
+}
@@ -77,6 +77,10 @@ type GadgetContext struct {
 	imageName      string
 	metadata       []byte
 	orasTarget     oras.ReadOnlyTarget
+	startup        *startupTimings
+
+	// updateLock serializes updates of the params of the running gadget
+	updateLock sync.Mutex
 }
 
 func New(
@@ -94,6 +98,7 @@ func New(
 		imageName:   imageName,
 		dataSources: make(map[string]datasource.DataSource),
 		vars:        make(map[string]any),
+		startup:     newStartupTimings(),
 	}
 	for _, option := range options {
 		option(gadgetContext)
@@ -448,6 +453,9 @@ func (c *GadgetContext) LoadGadgetInfo(info *api.GadgetInfo, paramValues api.Par
 	c.lock.Unlock()
 
 	c.Logger().Debug("loaded gadget info")
+	if run {
+		c.markStartupPhase(PhaseGadgetInfo)
+	}
 
 	// After loading gadget info, start local operators as well
 	err := c.instantiateOperators(paramValues)
@@ -456,12 +464,16 @@ func (c *GadgetContext) LoadGadgetInfo(info *api.GadgetInfo, paramValues api.Par
 	}
 
 	if run {
+		c.markStartupPhase(PhaseInstantiate)
 		if err := c.preStart(); err != nil {
 			return fmt.Errorf("pre-starting operators: %w", err)
 		}
+		c.markStartupPhase(PhasePreStart)
+		c.trackFirstEvent()
 		if err := c.start(); err != nil {
 			return fmt.Errorf("starting local operators: %w", err)
 		}
+		c.markStartupPhase(PhaseStart)
 		c.Logger().Debugf("running...")
 	}
 
@@ -220,6 +220,53 @@ func (c *GadgetContext) PrepareGadgetInfo(paramValues api.ParamValues) error {
 	return err
 }
 
+// ValidateGadget prepares the operators like PrepareGadgetInfo and checks the
+// given param values and whether the gadget could run on the current node,
+// without starting it. An error is returned if the operators can't be
+// prepared at all.
+func (c *GadgetContext) ValidateGadget(paramValues api.ParamValues) ([]*api.ValidationCheck, error) {
+	defer func() {
+		if err := c.close(); err != nil {
+			c.logger.Warn("error closing operators after validating gadget:", err)
+		}
+	}()
+
+	if err := c.instantiateOperators(paramValues); err != nil {
+		return nil, err
+	}
+
+	var checks []*api.ValidationCheck
+	for _, p := range c.Params() {
+		key := p.Prefix + p.Key
+		value, ok := paramValues[key]
+		if !ok || value == "" {
+			if p.IsMandatory && p.DefaultValue == "" {
+				checks = append(checks, &api.ValidationCheck{
+					Name:    "param/" + key,
+					Result:  api.ValidationResult_ValidationFailed,
+					Message: "missing value for mandatory param",
+				})
+			}
+			continue
+		}
+		if err := apihelpers.ParamToParamDesc(p).ToParam().Validate(value); err != nil {
+			checks = append(checks, &api.ValidationCheck{
+				Name:    "param/" + key,
+				Result:  api.ValidationResult_ValidationFailed,
+				Message: err.Error(),
+			})
+		}
+	}
+
+	for _, opInst := range c.localOperators {
+		if validator, ok := opInst.(operators.Validator); ok {
+			c.Logger().Debugf("validating op %q", opInst.Name())
+			checks = append(checks, validator.Validate(c)...)
+		}
+	}
+	return checks, nil
+}
+
 func (c *GadgetContext) Run(paramValues api.ParamValues) error {
 	defer c.cancel()
 	defer func() {
@@ -242,14 +289,19 @@ func (c *GadgetContext) Run(paramValues api.ParamValues) error {
 	if err != nil {
 		return fmt.Errorf("initializing and preparing operators: %w", err)
 	}
+	c.markStartupPhase(PhaseInstantiate)
 
 	if err := c.preStart(); err != nil {
 		return fmt.Errorf("pre-starting operators: %w", err)
 	}
+	c.markStartupPhase(PhasePreStart)
+	c.trackFirstEvent()
 
 	if err := c.start(); err != nil {
 		return fmt.Errorf("starting operators: %w", err)
 	}
+	c.markStartupPhase(PhaseStart)
+	c.Logger().Debugf("started after %s", c.startup)
 
 	c.Logger().Debugf("running...")
 	WaitForTimeoutOrDone(c)
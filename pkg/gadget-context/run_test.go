@@ -21,7 +21,9 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 )
 
 func TestRun(t *testing.T) {
@@ -312,3 +314,51 @@ func TestRun(t *testing.T) {
 		})
 	}
 }
+
+// validatingOperator reports a fixed check when validated
+type validatingOperator struct {
+	fakeOperator
+}
+
+func (o *validatingOperator) InstanceParams() api.Params {
+	return api.Params{
+		{Key: "name", IsMandatory: true},
+	}
+}
+
+// ExtraParams aren't validated when instantiating the operator
+func (o *validatingOperator) ExtraParams(operators.GadgetContext) api.Params {
+	return api.Params{
+		{Key: "count", TypeHint: string(params.TypeInt)},
+	}
+}
+
+func (o *validatingOperator) InstantiateDataOperator(operators.GadgetContext, api.ParamValues) (operators.DataOperatorInstance, error) {
+	return o, nil
+}
+
+func (o *validatingOperator) Validate(operators.GadgetContext) []*api.ValidationCheck {
+	return []*api.ValidationCheck{{Name: "kernel", Result: api.ValidationResult_ValidationWarning}}
+}
+
+func TestValidateGadget(t *testing.T) {
+	called := []string{}
+	op := &validatingOperator{fakeOperator{name: "op1", called: &called}}
+
+	gadgetCtx := New(t.Context(), "x.com/do-not-run-this", WithDataOperators(op))
+	checks, err := gadgetCtx.ValidateGadget(api.ParamValues{"operator.op1.count": "abc"})
+	require.NoError(t, err)
+
+	results := map[string]api.ValidationResult{}
+	for _, check := range checks {
+		results[check.Name] = check.Result
+	}
+	require.Equal(t, map[string]api.ValidationResult{
+		"param/operator.op1.count": api.ValidationResult_ValidationFailed,
+		"param/operator.op1.name":  api.ValidationResult_ValidationFailed,
+		"kernel":                   api.ValidationResult_ValidationWarning,
+	}, results)
+
+	// Nothing is started, but the operators are closed again
+	require.Equal(t, []string{"op1_close"}, called)
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetcontext
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/metrics"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
+)
+
+const (
+	PhaseGadgetInfo  = "gadget-info"
+	PhaseInstantiate = "instantiate"
+	PhasePreStart    = "pre-start"
+	PhaseStart       = "start"
+	PhaseFirstEvent  = "first-event"
+
+	// firstEventPriority makes sure the event was seen by all other
+	// subscribers before it's considered as delivered
+	firstEventPriority = math.MaxInt
+)
+
+type StartupPhase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// startupTimings keeps track of the time spent in each phase from the creation
+// of the gadget context to the delivery of the first event, to be able to tell
+// where the time goes when a gadget is slow to show something.
+type startupTimings struct {
+	mu         sync.Mutex
+	begin      time.Time
+	last       time.Time
+	phases     []StartupPhase
+	firstEvent bool
+
+	now func() time.Time
+}
+
+func newStartupTimings() *startupTimings {
+	t := &startupTimings{now: time.Now}
+	t.begin = t.now()
+	t.last = t.begin
+	return t
+}
+
+// mark records that the given phase ended now. It returns false once the first
+// event was delivered, as phases after that aren't part of the startup.
+func (t *startupTimings) mark(phase string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.firstEvent {
+		return 0, false
+	}
+	now := t.now()
+	d := now.Sub(t.last)
+	t.last = now
+	t.phases = append(t.phases, StartupPhase{Name: phase, Duration: d})
+	if phase == PhaseFirstEvent {
+		t.firstEvent = true
+	}
+	return d, true
+}
+
+func (t *startupTimings) get() ([]StartupPhase, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]StartupPhase(nil), t.phases...), t.last.Sub(t.begin)
+}
+
+func (t *startupTimings) String() string {
+	phases, total := t.get()
+	parts := make([]string, 0, len(phases))
+	for _, p := range phases {
+		parts = append(parts, fmt.Sprintf("%s: %s", p.Name, p.Duration.Round(time.Microsecond)))
+	}
+	return fmt.Sprintf("%s (%s)", total.Round(time.Microsecond), strings.Join(parts, ", "))
+}
+
+// StartupPhases returns the time spent in each phase so far and the total
+// time since the gadget context was created
+func (c *GadgetContext) StartupPhases() ([]StartupPhase, time.Duration) {
+	return c.startup.get()
+}
+
+func (c *GadgetContext) markStartupPhase(phase string) {
+	d, ok := c.startup.mark(phase)
+	if !ok {
+		return
+	}
+	end := time.Now()
+	_, span := tracing.Tracer().Start(c.ctx, "gadget "+phase,
+		trace.WithTimestamp(end.Add(-d)),
+		trace.WithAttributes(attribute.String("gadget_image", c.imageName)),
+	)
+	span.End(trace.WithTimestamp(end))
+	histStartupPhaseDuration.Record(context.Background(), d.Seconds(), metric.WithAttributes(
+		attribute.String("gadget_image", c.imageName),
+		attribute.String("phase", phase),
+	))
+}
+
+// trackFirstEvent reports how long it took to deliver the first event of any
+// data source after all other subscribers handled it
+func (c *GadgetContext) trackFirstEvent() {
+	var once sync.Once
+	report := func(datasource.DataSource, datasource.Packet) error {
+		once.Do(func() {
+			c.markStartupPhase(PhaseFirstEvent)
+			_, total := c.startup.get()
+			histFirstEventLatency.Record(context.Background(), total.Seconds(), metric.WithAttributes(
+				attribute.String("gadget_image", c.imageName),
+			))
+			c.Logger().Debugf("first event delivered after %s", c.startup)
+		})
+		return nil
+	}
+	for _, ds := range c.GetDataSources() {
+		if err := ds.SubscribePacket(report, firstEventPriority); err != nil {
+			c.Logger().Debugf("tracking first event of data source %q: %v", ds.Name(), err)
+		}
+	}
+}
+
+var histFirstEventLatency, _ = metrics.Float64Histogram("ig_gadget_first_event_latency",
+	metric.WithDescription("Time from creating a gadget context to delivering its first event"),
+	metric.WithUnit("s"),
+)
+
+var histStartupPhaseDuration, _ = metrics.Float64Histogram("ig_gadget_startup_phase_duration",
+	metric.WithDescription("Time spent in each phase until a gadget delivers its first event"),
+	metric.WithUnit("s"),
+)
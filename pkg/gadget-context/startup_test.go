@@ -0,0 +1,61 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetcontext
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupTimings(t *testing.T) {
+	now := time.Unix(0, 0)
+	timings := &startupTimings{
+		begin: now,
+		last:  now,
+		now:   func() time.Time { return now },
+	}
+
+	steps := []struct {
+		phase string
+		after time.Duration
+	}{
+		{PhaseInstantiate, 300 * time.Millisecond},
+		{PhasePreStart, 20 * time.Millisecond},
+		{PhaseStart, 100 * time.Millisecond},
+		{PhaseFirstEvent, 80 * time.Millisecond},
+	}
+	for _, step := range steps {
+		now = now.Add(step.after)
+		d, ok := timings.mark(step.phase)
+		require.True(t, ok)
+		assert.Equal(t, step.after, d)
+	}
+
+	// Nothing is recorded after the first event
+	now = now.Add(time.Second)
+	_, ok := timings.mark(PhaseStart)
+	assert.False(t, ok)
+
+	phases, total := timings.get()
+	assert.Equal(t, 500*time.Millisecond, total)
+	require.Len(t, phases, len(steps))
+	for i, step := range steps {
+		assert.Equal(t, StartupPhase{Name: step.phase, Duration: step.after}, phases[i])
+	}
+	assert.Equal(t, "500ms (instantiate: 300ms, pre-start: 20ms, start: 100ms, first-event: 80ms)", timings.String())
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetcontext
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
+// ErrRestartRequired is returned by UpdateParams if the new values can only be
+// applied by running the gadget again
+var ErrRestartRequired = errors.New("gadget needs to be restarted to apply the params")
+
+// UpdateParams applies new param values to the running gadget. Only the
+// operators whose params changed are updated; if any of them doesn't implement
+// operators.ParamUpdater, or params not belonging to an operator changed,
+// nothing is changed and ErrRestartRequired is returned.
+func (c *GadgetContext) UpdateParams(paramValues api.ParamValues) error {
+	c.updateLock.Lock()
+	defer c.updateLock.Unlock()
+
+	c.lock.Lock()
+	oldValues := c.paramValues
+	c.lock.Unlock()
+	if c.localOperators == nil {
+		return ErrRestartRequired
+	}
+
+	changed := make(map[string]struct{})
+	for k, v := range paramValues {
+		if old, ok := oldValues[k]; !ok || old != v {
+			changed[k] = struct{}{}
+		}
+	}
+	for k := range oldValues {
+		if _, ok := paramValues[k]; !ok {
+			changed[k] = struct{}{}
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	dataOperators := make(map[string]operators.DataOperator, len(c.dataOperators))
+	for _, op := range c.dataOperators {
+		dataOperators[op.Name()] = op
+	}
+
+	var apply []func()
+	for _, opInst := range c.localOperators {
+		opParamPrefix := fmt.Sprintf("operator.%s", opInst.Name())
+		affected := false
+		for k := range changed {
+			if strings.HasPrefix(k, opParamPrefix+".") {
+				delete(changed, k)
+				affected = true
+			}
+		}
+		if !affected {
+			continue
+		}
+		updater, ok := opInst.(operators.ParamUpdater)
+		if !ok {
+			return fmt.Errorf("%w: operator %q can't update its params", ErrRestartRequired, opInst.Name())
+		}
+
+		opParamValues := paramValues.ExtractPrefixedValues(opParamPrefix)
+		if op, ok := dataOperators[opInst.Name()]; ok {
+			instanceParams := op.InstanceParams().AddPrefix(opParamPrefix)
+			if err := apihelpers.Validate(instanceParams, opParamValues); err != nil {
+				return fmt.Errorf("validating instance params for operator %q: %w", opInst.Name(), err)
+			}
+		}
+		fn, err := updater.UpdateParams(c, opParamValues)
+		if err != nil {
+			return fmt.Errorf("updating params of operator %q: %w", opInst.Name(), err)
+		}
+		apply = append(apply, fn)
+	}
+	if len(changed) > 0 {
+		keys := slices.Sorted(maps.Keys(changed))
+		return fmt.Errorf("%w: %s changed", ErrRestartRequired, strings.Join(keys, ", "))
+	}
+
+	for _, fn := range apply {
+		fn()
+	}
+	c.lock.Lock()
+	c.paramValues = paramValues
+	c.lock.Unlock()
+	return nil
+}
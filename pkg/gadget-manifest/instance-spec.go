@@ -39,6 +39,25 @@ type InstanceSpec struct {
 	Tags        []string          `json:"tags" yaml:"tags"`
 	Nodes       []string          `json:"nodes" yaml:"nodes"`
 	ParamValues map[string]string `json:"paramValues" yaml:"paramValues"`
+
+	// NodeSelector, Tolerations and TTL use the format of the --node-selector,
+	// --tolerations and --ttl flags
+	NodeSelector string `json:"nodeSelector,omitempty" yaml:"nodeSelector,omitempty"`
+	Tolerations  string `json:"tolerations,omitempty" yaml:"tolerations,omitempty"`
+	TTL          string `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
+
+// WriteInstanceSpecs writes the given specs as a YAML stream with one document
+// per spec, as read by InstanceSpecsFromReader
+func WriteInstanceSpecs(w io.Writer, specs []*InstanceSpec) error {
+	yenc := yaml.NewEncoder(w)
+	yenc.SetIndent(2)
+	for _, spec := range specs {
+		if err := yenc.Encode(spec); err != nil {
+			return fmt.Errorf("encoding gadget spec %q: %w", spec.Name, err)
+		}
+	}
+	return yenc.Close()
 }
 
 func InstanceSpecsFromReader(r io.Reader) ([]*InstanceSpec, error) {
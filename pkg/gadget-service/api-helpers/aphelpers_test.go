@@ -195,9 +195,18 @@ func TestGetDurationValuesPerDataSource(t *testing.T) {
 			expectedErr: true,
 		},
 		{
-			name:        "invalid with invalid time duration (no unit)",
-			input:       "datasource1:10",
-			expectedErr: true,
+			name:  "valid with seconds (no unit)",
+			input: "datasource1:10",
+			expected: map[string]time.Duration{
+				"datasource1": 10 * time.Second,
+			},
+		},
+		{
+			name:  "valid with days",
+			input: "datasource1:1d",
+			expected: map[string]time.Duration{
+				"datasource1": 24 * time.Hour,
+			},
 		},
 	}
 
@@ -163,7 +163,7 @@ func GetDurationValuesPerDataSource(s string) (map[string]time.Duration, error)
 	}
 	res := make(map[string]time.Duration, len(m))
 	for k, v := range m {
-		res[k], err = time.ParseDuration(v)
+		res[k], err = params.ParseDuration(v)
 		if err != nil {
 			return nil, fmt.Errorf("converting %s to duration: %w", v, err)
 		}
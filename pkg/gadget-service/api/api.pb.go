@@ -120,12 +120,62 @@ func (Kind) EnumDescriptor() ([]byte, []int) {
 	return file_api_api_proto_rawDescGZIP(), []int{0}
 }
 
+type ValidationResult int32
+
+const (
+	ValidationResult_ValidationPassed  ValidationResult = 0
+	ValidationResult_ValidationWarning ValidationResult = 1
+	ValidationResult_ValidationFailed  ValidationResult = 2
+)
+
+// Enum value maps for ValidationResult.
+var (
+	ValidationResult_name = map[int32]string{
+		0: "ValidationPassed",
+		1: "ValidationWarning",
+		2: "ValidationFailed",
+	}
+	ValidationResult_value = map[string]int32{
+		"ValidationPassed":  0,
+		"ValidationWarning": 1,
+		"ValidationFailed":  2,
+	}
+)
+
+func (x ValidationResult) Enum() *ValidationResult {
+	p := new(ValidationResult)
+	*p = x
+	return p
+}
+
+func (x ValidationResult) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ValidationResult) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_api_proto_enumTypes[1].Descriptor()
+}
+
+func (ValidationResult) Type() protoreflect.EnumType {
+	return &file_api_api_proto_enumTypes[1]
+}
+
+func (x ValidationResult) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ValidationResult.Descriptor instead.
+func (ValidationResult) EnumDescriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{1}
+}
+
 type GadgetInstanceStatus int32
 
 const (
 	GadgetInstanceStatus_StatusInvalid GadgetInstanceStatus = 0
 	GadgetInstanceStatus_StatusRunning GadgetInstanceStatus = 1
 	GadgetInstanceStatus_StatusError   GadgetInstanceStatus = 2
+	GadgetInstanceStatus_StatusPaused  GadgetInstanceStatus = 3
 )
 
 // Enum value maps for GadgetInstanceStatus.
@@ -134,11 +184,13 @@ var (
 		0: "StatusInvalid",
 		1: "StatusRunning",
 		2: "StatusError",
+		3: "StatusPaused",
 	}
 	GadgetInstanceStatus_value = map[string]int32{
 		"StatusInvalid": 0,
 		"StatusRunning": 1,
 		"StatusError":   2,
+		"StatusPaused":  3,
 	}
 )
 
@@ -153,11 +205,11 @@ func (x GadgetInstanceStatus) String() string {
 }
 
 func (GadgetInstanceStatus) Descriptor() protoreflect.EnumDescriptor {
-	return file_api_api_proto_enumTypes[1].Descriptor()
+	return file_api_api_proto_enumTypes[2].Descriptor()
 }
 
 func (GadgetInstanceStatus) Type() protoreflect.EnumType {
-	return &file_api_api_proto_enumTypes[1]
+	return &file_api_api_proto_enumTypes[2]
 }
 
 func (x GadgetInstanceStatus) Number() protoreflect.EnumNumber {
@@ -166,7 +218,7 @@ func (x GadgetInstanceStatus) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use GadgetInstanceStatus.Descriptor instead.
 func (GadgetInstanceStatus) EnumDescriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{1}
+	return file_api_api_proto_rawDescGZIP(), []int{2}
 }
 
 type GadgetRunRequest struct {
@@ -184,7 +236,16 @@ type GadgetRunRequest struct {
 	LogLevel uint32 `protobuf:"varint,12,opt,name=logLevel,proto3" json:"logLevel,omitempty"`
 	// time that a gadget should run; use 0, if the gadget should run until it's being
 	// stopped or done; time is in nanoseconds and directly converted to time.Duration
-	Timeout       int64 `protobuf:"varint,13,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Timeout int64 `protobuf:"varint,13,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	// if set, the server sends an EventBatchChecksum after every checksumBatchSize
+	// payload events, so that the client can verify their integrity
+	ChecksumBatchSize uint32 `protobuf:"varint,14,opt,name=checksumBatchSize,proto3" json:"checksumBatchSize,omitempty"`
+	// compression is the codec the server should compress the events with, like
+	// gzip or zstd; it's only used if the client supports it, too
+	Compression string `protobuf:"bytes,15,opt,name=compression,proto3" json:"compression,omitempty"`
+	// if set, the server runs the gadget even if the events of a previous run
+	// of a one-shot gadget are cached; the cache is refreshed with the new ones
+	NoCache       bool `protobuf:"varint,16,opt,name=noCache,proto3" json:"noCache,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -261,12 +322,39 @@ func (x *GadgetRunRequest) GetTimeout() int64 {
 	return 0
 }
 
+func (x *GadgetRunRequest) GetChecksumBatchSize() uint32 {
+	if x != nil {
+		return x.ChecksumBatchSize
+	}
+	return 0
+}
+
+func (x *GadgetRunRequest) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
+func (x *GadgetRunRequest) GetNoCache() bool {
+	if x != nil {
+		return x.NoCache
+	}
+	return false
+}
+
 type GadgetAttachRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// id of the gadget to attach to
 	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	// used to inform the server about the expected protocol version
-	Version       uint32 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Version uint32 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	// only replay buffered events received by the server at or after this time,
+	// in nanoseconds since the epoch; 0 replays all buffered events
+	Since int64 `protobuf:"varint,3,opt,name=since,proto3" json:"since,omitempty"`
+	// compression is the codec the server should compress the events with, see
+	// GadgetRunRequest
+	Compression   string `protobuf:"bytes,4,opt,name=compression,proto3" json:"compression,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -315,6 +403,20 @@ func (x *GadgetAttachRequest) GetVersion() uint32 {
 	return 0
 }
 
+func (x *GadgetAttachRequest) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *GadgetAttachRequest) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
 type GadgetEvent struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types are specified in consts.go. Upper 16 bits are used for log severity levels
@@ -384,6 +486,150 @@ func (x *GadgetEvent) GetDataSourceID() uint32 {
 	return 0
 }
 
+// EventBatchChecksum is the payload of an EventTypeGadgetChecksum event; it
+// covers all payload events with a sequence number between firstSeq and lastSeq
+type EventBatchChecksum struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	FirstSeq uint32                 `protobuf:"varint,1,opt,name=firstSeq,proto3" json:"firstSeq,omitempty"`
+	LastSeq  uint32                 `protobuf:"varint,2,opt,name=lastSeq,proto3" json:"lastSeq,omitempty"`
+	Count    uint32                 `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	// SHA-256 digest, or HMAC-SHA256 signature if signed is set, see pkg/integrity
+	Digest        []byte `protobuf:"bytes,4,opt,name=digest,proto3" json:"digest,omitempty"`
+	Signed        bool   `protobuf:"varint,5,opt,name=signed,proto3" json:"signed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventBatchChecksum) Reset() {
+	*x = EventBatchChecksum{}
+	mi := &file_api_api_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventBatchChecksum) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventBatchChecksum) ProtoMessage() {}
+
+func (x *EventBatchChecksum) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventBatchChecksum.ProtoReflect.Descriptor instead.
+func (*EventBatchChecksum) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *EventBatchChecksum) GetFirstSeq() uint32 {
+	if x != nil {
+		return x.FirstSeq
+	}
+	return 0
+}
+
+func (x *EventBatchChecksum) GetLastSeq() uint32 {
+	if x != nil {
+		return x.LastSeq
+	}
+	return 0
+}
+
+func (x *EventBatchChecksum) GetCount() uint32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *EventBatchChecksum) GetDigest() []byte {
+	if x != nil {
+		return x.Digest
+	}
+	return nil
+}
+
+func (x *EventBatchChecksum) GetSigned() bool {
+	if x != nil {
+		return x.Signed
+	}
+	return false
+}
+
+// EventDrops is the payload of an EventTypeGadgetDrops event; it's sent when
+// payload events had to be dropped because the client didn't keep up with them
+type EventDrops struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// number of dropped events per data source ID since the previous EventDrops
+	Dropped map[uint32]uint64 `protobuf:"bytes,1,rep,name=dropped,proto3" json:"dropped,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// time covered by the counts, in nanoseconds
+	Interval int64 `protobuf:"varint,2,opt,name=interval,proto3" json:"interval,omitempty"`
+	// number of events the server buffers for the client
+	BufferLength  uint32 `protobuf:"varint,3,opt,name=bufferLength,proto3" json:"bufferLength,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventDrops) Reset() {
+	*x = EventDrops{}
+	mi := &file_api_api_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventDrops) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventDrops) ProtoMessage() {}
+
+func (x *EventDrops) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventDrops.ProtoReflect.Descriptor instead.
+func (*EventDrops) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EventDrops) GetDropped() map[uint32]uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return nil
+}
+
+func (x *EventDrops) GetInterval() int64 {
+	if x != nil {
+		return x.Interval
+	}
+	return 0
+}
+
+func (x *EventDrops) GetBufferLength() uint32 {
+	if x != nil {
+		return x.BufferLength
+	}
+	return 0
+}
+
 type GadgetStopRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -392,7 +638,7 @@ type GadgetStopRequest struct {
 
 func (x *GadgetStopRequest) Reset() {
 	*x = GadgetStopRequest{}
-	mi := &file_api_api_proto_msgTypes[3]
+	mi := &file_api_api_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -404,7 +650,7 @@ func (x *GadgetStopRequest) String() string {
 func (*GadgetStopRequest) ProtoMessage() {}
 
 func (x *GadgetStopRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[3]
+	mi := &file_api_api_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -417,7 +663,7 @@ func (x *GadgetStopRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GadgetStopRequest.ProtoReflect.Descriptor instead.
 func (*GadgetStopRequest) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{3}
+	return file_api_api_proto_rawDescGZIP(), []int{5}
 }
 
 type GadgetControlRequest struct {
@@ -434,7 +680,7 @@ type GadgetControlRequest struct {
 
 func (x *GadgetControlRequest) Reset() {
 	*x = GadgetControlRequest{}
-	mi := &file_api_api_proto_msgTypes[4]
+	mi := &file_api_api_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -446,7 +692,7 @@ func (x *GadgetControlRequest) String() string {
 func (*GadgetControlRequest) ProtoMessage() {}
 
 func (x *GadgetControlRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[4]
+	mi := &file_api_api_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -459,7 +705,7 @@ func (x *GadgetControlRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GadgetControlRequest.ProtoReflect.Descriptor instead.
 func (*GadgetControlRequest) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{4}
+	return file_api_api_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *GadgetControlRequest) GetEvent() isGadgetControlRequest_Event {
@@ -525,21 +771,773 @@ type InfoRequest struct {
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *InfoRequest) Reset() {
-	*x = InfoRequest{}
-	mi := &file_api_api_proto_msgTypes[5]
+func (x *InfoRequest) Reset() {
+	*x = InfoRequest{}
+	mi := &file_api_api_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoRequest) ProtoMessage() {}
+
+func (x *InfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoRequest.ProtoReflect.Descriptor instead.
+func (*InfoRequest) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *InfoRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type InfoResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Experimental  bool                   `protobuf:"varint,3,opt,name=experimental,proto3" json:"experimental,omitempty"`
+	ServerVersion string                 `protobuf:"bytes,4,opt,name=serverVersion,proto3" json:"serverVersion,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InfoResponse) Reset() {
+	*x = InfoResponse{}
+	mi := &file_api_api_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoResponse) ProtoMessage() {}
+
+func (x *InfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoResponse.ProtoReflect.Descriptor instead.
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *InfoResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetExperimental() bool {
+	if x != nil {
+		return x.Experimental
+	}
+	return false
+}
+
+func (x *InfoResponse) GetServerVersion() string {
+	if x != nil {
+		return x.ServerVersion
+	}
+	return ""
+}
+
+type DebugShellRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*DebugShellRequest_StartRequest
+	//	*DebugShellRequest_Stdin
+	Event         isDebugShellRequest_Event `protobuf_oneof:"Event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DebugShellRequest) Reset() {
+	*x = DebugShellRequest{}
+	mi := &file_api_api_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DebugShellRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DebugShellRequest) ProtoMessage() {}
+
+func (x *DebugShellRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DebugShellRequest.ProtoReflect.Descriptor instead.
+func (*DebugShellRequest) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DebugShellRequest) GetEvent() isDebugShellRequest_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *DebugShellRequest) GetStartRequest() *DebugShellStartRequest {
+	if x != nil {
+		if x, ok := x.Event.(*DebugShellRequest_StartRequest); ok {
+			return x.StartRequest
+		}
+	}
+	return nil
+}
+
+func (x *DebugShellRequest) GetStdin() []byte {
+	if x != nil {
+		if x, ok := x.Event.(*DebugShellRequest_Stdin); ok {
+			return x.Stdin
+		}
+	}
+	return nil
+}
+
+type isDebugShellRequest_Event interface {
+	isDebugShellRequest_Event()
+}
+
+type DebugShellRequest_StartRequest struct {
+	StartRequest *DebugShellStartRequest `protobuf:"bytes,1,opt,name=startRequest,proto3,oneof"`
+}
+
+type DebugShellRequest_Stdin struct {
+	// stdin holds input for the debug shell
+	Stdin []byte `protobuf:"bytes,2,opt,name=stdin,proto3,oneof"`
+}
+
+func (*DebugShellRequest_StartRequest) isDebugShellRequest_Event() {}
+
+func (*DebugShellRequest_Stdin) isDebugShellRequest_Event() {}
+
+type DebugShellStartRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// the container is selected by namespace, podName and containerName on
+	// Kubernetes and by its runtime containerName otherwise
+	Namespace     string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	PodName       string `protobuf:"bytes,2,opt,name=podName,proto3" json:"podName,omitempty"`
+	ContainerName string `protobuf:"bytes,3,opt,name=containerName,proto3" json:"containerName,omitempty"`
+	// command to run in the container; /bin/sh if empty
+	Command []string `protobuf:"bytes,4,rep,name=command,proto3" json:"command,omitempty"`
+	// reason is recorded in the audit log of the session and is mandatory
+	Reason string `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	// maximum duration of the session in nanoseconds; the node caps it
+	Timeout int64 `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	// tty allocates a terminal of the given size
+	Tty           bool   `protobuf:"varint,7,opt,name=tty,proto3" json:"tty,omitempty"`
+	Rows          uint32 `protobuf:"varint,8,opt,name=rows,proto3" json:"rows,omitempty"`
+	Cols          uint32 `protobuf:"varint,9,opt,name=cols,proto3" json:"cols,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DebugShellStartRequest) Reset() {
+	*x = DebugShellStartRequest{}
+	mi := &file_api_api_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DebugShellStartRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DebugShellStartRequest) ProtoMessage() {}
+
+func (x *DebugShellStartRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DebugShellStartRequest.ProtoReflect.Descriptor instead.
+func (*DebugShellStartRequest) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DebugShellStartRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *DebugShellStartRequest) GetPodName() string {
+	if x != nil {
+		return x.PodName
+	}
+	return ""
+}
+
+func (x *DebugShellStartRequest) GetContainerName() string {
+	if x != nil {
+		return x.ContainerName
+	}
+	return ""
+}
+
+func (x *DebugShellStartRequest) GetCommand() []string {
+	if x != nil {
+		return x.Command
+	}
+	return nil
+}
+
+func (x *DebugShellStartRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *DebugShellStartRequest) GetTimeout() int64 {
+	if x != nil {
+		return x.Timeout
+	}
+	return 0
+}
+
+func (x *DebugShellStartRequest) GetTty() bool {
+	if x != nil {
+		return x.Tty
+	}
+	return false
+}
+
+func (x *DebugShellStartRequest) GetRows() uint32 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *DebugShellStartRequest) GetCols() uint32 {
+	if x != nil {
+		return x.Cols
+	}
+	return 0
+}
+
+type DebugShellEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*DebugShellEvent_Started
+	//	*DebugShellEvent_Output
+	//	*DebugShellEvent_Exit
+	Event         isDebugShellEvent_Event `protobuf_oneof:"Event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DebugShellEvent) Reset() {
+	*x = DebugShellEvent{}
+	mi := &file_api_api_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DebugShellEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DebugShellEvent) ProtoMessage() {}
+
+func (x *DebugShellEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DebugShellEvent.ProtoReflect.Descriptor instead.
+func (*DebugShellEvent) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DebugShellEvent) GetEvent() isDebugShellEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *DebugShellEvent) GetStarted() *DebugShellStarted {
+	if x != nil {
+		if x, ok := x.Event.(*DebugShellEvent_Started); ok {
+			return x.Started
+		}
+	}
+	return nil
+}
+
+func (x *DebugShellEvent) GetOutput() []byte {
+	if x != nil {
+		if x, ok := x.Event.(*DebugShellEvent_Output); ok {
+			return x.Output
+		}
+	}
+	return nil
+}
+
+func (x *DebugShellEvent) GetExit() *DebugShellExit {
+	if x != nil {
+		if x, ok := x.Event.(*DebugShellEvent_Exit); ok {
+			return x.Exit
+		}
+	}
+	return nil
+}
+
+type isDebugShellEvent_Event interface {
+	isDebugShellEvent_Event()
+}
+
+type DebugShellEvent_Started struct {
+	Started *DebugShellStarted `protobuf:"bytes,1,opt,name=started,proto3,oneof"`
+}
+
+type DebugShellEvent_Output struct {
+	// output holds the output of the debug shell, stdout and stderr combined
+	Output []byte `protobuf:"bytes,2,opt,name=output,proto3,oneof"`
+}
+
+type DebugShellEvent_Exit struct {
+	Exit *DebugShellExit `protobuf:"bytes,3,opt,name=exit,proto3,oneof"`
+}
+
+func (*DebugShellEvent_Started) isDebugShellEvent_Event() {}
+
+func (*DebugShellEvent_Output) isDebugShellEvent_Event() {}
+
+func (*DebugShellEvent_Exit) isDebugShellEvent_Event() {}
+
+type DebugShellStarted struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionID string                 `protobuf:"bytes,1,opt,name=sessionID,proto3" json:"sessionID,omitempty"`
+	Node      string                 `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	Container string                 `protobuf:"bytes,3,opt,name=container,proto3" json:"container,omitempty"`
+	// time at which the session will be terminated, in nanoseconds since epoch
+	Deadline      int64 `protobuf:"varint,4,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DebugShellStarted) Reset() {
+	*x = DebugShellStarted{}
+	mi := &file_api_api_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DebugShellStarted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DebugShellStarted) ProtoMessage() {}
+
+func (x *DebugShellStarted) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DebugShellStarted.ProtoReflect.Descriptor instead.
+func (*DebugShellStarted) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DebugShellStarted) GetSessionID() string {
+	if x != nil {
+		return x.SessionID
+	}
+	return ""
+}
+
+func (x *DebugShellStarted) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *DebugShellStarted) GetContainer() string {
+	if x != nil {
+		return x.Container
+	}
+	return ""
+}
+
+func (x *DebugShellStarted) GetDeadline() int64 {
+	if x != nil {
+		return x.Deadline
+	}
+	return 0
+}
+
+type DebugShellExit struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ExitCode int32                  `protobuf:"varint,1,opt,name=exitCode,proto3" json:"exitCode,omitempty"`
+	// reason is set when the session was terminated by the node, e.g. because it
+	// timed out
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DebugShellExit) Reset() {
+	*x = DebugShellExit{}
+	mi := &file_api_api_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DebugShellExit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DebugShellExit) ProtoMessage() {}
+
+func (x *DebugShellExit) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DebugShellExit.ProtoReflect.Descriptor instead.
+func (*DebugShellExit) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DebugShellExit) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *DebugShellExit) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type NodeInfoRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// tracepoints is a list of tracepoints (in the form "category/name") whose
+	// availability should be checked
+	Tracepoints []string `protobuf:"bytes,1,rep,name=tracepoints,proto3" json:"tracepoints,omitempty"`
+	// kprobes is a list of kernel functions whose availability for kprobes
+	// should be checked
+	Kprobes       []string `protobuf:"bytes,2,rep,name=kprobes,proto3" json:"kprobes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeInfoRequest) Reset() {
+	*x = NodeInfoRequest{}
+	mi := &file_api_api_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeInfoRequest) ProtoMessage() {}
+
+func (x *NodeInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeInfoRequest.ProtoReflect.Descriptor instead.
+func (*NodeInfoRequest) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *NodeInfoRequest) GetTracepoints() []string {
+	if x != nil {
+		return x.Tracepoints
+	}
+	return nil
+}
+
+func (x *NodeInfoRequest) GetKprobes() []string {
+	if x != nil {
+		return x.Kprobes
+	}
+	return nil
+}
+
+type NodeInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KernelRelease string                 `protobuf:"bytes,1,opt,name=kernelRelease,proto3" json:"kernelRelease,omitempty"`
+	// btfAvailable is true if the kernel exposes its BTF information
+	BtfAvailable        bool `protobuf:"varint,2,opt,name=btfAvailable,proto3" json:"btfAvailable,omitempty"`
+	RingBufferSupported bool `protobuf:"varint,3,opt,name=ringBufferSupported,proto3" json:"ringBufferSupported,omitempty"`
+	// cgroupMode is either "v1", "v2" or "hybrid"
+	CgroupMode string `protobuf:"bytes,4,opt,name=cgroupMode,proto3" json:"cgroupMode,omitempty"`
+	// lsmBPFAvailable is true if the bpf LSM is active and BPF LSM programs
+	// can be loaded
+	LsmBPFAvailable bool `protobuf:"varint,5,opt,name=lsmBPFAvailable,proto3" json:"lsmBPFAvailable,omitempty"`
+	// lockdown holds the current kernel lockdown mode ("none", "integrity",
+	// "confidentiality") or is empty if lockdown is not supported
+	Lockdown            string `protobuf:"bytes,6,opt,name=lockdown,proto3" json:"lockdown,omitempty"`
+	TracepointCount     uint32 `protobuf:"varint,7,opt,name=tracepointCount,proto3" json:"tracepointCount,omitempty"`
+	KprobeFunctionCount uint32 `protobuf:"varint,8,opt,name=kprobeFunctionCount,proto3" json:"kprobeFunctionCount,omitempty"`
+	// tracepoints and kprobes hold the availability of the tracepoints and
+	// kprobes requested in NodeInfoRequest
+	Tracepoints   map[string]bool `protobuf:"bytes,9,rep,name=tracepoints,proto3" json:"tracepoints,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Kprobes       map[string]bool `protobuf:"bytes,10,rep,name=kprobes,proto3" json:"kprobes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeInfo) Reset() {
+	*x = NodeInfo{}
+	mi := &file_api_api_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeInfo) ProtoMessage() {}
+
+func (x *NodeInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeInfo.ProtoReflect.Descriptor instead.
+func (*NodeInfo) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *NodeInfo) GetKernelRelease() string {
+	if x != nil {
+		return x.KernelRelease
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetBtfAvailable() bool {
+	if x != nil {
+		return x.BtfAvailable
+	}
+	return false
+}
+
+func (x *NodeInfo) GetRingBufferSupported() bool {
+	if x != nil {
+		return x.RingBufferSupported
+	}
+	return false
+}
+
+func (x *NodeInfo) GetCgroupMode() string {
+	if x != nil {
+		return x.CgroupMode
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetLsmBPFAvailable() bool {
+	if x != nil {
+		return x.LsmBPFAvailable
+	}
+	return false
+}
+
+func (x *NodeInfo) GetLockdown() string {
+	if x != nil {
+		return x.Lockdown
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetTracepointCount() uint32 {
+	if x != nil {
+		return x.TracepointCount
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetKprobeFunctionCount() uint32 {
+	if x != nil {
+		return x.KprobeFunctionCount
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetTracepoints() map[string]bool {
+	if x != nil {
+		return x.Tracepoints
+	}
+	return nil
+}
+
+func (x *NodeInfo) GetKprobes() map[string]bool {
+	if x != nil {
+		return x.Kprobes
+	}
+	return nil
+}
+
+type NodeOverheadRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// intervalMs is the time in milliseconds over which CPU usage and event
+	// rates are measured; defaults to 1000
+	IntervalMs    uint32 `protobuf:"varint,1,opt,name=intervalMs,proto3" json:"intervalMs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NodeOverheadRequest) Reset() {
+	*x = NodeOverheadRequest{}
+	mi := &file_api_api_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeOverheadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeOverheadRequest) ProtoMessage() {}
+
+func (x *NodeOverheadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeOverheadRequest.ProtoReflect.Descriptor instead.
+func (*NodeOverheadRequest) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *NodeOverheadRequest) GetIntervalMs() uint32 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+type InstanceOverhead struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name            string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	ImageName       string                 `protobuf:"bytes,3,opt,name=imageName,proto3" json:"imageName,omitempty"`
+	EventsPerSecond float64                `protobuf:"fixed64,4,opt,name=eventsPerSecond,proto3" json:"eventsPerSecond,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *InstanceOverhead) Reset() {
+	*x = InstanceOverhead{}
+	mi := &file_api_api_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *InfoRequest) String() string {
+func (x *InstanceOverhead) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*InfoRequest) ProtoMessage() {}
+func (*InstanceOverhead) ProtoMessage() {}
 
-func (x *InfoRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[5]
+func (x *InstanceOverhead) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -550,42 +1548,73 @@ func (x *InfoRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use InfoRequest.ProtoReflect.Descriptor instead.
-func (*InfoRequest) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use InstanceOverhead.ProtoReflect.Descriptor instead.
+func (*InstanceOverhead) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{17}
 }
 
-func (x *InfoRequest) GetVersion() string {
+func (x *InstanceOverhead) GetId() string {
 	if x != nil {
-		return x.Version
+		return x.Id
 	}
 	return ""
 }
 
-type InfoResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
-	Experimental  bool                   `protobuf:"varint,3,opt,name=experimental,proto3" json:"experimental,omitempty"`
-	ServerVersion string                 `protobuf:"bytes,4,opt,name=serverVersion,proto3" json:"serverVersion,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *InstanceOverhead) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
 }
 
-func (x *InfoResponse) Reset() {
-	*x = InfoResponse{}
-	mi := &file_api_api_proto_msgTypes[6]
+func (x *InstanceOverhead) GetImageName() string {
+	if x != nil {
+		return x.ImageName
+	}
+	return ""
+}
+
+func (x *InstanceOverhead) GetEventsPerSecond() float64 {
+	if x != nil {
+		return x.EventsPerSecond
+	}
+	return 0
+}
+
+type NodeOverhead struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// cpuPercent is the CPU usage of the process running the gadgets; 100 means
+	// one CPU fully used
+	CpuPercent float64 `protobuf:"fixed64,1,opt,name=cpuPercent,proto3" json:"cpuPercent,omitempty"`
+	// memoryBytes is the resident memory of the process running the gadgets
+	MemoryBytes uint64 `protobuf:"varint,2,opt,name=memoryBytes,proto3" json:"memoryBytes,omitempty"`
+	// bpfRunTimePercent is the CPU time spent running the eBPF programs loaded
+	// by the process; 100 means one CPU fully used
+	BpfRunTimePercent float64 `protobuf:"fixed64,3,opt,name=bpfRunTimePercent,proto3" json:"bpfRunTimePercent,omitempty"`
+	BpfProgramCount   uint32  `protobuf:"varint,4,opt,name=bpfProgramCount,proto3" json:"bpfProgramCount,omitempty"`
+	// bpfMapMemoryBytes is the memory used by the eBPF maps held by the process
+	BpfMapMemoryBytes uint64              `protobuf:"varint,5,opt,name=bpfMapMemoryBytes,proto3" json:"bpfMapMemoryBytes,omitempty"`
+	BpfMapCount       uint32              `protobuf:"varint,6,opt,name=bpfMapCount,proto3" json:"bpfMapCount,omitempty"`
+	Instances         []*InstanceOverhead `protobuf:"bytes,7,rep,name=instances,proto3" json:"instances,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *NodeOverhead) Reset() {
+	*x = NodeOverhead{}
+	mi := &file_api_api_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *InfoResponse) String() string {
+func (x *NodeOverhead) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*InfoResponse) ProtoMessage() {}
+func (*NodeOverhead) ProtoMessage() {}
 
-func (x *InfoResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[6]
+func (x *NodeOverhead) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -596,30 +1625,58 @@ func (x *InfoResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use InfoResponse.ProtoReflect.Descriptor instead.
-func (*InfoResponse) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use NodeOverhead.ProtoReflect.Descriptor instead.
+func (*NodeOverhead) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{18}
 }
 
-func (x *InfoResponse) GetVersion() string {
+func (x *NodeOverhead) GetCpuPercent() float64 {
 	if x != nil {
-		return x.Version
+		return x.CpuPercent
 	}
-	return ""
+	return 0
 }
 
-func (x *InfoResponse) GetExperimental() bool {
+func (x *NodeOverhead) GetMemoryBytes() uint64 {
 	if x != nil {
-		return x.Experimental
+		return x.MemoryBytes
 	}
-	return false
+	return 0
 }
 
-func (x *InfoResponse) GetServerVersion() string {
+func (x *NodeOverhead) GetBpfRunTimePercent() float64 {
 	if x != nil {
-		return x.ServerVersion
+		return x.BpfRunTimePercent
 	}
-	return ""
+	return 0
+}
+
+func (x *NodeOverhead) GetBpfProgramCount() uint32 {
+	if x != nil {
+		return x.BpfProgramCount
+	}
+	return 0
+}
+
+func (x *NodeOverhead) GetBpfMapMemoryBytes() uint64 {
+	if x != nil {
+		return x.BpfMapMemoryBytes
+	}
+	return 0
+}
+
+func (x *NodeOverhead) GetBpfMapCount() uint32 {
+	if x != nil {
+		return x.BpfMapCount
+	}
+	return 0
+}
+
+func (x *NodeOverhead) GetInstances() []*InstanceOverhead {
+	if x != nil {
+		return x.Instances
+	}
+	return nil
 }
 
 type DataElement struct {
@@ -631,7 +1688,7 @@ type DataElement struct {
 
 func (x *DataElement) Reset() {
 	*x = DataElement{}
-	mi := &file_api_api_proto_msgTypes[7]
+	mi := &file_api_api_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -643,7 +1700,7 @@ func (x *DataElement) String() string {
 func (*DataElement) ProtoMessage() {}
 
 func (x *DataElement) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[7]
+	mi := &file_api_api_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -656,7 +1713,7 @@ func (x *DataElement) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DataElement.ProtoReflect.Descriptor instead.
 func (*DataElement) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{7}
+	return file_api_api_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *DataElement) GetPayload() [][]byte {
@@ -677,7 +1734,7 @@ type GadgetData struct {
 
 func (x *GadgetData) Reset() {
 	*x = GadgetData{}
-	mi := &file_api_api_proto_msgTypes[8]
+	mi := &file_api_api_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -689,7 +1746,7 @@ func (x *GadgetData) String() string {
 func (*GadgetData) ProtoMessage() {}
 
 func (x *GadgetData) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[8]
+	mi := &file_api_api_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -702,7 +1759,7 @@ func (x *GadgetData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GadgetData.ProtoReflect.Descriptor instead.
 func (*GadgetData) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{8}
+	return file_api_api_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *GadgetData) GetNode() string {
@@ -737,7 +1794,7 @@ type GadgetDataArray struct {
 
 func (x *GadgetDataArray) Reset() {
 	*x = GadgetDataArray{}
-	mi := &file_api_api_proto_msgTypes[9]
+	mi := &file_api_api_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -749,7 +1806,7 @@ func (x *GadgetDataArray) String() string {
 func (*GadgetDataArray) ProtoMessage() {}
 
 func (x *GadgetDataArray) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[9]
+	mi := &file_api_api_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -762,7 +1819,7 @@ func (x *GadgetDataArray) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GadgetDataArray.ProtoReflect.Descriptor instead.
 func (*GadgetDataArray) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{9}
+	return file_api_api_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *GadgetDataArray) GetNode() string {
@@ -806,7 +1863,7 @@ type Param struct {
 
 func (x *Param) Reset() {
 	*x = Param{}
-	mi := &file_api_api_proto_msgTypes[10]
+	mi := &file_api_api_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -818,7 +1875,7 @@ func (x *Param) String() string {
 func (*Param) ProtoMessage() {}
 
 func (x *Param) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[10]
+	mi := &file_api_api_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -831,7 +1888,7 @@ func (x *Param) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Param.ProtoReflect.Descriptor instead.
 func (*Param) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{10}
+	return file_api_api_proto_rawDescGZIP(), []int{22}
 }
 
 func (x *Param) GetKey() string {
@@ -934,7 +1991,7 @@ type GadgetInfo struct {
 
 func (x *GadgetInfo) Reset() {
 	*x = GadgetInfo{}
-	mi := &file_api_api_proto_msgTypes[11]
+	mi := &file_api_api_proto_msgTypes[23]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -946,7 +2003,7 @@ func (x *GadgetInfo) String() string {
 func (*GadgetInfo) ProtoMessage() {}
 
 func (x *GadgetInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[11]
+	mi := &file_api_api_proto_msgTypes[23]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -959,7 +2016,7 @@ func (x *GadgetInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GadgetInfo.ProtoReflect.Descriptor instead.
 func (*GadgetInfo) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{11}
+	return file_api_api_proto_rawDescGZIP(), []int{23}
 }
 
 func (x *GadgetInfo) GetName() string {
@@ -1028,7 +2085,7 @@ type ExtraInfo struct {
 
 func (x *ExtraInfo) Reset() {
 	*x = ExtraInfo{}
-	mi := &file_api_api_proto_msgTypes[12]
+	mi := &file_api_api_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1040,7 +2097,7 @@ func (x *ExtraInfo) String() string {
 func (*ExtraInfo) ProtoMessage() {}
 
 func (x *ExtraInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[12]
+	mi := &file_api_api_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1053,7 +2110,7 @@ func (x *ExtraInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExtraInfo.ProtoReflect.Descriptor instead.
 func (*ExtraInfo) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{12}
+	return file_api_api_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *ExtraInfo) GetData() map[string]*GadgetInspectAddendum {
@@ -1074,7 +2131,7 @@ type GadgetInspectAddendum struct {
 
 func (x *GadgetInspectAddendum) Reset() {
 	*x = GadgetInspectAddendum{}
-	mi := &file_api_api_proto_msgTypes[13]
+	mi := &file_api_api_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1086,7 +2143,7 @@ func (x *GadgetInspectAddendum) String() string {
 func (*GadgetInspectAddendum) ProtoMessage() {}
 
 func (x *GadgetInspectAddendum) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[13]
+	mi := &file_api_api_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1099,7 +2156,7 @@ func (x *GadgetInspectAddendum) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GadgetInspectAddendum.ProtoReflect.Descriptor instead.
 func (*GadgetInspectAddendum) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{13}
+	return file_api_api_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *GadgetInspectAddendum) GetContentType() string {
@@ -1131,7 +2188,7 @@ type DataSource struct {
 
 func (x *DataSource) Reset() {
 	*x = DataSource{}
-	mi := &file_api_api_proto_msgTypes[14]
+	mi := &file_api_api_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1143,7 +2200,7 @@ func (x *DataSource) String() string {
 func (*DataSource) ProtoMessage() {}
 
 func (x *DataSource) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[14]
+	mi := &file_api_api_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1156,7 +2213,7 @@ func (x *DataSource) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DataSource.ProtoReflect.Descriptor instead.
 func (*DataSource) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{14}
+	return file_api_api_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *DataSource) GetId() uint32 {
@@ -1253,7 +2310,7 @@ type Field struct {
 
 func (x *Field) Reset() {
 	*x = Field{}
-	mi := &file_api_api_proto_msgTypes[15]
+	mi := &file_api_api_proto_msgTypes[27]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1265,7 +2322,7 @@ func (x *Field) String() string {
 func (*Field) ProtoMessage() {}
 
 func (x *Field) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[15]
+	mi := &file_api_api_proto_msgTypes[27]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1278,7 +2335,7 @@ func (x *Field) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Field.ProtoReflect.Descriptor instead.
 func (*Field) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{15}
+	return file_api_api_proto_rawDescGZIP(), []int{27}
 }
 
 func (x *Field) GetName() string {
@@ -1381,7 +2438,7 @@ type GetGadgetInfoRequest struct {
 
 func (x *GetGadgetInfoRequest) Reset() {
 	*x = GetGadgetInfoRequest{}
-	mi := &file_api_api_proto_msgTypes[16]
+	mi := &file_api_api_proto_msgTypes[28]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1393,7 +2450,7 @@ func (x *GetGadgetInfoRequest) String() string {
 func (*GetGadgetInfoRequest) ProtoMessage() {}
 
 func (x *GetGadgetInfoRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[16]
+	mi := &file_api_api_proto_msgTypes[28]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1406,7 +2463,7 @@ func (x *GetGadgetInfoRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetGadgetInfoRequest.ProtoReflect.Descriptor instead.
 func (*GetGadgetInfoRequest) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{16}
+	return file_api_api_proto_rawDescGZIP(), []int{28}
 }
 
 func (x *GetGadgetInfoRequest) GetParamValues() map[string]string {
@@ -1453,7 +2510,7 @@ type GetGadgetInfoResponse struct {
 
 func (x *GetGadgetInfoResponse) Reset() {
 	*x = GetGadgetInfoResponse{}
-	mi := &file_api_api_proto_msgTypes[17]
+	mi := &file_api_api_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1465,7 +2522,168 @@ func (x *GetGadgetInfoResponse) String() string {
 func (*GetGadgetInfoResponse) ProtoMessage() {}
 
 func (x *GetGadgetInfoResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[17]
+	mi := &file_api_api_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGadgetInfoResponse.ProtoReflect.Descriptor instead.
+func (*GetGadgetInfoResponse) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *GetGadgetInfoResponse) GetGadgetInfo() *GadgetInfo {
+	if x != nil {
+		return x.GadgetInfo
+	}
+	return nil
+}
+
+type ValidateGadgetRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// params are the gadget's parameters
+	ParamValues   map[string]string `protobuf:"bytes,1,rep,name=paramValues,proto3" json:"paramValues,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ImageName     string            `protobuf:"bytes,2,opt,name=imageName,proto3" json:"imageName,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateGadgetRequest) Reset() {
+	*x = ValidateGadgetRequest{}
+	mi := &file_api_api_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateGadgetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateGadgetRequest) ProtoMessage() {}
+
+func (x *ValidateGadgetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateGadgetRequest.ProtoReflect.Descriptor instead.
+func (*ValidateGadgetRequest) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ValidateGadgetRequest) GetParamValues() map[string]string {
+	if x != nil {
+		return x.ParamValues
+	}
+	return nil
+}
+
+func (x *ValidateGadgetRequest) GetImageName() string {
+	if x != nil {
+		return x.ImageName
+	}
+	return ""
+}
+
+type ValidationCheck struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// name identifies what was checked, like "btf" or "program/ig_execve_e"
+	Name          string           `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Result        ValidationResult `protobuf:"varint,2,opt,name=result,proto3,enum=api.ValidationResult" json:"result,omitempty"`
+	Message       string           `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidationCheck) Reset() {
+	*x = ValidationCheck{}
+	mi := &file_api_api_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidationCheck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidationCheck) ProtoMessage() {}
+
+func (x *ValidationCheck) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidationCheck.ProtoReflect.Descriptor instead.
+func (*ValidationCheck) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ValidationCheck) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ValidationCheck) GetResult() ValidationResult {
+	if x != nil {
+		return x.Result
+	}
+	return ValidationResult_ValidationPassed
+}
+
+func (x *ValidationCheck) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ValidateGadgetResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// gadgetInfo describes what would run; it's empty if the gadget couldn't
+	// be prepared
+	GadgetInfo    *GadgetInfo        `protobuf:"bytes,1,opt,name=gadgetInfo,proto3" json:"gadgetInfo,omitempty"`
+	Checks        []*ValidationCheck `protobuf:"bytes,2,rep,name=checks,proto3" json:"checks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateGadgetResponse) Reset() {
+	*x = ValidateGadgetResponse{}
+	mi := &file_api_api_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateGadgetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateGadgetResponse) ProtoMessage() {}
+
+func (x *ValidateGadgetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1476,18 +2694,25 @@ func (x *GetGadgetInfoResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetGadgetInfoResponse.ProtoReflect.Descriptor instead.
-func (*GetGadgetInfoResponse) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{17}
+// Deprecated: Use ValidateGadgetResponse.ProtoReflect.Descriptor instead.
+func (*ValidateGadgetResponse) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{32}
 }
 
-func (x *GetGadgetInfoResponse) GetGadgetInfo() *GadgetInfo {
+func (x *ValidateGadgetResponse) GetGadgetInfo() *GadgetInfo {
 	if x != nil {
 		return x.GadgetInfo
 	}
 	return nil
 }
 
+func (x *ValidateGadgetResponse) GetChecks() []*ValidationCheck {
+	if x != nil {
+		return x.Checks
+	}
+	return nil
+}
+
 type CreateGadgetInstanceRequest struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
 	GadgetInstance    *GadgetInstance        `protobuf:"bytes,1,opt,name=gadgetInstance,proto3" json:"gadgetInstance,omitempty"`
@@ -1498,7 +2723,7 @@ type CreateGadgetInstanceRequest struct {
 
 func (x *CreateGadgetInstanceRequest) Reset() {
 	*x = CreateGadgetInstanceRequest{}
-	mi := &file_api_api_proto_msgTypes[18]
+	mi := &file_api_api_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1510,7 +2735,7 @@ func (x *CreateGadgetInstanceRequest) String() string {
 func (*CreateGadgetInstanceRequest) ProtoMessage() {}
 
 func (x *CreateGadgetInstanceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[18]
+	mi := &file_api_api_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1523,7 +2748,7 @@ func (x *CreateGadgetInstanceRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateGadgetInstanceRequest.ProtoReflect.Descriptor instead.
 func (*CreateGadgetInstanceRequest) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{18}
+	return file_api_api_proto_rawDescGZIP(), []int{33}
 }
 
 func (x *CreateGadgetInstanceRequest) GetGadgetInstance() *GadgetInstance {
@@ -1550,7 +2775,7 @@ type CreateGadgetInstanceResponse struct {
 
 func (x *CreateGadgetInstanceResponse) Reset() {
 	*x = CreateGadgetInstanceResponse{}
-	mi := &file_api_api_proto_msgTypes[19]
+	mi := &file_api_api_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1562,7 +2787,7 @@ func (x *CreateGadgetInstanceResponse) String() string {
 func (*CreateGadgetInstanceResponse) ProtoMessage() {}
 
 func (x *CreateGadgetInstanceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[19]
+	mi := &file_api_api_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1575,7 +2800,7 @@ func (x *CreateGadgetInstanceResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateGadgetInstanceResponse.ProtoReflect.Descriptor instead.
 func (*CreateGadgetInstanceResponse) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{19}
+	return file_api_api_proto_rawDescGZIP(), []int{34}
 }
 
 func (x *CreateGadgetInstanceResponse) GetResult() int32 {
@@ -1592,15 +2817,122 @@ func (x *CreateGadgetInstanceResponse) GetGadgetInstance() *GadgetInstance {
 	return nil
 }
 
+type UpdateGadgetInstanceRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// paramValues are merged into the paramValues of the instance; existing keys are overwritten
+	ParamValues   map[string]string `protobuf:"bytes,2,rep,name=paramValues,proto3" json:"paramValues,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateGadgetInstanceRequest) Reset() {
+	*x = UpdateGadgetInstanceRequest{}
+	mi := &file_api_api_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateGadgetInstanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateGadgetInstanceRequest) ProtoMessage() {}
+
+func (x *UpdateGadgetInstanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateGadgetInstanceRequest.ProtoReflect.Descriptor instead.
+func (*UpdateGadgetInstanceRequest) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *UpdateGadgetInstanceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateGadgetInstanceRequest) GetParamValues() map[string]string {
+	if x != nil {
+		return x.ParamValues
+	}
+	return nil
+}
+
+type UpdateGadgetInstanceResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Result         int32                  `protobuf:"varint,1,opt,name=result,proto3" json:"result,omitempty"`
+	GadgetInstance *GadgetInstance        `protobuf:"bytes,2,opt,name=gadgetInstance,proto3" json:"gadgetInstance,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *UpdateGadgetInstanceResponse) Reset() {
+	*x = UpdateGadgetInstanceResponse{}
+	mi := &file_api_api_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateGadgetInstanceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateGadgetInstanceResponse) ProtoMessage() {}
+
+func (x *UpdateGadgetInstanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateGadgetInstanceResponse.ProtoReflect.Descriptor instead.
+func (*UpdateGadgetInstanceResponse) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *UpdateGadgetInstanceResponse) GetResult() int32 {
+	if x != nil {
+		return x.Result
+	}
+	return 0
+}
+
+func (x *UpdateGadgetInstanceResponse) GetGadgetInstance() *GadgetInstance {
+	if x != nil {
+		return x.GadgetInstance
+	}
+	return nil
+}
+
 type ListGadgetInstancesRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// selector holds tags that must all be set on the returned instances; if empty, all instances are returned
+	Selector      []string `protobuf:"bytes,1,rep,name=selector,proto3" json:"selector,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListGadgetInstancesRequest) Reset() {
 	*x = ListGadgetInstancesRequest{}
-	mi := &file_api_api_proto_msgTypes[20]
+	mi := &file_api_api_proto_msgTypes[37]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1612,7 +2944,7 @@ func (x *ListGadgetInstancesRequest) String() string {
 func (*ListGadgetInstancesRequest) ProtoMessage() {}
 
 func (x *ListGadgetInstancesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[20]
+	mi := &file_api_api_proto_msgTypes[37]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1625,7 +2957,14 @@ func (x *ListGadgetInstancesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListGadgetInstancesRequest.ProtoReflect.Descriptor instead.
 func (*ListGadgetInstancesRequest) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{20}
+	return file_api_api_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *ListGadgetInstancesRequest) GetSelector() []string {
+	if x != nil {
+		return x.Selector
+	}
+	return nil
 }
 
 type GadgetInstance struct {
@@ -1643,14 +2982,26 @@ type GadgetInstance struct {
 	// nodes is a list of nodes the gadget should run on; if empty, all nodes will run the gadget
 	Nodes []string `protobuf:"bytes,5,rep,name=nodes,proto3" json:"nodes,omitempty"`
 	// state can be used to reflect the current state of the gadget instance
-	State         *GadgetInstanceState `protobuf:"bytes,7,opt,name=state,proto3" json:"state,omitempty"`
+	State *GadgetInstanceState `protobuf:"bytes,7,opt,name=state,proto3" json:"state,omitempty"`
+	// paused is set if the instance was paused; paused instances keep their configuration and ID, but don't run until
+	// they are resumed
+	Paused bool `protobuf:"varint,8,opt,name=paused,proto3" json:"paused,omitempty"`
+	// nodeSelector is a Kubernetes label selector like "node-role.kubernetes.io/worker=true"; if set, the instance only
+	// runs on nodes whose labels match it, including nodes that start matching it later on
+	NodeSelector string `protobuf:"bytes,9,opt,name=nodeSelector,proto3" json:"nodeSelector,omitempty"`
+	// tolerations allow the instance to run on nodes with matching NoSchedule or NoExecute taints; taints are only
+	// considered for instances that have a nodeSelector or tolerations set
+	Tolerations []*Toleration `protobuf:"bytes,10,rep,name=tolerations,proto3" json:"tolerations,omitempty"`
+	// ttl is the number of seconds after timeCreated the instance is stopped and removed; 0 means it runs until it's
+	// removed explicitly
+	Ttl           int64 `protobuf:"varint,11,opt,name=ttl,proto3" json:"ttl,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GadgetInstance) Reset() {
 	*x = GadgetInstance{}
-	mi := &file_api_api_proto_msgTypes[21]
+	mi := &file_api_api_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1662,7 +3013,7 @@ func (x *GadgetInstance) String() string {
 func (*GadgetInstance) ProtoMessage() {}
 
 func (x *GadgetInstance) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[21]
+	mi := &file_api_api_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1675,7 +3026,7 @@ func (x *GadgetInstance) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GadgetInstance.ProtoReflect.Descriptor instead.
 func (*GadgetInstance) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{21}
+	return file_api_api_proto_rawDescGZIP(), []int{38}
 }
 
 func (x *GadgetInstance) GetId() string {
@@ -1727,17 +3078,122 @@ func (x *GadgetInstance) GetState() *GadgetInstanceState {
 	return nil
 }
 
+func (x *GadgetInstance) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *GadgetInstance) GetNodeSelector() string {
+	if x != nil {
+		return x.NodeSelector
+	}
+	return ""
+}
+
+func (x *GadgetInstance) GetTolerations() []*Toleration {
+	if x != nil {
+		return x.Tolerations
+	}
+	return nil
+}
+
+func (x *GadgetInstance) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+// Toleration follows the semantics of Kubernetes tolerations
+type Toleration struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// key is the taint key to match; empty matches all keys if operator is "Exists"
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// operator is either "Equal" (default) or "Exists"
+	Operator string `protobuf:"bytes,2,opt,name=operator,proto3" json:"operator,omitempty"`
+	// value is the taint value to match if operator is "Equal"
+	Value string `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	// effect is the taint effect to match; empty matches all effects
+	Effect        string `protobuf:"bytes,4,opt,name=effect,proto3" json:"effect,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Toleration) Reset() {
+	*x = Toleration{}
+	mi := &file_api_api_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Toleration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Toleration) ProtoMessage() {}
+
+func (x *Toleration) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Toleration.ProtoReflect.Descriptor instead.
+func (*Toleration) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *Toleration) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Toleration) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *Toleration) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Toleration) GetEffect() string {
+	if x != nil {
+		return x.Effect
+	}
+	return ""
+}
+
 type GadgetInstanceState struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        GadgetInstanceStatus   `protobuf:"varint,1,opt,name=status,proto3,enum=api.GadgetInstanceStatus" json:"status,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Status   GadgetInstanceStatus   `protobuf:"varint,1,opt,name=status,proto3,enum=api.GadgetInstanceStatus" json:"status,omitempty"`
+	Message  string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Warnings []string               `protobuf:"bytes,3,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	// resources are the eBPF resources used by the gadget instance; unset if the
+	// gadget wasn't loaded (yet)
+	Resources     *GadgetInstanceResources `protobuf:"bytes,4,opt,name=resources,proto3" json:"resources,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GadgetInstanceState) Reset() {
 	*x = GadgetInstanceState{}
-	mi := &file_api_api_proto_msgTypes[22]
+	mi := &file_api_api_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1749,7 +3205,7 @@ func (x *GadgetInstanceState) String() string {
 func (*GadgetInstanceState) ProtoMessage() {}
 
 func (x *GadgetInstanceState) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[22]
+	mi := &file_api_api_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1762,7 +3218,7 @@ func (x *GadgetInstanceState) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GadgetInstanceState.ProtoReflect.Descriptor instead.
 func (*GadgetInstanceState) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{22}
+	return file_api_api_proto_rawDescGZIP(), []int{40}
 }
 
 func (x *GadgetInstanceState) GetStatus() GadgetInstanceStatus {
@@ -1779,6 +3235,91 @@ func (x *GadgetInstanceState) GetMessage() string {
 	return ""
 }
 
+func (x *GadgetInstanceState) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *GadgetInstanceState) GetResources() *GadgetInstanceResources {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+// GadgetInstanceResources holds the eBPF resources used by a gadget instance
+type GadgetInstanceResources struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Programs uint32                 `protobuf:"varint,1,opt,name=programs,proto3" json:"programs,omitempty"`
+	Maps     uint32                 `protobuf:"varint,2,opt,name=maps,proto3" json:"maps,omitempty"`
+	// mapMemory is the memory locked by the maps in bytes, ring buffers apart
+	MapMemory uint64 `protobuf:"varint,3,opt,name=mapMemory,proto3" json:"mapMemory,omitempty"`
+	// bufferMemory is the memory of the ring buffers and perf buffers in bytes
+	BufferMemory  uint64 `protobuf:"varint,4,opt,name=bufferMemory,proto3" json:"bufferMemory,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GadgetInstanceResources) Reset() {
+	*x = GadgetInstanceResources{}
+	mi := &file_api_api_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GadgetInstanceResources) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GadgetInstanceResources) ProtoMessage() {}
+
+func (x *GadgetInstanceResources) ProtoReflect() protoreflect.Message {
+	mi := &file_api_api_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GadgetInstanceResources.ProtoReflect.Descriptor instead.
+func (*GadgetInstanceResources) Descriptor() ([]byte, []int) {
+	return file_api_api_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *GadgetInstanceResources) GetPrograms() uint32 {
+	if x != nil {
+		return x.Programs
+	}
+	return 0
+}
+
+func (x *GadgetInstanceResources) GetMaps() uint32 {
+	if x != nil {
+		return x.Maps
+	}
+	return 0
+}
+
+func (x *GadgetInstanceResources) GetMapMemory() uint64 {
+	if x != nil {
+		return x.MapMemory
+	}
+	return 0
+}
+
+func (x *GadgetInstanceResources) GetBufferMemory() uint64 {
+	if x != nil {
+		return x.BufferMemory
+	}
+	return 0
+}
+
 type ListGadgetInstanceResponse struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
 	GadgetInstances []*GadgetInstance      `protobuf:"bytes,1,rep,name=gadgetInstances,proto3" json:"gadgetInstances,omitempty"`
@@ -1788,7 +3329,7 @@ type ListGadgetInstanceResponse struct {
 
 func (x *ListGadgetInstanceResponse) Reset() {
 	*x = ListGadgetInstanceResponse{}
-	mi := &file_api_api_proto_msgTypes[23]
+	mi := &file_api_api_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1800,7 +3341,7 @@ func (x *ListGadgetInstanceResponse) String() string {
 func (*ListGadgetInstanceResponse) ProtoMessage() {}
 
 func (x *ListGadgetInstanceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[23]
+	mi := &file_api_api_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1813,7 +3354,7 @@ func (x *ListGadgetInstanceResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListGadgetInstanceResponse.ProtoReflect.Descriptor instead.
 func (*ListGadgetInstanceResponse) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{23}
+	return file_api_api_proto_rawDescGZIP(), []int{42}
 }
 
 func (x *ListGadgetInstanceResponse) GetGadgetInstances() []*GadgetInstance {
@@ -1832,7 +3373,7 @@ type GadgetInstanceId struct {
 
 func (x *GadgetInstanceId) Reset() {
 	*x = GadgetInstanceId{}
-	mi := &file_api_api_proto_msgTypes[24]
+	mi := &file_api_api_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1844,7 +3385,7 @@ func (x *GadgetInstanceId) String() string {
 func (*GadgetInstanceId) ProtoMessage() {}
 
 func (x *GadgetInstanceId) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[24]
+	mi := &file_api_api_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1857,7 +3398,7 @@ func (x *GadgetInstanceId) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GadgetInstanceId.ProtoReflect.Descriptor instead.
 func (*GadgetInstanceId) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{24}
+	return file_api_api_proto_rawDescGZIP(), []int{43}
 }
 
 func (x *GadgetInstanceId) GetId() string {
@@ -1877,7 +3418,7 @@ type StatusResponse struct {
 
 func (x *StatusResponse) Reset() {
 	*x = StatusResponse{}
-	mi := &file_api_api_proto_msgTypes[25]
+	mi := &file_api_api_proto_msgTypes[44]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1889,7 +3430,7 @@ func (x *StatusResponse) String() string {
 func (*StatusResponse) ProtoMessage() {}
 
 func (x *StatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_api_proto_msgTypes[25]
+	mi := &file_api_api_proto_msgTypes[44]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1902,7 +3443,7 @@ func (x *StatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
 func (*StatusResponse) Descriptor() ([]byte, []int) {
-	return file_api_api_proto_rawDescGZIP(), []int{25}
+	return file_api_api_proto_rawDescGZIP(), []int{44}
 }
 
 func (x *StatusResponse) GetResult() int32 {
@@ -1923,25 +3464,44 @@ var File_api_api_proto protoreflect.FileDescriptor
 
 const file_api_api_proto_rawDesc = "" +
 	"\n" +
-	"\rapi/api.proto\x12\x03api\"\x9e\x02\n" +
+	"\rapi/api.proto\x12\x03api\"\x88\x03\n" +
 	"\x10GadgetRunRequest\x12\x1c\n" +
 	"\timageName\x18\x01 \x01(\tR\timageName\x12H\n" +
 	"\vparamValues\x18\x02 \x03(\v2&.api.GadgetRunRequest.ParamValuesEntryR\vparamValues\x12\x12\n" +
 	"\x04args\x18\x03 \x03(\tR\x04args\x12\x18\n" +
 	"\aversion\x18\x04 \x01(\rR\aversion\x12\x1a\n" +
 	"\blogLevel\x18\f \x01(\rR\blogLevel\x12\x18\n" +
-	"\atimeout\x18\r \x01(\x03R\atimeout\x1a>\n" +
+	"\atimeout\x18\r \x01(\x03R\atimeout\x12,\n" +
+	"\x11checksumBatchSize\x18\x0e \x01(\rR\x11checksumBatchSize\x12 \n" +
+	"\vcompression\x18\x0f \x01(\tR\vcompression\x12\x18\n" +
+	"\anoCache\x18\x10 \x01(\bR\anoCache\x1a>\n" +
 	"\x10ParamValuesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"?\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"w\n" +
 	"\x13GadgetAttachRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
-	"\aversion\x18\x02 \x01(\rR\aversion\"q\n" +
+	"\aversion\x18\x02 \x01(\rR\aversion\x12\x14\n" +
+	"\x05since\x18\x03 \x01(\x03R\x05since\x12 \n" +
+	"\vcompression\x18\x04 \x01(\tR\vcompression\"q\n" +
 	"\vGadgetEvent\x12\x12\n" +
 	"\x04type\x18\x01 \x01(\rR\x04type\x12\x10\n" +
 	"\x03seq\x18\x02 \x01(\rR\x03seq\x12\x18\n" +
 	"\apayload\x18\x03 \x01(\fR\apayload\x12\"\n" +
-	"\fdataSourceID\x18\x04 \x01(\rR\fdataSourceID\"\x13\n" +
+	"\fdataSourceID\x18\x04 \x01(\rR\fdataSourceID\"\x90\x01\n" +
+	"\x12EventBatchChecksum\x12\x1a\n" +
+	"\bfirstSeq\x18\x01 \x01(\rR\bfirstSeq\x12\x18\n" +
+	"\alastSeq\x18\x02 \x01(\rR\alastSeq\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\rR\x05count\x12\x16\n" +
+	"\x06digest\x18\x04 \x01(\fR\x06digest\x12\x16\n" +
+	"\x06signed\x18\x05 \x01(\bR\x06signed\"\xc0\x01\n" +
+	"\n" +
+	"EventDrops\x126\n" +
+	"\adropped\x18\x01 \x03(\v2\x1c.api.EventDrops.DroppedEntryR\adropped\x12\x1a\n" +
+	"\binterval\x18\x02 \x01(\x03R\binterval\x12\"\n" +
+	"\fbufferLength\x18\x03 \x01(\rR\fbufferLength\x1a:\n" +
+	"\fDroppedEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\rR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x04R\x05value:\x028\x01\"\x13\n" +
 	"\x11GadgetStopRequest\"\xd6\x01\n" +
 	"\x14GadgetControlRequest\x127\n" +
 	"\n" +
@@ -1955,7 +3515,76 @@ const file_api_api_proto_rawDesc = "" +
 	"\fInfoResponse\x12\x18\n" +
 	"\aversion\x18\x01 \x01(\tR\aversion\x12\"\n" +
 	"\fexperimental\x18\x03 \x01(\bR\fexperimental\x12$\n" +
-	"\rserverVersion\x18\x04 \x01(\tR\rserverVersion\"'\n" +
+	"\rserverVersion\x18\x04 \x01(\tR\rserverVersion\"w\n" +
+	"\x11DebugShellRequest\x12A\n" +
+	"\fstartRequest\x18\x01 \x01(\v2\x1b.api.DebugShellStartRequestH\x00R\fstartRequest\x12\x16\n" +
+	"\x05stdin\x18\x02 \x01(\fH\x00R\x05stdinB\a\n" +
+	"\x05Event\"\xfc\x01\n" +
+	"\x16DebugShellStartRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x18\n" +
+	"\apodName\x18\x02 \x01(\tR\apodName\x12$\n" +
+	"\rcontainerName\x18\x03 \x01(\tR\rcontainerName\x12\x18\n" +
+	"\acommand\x18\x04 \x03(\tR\acommand\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12\x18\n" +
+	"\atimeout\x18\x06 \x01(\x03R\atimeout\x12\x10\n" +
+	"\x03tty\x18\a \x01(\bR\x03tty\x12\x12\n" +
+	"\x04rows\x18\b \x01(\rR\x04rows\x12\x12\n" +
+	"\x04cols\x18\t \x01(\rR\x04cols\"\x93\x01\n" +
+	"\x0fDebugShellEvent\x122\n" +
+	"\astarted\x18\x01 \x01(\v2\x16.api.DebugShellStartedH\x00R\astarted\x12\x18\n" +
+	"\x06output\x18\x02 \x01(\fH\x00R\x06output\x12)\n" +
+	"\x04exit\x18\x03 \x01(\v2\x13.api.DebugShellExitH\x00R\x04exitB\a\n" +
+	"\x05Event\"\x7f\n" +
+	"\x11DebugShellStarted\x12\x1c\n" +
+	"\tsessionID\x18\x01 \x01(\tR\tsessionID\x12\x12\n" +
+	"\x04node\x18\x02 \x01(\tR\x04node\x12\x1c\n" +
+	"\tcontainer\x18\x03 \x01(\tR\tcontainer\x12\x1a\n" +
+	"\bdeadline\x18\x04 \x01(\x03R\bdeadline\"D\n" +
+	"\x0eDebugShellExit\x12\x1a\n" +
+	"\bexitCode\x18\x01 \x01(\x05R\bexitCode\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"M\n" +
+	"\x0fNodeInfoRequest\x12 \n" +
+	"\vtracepoints\x18\x01 \x03(\tR\vtracepoints\x12\x18\n" +
+	"\akprobes\x18\x02 \x03(\tR\akprobes\"\xbc\x04\n" +
+	"\bNodeInfo\x12$\n" +
+	"\rkernelRelease\x18\x01 \x01(\tR\rkernelRelease\x12\"\n" +
+	"\fbtfAvailable\x18\x02 \x01(\bR\fbtfAvailable\x120\n" +
+	"\x13ringBufferSupported\x18\x03 \x01(\bR\x13ringBufferSupported\x12\x1e\n" +
+	"\n" +
+	"cgroupMode\x18\x04 \x01(\tR\n" +
+	"cgroupMode\x12(\n" +
+	"\x0flsmBPFAvailable\x18\x05 \x01(\bR\x0flsmBPFAvailable\x12\x1a\n" +
+	"\blockdown\x18\x06 \x01(\tR\blockdown\x12(\n" +
+	"\x0ftracepointCount\x18\a \x01(\rR\x0ftracepointCount\x120\n" +
+	"\x13kprobeFunctionCount\x18\b \x01(\rR\x13kprobeFunctionCount\x12@\n" +
+	"\vtracepoints\x18\t \x03(\v2\x1e.api.NodeInfo.TracepointsEntryR\vtracepoints\x124\n" +
+	"\akprobes\x18\n" +
+	" \x03(\v2\x1a.api.NodeInfo.KprobesEntryR\akprobes\x1a>\n" +
+	"\x10TracepointsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x01\x1a:\n" +
+	"\fKprobesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\bR\x05value:\x028\x01\"5\n" +
+	"\x13NodeOverheadRequest\x12\x1e\n" +
+	"\n" +
+	"intervalMs\x18\x01 \x01(\rR\n" +
+	"intervalMs\"~\n" +
+	"\x10InstanceOverhead\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1c\n" +
+	"\timageName\x18\x03 \x01(\tR\timageName\x12(\n" +
+	"\x0feventsPerSecond\x18\x04 \x01(\x01R\x0feventsPerSecond\"\xad\x02\n" +
+	"\fNodeOverhead\x12\x1e\n" +
+	"\n" +
+	"cpuPercent\x18\x01 \x01(\x01R\n" +
+	"cpuPercent\x12 \n" +
+	"\vmemoryBytes\x18\x02 \x01(\x04R\vmemoryBytes\x12,\n" +
+	"\x11bpfRunTimePercent\x18\x03 \x01(\x01R\x11bpfRunTimePercent\x12(\n" +
+	"\x0fbpfProgramCount\x18\x04 \x01(\rR\x0fbpfProgramCount\x12,\n" +
+	"\x11bpfMapMemoryBytes\x18\x05 \x01(\x04R\x11bpfMapMemoryBytes\x12 \n" +
+	"\vbpfMapCount\x18\x06 \x01(\rR\vbpfMapCount\x123\n" +
+	"\tinstances\x18\a \x03(\v2\x15.api.InstanceOverheadR\tinstances\"'\n" +
 	"\vDataElement\x12\x18\n" +
 	"\apayload\x18\x01 \x03(\fR\apayload\"X\n" +
 	"\n" +
@@ -2045,14 +3674,39 @@ const file_api_api_proto_rawDesc = "" +
 	"\x15GetGadgetInfoResponse\x12/\n" +
 	"\n" +
 	"gadgetInfo\x18\x01 \x01(\v2\x0f.api.GadgetInfoR\n" +
-	"gadgetInfo\"\x88\x01\n" +
+	"gadgetInfo\"\xc4\x01\n" +
+	"\x15ValidateGadgetRequest\x12M\n" +
+	"\vparamValues\x18\x01 \x03(\v2+.api.ValidateGadgetRequest.ParamValuesEntryR\vparamValues\x12\x1c\n" +
+	"\timageName\x18\x02 \x01(\tR\timageName\x1a>\n" +
+	"\x10ParamValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"n\n" +
+	"\x0fValidationCheck\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12-\n" +
+	"\x06result\x18\x02 \x01(\x0e2\x15.api.ValidationResultR\x06result\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"w\n" +
+	"\x16ValidateGadgetResponse\x12/\n" +
+	"\n" +
+	"gadgetInfo\x18\x01 \x01(\v2\x0f.api.GadgetInfoR\n" +
+	"gadgetInfo\x12,\n" +
+	"\x06checks\x18\x02 \x03(\v2\x14.api.ValidationCheckR\x06checks\"\x88\x01\n" +
 	"\x1bCreateGadgetInstanceRequest\x12;\n" +
 	"\x0egadgetInstance\x18\x01 \x01(\v2\x13.api.GadgetInstanceR\x0egadgetInstance\x12,\n" +
 	"\x11eventBufferLength\x18\x02 \x01(\x05R\x11eventBufferLength\"s\n" +
 	"\x1cCreateGadgetInstanceResponse\x12\x16\n" +
 	"\x06result\x18\x01 \x01(\x05R\x06result\x12;\n" +
-	"\x0egadgetInstance\x18\x02 \x01(\v2\x13.api.GadgetInstanceR\x0egadgetInstance\"\x1c\n" +
-	"\x1aListGadgetInstancesRequest\"\xeb\x01\n" +
+	"\x0egadgetInstance\x18\x02 \x01(\v2\x13.api.GadgetInstanceR\x0egadgetInstance\"\xc2\x01\n" +
+	"\x1bUpdateGadgetInstanceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12S\n" +
+	"\vparamValues\x18\x02 \x03(\v21.api.UpdateGadgetInstanceRequest.ParamValuesEntryR\vparamValues\x1a>\n" +
+	"\x10ParamValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"s\n" +
+	"\x1cUpdateGadgetInstanceResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\x05R\x06result\x12;\n" +
+	"\x0egadgetInstance\x18\x02 \x01(\v2\x13.api.GadgetInstanceR\x0egadgetInstance\"8\n" +
+	"\x1aListGadgetInstancesRequest\x12\x1a\n" +
+	"\bselector\x18\x01 \x03(\tR\bselector\"\xec\x02\n" +
 	"\x0eGadgetInstance\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x129\n" +
 	"\fgadgetConfig\x18\x02 \x01(\v2\x15.api.GadgetRunRequestR\fgadgetConfig\x12\x12\n" +
@@ -2060,10 +3714,28 @@ const file_api_api_proto_rawDesc = "" +
 	"\vtimeCreated\x18\x04 \x01(\x03R\vtimeCreated\x12\x12\n" +
 	"\x04name\x18\x06 \x01(\tR\x04name\x12\x14\n" +
 	"\x05nodes\x18\x05 \x03(\tR\x05nodes\x12.\n" +
-	"\x05state\x18\a \x01(\v2\x18.api.GadgetInstanceStateR\x05state\"b\n" +
+	"\x05state\x18\a \x01(\v2\x18.api.GadgetInstanceStateR\x05state\x12\x16\n" +
+	"\x06paused\x18\b \x01(\bR\x06paused\x12\"\n" +
+	"\fnodeSelector\x18\t \x01(\tR\fnodeSelector\x121\n" +
+	"\vtolerations\x18\n" +
+	" \x03(\v2\x0f.api.TolerationR\vtolerations\x12\x10\n" +
+	"\x03ttl\x18\v \x01(\x03R\x03ttl\"h\n" +
+	"\n" +
+	"Toleration\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1a\n" +
+	"\boperator\x18\x02 \x01(\tR\boperator\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\x12\x16\n" +
+	"\x06effect\x18\x04 \x01(\tR\x06effect\"\xba\x01\n" +
 	"\x13GadgetInstanceState\x121\n" +
 	"\x06status\x18\x01 \x01(\x0e2\x19.api.GadgetInstanceStatusR\x06status\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"[\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1a\n" +
+	"\bwarnings\x18\x03 \x03(\tR\bwarnings\x12:\n" +
+	"\tresources\x18\x04 \x01(\v2\x1c.api.GadgetInstanceResourcesR\tresources\"\x8b\x01\n" +
+	"\x17GadgetInstanceResources\x12\x1a\n" +
+	"\bprograms\x18\x01 \x01(\rR\bprograms\x12\x12\n" +
+	"\x04maps\x18\x02 \x01(\rR\x04maps\x12\x1c\n" +
+	"\tmapMemory\x18\x03 \x01(\x04R\tmapMemory\x12\"\n" +
+	"\fbufferMemory\x18\x04 \x01(\x04R\fbufferMemory\"[\n" +
 	"\x1aListGadgetInstanceResponse\x12=\n" +
 	"\x0fgadgetInstances\x18\x01 \x03(\v2\x13.api.GadgetInstanceR\x0fgadgetInstances\"\"\n" +
 	"\x10GadgetInstanceId\x12\x0e\n" +
@@ -2091,21 +3763,34 @@ const file_api_api_proto_rawDesc = "" +
 	"\n" +
 	"\x06String\x10\f\x12\v\n" +
 	"\aCString\x10\r\x12\t\n" +
-	"\x05Bytes\x10\x0e*M\n" +
+	"\x05Bytes\x10\x0e*U\n" +
+	"\x10ValidationResult\x12\x14\n" +
+	"\x10ValidationPassed\x10\x00\x12\x15\n" +
+	"\x11ValidationWarning\x10\x01\x12\x14\n" +
+	"\x10ValidationFailed\x10\x02*_\n" +
 	"\x14GadgetInstanceStatus\x12\x11\n" +
 	"\rStatusInvalid\x10\x00\x12\x11\n" +
 	"\rStatusRunning\x10\x01\x12\x0f\n" +
-	"\vStatusError\x10\x022H\n" +
+	"\vStatusError\x10\x02\x12\x10\n" +
+	"\fStatusPaused\x10\x032\xc0\x01\n" +
 	"\x14BuiltInGadgetManager\x120\n" +
-	"\aGetInfo\x12\x10.api.InfoRequest\x1a\x11.api.InfoResponse\"\x002\x99\x01\n" +
+	"\aGetInfo\x12\x10.api.InfoRequest\x1a\x11.api.InfoResponse\"\x00\x124\n" +
+	"\vGetNodeInfo\x12\x14.api.NodeInfoRequest\x1a\r.api.NodeInfo\"\x00\x12@\n" +
+	"\x0fGetNodeOverhead\x12\x18.api.NodeOverheadRequest\x1a\x11.api.NodeOverhead\"\x002\xa8\x02\n" +
 	"\rGadgetManager\x12H\n" +
-	"\rGetGadgetInfo\x12\x19.api.GetGadgetInfoRequest\x1a\x1a.api.GetGadgetInfoResponse\"\x00\x12>\n" +
-	"\tRunGadget\x12\x19.api.GadgetControlRequest\x1a\x10.api.GadgetEvent\"\x00(\x010\x012\xda\x02\n" +
+	"\rGetGadgetInfo\x12\x19.api.GetGadgetInfoRequest\x1a\x1a.api.GetGadgetInfoResponse\"\x00\x12K\n" +
+	"\x0eValidateGadget\x12\x1a.api.ValidateGadgetRequest\x1a\x1b.api.ValidateGadgetResponse\"\x00\x12>\n" +
+	"\tRunGadget\x12\x19.api.GadgetControlRequest\x1a\x10.api.GadgetEvent\"\x00(\x010\x01\x12@\n" +
+	"\n" +
+	"DebugShell\x12\x16.api.DebugShellRequest\x1a\x14.api.DebugShellEvent\"\x00(\x010\x012\xc4\x04\n" +
 	"\x15GadgetInstanceManager\x12]\n" +
 	"\x14CreateGadgetInstance\x12 .api.CreateGadgetInstanceRequest\x1a!.api.CreateGadgetInstanceResponse\"\x00\x12Y\n" +
 	"\x13ListGadgetInstances\x12\x1f.api.ListGadgetInstancesRequest\x1a\x1f.api.ListGadgetInstanceResponse\"\x00\x12A\n" +
 	"\x11GetGadgetInstance\x12\x15.api.GadgetInstanceId\x1a\x13.api.GadgetInstance\"\x00\x12D\n" +
-	"\x14RemoveGadgetInstance\x12\x15.api.GadgetInstanceId\x1a\x13.api.StatusResponse\"\x00BEZCgithub.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/apib\x06proto3"
+	"\x14RemoveGadgetInstance\x12\x15.api.GadgetInstanceId\x1a\x13.api.StatusResponse\"\x00\x12C\n" +
+	"\x13PauseGadgetInstance\x12\x15.api.GadgetInstanceId\x1a\x13.api.StatusResponse\"\x00\x12D\n" +
+	"\x14ResumeGadgetInstance\x12\x15.api.GadgetInstanceId\x1a\x13.api.StatusResponse\"\x00\x12]\n" +
+	"\x14UpdateGadgetInstance\x12 .api.UpdateGadgetInstanceRequest\x1a!.api.UpdateGadgetInstanceResponse\"\x00BEZCgithub.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/apib\x06proto3"
 
 var (
 	file_api_api_proto_rawDescOnce sync.Once
@@ -2119,88 +3804,142 @@ func file_api_api_proto_rawDescGZIP() []byte {
 	return file_api_api_proto_rawDescData
 }
 
-var file_api_api_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_api_api_proto_msgTypes = make([]protoimpl.MessageInfo, 32)
+var file_api_api_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_api_api_proto_msgTypes = make([]protoimpl.MessageInfo, 56)
 var file_api_api_proto_goTypes = []any{
 	(Kind)(0),                            // 0: api.Kind
-	(GadgetInstanceStatus)(0),            // 1: api.GadgetInstanceStatus
-	(*GadgetRunRequest)(nil),             // 2: api.GadgetRunRequest
-	(*GadgetAttachRequest)(nil),          // 3: api.GadgetAttachRequest
-	(*GadgetEvent)(nil),                  // 4: api.GadgetEvent
-	(*GadgetStopRequest)(nil),            // 5: api.GadgetStopRequest
-	(*GadgetControlRequest)(nil),         // 6: api.GadgetControlRequest
-	(*InfoRequest)(nil),                  // 7: api.InfoRequest
-	(*InfoResponse)(nil),                 // 8: api.InfoResponse
-	(*DataElement)(nil),                  // 9: api.DataElement
-	(*GadgetData)(nil),                   // 10: api.GadgetData
-	(*GadgetDataArray)(nil),              // 11: api.GadgetDataArray
-	(*Param)(nil),                        // 12: api.Param
-	(*GadgetInfo)(nil),                   // 13: api.GadgetInfo
-	(*ExtraInfo)(nil),                    // 14: api.ExtraInfo
-	(*GadgetInspectAddendum)(nil),        // 15: api.GadgetInspectAddendum
-	(*DataSource)(nil),                   // 16: api.DataSource
-	(*Field)(nil),                        // 17: api.Field
-	(*GetGadgetInfoRequest)(nil),         // 18: api.GetGadgetInfoRequest
-	(*GetGadgetInfoResponse)(nil),        // 19: api.GetGadgetInfoResponse
-	(*CreateGadgetInstanceRequest)(nil),  // 20: api.CreateGadgetInstanceRequest
-	(*CreateGadgetInstanceResponse)(nil), // 21: api.CreateGadgetInstanceResponse
-	(*ListGadgetInstancesRequest)(nil),   // 22: api.ListGadgetInstancesRequest
-	(*GadgetInstance)(nil),               // 23: api.GadgetInstance
-	(*GadgetInstanceState)(nil),          // 24: api.GadgetInstanceState
-	(*ListGadgetInstanceResponse)(nil),   // 25: api.ListGadgetInstanceResponse
-	(*GadgetInstanceId)(nil),             // 26: api.GadgetInstanceId
-	(*StatusResponse)(nil),               // 27: api.StatusResponse
-	nil,                                  // 28: api.GadgetRunRequest.ParamValuesEntry
-	nil,                                  // 29: api.GadgetInfo.AnnotationsEntry
-	nil,                                  // 30: api.ExtraInfo.DataEntry
-	nil,                                  // 31: api.DataSource.AnnotationsEntry
-	nil,                                  // 32: api.Field.AnnotationsEntry
-	nil,                                  // 33: api.GetGadgetInfoRequest.ParamValuesEntry
+	(ValidationResult)(0),                // 1: api.ValidationResult
+	(GadgetInstanceStatus)(0),            // 2: api.GadgetInstanceStatus
+	(*GadgetRunRequest)(nil),             // 3: api.GadgetRunRequest
+	(*GadgetAttachRequest)(nil),          // 4: api.GadgetAttachRequest
+	(*GadgetEvent)(nil),                  // 5: api.GadgetEvent
+	(*EventBatchChecksum)(nil),           // 6: api.EventBatchChecksum
+	(*EventDrops)(nil),                   // 7: api.EventDrops
+	(*GadgetStopRequest)(nil),            // 8: api.GadgetStopRequest
+	(*GadgetControlRequest)(nil),         // 9: api.GadgetControlRequest
+	(*InfoRequest)(nil),                  // 10: api.InfoRequest
+	(*InfoResponse)(nil),                 // 11: api.InfoResponse
+	(*DebugShellRequest)(nil),            // 12: api.DebugShellRequest
+	(*DebugShellStartRequest)(nil),       // 13: api.DebugShellStartRequest
+	(*DebugShellEvent)(nil),              // 14: api.DebugShellEvent
+	(*DebugShellStarted)(nil),            // 15: api.DebugShellStarted
+	(*DebugShellExit)(nil),               // 16: api.DebugShellExit
+	(*NodeInfoRequest)(nil),              // 17: api.NodeInfoRequest
+	(*NodeInfo)(nil),                     // 18: api.NodeInfo
+	(*NodeOverheadRequest)(nil),          // 19: api.NodeOverheadRequest
+	(*InstanceOverhead)(nil),             // 20: api.InstanceOverhead
+	(*NodeOverhead)(nil),                 // 21: api.NodeOverhead
+	(*DataElement)(nil),                  // 22: api.DataElement
+	(*GadgetData)(nil),                   // 23: api.GadgetData
+	(*GadgetDataArray)(nil),              // 24: api.GadgetDataArray
+	(*Param)(nil),                        // 25: api.Param
+	(*GadgetInfo)(nil),                   // 26: api.GadgetInfo
+	(*ExtraInfo)(nil),                    // 27: api.ExtraInfo
+	(*GadgetInspectAddendum)(nil),        // 28: api.GadgetInspectAddendum
+	(*DataSource)(nil),                   // 29: api.DataSource
+	(*Field)(nil),                        // 30: api.Field
+	(*GetGadgetInfoRequest)(nil),         // 31: api.GetGadgetInfoRequest
+	(*GetGadgetInfoResponse)(nil),        // 32: api.GetGadgetInfoResponse
+	(*ValidateGadgetRequest)(nil),        // 33: api.ValidateGadgetRequest
+	(*ValidationCheck)(nil),              // 34: api.ValidationCheck
+	(*ValidateGadgetResponse)(nil),       // 35: api.ValidateGadgetResponse
+	(*CreateGadgetInstanceRequest)(nil),  // 36: api.CreateGadgetInstanceRequest
+	(*CreateGadgetInstanceResponse)(nil), // 37: api.CreateGadgetInstanceResponse
+	(*UpdateGadgetInstanceRequest)(nil),  // 38: api.UpdateGadgetInstanceRequest
+	(*UpdateGadgetInstanceResponse)(nil), // 39: api.UpdateGadgetInstanceResponse
+	(*ListGadgetInstancesRequest)(nil),   // 40: api.ListGadgetInstancesRequest
+	(*GadgetInstance)(nil),               // 41: api.GadgetInstance
+	(*Toleration)(nil),                   // 42: api.Toleration
+	(*GadgetInstanceState)(nil),          // 43: api.GadgetInstanceState
+	(*GadgetInstanceResources)(nil),      // 44: api.GadgetInstanceResources
+	(*ListGadgetInstanceResponse)(nil),   // 45: api.ListGadgetInstanceResponse
+	(*GadgetInstanceId)(nil),             // 46: api.GadgetInstanceId
+	(*StatusResponse)(nil),               // 47: api.StatusResponse
+	nil,                                  // 48: api.GadgetRunRequest.ParamValuesEntry
+	nil,                                  // 49: api.EventDrops.DroppedEntry
+	nil,                                  // 50: api.NodeInfo.TracepointsEntry
+	nil,                                  // 51: api.NodeInfo.KprobesEntry
+	nil,                                  // 52: api.GadgetInfo.AnnotationsEntry
+	nil,                                  // 53: api.ExtraInfo.DataEntry
+	nil,                                  // 54: api.DataSource.AnnotationsEntry
+	nil,                                  // 55: api.Field.AnnotationsEntry
+	nil,                                  // 56: api.GetGadgetInfoRequest.ParamValuesEntry
+	nil,                                  // 57: api.ValidateGadgetRequest.ParamValuesEntry
+	nil,                                  // 58: api.UpdateGadgetInstanceRequest.ParamValuesEntry
 }
 var file_api_api_proto_depIdxs = []int32{
-	28, // 0: api.GadgetRunRequest.paramValues:type_name -> api.GadgetRunRequest.ParamValuesEntry
-	2,  // 1: api.GadgetControlRequest.runRequest:type_name -> api.GadgetRunRequest
-	5,  // 2: api.GadgetControlRequest.stopRequest:type_name -> api.GadgetStopRequest
-	3,  // 3: api.GadgetControlRequest.attachRequest:type_name -> api.GadgetAttachRequest
-	9,  // 4: api.GadgetData.data:type_name -> api.DataElement
-	9,  // 5: api.GadgetDataArray.dataArray:type_name -> api.DataElement
-	16, // 6: api.GadgetInfo.dataSources:type_name -> api.DataSource
-	29, // 7: api.GadgetInfo.annotations:type_name -> api.GadgetInfo.AnnotationsEntry
-	12, // 8: api.GadgetInfo.params:type_name -> api.Param
-	14, // 9: api.GadgetInfo.extraInfo:type_name -> api.ExtraInfo
-	30, // 10: api.ExtraInfo.data:type_name -> api.ExtraInfo.DataEntry
-	17, // 11: api.DataSource.fields:type_name -> api.Field
-	31, // 12: api.DataSource.annotations:type_name -> api.DataSource.AnnotationsEntry
-	0,  // 13: api.Field.kind:type_name -> api.Kind
-	32, // 14: api.Field.annotations:type_name -> api.Field.AnnotationsEntry
-	33, // 15: api.GetGadgetInfoRequest.paramValues:type_name -> api.GetGadgetInfoRequest.ParamValuesEntry
-	13, // 16: api.GetGadgetInfoResponse.gadgetInfo:type_name -> api.GadgetInfo
-	23, // 17: api.CreateGadgetInstanceRequest.gadgetInstance:type_name -> api.GadgetInstance
-	23, // 18: api.CreateGadgetInstanceResponse.gadgetInstance:type_name -> api.GadgetInstance
-	2,  // 19: api.GadgetInstance.gadgetConfig:type_name -> api.GadgetRunRequest
-	24, // 20: api.GadgetInstance.state:type_name -> api.GadgetInstanceState
-	1,  // 21: api.GadgetInstanceState.status:type_name -> api.GadgetInstanceStatus
-	23, // 22: api.ListGadgetInstanceResponse.gadgetInstances:type_name -> api.GadgetInstance
-	15, // 23: api.ExtraInfo.DataEntry.value:type_name -> api.GadgetInspectAddendum
-	7,  // 24: api.BuiltInGadgetManager.GetInfo:input_type -> api.InfoRequest
-	18, // 25: api.GadgetManager.GetGadgetInfo:input_type -> api.GetGadgetInfoRequest
-	6,  // 26: api.GadgetManager.RunGadget:input_type -> api.GadgetControlRequest
-	20, // 27: api.GadgetInstanceManager.CreateGadgetInstance:input_type -> api.CreateGadgetInstanceRequest
-	22, // 28: api.GadgetInstanceManager.ListGadgetInstances:input_type -> api.ListGadgetInstancesRequest
-	26, // 29: api.GadgetInstanceManager.GetGadgetInstance:input_type -> api.GadgetInstanceId
-	26, // 30: api.GadgetInstanceManager.RemoveGadgetInstance:input_type -> api.GadgetInstanceId
-	8,  // 31: api.BuiltInGadgetManager.GetInfo:output_type -> api.InfoResponse
-	19, // 32: api.GadgetManager.GetGadgetInfo:output_type -> api.GetGadgetInfoResponse
-	4,  // 33: api.GadgetManager.RunGadget:output_type -> api.GadgetEvent
-	21, // 34: api.GadgetInstanceManager.CreateGadgetInstance:output_type -> api.CreateGadgetInstanceResponse
-	25, // 35: api.GadgetInstanceManager.ListGadgetInstances:output_type -> api.ListGadgetInstanceResponse
-	23, // 36: api.GadgetInstanceManager.GetGadgetInstance:output_type -> api.GadgetInstance
-	27, // 37: api.GadgetInstanceManager.RemoveGadgetInstance:output_type -> api.StatusResponse
-	31, // [31:38] is the sub-list for method output_type
-	24, // [24:31] is the sub-list for method input_type
-	24, // [24:24] is the sub-list for extension type_name
-	24, // [24:24] is the sub-list for extension extendee
-	0,  // [0:24] is the sub-list for field type_name
+	48, // 0: api.GadgetRunRequest.paramValues:type_name -> api.GadgetRunRequest.ParamValuesEntry
+	49, // 1: api.EventDrops.dropped:type_name -> api.EventDrops.DroppedEntry
+	3,  // 2: api.GadgetControlRequest.runRequest:type_name -> api.GadgetRunRequest
+	8,  // 3: api.GadgetControlRequest.stopRequest:type_name -> api.GadgetStopRequest
+	4,  // 4: api.GadgetControlRequest.attachRequest:type_name -> api.GadgetAttachRequest
+	13, // 5: api.DebugShellRequest.startRequest:type_name -> api.DebugShellStartRequest
+	15, // 6: api.DebugShellEvent.started:type_name -> api.DebugShellStarted
+	16, // 7: api.DebugShellEvent.exit:type_name -> api.DebugShellExit
+	50, // 8: api.NodeInfo.tracepoints:type_name -> api.NodeInfo.TracepointsEntry
+	51, // 9: api.NodeInfo.kprobes:type_name -> api.NodeInfo.KprobesEntry
+	20, // 10: api.NodeOverhead.instances:type_name -> api.InstanceOverhead
+	22, // 11: api.GadgetData.data:type_name -> api.DataElement
+	22, // 12: api.GadgetDataArray.dataArray:type_name -> api.DataElement
+	29, // 13: api.GadgetInfo.dataSources:type_name -> api.DataSource
+	52, // 14: api.GadgetInfo.annotations:type_name -> api.GadgetInfo.AnnotationsEntry
+	25, // 15: api.GadgetInfo.params:type_name -> api.Param
+	27, // 16: api.GadgetInfo.extraInfo:type_name -> api.ExtraInfo
+	53, // 17: api.ExtraInfo.data:type_name -> api.ExtraInfo.DataEntry
+	30, // 18: api.DataSource.fields:type_name -> api.Field
+	54, // 19: api.DataSource.annotations:type_name -> api.DataSource.AnnotationsEntry
+	0,  // 20: api.Field.kind:type_name -> api.Kind
+	55, // 21: api.Field.annotations:type_name -> api.Field.AnnotationsEntry
+	56, // 22: api.GetGadgetInfoRequest.paramValues:type_name -> api.GetGadgetInfoRequest.ParamValuesEntry
+	26, // 23: api.GetGadgetInfoResponse.gadgetInfo:type_name -> api.GadgetInfo
+	57, // 24: api.ValidateGadgetRequest.paramValues:type_name -> api.ValidateGadgetRequest.ParamValuesEntry
+	1,  // 25: api.ValidationCheck.result:type_name -> api.ValidationResult
+	26, // 26: api.ValidateGadgetResponse.gadgetInfo:type_name -> api.GadgetInfo
+	34, // 27: api.ValidateGadgetResponse.checks:type_name -> api.ValidationCheck
+	41, // 28: api.CreateGadgetInstanceRequest.gadgetInstance:type_name -> api.GadgetInstance
+	41, // 29: api.CreateGadgetInstanceResponse.gadgetInstance:type_name -> api.GadgetInstance
+	58, // 30: api.UpdateGadgetInstanceRequest.paramValues:type_name -> api.UpdateGadgetInstanceRequest.ParamValuesEntry
+	41, // 31: api.UpdateGadgetInstanceResponse.gadgetInstance:type_name -> api.GadgetInstance
+	3,  // 32: api.GadgetInstance.gadgetConfig:type_name -> api.GadgetRunRequest
+	43, // 33: api.GadgetInstance.state:type_name -> api.GadgetInstanceState
+	42, // 34: api.GadgetInstance.tolerations:type_name -> api.Toleration
+	2,  // 35: api.GadgetInstanceState.status:type_name -> api.GadgetInstanceStatus
+	44, // 36: api.GadgetInstanceState.resources:type_name -> api.GadgetInstanceResources
+	41, // 37: api.ListGadgetInstanceResponse.gadgetInstances:type_name -> api.GadgetInstance
+	28, // 38: api.ExtraInfo.DataEntry.value:type_name -> api.GadgetInspectAddendum
+	10, // 39: api.BuiltInGadgetManager.GetInfo:input_type -> api.InfoRequest
+	17, // 40: api.BuiltInGadgetManager.GetNodeInfo:input_type -> api.NodeInfoRequest
+	19, // 41: api.BuiltInGadgetManager.GetNodeOverhead:input_type -> api.NodeOverheadRequest
+	31, // 42: api.GadgetManager.GetGadgetInfo:input_type -> api.GetGadgetInfoRequest
+	33, // 43: api.GadgetManager.ValidateGadget:input_type -> api.ValidateGadgetRequest
+	9,  // 44: api.GadgetManager.RunGadget:input_type -> api.GadgetControlRequest
+	12, // 45: api.GadgetManager.DebugShell:input_type -> api.DebugShellRequest
+	36, // 46: api.GadgetInstanceManager.CreateGadgetInstance:input_type -> api.CreateGadgetInstanceRequest
+	40, // 47: api.GadgetInstanceManager.ListGadgetInstances:input_type -> api.ListGadgetInstancesRequest
+	46, // 48: api.GadgetInstanceManager.GetGadgetInstance:input_type -> api.GadgetInstanceId
+	46, // 49: api.GadgetInstanceManager.RemoveGadgetInstance:input_type -> api.GadgetInstanceId
+	46, // 50: api.GadgetInstanceManager.PauseGadgetInstance:input_type -> api.GadgetInstanceId
+	46, // 51: api.GadgetInstanceManager.ResumeGadgetInstance:input_type -> api.GadgetInstanceId
+	38, // 52: api.GadgetInstanceManager.UpdateGadgetInstance:input_type -> api.UpdateGadgetInstanceRequest
+	11, // 53: api.BuiltInGadgetManager.GetInfo:output_type -> api.InfoResponse
+	18, // 54: api.BuiltInGadgetManager.GetNodeInfo:output_type -> api.NodeInfo
+	21, // 55: api.BuiltInGadgetManager.GetNodeOverhead:output_type -> api.NodeOverhead
+	32, // 56: api.GadgetManager.GetGadgetInfo:output_type -> api.GetGadgetInfoResponse
+	35, // 57: api.GadgetManager.ValidateGadget:output_type -> api.ValidateGadgetResponse
+	5,  // 58: api.GadgetManager.RunGadget:output_type -> api.GadgetEvent
+	14, // 59: api.GadgetManager.DebugShell:output_type -> api.DebugShellEvent
+	37, // 60: api.GadgetInstanceManager.CreateGadgetInstance:output_type -> api.CreateGadgetInstanceResponse
+	45, // 61: api.GadgetInstanceManager.ListGadgetInstances:output_type -> api.ListGadgetInstanceResponse
+	41, // 62: api.GadgetInstanceManager.GetGadgetInstance:output_type -> api.GadgetInstance
+	47, // 63: api.GadgetInstanceManager.RemoveGadgetInstance:output_type -> api.StatusResponse
+	47, // 64: api.GadgetInstanceManager.PauseGadgetInstance:output_type -> api.StatusResponse
+	47, // 65: api.GadgetInstanceManager.ResumeGadgetInstance:output_type -> api.StatusResponse
+	39, // 66: api.GadgetInstanceManager.UpdateGadgetInstance:output_type -> api.UpdateGadgetInstanceResponse
+	53, // [53:67] is the sub-list for method output_type
+	39, // [39:53] is the sub-list for method input_type
+	39, // [39:39] is the sub-list for extension type_name
+	39, // [39:39] is the sub-list for extension extendee
+	0,  // [0:39] is the sub-list for field type_name
 }
 
 func init() { file_api_api_proto_init() }
@@ -2208,18 +3947,27 @@ func file_api_api_proto_init() {
 	if File_api_api_proto != nil {
 		return
 	}
-	file_api_api_proto_msgTypes[4].OneofWrappers = []any{
+	file_api_api_proto_msgTypes[6].OneofWrappers = []any{
 		(*GadgetControlRequest_RunRequest)(nil),
 		(*GadgetControlRequest_StopRequest)(nil),
 		(*GadgetControlRequest_AttachRequest)(nil),
 	}
+	file_api_api_proto_msgTypes[9].OneofWrappers = []any{
+		(*DebugShellRequest_StartRequest)(nil),
+		(*DebugShellRequest_Stdin)(nil),
+	}
+	file_api_api_proto_msgTypes[11].OneofWrappers = []any{
+		(*DebugShellEvent_Started)(nil),
+		(*DebugShellEvent_Output)(nil),
+		(*DebugShellEvent_Exit)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_api_proto_rawDesc), len(file_api_api_proto_rawDesc)),
-			NumEnums:      2,
-			NumMessages:   32,
+			NumEnums:      3,
+			NumMessages:   56,
 			NumExtensions: 0,
 			NumServices:   3,
 		},
@@ -18,6 +18,8 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type BuiltInGadgetManagerClient interface {
 	GetInfo(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
+	GetNodeInfo(ctx context.Context, in *NodeInfoRequest, opts ...grpc.CallOption) (*NodeInfo, error)
+	GetNodeOverhead(ctx context.Context, in *NodeOverheadRequest, opts ...grpc.CallOption) (*NodeOverhead, error)
 }
 
 type builtInGadgetManagerClient struct {
@@ -37,11 +39,31 @@ func (c *builtInGadgetManagerClient) GetInfo(ctx context.Context, in *InfoReques
 	return out, nil
 }
 
+func (c *builtInGadgetManagerClient) GetNodeInfo(ctx context.Context, in *NodeInfoRequest, opts ...grpc.CallOption) (*NodeInfo, error) {
+	out := new(NodeInfo)
+	err := c.cc.Invoke(ctx, "/api.BuiltInGadgetManager/GetNodeInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *builtInGadgetManagerClient) GetNodeOverhead(ctx context.Context, in *NodeOverheadRequest, opts ...grpc.CallOption) (*NodeOverhead, error) {
+	out := new(NodeOverhead)
+	err := c.cc.Invoke(ctx, "/api.BuiltInGadgetManager/GetNodeOverhead", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BuiltInGadgetManagerServer is the server API for BuiltInGadgetManager service.
 // All implementations must embed UnimplementedBuiltInGadgetManagerServer
 // for forward compatibility
 type BuiltInGadgetManagerServer interface {
 	GetInfo(context.Context, *InfoRequest) (*InfoResponse, error)
+	GetNodeInfo(context.Context, *NodeInfoRequest) (*NodeInfo, error)
+	GetNodeOverhead(context.Context, *NodeOverheadRequest) (*NodeOverhead, error)
 	mustEmbedUnimplementedBuiltInGadgetManagerServer()
 }
 
@@ -52,6 +74,12 @@ type UnimplementedBuiltInGadgetManagerServer struct {
 func (UnimplementedBuiltInGadgetManagerServer) GetInfo(context.Context, *InfoRequest) (*InfoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetInfo not implemented")
 }
+func (UnimplementedBuiltInGadgetManagerServer) GetNodeInfo(context.Context, *NodeInfoRequest) (*NodeInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNodeInfo not implemented")
+}
+func (UnimplementedBuiltInGadgetManagerServer) GetNodeOverhead(context.Context, *NodeOverheadRequest) (*NodeOverhead, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNodeOverhead not implemented")
+}
 func (UnimplementedBuiltInGadgetManagerServer) mustEmbedUnimplementedBuiltInGadgetManagerServer() {}
 
 // UnsafeBuiltInGadgetManagerServer may be embedded to opt out of forward compatibility for this service.
@@ -61,7 +89,7 @@ type UnsafeBuiltInGadgetManagerServer interface {
 	mustEmbedUnimplementedBuiltInGadgetManagerServer()
 }
 
-func RegisterBuiltInGadgetManagerServer(s *grpc.Server, srv BuiltInGadgetManagerServer) {
+func RegisterBuiltInGadgetManagerServer(s grpc.ServiceRegistrar, srv BuiltInGadgetManagerServer) {
 	s.RegisterService(&_BuiltInGadgetManager_serviceDesc, srv)
 }
 
@@ -83,6 +111,42 @@ func _BuiltInGadgetManager_GetInfo_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BuiltInGadgetManager_GetNodeInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BuiltInGadgetManagerServer).GetNodeInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.BuiltInGadgetManager/GetNodeInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BuiltInGadgetManagerServer).GetNodeInfo(ctx, req.(*NodeInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BuiltInGadgetManager_GetNodeOverhead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeOverheadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BuiltInGadgetManagerServer).GetNodeOverhead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.BuiltInGadgetManager/GetNodeOverhead",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BuiltInGadgetManagerServer).GetNodeOverhead(ctx, req.(*NodeOverheadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _BuiltInGadgetManager_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.BuiltInGadgetManager",
 	HandlerType: (*BuiltInGadgetManagerServer)(nil),
@@ -91,6 +155,14 @@ var _BuiltInGadgetManager_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetInfo",
 			Handler:    _BuiltInGadgetManager_GetInfo_Handler,
 		},
+		{
+			MethodName: "GetNodeInfo",
+			Handler:    _BuiltInGadgetManager_GetNodeInfo_Handler,
+		},
+		{
+			MethodName: "GetNodeOverhead",
+			Handler:    _BuiltInGadgetManager_GetNodeOverhead_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/api.proto",
@@ -101,7 +173,9 @@ var _BuiltInGadgetManager_serviceDesc = grpc.ServiceDesc{
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type GadgetManagerClient interface {
 	GetGadgetInfo(ctx context.Context, in *GetGadgetInfoRequest, opts ...grpc.CallOption) (*GetGadgetInfoResponse, error)
+	ValidateGadget(ctx context.Context, in *ValidateGadgetRequest, opts ...grpc.CallOption) (*ValidateGadgetResponse, error)
 	RunGadget(ctx context.Context, opts ...grpc.CallOption) (GadgetManager_RunGadgetClient, error)
+	DebugShell(ctx context.Context, opts ...grpc.CallOption) (GadgetManager_DebugShellClient, error)
 }
 
 type gadgetManagerClient struct {
@@ -121,6 +195,15 @@ func (c *gadgetManagerClient) GetGadgetInfo(ctx context.Context, in *GetGadgetIn
 	return out, nil
 }
 
+func (c *gadgetManagerClient) ValidateGadget(ctx context.Context, in *ValidateGadgetRequest, opts ...grpc.CallOption) (*ValidateGadgetResponse, error) {
+	out := new(ValidateGadgetResponse)
+	err := c.cc.Invoke(ctx, "/api.GadgetManager/ValidateGadget", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *gadgetManagerClient) RunGadget(ctx context.Context, opts ...grpc.CallOption) (GadgetManager_RunGadgetClient, error) {
 	stream, err := c.cc.NewStream(ctx, &_GadgetManager_serviceDesc.Streams[0], "/api.GadgetManager/RunGadget", opts...)
 	if err != nil {
@@ -152,12 +235,45 @@ func (x *gadgetManagerRunGadgetClient) Recv() (*GadgetEvent, error) {
 	return m, nil
 }
 
+func (c *gadgetManagerClient) DebugShell(ctx context.Context, opts ...grpc.CallOption) (GadgetManager_DebugShellClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GadgetManager_serviceDesc.Streams[1], "/api.GadgetManager/DebugShell", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gadgetManagerDebugShellClient{stream}
+	return x, nil
+}
+
+type GadgetManager_DebugShellClient interface {
+	Send(*DebugShellRequest) error
+	Recv() (*DebugShellEvent, error)
+	grpc.ClientStream
+}
+
+type gadgetManagerDebugShellClient struct {
+	grpc.ClientStream
+}
+
+func (x *gadgetManagerDebugShellClient) Send(m *DebugShellRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *gadgetManagerDebugShellClient) Recv() (*DebugShellEvent, error) {
+	m := new(DebugShellEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // GadgetManagerServer is the server API for GadgetManager service.
 // All implementations must embed UnimplementedGadgetManagerServer
 // for forward compatibility
 type GadgetManagerServer interface {
 	GetGadgetInfo(context.Context, *GetGadgetInfoRequest) (*GetGadgetInfoResponse, error)
+	ValidateGadget(context.Context, *ValidateGadgetRequest) (*ValidateGadgetResponse, error)
 	RunGadget(GadgetManager_RunGadgetServer) error
+	DebugShell(GadgetManager_DebugShellServer) error
 	mustEmbedUnimplementedGadgetManagerServer()
 }
 
@@ -168,9 +284,15 @@ type UnimplementedGadgetManagerServer struct {
 func (UnimplementedGadgetManagerServer) GetGadgetInfo(context.Context, *GetGadgetInfoRequest) (*GetGadgetInfoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetGadgetInfo not implemented")
 }
+func (UnimplementedGadgetManagerServer) ValidateGadget(context.Context, *ValidateGadgetRequest) (*ValidateGadgetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateGadget not implemented")
+}
 func (UnimplementedGadgetManagerServer) RunGadget(GadgetManager_RunGadgetServer) error {
 	return status.Errorf(codes.Unimplemented, "method RunGadget not implemented")
 }
+func (UnimplementedGadgetManagerServer) DebugShell(GadgetManager_DebugShellServer) error {
+	return status.Errorf(codes.Unimplemented, "method DebugShell not implemented")
+}
 func (UnimplementedGadgetManagerServer) mustEmbedUnimplementedGadgetManagerServer() {}
 
 // UnsafeGadgetManagerServer may be embedded to opt out of forward compatibility for this service.
@@ -180,7 +302,7 @@ type UnsafeGadgetManagerServer interface {
 	mustEmbedUnimplementedGadgetManagerServer()
 }
 
-func RegisterGadgetManagerServer(s *grpc.Server, srv GadgetManagerServer) {
+func RegisterGadgetManagerServer(s grpc.ServiceRegistrar, srv GadgetManagerServer) {
 	s.RegisterService(&_GadgetManager_serviceDesc, srv)
 }
 
@@ -202,6 +324,24 @@ func _GadgetManager_GetGadgetInfo_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GadgetManager_ValidateGadget_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateGadgetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetManagerServer).ValidateGadget(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GadgetManager/ValidateGadget",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetManagerServer).ValidateGadget(ctx, req.(*ValidateGadgetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _GadgetManager_RunGadget_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(GadgetManagerServer).RunGadget(&gadgetManagerRunGadgetServer{stream})
 }
@@ -228,6 +368,32 @@ func (x *gadgetManagerRunGadgetServer) Recv() (*GadgetControlRequest, error) {
 	return m, nil
 }
 
+func _GadgetManager_DebugShell_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GadgetManagerServer).DebugShell(&gadgetManagerDebugShellServer{stream})
+}
+
+type GadgetManager_DebugShellServer interface {
+	Send(*DebugShellEvent) error
+	Recv() (*DebugShellRequest, error)
+	grpc.ServerStream
+}
+
+type gadgetManagerDebugShellServer struct {
+	grpc.ServerStream
+}
+
+func (x *gadgetManagerDebugShellServer) Send(m *DebugShellEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *gadgetManagerDebugShellServer) Recv() (*DebugShellRequest, error) {
+	m := new(DebugShellRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _GadgetManager_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.GadgetManager",
 	HandlerType: (*GadgetManagerServer)(nil),
@@ -236,6 +402,10 @@ var _GadgetManager_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetGadgetInfo",
 			Handler:    _GadgetManager_GetGadgetInfo_Handler,
 		},
+		{
+			MethodName: "ValidateGadget",
+			Handler:    _GadgetManager_ValidateGadget_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -244,6 +414,12 @@ var _GadgetManager_serviceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "DebugShell",
+			Handler:       _GadgetManager_DebugShell_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "api/api.proto",
 }
@@ -256,6 +432,9 @@ type GadgetInstanceManagerClient interface {
 	ListGadgetInstances(ctx context.Context, in *ListGadgetInstancesRequest, opts ...grpc.CallOption) (*ListGadgetInstanceResponse, error)
 	GetGadgetInstance(ctx context.Context, in *GadgetInstanceId, opts ...grpc.CallOption) (*GadgetInstance, error)
 	RemoveGadgetInstance(ctx context.Context, in *GadgetInstanceId, opts ...grpc.CallOption) (*StatusResponse, error)
+	PauseGadgetInstance(ctx context.Context, in *GadgetInstanceId, opts ...grpc.CallOption) (*StatusResponse, error)
+	ResumeGadgetInstance(ctx context.Context, in *GadgetInstanceId, opts ...grpc.CallOption) (*StatusResponse, error)
+	UpdateGadgetInstance(ctx context.Context, in *UpdateGadgetInstanceRequest, opts ...grpc.CallOption) (*UpdateGadgetInstanceResponse, error)
 }
 
 type gadgetInstanceManagerClient struct {
@@ -302,6 +481,33 @@ func (c *gadgetInstanceManagerClient) RemoveGadgetInstance(ctx context.Context,
 	return out, nil
 }
 
+func (c *gadgetInstanceManagerClient) PauseGadgetInstance(ctx context.Context, in *GadgetInstanceId, opts ...grpc.CallOption) (*StatusResponse, error) {
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, "/api.GadgetInstanceManager/PauseGadgetInstance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetInstanceManagerClient) ResumeGadgetInstance(ctx context.Context, in *GadgetInstanceId, opts ...grpc.CallOption) (*StatusResponse, error) {
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, "/api.GadgetInstanceManager/ResumeGadgetInstance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetInstanceManagerClient) UpdateGadgetInstance(ctx context.Context, in *UpdateGadgetInstanceRequest, opts ...grpc.CallOption) (*UpdateGadgetInstanceResponse, error) {
+	out := new(UpdateGadgetInstanceResponse)
+	err := c.cc.Invoke(ctx, "/api.GadgetInstanceManager/UpdateGadgetInstance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GadgetInstanceManagerServer is the server API for GadgetInstanceManager service.
 // All implementations must embed UnimplementedGadgetInstanceManagerServer
 // for forward compatibility
@@ -310,6 +516,9 @@ type GadgetInstanceManagerServer interface {
 	ListGadgetInstances(context.Context, *ListGadgetInstancesRequest) (*ListGadgetInstanceResponse, error)
 	GetGadgetInstance(context.Context, *GadgetInstanceId) (*GadgetInstance, error)
 	RemoveGadgetInstance(context.Context, *GadgetInstanceId) (*StatusResponse, error)
+	PauseGadgetInstance(context.Context, *GadgetInstanceId) (*StatusResponse, error)
+	ResumeGadgetInstance(context.Context, *GadgetInstanceId) (*StatusResponse, error)
+	UpdateGadgetInstance(context.Context, *UpdateGadgetInstanceRequest) (*UpdateGadgetInstanceResponse, error)
 	mustEmbedUnimplementedGadgetInstanceManagerServer()
 }
 
@@ -329,6 +538,15 @@ func (UnimplementedGadgetInstanceManagerServer) GetGadgetInstance(context.Contex
 func (UnimplementedGadgetInstanceManagerServer) RemoveGadgetInstance(context.Context, *GadgetInstanceId) (*StatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveGadgetInstance not implemented")
 }
+func (UnimplementedGadgetInstanceManagerServer) PauseGadgetInstance(context.Context, *GadgetInstanceId) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseGadgetInstance not implemented")
+}
+func (UnimplementedGadgetInstanceManagerServer) ResumeGadgetInstance(context.Context, *GadgetInstanceId) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeGadgetInstance not implemented")
+}
+func (UnimplementedGadgetInstanceManagerServer) UpdateGadgetInstance(context.Context, *UpdateGadgetInstanceRequest) (*UpdateGadgetInstanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateGadgetInstance not implemented")
+}
 func (UnimplementedGadgetInstanceManagerServer) mustEmbedUnimplementedGadgetInstanceManagerServer() {}
 
 // UnsafeGadgetInstanceManagerServer may be embedded to opt out of forward compatibility for this service.
@@ -338,7 +556,7 @@ type UnsafeGadgetInstanceManagerServer interface {
 	mustEmbedUnimplementedGadgetInstanceManagerServer()
 }
 
-func RegisterGadgetInstanceManagerServer(s *grpc.Server, srv GadgetInstanceManagerServer) {
+func RegisterGadgetInstanceManagerServer(s grpc.ServiceRegistrar, srv GadgetInstanceManagerServer) {
 	s.RegisterService(&_GadgetInstanceManager_serviceDesc, srv)
 }
 
@@ -414,6 +632,60 @@ func _GadgetInstanceManager_RemoveGadgetInstance_Handler(srv interface{}, ctx co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GadgetInstanceManager_PauseGadgetInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GadgetInstanceId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetInstanceManagerServer).PauseGadgetInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GadgetInstanceManager/PauseGadgetInstance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetInstanceManagerServer).PauseGadgetInstance(ctx, req.(*GadgetInstanceId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GadgetInstanceManager_ResumeGadgetInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GadgetInstanceId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetInstanceManagerServer).ResumeGadgetInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GadgetInstanceManager/ResumeGadgetInstance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetInstanceManagerServer).ResumeGadgetInstance(ctx, req.(*GadgetInstanceId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GadgetInstanceManager_UpdateGadgetInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateGadgetInstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetInstanceManagerServer).UpdateGadgetInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GadgetInstanceManager/UpdateGadgetInstance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetInstanceManagerServer).UpdateGadgetInstance(ctx, req.(*UpdateGadgetInstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _GadgetInstanceManager_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.GadgetInstanceManager",
 	HandlerType: (*GadgetInstanceManagerServer)(nil),
@@ -434,6 +706,18 @@ var _GadgetInstanceManager_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveGadgetInstance",
 			Handler:    _GadgetInstanceManager_RemoveGadgetInstance_Handler,
 		},
+		{
+			MethodName: "PauseGadgetInstance",
+			Handler:    _GadgetInstanceManager_PauseGadgetInstance_Handler,
+		},
+		{
+			MethodName: "ResumeGadgetInstance",
+			Handler:    _GadgetInstanceManager_ResumeGadgetInstance_Handler,
+		},
+		{
+			MethodName: "UpdateGadgetInstance",
+			Handler:    _GadgetInstanceManager_UpdateGadgetInstance_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/api.proto",
@@ -30,6 +30,13 @@ const (
 	// expected / sent.
 	EventTypeGadgetInfo uint32 = 4
 
+	// EventTypeGadgetChecksum carries an EventBatchChecksum covering the previous payload events
+	EventTypeGadgetChecksum uint32 = 5
+
+	// EventTypeGadgetDrops carries an EventDrops with the number of payload events dropped because the client was
+	// too slow; dropped events don't use up sequence numbers
+	EventTypeGadgetDrops uint32 = 6
+
 	EventLogShift = 16
 )
 
@@ -72,6 +79,7 @@ const (
 	TypeFloat32     = "float32"
 	TypeFloat64     = "float64"
 	TypeDuration    = "duration"
+	TypeSize        = "size"
 	TypeIP          = "ip"
 	TypeStringSlice = "[]string"
 )
@@ -84,6 +92,10 @@ const (
 const (
 	FetchCountAnnotation    = "fetch-count"
 	FetchIntervalAnnotation = "fetch-interval"
+
+	// DroppedEventsAnnotation is set by clients on data sources to the number of their events the servers had to
+	// drop because the client was too slow
+	DroppedEventsAnnotation = "stream.dropped-events"
 )
 
 const (
@@ -0,0 +1,91 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/metrics"
+)
+
+// DropsReportInterval is how often streams report the events they dropped
+const DropsReportInterval = time.Second
+
+var ctrEventsDropped, _ = metrics.Int64Counter("ig_events_dropped",
+	metric.WithUnit("{event}"),
+	metric.WithDescription("Number of events dropped because clients didn't keep up with them"),
+)
+
+// DropCounter counts the payload events a stream dropped because its client
+// didn't keep up with them
+type DropCounter struct {
+	mu           sync.Mutex
+	dropped      map[uint32]uint64
+	since        time.Time
+	bufferLength uint32
+}
+
+func NewDropCounter(bufferLength uint32) *DropCounter {
+	return &DropCounter{
+		dropped:      make(map[uint32]uint64),
+		since:        time.Now(),
+		bufferLength: bufferLength,
+	}
+}
+
+// Add counts a dropped event of the given data source
+func (c *DropCounter) Add(dataSourceID uint32) {
+	c.mu.Lock()
+	c.dropped[dataSourceID]++
+	c.mu.Unlock()
+}
+
+// Take returns an EventTypeGadgetDrops event with the events dropped since the
+// previous call and resets the counts; it returns nil if none were dropped
+func (c *DropCounter) Take() *GadgetEvent {
+	now := time.Now()
+
+	c.mu.Lock()
+	dropped := c.dropped
+	since := c.since
+	c.since = now
+	if len(dropped) > 0 {
+		c.dropped = make(map[uint32]uint64)
+	}
+	c.mu.Unlock()
+
+	if len(dropped) == 0 {
+		return nil
+	}
+	var total uint64
+	for _, n := range dropped {
+		total += n
+	}
+	ctrEventsDropped.Add(context.Background(), int64(total))
+	d, _ := proto.Marshal(&EventDrops{
+		Dropped:      dropped,
+		Interval:     int64(now.Sub(since)),
+		BufferLength: c.bufferLength,
+	})
+	return &GadgetEvent{
+		Type:    EventTypeGadgetDrops,
+		Payload: d,
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDropCounter(t *testing.T) {
+	t.Parallel()
+
+	c := NewDropCounter(16)
+	assert.Nil(t, c.Take())
+
+	c.Add(0)
+	c.Add(0)
+	c.Add(3)
+
+	ev := c.Take()
+	require.NotNil(t, ev)
+	assert.Equal(t, EventTypeGadgetDrops, ev.Type)
+
+	drops := &EventDrops{}
+	require.NoError(t, proto.Unmarshal(ev.Payload, drops))
+	assert.Equal(t, map[uint32]uint64{0: 2, 3: 1}, drops.Dropped)
+	assert.Equal(t, uint32(16), drops.BufferLength)
+	assert.Positive(t, drops.Interval)
+
+	// The counts are reset after being taken
+	assert.Nil(t, c.Take())
+}
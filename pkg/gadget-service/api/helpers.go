@@ -21,7 +21,9 @@ import (
 	"io"
 	"net/url"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 )
 
 type (
@@ -42,6 +44,32 @@ func IsValidInstanceName(name string) bool {
 	return nameRegex.MatchString(name)
 }
 
+// MatchesSelector returns whether all tags of the selector are set on the
+// instance; an empty selector matches every instance
+func (x *GadgetInstance) MatchesSelector(selector []string) bool {
+	for _, tag := range selector {
+		if !slices.Contains(x.GetTags(), tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// ExpiresAt returns the time the instance is removed at because of its TTL; the
+// zero time is returned if it doesn't have a TTL
+func (x *GadgetInstance) ExpiresAt() time.Time {
+	if x.GetTtl() <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(x.GetTimeCreated()+x.GetTtl(), 0)
+}
+
+// IsExpired returns whether the TTL of the instance has passed at the given time
+func (x *GadgetInstance) IsExpired(now time.Time) bool {
+	expiresAt := x.ExpiresAt()
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
+
 func NewInstanceID() (string, error) {
 	id := make([]byte, 16)
 	_, err := io.ReadFull(rand.Reader, id)
@@ -0,0 +1,77 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesSelector(t *testing.T) {
+	t.Parallel()
+
+	instance := &GadgetInstance{Tags: []string{"team=netops", "env=prod", "debug"}}
+
+	tests := []struct {
+		name     string
+		selector []string
+		expected bool
+	}{
+		{name: "empty", selector: nil, expected: true},
+		{name: "single", selector: []string{"env=prod"}, expected: true},
+		{name: "all", selector: []string{"team=netops", "env=prod", "debug"}, expected: true},
+		{name: "different_value", selector: []string{"env=dev"}, expected: false},
+		{name: "one_missing", selector: []string{"team=netops", "env=dev"}, expected: false},
+		{name: "key_only", selector: []string{"team"}, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, instance.MatchesSelector(test.selector))
+		})
+	}
+
+	assert.True(t, (&GadgetInstance{}).MatchesSelector(nil))
+	assert.False(t, (&GadgetInstance{}).MatchesSelector([]string{"env=prod"}))
+}
+
+func TestIsExpired(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		ttl      int64
+		now      time.Time
+		expected bool
+	}{
+		{name: "no_ttl", ttl: 0, now: created.Add(24 * time.Hour), expected: false},
+		{name: "before", ttl: 60, now: created.Add(59 * time.Second), expected: false},
+		{name: "at", ttl: 60, now: created.Add(60 * time.Second), expected: true},
+		{name: "after", ttl: 60, now: created.Add(time.Hour), expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			instance := &GadgetInstance{TimeCreated: created.Unix(), Ttl: test.ttl}
+			assert.Equal(t, test.expected, instance.IsExpired(test.now))
+		})
+	}
+
+	assert.True(t, (&GadgetInstance{}).ExpiresAt().IsZero())
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit records who ran, created or removed which gadgets on the
+// gadget service.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
+)
+
+// Record describes a request handled by the gadget service
+type Record struct {
+	Time      time.Time       `json:"time"`
+	Operation authz.Operation `json:"operation"`
+	User      string          `json:"user"`
+	Groups    []string        `json:"groups,omitempty"`
+
+	// Peer is the address of the client
+	Peer string `json:"peer,omitempty"`
+
+	// Node is the node that handled the request
+	Node string `json:"node,omitempty"`
+
+	Image        string            `json:"image,omitempty"`
+	InstanceID   string            `json:"instanceID,omitempty"`
+	InstanceName string            `json:"instanceName,omitempty"`
+	ParamValues  map[string]string `json:"paramValues,omitempty"`
+
+	// Nodes are the nodes a gadget instance was requested for; all nodes if
+	// empty
+	Nodes []string `json:"nodes,omitempty"`
+
+	// Error is set if the request was denied or failed
+	Error string `json:"error,omitempty"`
+}
+
+type Auditor interface {
+	Audit(*Record)
+}
+
+// Auditors sends the records to all of its auditors
+type Auditors []Auditor
+
+func (a Auditors) Audit(r *Record) {
+	for _, auditor := range a {
+		auditor.Audit(r)
+	}
+}
+
+// FileAuditor appends the records to a file as JSON lines
+type FileAuditor struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileAuditor opens the given file for appending, creating it if needed
+func NewFileAuditor(path string) (*FileAuditor, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	return &FileAuditor{file: f}, nil
+}
+
+func (a *FileAuditor) Audit(r *Record) {
+	d, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.file.Write(append(d, '\n'))
+}
+
+func (a *FileAuditor) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
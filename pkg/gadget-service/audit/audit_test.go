@@ -0,0 +1,113 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+func testRecords() []*Record {
+	now := time.Now().UTC().Truncate(time.Second)
+	return []*Record{
+		{
+			Time:        now,
+			Operation:   authz.OperationRun,
+			User:        "alice",
+			Groups:      []string{"team-a"},
+			Peer:        "10.0.0.1:4321",
+			Node:        "node-1",
+			Image:       "ghcr.io/inspektor-gadget/gadget/trace_dns:latest",
+			ParamValues: map[string]string{"operator.KubeManager.namespace": "default"},
+		},
+		{
+			Time:       now,
+			Operation:  authz.OperationRemove,
+			User:       "bob",
+			Node:       "node-1",
+			InstanceID: "0123456789abcdef0123456789abcdef",
+			Error:      "rpc error: code = PermissionDenied desc = bob is not allowed to remove",
+		},
+	}
+}
+
+func TestFileAuditor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	records := testRecords()
+
+	a, err := NewFileAuditor(path)
+	require.NoError(t, err)
+	a.Audit(records[0])
+	require.NoError(t, a.Close())
+
+	// Records are appended to existing logs
+	a, err = NewFileAuditor(path)
+	require.NoError(t, err)
+	a.Audit(records[1])
+	require.NoError(t, a.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var got []*Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := &Record{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), r))
+		got = append(got, r)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, records, got)
+}
+
+func TestK8sEventAuditor(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	a := NewK8sEventAuditor(client, "gadget", "gadget-abcde", "1234", logger.DefaultLogger())
+	for _, r := range testRecords() {
+		a.Audit(r)
+	}
+
+	var events *corev1.EventList
+	require.Eventually(t, func() bool {
+		var err error
+		events, err = client.CoreV1().Events("gadget").List(context.Background(), metav1.ListOptions{})
+		return err == nil && len(events.Items) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	reasons := map[string]string{}
+	for _, e := range events.Items {
+		assert.Equal(t, "gadget-abcde", e.InvolvedObject.Name)
+		reasons[e.Reason] = e.Type
+	}
+	assert.Equal(t, map[string]string{
+		"GadgetRun":    corev1.EventTypeNormal,
+		"GadgetRemove": corev1.EventTypeWarning,
+	}, reasons)
+}
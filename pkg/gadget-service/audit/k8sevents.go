@@ -0,0 +1,102 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+const (
+	eventReasonPrefix = "Gadget"
+	eventComponent    = "gadget-service"
+	eventTimeout      = 5 * time.Second
+)
+
+// K8sEventAuditor creates a Kubernetes event for each record, attached to the
+// gadget pod; the message holds the record as JSON
+type K8sEventAuditor struct {
+	client kubernetes.Interface
+	pod    corev1.ObjectReference
+	logger logger.Logger
+}
+
+// NewK8sEventAuditor returns an auditor creating events for the given gadget
+// pod
+func NewK8sEventAuditor(client kubernetes.Interface, namespace, podName, podUID string, log logger.Logger) *K8sEventAuditor {
+	return &K8sEventAuditor{
+		client: client,
+		pod: corev1.ObjectReference{
+			Kind:       "Pod",
+			APIVersion: "v1",
+			Namespace:  namespace,
+			Name:       podName,
+			UID:        types.UID(podUID),
+		},
+		logger: log,
+	}
+}
+
+func (a *K8sEventAuditor) Audit(r *Record) {
+	d, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if r.Error != "" {
+		eventType = corev1.EventTypeWarning
+	}
+	// The reason is like GadgetRun or GadgetRemove
+	reason := eventReasonPrefix
+	if op := string(r.Operation); op != "" {
+		reason += strings.ToUpper(op[:1]) + op[1:]
+	}
+	now := metav1.NewTime(r.Time)
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			// Named like the events of client-go's event recorder
+			Name:      fmt.Sprintf("%s.%x", a.pod.Name, time.Now().UnixNano()),
+			Namespace: a.pod.Namespace,
+		},
+		InvolvedObject: a.pod,
+		Reason:         reason,
+		Message:        string(d),
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: eventComponent, Host: r.Node},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+
+	// Don't hold back the request while the event is created
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+		defer cancel()
+		_, err := a.client.CoreV1().Events(a.pod.Namespace).Create(ctx, event, metav1.CreateOptions{})
+		if err != nil {
+			a.logger.Warnf("creating %s audit event: %v", reason, err)
+		}
+	}()
+}
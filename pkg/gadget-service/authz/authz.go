@@ -0,0 +1,164 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package authz authenticates the clients of the gadget service using bearer
+// tokens or client certificates and authorizes their requests against a
+// policy granting operations on gadget images to users and groups.
+package authz
+
+import (
+	"context"
+	"crypto/subtle"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+const (
+	// AuthorizationHeader is the gRPC metadata key holding the bearer token
+	AuthorizationHeader = "authorization"
+
+	// AnonymousUser and UnauthenticatedGroup identify clients sending neither
+	// a token nor a client certificate
+	AnonymousUser        = "system:anonymous"
+	UnauthenticatedGroup = "system:unauthenticated"
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	User   string
+	Groups []string
+}
+
+func (id *Identity) String() string {
+	if len(id.Groups) == 0 {
+		return id.User
+	}
+	return id.User + " (" + strings.Join(id.Groups, ",") + ")"
+}
+
+type identityKey struct{}
+
+// NewContext returns a context carrying the given identity
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity stored in ctx by the authorizer, if any
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok
+}
+
+// TokenReviewer validates tokens unknown to the policy
+type TokenReviewer interface {
+	Review(ctx context.Context, token string) (*Identity, error)
+}
+
+type Authorizer struct {
+	policy   *Policy
+	reviewer TokenReviewer
+}
+
+// New returns an authorizer enforcing the given policy; reviewer is used to
+// validate tokens if the policy enables TokenReview
+func New(policy *Policy, reviewer TokenReviewer) *Authorizer {
+	return &Authorizer{
+		policy:   policy,
+		reviewer: reviewer,
+	}
+}
+
+// Authenticate returns the identity of the caller of the request in ctx. A bearer token takes precedence over the
+// subject of a verified client certificate.
+func (a *Authorizer) Authenticate(ctx context.Context) (*Identity, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(AuthorizationHeader); len(values) > 0 {
+			token, found := strings.CutPrefix(values[0], "Bearer ")
+			if !found || token == "" {
+				return nil, status.Error(codes.Unauthenticated, "invalid authorization header, expected a bearer token")
+			}
+			return a.authenticateToken(ctx, token)
+		}
+	}
+
+	return PeerIdentity(ctx), nil
+}
+
+// PeerIdentity returns the identity given by the verified client certificate of the request in ctx, or the
+// anonymous user if there's none
+func PeerIdentity(ctx context.Context) *Identity {
+	if p, ok := peer.FromContext(ctx); ok && p.AuthInfo != nil {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			cert := tlsInfo.State.VerifiedChains[0][0]
+			return &Identity{User: cert.Subject.CommonName, Groups: cert.Subject.Organization}
+		}
+	}
+	return &Identity{User: AnonymousUser, Groups: []string{UnauthenticatedGroup}}
+}
+
+func (a *Authorizer) authenticateToken(ctx context.Context, token string) (*Identity, error) {
+	for _, t := range a.policy.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			return &Identity{User: t.User, Groups: t.Groups}, nil
+		}
+	}
+	if a.policy.TokenReview && a.reviewer != nil {
+		id, err := a.reviewer.Review(ctx, token)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "reviewing token: %v", err)
+		}
+		return id, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "invalid token")
+}
+
+// Authorize returns a PermissionDenied error unless a rule of the policy grants op on image to id; image is
+// ignored for operations not related to any image
+func (a *Authorizer) Authorize(id *Identity, op Operation, image string) error {
+	if a.Allowed(id, op, image) {
+		return nil
+	}
+	if image != "" {
+		return status.Errorf(codes.PermissionDenied, "%s is not allowed to %s %s", id.User, op, image)
+	}
+	if !slices.Contains(imagelessOperations, op) {
+		return status.Errorf(codes.PermissionDenied, "%s is not allowed to %s unknown gadget instances", id.User, op)
+	}
+	return status.Errorf(codes.PermissionDenied, "%s is not allowed to %s", id.User, op)
+}
+
+// Allowed returns whether a rule of the policy grants op on image to id. An empty image only matches rules not
+// restricted to any image, unless op isn't related to any image.
+func (a *Authorizer) Allowed(id *Identity, op Operation, image string) bool {
+	imageless := image == "" && slices.Contains(imagelessOperations, op)
+	if image != "" {
+		if normalized, err := oci.NormalizeImageName(image); err == nil {
+			image = normalized
+		}
+	}
+	for _, rule := range a.policy.Rules {
+		if rule.matchesIdentity(id) && rule.matchesOperation(op) && (imageless || rule.matchesImage(image)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testPolicy = `
+tokens:
+  - user: alice
+    groups: [team-a]
+    token: alice-token
+rules:
+  - groups: [team-a]
+    operations: [run, attach, list, get]
+    images: [trace_dns]
+  - users: [admin]
+    operations: ["*"]
+  - users: [ci]
+    operations: [run]
+    images: ["ghcr.io/inspektor-gadget/gadget/snapshot_*"]
+  - users: [oncall]
+    operations: ["*", debug-shell]
+`
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	require.Len(t, policy.Rules, 4)
+	assert.Equal(t, []string{"ghcr.io/inspektor-gadget/gadget/trace_dns:latest"}, policy.Rules[0].Images)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret\n"), 0o600))
+	policy, err = ParsePolicy([]byte("tokens:\n  - user: bob\n    tokenFile: " + tokenFile + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", policy.Tokens[0].Token)
+
+	for name, data := range map[string]string{
+		"invalid operation":  "rules:\n  - users: [bob]\n    operations: [delete]\n",
+		"no subjects":        "rules:\n  - operations: [run]\n",
+		"token without user": "tokens:\n  - token: abc\n",
+		"missing token":      "tokens:\n  - user: bob\n",
+		"debug-shell images": "rules:\n  - users: [bob]\n    operations: [debug-shell]\n    images: [trace_dns]\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParsePolicy([]byte(data))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	a := New(policy, nil)
+
+	alice := &Identity{User: "alice", Groups: []string{"team-a"}}
+	admin := &Identity{User: "admin"}
+	ci := &Identity{User: "ci"}
+	oncall := &Identity{User: "oncall"}
+
+	tests := []struct {
+		name    string
+		id      *Identity
+		op      Operation
+		image   string
+		allowed bool
+	}{
+		{"group allowed image", alice, OperationRun, "trace_dns", true},
+		{"group allowed full image name", alice, OperationRun, "ghcr.io/inspektor-gadget/gadget/trace_dns:latest", true},
+		{"group other image", alice, OperationRun, "trace_exec", false},
+		{"group other tag", alice, OperationRun, "trace_dns:v0.40.0", false},
+		{"group operation without image", alice, OperationList, "", true},
+		{"group denied operation", alice, OperationRemove, "trace_dns", false},
+		{"wildcard operation", admin, OperationRemove, "trace_exec", true},
+		{"image prefix", ci, OperationRun, "snapshot_process:v0.40.0", true},
+		{"image prefix other image", ci, OperationRun, "trace_open", false},
+		{"unknown user", &Identity{User: "bob"}, OperationList, "", false},
+		{"group unknown image", alice, OperationGet, "", false},
+		{"unrestricted unknown image", admin, OperationGet, "", true},
+		{"wildcard operation debug-shell", admin, OperationDebugShell, "", false},
+		{"images debug-shell", alice, OperationDebugShell, "", false},
+		{"explicit debug-shell", oncall, OperationDebugShell, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := a.Authorize(test.id, test.op, test.image)
+			if test.allowed {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		})
+	}
+}
+
+func TestAllowedList(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	a := New(policy, nil)
+
+	alice := &Identity{User: "alice", Groups: []string{"team-a"}}
+	assert.True(t, a.Allowed(alice, OperationList, ""))
+	assert.True(t, a.Allowed(alice, OperationList, "trace_dns"))
+	assert.False(t, a.Allowed(alice, OperationList, "trace_exec"))
+
+	ci := &Identity{User: "ci"}
+	assert.False(t, a.Allowed(ci, OperationList, ""))
+}
+
+func TestAllowedDebugShell(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+rules:
+  - users: [bob]
+    operations: ["*"]
+    images: [trace_dns]
+`))
+	require.NoError(t, err)
+	a := New(policy, nil)
+
+	bob := &Identity{User: "bob"}
+	assert.True(t, a.Allowed(bob, OperationRun, "trace_dns"))
+	assert.True(t, a.Allowed(bob, OperationNodeInfo, ""))
+	assert.False(t, a.Allowed(bob, OperationDebugShell, ""))
+	assert.False(t, a.Allowed(bob, OperationDebugShell, "trace_dns"))
+}
+
+type fakeReviewer struct{}
+
+func (fakeReviewer) Review(ctx context.Context, token string) (*Identity, error) {
+	if token == "sa-token" {
+		return &Identity{User: "system:serviceaccount:default:ci"}, nil
+	}
+	return nil, errors.New("invalid")
+}
+
+func TestAuthenticate(t *testing.T) {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+
+	withToken := func(header string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(AuthorizationHeader, header))
+	}
+
+	a := New(policy, fakeReviewer{})
+
+	id, err := a.Authenticate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, AnonymousUser, id.User)
+
+	id, err = a.Authenticate(withToken("Bearer alice-token"))
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{User: "alice", Groups: []string{"team-a"}}, id)
+
+	_, err = a.Authenticate(withToken("Basic abc"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// The token review is only used if the policy enables it
+	_, err = a.Authenticate(withToken("Bearer sa-token"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	policy.TokenReview = true
+	id, err = a.Authenticate(withToken("Bearer sa-token"))
+	require.NoError(t, err)
+	assert.Equal(t, "system:serviceaccount:default:ci", id.User)
+
+	_, err = a.Authenticate(withToken("Bearer other-token"))
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+// Operation is an action a client can request from the gadget service
+type Operation string
+
+const (
+	OperationInfo       Operation = "info"        // GetGadgetInfo
+	OperationNodeInfo   Operation = "node-info"   // GetNodeInfo, GetNodeOverhead
+	OperationRun        Operation = "run"         // RunGadget
+	OperationAttach     Operation = "attach"      // RunGadget with an attach request
+	OperationDebugShell Operation = "debug-shell" // DebugShell
+	OperationCreate     Operation = "create"      // CreateGadgetInstance
+	OperationList       Operation = "list"        // ListGadgetInstances
+	OperationGet        Operation = "get"         // GetGadgetInstance
+	OperationRemove     Operation = "remove"      // RemoveGadgetInstance
+	OperationPause      Operation = "pause"       // PauseGadgetInstance
+	OperationResume     Operation = "resume"      // ResumeGadgetInstance
+	OperationUpdate     Operation = "update"      // UpdateGadgetInstance
+
+	// OperationAll matches all operations in rules except OperationDebugShell, which must be granted explicitly
+	OperationAll Operation = "*"
+)
+
+// imagelessOperations aren't related to any image, so rules restricted to images grant them as well; the instances
+// returned by list are filtered by the images of the rules instead
+var imagelessOperations = []Operation{OperationNodeInfo, OperationList}
+
+var operations = []Operation{
+	OperationInfo, OperationNodeInfo, OperationRun, OperationAttach, OperationDebugShell, OperationCreate,
+	OperationList, OperationGet, OperationRemove, OperationPause, OperationResume, OperationUpdate, OperationAll,
+}
+
+// Policy holds the tokens accepted by the gadget service and the rules deciding what their holders may do
+type Policy struct {
+	// Tokens are the static bearer tokens accepted
+	Tokens []Token `json:"tokens" yaml:"tokens"`
+
+	// TokenReview enables validating bearer tokens that aren't static tokens using the TokenReview API of
+	// Kubernetes, like the tokens of service accounts
+	TokenReview bool `json:"tokenReview" yaml:"tokenReview"`
+
+	// Rules grant operations; everything not granted by any rule is denied
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Token is a static bearer token identifying a user
+type Token struct {
+	// User is the name of the user the token belongs to
+	User   string   `json:"user" yaml:"user"`
+	Groups []string `json:"groups" yaml:"groups"`
+
+	// Token or TokenFile, the path to a file holding it, must be set
+	Token     string `json:"token,omitempty" yaml:"token,omitempty"`
+	TokenFile string `json:"tokenFile,omitempty" yaml:"tokenFile,omitempty"`
+}
+
+// Rule grants operations on images to users and groups
+type Rule struct {
+	Users  []string `json:"users" yaml:"users"`
+	Groups []string `json:"groups" yaml:"groups"`
+
+	Operations []Operation `json:"operations" yaml:"operations"`
+
+	// Images restricts the rule to the given images, like trace_dns or ghcr.io/inspektor-gadget/gadget/*;
+	// all images if empty. Operations not related to any image, like node-info, aren't restricted, and list only
+	// returns the instances of the given images. Rules granting debug-shell can't be restricted to images.
+	Images []string `json:"images" yaml:"images"`
+}
+
+// LoadPolicyFile reads a policy in YAML format from the given file
+func LoadPolicyFile(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading authorization policy: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses and validates a policy in YAML format; tokens given by file are read
+func ParsePolicy(data []byte) (*Policy, error) {
+	policy := &Policy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("parsing authorization policy: %w", err)
+	}
+
+	for i := range policy.Tokens {
+		token := &policy.Tokens[i]
+		if token.User == "" {
+			return nil, fmt.Errorf("token %d: missing user", i)
+		}
+		if token.TokenFile != "" {
+			content, err := os.ReadFile(token.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("token of %q: reading token file: %w", token.User, err)
+			}
+			token.Token = strings.TrimSpace(string(content))
+		}
+		if token.Token == "" {
+			return nil, fmt.Errorf("token of %q: missing token", token.User)
+		}
+	}
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+			return nil, fmt.Errorf("rule %d: no users or groups given", i)
+		}
+		for _, op := range rule.Operations {
+			if !slices.Contains(operations, op) {
+				return nil, fmt.Errorf("rule %d: invalid operation %q", i, op)
+			}
+			if op == OperationDebugShell && len(rule.Images) > 0 {
+				return nil, fmt.Errorf("rule %d: %s can't be restricted to images", i, op)
+			}
+		}
+		for j, image := range rule.Images {
+			if strings.HasSuffix(image, "*") {
+				continue
+			}
+			normalized, err := oci.NormalizeImageName(image)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+			rule.Images[j] = normalized
+		}
+	}
+	return policy, nil
+}
+
+func (r *Rule) matchesIdentity(id *Identity) bool {
+	if slices.Contains(r.Users, id.User) || slices.Contains(r.Users, "*") {
+		return true
+	}
+	for _, group := range id.Groups {
+		if slices.Contains(r.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesOperation returns whether the rule grants op; debug shells give access
+// to whole containers, so they're only granted by rules naming them
+func (r *Rule) matchesOperation(op Operation) bool {
+	if slices.Contains(r.Operations, op) {
+		return true
+	}
+	return op != OperationDebugShell && slices.Contains(r.Operations, OperationAll)
+}
+
+// matchesImage returns whether the rule applies to image; an empty image, like
+// the one of an instance that couldn't be found, is only matched by rules not
+// restricted to any image
+func (r *Rule) matchesImage(image string) bool {
+	if len(r.Images) == 0 {
+		return true
+	}
+	if image == "" {
+		return false
+	}
+	for _, pattern := range r.Images {
+		if pattern == image {
+			return true
+		}
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(image, pattern[:len(pattern)-1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type k8sTokenReviewer struct {
+	client kubernetes.Interface
+}
+
+// NewK8sTokenReviewer returns a reviewer validating tokens with the TokenReview API of Kubernetes
+func NewK8sTokenReviewer(client kubernetes.Interface) TokenReviewer {
+	return &k8sTokenReviewer{client: client}
+}
+
+func (r *k8sTokenReviewer) Review(ctx context.Context, token string) (*Identity, error) {
+	review, err := r.client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("creating token review: %w", err)
+	}
+	if !review.Status.Authenticated {
+		if review.Status.Error != "" {
+			return nil, errors.New(review.Status.Error)
+		}
+		return nil, errors.New("token not authenticated")
+	}
+	return &Identity{
+		User:   review.Status.User.Username,
+		Groups: review.Status.User.Groups,
+	}, nil
+}
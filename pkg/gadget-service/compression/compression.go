@@ -0,0 +1,140 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compression registers the codecs the gadget service can compress the
+// event streams with. Importing it on both sides is enough for clients to
+// advertise them; the server only compresses the events of a stream with the
+// codec requested by the client.
+package compression
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+const (
+	None = "none"
+	Gzip = gzip.Name
+	Zstd = "zstd"
+)
+
+// Names returns the names of the supported codecs, including None
+func Names() []string {
+	return []string{None, Gzip, Zstd}
+}
+
+// SetSendCompressor makes the server compress the messages it sends on the
+// stream of ctx with the given codec. It must be called before anything is
+// sent. An error is returned if the client doesn't support the codec, in which
+// case the messages are sent uncompressed.
+func SetSendCompressor(ctx context.Context, name string) error {
+	switch name {
+	case "", None:
+		return nil
+	case Gzip, Zstd:
+	default:
+		return fmt.Errorf("unknown compression %q, expected one of %v", name, Names())
+	}
+	supported, err := grpc.ClientSupportedCompressors(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(supported, name) {
+		return fmt.Errorf("compression %q isn't supported by the client", name)
+	}
+	return grpc.SetSendCompressor(ctx, name)
+}
+
+func init() {
+	encoding.RegisterCompressor(newZstdCompressor())
+}
+
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+func newZstdCompressor() *zstdCompressor {
+	c := &zstdCompressor{}
+	c.encoders.New = func() any {
+		// Events are small, so favor speed and don't spawn goroutines for
+		// every message
+		enc, _ := zstd.NewWriter(nil,
+			zstd.WithEncoderLevel(zstd.SpeedFastest),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithLowerEncoderMem(true),
+		)
+		return &zstdWriter{Encoder: enc, pool: &c.encoders}
+	}
+	return c
+}
+
+func (c *zstdCompressor) Name() string {
+	return Zstd
+}
+
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	z := c.encoders.Get().(*zstdWriter)
+	z.Encoder.Reset(w)
+	return z, nil
+}
+
+func (z *zstdWriter) Close() error {
+	defer z.pool.Put(z)
+	return z.Encoder.Close()
+}
+
+type zstdReader struct {
+	*zstd.Decoder
+	pool *sync.Pool
+}
+
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	z, inPool := c.decoders.Get().(*zstdReader)
+	if !inPool {
+		dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdReader{Decoder: dec, pool: &c.decoders}, nil
+	}
+	if err := z.Decoder.Reset(r); err != nil {
+		c.decoders.Put(z)
+		return nil, err
+	}
+	return z, nil
+}
+
+func (z *zstdReader) Read(p []byte) (int, error) {
+	n, err := z.Decoder.Read(p)
+	if err == io.EOF {
+		// Release the reference to the message before reusing the decoder
+		z.Decoder.Reset(nil)
+		z.pool.Put(z)
+	}
+	return n, err
+}
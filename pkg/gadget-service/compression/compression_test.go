@@ -0,0 +1,73 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/encoding"
+)
+
+func TestRegistered(t *testing.T) {
+	for _, name := range Names() {
+		if name == None {
+			continue
+		}
+		c := encoding.GetCompressor(name)
+		require.NotNil(t, c, name)
+		assert.Equal(t, name, c.Name())
+	}
+}
+
+func TestZstdRoundTrip(t *testing.T) {
+	c := encoding.GetCompressor(Zstd)
+	require.NotNil(t, c)
+
+	// Compress several messages to reuse the pooled encoders and decoders
+	for _, msg := range [][]byte{
+		bytes.Repeat([]byte(`{"comm":"curl","pid":1234}`), 100),
+		[]byte("short"),
+		{},
+	} {
+		var buf bytes.Buffer
+		w, err := c.Compress(&buf)
+		require.NoError(t, err)
+		_, err = w.Write(msg)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		r, err := c.Decompress(&buf)
+		require.NoError(t, err)
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, msg, got)
+	}
+}
+
+func TestSetSendCompressor(t *testing.T) {
+	// Nothing needs to be set without compression
+	require.NoError(t, SetSendCompressor(context.Background(), ""))
+	require.NoError(t, SetSendCompressor(context.Background(), None))
+
+	require.ErrorContains(t, SetSendCompressor(context.Background(), "lz4"), "unknown compression")
+
+	// Outside of a gRPC handler, the stream can't be found
+	require.Error(t, SetSendCompressor(context.Background(), Zstd))
+}
@@ -0,0 +1,460 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gateway translates HTTP requests with JSON payloads into calls to
+// the gadget service, so web UIs and scripts can run gadgets and consume their
+// events without a gRPC client. Event streams are sent as server-sent events
+// or, if the client asks for it, over a WebSocket.
+//
+// The gateway is a regular client of the gadget service: authentication,
+// authorization, auditing and quotas all apply to the requests it forwards.
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	jsonformatter "github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/formatters/json"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+// maxRequestSize limits the size of the JSON requests read from clients
+const maxRequestSize = 1 << 20
+
+// Types of the messages sent on event streams
+const (
+	MessageTypeInfo   = "info"
+	MessageTypeData   = "data"
+	MessageTypeResult = "result"
+	MessageTypeDrops  = "drops"
+	MessageTypeLog    = "log"
+	MessageTypeError  = "error"
+)
+
+// Message is sent to clients for every event of a gadget
+type Message struct {
+	Type string `json:"type"`
+
+	// Seq is the sequence number of data messages
+	Seq uint32 `json:"seq,omitempty"`
+
+	// DataSource is the name of the data source of data messages
+	DataSource string `json:"datasource,omitempty"`
+
+	// Data holds the gadget information of info messages, the fields of the
+	// event (or an array of events) of data messages and the number of dropped
+	// events per data source of drops messages
+	Data json.RawMessage `json:"data,omitempty"`
+
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type Gateway struct {
+	gadgetManager   api.GadgetManagerClient
+	instanceManager api.GadgetInstanceManagerClient
+	logger          logger.Logger
+	mux             *http.ServeMux
+	upgrader        websocket.Upgrader
+}
+
+// New returns a gateway forwarding requests to the gadget service reachable
+// through conn
+func New(conn grpc.ClientConnInterface, logger logger.Logger) *Gateway {
+	g := &Gateway{
+		gadgetManager:   api.NewGadgetManagerClient(conn),
+		instanceManager: api.NewGadgetInstanceManagerClient(conn),
+		logger:          logger,
+		mux:             http.NewServeMux(),
+	}
+	g.mux.HandleFunc("POST /v1/gadgets/run", g.runGadget)
+	g.mux.HandleFunc("GET /v1/instances", g.listInstances)
+	g.mux.HandleFunc("GET /v1/instances/{id}/attach", g.attachInstance)
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+// outgoingContext forwards the credentials of the HTTP request to the gadget
+// service
+func outgoingContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, authz.AuthorizationHeader, auth)
+	}
+	return ctx
+}
+
+func (g *Gateway) listInstances(w http.ResponseWriter, r *http.Request) {
+	res, err := g.instanceManager.ListGadgetInstances(outgoingContext(r), &api.ListGadgetInstancesRequest{
+		Selector: r.URL.Query()["selector"],
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	d, err := protojson.Marshal(res)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(d)
+}
+
+// runGadget runs the gadget given by the GadgetRunRequest in the body and
+// streams its events as server-sent events. It only accepts POST requests, so
+// gadgets can't be run by cross-site WebSocket connections.
+func (g *Gateway) runGadget(w http.ResponseWriter, r *http.Request) {
+	req := &api.GadgetRunRequest{}
+	d, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reading run request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := protojson.Unmarshal(d, req); err != nil {
+		http.Error(w, fmt.Sprintf("decoding run request: %v", err), http.StatusBadRequest)
+		return
+	}
+	s, err := newSSESink(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if req.Version == 0 {
+		req.Version = api.VersionGadgetRunProtocol
+	}
+	// The events are decoded here, so checksums can't be verified by clients
+	// and compression wouldn't help
+	req.ChecksumBatchSize = 0
+	req.Compression = ""
+
+	g.stream(outgoingContext(r), s, &api.GadgetControlRequest{
+		Event: &api.GadgetControlRequest_RunRequest{RunRequest: req},
+	})
+}
+
+// attachInstance attaches to a gadget instance and streams its events; the
+// optional since parameter replays the events buffered during the given
+// duration
+func (g *Gateway) attachInstance(w http.ResponseWriter, r *http.Request) {
+	req := &api.GadgetAttachRequest{
+		Id:      r.PathValue("id"),
+		Version: api.VersionGadgetRunProtocol,
+	}
+	if since := r.URL.Query().Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since parameter: %v", err), http.StatusBadRequest)
+			return
+		}
+		req.Since = time.Now().Add(-d).UnixNano()
+	}
+
+	var s sink
+	if websocket.IsWebSocketUpgrade(r) {
+		conn, err := g.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s = &websocketSink{conn: conn}
+	} else {
+		sse, err := newSSESink(w)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s = sse
+	}
+
+	g.stream(outgoingContext(r), s, &api.GadgetControlRequest{
+		Event: &api.GadgetControlRequest_AttachRequest{AttachRequest: req},
+	})
+}
+
+// stream sends the control request to the gadget service and forwards the
+// events it sends back to s until the stream ends or the client goes away
+func (g *Gateway) stream(ctx context.Context, s sink, controlRequest *api.GadgetControlRequest) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Stop the gadget as soon as WebSocket clients close the connection
+	if ws, ok := s.(*websocketSink); ok {
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := ws.conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	err := g.forward(ctx, s, controlRequest)
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+	// Reply with a proper status code if the gadget service refused the
+	// request before anything was streamed
+	if sse, ok := s.(*sseSink); ok && !sse.started {
+		writeError(sse.w, err)
+		return
+	}
+	s.send(&Message{Type: MessageTypeError, Message: errorMessage(err)})
+}
+
+func (g *Gateway) forward(ctx context.Context, s sink, controlRequest *api.GadgetControlRequest) error {
+	client, err := g.gadgetManager.RunGadget(ctx)
+	if err != nil {
+		return err
+	}
+	if err := client.Send(controlRequest); err != nil {
+		return err
+	}
+
+	dec := newDecoder()
+	for {
+		ev, err := client.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		msg, err := dec.decode(ev)
+		if err != nil {
+			g.logger.Debugf("gateway: decoding event of type %d: %v", ev.Type, err)
+			continue
+		}
+		if msg == nil {
+			continue
+		}
+		if err := s.send(msg); err != nil {
+			// The client went away
+			return nil
+		}
+	}
+}
+
+type decodedDataSource struct {
+	ds        datasource.DataSource
+	formatter *jsonformatter.Formatter
+}
+
+// decoder turns the events of the gadget service into messages for clients
+type decoder struct {
+	dataSources map[uint32]*decodedDataSource
+}
+
+func newDecoder() *decoder {
+	return &decoder{
+		dataSources: make(map[uint32]*decodedDataSource),
+	}
+}
+
+// decode returns the message to send for ev or nil if it isn't of interest to
+// clients
+func (d *decoder) decode(ev *api.GadgetEvent) (*Message, error) {
+	switch ev.Type {
+	case api.EventTypeGadgetInfo:
+		gi := &api.GadgetInfo{}
+		if err := proto.Unmarshal(ev.Payload, gi); err != nil {
+			return nil, err
+		}
+		for _, inds := range gi.DataSources {
+			ds, err := datasource.NewFromAPI(inds)
+			if err != nil {
+				return nil, fmt.Errorf("creating data source %q: %w", inds.Name, err)
+			}
+			formatter, err := jsonformatter.New(ds, jsonformatter.WithShowAll(true))
+			if err != nil {
+				return nil, fmt.Errorf("creating formatter for data source %q: %w", inds.Name, err)
+			}
+			d.dataSources[inds.Id] = &decodedDataSource{ds: ds, formatter: formatter}
+		}
+		data, err := protojson.Marshal(gi)
+		if err != nil {
+			return nil, err
+		}
+		return &Message{Type: MessageTypeInfo, Data: data}, nil
+	case api.EventTypeGadgetPayload:
+		dds, ok := d.dataSources[ev.DataSourceID]
+		if !ok {
+			return nil, fmt.Errorf("unknown data source %d", ev.DataSourceID)
+		}
+		var data []byte
+		switch dds.ds.Type() {
+		case datasource.TypeSingle:
+			p, err := dds.ds.NewPacketSingleFromRaw(ev.Payload)
+			if err != nil {
+				return nil, err
+			}
+			data = dds.formatter.Marshal(p)
+			dds.ds.Release(p)
+		case datasource.TypeArray:
+			p, err := dds.ds.NewPacketArrayFromRaw(ev.Payload)
+			if err != nil {
+				return nil, err
+			}
+			data = dds.formatter.MarshalArray(p)
+			dds.ds.Release(p)
+		default:
+			return nil, fmt.Errorf("unknown data source type %d", dds.ds.Type())
+		}
+		return &Message{
+			Type:       MessageTypeData,
+			Seq:        ev.Seq,
+			DataSource: dds.ds.Name(),
+			Data:       data,
+		}, nil
+	case api.EventTypeGadgetResult:
+		return &Message{Type: MessageTypeResult, Message: string(ev.Payload)}, nil
+	case api.EventTypeGadgetDrops:
+		drops := &api.EventDrops{}
+		if err := proto.Unmarshal(ev.Payload, drops); err != nil {
+			return nil, err
+		}
+		dropped := make(map[string]uint64, len(drops.Dropped))
+		for dsID, n := range drops.Dropped {
+			if dds, ok := d.dataSources[dsID]; ok {
+				dropped[dds.ds.Name()] = n
+			}
+		}
+		data, err := json.Marshal(dropped)
+		if err != nil {
+			return nil, err
+		}
+		return &Message{Type: MessageTypeDrops, Data: data}, nil
+	default:
+		if ev.Type >= 1<<api.EventLogShift {
+			return &Message{
+				Type:    MessageTypeLog,
+				Level:   logger.Level(ev.Type >> api.EventLogShift).String(),
+				Message: string(ev.Payload),
+			}, nil
+		}
+		return nil, nil
+	}
+}
+
+type sink interface {
+	send(*Message) error
+}
+
+// sseSink writes messages as server-sent events; the response header is only
+// written with the first message
+type sseSink struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool
+}
+
+func newSSESink(w http.ResponseWriter) (*sseSink, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming is not supported by the connection")
+	}
+	return &sseSink{w: w, flusher: flusher}, nil
+}
+
+func (s *sseSink) send(msg *Message) error {
+	d, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.WriteHeader(http.StatusOK)
+	}
+	if msg.Type == MessageTypeData {
+		if _, err := fmt.Fprintf(s.w, "id: %d\n", msg.Seq); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", msg.Type, d); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// websocketSink writes messages as text messages to a WebSocket
+type websocketSink struct {
+	conn *websocket.Conn
+}
+
+func (s *websocketSink) send(msg *Message) error {
+	return s.conn.WriteJSON(msg)
+}
+
+// errorMessage returns the message of gRPC errors without their code
+func errorMessage(err error) string {
+	if st, ok := status.FromError(err); ok {
+		return st.Message()
+	}
+	return err.Error()
+}
+
+// httpStatus maps the code of gRPC errors to HTTP status codes
+func httpStatus(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	http.Error(w, strings.TrimSpace(errorMessage(err)), httpStatus(err))
+}
@@ -0,0 +1,242 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+type fakeService struct {
+	api.UnimplementedGadgetManagerServer
+	api.UnimplementedGadgetInstanceManagerServer
+	t *testing.T
+}
+
+func (f *fakeService) ListGadgetInstances(ctx context.Context, req *api.ListGadgetInstancesRequest) (*api.ListGadgetInstanceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(authz.AuthorizationHeader); len(v) == 0 || v[0] != "Bearer secret" {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return &api.ListGadgetInstanceResponse{
+		GadgetInstances: []*api.GadgetInstance{{Id: "abc", Tags: req.Selector}},
+	}, nil
+}
+
+func (f *fakeService) RunGadget(stream api.GadgetManager_RunGadgetServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if runRequest := req.GetRunRequest(); runRequest != nil && runRequest.ImageName == "trace_exec" {
+		assert.Equal(f.t, uint32(api.VersionGadgetRunProtocol), runRequest.Version)
+	} else if attachRequest := req.GetAttachRequest(); attachRequest == nil || attachRequest.Id != "abc" {
+		return status.Error(codes.InvalidArgument, "unexpected request")
+	}
+
+	ds, err := datasource.New(datasource.TypeSingle, "exec")
+	require.NoError(f.t, err)
+	comm, err := ds.AddField("comm", api.Kind_String)
+	require.NoError(f.t, err)
+
+	gi, _ := proto.Marshal(&api.GadgetInfo{
+		DataSources: []*api.DataSource{{
+			Id:     7,
+			Type:   uint32(ds.Type()),
+			Name:   ds.Name(),
+			Fields: ds.Fields(),
+		}},
+	})
+	if err := stream.Send(&api.GadgetEvent{Type: api.EventTypeGadgetInfo, Payload: gi}); err != nil {
+		return err
+	}
+
+	p, err := ds.NewPacketSingle()
+	require.NoError(f.t, err)
+	require.NoError(f.t, comm.PutString(p, "curl"))
+	payload, _ := proto.Marshal(p.Raw())
+	if err := stream.Send(&api.GadgetEvent{Type: api.EventTypeGadgetPayload, Seq: 1, DataSourceID: 7, Payload: payload}); err != nil {
+		return err
+	}
+
+	drops, _ := proto.Marshal(&api.EventDrops{Dropped: map[uint32]uint64{7: 3}})
+	if err := stream.Send(&api.GadgetEvent{Type: api.EventTypeGadgetDrops, Payload: drops}); err != nil {
+		return err
+	}
+	return stream.Send(&api.GadgetEvent{Type: uint32(logger.WarnLevel) << api.EventLogShift, Payload: []byte("careful")})
+}
+
+func newTestGateway(t *testing.T) *httptest.Server {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	svc := &fakeService{t: t}
+	api.RegisterGadgetManagerServer(server, svc)
+	api.RegisterGadgetInstanceManagerServer(server, svc)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///test",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ts := httptest.NewServer(New(conn, logger.DefaultLogger()))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func checkMessages(t *testing.T, msgs []*Message) {
+	require.Len(t, msgs, 4)
+
+	assert.Equal(t, MessageTypeInfo, msgs[0].Type)
+
+	assert.Equal(t, MessageTypeData, msgs[1].Type)
+	assert.Equal(t, uint32(1), msgs[1].Seq)
+	assert.Equal(t, "exec", msgs[1].DataSource)
+	assert.JSONEq(t, `{"comm":"curl"}`, string(msgs[1].Data))
+
+	assert.Equal(t, MessageTypeDrops, msgs[2].Type)
+	assert.JSONEq(t, `{"exec":3}`, string(msgs[2].Data))
+
+	assert.Equal(t, MessageTypeLog, msgs[3].Type)
+	assert.Equal(t, "warning", msgs[3].Level)
+	assert.Equal(t, "careful", msgs[3].Message)
+}
+
+func TestListInstances(t *testing.T) {
+	ts := newTestGateway(t)
+
+	res, err := http.Get(ts.URL + "/v1/instances")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/instances?selector=a&selector=b", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var list struct {
+		GadgetInstances []struct {
+			ID   string   `json:"id"`
+			Tags []string `json:"tags"`
+		} `json:"gadgetInstances"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&list))
+	require.Len(t, list.GadgetInstances, 1)
+	assert.Equal(t, "abc", list.GadgetInstances[0].ID)
+	assert.Equal(t, []string{"a", "b"}, list.GadgetInstances[0].Tags)
+}
+
+func TestRunGadgetSSE(t *testing.T) {
+	ts := newTestGateway(t)
+
+	res, err := http.Post(ts.URL+"/v1/gadgets/run", "application/json", strings.NewReader(`{"imageName":"trace_exec"}`))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	var msgs []*Message
+	var event string
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if v, ok := strings.CutPrefix(line, "event: "); ok {
+			event = v
+		}
+		if v, ok := strings.CutPrefix(line, "data: "); ok {
+			msg := &Message{}
+			require.NoError(t, json.Unmarshal([]byte(v), msg))
+			assert.Equal(t, event, msg.Type)
+			msgs = append(msgs, msg)
+		}
+	}
+	checkMessages(t, msgs)
+}
+
+func TestRunGadgetSSEError(t *testing.T) {
+	ts := newTestGateway(t)
+
+	res, err := http.Post(ts.URL+"/v1/gadgets/run", "application/json", strings.NewReader(`{"imageName":"unknown"}`))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res, err = http.Post(ts.URL+"/v1/gadgets/run", "application/json", strings.NewReader(`{"imageName":`))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestRunGadgetPostOnly(t *testing.T) {
+	ts := newTestGateway(t)
+
+	res, err := http.Get(ts.URL + "/v1/gadgets/run")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	_, res, err = websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/v1/gadgets/run", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+}
+
+func TestAttachInstanceWebSocket(t *testing.T) {
+	ts := newTestGateway(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/v1/instances/abc/attach", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var msgs []*Message
+	for {
+		msg := &Message{}
+		if err := conn.ReadJSON(msg); err != nil {
+			break
+		}
+		msgs = append(msgs, msg)
+	}
+	checkMessages(t, msgs)
+}
@@ -16,6 +16,7 @@ package instancemanager
 
 import (
 	"sync"
+	"time"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 )
@@ -27,14 +28,18 @@ type GadgetInstanceClient struct {
 	seq        uint32
 	gadgetDone chan struct{}
 	replayBuf  []*bufferedEvent
+	drops      *api.DropCounter
 }
 
+const clientBufferLength = 1024
+
 func NewGadgetInstanceClient(client api.GadgetManager_RunGadgetServer) *GadgetInstanceClient {
 	c := &GadgetInstanceClient{
 		client:     client,
-		buffer:     make(chan *api.GadgetEvent, 1024),
+		buffer:     make(chan *api.GadgetEvent, clientBufferLength),
 		seq:        0,
 		gadgetDone: make(chan struct{}),
+		drops:      api.NewDropCounter(clientBufferLength),
 	}
 	return c
 }
@@ -57,6 +62,8 @@ func (c *GadgetInstanceClient) Run() error {
 		}
 	}
 	c.replayBuf = nil
+	ticker := time.NewTicker(api.DropsReportInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case buf := <-c.buffer:
@@ -64,6 +71,12 @@ func (c *GadgetInstanceClient) Run() error {
 			if err != nil {
 				return err
 			}
+		case <-ticker.C:
+			if ev := c.drops.Take(); ev != nil {
+				if err := c.client.Send(ev); err != nil {
+					return err
+				}
+			}
 		case <-done:
 			return nil
 		case <-c.gadgetDone:
@@ -85,5 +98,8 @@ func (c *GadgetInstanceClient) SendPayload(datasourceID uint32, payload []byte)
 	select {
 	case c.buffer <- event:
 	default:
+		// Dropped events are reported instead of using up sequence numbers
+		c.seq--
+		c.drops.Add(datasourceID)
 	}
 }
@@ -0,0 +1,327 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instancemanager
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDiskBufferMaxBytes is the default limit of the size of the events
+	// stored on disk for each gadget instance
+	DefaultDiskBufferMaxBytes = 64 * 1024 * 1024
+
+	// DefaultDiskBufferMaxAge is the default limit of the age of the events
+	// stored on disk
+	DefaultDiskBufferMaxAge = time.Hour
+
+	// diskBufferSegments is the number of segments the events are split into;
+	// the oldest segment is dropped as a whole once the limit is reached
+	diskBufferSegments = 8
+
+	diskBufferMetaFile      = "meta.json"
+	diskBufferSegmentSuffix = ".seg"
+
+	// recordHeaderSize is the size of the header of each event: the length of
+	// the payload, the ID of its data source and its timestamp
+	recordHeaderSize = 4 + 4 + 8
+)
+
+// DiskBufferConfig configures storing the buffered events of the gadget
+// instances on disk, so that they can be replayed after the daemon restarts
+type DiskBufferConfig struct {
+	// Dir is the directory holding a subdirectory for each gadget instance;
+	// events are only stored on disk if it's set
+	Dir string
+
+	// MaxBytes limits the size of the events stored for each gadget instance
+	MaxBytes int64
+
+	// MaxAge limits the age of the events stored; 0 = no limit
+	MaxAge time.Duration
+}
+
+type diskBufferMeta struct {
+	DataSources []string `json:"dataSources"`
+}
+
+type diskSegment struct {
+	seq  uint64
+	size int64
+
+	// newest is the timestamp of the newest event of the segment
+	newest int64
+}
+
+// diskBuffer stores the events of a gadget instance in a directory. Events are
+// appended to the newest segment file; once it's full a new one is started and
+// the oldest segments are removed to keep the total size below maxBytes.
+// Segments only holding events older than maxAge are removed as well.
+type diskBuffer struct {
+	dir         string
+	maxBytes    int64
+	maxAge      time.Duration
+	segmentSize int64
+
+	// dataSources holds the names of the data sources the IDs of the stored
+	// events refer to
+	dataSources []string
+
+	segments []*diskSegment // oldest first
+	cur      *os.File
+	curSeg   *diskSegment
+}
+
+func openDiskBuffer(dir string, maxBytes int64, maxAge time.Duration) (*diskBuffer, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultDiskBufferMaxBytes
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating event buffer directory: %w", err)
+	}
+	b := &diskBuffer{
+		dir:         dir,
+		maxBytes:    maxBytes,
+		maxAge:      maxAge,
+		segmentSize: max(maxBytes/diskBufferSegments, recordHeaderSize),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading event buffer directory: %w", err)
+	}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), diskBufferSegmentSuffix)
+		if !ok {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, fmt.Errorf("reading event buffer segment: %w", err)
+		}
+		b.segments = append(b.segments, &diskSegment{seq: seq, size: info.Size()})
+	}
+	sort.Slice(b.segments, func(i, j int) bool { return b.segments[i].seq < b.segments[j].seq })
+	return b, nil
+}
+
+func (b *diskBuffer) segmentPath(seg *diskSegment) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%016d%s", seg.seq, diskBufferSegmentSuffix))
+}
+
+// load returns the data sources and the events stored, oldest first. Events
+// older than maxAge are skipped, as well as an incomplete event at the end of
+// a segment, as left behind if the daemon was killed while writing it.
+func (b *diskBuffer) load() ([]string, []*bufferedEvent, error) {
+	blob, err := os.ReadFile(filepath.Join(b.dir, diskBufferMetaFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading event buffer metadata: %w", err)
+	}
+	meta := &diskBufferMeta{}
+	if err := json.Unmarshal(blob, meta); err != nil {
+		return nil, nil, fmt.Errorf("unmarshaling event buffer metadata: %w", err)
+	}
+	b.dataSources = meta.DataSources
+
+	var oldest int64
+	if b.maxAge > 0 {
+		oldest = time.Now().Add(-b.maxAge).UnixNano()
+	}
+	var events []*bufferedEvent
+	for _, seg := range b.segments {
+		segEvents, err := readSegment(b.segmentPath(seg), b.maxBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ev := range segEvents {
+			seg.newest = max(seg.newest, ev.timestamp)
+			if ev.timestamp >= oldest {
+				events = append(events, ev)
+			}
+		}
+	}
+	return meta.DataSources, events, nil
+}
+
+// readSegment returns the events of a segment; reading stops at an event
+// larger than maxSize, as the segment has to be corrupted then
+func readSegment(path string, maxSize int64) ([]*bufferedEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening event buffer segment: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var events []*bufferedEvent
+	header := make([]byte, recordHeaderSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return events, nil
+			}
+			return nil, fmt.Errorf("reading event buffer segment: %w", err)
+		}
+		size := binary.LittleEndian.Uint32(header[0:4])
+		if int64(size) > maxSize {
+			return events, nil
+		}
+		ev := &bufferedEvent{
+			payload:      make([]byte, size),
+			datasourceID: binary.LittleEndian.Uint32(header[4:8]),
+			timestamp:    int64(binary.LittleEndian.Uint64(header[8:16])),
+		}
+		if _, err := io.ReadFull(r, ev.payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return events, nil
+			}
+			return nil, fmt.Errorf("reading event buffer segment: %w", err)
+		}
+		events = append(events, ev)
+	}
+}
+
+// setDataSources stores the data sources the IDs of the events refer to; if
+// they changed, the events stored so far are dropped
+func (b *diskBuffer) setDataSources(dataSources []string) error {
+	if b.dataSources != nil && slices.Equal(b.dataSources, dataSources) {
+		return nil
+	}
+	if err := b.reset(); err != nil {
+		return err
+	}
+	b.dataSources = dataSources
+	blob, err := json.Marshal(&diskBufferMeta{DataSources: dataSources})
+	if err != nil {
+		return fmt.Errorf("marshaling event buffer metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(b.dir, diskBufferMetaFile), blob, 0o600); err != nil {
+		return fmt.Errorf("writing event buffer metadata: %w", err)
+	}
+	return nil
+}
+
+// reset removes all the events stored
+func (b *diskBuffer) reset() error {
+	if b.cur != nil {
+		b.cur.Close()
+		b.cur = nil
+		b.curSeg = nil
+	}
+	for _, seg := range b.segments {
+		if err := os.Remove(b.segmentPath(seg)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing event buffer segment: %w", err)
+		}
+	}
+	b.segments = nil
+	return nil
+}
+
+// append stores an event, starting a new segment and dropping old ones if
+// needed
+func (b *diskBuffer) append(ev *bufferedEvent) error {
+	if b.cur == nil || b.curSeg.size >= b.segmentSize {
+		if err := b.rotate(ev.timestamp); err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, recordHeaderSize+len(ev.payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(ev.payload)))
+	binary.LittleEndian.PutUint32(record[4:8], ev.datasourceID)
+	binary.LittleEndian.PutUint64(record[8:16], uint64(ev.timestamp))
+	copy(record[recordHeaderSize:], ev.payload)
+	n, err := b.cur.Write(record)
+	b.curSeg.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing event buffer segment: %w", err)
+	}
+	b.curSeg.newest = ev.timestamp
+	return nil
+}
+
+// rotate starts a new segment and removes the segments exceeding the limits
+func (b *diskBuffer) rotate(now int64) error {
+	if b.cur != nil {
+		if err := b.cur.Close(); err != nil {
+			return fmt.Errorf("closing event buffer segment: %w", err)
+		}
+		b.cur = nil
+	}
+
+	seg := &diskSegment{}
+	if len(b.segments) > 0 {
+		seg.seq = b.segments[len(b.segments)-1].seq + 1
+	}
+	f, err := os.OpenFile(b.segmentPath(seg), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("creating event buffer segment: %w", err)
+	}
+	b.cur = f
+	b.curSeg = seg
+	b.segments = append(b.segments, seg)
+
+	var oldest int64
+	if b.maxAge > 0 {
+		oldest = now - b.maxAge.Nanoseconds()
+	}
+	var total int64
+	for _, s := range b.segments {
+		total += s.size
+	}
+	// Keep room for the new segment
+	total += b.segmentSize
+	drop := 0
+	for _, s := range b.segments[:len(b.segments)-1] {
+		if total <= b.maxBytes && s.newest >= oldest {
+			break
+		}
+		if err := os.Remove(b.segmentPath(s)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing event buffer segment: %w", err)
+		}
+		total -= s.size
+		drop++
+	}
+	b.segments = slices.Delete(b.segments, 0, drop)
+	return nil
+}
+
+func (b *diskBuffer) close() error {
+	if b.cur == nil {
+		return nil
+	}
+	err := b.cur.Close()
+	b.cur = nil
+	return err
+}
@@ -0,0 +1,193 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instancemanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func payloads(events []*bufferedEvent) []byte {
+	var res []byte
+	for _, ev := range events {
+		res = append(res, ev.payload[0])
+	}
+	return res
+}
+
+func TestDiskBufferReopen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "abc")
+	now := time.Now().UnixNano()
+
+	b, err := openDiskBuffer(dir, 1024, time.Hour)
+	require.NoError(t, err)
+	ds, events, err := b.load()
+	require.NoError(t, err)
+	assert.Nil(t, ds)
+	assert.Empty(t, events)
+
+	require.NoError(t, b.setDataSources([]string{"exec"}))
+	for i := range 3 {
+		require.NoError(t, b.append(&bufferedEvent{datasourceID: 0, payload: []byte{byte(i)}, timestamp: now + int64(i)}))
+	}
+	require.NoError(t, b.close())
+
+	b, err = openDiskBuffer(dir, 1024, time.Hour)
+	require.NoError(t, err)
+	ds, events, err = b.load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec"}, ds)
+	assert.Equal(t, []byte{0, 1, 2}, payloads(events))
+	assert.Equal(t, now+2, events[2].timestamp)
+
+	// New events go to a new segment
+	require.NoError(t, b.setDataSources([]string{"exec"}))
+	require.NoError(t, b.append(&bufferedEvent{payload: []byte{3}, timestamp: now + 3}))
+	require.NoError(t, b.close())
+
+	b, err = openDiskBuffer(dir, 1024, time.Hour)
+	require.NoError(t, err)
+	_, events, err = b.load()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2, 3}, payloads(events))
+
+	// Changing the data sources drops the events
+	require.NoError(t, b.setDataSources([]string{"exec", "metrics"}))
+	require.NoError(t, b.close())
+	b, err = openDiskBuffer(dir, 1024, time.Hour)
+	require.NoError(t, err)
+	ds, events, err = b.load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec", "metrics"}, ds)
+	assert.Empty(t, events)
+}
+
+func TestDiskBufferLimits(t *testing.T) {
+	now := time.Now().UnixNano()
+
+	// Each event takes 17 bytes and segments are rotated after 40 bytes; the
+	// oldest segments are dropped to stay below the limit
+	dir := filepath.Join(t.TempDir(), "size")
+	b, err := openDiskBuffer(dir, 8*40, 0)
+	require.NoError(t, err)
+	require.NoError(t, b.setDataSources([]string{"exec"}))
+	for i := range 100 {
+		require.NoError(t, b.append(&bufferedEvent{payload: []byte{byte(i)}, timestamp: now}))
+	}
+	require.NoError(t, b.close())
+
+	var total int64
+	for _, seg := range b.segments {
+		total += seg.size
+	}
+	assert.LessOrEqual(t, total, int64(8*40))
+
+	b, err = openDiskBuffer(dir, 8*40, 0)
+	require.NoError(t, err)
+	_, events, err := b.load()
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, byte(99), events[len(events)-1].payload[0])
+	assert.Less(t, len(events), 100)
+
+	// Old events are skipped when loading and their segments removed
+	dir = filepath.Join(t.TempDir(), "age")
+	b, err = openDiskBuffer(dir, 8*40, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, b.setDataSources([]string{"exec"}))
+	old := now - (2 * time.Minute).Nanoseconds()
+	for i := range 3 {
+		require.NoError(t, b.append(&bufferedEvent{payload: []byte{byte(i)}, timestamp: old}))
+	}
+	require.NoError(t, b.append(&bufferedEvent{payload: []byte{3}, timestamp: now}))
+	require.NoError(t, b.close())
+	assert.Len(t, b.segments, 1)
+
+	b, err = openDiskBuffer(dir, 8*40, time.Minute)
+	require.NoError(t, err)
+	_, events, err = b.load()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{3}, payloads(events))
+}
+
+func TestDiskBufferTruncatedEvent(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "abc")
+	b, err := openDiskBuffer(dir, 1024, 0)
+	require.NoError(t, err)
+	require.NoError(t, b.setDataSources([]string{"exec"}))
+	require.NoError(t, b.append(&bufferedEvent{payload: []byte{1}, timestamp: 1}))
+	require.NoError(t, b.append(&bufferedEvent{payload: []byte{2, 2, 2}, timestamp: 2}))
+	require.NoError(t, b.close())
+
+	// Simulate being killed while writing the last event
+	path := b.segmentPath(b.segments[0])
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-2))
+
+	b, err = openDiskBuffer(dir, 1024, 0)
+	require.NoError(t, err)
+	_, events, err := b.load()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1}, payloads(events))
+}
+
+func TestRestoreDiskBuffer(t *testing.T) {
+	dir := t.TempDir()
+	b, err := openDiskBuffer(dir, 1024, 0)
+	require.NoError(t, err)
+	require.NoError(t, b.setDataSources([]string{"exec", "metrics"}))
+	for i := range 6 {
+		require.NoError(t, b.append(&bufferedEvent{payload: []byte{byte(i)}, timestamp: int64(i)}))
+	}
+	require.NoError(t, b.close())
+
+	// Only the newest events fitting in memory are kept
+	gi := newTestInstance("abc", 4)
+	gi.disk, err = openDiskBuffer(dir, 1024, 0)
+	require.NoError(t, err)
+	gi.restoreDiskBuffer([]string{"exec", "metrics"})
+	assert.Equal(t, []byte{2, 3, 4, 5}, payloads(gi.bufferedEvents()))
+
+	// Events are dropped if the data sources changed
+	gi = newTestInstance("abc", 4)
+	gi.disk, err = openDiskBuffer(dir, 1024, 0)
+	require.NoError(t, err)
+	gi.restoreDiskBuffer([]string{"exec"})
+	assert.Empty(t, gi.bufferedEvents())
+}
+
+func TestRemoveGadgetRemovesDiskBuffer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := New(nil, WithDiskBuffer(DiskBufferConfig{Dir: dir}))
+	require.NoError(t, err)
+
+	m.RunGadget(&api.GadgetInstance{
+		Id:           "abc",
+		GadgetConfig: &api.GadgetRunRequest{ImageName: "trace_exec"},
+		Paused:       true,
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "abc"), 0o700))
+
+	require.NoError(t, m.RemoveGadget("abc"))
+	assert.NoDirExists(t, filepath.Join(dir, "abc"))
+}
@@ -0,0 +1,278 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instancemanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+// HandoffVersion is increased whenever the format of the handoff file changes
+// in an incompatible way
+const HandoffVersion = 1
+
+const ociPullParam = "operator.oci.pull"
+
+// Handoff describes the gadget instances that were running when the daemon was
+// shut down, so that the next one can pick up where it left off, including the
+// events that were buffered for clients attaching later on.
+type Handoff struct {
+	Version   int                `json:"version"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Instances []*HandoffInstance `json:"instances"`
+}
+
+type HandoffInstance struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	ImageName  string `json:"imageName"`
+	EventCount uint64 `json:"eventCount"`
+
+	// DataSources holds the names of the data sources in the order used for
+	// the IDs of the buffered events
+	DataSources []string        `json:"dataSources"`
+	Events      []*HandoffEvent `json:"events,omitempty"`
+}
+
+type HandoffEvent struct {
+	DataSourceID uint32 `json:"dataSourceID"`
+	Payload      []byte `json:"payload"`
+	Timestamp    int64  `json:"timestamp,omitempty"`
+}
+
+// ReadHandoffFile reads a handoff file written by WriteHandoffFile. It returns
+// nil if the file doesn't exist.
+func ReadHandoffFile(path string) (*Handoff, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading handoff file %q: %w", path, err)
+	}
+	h := &Handoff{}
+	if err := json.Unmarshal(blob, h); err != nil {
+		return nil, fmt.Errorf("unmarshaling handoff file %q: %w", path, err)
+	}
+	if h.Version != HandoffVersion {
+		return nil, fmt.Errorf("unsupported handoff file version %d, expected %d", h.Version, HandoffVersion)
+	}
+	return h, nil
+}
+
+// WriteHandoffFile atomically writes the handoff to the given path
+func WriteHandoffFile(path string, h *Handoff) error {
+	blob, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("marshaling handoff: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("creating handoff file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(blob); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing handoff file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing handoff file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing handoff file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("renaming handoff file: %w", err)
+	}
+	return nil
+}
+
+// Handoff returns the state of the running gadget instances to be restored by
+// the next daemon using SetHandoff
+func (m *Manager) Handoff() *Handoff {
+	m.mu.Lock()
+	instances := make([]*GadgetInstance, 0, len(m.gadgetInstances))
+	for _, gi := range m.gadgetInstances {
+		instances = append(instances, gi)
+	}
+	m.mu.Unlock()
+
+	h := &Handoff{
+		Version:   HandoffVersion,
+		CreatedAt: time.Now(),
+	}
+	for _, gi := range instances {
+		if hi := gi.handoff(); hi != nil {
+			h.Instances = append(h.Instances, hi)
+		}
+	}
+	slices.SortFunc(h.Instances, func(a, b *HandoffInstance) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return h
+}
+
+// SetHandoff makes the instances started afterwards with the IDs found in the
+// handoff pick up their previous state. It has to be called before resuming
+// the stored gadget instances.
+func (m *Manager) SetHandoff(h *Handoff) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handoff = make(map[string]*HandoffInstance, len(h.Instances))
+	for _, hi := range h.Instances {
+		m.handoff[hi.ID] = hi
+	}
+}
+
+// takeHandoff returns and forgets the handoff of the given instance; it must
+// be called with m.mu held
+func (m *Manager) takeHandoff(id string) *HandoffInstance {
+	hi, ok := m.handoff[id]
+	if !ok {
+		return nil
+	}
+	delete(m.handoff, id)
+	return hi
+}
+
+// Shutdown stops all gadget instances and waits for them to be done or for the
+// context to expire
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	instances := make([]*GadgetInstance, 0, len(m.gadgetInstances))
+	for _, gi := range m.gadgetInstances {
+		gi.cancel()
+		instances = append(instances, gi)
+	}
+	m.mu.Unlock()
+
+	for _, gi := range instances {
+		select {
+		case <-gi.done:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for gadget instance %q to stop: %w", gi.id, ctx.Err())
+		}
+	}
+	return nil
+}
+
+// handoff returns the state of the instance, or nil if it isn't running
+func (p *GadgetInstance) handoff() *HandoffInstance {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state != stateRunning || p.gadgetInfo == nil {
+		return nil
+	}
+	return p.handoffLocked()
+}
+
+// restartHandoff returns the state of a stopped instance to be picked up when
+// running it again, also if it failed; the state it restored itself is passed
+// on if it didn't get to do so
+func (p *GadgetInstance) restartHandoff() *HandoffInstance {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.gadgetInfo == nil {
+		return p.restore
+	}
+	return p.handoffLocked()
+}
+
+// handoffLocked must be called with p.mu held
+func (p *GadgetInstance) handoffLocked() *HandoffInstance {
+	hi := &HandoffInstance{
+		ID:         p.id,
+		Name:       p.name,
+		ImageName:  p.request.ImageName,
+		EventCount: p.eventCount,
+	}
+	for _, ds := range p.gadgetInfo.DataSources {
+		hi.DataSources = append(hi.DataSources, ds.Name)
+	}
+	for _, ev := range p.bufferedEvents() {
+		hi.Events = append(hi.Events, &HandoffEvent{
+			DataSourceID: ev.datasourceID,
+			Payload:      ev.payload,
+			Timestamp:    ev.timestamp,
+		})
+	}
+	return hi
+}
+
+// restoreHandoff fills the event buffer with the events from the handoff; this
+// is only done if the data sources didn't change, as their IDs would have a
+// different meaning otherwise. It must be called with p.mu held.
+func (p *GadgetInstance) restoreHandoff(dataSources []*api.DataSource) {
+	hi := p.restore
+	p.restore = nil
+
+	dsNames := make([]string, 0, len(dataSources))
+	for _, ds := range dataSources {
+		dsNames = append(dsNames, ds.Name)
+	}
+	if !slices.Equal(dsNames, hi.DataSources) {
+		log.Warnf("[%s] data sources changed since the handoff, dropping %d buffered events", p.id, len(hi.Events))
+		return
+	}
+
+	events := hi.Events
+	if len(events) > len(p.eventBuffer) {
+		events = events[len(events)-len(p.eventBuffer):]
+	}
+	for _, ev := range events {
+		p.bufferEvent(&bufferedEvent{
+			datasourceID: ev.DataSourceID,
+			payload:      ev.Payload,
+			timestamp:    ev.Timestamp,
+		})
+	}
+	p.eventCount = hi.EventCount
+	log.Debugf("[%s] restored %d buffered events from handoff", p.id, len(events))
+}
+
+// ensureHandoffImage makes sure the image of a restored instance can be found,
+// as it could have been removed during the maintenance window: if it's gone,
+// instances configured to never pull are allowed to pull it again.
+func (p *GadgetInstance) ensureHandoffImage(ctx context.Context) {
+	if _, err := oci.GetGadgetImageDesc(ctx, p.request.ImageName); err == nil {
+		return
+	}
+	if p.request.ParamValues[ociPullParam] == oci.PullImageNever {
+		log.Infof("[%s] image %q not found locally, pulling it again", p.id, p.request.ImageName)
+		req := proto.Clone(p.request).(*api.GadgetRunRequest)
+		req.ParamValues[ociPullParam] = oci.PullImageMissing
+		p.mu.Lock()
+		p.request = req
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instancemanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func newTestInstance(id string, bufferLen int) *GadgetInstance {
+	return &GadgetInstance{
+		id:          id,
+		name:        id + "-name",
+		request:     &api.GadgetRunRequest{ImageName: "trace_exec"},
+		eventBuffer: make([]*bufferedEvent, bufferLen),
+		state:       stateRunning,
+		gadgetInfo: &api.GadgetInfo{
+			DataSources: []*api.DataSource{{Name: "exec"}, {Name: "metrics"}},
+		},
+	}
+}
+
+func TestHandoffFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "handoff.json")
+
+	h, err := ReadHandoffFile(path)
+	require.NoError(t, err)
+	require.Nil(t, h)
+
+	in := &Handoff{
+		Version: HandoffVersion,
+		Instances: []*HandoffInstance{{
+			ID:          "abc",
+			Name:        "mygadget",
+			ImageName:   "trace_exec",
+			EventCount:  42,
+			DataSources: []string{"exec"},
+			Events:      []*HandoffEvent{{DataSourceID: 0, Payload: []byte{1, 2, 3}}},
+		}},
+	}
+	require.NoError(t, WriteHandoffFile(path, in))
+
+	out, err := ReadHandoffFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, in.Instances, out.Instances)
+
+	in.Version = HandoffVersion + 1
+	require.NoError(t, WriteHandoffFile(path, in))
+	_, err = ReadHandoffFile(path)
+	require.Error(t, err)
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestHandoffRestore(t *testing.T) {
+	type testCase struct {
+		name           string
+		dataSources    []string
+		events         int
+		bufferLen      int
+		expectedEvents int
+	}
+
+	tests := []testCase{
+		{
+			name:           "fits_in_buffer",
+			dataSources:    []string{"exec", "metrics"},
+			events:         3,
+			bufferLen:      4,
+			expectedEvents: 3,
+		},
+		{
+			name:           "keeps_latest_events",
+			dataSources:    []string{"exec", "metrics"},
+			events:         6,
+			bufferLen:      4,
+			expectedEvents: 4,
+		},
+		{
+			name:           "data_sources_changed",
+			dataSources:    []string{"metrics", "exec"},
+			events:         3,
+			bufferLen:      4,
+			expectedEvents: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			prev := newTestInstance("abc", tc.events)
+			for i := 0; i < tc.events; i++ {
+				prev.eventBuffer[i] = &bufferedEvent{datasourceID: uint32(i % 2), payload: []byte{byte(i)}}
+			}
+			prev.eventBufferOffs = 0
+			prev.eventOverflow = true
+			prev.eventCount = 100
+
+			hi := prev.handoff()
+			require.NotNil(t, hi)
+			require.Len(t, hi.Events, tc.events)
+			hi.DataSources = tc.dataSources
+
+			next := newTestInstance("abc", tc.bufferLen)
+			next.restore = hi
+			next.restoreHandoff(next.gadgetInfo.DataSources)
+			require.Nil(t, next.restore)
+
+			events := next.bufferedEvents()
+			require.Len(t, events, tc.expectedEvents)
+			if tc.expectedEvents == 0 {
+				assert.Zero(t, next.eventCount)
+				return
+			}
+			assert.Equal(t, uint64(100), next.eventCount)
+			// The latest events are kept, in order
+			for i, ev := range events {
+				n := tc.events - tc.expectedEvents + i
+				assert.Equal(t, []byte{byte(n)}, ev.payload)
+				assert.Equal(t, uint32(n%2), ev.datasourceID)
+			}
+		})
+	}
+}
+
+func TestHandoffSkipsInstancesNotRunning(t *testing.T) {
+	gi := newTestInstance("abc", 4)
+	gi.state = stateError
+	assert.Nil(t, gi.handoff())
+}
@@ -15,9 +15,12 @@
 package instancemanager
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/protobuf/proto"
@@ -37,6 +40,7 @@ const (
 	stateInvalid gadgetState = iota
 	stateRunning
 	stateError
+	statePaused
 )
 
 func (s gadgetState) ToGadgetStatus() api.GadgetInstanceStatus {
@@ -45,6 +49,8 @@ func (s gadgetState) ToGadgetStatus() api.GadgetInstanceStatus {
 		return api.GadgetInstanceStatus_StatusRunning
 	case stateError:
 		return api.GadgetInstanceStatus_StatusError
+	case statePaused:
+		return api.GadgetInstanceStatus_StatusPaused
 	default:
 		return api.GadgetInstanceStatus_StatusInvalid
 	}
@@ -53,6 +59,10 @@ func (s gadgetState) ToGadgetStatus() api.GadgetInstanceStatus {
 type bufferedEvent struct {
 	datasourceID uint32
 	payload      []byte
+
+	// timestamp is the time the event was received, in nanoseconds since the
+	// epoch
+	timestamp int64
 }
 
 type GadgetInstance struct {
@@ -66,34 +76,54 @@ type GadgetInstance struct {
 	eventBuffer          []*bufferedEvent
 	eventBufferOffs      int
 	eventOverflow        bool
+	eventCount           uint64
 	clients              map[*GadgetInstanceClient]struct{}
 	cancel               func()
 	state                gadgetState
 	error                error
+	warnings             []string
+	resources            *api.GadgetInstanceResources
 	ready                chan struct{}
+	started              chan struct{}
+	done                 chan struct{}
+
+	// gadgetCtx is the context of the running gadget, used to update its
+	// params
+	gadgetCtx *gadgetcontext.GadgetContext
+
+	// restore is the state handed off by a previous daemon, if any
+	restore *HandoffInstance
+
+	// disk stores the buffered events on disk, if enabled
+	disk *diskBuffer
 }
 
 func (p *GadgetInstance) GadgetInfo() (*api.GadgetInfo, error) {
 	<-p.ready
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.state == statePaused {
+		return nil, ErrPaused
+	}
 	return p.gadgetInfo, p.error
 }
 
-func (p *GadgetInstance) AddClient(client api.GadgetManager_RunGadgetServer) chan struct{} {
+// Paused returns whether the instance is paused
+func (p *GadgetInstance) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state == statePaused
+}
+
+// AddClient starts streaming the events of the instance to client. Buffered
+// events received at or after since, in nanoseconds since the epoch, are
+// replayed first; 0 replays all of them.
+func (p *GadgetInstance) AddClient(client api.GadgetManager_RunGadgetServer, since int64) chan struct{} {
 	log.Debugf("[%s] client connected", p.gadgetInfo.Id)
 	p.mu.Lock()
 	cl := NewGadgetInstanceClient(client)
 	p.clients[cl] = struct{}{}
-	var replayBuf []*bufferedEvent
-	if p.eventOverflow {
-		replayBuf = make([]*bufferedEvent, 0, len(p.eventBuffer))
-		replayBuf = append(replayBuf, p.eventBuffer[p.eventBufferOffs:]...)
-		replayBuf = append(replayBuf, p.eventBuffer[:p.eventBufferOffs]...)
-	} else {
-		replayBuf = make([]*bufferedEvent, 0, p.eventBufferOffs)
-		replayBuf = append(replayBuf, p.eventBuffer[:p.eventBufferOffs]...)
-	}
+	replayBuf := eventsSince(p.bufferedEvents(), since)
 	log.Debugf("replaying %d entries (%d)", len(replayBuf), p.eventBufferOffs)
 	cl.replayBuf = replayBuf
 
@@ -127,6 +157,170 @@ func (p *GadgetInstance) AddClient(client api.GadgetManager_RunGadgetServer) cha
 	return done
 }
 
+// bufferedEvents returns the buffered events, oldest first; it must be called
+// with p.mu held
+func (p *GadgetInstance) bufferedEvents() []*bufferedEvent {
+	var buf []*bufferedEvent
+	if p.eventOverflow {
+		buf = make([]*bufferedEvent, 0, len(p.eventBuffer))
+		buf = append(buf, p.eventBuffer[p.eventBufferOffs:]...)
+		buf = append(buf, p.eventBuffer[:p.eventBufferOffs]...)
+	} else {
+		buf = make([]*bufferedEvent, 0, p.eventBufferOffs)
+		buf = append(buf, p.eventBuffer[:p.eventBufferOffs]...)
+	}
+	return buf
+}
+
+// bufferEvent adds an event to the ring buffer; it must be called with p.mu
+// held
+func (p *GadgetInstance) bufferEvent(ev *bufferedEvent) {
+	p.eventBuffer[p.eventBufferOffs] = ev
+	p.eventBufferOffs = (p.eventBufferOffs + 1) % len(p.eventBuffer)
+	if p.eventBufferOffs == 0 {
+		p.eventOverflow = true
+	}
+}
+
+// storeEvent writes an event to the disk buffer; storing events on disk is
+// stopped on errors. It must be called with p.mu held.
+func (p *GadgetInstance) storeEvent(ev *bufferedEvent) {
+	if p.disk == nil {
+		return
+	}
+	if err := p.disk.append(ev); err != nil {
+		log.Warnf("[%s] storing events on disk, disabling it: %v", p.id, err)
+		p.closeDiskBuffer()
+	}
+}
+
+// restoreDiskBuffer fills the event buffer with the events stored on disk by
+// a previous run, if the data sources didn't change. It must be called with
+// p.mu held.
+func (p *GadgetInstance) restoreDiskBuffer(dataSources []string) {
+	stored, events, err := p.disk.load()
+	if err != nil {
+		log.Warnf("[%s] loading events stored on disk: %v", p.id, err)
+		return
+	}
+	if len(events) > 0 && !slices.Equal(stored, dataSources) {
+		log.Warnf("[%s] data sources changed since the events were stored, dropping %d events", p.id, len(events))
+		return
+	}
+	if len(events) > len(p.eventBuffer) {
+		events = events[len(events)-len(p.eventBuffer):]
+	}
+	for _, ev := range events {
+		p.bufferEvent(ev)
+	}
+	log.Debugf("[%s] restored %d buffered events from disk", p.id, len(events))
+}
+
+// closeDiskBuffer stops storing events on disk; it must be called with p.mu
+// held
+func (p *GadgetInstance) closeDiskBuffer() {
+	if p.disk == nil {
+		return
+	}
+	if err := p.disk.close(); err != nil {
+		log.Warnf("[%s] closing event buffer: %v", p.id, err)
+	}
+	p.disk = nil
+}
+
+// eventsSince returns the events received at or after since; events need to
+// be sorted oldest first
+func eventsSince(events []*bufferedEvent, since int64) []*bufferedEvent {
+	if since <= 0 {
+		return events
+	}
+	i, _ := slices.BinarySearchFunc(events, since, func(ev *bufferedEvent, since int64) int {
+		return cmp.Compare(ev.timestamp, since)
+	})
+	return events[i:]
+}
+
+// maxWarnings limits the number of warnings stored for an instance
+const maxWarnings = 32
+
+// ReportWarning implements operators.StateReporter; the same warning is only
+// stored once
+func (p *GadgetInstance) ReportWarning(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if slices.Contains(p.warnings, msg) || len(p.warnings) >= maxWarnings {
+		return
+	}
+	p.warnings = append(p.warnings, msg)
+}
+
+// ReportResources implements operators.ResourceReporter
+func (p *GadgetInstance) ReportResources(usage operators.ResourceUsage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resources = &api.GadgetInstanceResources{
+		Programs:     uint32(usage.Programs),
+		Maps:         uint32(usage.Maps),
+		MapMemory:    usage.MapMemory,
+		BufferMemory: usage.BufferMemory,
+	}
+}
+
+// updateParams applies the param values of instance to the running gadget
+// without restarting it. It returns gadgetcontext.ErrRestartRequired if
+// anything else changed or the operators can't apply the new values.
+func (p *GadgetInstance) updateParams(instance *api.GadgetInstance) error {
+	p.mu.Lock()
+	gadgetCtx, state, request := p.gadgetCtx, p.state, p.request
+	p.mu.Unlock()
+
+	select {
+	case <-p.started:
+	default:
+		return gadgetcontext.ErrRestartRequired
+	}
+	if instance.Paused || state != stateRunning || gadgetCtx == nil || instance.Name != p.name {
+		return gadgetcontext.ErrRestartRequired
+	}
+	oldRequest := proto.Clone(request).(*api.GadgetRunRequest)
+	oldRequest.ParamValues = nil
+	newRequest := proto.Clone(instance.GadgetConfig).(*api.GadgetRunRequest)
+	newRequest.ParamValues = nil
+	if !proto.Equal(oldRequest, newRequest) {
+		return gadgetcontext.ErrRestartRequired
+	}
+
+	if err := gadgetCtx.UpdateParams(instance.GadgetConfig.ParamValues); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.request = instance.GadgetConfig
+	p.mu.Unlock()
+	return nil
+}
+
+// waitStarted waits for all operators of the gadget to be started and returns
+// the error the gadget failed with otherwise
+func (p *GadgetInstance) waitStarted() error {
+	select {
+	case <-p.started:
+		return nil
+	case <-p.done:
+	}
+	// It could have been stopped right after starting
+	select {
+	case <-p.started:
+		return nil
+	default:
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.error != nil {
+		return p.error
+	}
+	return fmt.Errorf("gadget instance %q stopped", p.id)
+}
+
 func (p *GadgetInstance) RemoveClients() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -162,6 +356,27 @@ func (p *GadgetInstance) Run(
 				dsLookup[ds.Name] = ds.Id
 			}
 
+			dsNames := make([]string, 0, len(gi.DataSources))
+			for _, ds := range gi.DataSources {
+				dsNames = append(dsNames, ds.Name)
+			}
+
+			p.mu.Lock()
+			// A handoff is preferred over the events stored on disk, as it
+			// also holds the number of events
+			if p.restore != nil {
+				p.restoreHandoff(gi.DataSources)
+			} else if p.disk != nil {
+				p.restoreDiskBuffer(dsNames)
+			}
+			if p.disk != nil {
+				if err := p.disk.setDataSources(dsNames); err != nil {
+					log.Warnf("[%s] storing events on disk, disabling it: %v", p.id, err)
+					p.closeDiskBuffer()
+				}
+			}
+			p.mu.Unlock()
+
 			// todo: skip DataSources we're not interested in
 
 			for _, ds := range gadgetCtx.GetDataSources() {
@@ -172,14 +387,13 @@ func (p *GadgetInstance) Run(
 					event := &bufferedEvent{
 						payload:      d,
 						datasourceID: dsID,
+						timestamp:    time.Now().UnixNano(),
 					}
 
 					p.mu.Lock()
-					p.eventBuffer[p.eventBufferOffs] = event
-					p.eventBufferOffs = (p.eventBufferOffs + 1) % len(p.eventBuffer)
-					if p.eventBufferOffs == 0 {
-						p.eventOverflow = true
-					}
+					p.bufferEvent(event)
+					p.storeEvent(event)
+					p.eventCount++
 					for client := range p.clients {
 						// This doesn't block
 						client.SendPayload(dsID, d)
@@ -202,6 +416,11 @@ func (p *GadgetInstance) Run(
 			close(p.ready)
 			return nil
 		}),
+		// This operator is started last, so all the others are running
+		simple.OnStart(func(gadgetCtx operators.GadgetContext) error {
+			close(p.started)
+			return nil
+		}),
 	)
 
 	ops := make([]operators.DataOperator, 0)
@@ -219,12 +438,17 @@ func (p *GadgetInstance) Run(
 		gadgetcontext.WithName(p.name),
 		gadgetcontext.WithID(p.id),
 	)
+	gadgetCtx.SetVar(operators.StateReporterVarName, p)
+	if p.mgr.resourceLimits.Enabled() {
+		gadgetCtx.SetVar(operators.ResourceLimitsVarName, p.mgr.resourceLimits)
+	}
 
 	runtimeParams := runtime.ParamDescs().ToParams()
 	runtimeParams.CopyFromMap(p.request.ParamValues, "runtime.")
 
 	p.mu.Lock()
 	p.state = stateRunning
+	p.gadgetCtx = gadgetCtx
 	p.mu.Unlock()
 
 	return runtime.RunGadget(gadgetCtx, runtimeParams, p.request.ParamValues)
@@ -0,0 +1,316 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package instancemanager
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
+func TestEventsSince(t *testing.T) {
+	events := []*bufferedEvent{
+		{payload: []byte{0}, timestamp: 10},
+		{payload: []byte{1}, timestamp: 20},
+		{payload: []byte{2}, timestamp: 20},
+		{payload: []byte{3}, timestamp: 30},
+	}
+
+	type testCase struct {
+		name     string
+		since    int64
+		expected []byte
+	}
+	tests := []testCase{
+		{name: "zero_replays_all", since: 0, expected: []byte{0, 1, 2, 3}},
+		{name: "before_first", since: 5, expected: []byte{0, 1, 2, 3}},
+		{name: "exact_match_included", since: 20, expected: []byte{1, 2, 3}},
+		{name: "between", since: 25, expected: []byte{3}},
+		{name: "after_last", since: 31, expected: []byte{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := []byte{}
+			for _, ev := range eventsSince(events, tc.since) {
+				got = append(got, ev.payload[0])
+			}
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+// fakeStream records the events sent to an attached client
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+
+	mu     sync.Mutex
+	events []*api.GadgetEvent
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeStream) Send(ev *api.GadgetEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, ev)
+	return nil
+}
+
+func (s *fakeStream) Recv() (*api.GadgetControlRequest, error) {
+	<-s.ctx.Done()
+	return nil, s.ctx.Err()
+}
+
+func (s *fakeStream) payloads() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []byte
+	for _, ev := range s.events {
+		if ev.Type == api.EventTypeGadgetPayload {
+			res = append(res, ev.Payload[0])
+		}
+	}
+	return res
+}
+
+func (s *fakeStream) seqs() []uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []uint32
+	for _, ev := range s.events {
+		if ev.Type == api.EventTypeGadgetPayload {
+			res = append(res, ev.Seq)
+		}
+	}
+	return res
+}
+
+func TestAddClientSince(t *testing.T) {
+	p := newTestInstance("abc", 4)
+	p.gadgetInfo.Id = p.id
+	p.gadgetInfoSerialized = &api.GadgetEvent{Type: api.EventTypeGadgetInfo}
+	p.clients = map[*GadgetInstanceClient]struct{}{}
+	for i := range 3 {
+		p.eventBuffer[i] = &bufferedEvent{payload: []byte{byte(i)}, timestamp: int64(i+1) * 100}
+	}
+	p.eventBufferOffs = 3
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	all := &fakeStream{ctx: ctx}
+	recent := &fakeStream{ctx: ctx}
+	doneAll := p.AddClient(all, 0)
+	doneRecent := p.AddClient(recent, 200)
+
+	// Live events are sent to all clients, after the replayed ones
+	p.mu.Lock()
+	require.Len(t, p.clients, 2)
+	for client := range p.clients {
+		client.SendPayload(0, []byte{3})
+	}
+	p.mu.Unlock()
+
+	require.Eventually(t, func() bool {
+		return len(all.payloads()) == 4 && len(recent.payloads()) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, []byte{0, 1, 2, 3}, all.payloads())
+	assert.Equal(t, []uint32{1, 2, 3, 4}, all.seqs())
+	assert.Equal(t, []byte{1, 2, 3}, recent.payloads())
+	assert.Equal(t, []uint32{1, 2, 3}, recent.seqs())
+
+	cancel()
+	<-doneAll
+	<-doneRecent
+	assert.Empty(t, p.clients)
+}
+
+func TestPausedInstance(t *testing.T) {
+	m, err := New(nil)
+	require.NoError(t, err)
+
+	m.RunGadget(&api.GadgetInstance{
+		Id:           "abc",
+		Name:         "paused",
+		GadgetConfig: &api.GadgetRunRequest{ImageName: "trace_exec"},
+		Paused:       true,
+	})
+
+	state, err := m.InstanceState("abc")
+	require.NoError(t, err)
+	assert.Equal(t, api.GadgetInstanceStatus_StatusPaused, state.Status)
+
+	gi := m.LookupInstance("paused")
+	require.NotNil(t, gi)
+	assert.True(t, gi.Paused())
+	_, err = gi.GadgetInfo()
+	require.ErrorIs(t, err, ErrPaused)
+
+	err = m.AttachToGadgetInstance("abc", 0, &fakeStream{ctx: context.Background()})
+	require.ErrorIs(t, err, ErrPaused)
+
+	require.NoError(t, m.RemoveGadget("abc"))
+	assert.Nil(t, m.LookupInstance("abc"))
+}
+
+func TestUpdateGadgetKeepsBufferedEvents(t *testing.T) {
+	m, err := New(nil)
+	require.NoError(t, err)
+
+	old := newTestInstance("abc", 4)
+	old.eventCount = 2
+	old.eventBuffer[0] = &bufferedEvent{payload: []byte{0}, timestamp: 10}
+	old.eventBuffer[1] = &bufferedEvent{payload: []byte{1}, timestamp: 20}
+	old.eventBufferOffs = 2
+	cancelled := false
+	old.cancel = func() { cancelled = true }
+	old.done = make(chan struct{})
+	close(old.done)
+	m.gadgetInstances[old.id] = old
+
+	// Update into a paused instance so no gadget is run; the events of the
+	// previous run are kept for when it runs again
+	m.UpdateGadget(&api.GadgetInstance{
+		Id:   "abc",
+		Name: "updated",
+		GadgetConfig: &api.GadgetRunRequest{
+			ImageName:   "trace_exec",
+			ParamValues: map[string]string{"operator.filter.filter": "pid==1"},
+		},
+		Paused: true,
+	})
+	assert.True(t, cancelled)
+
+	gi := m.LookupInstance("abc")
+	require.NotNil(t, gi)
+	assert.NotSame(t, old, gi)
+	assert.Equal(t, "updated", gi.name)
+
+	hi := m.handoff["abc"]
+	require.NotNil(t, hi)
+	assert.Equal(t, uint64(2), hi.EventCount)
+	require.Len(t, hi.Events, 2)
+	assert.Equal(t, []byte{1}, hi.Events[1].Payload)
+}
+
+func TestClientDrops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := &fakeStream{ctx: ctx}
+	cl := NewGadgetInstanceClient(stream)
+
+	// Fill the buffer before the client sends anything
+	for range clientBufferLength + 5 {
+		cl.SendPayload(2, []byte{0})
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- cl.Run()
+	}()
+
+	var drops *api.EventDrops
+	require.Eventually(t, func() bool {
+		stream.mu.Lock()
+		defer stream.mu.Unlock()
+		for _, ev := range stream.events {
+			if ev.Type == api.EventTypeGadgetDrops {
+				drops = &api.EventDrops{}
+				return proto.Unmarshal(ev.Payload, drops) == nil
+			}
+		}
+		return false
+	}, 3*api.DropsReportInterval, 10*time.Millisecond)
+
+	assert.Equal(t, map[uint32]uint64{2: 5}, drops.Dropped)
+	assert.Equal(t, uint32(clientBufferLength), drops.BufferLength)
+
+	// Dropped events don't leave gaps in the sequence numbers
+	seqs := stream.seqs()
+	require.Len(t, seqs, clientBufferLength)
+	for i, seq := range seqs {
+		assert.Equal(t, uint32(i+1), seq)
+	}
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestInstanceStateResources(t *testing.T) {
+	m, err := New(nil)
+	require.NoError(t, err)
+
+	p := newTestInstance("abc", 4)
+	m.gadgetInstances[p.id] = p
+
+	st, err := m.InstanceState(p.id)
+	require.NoError(t, err)
+	assert.Nil(t, st.Resources)
+
+	p.ReportResources(operators.ResourceUsage{Programs: 2, Maps: 3, MapMemory: 4096, BufferMemory: 256 * 1024})
+	st, err = m.InstanceState(p.id)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&api.GadgetInstanceResources{
+		Programs:     2,
+		Maps:         3,
+		MapMemory:    4096,
+		BufferMemory: 256 * 1024,
+	}, st.Resources))
+}
+
+func TestUpdateGadgetRollsBack(t *testing.T) {
+	m, err := New(nil)
+	require.NoError(t, err)
+
+	old := newTestInstance("abc", 4)
+	old.eventCount = 1
+	old.eventBuffer[0] = &bufferedEvent{payload: []byte{0}, timestamp: 10}
+	old.eventBufferOffs = 1
+	old.cancel = func() {}
+	old.done = make(chan struct{})
+	close(old.done)
+	m.gadgetInstances[old.id] = old
+
+	// The new configuration fails to start as its version is missing
+	err = m.UpdateGadget(&api.GadgetInstance{
+		Id:   "abc",
+		Name: "updated",
+		GadgetConfig: &api.GadgetRunRequest{
+			ImageName:   "trace_open",
+			ParamValues: map[string]string{"operator.filter.filter": "pid==1"},
+		},
+	})
+	require.ErrorContains(t, err, "expected version")
+
+	gi := m.LookupInstance("abc")
+	require.NotNil(t, gi)
+	assert.Equal(t, "abc-name", gi.name)
+	assert.Equal(t, "trace_exec", gi.request.ImageName)
+	assert.Empty(t, gi.request.ParamValues)
+}
@@ -20,14 +20,17 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 )
 
-func (m *Manager) AttachToGadgetInstance(gadgetInstanceID string, stream api.GadgetManager_RunGadgetServer) error {
+func (m *Manager) AttachToGadgetInstance(gadgetInstanceID string, since int64, stream api.GadgetManager_RunGadgetServer) error {
 	m.mu.Lock()
 	gi, ok := m.gadgetInstances[gadgetInstanceID]
 	m.mu.Unlock()
 	if !ok {
 		return fmt.Errorf("gadget %s not found", gadgetInstanceID)
 	}
+	if gi.Paused() {
+		return fmt.Errorf("gadget %s: %w", gadgetInstanceID, ErrPaused)
+	}
 
-	<-gi.AddClient(stream)
+	<-gi.AddClient(stream, since)
 	return nil
 }
@@ -16,14 +16,22 @@ package instancemanager
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
 
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/overhead"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
 )
@@ -36,6 +44,7 @@ func (err mgrError) Error() string {
 
 const (
 	ErrNotFound = mgrError("gadget not found")
+	ErrPaused   = mgrError("gadget instance is paused")
 )
 
 type Service interface {
@@ -60,6 +69,16 @@ type Manager struct {
 
 	runtime runtime.Runtime
 
+	// handoff holds the state of instances from a previous daemon that still
+	// need to be restored
+	handoff map[string]*HandoffInstance
+
+	// diskBuffer configures storing the buffered events on disk
+	diskBuffer DiskBufferConfig
+
+	// resourceLimits limits the eBPF resources of each gadget instance
+	resourceLimits operators.ResourceLimits
+
 	Service
 }
 
@@ -77,20 +96,39 @@ func New(runtime runtime.Runtime, options ...Option) (*Manager, error) {
 	return mgr, nil
 }
 
-// RemoveGadget cancels and removes a gadget
+// RemoveGadget cancels and removes a gadget, including the events it stored
+// on disk
 func (m *Manager) RemoveGadget(id string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	gadgetInstance, ok := m.gadgetInstances[id]
 	if !ok {
+		m.mu.Unlock()
 		return ErrNotFound
 	}
 	gadgetInstance.cancel()
 	delete(m.gadgetInstances, id)
+	m.mu.Unlock()
+
+	if m.diskBuffer.Dir != "" {
+		// Wait for the instance to stop writing its events
+		<-gadgetInstance.done
+		if err := os.RemoveAll(m.diskBufferDir(id)); err != nil {
+			log.Warnf("[%s] removing events stored on disk: %v", id, err)
+		}
+	}
 	return nil
 }
 
+func (m *Manager) diskBufferDir(id string) string {
+	return filepath.Join(m.diskBuffer.Dir, id)
+}
+
 func (m *Manager) RunGadget(instance *api.GadgetInstance) {
+	if instance.Paused {
+		m.addPausedGadget(instance)
+		return
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	gi := &GadgetInstance{
 		id:              instance.Id,
@@ -102,15 +140,26 @@ func (m *Manager) RunGadget(instance *api.GadgetInstance) {
 		cancel:          cancel,
 		clients:         map[*GadgetInstanceClient]struct{}{},
 		ready:           make(chan struct{}),
+		started:         make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+	if m.diskBuffer.Dir != "" {
+		disk, err := openDiskBuffer(m.diskBufferDir(gi.id), m.diskBuffer.MaxBytes, m.diskBuffer.MaxAge)
+		if err != nil {
+			log.Warnf("[%s] storing events on disk: %v", gi.id, err)
+		} else {
+			gi.disk = disk
+		}
 	}
 	m.mu.Lock()
 	m.gadgetInstances[gi.id] = gi
+	gi.restore = m.takeHandoff(gi.id)
 	// Adopt all clients in the waiting room
 	if m.asyncGadgetRunCreation {
 		m.waitingRoom.Range(func(key, value any) bool {
 			if value.(string) == gi.id {
 				log.Debugf("adopting client for gadget instance %q", gi.id)
-				gi.AddClient(key.(api.GadgetManager_RunGadgetServer))
+				gi.AddClient(key.(api.GadgetManager_RunGadgetServer), 0)
 				m.waitingRoom.Delete(key)
 			}
 			return true
@@ -118,7 +167,16 @@ func (m *Manager) RunGadget(instance *api.GadgetInstance) {
 	}
 	m.mu.Unlock()
 	go func() {
+		defer close(gi.done)
 		defer cancel()
+		defer func() {
+			gi.mu.Lock()
+			gi.closeDiskBuffer()
+			gi.mu.Unlock()
+		}()
+		if gi.restore != nil {
+			gi.ensureHandoffImage(ctx)
+		}
 		l := log.StandardLogger()
 		l.SetFormatter(&log.JSONFormatter{})
 		lwr := &logWrapper{Entry: l.WithFields(log.Fields{
@@ -139,6 +197,103 @@ func (m *Manager) RunGadget(instance *api.GadgetInstance) {
 	}()
 }
 
+// UpdateGadget replaces the configuration of a gadget instance, keeping its
+// ID. If only params of operators able to apply them while running changed,
+// the instance is updated in place. Otherwise it's restarted, carrying over its
+// buffered events as long as its data sources don't change; if it fails to
+// start with the new configuration, the previous one is run again and the
+// error is returned.
+func (m *Manager) UpdateGadget(instance *api.GadgetInstance) error {
+	m.mu.Lock()
+	gi, ok := m.gadgetInstances[instance.Id]
+	m.mu.Unlock()
+	if !ok {
+		m.RunGadget(instance)
+		return nil
+	}
+
+	err := gi.updateParams(instance)
+	if err == nil {
+		log.Infof("[%s] updated params of gadget instance in place", instance.Id)
+		return nil
+	}
+	if !errors.Is(err, gadgetcontext.ErrRestartRequired) {
+		return err
+	}
+	log.Infof("[%s] restarting gadget instance: %v", instance.Id, err)
+
+	gi.mu.Lock()
+	previous := &api.GadgetInstance{
+		Id:           gi.id,
+		Name:         gi.name,
+		GadgetConfig: gi.request,
+		Paused:       gi.state == statePaused,
+	}
+	gi.mu.Unlock()
+
+	gi = m.restartGadget(gi, instance)
+	err = gi.waitStarted()
+	if err == nil {
+		return nil
+	}
+	log.Warnf("[%s] starting gadget instance with the new configuration failed, restoring the previous one: %v", instance.Id, err)
+	m.restartGadget(gi, previous)
+	return fmt.Errorf("starting gadget instance with the new configuration: %w", err)
+}
+
+// restartGadget stops gi and runs instance in its place, handing its buffered
+// events over to the new run
+func (m *Manager) restartGadget(gi *GadgetInstance, instance *api.GadgetInstance) *GadgetInstance {
+	m.mu.Lock()
+	if m.gadgetInstances[gi.id] == gi {
+		delete(m.gadgetInstances, gi.id)
+	}
+	m.mu.Unlock()
+
+	gi.cancel()
+	// Wait for the previous run to be done, so it doesn't overlap with the new
+	// one
+	<-gi.done
+	if hi := gi.restartHandoff(); hi != nil {
+		m.mu.Lock()
+		if m.handoff == nil {
+			m.handoff = make(map[string]*HandoffInstance)
+		}
+		m.handoff[instance.Id] = hi
+		m.mu.Unlock()
+	}
+	m.RunGadget(instance)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gadgetInstances[instance.Id]
+}
+
+// addPausedGadget registers a paused gadget instance without running it, so its
+// state can be queried until it's resumed by running it again
+func (m *Manager) addPausedGadget(instance *api.GadgetInstance) {
+	ready := make(chan struct{})
+	close(ready)
+	done := make(chan struct{})
+	close(done)
+	gi := &GadgetInstance{
+		id:      instance.Id,
+		name:    instance.Name,
+		mgr:     m,
+		request: instance.GadgetConfig,
+		cancel:  func() {},
+		clients: map[*GadgetInstanceClient]struct{}{},
+		state:   statePaused,
+		ready:   ready,
+		started: ready,
+		done:    done,
+	}
+	m.mu.Lock()
+	m.gadgetInstances[gi.id] = gi
+	m.mu.Unlock()
+	log.Infof("gadget instance %q is paused", gi.id)
+}
+
 func (m *Manager) LookupInstance(gadgetInstanceID string) *GadgetInstance {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -170,12 +325,39 @@ func (m *Manager) InstanceState(gadgetInstanceID string) (*api.GadgetInstanceSta
 	if gi == nil {
 		return nil, ErrNotFound
 	}
+	gi.mu.Lock()
+	defer gi.mu.Unlock()
 	var msg string
 	if gi.error != nil {
 		msg = gi.error.Error()
 	}
 	return &api.GadgetInstanceState{
-		Status:  gi.state.ToGadgetStatus(),
-		Message: msg,
+		Status:    gi.state.ToGadgetStatus(),
+		Message:   msg,
+		Warnings:  slices.Clone(gi.warnings),
+		Resources: proto.CloneOf(gi.resources),
 	}, nil
 }
+
+// InstanceEvents returns the number of events emitted by each gadget instance
+func (m *Manager) InstanceEvents() []overhead.InstanceEvents {
+	m.mu.Lock()
+	instances := make([]*GadgetInstance, 0, len(m.gadgetInstances))
+	for _, gi := range m.gadgetInstances {
+		instances = append(instances, gi)
+	}
+	m.mu.Unlock()
+
+	res := make([]overhead.InstanceEvents, 0, len(instances))
+	for _, gi := range instances {
+		gi.mu.Lock()
+		res = append(res, overhead.InstanceEvents{
+			ID:        gi.id,
+			Name:      gi.name,
+			ImageName: gi.request.ImageName,
+			Events:    gi.eventCount,
+		})
+		gi.mu.Unlock()
+	}
+	return res
+}
@@ -14,6 +14,10 @@
 
 package instancemanager
 
+import (
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
 type Option func(*Manager) error
 
 func WithAsync(val bool) Option {
@@ -22,3 +26,20 @@ func WithAsync(val bool) Option {
 		return nil
 	}
 }
+
+// WithDiskBuffer stores the buffered events of the gadget instances on disk,
+// so they can be replayed after the daemon restarts
+func WithDiskBuffer(cfg DiskBufferConfig) Option {
+	return func(m *Manager) error {
+		m.diskBuffer = cfg
+		return nil
+	}
+}
+
+// WithResourceLimits limits the eBPF resources each gadget instance can use
+func WithResourceLimits(limits operators.ResourceLimits) Option {
+	return func(m *Manager) error {
+		m.resourceLimits = limits
+		return nil
+	}
+}
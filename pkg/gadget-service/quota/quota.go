@@ -0,0 +1,294 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quota limits the gadgets run by the clients of the gadget service
+// and the rate of their requests. Requests exceeding a limit fail with
+// RESOURCE_EXHAUSTED, detailed by a QuotaFailure and, for rate limits, a
+// RetryInfo.
+package quota
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// Subjects of the violations reported in QuotaFailure details; client, namespace
+// and tag subjects are followed by ":" and their name
+const (
+	SubjectGlobal    = "global"
+	SubjectClient    = "client"
+	SubjectNamespace = "namespace"
+	SubjectTag       = "tag"
+)
+
+// namespaceParams are the params holding the namespace a gadget instance is
+// restricted to
+var namespaceParams = []string{
+	"operator.KubeManager.namespace",
+	"operator.KubeManager.k8s-namespace",
+	"operator.LocalManager.k8s-namespace",
+}
+
+// maxIdleClients is the number of per-client rate limiters kept before the
+// ones that are back at their full burst get dropped
+const maxIdleClients = 1024
+
+// Limits holds the quotas to enforce; zero values disable a limit
+type Limits struct {
+	// MaxRunningGadgets limits the gadgets running at once, counting both the
+	// gadgets run directly and the gadget instances that are not paused
+	MaxRunningGadgets int
+
+	// MaxRunningGadgetsPerClient limits the gadgets each client runs directly
+	// at once
+	MaxRunningGadgetsPerClient int
+
+	// MaxInstancesPerNamespace limits the gadget instances for the same
+	// namespace; instances for all namespaces are counted together
+	MaxInstancesPerNamespace int
+
+	// MaxInstancesPerTag limits the gadget instances having the same tag
+	MaxInstancesPerTag int
+
+	// RequestsPerSecond and RequestBurst limit the rate of requests of all
+	// clients together
+	RequestsPerSecond float64
+	RequestBurst      int
+
+	// ClientRequestsPerSecond and ClientRequestBurst limit the rate of requests
+	// of each client
+	ClientRequestsPerSecond float64
+	ClientRequestBurst      int
+}
+
+// Enabled returns whether any of the limits is set
+func (l Limits) Enabled() bool {
+	return l != Limits{}
+}
+
+// Limiter enforces Limits; clients are identified by their user name
+type Limiter struct {
+	limits Limits
+
+	mu               sync.Mutex
+	running          int
+	runningPerClient map[string]int
+	requests         *rate.Limiter
+	clientRequests   map[string]*rate.Limiter
+}
+
+func New(limits Limits) *Limiter {
+	l := &Limiter{
+		limits:           limits,
+		runningPerClient: make(map[string]int),
+		clientRequests:   make(map[string]*rate.Limiter),
+	}
+	if limits.RequestsPerSecond > 0 {
+		l.requests = rate.NewLimiter(rate.Limit(limits.RequestsPerSecond), max(limits.RequestBurst, 1))
+	}
+	return l
+}
+
+// Allow takes a request of client from the rate limits
+func (l *Limiter) Allow(client string) error {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.requests != nil {
+		if delay := reserve(l.requests, now); delay > 0 {
+			return exhausted(SubjectGlobal, delay,
+				fmt.Sprintf("rate limit of %g requests per second exceeded", l.limits.RequestsPerSecond))
+		}
+	}
+	if l.limits.ClientRequestsPerSecond <= 0 {
+		return nil
+	}
+	limiter, ok := l.clientRequests[client]
+	if !ok {
+		l.pruneClients(now)
+		limiter = rate.NewLimiter(rate.Limit(l.limits.ClientRequestsPerSecond), max(l.limits.ClientRequestBurst, 1))
+		l.clientRequests[client] = limiter
+	}
+	if delay := reserve(limiter, now); delay > 0 {
+		return exhausted(SubjectClient+":"+client, delay,
+			fmt.Sprintf("rate limit of %g requests per second exceeded for %s", l.limits.ClientRequestsPerSecond, client))
+	}
+	return nil
+}
+
+// reserve takes a token from limiter if one is available, otherwise it returns
+// how long to wait for the next one
+func reserve(limiter *rate.Limiter, now time.Time) time.Duration {
+	r := limiter.ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+func (l *Limiter) pruneClients(now time.Time) {
+	if len(l.clientRequests) < maxIdleClients {
+		return
+	}
+	for client, limiter := range l.clientRequests {
+		if limiter.TokensAt(now) >= float64(limiter.Burst()) {
+			delete(l.clientRequests, client)
+		}
+	}
+}
+
+// AcquireRun counts a gadget run directly by client, given the gadget
+// instances running in addition. release has to be called once the gadget
+// stopped.
+func (l *Limiter) AcquireRun(client string, instances []*api.GadgetInstance) (release func(), err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.checkRunning(instances); err != nil {
+		return nil, err
+	}
+	if limit := l.limits.MaxRunningGadgetsPerClient; limit > 0 && l.runningPerClient[client] >= limit {
+		return nil, exhausted(SubjectClient+":"+client, 0,
+			fmt.Sprintf("%s is already running %d gadgets, the maximum", client, limit))
+	}
+
+	l.running++
+	l.runningPerClient[client]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.running--
+			if l.runningPerClient[client]--; l.runningPerClient[client] == 0 {
+				delete(l.runningPerClient, client)
+			}
+		})
+	}, nil
+}
+
+// CheckInstance returns an error if adding or resuming the gadget instance gi
+// would exceed a limit, given the existing gadget instances
+func (l *Limiter) CheckInstance(instances []*api.GadgetInstance, gi *api.GadgetInstance) error {
+	var others []*api.GadgetInstance
+	for _, other := range instances {
+		if other.Id != gi.Id {
+			others = append(others, other)
+		}
+	}
+
+	l.mu.Lock()
+	err := l.checkRunning(others)
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if limit := l.limits.MaxInstancesPerNamespace; limit > 0 {
+		ns := instanceNamespace(gi)
+		count := 0
+		for _, other := range others {
+			if instanceNamespace(other) == ns {
+				count++
+			}
+		}
+		if count >= limit {
+			desc := fmt.Sprintf("namespace %q already has %d gadget instances, the maximum", ns, limit)
+			if ns == "" {
+				desc = fmt.Sprintf("there are already %d gadget instances for all namespaces, the maximum", limit)
+			}
+			return exhausted(SubjectNamespace+":"+ns, 0, desc)
+		}
+	}
+
+	if limit := l.limits.MaxInstancesPerTag; limit > 0 {
+		for _, tag := range gi.Tags {
+			count := 0
+			for _, other := range others {
+				for _, otherTag := range other.Tags {
+					if otherTag == tag {
+						count++
+						break
+					}
+				}
+			}
+			if count >= limit {
+				return exhausted(SubjectTag+":"+tag, 0,
+					fmt.Sprintf("tag %q already has %d gadget instances, the maximum", tag, limit))
+			}
+		}
+	}
+	return nil
+}
+
+// checkRunning checks MaxRunningGadgets for one more gadget; l.mu must be held
+func (l *Limiter) checkRunning(instances []*api.GadgetInstance) error {
+	limit := l.limits.MaxRunningGadgets
+	if limit <= 0 {
+		return nil
+	}
+	count := l.running
+	for _, gi := range instances {
+		if !gi.Paused {
+			count++
+		}
+	}
+	if count >= limit {
+		return exhausted(SubjectGlobal, 0, fmt.Sprintf("%d gadgets are already running, the maximum", limit))
+	}
+	return nil
+}
+
+func instanceNamespace(gi *api.GadgetInstance) string {
+	for _, key := range namespaceParams {
+		if ns := gi.GetGadgetConfig().GetParamValues()[key]; ns != "" {
+			return ns
+		}
+	}
+	return ""
+}
+
+// exhausted returns a RESOURCE_EXHAUSTED error detailing the violated quota;
+// retryDelay is added as RetryInfo if set
+func exhausted(subject string, retryDelay time.Duration, description string) error {
+	details := []protoadapt.MessageV1{
+		&errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{{
+				Subject:     subject,
+				Description: description,
+			}},
+		},
+	}
+	if retryDelay > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)})
+	}
+	st, err := status.New(codes.ResourceExhausted, description).WithDetails(details...)
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, description)
+	}
+	return st.Err()
+}
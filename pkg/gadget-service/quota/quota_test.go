@@ -0,0 +1,120 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// requireExhausted checks that err is RESOURCE_EXHAUSTED with a violation of
+// the given subject and returns its RetryInfo, if any
+func requireExhausted(t *testing.T, err error, subject string) *errdetails.RetryInfo {
+	t.Helper()
+	require.Error(t, err)
+	st := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+
+	var retryInfo *errdetails.RetryInfo
+	var quotaFailure *errdetails.QuotaFailure
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.QuotaFailure:
+			quotaFailure = d
+		case *errdetails.RetryInfo:
+			retryInfo = d
+		}
+	}
+	require.NotNil(t, quotaFailure)
+	require.Len(t, quotaFailure.Violations, 1)
+	assert.Equal(t, subject, quotaFailure.Violations[0].Subject)
+	return retryInfo
+}
+
+func TestAcquireRun(t *testing.T) {
+	l := New(Limits{MaxRunningGadgets: 3, MaxRunningGadgetsPerClient: 2})
+
+	releaseAlice1, err := l.AcquireRun("alice", nil)
+	require.NoError(t, err)
+	_, err = l.AcquireRun("alice", nil)
+	require.NoError(t, err)
+	_, err = l.AcquireRun("alice", nil)
+	requireExhausted(t, err, "client:alice")
+
+	// Gadget instances that are not paused count towards the global limit
+	instances := []*api.GadgetInstance{{Id: "a"}, {Id: "b", Paused: true}}
+	_, err = l.AcquireRun("bob", instances)
+	requireExhausted(t, err, "global")
+
+	releaseAlice1()
+	// Releasing twice has no effect
+	releaseAlice1()
+	_, err = l.AcquireRun("bob", nil)
+	require.NoError(t, err)
+	_, err = l.AcquireRun("bob", nil)
+	require.NoError(t, err)
+	_, err = l.AcquireRun("carol", nil)
+	requireExhausted(t, err, "global")
+}
+
+func TestCheckInstance(t *testing.T) {
+	l := New(Limits{MaxInstancesPerNamespace: 1, MaxInstancesPerTag: 2})
+
+	newInstance := func(id, namespace string, tags ...string) *api.GadgetInstance {
+		return &api.GadgetInstance{
+			Id:   id,
+			Tags: tags,
+			GadgetConfig: &api.GadgetRunRequest{
+				ParamValues: map[string]string{"operator.KubeManager.namespace": namespace},
+			},
+		}
+	}
+	instances := []*api.GadgetInstance{
+		newInstance("1", "default", "ci"),
+		newInstance("2", "", "ci"),
+	}
+
+	requireExhausted(t, l.CheckInstance(instances, newInstance("3", "default")), "namespace:default")
+	requireExhausted(t, l.CheckInstance(instances, newInstance("3", "")), "namespace:")
+	requireExhausted(t, l.CheckInstance(instances, newInstance("3", "kube-system", "ci")), "tag:ci")
+	require.NoError(t, l.CheckInstance(instances, newInstance("3", "kube-system", "other")))
+
+	// Resuming an instance doesn't count the instance itself
+	require.NoError(t, l.CheckInstance(instances, instances[0]))
+}
+
+func TestAllow(t *testing.T) {
+	l := New(Limits{ClientRequestsPerSecond: 0.001, ClientRequestBurst: 2})
+
+	require.NoError(t, l.Allow("alice"))
+	require.NoError(t, l.Allow("alice"))
+	retryInfo := requireExhausted(t, l.Allow("alice"), "client:alice")
+	require.NotNil(t, retryInfo)
+	assert.Positive(t, retryInfo.RetryDelay.AsDuration())
+
+	// Other clients have their own limit
+	require.NoError(t, l.Allow("bob"))
+
+	l = New(Limits{RequestsPerSecond: 0.001, RequestBurst: 1})
+	require.NoError(t, l.Allow("alice"))
+	requireExhausted(t, l.Allow("bob"), "global")
+}
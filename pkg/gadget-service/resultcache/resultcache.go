@@ -0,0 +1,182 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resultcache caches the events of one-shot gadgets, like
+// snapshot_process, so that clients polling them frequently don't make the
+// server collect the same data over and over again. Each server has its own
+// cache, so cached events are always those of the node they're requested from.
+package resultcache
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// DefaultMaxEntries is the number of runs cached at most
+const DefaultMaxEntries = 64
+
+type entry struct {
+	events  []*api.GadgetEvent
+	expires time.Time
+}
+
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// New returns a cache keeping the events of runs for the given duration
+func New(ttl time.Duration, maxEntries int) *Cache {
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*entry),
+	}
+}
+
+// Key returns the cache key of a run request. Settings of the client-side
+// runtime and the timeout don't change the events of one-shot gadgets, so
+// they're not part of it.
+func Key(req *api.GadgetRunRequest) string {
+	keys := make([]string, 0, len(req.ParamValues))
+	for k := range req.ParamValues {
+		if strings.HasPrefix(k, "runtime.") {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var sb strings.Builder
+	sb.WriteString(req.ImageName)
+	for _, k := range keys {
+		sb.WriteByte(0)
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(req.ParamValues[k])
+	}
+	for _, arg := range req.Args {
+		sb.WriteByte(0)
+		sb.WriteString(arg)
+	}
+	return sb.String()
+}
+
+// Get returns the cached events for key and their age
+func (c *Cache) Get(key string) ([]*api.GadgetEvent, time.Duration, bool) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+	if now.After(e.expires) {
+		delete(c.entries, key)
+		return nil, 0, false
+	}
+	return e.events, c.ttl - e.expires.Sub(now), true
+}
+
+// Put caches the events of a run; if the cache is full, the entry expiring
+// first is evicted
+func (c *Cache) Put(key string, events []*api.GadgetEvent) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || e.expires.Before(oldest) {
+				oldestKey, oldest = k, e.expires
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			delete(c.entries, oldestKey)
+		}
+	}
+	c.entries[key] = &entry{
+		events:  events,
+		expires: now.Add(c.ttl),
+	}
+}
+
+// isOneShot returns whether the data source emits its data exactly once, like
+// the ones of snapshotters
+func isOneShot(ds *api.DataSource) bool {
+	return ds.Annotations[api.FetchCountAnnotation] == "1" &&
+		ds.Annotations[api.FetchIntervalAnnotation] == "0"
+}
+
+// Recorder collects the events of a run until each data source emitted its
+// single packet
+type Recorder struct {
+	mu      sync.Mutex
+	events  []*api.GadgetEvent
+	pending map[uint32]struct{}
+}
+
+// NewRecorder returns a recorder for the run of a gadget with the given
+// information and the event it was sent with, or nil if not all of its data
+// sources are one-shot
+func NewRecorder(gi *api.GadgetInfo, infoEvent *api.GadgetEvent) *Recorder {
+	if len(gi.DataSources) == 0 {
+		return nil
+	}
+	pending := make(map[uint32]struct{}, len(gi.DataSources))
+	for _, ds := range gi.DataSources {
+		if !isOneShot(ds) {
+			return nil
+		}
+		pending[ds.Id] = struct{}{}
+	}
+	return &Recorder{
+		events:  []*api.GadgetEvent{infoEvent},
+		pending: pending,
+	}
+}
+
+// Add records the payload of a data source; once all data sources emitted
+// theirs, the events of the run are returned
+func (r *Recorder) Add(dataSourceID uint32, payload []byte) []*api.GadgetEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pending[dataSourceID]; !ok {
+		return nil
+	}
+	delete(r.pending, dataSourceID)
+	r.events = append(r.events, &api.GadgetEvent{
+		Type:         api.EventTypeGadgetPayload,
+		Payload:      payload,
+		DataSourceID: dataSourceID,
+	})
+	if len(r.pending) > 0 {
+		return nil
+	}
+	return r.events
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resultcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func TestKey(t *testing.T) {
+	req := &api.GadgetRunRequest{
+		ImageName: "snapshot_process",
+		ParamValues: map[string]string{
+			"operator.oci.ebpf.threads": "true",
+			"operator.filter.filter":    "comm==bash",
+			"runtime.compression":       "zstd",
+		},
+		Timeout: int64(time.Second),
+	}
+	same := &api.GadgetRunRequest{
+		ImageName: "snapshot_process",
+		ParamValues: map[string]string{
+			"operator.filter.filter":    "comm==bash",
+			"operator.oci.ebpf.threads": "true",
+		},
+	}
+	assert.Equal(t, Key(req), Key(same))
+
+	other := &api.GadgetRunRequest{
+		ImageName: "snapshot_process",
+		ParamValues: map[string]string{
+			"operator.oci.ebpf.threads": "false",
+			"operator.filter.filter":    "comm==bash",
+		},
+	}
+	assert.NotEqual(t, Key(req), Key(other))
+
+	assert.NotEqual(t,
+		Key(&api.GadgetRunRequest{ImageName: "a", Args: []string{"b"}}),
+		Key(&api.GadgetRunRequest{ImageName: "ab"}),
+	)
+}
+
+func TestCache(t *testing.T) {
+	c := New(50*time.Millisecond, 2)
+	events := []*api.GadgetEvent{{Type: api.EventTypeGadgetInfo}}
+
+	_, _, ok := c.Get("a")
+	assert.False(t, ok)
+
+	c.Put("a", events)
+	got, age, ok := c.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, events, got)
+	assert.Less(t, age, 50*time.Millisecond)
+
+	// The entry expiring first is evicted when the cache is full
+	c.Put("b", events)
+	c.Put("c", events)
+	_, _, ok = c.Get("a")
+	assert.False(t, ok)
+	_, _, ok = c.Get("b")
+	assert.True(t, ok)
+
+	time.Sleep(60 * time.Millisecond)
+	_, _, ok = c.Get("c")
+	assert.False(t, ok)
+}
+
+func oneShot(id uint32) *api.DataSource {
+	return &api.DataSource{
+		Id: id,
+		Annotations: map[string]string{
+			api.FetchCountAnnotation:    "1",
+			api.FetchIntervalAnnotation: "0",
+		},
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	info := &api.GadgetEvent{Type: api.EventTypeGadgetInfo}
+
+	// Gadgets emitting events continuously aren't recorded
+	assert.Nil(t, NewRecorder(&api.GadgetInfo{}, info))
+	assert.Nil(t, NewRecorder(&api.GadgetInfo{
+		DataSources: []*api.DataSource{oneShot(0), {Id: 1}},
+	}, info))
+
+	r := NewRecorder(&api.GadgetInfo{
+		DataSources: []*api.DataSource{oneShot(0), oneShot(1)},
+	}, info)
+	require.NotNil(t, r)
+
+	assert.Nil(t, r.Add(1, []byte("b")))
+	// Only the first packet of a data source is recorded
+	assert.Nil(t, r.Add(1, []byte("c")))
+
+	events := r.Add(0, []byte("a"))
+	require.Len(t, events, 3)
+	assert.Equal(t, info, events[0])
+	assert.Equal(t, uint32(1), events[1].DataSourceID)
+	assert.Equal(t, []byte("b"), events[1].Payload)
+	assert.Equal(t, uint32(0), events[2].DataSourceID)
+	assert.Equal(t, []byte("a"), events[2].Payload)
+
+	assert.Nil(t, r.Add(0, []byte("d")))
+}
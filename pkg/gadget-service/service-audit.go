@@ -0,0 +1,83 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"google.golang.org/grpc/peer"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/audit"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
+)
+
+// auditedOperations are the operations starting, changing or stopping gadgets;
+// read-only operations are not audited
+var auditedOperations = map[authz.Operation]struct{}{
+	authz.OperationRun:        {},
+	authz.OperationAttach:     {},
+	authz.OperationDebugShell: {},
+	authz.OperationCreate:     {},
+	authz.OperationRemove:     {},
+	authz.OperationPause:      {},
+	authz.OperationResume:     {},
+	authz.OperationUpdate:     {},
+}
+
+// SetAuditor makes the service record the callers of all requests starting,
+// changing or stopping gadgets to a, including denied and failed ones
+func (s *Service) SetAuditor(a audit.Auditor) {
+	s.auditor = a
+}
+
+func (s *Service) audit(ctx context.Context, id *authz.Identity, op authz.Operation, instance *api.GadgetInstance, err error) {
+	if s.auditor == nil {
+		return
+	}
+	if _, ok := auditedOperations[op]; !ok {
+		return
+	}
+
+	record := &audit.Record{
+		Time:         time.Now(),
+		Operation:    op,
+		User:         id.User,
+		Groups:       id.Groups,
+		Node:         auditNodeName(),
+		Image:        instance.GetGadgetConfig().GetImageName(),
+		InstanceID:   instance.GetId(),
+		InstanceName: instance.GetName(),
+		ParamValues:  instance.GetGadgetConfig().GetParamValues(),
+		Nodes:        instance.GetNodes(),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		record.Peer = p.Addr.String()
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	s.auditor.Audit(record)
+}
+
+func auditNodeName() string {
+	node := os.Getenv("NODE_NAME")
+	if node == "" {
+		node, _ = os.Hostname()
+	}
+	return node
+}
@@ -0,0 +1,245 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
+)
+
+// methodOperations maps the gRPC methods to the operations checked by the
+// authorizer; unary methods listed neither here nor in authenticatedMethods
+// are denied
+var methodOperations = map[string]authz.Operation{
+	"/api.BuiltInGadgetManager/GetNodeInfo":           authz.OperationNodeInfo,
+	"/api.BuiltInGadgetManager/GetNodeOverhead":       authz.OperationNodeInfo,
+	"/api.GadgetManager/GetGadgetInfo":                authz.OperationInfo,
+	"/api.GadgetManager/ValidateGadget":               authz.OperationInfo,
+	"/api.GadgetManager/DebugShell":                   authz.OperationDebugShell,
+	"/api.GadgetInstanceManager/CreateGadgetInstance": authz.OperationCreate,
+	"/api.GadgetInstanceManager/ListGadgetInstances":  authz.OperationList,
+	"/api.GadgetInstanceManager/GetGadgetInstance":    authz.OperationGet,
+	"/api.GadgetInstanceManager/RemoveGadgetInstance": authz.OperationRemove,
+	"/api.GadgetInstanceManager/PauseGadgetInstance":  authz.OperationPause,
+	"/api.GadgetInstanceManager/ResumeGadgetInstance": authz.OperationResume,
+	"/api.GadgetInstanceManager/UpdateGadgetInstance": authz.OperationUpdate,
+}
+
+// authenticatedMethods only require the caller to be authenticated
+var authenticatedMethods = map[string]struct{}{
+	"/api.BuiltInGadgetManager/GetInfo": {},
+}
+
+// runGadgetMethod is authorized once its first message tells whether a gadget
+// is run or attached to
+const runGadgetMethod = "/api.GadgetManager/RunGadget"
+
+// SetAuthorizer makes the service authenticate the callers of all requests and
+// check them against the policy of a; by default, all requests are allowed
+func (s *Service) SetAuthorizer(a *authz.Authorizer) {
+	s.authorizer = a
+}
+
+// interceptorServerOptions returns the interceptors enforcing the authorizer
+// and the quotas and feeding the auditor, if any of them is set
+func (s *Service) interceptorServerOptions() []grpc.ServerOption {
+	if s.authorizer == nil && s.auditor == nil && s.quotaLimiter == nil {
+		return nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	}
+}
+
+// authenticate returns the identity of the caller; without authorizer, only
+// client certificates are considered
+func (s *Service) authenticate(ctx context.Context) (*authz.Identity, error) {
+	if s.authorizer == nil {
+		return authz.PeerIdentity(ctx), nil
+	}
+	return s.authorizer.Authenticate(ctx)
+}
+
+func (s *Service) authorize(id *authz.Identity, method string, op authz.Operation, image string) error {
+	if s.authorizer == nil {
+		return nil
+	}
+	if err := s.authorizer.Authorize(id, op, image); err != nil {
+		s.logger.Warnf("[%s] %s: %v", id, method, err)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	id, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRate(id, info.FullMethod); err != nil {
+		return nil, err
+	}
+	op, ok := methodOperations[info.FullMethod]
+	if !ok {
+		if _, ok := authenticatedMethods[info.FullMethod]; !ok && s.authorizer != nil {
+			s.logger.Warnf("[%s] %s: denying method without operation", id, info.FullMethod)
+			return nil, status.Errorf(codes.PermissionDenied, "%s is not allowed to call %s", id.User, info.FullMethod)
+		}
+		return handler(authz.NewContext(ctx, id), req)
+	}
+
+	instance := s.requestInstance(ctx, req)
+	err = s.authorize(id, info.FullMethod, op, instance.GetGadgetConfig().GetImageName())
+	var resp any
+	if err == nil {
+		unlock := s.lockInstanceQuota(op)
+		// Gadgets are only run by streams, so there's nothing to release
+		_, err = s.checkQuota(ctx, id, info.FullMethod, op, instance)
+		if err == nil {
+			resp, err = handler(authz.NewContext(ctx, id), req)
+		}
+		unlock()
+	}
+	s.audit(ctx, id, op, instance, err)
+	return resp, err
+}
+
+func (s *Service) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id, err := s.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	if err := s.checkRate(id, info.FullMethod); err != nil {
+		return err
+	}
+	if op, ok := methodOperations[info.FullMethod]; ok {
+		err := s.authorize(id, info.FullMethod, op, "")
+		s.audit(ss.Context(), id, op, nil, err)
+		if err != nil {
+			return err
+		}
+	}
+	stream := &authzServerStream{
+		ServerStream: ss,
+		ctx:          authz.NewContext(ss.Context(), id),
+	}
+	if info.FullMethod == runGadgetMethod {
+		stream.onFirstMessage = func(m any) error {
+			op, instance := s.controlRequestInstance(ss.Context(), m)
+			err := s.authorize(id, info.FullMethod, op, instance.GetGadgetConfig().GetImageName())
+			if err == nil {
+				stream.release, err = s.checkQuota(ss.Context(), id, info.FullMethod, op, instance)
+			}
+			s.audit(ss.Context(), id, op, instance, err)
+			return err
+		}
+	}
+	err = handler(srv, stream)
+	if stream.release != nil {
+		stream.release()
+	}
+	return err
+}
+
+// authzServerStream carries the identity of the caller and checks the first
+// message received, if needed
+type authzServerStream struct {
+	grpc.ServerStream
+	ctx            context.Context
+	onFirstMessage func(m any) error
+	once           sync.Once
+
+	// release frees the quota taken by a gadget run on this stream
+	release func()
+}
+
+func (s *authzServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authzServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	var err error
+	s.once.Do(func() {
+		if s.onFirstMessage != nil {
+			err = s.onFirstMessage(m)
+		}
+	})
+	return err
+}
+
+// controlRequestInstance returns the operation requested by the first message
+// of RunGadget and the gadget instance it's about; a gadget run directly is
+// described as an instance without ID
+func (s *Service) controlRequestInstance(ctx context.Context, m any) (authz.Operation, *api.GadgetInstance) {
+	ctrl, ok := m.(*api.GadgetControlRequest)
+	if !ok {
+		return authz.OperationRun, nil
+	}
+	if attach := ctrl.GetAttachRequest(); attach != nil {
+		return authz.OperationAttach, s.lookupInstance(ctx, attach.Id)
+	}
+	return authz.OperationRun, &api.GadgetInstance{GadgetConfig: ctrl.GetRunRequest()}
+}
+
+// requestInstance returns the gadget instance a unary request is about, if any
+func (s *Service) requestInstance(ctx context.Context, req any) *api.GadgetInstance {
+	switch r := req.(type) {
+	case *api.GetGadgetInfoRequest:
+		if r.Flags&api.GadgetInfoRequestFlagUseInstance != 0 {
+			return s.lookupInstance(ctx, r.ImageName)
+		}
+		return &api.GadgetInstance{GadgetConfig: &api.GadgetRunRequest{ImageName: r.ImageName}}
+	case *api.ValidateGadgetRequest:
+		return &api.GadgetInstance{GadgetConfig: &api.GadgetRunRequest{ImageName: r.ImageName, ParamValues: r.ParamValues}}
+	case *api.CreateGadgetInstanceRequest:
+		return r.GetGadgetInstance()
+	case *api.GadgetInstanceId:
+		return s.lookupInstance(ctx, r.Id)
+	case *api.UpdateGadgetInstanceRequest:
+		// Describe the change instead of the current params
+		gi := proto.Clone(s.lookupInstance(ctx, r.Id)).(*api.GadgetInstance)
+		if gi.GadgetConfig == nil {
+			gi.GadgetConfig = &api.GadgetRunRequest{}
+		}
+		gi.GadgetConfig.ParamValues = r.ParamValues
+		return gi
+	}
+	return nil
+}
+
+// lookupInstance returns the given gadget instance from the store, or an
+// instance only holding the ID if it's unknown
+func (s *Service) lookupInstance(ctx context.Context, id string) *api.GadgetInstance {
+	if s.store == nil || !api.IsValidInstanceID(id) {
+		return &api.GadgetInstance{Id: id}
+	}
+	gi, err := s.store.GetGadgetInstance(ctx, &api.GadgetInstanceId{Id: id})
+	if err != nil {
+		return &api.GadgetInstance{Id: id}
+	}
+	return gi
+}
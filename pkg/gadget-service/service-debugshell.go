@@ -0,0 +1,228 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/debugshell"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
+// debugShellAuditOperator is the data operator exporting the audit records of
+// debug shells
+const debugShellAuditOperator = "otel-logs"
+
+type DebugShellConfig struct {
+	Enabled     bool
+	MaxDuration time.Duration
+
+	// AuditParamValues holds the instance parameters of the operators
+	// handling the audit records, like
+	// "operator.otel-logs.otel-logs-exporter"
+	AuditParamValues api.ParamValues
+}
+
+// SetDebugShellConfig configures the debug shells; they're disabled by default
+func (s *Service) SetDebugShellConfig(cfg DebugShellConfig) {
+	s.debugShell = cfg
+}
+
+type containerCollectionProvider interface {
+	ContainerCollection() *containercollection.ContainerCollection
+}
+
+func (s *Service) findDebugShellTarget(req *api.DebugShellStartRequest) (debugshell.Target, error) {
+	selector := &containercollection.ContainerSelector{}
+	selector.K8s.Namespace = req.Namespace
+	selector.K8s.PodName = req.PodName
+
+	k8s := req.Namespace != "" || req.PodName != ""
+	name := req.ContainerName
+	if k8s {
+		selector.K8s.ContainerName = req.ContainerName
+		name = fmt.Sprintf("%s/%s/%s", req.Namespace, req.PodName, req.ContainerName)
+	} else {
+		if req.ContainerName == "" {
+			return debugshell.Target{}, status.Error(codes.InvalidArgument, "no container given")
+		}
+		selector.Runtime.ContainerName = req.ContainerName
+	}
+
+	for op := range s.operators {
+		provider, ok := op.(containerCollectionProvider)
+		if !ok || provider.ContainerCollection() == nil {
+			continue
+		}
+		containers := provider.ContainerCollection().GetContainersBySelector(selector)
+		switch len(containers) {
+		case 0:
+			continue
+		case 1:
+		default:
+			return debugshell.Target{}, status.Errorf(codes.InvalidArgument, "%d containers match %q, specify the container", len(containers), name)
+		}
+		c := containers[0]
+		if k8s {
+			name = fmt.Sprintf("%s/%s/%s", c.K8s.Namespace, c.K8s.PodName, c.K8s.ContainerName)
+		}
+		return debugshell.Target{Pid: c.ContainerPid(), Name: name}, nil
+	}
+	return debugshell.Target{}, status.Errorf(codes.NotFound, "container %q not found", name)
+}
+
+// debugShellUser returns who opens a debug shell: the identity set by the
+// authorizer, or the subject of the verified client certificate without it. It
+// returns an empty string for anonymous callers.
+func debugShellUser(ctx context.Context) string {
+	if id, ok := authz.FromContext(ctx); ok && id.User != authz.AnonymousUser {
+		return id.String()
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.AuthInfo != nil {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.VerifiedChains) > 0 {
+			return tlsInfo.State.VerifiedChains[0][0].Subject.String()
+		}
+	}
+	return ""
+}
+
+// DebugShell opens an audited debug shell in a container of this node
+func (s *Service) DebugShell(stream api.GadgetManager_DebugShellServer) error {
+	if !s.debugShell.Enabled {
+		return status.Error(codes.PermissionDenied, "debug shells are disabled on this node")
+	}
+
+	user := debugShellUser(stream.Context())
+	if user == "" {
+		// Sessions have to be attributed to someone in the audit records
+		return status.Error(codes.Unauthenticated, "debug shells require an authenticated user, use a client certificate or a token")
+	}
+
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	req := msg.GetStartRequest()
+	if req == nil {
+		return status.Error(codes.InvalidArgument, "expected first message to be a start request")
+	}
+
+	target, err := s.findDebugShellTarget(req)
+	if err != nil {
+		return err
+	}
+
+	node := os.Getenv("NODE_NAME")
+	if node == "" {
+		node, _ = os.Hostname()
+	}
+
+	auditors := []debugshell.Auditor{&debugshell.LoggerAuditor{Logger: s.logger}}
+	var ops []operators.DataOperator
+	for op := range s.operators {
+		if op.Name() == debugShellAuditOperator {
+			ops = append(ops, op)
+		}
+	}
+	pipeline, err := debugshell.NewPipelineAuditor(ops, s.debugShell.AuditParamValues, s.logger)
+	if err != nil {
+		// Don't open sessions that can't be audited as configured
+		return status.Errorf(codes.Internal, "starting audit: %v", err)
+	}
+	defer pipeline.Close()
+	auditors = append(auditors, pipeline)
+
+	session, err := debugshell.NewSession(req, target, debugshell.Options{
+		Node:        node,
+		User:        user,
+		MaxDuration: s.debugShell.MaxDuration,
+		Auditors:    auditors,
+	})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	var sendLock sync.Mutex
+	send := func(ev *api.DebugShellEvent) error {
+		sendLock.Lock()
+		defer sendLock.Unlock()
+		return stream.Send(ev)
+	}
+
+	stdin := make(chan []byte, 16)
+	go func() {
+		defer close(stdin)
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				// The client closing its side only closes the input
+				if !errors.Is(err, io.EOF) {
+					cancel()
+				}
+				return
+			}
+			data := msg.GetStdin()
+			if data == nil {
+				continue
+			}
+			select {
+			case stdin <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Hold back the output until the client knows the session started
+	started := make(chan struct{})
+	err = session.Start(stdin, func(data []byte) error {
+		<-started
+		return send(&api.DebugShellEvent{Event: &api.DebugShellEvent_Output{Output: data}})
+	})
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	err = send(&api.DebugShellEvent{Event: &api.DebugShellEvent_Started{Started: &api.DebugShellStarted{
+		SessionID: session.ID(),
+		Node:      node,
+		Container: target.Name,
+		Deadline:  session.Deadline().UnixNano(),
+	}}})
+	close(started)
+	if err != nil {
+		cancel()
+	}
+
+	exit := session.Wait(ctx)
+	return send(&api.DebugShellEvent{Event: &api.DebugShellEvent_Exit{Exit: exit}})
+}
@@ -0,0 +1,223 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/gateway"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
+)
+
+const gatewayBufferSize = 1 << 20
+
+// forwardedHeader is set on the attach requests forwarded by another replica,
+// which are always served locally
+const forwardedHeader = "X-Inspektor-Gadget-Forwarded-By"
+
+type GatewayConfig struct {
+	// Address is the ip:port the HTTP gateway listens on; the gateway is
+	// disabled if empty
+	Address string
+
+	// TLSConfig is used to serve the gateway over HTTPS. It's required, as
+	// the gateway can't be served over plain HTTP
+	TLSConfig *tls.Config
+
+	// AdvertiseAddress is the host:port at which the other replicas sharing
+	// the store reach the gateway, to forward attach requests for gadget
+	// instances this replica doesn't run
+	AdvertiseAddress string
+}
+
+// SetGatewayConfig configures the HTTP gateway; it's disabled by default. An
+// authorizer has to be set as well, see SetAuthorizer.
+func (s *Service) SetGatewayConfig(cfg GatewayConfig) {
+	s.gateway = cfg
+}
+
+// startGateway serves the HTTP gateway. It talks to an internal gRPC server
+// using an in-memory connection, which applies the same interceptors as the
+// public one, but not its transport credentials: clients of the gateway are
+// identified by their bearer tokens only.
+func (s *Service) startGateway() error {
+	if s.gateway.TLSConfig == nil {
+		return errors.New("the HTTP gateway requires a TLS configuration")
+	}
+	if s.authorizer == nil {
+		return errors.New("the HTTP gateway requires an authorizer")
+	}
+
+	listener, err := net.Listen("tcp", s.gateway.Address)
+	if err != nil {
+		return fmt.Errorf("creating gateway listener: %w", err)
+	}
+	listener = tls.NewListener(listener, s.gateway.TLSConfig)
+
+	internalListener := bufconn.Listen(gatewayBufferSize)
+	server := grpc.NewServer(append(s.interceptorServerOptions(), tracing.ServerOption())...)
+	api.RegisterGadgetManagerServer(server, s)
+	if s.store != nil {
+		api.RegisterGadgetInstanceManagerServer(server, s)
+	}
+	s.servers[server] = struct{}{}
+	go server.Serve(internalListener)
+
+	conn, err := grpc.NewClient("passthrough:///gateway",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return internalListener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("connecting gateway: %w", err)
+	}
+
+	var handler http.Handler = gateway.New(conn, s.logger)
+	if cluster, ok := s.store.(store.Cluster); ok {
+		cluster.SetGatewayAddress(s.gateway.AdvertiseAddress)
+		handler = newAttachRouter(cluster, handler, s.peerTransport(), s.logger)
+	}
+
+	s.gatewayServer = &http.Server{Handler: handler}
+	go func() {
+		defer conn.Close()
+		if err := s.gatewayServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Errorf("serving gateway: %v", err)
+		}
+	}()
+	s.logger.Infof("serving HTTP gateway at %q", s.gateway.Address)
+	return nil
+}
+
+// peerTransport connects to the gateways of the other replicas, which are
+// expected to use certificates of the same CA as their clients: the server
+// certificate of this replica is used as client certificate, and the client CA
+// verifies theirs
+func (s *Service) peerTransport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		Certificates: s.gateway.TLSConfig.Certificates,
+		RootCAs:      s.gateway.TLSConfig.ClientCAs,
+	}
+	return transport
+}
+
+// attachRouter forwards the attach requests of the HTTP gateway for gadget
+// instances not run by this replica to one running them. The replica is chosen
+// by rendezvous hashing, so the requests for an instance keep going to the
+// same replica as long as it's alive.
+type attachRouter struct {
+	cluster   store.Cluster
+	next      http.Handler
+	transport http.RoundTripper
+	logger    logger.Logger
+	mux       *http.ServeMux
+}
+
+func newAttachRouter(cluster store.Cluster, next http.Handler, transport http.RoundTripper, logger logger.Logger) *attachRouter {
+	r := &attachRouter{
+		cluster:   cluster,
+		next:      next,
+		transport: transport,
+		logger:    logger,
+		mux:       http.NewServeMux(),
+	}
+	r.mux.HandleFunc("GET /v1/instances/{id}/attach", r.route)
+	r.mux.Handle("/", next)
+	return r
+}
+
+func (r *attachRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
+
+func (r *attachRouter) route(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get(forwardedHeader) != "" {
+		r.next.ServeHTTP(w, req)
+		return
+	}
+	replica, err := r.pick(req.Context(), req.PathValue("id"))
+	if err != nil {
+		// Served locally, which reports the error to the client
+		r.logger.Debugf("routing attach request for gadget instance %q: %v", req.PathValue("id"), err)
+	}
+	if replica == nil {
+		r.next.ServeHTTP(w, req)
+		return
+	}
+
+	target := &url.URL{Scheme: "https", Host: replica.GatewayAddress}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			pr.Out.Header.Set(forwardedHeader, r.cluster.Replica())
+		},
+		Transport: r.transport,
+		// Events are sent right away
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			r.logger.Warnf("forwarding attach request to replica %q: %v", replica.Name, err)
+			http.Error(w, fmt.Sprintf("forwarding to replica %q failed", replica.Name), http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(w, req)
+}
+
+// pick returns the replica to forward the attach request for the gadget
+// instance to, or nil if this replica runs it
+func (r *attachRouter) pick(ctx context.Context, id string) (*store.Replica, error) {
+	replicas, err := r.cluster.InstanceReplicas(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *store.Replica
+	var bestScore uint64
+	for i := range replicas {
+		replica := &replicas[i]
+		if replica.Name == r.cluster.Replica() {
+			return nil, nil
+		}
+		if replica.GatewayAddress == "" {
+			continue
+		}
+		h := fnv.New64a()
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+		h.Write([]byte(replica.Name))
+		if score := h.Sum64(); best == nil || score > bestScore {
+			best, bestScore = replica, score
+		}
+	}
+	return best, nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+// fakeCluster maps gadget instances to the replicas running them
+type fakeCluster struct {
+	name      string
+	instances map[string][]store.Replica
+}
+
+func (f *fakeCluster) IsLeader() bool                   { return false }
+func (f *fakeCluster) SetGatewayAddress(address string) {}
+func (f *fakeCluster) Replica() string                  { return f.name }
+
+func (f *fakeCluster) InstanceReplicas(ctx context.Context, id string) ([]store.Replica, error) {
+	replicas, ok := f.instances[id]
+	if !ok {
+		return nil, fmt.Errorf("gadget instance %q not found", id)
+	}
+	return replicas, nil
+}
+
+func TestAttachRouter(t *testing.T) {
+	// Answers with the name of the replica and who forwarded the request
+	newReplica := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", name, r.Header.Get(forwardedHeader))
+		})
+	}
+	peer := httptest.NewTLSServer(newReplica("node2"))
+	t.Cleanup(peer.Close)
+	peerAddress := strings.TrimPrefix(peer.URL, "https://")
+
+	cluster := &fakeCluster{
+		name: "node1",
+		instances: map[string][]store.Replica{
+			"local":     {{Name: "node1"}, {Name: "node2", GatewayAddress: peerAddress}},
+			"remote":    {{Name: "node2", GatewayAddress: peerAddress}},
+			"unknown":   {{Name: "node3"}},
+			"nowhere":   {},
+			"unhealthy": {{Name: "node4", GatewayAddress: "127.0.0.1:1"}},
+		},
+	}
+	router := newAttachRouter(cluster, newReplica("node1"), peer.Client().Transport, logger.DefaultLogger())
+
+	get := func(path string, header http.Header) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		body, err := io.ReadAll(w.Result().Body)
+		require.NoError(t, err)
+		return w.Code, strings.TrimSpace(string(body))
+	}
+
+	for name, tc := range map[string]struct {
+		path   string
+		header http.Header
+		code   int
+		body   string
+	}{
+		"local":           {path: "/v1/instances/local/attach", code: http.StatusOK, body: "node1"},
+		"remote":          {path: "/v1/instances/remote/attach", code: http.StatusOK, body: "node2 node1"},
+		"already-routed":  {path: "/v1/instances/remote/attach", header: http.Header{forwardedHeader: {"node3"}}, code: http.StatusOK, body: "node1 node3"},
+		"not-found":       {path: "/v1/instances/missing/attach", code: http.StatusOK, body: "node1"},
+		"no-address":      {path: "/v1/instances/unknown/attach", code: http.StatusOK, body: "node1"},
+		"not-running":     {path: "/v1/instances/nowhere/attach", code: http.StatusOK, body: "node1"},
+		"other-endpoints": {path: "/v1/instances", code: http.StatusOK, body: "node1"},
+		"unreachable":     {path: "/v1/instances/unhealthy/attach", code: http.StatusBadGateway, body: `forwarding to replica "node4" failed`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			code, body := get(tc.path, tc.header)
+			assert.Equal(t, tc.code, code)
+			assert.Equal(t, tc.body, body)
+		})
+	}
+}
+
+func TestAttachRouterSticky(t *testing.T) {
+	replicas := []store.Replica{
+		{Name: "node2", GatewayAddress: "node2:8443"},
+		{Name: "node3", GatewayAddress: "node3:8443"},
+		{Name: "node4", GatewayAddress: "node4:8443"},
+	}
+	cluster := &fakeCluster{name: "node1", instances: map[string][]store.Replica{}}
+	router := newAttachRouter(cluster, http.NotFoundHandler(), nil, logger.DefaultLogger())
+
+	picked := map[string]int{}
+	for i := range 30 {
+		id := fmt.Sprintf("%032x", i)
+		cluster.instances[id] = replicas
+		first, err := router.pick(context.Background(), id)
+		require.NoError(t, err)
+		require.NotNil(t, first)
+		picked[first.Name]++
+
+		// The same replica is picked every time
+		again, err := router.pick(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, first.Name, again.Name)
+
+		// and as long as it's alive, even if others go away
+		cluster.instances[id] = []store.Replica{*first}
+		for _, r := range replicas {
+			if r.Name != first.Name && len(cluster.instances[id]) < 2 {
+				cluster.instances[id] = append(cluster.instances[id], r)
+			}
+		}
+		again, err = router.pick(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, first.Name, again.Name)
+	}
+	// Instances are spread over the replicas
+	assert.Len(t, picked, len(replicas))
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/metrics"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/overhead"
+)
+
+var (
+	descGadgetInstances = prometheus.NewDesc("ig_gadget_instances",
+		"Number of gadget instances", nil, nil)
+	descInstanceEvents = prometheus.NewDesc("ig_gadget_instance_events_total",
+		"Number of events emitted by a gadget instance since it started",
+		[]string{"instance_id", "instance_name", "gadget_image"}, nil)
+	descBPFMaps = prometheus.NewDesc("ig_bpf_maps",
+		"Number of eBPF maps held by the daemon", nil, nil)
+	descBPFMapMemory = prometheus.NewDesc("ig_bpf_map_memory_bytes",
+		"Memory used by the eBPF maps held by the daemon", nil, nil)
+)
+
+// SetMetricsAddress sets the ip:port the metrics of the daemon itself are
+// served on at /metrics; they're not served if empty
+func (s *Service) SetMetricsAddress(address string) {
+	s.metricsAddress = address
+}
+
+// daemonCollector collects the metrics of the daemon that are computed when
+// scraped
+type daemonCollector struct {
+	s *Service
+}
+
+func (c *daemonCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descGadgetInstances
+	ch <- descInstanceEvents
+	ch <- descBPFMaps
+	ch <- descBPFMapMemory
+}
+
+func (c *daemonCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.s.instanceMgr != nil {
+		instances := c.s.instanceMgr.InstanceEvents()
+		ch <- prometheus.MustNewConstMetric(descGadgetInstances, prometheus.GaugeValue, float64(len(instances)))
+		for _, inst := range instances {
+			ch <- prometheus.MustNewConstMetric(descInstanceEvents, prometheus.CounterValue, float64(inst.Events),
+				inst.ID, inst.Name, inst.ImageName)
+		}
+	}
+
+	maps, memory, err := overhead.BPFMaps()
+	if err != nil {
+		c.s.logger.Debugf("collecting eBPF map metrics: %v", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(descBPFMaps, prometheus.GaugeValue, float64(maps))
+	ch <- prometheus.MustNewConstMetric(descBPFMapMemory, prometheus.GaugeValue, float64(memory))
+}
+
+// startMetrics serves the metrics of the daemon itself: the internal metrics
+// registered with pkg/metrics, the ones of daemonCollector and the ones of the
+// Go runtime and the process. Metrics generated by gadgets are served by the
+// otel-metrics operator instead.
+func (s *Service) startMetrics() error {
+	registry := prometheus.NewRegistry()
+	exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return fmt.Errorf("initializing metrics exporter: %w", err)
+	}
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(exporter))
+	if err := metrics.RegisterProvider(provider); err != nil {
+		return fmt.Errorf("registering metrics provider: %w", err)
+	}
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		&daemonCollector{s: s},
+	)
+
+	listener, err := net.Listen("tcp", s.metricsAddress)
+	if err != nil {
+		metrics.UnregisterProvider(provider)
+		return fmt.Errorf("creating metrics listener: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	s.metricsServer = &http.Server{Handler: mux}
+	go func() {
+		defer metrics.UnregisterProvider(provider)
+		if err := s.metricsServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Errorf("serving metrics: %v", err)
+		}
+	}()
+	s.logger.Infof("serving metrics at %q", s.metricsAddress)
+	return nil
+}
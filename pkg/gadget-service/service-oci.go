@@ -23,17 +23,23 @@ import (
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
 	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/compression"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/resultcache"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/integrity"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
 )
 
 func (s *Service) initOperators() error {
@@ -107,13 +113,57 @@ func (s *Service) GetGadgetInfo(ctx context.Context, req *api.GetGadgetInfoReque
 	return &api.GetGadgetInfoResponse{GadgetInfo: gi}, nil
 }
 
+// ValidateGadget prepares the gadget and checks whether it would run on this
+// node, without starting it
+func (s *Service) ValidateGadget(ctx context.Context, req *api.ValidateGadgetRequest) (*api.ValidateGadgetResponse, error) {
+	validator, ok := s.runtime.(runtime.GadgetValidator)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "runtime doesn't support validating gadgets")
+	}
+
+	// Get all available operators
+	ops := make([]operators.DataOperator, 0)
+	for op := range s.operators {
+		ops = append(ops, op)
+	}
+
+	gadgetCtx := gadgetcontext.New(
+		ctx,
+		req.ImageName,
+		gadgetcontext.WithDataOperators(ops...),
+		gadgetcontext.WithAsRemoteCall(true),
+	)
+
+	res, err := validator.ValidateGadget(gadgetCtx, s.runtime.ParamDescs().ToParams(), req.ParamValues)
+	if err != nil {
+		return nil, fmt.Errorf("validating gadget: %w", err)
+	}
+	for _, r := range res {
+		return r, nil
+	}
+	return nil, fmt.Errorf("validating gadget: no result")
+}
+
 func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 	ctrl, err := runGadget.Recv()
 	if err != nil {
 		return err
 	}
 
+	s.udCtrClients.Add(context.Background(), 1)
+	defer s.udCtrClients.Add(context.Background(), -1)
+
 	attachRequest := ctrl.GetAttachRequest()
+
+	// The compressor has to be set before anything is sent on the stream
+	codec := ctrl.GetRunRequest().GetCompression()
+	if attachRequest != nil {
+		codec = attachRequest.Compression
+	}
+	if err := compression.SetSendCompressor(runGadget.Context(), codec); err != nil {
+		s.logger.Warnf("sending uncompressed events: %v", err)
+	}
+
 	if attachRequest != nil {
 		if attachRequest.Version != api.VersionGadgetRunProtocol {
 			return fmt.Errorf("expected version to be %d, got %d", api.VersionGadgetRunProtocol, attachRequest.Version)
@@ -123,7 +173,7 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 		}
 
 		s.ctrAttachGadget.Add(context.Background(), 1)
-		return s.instanceMgr.AttachToGadgetInstance(attachRequest.Id, runGadget)
+		return s.instanceMgr.AttachToGadgetInstance(attachRequest.Id, attachRequest.Since, runGadget)
 	}
 
 	ociRequest := ctrl.GetRunRequest()
@@ -151,6 +201,10 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 		return fmt.Errorf("expected version to be %d, got %d", api.VersionGadgetRunProtocol, ociRequest.Version)
 	}
 
+	if sent, err := s.sendCachedRun(runGadget, ociRequest); sent {
+		return err
+	}
+
 	// Create payload buffer
 	outputBuffer := make(chan *api.GadgetEvent, s.eventBufferLength)
 
@@ -173,10 +227,35 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 	}
 
 	done := make(chan bool)
+	var pump sync.WaitGroup
 	defer func() {
 		close(done)
+		// Let the message pump send what's left in the buffer before the
+		// stream is closed
+		pump.Wait()
 	}()
 
+	seq := uint32(0)
+	var seqLock sync.Mutex
+
+	drops := api.NewDropCounter(uint32(s.eventBufferLength))
+
+	var sealer *integrity.Sealer
+	if ociRequest.ChecksumBatchSize > 0 {
+		sealer = integrity.NewSealer(ociRequest.ChecksumBatchSize, s.checksumKey)
+	}
+	sendChecksum := func(c *api.EventBatchChecksum) {
+		if c == nil {
+			return
+		}
+		d, _ := proto.Marshal(c)
+		select {
+		case outputBuffer <- &api.GadgetEvent{Type: api.EventTypeGadgetChecksum, Payload: d}:
+		default:
+			// The client will report the events of this batch as unverified
+		}
+	}
+
 	// Build a simple operator that subscribes to all events and forwards them
 	svc := simple.New("svc",
 		simple.WithPriority(50000),
@@ -203,21 +282,34 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 				}
 			}()
 
+			pump.Add(1)
 			go func() {
-				// Message pump to handle slow readers
+				defer pump.Done()
+				// Message pump to handle slow readers; the events that don't fit
+				// into the buffer are reported instead of blocking the gadget
+				ticker := time.NewTicker(api.DropsReportInterval)
+				defer ticker.Stop()
 				for {
 					select {
 					case ev := <-outputBuffer:
 						runGadget.Send(ev)
+					case <-ticker.C:
+						if ev := drops.Take(); ev != nil {
+							runGadget.Send(ev)
+						}
 					case <-done:
-						return
+						for {
+							select {
+							case ev := <-outputBuffer:
+								runGadget.Send(ev)
+							default:
+								return
+							}
+						}
 					}
 				}
 			}()
 
-			seq := uint32(0)
-			var seqLock sync.Mutex
-
 			gi, err := gadgetCtx.SerializeGadgetInfo(false)
 			if err != nil {
 				return fmt.Errorf("serializing gadget info: %w", err)
@@ -234,11 +326,30 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 
 			// todo: skip DataSources we're not interested in
 
+			d, _ := proto.Marshal(gi)
+			infoEvent := &api.GadgetEvent{
+				Type:    api.EventTypeGadgetInfo,
+				Payload: d,
+			}
+
+			// Record the events of one-shot gadgets to serve them from the
+			// cache to the following clients
+			var recorder *resultcache.Recorder
+			if s.resultCache != nil {
+				recorder = resultcache.NewRecorder(gi, infoEvent)
+			}
+
 			for _, ds := range gadgetCtx.GetDataSources() {
 				dsID := dsLookup[ds.Name()]
 				ds.SubscribePacket(func(ds datasource.DataSource, packet datasource.Packet) error {
 					d, _ := proto.Marshal(packet.Raw())
 
+					if recorder != nil {
+						if events := recorder.Add(dsID, d); events != nil {
+							s.resultCache.Put(resultcache.Key(ociRequest), events)
+						}
+					}
+
 					event := &api.GadgetEvent{
 						Type:         api.EventTypeGadgetPayload,
 						Payload:      d,
@@ -250,10 +361,15 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 					event.Seq = seq
 
 					// Try to send event; if outputBuffer is full, it will be dropped by taking
-					// the default path.
+					// the default path and counted instead.
 					select {
 					case outputBuffer <- event:
+						if sealer != nil {
+							sendChecksum(sealer.Add(event.Seq, dsID, d))
+						}
 					default:
+						seq--
+						drops.Add(dsID)
 					}
 					seqLock.Unlock()
 					return nil
@@ -261,11 +377,7 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 			}
 
 			// Send gadget information
-			d, _ := proto.Marshal(gi)
-			err = runGadget.Send(&api.GadgetEvent{
-				Type:    api.EventTypeGadgetInfo,
-				Payload: d,
-			})
+			err = runGadget.Send(infoEvent)
 			if err != nil {
 				s.logger.Warnf("sending gadgetInfo: %v", err)
 			}
@@ -289,11 +401,30 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 		gadgetcontext.WithTimeout(time.Duration(ociRequest.Timeout)),
 		gadgetcontext.WithAsRemoteCall(true),
 	)
+	if s.resourceLimits.Enabled() {
+		gadgetCtx.SetVar(operators.ResourceLimitsVarName, s.resourceLimits)
+	}
 
 	runtimeParams := s.runtime.ParamDescs().ToParams()
 	runtimeParams.CopyFromMap(ociRequest.ParamValues, "runtime.")
 
 	err = s.runtime.RunGadget(gadgetCtx, runtimeParams, ociRequest.ParamValues)
+
+	if sealer != nil {
+		// Cover the remaining events; unlike the other checksums, this one
+		// waits for room in the buffer, as no further one would cover them
+		seqLock.Lock()
+		c := sealer.Flush()
+		seqLock.Unlock()
+		if c != nil {
+			d, _ := proto.Marshal(c)
+			select {
+			case outputBuffer <- &api.GadgetEvent{Type: api.EventTypeGadgetChecksum, Payload: d}:
+			case <-runGadget.Context().Done():
+			}
+		}
+	}
+
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,102 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/quota"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
+// SetQuotaLimiter makes the service enforce the quotas and rate limits of l on
+// all requests; by default, nothing is limited
+func (s *Service) SetQuotaLimiter(l *quota.Limiter) {
+	s.quotaLimiter = l
+}
+
+// SetResourceLimits limits the eBPF resources of each gadget run directly;
+// gadget instances are limited by their instance manager
+func (s *Service) SetResourceLimits(limits operators.ResourceLimits) {
+	s.resourceLimits = limits
+}
+
+func (s *Service) checkRate(id *authz.Identity, method string) error {
+	if s.quotaLimiter == nil {
+		return nil
+	}
+	if err := s.quotaLimiter.Allow(id.User); err != nil {
+		s.logger.Debugf("[%s] %s: %v", id, method, err)
+		return err
+	}
+	return nil
+}
+
+// lockInstanceQuota serializes creating or resuming a gadget instance, from
+// checking its quota until the handler stored it, with the other requests
+// counted by the same quotas; otherwise, concurrent requests could all pass the
+// check before any of them is stored. unlock has to be called once the request
+// is handled.
+func (s *Service) lockInstanceQuota(op authz.Operation) (unlock func()) {
+	if s.quotaLimiter == nil || (op != authz.OperationCreate && op != authz.OperationResume) {
+		return func() {}
+	}
+	s.quotaMu.Lock()
+	return s.quotaMu.Unlock
+}
+
+// checkQuota returns an error if op on instance would exceed a quota. If a
+// gadget is run, release has to be called once it stopped. Creating and
+// resuming gadget instances must be done holding lockInstanceQuota.
+func (s *Service) checkQuota(ctx context.Context, id *authz.Identity, method string, op authz.Operation, instance *api.GadgetInstance) (release func(), err error) {
+	release = func() {}
+	if s.quotaLimiter == nil {
+		return release, nil
+	}
+
+	switch op {
+	case authz.OperationRun:
+		// Gadgets run directly are counted as soon as they're acquired, so
+		// it's enough to not interleave with instances being created
+		s.quotaMu.Lock()
+		defer s.quotaMu.Unlock()
+	case authz.OperationCreate, authz.OperationResume:
+	default:
+		return release, nil
+	}
+
+	var instances []*api.GadgetInstance
+	if s.store != nil {
+		resp, err := s.store.ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("listing gadget instances: %w", err)
+		}
+		instances = resp.GadgetInstances
+	}
+
+	if op == authz.OperationRun {
+		release, err = s.quotaLimiter.AcquireRun(id.User, instances)
+	} else {
+		err = s.quotaLimiter.CheckInstance(instances, instance)
+	}
+	if err != nil {
+		s.logger.Warnf("[%s] %s: %v", id, method, err)
+		return nil, err
+	}
+	return release, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/quota"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+// fakeStore keeps gadget instances in memory
+type fakeStore struct {
+	api.UnimplementedGadgetInstanceManagerServer
+
+	mu        sync.Mutex
+	instances []*api.GadgetInstance
+}
+
+func (f *fakeStore) ResumeStoredGadgets() error {
+	return nil
+}
+
+func (f *fakeStore) CreateGadgetInstance(ctx context.Context, req *api.CreateGadgetInstanceRequest) (*api.CreateGadgetInstanceResponse, error) {
+	// Give concurrent requests time to check their quota
+	time.Sleep(10 * time.Millisecond)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.instances = append(f.instances, req.GadgetInstance)
+	return &api.CreateGadgetInstanceResponse{GadgetInstance: req.GadgetInstance}, nil
+}
+
+func (f *fakeStore) ListGadgetInstances(ctx context.Context, req *api.ListGadgetInstancesRequest) (*api.ListGadgetInstanceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &api.ListGadgetInstanceResponse{GadgetInstances: append([]*api.GadgetInstance(nil), f.instances...)}, nil
+}
+
+func TestConcurrentCreateQuota(t *testing.T) {
+	const limit = 3
+
+	st := &fakeStore{}
+	s := NewService(logger.DefaultLogger())
+	s.SetStore(st)
+	s.SetQuotaLimiter(quota.New(quota.Limits{MaxRunningGadgets: limit}))
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/api.GadgetInstanceManager/CreateGadgetInstance"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return st.CreateGadgetInstance(ctx, req.(*api.CreateGadgetInstanceRequest))
+	}
+
+	const requests = 10
+	var wg sync.WaitGroup
+	errs := make([]error, requests)
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := &api.CreateGadgetInstanceRequest{GadgetInstance: &api.GadgetInstance{
+				Id:           fmt.Sprintf("%032x", i),
+				GadgetConfig: &api.GadgetRunRequest{ImageName: "trace_open"},
+			}}
+			_, errs[i] = s.unaryInterceptor(context.Background(), req, info, handler)
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	}
+	assert.Equal(t, limit, created)
+
+	resp, err := st.ListGadgetInstances(context.Background(), &api.ListGadgetInstancesRequest{})
+	require.NoError(t, err)
+	assert.Len(t, resp.GadgetInstances, limit)
+}
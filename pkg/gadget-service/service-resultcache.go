@@ -0,0 +1,92 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/resultcache"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/integrity"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+// SetResultCache enables caching the events of one-shot gadgets
+func (s *Service) SetResultCache(cache *resultcache.Cache) {
+	s.resultCache = cache
+}
+
+// sendCachedRun sends the cached events of a previous run of the requested
+// gadget, if there are any, and returns whether it did
+func (s *Service) sendCachedRun(runGadget api.GadgetManager_RunGadgetServer, req *api.GadgetRunRequest) (bool, error) {
+	if s.resultCache == nil || req.NoCache {
+		return false, nil
+	}
+	events, age, ok := s.resultCache.Get(resultcache.Key(req))
+	if !ok {
+		return false, nil
+	}
+
+	if logger.Level(req.LogLevel) >= logger.DebugLevel {
+		msg := fmt.Sprintf("sending events cached %s ago", age.Round(time.Millisecond))
+		err := runGadget.Send(&api.GadgetEvent{
+			Type:    uint32(logger.DebugLevel) << api.EventLogShift,
+			Payload: []byte(msg),
+		})
+		if err != nil {
+			return true, err
+		}
+	}
+
+	var sealer *integrity.Sealer
+	if req.ChecksumBatchSize > 0 {
+		sealer = integrity.NewSealer(req.ChecksumBatchSize, s.checksumKey)
+	}
+	sendChecksum := func(c *api.EventBatchChecksum) error {
+		if c == nil {
+			return nil
+		}
+		d, _ := proto.Marshal(c)
+		return runGadget.Send(&api.GadgetEvent{Type: api.EventTypeGadgetChecksum, Payload: d})
+	}
+
+	seq := uint32(0)
+	for _, ev := range events {
+		// Cached events are shared between runs, so sequence numbers are
+		// assigned on copies
+		ev = proto.Clone(ev).(*api.GadgetEvent)
+		if ev.Type == api.EventTypeGadgetPayload {
+			seq++
+			ev.Seq = seq
+		}
+		if err := runGadget.Send(ev); err != nil {
+			return true, err
+		}
+		if sealer != nil && ev.Type == api.EventTypeGadgetPayload {
+			if err := sendChecksum(sealer.Add(ev.Seq, ev.DataSourceID, ev.Payload)); err != nil {
+				return true, err
+			}
+		}
+	}
+	if sealer != nil {
+		if err := sendChecksum(sealer.Flush()); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
@@ -16,11 +16,23 @@ package gadgetservice
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/moby/moby/pkg/namesgenerator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
+	instancemanager "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/instance-manager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
 )
 
 func (s *Service) CreateGadgetInstance(ctx context.Context, request *api.CreateGadgetInstanceRequest) (*api.CreateGadgetInstanceResponse, error) {
@@ -42,16 +54,110 @@ func (s *Service) CreateGadgetInstance(ctx context.Context, request *api.CreateG
 	} else if !api.IsValidInstanceName(request.GadgetInstance.Name) {
 		return nil, fmt.Errorf("invalid gadget instance name: %s", request.GadgetInstance.Name)
 	}
+	if err := store.ValidateScheduling(request.GadgetInstance); err != nil {
+		return nil, err
+	}
+	if err := s.checkInstanceName(ctx, request.GadgetInstance); err != nil {
+		return nil, err
+	}
+	if request.GadgetInstance.Ttl < 0 {
+		return nil, fmt.Errorf("invalid gadget instance ttl: %d", request.GadgetInstance.Ttl)
+	}
+	// The TTL counts from the creation time; stores that track it on their own
+	// overwrite it
+	if request.GadgetInstance.TimeCreated == 0 {
+		request.GadgetInstance.TimeCreated = time.Now().Unix()
+	}
 	return s.store.CreateGadgetInstance(ctx, request)
 }
 
+// checkInstanceName makes sure that no other gadget instance uses the name of
+// the given one, so that instances can be looked up by their name
+func (s *Service) checkInstanceName(ctx context.Context, instance *api.GadgetInstance) error {
+	resp, err := s.store.ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{})
+	if err != nil {
+		return fmt.Errorf("listing gadget instances: %w", err)
+	}
+	for _, gi := range resp.GadgetInstances {
+		if gi.Name == instance.Name && gi.Id != instance.Id {
+			return status.Errorf(codes.AlreadyExists, "gadget instance with name %q already exists: %s", instance.Name, gi.Id)
+		}
+	}
+	return nil
+}
+
+// expiryInterval is how often the gadget instances are checked for having
+// exceeded their TTL
+var expiryInterval = 10 * time.Second
+
+// expireGadgetInstances removes gadget instances whose TTL has passed until ctx
+// is done. With stores shared by several replicas, only the leader does it.
+func (s *Service) expireGadgetInstances(ctx context.Context) {
+	cluster, _ := s.store.(store.Cluster)
+
+	ticker := time.NewTicker(expiryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if cluster != nil && !cluster.IsLeader() {
+			continue
+		}
+		s.removeExpiredGadgetInstances(ctx, time.Now())
+	}
+}
+
+func (s *Service) removeExpiredGadgetInstances(ctx context.Context, now time.Time) {
+	resp, err := s.store.ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{})
+	if err != nil {
+		s.logger.Warnf("listing gadget instances to expire: %v", err)
+		return
+	}
+	for _, gi := range resp.GadgetInstances {
+		if !gi.IsExpired(now) {
+			continue
+		}
+		s.logger.Infof("removing gadget instance %q (%s): ttl of %s passed", gi.Id, gi.Name, time.Duration(gi.Ttl)*time.Second)
+		res, err := s.store.RemoveGadgetInstance(ctx, &api.GadgetInstanceId{Id: gi.Id})
+		if err != nil {
+			s.logger.Warnf("removing expired gadget instance %q: %v", gi.Id, err)
+			continue
+		}
+		if res.Result != 0 {
+			// It could have been removed in the meantime
+			s.logger.Debugf("removing expired gadget instance %q: %s", gi.Id, res.Message)
+		}
+	}
+}
+
 func (s *Service) ListGadgetInstances(ctx context.Context, request *api.ListGadgetInstancesRequest) (*api.ListGadgetInstanceResponse, error) {
 	resp, err := s.store.ListGadgetInstances(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("listing gadget instances: %w", err)
 	}
+	resp.GadgetInstances = slices.DeleteFunc(resp.GadgetInstances, func(gi *api.GadgetInstance) bool {
+		return !gi.MatchesSelector(request.Selector)
+	})
+	if s.authorizer != nil {
+		// Only return the instances of the images the caller may list
+		id, ok := authz.FromContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, "listing gadget instances: unknown caller")
+		}
+		resp.GadgetInstances = slices.DeleteFunc(resp.GadgetInstances, func(gi *api.GadgetInstance) bool {
+			return !s.authorizer.Allowed(id, authz.OperationList, gi.GetGadgetConfig().GetImageName())
+		})
+	}
 	for _, gi := range resp.GadgetInstances {
 		st, err := s.instanceMgr.InstanceState(gi.Id)
+		if errors.Is(err, instancemanager.ErrNotFound) {
+			// The instance wasn't started on this node (yet); leave its state
+			// empty so clients can tell
+			continue
+		}
 		if err != nil {
 			return nil, fmt.Errorf("getting instance status for %q: %w", gi.Id, err)
 		}
@@ -82,3 +188,65 @@ func (s *Service) RemoveGadgetInstance(ctx context.Context, id *api.GadgetInstan
 	}
 	return s.store.RemoveGadgetInstance(ctx, id)
 }
+
+func (s *Service) PauseGadgetInstance(ctx context.Context, id *api.GadgetInstanceId) (*api.StatusResponse, error) {
+	if !api.IsValidInstanceID(id.Id) {
+		return nil, fmt.Errorf("invalid gadget instance id: %s", id.Id)
+	}
+	return s.store.PauseGadgetInstance(ctx, id)
+}
+
+func (s *Service) ResumeGadgetInstance(ctx context.Context, id *api.GadgetInstanceId) (*api.StatusResponse, error) {
+	if !api.IsValidInstanceID(id.Id) {
+		return nil, fmt.Errorf("invalid gadget instance id: %s", id.Id)
+	}
+	return s.store.ResumeGadgetInstance(ctx, id)
+}
+
+func (s *Service) UpdateGadgetInstance(ctx context.Context, req *api.UpdateGadgetInstanceRequest) (*api.UpdateGadgetInstanceResponse, error) {
+	if !api.IsValidInstanceID(req.Id) {
+		return nil, fmt.Errorf("invalid gadget instance id: %s", req.Id)
+	}
+	instance, err := s.store.GetGadgetInstance(ctx, &api.GadgetInstanceId{Id: req.Id})
+	if err != nil {
+		return nil, fmt.Errorf("getting gadget instance from store: %w", err)
+	}
+	paramValues := maps.Clone(instance.GadgetConfig.ParamValues)
+	if paramValues == nil {
+		paramValues = make(map[string]string, len(req.ParamValues))
+	}
+	maps.Copy(paramValues, req.ParamValues)
+	if err := s.validateParamValues(ctx, instance.GadgetConfig.ImageName, paramValues); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validating params: %v", err)
+	}
+	return s.store.UpdateGadgetInstance(ctx, req)
+}
+
+// validateParamValues prepares the gadget with the given param values and
+// checks them against its params, so that invalid values aren't stored
+func (s *Service) validateParamValues(ctx context.Context, imageName string, paramValues api.ParamValues) error {
+	ops := make([]operators.DataOperator, 0, len(s.operators))
+	for op := range s.operators {
+		ops = append(ops, op)
+	}
+	gadgetCtx := gadgetcontext.New(
+		ctx,
+		imageName,
+		gadgetcontext.WithDataOperators(ops...),
+		gadgetcontext.WithAsRemoteCall(true),
+	)
+	gi, err := s.runtime.GetGadgetInfo(gadgetCtx, s.runtime.ParamDescs().ToParams(), paramValues)
+	if err != nil {
+		return err
+	}
+	for _, p := range gi.Params {
+		v := paramValues[p.Prefix+p.Key]
+		if v == "" {
+			continue
+		}
+		if err := apihelpers.ParamToParamDesc(p).Validate(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
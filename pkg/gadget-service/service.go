@@ -18,11 +18,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"go.opentelemetry.io/otel/metric"
 	"google.golang.org/grpc"
@@ -31,14 +35,21 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/audit"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
 	instancemanager "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/instance-manager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/quota"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/resultcache"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/metrics"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/nodeinfo"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/overhead"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/local"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/experimental"
 )
 
@@ -66,6 +77,16 @@ type Service struct {
 	logger            logger.Logger
 	servers           map[*grpc.Server]struct{}
 	eventBufferLength uint64
+	checksumKey       []byte
+	debugShell        DebugShellConfig
+	authorizer        *authz.Authorizer
+	auditor           audit.Auditor
+	quotaLimiter      *quota.Limiter
+	quotaMu           sync.Mutex
+	resourceLimits    operators.ResourceLimits
+	resultCache       *resultcache.Cache
+	gateway           GatewayConfig
+	gatewayServer     *http.Server
 
 	// operators stores all global parameters for DataOperators (non-legacy)
 	operators map[operators.DataOperator]*params.Params
@@ -74,6 +95,10 @@ type Service struct {
 	ctrGetGadgetInfo metric.Int64Counter
 	ctrRunGadget     metric.Int64Counter
 	ctrAttachGadget  metric.Int64Counter
+	udCtrClients     metric.Int64UpDownCounter
+
+	metricsAddress string
+	metricsServer  *http.Server
 }
 
 func NewService(defaultLogger logger.Logger) *Service {
@@ -100,6 +125,10 @@ func NewService(defaultLogger logger.Logger) *Service {
 		metric.WithUnit("{request}"),
 		metric.WithDescription("Number of RunGadget()/Attach gRPC requests"),
 	)
+	svc.udCtrClients, _ = metrics.Int64UpDownCounter("ig_grpc_clients",
+		metric.WithUnit("{client}"),
+		metric.WithDescription("Number of clients receiving events from RunGadget()"),
+	)
 
 	return svc
 }
@@ -108,6 +137,12 @@ func (s *Service) SetEventBufferLength(val uint64) {
 	s.eventBufferLength = val
 }
 
+// SetChecksumKey sets the key used to sign the checksums of event batches
+// requested by clients; without key, plain digests are sent
+func (s *Service) SetChecksumKey(key []byte) {
+	s.checksumKey = key
+}
+
 func (s *Service) SetInstanceManager(mgr *instancemanager.Manager) {
 	s.instanceMgr = mgr
 	mgr.Service = s
@@ -125,6 +160,19 @@ func (s *Service) GetInfo(ctx context.Context, request *api.InfoRequest) (*api.I
 	}, nil
 }
 
+func (s *Service) GetNodeInfo(ctx context.Context, request *api.NodeInfoRequest) (*api.NodeInfo, error) {
+	return nodeinfo.Collect(request), nil
+}
+
+func (s *Service) GetNodeOverhead(ctx context.Context, request *api.NodeOverheadRequest) (*api.NodeOverhead, error) {
+	var instances func() []overhead.InstanceEvents
+	if s.instanceMgr != nil {
+		instances = s.instanceMgr.InstanceEvents
+	}
+	interval := time.Duration(request.IntervalMs) * time.Millisecond
+	return overhead.Collect(ctx, interval, instances)
+}
+
 func newUnixListener(address string, gid int) (net.Listener, error) {
 	if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("removing existing unix socket at %q: %w", address, err)
@@ -213,7 +261,8 @@ func (s *Service) Run(runConfig RunConfig, serverOptions ...grpc.ServerOption) e
 		return fmt.Errorf("invalid socket type: %s", runConfig.SocketType)
 	}
 
-	server := grpc.NewServer(serverOptions...)
+	serverOptions = append(serverOptions, tracing.ServerOption())
+	server := grpc.NewServer(append(serverOptions, s.interceptorServerOptions()...)...)
 	api.RegisterBuiltInGadgetManagerServer(server, s)
 	api.RegisterGadgetManagerServer(server, s)
 
@@ -228,19 +277,49 @@ func (s *Service) Run(runConfig RunConfig, serverOptions ...grpc.ServerOption) e
 		return fmt.Errorf("initializing operators: %w", err)
 	}
 
+	if s.gateway.Address != "" {
+		if err := s.startGateway(); err != nil {
+			return err
+		}
+	}
+
+	if s.metricsAddress != "" {
+		if err := s.startMetrics(); err != nil {
+			return err
+		}
+	}
+
 	if s.store != nil {
 		err = s.store.ResumeStoredGadgets()
 		if err != nil {
 			return fmt.Errorf("loading stored gadgets: %w", err)
 		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go s.expireGadgetInstances(ctx)
 	}
 
 	return server.Serve(s.listener)
 }
 
 func (s *Service) Close() {
+	if s.gatewayServer != nil {
+		s.gatewayServer.Close()
+	}
+	if s.metricsServer != nil {
+		s.metricsServer.Close()
+	}
 	for server := range s.servers {
 		server.Stop()
 		delete(s.servers, server)
 	}
+	if closer, ok := s.store.(io.Closer); ok {
+		closer.Close()
+	}
+	for op := range s.operators {
+		if closer, ok := op.(io.Closer); ok {
+			closer.Close()
+		}
+	}
 }
@@ -0,0 +1,291 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	rangePath     = "/v3/kv/range"
+	putPath       = "/v3/kv/put"
+	txnPath       = "/v3/kv/txn"
+	grantPath     = "/v3/lease/grant"
+	keepAlivePath = "/v3/lease/keepalive"
+	revokePath    = "/v3/lease/revoke"
+)
+
+// client talks to etcd using the JSON gateway of its v3 API, trying the
+// endpoints in order until one of them answers. Transactions aren't
+// idempotent, so they're only sent to the next endpoint if the previous one
+// couldn't even be connected to.
+type client struct {
+	endpoints  []string
+	httpClient *http.Client
+}
+
+type keyValue struct {
+	Key         []byte `json:"key"`
+	Value       []byte `json:"value"`
+	ModRevision int64  `json:"mod_revision,string"`
+	Lease       int64  `json:"lease,string,omitempty"`
+}
+
+type rangeRequest struct {
+	Key      []byte `json:"key"`
+	RangeEnd []byte `json:"range_end,omitempty"`
+}
+
+type rangeResponse struct {
+	Kvs []*keyValue `json:"kvs"`
+}
+
+type putRequest struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+	// Lease deletes the key when it expires
+	Lease int64 `json:"lease,string,omitempty"`
+}
+
+type leaseRequest struct {
+	ID  int64 `json:"ID,string,omitempty"`
+	TTL int64 `json:"TTL,string,omitempty"`
+}
+
+type leaseResponse struct {
+	ID  int64 `json:"ID,string"`
+	TTL int64 `json:"TTL,string"`
+}
+
+type keepAliveResponse struct {
+	Result *leaseResponse `json:"result"`
+}
+
+type compare struct {
+	Key            []byte `json:"key"`
+	Result         string `json:"result"`
+	Target         string `json:"target"`
+	CreateRevision int64  `json:"create_revision,string,omitempty"`
+	ModRevision    int64  `json:"mod_revision,string,omitempty"`
+}
+
+type requestOp struct {
+	RequestPut         *putRequest   `json:"request_put,omitempty"`
+	RequestDeleteRange *rangeRequest `json:"request_delete_range,omitempty"`
+}
+
+type txnRequest struct {
+	Compare []*compare   `json:"compare"`
+	Success []*requestOp `json:"success"`
+}
+
+type txnResponse struct {
+	Succeeded bool `json:"succeeded"`
+}
+
+// prefixEnd returns the end of the range of keys starting with prefix
+func prefixEnd(prefix []byte) []byte {
+	end := bytes.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// All keys
+	return []byte{0}
+}
+
+func (c *client) list(ctx context.Context, prefix string) ([]*keyValue, error) {
+	resp := &rangeResponse{}
+	err := c.do(ctx, rangePath, &rangeRequest{
+		Key:      []byte(prefix),
+		RangeEnd: prefixEnd([]byte(prefix)),
+	}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Kvs, nil
+}
+
+func (c *client) get(ctx context.Context, key string) (*keyValue, error) {
+	resp := &rangeResponse{}
+	if err := c.do(ctx, rangePath, &rangeRequest{Key: []byte(key)}, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	return resp.Kvs[0], nil
+}
+
+// create stores all the values in a single transaction if none of the keys
+// exist yet; it returns false if any of them does
+func (c *client) create(ctx context.Context, kvs ...*putRequest) (bool, error) {
+	req := &txnRequest{}
+	for _, kv := range kvs {
+		req.Compare = append(req.Compare, &compare{
+			Key:            kv.Key,
+			Result:         "EQUAL",
+			Target:         "CREATE",
+			CreateRevision: 0,
+		})
+		req.Success = append(req.Success, &requestOp{RequestPut: kv})
+	}
+	resp := &txnResponse{}
+	if err := c.do(ctx, txnPath, req, resp); err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// update stores the value if the key exists and wasn't modified since
+// modRevision; it returns false otherwise
+func (c *client) update(ctx context.Context, key string, value []byte, modRevision int64) (bool, error) {
+	resp := &txnResponse{}
+	err := c.do(ctx, txnPath, &txnRequest{
+		Compare: []*compare{{
+			Key:         []byte(key),
+			Result:      "EQUAL",
+			Target:      "MOD",
+			ModRevision: modRevision,
+		}},
+		Success: []*requestOp{{
+			RequestPut: &putRequest{Key: []byte(key), Value: value},
+		}},
+	}, resp)
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// delete removes the key together with the other keys in a single
+// transaction if the key wasn't modified since modRevision; it returns false
+// otherwise
+func (c *client) delete(ctx context.Context, key string, modRevision int64, others ...string) (bool, error) {
+	req := &txnRequest{
+		Compare: []*compare{{
+			Key:         []byte(key),
+			Result:      "EQUAL",
+			Target:      "MOD",
+			ModRevision: modRevision,
+		}},
+	}
+	for _, k := range append([]string{key}, others...) {
+		req.Success = append(req.Success, &requestOp{
+			RequestDeleteRange: &rangeRequest{Key: []byte(k)},
+		})
+	}
+	resp := &txnResponse{}
+	if err := c.do(ctx, txnPath, req, resp); err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// put stores the value unconditionally
+func (c *client) put(ctx context.Context, kv *putRequest) error {
+	return c.do(ctx, putPath, kv, &struct{}{})
+}
+
+// grant creates a lease expiring after ttl seconds unless it's kept alive
+func (c *client) grant(ctx context.Context, ttl int64) (int64, error) {
+	resp := &leaseResponse{}
+	if err := c.do(ctx, grantPath, &leaseRequest{TTL: ttl}, resp); err != nil {
+		return 0, err
+	}
+	return resp.ID, nil
+}
+
+// keepAlive renews the lease; it returns false if the lease already expired
+func (c *client) keepAlive(ctx context.Context, id int64) (bool, error) {
+	resp := &keepAliveResponse{}
+	if err := c.do(ctx, keepAlivePath, &leaseRequest{ID: id}, resp); err != nil {
+		return false, err
+	}
+	return resp.Result != nil && resp.Result.TTL > 0, nil
+}
+
+// revoke expires the lease right away, deleting the keys attached to it
+func (c *client) revoke(ctx context.Context, id int64) error {
+	return c.do(ctx, revokePath, &leaseRequest{ID: id}, &struct{}{})
+}
+
+func (c *client) do(ctx context.Context, path string, req any, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+
+	var errs []error
+	for _, endpoint := range c.endpoints {
+		err := c.doEndpoint(ctx, strings.TrimSuffix(endpoint, "/")+path, body, resp)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+		// The transaction may have been applied even if the answer got lost,
+		// so it can only be retried if it was never sent
+		if path == txnPath && !isDialError(err) {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isDialError returns whether err comes from failing to connect, meaning no
+// request was sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (c *client) doEndpoint(ctx context.Context, url string, body []byte, resp any) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %q: %s", httpResp.Status, bytes.TrimSpace(respBody))
+	}
+	if err := json.Unmarshal(respBody, resp); err != nil {
+		return fmt.Errorf("unmarshaling response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store"
+)
+
+var _ store.Cluster = (*Store)(nil)
+
+// IsLeader returns whether this replica holds the leader key. It stops being
+// the leader once its lease could have expired, even if etcd can't be reached
+// to find out.
+func (s *Store) IsLeader() bool {
+	s.leaderMu.Lock()
+	defer s.leaderMu.Unlock()
+	return time.Now().Before(s.leaderUntil)
+}
+
+func (s *Store) setLeaderUntil(t time.Time) {
+	s.leaderMu.Lock()
+	defer s.leaderMu.Unlock()
+	s.leaderUntil = t
+}
+
+// SetGatewayAddress sets the address of the HTTP gateway registered for this
+// replica
+func (s *Store) SetGatewayAddress(address string) {
+	s.gatewayAddress = address
+}
+
+// Replica returns the name of the node of this replica
+func (s *Store) Replica() string {
+	return s.node.Name
+}
+
+// InstanceReplicas returns the registered replicas whose node matches the
+// gadget instance; there are none if it's paused
+func (s *Store) InstanceReplicas(ctx context.Context, id string) ([]store.Replica, error) {
+	instance, err := s.GetGadgetInstance(ctx, &api.GadgetInstanceId{Id: id})
+	if err != nil {
+		return nil, err
+	}
+	if instance.Paused {
+		return nil, nil
+	}
+
+	kvs, err := s.client.list(ctx, s.replicasPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing replicas: %w", err)
+	}
+	// Replicas are sorted by name, as their keys are
+	var replicas []store.Replica
+	for _, kv := range kvs {
+		var replica store.Replica
+		if err := json.Unmarshal(kv.Value, &replica); err != nil {
+			log.Warnf("could not read replica %q: %v", kv.Key, err)
+			continue
+		}
+		node := store.Node{Name: replica.Name, Labels: replica.Labels}
+		shouldRun, err := node.ShouldRun(instance)
+		if err != nil {
+			return nil, fmt.Errorf("scheduling gadget instance %q: %w", id, err)
+		}
+		if shouldRun {
+			replicas = append(replicas, replica)
+		}
+	}
+	return replicas, nil
+}
+
+// heartbeat keeps the lease of this replica alive and campaigns for the
+// leadership until ctx is done. The lease is revoked then, so that another
+// replica takes over right away.
+func (s *Store) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(s.leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		renewCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		if err := s.renew(renewCtx); err != nil && ctx.Err() == nil {
+			log.Warnf("renewing the lease of replica %q in etcd: %v", s.node.Name, err)
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			s.resign()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renew keeps the lease alive, getting a new one if it expired, makes sure
+// this replica is registered and becomes the leader if there's none
+func (s *Store) renew(ctx context.Context) error {
+	start := time.Now()
+
+	alive := false
+	if s.lease != 0 {
+		var err error
+		alive, err = s.client.keepAlive(ctx, s.lease)
+		if err != nil {
+			return fmt.Errorf("keeping lease alive: %w", err)
+		}
+		if !alive {
+			log.Warnf("lease of replica %q expired, registering it again", s.node.Name)
+		}
+	}
+	if !alive {
+		s.setLeaderUntil(time.Time{})
+		lease, err := s.client.grant(ctx, int64(math.Ceil(s.leaseTTL.Seconds())))
+		if err != nil {
+			return fmt.Errorf("granting lease: %w", err)
+		}
+		s.lease = lease
+		s.registered = false
+	}
+
+	if !s.registered {
+		blob, err := json.Marshal(&store.Replica{
+			Name:           s.node.Name,
+			Labels:         s.node.Labels,
+			GatewayAddress: s.gatewayAddress,
+		})
+		if err != nil {
+			return fmt.Errorf("marshaling replica: %w", err)
+		}
+		err = s.client.put(ctx, &putRequest{Key: []byte(s.replicaKey(s.node.Name)), Value: blob, Lease: s.lease})
+		if err != nil {
+			return fmt.Errorf("registering replica: %w", err)
+		}
+		s.registered = true
+	}
+
+	// The leader key is attached to the lease of the leader, so it's deleted
+	// when the leader stops renewing it
+	kv, err := s.client.get(ctx, s.leaderKey)
+	if err != nil {
+		return fmt.Errorf("getting leader: %w", err)
+	}
+	leader := kv != nil && kv.Lease == s.lease
+	if kv == nil {
+		leader, err = s.client.create(ctx, &putRequest{Key: []byte(s.leaderKey), Value: []byte(s.node.Name), Lease: s.lease})
+		if err != nil {
+			return fmt.Errorf("campaigning for leader: %w", err)
+		}
+	}
+
+	wasLeader := s.IsLeader()
+	if leader {
+		// The lease lasts at least leaseTTL from when it was renewed
+		s.setLeaderUntil(start.Add(s.leaseTTL))
+	} else {
+		s.setLeaderUntil(time.Time{})
+	}
+	if leader && !wasLeader {
+		log.Infof("replica %q is now the leader", s.node.Name)
+	} else if !leader && wasLeader {
+		log.Infof("replica %q is no longer the leader", s.node.Name)
+	}
+	return nil
+}
+
+// resign revokes the lease, which removes the registration of this replica and
+// its leader key if it holds it
+func (s *Store) resign() {
+	if s.lease == 0 {
+		return
+	}
+	s.setLeaderUntil(time.Time{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	if err := s.client.revoke(ctx, s.lease); err != nil {
+		log.Warnf("revoking the lease of replica %q in etcd: %v", s.node.Name, err)
+	}
+	s.lease = 0
+	s.registered = false
+}
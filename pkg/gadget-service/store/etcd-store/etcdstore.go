@@ -0,0 +1,547 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package etcdstore stores gadget instances in etcd, so that several replicas
+// can share them. Every replica periodically looks for changes and runs the
+// instances meant for its node. Replicas register themselves with a lease,
+// which also backs the election of the leader reconciling the shared state.
+package etcdstore
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	instancemanager "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/instance-manager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store"
+	gadgettls "github.com/inspektor-gadget/inspektor-gadget/pkg/utils/tls"
+)
+
+const (
+	// Name is the name of the backend to use in the configuration
+	Name = "etcd"
+
+	EndpointsKey    = "endpoints"
+	PrefixKey       = "prefix"
+	PollIntervalKey = "poll-interval"
+	TimeoutKey      = "timeout"
+	TLSCertKey      = "tls-cert-file"
+	TLSKeyKey       = "tls-key-file"
+	TLSCAKey        = "tls-ca-file"
+	NodeLabelsKey   = "node-labels"
+	LeaseTTLKey     = "lease-ttl"
+
+	DefaultPrefix       = "/inspektor-gadget/instances/"
+	DefaultPollInterval = 2 * time.Second
+	DefaultTimeout      = 5 * time.Second
+	DefaultLeaseTTL     = 15 * time.Second
+)
+
+func init() {
+	store.RegisterBackend(Name, func(mgr *instancemanager.Manager, cfg *viper.Viper) (store.Store, error) {
+		opts, err := optionsFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return New(mgr, opts)
+	})
+}
+
+type Options struct {
+	Endpoints    []string
+	Prefix       string
+	PollInterval time.Duration
+	Timeout      time.Duration
+	TLSConfig    *tls.Config
+
+	// NodeLabels are matched against the node selector of gadget instances
+	NodeLabels map[string]string
+
+	// LeaseTTL is how long the registration and the leadership of a replica
+	// outlive it when it stops renewing them, e.g. because it crashed
+	LeaseTTL time.Duration
+}
+
+func optionsFromConfig(cfg *viper.Viper) (*Options, error) {
+	key := func(setting string) string {
+		return store.SettingKey(Name, setting)
+	}
+
+	opts := &Options{
+		Endpoints:    cfg.GetStringSlice(key(EndpointsKey)),
+		Prefix:       cfg.GetString(key(PrefixKey)),
+		PollInterval: cfg.GetDuration(key(PollIntervalKey)),
+		Timeout:      cfg.GetDuration(key(TimeoutKey)),
+		NodeLabels:   cfg.GetStringMapString(key(NodeLabelsKey)),
+		LeaseTTL:     cfg.GetDuration(key(LeaseTTLKey)),
+	}
+
+	cert, certKey, ca := cfg.GetString(key(TLSCertKey)), cfg.GetString(key(TLSKeyKey)), cfg.GetString(key(TLSCAKey))
+	if cert != "" || certKey != "" || ca != "" {
+		opts.TLSConfig = &tls.Config{}
+		if cert != "" || certKey != "" {
+			c, err := gadgettls.LoadTLSCert(cert, certKey)
+			if err != nil {
+				return nil, fmt.Errorf("creating TLS certificate: %w", err)
+			}
+			opts.TLSConfig.Certificates = []tls.Certificate{c}
+		}
+		if ca != "" {
+			pool, err := gadgettls.LoadTLSCA(ca)
+			if err != nil {
+				return nil, fmt.Errorf("creating TLS certificate authority: %w", err)
+			}
+			opts.TLSConfig.RootCAs = pool
+		}
+	}
+	return opts, nil
+}
+
+type Store struct {
+	api.UnimplementedGadgetInstanceManagerServer
+
+	client         *client
+	prefix         string
+	namesPrefix    string
+	replicasPrefix string
+	leaderKey      string
+	pollInterval   time.Duration
+	timeout        time.Duration
+	leaseTTL       time.Duration
+	node           store.Node
+	gatewayAddress string
+
+	runGadget    func(*api.GadgetInstance)
+	updateGadget func(*api.GadgetInstance) error
+	removeGadget func(id string) error
+
+	mu sync.Mutex
+	// revisions holds the revision of the instances currently run by this
+	// replica
+	revisions map[string]int64
+	syncNow   chan struct{}
+
+	// lease keeps the registration of this replica, and its leadership if
+	// it holds the leader key, alive. It's only used by heartbeat.
+	lease      int64
+	registered bool
+
+	leaderMu sync.Mutex
+	// leaderUntil is when the leadership of this replica ends unless it's
+	// renewed; it's zero if another replica is the leader
+	leaderUntil time.Time
+
+	// cancel stops the sync loop started by ResumeStoredGadgets, done is
+	// closed once it returned
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(mgr *instancemanager.Manager, opts *Options) (*Store, error) {
+	s, err := newStore(opts)
+	if err != nil {
+		return nil, err
+	}
+	s.runGadget = mgr.RunGadget
+	s.updateGadget = mgr.UpdateGadget
+	s.removeGadget = mgr.RemoveGadget
+	return s, nil
+}
+
+func newStore(opts *Options) (*Store, error) {
+	if len(opts.Endpoints) == 0 {
+		return nil, fmt.Errorf("no etcd endpoints given, set %s", store.SettingKey(Name, EndpointsKey))
+	}
+	for _, endpoint := range opts.Endpoints {
+		if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+			return nil, fmt.Errorf("invalid etcd endpoint %q: expected http:// or https://", endpoint)
+		}
+	}
+
+	s := &Store{
+		prefix:       opts.Prefix,
+		pollInterval: opts.PollInterval,
+		timeout:      opts.Timeout,
+		revisions:    make(map[string]int64),
+		syncNow:      make(chan struct{}, 1),
+	}
+	if s.prefix == "" {
+		s.prefix = DefaultPrefix
+	}
+	if !strings.HasSuffix(s.prefix, "/") {
+		s.prefix += "/"
+	}
+	// Names are reserved and replicas registered next to the instances,
+	// outside of their range
+	s.namesPrefix = strings.TrimSuffix(s.prefix, "/") + ".names/"
+	s.replicasPrefix = strings.TrimSuffix(s.prefix, "/") + ".replicas/"
+	s.leaderKey = strings.TrimSuffix(s.prefix, "/") + ".leader"
+	if s.pollInterval <= 0 {
+		s.pollInterval = DefaultPollInterval
+	}
+	if s.timeout <= 0 {
+		s.timeout = DefaultTimeout
+	}
+	s.leaseTTL = opts.LeaseTTL
+	if s.leaseTTL <= 0 {
+		s.leaseTTL = DefaultLeaseTTL
+	}
+	if s.leaseTTL < time.Second {
+		return nil, fmt.Errorf("invalid lease ttl %s: etcd leases last at least one second", s.leaseTTL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = opts.TLSConfig
+	s.client = &client{
+		endpoints:  opts.Endpoints,
+		httpClient: &http.Client{Transport: transport, Timeout: s.timeout},
+	}
+
+	s.node.Name = os.Getenv("NODE_NAME")
+	if s.node.Name == "" {
+		s.node.Name, _ = os.Hostname()
+	}
+	s.node.Labels = opts.NodeLabels
+
+	log.Infof("initializing etcd store for node %q using %v", s.node.Name, opts.Endpoints)
+	return s, nil
+}
+
+func (s *Store) key(id string) string {
+	return s.prefix + id
+}
+
+func (s *Store) nameKey(name string) string {
+	return s.namesPrefix + name
+}
+
+func (s *Store) replicaKey(name string) string {
+	return s.replicasPrefix + name
+}
+
+func (s *Store) list(ctx context.Context) (map[string]*api.GadgetInstance, map[string]int64, error) {
+	kvs, err := s.client.list(ctx, s.prefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing gadget instances: %w", err)
+	}
+	instances := make(map[string]*api.GadgetInstance, len(kvs))
+	revisions := make(map[string]int64, len(kvs))
+	for _, kv := range kvs {
+		instance := &api.GadgetInstance{}
+		if err := protojson.Unmarshal(kv.Value, instance); err != nil {
+			log.Warnf("could not read gadget instance %q: %v", kv.Key, err)
+			continue
+		}
+		instances[instance.Id] = instance
+		revisions[instance.Id] = kv.ModRevision
+	}
+	return instances, revisions, nil
+}
+
+func (s *Store) triggerSync() {
+	select {
+	case s.syncNow <- struct{}{}:
+	default:
+	}
+}
+
+// CreateGadgetInstance stores the gadget instance in etcd; the replicas start
+// it when they see it. Its name is reserved in the same transaction, so that
+// concurrent creations can't use the same name.
+func (s *Store) CreateGadgetInstance(ctx context.Context, req *api.CreateGadgetInstanceRequest) (*api.CreateGadgetInstanceResponse, error) {
+	req.GadgetInstance.TimeCreated = time.Now().Unix()
+	blob, err := protojson.Marshal(req.GadgetInstance)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling gadget instance: %w", err)
+	}
+
+	kvs := []*putRequest{{Key: []byte(s.key(req.GadgetInstance.Id)), Value: blob}}
+	if req.GadgetInstance.Name != "" {
+		kvs = append(kvs, &putRequest{
+			Key:   []byte(s.nameKey(req.GadgetInstance.Name)),
+			Value: []byte(req.GadgetInstance.Id),
+		})
+	}
+	created, err := s.client.create(ctx, kvs...)
+	if err != nil {
+		return nil, fmt.Errorf("storing gadget instance: %w", err)
+	}
+	if !created {
+		return nil, fmt.Errorf("gadget instance with id %q or name %q already exists",
+			req.GadgetInstance.Id, req.GadgetInstance.Name)
+	}
+
+	s.triggerSync()
+	return &api.CreateGadgetInstanceResponse{
+		Result:         0,
+		GadgetInstance: req.GadgetInstance,
+	}, nil
+}
+
+// ListGadgetInstances lists all gadget instances stored in etcd
+func (s *Store) ListGadgetInstances(ctx context.Context, request *api.ListGadgetInstancesRequest) (*api.ListGadgetInstanceResponse, error) {
+	instances, _, err := s.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*api.GadgetInstance, 0, len(instances))
+	for _, instance := range instances {
+		res = append(res, instance)
+	}
+	slices.SortFunc(res, func(a, b *api.GadgetInstance) int {
+		return strings.Compare(a.Id, b.Id)
+	})
+	return &api.ListGadgetInstanceResponse{GadgetInstances: res}, nil
+}
+
+// GetGadgetInstance returns the configuration of the given gadget instance
+func (s *Store) GetGadgetInstance(ctx context.Context, req *api.GadgetInstanceId) (*api.GadgetInstance, error) {
+	kv, err := s.client.get(ctx, s.key(req.Id))
+	if err != nil {
+		return nil, fmt.Errorf("getting gadget instance: %w", err)
+	}
+	if kv == nil {
+		return nil, fmt.Errorf("gadget instance %q not found", req.Id)
+	}
+	instance := &api.GadgetInstance{}
+	if err := protojson.Unmarshal(kv.Value, instance); err != nil {
+		return nil, fmt.Errorf("unmarshaling gadget instance %q: %w", req.Id, err)
+	}
+	return instance, nil
+}
+
+// RemoveGadgetInstance removes the gadget instance and the reservation of its
+// name from etcd; the replicas stop it when they see it's gone
+func (s *Store) RemoveGadgetInstance(ctx context.Context, req *api.GadgetInstanceId) (*api.StatusResponse, error) {
+	kv, err := s.client.get(ctx, s.key(req.Id))
+	if err != nil {
+		return &api.StatusResponse{Result: 1, Message: err.Error()}, nil
+	}
+	if kv == nil {
+		return &api.StatusResponse{Result: 1, Message: fmt.Sprintf("gadget instance %q not found", req.Id)}, nil
+	}
+	var names []string
+	instance := &api.GadgetInstance{}
+	if err := protojson.Unmarshal(kv.Value, instance); err != nil {
+		log.Warnf("could not read gadget instance %q, keeping its name reserved: %v", req.Id, err)
+	} else if instance.Name != "" {
+		names = append(names, s.nameKey(instance.Name))
+	}
+	deleted, err := s.client.delete(ctx, s.key(req.Id), kv.ModRevision, names...)
+	if err != nil {
+		return &api.StatusResponse{Result: 1, Message: err.Error()}, nil
+	}
+	if !deleted {
+		return &api.StatusResponse{Result: 1, Message: fmt.Sprintf("gadget instance %q was modified concurrently", req.Id)}, nil
+	}
+	s.triggerSync()
+	return &api.StatusResponse{Result: 0}, nil
+}
+
+// PauseGadgetInstance marks the gadget instance as paused in etcd; the replicas
+// stop it when they see the change
+func (s *Store) PauseGadgetInstance(ctx context.Context, req *api.GadgetInstanceId) (*api.StatusResponse, error) {
+	return s.setPaused(ctx, req.Id, true)
+}
+
+// ResumeGadgetInstance removes the paused mark of the gadget instance in etcd;
+// the replicas run it again when they see the change
+func (s *Store) ResumeGadgetInstance(ctx context.Context, req *api.GadgetInstanceId) (*api.StatusResponse, error) {
+	return s.setPaused(ctx, req.Id, false)
+}
+
+func (s *Store) setPaused(ctx context.Context, id string, paused bool) (*api.StatusResponse, error) {
+	_, err := s.modifyInstance(ctx, id, func(instance *api.GadgetInstance) bool {
+		if instance.Paused == paused {
+			return false
+		}
+		instance.Paused = paused
+		return true
+	})
+	if err != nil {
+		return &api.StatusResponse{Result: 1, Message: err.Error()}, nil
+	}
+	return &api.StatusResponse{Result: 0}, nil
+}
+
+// UpdateGadgetInstance merges the given param values into the gadget instance
+// in etcd; the replicas restart it with the new values when they see the
+// change, keeping its buffered events
+func (s *Store) UpdateGadgetInstance(ctx context.Context, req *api.UpdateGadgetInstanceRequest) (*api.UpdateGadgetInstanceResponse, error) {
+	instance, err := s.modifyInstance(ctx, req.Id, func(instance *api.GadgetInstance) bool {
+		if instance.GadgetConfig.ParamValues == nil {
+			instance.GadgetConfig.ParamValues = make(map[string]string, len(req.ParamValues))
+		}
+		maps.Copy(instance.GadgetConfig.ParamValues, req.ParamValues)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.UpdateGadgetInstanceResponse{
+		Result:         0,
+		GadgetInstance: instance,
+	}, nil
+}
+
+// modifyInstance applies fn to the gadget instance stored in etcd and writes it
+// back if fn reports a change; the write fails if the instance was modified
+// concurrently
+func (s *Store) modifyInstance(ctx context.Context, id string, fn func(*api.GadgetInstance) bool) (*api.GadgetInstance, error) {
+	kv, err := s.client.get(ctx, s.key(id))
+	if err != nil {
+		return nil, err
+	}
+	if kv == nil {
+		return nil, fmt.Errorf("gadget instance %q not found", id)
+	}
+	instance := &api.GadgetInstance{}
+	if err := protojson.Unmarshal(kv.Value, instance); err != nil {
+		return nil, fmt.Errorf("unmarshaling gadget instance %q: %w", id, err)
+	}
+	if !fn(instance) {
+		return instance, nil
+	}
+
+	blob, err := protojson.Marshal(instance)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling gadget instance: %w", err)
+	}
+	updated, err := s.client.update(ctx, s.key(id), blob, kv.ModRevision)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, fmt.Errorf("gadget instance %q was modified concurrently", id)
+	}
+	s.triggerSync()
+	return instance, nil
+}
+
+func (s *Store) ResumeStoredGadgets() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx)
+	return nil
+}
+
+// Close stops looking for changes in etcd. The gadget instances already
+// running are left to the instance manager.
+func (s *Store) Close() error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+func (s *Store) run(ctx context.Context) {
+	defer close(s.done)
+
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		s.heartbeat(ctx)
+	}()
+	defer func() { <-heartbeatDone }()
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+	for {
+		syncCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		if err := s.sync(syncCtx); err != nil && ctx.Err() == nil {
+			log.Warnf("syncing gadget instances from etcd: %v", err)
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.syncNow:
+		}
+	}
+}
+
+// sync starts, restarts and stops the gadget instances of this node to match
+// what is stored in etcd
+func (s *Store) sync(ctx context.Context) error {
+	instances, revisions, err := s.list(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.revisions {
+		if _, ok := instances[id]; ok {
+			continue
+		}
+		log.Infof("stopping gadget instance %q", id)
+		if err := s.removeGadget(id); err != nil && !errors.Is(err, instancemanager.ErrNotFound) {
+			log.Warnf("stopping gadget instance %q: %v", id, err)
+		}
+		delete(s.revisions, id)
+	}
+
+	for id, instance := range instances {
+		shouldRun, err := s.node.ShouldRun(instance)
+		if err != nil {
+			log.Warnf("scheduling gadget instance %q: %v", id, err)
+		}
+		if !shouldRun {
+			if _, ok := s.revisions[id]; ok {
+				log.Infof("stopping gadget instance %q, it doesn't match node %q anymore", id, s.node.Name)
+				if err := s.removeGadget(id); err != nil && !errors.Is(err, instancemanager.ErrNotFound) {
+					log.Warnf("stopping gadget instance %q: %v", id, err)
+				}
+				delete(s.revisions, id)
+			}
+			continue
+		}
+		rev, ok := s.revisions[id]
+		if ok && rev == revisions[id] {
+			continue
+		}
+		if ok {
+			// The instance was changed, update it; it keeps running with
+			// the previous configuration if that fails
+			log.Infof("updating gadget instance %q", id)
+			if err := s.updateGadget(instance); err != nil {
+				log.Warnf("updating gadget instance %q: %v", id, err)
+			}
+		} else {
+			log.Infof("starting gadget instance %q", id)
+			s.runGadget(instance)
+		}
+		s.revisions[id] = revisions[id]
+	}
+	return nil
+}
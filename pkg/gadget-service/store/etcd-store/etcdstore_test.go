@@ -0,0 +1,505 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// fakeEtcd implements the subset of the etcd v3 JSON gateway used by the store;
+// leases only expire when revoked
+type fakeEtcd struct {
+	mu       sync.Mutex
+	kvs      map[string]*keyValue
+	revision int64
+	leases   map[int64]bool
+}
+
+// revoke deletes the lease and the keys attached to it
+func (f *fakeEtcd) revoke(id int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.leases, id)
+	for k, kv := range f.kvs {
+		if kv.Lease == id {
+			delete(f.kvs, k)
+		}
+	}
+}
+
+func newFakeEtcd(t *testing.T) *httptest.Server {
+	srv, _ := newFakeEtcdWithLeases(t)
+	return srv
+}
+
+func newFakeEtcdWithLeases(t *testing.T) (*httptest.Server, *fakeEtcd) {
+	f := &fakeEtcd{kvs: make(map[string]*keyValue), leases: make(map[int64]bool)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/kv/range", func(w http.ResponseWriter, r *http.Request) {
+		req := &rangeRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		resp := &rangeResponse{}
+		for k, kv := range f.kvs {
+			if k == string(req.Key) ||
+				(req.RangeEnd != nil && k >= string(req.Key) && bytes.Compare([]byte(k), req.RangeEnd) < 0) {
+				resp.Kvs = append(resp.Kvs, kv)
+			}
+		}
+		sort.Slice(resp.Kvs, func(i, j int) bool { return string(resp.Kvs[i].Key) < string(resp.Kvs[j].Key) })
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("/v3/kv/txn", func(w http.ResponseWriter, r *http.Request) {
+		req := &txnRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for _, cmp := range req.Compare {
+			kv, exists := f.kvs[string(cmp.Key)]
+			switch cmp.Target {
+			case "CREATE":
+				if exists {
+					json.NewEncoder(w).Encode(&txnResponse{Succeeded: false})
+					return
+				}
+			case "MOD":
+				if !exists || kv.ModRevision != cmp.ModRevision {
+					json.NewEncoder(w).Encode(&txnResponse{Succeeded: false})
+					return
+				}
+			}
+		}
+		f.revision++
+		for _, op := range req.Success {
+			if put := op.RequestPut; put != nil {
+				f.kvs[string(put.Key)] = &keyValue{Key: put.Key, Value: put.Value, ModRevision: f.revision, Lease: put.Lease}
+			}
+			if del := op.RequestDeleteRange; del != nil {
+				delete(f.kvs, string(del.Key))
+			}
+		}
+		json.NewEncoder(w).Encode(&txnResponse{Succeeded: true})
+	})
+	mux.HandleFunc("/v3/kv/put", func(w http.ResponseWriter, r *http.Request) {
+		put := &putRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(put))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.revision++
+		f.kvs[string(put.Key)] = &keyValue{Key: put.Key, Value: put.Value, ModRevision: f.revision, Lease: put.Lease}
+		w.Write([]byte("{}"))
+	})
+	mux.HandleFunc("/v3/lease/grant", func(w http.ResponseWriter, r *http.Request) {
+		req := &leaseRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.revision++
+		f.leases[f.revision] = true
+		json.NewEncoder(w).Encode(&leaseResponse{ID: f.revision, TTL: req.TTL})
+	})
+	mux.HandleFunc("/v3/lease/keepalive", func(w http.ResponseWriter, r *http.Request) {
+		req := &leaseRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		resp := &keepAliveResponse{Result: &leaseResponse{ID: req.ID}}
+		if f.leases[req.ID] {
+			resp.Result.TTL = 15
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("/v3/lease/revoke", func(w http.ResponseWriter, r *http.Request) {
+		req := &leaseRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		f.revoke(req.ID)
+		w.Write([]byte("{}"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, f
+}
+
+func newInstance(id, name string, nodes ...string) *api.CreateGadgetInstanceRequest {
+	return &api.CreateGadgetInstanceRequest{
+		GadgetInstance: &api.GadgetInstance{
+			Id:    id,
+			Name:  name,
+			Nodes: nodes,
+			GadgetConfig: &api.GadgetRunRequest{
+				ImageName: "trace_exec",
+				Version:   api.VersionGadgetRunProtocol,
+			},
+		},
+	}
+}
+
+func TestEtcdStore(t *testing.T) {
+	srv := newFakeEtcd(t)
+	ctx := context.Background()
+
+	s, err := newStore(&Options{Endpoints: []string{"http://127.0.0.1:1", srv.URL}})
+	require.NoError(t, err)
+	s.node.Name = "node1"
+
+	running := map[string]bool{}
+	s.runGadget = func(instance *api.GadgetInstance) { running[instance.Id] = true }
+	s.removeGadget = func(id string) error {
+		delete(running, id)
+		return nil
+	}
+	updated := map[string]int{}
+	s.updateGadget = func(instance *api.GadgetInstance) error {
+		updated[instance.Id]++
+		s.runGadget(instance)
+		return nil
+	}
+
+	_, err = s.CreateGadgetInstance(ctx, newInstance("aaa", "first"))
+	require.NoError(t, err)
+	_, err = s.CreateGadgetInstance(ctx, newInstance("bbb", "second", "node2"))
+	require.NoError(t, err)
+
+	_, err = s.CreateGadgetInstance(ctx, newInstance("aaa", "other"))
+	require.ErrorContains(t, err, "already exists")
+	_, err = s.CreateGadgetInstance(ctx, newInstance("ccc", "first"))
+	require.ErrorContains(t, err, "already exists")
+
+	list, err := s.ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GadgetInstances, 2)
+	assert.Equal(t, "aaa", list.GadgetInstances[0].Id)
+	assert.Equal(t, "bbb", list.GadgetInstances[1].Id)
+
+	gi, err := s.GetGadgetInstance(ctx, &api.GadgetInstanceId{Id: "aaa"})
+	require.NoError(t, err)
+	assert.Equal(t, "first", gi.Name)
+	assert.Equal(t, "trace_exec", gi.GadgetConfig.ImageName)
+
+	_, err = s.GetGadgetInstance(ctx, &api.GadgetInstanceId{Id: "zzz"})
+	require.Error(t, err)
+
+	// Only the instances meant for this node are run
+	require.NoError(t, s.sync(ctx))
+	assert.Equal(t, map[string]bool{"aaa": true}, running)
+
+	// Pausing stops the instance and keeps its configuration
+	res, err := s.PauseGadgetInstance(ctx, &api.GadgetInstanceId{Id: "aaa"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), res.Result)
+	gi, err = s.GetGadgetInstance(ctx, &api.GadgetInstanceId{Id: "aaa"})
+	require.NoError(t, err)
+	assert.True(t, gi.Paused)
+	assert.Equal(t, "first", gi.Name)
+
+	paused := map[string]bool{}
+	s.runGadget = func(instance *api.GadgetInstance) {
+		running[instance.Id] = !instance.Paused
+		paused[instance.Id] = instance.Paused
+	}
+	require.NoError(t, s.sync(ctx))
+	assert.Equal(t, map[string]bool{"aaa": false}, running)
+	assert.Equal(t, map[string]bool{"aaa": true}, paused)
+
+	res, err = s.ResumeGadgetInstance(ctx, &api.GadgetInstanceId{Id: "aaa"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), res.Result)
+	require.NoError(t, s.sync(ctx))
+	assert.Equal(t, map[string]bool{"aaa": true}, running)
+
+	res, err = s.PauseGadgetInstance(ctx, &api.GadgetInstanceId{Id: "zzz"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), res.Result)
+
+	// Updating merges the param values and restarts the instance in place
+	upd, err := s.UpdateGadgetInstance(ctx, &api.UpdateGadgetInstanceRequest{
+		Id:          "aaa",
+		ParamValues: map[string]string{"operator.filter.filter": "pid==1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "pid==1", upd.GadgetInstance.GadgetConfig.ParamValues["operator.filter.filter"])
+	gi, err = s.GetGadgetInstance(ctx, &api.GadgetInstanceId{Id: "aaa"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"operator.filter.filter": "pid==1"}, gi.GadgetConfig.ParamValues)
+	require.NoError(t, s.sync(ctx))
+	assert.Equal(t, map[string]int{"aaa": 3}, updated)
+	assert.Equal(t, map[string]bool{"aaa": true}, running)
+
+	_, err = s.UpdateGadgetInstance(ctx, &api.UpdateGadgetInstanceRequest{Id: "zzz"})
+	require.ErrorContains(t, err, "not found")
+
+	res, err = s.RemoveGadgetInstance(ctx, &api.GadgetInstanceId{Id: "aaa"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), res.Result)
+
+	res, err = s.RemoveGadgetInstance(ctx, &api.GadgetInstanceId{Id: "aaa"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), res.Result)
+
+	require.NoError(t, s.sync(ctx))
+	assert.Empty(t, running)
+
+	// The name of a removed instance can be used again
+	_, err = s.CreateGadgetInstance(ctx, newInstance("ddd", "first"))
+	require.NoError(t, err)
+}
+
+func TestEtcdStoreConcurrentCreate(t *testing.T) {
+	srv := newFakeEtcd(t)
+	ctx := context.Background()
+
+	s, err := newStore(&Options{Endpoints: []string{srv.URL}})
+	require.NoError(t, err)
+
+	const n = 10
+	var wg sync.WaitGroup
+	var created atomic.Int32
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.CreateGadgetInstance(ctx, newInstance(fmt.Sprintf("id%d", i), "same"))
+			if err == nil {
+				created.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), created.Load())
+
+	list, err := s.ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GadgetInstances, 1)
+}
+
+func TestEtcdStoreClose(t *testing.T) {
+	srv := newFakeEtcd(t)
+
+	s, err := newStore(&Options{Endpoints: []string{srv.URL}, PollInterval: time.Millisecond})
+	require.NoError(t, err)
+	s.runGadget = func(*api.GadgetInstance) {}
+	s.removeGadget = func(string) error { return nil }
+
+	require.NoError(t, s.ResumeStoredGadgets())
+	require.NoError(t, s.Close())
+
+	select {
+	case <-s.done:
+	default:
+		t.Fatal("sync loop still running after Close")
+	}
+}
+
+func TestEtcdStoreNodeSelector(t *testing.T) {
+	srv := newFakeEtcd(t)
+	ctx := context.Background()
+
+	s, err := newStore(&Options{
+		Endpoints:  []string{srv.URL},
+		NodeLabels: map[string]string{"node-role.kubernetes.io/worker": "true"},
+	})
+	require.NoError(t, err)
+	s.node.Name = "node1"
+
+	running := map[string]bool{}
+	s.runGadget = func(instance *api.GadgetInstance) { running[instance.Id] = true }
+	s.removeGadget = func(id string) error {
+		delete(running, id)
+		return nil
+	}
+
+	worker := newInstance("aaa", "worker")
+	worker.GadgetInstance.NodeSelector = "node-role.kubernetes.io/worker=true"
+	_, err = s.CreateGadgetInstance(ctx, worker)
+	require.NoError(t, err)
+	control := newInstance("bbb", "control")
+	control.GadgetInstance.NodeSelector = "node-role.kubernetes.io/control-plane"
+	_, err = s.CreateGadgetInstance(ctx, control)
+	require.NoError(t, err)
+
+	gi, err := s.GetGadgetInstance(ctx, &api.GadgetInstanceId{Id: "aaa"})
+	require.NoError(t, err)
+	assert.Equal(t, "node-role.kubernetes.io/worker=true", gi.NodeSelector)
+
+	require.NoError(t, s.sync(ctx))
+	assert.Equal(t, map[string]bool{"aaa": true}, running)
+
+	// The instance is started once the node matches its selector and stopped
+	// when it doesn't anymore
+	s.node.Labels = map[string]string{"node-role.kubernetes.io/control-plane": ""}
+	require.NoError(t, s.sync(ctx))
+	assert.Equal(t, map[string]bool{"bbb": true}, running)
+}
+
+func TestEtcdStoreLeaderElection(t *testing.T) {
+	srv, f := newFakeEtcdWithLeases(t)
+	ctx := context.Background()
+
+	newReplica := func(name string) *Store {
+		s, err := newStore(&Options{Endpoints: []string{srv.URL}})
+		require.NoError(t, err)
+		s.node.Name = name
+		return s
+	}
+	s1, s2 := newReplica("node1"), newReplica("node2")
+
+	require.NoError(t, s1.renew(ctx))
+	require.NoError(t, s2.renew(ctx))
+	assert.True(t, s1.IsLeader())
+	assert.False(t, s2.IsLeader())
+
+	// Renewing keeps the leadership
+	require.NoError(t, s1.renew(ctx))
+	require.NoError(t, s2.renew(ctx))
+	assert.True(t, s1.IsLeader())
+	assert.False(t, s2.IsLeader())
+
+	// Another replica takes over once the lease of the leader expires
+	f.revoke(s1.lease)
+	require.NoError(t, s2.renew(ctx))
+	assert.True(t, s2.IsLeader())
+	require.NoError(t, s1.renew(ctx))
+	assert.False(t, s1.IsLeader())
+
+	// or when it stops
+	s2.resign()
+	assert.False(t, s2.IsLeader())
+	require.NoError(t, s1.renew(ctx))
+	assert.True(t, s1.IsLeader())
+
+	// The leadership ends with the lease when etcd can't be reached
+	s1.setLeaderUntil(time.Now())
+	assert.False(t, s1.IsLeader())
+}
+
+func TestEtcdStoreInstanceReplicas(t *testing.T) {
+	srv := newFakeEtcd(t)
+	ctx := context.Background()
+
+	newReplica := func(name string, labels map[string]string) *Store {
+		s, err := newStore(&Options{Endpoints: []string{srv.URL}, NodeLabels: labels})
+		require.NoError(t, err)
+		s.node.Name = name
+		s.SetGatewayAddress(name + ":8443")
+		require.NoError(t, s.renew(ctx))
+		return s
+	}
+	s1 := newReplica("node1", map[string]string{"node-role.kubernetes.io/worker": "true"})
+	newReplica("node2", nil)
+
+	_, err := s1.CreateGadgetInstance(ctx, newInstance("aaa", "all"))
+	require.NoError(t, err)
+	worker := newInstance("bbb", "worker")
+	worker.GadgetInstance.NodeSelector = "node-role.kubernetes.io/worker=true"
+	_, err = s1.CreateGadgetInstance(ctx, worker)
+	require.NoError(t, err)
+
+	replicas, err := s1.InstanceReplicas(ctx, "aaa")
+	require.NoError(t, err)
+	require.Len(t, replicas, 2)
+	assert.Equal(t, "node1", replicas[0].Name)
+	assert.Equal(t, "node2:8443", replicas[1].GatewayAddress)
+
+	replicas, err = s1.InstanceReplicas(ctx, "bbb")
+	require.NoError(t, err)
+	require.Len(t, replicas, 1)
+	assert.Equal(t, "node1", replicas[0].Name)
+
+	// Paused instances run nowhere
+	_, err = s1.PauseGadgetInstance(ctx, &api.GadgetInstanceId{Id: "aaa"})
+	require.NoError(t, err)
+	replicas, err = s1.InstanceReplicas(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Empty(t, replicas)
+
+	_, err = s1.InstanceReplicas(ctx, "zzz")
+	require.Error(t, err)
+}
+
+func TestNewStoreValidation(t *testing.T) {
+	_, err := newStore(&Options{})
+	require.Error(t, err)
+
+	_, err = newStore(&Options{Endpoints: []string{"127.0.0.1:2379"}})
+	require.Error(t, err)
+
+	s, err := newStore(&Options{Endpoints: []string{"http://127.0.0.1:2379"}, Prefix: "/ig"})
+	require.NoError(t, err)
+	assert.Equal(t, "/ig/", s.prefix)
+	assert.Equal(t, DefaultPollInterval, s.pollInterval)
+	assert.Equal(t, DefaultLeaseTTL, s.leaseTTL)
+	assert.Equal(t, "/ig.replicas/", s.replicasPrefix)
+	assert.Equal(t, "/ig.leader", s.leaderKey)
+
+	_, err = newStore(&Options{Endpoints: []string{"http://127.0.0.1:2379"}, LeaseTTL: time.Millisecond})
+	require.Error(t, err)
+}
+
+func TestClientFailover(t *testing.T) {
+	srv := newFakeEtcd(t)
+	ctx := context.Background()
+
+	// The answer of the first endpoint gets lost after it applied the
+	// transaction
+	var lost atomic.Int32
+	lossy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lost.Add(1)
+		http.Error(w, "lost", http.StatusBadGateway)
+	}))
+	t.Cleanup(lossy.Close)
+
+	c := &client{endpoints: []string{lossy.URL, srv.URL}, httpClient: http.DefaultClient}
+
+	// Reads are retried on the next endpoint
+	kv, err := c.get(ctx, "/ig/a")
+	require.NoError(t, err)
+	assert.Nil(t, kv)
+
+	// Transactions aren't, as they may have been applied
+	_, err = c.create(ctx, &putRequest{Key: []byte("/ig/a"), Value: []byte("a")})
+	require.Error(t, err)
+	assert.EqualValues(t, 2, lost.Load())
+	kv, err = c.get(ctx, "/ig/a")
+	require.NoError(t, err)
+	assert.Nil(t, kv)
+
+	// unless the endpoint couldn't be connected to
+	c.endpoints = []string{"http://127.0.0.1:1", srv.URL}
+	created, err := c.create(ctx, &putRequest{Key: []byte("/ig/a"), Value: []byte("a")})
+	require.NoError(t, err)
+	assert.True(t, created)
+}
+
+func TestPrefixEnd(t *testing.T) {
+	assert.Equal(t, []byte("/ig0"), prefixEnd([]byte("/ig/")))
+	assert.Equal(t, []byte("b"), prefixEnd([]byte("a\xff")))
+	assert.Equal(t, []byte{0}, prefixEnd([]byte{0xff}))
+}
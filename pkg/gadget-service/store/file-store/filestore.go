@@ -18,13 +18,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 	instancemanager "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/instance-manager"
@@ -32,10 +35,19 @@ import (
 )
 
 const (
+	// Name is the name of the backend to use in the configuration
+	Name = "file"
+
 	GadgetBaseDir     = "/var/lib/ig"
 	GadgetInstanceDir = "/var/lib/ig/gadgets"
 )
 
+func init() {
+	store.RegisterBackend(Name, func(mgr *instancemanager.Manager, _ *viper.Viper) (store.Store, error) {
+		return New(mgr)
+	})
+}
+
 type FileStore struct {
 	api.GadgetInstanceManagerServer
 	instanceMgr *instancemanager.Manager
@@ -202,3 +214,80 @@ func (s *FileStore) RemoveGadgetInstance(ctx context.Context, request *api.Gadge
 	}
 	return &api.StatusResponse{Result: 0}, nil
 }
+
+// PauseGadgetInstance stops the gadget instance, keeping its configuration so
+// it can be resumed later on
+func (s *FileStore) PauseGadgetInstance(ctx context.Context, request *api.GadgetInstanceId) (*api.StatusResponse, error) {
+	return s.setPaused(request.Id, true)
+}
+
+// ResumeGadgetInstance runs a paused gadget instance again
+func (s *FileStore) ResumeGadgetInstance(ctx context.Context, request *api.GadgetInstanceId) (*api.StatusResponse, error) {
+	return s.setPaused(request.Id, false)
+}
+
+func (s *FileStore) setPaused(id string, paused bool) (*api.StatusResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := filepath.Join(GadgetInstanceDir, fmt.Sprintf("%s.gadget", id))
+	gadget, err := loadGadgetFile(path)
+	if err != nil {
+		return &api.StatusResponse{Result: 1, Message: err.Error()}, nil
+	}
+	if gadget.GadgetInstance.Paused == paused {
+		return &api.StatusResponse{Result: 0}, nil
+	}
+
+	gadget.GadgetInstance.Paused = paused
+	gadgetBlob, _ := protojson.Marshal(gadget)
+	err = os.WriteFile(path, gadgetBlob, 0o644)
+	if err != nil {
+		return &api.StatusResponse{Result: 1, Message: err.Error()}, nil
+	}
+
+	err = s.instanceMgr.RemoveGadget(id)
+	if err != nil && !errors.Is(err, instancemanager.ErrNotFound) {
+		return &api.StatusResponse{Result: 1, Message: err.Error()}, nil
+	}
+	s.instanceMgr.RunGadget(gadget.GadgetInstance)
+	return &api.StatusResponse{Result: 0}, nil
+}
+
+// UpdateGadgetInstance applies new param values to the gadget instance, keeping
+// its ID and buffered events. They are only stored once the instance is
+// running with them; it keeps the previous values otherwise.
+func (s *FileStore) UpdateGadgetInstance(ctx context.Context, req *api.UpdateGadgetInstanceRequest) (*api.UpdateGadgetInstanceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := filepath.Join(GadgetInstanceDir, fmt.Sprintf("%s.gadget", req.Id))
+	gadget, err := loadGadgetFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading gadget: %w", err)
+	}
+	previous := proto.Clone(gadget.GadgetInstance).(*api.GadgetInstance)
+
+	if gadget.GadgetInstance.GadgetConfig.ParamValues == nil {
+		gadget.GadgetInstance.GadgetConfig.ParamValues = make(map[string]string, len(req.ParamValues))
+	}
+	maps.Copy(gadget.GadgetInstance.GadgetConfig.ParamValues, req.ParamValues)
+
+	log.Debugf("updating gadget %q", req.Id)
+	if err := s.instanceMgr.UpdateGadget(gadget.GadgetInstance); err != nil {
+		return nil, fmt.Errorf("updating gadget: %w", err)
+	}
+
+	gadgetBlob, _ := protojson.Marshal(gadget)
+	err = os.WriteFile(path, gadgetBlob, 0o644)
+	if err != nil {
+		if err := s.instanceMgr.UpdateGadget(previous); err != nil {
+			log.Warnf("restoring gadget %q: %v", req.Id, err)
+		}
+		return nil, fmt.Errorf("storing gadget information: %w", err)
+	}
+	return &api.UpdateGadgetInstanceResponse{
+		Result:         0,
+		GadgetInstance: gadget.GadgetInstance,
+	}, nil
+}
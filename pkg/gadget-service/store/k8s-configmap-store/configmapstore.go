@@ -16,17 +16,22 @@ package k8sconfigmapstore
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -34,30 +39,61 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/config/gadgettracermanagerconfig"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 	instancemanager "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/instance-manager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/store"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
 )
 
 const (
+	// Name is the name of the backend to use in the configuration
+	Name = "configmap"
+
 	GadgetInstance = "gadget-instance"
 
-	gadgetImage    = "gadgetImage"
-	gadgetLogLevel = "gadgetLogLevel"
-	gadgetNodes    = "gadgetNodes"
-	gadgetTags     = "gadgetTags"
-	gadgetTimeout  = "gadgetTimeout"
+	gadgetImage        = "gadgetImage"
+	gadgetLogLevel     = "gadgetLogLevel"
+	gadgetNodes        = "gadgetNodes"
+	gadgetNodeSelector = "gadgetNodeSelector"
+	gadgetPaused       = "gadgetPaused"
+	gadgetParams       = "gadgetParamValues"
+	gadgetTags         = "gadgetTags"
+	gadgetTimeout      = "gadgetTimeout"
+	gadgetTolerations  = "gadgetTolerations"
+	gadgetTTL          = "gadgetTTL"
 )
 
+func init() {
+	store.RegisterBackend(Name, func(mgr *instancemanager.Manager, cfg *viper.Viper) (store.Store, error) {
+		namespace := cfg.GetString(gadgettracermanagerconfig.GadgetNamespace)
+		if namespace == "" {
+			return nil, errors.New("gadget namespace must not be empty")
+		}
+		return New(mgr, namespace)
+	})
+}
+
 type Store struct {
 	api.UnimplementedGadgetInstanceManagerServer
 	nodeName        string
 	store           cache.Store
 	queue           workqueue.TypedRateLimitingInterface[string]
 	informer        cache.Controller
+	nodeInformer    cache.Controller
 	clientset       *kubernetes.Clientset
 	instanceMgr     *instancemanager.Manager
 	gadgetNamespace string
+
+	// node holds the labels and taints of the node, used to decide which
+	// instances to run
+	nodeMu sync.Mutex
+	node   store.Node
+
+	// running maps the IDs of the instances started on this node to the
+	// resource version of their config map, so that they are only restarted
+	// when the config map changes
+	running map[string]string
 }
 
 func New(mgr *instancemanager.Manager, namespace string) (*Store, error) {
@@ -69,6 +105,8 @@ func New(mgr *instancemanager.Manager, namespace string) (*Store, error) {
 		instanceMgr:     mgr,
 		nodeName:        nodeName,
 		gadgetNamespace: namespace,
+		node:            store.Node{Name: nodeName},
+		running:         map[string]string{},
 	}
 	err := s.init()
 	if err != nil {
@@ -129,16 +167,54 @@ func (s *Store) init() error {
 	s.queue = queue
 	s.store = store
 	s.informer = controller
+
+	// Watch the node this store runs on, so instances using a node selector
+	// or tolerations are started or stopped when its labels or taints change
+	nodeListWatcher := cache.NewListWatchFromClient(clientset.CoreV1().RESTClient(), "nodes", "",
+		fields.OneTermEqualSelector("metadata.name", s.nodeName))
+	_, s.nodeInformer = cache.NewInformerWithOptions(cache.InformerOptions{
+		ListerWatcher: nodeListWatcher,
+		ObjectType:    &corev1.Node{},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: s.updateNode,
+			UpdateFunc: func(old interface{}, new interface{}) {
+				s.updateNode(new)
+			},
+		},
+	})
 	return nil
 }
 
+// updateNode stores the labels and taints of the node and reconciles all
+// instances again if they changed
+func (s *Store) updateNode(obj interface{}) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return
+	}
+	s.nodeMu.Lock()
+	changed := !maps.Equal(s.node.Labels, node.Labels) ||
+		!slices.EqualFunc(s.node.Taints, node.Spec.Taints, func(a, b corev1.Taint) bool { return a.MatchTaint(&b) })
+	s.node.Labels = node.Labels
+	s.node.Taints = node.Spec.Taints
+	s.nodeMu.Unlock()
+	if !changed {
+		return
+	}
+	log.Debugf("labels or taints of node %q changed, reconciling gadget instances", s.nodeName)
+	for _, key := range s.store.ListKeys() {
+		s.queue.Add(key)
+	}
+}
+
 func (s *Store) runController() {
 	stopChan := make(chan struct{})
 
 	defer s.queue.ShutDown()
 	go s.informer.Run(stopChan)
+	go s.nodeInformer.Run(stopChan)
 
-	if !cache.WaitForCacheSync(stopChan, s.informer.HasSynced) {
+	if !cache.WaitForCacheSync(stopChan, s.informer.HasSynced, s.nodeInformer.HasSynced) {
 		runtime.HandleError(fmt.Errorf("timed out waiting for caches to sync"))
 		return
 	}
@@ -183,10 +259,10 @@ func (s *Store) reconcile(key string) error {
 		return fmt.Errorf("invalid key; expected %q, got %q", "namespace/name", key)
 	}
 
-	err = s.instanceMgr.RemoveGadget(namespacedName[1])
 	if !exists {
 		// instance was deleted, so return the result of the deletion
-		return err
+		delete(s.running, namespacedName[1])
+		return s.instanceMgr.RemoveGadget(namespacedName[1])
 	}
 
 	configMap, ok := obj.(*corev1.ConfigMap)
@@ -197,12 +273,34 @@ func (s *Store) reconcile(key string) error {
 	if err != nil {
 		return fmt.Errorf("converting configMap to gadgetInstance: %w", err)
 	}
-	if len(instance.Nodes) > 0 && !slices.Contains(instance.Nodes, s.nodeName) {
+	s.nodeMu.Lock()
+	node := s.node
+	s.nodeMu.Unlock()
+	shouldRun, err := node.ShouldRun(instance)
+	if err != nil {
+		return fmt.Errorf("scheduling gadget instance %q: %w", instance.Id, err)
+	}
+	if !shouldRun {
+		if _, ok := s.running[instance.Id]; ok {
+			log.Infof("stopping gadget %q, it doesn't match node %q anymore", configMap.Name, s.nodeName)
+		}
+		delete(s.running, instance.Id)
+		s.instanceMgr.RemoveGadget(instance.Id)
+		return nil
+	}
+	if s.running[instance.Id] == configMap.ResourceVersion {
+		// Already running with this configuration
 		return nil
 	}
 
+	// This also updates an instance that is already running, keeping its
+	// buffered events; it keeps running with the previous configuration if
+	// that fails
 	log.Infof("starting gadget %q", configMap.Name)
-	s.instanceMgr.RunGadget(instance)
+	if err := s.instanceMgr.UpdateGadget(instance); err != nil {
+		log.Warnf("updating gadget %q: %v", configMap.Name, err)
+	}
+	s.running[instance.Id] = configMap.ResourceVersion
 	return nil
 }
 
@@ -271,6 +369,19 @@ func (s *Store) CreateGadgetInstance(ctx context.Context, req *api.CreateGadgetI
 		Data:       req.GadgetInstance.GadgetConfig.ParamValues,
 		BinaryData: nil,
 	}
+	if req.GadgetInstance.NodeSelector != "" {
+		cmap.Annotations[gadgetNodeSelector] = req.GadgetInstance.NodeSelector
+	}
+	if len(req.GadgetInstance.Tolerations) > 0 {
+		tolerations, err := json.Marshal(req.GadgetInstance.Tolerations)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling tolerations: %w", err)
+		}
+		cmap.Annotations[gadgetTolerations] = string(tolerations)
+	}
+	if req.GadgetInstance.Ttl > 0 {
+		cmap.Annotations[gadgetTTL] = fmt.Sprintf("%d", req.GadgetInstance.Ttl)
+	}
 
 	_, err = s.clientset.CoreV1().ConfigMaps(s.gadgetNamespace).Create(ctx, cmap, v1.CreateOptions{})
 	if err != nil {
@@ -312,6 +423,82 @@ func (s *Store) RemoveGadgetInstance(ctx context.Context, id *api.GadgetInstance
 	}, nil
 }
 
+// PauseGadgetInstance marks the config map of the given gadget instance as
+// paused; the nodes stop the instance when reconciling it
+func (s *Store) PauseGadgetInstance(ctx context.Context, id *api.GadgetInstanceId) (*api.StatusResponse, error) {
+	return s.setPaused(ctx, id.Id, true)
+}
+
+// ResumeGadgetInstance removes the paused mark from the config map of the given
+// gadget instance; the nodes run the instance again when reconciling it
+func (s *Store) ResumeGadgetInstance(ctx context.Context, id *api.GadgetInstanceId) (*api.StatusResponse, error) {
+	return s.setPaused(ctx, id.Id, false)
+}
+
+func (s *Store) setPaused(ctx context.Context, id string, paused bool) (*api.StatusResponse, error) {
+	// Only the data of the config map is immutable, annotations can still be
+	// updated
+	cmap, err := s.clientset.CoreV1().ConfigMaps(s.gadgetNamespace).Get(ctx, id, v1.GetOptions{})
+	if err != nil {
+		return &api.StatusResponse{Result: 1, Message: err.Error()}, nil
+	}
+	if (cmap.Annotations[gadgetPaused] == "true") == paused {
+		return &api.StatusResponse{Result: 0}, nil
+	}
+	if cmap.Annotations == nil {
+		cmap.Annotations = map[string]string{}
+	}
+	if paused {
+		cmap.Annotations[gadgetPaused] = "true"
+	} else {
+		delete(cmap.Annotations, gadgetPaused)
+	}
+	_, err = s.clientset.CoreV1().ConfigMaps(s.gadgetNamespace).Update(ctx, cmap, v1.UpdateOptions{})
+	if err != nil {
+		return &api.StatusResponse{Result: 1, Message: err.Error()}, nil
+	}
+	return &api.StatusResponse{Result: 0}, nil
+}
+
+// UpdateGadgetInstance merges the given param values into the gadget instance;
+// as the data of the config map is immutable, they are stored in an
+// annotation that takes precedence over it. The nodes restart the instance
+// with the new values when reconciling it.
+func (s *Store) UpdateGadgetInstance(ctx context.Context, req *api.UpdateGadgetInstanceRequest) (*api.UpdateGadgetInstanceResponse, error) {
+	cmap, err := s.clientset.CoreV1().ConfigMaps(s.gadgetNamespace).Get(ctx, req.Id, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	paramValues := map[string]string{}
+	if v, ok := cmap.Annotations[gadgetParams]; ok {
+		if err := json.Unmarshal([]byte(v), &paramValues); err != nil {
+			return nil, fmt.Errorf("parsing %s annotation for %q: %w", gadgetParams, cmap.Name, err)
+		}
+	}
+	maps.Copy(paramValues, req.ParamValues)
+	blob, err := json.Marshal(paramValues)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling param values: %w", err)
+	}
+	if cmap.Annotations == nil {
+		cmap.Annotations = map[string]string{}
+	}
+	cmap.Annotations[gadgetParams] = string(blob)
+	cmap, err = s.clientset.CoreV1().ConfigMaps(s.gadgetNamespace).Update(ctx, cmap, v1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := configMapToGadgetInstance(cmap)
+	if err != nil {
+		return nil, fmt.Errorf("converting configMap to gadgetInstance: %w", err)
+	}
+	return &api.UpdateGadgetInstanceResponse{
+		Result:         0,
+		GadgetInstance: instance,
+	}, nil
+}
+
 // GetGadgetInstance returns the configuration of the given gadget instance
 func (s *Store) GetGadgetInstance(ctx context.Context, req *api.GadgetInstanceId) (*api.GadgetInstance, error) {
 	configMap, ok, err := s.store.GetByKey(s.gadgetNamespace + "/" + req.Id)
@@ -338,6 +525,28 @@ func configMapToGadgetInstance(cm *corev1.ConfigMap) (*api.GadgetInstance, error
 	if err != nil && cm.Annotations[gadgetLogLevel] != "" {
 		return nil, fmt.Errorf("parsing %s annotation for %q: %w", gadgetLogLevel, cm.Name, err)
 	}
+	ttl, err := strconv.ParseInt(cm.Annotations[gadgetTTL], 10, 64)
+	if err != nil && cm.Annotations[gadgetTTL] != "" {
+		return nil, fmt.Errorf("parsing %s annotation for %q: %w", gadgetTTL, cm.Name, err)
+	}
+	paramValues := cm.Data
+	if v, ok := cm.Annotations[gadgetParams]; ok {
+		var updated map[string]string
+		if err := json.Unmarshal([]byte(v), &updated); err != nil {
+			return nil, fmt.Errorf("parsing %s annotation for %q: %w", gadgetParams, cm.Name, err)
+		}
+		paramValues = maps.Clone(cm.Data)
+		if paramValues == nil {
+			paramValues = make(map[string]string, len(updated))
+		}
+		maps.Copy(paramValues, updated)
+	}
+	var tolerations []*api.Toleration
+	if v, ok := cm.Annotations[gadgetTolerations]; ok {
+		if err := json.Unmarshal([]byte(v), &tolerations); err != nil {
+			return nil, fmt.Errorf("parsing %s annotation for %q: %w", gadgetTolerations, cm.Name, err)
+		}
+	}
 	nodes := strings.Split(cm.Annotations["gadgetNodes"], ",")
 	if len(nodes) == 1 && nodes[0] == "" {
 		// no nodes given, make sure the array is empty
@@ -347,14 +556,18 @@ func configMapToGadgetInstance(cm *corev1.ConfigMap) (*api.GadgetInstance, error
 		Id: cm.Name,
 		GadgetConfig: &api.GadgetRunRequest{
 			ImageName:   cm.Annotations[gadgetImage],
-			ParamValues: cm.Data,
+			ParamValues: paramValues,
 			LogLevel:    uint32(logLevel),
 			Timeout:     timeout,
 			Version:     api.VersionGadgetRunProtocol,
 		},
-		Nodes:       nodes,
-		Name:        cm.Labels["name"],
-		Tags:        strings.Split(cm.Annotations[gadgetTags], ","),
-		TimeCreated: cm.CreationTimestamp.Unix(),
+		Nodes:        nodes,
+		NodeSelector: cm.Annotations[gadgetNodeSelector],
+		Tolerations:  tolerations,
+		Name:         cm.Labels["name"],
+		Tags:         strings.Split(cm.Annotations[gadgetTags], ","),
+		TimeCreated:  cm.CreationTimestamp.Unix(),
+		Paused:       cm.Annotations[gadgetPaused] == "true",
+		Ttl:          ttl,
 	}, nil
 }
@@ -0,0 +1,113 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// Node holds what's known about the node a store runs on, to decide which
+// gadget instances it has to run
+type Node struct {
+	Name   string
+	Labels map[string]string
+	Taints []corev1.Taint
+}
+
+// ShouldRun returns whether the gadget instance has to run on the node. Taints
+// are only considered if the instance has a node selector or tolerations, so
+// that instances not using them keep running on all nodes.
+func (n *Node) ShouldRun(instance *api.GadgetInstance) (bool, error) {
+	if len(instance.Nodes) > 0 && !slices.Contains(instance.Nodes, n.Name) {
+		return false, nil
+	}
+	if instance.NodeSelector == "" && len(instance.Tolerations) == 0 {
+		return true, nil
+	}
+
+	if instance.NodeSelector != "" {
+		selector, err := labels.Parse(instance.NodeSelector)
+		if err != nil {
+			return false, fmt.Errorf("parsing node selector %q: %w", instance.NodeSelector, err)
+		}
+		if !selector.Matches(labels.Set(n.Labels)) {
+			return false, nil
+		}
+	}
+
+	tolerations := toK8sTolerations(instance.Tolerations)
+	for i := range n.Taints {
+		taint := &n.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := slices.ContainsFunc(tolerations, func(t corev1.Toleration) bool {
+			return t.ToleratesTaint(taint)
+		})
+		if !tolerated {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ValidateScheduling checks the node selector and tolerations of the gadget
+// instance
+func ValidateScheduling(instance *api.GadgetInstance) error {
+	if instance.NodeSelector != "" {
+		if _, err := labels.Parse(instance.NodeSelector); err != nil {
+			return fmt.Errorf("invalid node selector %q: %w", instance.NodeSelector, err)
+		}
+	}
+	for _, t := range instance.Tolerations {
+		switch corev1.TolerationOperator(t.Operator) {
+		case "", corev1.TolerationOpEqual:
+			if t.Key == "" {
+				return fmt.Errorf("invalid toleration: operator %q requires a key", corev1.TolerationOpEqual)
+			}
+		case corev1.TolerationOpExists:
+			if t.Value != "" {
+				return fmt.Errorf("invalid toleration for %q: operator %q doesn't take a value", t.Key, corev1.TolerationOpExists)
+			}
+		default:
+			return fmt.Errorf("invalid toleration for %q: unknown operator %q", t.Key, t.Operator)
+		}
+		switch corev1.TaintEffect(t.Effect) {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("invalid toleration for %q: unknown effect %q", t.Key, t.Effect)
+		}
+	}
+	return nil
+}
+
+func toK8sTolerations(tolerations []*api.Toleration) []corev1.Toleration {
+	res := make([]corev1.Toleration, 0, len(tolerations))
+	for _, t := range tolerations {
+		res = append(res, corev1.Toleration{
+			Key:      t.Key,
+			Operator: corev1.TolerationOperator(t.Operator),
+			Value:    t.Value,
+			Effect:   corev1.TaintEffect(t.Effect),
+		})
+	}
+	return res
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func TestNodeShouldRun(t *testing.T) {
+	worker := &Node{
+		Name:   "node1",
+		Labels: map[string]string{"node-role.kubernetes.io/worker": "true", "zone": "a"},
+	}
+	tainted := &Node{
+		Name:   "node2",
+		Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+		Taints: []corev1.Taint{
+			{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "example.com/slow", Value: "true", Effect: corev1.TaintEffectPreferNoSchedule},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		node     *Node
+		instance *api.GadgetInstance
+		expected bool
+		err      bool
+	}{
+		{
+			name:     "no constraints",
+			node:     tainted,
+			instance: &api.GadgetInstance{},
+			expected: true,
+		},
+		{
+			name:     "node not listed",
+			node:     worker,
+			instance: &api.GadgetInstance{Nodes: []string{"node2"}},
+			expected: false,
+		},
+		{
+			name:     "selector matches",
+			node:     worker,
+			instance: &api.GadgetInstance{NodeSelector: "node-role.kubernetes.io/worker=true,zone in (a,b)"},
+			expected: true,
+		},
+		{
+			name:     "selector doesn't match",
+			node:     worker,
+			instance: &api.GadgetInstance{NodeSelector: "zone=b"},
+			expected: false,
+		},
+		{
+			name:     "selector and nodes",
+			node:     worker,
+			instance: &api.GadgetInstance{Nodes: []string{"node2"}, NodeSelector: "zone=a"},
+			expected: false,
+		},
+		{
+			name:     "taint not tolerated",
+			node:     tainted,
+			instance: &api.GadgetInstance{NodeSelector: "node-role.kubernetes.io/control-plane"},
+			expected: false,
+		},
+		{
+			name: "taint tolerated",
+			node: tainted,
+			instance: &api.GadgetInstance{
+				NodeSelector: "node-role.kubernetes.io/control-plane",
+				Tolerations:  []*api.Toleration{{Key: "node-role.kubernetes.io/control-plane", Operator: "Exists"}},
+			},
+			expected: true,
+		},
+		{
+			name: "toleration with other effect",
+			node: tainted,
+			instance: &api.GadgetInstance{
+				Tolerations: []*api.Toleration{{Key: "node-role.kubernetes.io/control-plane", Operator: "Exists", Effect: "NoExecute"}},
+			},
+			expected: false,
+		},
+		{
+			name: "tolerate everything",
+			node: tainted,
+			instance: &api.GadgetInstance{
+				Tolerations: []*api.Toleration{{Operator: "Exists"}},
+			},
+			expected: true,
+		},
+		{
+			name:     "invalid selector",
+			node:     worker,
+			instance: &api.GadgetInstance{NodeSelector: "zone in a"},
+			err:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			run, err := test.node.ShouldRun(test.instance)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, run)
+		})
+	}
+}
+
+func TestValidateScheduling(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance *api.GadgetInstance
+		err      bool
+	}{
+		{
+			name:     "empty",
+			instance: &api.GadgetInstance{},
+		},
+		{
+			name: "valid",
+			instance: &api.GadgetInstance{
+				NodeSelector: "node-role.kubernetes.io/worker=true",
+				Tolerations: []*api.Toleration{
+					{Key: "a", Operator: "Equal", Value: "b", Effect: "NoSchedule"},
+					{Key: "c", Operator: "Exists"},
+					{Operator: "Exists"},
+				},
+			},
+		},
+		{
+			name:     "invalid selector",
+			instance: &api.GadgetInstance{NodeSelector: "==a"},
+			err:      true,
+		},
+		{
+			name:     "equal without key",
+			instance: &api.GadgetInstance{Tolerations: []*api.Toleration{{Value: "b"}}},
+			err:      true,
+		},
+		{
+			name:     "exists with value",
+			instance: &api.GadgetInstance{Tolerations: []*api.Toleration{{Key: "a", Operator: "Exists", Value: "b"}}},
+			err:      true,
+		},
+		{
+			name:     "unknown operator",
+			instance: &api.GadgetInstance{Tolerations: []*api.Toleration{{Key: "a", Operator: "Gt"}}},
+			err:      true,
+		},
+		{
+			name:     "unknown effect",
+			instance: &api.GadgetInstance{Tolerations: []*api.Toleration{{Key: "a", Effect: "NoRun"}}},
+			err:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateScheduling(test.instance)
+			if test.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
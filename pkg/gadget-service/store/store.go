@@ -1,4 +1,4 @@
-// Copyright 2023-2024 The Inspektor Gadget authors
+// Copyright 2023-2025 The Inspektor Gadget authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -15,10 +15,101 @@
 package store
 
 import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	instancemanager "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/instance-manager"
 )
 
+// BackendKey is the configuration key selecting the backend of the store;
+// settings of the backends are read from "store.<backend>.*"
+const BackendKey = "store.backend"
+
 type Store interface {
 	api.GadgetInstanceManagerServer
 	ResumeStoredGadgets() error
 }
+
+// Cluster is implemented by stores shared by several replicas of the gadget
+// service, which coordinate through them
+type Cluster interface {
+	// IsLeader returns whether this replica reconciles the state shared by
+	// all replicas, like removing expired gadget instances; there's at most
+	// one leader at a time
+	IsLeader() bool
+
+	// SetGatewayAddress announces the host:port at which the other replicas
+	// reach the HTTP gateway of this one. It has to be called before
+	// ResumeStoredGadgets.
+	SetGatewayAddress(address string)
+
+	// Replica returns the name of this replica
+	Replica() string
+
+	// InstanceReplicas returns the live replicas running the given gadget
+	// instance, sorted by name
+	InstanceReplicas(ctx context.Context, id string) ([]Replica, error)
+}
+
+// Replica describes a replica of the gadget service sharing a store
+type Replica struct {
+	Name           string            `json:"name"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	GatewayAddress string            `json:"gatewayAddress,omitempty"`
+}
+
+// Factory creates a store using the settings found in cfg
+type Factory func(mgr *instancemanager.Manager, cfg *viper.Viper) (Store, error)
+
+var (
+	registryLock sync.Mutex
+	backends     = map[string]Factory{}
+)
+
+// RegisterBackend registers a store backend under the given name
+func RegisterBackend(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	backends[name] = factory
+}
+
+// Backends returns the names of the registered backends
+func Backends() []string {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	return slices.Sorted(maps.Keys(backends))
+}
+
+// New creates a store using the backend configured by BackendKey, or
+// defaultBackend if none is configured
+func New(mgr *instancemanager.Manager, cfg *viper.Viper, defaultBackend string) (Store, error) {
+	name := cfg.GetString(BackendKey)
+	if name == "" {
+		name = defaultBackend
+	}
+
+	registryLock.Lock()
+	factory, ok := backends[name]
+	registryLock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown store backend %q, available backends: %s", name, strings.Join(Backends(), ", "))
+	}
+
+	s, err := factory(mgr, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("initializing %s store: %w", name, err)
+	}
+	return s, nil
+}
+
+// SettingKey returns the configuration key of a setting of the given backend
+func SettingKey(backend, setting string) string {
+	return "store." + backend + "." + setting
+}
@@ -0,0 +1,230 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package integrity computes and verifies checksums over batches of events sent
+// from a node to a client, to detect events that were corrupted or tampered
+// with in transit. When both ends share a key, the checksums are HMAC-SHA256
+// signatures instead of plain SHA-256 digests, so they can't be recomputed by
+// whoever modified the events.
+//
+// Each event is hashed together with its sequence number and data source ID.
+// The checksum of a batch is computed over the hashes of its events, in order,
+// and is sent along with the range of sequence numbers it covers.
+package integrity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// ReporterVarName is the name of the gadget context variable holding the
+// Reporter to send verification errors to
+const ReporterVarName = "integrityReporter"
+
+// Reporter receives verification errors
+type Reporter interface {
+	ReportVerificationError(*VerificationError)
+}
+
+// VerificationError describes a batch of events that didn't match its checksum
+type VerificationError struct {
+	Node     string `json:"node"`
+	FirstSeq uint32 `json:"firstSeq"`
+	LastSeq  uint32 `json:"lastSeq"`
+	Reason   string `json:"reason"`
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("integrity check failed for events %d-%d from %q: %s", e.FirstSeq, e.LastSeq, e.Node, e.Reason)
+}
+
+// JSON returns the error as a JSON object
+func (e *VerificationError) JSON() string {
+	d, _ := json.Marshal(e)
+	return string(d)
+}
+
+// LoadKey reads a key shared between the nodes and the clients
+func LoadKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading integrity key: %w", err)
+	}
+	key = []byte(strings.TrimSpace(string(key)))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("integrity key %q is empty", path)
+	}
+	return key, nil
+}
+
+func eventDigest(seq, dsID uint32, payload []byte) []byte {
+	var hdr [8]byte
+	binary.LittleEndian.PutUint32(hdr[0:], seq)
+	binary.LittleEndian.PutUint32(hdr[4:], dsID)
+	h := sha256.New()
+	h.Write(hdr[:])
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+func batchHash(key []byte) hash.Hash {
+	if len(key) > 0 {
+		return hmac.New(sha256.New, key)
+	}
+	return sha256.New()
+}
+
+// Sealer computes the checksums of the events sent to a client
+type Sealer struct {
+	key       []byte
+	batchSize uint32
+
+	h        hash.Hash
+	count    uint32
+	firstSeq uint32
+	lastSeq  uint32
+}
+
+// NewSealer returns a Sealer emitting a checksum every batchSize events
+func NewSealer(batchSize uint32, key []byte) *Sealer {
+	return &Sealer{
+		key:       key,
+		batchSize: batchSize,
+		h:         batchHash(key),
+	}
+}
+
+// Add adds an event to the current batch. It returns the checksum of the batch
+// once it's complete.
+func (s *Sealer) Add(seq, dsID uint32, payload []byte) *api.EventBatchChecksum {
+	if s.count == 0 {
+		s.firstSeq = seq
+	}
+	s.lastSeq = seq
+	s.count++
+	s.h.Write(eventDigest(seq, dsID, payload))
+	if s.count < s.batchSize {
+		return nil
+	}
+	return s.Flush()
+}
+
+// Flush returns the checksum of the current batch, even if it's not complete,
+// and starts a new one. It returns nil if the batch is empty.
+func (s *Sealer) Flush() *api.EventBatchChecksum {
+	if s.count == 0 {
+		return nil
+	}
+	c := &api.EventBatchChecksum{
+		FirstSeq: s.firstSeq,
+		LastSeq:  s.lastSeq,
+		Count:    s.count,
+		Digest:   s.h.Sum(nil),
+		Signed:   len(s.key) > 0,
+	}
+	s.h = batchHash(s.key)
+	s.count = 0
+	return c
+}
+
+// maxPendingBatches is the number of batches a Verifier keeps the events of
+// while waiting for their checksums
+const maxPendingBatches = 16
+
+type receivedEvent struct {
+	seq    uint32
+	digest []byte
+}
+
+// Verifier checks the events received from a node against their checksums
+type Verifier struct {
+	node       string
+	key        []byte
+	maxPending int
+	pending    []receivedEvent
+}
+
+// NewVerifier returns a Verifier for the events of node, which sends a
+// checksum every batchSize events
+func NewVerifier(node string, batchSize uint32, key []byte) *Verifier {
+	return &Verifier{
+		node:       node,
+		key:        key,
+		maxPending: int(batchSize) * maxPendingBatches,
+	}
+}
+
+// Add records a received event. If the checksums stopped arriving and too many
+// events are waiting for one, they are forgotten and an error covering them is
+// returned.
+func (v *Verifier) Add(seq, dsID uint32, payload []byte) *VerificationError {
+	var verr *VerificationError
+	if len(v.pending) >= v.maxPending && len(v.pending) > 0 {
+		verr = &VerificationError{
+			Node:     v.node,
+			FirstSeq: v.pending[0].seq,
+			LastSeq:  v.pending[len(v.pending)-1].seq,
+			Reason:   fmt.Sprintf("no checksum received for %d events", len(v.pending)),
+		}
+		v.pending = nil
+	}
+	v.pending = append(v.pending, receivedEvent{seq: seq, digest: eventDigest(seq, dsID, payload)})
+	return verr
+}
+
+// Verify checks the events of the batch described by c. Events up to the end
+// of the batch are forgotten afterwards, so a lost checksum only makes the
+// events of its own batch unverified.
+func (v *Verifier) Verify(c *api.EventBatchChecksum) *VerificationError {
+	verr := func(format string, args ...any) *VerificationError {
+		return &VerificationError{
+			Node:     v.node,
+			FirstSeq: c.FirstSeq,
+			LastSeq:  c.LastSeq,
+			Reason:   fmt.Sprintf(format, args...),
+		}
+	}
+
+	h := batchHash(v.key)
+	var count uint32
+	i := 0
+	for ; i < len(v.pending) && v.pending[i].seq <= c.LastSeq; i++ {
+		if v.pending[i].seq < c.FirstSeq {
+			continue
+		}
+		h.Write(v.pending[i].digest)
+		count++
+	}
+	v.pending = v.pending[i:]
+
+	switch {
+	case c.Signed && len(v.key) == 0:
+		return verr("batch is signed but no key was configured to verify it")
+	case !c.Signed && len(v.key) > 0:
+		return verr("batch isn't signed")
+	case count != c.Count:
+		return verr("expected %d events, received %d", c.Count, count)
+	case !hmac.Equal(h.Sum(nil), c.Digest):
+		return verr("checksum mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integrity
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+type event struct {
+	seq     uint32
+	dsID    uint32
+	payload []byte
+}
+
+func seal(t *testing.T, sealer *Sealer, events []event) []*api.EventBatchChecksum {
+	t.Helper()
+	var checksums []*api.EventBatchChecksum
+	for _, ev := range events {
+		if c := sealer.Add(ev.seq, ev.dsID, ev.payload); c != nil {
+			checksums = append(checksums, c)
+		}
+	}
+	if c := sealer.Flush(); c != nil {
+		checksums = append(checksums, c)
+	}
+	return checksums
+}
+
+func TestVerifier(t *testing.T) {
+	events := make([]event, 0, 5)
+	for i := uint32(1); i <= 5; i++ {
+		events = append(events, event{seq: i, dsID: i % 2, payload: []byte(fmt.Sprintf("event %d", i))})
+	}
+
+	type testCase struct {
+		sealKey   []byte
+		verifyKey []byte
+		// modify changes the events received by the client
+		modify func([]event) []event
+		// errs are the expected reasons, one per batch; empty if the batch is valid
+		errs []string
+	}
+
+	tests := map[string]testCase{
+		"valid": {
+			errs: []string{"", "", ""},
+		},
+		"valid signed": {
+			sealKey:   []byte("secret"),
+			verifyKey: []byte("secret"),
+			errs:      []string{"", "", ""},
+		},
+		"modified payload": {
+			modify: func(events []event) []event {
+				events[2].payload = []byte("forged")
+				return events
+			},
+			errs: []string{"", "checksum mismatch", ""},
+		},
+		"modified data source": {
+			modify: func(events []event) []event {
+				events[0].dsID = 7
+				return events
+			},
+			errs: []string{"checksum mismatch", "", ""},
+		},
+		"dropped event": {
+			modify: func(events []event) []event {
+				return append(events[:3], events[4:]...)
+			},
+			errs: []string{"", "expected 2 events, received 1", ""},
+		},
+		"wrong key": {
+			sealKey:   []byte("secret"),
+			verifyKey: []byte("other"),
+			errs:      []string{"checksum mismatch", "checksum mismatch", "checksum mismatch"},
+		},
+		"missing key": {
+			sealKey: []byte("secret"),
+			errs: []string{
+				"batch is signed but no key was configured to verify it",
+				"batch is signed but no key was configured to verify it",
+				"batch is signed but no key was configured to verify it",
+			},
+		},
+		"unsigned": {
+			verifyKey: []byte("secret"),
+			errs:      []string{"batch isn't signed", "batch isn't signed", "batch isn't signed"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			checksums := seal(t, NewSealer(2, test.sealKey), events)
+			require.Len(t, checksums, len(test.errs))
+
+			received := append([]event(nil), events...)
+			if test.modify != nil {
+				received = test.modify(received)
+			}
+
+			verifier := NewVerifier("node1", 2, test.verifyKey)
+			next := 0
+			for i, c := range checksums {
+				for ; next < len(received) && received[next].seq <= c.LastSeq; next++ {
+					verifier.Add(received[next].seq, received[next].dsID, received[next].payload)
+				}
+				verr := verifier.Verify(c)
+				if test.errs[i] == "" {
+					assert.Nil(t, verr, "batch %d", i)
+					continue
+				}
+				require.NotNil(t, verr, "batch %d", i)
+				assert.Equal(t, test.errs[i], verr.Reason)
+				assert.Equal(t, "node1", verr.Node)
+				assert.Equal(t, c.FirstSeq, verr.FirstSeq)
+				assert.Equal(t, c.LastSeq, verr.LastSeq)
+			}
+		})
+	}
+}
+
+func TestVerifierLostChecksum(t *testing.T) {
+	sealer := NewSealer(2, nil)
+	verifier := NewVerifier("node1", 2, nil)
+
+	var checksums []*api.EventBatchChecksum
+	for seq := uint32(1); seq <= 4; seq++ {
+		payload := []byte(fmt.Sprintf("event %d", seq))
+		verifier.Add(seq, 0, payload)
+		if c := sealer.Add(seq, 0, payload); c != nil {
+			checksums = append(checksums, c)
+		}
+	}
+	require.Len(t, checksums, 2)
+
+	// The checksum of the first batch was lost; the second batch can still be
+	// verified
+	assert.Nil(t, verifier.Verify(checksums[1]))
+	assert.Empty(t, verifier.pending)
+}
+
+func TestVerifierNoChecksums(t *testing.T) {
+	verifier := NewVerifier("node1", 2, nil)
+
+	// The events of maxPendingBatches batches are kept while waiting for
+	// their checksums
+	for seq := uint32(1); seq <= 2*maxPendingBatches; seq++ {
+		assert.Nil(t, verifier.Add(seq, 0, []byte("event")))
+	}
+
+	verr := verifier.Add(2*maxPendingBatches+1, 0, []byte("event"))
+	require.NotNil(t, verr)
+	assert.Equal(t, uint32(1), verr.FirstSeq)
+	assert.Equal(t, uint32(2*maxPendingBatches), verr.LastSeq)
+	assert.Equal(t, fmt.Sprintf("no checksum received for %d events", 2*maxPendingBatches), verr.Reason)
+	assert.Len(t, verifier.pending, 1)
+}
+
+func TestLoadKey(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "key")
+	require.NoError(t, os.WriteFile(path, []byte("secret\n"), 0o600))
+	key, err := LoadKey(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), key)
+
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(empty, []byte("\n"), 0o600))
+	_, err = LoadKey(empty)
+	require.Error(t, err)
+
+	_, err = LoadKey(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
@@ -0,0 +1,242 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nodeinfo inventories the kernel features of the current node that
+// decide whether a gadget is able to run on it, like the presence of BTF,
+// ring buffer support, the cgroup mode or available tracepoints and kprobes.
+package nodeinfo
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+const (
+	CgroupModeV1     = "v1"
+	CgroupModeV2     = "v2"
+	CgroupModeHybrid = "hybrid"
+)
+
+var (
+	tracefsPaths   = []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"}
+	btfPath        = "/sys/kernel/btf/vmlinux"
+	cgroupPath     = "/sys/fs/cgroup"
+	lsmPath        = "/sys/kernel/security/lsm"
+	lockdownPath   = "/sys/kernel/security/lockdown"
+	errNoTracefs   = errors.New("tracefs not found")
+	errNotCgroupFs = errors.New("no cgroup filesystem found")
+)
+
+// Collect gathers information about the kernel features of the current node.
+// It doesn't fail if single features can't be determined; those will be
+// reported as unavailable and logged instead.
+func Collect(req *api.NodeInfoRequest) *api.NodeInfo {
+	info := &api.NodeInfo{
+		Tracepoints: make(map[string]bool),
+		Kprobes:     make(map[string]bool),
+	}
+
+	var utsname unix.Utsname
+	if err := unix.Uname(&utsname); err == nil {
+		info.KernelRelease = unix.ByteSliceToString(utsname.Release[:])
+	}
+
+	if _, err := os.Stat(btfPath); err == nil {
+		info.BtfAvailable = true
+	}
+
+	info.RingBufferSupported = features.HaveMapType(ebpf.RingBuf) == nil
+
+	cgroupMode, err := getCgroupMode()
+	if err != nil {
+		log.Debugf("getting cgroup mode: %v", err)
+	}
+	info.CgroupMode = cgroupMode
+
+	info.LsmBPFAvailable = isLSMBPFAvailable()
+
+	if lockdown, err := os.ReadFile(lockdownPath); err == nil {
+		info.Lockdown = parseLockdown(string(lockdown))
+	}
+
+	tracefs, err := TracefsPath()
+	if err != nil {
+		log.Debugf("getting tracefs: %v", err)
+		for _, tp := range req.GetTracepoints() {
+			info.Tracepoints[tp] = false
+		}
+		for _, kp := range req.GetKprobes() {
+			info.Kprobes[kp] = false
+		}
+		return info
+	}
+
+	info.TracepointCount = countTracepoints(filepath.Join(tracefs, "events"))
+	for _, tp := range req.GetTracepoints() {
+		info.Tracepoints[tp] = hasTracepoint(filepath.Join(tracefs, "events"), tp)
+	}
+
+	f, err := os.Open(filepath.Join(tracefs, "available_filter_functions"))
+	if err != nil {
+		log.Debugf("reading available filter functions: %v", err)
+		for _, kp := range req.GetKprobes() {
+			info.Kprobes[kp] = false
+		}
+		return info
+	}
+	defer f.Close()
+
+	count, found := scanFilterFunctions(f, req.GetKprobes())
+	info.KprobeFunctionCount = count
+	for _, kp := range req.GetKprobes() {
+		info.Kprobes[kp] = found[kp]
+	}
+
+	return info
+}
+
+// TracefsPath returns the path where tracefs is mounted
+func TracefsPath() (string, error) {
+	for _, path := range tracefsPaths {
+		var statfs unix.Statfs_t
+		if err := unix.Statfs(path, &statfs); err != nil {
+			continue
+		}
+		if statfs.Type == unix.TRACEFS_MAGIC {
+			return path, nil
+		}
+	}
+	return "", errNoTracefs
+}
+
+func getCgroupMode() (string, error) {
+	var statfs unix.Statfs_t
+	if err := unix.Statfs(cgroupPath, &statfs); err != nil {
+		return "", err
+	}
+	switch statfs.Type {
+	case unix.CGROUP2_SUPER_MAGIC:
+		return CgroupModeV2, nil
+	case unix.TMPFS_MAGIC:
+		if err := unix.Statfs(filepath.Join(cgroupPath, "unified"), &statfs); err == nil &&
+			statfs.Type == unix.CGROUP2_SUPER_MAGIC {
+			return CgroupModeHybrid, nil
+		}
+		return CgroupModeV1, nil
+	}
+	return "", errNotCgroupFs
+}
+
+func isLSMBPFAvailable() bool {
+	lsms, err := os.ReadFile(lsmPath)
+	if err != nil {
+		return false
+	}
+	if !parseLSMs(string(lsms))["bpf"] {
+		return false
+	}
+	return features.HaveProgramType(ebpf.LSM) == nil
+}
+
+// parseLSMs parses the comma-separated list of active LSMs
+func parseLSMs(content string) map[string]bool {
+	res := make(map[string]bool)
+	for _, lsm := range strings.Split(strings.TrimSpace(content), ",") {
+		if lsm != "" {
+			res[lsm] = true
+		}
+	}
+	return res
+}
+
+// parseLockdown returns the active lockdown mode, which is enclosed in square
+// brackets, e.g. "none [integrity] confidentiality"
+func parseLockdown(content string) string {
+	for _, mode := range strings.Fields(content) {
+		if strings.HasPrefix(mode, "[") && strings.HasSuffix(mode, "]") {
+			return strings.Trim(mode, "[]")
+		}
+	}
+	return ""
+}
+
+func countTracepoints(eventsPath string) uint32 {
+	categories, err := os.ReadDir(eventsPath)
+	if err != nil {
+		return 0
+	}
+	count := uint32(0)
+	for _, category := range categories {
+		if !category.IsDir() {
+			continue
+		}
+		events, err := os.ReadDir(filepath.Join(eventsPath, category.Name()))
+		if err != nil {
+			continue
+		}
+		for _, event := range events {
+			if event.IsDir() {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// hasTracepoint checks whether the given tracepoint exists; the tracepoint
+// can be given as "category/name" or "category:name"
+func hasTracepoint(eventsPath string, tracepoint string) bool {
+	category, name, ok := strings.Cut(strings.Replace(tracepoint, ":", "/", 1), "/")
+	if !ok || category == "" || name == "" || strings.Contains(name, "/") {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(eventsPath, category, name, "id"))
+	return err == nil
+}
+
+// scanFilterFunctions reads available_filter_functions and returns the number
+// of functions as well as which of the wanted functions have been found. Lines
+// look like "function_name" or "function_name [module]".
+func scanFilterFunctions(r io.Reader, wanted []string) (uint32, map[string]bool) {
+	found := make(map[string]bool, len(wanted))
+	lookup := make(map[string]struct{}, len(wanted))
+	for _, fn := range wanted {
+		lookup[fn] = struct{}{}
+	}
+
+	count := uint32(0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		count++
+		if _, ok := lookup[fields[0]]; ok {
+			found[fields[0]] = true
+		}
+	}
+	return count, found
+}
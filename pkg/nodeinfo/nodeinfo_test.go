@@ -0,0 +1,68 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLockdown(t *testing.T) {
+	require.Equal(t, "none", parseLockdown("[none] integrity confidentiality\n"))
+	require.Equal(t, "integrity", parseLockdown("none [integrity] confidentiality\n"))
+	require.Equal(t, "", parseLockdown(""))
+}
+
+func TestParseLSMs(t *testing.T) {
+	lsms := parseLSMs("lockdown,capability,landlock,yama,apparmor,bpf\n")
+	require.True(t, lsms["bpf"])
+	require.True(t, lsms["apparmor"])
+	require.False(t, lsms["selinux"])
+	require.Empty(t, parseLSMs(""))
+}
+
+func TestScanFilterFunctions(t *testing.T) {
+	content := `do_sys_open
+vfs_read
+tcp_connect
+nf_conntrack_in [nf_conntrack]
+`
+	count, found := scanFilterFunctions(strings.NewReader(content), []string{"vfs_read", "nf_conntrack_in", "missing"})
+	require.Equal(t, uint32(4), count)
+	require.True(t, found["vfs_read"])
+	require.True(t, found["nf_conntrack_in"])
+	require.False(t, found["missing"])
+}
+
+func TestTracepoints(t *testing.T) {
+	eventsPath := t.TempDir()
+	for _, tp := range []string{"syscalls/sys_enter_execve", "syscalls/sys_exit_execve", "sched/sched_process_exit"} {
+		dir := filepath.Join(eventsPath, tp)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "id"), []byte("1\n"), 0o644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(eventsPath, "enable"), []byte("0\n"), 0o644))
+
+	require.Equal(t, uint32(3), countTracepoints(eventsPath))
+	require.True(t, hasTracepoint(eventsPath, "syscalls/sys_enter_execve"))
+	require.True(t, hasTracepoint(eventsPath, "sched:sched_process_exit"))
+	require.False(t, hasTracepoint(eventsPath, "sched/sched_switch"))
+	require.False(t, hasTracepoint(eventsPath, "syscalls"))
+	require.False(t, hasTracepoint(eventsPath, "syscalls/../sched/sched_process_exit"))
+}
@@ -58,6 +58,9 @@ type AuthOptions struct {
 
 type AllowedGadgetsOptions struct {
 	AllowedGadgets []string
+	// Policy, if set, provides the gadget policy synced from an OCI artifact
+	// that images also need to comply with
+	Policy *PolicySyncer
 }
 
 type VerifyOptions struct {
@@ -647,6 +650,16 @@ func SplitIGDomain(name string) (domain, remainder string) {
 	return
 }
 
+// NormalizeImageName returns the fully qualified name of the image, adding
+// the default registry and tag if they are missing.
+func NormalizeImageName(image string) (string, error) {
+	named, err := normalizeImageName(image)
+	if err != nil {
+		return "", err
+	}
+	return named.String(), nil
+}
+
 func normalizeImageName(image string) (reference.Named, error) {
 	// Use the default gadget's registry if no domain is specified.
 	domain, remainer := SplitIGDomain(image)
@@ -781,6 +794,43 @@ func getContentBytesFromDescriptor(ctx context.Context, fetcher content.Fetcher,
 	return bytes, nil
 }
 
+// CheckAllowedGadget checks that the image in the target is allowed by the
+// allowed gadgets and the gadget policy, if any
+func CheckAllowedGadget(ctx context.Context, target oras.ReadOnlyTarget, image string, opts *AllowedGadgetsOptions) error {
+	if len(opts.AllowedGadgets) == 0 && opts.Policy == nil {
+		return nil
+	}
+
+	normalizedImage, err := normalizeImageName(image)
+	if err != nil {
+		return fmt.Errorf("normalizing image: %w", err)
+	}
+
+	imageStr := normalizedImage.String()
+
+	desc, err := target.Resolve(ctx, imageStr)
+	if err != nil {
+		return fmt.Errorf("resolving image %q on local registry: %w", imageStr, err)
+	}
+
+	imageDigest := normalizedImage.Name() + "@" + desc.Digest.String()
+
+	if len(opts.AllowedGadgets) > 0 && !matchesGadget(opts.AllowedGadgets, imageStr, imageDigest) {
+		return fmt.Errorf("%s is not part of allowed gadgets: %v", image, strings.Join(opts.AllowedGadgets, ", "))
+	}
+
+	if opts.Policy != nil {
+		policy, err := opts.Policy.Policy()
+		if err != nil {
+			return fmt.Errorf("checking gadget policy: %w", err)
+		}
+		if err := policy.Check(imageStr, imageDigest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func ensureImage(ctx context.Context, imageStore oras.GraphTarget, image string, imgOpts *ImageOptions, pullPolicy string) error {
 	switch pullPolicy {
 	case PullImageAlways:
@@ -803,41 +853,8 @@ func ensureImage(ctx context.Context, imageStore oras.GraphTarget, image string,
 		}
 	}
 
-	if len(imgOpts.AllowedGadgets) > 0 {
-		found := false
-
-		normalizedImage, err := normalizeImageName(image)
-		if err != nil {
-			return fmt.Errorf("normalizing image: %w", err)
-		}
-
-		imageStr := normalizedImage.String()
-
-		desc, err := imageStore.Resolve(ctx, imageStr)
-		if err != nil {
-			return fmt.Errorf("resolving image %q on local registry: %w", imageStr, err)
-		}
-
-		imageDigest := normalizedImage.Name() + "@" + desc.Digest.String()
-
-		for _, allowedGadget := range imgOpts.AllowedGadgets {
-			// Check full match on digest or name
-			if imageDigest == allowedGadget || imageStr == allowedGadget {
-				found = true
-				break
-			}
-			// Check prefix match
-			if allowedGadget[len(allowedGadget)-1] == '*' {
-				if strings.HasPrefix(imageStr, allowedGadget[:len(allowedGadget)-1]) {
-					found = true
-					break
-				}
-			}
-		}
-
-		if !found {
-			return fmt.Errorf("%s is not part of allowed gadgets: %v", image, strings.Join(imgOpts.AllowedGadgets, ", "))
-		}
+	if err := CheckAllowedGadget(ctx, imageStore, image, &imgOpts.AllowedGadgetsOptions); err != nil {
+		return err
 	}
 
 	if !imgOpts.VerifySignature {
@@ -0,0 +1,258 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	log "github.com/sirupsen/logrus"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/signature"
+)
+
+const (
+	// GadgetPolicyArtifactType is the artifact type of the OCI artifacts
+	// holding the gadgets approved by an organization
+	GadgetPolicyArtifactType = "application/vnd.gadget.policy.v1"
+
+	// AllowedGadgetsAnnotation and DeniedGadgetsAnnotation are the manifest
+	// annotations of a policy artifact holding comma-separated lists of
+	// gadgets, using the same syntax as the allowed-gadgets parameter
+	AllowedGadgetsAnnotation = "io.inspektor-gadget.policy.allowed-gadgets"
+	DeniedGadgetsAnnotation  = "io.inspektor-gadget.policy.denied-gadgets"
+)
+
+// GadgetPolicy lists the gadgets that are allowed and denied. Entries are
+// image names, image names with a digest or prefixes ending with "*".
+type GadgetPolicy struct {
+	// Allowed lists the allowed gadgets; all of them if empty
+	Allowed []string
+	// Denied lists the denied gadgets, even if they are allowed
+	Denied []string
+	// Digest is the digest of the artifact the policy was read from
+	Digest string
+}
+
+// matchesGadget returns whether the image, given by its normalized name and
+// by its name with digest, matches any of the patterns
+func matchesGadget(patterns []string, imageStr, imageDigest string) bool {
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		// Check full match on digest or name
+		if imageDigest == pattern || imageStr == pattern {
+			return true
+		}
+		// Check prefix match
+		if pattern[len(pattern)-1] == '*' && strings.HasPrefix(imageStr, pattern[:len(pattern)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error if the policy doesn't allow the image
+func (p *GadgetPolicy) Check(imageStr, imageDigest string) error {
+	if matchesGadget(p.Denied, imageStr, imageDigest) {
+		return fmt.Errorf("%s is denied by the gadget policy %s", imageStr, p.Digest)
+	}
+	if len(p.Allowed) > 0 && !matchesGadget(p.Allowed, imageStr, imageDigest) {
+		return fmt.Errorf("%s is not allowed by the gadget policy %s", imageStr, p.Digest)
+	}
+	return nil
+}
+
+func splitPolicyList(s string) []string {
+	var list []string
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// policyFromManifest reads the policy from the annotations of the manifest of
+// a policy artifact
+func policyFromManifest(manifestBytes []byte, digest string) (*GadgetPolicy, error) {
+	manifest := &ocispec.Manifest{}
+	if err := json.Unmarshal(manifestBytes, manifest); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+	if manifest.ArtifactType != GadgetPolicyArtifactType {
+		return nil, fmt.Errorf("unexpected artifact type %q, expected %q", manifest.ArtifactType, GadgetPolicyArtifactType)
+	}
+	return &GadgetPolicy{
+		Allowed: splitPolicyList(manifest.Annotations[AllowedGadgetsAnnotation]),
+		Denied:  splitPolicyList(manifest.Annotations[DeniedGadgetsAnnotation]),
+		Digest:  digest,
+	}, nil
+}
+
+// PushGadgetPolicy publishes the policy as an OCI artifact to the given
+// reference and returns its digest
+func PushGadgetPolicy(ctx context.Context, ref string, policy *GadgetPolicy, authOpts *AuthOptions) (string, error) {
+	targetRef, err := normalizeImageName(ref)
+	if err != nil {
+		return "", fmt.Errorf("normalizing reference: %w", err)
+	}
+	repo, err := newRepository(targetRef, authOpts)
+	if err != nil {
+		return "", fmt.Errorf("creating remote repository: %w", err)
+	}
+
+	desc, err := oras.PackManifest(ctx, repo, oras.PackManifestVersion1_1, GadgetPolicyArtifactType,
+		oras.PackManifestOptions{
+			ManifestAnnotations: map[string]string{
+				AllowedGadgetsAnnotation: strings.Join(policy.Allowed, ","),
+				DeniedGadgetsAnnotation:  strings.Join(policy.Denied, ","),
+			},
+		})
+	if err != nil {
+		return "", fmt.Errorf("pushing policy: %w", err)
+	}
+
+	if tagged, ok := targetRef.(reference.Tagged); ok {
+		if err := repo.Tag(ctx, desc, tagged.Tag()); err != nil {
+			return "", fmt.Errorf("tagging policy: %w", err)
+		}
+	}
+	return desc.Digest.String(), nil
+}
+
+// PullGadgetPolicy reads the policy published at the given reference. If
+// verifyOpts enables it, the signature of the artifact is verified like the
+// ones of gadget images and the policy is rejected if it isn't valid.
+func PullGadgetPolicy(ctx context.Context, ref string, authOpts *AuthOptions, verifyOpts *VerifyOptions) (*GadgetPolicy, error) {
+	targetRef, err := normalizeImageName(ref)
+	if err != nil {
+		return nil, fmt.Errorf("normalizing reference: %w", err)
+	}
+	repo, err := newRepository(targetRef, authOpts)
+	if err != nil {
+		return nil, fmt.Errorf("creating remote repository: %w", err)
+	}
+
+	// The artifact is pulled into a temporary store, so the manifest read is
+	// the one whose signature is verified
+	store := memory.New()
+	desc, err := oras.Copy(ctx, repo, targetRef.String(), store, targetRef.String(), oras.DefaultCopyOptions)
+	if err != nil {
+		return nil, fmt.Errorf("fetching policy %q: %w", targetRef.String(), err)
+	}
+
+	if verifyOpts != nil && verifyOpts.VerifySignature {
+		if err := signature.DefaultSignaturePuller.PullSigningInformation(ctx, repo, store, desc.Digest.String()); err != nil {
+			log.Debugf("pulling signature of gadget policy %q: %v", targetRef.String(), err)
+		}
+		if err := verifyOpts.Verifier.Verify(ctx, repo, store, targetRef); err != nil {
+			return nil, fmt.Errorf("verifying gadget policy signature %q: %w", targetRef.String(), err)
+		}
+	}
+
+	manifestBytes, err := content.FetchAll(ctx, store, desc)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy %q: %w", targetRef.String(), err)
+	}
+	return policyFromManifest(manifestBytes, desc.Digest.String())
+}
+
+// PolicySyncer periodically pulls the gadget policy published as an OCI
+// artifact and keeps the last one it could pull. Until a policy is pulled, all
+// gadgets are denied.
+type PolicySyncer struct {
+	ref      string
+	interval time.Duration
+	fetch    func(ctx context.Context) (*GadgetPolicy, error)
+
+	mu     sync.RWMutex
+	policy *GadgetPolicy
+	err    error
+}
+
+// NewPolicySyncer creates a PolicySyncer pulling the policy published at ref
+// every interval; policies whose signature can't be verified as configured by
+// verifyOpts are ignored
+func NewPolicySyncer(ref string, interval time.Duration, authOpts *AuthOptions, verifyOpts *VerifyOptions) *PolicySyncer {
+	return &PolicySyncer{
+		ref:      ref,
+		interval: interval,
+		fetch: func(ctx context.Context) (*GadgetPolicy, error) {
+			return PullGadgetPolicy(ctx, ref, authOpts, verifyOpts)
+		},
+		err: errors.New("gadget policy not synced yet"),
+	}
+}
+
+// Sync pulls the policy once. If it fails, the last policy is kept.
+func (s *PolicySyncer) Sync(ctx context.Context) error {
+	policy, err := s.fetch(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		if s.policy == nil {
+			s.err = fmt.Errorf("gadget policy %q not synced yet: %w", s.ref, err)
+		}
+		return err
+	}
+	if s.policy == nil || s.policy.Digest != policy.Digest {
+		log.Infof("Using gadget policy %s@%s: %d allowed, %d denied", s.ref, policy.Digest,
+			len(policy.Allowed), len(policy.Denied))
+	}
+	s.policy = policy
+	s.err = nil
+	return nil
+}
+
+// Run syncs the policy every interval until ctx is done
+func (s *PolicySyncer) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Sync(ctx); err != nil {
+				log.Warnf("syncing gadget policy %q: %v", s.ref, err)
+			}
+		}
+	}
+}
+
+// Policy returns the last policy pulled
+func (s *PolicySyncer) Policy() (*GadgetPolicy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy, s.err
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testImage       = "ghcr.io/myorg/gadgets/trace_exec:v1.0.0"
+	testImageDigest = "ghcr.io/myorg/gadgets/trace_exec@sha256:1234"
+)
+
+func TestGadgetPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  GadgetPolicy
+		allowed bool
+	}{
+		{
+			name:    "empty",
+			allowed: true,
+		},
+		{
+			name:    "allowed by name",
+			policy:  GadgetPolicy{Allowed: []string{testImage}},
+			allowed: true,
+		},
+		{
+			name:    "allowed by digest",
+			policy:  GadgetPolicy{Allowed: []string{testImageDigest}},
+			allowed: true,
+		},
+		{
+			name:    "allowed by prefix",
+			policy:  GadgetPolicy{Allowed: []string{"ghcr.io/myorg/*"}},
+			allowed: true,
+		},
+		{
+			name:    "not allowed",
+			policy:  GadgetPolicy{Allowed: []string{"ghcr.io/otherorg/*"}},
+			allowed: false,
+		},
+		{
+			name:    "denied",
+			policy:  GadgetPolicy{Denied: []string{testImage}},
+			allowed: false,
+		},
+		{
+			name:    "denied overrides allowed",
+			policy:  GadgetPolicy{Allowed: []string{"ghcr.io/myorg/*"}, Denied: []string{testImageDigest}},
+			allowed: false,
+		},
+		{
+			name:    "other denied",
+			policy:  GadgetPolicy{Denied: []string{"ghcr.io/myorg/gadgets/trace_open*"}},
+			allowed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.policy.Check(testImage, testImageDigest)
+			if test.allowed {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicyFromManifest(t *testing.T) {
+	manifest := ocispec.Manifest{
+		ArtifactType: GadgetPolicyArtifactType,
+		Annotations: map[string]string{
+			AllowedGadgetsAnnotation: "ghcr.io/myorg/*, ghcr.io/inspektor-gadget/gadget/trace_open:latest",
+			DeniedGadgetsAnnotation:  "",
+		},
+	}
+	b, err := json.Marshal(manifest)
+	require.NoError(t, err)
+
+	policy, err := policyFromManifest(b, "sha256:abcd")
+	require.NoError(t, err)
+	assert.Equal(t, &GadgetPolicy{
+		Allowed: []string{"ghcr.io/myorg/*", "ghcr.io/inspektor-gadget/gadget/trace_open:latest"},
+		Digest:  "sha256:abcd",
+	}, policy)
+
+	manifest.ArtifactType = "application/vnd.gadget.config.v1+yaml"
+	b, err = json.Marshal(manifest)
+	require.NoError(t, err)
+	_, err = policyFromManifest(b, "sha256:abcd")
+	require.ErrorContains(t, err, "unexpected artifact type")
+}
+
+func TestPolicySyncer(t *testing.T) {
+	var next *GadgetPolicy
+	var nextErr error
+
+	s := NewPolicySyncer("ghcr.io/myorg/gadget-policy:latest", 0, &AuthOptions{}, nil)
+	s.fetch = func(ctx context.Context) (*GadgetPolicy, error) {
+		return next, nextErr
+	}
+
+	// Denied until synced
+	_, err := s.Policy()
+	require.Error(t, err)
+
+	nextErr = errors.New("registry unavailable")
+	require.Error(t, s.Sync(context.Background()))
+	_, err = s.Policy()
+	require.ErrorContains(t, err, "registry unavailable")
+
+	next, nextErr = &GadgetPolicy{Allowed: []string{"ghcr.io/myorg/*"}, Digest: "sha256:1"}, nil
+	require.NoError(t, s.Sync(context.Background()))
+	policy, err := s.Policy()
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:1", policy.Digest)
+
+	// The last policy is kept if syncing fails
+	next, nextErr = nil, errors.New("registry unavailable")
+	require.Error(t, s.Sync(context.Background()))
+	policy, err = s.Policy()
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:1", policy.Digest)
+}
@@ -0,0 +1,377 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package aggregate is a data operator that groups the events of data sources
+// by some of their fields and periodically emits the number of events of each
+// group instead of the events themselves. Running it next to the gadget
+// drastically reduces the amount of data sent to clients for gadgets emitting
+// lots of events, like trace_tcpconnect on busy nodes.
+package aggregate
+
+import (
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	Name     = "aggregate"
+	Priority = 9050 // after the filter operator, so only matching events are counted
+
+	ParamAggregateBy       = "aggregate-by"
+	ParamAggregateInterval = "aggregate-interval"
+
+	DataSourcePrefix = "aggregated"
+
+	// CountField holds the number of events of each group
+	CountField = "count"
+)
+
+type aggregateOperator struct{}
+
+func (a *aggregateOperator) Name() string {
+	return Name
+}
+
+func (a *aggregateOperator) Init(params *params.Params) error {
+	return nil
+}
+
+func (a *aggregateOperator) GlobalParams() api.Params {
+	return nil
+}
+
+func (a *aggregateOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamAggregateBy,
+			Title: "Aggregate By",
+			Description: "Group the events by fields and emit the number of events of each group per interval " +
+				"instead of the events. Join multiple fields with ','. " +
+				"If using multiple data sources, prefix fields with 'datasourcename:' and separate with ';'",
+		},
+		{
+			Key:          ParamAggregateInterval,
+			Title:        "Aggregate Interval",
+			Description:  "Interval at which the number of events of each group is emitted",
+			DefaultValue: "1s",
+			TypeHint:     api.TypeDuration,
+		},
+	}
+}
+
+// parseAggregateBy returns the fields to group by per data source; fields
+// given without data source are stored with an empty key
+func parseAggregateBy(s string) (map[string][]string, error) {
+	res := make(map[string][]string)
+	for _, agg := range strings.Split(s, ";") {
+		if agg == "" {
+			continue
+		}
+		dsName, fieldList, ok := strings.Cut(agg, ":")
+		if !ok {
+			dsName, fieldList = "", agg
+		}
+		var fields []string
+		for _, f := range strings.Split(fieldList, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("no fields given for data source %q", dsName)
+		}
+		if _, ok := res[dsName]; ok {
+			return nil, fmt.Errorf("fields given twice for data source %q", dsName)
+		}
+		res[dsName] = fields
+	}
+	if _, ok := res[""]; ok && len(res) > 1 {
+		return nil, fmt.Errorf("mixed fields with and without specifying data source")
+	}
+	return res, nil
+}
+
+func (a *aggregateOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	params := apihelpers.ToParamDescs(a.InstanceParams()).ToParams()
+	if err := params.CopyFromMap(instanceParamValues, ""); err != nil {
+		return nil, err
+	}
+
+	aggregateBy, err := parseAggregateBy(params.Get(ParamAggregateBy).AsString())
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ParamAggregateBy, err)
+	}
+	if len(aggregateBy) == 0 {
+		return nil, nil
+	}
+
+	interval := params.Get(ParamAggregateInterval).AsDuration()
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid value for %s: %s", ParamAggregateInterval, interval)
+	}
+
+	instance := &aggregateOperatorInstance{
+		interval: interval,
+	}
+
+	dataSources := gadgetCtx.GetDataSources()
+	for dsName := range aggregateBy {
+		if _, ok := dataSources[dsName]; dsName != "" && !ok {
+			return nil, fmt.Errorf("data source %q not found", dsName)
+		}
+	}
+
+	for _, ds := range dataSources {
+		fields, ok := aggregateBy[ds.Name()]
+		if !ok {
+			fields, ok = aggregateBy[""]
+		}
+		if !ok {
+			continue
+		}
+
+		// Arrays are already summaries, like the ones of top gadgets
+		if ds.Type() != datasource.TypeSingle {
+			if _, ok := aggregateBy[ds.Name()]; ok {
+				return nil, fmt.Errorf("%s can only be used on data sources emitting single events", ParamAggregateBy)
+			}
+			continue
+		}
+
+		agg, err := newAggregator(gadgetCtx, ds, fields, interval)
+		if err != nil {
+			return nil, fmt.Errorf("aggregating data source %q: %w", ds.Name(), err)
+		}
+		instance.aggregators = append(instance.aggregators, agg)
+
+		gadgetCtx.Logger().Debugf("aggregate: grouping %q by %v into %q", ds.Name(), fields, agg.aggregatedDs.Name())
+	}
+
+	if len(instance.aggregators) == 0 {
+		return nil, nil
+	}
+	return instance, nil
+}
+
+func (a *aggregateOperator) Priority() int {
+	return Priority
+}
+
+// aggregator counts the events of a data source per group
+type aggregator struct {
+	ds           datasource.DataSource
+	aggregatedDs datasource.DataSource
+
+	// fields are the fields of ds to group by and groupFields the matching
+	// fields of aggregatedDs
+	fields      []datasource.FieldAccessor
+	groupFields []datasource.FieldAccessor
+	countField  datasource.FieldAccessor
+
+	mu     sync.Mutex
+	groups map[string]*group
+	// order keeps the groups in the order they were first seen, so the output
+	// is stable without sorting
+	order []string
+}
+
+type group struct {
+	values [][]byte
+	count  uint64
+}
+
+func newAggregator(gadgetCtx operators.GadgetContext, ds datasource.DataSource, fieldNames []string, interval time.Duration) (*aggregator, error) {
+	agg := &aggregator{
+		ds:     ds,
+		groups: make(map[string]*group),
+	}
+
+	for _, name := range fieldNames {
+		f := ds.GetField(name)
+		if f == nil {
+			return nil, fmt.Errorf("field %q not found", name)
+		}
+		if datasource.FieldFlagEmpty.In(f.Flags()) {
+			return nil, fmt.Errorf("field %q has no value to group by", name)
+		}
+		agg.fields = append(agg.fields, f)
+	}
+
+	aggregatedDs, err := gadgetCtx.RegisterDataSource(
+		datasource.TypeArray,
+		fmt.Sprintf("%s-%s", DataSourcePrefix, ds.Name()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("registering data source: %w", err)
+	}
+	agg.aggregatedDs = aggregatedDs
+
+	for _, f := range agg.fields {
+		// Show the fields used for grouping even if they're hidden by default
+		annotations := maps.Clone(f.Annotations())
+		delete(annotations, metadatav1.ColumnsHiddenAnnotation)
+		groupField, err := aggregatedDs.AddField(f.FullName(), f.Type(), datasource.WithAnnotations(annotations))
+		if err != nil {
+			return nil, fmt.Errorf("adding field %q: %w", f.FullName(), err)
+		}
+		agg.groupFields = append(agg.groupFields, groupField)
+	}
+	agg.countField, err = aggregatedDs.AddField(CountField, api.Kind_Uint64, datasource.WithAnnotations(map[string]string{
+		metadatav1.DescriptionAnnotation:  fmt.Sprintf("Number of events in the last %s", interval),
+		metadatav1.ColumnsWidthAnnotation: "10",
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("adding field %q: %w", CountField, err)
+	}
+
+	// Let clients combine and refresh the output of each interval like they do
+	// for top gadgets
+	aggregatedDs.AddAnnotation(api.FetchIntervalAnnotation, interval.String())
+	aggregatedDs.AddAnnotation("cli.clear-screen-before", "true")
+
+	// Only the aggregated events are sent to the following operators and to
+	// clients
+	ds.Unreference()
+
+	return agg, nil
+}
+
+// add counts an event in its group
+func (a *aggregator) add(data datasource.Data) {
+	values := make([][]byte, len(a.fields))
+	var key strings.Builder
+	for i, f := range a.fields {
+		values[i] = f.Get(data)
+		// Prefix the values with their length to keep the key unambiguous
+		key.Write(binary.AppendUvarint(nil, uint64(len(values[i]))))
+		key.Write(values[i])
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	g, ok := a.groups[key.String()]
+	if !ok {
+		// The values point to the payload of the event, which is reused once
+		// it's released
+		for i := range values {
+			values[i] = append([]byte(nil), values[i]...)
+		}
+		g = &group{values: values}
+		a.groups[key.String()] = g
+		a.order = append(a.order, key.String())
+	}
+	g.count++
+}
+
+// emit emits the groups counted since the last call and resets them
+func (a *aggregator) emit() error {
+	a.mu.Lock()
+	groups, order := a.groups, a.order
+	a.groups = make(map[string]*group, len(groups))
+	a.order = nil
+	a.mu.Unlock()
+
+	arr, err := a.aggregatedDs.NewPacketArray()
+	if err != nil {
+		return fmt.Errorf("creating packet array: %w", err)
+	}
+	for _, key := range order {
+		g := groups[key]
+		data := arr.New()
+		for i, f := range a.groupFields {
+			if err := f.Set(data, g.values[i]); err != nil {
+				a.aggregatedDs.Release(arr)
+				return fmt.Errorf("setting %s: %w", f.FullName(), err)
+			}
+		}
+		if err := a.countField.PutUint64(data, g.count); err != nil {
+			a.aggregatedDs.Release(arr)
+			return fmt.Errorf("setting %s: %w", CountField, err)
+		}
+		arr.Append(data)
+	}
+	return a.aggregatedDs.EmitAndRelease(arr)
+}
+
+type aggregateOperatorInstance struct {
+	interval    time.Duration
+	aggregators []*aggregator
+	done        chan struct{}
+}
+
+func (a *aggregateOperatorInstance) Name() string {
+	return Name
+}
+
+func (a *aggregateOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for _, agg := range a.aggregators {
+		err := agg.ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			agg.add(data)
+			return datasource.ErrDiscard
+		}, Priority)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *aggregateOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	a.done = make(chan struct{})
+	go func(done chan struct{}) {
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				for _, agg := range a.aggregators {
+					if err := agg.emit(); err != nil {
+						gadgetCtx.Logger().Errorf("aggregate: emitting %q: %v", agg.aggregatedDs.Name(), err)
+					}
+				}
+			}
+		}
+	}(a.done)
+	return nil
+}
+
+func (a *aggregateOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	if a.done != nil {
+		close(a.done)
+		a.done = nil
+	}
+	return nil
+}
+
+func (a *aggregateOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func init() {
+	operators.RegisterDataOperator(&aggregateOperator{})
+}
@@ -0,0 +1,194 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func TestParseAggregateBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string][]string
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			expected: map[string][]string{},
+		},
+		{
+			name:     "all data sources",
+			value:    "comm,pid",
+			expected: map[string][]string{"": {"comm", "pid"}},
+		},
+		{
+			name:  "per data source",
+			value: "open:comm;exec:pid,uid",
+			expected: map[string][]string{
+				"open": {"comm"},
+				"exec": {"pid", "uid"},
+			},
+		},
+		{
+			name:    "mixed",
+			value:   "comm;exec:pid",
+			wantErr: true,
+		},
+		{
+			name:    "no fields",
+			value:   "exec:",
+			wantErr: true,
+		},
+		{
+			name:    "twice",
+			value:   "exec:pid;exec:uid",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseAggregateBy(test.value)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+type result struct {
+	comm  string
+	count uint64
+}
+
+func TestAggregate(t *testing.T) {
+	var ds datasource.DataSource
+	var commField, pidField datasource.FieldAccessor
+	var got []result
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "test")
+		require.NoError(t, err)
+		commField, err = ds.AddField("comm", api.Kind_String)
+		require.NoError(t, err)
+		pidField, err = ds.AddField("pid", api.Kind_Uint32)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for i, comm := range []string{"curl", "nginx", "curl", "curl", "nginx", "sh"} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, commField.PutString(data, comm))
+			require.NoError(t, pidField.PutUint32(data, uint32(i)))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		dataSources := gadgetCtx.GetDataSources()
+		require.NotContains(t, dataSources, "test")
+		aggregatedDs := dataSources[DataSourcePrefix+"-test"]
+		require.NotNil(t, aggregatedDs)
+		require.Nil(t, aggregatedDs.GetField("pid"))
+
+		aggCommField := aggregatedDs.GetField("comm")
+		require.NotNil(t, aggCommField)
+		countField := aggregatedDs.GetField(CountField)
+		require.NotNil(t, countField)
+
+		return aggregatedDs.SubscribeArray(func(ds datasource.DataSource, arr datasource.DataArray) error {
+			if arr.Len() == 0 {
+				return nil
+			}
+			for i := range arr.Len() {
+				comm, err := aggCommField.String(arr.Get(i))
+				require.NoError(t, err)
+				count, err := countField.Uint64(arr.Get(i))
+				require.NoError(t, err)
+				got = append(got, result{comm: comm, count: count})
+			}
+			cancel()
+			return nil
+		}, Priority+1)
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	o := &aggregateOperator{}
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(o, producer, consumer))
+	err := gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamAggregateBy:       "comm",
+		"operator." + Name + "." + ParamAggregateInterval: "100ms",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []result{
+		{comm: "curl", count: 3},
+		{comm: "nginx", count: 2},
+		{comm: "sh", count: 1},
+	}, got)
+}
+
+func TestAggregateUnknownField(t *testing.T) {
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		ds, err := gadgetCtx.RegisterDataSource(datasource.TypeSingle, "test")
+		require.NoError(t, err)
+		_, err = ds.AddField("comm", api.Kind_String)
+		require.NoError(t, err)
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+	)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	o := &aggregateOperator{}
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(o, producer))
+	err := gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamAggregateBy: "test:uid",
+	})
+	require.ErrorContains(t, err, `field "uid" not found`)
+}
@@ -0,0 +1,253 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ancestry is a data operator that enriches the processes of the
+// events with the chain of their ancestors, like
+// "systemd → containerd-shim → bash → curl", read from /proc. It's disabled by
+// default, as it reads /proc for every event.
+package ancestry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
+)
+
+const (
+	Name     = "ancestry"
+	Priority = 40 // with the other operators enriching events, before sampling them
+
+	ParamEnable   = "ancestry-enable"
+	ParamMaxDepth = "ancestry-max-depth"
+
+	// separator separates the commands of the ancestors
+	separator = " → "
+
+	cacheTTL        = 2 * time.Second
+	cacheMaxEntries = 16384
+)
+
+type ancestryOperator struct {
+	cache *procCache
+}
+
+func (a *ancestryOperator) Name() string {
+	return Name
+}
+
+func (a *ancestryOperator) Init(params *params.Params) error {
+	a.cache = newProcCache(host.HostProcFs, cacheTTL, cacheMaxEntries)
+	return nil
+}
+
+func (a *ancestryOperator) GlobalParams() api.Params {
+	return nil
+}
+
+func (a *ancestryOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:          ParamEnable,
+			Title:        "Process Ancestry",
+			Description:  "Add the chain of ancestors of the processes, read from /proc for every event",
+			DefaultValue: "false",
+			TypeHint:     api.TypeBool,
+		},
+		{
+			Key:          ParamMaxDepth,
+			Title:        "Maximum Depth",
+			Description:  "Maximum number of processes in the chains, including the process itself",
+			DefaultValue: "32",
+			TypeHint:     api.TypeUint,
+		},
+	}
+}
+
+// processAccessors holds the fields of a process struct and the ones added to
+// it. ppid is nil for processes without parent.
+type processAccessors struct {
+	pid  datasource.FieldAccessor
+	comm datasource.FieldAccessor
+	ppid datasource.FieldAccessor
+
+	ancestors     datasource.FieldAccessor
+	ancestorsPids datasource.FieldAccessor
+}
+
+func (a *ancestryOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	params := apihelpers.ToParamDescs(a.InstanceParams()).ToParams()
+	if err := params.CopyFromMap(instanceParamValues, ""); err != nil {
+		return nil, err
+	}
+	if !params.Get(ParamEnable).AsBool() {
+		return nil, nil
+	}
+	maxDepth := params.Get(ParamMaxDepth).AsInt()
+	if maxDepth <= 0 {
+		return nil, fmt.Errorf("%s must be positive", ParamMaxDepth)
+	}
+
+	procAccessors := make(map[datasource.DataSource][]processAccessors)
+	for _, ds := range gadgetCtx.GetDataSources() {
+		for _, proc := range ds.GetFieldsWithTag("type:" + ebpftypes.ProcessTypeName) {
+			pa, err := newProcessAccessors(proc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", proc.FullName(), err)
+			}
+			procAccessors[ds] = append(procAccessors[ds], pa)
+		}
+	}
+	if len(procAccessors) == 0 {
+		return nil, nil
+	}
+
+	return &ancestryOperatorInstance{
+		cache:          a.cache,
+		maxDepth:       maxDepth,
+		procsAccessors: procAccessors,
+	}, nil
+}
+
+func newProcessAccessors(proc datasource.FieldAccessor) (processAccessors, error) {
+	var pa processAccessors
+
+	pid := proc.GetSubFieldsWithTag("type:" + ebpftypes.PidTypeName)
+	if len(pid) != 1 {
+		return pa, fmt.Errorf("expected one %q field", ebpftypes.PidTypeName)
+	}
+	pa.pid = pid[0]
+	comm := proc.GetSubFieldsWithTag("type:" + ebpftypes.CommTypeName)
+	if len(comm) != 1 {
+		return pa, fmt.Errorf("expected one %q field", ebpftypes.CommTypeName)
+	}
+	pa.comm = comm[0]
+	if parent := proc.GetSubFieldsWithTag("type:" + ebpftypes.ParentTypeName); len(parent) == 1 {
+		if ppid := parent[0].GetSubFieldsWithTag("type:" + ebpftypes.PpidTypeName); len(ppid) == 1 {
+			pa.ppid = ppid[0]
+		}
+	}
+
+	var err error
+	pa.ancestors, err = proc.AddSubField("ancestors", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation:     "Commands of the ancestors of the process, from the oldest to the process itself",
+			metadatav1.ColumnsMaxWidthAnnotation: "60",
+		}),
+	)
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "ancestors", err)
+	}
+	pa.ancestorsPids, err = proc.AddSubField("ancestors_pids", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation: "Pids of the ancestors of the process, from the oldest to the process itself",
+		}),
+		datasource.WithFlags(datasource.FieldFlagHidden),
+	)
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "ancestors_pids", err)
+	}
+	return pa, nil
+}
+
+func (a *ancestryOperator) Priority() int {
+	return Priority
+}
+
+type ancestryOperatorInstance struct {
+	cache          *procCache
+	maxDepth       int
+	procsAccessors map[datasource.DataSource][]processAccessors
+}
+
+func (a *ancestryOperatorInstance) Name() string {
+	return Name
+}
+
+// chain returns the ancestors of the process of an event, including itself.
+// The process itself and its parent are taken from the event, as they could
+// have exited already, like short-lived commands.
+func (a *ancestryOperatorInstance) chain(data datasource.Data, pa *processAccessors, now time.Time) []ancestor {
+	pid, err := pa.pid.Uint32(data)
+	if err != nil || pid == 0 {
+		return nil
+	}
+	comm, _ := pa.comm.String(data)
+
+	var ppid uint32
+	if pa.ppid != nil {
+		ppid, _ = pa.ppid.Uint32(data)
+	} else if p, err := a.cache.get(pid, now); err == nil {
+		ppid = p.ppid
+	}
+
+	chain := a.cache.ancestors(ppid, a.maxDepth-1, now)
+	return append(chain, ancestor{pid: pid, comm: comm})
+}
+
+func (a *ancestryOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, accessors := range a.procsAccessors {
+		err := ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			now := time.Now()
+			for i := range accessors {
+				pa := &accessors[i]
+				chain := a.chain(data, pa, now)
+				if len(chain) == 0 {
+					continue
+				}
+
+				comms := make([]string, len(chain))
+				pids := make([]string, len(chain))
+				for i, p := range chain {
+					comms[i] = p.comm
+					pids[i] = strconv.FormatUint(uint64(p.pid), 10)
+				}
+				pa.ancestors.PutString(data, strings.Join(comms, separator))
+				pa.ancestorsPids.PutString(data, strings.Join(pids, ","))
+			}
+			return nil
+		}, Priority)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *ancestryOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (a *ancestryOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (a *ancestryOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &ancestryOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ancestry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+type result struct {
+	ancestors string
+	pids      string
+}
+
+func TestAncestry(t *testing.T) {
+	dir := writeProcFs(t, map[uint32]proc{
+		1:   {"systemd", 0},
+		100: {"containerd-shim", 1},
+		200: {"bash", 100},
+	})
+
+	var ds datasource.DataSource
+	var pidField, commField, ppidField datasource.FieldAccessor
+	var got []result
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "exec")
+		require.NoError(t, err)
+		proc, err := ds.AddField("proc", api.Kind_Invalid, datasource.WithTags("type:"+ebpftypes.ProcessTypeName))
+		require.NoError(t, err)
+		pidField, err = proc.AddSubField("pid", api.Kind_Uint32, datasource.WithTags("type:"+ebpftypes.PidTypeName))
+		require.NoError(t, err)
+		commField, err = proc.AddSubField("comm", api.Kind_String, datasource.WithTags("type:"+ebpftypes.CommTypeName))
+		require.NoError(t, err)
+		parent, err := proc.AddSubField("parent", api.Kind_Invalid, datasource.WithTags("type:"+ebpftypes.ParentTypeName))
+		require.NoError(t, err)
+		ppidField, err = parent.AddSubField("pid", api.Kind_Uint32, datasource.WithTags("type:"+ebpftypes.PpidTypeName))
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		// curl already exited, it's taken from the event
+		for _, p := range []struct {
+			pid  uint32
+			comm string
+			ppid uint32
+		}{
+			{300, "curl", 200},
+			{1, "systemd", 0},
+		} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, pidField.PutUint32(data, p.pid))
+			require.NoError(t, commField.PutString(data, p.comm))
+			require.NoError(t, ppidField.PutUint32(data, p.ppid))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		ancestors := ds.GetField("proc.ancestors")
+		require.NotNil(t, ancestors)
+		pids := ds.GetField("proc.ancestors_pids")
+		require.NotNil(t, pids)
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			var r result
+			var err error
+			r.ancestors, err = ancestors.String(data)
+			require.NoError(t, err)
+			r.pids, err = pids.String(data)
+			require.NoError(t, err)
+			got = append(got, r)
+			if len(got) == 2 {
+				cancel()
+			}
+			return nil
+		}, Priority+1)
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	op := &ancestryOperator{cache: newProcCache(dir, time.Minute, 10)}
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(op, producer, consumer))
+	require.NoError(t, gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamEnable:   "true",
+		"operator." + Name + "." + ParamMaxDepth: "4",
+	}))
+
+	assert.Equal(t, []result{
+		{ancestors: "systemd → containerd-shim → bash → curl", pids: "1,100,200,300"},
+		{ancestors: "systemd", pids: "1"},
+	}, got)
+}
+
+func TestAncestryInvalidMaxDepth(t *testing.T) {
+	op := &ancestryOperator{cache: newProcCache(t.TempDir(), time.Minute, 10)}
+	gadgetCtx := gadgetcontext.New(context.Background(), "", gadgetcontext.WithDataOperators(op))
+	err := gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamEnable:   "true",
+		"operator." + Name + "." + ParamMaxDepth: "0",
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ancestry
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// procInfo holds what's needed from /proc/<pid>/stat to walk the ancestors
+type procInfo struct {
+	comm    string
+	ppid    uint32
+	expires time.Time
+}
+
+// readStat returns the command and the parent pid of a process
+func readStat(procFs string, pid uint32) (string, uint32, error) {
+	b, err := os.ReadFile(filepath.Join(procFs, strconv.FormatUint(uint64(pid), 10), "stat"))
+	if err != nil {
+		return "", 0, err
+	}
+
+	// The command is between parentheses and can contain spaces and
+	// parentheses itself, like "1234 (my (cmd)) S 1 ..."
+	start := bytes.IndexByte(b, '(')
+	end := bytes.LastIndexByte(b, ')')
+	if start < 0 || end < start {
+		return "", 0, fmt.Errorf("invalid stat of pid %d", pid)
+	}
+	fields := bytes.Fields(b[end+1:])
+	if len(fields) < 2 {
+		return "", 0, fmt.Errorf("invalid stat of pid %d", pid)
+	}
+	ppid, err := strconv.ParseUint(string(fields[1]), 10, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("parsing parent pid of pid %d: %w", pid, err)
+	}
+	return string(b[start+1 : end]), uint32(ppid), nil
+}
+
+// procCache caches the processes read from /proc for a short time, as events
+// of the same processes come in bursts. It's short to avoid mixing up
+// processes when pids are reused.
+type procCache struct {
+	procFs     string
+	ttl        time.Duration
+	maxEntries int
+
+	mu    sync.Mutex
+	procs map[uint32]procInfo
+}
+
+func newProcCache(procFs string, ttl time.Duration, maxEntries int) *procCache {
+	return &procCache{
+		procFs:     procFs,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		procs:      make(map[uint32]procInfo),
+	}
+}
+
+func (c *procCache) get(pid uint32, now time.Time) (procInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.procs[pid]; ok && now.Before(p.expires) {
+		return p, nil
+	}
+
+	comm, ppid, err := readStat(c.procFs, pid)
+	if err != nil {
+		return procInfo{}, err
+	}
+	if len(c.procs) >= c.maxEntries {
+		for pid, p := range c.procs {
+			if !now.Before(p.expires) {
+				delete(c.procs, pid)
+			}
+		}
+		// All the entries are recent, start over
+		if len(c.procs) >= c.maxEntries {
+			clear(c.procs)
+		}
+	}
+	p := procInfo{comm: comm, ppid: ppid, expires: now.Add(c.ttl)}
+	c.procs[pid] = p
+	return p, nil
+}
+
+// ancestor is a process in the chain of ancestors of another one
+type ancestor struct {
+	pid  uint32
+	comm string
+}
+
+// ancestors returns the ancestors of a process starting with the given one,
+// from the oldest to the youngest, like [systemd containerd-shim bash]. It
+// stops at processes that don't exist anymore and after maxDepth ones.
+func (c *procCache) ancestors(pid uint32, maxDepth int, now time.Time) []ancestor {
+	var ret []ancestor
+	seen := make(map[uint32]struct{})
+	for pid != 0 && len(ret) < maxDepth {
+		if _, ok := seen[pid]; ok {
+			break
+		}
+		seen[pid] = struct{}{}
+
+		p, err := c.get(pid, now)
+		if err != nil {
+			break
+		}
+		ret = append(ret, ancestor{pid: pid, comm: p.comm})
+		pid = p.ppid
+	}
+	// They were collected from the youngest
+	slices.Reverse(ret)
+	return ret
+}
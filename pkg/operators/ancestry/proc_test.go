@@ -0,0 +1,110 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ancestry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type proc struct {
+	comm string
+	ppid uint32
+}
+
+// writeProcFs writes the stat files of fake processes, by pid
+func writeProcFs(t *testing.T, procs map[uint32]proc) string {
+	dir := t.TempDir()
+	for pid, p := range procs {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, fmt.Sprint(pid)), 0o755))
+		stat := fmt.Sprintf("%d (%s) S %d %d 0 0 -1 4194560 0 0 0 0 0 0\n", pid, p.comm, p.ppid, pid)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprint(pid), "stat"), []byte(stat), 0o644))
+	}
+	return dir
+}
+
+func TestReadStat(t *testing.T) {
+	t.Parallel()
+
+	dir := writeProcFs(t, map[uint32]proc{
+		1:   {"systemd", 0},
+		100: {"my (weird) cmd", 1},
+	})
+
+	comm, ppid, err := readStat(dir, 100)
+	require.NoError(t, err)
+	assert.Equal(t, "my (weird) cmd", comm)
+	assert.Equal(t, uint32(1), ppid)
+
+	_, _, err = readStat(dir, 200)
+	require.Error(t, err)
+}
+
+func TestAncestors(t *testing.T) {
+	t.Parallel()
+
+	dir := writeProcFs(t, map[uint32]proc{
+		1:   {"systemd", 0},
+		100: {"containerd-shim", 1},
+		200: {"bash", 100},
+		// 300 has a parent that doesn't exist anymore
+		300: {"orphan", 250},
+		// 400 and 401 are each other's parent
+		400: {"a", 401},
+		401: {"b", 400},
+	})
+	c := newProcCache(dir, time.Minute, 10)
+	now := time.Now()
+
+	assert.Equal(t, []ancestor{{1, "systemd"}, {100, "containerd-shim"}, {200, "bash"}}, c.ancestors(200, 10, now))
+	assert.Equal(t, []ancestor{{100, "containerd-shim"}, {200, "bash"}}, c.ancestors(200, 2, now))
+	assert.Equal(t, []ancestor{{300, "orphan"}}, c.ancestors(300, 10, now))
+	assert.Equal(t, []ancestor{{401, "b"}, {400, "a"}}, c.ancestors(400, 10, now))
+	assert.Empty(t, c.ancestors(0, 10, now))
+	assert.Empty(t, c.ancestors(999, 10, now))
+}
+
+func TestProcCache(t *testing.T) {
+	t.Parallel()
+
+	dir := writeProcFs(t, map[uint32]proc{
+		1: {"systemd", 0},
+		2: {"kthreadd", 0},
+	})
+	c := newProcCache(dir, time.Second, 1)
+	now := time.Now()
+
+	p, err := c.get(1, now)
+	require.NoError(t, err)
+	assert.Equal(t, "systemd", p.comm)
+
+	// Cached processes aren't read again until they expire
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "1")))
+	_, err = c.get(1, now)
+	require.NoError(t, err)
+	_, err = c.get(1, now.Add(time.Second))
+	require.Error(t, err)
+
+	// The cache doesn't grow beyond its maximum size
+	_, err = c.get(2, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Len(t, c.procs, 1)
+}
@@ -20,7 +20,6 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
-	"sync"
 
 	"github.com/cilium/ebpf/btf"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -37,11 +36,6 @@ const (
 	kernelTypesVar = "kernelTypes"
 )
 
-var kernelHasBTF func() bool = sync.OnceValue(func() bool {
-	_, err := btf.LoadKernelSpec()
-	return err == nil
-})
-
 type btfgenOperator struct{}
 
 func (o *btfgenOperator) Name() string {
@@ -61,7 +55,7 @@ func (o *btfgenOperator) InstantiateImageOperator(
 	logger := gadgetCtx.Logger()
 
 	// If the kernel exposes BTF; nothing to do
-	if kernelHasBTF() {
+	if btfgen.KernelHasBTF() {
 		logger.Debugf("kernel provides BTF, nothing to do on btfgen operator")
 		return nil, nil
 	}
@@ -80,7 +74,9 @@ func (o *btfgenOperator) InstantiateImageOperator(
 	btfFileName := fmt.Sprintf("%s/%s/%s/%s.btf", info.ID, info.VersionID, info.Arch, info.Kernel)
 	btfBytes, err := getBTFFile(r, btfFileName)
 	if err != nil {
-		return nil, fmt.Errorf("getting BTF file: %w", err)
+		// The ebpf operator falls back to BTFHub, if configured
+		logger.Debugf("gadget doesn't ship BTF for this kernel: %s", err)
+		return nil, nil
 	}
 
 	btfSpec, err := btf.LoadSpecFromReader(bytes.NewReader(btfBytes))
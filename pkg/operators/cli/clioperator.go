@@ -56,6 +56,9 @@ const (
 	ModeNone       = "none"
 	ModeRaw        = "raw"
 	ModePCAPNG     = "pcap-ng"
+	ModeFolded     = "folded"
+	ModePprof      = "pprof"
+	ModeSVG        = "svg"
 
 	DefaultOutputMode = ModeColumns
 
@@ -131,6 +134,9 @@ type cliOperatorInstance struct {
 	supportedOutputModes map[string][]string
 	// key: datasource name, value: default output mode
 	defaultOutputMode map[string]string
+	// stacks printed after the gadget is stopped, for the folded, pprof and
+	// svg output modes
+	stackCollectors []*stackCollector
 }
 
 func (o *cliOperatorInstance) Name() string {
@@ -484,6 +490,32 @@ func (o *cliOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error
 					return nil
 				}, Priority)
 			}
+		case ModeFolded, ModePprof, ModeSVG:
+			sc, err := newStackCollector(ds, mode)
+			if err != nil {
+				gadgetCtx.Logger().Warnf("failed to initialize %s output: %v; skipping data source %q", mode, err, ds.Name())
+				continue
+			}
+			if imageName := gadgetCtx.ImageName(); imageName != "" {
+				sc.title = imageName
+			}
+
+			switch ds.Type() {
+			case datasource.TypeSingle:
+				ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+					sc.add(data)
+					return nil
+				}, Priority)
+			case datasource.TypeArray:
+				ds.SubscribeArray(func(ds datasource.DataSource, dataArray datasource.DataArray) error {
+					for i := 0; i < dataArray.Len(); i++ {
+						sc.add(dataArray.Get(i))
+					}
+					return nil
+				}, Priority)
+			}
+
+			o.stackCollectors = append(o.stackCollectors, sc)
 		case ModePCAPNG:
 			// Check ds for compatiblity
 			payloadField := ds.GetField(ds.Annotations()[AnnotationPCAPPayload])
@@ -652,6 +684,20 @@ func (o *cliOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
 	return nil
 }
 
+// PostStop prints the stacks of the folded, pprof and svg output modes; it's called
+// once all operators are stopped, so that maps flushed on stop are included
+func (o *cliOperatorInstance) PostStop(gadgetCtx operators.GadgetContext) error {
+	cliWriteMutex.Lock()
+	defer cliWriteMutex.Unlock()
+
+	for _, sc := range o.stackCollectors {
+		if err := sc.write(os.Stdout); err != nil {
+			return fmt.Errorf("writing %s output: %w", sc.mode, err)
+		}
+	}
+	return nil
+}
+
 func (o *cliOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
 	return nil
 }
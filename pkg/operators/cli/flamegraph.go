@@ -0,0 +1,160 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clioperator
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Layout of the flame graphs, similar to the defaults of flamegraph.pl
+const (
+	flamegraphWidth       = 1200
+	flamegraphFrameHeight = 16
+	flamegraphFontSize    = 12
+	flamegraphPadTop      = 36
+	flamegraphPadBottom   = 10
+	flamegraphPadSide     = 10
+	// Frames narrower than this (in pixels) are omitted
+	flamegraphMinWidth = 0.1
+)
+
+type flameNode struct {
+	name     string
+	value    uint64
+	children map[string]*flameNode
+}
+
+func (n *flameNode) child(name string) *flameNode {
+	if c, ok := n.children[name]; ok {
+		return c
+	}
+	c := &flameNode{name: name, children: make(map[string]*flameNode)}
+	n.children[name] = c
+	return c
+}
+
+func (n *flameNode) depth() int {
+	d := 0
+	for _, c := range n.children {
+		d = max(d, c.depth()+1)
+	}
+	return d
+}
+
+// sortedChildren returns the children ordered by name, as flamegraph.pl does;
+// the x-axis of a flame graph doesn't show the passage of time
+func (n *flameNode) sortedChildren() []*flameNode {
+	children := make([]*flameNode, 0, len(n.children))
+	for _, c := range n.children {
+		children = append(children, c)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].name < children[j].name
+	})
+	return children
+}
+
+// buildFlameTree merges stacks whose frames are separated by ";", from the
+// root to the leaf, into a tree
+func buildFlameTree(stacks map[string]uint64) *flameNode {
+	root := &flameNode{name: "all", children: make(map[string]*flameNode)}
+	for stack, value := range stacks {
+		root.value += value
+		n := root
+		for _, frame := range strings.Split(stack, ";") {
+			n = n.child(frame)
+			n.value += value
+		}
+	}
+	return root
+}
+
+// flameColor returns a warm color that is stable for a given frame name
+func flameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	r := 205 + v%50
+	g := (v >> 8) % 230
+	b := (v >> 16) % 55
+	return fmt.Sprintf("rgb(%d,%d,%d)", r, g, b)
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// writeFlamegraphSVG renders the stacks as a flame graph in SVG format, with
+// the root at the bottom and the width of the frames proportional to their
+// weight
+func writeFlamegraphSVG(w io.Writer, title string, unit string, stacks map[string]uint64) error {
+	root := buildFlameTree(stacks)
+	depth := root.depth()
+	height := flamegraphPadTop + (depth+1)*flamegraphFrameHeight + flamegraphPadBottom
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<?xml version="1.0" standalone="no"?>
+<svg version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">
+<rect x="0" y="0" width="%d" height="%d" fill="rgb(248,248,248)"/>
+<text x="%d" y="24" font-size="17" font-family="Verdana" text-anchor="middle">%s</text>
+`, flamegraphWidth, height, flamegraphWidth, height, flamegraphWidth, height, flamegraphWidth/2, xmlEscape(title))
+
+	if root.value > 0 {
+		scale := float64(flamegraphWidth-2*flamegraphPadSide) / float64(root.value)
+		var draw func(n *flameNode, x float64, level int)
+		draw = func(n *flameNode, x float64, level int) {
+			width := float64(n.value) * scale
+			if width < flamegraphMinWidth {
+				return
+			}
+			y := height - flamegraphPadBottom - (level+1)*flamegraphFrameHeight
+			info := fmt.Sprintf("%s (%d %s, %.2f%%)", n.name, n.value, unit,
+				100*float64(n.value)/float64(root.value))
+
+			fmt.Fprintf(bw, "<g>\n<title>%s</title>\n", xmlEscape(info))
+			fmt.Fprintf(bw, "<rect x=\"%.1f\" y=\"%d\" width=\"%.1f\" height=\"%d\" fill=\"%s\" rx=\"2\" ry=\"2\"/>\n",
+				x, y, width, flamegraphFrameHeight-1, flameColor(n.name))
+
+			// Only print the names that fit, at least partially
+			chars := int((width - 6) / (flamegraphFontSize * 0.59))
+			if chars >= 3 {
+				label := n.name
+				if len(label) > chars {
+					label = label[:chars-2] + ".."
+				}
+				fmt.Fprintf(bw, "<text x=\"%.1f\" y=\"%d\" font-size=\"%d\" font-family=\"Verdana\">%s</text>\n",
+					x+3, y+flamegraphFrameHeight-5, flamegraphFontSize, xmlEscape(label))
+			}
+			fmt.Fprintf(bw, "</g>\n")
+
+			for _, c := range n.sortedChildren() {
+				draw(c, x, level+1)
+				x += float64(c.value) * scale
+			}
+		}
+		draw(root, flamegraphPadSide, 0)
+	}
+
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
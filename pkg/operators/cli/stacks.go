@@ -0,0 +1,316 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clioperator
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/pprof/profile"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+const (
+	// AnnotationFlamegraphLevel defines the position of a field in the stacks
+	// built for the folded, pprof and svg output modes; fields with lower levels
+	// are closer to the root
+	AnnotationFlamegraphLevel = "flamegraph.level"
+
+	// AnnotationFlamegraphType defines how a field is used to build the stacks
+	// for the folded, pprof and svg output modes, see FlamegraphType*
+	AnnotationFlamegraphType = "flamegraph.type"
+
+	// FlamegraphTypeSingle adds the value of the field as a single frame
+	FlamegraphTypeSingle = "single"
+
+	// FlamegraphTypeStack adds the frames of a stack trace formatted as
+	// "[0]leaf; [1]caller; ..."
+	FlamegraphTypeStack = "stack"
+
+	// FlamegraphTypeValue marks the numeric field holding the weight of a
+	// stack; without it, every event counts as one sample
+	FlamegraphTypeValue = "value"
+)
+
+type stackField struct {
+	level    int
+	isStack  bool
+	accessor datasource.FieldAccessor
+}
+
+// stackCollector aggregates the stacks of a DataSource to print them once
+// the gadget is stopped, as folded stacks, as a pprof profile or as a flame
+// graph
+type stackCollector struct {
+	mode      string
+	title     string
+	fields    []stackField
+	value     datasource.FieldAccessor
+	valueType *profile.ValueType
+	start     time.Time
+
+	mu sync.Mutex
+	// key: frames joined with ";" from the root to the leaf, value: weight
+	stacks map[string]uint64
+}
+
+func newStackCollector(ds datasource.DataSource, mode string) (*stackCollector, error) {
+	sc := &stackCollector{
+		mode:      mode,
+		title:     ds.Name(),
+		valueType: &profile.ValueType{Type: "samples", Unit: "count"},
+		start:     time.Now(),
+		stacks:    make(map[string]uint64),
+	}
+
+	for _, f := range ds.Fields() {
+		typ, ok := f.Annotations[AnnotationFlamegraphType]
+		if !ok {
+			continue
+		}
+		acc := ds.GetField(f.FullName)
+		if acc == nil {
+			return nil, fmt.Errorf("field %q not found", f.FullName)
+		}
+
+		switch typ {
+		case FlamegraphTypeValue:
+			if !isNumeric(acc.Type()) {
+				return nil, fmt.Errorf("field %q cannot be used as value: not numeric", f.FullName)
+			}
+			sc.value = acc
+			sc.valueType.Type = strings.TrimSuffix(f.Name, "_raw")
+			if acc.HasAnyTagsOf("type:gadget_duration") {
+				sc.valueType.Unit = "nanoseconds"
+			} else if acc.HasAnyTagsOf("type:gadget_bytes") {
+				sc.valueType.Unit = "bytes"
+			}
+			continue
+		case FlamegraphTypeSingle, FlamegraphTypeStack:
+		default:
+			return nil, fmt.Errorf("invalid %s annotation %q for field %q", AnnotationFlamegraphType, typ, f.FullName)
+		}
+
+		level, err := strconv.Atoi(f.Annotations[AnnotationFlamegraphLevel])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation for field %q: %w", AnnotationFlamegraphLevel, f.FullName, err)
+		}
+
+		// User stacks are structs, their symbols are in a subfield
+		if acc.Type() != api.Kind_String && acc.Type() != api.Kind_CString {
+			symbols := acc.GetSubFieldsWithTag("name:symbols")
+			if len(symbols) != 1 {
+				return nil, fmt.Errorf("field %q is neither a string nor has a symbols subfield", f.FullName)
+			}
+			acc = symbols[0]
+		}
+
+		sc.fields = append(sc.fields, stackField{
+			level:    level,
+			isStack:  typ == FlamegraphTypeStack,
+			accessor: acc,
+		})
+	}
+
+	if len(sc.fields) == 0 {
+		return nil, fmt.Errorf("no fields with the %s annotation", AnnotationFlamegraphType)
+	}
+
+	sort.SliceStable(sc.fields, func(i, j int) bool {
+		return sc.fields[i].level < sc.fields[j].level
+	})
+
+	return sc, nil
+}
+
+func isNumeric(kind api.Kind) bool {
+	switch kind {
+	case api.Kind_Int8, api.Kind_Int16, api.Kind_Int32, api.Kind_Int64,
+		api.Kind_Uint8, api.Kind_Uint16, api.Kind_Uint32, api.Kind_Uint64:
+		return true
+	}
+	return false
+}
+
+func uint64Value(acc datasource.FieldAccessor, data datasource.Data) uint64 {
+	switch acc.Type() {
+	case api.Kind_Int8:
+		v, _ := acc.Int8(data)
+		return uint64(v)
+	case api.Kind_Int16:
+		v, _ := acc.Int16(data)
+		return uint64(v)
+	case api.Kind_Int32:
+		v, _ := acc.Int32(data)
+		return uint64(v)
+	case api.Kind_Int64:
+		v, _ := acc.Int64(data)
+		return uint64(v)
+	case api.Kind_Uint8:
+		v, _ := acc.Uint8(data)
+		return uint64(v)
+	case api.Kind_Uint16:
+		v, _ := acc.Uint16(data)
+		return uint64(v)
+	case api.Kind_Uint32:
+		v, _ := acc.Uint32(data)
+		return uint64(v)
+	case api.Kind_Uint64:
+		v, _ := acc.Uint64(data)
+		return v
+	}
+	return 0
+}
+
+// parseStack returns the frames of a stack formatted as
+// "[0]leaf; [1]caller; ", from the root to the leaf
+func parseStack(s string) []string {
+	var frames []string
+	for _, frame := range strings.Split(s, ";") {
+		frame = strings.TrimSpace(frame)
+		if strings.HasPrefix(frame, "[") {
+			if idx := strings.IndexByte(frame, ']'); idx >= 0 {
+				frame = frame[idx+1:]
+			}
+		}
+		if frame == "" {
+			continue
+		}
+		frames = append(frames, frame)
+	}
+	slices.Reverse(frames)
+	return frames
+}
+
+func (sc *stackCollector) add(data datasource.Data) {
+	var frames []string
+	for _, f := range sc.fields {
+		s, err := f.accessor.String(data)
+		if err != nil || s == "" {
+			continue
+		}
+		if f.isStack {
+			frames = append(frames, parseStack(s)...)
+			continue
+		}
+		// ";" separates the frames of folded stacks
+		frames = append(frames, strings.ReplaceAll(s, ";", "_"))
+	}
+	if len(frames) == 0 {
+		return
+	}
+
+	weight := uint64(1)
+	if sc.value != nil {
+		weight = uint64Value(sc.value, data)
+	}
+	if weight == 0 {
+		return
+	}
+
+	key := strings.Join(frames, ";")
+
+	sc.mu.Lock()
+	sc.stacks[key] += weight
+	sc.mu.Unlock()
+}
+
+func (sc *stackCollector) write(w io.Writer) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	switch sc.mode {
+	case ModePprof:
+		return sc.writePprof(w)
+	case ModeSVG:
+		return writeFlamegraphSVG(w, sc.title, sc.valueType.Unit, sc.stacks)
+	}
+	return sc.writeFolded(w)
+}
+
+// writeFolded prints one line per stack, as expected by flamegraph.pl and
+// most flame graph tools: "root;caller;leaf weight"
+func (sc *stackCollector) writeFolded(w io.Writer) error {
+	keys := make([]string, 0, len(sc.stacks))
+	for k := range sc.stacks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s %d\n", k, sc.stacks[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sc *stackCollector) writePprof(w io.Writer) error {
+	now := time.Now()
+	p := &profile.Profile{
+		SampleType:    []*profile.ValueType{sc.valueType},
+		TimeNanos:     sc.start.UnixNano(),
+		DurationNanos: now.Sub(sc.start).Nanoseconds(),
+	}
+
+	locations := make(map[string]*profile.Location)
+	location := func(name string) *profile.Location {
+		if loc, ok := locations[name]; ok {
+			return loc
+		}
+		fn := &profile.Function{
+			ID:         uint64(len(p.Function) + 1),
+			Name:       name,
+			SystemName: name,
+		}
+		p.Function = append(p.Function, fn)
+		loc := &profile.Location{
+			ID:   uint64(len(p.Location) + 1),
+			Line: []profile.Line{{Function: fn}},
+		}
+		p.Location = append(p.Location, loc)
+		locations[name] = loc
+		return loc
+	}
+
+	keys := make([]string, 0, len(sc.stacks))
+	for k := range sc.stacks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		frames := strings.Split(k, ";")
+		// pprof expects the leaf first
+		locs := make([]*profile.Location, 0, len(frames))
+		for i := len(frames) - 1; i >= 0; i-- {
+			locs = append(locs, location(frames[i]))
+		}
+		p.Sample = append(p.Sample, &profile.Sample{
+			Location: locs,
+			Value:    []int64{int64(sc.stacks[k])},
+		})
+	}
+
+	return p.Write(w)
+}
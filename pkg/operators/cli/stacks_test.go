@@ -0,0 +1,152 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clioperator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func TestParseStack(t *testing.T) {
+	assert.Equal(t, []string{"c", "b", "a"}, parseStack("[0]a; [1]b; [2]c; "))
+	assert.Equal(t, []string{"main", "[unknown]"}, parseStack("[0][unknown]; [1]main; "))
+	assert.Empty(t, parseStack(""))
+}
+
+func newStacksTestDataSource(t *testing.T) (datasource.DataSource, datasource.FieldAccessor, datasource.FieldAccessor, datasource.FieldAccessor) {
+	ds, err := datasource.New(datasource.TypeSingle, "test")
+	require.NoError(t, err)
+
+	comm, err := ds.AddField("comm", api.Kind_String, datasource.WithAnnotations(map[string]string{
+		AnnotationFlamegraphLevel: "10",
+		AnnotationFlamegraphType:  FlamegraphTypeSingle,
+	}))
+	require.NoError(t, err)
+	stack, err := ds.AddField("kern_stack", api.Kind_String, datasource.WithAnnotations(map[string]string{
+		AnnotationFlamegraphLevel: "30",
+		AnnotationFlamegraphType:  FlamegraphTypeStack,
+	}))
+	require.NoError(t, err)
+	value, err := ds.AddField("blocked_raw", api.Kind_Uint64, datasource.WithAnnotations(map[string]string{
+		AnnotationFlamegraphType: FlamegraphTypeValue,
+	}), datasource.WithTags("type:gadget_duration"))
+	require.NoError(t, err)
+
+	return ds, comm, stack, value
+}
+
+func TestStackCollector(t *testing.T) {
+	ds, comm, stack, value := newStacksTestDataSource(t)
+
+	events := []struct {
+		comm  string
+		stack string
+		value uint64
+	}{
+		{"cat", "[0]schedule; [1]do_nanosleep; ", 100},
+		{"cat", "[0]schedule; [1]do_nanosleep; ", 50},
+		{"cat", "[0]schedule; [1]pipe_read; ", 10},
+		{"sh", "[0]schedule; [1]do_wait; ", 0},
+	}
+
+	for _, mode := range []string{ModeFolded, ModePprof} {
+		t.Run(mode, func(t *testing.T) {
+			sc, err := newStackCollector(ds, mode)
+			require.NoError(t, err)
+
+			for _, e := range events {
+				data, err := ds.NewPacketSingle()
+				require.NoError(t, err)
+				require.NoError(t, comm.PutString(data, e.comm))
+				require.NoError(t, stack.PutString(data, e.stack))
+				require.NoError(t, value.PutUint64(data, e.value))
+				sc.add(data)
+			}
+
+			var buf bytes.Buffer
+			require.NoError(t, sc.write(&buf))
+
+			if mode == ModeFolded {
+				assert.Equal(t, "cat;do_nanosleep;schedule 150\ncat;pipe_read;schedule 10\n", buf.String())
+				return
+			}
+
+			p, err := profile.Parse(&buf)
+			require.NoError(t, err)
+			require.Len(t, p.SampleType, 1)
+			assert.Equal(t, "blocked", p.SampleType[0].Type)
+			assert.Equal(t, "nanoseconds", p.SampleType[0].Unit)
+			require.Len(t, p.Sample, 2)
+			sample := p.Sample[0]
+			assert.Equal(t, []int64{150}, sample.Value)
+			require.Len(t, sample.Location, 3)
+			assert.Equal(t, "schedule", sample.Location[0].Line[0].Function.Name)
+			assert.Equal(t, "cat", sample.Location[2].Line[0].Function.Name)
+		})
+	}
+}
+
+func TestStackCollectorNoFields(t *testing.T) {
+	ds, err := datasource.New(datasource.TypeSingle, "test")
+	require.NoError(t, err)
+	_, err = ds.AddField("comm", api.Kind_String)
+	require.NoError(t, err)
+
+	_, err = newStackCollector(ds, ModeFolded)
+	require.Error(t, err)
+}
+
+func TestFlamegraphSVG(t *testing.T) {
+	stacks := map[string]uint64{
+		"cat;do_nanosleep;schedule": 150,
+		"cat;pipe_read;schedule":    50,
+		"sh;<unknown>":              1,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeFlamegraphSVG(&buf, "test", "nanoseconds", stacks))
+
+	// The output must be valid XML
+	var svg struct {
+		XMLName xml.Name `xml:"svg"`
+		Groups  []struct {
+			Title string `xml:"title"`
+		} `xml:"g"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &svg))
+
+	titles := make([]string, 0, len(svg.Groups))
+	for _, g := range svg.Groups {
+		titles = append(titles, g.Title)
+	}
+	assert.Equal(t, []string{
+		"all (201 nanoseconds, 100.00%)",
+		"cat (200 nanoseconds, 99.50%)",
+		"do_nanosleep (150 nanoseconds, 74.63%)",
+		"schedule (150 nanoseconds, 74.63%)",
+		"pipe_read (50 nanoseconds, 24.88%)",
+		"schedule (50 nanoseconds, 24.88%)",
+		"sh (1 nanoseconds, 0.50%)",
+		"<unknown> (1 nanoseconds, 0.50%)",
+	}, titles)
+}
@@ -0,0 +1,378 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clickhouse is a data operator that inserts the events of data
+// sources into ClickHouse tables, whose schema is derived from the fields of
+// the data sources, to store large amounts of events for a long time.
+package clickhouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/common"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/routing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	name     = "clickhouse"
+	Priority = 9999
+
+	ParamClickHouseExporter      = "clickhouse-exporter"
+	ParamClickHouseTable         = "clickhouse-table"
+	ParamClickHouseBatchSize     = "clickhouse-batch-size"
+	ParamClickHouseFlushInterval = "clickhouse-flush-interval"
+
+	DefaultTable         = "{gadget}_{datasource}"
+	DefaultBatchSize     = 10000
+	DefaultFlushInterval = 5 * time.Second
+	DefaultMaxRetries    = 3
+	DefaultTimeout       = 30 * time.Second
+
+	// schemaTimeout is the time to create or update a table
+	schemaTimeout = 30 * time.Second
+)
+
+type exporterConfig struct {
+	DSN           string        `json:"dsn" yaml:"dsn"`
+	Table         string        `json:"table" yaml:"table"`
+	BatchSize     int           `json:"batchSize" yaml:"batchSize"`
+	FlushInterval time.Duration `json:"flushInterval" yaml:"flushInterval"`
+	TTL           time.Duration `json:"ttl" yaml:"ttl"`
+	MaxRetries    *int          `json:"maxRetries" yaml:"maxRetries"`
+	Timeout       time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+type exporter struct {
+	table         string
+	batchSize     int
+	flushInterval time.Duration
+	ttl           time.Duration
+	maxRetries    int
+	executor      executor
+}
+
+func newExporter(exporterName string, cfg *exporterConfig) (*exporter, error) {
+	if cfg.BatchSize < 0 || cfg.FlushInterval < 0 || cfg.TTL < 0 || cfg.Timeout < 0 ||
+		(cfg.MaxRetries != nil && *cfg.MaxRetries < 0) {
+		return nil, fmt.Errorf("exporter %q: batchSize, flushInterval, ttl, maxRetries and timeout can't be negative", exporterName)
+	}
+	e := &exporter{
+		table:         cfg.Table,
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		ttl:           cfg.TTL,
+		maxRetries:    DefaultMaxRetries,
+	}
+	if e.table == "" {
+		e.table = DefaultTable
+	}
+	if e.batchSize == 0 {
+		e.batchSize = DefaultBatchSize
+	}
+	if e.flushInterval == 0 {
+		e.flushInterval = DefaultFlushInterval
+	}
+	if cfg.MaxRetries != nil {
+		e.maxRetries = *cfg.MaxRetries
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	c, err := newClient(cfg.DSN, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("exporter %q: %w", exporterName, err)
+	}
+	e.executor = c
+	return e, nil
+}
+
+// expandTable fills in the placeholders of a table name template
+func expandTable(template, gadget, dataSource string) string {
+	return identifier(strings.NewReplacer(
+		"{gadget}", gadget,
+		"{datasource}", dataSource,
+	).Replace(template))
+}
+
+type clickHouseOperator struct {
+	exporters map[string]*exporter
+	node      string
+}
+
+func (c *clickHouseOperator) Name() string {
+	return name
+}
+
+func (c *clickHouseOperator) Init(params *params.Params) error {
+	c.exporters = make(map[string]*exporter)
+
+	c.node = os.Getenv("NODE_NAME")
+	if c.node == "" {
+		c.node, _ = os.Hostname()
+	}
+
+	if config.Config == nil {
+		return nil
+	}
+
+	configs := make(map[string]*exporterConfig)
+	log.Debugf("loading clickhouse exporters")
+	err := config.Config.UnmarshalKey("operator.clickhouse.exporters", &configs)
+	if err != nil {
+		log.Warnf("failed to load operator.clickhouse.exporters: %v", err)
+	}
+	for exporterName, cfg := range configs {
+		e, err := newExporter(exporterName, cfg)
+		if err != nil {
+			return err
+		}
+		c.exporters[exporterName] = e
+		log.Debugf("> clickhouse exporter %q loaded", exporterName)
+	}
+	return nil
+}
+
+func (c *clickHouseOperator) GlobalParams() api.Params {
+	return api.Params{}
+}
+
+func (c *clickHouseOperator) InstanceParams() api.Params {
+	return api.Params{
+		&api.Param{
+			Key:          ParamClickHouseExporter,
+			Description:  "ClickHouse exporter to insert the events with, as DATASOURCE:EXPORTER or EXPORTER for all data sources",
+			DefaultValue: "",
+		},
+		&api.Param{
+			Key:          ParamClickHouseTable,
+			Description:  "Table to insert the events into, overriding the one of the exporter, as DATASOURCE:TABLE or TABLE for all data sources",
+			DefaultValue: "",
+		},
+		&api.Param{
+			Key:          ParamClickHouseBatchSize,
+			Description:  "Maximum number of events inserted at once; 0 uses the setting of the exporter",
+			DefaultValue: "0",
+			TypeHint:     api.TypeUint,
+		},
+		&api.Param{
+			Key:          ParamClickHouseFlushInterval,
+			Description:  "Maximum time events are queued before being inserted; 0 uses the setting of the exporter",
+			DefaultValue: "0s",
+			TypeHint:     api.TypeDuration,
+		},
+	}
+}
+
+func (c *clickHouseOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	if len(c.exporters) == 0 {
+		return nil, nil
+	}
+	mappings, err := apihelpers.GetStringValuesPerDataSource(instanceParamValues[ParamClickHouseExporter])
+	if err != nil {
+		return nil, fmt.Errorf("parsing exporter mappings: %w", err)
+	}
+	tables, err := apihelpers.GetStringValuesPerDataSource(instanceParamValues[ParamClickHouseTable])
+	if err != nil {
+		return nil, fmt.Errorf("parsing tables: %w", err)
+	}
+	var batchSize int
+	if v := instanceParamValues[ParamClickHouseBatchSize]; v != "" {
+		batchSize, err = strconv.Atoi(v)
+		if err != nil || batchSize < 0 {
+			return nil, fmt.Errorf("invalid batch size %q", v)
+		}
+	}
+	var flushInterval time.Duration
+	if v := instanceParamValues[ParamClickHouseFlushInterval]; v != "" {
+		flushInterval, err = time.ParseDuration(v)
+		if err != nil || flushInterval < 0 {
+			return nil, fmt.Errorf("invalid flush interval %q", v)
+		}
+	}
+
+	inst := &clickHouseOperatorInstance{
+		node:    c.node,
+		outputs: make(map[datasource.DataSource]*dsOutputs),
+	}
+	gadget := common.GadgetName(gadgetCtx.ImageName())
+	for _, ds := range gadgetCtx.GetDataSources() {
+		exporterName, ok := mappings[ds.Name()]
+		if !ok {
+			exporterName = mappings[""]
+		}
+		if _, ok := c.exporters[exporterName]; exporterName != "" && !ok {
+			return nil, fmt.Errorf("clickhouse exporter not found: %q", exporterName)
+		}
+		dispatcher := routing.NewDispatcher(gadgetCtx, ds, name, exporterName, func(name string) bool {
+			_, ok := c.exporters[name]
+			return ok
+		})
+		if dispatcher == nil {
+			continue
+		}
+		table, hasTable := tables[ds.Name()]
+		if !hasTable {
+			table, hasTable = tables[""]
+		}
+		outs := &dsOutputs{dispatcher: dispatcher, outputs: make(map[string]*output)}
+		for _, exporterName := range dispatcher.Exporters() {
+			e := c.exporters[exporterName]
+			out := &output{
+				exporter: e,
+				inserter: &inserter{
+					executor:      e.executor,
+					table:         expandTable(e.table, gadget, ds.Name()),
+					batchSize:     e.batchSize,
+					flushInterval: e.flushInterval,
+					maxRetries:    e.maxRetries,
+				},
+			}
+			if hasTable {
+				out.inserter.table = expandTable(table, gadget, ds.Name())
+			}
+			if batchSize > 0 {
+				out.inserter.batchSize = batchSize
+			}
+			if flushInterval > 0 {
+				out.inserter.flushInterval = flushInterval
+			}
+			gadgetCtx.Logger().Debugf("inserting %q into table %q of clickhouse exporter %q", ds.Name(), out.inserter.table, exporterName)
+			outs.outputs[exporterName] = out
+		}
+		inst.outputs[ds] = outs
+	}
+	if len(inst.outputs) == 0 {
+		return nil, nil
+	}
+	return inst, nil
+}
+
+func (c *clickHouseOperator) Priority() int {
+	return Priority
+}
+
+// output is where the events of a data source are inserted
+type output struct {
+	exporter *exporter
+	inserter *inserter
+	started  bool
+}
+
+// dsOutputs holds the outputs of the exporters the events of a data source
+// are inserted with
+type dsOutputs struct {
+	dispatcher *routing.Dispatcher
+	outputs    map[string]*output
+}
+
+type clickHouseOperatorInstance struct {
+	node    string
+	outputs map[datasource.DataSource]*dsOutputs
+}
+
+func (c *clickHouseOperatorInstance) Name() string {
+	return name
+}
+
+// prepareTable creates the table of a data source, or adds the columns it
+// misses
+func prepareTable(ctx context.Context, out *output, cols []*column) error {
+	ctx, cancel := context.WithTimeout(ctx, schemaTimeout)
+	defer cancel()
+
+	table := out.inserter.table
+	if err := out.exporter.executor.exec(ctx, createTableQuery(table, cols, out.exporter.ttl), nil); err != nil {
+		return fmt.Errorf("creating table %q: %w", table, err)
+	}
+	if err := out.exporter.executor.exec(ctx, addColumnsQuery(table, cols), nil); err != nil {
+		return fmt.Errorf("adding columns to table %q: %w", table, err)
+	}
+	return nil
+}
+
+func (c *clickHouseOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, outs := range c.outputs {
+		cols := columns(ds, c.node)
+		for _, out := range outs.outputs {
+			if err := prepareTable(gadgetCtx.Context(), out, cols); err != nil {
+				return err
+			}
+			out.inserter.start()
+			out.started = true
+		}
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			var encoded []byte
+			for _, exporterName := range outs.dispatcher.Route(data) {
+				if encoded == nil {
+					row := make(map[string]any, len(cols))
+					for _, col := range cols {
+						row[col.name] = col.value(data)
+					}
+					var err error
+					encoded, err = json.Marshal(row)
+					if err != nil {
+						gadgetCtx.Logger().Warnf("encoding row of %s: %v", ds.Name(), err)
+						return nil
+					}
+				}
+				outs.outputs[exporterName].inserter.insert(encoded)
+			}
+			return nil
+		}, Priority)
+	}
+	return nil
+}
+
+func (c *clickHouseOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (c *clickHouseOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+// Close inserts the events still queued
+func (c *clickHouseOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	for _, outs := range c.outputs {
+		for _, out := range outs.outputs {
+			if out.started {
+				out.inserter.close()
+				out.started = false
+			}
+		}
+	}
+	return nil
+}
+
+var Operator = &clickHouseOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func TestNewExporter(t *testing.T) {
+	_, err := newExporter("test", &exporterConfig{})
+	require.Error(t, err)
+
+	_, err = newExporter("test", &exporterConfig{DSN: "http://clickhouse:8123", TTL: -time.Hour})
+	require.Error(t, err)
+
+	e, err := newExporter("test", &exporterConfig{DSN: "http://clickhouse:8123"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTable, e.table)
+	assert.Equal(t, DefaultBatchSize, e.batchSize)
+	assert.Equal(t, DefaultFlushInterval, e.flushInterval)
+	assert.Equal(t, DefaultMaxRetries, e.maxRetries)
+}
+
+func TestExpandTable(t *testing.T) {
+	assert.Equal(t, "trace_dns_dns", expandTable(DefaultTable, "trace_dns", "dns"))
+	assert.Equal(t, "ig_trace_dns", expandTable("ig-{gadget}", "trace_dns", "dns"))
+}
+
+func runProducer(t *testing.T, o *clickHouseOperator, paramValues api.ParamValues) {
+	var ds datasource.DataSource
+	var name, qtype, latency datasource.FieldAccessor
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "dns")
+		require.NoError(t, err)
+		name, err = ds.AddField("name", api.Kind_String)
+		require.NoError(t, err)
+		qtype, err = ds.AddField("qtype", api.Kind_Uint16, datasource.WithFlags(datasource.FieldFlagHidden))
+		require.NoError(t, err)
+		latency, err = ds.AddField("latency_ns", api.Kind_Uint64)
+		require.NoError(t, err)
+		_, err = ds.AddField("raw", api.Kind_Bytes)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for i, n := range []string{"example.com.", "example.org."} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, name.PutString(data, n))
+			require.NoError(t, qtype.PutUint16(data, 28))
+			require.NoError(t, latency.PutUint64(data, uint64(i+1)*1000))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		cancel()
+		return nil
+	}
+
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "ghcr.io/inspektor-gadget/gadget/trace_dns:latest",
+		gadgetcontext.WithDataOperators(o, producer))
+	require.NoError(t, gadgetCtx.Run(paramValues))
+}
+
+func TestInsertEvents(t *testing.T) {
+	executor := &fakeExecutor{}
+	o := &clickHouseOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"forensics": {
+				table:         DefaultTable,
+				batchSize:     DefaultBatchSize,
+				flushInterval: time.Hour,
+				ttl:           14 * 24 * time.Hour,
+				executor:      executor,
+			},
+		},
+	}
+	runProducer(t, o, api.ParamValues{
+		"operator.clickhouse." + ParamClickHouseExporter: "forensics",
+	})
+
+	require.Len(t, executor.queries, 3)
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS `trace_dns_dns` ("+
+		"`event_time` DateTime64(9, 'UTC'), `node` LowCardinality(String), "+
+		"`name` String, `qtype` UInt16, `latency_ns` UInt64"+
+		") ENGINE = MergeTree ORDER BY (`node`, `event_time`) TTL toDateTime(`event_time`) + INTERVAL 1209600 SECOND",
+		executor.queries[0])
+	assert.True(t, strings.HasPrefix(executor.queries[1], "ALTER TABLE `trace_dns_dns` ADD COLUMN IF NOT EXISTS `event_time`"))
+	assert.Equal(t, "INSERT INTO `trace_dns_dns` FORMAT JSONEachRow", executor.queries[2])
+
+	rows := strings.Split(strings.TrimSuffix(executor.bodies[2], "\n"), "\n")
+	require.Len(t, rows, 2)
+	for i, n := range []string{"example.com.", "example.org."} {
+		var row map[string]any
+		require.NoError(t, json.Unmarshal([]byte(rows[i]), &row))
+		assert.Equal(t, n, row["name"])
+		assert.Equal(t, "node-1", row[ColumnNode])
+		assert.EqualValues(t, 28, row["qtype"])
+		assert.EqualValues(t, (i+1)*1000, row["latency_ns"])
+		_, err := time.Parse(eventTimeFormat, row[ColumnEventTime].(string))
+		assert.NoError(t, err)
+	}
+}
+
+func TestInsertEventsWithParams(t *testing.T) {
+	executor := &fakeExecutor{}
+	o := &clickHouseOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"forensics": {
+				table:         DefaultTable,
+				batchSize:     DefaultBatchSize,
+				flushInterval: time.Hour,
+				executor:      executor,
+			},
+		},
+	}
+	runProducer(t, o, api.ParamValues{
+		"operator.clickhouse." + ParamClickHouseExporter:  "dns:forensics",
+		"operator.clickhouse." + ParamClickHouseTable:     "dns:queries",
+		"operator.clickhouse." + ParamClickHouseBatchSize: "1",
+	})
+
+	// One insert per event
+	require.Len(t, executor.queries, 4)
+	assert.True(t, strings.HasPrefix(executor.queries[0], "CREATE TABLE IF NOT EXISTS `queries`"))
+	assert.Equal(t, "INSERT INTO `queries` FORMAT JSONEachRow", executor.queries[2])
+	assert.Equal(t, "INSERT INTO `queries` FORMAT JSONEachRow", executor.queries[3])
+}
+
+func TestNoExporter(t *testing.T) {
+	executor := &fakeExecutor{}
+	o := &clickHouseOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"forensics": {table: DefaultTable, batchSize: 1, flushInterval: time.Hour, executor: executor},
+		},
+	}
+	runProducer(t, o, api.ParamValues{})
+	assert.Empty(t, executor.queries)
+}
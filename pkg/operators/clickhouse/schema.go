@@ -0,0 +1,151 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+const (
+	// ColumnEventTime and ColumnNode are added to the columns of the fields
+	ColumnEventTime = "event_time"
+	ColumnNode      = "node"
+
+	// eventTimeFormat is the format of DateTime64(9) values
+	eventTimeFormat = "2006-01-02 15:04:05.000000000"
+)
+
+// column is a column of a table, with the function getting its value from the
+// events
+type column struct {
+	name   string
+	chType string
+	value  func(datasource.Data) any
+}
+
+var identifierRegex = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// identifier makes s usable as name of a table or column
+func identifier(s string) string {
+	return identifierRegex.ReplaceAllString(s, "_")
+}
+
+// quote quotes an identifier
+func quote(s string) string {
+	return "`" + s + "`"
+}
+
+// valueFunc returns the function getting the value of a field for a row, with
+// the ClickHouse type of its column
+func valueFunc(f datasource.FieldAccessor) (string, func(datasource.Data) any, error) {
+	switch f.Type() {
+	case api.Kind_Bool:
+		return "Bool", func(data datasource.Data) any { v, _ := f.Bool(data); return v }, nil
+	case api.Kind_Int8:
+		return "Int8", func(data datasource.Data) any { v, _ := f.Int8(data); return v }, nil
+	case api.Kind_Int16:
+		return "Int16", func(data datasource.Data) any { v, _ := f.Int16(data); return v }, nil
+	case api.Kind_Int32:
+		return "Int32", func(data datasource.Data) any { v, _ := f.Int32(data); return v }, nil
+	case api.Kind_Int64:
+		return "Int64", func(data datasource.Data) any { v, _ := f.Int64(data); return v }, nil
+	case api.Kind_Uint8:
+		return "UInt8", func(data datasource.Data) any { v, _ := f.Uint8(data); return v }, nil
+	case api.Kind_Uint16:
+		return "UInt16", func(data datasource.Data) any { v, _ := f.Uint16(data); return v }, nil
+	case api.Kind_Uint32:
+		return "UInt32", func(data datasource.Data) any { v, _ := f.Uint32(data); return v }, nil
+	case api.Kind_Uint64:
+		return "UInt64", func(data datasource.Data) any { v, _ := f.Uint64(data); return v }, nil
+	case api.Kind_Float32:
+		return "Float32", func(data datasource.Data) any { v, _ := f.Float32(data); return v }, nil
+	case api.Kind_Float64:
+		return "Float64", func(data datasource.Data) any { v, _ := f.Float64(data); return v }, nil
+	case api.Kind_String, api.Kind_CString:
+		return "String", func(data datasource.Data) any { v, _ := f.String(data); return v }, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported field type %s", f.Type())
+	}
+}
+
+// columns derives the columns of the table of a data source from its fields.
+// Field names are turned into column names like k8s.podName to k8s_podName;
+// fields of unsupported types are skipped.
+func columns(ds datasource.DataSource, node string) []*column {
+	cols := []*column{
+		{
+			name:   ColumnEventTime,
+			chType: "DateTime64(9, 'UTC')",
+			value: func(datasource.Data) any {
+				return time.Now().UTC().Format(eventTimeFormat)
+			},
+		},
+		{
+			name:   ColumnNode,
+			chType: "LowCardinality(String)",
+			value:  func(datasource.Data) any { return node },
+		},
+	}
+	for _, f := range ds.Accessors(false) {
+		flags := f.Flags()
+		if len(f.SubFields()) > 0 || datasource.FieldFlagUnreferenced.In(flags) || datasource.FieldFlagEmpty.In(flags) {
+			continue
+		}
+		chType, fn, err := valueFunc(f)
+		if err != nil {
+			continue
+		}
+		cols = append(cols, &column{name: identifier(f.FullName()), chType: chType, value: fn})
+	}
+	return cols
+}
+
+// createTableQuery returns the query creating the table if it doesn't exist;
+// rows older than ttl are removed if it is set
+func createTableQuery(table string, cols []*column, ttl time.Duration) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "CREATE TABLE IF NOT EXISTS %s (", quote(table))
+	for i, col := range cols {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%s %s", quote(col.name), col.chType)
+	}
+	fmt.Fprintf(&sb, ") ENGINE = MergeTree ORDER BY (%s, %s)", quote(ColumnNode), quote(ColumnEventTime))
+	if ttl > 0 {
+		fmt.Fprintf(&sb, " TTL toDateTime(%s) + INTERVAL %d SECOND", quote(ColumnEventTime), int64(ttl.Seconds()))
+	}
+	return sb.String()
+}
+
+// addColumnsQuery returns the query adding the columns missing in an existing
+// table, like ones of fields added by newer versions of a gadget
+func addColumnsQuery(table string, cols []*column) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ALTER TABLE %s", quote(table))
+	for i, col := range cols {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, " ADD COLUMN IF NOT EXISTS %s %s", quote(col.name), col.chType)
+	}
+	return sb.String()
+}
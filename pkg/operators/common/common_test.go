@@ -119,11 +119,14 @@ func TestInventoryCacheAdd(t *testing.T) {
 		t.Run(tc.testName, func(t *testing.T) {
 			// Prepare the cache with all maps.
 			cache := &inventoryCache{
-				pods:     cachedmap.NewCachedMap[string, *SlimPod](time.Second),
-				podsByIp: cachedmap.NewCachedMap[string, *SlimPod](time.Second),
-				svcs:     cachedmap.NewCachedMap[string, *SlimService](time.Second),
-				svcsByIp: cachedmap.NewCachedMap[string, *SlimService](time.Second),
+				pods:           cachedmap.NewCachedMap[string, *SlimPod](time.Second),
+				podsByIp:       cachedmap.NewCachedMap[string, *SlimPod](time.Second),
+				svcs:           cachedmap.NewCachedMap[string, *SlimService](time.Second),
+				svcsByIp:       cachedmap.NewCachedMap[string, *SlimService](time.Second),
+				svcsByNodePort: cachedmap.NewCachedMap[int32, *SlimService](time.Second),
+				nodesByIp:      cachedmap.NewCachedMap[string, string](time.Second),
 			}
+			defer cache.Close()
 
 			// If we expect an error, capture log output.
 			if !tc.ok {
@@ -216,11 +219,14 @@ func TestInventoryCacheUpdate(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.testName, func(t *testing.T) {
 			cache := &inventoryCache{
-				pods:     cachedmap.NewCachedMap[string, *SlimPod](time.Nanosecond),
-				podsByIp: cachedmap.NewCachedMap[string, *SlimPod](time.Nanosecond),
-				svcs:     cachedmap.NewCachedMap[string, *SlimService](time.Nanosecond),
-				svcsByIp: cachedmap.NewCachedMap[string, *SlimService](time.Nanosecond),
+				pods:           cachedmap.NewCachedMap[string, *SlimPod](time.Nanosecond),
+				podsByIp:       cachedmap.NewCachedMap[string, *SlimPod](time.Nanosecond),
+				svcs:           cachedmap.NewCachedMap[string, *SlimService](time.Nanosecond),
+				svcsByIp:       cachedmap.NewCachedMap[string, *SlimService](time.Nanosecond),
+				svcsByNodePort: cachedmap.NewCachedMap[int32, *SlimService](time.Second),
+				nodesByIp:      cachedmap.NewCachedMap[string, string](time.Second),
 			}
+			defer cache.Close()
 
 			cache.OnAdd(tc.initialObj, false)
 
@@ -291,11 +297,14 @@ func TestInventoryCacheDelete(t *testing.T) {
 		t.Run(tc.testName, func(t *testing.T) {
 			// Use a very short duration so that cache entries can expire.
 			cache := &inventoryCache{
-				pods:     cachedmap.NewCachedMap[string, *SlimPod](time.Nanosecond),
-				podsByIp: cachedmap.NewCachedMap[string, *SlimPod](time.Nanosecond),
-				svcs:     cachedmap.NewCachedMap[string, *SlimService](time.Nanosecond),
-				svcsByIp: cachedmap.NewCachedMap[string, *SlimService](time.Nanosecond),
+				pods:           cachedmap.NewCachedMap[string, *SlimPod](time.Nanosecond),
+				podsByIp:       cachedmap.NewCachedMap[string, *SlimPod](time.Nanosecond),
+				svcs:           cachedmap.NewCachedMap[string, *SlimService](time.Nanosecond),
+				svcsByIp:       cachedmap.NewCachedMap[string, *SlimService](time.Nanosecond),
+				svcsByNodePort: cachedmap.NewCachedMap[int32, *SlimService](time.Second),
+				nodesByIp:      cachedmap.NewCachedMap[string, string](time.Second),
 			}
+			defer cache.Close()
 
 			if tc.ok {
 				cache.OnAdd(tc.initialObj, false)
@@ -362,3 +371,64 @@ func constructService(name, namespace, clusterIP string) *v1.Service {
 		},
 	}
 }
+
+func TestInventoryCacheServices(t *testing.T) {
+	cache := &inventoryCache{
+		svcs:           cachedmap.NewCachedMap[string, *SlimService](time.Millisecond),
+		svcsByIp:       cachedmap.NewCachedMap[string, *SlimService](time.Millisecond),
+		svcsByNodePort: cachedmap.NewCachedMap[int32, *SlimService](time.Millisecond),
+		nodesByIp:      cachedmap.NewCachedMap[string, string](time.Millisecond),
+	}
+	defer cache.Close()
+
+	svc := constructService("web", "default", "10.96.0.20")
+	svc.Spec.Type = v1.ServiceTypeLoadBalancer
+	svc.Spec.ClusterIPs = []string{"10.96.0.20", "fd00::20"}
+	svc.Spec.ExternalIPs = []string{"192.0.2.10"}
+	svc.Spec.Ports = []v1.ServicePort{{Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080}}
+	svc.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "203.0.113.5"}, {Hostname: "lb.example.com"}}
+
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: v1.NodeStatus{
+			Addresses: []v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: "172.18.0.2"},
+				{Type: v1.NodeHostName, Address: "node-1"},
+			},
+		},
+	}
+
+	cache.OnAdd(svc, false)
+	cache.OnAdd(node, false)
+
+	slim := cache.GetSvcByName("default", "web")
+	require.NotNil(t, slim)
+	assert.Equal(t, v1.ServiceTypeLoadBalancer, slim.Spec.Type)
+	assert.Equal(t, []string{"10.96.0.20", "fd00::20", "192.0.2.10", "203.0.113.5"}, slim.IPs())
+	assert.Equal(t, []int32{30080}, slim.NodePorts())
+
+	for _, ip := range slim.IPs() {
+		assert.Equal(t, slim, cache.GetSvcByIp(ip), ip)
+	}
+	assert.Equal(t, slim, cache.GetSvcByNodePort("172.18.0.2", 30080))
+	assert.Nil(t, cache.GetSvcByNodePort("172.18.0.2", 30081))
+	assert.Nil(t, cache.GetSvcByNodePort("172.18.0.3", 30080))
+
+	// Changing the type to ClusterIP removes the other addresses
+	updated := constructService("web", "default", "10.96.0.20")
+	updated.Spec.Type = v1.ServiceTypeClusterIP
+	updated.Spec.Ports = []v1.ServicePort{{Protocol: v1.ProtocolTCP, Port: 80}}
+	cache.OnUpdate(svc, updated)
+
+	// Removed entries are kept until they expire
+	require.Eventually(t, func() bool {
+		return cache.GetSvcByIp("203.0.113.5") == nil && cache.GetSvcByNodePort("172.18.0.2", 30080) == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.NotNil(t, cache.GetSvcByIp("10.96.0.20"))
+}
+
+func TestGadgetName(t *testing.T) {
+	assert.Equal(t, "trace_exec", GadgetName("trace_exec"))
+	assert.Equal(t, "trace_exec", GadgetName("ghcr.io/inspektor-gadget/gadget/trace_exec:latest"))
+	assert.Equal(t, "trace_exec", GadgetName("localhost:5000/trace_exec@sha256:0123"))
+}
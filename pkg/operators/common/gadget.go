@@ -0,0 +1,32 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import "strings"
+
+// GadgetName returns the name of the gadget of an image, like trace_exec for
+// ghcr.io/inspektor-gadget/gadget/trace_exec:latest
+func GadgetName(imageName string) string {
+	if i := strings.LastIndex(imageName, "@"); i >= 0 {
+		imageName = imageName[:i]
+	}
+	if i := strings.LastIndex(imageName, "/"); i >= 0 {
+		imageName = imageName[i+1:]
+	}
+	if i := strings.LastIndex(imageName, ":"); i >= 0 {
+		imageName = imageName[:i]
+	}
+	return imageName
+}
@@ -16,6 +16,7 @@ package common
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -83,15 +84,30 @@ func NewSlimPod(p *v1.Pod) *SlimPod {
 type SlimService struct {
 	metav1.TypeMeta `json:",inline"`
 	SlimObjectMeta  `json:",inline"`
-	Spec            SlimServiceSpec `json:"spec"`
+	Spec            SlimServiceSpec   `json:"spec"`
+	Status          SlimServiceStatus `json:"status"`
 }
 
 type SlimServiceSpec struct {
-	ClusterIP string `json:"clusterIP"`
+	Type        v1.ServiceType    `json:"type"`
+	ClusterIP   string            `json:"clusterIP"`
+	ClusterIPs  []string          `json:"clusterIPs"`
+	ExternalIPs []string          `json:"externalIPs"`
+	Ports       []SlimServicePort `json:"ports"`
+}
+
+type SlimServicePort struct {
+	Protocol v1.Protocol `json:"protocol"`
+	Port     int32       `json:"port"`
+	NodePort int32       `json:"nodePort"`
+}
+
+type SlimServiceStatus struct {
+	LoadBalancerIPs []string `json:"loadBalancerIPs"`
 }
 
 func NewSlimService(s *v1.Service) *SlimService {
-	return &SlimService{
+	svc := &SlimService{
 		TypeMeta: s.TypeMeta,
 		SlimObjectMeta: SlimObjectMeta{
 			Name:            s.Name,
@@ -101,9 +117,58 @@ func NewSlimService(s *v1.Service) *SlimService {
 			OwnerReferences: s.OwnerReferences,
 		},
 		Spec: SlimServiceSpec{
-			ClusterIP: s.Spec.ClusterIP,
+			Type:        s.Spec.Type,
+			ClusterIP:   s.Spec.ClusterIP,
+			ClusterIPs:  s.Spec.ClusterIPs,
+			ExternalIPs: s.Spec.ExternalIPs,
 		},
 	}
+	for _, p := range s.Spec.Ports {
+		svc.Spec.Ports = append(svc.Spec.Ports, SlimServicePort{
+			Protocol: p.Protocol,
+			Port:     p.Port,
+			NodePort: p.NodePort,
+		})
+	}
+	for _, ingress := range s.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			svc.Status.LoadBalancerIPs = append(svc.Status.LoadBalancerIPs, ingress.IP)
+		}
+	}
+	return svc
+}
+
+// IPs returns the IP addresses the service is reachable at: its cluster IPs,
+// its external IPs and the ones of its load balancers.
+func (s *SlimService) IPs() []string {
+	var ips []string
+	add := func(ip string) {
+		if ip != "" && ip != v1.ClusterIPNone && !slices.Contains(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	add(s.Spec.ClusterIP)
+	for _, ip := range s.Spec.ClusterIPs {
+		add(ip)
+	}
+	for _, ip := range s.Spec.ExternalIPs {
+		add(ip)
+	}
+	for _, ip := range s.Status.LoadBalancerIPs {
+		add(ip)
+	}
+	return ips
+}
+
+// NodePorts returns the ports the service is reachable at on the nodes
+func (s *SlimService) NodePorts() []int32 {
+	var ports []int32
+	for _, p := range s.Spec.Ports {
+		if p.NodePort != 0 && !slices.Contains(ports, p.NodePort) {
+			ports = append(ports, p.NodePort)
+		}
+	}
+	return ports
 }
 
 // K8sInventoryCache is a cache of Kubernetes resources such as pods and services
@@ -119,6 +184,9 @@ type K8sInventoryCache interface {
 	GetSvcs() []*SlimService
 	GetSvcByName(namespace string, name string) *SlimService
 	GetSvcByIp(ip string) *SlimService
+	// GetSvcByNodePort returns the service exposed at the given port of the
+	// node with the given IP, if any
+	GetSvcByNodePort(ip string, port uint16) *SlimService
 }
 
 type inventoryCache struct {
@@ -128,10 +196,13 @@ type inventoryCache struct {
 	podsHandler k8sCache.ResourceEventHandlerRegistration
 	svcsHandler k8sCache.ResourceEventHandlerRegistration
 
-	pods     cachedmap.CachedMap[string, *SlimPod]
-	podsByIp cachedmap.CachedMap[string, *SlimPod]
-	svcs     cachedmap.CachedMap[string, *SlimService]
-	svcsByIp cachedmap.CachedMap[string, *SlimService]
+	pods           cachedmap.CachedMap[string, *SlimPod]
+	podsByIp       cachedmap.CachedMap[string, *SlimPod]
+	svcs           cachedmap.CachedMap[string, *SlimService]
+	svcsByIp       cachedmap.CachedMap[string, *SlimService]
+	svcsByNodePort cachedmap.CachedMap[int32, *SlimService]
+	// nodesByIp holds the names of the nodes by IP address
+	nodesByIp cachedmap.CachedMap[string, string]
 
 	exit chan struct{}
 
@@ -189,10 +260,45 @@ func transformObject(obj any) (any, error) {
 				OwnerReferences: t.OwnerReferences,
 			},
 			Spec: v1.ServiceSpec{
-				ClusterIP: t.Spec.ClusterIP,
+				Type:        t.Spec.Type,
+				ClusterIP:   t.Spec.ClusterIP,
+				ClusterIPs:  t.Spec.ClusterIPs,
+				ExternalIPs: t.Spec.ExternalIPs,
 			},
 		}
+		for _, p := range t.Spec.Ports {
+			s.Spec.Ports = append(s.Spec.Ports, v1.ServicePort{
+				Protocol: p.Protocol,
+				Port:     p.Port,
+				NodePort: p.NodePort,
+			})
+		}
+		for _, ingress := range t.Status.LoadBalancer.Ingress {
+			s.Status.LoadBalancer.Ingress = append(s.Status.LoadBalancer.Ingress, v1.LoadBalancerIngress{IP: ingress.IP})
+		}
 		return s, nil
+	case *v1.Node:
+		n := &v1.Node{
+			TypeMeta: t.TypeMeta,
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            t.Name,
+				ResourceVersion: t.ResourceVersion,
+			},
+			Status: v1.NodeStatus{
+				Addresses: t.Status.Addresses,
+			},
+		}
+		return n, nil
+	case *v1.Namespace:
+		ns := &v1.Namespace{
+			TypeMeta: t.TypeMeta,
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            t.Name,
+				ResourceVersion: t.ResourceVersion,
+				Labels:          t.Labels,
+			},
+		}
+		return ns, nil
 	default:
 		return obj, nil
 	}
@@ -234,6 +340,14 @@ func (cache *inventoryCache) Close() {
 		cache.svcsByIp.Close()
 		cache.svcsByIp = nil
 	}
+	if cache.svcsByNodePort != nil {
+		cache.svcsByNodePort.Close()
+		cache.svcsByNodePort = nil
+	}
+	if cache.nodesByIp != nil {
+		cache.nodesByIp.Close()
+		cache.nodesByIp = nil
+	}
 }
 
 func (cache *inventoryCache) Start() {
@@ -249,9 +363,12 @@ func (cache *inventoryCache) Start() {
 		cache.podsByIp = cachedmap.NewCachedMap[string, *SlimPod](2 * time.Second)
 		cache.svcs = cachedmap.NewCachedMap[string, *SlimService](2 * time.Second)
 		cache.svcsByIp = cachedmap.NewCachedMap[string, *SlimService](2 * time.Second)
+		cache.svcsByNodePort = cachedmap.NewCachedMap[int32, *SlimService](2 * time.Second)
+		cache.nodesByIp = cachedmap.NewCachedMap[string, string](2 * time.Second)
 
 		cache.factory.Core().V1().Pods().Informer().AddEventHandler(cache)
 		cache.factory.Core().V1().Services().Informer().AddEventHandler(cache)
+		cache.factory.Core().V1().Nodes().Informer().AddEventHandler(cache)
 		cache.exit = make(chan struct{})
 		cache.factory.Start(cache.exit)
 		cache.factory.WaitForCacheSync(cache.exit)
@@ -310,6 +427,51 @@ func (cache *inventoryCache) GetSvcByIp(ip string) *SlimService {
 	return svc
 }
 
+func (cache *inventoryCache) GetSvcByNodePort(ip string, port uint16) *SlimService {
+	if _, found := cache.nodesByIp.Get(ip); !found {
+		return nil
+	}
+	svc, found := cache.svcsByNodePort.Get(int32(port))
+	if !found {
+		return nil
+	}
+	return svc
+}
+
+func (cache *inventoryCache) addSvc(svc *SlimService) {
+	for _, ip := range svc.IPs() {
+		cache.svcsByIp.Add(ip, svc)
+	}
+	for _, port := range svc.NodePorts() {
+		cache.svcsByNodePort.Add(port, svc)
+	}
+}
+
+func (cache *inventoryCache) removeSvc(svc *SlimService) {
+	for _, ip := range svc.IPs() {
+		cache.svcsByIp.Remove(ip)
+	}
+	for _, port := range svc.NodePorts() {
+		cache.svcsByNodePort.Remove(port)
+	}
+}
+
+func (cache *inventoryCache) addNode(node *v1.Node) {
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == v1.NodeInternalIP || addr.Type == v1.NodeExternalIP {
+			cache.nodesByIp.Add(addr.Address, node.Name)
+		}
+	}
+}
+
+func (cache *inventoryCache) removeNode(node *v1.Node) {
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == v1.NodeInternalIP || addr.Type == v1.NodeExternalIP {
+			cache.nodesByIp.Remove(addr.Address)
+		}
+	}
+}
+
 func (cache *inventoryCache) OnAdd(obj any, _ bool) {
 	switch o := obj.(type) {
 	case *v1.Pod:
@@ -339,15 +501,15 @@ func (cache *inventoryCache) OnAdd(obj any, _ bool) {
 		}
 		slimService := NewSlimService(o)
 		cache.svcs.Add(key, slimService)
-		if ip := slimService.Spec.ClusterIP; ip != "" {
-			cache.svcsByIp.Add(ip, slimService)
-		}
+		cache.addSvc(slimService)
+	case *v1.Node:
+		cache.addNode(o)
 	default:
 		log.Warnf("OnAdd: unknown object type: %T", o)
 	}
 }
 
-func (cache *inventoryCache) OnUpdate(_, newObj any) {
+func (cache *inventoryCache) OnUpdate(oldObj, newObj any) {
 	switch o := newObj.(type) {
 	case *v1.Pod:
 		key, err := k8sCache.MetaNamespaceKeyFunc(o)
@@ -374,11 +536,18 @@ func (cache *inventoryCache) OnUpdate(_, newObj any) {
 			log.Warnf("OnUpdate: empty key for svc")
 			return
 		}
+		// Addresses and ports can change, e.g. when the type changes
+		if old, ok := oldObj.(*v1.Service); ok {
+			cache.removeSvc(NewSlimService(old))
+		}
 		slimService := NewSlimService(o)
 		cache.svcs.Add(key, slimService)
-		if ip := slimService.Spec.ClusterIP; ip != "" {
-			cache.svcsByIp.Add(ip, slimService)
+		cache.addSvc(slimService)
+	case *v1.Node:
+		if old, ok := oldObj.(*v1.Node); ok {
+			cache.removeNode(old)
 		}
+		cache.addNode(o)
 	default:
 		log.Warnf("OnUpdate: unknown object type: %T", o)
 	}
@@ -403,9 +572,9 @@ func (cache *inventoryCache) OnDelete(obj any) {
 			return
 		}
 		cache.svcs.Remove(key)
-		if ip := o.Spec.ClusterIP; ip != "" {
-			cache.svcsByIp.Remove(ip)
-		}
+		cache.removeSvc(NewSlimService(o))
+	case *v1.Node:
+		cache.removeNode(o)
 	case k8sCache.DeletedFinalStateUnknown:
 		cache.OnDelete(o.Obj)
 	default:
@@ -0,0 +1,337 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	k8sCache "k8s.io/client-go/tools/cache"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
+)
+
+// NetworkPolicy is a networkingv1.NetworkPolicy with its selectors and CIDRs
+// parsed, so flows can be checked against it quickly.
+type NetworkPolicy struct {
+	Namespace   string
+	Name        string
+	PodSelector labels.Selector
+
+	// Ingress and Egress tell whether the policy isolates the selected pods
+	// for ingress and egress traffic
+	Ingress      bool
+	Egress       bool
+	IngressRules []NetworkPolicyRule
+	EgressRules  []NetworkPolicyRule
+}
+
+// NetworkPolicyRule allows traffic from (ingress) or to (egress) any of its
+// peers on any of its ports. No peers or no ports match everything.
+type NetworkPolicyRule struct {
+	Peers []NetworkPolicyPeer
+	Ports []NetworkPolicyPort
+}
+
+// NetworkPolicyPeer matches pods or IP blocks. Nil selectors are the ones that
+// aren't set, which is different from empty selectors matching everything.
+type NetworkPolicyPeer struct {
+	PodSelector       labels.Selector
+	NamespaceSelector labels.Selector
+	IPBlock           *NetworkPolicyIPBlock
+}
+
+type NetworkPolicyIPBlock struct {
+	CIDR   *net.IPNet
+	Except []*net.IPNet
+}
+
+type NetworkPolicyPort struct {
+	Protocol v1.Protocol
+	// Port is 0 for all the ports. EndPort is 0 unless it's a range.
+	Port    int32
+	EndPort int32
+	// NamedPort is the name of the port of the pods, if it isn't a number
+	NamedPort string
+}
+
+// Key returns the namespace and the name of the policy, like "default/deny-all"
+func (np *NetworkPolicy) Key() string {
+	return np.Namespace + "/" + np.Name
+}
+
+func NewNetworkPolicy(np *networkingv1.NetworkPolicy) (*NetworkPolicy, error) {
+	podSelector, err := metav1.LabelSelectorAsSelector(&np.Spec.PodSelector)
+	if err != nil {
+		return nil, fmt.Errorf("parsing pod selector: %w", err)
+	}
+	ret := &NetworkPolicy{
+		Namespace:   np.Namespace,
+		Name:        np.Name,
+		PodSelector: podSelector,
+	}
+
+	// Without policy types, policies always isolate for ingress and for
+	// egress only if they have egress rules
+	if len(np.Spec.PolicyTypes) == 0 {
+		ret.Ingress = true
+		ret.Egress = len(np.Spec.Egress) > 0
+	}
+	for _, t := range np.Spec.PolicyTypes {
+		switch t {
+		case networkingv1.PolicyTypeIngress:
+			ret.Ingress = true
+		case networkingv1.PolicyTypeEgress:
+			ret.Egress = true
+		}
+	}
+
+	for _, r := range np.Spec.Ingress {
+		rule, err := newNetworkPolicyRule(r.From, r.Ports)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ingress rule: %w", err)
+		}
+		ret.IngressRules = append(ret.IngressRules, rule)
+	}
+	for _, r := range np.Spec.Egress {
+		rule, err := newNetworkPolicyRule(r.To, r.Ports)
+		if err != nil {
+			return nil, fmt.Errorf("parsing egress rule: %w", err)
+		}
+		ret.EgressRules = append(ret.EgressRules, rule)
+	}
+	return ret, nil
+}
+
+func newNetworkPolicyRule(peers []networkingv1.NetworkPolicyPeer, ports []networkingv1.NetworkPolicyPort) (NetworkPolicyRule, error) {
+	var rule NetworkPolicyRule
+	for _, p := range peers {
+		var peer NetworkPolicyPeer
+		var err error
+		if p.PodSelector != nil {
+			if peer.PodSelector, err = metav1.LabelSelectorAsSelector(p.PodSelector); err != nil {
+				return rule, fmt.Errorf("parsing pod selector: %w", err)
+			}
+		}
+		if p.NamespaceSelector != nil {
+			if peer.NamespaceSelector, err = metav1.LabelSelectorAsSelector(p.NamespaceSelector); err != nil {
+				return rule, fmt.Errorf("parsing namespace selector: %w", err)
+			}
+		}
+		if p.IPBlock != nil {
+			peer.IPBlock = &NetworkPolicyIPBlock{}
+			if _, peer.IPBlock.CIDR, err = net.ParseCIDR(p.IPBlock.CIDR); err != nil {
+				return rule, fmt.Errorf("parsing CIDR: %w", err)
+			}
+			for _, e := range p.IPBlock.Except {
+				_, except, err := net.ParseCIDR(e)
+				if err != nil {
+					return rule, fmt.Errorf("parsing CIDR: %w", err)
+				}
+				peer.IPBlock.Except = append(peer.IPBlock.Except, except)
+			}
+		}
+		rule.Peers = append(rule.Peers, peer)
+	}
+	for _, p := range ports {
+		port := NetworkPolicyPort{Protocol: v1.ProtocolTCP}
+		if p.Protocol != nil {
+			port.Protocol = *p.Protocol
+		}
+		if p.Port != nil {
+			if p.Port.StrVal != "" {
+				port.NamedPort = p.Port.StrVal
+			} else {
+				port.Port = p.Port.IntVal
+			}
+		}
+		if p.EndPort != nil {
+			port.EndPort = *p.EndPort
+		}
+		rule.Ports = append(rule.Ports, port)
+	}
+	return rule, nil
+}
+
+// NetworkPolicyCache is a cache of the NetworkPolicies of the cluster and of
+// the labels of the namespaces, that can be used by operators to tell whether
+// flows are allowed.
+type NetworkPolicyCache interface {
+	Start()
+	Stop()
+
+	GetNetworkPolicies(namespace string) []*NetworkPolicy
+	GetNamespaceLabels(namespace string) map[string]string
+}
+
+type networkPolicyCache struct {
+	clientset kubernetes.Interface
+
+	factory informers.SharedInformerFactory
+
+	mu sync.RWMutex
+	// policies holds the policies by namespace and name
+	policies        map[string]map[string]*NetworkPolicy
+	namespaceLabels map[string]map[string]string
+
+	exit chan struct{}
+
+	useCount      int
+	useCountMutex sync.Mutex
+}
+
+var (
+	networkPolicySingleton *networkPolicyCache
+	networkPolicyErr       error
+	networkPolicyOnce      sync.Once
+)
+
+// GetNetworkPolicyCache returns the NetworkPolicy cache. It's separated from
+// the K8sInventoryCache as it needs permissions to watch NetworkPolicies.
+func GetNetworkPolicyCache() (NetworkPolicyCache, error) {
+	networkPolicyOnce.Do(func() {
+		var clientset kubernetes.Interface
+		clientset, networkPolicyErr = k8sutil.NewClientsetWithProtobuf("", "network-policy-cache")
+		if networkPolicyErr != nil {
+			networkPolicyErr = fmt.Errorf("creating new k8s clientset: %w", networkPolicyErr)
+			return
+		}
+		networkPolicySingleton = &networkPolicyCache{
+			clientset: clientset,
+		}
+	})
+	return networkPolicySingleton, networkPolicyErr
+}
+
+func (cache *networkPolicyCache) Close() {
+	if cache.exit != nil {
+		close(cache.exit)
+		cache.exit = nil
+	}
+	if cache.factory != nil {
+		cache.factory.Shutdown()
+		cache.factory = nil
+	}
+	cache.mu.Lock()
+	cache.policies = nil
+	cache.namespaceLabels = nil
+	cache.mu.Unlock()
+}
+
+func (cache *networkPolicyCache) Start() {
+	cache.useCountMutex.Lock()
+	defer cache.useCountMutex.Unlock()
+
+	// No uses before us, we are the first one
+	if cache.useCount == 0 {
+		cache.mu.Lock()
+		cache.policies = make(map[string]map[string]*NetworkPolicy)
+		cache.namespaceLabels = make(map[string]map[string]string)
+		cache.mu.Unlock()
+
+		cache.factory = informers.NewSharedInformerFactoryWithOptions(
+			cache.clientset, informerResync, informers.WithTransform(transformObject),
+		)
+		cache.factory.Networking().V1().NetworkPolicies().Informer().AddEventHandler(cache)
+		cache.factory.Core().V1().Namespaces().Informer().AddEventHandler(cache)
+		cache.exit = make(chan struct{})
+		cache.factory.Start(cache.exit)
+		cache.factory.WaitForCacheSync(cache.exit)
+	}
+	cache.useCount++
+}
+
+func (cache *networkPolicyCache) Stop() {
+	cache.useCountMutex.Lock()
+	defer cache.useCountMutex.Unlock()
+
+	// We are the last user, stop everything
+	if cache.useCount == 1 {
+		cache.Close()
+	}
+	cache.useCount--
+}
+
+func (cache *networkPolicyCache) GetNetworkPolicies(namespace string) []*NetworkPolicy {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	policies := make([]*NetworkPolicy, 0, len(cache.policies[namespace]))
+	for _, np := range cache.policies[namespace] {
+		policies = append(policies, np)
+	}
+	return policies
+}
+
+func (cache *networkPolicyCache) GetNamespaceLabels(namespace string) map[string]string {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	return cache.namespaceLabels[namespace]
+}
+
+func (cache *networkPolicyCache) set(obj any) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	switch o := obj.(type) {
+	case *networkingv1.NetworkPolicy:
+		np, err := NewNetworkPolicy(o)
+		if err != nil {
+			log.Warnf("network policy %s/%s: %v", o.Namespace, o.Name, err)
+			return
+		}
+		if cache.policies[o.Namespace] == nil {
+			cache.policies[o.Namespace] = make(map[string]*NetworkPolicy)
+		}
+		cache.policies[o.Namespace][o.Name] = np
+	case *v1.Namespace:
+		cache.namespaceLabels[o.Name] = o.Labels
+	default:
+		log.Warnf("network policy cache: unknown object type: %T", o)
+	}
+}
+
+func (cache *networkPolicyCache) OnAdd(obj any, _ bool) {
+	cache.set(obj)
+}
+
+func (cache *networkPolicyCache) OnUpdate(_, newObj any) {
+	cache.set(newObj)
+}
+
+func (cache *networkPolicyCache) OnDelete(obj any) {
+	switch o := obj.(type) {
+	case *networkingv1.NetworkPolicy:
+		cache.mu.Lock()
+		delete(cache.policies[o.Namespace], o.Name)
+		cache.mu.Unlock()
+	case *v1.Namespace:
+		cache.mu.Lock()
+		delete(cache.namespaceLabels, o.Name)
+		cache.mu.Unlock()
+	case k8sCache.DeletedFinalStateUnknown:
+		cache.OnDelete(o.Obj)
+	default:
+		log.Warnf("network policy cache: unknown object type: %T", o)
+	}
+}
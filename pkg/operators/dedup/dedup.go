@@ -0,0 +1,429 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dedup is a data operator that collapses the events of a data source
+// that have the same values for some of their fields within a time window, and
+// emits the first of them with the number of events it stands for. Unlike the
+// aggregate operator, the events keep all their fields, so floods of identical
+// events, like repeated NXDOMAIN responses, can be reduced without changing the
+// output of the gadget.
+package dedup
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	Name     = "dedup"
+	Priority = 9070 // after the filter operator, so only matching events are collapsed
+
+	ParamDedupBy     = "dedup-by"
+	ParamDedupWindow = "dedup-window"
+
+	DataSourcePrefix = "deduplicated"
+
+	// CountField holds the number of events collapsed into an event
+	CountField = "count"
+
+	// maxGroups is the maximum number of events held per window; events of
+	// new groups are emitted right away once it's reached
+	maxGroups = 10000
+)
+
+type dedupOperator struct{}
+
+func (d *dedupOperator) Name() string {
+	return Name
+}
+
+func (d *dedupOperator) Init(params *params.Params) error {
+	return nil
+}
+
+func (d *dedupOperator) GlobalParams() api.Params {
+	return nil
+}
+
+func (d *dedupOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamDedupBy,
+			Title: "Deduplicate By",
+			Description: "Collapse the events with the same values for these fields within " + ParamDedupWindow +
+				" into the first of them, with the number of events in the " + CountField + " field. " +
+				"Join multiple fields with ','. " +
+				"If using multiple data sources, prefix fields with 'datasourcename:' and separate with ';'",
+		},
+		{
+			Key:          ParamDedupWindow,
+			Title:        "Deduplication Window",
+			Description:  "Time window in which identical events are collapsed; events are delayed by up to this time",
+			DefaultValue: "1s",
+			TypeHint:     api.TypeDuration,
+		},
+	}
+}
+
+// parseDedupBy returns the fields to collapse events by per data source;
+// fields given without data source are stored with an empty key
+func parseDedupBy(s string) (map[string][]string, error) {
+	res := make(map[string][]string)
+	for _, entry := range strings.Split(s, ";") {
+		if entry == "" {
+			continue
+		}
+		dsName, fieldList, ok := strings.Cut(entry, ":")
+		if !ok {
+			dsName, fieldList = "", entry
+		}
+		var fields []string
+		for _, f := range strings.Split(fieldList, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("no fields given for data source %q", dsName)
+		}
+		if _, ok := res[dsName]; ok {
+			return nil, fmt.Errorf("fields given twice for data source %q", dsName)
+		}
+		res[dsName] = fields
+	}
+	if _, ok := res[""]; ok && len(res) > 1 {
+		return nil, fmt.Errorf("mixed fields with and without specifying data source")
+	}
+	return res, nil
+}
+
+func (d *dedupOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	params := apihelpers.ToParamDescs(d.InstanceParams()).ToParams()
+	if err := params.CopyFromMap(instanceParamValues, ""); err != nil {
+		return nil, err
+	}
+
+	dedupBy, err := parseDedupBy(params.Get(ParamDedupBy).AsString())
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ParamDedupBy, err)
+	}
+	if len(dedupBy) == 0 {
+		return nil, nil
+	}
+
+	window := params.Get(ParamDedupWindow).AsDuration()
+	if window <= 0 {
+		return nil, fmt.Errorf("invalid value for %s: %s", ParamDedupWindow, window)
+	}
+
+	instance := &dedupOperatorInstance{
+		window: window,
+	}
+
+	dataSources := gadgetCtx.GetDataSources()
+	for dsName := range dedupBy {
+		if _, ok := dataSources[dsName]; dsName != "" && !ok {
+			return nil, fmt.Errorf("data source %q not found", dsName)
+		}
+	}
+
+	for _, ds := range dataSources {
+		fields, ok := dedupBy[ds.Name()]
+		if !ok {
+			fields, ok = dedupBy[""]
+		}
+		if !ok {
+			continue
+		}
+
+		if ds.Type() != datasource.TypeSingle {
+			if _, ok := dedupBy[ds.Name()]; ok {
+				return nil, fmt.Errorf("%s can only be used on data sources emitting single events", ParamDedupBy)
+			}
+			continue
+		}
+
+		dd, err := newDeduplicator(gadgetCtx, ds, fields, window)
+		if err != nil {
+			return nil, fmt.Errorf("deduplicating data source %q: %w", ds.Name(), err)
+		}
+		instance.deduplicators = append(instance.deduplicators, dd)
+
+		gadgetCtx.Logger().Debugf("dedup: collapsing %q by %v into %q", ds.Name(), fields, dd.dedupDs.Name())
+	}
+
+	if len(instance.deduplicators) == 0 {
+		return nil, nil
+	}
+	return instance, nil
+}
+
+func (d *dedupOperator) Priority() int {
+	return Priority
+}
+
+// deduplicator collapses the events of a data source per group
+type deduplicator struct {
+	ds      datasource.DataSource
+	dedupDs datasource.DataSource
+
+	fields     []datasource.FieldAccessor
+	countField datasource.FieldAccessor
+
+	mu     sync.Mutex
+	groups map[string]*group
+	// order keeps the groups in the order they were first seen, so events are
+	// emitted in the order they happened
+	order []string
+}
+
+// group is the first event of a group in the current window, as a packet of
+// dedupDs, and the number of events seen
+type group struct {
+	packet datasource.PacketSingle
+	count  uint64
+}
+
+func newDeduplicator(gadgetCtx operators.GadgetContext, ds datasource.DataSource, fieldNames []string, window time.Duration) (*deduplicator, error) {
+	dd := &deduplicator{
+		ds:     ds,
+		groups: make(map[string]*group),
+	}
+
+	for _, name := range fieldNames {
+		f := ds.GetField(name)
+		if f == nil {
+			return nil, fmt.Errorf("field %q not found", name)
+		}
+		if datasource.FieldFlagEmpty.In(f.Flags()) {
+			return nil, fmt.Errorf("field %q has no value to collapse events by", name)
+		}
+		dd.fields = append(dd.fields, f)
+	}
+
+	dedupDs, err := gadgetCtx.RegisterDataSource(
+		datasource.TypeSingle,
+		fmt.Sprintf("%s-%s", DataSourcePrefix, ds.Name()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("registering data source: %w", err)
+	}
+	dd.dedupDs = dedupDs
+
+	// Use the same fields and annotations as the original data source, so
+	// events keep their layout and can be copied as they are
+	if err := ds.CopyFieldsTo(dedupDs); err != nil {
+		return nil, fmt.Errorf("copying fields: %w", err)
+	}
+	for k, v := range ds.Annotations() {
+		dedupDs.AddAnnotation(k, v)
+	}
+	dd.countField, err = dedupDs.AddField(CountField, api.Kind_Uint64, datasource.WithAnnotations(map[string]string{
+		metadatav1.DescriptionAnnotation:  fmt.Sprintf("Number of identical events in %s", window),
+		metadatav1.ColumnsWidthAnnotation: "10",
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("adding field %q: %w", CountField, err)
+	}
+
+	// Only the collapsed events are sent to the following operators and to
+	// clients
+	ds.Unreference()
+
+	return dd, nil
+}
+
+// copyPayload copies the payload of an event of ds to a packet of dedupDs,
+// which has the same fields followed by CountField
+func copyPayload(dst, src datasource.PacketSingle) error {
+	dstRaw, ok := dst.Raw().(*api.GadgetData)
+	if !ok {
+		return errors.New("unexpected packet type")
+	}
+	srcRaw, ok := src.Raw().(*api.GadgetData)
+	if !ok {
+		return errors.New("unexpected packet type")
+	}
+	if len(srcRaw.Data.Payload) >= len(dstRaw.Data.Payload) {
+		return fmt.Errorf("unexpected payload count %d", len(srcRaw.Data.Payload))
+	}
+	// The payload of src is reused once it's released
+	for i, p := range srcRaw.Data.Payload {
+		dstRaw.Data.Payload[i] = bytes.Clone(p)
+	}
+	return nil
+}
+
+// groupKey returns the values of the fields to collapse events by, prefixed
+// with their length to keep the key unambiguous
+func (d *deduplicator) groupKey(data datasource.Data) string {
+	var key strings.Builder
+	for _, f := range d.fields {
+		value := f.Get(data)
+		key.Write(binary.AppendUvarint(nil, uint64(len(value))))
+		key.Write(value)
+	}
+	return key.String()
+}
+
+// add collapses an event into its group and returns the packet to emit right
+// away, if any, when too many groups are held already
+func (d *deduplicator) add(p datasource.PacketSingle) (datasource.PacketSingle, error) {
+	key := d.groupKey(p)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if g, ok := d.groups[key]; ok {
+		g.count++
+		return nil, nil
+	}
+
+	packet, err := d.dedupDs.NewPacketSingle()
+	if err != nil {
+		return nil, fmt.Errorf("creating packet: %w", err)
+	}
+	if err := copyPayload(packet, p); err != nil {
+		d.dedupDs.Release(packet)
+		return nil, fmt.Errorf("copying event: %w", err)
+	}
+	if len(d.groups) >= maxGroups {
+		return packet, nil
+	}
+	d.groups[key] = &group{packet: packet, count: 1}
+	d.order = append(d.order, key)
+	return nil, nil
+}
+
+// emit emits the events collapsed since the last call and resets the groups
+func (d *deduplicator) emit() error {
+	d.mu.Lock()
+	groups, order := d.groups, d.order
+	d.groups = make(map[string]*group, len(groups))
+	d.order = nil
+	d.mu.Unlock()
+
+	var errs []error
+	for _, key := range order {
+		g := groups[key]
+		if err := d.countField.PutUint64(g.packet, g.count); err != nil {
+			d.dedupDs.Release(g.packet)
+			errs = append(errs, fmt.Errorf("setting %s: %w", CountField, err))
+			continue
+		}
+		if err := d.dedupDs.EmitAndRelease(g.packet); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type dedupOperatorInstance struct {
+	window        time.Duration
+	deduplicators []*deduplicator
+	done          chan struct{}
+	wg            sync.WaitGroup
+}
+
+func (d *dedupOperatorInstance) Name() string {
+	return Name
+}
+
+func (d *dedupOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for _, dd := range d.deduplicators {
+		err := dd.ds.SubscribePacket(func(ds datasource.DataSource, p datasource.Packet) error {
+			single, ok := p.(datasource.PacketSingle)
+			if !ok {
+				return datasource.ErrDiscard
+			}
+			packet, err := dd.add(single)
+			if err != nil {
+				gadgetCtx.Logger().Warnf("dedup: %q: %v", ds.Name(), err)
+				return datasource.ErrDiscard
+			}
+			if packet != nil {
+				if err := dd.countField.PutUint64(packet, 1); err != nil {
+					dd.dedupDs.Release(packet)
+					return datasource.ErrDiscard
+				}
+				if err := dd.dedupDs.EmitAndRelease(packet); err != nil {
+					gadgetCtx.Logger().Warnf("dedup: emitting %q: %v", dd.dedupDs.Name(), err)
+				}
+			}
+			return datasource.ErrDiscard
+		}, Priority)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *dedupOperatorInstance) emit(gadgetCtx operators.GadgetContext) {
+	for _, dd := range d.deduplicators {
+		if err := dd.emit(); err != nil {
+			gadgetCtx.Logger().Errorf("dedup: emitting %q: %v", dd.dedupDs.Name(), err)
+		}
+	}
+}
+
+func (d *dedupOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	d.done = make(chan struct{})
+	d.wg.Add(1)
+	go func(done chan struct{}) {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				d.emit(gadgetCtx)
+			}
+		}
+	}(d.done)
+	return nil
+}
+
+// Stop emits the events still held, so they aren't lost
+func (d *dedupOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	if d.done != nil {
+		close(d.done)
+		d.wg.Wait()
+		d.done = nil
+		d.emit(gadgetCtx)
+	}
+	return nil
+}
+
+func (d *dedupOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func init() {
+	operators.RegisterDataOperator(&dedupOperator{})
+}
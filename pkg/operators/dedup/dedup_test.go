@@ -0,0 +1,224 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func TestParseDedupBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string][]string
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			expected: map[string][]string{},
+		},
+		{
+			name:     "all data sources",
+			value:    "name,rcode",
+			expected: map[string][]string{"": {"name", "rcode"}},
+		},
+		{
+			name:  "per data source",
+			value: "dns:name,rcode;exec:comm",
+			expected: map[string][]string{
+				"dns":  {"name", "rcode"},
+				"exec": {"comm"},
+			},
+		},
+		{
+			name:    "mixed",
+			value:   "name;dns:rcode",
+			wantErr: true,
+		},
+		{
+			name:    "no fields",
+			value:   "dns:",
+			wantErr: true,
+		},
+		{
+			name:    "twice",
+			value:   "dns:name;dns:rcode",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseDedupBy(test.value)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+type result struct {
+	name  string
+	rcode string
+	id    uint16
+	count uint64
+}
+
+func TestDedup(t *testing.T) {
+	var ds datasource.DataSource
+	var nameField, rcodeField, idField datasource.FieldAccessor
+	var got []result
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "dns")
+		require.NoError(t, err)
+		nameField, err = ds.AddField("name", api.Kind_String)
+		require.NoError(t, err)
+		rcodeField, err = ds.AddField("rcode", api.Kind_String)
+		require.NoError(t, err)
+		idField, err = ds.AddField("id", api.Kind_Uint16)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		events := []struct {
+			name, rcode string
+		}{
+			{"nope.example.com.", "NXDOMAIN"},
+			{"example.com.", "NOERROR"},
+			{"nope.example.com.", "NXDOMAIN"},
+			{"nope.example.com.", "NOERROR"},
+			{"nope.example.com.", "NXDOMAIN"},
+		}
+		for i, e := range events {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, nameField.PutString(data, e.name))
+			require.NoError(t, rcodeField.PutString(data, e.rcode))
+			require.NoError(t, idField.PutUint16(data, uint16(i)))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		dedupDs := gadgetCtx.GetDataSources()[DataSourcePrefix+"-dns"]
+		require.NotNil(t, dedupDs)
+		name := dedupDs.GetField("name")
+		rcode := dedupDs.GetField("rcode")
+		id := dedupDs.GetField("id")
+		count := dedupDs.GetField(CountField)
+		require.NotNil(t, count)
+
+		dedupDs.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			var r result
+			var err error
+			r.name, err = name.String(data)
+			require.NoError(t, err)
+			r.rcode, err = rcode.String(data)
+			require.NoError(t, err)
+			r.id, err = id.Uint16(data)
+			require.NoError(t, err)
+			r.count, err = count.Uint64(data)
+			require.NoError(t, err)
+			got = append(got, r)
+			cancel()
+			return nil
+		}, Priority+1)
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(&dedupOperator{}, producer, consumer))
+	err := gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamDedupBy:     "dns:name,rcode",
+		"operator." + Name + "." + ParamDedupWindow: "100ms",
+	})
+	require.NoError(t, err)
+
+	// The first event of each group is kept with all its fields
+	assert.Equal(t, []result{
+		{name: "nope.example.com.", rcode: "NXDOMAIN", id: 0, count: 3},
+		{name: "example.com.", rcode: "NOERROR", id: 1, count: 1},
+		{name: "nope.example.com.", rcode: "NOERROR", id: 3, count: 1},
+	}, got)
+	assert.False(t, ds.IsReferenced())
+}
+
+func TestDedupInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		paramValues api.ParamValues
+	}{
+		{
+			name:        "unknown field",
+			paramValues: api.ParamValues{"operator." + Name + "." + ParamDedupBy: "qname"},
+		},
+		{
+			name:        "unknown data source",
+			paramValues: api.ParamValues{"operator." + Name + "." + ParamDedupBy: "exec:comm"},
+		},
+		{
+			name: "invalid window",
+			paramValues: api.ParamValues{
+				"operator." + Name + "." + ParamDedupBy:     "name",
+				"operator." + Name + "." + ParamDedupWindow: "0s",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			producer := simple.New("producer",
+				simple.WithPriority(Priority-1),
+				simple.OnInit(func(gadgetCtx operators.GadgetContext) error {
+					ds, err := gadgetCtx.RegisterDataSource(datasource.TypeSingle, "dns")
+					require.NoError(t, err)
+					_, err = ds.AddField("name", api.Kind_String)
+					require.NoError(t, err)
+					return nil
+				}),
+			)
+			gadgetCtx := gadgetcontext.New(context.Background(), "", gadgetcontext.WithDataOperators(&dedupOperator{}, producer))
+			require.Error(t, gadgetCtx.Run(test.paramValues))
+		})
+	}
+}
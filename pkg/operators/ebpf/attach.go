@@ -26,6 +26,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/uprobetracer"
 )
 
@@ -68,6 +69,9 @@ func (i *ebpfInstance) attachProgram(gadgetCtx operators.GadgetContext, p *ebpf.
 		case strings.HasPrefix(p.SectionName, kretprobePrefix):
 			i.logger.Debugf("Attaching kretprobe %q to %q", p.Name, attachTo)
 			return link.Kretprobe(attachTo, prog, nil)
+		case strings.HasPrefix(p.SectionName, kprobeMultiPrefix) ||
+			strings.HasPrefix(p.SectionName, kretprobeMultiPrefix):
+			return i.attachKprobeMulti(p, prog, attachTo)
 		case strings.HasPrefix(p.SectionName, uprobePrefix) ||
 			strings.HasPrefix(p.SectionName, uretprobePrefix) ||
 			strings.HasPrefix(p.SectionName, usdtPrefix):
@@ -78,6 +82,10 @@ func (i *ebpfInstance) attachProgram(gadgetCtx operators.GadgetContext, p *ebpf.
 			case "uretprobe":
 				return nil, uprobeTracer.AttachProg(p.Name, uprobetracer.ProgUretprobe, attachTo, prog)
 			case "usdt":
+				// Gadgets including gadget/usdt.h read the arguments from this map
+				if m, ok := i.collection.Maps[ebpftypes.USDTSpecsMapName]; ok {
+					uprobeTracer.SetUSDTSpecsMap(m)
+				}
 				return nil, uprobeTracer.AttachProg(p.Name, uprobetracer.ProgUSDT, attachTo, prog)
 			}
 		}
@@ -94,8 +102,7 @@ func (i *ebpfInstance) attachProgram(gadgetCtx operators.GadgetContext, p *ebpf.
 		switch {
 		case strings.HasPrefix(p.SectionName, iterPrefix):
 			i.logger.Debugf("Attaching iter %q to %q", p.Name, attachTo)
-			switch attachTo {
-			case "task", "task_file", "tcp", "udp", "ksym":
+			if isIteratorKindSupported(attachTo) {
 				return link.AttachIter(link.IterOptions{
 					Program: prog,
 				})
@@ -0,0 +1,272 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cilium/ebpf"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	ParamBufferAutotune    = "buffer-autotune"
+	ParamBufferMaxSize     = "buffer-max-size"
+	ParamBufferMaxSampling = "buffer-max-sampling"
+
+	AnnotationBufferAutotune    = "ebpf.buffer.autotune"
+	AnnotationBufferMaxSize     = "ebpf.buffer.max-size"
+	AnnotationBufferMaxSampling = "ebpf.buffer.max-sampling"
+
+	// Variables defined in include/gadget/buffer.h
+	bufLostVar     = "gadget_buf_lost"
+	bufSamplingVar = "gadget_buf_sampling"
+
+	autotuneInterval = time.Second
+	// Buffers are tuned when more than 1% of the events are lost
+	autotuneDropThreshold = 0.01
+	// Sampling is relaxed after this number of intervals without losses
+	autotuneRelaxIntervals = 10
+)
+
+// bufferStats are the events received and lost by buffers during an interval
+type bufferStats struct {
+	received uint64
+	lost     uint64
+	// size is the size of the per-CPU buffers of perf event arrays, 0 for
+	// buffers that can't grow, like ring buffers
+	size int
+}
+
+func (s bufferStats) dropping() bool {
+	total := s.received + s.lost
+	return total > 0 && float64(s.lost)/float64(total) > autotuneDropThreshold
+}
+
+// bufferAutotuner decides how to tune the buffers of a gadget from the events
+// they lose. Perf buffers are grown up to maxSize first, then events are
+// sampled, keeping one out of sampling ones, up to maxSampling.
+type bufferAutotuner struct {
+	maxSize     int
+	maxSampling uint32
+
+	sampling uint32
+	quiet    int
+}
+
+// decide returns the new sizes of the buffers, 0 for the ones that don't
+// change, and the new sampling.
+func (a *bufferAutotuner) decide(stats []bufferStats) ([]int, uint32) {
+	sizes := make([]int, len(stats))
+	needsSampling := false
+	dropping := false
+	for idx, s := range stats {
+		if !s.dropping() {
+			continue
+		}
+		dropping = true
+		if s.size > 0 && s.size < a.maxSize {
+			sizes[idx] = min(s.size*2, a.maxSize)
+			continue
+		}
+		needsSampling = true
+	}
+
+	if !dropping {
+		a.quiet++
+		if a.quiet >= autotuneRelaxIntervals && a.sampling > 1 {
+			a.sampling /= 2
+			a.quiet = 0
+		}
+		return sizes, a.sampling
+	}
+
+	a.quiet = 0
+	if needsSampling && a.maxSampling > 1 && a.sampling < a.maxSampling {
+		a.sampling = min(max(a.sampling*2, 2), a.maxSampling)
+	}
+	return sizes, a.sampling
+}
+
+func (i *ebpfInstance) addAutotuneParams() {
+	if len(i.tracers) == 0 {
+		return
+	}
+
+	i.params[ParamBufferAutotune] = &param{
+		Param: &api.Param{
+			Key:          ParamBufferAutotune,
+			Description:  "Grow the buffers of the events or sample the events when they are lost",
+			DefaultValue: "false",
+			TypeHint:     api.TypeBool,
+		},
+	}
+	i.params[ParamBufferMaxSize] = &param{
+		Param: &api.Param{
+			Key:          ParamBufferMaxSize,
+			Description:  "Maximum size of the per-CPU perf buffers when auto-tuning them, like 4MiB",
+			DefaultValue: strconv.Itoa(16 * gadgets.PerfBufferPages * os.Getpagesize()),
+			TypeHint:     api.TypeSize,
+		},
+	}
+	i.params[ParamBufferMaxSampling] = &param{
+		Param: &api.Param{
+			Key:          ParamBufferMaxSampling,
+			Description:  "Keep at least one out of this number of events when auto-tuning the buffers. 1 disables sampling",
+			DefaultValue: "16",
+			TypeHint:     api.TypeUint,
+		},
+	}
+
+	if autotune, _ := strconv.ParseBool(i.paramValues[ParamBufferAutotune]); !autotune {
+		return
+	}
+	for _, t := range i.tracers {
+		t.ds.AddAnnotation(AnnotationBufferAutotune, "true")
+		if v, ok := i.paramValues[ParamBufferMaxSize]; ok {
+			t.ds.AddAnnotation(AnnotationBufferMaxSize, v)
+		}
+		if v, ok := i.paramValues[ParamBufferMaxSampling]; ok {
+			t.ds.AddAnnotation(AnnotationBufferMaxSampling, v)
+		}
+	}
+}
+
+// newBufferAutotuner returns nil if the buffers aren't auto-tuned
+func (i *ebpfInstance) newBufferAutotuner(paramMap map[string]*params.Param) (*bufferAutotuner, error) {
+	if len(i.tracers) == 0 || !paramMap[ParamBufferAutotune].AsBool() {
+		return nil, nil
+	}
+	maxSize := paramMap[ParamBufferMaxSize].AsSize()
+	if maxSize == 0 || maxSize > math.MaxInt32 {
+		return nil, fmt.Errorf("%s must be positive and less than 2GiB", ParamBufferMaxSize)
+	}
+	maxSampling := paramMap[ParamBufferMaxSampling].AsUint32()
+	if maxSampling == 0 {
+		return nil, fmt.Errorf("%s must be positive", ParamBufferMaxSampling)
+	}
+	return &bufferAutotuner{
+		maxSize:     int(maxSize),
+		maxSampling: maxSampling,
+	}, nil
+}
+
+func (i *ebpfInstance) runBufferAutotuner() {
+	if i.autotuner == nil {
+		return
+	}
+
+	var perfTracers, ringbufTracers []*Tracer
+	for _, t := range i.tracers {
+		switch t.mapType {
+		case ebpf.PerfEventArray:
+			perfTracers = append(perfTracers, t)
+		case ebpf.RingBuf:
+			ringbufTracers = append(ringbufTracers, t)
+		}
+	}
+
+	// Gadgets built before the variables were added to buffer.h can't be
+	// sampled and their ring buffers don't count the lost events
+	lostVar := i.collection.Variables[bufLostVar]
+	samplingVar := i.collection.Variables[bufSamplingVar]
+	if samplingVar == nil {
+		i.autotuner.maxSampling = 1
+	}
+
+	i.wg.Add(1)
+	go func() {
+		defer i.wg.Done()
+
+		ticker := time.NewTicker(autotuneInterval)
+		defer ticker.Stop()
+
+		var prevLost uint64
+		for {
+			select {
+			case <-i.done:
+				return
+			case <-ticker.C:
+			}
+
+			stats := make([]bufferStats, 0, len(perfTracers)+1)
+			for _, t := range perfTracers {
+				stats = append(stats, bufferStats{
+					received: t.received.Swap(0),
+					lost:     t.lost.Swap(0),
+					size:     t.perfBufferSize,
+				})
+			}
+			if len(ringbufTracers) > 0 && lostVar != nil {
+				// The lost events are counted for all the ring buffers
+				var s bufferStats
+				for _, t := range ringbufTracers {
+					s.received += t.received.Swap(0)
+				}
+				var lost uint64
+				if err := lostVar.Get(&lost); err == nil {
+					s.lost = lost - prevLost
+					prevLost = lost
+				}
+				if s.lost > 0 && len(ringbufTracers) == 1 {
+					ringbufTracers[0].ds.ReportLostData(s.lost)
+				}
+				stats = append(stats, s)
+			}
+
+			prevSampling := i.autotuner.sampling
+			sizes, sampling := i.autotuner.decide(stats)
+
+			for idx, size := range sizes {
+				if size == 0 {
+					continue
+				}
+				t := perfTracers[idx]
+				prevSize := t.perfBufferSize
+				if err := t.resizePerfBuffer(size); err != nil {
+					i.logger.Warnf("growing buffer of %q: %v", t.mapName, err)
+					continue
+				}
+				i.logger.Infof("growing per-CPU buffers of %q from %d to %d bytes, %d%% of the events were lost",
+					t.mapName, prevSize, size, stats[idx].lost*100/(stats[idx].received+stats[idx].lost))
+			}
+
+			if sampling == prevSampling {
+				continue
+			}
+			if err := samplingVar.Set(sampling); err != nil {
+				i.logger.Warnf("setting events sampling: %v", err)
+				i.autotuner.sampling = prevSampling
+				i.autotuner.maxSampling = max(prevSampling, 1)
+				continue
+			}
+			switch {
+			case sampling > prevSampling:
+				i.logger.Infof("events are lost with the largest buffers: keeping one out of %d events", sampling)
+			case sampling > 1:
+				i.logger.Infof("no events lost: keeping one out of %d events", sampling)
+			default:
+				i.logger.Infof("no events lost: keeping all the events")
+			}
+		}
+	}()
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferAutotunerGrow(t *testing.T) {
+	a := &bufferAutotuner{maxSize: 1000, maxSampling: 8}
+
+	sizes, sampling := a.decide([]bufferStats{
+		{received: 1000, lost: 100, size: 300},
+		{received: 1000, lost: 1, size: 300},
+		{received: 1000, lost: 100, size: 600},
+	})
+	assert.Equal(t, []int{600, 0, 1000}, sizes)
+	assert.Equal(t, uint32(0), sampling)
+}
+
+func TestBufferAutotunerSampling(t *testing.T) {
+	a := &bufferAutotuner{maxSize: 1000, maxSampling: 8}
+
+	// Perf buffers at their maximum size and ring buffers can't grow
+	for _, expected := range []uint32{2, 4, 8, 8} {
+		sizes, sampling := a.decide([]bufferStats{
+			{received: 1000, lost: 100, size: 1000},
+			{received: 1000, lost: 100},
+		})
+		assert.Equal(t, []int{0, 0}, sizes)
+		assert.Equal(t, expected, sampling)
+	}
+
+	// Sampling is relaxed after some intervals without losses
+	for range autotuneRelaxIntervals - 1 {
+		_, sampling := a.decide([]bufferStats{{received: 1000}})
+		assert.Equal(t, uint32(8), sampling)
+	}
+	_, sampling := a.decide([]bufferStats{{received: 1000}})
+	assert.Equal(t, uint32(4), sampling)
+
+	// Losses reset the count of intervals without losses
+	for range autotuneRelaxIntervals - 1 {
+		a.decide([]bufferStats{{received: 1000}})
+	}
+	_, sampling = a.decide([]bufferStats{{received: 1000, lost: 100}})
+	assert.Equal(t, uint32(8), sampling)
+	_, sampling = a.decide([]bufferStats{{received: 1000}})
+	assert.Equal(t, uint32(8), sampling)
+}
+
+func TestBufferAutotunerNoSampling(t *testing.T) {
+	a := &bufferAutotuner{maxSize: 1000, maxSampling: 1}
+
+	sizes, sampling := a.decide([]bufferStats{{received: 10, lost: 10}})
+	assert.Equal(t, []int{0}, sizes)
+	assert.Equal(t, uint32(0), sampling)
+
+	// No events at all
+	sizes, sampling = a.decide([]bufferStats{{size: 300}})
+	assert.Equal(t, []int{0}, sizes)
+	assert.Equal(t, uint32(0), sampling)
+}
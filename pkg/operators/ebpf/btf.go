@@ -0,0 +1,71 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/btfgen"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
+// btfHubConfig returns the local BTFHub archive and the URL to download BTF
+// files from, any of them can be empty
+func (o *ebpfOperator) btfHubConfig() (string, string) {
+	if o.globalParams == nil {
+		return "", ""
+	}
+	return o.globalParams.Get(ParamBTFHubArchive).AsString(), o.globalParams.Get(ParamBTFHubURL).AsString()
+}
+
+// kernelTypes returns the BTF of the running kernel if it doesn't expose it:
+// the one shipped with the gadget, stored in the context by the btfgen
+// operator, or the one of BTFHub otherwise. It returns nil if the kernel
+// exposes BTF or no BTF was found, loading the programs fails then only if
+// they need it.
+func (i *ebpfInstance) kernelTypes(gadgetCtx operators.GadgetContext) (*btf.Spec, error) {
+	if btfSpecI, ok := gadgetCtx.GetVar(kernelTypesVar); ok {
+		i.logger.Debugf("using kernel types shipped with the gadget")
+		btfSpec, ok := btfSpecI.(*btf.Spec)
+		if !ok {
+			return nil, fmt.Errorf("invalid BTF spec: expected btf.Spec, got %T", btfSpecI)
+		}
+		return btfSpec, nil
+	}
+
+	if btfgen.KernelHasBTF() {
+		return nil, nil
+	}
+
+	archiveDir, url := i.bpfOperator.btfHubConfig()
+	if archiveDir == "" && url == "" {
+		return nil, nil
+	}
+
+	info, err := btfgen.GetOSInfo()
+	if err != nil {
+		i.logger.Warnf("kernel doesn't expose BTF and getting OS info failed: %s", err)
+		return nil, nil
+	}
+	btfSpec, err := btfgen.LoadBTFHubSpec(gadgetCtx.Context(), info, archiveDir, url)
+	if err != nil {
+		i.logger.Warnf("kernel doesn't expose BTF and loading %q from BTFHub failed: %s", btfgen.BTFHubPath(info), err)
+		return nil, nil
+	}
+	i.logger.Debugf("using kernel types from BTFHub")
+	return btfSpec, nil
+}
@@ -35,6 +35,9 @@ import (
 	"github.com/cilium/ebpf/link"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sys/unix"
 	"oras.land/oras-go/v2"
 
@@ -53,6 +56,7 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/socketenricher"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/tchandler"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/uprobetracer"
 	ebpfutils "github.com/inspektor-gadget/inspektor-gadget/pkg/utils/ebpf"
 )
@@ -65,6 +69,9 @@ const (
 	ParamIface       = "iface"
 	ParamTraceKernel = "trace-pipe"
 
+	ParamBTFHubArchive = "btfhub-archive"
+	ParamBTFHubURL     = "btfhub-url"
+
 	kernelTypesVar = "kernelTypes"
 
 	AnnotationFlushOnStop = "ebpf.map.flush-on-stop"
@@ -81,6 +88,8 @@ type gadgetObjects struct {
 type ebpfOperator struct {
 	mu         sync.Mutex
 	gadgetObjs map[operators.GadgetContext]gadgetObjects
+
+	globalParams *params.Params
 }
 
 func (o *ebpfOperator) Name() string {
@@ -198,6 +207,19 @@ type ebpfInstance struct {
 	kernelStackMap *ebpf.Map
 	userStackMap   *ebpf.Map
 
+	// autotuner is nil if the buffers of the tracers aren't auto-tuned
+	autotuner *bufferAutotuner
+
+	// enforcing is true if the LSM programs of the gadget are allowed to
+	// block actions, enforcingDryRun if they only report what they would block
+	enforcing       bool
+	enforcingDryRun bool
+
+	sharedMapExports []*sharedMap
+	sharedMapImports []*sharedMap
+	exportedMaps     []*sharedMap
+	importedMaps     []*ebpf.Map
+
 	gadgetCtx operators.GadgetContext
 	done      chan struct{}
 
@@ -337,6 +359,16 @@ func (i *ebpfInstance) init(gadgetCtx operators.GadgetContext) error {
 		return fmt.Errorf("analyzing: %w", err)
 	}
 
+	err = i.initEnforcement()
+	if err != nil {
+		return fmt.Errorf("initializing enforcement: %w", err)
+	}
+
+	i.sharedMapExports, i.sharedMapImports, err = i.getSharedMaps()
+	if err != nil {
+		return fmt.Errorf("getting shared maps: %w", err)
+	}
+
 	err = i.register(gadgetCtx)
 	if err != nil {
 		return fmt.Errorf("registering datasources: %w", err)
@@ -453,6 +485,8 @@ func (i *ebpfInstance) init(gadgetCtx operators.GadgetContext) error {
 		},
 	}
 
+	i.addAutotuneParams()
+
 	for name, m := range i.collectionSpec.Maps {
 		gadgetCtx.SetVar(operators.MapSpecPrefix+name, m)
 	}
@@ -669,6 +703,27 @@ func (i *ebpfInstance) Start(gadgetCtx operators.GadgetContext) error {
 		}
 	}
 
+	if err := i.setupEnforcement(gadgetCtx, paramMap); err != nil {
+		return err
+	}
+
+	if err := i.confineLSMPrograms(gadgetCtx); err != nil {
+		return err
+	}
+
+	i.autotuner, err = i.newBufferAutotuner(paramMap)
+	if err != nil {
+		return err
+	}
+
+	if err := i.setupFentry(); err != nil {
+		return err
+	}
+
+	if err := i.setupKprobeMulti(); err != nil {
+		return err
+	}
+
 	mapReplacements := make(map[string]*ebpf.Map)
 
 	// Set gadget params
@@ -705,6 +760,11 @@ func (i *ebpfInstance) Start(gadgetCtx operators.GadgetContext) error {
 			}
 			copy(bytes[:], strBytes)
 			paramVal = bytes
+		case api.TypeSize:
+			paramVal, err = sizeParamValue(p.intHint, paramMap[name].AsSize())
+			if err != nil {
+				return fmt.Errorf("size param %q: %w", name, err)
+			}
 		}
 
 		i.logger.Debugf("setting param value %q = %v", name, paramVal)
@@ -773,6 +833,10 @@ func (i *ebpfInstance) Start(gadgetCtx operators.GadgetContext) error {
 		}
 	}
 
+	if err := i.importSharedMaps(mapReplacements); err != nil {
+		return err
+	}
+
 	// Maps with a parameter _max_entries can have their size set at runtime.
 	// Example:
 	//     const volatile int mymap_max_entries = 1024;
@@ -799,18 +863,28 @@ func (i *ebpfInstance) Start(gadgetCtx operators.GadgetContext) error {
 		m.MaxEntries = maxEntries
 	}
 
+	if err := i.checkResourceLimits(gadgetCtx, mapReplacements); err != nil {
+		return err
+	}
+
 	i.logger.Debugf("creating ebpf collection")
 
-	// check if the btfgen operator has stored the kernel types in the context
-	if btfSpecI, ok := gadgetCtx.GetVar(kernelTypesVar); ok {
-		gadgetCtx.Logger().Debugf("using kernel types from BTFHub")
-		btfSpec, ok := btfSpecI.(*btf.Spec)
-		if !ok {
-			return fmt.Errorf("invalid BTF spec: expected btf.Spec, got %T", btfSpecI)
-		}
-		opts.Programs.KernelTypes = btfSpec
-	}
+	kernelTypes, err := i.kernelTypes(gadgetCtx)
+	if err != nil {
+		return err
+	}
+	opts.Programs.KernelTypes = kernelTypes
+	_, span := tracing.Tracer().Start(gadgetCtx.Context(), "load eBPF objects", trace.WithAttributes(
+		attribute.String("gadget_image", gadgetCtx.ImageName()),
+		attribute.Int("programs", len(i.collectionSpec.Programs)),
+		attribute.Int("maps", len(i.collectionSpec.Maps)),
+	))
 	collection, err := ebpf.NewCollectionWithOptions(i.collectionSpec, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
 	if err != nil {
 		var verifierErr *ebpf.VerifierError
 		if errors.As(err, &verifierErr) {
@@ -821,6 +895,14 @@ func (i *ebpfInstance) Start(gadgetCtx operators.GadgetContext) error {
 	}
 	i.collection = collection
 
+	if err := i.accountResources(gadgetCtx, mapReplacements); err != nil {
+		return err
+	}
+
+	if err := i.exportSharedMaps(); err != nil {
+		return err
+	}
+
 	// collect program IDs and map IDs for this gadget
 	gadgetObjs := gadgetObjects{}
 
@@ -870,6 +952,7 @@ func (i *ebpfInstance) Start(gadgetCtx operators.GadgetContext) error {
 			return fmt.Errorf("running tracer %q: %w", tracer.mapName, err)
 		}
 	}
+	i.runBufferAutotuner()
 
 	// Attach programs
 	for progName, p := range i.collectionSpec.Programs {
@@ -937,6 +1020,8 @@ func (i *ebpfInstance) Stop(gadgetCtx operators.GadgetContext) error {
 	}
 	i.links = nil
 
+	i.teardownEnforcement(gadgetCtx)
+
 	for _, fd := range i.perfFds {
 		// Disable perf event.
 		err := unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_DISABLE, 0)
@@ -955,6 +1040,8 @@ func (i *ebpfInstance) Stop(gadgetCtx operators.GadgetContext) error {
 }
 
 func (i *ebpfInstance) Close(gadgetCtx operators.GadgetContext) error {
+	i.closeSharedMaps()
+
 	if i.collection != nil {
 		i.collection.Close()
 		i.collection = nil
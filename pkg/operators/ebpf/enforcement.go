@@ -0,0 +1,266 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+// Enforcement allows gadgets to block actions using BPF LSM programs instead
+// of only observing them. To be able to do so:
+//   - the gadget has to be marked with the AnnotationEnforcement annotation
+//     in its metadata,
+//   - its LSM programs must only deny actions through gadget_deny() (see
+//     include/gadget/enforcement.h), which is a no-op unless gadget_enforce
+//     is set,
+//   - the user has to request it with the enforce param and
+//   - the image has to be part of the enforcement-allowed-gadgets global
+//     param, which is empty (enforcement disabled) by default.
+//
+// The policy doesn't rely on gadgets honoring gadget_enforce: the LSM programs
+// of any gadget that isn't part of the policy are loaded as fentry programs on
+// the same hooks. The verifier only accepts fentry programs returning 0 and
+// their return value is ignored, so they can still observe the hooks, like
+// trace_lsm does, but can't block anything.
+//
+// Enforcement gadgets can also run in dry-run mode, enabled with the
+// enforce-dry-run param. In this mode, nothing is blocked but the gadget
+// reports the actions that would have been blocked, and by which rule, as a
+// gadget_verdict field in its events. It doesn't require the gadget to be part
+// of the policy.
+//
+// Every time enforcement or dry-run is enabled or disabled, an audit entry is
+// written to the log of the process running the gadget.
+
+const (
+	AnnotationEnforcement = "ebpf.enforcement"
+
+	ParamEnforce                   = "enforce"
+	ParamEnforceDryRun             = "enforce-dry-run"
+	ParamEnforcementAllowedGadgets = "enforcement-allowed-gadgets"
+
+	// Prefix of the kernel functions BPF LSM programs are attached to
+	lsmFuncPrefix = "bpf_lsm_"
+
+	enforceVarName       = "gadget_enforce"
+	enforceDryRunVarName = "gadget_enforce_dry_run"
+)
+
+// isEnforcementGadget returns whether the gadget is marked as being able to
+// enforce policies
+func (i *ebpfInstance) isEnforcementGadget() bool {
+	annotations := i.config.GetStringMapString("annotations")
+	return annotations[AnnotationEnforcement] == "true"
+}
+
+func (i *ebpfInstance) lsmPrograms() []string {
+	var res []string
+	for name, p := range i.collectionSpec.Programs {
+		if p.Type == ebpf.LSM {
+			res = append(res, name)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+// confineLSMPrograms loads the LSM programs of gadgets that aren't allowed by
+// the enforcement policy as fentry programs on the bpf_lsm_ functions
+// implementing the same hooks, so they can't deny any action. It must be
+// called before the collection is created.
+func (i *ebpfInstance) confineLSMPrograms(gadgetCtx operators.GadgetContext) error {
+	programs := i.lsmPrograms()
+	if len(programs) == 0 {
+		return nil
+	}
+
+	allowed, err := i.bpfOperator.enforcementAllowed(gadgetCtx.ImageName())
+	if err != nil {
+		return fmt.Errorf("checking enforcement policy: %w", err)
+	}
+	if allowed {
+		return nil
+	}
+
+	for _, name := range programs {
+		confineLSMProgram(i.collectionSpec.Programs[name])
+	}
+	i.logger.Debugf("Loading LSM programs %v as fentry programs: gadget %q isn't allowed by the %s policy",
+		programs, gadgetCtx.ImageName(), ParamEnforcementAllowedGadgets)
+	return nil
+}
+
+func confineLSMProgram(p *ebpf.ProgramSpec) {
+	p.Type = ebpf.Tracing
+	p.AttachType = ebpf.AttachTraceFEntry
+	p.AttachTo = lsmFuncPrefix + p.AttachTo
+	p.SectionName = fentryPrefix + p.AttachTo
+}
+
+// initEnforcement validates enforcement gadgets and adds the param to enable
+// enforcement
+func (i *ebpfInstance) initEnforcement() error {
+	if !i.isEnforcementGadget() {
+		return nil
+	}
+
+	if len(i.lsmPrograms()) == 0 {
+		return fmt.Errorf("gadget marked with %q doesn't have any LSM program", AnnotationEnforcement)
+	}
+	if _, ok := i.collectionSpec.Variables[enforceVarName]; !ok {
+		return fmt.Errorf("gadget marked with %q doesn't declare %q", AnnotationEnforcement, enforceVarName)
+	}
+
+	i.params[ParamEnforce] = &param{
+		Param: &api.Param{
+			Key:          ParamEnforce,
+			Description:  "Block the actions matched by the gadget instead of only reporting them. Requires the gadget to be allowed by the enforcement policy",
+			DefaultValue: "false",
+			TypeHint:     api.TypeBool,
+		},
+	}
+
+	// Gadgets built with older versions of enforcement.h don't support dry-run
+	if _, ok := i.collectionSpec.Variables[enforceDryRunVarName]; ok {
+		i.params[ParamEnforceDryRun] = &param{
+			Param: &api.Param{
+				Key:          ParamEnforceDryRun,
+				Description:  "Report the actions that would have been blocked without blocking them",
+				DefaultValue: "false",
+				TypeHint:     api.TypeBool,
+			},
+		}
+	}
+	return nil
+}
+
+// enableEnforcement checks the server-side policy and, if it allows it, turns
+// on enforcement in the eBPF programs. It must be called before the collection
+// is created.
+func (i *ebpfInstance) enableEnforcement(gadgetCtx operators.GadgetContext) error {
+	allowed, err := i.bpfOperator.enforcementAllowed(gadgetCtx.ImageName())
+	if err != nil {
+		return fmt.Errorf("checking enforcement policy: %w", err)
+	}
+	if !allowed {
+		i.auditEnforcement(gadgetCtx, "enforcement denied by policy")
+		return fmt.Errorf("enforcement is not allowed for gadget %q by the %s policy",
+			gadgetCtx.ImageName(), ParamEnforcementAllowedGadgets)
+	}
+
+	if err := i.collectionSpec.Variables[enforceVarName].Set(true); err != nil {
+		return fmt.Errorf("setting %q: %w", enforceVarName, err)
+	}
+
+	i.enforcing = true
+	i.auditEnforcement(gadgetCtx, "enforcement enabled")
+	return nil
+}
+
+// enableEnforcementDryRun makes the eBPF programs report the actions they
+// would block. It must be called before the collection is created.
+func (i *ebpfInstance) enableEnforcementDryRun(gadgetCtx operators.GadgetContext) error {
+	if err := i.collectionSpec.Variables[enforceDryRunVarName].Set(true); err != nil {
+		return fmt.Errorf("setting %q: %w", enforceDryRunVarName, err)
+	}
+
+	i.enforcingDryRun = true
+	i.auditEnforcement(gadgetCtx, "enforcement dry-run enabled")
+	return nil
+}
+
+// setupEnforcement enables enforcement or its dry-run mode, depending on the
+// given params
+func (i *ebpfInstance) setupEnforcement(gadgetCtx operators.GadgetContext, paramMap map[string]*params.Param) error {
+	enforce := paramMap[ParamEnforce] != nil && paramMap[ParamEnforce].AsBool()
+	dryRun := paramMap[ParamEnforceDryRun] != nil && paramMap[ParamEnforceDryRun].AsBool()
+
+	switch {
+	case enforce && dryRun:
+		return fmt.Errorf("%s and %s can't be used together", ParamEnforce, ParamEnforceDryRun)
+	case enforce:
+		return i.enableEnforcement(gadgetCtx)
+	case dryRun:
+		return i.enableEnforcementDryRun(gadgetCtx)
+	}
+	return nil
+}
+
+// teardownEnforcement writes the audit entry once the LSM programs have been
+// detached
+func (i *ebpfInstance) teardownEnforcement(gadgetCtx operators.GadgetContext) {
+	if i.enforcing {
+		i.auditEnforcement(gadgetCtx, "enforcement disabled")
+		i.enforcing = false
+	}
+	if i.enforcingDryRun {
+		i.auditEnforcement(gadgetCtx, "enforcement dry-run disabled")
+		i.enforcingDryRun = false
+	}
+}
+
+func (i *ebpfInstance) auditEnforcement(gadgetCtx operators.GadgetContext, msg string) {
+	log.WithFields(log.Fields{
+		"audit":    "enforcement",
+		"gadget":   gadgetCtx.ImageName(),
+		"instance": gadgetCtx.ID(),
+		"programs": strings.Join(i.lsmPrograms(), ","),
+	}).Warn(msg)
+	i.logger.Warnf("%s for gadget %q", msg, gadgetCtx.ImageName())
+}
+
+// enforcementAllowed checks whether the image is part of the allowed gadgets
+// for enforcement. Entries can either match the full image name or, if ending
+// with '*', its prefix.
+func (o *ebpfOperator) enforcementAllowed(image string) (bool, error) {
+	if o.globalParams == nil {
+		return false, nil
+	}
+	allowedGadgets := o.globalParams.Get(ParamEnforcementAllowedGadgets).AsStringSlice()
+	if len(allowedGadgets) == 0 {
+		return false, nil
+	}
+
+	imageStr, err := oci.NormalizeImageName(image)
+	if err != nil {
+		return false, fmt.Errorf("normalizing image: %w", err)
+	}
+
+	for _, allowedGadget := range allowedGadgets {
+		if allowedGadget == "" {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(allowedGadget, "*"); ok {
+			if strings.HasPrefix(imageStr, prefix) {
+				return true, nil
+			}
+			continue
+		}
+		if imageStr == allowedGadget {
+			return true, nil
+		}
+	}
+	return false, nil
+}
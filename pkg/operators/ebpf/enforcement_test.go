@@ -0,0 +1,102 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/require"
+
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+)
+
+func TestEnforcementAllowed(t *testing.T) {
+	type testCase struct {
+		allowedGadgets string
+		image          string
+		expected       bool
+	}
+
+	tests := map[string]testCase{
+		"no_policy": {
+			image:    "trace_exec",
+			expected: false,
+		},
+		"full_name": {
+			allowedGadgets: "ghcr.io/inspektor-gadget/gadget/deny_exec:latest",
+			image:          "deny_exec",
+			expected:       true,
+		},
+		"full_name_other_tag": {
+			allowedGadgets: "ghcr.io/inspektor-gadget/gadget/deny_exec:latest",
+			image:          "deny_exec:v0.40.0",
+			expected:       false,
+		},
+		"prefix": {
+			allowedGadgets: "ghcr.io/inspektor-gadget/gadget/deny_*",
+			image:          "ghcr.io/inspektor-gadget/gadget/deny_connect:v0.40.0",
+			expected:       true,
+		},
+		"prefix_no_match": {
+			allowedGadgets: "ghcr.io/inspektor-gadget/gadget/deny_*",
+			image:          "trace_exec",
+			expected:       false,
+		},
+		"multiple": {
+			allowedGadgets: "myregistry.io/foo:latest,ghcr.io/inspektor-gadget/gadget/*",
+			image:          "trace_exec",
+			expected:       true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			o := &ebpfOperator{}
+			globalParams := apihelpers.ToParamDescs(o.GlobalParams()).ToParams()
+			require.NoError(t, globalParams.Set(ParamEnforcementAllowedGadgets, test.allowedGadgets))
+			require.NoError(t, o.Init(globalParams))
+
+			allowed, err := o.enforcementAllowed(test.image)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, allowed)
+		})
+	}
+
+	t.Run("not_initialized", func(t *testing.T) {
+		o := &ebpfOperator{}
+		allowed, err := o.enforcementAllowed("trace_exec")
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+}
+
+func TestConfineLSMProgram(t *testing.T) {
+	p := &ebpf.ProgramSpec{
+		Name:        "deny_file_open",
+		Type:        ebpf.LSM,
+		AttachType:  ebpf.AttachLSMMac,
+		AttachTo:    "file_open",
+		SectionName: "lsm/file_open",
+	}
+	confineLSMProgram(p)
+
+	require.Equal(t, ebpf.Tracing, p.Type)
+	require.Equal(t, ebpf.AttachTraceFEntry, p.AttachType)
+	require.Equal(t, "bpf_lsm_file_open", p.AttachTo)
+	require.Equal(t, "fentry/bpf_lsm_file_open", p.SectionName)
+}
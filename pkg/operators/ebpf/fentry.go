@@ -0,0 +1,139 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/btf"
+	"github.com/cilium/ebpf/link"
+)
+
+// Attach modes of fentry and fexit programs having a fallback, set with
+// programs.<name>.attach_mode in the gadget metadata; attachModeAuto uses the
+// fentry or fexit program if the kernel can attach it, its fallback otherwise
+const (
+	// attachModeFentry always uses the fentry or fexit program
+	attachModeFentry = "fentry"
+	// attachModeKprobe always uses the fallback
+	attachModeKprobe = "kprobe"
+)
+
+// fentryProbeFunction is a kernel function built with BPF support that fentry
+// programs can be attached to
+const fentryProbeFunction = "bpf_fentry_test1"
+
+// haveFentry returns true if the kernel can attach fentry and fexit programs:
+// it needs BTF and BPF trampolines, available since Linux 5.5 on x86 and 6.0
+// on arm64
+var haveFentry = sync.OnceValue(func() bool {
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:       ebpf.Tracing,
+		AttachType: ebpf.AttachTraceFEntry,
+		AttachTo:   fentryProbeFunction,
+		Instructions: asm.Instructions{
+			asm.Mov.Imm(asm.R0, 0),
+			asm.Return(),
+		},
+		License: "GPL",
+	})
+	if err != nil {
+		return false
+	}
+	defer prog.Close()
+
+	l, err := link.AttachTracing(link.TracingOptions{Program: prog})
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+})
+
+// kernelHasFunction returns true if the kernel function is described by the
+// BTF of the kernel, which is needed to attach fentry and fexit programs to it
+var kernelHasFunction = func(name string) bool {
+	spec, err := btf.LoadKernelSpec()
+	if err != nil {
+		return false
+	}
+	var fn *btf.Func
+	return spec.TypeByName(name, &fn) == nil
+}
+
+func isFentry(p *ebpf.ProgramSpec) bool {
+	return p.Type == ebpf.Tracing &&
+		(strings.HasPrefix(p.SectionName, fentryPrefix) || strings.HasPrefix(p.SectionName, fexitPrefix))
+}
+
+// setupFentry chooses between the fentry and fexit programs having a fallback,
+// set with programs.<name>.fallback in the gadget metadata, and their
+// fallback, which is a kprobe or kretprobe program doing the same. The program
+// not chosen isn't loaded.
+func (i *ebpfInstance) setupFentry() error {
+	for _, name := range slices.Sorted(maps.Keys(i.collectionSpec.Programs)) {
+		p, ok := i.collectionSpec.Programs[name]
+		if !ok {
+			// Already removed as fallback of another program
+			continue
+		}
+		fallbackName := i.config.GetString("programs." + name + ".fallback")
+		if fallbackName == "" {
+			continue
+		}
+		if !isFentry(p) {
+			return fmt.Errorf("program %q: only fentry and fexit programs can have a fallback", name)
+		}
+		fallback, ok := i.collectionSpec.Programs[fallbackName]
+		if !ok {
+			return fmt.Errorf("program %q: fallback %q not found", name, fallbackName)
+		}
+		if fallback.Type != ebpf.Kprobe {
+			return fmt.Errorf("program %q: fallback %q must be a kprobe or kretprobe program", name, fallbackName)
+		}
+
+		useFentry := false
+		switch mode := i.programAttachMode(p); mode {
+		case attachModeAuto:
+			switch {
+			case !haveFentry():
+				i.logger.Debugf("fentry programs not supported, using %q instead of %q", fallbackName, name)
+			case !kernelHasFunction(p.AttachTo):
+				i.logger.Debugf("%q not found in the kernel BTF, using %q instead of %q", p.AttachTo, fallbackName, name)
+			default:
+				useFentry = true
+			}
+		case attachModeFentry:
+			useFentry = true
+		case attachModeKprobe:
+		default:
+			return fmt.Errorf("program %q: invalid attach mode %q, expected %q, %q or %q",
+				name, mode, attachModeAuto, attachModeFentry, attachModeKprobe)
+		}
+
+		if useFentry {
+			delete(i.collectionSpec.Programs, fallbackName)
+		} else {
+			delete(i.collectionSpec.Programs, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+func TestSetupFentry(t *testing.T) {
+	origHaveFentry, origKernelHasFunction := haveFentry, kernelHasFunction
+	t.Cleanup(func() {
+		haveFentry, kernelHasFunction = origHaveFentry, origKernelHasFunction
+	})
+
+	newInstance := func(settings map[string]string) *ebpfInstance {
+		config := viper.New()
+		config.Set("programs.ig_fentry.fallback", "ig_kprobe")
+		for k, v := range settings {
+			config.Set(k, v)
+		}
+		return &ebpfInstance{
+			config: config,
+			logger: logger.DefaultLogger(),
+			collectionSpec: &ebpf.CollectionSpec{
+				Programs: map[string]*ebpf.ProgramSpec{
+					"ig_fentry": {Name: "ig_fentry", Type: ebpf.Tracing, AttachType: ebpf.AttachTraceFEntry, SectionName: "fentry/vfs_read", AttachTo: "vfs_read"},
+					"ig_kprobe": {Name: "ig_kprobe", Type: ebpf.Kprobe, SectionName: "kprobe/vfs_read", AttachTo: "vfs_read"},
+					"ig_fexit":  {Name: "ig_fexit", Type: ebpf.Tracing, AttachType: ebpf.AttachTraceFExit, SectionName: "fexit/vfs_write", AttachTo: "vfs_write"},
+				},
+			},
+		}
+	}
+	programs := func(i *ebpfInstance) []string {
+		return slices.Sorted(maps.Keys(i.collectionSpec.Programs))
+	}
+
+	type testCase struct {
+		settings      map[string]string
+		fentry        bool
+		kernelHasFunc bool
+		expected      []string
+		err           bool
+	}
+	for name, tc := range map[string]testCase{
+		"auto-supported": {
+			fentry:        true,
+			kernelHasFunc: true,
+			expected:      []string{"ig_fentry", "ig_fexit"},
+		},
+		"auto-not-supported": {
+			kernelHasFunc: true,
+			expected:      []string{"ig_fexit", "ig_kprobe"},
+		},
+		"auto-function-without-btf": {
+			fentry:   true,
+			expected: []string{"ig_fexit", "ig_kprobe"},
+		},
+		"fentry": {
+			settings: map[string]string{"programs.ig_fentry.attach_mode": attachModeFentry},
+			expected: []string{"ig_fentry", "ig_fexit"},
+		},
+		"kprobe": {
+			settings:      map[string]string{"programs.ig_fentry.attach_mode": attachModeKprobe},
+			fentry:        true,
+			kernelHasFunc: true,
+			expected:      []string{"ig_fexit", "ig_kprobe"},
+		},
+		"invalid-mode": {
+			settings: map[string]string{"programs.ig_fentry.attach_mode": "multi"},
+			err:      true,
+		},
+		"unknown-fallback": {
+			settings: map[string]string{"programs.ig_fentry.fallback": "does_not_exist"},
+			err:      true,
+		},
+		"fallback-not-kprobe": {
+			settings: map[string]string{"programs.ig_fentry.fallback": "ig_fexit"},
+			err:      true,
+		},
+		"fallback-of-kprobe": {
+			settings: map[string]string{"programs.ig_kprobe.fallback": "ig_fentry"},
+			err:      true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			haveFentry = func() bool { return tc.fentry }
+			kernelHasFunction = func(string) bool { return tc.kernelHasFunc }
+
+			i := newInstance(tc.settings)
+			err := i.setupFentry()
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, programs(i))
+		})
+	}
+}
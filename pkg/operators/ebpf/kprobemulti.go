@@ -0,0 +1,215 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/nodeinfo"
+)
+
+const (
+	// kprobeMultiPrefix and kretprobeMultiPrefix are followed by a
+	// comma-separated list of patterns of kernel functions
+	kprobeMultiPrefix    = "kprobe.multi/"
+	kretprobeMultiPrefix = "kretprobe.multi/"
+)
+
+// Attach modes of kprobe.multi and kretprobe.multi programs, set with
+// programs.<name>.attach_mode in the gadget metadata
+const (
+	// attachModeAuto uses a kprobe_multi link if the kernel supports it, one
+	// kprobe per function otherwise
+	attachModeAuto = "auto"
+	// attachModeMulti always uses a kprobe_multi link
+	attachModeMulti = "multi"
+	// attachModeSingle always uses one kprobe per function
+	attachModeSingle = "single"
+)
+
+// haveKprobeMulti returns true if the kernel supports kprobe_multi links:
+// Linux 5.18 built with CONFIG_FPROBE
+var haveKprobeMulti = sync.OnceValue(func() bool {
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:       ebpf.Kprobe,
+		AttachType: ebpf.AttachTraceKprobeMulti,
+		Instructions: asm.Instructions{
+			asm.Mov.Imm(asm.R0, 0),
+			asm.Return(),
+		},
+		License: "GPL",
+	})
+	if err != nil {
+		return false
+	}
+	defer prog.Close()
+
+	l, err := link.KprobeMulti(prog, link.KprobeMultiOptions{Symbols: []string{"vprintk"}})
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+})
+
+func (i *ebpfInstance) programAttachMode(p *ebpf.ProgramSpec) string {
+	if mode := i.config.GetString("programs." + p.Name + ".attach_mode"); mode != "" {
+		return mode
+	}
+	return attachModeAuto
+}
+
+// setupKprobeMulti selects how kprobe.multi and kretprobe.multi programs are
+// attached. Programs attached with one kprobe per function are loaded as
+// regular kprobes, as the kernel doesn't allow attaching them otherwise.
+func (i *ebpfInstance) setupKprobeMulti() error {
+	for name, p := range i.collectionSpec.Programs {
+		if p.Type != ebpf.Kprobe || p.AttachType != ebpf.AttachTraceKprobeMulti {
+			continue
+		}
+
+		switch mode := i.programAttachMode(p); mode {
+		case attachModeAuto:
+			if haveKprobeMulti() {
+				continue
+			}
+			i.logger.Debugf("kprobe_multi links not supported, attaching program %q with one kprobe per function", name)
+		case attachModeMulti:
+			if !haveKprobeMulti() {
+				return fmt.Errorf("program %q: kprobe_multi links not supported, they need Linux 5.18 built with CONFIG_FPROBE", name)
+			}
+			continue
+		case attachModeSingle:
+		default:
+			return fmt.Errorf("program %q: invalid attach mode %q, expected %q, %q or %q",
+				name, mode, attachModeAuto, attachModeMulti, attachModeSingle)
+		}
+		p.AttachType = ebpf.AttachNone
+	}
+	return nil
+}
+
+// attachKprobeMulti attaches a kprobe.multi or kretprobe.multi program to the
+// kernel functions matching the comma-separated list of patterns, like
+// "__x64_sys_*". Without kprobe_multi links, it adds a link for each function
+// to i.links and returns a nil link.
+func (i *ebpfInstance) attachKprobeMulti(p *ebpf.ProgramSpec, prog *ebpf.Program, attachTo string) (link.Link, error) {
+	symbols, err := kprobeSymbols(strings.Split(attachTo, ","))
+	if err != nil {
+		return nil, err
+	}
+	ret := strings.HasPrefix(p.SectionName, kretprobeMultiPrefix)
+
+	if p.AttachType == ebpf.AttachTraceKprobeMulti {
+		i.logger.Debugf("Attaching kprobe_multi %q to %d functions", p.Name, len(symbols))
+		opts := link.KprobeMultiOptions{Symbols: symbols}
+		if ret {
+			return link.KretprobeMulti(prog, opts)
+		}
+		return link.KprobeMulti(prog, opts)
+	}
+
+	i.logger.Debugf("Attaching kprobe %q to %d functions", p.Name, len(symbols))
+	for _, symbol := range symbols {
+		var l link.Link
+		if ret {
+			l, err = link.Kretprobe(symbol, prog, nil)
+		} else {
+			l, err = link.Kprobe(symbol, prog, nil)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("attaching to %q: %w", symbol, err)
+		}
+		i.links = append(i.links, l)
+	}
+	return nil, nil
+}
+
+// kprobeSymbols returns the kernel functions that can be probed matching the
+// patterns
+func kprobeSymbols(patterns []string) ([]string, error) {
+	tracefs, err := nodeinfo.TracefsPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(tracefs, "available_filter_functions"))
+	if err != nil {
+		return nil, fmt.Errorf("reading functions that can be probed: %w", err)
+	}
+	defer f.Close()
+
+	return matchFilterFunctions(f, patterns)
+}
+
+// matchFilterFunctions returns the functions of available_filter_functions
+// matching the patterns, in the syntax of path.Match(). Lines look like
+// "function_name" or "function_name [module]", functions can be listed more
+// than once. It fails if a pattern doesn't match any function.
+func matchFilterFunctions(r io.Reader, patterns []string) ([]string, error) {
+	for idx, pattern := range patterns {
+		patterns[idx] = strings.TrimSpace(pattern)
+		if _, err := path.Match(patterns[idx], ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	var symbols []string
+	seen := make(map[string]struct{})
+	matched := make([]bool, len(patterns))
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		fn := fields[0]
+		for idx, pattern := range patterns {
+			if ok, _ := path.Match(pattern, fn); !ok {
+				continue
+			}
+			matched[idx] = true
+			if _, ok := seen[fn]; !ok {
+				seen[fn] = struct{}{}
+				symbols = append(symbols, fn)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for idx, pattern := range patterns {
+		if !matched[idx] {
+			errs = append(errs, fmt.Errorf("no kernel function matching %q can be probed", pattern))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+const filterFunctions = `__x64_sys_read
+__x64_sys_write
+__x64_sys_openat
+vfs_read
+vfs_read
+tcp_connect [tcp]
+`
+
+func TestMatchFilterFunctions(t *testing.T) {
+	type testCase struct {
+		patterns []string
+		expected []string
+		err      bool
+	}
+	for name, tc := range map[string]testCase{
+		"exact": {
+			patterns: []string{"vfs_read"},
+			expected: []string{"vfs_read"},
+		},
+		"glob": {
+			patterns: []string{"__x64_sys_*"},
+			expected: []string{"__x64_sys_read", "__x64_sys_write", "__x64_sys_openat"},
+		},
+		"several": {
+			patterns: []string{"__x64_sys_*read*", " vfs_* ", "tcp_connect"},
+			expected: []string{"__x64_sys_read", "vfs_read", "tcp_connect"},
+		},
+		"no-match": {
+			patterns: []string{"vfs_*", "do_exit"},
+			err:      true,
+		},
+		"invalid": {
+			patterns: []string{"vfs_[read"},
+			err:      true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			symbols, err := matchFilterFunctions(strings.NewReader(filterFunctions), tc.patterns)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, symbols)
+		})
+	}
+}
+
+func TestSetupKprobeMulti(t *testing.T) {
+	newInstance := func(mode string) *ebpfInstance {
+		config := viper.New()
+		if mode != "" {
+			config.Set("programs.multi.attach_mode", mode)
+		}
+		return &ebpfInstance{
+			config: config,
+			logger: logger.DefaultLogger(),
+			collectionSpec: &ebpf.CollectionSpec{
+				Programs: map[string]*ebpf.ProgramSpec{
+					"multi":  {Name: "multi", Type: ebpf.Kprobe, AttachType: ebpf.AttachTraceKprobeMulti, SectionName: "kprobe.multi/__x64_sys_*"},
+					"kprobe": {Name: "kprobe", Type: ebpf.Kprobe, SectionName: "kprobe/vfs_read"},
+				},
+			},
+		}
+	}
+
+	i := newInstance(attachModeSingle)
+	require.NoError(t, i.setupKprobeMulti())
+	assert.Equal(t, ebpf.AttachNone, i.collectionSpec.Programs["multi"].AttachType)
+
+	i = newInstance("")
+	require.NoError(t, i.setupKprobeMulti())
+	expected := ebpf.AttachNone
+	if haveKprobeMulti() {
+		expected = ebpf.AttachTraceKprobeMulti
+	}
+	assert.Equal(t, expected, i.collectionSpec.Programs["multi"].AttachType)
+	assert.Equal(t, ebpf.AttachNone, i.collectionSpec.Programs["kprobe"].AttachType)
+
+	i = newInstance(attachModeMulti)
+	if haveKprobeMulti() {
+		assert.NoError(t, i.setupKprobeMulti())
+	} else {
+		assert.Error(t, i.setupKprobeMulti())
+	}
+
+	assert.Error(t, newInstance("invalid").setupKprobeMulti())
+}
@@ -0,0 +1,218 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
+)
+
+// Modes of map iterators; the map is always read and cleared every interval,
+// the modes define what is emitted
+const (
+	// MapFetchModeReset emits the values of the last interval
+	MapFetchModeReset = "reset"
+	// MapFetchModeCumulative emits the values accumulated since the gadget
+	// started
+	MapFetchModeCumulative = "cumulative"
+	// MapFetchModeRolling emits the values accumulated over the last
+	// map-fetch-window intervals
+	MapFetchModeRolling = "rolling"
+)
+
+var mapFetchModes = []string{MapFetchModeReset, MapFetchModeCumulative, MapFetchModeRolling}
+
+// counterField is a numeric field of the value struct of a map, or an array
+// of numeric values like histogram slots, that is summed when aggregating
+type counterField struct {
+	offset uint32
+	size   uint32
+	kind   api.Kind
+}
+
+func kindSize(kind api.Kind) uint32 {
+	switch kind {
+	case api.Kind_Int8, api.Kind_Uint8:
+		return 1
+	case api.Kind_Int16, api.Kind_Uint16:
+		return 2
+	case api.Kind_Int32, api.Kind_Uint32, api.Kind_Float32:
+		return 4
+	case api.Kind_Int64, api.Kind_Uint64, api.Kind_Float64:
+		return 8
+	}
+	return 0
+}
+
+// summableTypes are the gadget types whose values can be summed. Only fields
+// explicitly declared with one of them are counters: plain integers can be
+// ids, pids or flags too.
+var summableTypes = []string{
+	ebpftypes.CounterU32TypeName,
+	ebpftypes.CounterU64TypeName,
+	ebpftypes.HistogramSlotU32TypeName,
+	ebpftypes.HistogramSlotU64TypeName,
+	ebpftypes.BytesTypeName,
+	ebpftypes.DurationTypeName,
+}
+
+func isCounter(f *Field) bool {
+	for _, tag := range f.Tags {
+		typ, ok := strings.CutPrefix(tag, "type:")
+		if ok && slices.Contains(summableTypes, typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// counterFields returns the fields of a value struct that are counters
+func counterFields(fields []*Field) []counterField {
+	res := make([]counterField, 0, len(fields))
+	for _, f := range fields {
+		if !isCounter(f) {
+			continue
+		}
+		kind := f.kind &^ api.KindFlagArray
+		elemSize := kindSize(kind)
+		if elemSize == 0 || f.Size%elemSize != 0 {
+			continue
+		}
+		res = append(res, counterField{offset: f.Offset, size: f.Size, kind: kind})
+	}
+	return res
+}
+
+// add adds the values of the field in src to the ones in dst
+func (c *counterField) add(dst, src []byte) {
+	elemSize := kindSize(c.kind)
+	for off := c.offset; off+elemSize <= c.offset+c.size; off += elemSize {
+		d := dst[off : off+elemSize]
+		s := src[off : off+elemSize]
+		switch c.kind {
+		case api.Kind_Int8, api.Kind_Uint8:
+			d[0] += s[0]
+		case api.Kind_Int16, api.Kind_Uint16:
+			binary.NativeEndian.PutUint16(d, binary.NativeEndian.Uint16(d)+binary.NativeEndian.Uint16(s))
+		case api.Kind_Int32, api.Kind_Uint32:
+			binary.NativeEndian.PutUint32(d, binary.NativeEndian.Uint32(d)+binary.NativeEndian.Uint32(s))
+		case api.Kind_Int64, api.Kind_Uint64:
+			binary.NativeEndian.PutUint64(d, binary.NativeEndian.Uint64(d)+binary.NativeEndian.Uint64(s))
+		case api.Kind_Float32:
+			v := math.Float32frombits(binary.NativeEndian.Uint32(d)) + math.Float32frombits(binary.NativeEndian.Uint32(s))
+			binary.NativeEndian.PutUint32(d, math.Float32bits(v))
+		case api.Kind_Float64:
+			v := math.Float64frombits(binary.NativeEndian.Uint64(d)) + math.Float64frombits(binary.NativeEndian.Uint64(s))
+			binary.NativeEndian.PutUint64(d, math.Float64bits(v))
+		}
+	}
+}
+
+// mapAggregator accumulates the entries read from a map every interval.
+// Counter fields of the values are summed, the other fields keep the value of
+// the last interval the key was seen in.
+type mapAggregator struct {
+	mode     string
+	window   int
+	counters []counterField
+
+	// totals holds the accumulated entries in cumulative mode
+	totals map[string][]byte
+	// intervals holds the entries of the last intervals in rolling mode,
+	// oldest first
+	intervals []map[string][]byte
+}
+
+// newMapAggregator returns the aggregator for the given mode, or nil if the
+// values of the last interval are emitted as they are
+func newMapAggregator(mode string, window int, valueFields []*Field) (*mapAggregator, error) {
+	if !slices.Contains(mapFetchModes, mode) {
+		return nil, fmt.Errorf("invalid map fetch mode %q, valid values are: %s", mode, strings.Join(mapFetchModes, ", "))
+	}
+	if mode == MapFetchModeRolling && window < 1 {
+		return nil, fmt.Errorf("invalid map fetch window %d, it needs to be at least 1", window)
+	}
+	if mode == MapFetchModeReset {
+		return nil, nil
+	}
+	return &mapAggregator{
+		mode:     mode,
+		window:   window,
+		counters: counterFields(valueFields),
+		totals:   make(map[string][]byte),
+	}, nil
+}
+
+func (a *mapAggregator) sum(dst, src []byte) {
+	for i := range a.counters {
+		a.counters[i].add(dst, src)
+	}
+}
+
+// update adds the entries of the last interval and returns the keys and values
+// to emit, sorted by key
+func (a *mapAggregator) update(entries map[string][]byte) ([]string, [][]byte) {
+	var res map[string][]byte
+	switch a.mode {
+	case MapFetchModeCumulative:
+		for key, val := range entries {
+			total, ok := a.totals[key]
+			if !ok {
+				a.totals[key] = slices.Clone(val)
+				continue
+			}
+			// Keep non-counter fields of the last interval
+			merged := slices.Clone(val)
+			a.sum(merged, total)
+			a.totals[key] = merged
+		}
+		res = a.totals
+	case MapFetchModeRolling:
+		a.intervals = append(a.intervals, entries)
+		if len(a.intervals) > a.window {
+			a.intervals = a.intervals[len(a.intervals)-a.window:]
+		}
+		res = make(map[string][]byte)
+		for _, interval := range a.intervals {
+			for key, val := range interval {
+				total, ok := res[key]
+				if !ok {
+					res[key] = slices.Clone(val)
+					continue
+				}
+				merged := slices.Clone(val)
+				a.sum(merged, total)
+				res[key] = merged
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(res))
+	for key := range res {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	vals := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		vals = append(vals, res[key])
+	}
+	return keys, vals
+}
@@ -0,0 +1,143 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// testValue mirrors a value struct with a counter, a gauge and a histogram
+type testValue struct {
+	count uint64
+	gauge uint32
+	hist  [2]uint32
+}
+
+var testValueFields = []*Field{
+	{name: "count", Offset: 0, Size: 8, kind: api.Kind_Uint64, Tags: []string{"type:gadget_counter__u64"}, parent: -1},
+	{name: "gauge", Offset: 8, Size: 4, kind: api.Kind_Uint32, Tags: []string{"type:gadget_gauge__u32"}, parent: -1},
+	{name: "hist", Offset: 12, Size: 8, kind: api.ArrayOf(api.Kind_Uint32), Tags: []string{"type:gadget_histogram_slot__u32"}, parent: -1},
+}
+
+func (v testValue) encode() []byte {
+	b := make([]byte, 20)
+	binary.NativeEndian.PutUint64(b[0:], v.count)
+	binary.NativeEndian.PutUint32(b[8:], v.gauge)
+	binary.NativeEndian.PutUint32(b[12:], v.hist[0])
+	binary.NativeEndian.PutUint32(b[16:], v.hist[1])
+	return b
+}
+
+func decodeTestValue(b []byte) testValue {
+	return testValue{
+		count: binary.NativeEndian.Uint64(b[0:]),
+		gauge: binary.NativeEndian.Uint32(b[8:]),
+		hist:  [2]uint32{binary.NativeEndian.Uint32(b[12:]), binary.NativeEndian.Uint32(b[16:])},
+	}
+}
+
+func TestNewMapAggregator(t *testing.T) {
+	a, err := newMapAggregator(MapFetchModeReset, 0, testValueFields)
+	require.NoError(t, err)
+	assert.Nil(t, a)
+
+	_, err = newMapAggregator("sum", 5, testValueFields)
+	require.Error(t, err)
+
+	_, err = newMapAggregator(MapFetchModeRolling, 0, testValueFields)
+	require.Error(t, err)
+
+	a, err = newMapAggregator(MapFetchModeCumulative, 0, testValueFields)
+	require.NoError(t, err)
+	assert.Len(t, a.counters, 2)
+}
+
+func TestCounterFields(t *testing.T) {
+	fields := []*Field{
+		{name: "proc", Offset: 0, Size: 8, kind: api.Kind_Invalid, Tags: []string{"type:gadget_process"}, parent: -1},
+		{name: "pid", Offset: 0, Size: 4, kind: api.Kind_Uint32, Tags: []string{"type:gadget_pid"}, parent: 0},
+		{name: "uid", Offset: 4, Size: 4, kind: api.Kind_Uint32, Tags: []string{"type:__u32"}, parent: 0},
+		{name: "id", Offset: 8, Size: 8, kind: api.Kind_Uint64, Tags: []string{"type:__u64"}, parent: -1},
+		{name: "reads", Offset: 16, Size: 8, kind: api.Kind_Uint64, Tags: []string{"type:gadget_counter__u64"}, parent: -1},
+		{name: "bytes", Offset: 24, Size: 8, kind: api.Kind_Uint64, Tags: []string{"type:gadget_bytes"}, parent: -1},
+	}
+	assert.Equal(t, []counterField{
+		{offset: 16, size: 8, kind: api.Kind_Uint64},
+		{offset: 24, size: 8, kind: api.Kind_Uint64},
+	}, counterFields(fields))
+}
+
+func TestMapAggregator(t *testing.T) {
+	intervals := []map[string]testValue{
+		{"a": {count: 1, gauge: 10, hist: [2]uint32{1, 0}}},
+		{"a": {count: 2, gauge: 20, hist: [2]uint32{0, 1}}, "b": {count: 5, gauge: 1}},
+		{"b": {count: 1, gauge: 2}},
+		{"a": {count: 4, gauge: 5, hist: [2]uint32{2, 2}}},
+	}
+
+	tests := []struct {
+		mode     string
+		window   int
+		expected []map[string]testValue
+	}{
+		{
+			mode: MapFetchModeCumulative,
+			expected: []map[string]testValue{
+				{"a": {count: 1, gauge: 10, hist: [2]uint32{1, 0}}},
+				{"a": {count: 3, gauge: 20, hist: [2]uint32{1, 1}}, "b": {count: 5, gauge: 1}},
+				{"a": {count: 3, gauge: 20, hist: [2]uint32{1, 1}}, "b": {count: 6, gauge: 2}},
+				{"a": {count: 7, gauge: 5, hist: [2]uint32{3, 3}}, "b": {count: 6, gauge: 2}},
+			},
+		},
+		{
+			mode:   MapFetchModeRolling,
+			window: 2,
+			expected: []map[string]testValue{
+				{"a": {count: 1, gauge: 10, hist: [2]uint32{1, 0}}},
+				{"a": {count: 3, gauge: 20, hist: [2]uint32{1, 1}}, "b": {count: 5, gauge: 1}},
+				{"a": {count: 2, gauge: 20, hist: [2]uint32{0, 1}}, "b": {count: 6, gauge: 2}},
+				{"a": {count: 4, gauge: 5, hist: [2]uint32{2, 2}}, "b": {count: 1, gauge: 2}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			a, err := newMapAggregator(test.mode, test.window, testValueFields)
+			require.NoError(t, err)
+
+			for i, interval := range intervals {
+				entries := make(map[string][]byte)
+				for key, val := range interval {
+					entries[key] = val.encode()
+				}
+
+				keys, vals := a.update(entries)
+				got := make(map[string]testValue)
+				for c, key := range keys {
+					got[key] = decodeTestValue(vals[c])
+				}
+				assert.Equal(t, test.expected[i], got, "interval %d", i)
+				assert.IsIncreasing(t, keys)
+			}
+		})
+	}
+}
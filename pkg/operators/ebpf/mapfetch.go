@@ -0,0 +1,195 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// mapBatchMaxSize is the maximum number of entries read by each batch
+	// syscall
+	mapBatchMaxSize = 4096
+
+	// ENOTSUPP is returned by the kernel for map types without batch
+	// operations, it's not exposed to user space headers
+	errnoENOTSUPP = unix.Errno(524)
+)
+
+var errBatchUnsupported = errors.New("batch operations not supported")
+
+// mapFetcher reads and deletes all the entries of a map. It uses batch
+// syscalls when the kernel supports them (5.6+) and falls back to reading the
+// entries one by one otherwise. Each entry is read and deleted atomically,
+// except on kernels without lookup and delete for the map type either (5.14
+// for hash maps), where updates done by eBPF programs between reading and
+// deleting an entry are lost. key and val passed to the callbacks aren't
+// reused and can be kept.
+type mapFetcher struct {
+	m         *ebpf.Map
+	keySize   int
+	valSize   int
+	batchSize int
+
+	// noBatch is set once the kernel refused a batch syscall
+	noBatch bool
+	// noLookupAndDelete is set once the kernel refused a lookup and delete
+	// syscall
+	noLookupAndDelete bool
+}
+
+func newMapFetcher(m *ebpf.Map) *mapFetcher {
+	return &mapFetcher{
+		m:         m,
+		keySize:   int(m.KeySize()),
+		valSize:   int(m.ValueSize()),
+		batchSize: max(min(int(m.MaxEntries()), mapBatchMaxSize), 1),
+	}
+}
+
+// fetch calls cb for all the entries of the map and deletes them
+func (f *mapFetcher) fetch(cb func(key, val []byte)) error {
+	if !f.noBatch {
+		err := f.fetchBatch(cb)
+		if !errors.Is(err, errBatchUnsupported) {
+			return err
+		}
+		f.noBatch = true
+	}
+	return f.fetchOneByOne(cb)
+}
+
+func (f *mapFetcher) fetchBatch(cb func(key, val []byte)) error {
+	// The kernel uses at least 4 bytes for the batch token of hash maps, the
+	// index of a bucket
+	tokenSize := max(f.keySize, 4)
+	var inBatch []byte
+	outBatch := make([]byte, tokenSize)
+	first := true
+	for {
+		// The buffers are passed to cb, allocate new ones every time
+		keys := make([]byte, f.keySize*f.batchSize)
+		vals := make([]byte, f.valSize*f.batchSize)
+
+		// TODO: use cilium lib once raw byte access has been added
+		attr := MapLookupBatchAttr{
+			MapFd:    uint32(f.m.FD()),
+			Keys:     Pointer{ptr: unsafe.Pointer(&keys[0])},
+			Values:   Pointer{ptr: unsafe.Pointer(&vals[0])},
+			Count:    uint32(f.batchSize),
+			OutBatch: Pointer{ptr: unsafe.Pointer(&outBatch[0])},
+		}
+		if inBatch != nil {
+			attr.InBatch = Pointer{ptr: unsafe.Pointer(&inBatch[0])}
+		}
+
+		_, err := BPF(BPF_MAP_LOOKUP_AND_DELETE_BATCH, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+		switch {
+		case err == nil, errors.Is(err, unix.ENOENT):
+		case errors.Is(err, unix.ENOSPC) && attr.Count == 0:
+			// A bucket of the hash map has more entries than the batch,
+			// retry with a larger one
+			f.batchSize *= 2
+			continue
+		case first && (errors.Is(err, unix.EINVAL) || errors.Is(err, errnoENOTSUPP)):
+			// Unknown command or map type without batch operations
+			return errBatchUnsupported
+		default:
+			return fmt.Errorf("reading map in batch: %w", err)
+		}
+		first = false
+
+		for c := range int(attr.Count) {
+			cb(keys[f.keySize*c:f.keySize*(c+1)], vals[f.valSize*c:f.valSize*(c+1)])
+		}
+		if errors.Is(err, unix.ENOENT) { // ebpf.ErrKeyNotExist when doing this with cilium/ebpf later on
+			return nil
+		}
+		inBatch, outBatch = outBatch, inBatch
+		if outBatch == nil {
+			outBatch = make([]byte, tokenSize)
+		}
+	}
+}
+
+// fetchOneByOne collects the keys first, as deleting entries while iterating
+// over a hash map restarts the iteration. Entries deleted by the eBPF programs
+// meanwhile can restart it too, so it stops after reading as many keys as the
+// map can hold.
+func (f *mapFetcher) fetchOneByOne(cb func(key, val []byte)) error {
+	var keys [][]byte
+	var key any
+	for len(keys) < int(f.m.MaxEntries()) {
+		next, err := f.m.NextKeyBytes(key)
+		if err != nil {
+			return fmt.Errorf("iterating over map: %w", err)
+		}
+		if next == nil {
+			break
+		}
+		keys = append(keys, next)
+		key = next
+	}
+
+	for _, key := range keys {
+		val, err := f.lookupAndDelete(key)
+		if err != nil {
+			return err
+		}
+		// Deleted meanwhile
+		if val == nil {
+			continue
+		}
+		cb(key, val)
+	}
+	return nil
+}
+
+// lookupAndDelete reads and deletes an entry of the map. It returns nil if the
+// entry doesn't exist.
+func (f *mapFetcher) lookupAndDelete(key []byte) ([]byte, error) {
+	if !f.noLookupAndDelete {
+		val := make([]byte, f.valSize)
+		err := f.m.LookupAndDelete(key, val)
+		switch {
+		case err == nil:
+			return val, nil
+		case errors.Is(err, ebpf.ErrKeyNotExist):
+			return nil, nil
+		case errors.Is(err, ebpf.ErrNotSupported), errors.Is(err, unix.EINVAL):
+			f.noLookupAndDelete = true
+		default:
+			return nil, fmt.Errorf("looking up and deleting map entry: %w", err)
+		}
+	}
+
+	// Updates done between the lookup and the deletion are lost
+	val, err := f.m.LookupBytes(key)
+	if err != nil {
+		return nil, fmt.Errorf("looking up map entry: %w", err)
+	}
+	if val == nil {
+		return nil, nil
+	}
+	if err := f.m.Delete(key); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return nil, fmt.Errorf("deleting map entry: %w", err)
+	}
+	return val, nil
+}
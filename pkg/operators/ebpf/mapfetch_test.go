@@ -0,0 +1,88 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/utils"
+)
+
+func TestMapFetcher(t *testing.T) {
+	utils.RequireRoot(t)
+
+	type testCase struct {
+		keySize   uint32
+		entries   int
+		batchSize int
+		noBatch   bool
+		// noLookupAndDelete uses a lookup and a delete syscall per entry
+		noLookupAndDelete bool
+	}
+	for name, tc := range map[string]testCase{
+		"batch":              {keySize: 4, entries: 10000},
+		"small-batch":        {keySize: 4, entries: 1000, batchSize: 1},
+		"small-keys":         {keySize: 2, entries: 1000, batchSize: 7},
+		"one-by-one":         {keySize: 4, entries: 1000, noBatch: true},
+		"lookup-then-delete": {keySize: 4, entries: 1000, noBatch: true, noLookupAndDelete: true},
+		"empty":              {keySize: 4},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m, err := ebpf.NewMap(&ebpf.MapSpec{
+				Type:       ebpf.Hash,
+				KeySize:    tc.keySize,
+				ValueSize:  8,
+				MaxEntries: 10000,
+			})
+			require.NoError(t, err)
+			t.Cleanup(func() { m.Close() })
+
+			expected := make(map[string]uint64)
+			for k := range tc.entries {
+				key := binary.NativeEndian.AppendUint32(nil, uint32(k))[:tc.keySize]
+				val := binary.NativeEndian.AppendUint64(nil, uint64(k)*3)
+				require.NoError(t, m.Put(key, val))
+				expected[string(key)] = uint64(k) * 3
+			}
+
+			f := newMapFetcher(m)
+			f.noBatch = tc.noBatch
+			f.noLookupAndDelete = tc.noLookupAndDelete
+			if tc.batchSize > 0 {
+				f.batchSize = tc.batchSize
+			}
+
+			got := make(map[string]uint64)
+			err = f.fetch(func(key, val []byte) {
+				_, ok := got[string(key)]
+				assert.False(t, ok, fmt.Sprintf("duplicate key %x", key))
+				got[string(key)] = binary.NativeEndian.Uint64(val)
+			})
+			require.NoError(t, err)
+			assert.Equal(t, expected, got)
+
+			// The entries are deleted
+			next, err := m.NextKeyBytes(nil)
+			require.NoError(t, err)
+			assert.Nil(t, next)
+		})
+	}
+}
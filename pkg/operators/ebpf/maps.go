@@ -15,16 +15,13 @@
 package ebpfoperator
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 	"time"
-	"unsafe"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/btf"
-	"golang.org/x/sys/unix"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
@@ -34,8 +31,11 @@ import (
 const (
 	ParamMapIterInterval = "map-fetch-interval"
 	ParamMapIterCount    = "map-fetch-count"
+	ParamMapIterMode     = "map-fetch-mode"
+	ParamMapIterWindow   = "map-fetch-window"
 
 	mapIterIntervalDefault = "1000ms"
+	mapIterWindowDefault   = 5
 )
 
 type mapIter struct {
@@ -50,6 +50,10 @@ type mapIter struct {
 
 	interval time.Duration
 	count    int
+	mode     string
+	window   int
+
+	aggregator *mapAggregator
 
 	flushOnStop bool
 }
@@ -77,8 +81,9 @@ func (i *ebpfInstance) mapParams() api.Params {
 	}
 	return api.Params{
 		{
-			Key:          ParamMapIterInterval,
-			Description:  "interval in which to iterate over maps",
+			Key: ParamMapIterInterval,
+			Description: "interval in which to iterate over maps. Entries are read and deleted atomically, except on kernels " +
+				"before 5.6 where updates done while reading an entry are lost",
 			DefaultValue: mapIterIntervalDefault,
 			TypeHint:     api.TypeString,
 			Title:        "Map fetch interval",
@@ -90,6 +95,21 @@ func (i *ebpfInstance) mapParams() api.Params {
 			TypeHint:     api.TypeInt,
 			Title:        "Map fetch count",
 		},
+		{
+			Key: ParamMapIterMode,
+			Description: "whether values are reset every interval (reset), accumulated since the start (cumulative) " +
+				"or accumulated over the last map-fetch-window intervals (rolling)",
+			DefaultValue: MapFetchModeReset,
+			TypeHint:     api.TypeString,
+			Title:        "Map fetch mode",
+		},
+		{
+			Key:          ParamMapIterWindow,
+			Description:  "number of intervals accumulated in rolling mode",
+			DefaultValue: fmt.Sprintf("%d", mapIterWindowDefault),
+			TypeHint:     api.TypeInt,
+			Title:        "Map fetch window",
+		},
 	}
 }
 
@@ -140,6 +160,40 @@ func (i *ebpfInstance) evaluateMapParams(paramValues api.ParamValues) error {
 		iter.count = count
 	}
 
+	globalMode := MapFetchModeReset
+	modes, err := apihelpers.GetStringValuesPerDataSource(paramValues[ParamMapIterMode])
+	if err != nil {
+		return fmt.Errorf("evaluating map fetch mode: %w", err)
+	}
+	for dsName, mode := range modes {
+		if dsName == "" {
+			globalMode = mode
+			continue
+		}
+		iter, ok := i.mapIters[dsName]
+		if !ok {
+			return fmt.Errorf("map fetch mode found for non-existing iterator %q", dsName)
+		}
+		iter.mode = mode
+	}
+
+	globalWindow := mapIterWindowDefault
+	windows, err := apihelpers.GetIntValuesPerDataSource(paramValues[ParamMapIterWindow])
+	if err != nil {
+		return fmt.Errorf("evaluating map fetch window: %w", err)
+	}
+	for dsName, window := range windows {
+		if dsName == "" {
+			globalWindow = window
+			continue
+		}
+		iter, ok := i.mapIters[dsName]
+		if !ok {
+			return fmt.Errorf("map fetch window found for non-existing iterator %q", dsName)
+		}
+		iter.window = window
+	}
+
 	for _, iter := range i.mapIters {
 		if iter.interval == 0 {
 			iter.interval = globalDuration
@@ -147,6 +201,16 @@ func (i *ebpfInstance) evaluateMapParams(paramValues api.ParamValues) error {
 		if iter.count == 0 {
 			iter.count = globalCount
 		}
+		if iter.mode == "" {
+			iter.mode = globalMode
+		}
+		if iter.window == 0 {
+			iter.window = globalWindow
+		}
+		iter.aggregator, err = newMapAggregator(iter.mode, iter.window, i.structs[iter.valStructName].Fields)
+		if err != nil {
+			return fmt.Errorf("map iterator %q: %w", iter.name, err)
+		}
 		iter.ds.AddAnnotation(api.FetchCountAnnotation, fmt.Sprintf("%d", iter.count))
 		iter.ds.AddAnnotation(api.FetchIntervalAnnotation, iter.interval.String())
 	}
@@ -163,6 +227,7 @@ func (i *ebpfInstance) runMapIterators() error {
 		if !ok {
 			return fmt.Errorf("map %q not found", iter.mapName)
 		}
+		fetcher := newMapFetcher(iterMap)
 		fetch := func() {
 			p, err := iter.ds.NewPacketArray()
 			if err != nil {
@@ -170,49 +235,32 @@ func (i *ebpfInstance) runMapIterators() error {
 				return
 			}
 
-			var prevKey []byte
-
-			batchSize := 100 // discuss
-
-			keySize := int(iterMap.KeySize())
-			valSize := int(iterMap.ValueSize())
-			for {
-				keys := make([]byte, keySize*batchSize)
-				vals := make([]byte, valSize*batchSize)
-				keysPtr := Pointer{ptr: unsafe.Pointer(&keys[0])}
-				valuesPtr := Pointer{ptr: unsafe.Pointer(&vals[0])}
-
-				// TODO: use cilium lib once raw byte access has been added
-				// TODO: open PR to actually make that happen
-				nk := make([]byte, keySize)
-				attr := MapLookupBatchAttr{
-					MapFd:    uint32(iterMap.FD()),
-					Keys:     keysPtr,
-					Values:   valuesPtr,
-					Count:    uint32(batchSize),
-					OutBatch: Pointer{ptr: unsafe.Pointer(&nk[0])},
-				}
-				if prevKey != nil {
-					attr.InBatch = Pointer{ptr: unsafe.Pointer(&prevKey[0])}
-				}
-
-				_, err := BPF(BPF_MAP_LOOKUP_AND_DELETE_BATCH, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
-				if err != nil && !errors.Is(err, unix.ENOENT) {
-					i.logger.Warnf("error from map iterator: %v", err)
-					break
+			// Entries of this interval, only used when aggregating
+			var entries map[string][]byte
+			if iter.aggregator != nil {
+				entries = make(map[string][]byte)
+			}
+			err = fetcher.fetch(func(key, val []byte) {
+				if entries != nil {
+					entries[string(key)] = val
+					return
 				}
-				n := int(attr.Count)
-
-				prevKey = nk
-				for c := range n {
+				d := p.New()
+				iter.keyAccessor.Set(d, key)
+				iter.valAccessor.Set(d, val)
+				p.Append(d)
+			})
+			if err != nil {
+				i.logger.Warnf("error from map iterator: %v", err)
+			}
+			if entries != nil {
+				aggKeys, aggVals := iter.aggregator.update(entries)
+				for c := range aggKeys {
 					d := p.New()
-					iter.keyAccessor.Set(d, keys[keySize*c:keySize*(c+1)])
-					iter.valAccessor.Set(d, vals[valSize*c:valSize*(c+1)])
+					iter.keyAccessor.Set(d, []byte(aggKeys[c]))
+					iter.valAccessor.Set(d, aggVals[c])
 					p.Append(d)
 				}
-				if errors.Is(err, unix.ENOENT) { // ebpf.ErrKeyNotExist when doing this with cilium/ebpf later on
-					break
-				}
 			}
 			iter.ds.EmitAndRelease(p)
 		}
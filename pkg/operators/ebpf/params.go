@@ -30,6 +30,9 @@ type param struct {
 	fromEbpf bool
 	// Only valid for string parameters
 	strLen int
+	// Only valid for size parameters: type of the variable in the eBPF
+	// program
+	intHint params.TypeHint
 }
 
 func getTypeHint(typ btf.Type) params.TypeHint {
@@ -190,8 +193,46 @@ func (i *ebpfInstance) populateParam(t btf.Type, varName string) error {
 		if s := paramInfo.GetString("description"); s != "" {
 			newParam.Description = s
 		}
+		if s := paramInfo.GetString("typeHint"); s != "" {
+			if s != api.TypeSize {
+				return fmt.Errorf("param %q: unsupported type hint %q", varName, s)
+			}
+			if _, ok := sizeBits[th]; !ok {
+				return fmt.Errorf("param %q: type hint %q requires an unsigned integer, got %q", varName, s, th)
+			}
+			newParam.intHint = th
+			newParam.TypeHint = s
+		}
 	}
 
 	i.params[varName] = newParam
 	return nil
 }
+
+// sizeBits is the number of bits of the unsigned integers that can hold
+// size parameters
+var sizeBits = map[params.TypeHint]int{
+	params.TypeUint8:  8,
+	params.TypeUint16: 16,
+	params.TypeUint32: 32,
+	params.TypeUint64: 64,
+}
+
+// sizeParamValue converts size to the unsigned integer type of the eBPF
+// variable given by th
+func sizeParamValue(th params.TypeHint, size uint64) (any, error) {
+	if bits := sizeBits[th]; bits < 64 && size >= 1<<bits {
+		return nil, fmt.Errorf("size %d too large for %s", size, th)
+	}
+	switch th {
+	case params.TypeUint8:
+		return uint8(size), nil
+	case params.TypeUint16:
+		return uint16(size), nil
+	case params.TypeUint32:
+		return uint32(size), nil
+	case params.TypeUint64:
+		return size, nil
+	}
+	return nil, fmt.Errorf("unsupported type %q for size", th)
+}
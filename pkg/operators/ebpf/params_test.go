@@ -0,0 +1,44 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+func TestSizeParamValue(t *testing.T) {
+	v, err := sizeParamValue(params.TypeUint16, 64*1024-1)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(65535), v)
+
+	_, err = sizeParamValue(params.TypeUint16, 64*1024)
+	require.Error(t, err)
+
+	v, err = sizeParamValue(params.TypeUint32, 4*1024*1024)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(4*1024*1024), v)
+
+	v, err = sizeParamValue(params.TypeUint64, 1<<40)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1<<40), v)
+
+	_, err = sizeParamValue(params.TypeInt32, 1)
+	require.Error(t, err)
+}
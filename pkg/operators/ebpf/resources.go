@@ -0,0 +1,162 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cilium/ebpf"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/bpfstats"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
+// perCPUMapTypes are the map types holding a value for each CPU
+var perCPUMapTypes = map[ebpf.MapType]struct{}{
+	ebpf.PerCPUHash:          {},
+	ebpf.PerCPUArray:         {},
+	ebpf.LRUCPUHash:          {},
+	ebpf.PerCPUCGroupStorage: {},
+}
+
+// perfBufferMemory is the memory of the perf buffers of a tracer, one of size
+// bytes for each CPU
+func perfBufferMemory(size, nCPU int) uint64 {
+	return uint64(size) * uint64(nCPU)
+}
+
+// estimateResources estimates the resources needed by the maps and programs
+// of a collection spec before loading it. The memory of maps is estimated
+// from the size of their entries, the kernel needs a bit more. Maps in skip,
+// like the ones shared with other gadgets, aren't created by the gadget and
+// aren't counted. Perf buffers are only counted for the maps in perfBuffers,
+// the ones events are read from, with perfBufferSize bytes for each CPU.
+func estimateResources(spec *ebpf.CollectionSpec, skip map[string]*ebpf.Map, perfBuffers map[string]*Tracer, perfBufferSize, nCPU int) operators.ResourceUsage {
+	usage := operators.ResourceUsage{
+		Programs: len(spec.Programs),
+	}
+	for name, m := range spec.Maps {
+		if _, ok := skip[name]; ok {
+			continue
+		}
+		usage.Maps++
+
+		switch m.Type {
+		case ebpf.RingBuf:
+			usage.BufferMemory += uint64(m.MaxEntries)
+			continue
+		case ebpf.PerfEventArray:
+			if _, ok := perfBuffers[name]; ok {
+				usage.BufferMemory += perfBufferMemory(perfBufferSize, nCPU)
+			}
+			continue
+		}
+
+		valueSize := uint64(m.ValueSize)
+		if _, ok := perCPUMapTypes[m.Type]; ok {
+			valueSize *= uint64(nCPU)
+		}
+		usage.MapMemory += (uint64(m.KeySize) + valueSize) * uint64(m.MaxEntries)
+	}
+	return usage
+}
+
+// loadedResources returns the resources used by the loaded collection, maps
+// in skip apart, see estimateResources
+func (i *ebpfInstance) loadedResources(skip map[string]*ebpf.Map, nCPU int) (operators.ResourceUsage, error) {
+	usage := operators.ResourceUsage{
+		Programs: len(i.collection.Programs),
+	}
+	for name, m := range i.collection.Maps {
+		if _, ok := skip[name]; ok {
+			continue
+		}
+		usage.Maps++
+
+		switch m.Type() {
+		case ebpf.RingBuf:
+			usage.BufferMemory += uint64(m.MaxEntries())
+			continue
+		case ebpf.PerfEventArray:
+			if t, ok := i.tracers[name]; ok {
+				usage.BufferMemory += perfBufferMemory(t.perfBufferSize, nCPU)
+			}
+			continue
+		}
+
+		mem, err := bpfstats.GetMapMemUsage(m)
+		if err != nil {
+			return usage, fmt.Errorf("getting memory usage of map %q: %w", name, err)
+		}
+		usage.MapMemory += mem
+	}
+	return usage, nil
+}
+
+// checkResourceLimits fails if the collection spec would exceed the resource
+// limits of the gadget context
+func (i *ebpfInstance) checkResourceLimits(gadgetCtx operators.GadgetContext, skip map[string]*ebpf.Map) error {
+	limits, ok := operators.GetResourceLimits(gadgetCtx)
+	if !ok || !limits.Enabled() {
+		return nil
+	}
+	nCPU, err := ebpf.PossibleCPU()
+	if err != nil {
+		return fmt.Errorf("getting number of CPUs: %w", err)
+	}
+	// Auto-tuned perf buffers can grow up to their maximum size
+	perfBufferSize := gadgets.PerfBufferPages * os.Getpagesize()
+	if i.autotuner != nil {
+		perfBufferSize = max(perfBufferSize, i.autotuner.maxSize)
+	}
+	if err := limits.Check(estimateResources(i.collectionSpec, skip, i.tracers, perfBufferSize, nCPU)); err != nil {
+		return fmt.Errorf("gadget exceeds resource limits: %w", err)
+	}
+	return nil
+}
+
+// accountResources reports the resources used by the loaded collection and
+// fails if they exceed the resource limits of the gadget context
+func (i *ebpfInstance) accountResources(gadgetCtx operators.GadgetContext, skip map[string]*ebpf.Map) error {
+	limits, _ := operators.GetResourceLimits(gadgetCtx)
+	reporter, ok := operators.GetResourceReporter(gadgetCtx)
+	if !ok && !limits.Enabled() {
+		return nil
+	}
+
+	nCPU, err := ebpf.PossibleCPU()
+	if err != nil {
+		return fmt.Errorf("getting number of CPUs: %w", err)
+	}
+	usage, err := i.loadedResources(skip, nCPU)
+	if err != nil {
+		if limits.Enabled() {
+			return err
+		}
+		i.logger.Warnf("accounting eBPF resources: %v", err)
+		return nil
+	}
+	i.logger.Debugf("eBPF resources: %+v", usage)
+
+	if ok {
+		reporter.ReportResources(usage)
+	}
+	if err := limits.Check(usage); err != nil {
+		return fmt.Errorf("gadget exceeds resource limits: %w", err)
+	}
+	return nil
+}
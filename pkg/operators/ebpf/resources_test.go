@@ -0,0 +1,53 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
+func TestEstimateResources(t *testing.T) {
+	spec := &ebpf.CollectionSpec{
+		Programs: map[string]*ebpf.ProgramSpec{
+			"prog1": {},
+			"prog2": {},
+		},
+		Maps: map[string]*ebpf.MapSpec{
+			"hash":   {Type: ebpf.Hash, KeySize: 4, ValueSize: 12, MaxEntries: 1024},
+			"percpu": {Type: ebpf.PerCPUArray, KeySize: 4, ValueSize: 8, MaxEntries: 10},
+			"events": {Type: ebpf.RingBuf, MaxEntries: 256 * 1024},
+			"perf":   {Type: ebpf.PerfEventArray},
+			"unread": {Type: ebpf.PerfEventArray},
+			"shared": {Type: ebpf.Hash, KeySize: 4, ValueSize: 4, MaxEntries: 1 << 20},
+		},
+	}
+	skip := map[string]*ebpf.Map{"shared": nil}
+	tracers := map[string]*Tracer{"perf": nil}
+
+	usage := estimateResources(spec, skip, tracers, gadgets.PerfBufferPages*os.Getpagesize(), 4)
+	assert.Equal(t, operators.ResourceUsage{
+		Programs:     2,
+		Maps:         5,
+		MapMemory:    16*1024 + (4+8*4)*10,
+		BufferMemory: 256*1024 + uint64(gadgets.PerfBufferPages*os.Getpagesize()*4),
+	}, usage)
+}
@@ -0,0 +1,358 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+)
+
+// Shared maps allow a gadget (producer) to export one of its maps under a
+// well-known name, so other gadgets (consumers) running on the same node can
+// use it instead of creating their own, e.g. a blocklist filled by one gadget
+// and checked by others. The contract is defined in the gadget metadata:
+//
+//	maps:
+//	  blocked_ips:
+//	    export: blocklist
+//
+// and on the consumer side:
+//
+//	maps:
+//	  blocklist_ips:
+//	    import: blocklist
+//
+// Exported maps are pinned to a directory of sharedMapsPinPath named after
+// the shared name while the producer is running. Besides the map, the
+// directory holds a second pin of it whose name identifies the process of the
+// producer. The directory is prepared under a temporary name and then renamed
+// without replacing any existing one, so only one gadget can export a name,
+// and exports left behind by processes that are gone can be detected.
+// Consumers require the producer to be running and their map definition to be
+// compatible with the exported one.
+
+const (
+	sharedMapPinName     = "map"
+	sharedMapOwnerPrefix = "owner-"
+)
+
+var (
+	sharedMapsPinPath = filepath.Join(gadgets.PinPath, "shared")
+
+	sharedMapNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+type sharedMap struct {
+	mapName    string
+	sharedName string
+}
+
+func (s *sharedMap) pinDir() string {
+	return filepath.Join(sharedMapsPinPath, s.sharedName)
+}
+
+func (s *sharedMap) pinPath() string {
+	return filepath.Join(s.pinDir(), sharedMapPinName)
+}
+
+// sharedMapOwner identifies the process exporting a shared map. The start time
+// of the process prevents a new process reusing the same PID from being taken
+// for the owner.
+type sharedMapOwner struct {
+	pidNs     uint64
+	pid       int
+	startTime uint64
+}
+
+func (o sharedMapOwner) String() string {
+	return fmt.Sprintf("%s%d-%d-%d", sharedMapOwnerPrefix, o.pidNs, o.pid, o.startTime)
+}
+
+func parseSharedMapOwner(name string) (sharedMapOwner, error) {
+	var o sharedMapOwner
+	parts := strings.Split(strings.TrimPrefix(name, sharedMapOwnerPrefix), "-")
+	if !strings.HasPrefix(name, sharedMapOwnerPrefix) || len(parts) != 3 {
+		return o, fmt.Errorf("invalid owner %q", name)
+	}
+	var err error
+	if o.pidNs, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return o, fmt.Errorf("invalid owner %q: %w", name, err)
+	}
+	if o.pid, err = strconv.Atoi(parts[1]); err != nil {
+		return o, fmt.Errorf("invalid owner %q: %w", name, err)
+	}
+	if o.startTime, err = strconv.ParseUint(parts[2], 10, 64); err != nil {
+		return o, fmt.Errorf("invalid owner %q: %w", name, err)
+	}
+	return o, nil
+}
+
+func currentPidNs() (uint64, error) {
+	var st unix.Stat_t
+	if err := unix.Stat("/proc/self/ns/pid", &st); err != nil {
+		return 0, fmt.Errorf("getting pid namespace: %w", err)
+	}
+	return st.Ino, nil
+}
+
+// processStartTime returns the start time of the process, in clock ticks
+// since boot, as reported in /proc/<pid>/stat
+func processStartTime(pid int) (uint64, error) {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// The command can contain spaces and parentheses, skip it
+	idx := bytes.LastIndexByte(stat, ')')
+	if idx < 0 {
+		return 0, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	// Fields after the command start with the state (3rd field), the start
+	// time is the 22nd one
+	fields := strings.Fields(string(stat[idx+1:]))
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+func currentSharedMapOwner() (sharedMapOwner, error) {
+	pidNs, err := currentPidNs()
+	if err != nil {
+		return sharedMapOwner{}, err
+	}
+	pid := os.Getpid()
+	startTime, err := processStartTime(pid)
+	if err != nil {
+		return sharedMapOwner{}, fmt.Errorf("getting start time of process: %w", err)
+	}
+	return sharedMapOwner{pidNs: pidNs, pid: pid, startTime: startTime}, nil
+}
+
+// alive returns whether the owner is still running. Owners from other PID
+// namespaces can't be checked and are considered alive.
+func (o sharedMapOwner) alive() bool {
+	pidNs, err := currentPidNs()
+	if err != nil || pidNs != o.pidNs {
+		return true
+	}
+	startTime, err := processStartTime(o.pid)
+	return err == nil && startTime == o.startTime
+}
+
+// readSharedMapOwner returns the owner of the export stored in dir
+func readSharedMapOwner(dir string) (sharedMapOwner, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return sharedMapOwner{}, err
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), sharedMapOwnerPrefix) {
+			return parseSharedMapOwner(entry.Name())
+		}
+	}
+	return sharedMapOwner{}, fmt.Errorf("no owner found in %s", dir)
+}
+
+// getSharedMaps returns the maps the gadget exports and imports according to
+// its metadata
+func (i *ebpfInstance) getSharedMaps() (exports []*sharedMap, imports []*sharedMap, err error) {
+	mapsCfg := i.config.GetStringMap("maps")
+	for _, mapName := range slices.Sorted(maps.Keys(mapsCfg)) {
+		if _, ok := i.collectionSpec.Maps[mapName]; !ok {
+			return nil, nil, fmt.Errorf("map %q defined in metadata not found in eBPF object", mapName)
+		}
+
+		export := i.config.GetString("maps." + mapName + ".export")
+		imp := i.config.GetString("maps." + mapName + ".import")
+		if export != "" && imp != "" {
+			return nil, nil, fmt.Errorf("map %q can't be exported and imported at the same time", mapName)
+		}
+		if export != "" {
+			if !sharedMapNameRegex.MatchString(export) {
+				return nil, nil, fmt.Errorf("invalid shared name %q for map %q", export, mapName)
+			}
+			exports = append(exports, &sharedMap{mapName: mapName, sharedName: export})
+		}
+		if imp != "" {
+			if !sharedMapNameRegex.MatchString(imp) {
+				return nil, nil, fmt.Errorf("invalid shared name %q for map %q", imp, mapName)
+			}
+			imports = append(imports, &sharedMap{mapName: mapName, sharedName: imp})
+		}
+	}
+	return exports, imports, nil
+}
+
+// importSharedMaps loads the maps exported by other gadgets and adds them as
+// replacements for the imported maps
+func (i *ebpfInstance) importSharedMaps(mapReplacements map[string]*ebpf.Map) error {
+	for _, s := range i.sharedMapImports {
+		owner, err := readSharedMapOwner(s.pinDir())
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("reading owner of shared map %q: %w", s.sharedName, err)
+		}
+		if errors.Is(err, os.ErrNotExist) || !owner.alive() {
+			return fmt.Errorf("shared map %q is not exported by any running gadget", s.sharedName)
+		}
+
+		m, err := ebpf.LoadPinnedMap(s.pinPath(), nil)
+		if err != nil {
+			return fmt.Errorf("loading shared map %q: %w", s.sharedName, err)
+		}
+
+		if err := i.collectionSpec.Maps[s.mapName].Compatible(m); err != nil {
+			m.Close()
+			return fmt.Errorf("map %q is incompatible with shared map %q: %w", s.mapName, s.sharedName, err)
+		}
+
+		i.logger.Debugf("using shared map %q for map %q", s.sharedName, s.mapName)
+		mapReplacements[s.mapName] = m
+		i.importedMaps = append(i.importedMaps, m)
+	}
+	return nil
+}
+
+// exportSharedMaps pins the exported maps so other gadgets can use them
+func (i *ebpfInstance) exportSharedMaps() error {
+	if len(i.sharedMapExports) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(sharedMapsPinPath, 0o700); err != nil {
+		return fmt.Errorf("creating directory for shared maps: %w", err)
+	}
+
+	owner, err := currentSharedMapOwner()
+	if err != nil {
+		return fmt.Errorf("identifying shared maps owner: %w", err)
+	}
+
+	for _, s := range i.sharedMapExports {
+		err := exportSharedMap(i.collection.Maps[s.mapName], s, owner)
+		if errors.Is(err, os.ErrExist) {
+			err = i.removeStaleExport(s)
+			if err == nil {
+				err = exportSharedMap(i.collection.Maps[s.mapName], s, owner)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("exporting map %q as %q: %w", s.mapName, s.sharedName, err)
+		}
+		i.logger.Debugf("exported map %q as %q", s.mapName, s.sharedName)
+		i.exportedMaps = append(i.exportedMaps, s)
+	}
+	return nil
+}
+
+// exportSharedMap pins the map and its owner in a temporary directory and
+// moves it to the directory of the shared name, unless it exists already, in
+// which case it returns an error wrapping os.ErrExist.
+func exportSharedMap(m *ebpf.Map, s *sharedMap, owner sharedMapOwner) error {
+	tmpDir, err := os.MkdirTemp(sharedMapsPinPath, ".tmp-"+s.sharedName+"-")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	// No-op once the directory was moved
+	defer os.RemoveAll(tmpDir)
+
+	// Pin clones, so the map itself doesn't keep track of the temporary paths
+	for _, name := range []string{sharedMapPinName, owner.String()} {
+		clone, err := m.Clone()
+		if err != nil {
+			return fmt.Errorf("cloning map: %w", err)
+		}
+		err = clone.Pin(filepath.Join(tmpDir, name))
+		clone.Close()
+		if err != nil {
+			return fmt.Errorf("pinning map: %w", err)
+		}
+	}
+
+	err = unix.Renameat2(unix.AT_FDCWD, tmpDir, unix.AT_FDCWD, s.pinDir(), unix.RENAME_NOREPLACE)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: tmpDir, New: s.pinDir(), Err: err}
+	}
+	return nil
+}
+
+// removeStaleExport removes the export of the shared name if its owner is gone.
+// The export is first moved away, so an export made by another gadget in the
+// meantime is put back instead of being removed.
+func (i *ebpfInstance) removeStaleExport(s *sharedMap) error {
+	owner, err := readSharedMapOwner(s.pinDir())
+	if err != nil {
+		return fmt.Errorf("reading owner of existing export: %w", err)
+	}
+	if owner.alive() {
+		return fmt.Errorf("shared map %q is already exported by another gadget (pinned at %s)", s.sharedName, s.pinDir())
+	}
+
+	trashDir, err := os.MkdirTemp(sharedMapsPinPath, ".stale-"+s.sharedName+"-")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(trashDir)
+
+	staleDir := filepath.Join(trashDir, s.sharedName)
+	err = unix.Renameat2(unix.AT_FDCWD, s.pinDir(), unix.AT_FDCWD, staleDir, unix.RENAME_NOREPLACE)
+	if errors.Is(err, unix.ENOENT) {
+		// Removed by another gadget
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("moving stale export: %w", err)
+	}
+
+	if moved, err := readSharedMapOwner(staleDir); err != nil || moved != owner {
+		err := unix.Renameat2(unix.AT_FDCWD, staleDir, unix.AT_FDCWD, s.pinDir(), unix.RENAME_NOREPLACE)
+		if err != nil {
+			return fmt.Errorf("restoring export of shared map %q replaced concurrently: %w", s.sharedName, err)
+		}
+		return fmt.Errorf("shared map %q is already exported by another gadget (pinned at %s)", s.sharedName, s.pinDir())
+	}
+
+	i.logger.Warnf("removed export of shared map %q left by %s, which isn't running anymore", s.sharedName, owner)
+	return nil
+}
+
+// closeSharedMaps unpins the exported maps and releases the imported ones.
+// Consumers still running keep their reference to the map.
+func (i *ebpfInstance) closeSharedMaps() {
+	for _, s := range i.exportedMaps {
+		if err := os.RemoveAll(s.pinDir()); err != nil {
+			i.logger.Warnf("unpinning shared map %q: %v", s.sharedName, err)
+		}
+	}
+	i.exportedMaps = nil
+
+	for _, m := range i.importedMaps {
+		m.Close()
+	}
+	i.importedMaps = nil
+}
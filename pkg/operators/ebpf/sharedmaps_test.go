@@ -0,0 +1,129 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSharedMaps(t *testing.T) {
+	type testCase struct {
+		metadata        string
+		expectedExports []*sharedMap
+		expectedImports []*sharedMap
+		expectedErr     bool
+	}
+
+	tests := map[string]testCase{
+		"none": {
+			metadata: "name: foo\n",
+		},
+		"export_and_import": {
+			metadata: `
+maps:
+  blocked_ips:
+    export: blocklist
+  dns_cache:
+    import: dns-cache.v1
+`,
+			expectedExports: []*sharedMap{{mapName: "blocked_ips", sharedName: "blocklist"}},
+			expectedImports: []*sharedMap{{mapName: "dns_cache", sharedName: "dns-cache.v1"}},
+		},
+		"unknown_map": {
+			metadata: `
+maps:
+  unknown:
+    export: blocklist
+`,
+			expectedErr: true,
+		},
+		"export_and_import_same_map": {
+			metadata: `
+maps:
+  blocked_ips:
+    export: blocklist
+    import: blocklist
+`,
+			expectedErr: true,
+		},
+		"invalid_name": {
+			metadata: `
+maps:
+  blocked_ips:
+    export: ../blocklist
+`,
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			v := viper.New()
+			v.SetConfigType("yaml")
+			require.NoError(t, v.ReadConfig(bytes.NewBufferString(test.metadata)))
+
+			i := &ebpfInstance{
+				config: v,
+				collectionSpec: &ebpf.CollectionSpec{
+					Maps: map[string]*ebpf.MapSpec{
+						"blocked_ips": {Name: "blocked_ips"},
+						"dns_cache":   {Name: "dns_cache"},
+					},
+				},
+			}
+
+			exports, imports, err := i.getSharedMaps()
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expectedExports, exports)
+			require.Equal(t, test.expectedImports, imports)
+		})
+	}
+}
+
+func TestSharedMapOwner(t *testing.T) {
+	owner, err := currentSharedMapOwner()
+	require.NoError(t, err)
+	require.True(t, owner.alive())
+
+	parsed, err := parseSharedMapOwner(owner.String())
+	require.NoError(t, err)
+	require.Equal(t, owner, parsed)
+
+	// Same PID, but another process
+	restarted := owner
+	restarted.startTime++
+	require.False(t, restarted.alive())
+
+	// Owners from other PID namespaces can't be checked
+	otherNs := restarted
+	otherNs.pidNs++
+	require.True(t, otherNs.alive())
+
+	for _, name := range []string{"map", "owner-1-2", "owner-a-1-2", "owner-1-2-3-4"} {
+		_, err := parseSharedMapOwner(name)
+		require.Error(t, err, name)
+	}
+}
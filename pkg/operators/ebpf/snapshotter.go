@@ -67,9 +67,12 @@ func (i *ebpfInstance) parseSnapshotterPrograms(programs []string) (map[string]s
 			return nil, fmt.Errorf("program %q not found in eBPF object", program)
 		}
 
-		if p.Type != ebpf.Tracing || !strings.HasPrefix(p.SectionName, "iter/") {
-			return nil, fmt.Errorf("invalid program %q: expecting type %q and section name prefix \"iter/\", got %q and %q",
-				program, ebpf.Tracing, p.Type, p.SectionName)
+		if p.Type != ebpf.Tracing || !strings.HasPrefix(p.SectionName, iterPrefix) {
+			return nil, fmt.Errorf("invalid program %q: expecting type %q and section name prefix %q, got %q and %q",
+				program, ebpf.Tracing, iterPrefix, p.Type, p.SectionName)
+		}
+		if !isIteratorKindSupported(p.AttachTo) {
+			return nil, fmt.Errorf("invalid program %q: iterator kind %q is not supported", program, p.AttachTo)
 		}
 
 		iterators[program] = struct{}{}
@@ -212,28 +215,37 @@ func (i *ebpfInstance) runSnapshotters() error {
 	return nil
 }
 
+// iteratorKinds are the iterator kinds supported by Inspektor Gadget. The value
+// is true for the ones that need to be run per network namespace.
+//
+// Linux 6.9 supports the following iterator kinds:
+//
+// $ git grep -w '^DEFINE_BPF_ITER_FUNC'|sed 's/^.*(\([a-z0-9_]*\),.*$/\1/'
+// bpf_link bpf_map bpf_map_elem bpf_prog bpf_sk_storage_map cgroup
+// ipv6_route ksym netlink sockmap task task_file task_vma tcp udp unix
+//
+// The ones needing a map or a cgroup to iterate over aren't supported.
+var iteratorKinds = map[string]bool{
+	"bpf_map":   false,
+	"bpf_prog":  false,
+	"ksym":      false,
+	"task":      false,
+	"task_file": false,
+	"task_vma":  false,
+	"tcp":       true,
+	"udp":       true,
+	"unix":      true,
+}
+
 // isIteratorKindPerNetNs returns true if the iterator kind needs to be run per
 // network namespace.
 func isIteratorKindPerNetNs(kind string) bool {
-	if kind == "tcp" || kind == "udp" {
-		return true
-	}
-	return false
+	return iteratorKinds[kind]
 }
 
 // isIteratorKindSupported returns true if the iterator kind is supported by
 // Inspektor Gadget.
 func isIteratorKindSupported(kind string) bool {
-	// Linux 6.9 supports the following iterator kinds:
-	//
-	// $ git grep -w '^DEFINE_BPF_ITER_FUNC'|sed 's/^.*(\([a-z0-9_]*\),.*$/\1/'
-	// bpf_link bpf_map bpf_map_elem bpf_prog bpf_sk_storage_map cgroup
-	// ipv6_route ksym netlink sockmap task task_file task_vma tcp udp unix
-	//
-	// But at the moment, only a subset is supported by Inspektor Gadget.
-	switch kind {
-	case "task", "task_file", "ksym", "tcp", "udp":
-		return true
-	}
-	return false
+	_, ok := iteratorKinds[kind]
+	return ok
 }
@@ -0,0 +1,62 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+func TestParseSnapshotterPrograms(t *testing.T) {
+	i := &ebpfInstance{
+		logger: logger.DefaultLogger(),
+		collectionSpec: &ebpf.CollectionSpec{
+			Programs: map[string]*ebpf.ProgramSpec{
+				"ig_snap_tcp":  {Type: ebpf.Tracing, SectionName: "iter/tcp", AttachTo: "tcp"},
+				"ig_snap_unix": {Type: ebpf.Tracing, SectionName: "iter/unix", AttachTo: "unix"},
+				"ig_snap_vma":  {Type: ebpf.Tracing, SectionName: "iter/task_vma", AttachTo: "task_vma"},
+				"ig_snap_elem": {Type: ebpf.Tracing, SectionName: "iter/bpf_map_elem", AttachTo: "bpf_map_elem"},
+				"ig_fentry":    {Type: ebpf.Tracing, SectionName: "fentry/do_exit", AttachTo: "do_exit"},
+			},
+		},
+	}
+
+	iterators, err := i.parseSnapshotterPrograms([]string{"ig_snap_tcp", "ig_snap_unix", "ig_snap_vma"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]struct{}{
+		"ig_snap_tcp":  {},
+		"ig_snap_unix": {},
+		"ig_snap_vma":  {},
+	}, iterators)
+
+	for _, programs := range [][]string{
+		{""},
+		{"missing"},
+		{"ig_fentry"},
+		// Needs a map to iterate over
+		{"ig_snap_elem"},
+	} {
+		_, err := i.parseSnapshotterPrograms(programs)
+		assert.Error(t, err, programs)
+	}
+
+	assert.True(t, isIteratorKindPerNetNs("unix"))
+	assert.False(t, isIteratorKindPerNetNs("task_vma"))
+}
@@ -42,10 +42,28 @@ const (
 )
 
 func (o *ebpfOperator) GlobalParams() api.Params {
-	return nil
+	return api.Params{
+		{
+			Key:         ParamEnforcementAllowedGadgets,
+			Title:       "Enforcement allowed gadgets",
+			Description: "List of gadgets allowed to block actions using LSM programs, entries ending with '*' match by prefix. By default, enforcement is disabled for all gadgets",
+			TypeHint:    api.TypeStringSlice,
+		},
+		{
+			Key:         ParamBTFHubArchive,
+			Title:       "BTFHub archive",
+			Description: "Directory with a copy of the BTFHub archive, used for kernels without BTF when gadgets don't ship BTF for them. BTF files downloaded from btfhub-url are stored there",
+		},
+		{
+			Key:         ParamBTFHubURL,
+			Title:       "BTFHub URL",
+			Description: "URL of the BTFHub archive to download BTF files from for kernels without BTF, like https://github.com/aquasecurity/btfhub-archive/raw/main. Downloads are disabled if empty",
+		},
+	}
 }
 
 func (o *ebpfOperator) Init(params *params.Params) error {
+	o.globalParams = params
 	return nil
 }
 
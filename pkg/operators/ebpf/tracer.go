@@ -20,6 +20,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/btf"
@@ -45,6 +46,16 @@ type Tracer struct {
 	ringbufReader *ringbuf.Reader
 	perfReader    *perf.Reader
 	slowBuf       []byte
+
+	// Used to auto-tune the buffers. nextPerfReader is the reader replacing
+	// perfReader once all its events have been read.
+	bufMap         *ebpf.Map
+	perfBufferSize int
+	readerMu       sync.Mutex
+	nextPerfReader *perf.Reader
+	closed         bool
+	received       atomic.Uint64
+	lost           atomic.Uint64
 }
 
 func validateTracerMap(traceMap *ebpf.MapSpec) error {
@@ -103,9 +114,10 @@ func (i *ebpfInstance) populateTracer(t btf.Type, varName string) error {
 
 	i.logger.Debugf("adding tracer %q", name)
 	i.tracers[name] = &Tracer{
-		mapName:    mapName,
-		structName: btfStruct.Name,
-		eventSize:  btfStruct.Size,
+		mapName:        mapName,
+		structName:     btfStruct.Name,
+		eventSize:      btfStruct.Size,
+		perfBufferSize: gadgets.PerfBufferPages * os.Getpagesize(),
 	}
 
 	err := i.populateStructDirect(btfStruct)
@@ -130,6 +142,9 @@ func (t *Tracer) receiveEvents(gadgetCtx operators.GadgetContext, wg *sync.WaitG
 	case ebpf.PerfEventArray:
 		readCb = func() ([]byte, uint64, error) {
 			rec, err := t.perfReader.Read()
+			if errors.Is(err, perf.ErrFlushed) && t.swapPerfReader() {
+				return nil, 0, nil
+			}
 			return rec.RawSample, rec.LostSamples, err
 		}
 	default:
@@ -150,8 +165,14 @@ func (t *Tracer) receiveEvents(gadgetCtx operators.GadgetContext, wg *sync.WaitG
 		if lost > 0 {
 			gadgetCtx.Logger().Warnf("reading event: lost %d samples", lost)
 			t.ds.ReportLostData(lost)
+			t.lost.Add(lost)
 			continue
 		}
+		if sample == nil {
+			// The perf reader was replaced
+			continue
+		}
+		t.received.Add(1)
 
 		if err := t.processEvent(gadgetCtx, sample); err != nil {
 			gadgetCtx.Logger().Warnf("error processing event: %v", err)
@@ -213,13 +234,63 @@ func (t *Tracer) processEvent(gadgetCtx operators.GadgetContext, fullSample []by
 	return nil
 }
 
+// resizePerfBuffer replaces the perf reader by one whose per-CPU buffers have
+// the given size. The kernel writes to the new buffers right away, the events
+// still in the old ones are read before switching to them.
+func (t *Tracer) resizePerfBuffer(size int) error {
+	t.readerMu.Lock()
+	defer t.readerMu.Unlock()
+
+	if t.closed {
+		return os.ErrClosed
+	}
+	if t.nextPerfReader != nil {
+		return errors.New("resize already in progress")
+	}
+	r, err := perf.NewReader(t.bufMap, size)
+	if err != nil {
+		return err
+	}
+	// Makes Read() return perf.ErrFlushed once the old buffers are empty
+	if err := t.perfReader.Flush(); err != nil {
+		r.Close()
+		return err
+	}
+	t.nextPerfReader = r
+	t.perfBufferSize = size
+	return nil
+}
+
+// swapPerfReader switches to the reader created by resizePerfBuffer(). It
+// returns false if there is none.
+func (t *Tracer) swapPerfReader() bool {
+	t.readerMu.Lock()
+	defer t.readerMu.Unlock()
+
+	if t.nextPerfReader == nil {
+		return false
+	}
+	t.perfReader.Close()
+	t.perfReader = t.nextPerfReader
+	t.nextPerfReader = nil
+	return true
+}
+
 func (t *Tracer) close() {
+	t.readerMu.Lock()
+	defer t.readerMu.Unlock()
+
+	t.closed = true
 	if t.ringbufReader != nil {
 		t.ringbufReader.Close()
 	}
 	if t.perfReader != nil {
 		t.perfReader.Close()
 	}
+	if t.nextPerfReader != nil {
+		t.nextPerfReader.Close()
+		t.nextPerfReader = nil
+	}
 }
 
 func (i *ebpfInstance) runTracer(gadgetCtx operators.GadgetContext, tracer *Tracer) error {
@@ -233,6 +304,7 @@ func (i *ebpfInstance) runTracer(gadgetCtx operators.GadgetContext, tracer *Trac
 	}
 
 	tracer.mapType = m.Type()
+	tracer.bufMap = m
 
 	var err error
 	switch m.Type() {
@@ -241,7 +313,7 @@ func (i *ebpfInstance) runTracer(gadgetCtx operators.GadgetContext, tracer *Trac
 		tracer.ringbufReader, err = ringbuf.NewReader(m)
 	case ebpf.PerfEventArray:
 		i.logger.Debugf("creating perf reader for map %q", tracer.mapName)
-		tracer.perfReader, err = perf.NewReader(m, gadgets.PerfBufferPages*os.Getpagesize())
+		tracer.perfReader, err = perf.NewReader(m, tracer.perfBufferSize)
 	default:
 		return fmt.Errorf("unknown type for tracer map %q", tracer.mapName)
 	}
@@ -250,7 +322,8 @@ func (i *ebpfInstance) runTracer(gadgetCtx operators.GadgetContext, tracer *Trac
 	}
 
 	// TODO: freezing ringbuf doesn't work: "device or resource busy"
-	if m.Type() == ebpf.PerfEventArray {
+	// Perf buffers are replaced when they are auto-tuned
+	if m.Type() == ebpf.PerfEventArray && i.autotuner == nil {
 		if err := gadgets.FreezeMaps(m); err != nil {
 			return err
 		}
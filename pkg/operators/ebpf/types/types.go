@@ -42,6 +42,7 @@ const (
 	ParentTypeName      = "gadget_parent"
 	FileModeTypeName    = "gadget_file_mode"
 	FileFlagsTypeName   = "gadget_file_flags"
+	VerdictTypeName     = "gadget_verdict"
 
 	// Metrics
 	CounterU32TypeName       = "gadget_counter__u32"
@@ -59,6 +60,8 @@ const (
 	UserStackMapName      = "ig_ustack"
 	BuildIdMapName        = "ig_build_id"
 	UserPerfMaxStackDepth = 127
+	// Keep in sync with `include/gadget/usdt.h`
+	USDTSpecsMapName = "ig_usdt_specs"
 )
 
 // L3Endpoint is the Golang representation of struct gadget_l3endpoint_t
@@ -0,0 +1,206 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/features"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/nodeinfo"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
+func passed(name, format string, a ...any) *api.ValidationCheck {
+	return &api.ValidationCheck{Name: name, Result: api.ValidationResult_ValidationPassed, Message: fmt.Sprintf(format, a...)}
+}
+
+func warning(name, format string, a ...any) *api.ValidationCheck {
+	return &api.ValidationCheck{Name: name, Result: api.ValidationResult_ValidationWarning, Message: fmt.Sprintf(format, a...)}
+}
+
+func failed(name, format string, a ...any) *api.ValidationCheck {
+	return &api.ValidationCheck{Name: name, Result: api.ValidationResult_ValidationFailed, Message: fmt.Sprintf(format, a...)}
+}
+
+// featureCheck turns the result of a feature probe into a check; errors other
+// than ErrNotSupported mean that the probe itself failed, e.g. because of
+// missing privileges
+func featureCheck(name string, err error, what string) *api.ValidationCheck {
+	switch {
+	case err == nil:
+		return passed(name, "%s is supported", what)
+	case errors.Is(err, ebpf.ErrNotSupported):
+		return failed(name, "%s is not supported by the kernel", what)
+	default:
+		return warning(name, "checking %s: %v", what, err)
+	}
+}
+
+// programTarget returns what the program would be attached to, or an empty
+// string if it's disabled
+func (i *ebpfInstance) programTarget(p *ebpf.ProgramSpec) string {
+	attachTo := p.AttachTo
+	if attachToCfg := i.config.GetString("programs." + p.Name + ".attach_to"); attachToCfg != "" {
+		attachTo = attachToCfg
+	}
+	if attachTo == disabledProgram {
+		return ""
+	}
+	return attachTo
+}
+
+// programHelpers returns the helper functions called by the program
+func programHelpers(p *ebpf.ProgramSpec) []asm.BuiltinFunc {
+	var helpers []asm.BuiltinFunc
+	for _, ins := range p.Instructions {
+		if !ins.IsBuiltinCall() {
+			continue
+		}
+		helper := asm.BuiltinFunc(ins.Constant)
+		if !slices.Contains(helpers, helper) {
+			helpers = append(helpers, helper)
+		}
+	}
+	return helpers
+}
+
+// Validate checks the programs and maps of the gadget against the running
+// kernel without loading them
+func (i *ebpfInstance) Validate(gadgetCtx operators.GadgetContext) []*api.ValidationCheck {
+	var checks []*api.ValidationCheck
+
+	programNames := slices.Sorted(maps.Keys(i.collectionSpec.Programs))
+
+	// Collect the attach targets that can be checked in advance
+	req := &api.NodeInfoRequest{}
+	for _, name := range programNames {
+		p := i.collectionSpec.Programs[name]
+		target := i.programTarget(p)
+		if target == "" {
+			continue
+		}
+		switch {
+		case p.Type == ebpf.TracePoint:
+			req.Tracepoints = append(req.Tracepoints, target)
+		case strings.HasPrefix(p.SectionName, kprobePrefix), strings.HasPrefix(p.SectionName, kretprobePrefix):
+			req.Kprobes = append(req.Kprobes, target)
+		}
+	}
+	info := nodeinfo.Collect(req)
+
+	checks = append(checks, passed("kernel", "Linux %s", info.KernelRelease))
+
+	switch {
+	case info.BtfAvailable:
+		checks = append(checks, passed("btf", "the kernel exposes BTF"))
+	default:
+		archiveDir, url := i.bpfOperator.btfHubConfig()
+		if _, ok := gadgetCtx.GetVar(kernelTypesVar); ok {
+			checks = append(checks, passed("btf", "the kernel doesn't expose BTF, using the BTF shipped with the gadget"))
+		} else if archiveDir != "" || url != "" {
+			checks = append(checks, passed("btf", "the kernel doesn't expose BTF, looking it up in BTFHub"))
+		} else {
+			checks = append(checks, failed("btf", "the kernel doesn't expose BTF and the gadget doesn't ship BTF for it"))
+		}
+	}
+
+	// The attach targets are unknown if tracefs isn't available
+	tracefsAvailable := info.TracepointCount > 0 || info.KprobeFunctionCount > 0
+
+	progTypeErrs := map[ebpf.ProgramType]error{}
+	for _, name := range programNames {
+		p := i.collectionSpec.Programs[name]
+		checkName := "program/" + name
+
+		target := i.programTarget(p)
+		if target == "" {
+			checks = append(checks, passed(checkName, "%s is disabled", p.SectionName))
+			continue
+		}
+
+		err, ok := progTypeErrs[p.Type]
+		if !ok {
+			err = features.HaveProgramType(p.Type)
+			progTypeErrs[p.Type] = err
+		}
+		if err != nil {
+			checks = append(checks, featureCheck(checkName, err, fmt.Sprintf("program type %s", p.Type)))
+			continue
+		}
+
+		switch {
+		case p.Type == ebpf.LSM && !info.LsmBPFAvailable:
+			checks = append(checks, failed(checkName, "%s needs the bpf LSM, which isn't active", p.SectionName))
+		case p.Type == ebpf.TracePoint && !info.Tracepoints[target]:
+			if tracefsAvailable {
+				checks = append(checks, failed(checkName, "tracepoint %s doesn't exist", target))
+			} else {
+				checks = append(checks, warning(checkName, "tracepoint %s can't be checked without tracefs", target))
+			}
+		case (strings.HasPrefix(p.SectionName, kprobePrefix) || strings.HasPrefix(p.SectionName, kretprobePrefix)) && !info.Kprobes[target]:
+			if tracefsAvailable {
+				checks = append(checks, failed(checkName, "kernel function %s can't be probed", target))
+			} else {
+				checks = append(checks, warning(checkName, "kernel function %s can't be checked without tracefs", target))
+			}
+		case strings.HasPrefix(p.SectionName, kprobeMultiPrefix), strings.HasPrefix(p.SectionName, kretprobeMultiPrefix):
+			mode := i.programAttachMode(p)
+			switch {
+			case mode != attachModeSingle && haveKprobeMulti():
+				checks = append(checks, passed(checkName, "%s would be attached to %s with a kprobe_multi link", p.Type, target))
+			case mode == attachModeMulti:
+				checks = append(checks, failed(checkName, "%s needs kprobe_multi links, which aren't supported", p.SectionName))
+			default:
+				checks = append(checks, passed(checkName, "%s would be attached to %s with one kprobe per function", p.Type, target))
+			}
+		default:
+			checks = append(checks, passed(checkName, "%s would be attached to %s", p.Type, target))
+		}
+
+		// Calls to missing helpers are fine as long as the gadget guards them,
+		// e.g. with bpf_core_enum_value_exists(), so only warn about them
+		var missing []string
+		for _, helper := range programHelpers(p) {
+			if err := features.HaveProgramHelper(p.Type, helper); errors.Is(err, ebpf.ErrNotSupported) {
+				missing = append(missing, helper.String())
+			}
+		}
+		if len(missing) > 0 {
+			checks = append(checks, warning(checkName+"/helpers",
+				"helpers not available for %s programs: %s", p.Type, strings.Join(missing, ", ")))
+		}
+	}
+
+	mapTypes := map[ebpf.MapType]struct{}{}
+	for _, m := range i.collectionSpec.Maps {
+		mapTypes[m.Type] = struct{}{}
+	}
+	for _, mt := range slices.Sorted(maps.Keys(mapTypes)) {
+		if mt == ebpf.UnspecifiedMap {
+			continue
+		}
+		checks = append(checks, featureCheck("map-type/"+mt.String(), features.HaveMapType(mt), fmt.Sprintf("map type %s", mt)))
+	}
+
+	return checks
+}
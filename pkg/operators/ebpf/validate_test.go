@@ -0,0 +1,48 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ebpfoperator
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgramHelpers(t *testing.T) {
+	p := &ebpf.ProgramSpec{
+		Instructions: asm.Instructions{
+			asm.FnKtimeGetBootNs.Call(),
+			asm.FnGetCurrentPidTgid.Call(),
+			asm.FnKtimeGetBootNs.Call(),
+			asm.Mov.Imm(asm.R0, 0),
+			asm.Return(),
+		},
+	}
+	assert.Equal(t, []asm.BuiltinFunc{asm.FnKtimeGetBootNs, asm.FnGetCurrentPidTgid}, programHelpers(p))
+}
+
+func TestProgramTarget(t *testing.T) {
+	config := viper.New()
+	config.Set("programs.overridden.attach_to", "do_exit")
+	config.Set("programs.disabled.attach_to", disabledProgram)
+	i := &ebpfInstance{config: config}
+
+	assert.Equal(t, "do_fork", i.programTarget(&ebpf.ProgramSpec{Name: "default", AttachTo: "do_fork"}))
+	assert.Equal(t, "do_exit", i.programTarget(&ebpf.ProgramSpec{Name: "overridden", AttachTo: "do_fork"}))
+	assert.Equal(t, "", i.programTarget(&ebpf.ProgramSpec{Name: "disabled", AttachTo: "do_fork"}))
+}
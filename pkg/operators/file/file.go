@@ -0,0 +1,324 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package file is a data operator that writes the events of data sources to
+// local files, rotating them by size or time, so that nodes without network
+// egress can record events for later collection.
+package file
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	igjson "github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/formatters/json"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/common"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/routing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	name     = "file"
+	Priority = 9999
+
+	ParamFileExporter = "file-exporter"
+
+	DefaultPath = "/var/log/inspektor-gadget/{gadget}/{datasource}.jsonl"
+)
+
+type exporterConfig struct {
+	Path           string        `json:"path" yaml:"path"`
+	MaxSize        string        `json:"maxSize" yaml:"maxSize"`
+	RotateInterval time.Duration `json:"rotateInterval" yaml:"rotateInterval"`
+	MaxFiles       int           `json:"maxFiles" yaml:"maxFiles"`
+	Compress       bool          `json:"compress" yaml:"compress"`
+}
+
+type exporter struct {
+	path string
+	rotateConfig
+}
+
+func newExporter(exporterName string, cfg *exporterConfig) (*exporter, error) {
+	e := &exporter{
+		path: cfg.Path,
+		rotateConfig: rotateConfig{
+			interval: cfg.RotateInterval,
+			maxFiles: cfg.MaxFiles,
+			compress: cfg.Compress,
+		},
+	}
+	if e.path == "" {
+		e.path = DefaultPath
+	}
+	if !filepath.IsAbs(e.path) {
+		return nil, fmt.Errorf("exporter %q: path %q must be absolute", exporterName, e.path)
+	}
+	if cfg.MaxSize != "" {
+		size, err := params.ParseSize(cfg.MaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("exporter %q: maxSize: %w", exporterName, err)
+		}
+		if size > math.MaxInt64 {
+			return nil, fmt.Errorf("exporter %q: maxSize %q too large", exporterName, cfg.MaxSize)
+		}
+		e.maxSize = int64(size)
+	}
+	if e.interval < 0 || e.maxFiles < 0 {
+		return nil, fmt.Errorf("exporter %q: rotateInterval and maxFiles can't be negative", exporterName)
+	}
+	return e, nil
+}
+
+// pathValue makes s usable as part of a path
+var pathValue = strings.NewReplacer("/", "_", "..", "_")
+
+// expandPath fills in the placeholders of a path template
+func expandPath(template, gadget, dataSource, node, instance string) string {
+	return filepath.Clean(strings.NewReplacer(
+		"{gadget}", pathValue.Replace(gadget),
+		"{datasource}", pathValue.Replace(dataSource),
+		"{node}", pathValue.Replace(node),
+		"{instance}", pathValue.Replace(instance),
+	).Replace(template))
+}
+
+type fileOperator struct {
+	exporters map[string]*exporter
+	node      string
+
+	// files holds the open files with the number of gadget instances writing
+	// to them, so that instances using the same path share the file
+	filesMu sync.Mutex
+	files   map[string]*openFile
+}
+
+type openFile struct {
+	*rotatingFile
+	refs int
+}
+
+func (o *fileOperator) acquire(path string, cfg rotateConfig) *rotatingFile {
+	o.filesMu.Lock()
+	defer o.filesMu.Unlock()
+	f, ok := o.files[path]
+	if !ok {
+		f = &openFile{rotatingFile: newRotatingFile(path, cfg)}
+		o.files[path] = f
+	}
+	f.refs++
+	return f.rotatingFile
+}
+
+func (o *fileOperator) release(path string) error {
+	o.filesMu.Lock()
+	defer o.filesMu.Unlock()
+	f, ok := o.files[path]
+	if !ok {
+		return nil
+	}
+	f.refs--
+	if f.refs > 0 {
+		return nil
+	}
+	delete(o.files, path)
+	return f.Close()
+}
+
+func (o *fileOperator) Name() string {
+	return name
+}
+
+func (o *fileOperator) Init(params *params.Params) error {
+	o.exporters = make(map[string]*exporter)
+	o.files = make(map[string]*openFile)
+
+	o.node = os.Getenv("NODE_NAME")
+	if o.node == "" {
+		o.node, _ = os.Hostname()
+	}
+
+	if config.Config == nil {
+		return nil
+	}
+
+	configs := make(map[string]*exporterConfig)
+	log.Debugf("loading file exporters")
+	err := config.Config.UnmarshalKey("operator.file.exporters", &configs)
+	if err != nil {
+		log.Warnf("failed to load operator.file.exporters: %v", err)
+	}
+	for exporterName, cfg := range configs {
+		e, err := newExporter(exporterName, cfg)
+		if err != nil {
+			return err
+		}
+		o.exporters[exporterName] = e
+		log.Debugf("> file exporter %q with path %q loaded", exporterName, e.path)
+	}
+	return nil
+}
+
+func (o *fileOperator) GlobalParams() api.Params {
+	return api.Params{}
+}
+
+func (o *fileOperator) InstanceParams() api.Params {
+	return api.Params{
+		&api.Param{
+			Key:          ParamFileExporter,
+			Description:  "File exporter to write the events to, as DATASOURCE:EXPORTER or EXPORTER for all data sources",
+			DefaultValue: "",
+		},
+	}
+}
+
+func (o *fileOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	if len(o.exporters) == 0 {
+		return nil, nil
+	}
+	mappings, err := apihelpers.GetStringValuesPerDataSource(instanceParamValues[ParamFileExporter])
+	if err != nil {
+		return nil, fmt.Errorf("parsing exporter mappings: %w", err)
+	}
+
+	inst := &fileOperatorInstance{
+		o:       o,
+		outputs: make(map[datasource.DataSource]*dsOutputs),
+	}
+	gadget := common.GadgetName(gadgetCtx.ImageName())
+	for _, ds := range gadgetCtx.GetDataSources() {
+		exporterName, ok := mappings[ds.Name()]
+		if !ok {
+			exporterName = mappings[""]
+		}
+		if _, ok := o.exporters[exporterName]; exporterName != "" && !ok {
+			return nil, fmt.Errorf("file exporter not found: %q", exporterName)
+		}
+		dispatcher := routing.NewDispatcher(gadgetCtx, ds, name, exporterName, func(name string) bool {
+			_, ok := o.exporters[name]
+			return ok
+		})
+		if dispatcher == nil {
+			continue
+		}
+		outs := &dsOutputs{dispatcher: dispatcher, outputs: make(map[string]*output)}
+		for _, exporterName := range dispatcher.Exporters() {
+			e := o.exporters[exporterName]
+			path := expandPath(e.path, gadget, ds.Name(), o.node, gadgetCtx.ID())
+			gadgetCtx.Logger().Debugf("writing %q to %q", ds.Name(), path)
+			outs.outputs[exporterName] = &output{exporter: e, path: path}
+		}
+		inst.outputs[ds] = outs
+	}
+	if len(inst.outputs) == 0 {
+		return nil, nil
+	}
+	return inst, nil
+}
+
+func (o *fileOperator) Priority() int {
+	return Priority
+}
+
+// output is the file the events of a data source are written to
+type output struct {
+	exporter *exporter
+	path     string
+	file     *rotatingFile
+}
+
+// dsOutputs holds the files of the exporters the events of a data source are
+// written to
+type dsOutputs struct {
+	dispatcher *routing.Dispatcher
+	outputs    map[string]*output
+}
+
+type fileOperatorInstance struct {
+	o       *fileOperator
+	outputs map[datasource.DataSource]*dsOutputs
+}
+
+func (f *fileOperatorInstance) Name() string {
+	return name
+}
+
+func (f *fileOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, outs := range f.outputs {
+		formatter, err := igjson.New(ds, igjson.WithShowAll(true))
+		if err != nil {
+			return fmt.Errorf("creating json formatter for %s: %w", ds.Name(), err)
+		}
+		for _, out := range outs.outputs {
+			out.file = f.o.acquire(out.path, out.exporter.rotateConfig)
+		}
+
+		// One event per line
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			var line []byte
+			for _, exporterName := range outs.dispatcher.Route(data) {
+				if line == nil {
+					line = append(formatter.Marshal(data), '\n')
+				}
+				out := outs.outputs[exporterName]
+				if _, err := out.file.Write(line); err != nil {
+					gadgetCtx.Logger().Warnf("writing event of %s to %q: %v", ds.Name(), out.path, err)
+				}
+			}
+			return nil
+		}, Priority)
+	}
+	return nil
+}
+
+func (f *fileOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (f *fileOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (f *fileOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	for _, outs := range f.outputs {
+		for _, out := range outs.outputs {
+			if out.file == nil {
+				continue
+			}
+			if err := f.o.release(out.path); err != nil {
+				gadgetCtx.Logger().Warnf("closing %q: %v", out.path, err)
+			}
+			out.file = nil
+		}
+	}
+	return nil
+}
+
+var Operator = &fileOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/routing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func TestExpandPath(t *testing.T) {
+	assert.Equal(t, "/var/log/ig/trace_exec/exec.jsonl",
+		expandPath("/var/log/ig/{gadget}/{datasource}.jsonl", "trace_exec", "exec", "node-1", "abc"))
+	assert.Equal(t, "/var/log/ig/node-1-abc.jsonl",
+		expandPath("/var/log/ig/{node}-{instance}.jsonl", "trace_exec", "exec", "node-1", "abc"))
+	assert.Equal(t, "/var/log/ig/_/_etc.jsonl",
+		expandPath("/var/log/ig/{gadget}/{datasource}.jsonl", "..", "/etc", "node-1", "abc"))
+}
+
+func TestNewExporter(t *testing.T) {
+	e, err := newExporter("test", &exporterConfig{MaxSize: "10MiB"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPath, e.path)
+	assert.Equal(t, int64(10*1024*1024), e.maxSize)
+
+	e, err = newExporter("test", &exporterConfig{MaxSize: "512"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(512), e.maxSize)
+
+	_, err = newExporter("test", &exporterConfig{MaxSize: "10 apples"})
+	require.Error(t, err)
+
+	_, err = newExporter("test", &exporterConfig{Path: "events.jsonl"})
+	require.Error(t, err)
+
+	_, err = newExporter("test", &exporterConfig{Path: "/tmp/events.jsonl", MaxFiles: -1})
+	require.Error(t, err)
+}
+
+// runWriteEvents runs a gadget emitting two exec events with the given params
+func runWriteEvents(t *testing.T, o *fileOperator, paramValues api.ParamValues) error {
+	var ds datasource.DataSource
+	var comm datasource.FieldAccessor
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "exec")
+		require.NoError(t, err)
+		comm, err = ds.AddField("comm", api.Kind_String)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for _, c := range []string{"bash", "sh"} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, comm.PutString(data, c))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		cancel()
+		return nil
+	}
+
+	producer := simple.New("producer",
+		simple.WithPriority(routing.Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "ghcr.io/inspektor-gadget/gadget/trace_exec:latest",
+		gadgetcontext.WithDataOperators(o, routing.Operator, producer))
+	return gadgetCtx.Run(paramValues)
+}
+
+func TestWriteEvents(t *testing.T) {
+	dir := t.TempDir()
+	o := &fileOperator{
+		node:  "node-1",
+		files: make(map[string]*openFile),
+		exporters: map[string]*exporter{
+			"archive": {path: filepath.Join(dir, "{gadget}", "{datasource}.jsonl")},
+		},
+	}
+
+	require.NoError(t, runWriteEvents(t, o, api.ParamValues{
+		"operator.file." + ParamFileExporter: "archive",
+	}))
+
+	content, err := os.ReadFile(filepath.Join(dir, "trace_exec", "exec.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"comm\":\"bash\"}\n{\"comm\":\"sh\"}\n", string(content))
+	assert.Empty(t, o.files)
+}
+
+func TestRouteEvents(t *testing.T) {
+	dir := t.TempDir()
+	o := &fileOperator{
+		node:  "node-1",
+		files: make(map[string]*openFile),
+		exporters: map[string]*exporter{
+			"archive": {path: filepath.Join(dir, "archive.jsonl")},
+			"shells":  {path: filepath.Join(dir, "shells.jsonl")},
+		},
+	}
+
+	require.NoError(t, runWriteEvents(t, o, api.ParamValues{
+		"operator.file." + ParamFileExporter:      "archive",
+		"operator.routing." + routing.ParamRoutes: `shells=comm == "sh"; archive=`,
+	}))
+
+	content, err := os.ReadFile(filepath.Join(dir, "archive.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"comm\":\"bash\"}\n{\"comm\":\"sh\"}\n", string(content))
+	content, err = os.ReadFile(filepath.Join(dir, "shells.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"comm\":\"sh\"}\n", string(content))
+	assert.Empty(t, o.files)
+
+	// Routes to exporters that don't exist fail
+	err = runWriteEvents(t, o, api.ParamValues{
+		"operator.routing." + routing.ParamRoutes: `siem=comm == "sh"`,
+	})
+	require.ErrorContains(t, err, `route to "siem"`)
+}
@@ -0,0 +1,228 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// rotatedSuffix is the format of the suffix of rotated files; it sorts
+	// like the time of the rotation
+	rotatedSuffix = "20060102T150405.000"
+
+	gzipSuffix = ".gz"
+)
+
+type rotateConfig struct {
+	maxSize  int64
+	interval time.Duration
+	maxFiles int
+	compress bool
+}
+
+// rotatingFile appends to a file that is rotated once it reaches maxSize bytes
+// or was opened interval ago. Rotated files get the time of the rotation as
+// suffix, are compressed with gzip if compress is set, and only the last
+// maxFiles of them are kept.
+type rotatingFile struct {
+	rotateConfig
+	path string
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	openedAt time.Time
+
+	// compressing tracks the rotated files being compressed
+	compressing sync.WaitGroup
+
+	// pruneMu serializes removing old rotated files
+	pruneMu sync.Mutex
+
+	// now can be replaced for testing
+	now func() time.Time
+}
+
+func newRotatingFile(path string, cfg rotateConfig) *rotatingFile {
+	return &rotatingFile{
+		rotateConfig: cfg,
+		path:         path,
+		now:          time.Now,
+	}
+}
+
+func (r *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o750); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("getting size of file: %w", err)
+	}
+	r.f = f
+	r.size = info.Size()
+	r.openedAt = r.now()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f != nil && r.size > 0 {
+		if (r.maxSize > 0 && r.size+int64(len(p)) > r.maxSize) ||
+			(r.interval > 0 && r.now().Sub(r.openedAt) >= r.interval) {
+			if err := r.rotate(); err != nil {
+				return 0, fmt.Errorf("rotating %q: %w", r.path, err)
+			}
+		}
+	}
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, fmt.Errorf("%q: %w", r.path, err)
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file; the next write opens a new one
+func (r *rotatingFile) rotate() error {
+	err := r.f.Close()
+	r.f = nil
+	if err != nil {
+		return err
+	}
+	rotated := r.path + "." + r.now().UTC().Format(rotatedSuffix)
+	if err := os.Rename(r.path, rotated); err != nil {
+		return err
+	}
+
+	r.compressing.Add(1)
+	go func() {
+		defer r.compressing.Done()
+		if r.compress {
+			if err := compressFile(rotated); err != nil {
+				log.Warnf("compressing %q: %v", rotated, err)
+			}
+		}
+		r.prune()
+	}()
+	return nil
+}
+
+// compressFile replaces a file with a gzip-compressed copy of it
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := path + gzipSuffix + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+gzipSuffix)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// rotatedFiles returns the rotated files, oldest first
+func (r *rotatingFile) rotatedFiles() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(r.path))
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(r.path) + "."
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		if _, err := time.Parse(rotatedSuffix, strings.TrimSuffix(name[len(prefix):], gzipSuffix)); err != nil {
+			continue
+		}
+		files = append(files, filepath.Join(filepath.Dir(r.path), name))
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// prune removes the oldest rotated files when there are more than maxFiles
+func (r *rotatingFile) prune() {
+	if r.maxFiles <= 0 {
+		return
+	}
+	r.pruneMu.Lock()
+	defer r.pruneMu.Unlock()
+
+	files, err := r.rotatedFiles()
+	if err != nil {
+		log.Warnf("listing rotated files of %q: %v", r.path, err)
+		return
+	}
+	for len(files) > r.maxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			log.Warnf("removing rotated file: %v", err)
+		}
+		files = files[1:]
+	}
+}
+
+// Close closes the current file and waits for rotated files to be compressed
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.f != nil {
+		err = r.f.Close()
+		r.f = nil
+	}
+	r.mu.Unlock()
+	r.compressing.Wait()
+	return err
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFile(t *testing.T, cfg rotateConfig) (*rotatingFile, *time.Time) {
+	path := filepath.Join(t.TempDir(), "sub", "events.jsonl")
+	r := newRotatingFile(path, cfg)
+	now := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	return r, &now
+}
+
+func TestRotateBySize(t *testing.T) {
+	r, now := newTestFile(t, rotateConfig{maxSize: 10, maxFiles: 2})
+
+	for range 4 {
+		_, err := r.Write([]byte("0123456\n"))
+		require.NoError(t, err)
+		*now = now.Add(time.Second)
+	}
+	require.NoError(t, r.Close())
+
+	files, err := r.rotatedFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		r.path + ".20250304T050609.000",
+		r.path + ".20250304T050610.000",
+	}, files)
+
+	content, err := os.ReadFile(r.path)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456\n", string(content))
+}
+
+func TestRotateByInterval(t *testing.T) {
+	r, now := newTestFile(t, rotateConfig{interval: time.Minute, compress: true})
+
+	_, err := r.Write([]byte("first\n"))
+	require.NoError(t, err)
+	*now = now.Add(30 * time.Second)
+	_, err = r.Write([]byte("second\n"))
+	require.NoError(t, err)
+	*now = now.Add(30 * time.Second)
+	_, err = r.Write([]byte("third\n"))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	files, err := r.rotatedFiles()
+	require.NoError(t, err)
+	require.Equal(t, []string{r.path + ".20250304T050707.000.gz"}, files)
+
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	content, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(content))
+
+	content, err = os.ReadFile(r.path)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(content))
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"sync/atomic"
 
 	"golang.org/x/exp/constraints"
 
@@ -72,20 +73,30 @@ func keyForDataSource(param string, ds datasource.DataSource) string {
 func (f *filterOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
 	fop := &filterOperatorInstance{
 		gadgetCtx: gadgetCtx,
-		ffns:      map[datasource.DataSource][]func(datasource.DataSource, datasource.Data) bool{},
 	}
+	ffns, err := newFilters(gadgetCtx, instanceParamValues)
+	if err != nil {
+		return nil, err
+	}
+	fop.ffns.Store(&ffns)
+	return fop, nil
+}
+
+// newFilters returns the filter functions of each datasource set by the params
+func newFilters(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (map[datasource.DataSource][]filterFunc, error) {
+	ffns := map[datasource.DataSource][]filterFunc{}
 
 	datasources := gadgetCtx.GetDataSources()
 	for _, ds := range datasources {
 		key := keyForDataSource(ParamFilter, ds)
 		if filterStr := instanceParamValues[key]; filterStr != "" {
-			err := fop.addFilters(gadgetCtx, ds, filterStr)
+			err := addFilters(gadgetCtx, ffns, ds, filterStr)
 			if err != nil {
 				return nil, err
 			}
 		}
 		if filterStr := instanceParamValues[ParamFilter]; filterStr != "" {
-			err := fop.addFilters(gadgetCtx, ds, filterStr)
+			err := addFilters(gadgetCtx, ffns, ds, filterStr)
 			if err != nil {
 				return nil, err
 			}
@@ -93,23 +104,23 @@ func (f *filterOperator) InstantiateDataOperator(gadgetCtx operators.GadgetConte
 
 		key = keyForDataSource(ParamFilterExpr, ds)
 		if filterExpr := instanceParamValues[key]; filterExpr != "" {
-			err := fop.addFilterExpr(gadgetCtx, ds, filterExpr)
+			err := addFilterExpr(gadgetCtx, ffns, ds, filterExpr)
 			if err != nil {
 				return nil, err
 			}
 		}
 		if instanceParamValues[ParamFilterExpr] != "" {
-			err := fop.addFilterExpr(gadgetCtx, ds, instanceParamValues[ParamFilterExpr])
+			err := addFilterExpr(gadgetCtx, ffns, ds, instanceParamValues[ParamFilterExpr])
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
 
-	return fop, nil
+	return ffns, nil
 }
 
-func (f *filterOperatorInstance) addFilterExpr(gadgetCtx operators.GadgetContext, ds datasource.DataSource, filterExpr string) error {
+func addFilterExpr(gadgetCtx operators.GadgetContext, ffns map[datasource.DataSource][]filterFunc, ds datasource.DataSource, filterExpr string) error {
 	prog, err := expr.CompileFilterProgram(ds, filterExpr)
 	if err != nil {
 		return fmt.Errorf("compiling filter expression %q for datasource %s: %w", filterExpr, ds.Name(), err)
@@ -127,7 +138,7 @@ func (f *filterOperatorInstance) addFilterExpr(gadgetCtx operators.GadgetContext
 		}
 		return retB
 	}
-	f.ffns[ds] = append(f.ffns[ds], ff)
+	ffns[ds] = append(ffns[ds], ff)
 	return nil
 }
 
@@ -135,10 +146,14 @@ func (f *filterOperator) Priority() int {
 	return Priority
 }
 
+type filterFunc func(datasource.DataSource, datasource.Data) bool
+
 type filterOperatorInstance struct {
 	gadgetCtx operators.GadgetContext
 
-	ffns map[datasource.DataSource][]func(datasource.DataSource, datasource.Data) bool
+	// ffns holds the filter functions of each datasource; it's replaced when
+	// the params are updated while the gadget is running
+	ffns atomic.Pointer[map[datasource.DataSource][]filterFunc]
 }
 
 func (f *filterOperatorInstance) Name() string {
@@ -162,6 +177,8 @@ func (f *filterOperatorInstance) ExtraParams(gadgetCtx operators.GadgetContext)
   Example: --filter 'field!~regex'
         `
 	descriptionFilterExp := `Filter rules with an expression language for the datasource %q.
+  Fields can be compared and combined with any condition, and durations can be given like 10ms or 1.5s.
+  Example: --filter-expr 'dst.port == 443 && comm != "kubelet" && latency_ns > 10ms'
                  See [https://expr-lang.org/] for more information on the syntax`
 
 	var filtersParam []*api.Param
@@ -198,10 +215,11 @@ func (f *filterOperatorInstance) ExtraParams(gadgetCtx operators.GadgetContext)
 }
 
 func (f *filterOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
-	for ds, funcs := range f.ffns {
-		funcs := funcs
+	// All datasources are subscribed, so that filters can be added to them
+	// later on
+	for _, ds := range gadgetCtx.GetDataSources() {
 		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
-			for _, fn := range funcs {
+			for _, fn := range (*f.ffns.Load())[ds] {
 				if !fn(ds, data) {
 					return datasource.ErrDiscard
 				}
@@ -212,6 +230,18 @@ func (f *filterOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) err
 	return nil
 }
 
+// UpdateParams implements operators.ParamUpdater; the new filters replace the
+// previous ones at once
+func (f *filterOperatorInstance) UpdateParams(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (func(), error) {
+	ffns, err := newFilters(gadgetCtx, instanceParamValues)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		f.ffns.Store(&ffns)
+	}, nil
+}
+
 func (f *filterOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
 	return nil
 }
@@ -309,14 +339,14 @@ nextChar:
 	return "", comparisonTypeUnknown, false, "", fmt.Errorf("incomplete filter rule: %q", filter)
 }
 
-func (f *filterOperatorInstance) addFilters(gadgetCtx operators.GadgetContext, ds datasource.DataSource, filterStr string) error {
+func addFilters(gadgetCtx operators.GadgetContext, ffns map[datasource.DataSource][]filterFunc, ds datasource.DataSource, filterStr string) error {
 	filters := api.SplitStringWithEscape(filterStr, ',')
 	for _, filter := range filters {
 		if filter == "" {
 			continue
 		}
 		gadgetCtx.Logger().Debugf("adding filter %q", filter)
-		err := f.addFilter(gadgetCtx, ds, filter)
+		err := addFilter(ffns, ds, filter)
 		if err != nil {
 			return err
 		}
@@ -324,7 +354,7 @@ func (f *filterOperatorInstance) addFilters(gadgetCtx operators.GadgetContext, d
 	return nil
 }
 
-func (f *filterOperatorInstance) addFilter(gadgetCtx operators.GadgetContext, ds datasource.DataSource, filter string) error {
+func addFilter(ffns map[datasource.DataSource][]filterFunc, ds datasource.DataSource, filter string) error {
 	fieldName, op, negate, value, err := extractFilter(filter)
 	if err != nil {
 		return fmt.Errorf("extracting filter rule %q: %w", filter, err)
@@ -340,7 +370,7 @@ func (f *filterOperatorInstance) addFilter(gadgetCtx operators.GadgetContext, ds
 		return err
 	}
 
-	f.ffns[ds] = append(f.ffns[ds], ff)
+	ffns[ds] = append(ffns[ds], ff)
 	return nil
 }
 
@@ -513,3 +513,65 @@ func Tester(
 
 	return gadgetCtx.Run(paramValues)
 }
+
+func TestUpdateFilter(t *testing.T) {
+	var ds datasource.DataSource
+	var commField datasource.FieldAccessor
+	var comms []string
+	emit := func(comm string) {
+		data, err := ds.NewPacketSingle()
+		require.NoError(t, err)
+		require.NoError(t, commField.PutString(data, comm))
+		require.NoError(t, ds.EmitAndRelease(data))
+	}
+	err := Tester(
+		t,
+		&filterOperator{},
+		api.ParamValues{
+			"operator.filter.filter": "comm==a",
+		},
+		func(gadgetCtx operators.GadgetContext) error {
+			var err error
+			ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "filter")
+			require.NoError(t, err)
+			commField, err = ds.AddField("comm", api.Kind_String)
+			require.NoError(t, err)
+			return nil
+		},
+		func(gadgetCtx operators.GadgetContext) error {
+			gCtx := gadgetCtx.(*gadgetcontext.GadgetContext)
+			emit("a")
+			emit("b")
+
+			err := gCtx.UpdateParams(api.ParamValues{"operator.filter.filter": "comm==b"})
+			require.NoError(t, err)
+			emit("a")
+			emit("b")
+
+			// Invalid filters and params of other operators keep the current
+			// filter
+			err = gCtx.UpdateParams(api.ParamValues{"operator.filter.filter": "comm="})
+			require.Error(t, err)
+			err = gCtx.UpdateParams(api.ParamValues{
+				"operator.filter.filter": "comm==a",
+				"operator.producer.foo":  "bar",
+			})
+			require.ErrorIs(t, err, gadgetcontext.ErrRestartRequired)
+			emit("a")
+			emit("b")
+			return nil
+		},
+		func(gadgetCtx operators.GadgetContext) error {
+			err := ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+				comm, err := commField.String(data)
+				require.NoError(t, err)
+				comms = append(comms, comm)
+				return nil
+			}, Priority+1)
+			require.NoError(t, err)
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "b"}, comms)
+}
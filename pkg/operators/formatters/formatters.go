@@ -50,6 +50,7 @@ const (
 	durationTargetAnnotation  = "formatters.duration.target"
 	fileModeTargetAnnotation  = "formatters.file_mode.target"
 	fileFlagsTargetAnnotation = "formatters.file_flags.target"
+	verdictTargetAnnotation   = "formatters.verdict.target"
 	Priority                  = 0
 )
 
@@ -672,6 +673,49 @@ var replacers = []replacer{
 		},
 		priority: 0,
 	},
+	{
+		name:      "verdict",
+		selectors: []string{"type:" + ebpftypes.VerdictTypeName},
+		replace: func(logger logger.Logger, ds datasource.DataSource, in datasource.FieldAccessor) (func(data datasource.Data) error, error) {
+			if in.Type() != api.Kind_Uint32 {
+				return nil, fmt.Errorf("checking field %q: expected uint32", in.Name())
+			}
+
+			outName, err := annotations.GetTargetNameFromAnnotation(logger, "formatters.verdict", in, verdictTargetAnnotation)
+			if err != nil {
+				return nil, err
+			}
+
+			opts := []datasource.FieldOption{
+				datasource.WithAnnotations(map[string]string{
+					metadatav1.ValueOneOfAnnotation: strings.Join(verdictNames, ", "),
+				}),
+				datasource.WithSameParentAs(in),
+				datasource.WithSameOrderAs(in),
+			}
+			verdictField, err := ds.AddField(outName, api.Kind_String, opts...)
+			if err != nil {
+				return nil, err
+			}
+
+			annotations.SetFieldVisibility(true, in)
+
+			return func(data datasource.Data) error {
+				verdict, err := in.Uint32(data)
+				if err != nil {
+					return err
+				}
+
+				if int(verdict) < len(verdictNames) {
+					verdictField.PutString(data, verdictNames[verdict])
+				} else {
+					verdictField.PutString(data, fmt.Sprintf("verdict#%d", verdict))
+				}
+				return nil
+			}, nil
+		},
+		priority: 0,
+	},
 }
 
 func (f *formattersOperator) Priority() int {
@@ -466,6 +466,37 @@ func TestGeneral(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "verdict",
+			kind:     api.Kind_Uint32,
+			epbftype: ebpftypes.VerdictTypeName,
+			putFunc: func(fa datasource.FieldAccessor, data datasource.Data, value any) {
+				fa.PutUint32(data, value.(uint32))
+			},
+			data: []testCaseDatum{
+				{
+					value:    uint32(0),
+					ok:       true,
+					expected: "allowed",
+				},
+				{
+					value:    uint32(1),
+					ok:       true,
+					expected: "blocked",
+				},
+				{
+					value:      uint32(2),
+					ok:         true,
+					expected:   "would-block",
+					annotation: map[string]string{"formatters.verdict.target": "action"},
+				},
+				{
+					value:    uint32(7),
+					ok:       true,
+					expected: "verdict#7",
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
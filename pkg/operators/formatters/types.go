@@ -187,3 +187,11 @@ var errnoNames = []string{
 	"EXDEV",
 	"EXFULL",
 }
+
+// verdictNames are indexed by the value of gadget_verdict. Keep in sync with
+// include/gadget/enforcement.h
+var verdictNames = []string{
+	"allowed",
+	"blocked",
+	"would-block",
+}
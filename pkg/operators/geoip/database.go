@@ -0,0 +1,109 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// record holds the fields read from the databases. Country databases like
+// GeoLite2-Country and GeoLite2-City have the country, ASN databases like
+// GeoLite2-ASN the autonomous system; databases with both are supported too.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	ASN uint32 `maxminddb:"autonomous_system_number"`
+	Org string `maxminddb:"autonomous_system_organization"`
+}
+
+// merge sets the fields of r that are empty to the ones of other
+func (r *record) merge(other *record) {
+	if r.Country.ISOCode == "" {
+		r.Country.ISOCode = other.Country.ISOCode
+	}
+	if r.ASN == 0 {
+		r.ASN = other.ASN
+	}
+	if r.Org == "" {
+		r.Org = other.Org
+	}
+}
+
+// database is a MaxMind DB file that is reloaded when it changes, like when
+// geoipupdate downloads a new version
+type database struct {
+	path string
+
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+}
+
+func openDatabase(path string) (*database, error) {
+	db := &database{path: path}
+	if _, err := db.reload(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// reload opens the file again if it was modified since it was last opened,
+// and returns whether it was
+func (db *database) reload() (bool, error) {
+	fi, err := os.Stat(db.path)
+	if err != nil {
+		return false, fmt.Errorf("checking database %q: %w", db.path, err)
+	}
+
+	db.mu.RLock()
+	unchanged := db.reader != nil && fi.ModTime().Equal(db.modTime)
+	db.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	reader, err := maxminddb.Open(db.path)
+	if err != nil {
+		return false, fmt.Errorf("opening database %q: %w", db.path, err)
+	}
+
+	db.mu.Lock()
+	old := db.reader
+	db.reader = reader
+	db.modTime = fi.ModTime()
+	db.mu.Unlock()
+
+	// Lookups hold the read lock, so none uses the old reader anymore
+	if old != nil {
+		old.Close()
+	}
+	return true, nil
+}
+
+func (db *database) lookup(ip net.IP, r *record) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.reader == nil {
+		return nil
+	}
+	return db.reader.Lookup(ip, r)
+}
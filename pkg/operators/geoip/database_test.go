@@ -0,0 +1,77 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoip
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func country(code string) map[string]any {
+	return map[string]any{"country": map[string]any{"iso_code": code}}
+}
+
+func TestDatabase(t *testing.T) {
+	dir := t.TempDir()
+	path := writeMMDB(t, dir, "country.mmdb", map[string]map[string]any{
+		"1.1.1.0/24": country("AU"),
+		"8.8.8.0/24": country("US"),
+	})
+
+	db, err := openDatabase(path)
+	require.NoError(t, err)
+
+	var r record
+	require.NoError(t, db.lookup(net.ParseIP("1.1.1.1"), &r))
+	assert.Equal(t, "AU", r.Country.ISOCode)
+
+	r = record{}
+	require.NoError(t, db.lookup(net.ParseIP("9.9.9.9"), &r))
+	assert.Equal(t, "", r.Country.ISOCode)
+
+	// Not reloaded if unchanged
+	reloaded, err := db.reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	writeMMDB(t, dir, "country.mmdb", map[string]map[string]any{
+		"1.1.1.0/24": country("NZ"),
+	})
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	reloaded, err = db.reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	r = record{}
+	require.NoError(t, db.lookup(net.ParseIP("1.1.1.1"), &r))
+	assert.Equal(t, "NZ", r.Country.ISOCode)
+}
+
+func TestOpenDatabaseInvalid(t *testing.T) {
+	_, err := openDatabase("/nonexistent/country.mmdb")
+	require.Error(t, err)
+
+	path := t.TempDir() + "/invalid.mmdb"
+	require.NoError(t, os.WriteFile(path, []byte("not a database"), 0o644))
+	_, err = openDatabase(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,299 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package geoip is a data operator that enriches the endpoints of the events,
+// like the destination of trace_tcp, with the country and the autonomous
+// system of their IP address, looked up in local MaxMind DB files like the
+// GeoLite2 databases.
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	Name     = "geoip"
+	Priority = 20 // after the KubeIPResolver operator, which resolves the addresses inside the cluster
+
+	ParamCountryDB      = "geoip-country-db"
+	ParamASNDB          = "geoip-asn-db"
+	ParamReloadInterval = "geoip-reload-interval"
+)
+
+type geoipOperator struct {
+	dbs []*database
+}
+
+func (g *geoipOperator) Name() string {
+	return Name
+}
+
+func (g *geoipOperator) Init(params *params.Params) error {
+	for _, p := range []string{ParamCountryDB, ParamASNDB} {
+		path := params.Get(p).AsString()
+		if path == "" {
+			continue
+		}
+		db, err := openDatabase(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		g.dbs = append(g.dbs, db)
+	}
+
+	if interval := params.Get(ParamReloadInterval).AsDuration(); interval > 0 && len(g.dbs) > 0 {
+		go g.reloadEvery(interval)
+	}
+	return nil
+}
+
+// reloadEvery reloads the databases that changed, for as long as the operator
+// is loaded
+func (g *geoipOperator) reloadEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, db := range g.dbs {
+			reloaded, err := db.reload()
+			if err != nil {
+				log.Warnf("geoip: %v", err)
+				continue
+			}
+			if reloaded {
+				log.Debugf("geoip: reloaded database %q", db.path)
+			}
+		}
+	}
+}
+
+func (g *geoipOperator) GlobalParams() api.Params {
+	return api.Params{
+		{
+			Key:         ParamCountryDB,
+			Title:       "Country Database",
+			Description: "Path to a MaxMind DB file with countries, like GeoLite2-Country.mmdb or GeoLite2-City.mmdb",
+			TypeHint:    api.TypeString,
+		},
+		{
+			Key:         ParamASNDB,
+			Title:       "ASN Database",
+			Description: "Path to a MaxMind DB file with autonomous systems, like GeoLite2-ASN.mmdb",
+			TypeHint:    api.TypeString,
+		},
+		{
+			Key:          ParamReloadInterval,
+			Title:        "Reload Interval",
+			Description:  "Interval to check whether the database files changed and reload them. Use 0 to disable it.",
+			DefaultValue: "1h",
+			TypeHint:     api.TypeDuration,
+		},
+	}
+}
+
+func (g *geoipOperator) InstanceParams() api.Params {
+	return nil
+}
+
+// endpointAccessors holds the fields of an endpoint and the ones added to it
+type endpointAccessors struct {
+	ip      datasource.FieldAccessor
+	version datasource.FieldAccessor
+
+	country datasource.FieldAccessor
+	asn     datasource.FieldAccessor
+	org     datasource.FieldAccessor
+}
+
+func (g *geoipOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	if len(g.dbs) == 0 {
+		return nil, nil
+	}
+
+	epAccessors := make(map[datasource.DataSource][]endpointAccessors)
+	for _, ds := range gadgetCtx.GetDataSources() {
+		endpoints := ds.GetFieldsWithTag("type:"+ebpftypes.L3EndpointTypeName, "type:"+ebpftypes.L4EndpointTypeName)
+		for _, ep := range endpoints {
+			ips := ep.GetSubFieldsWithTag("type:" + ebpftypes.IPAddrTypeName)
+			if len(ips) != 1 {
+				return nil, fmt.Errorf("%s: expected one %q field", ep.FullName(), ebpftypes.IPAddrTypeName)
+			}
+			version := ep.GetSubFieldsWithTag("name:version")
+			if len(version) != 1 {
+				return nil, fmt.Errorf("%s: expected one %q field", ep.FullName(), "version")
+			}
+
+			ea, err := addGeoFields(ep)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ep.FullName(), err)
+			}
+			ea.ip = ips[0]
+			ea.version = version[0]
+			epAccessors[ds] = append(epAccessors[ds], ea)
+		}
+	}
+	if len(epAccessors) == 0 {
+		return nil, nil
+	}
+
+	return &geoipOperatorInstance{
+		dbs:                g.dbs,
+		endpointsAccessors: epAccessors,
+	}, nil
+}
+
+// addGeoFields adds the geo.country, geo.asn and geo.org fields to an endpoint
+func addGeoFields(ep datasource.FieldAccessor) (endpointAccessors, error) {
+	var ea endpointAccessors
+
+	geo, err := ep.AddSubField("geo", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+	if err != nil {
+		return ea, fmt.Errorf("adding field %q: %w", "geo", err)
+	}
+	ea.country, err = geo.AddSubField("country", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation:  "ISO code of the country of the IP address",
+			metadatav1.ColumnsWidthAnnotation: "7",
+		}),
+	)
+	if err != nil {
+		return ea, fmt.Errorf("adding field %q: %w", "country", err)
+	}
+	ea.asn, err = geo.AddSubField("asn", api.Kind_Uint32,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation: "Number of the autonomous system of the IP address",
+		}),
+		datasource.WithFlags(datasource.FieldFlagHidden),
+	)
+	if err != nil {
+		return ea, fmt.Errorf("adding field %q: %w", "asn", err)
+	}
+	ea.org, err = geo.AddSubField("org", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation: "Organization of the autonomous system of the IP address",
+		}),
+		datasource.WithFlags(datasource.FieldFlagHidden),
+	)
+	if err != nil {
+		return ea, fmt.Errorf("adding field %q: %w", "org", err)
+	}
+	return ea, nil
+}
+
+func (g *geoipOperator) Priority() int {
+	return Priority
+}
+
+type geoipOperatorInstance struct {
+	dbs                []*database
+	endpointsAccessors map[datasource.DataSource][]endpointAccessors
+}
+
+func (g *geoipOperatorInstance) Name() string {
+	return Name
+}
+
+// getIP returns the IP address of an endpoint, or nil if it can't be in the
+// databases, like private addresses
+func getIP(data datasource.Data, ea *endpointAccessors) net.IP {
+	b := ea.ip.Get(data)
+	if len(b) != 16 {
+		return nil
+	}
+	v, err := ea.version.Uint8(data)
+	if err != nil {
+		return nil
+	}
+
+	var ip net.IP
+	switch v {
+	case 4:
+		ip = net.IP(b[:4])
+	case 6:
+		ip = net.IP(b)
+	default:
+		return nil
+	}
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return nil
+	}
+	return ip
+}
+
+func (g *geoipOperatorInstance) lookup(ip net.IP) (*record, error) {
+	res := &record{}
+	for _, db := range g.dbs {
+		var r record
+		if err := db.lookup(ip, &r); err != nil {
+			return nil, err
+		}
+		res.merge(&r)
+	}
+	return res, nil
+}
+
+func (g *geoipOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, accessors := range g.endpointsAccessors {
+		err := ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			for i := range accessors {
+				ea := &accessors[i]
+				ip := getIP(data, ea)
+				if ip == nil {
+					continue
+				}
+				r, err := g.lookup(ip)
+				if err != nil {
+					gadgetCtx.Logger().Debugf("geoip: looking up %s: %v", ip, err)
+					continue
+				}
+				ea.country.PutString(data, r.Country.ISOCode)
+				ea.asn.PutUint32(data, r.ASN)
+				ea.org.PutString(data, r.Org)
+			}
+			return nil
+		}, Priority)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *geoipOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (g *geoipOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (g *geoipOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &geoipOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoip
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+type result struct {
+	country string
+	asn     uint32
+	org     string
+}
+
+func TestGeoIP(t *testing.T) {
+	dir := t.TempDir()
+	countryDB, err := openDatabase(writeMMDB(t, dir, "country.mmdb", map[string]map[string]any{
+		"1.1.1.0/24": country("AU"),
+	}))
+	require.NoError(t, err)
+	asnDB, err := openDatabase(writeMMDB(t, dir, "asn.mmdb", map[string]map[string]any{
+		"1.1.1.0/24": {
+			"autonomous_system_number":       uint32(13335),
+			"autonomous_system_organization": "CLOUDFLARENET",
+		},
+	}))
+	require.NoError(t, err)
+
+	var ds datasource.DataSource
+	var ipField, versionField datasource.FieldAccessor
+	var got []result
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "tcp")
+		require.NoError(t, err)
+		dst, err := ds.AddField("dst", api.Kind_Invalid, datasource.WithTags("type:"+ebpftypes.L4EndpointTypeName))
+		require.NoError(t, err)
+		ipField, err = dst.AddSubField("addr_raw", api.Kind_Bytes, datasource.WithTags("type:"+ebpftypes.IPAddrTypeName))
+		require.NoError(t, err)
+		versionField, err = dst.AddSubField("version", api.Kind_Uint8, datasource.WithTags("name:version"))
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for _, addr := range []string{"1.1.1.1", "10.0.0.1", "9.9.9.9"} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			ip := make([]byte, 16)
+			copy(ip, net.ParseIP(addr).To4())
+			require.NoError(t, ipField.PutBytes(data, ip))
+			require.NoError(t, versionField.PutUint8(data, 4))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		country := ds.GetField("dst.geo.country")
+		require.NotNil(t, country)
+		asn := ds.GetField("dst.geo.asn")
+		require.NotNil(t, asn)
+		org := ds.GetField("dst.geo.org")
+		require.NotNil(t, org)
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			var r result
+			var err error
+			r.country, err = country.String(data)
+			require.NoError(t, err)
+			r.asn, err = asn.Uint32(data)
+			require.NoError(t, err)
+			r.org, err = org.String(data)
+			require.NoError(t, err)
+			got = append(got, r)
+			if len(got) == 3 {
+				cancel()
+			}
+			return nil
+		}, Priority+1)
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	op := &geoipOperator{dbs: []*database{countryDB, asnDB}}
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(op, producer, consumer))
+	require.NoError(t, gadgetCtx.Run(api.ParamValues{}))
+
+	assert.Equal(t, []result{
+		{country: "AU", asn: 13335, org: "CLOUDFLARENET"},
+		{},
+		{},
+	}, got)
+}
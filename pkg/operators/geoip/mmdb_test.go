@@ -0,0 +1,173 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// This file writes minimal IPv4 MaxMind DB files for the tests, see
+// https://maxmind.github.io/MaxMind-DB/ for the format.
+
+const (
+	mmdbTypeString = 2
+	mmdbTypeUint16 = 5
+	mmdbTypeUint32 = 6
+	mmdbTypeMap    = 7
+	mmdbTypeUint64 = 9
+	mmdbTypeArray  = 11
+)
+
+func mmdbControl(buf *bytes.Buffer, typ int, size int) {
+	ctrl := byte(0)
+	if typ <= 7 {
+		ctrl = byte(typ) << 5
+	}
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+	case size < 29+256:
+		ctrl |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	default:
+		ctrl |= 30
+		sizeBytes = binary.BigEndian.AppendUint16(nil, uint16(size-285))
+	}
+	buf.WriteByte(ctrl)
+	if typ > 7 {
+		buf.WriteByte(byte(typ - 7))
+	}
+	buf.Write(sizeBytes)
+}
+
+func mmdbUint(buf *bytes.Buffer, typ int, v uint64) {
+	b := binary.BigEndian.AppendUint64(nil, v)
+	b = bytes.TrimLeft(b, "\x00")
+	mmdbControl(buf, typ, len(b))
+	buf.Write(b)
+}
+
+func mmdbEncode(buf *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case string:
+		mmdbControl(buf, mmdbTypeString, len(v))
+		buf.WriteString(v)
+	case uint16:
+		mmdbUint(buf, mmdbTypeUint16, uint64(v))
+	case uint32:
+		mmdbUint(buf, mmdbTypeUint32, uint64(v))
+	case uint64:
+		mmdbUint(buf, mmdbTypeUint64, v)
+	case []any:
+		mmdbControl(buf, mmdbTypeArray, len(v))
+		for _, e := range v {
+			mmdbEncode(buf, e)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		mmdbControl(buf, mmdbTypeMap, len(v))
+		for _, k := range keys {
+			mmdbEncode(buf, k)
+			mmdbEncode(buf, v[k])
+		}
+	default:
+		panic("unsupported type")
+	}
+}
+
+// writeMMDB writes a database with the given records for IPv4 networks and
+// returns its path
+func writeMMDB(t *testing.T, dir string, name string, networks map[string]map[string]any) string {
+	// Nodes of the search tree, with the record for the 0 and 1 bits. Records
+	// are -1 if empty and -2-N for the Nth data.
+	nodes := [][2]int{{-1, -1}}
+	var data []map[string]any
+	for cidr, d := range networks {
+		_, network, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		ones, _ := network.Mask.Size()
+		ip := network.IP.To4()
+
+		data = append(data, d)
+		node := 0
+		for i := 0; i < ones; i++ {
+			bit := int(ip[i/8]>>(7-i%8)) & 1
+			if i == ones-1 {
+				nodes[node][bit] = -1 - len(data)
+				break
+			}
+			if nodes[node][bit] < 0 {
+				nodes = append(nodes, [2]int{-1, -1})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+	}
+
+	var dataSection bytes.Buffer
+	offsets := make([]int, len(data))
+	for i, d := range data {
+		offsets[i] = dataSection.Len()
+		mmdbEncode(&dataSection, d)
+	}
+
+	var buf bytes.Buffer
+	nodeCount := len(nodes)
+	for _, n := range nodes {
+		for _, r := range n {
+			var v int
+			switch {
+			case r == -1:
+				v = nodeCount
+			case r < -1:
+				v = nodeCount + 16 + offsets[-r-2]
+			default:
+				v = r
+			}
+			buf.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(dataSection.Bytes())
+	buf.WriteString("\xab\xcd\xefMaxMind.com")
+	mmdbEncode(&buf, map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"database_type":               "Test",
+		"description":                 map[string]any{"en": "Test database"},
+		"ip_version":                  uint16(4),
+		"languages":                   []any{"en"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	return path
+}
@@ -0,0 +1,274 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package identity is a data operator that overrides or relabels the fields
+// identifying where events come from, like the node name, before they are
+// filtered and exported. This allows using stable, logical names instead of
+// ephemeral ones, like the node names of autoscaled clusters, so joins done
+// downstream don't break.
+package identity
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	Name     = "identity"
+	Priority = 8900 // before the filter operator, so filters see the final values
+
+	ParamNodeName    = "identity-node-name"
+	ParamClusterName = "identity-cluster-name"
+	ParamRelabel     = "identity-relabel"
+
+	// nodeTemplate is the template annotation value of the fields holding the
+	// node name, like k8s.node
+	nodeTemplate = "node"
+
+	clusterField = "cluster"
+	k8sField     = "k8s"
+)
+
+// rule sets the value of a field, either statically or by replacing the
+// matches of a regular expression in the current value
+type rule struct {
+	field       string
+	re          *regexp.Regexp
+	replacement string
+}
+
+func (r *rule) apply(value string) string {
+	if r.re == nil {
+		return r.replacement
+	}
+	return r.re.ReplaceAllString(value, r.replacement)
+}
+
+// parseRules parses comma-separated rules like "FIELD=VALUE" to set FIELD to
+// VALUE or "FIELD~REGEX=REPLACEMENT" to replace the matches of REGEX in FIELD
+// with REPLACEMENT, which can reference capture groups like $1
+func parseRules(s string) ([]rule, error) {
+	var rules []rule
+	for _, r := range api.SplitStringWithEscape(s, ',') {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+
+		idx := strings.IndexAny(r, "~=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid relabel rule %q: expected FIELD=VALUE or FIELD~REGEX=REPLACEMENT", r)
+		}
+		field := r[:idx]
+
+		if r[idx] == '=' {
+			rules = append(rules, rule{field: field, replacement: r[idx+1:]})
+			continue
+		}
+
+		expr, replacement, ok := strings.Cut(r[idx+1:], "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid relabel rule %q: missing replacement", r)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid relabel rule %q: %w", r, err)
+		}
+		rules = append(rules, rule{field: field, re: re, replacement: replacement})
+	}
+	return rules, nil
+}
+
+type identityOperator struct {
+	nodeName    string
+	clusterName string
+	rules       []rule
+}
+
+func (i *identityOperator) Name() string {
+	return Name
+}
+
+func (i *identityOperator) Init(params *params.Params) error {
+	i.nodeName = params.Get(ParamNodeName).AsString()
+	i.clusterName = params.Get(ParamClusterName).AsString()
+
+	rules, err := parseRules(params.Get(ParamRelabel).AsString())
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", ParamRelabel, err)
+	}
+	i.rules = rules
+	return nil
+}
+
+func (i *identityOperator) GlobalParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamNodeName,
+			Title: "Node Name",
+			Description: "Name to use in the fields holding the node name, like k8s.node, " +
+				"instead of the name of the node the gadget runs on",
+			TypeHint: api.TypeString,
+		},
+		{
+			Key:   ParamClusterName,
+			Title: "Cluster Name",
+			Description: "Name of the cluster to add to the events, as k8s.cluster if the events have " +
+				"Kubernetes metadata or as cluster otherwise",
+			TypeHint: api.TypeString,
+		},
+		{
+			Key:   ParamRelabel,
+			Title: "Relabel Rules",
+			Description: "Comma-separated list of rules changing the value of string fields, either " +
+				"FIELD=VALUE to set it or FIELD~REGEX=REPLACEMENT to replace the matches of REGEX, like " +
+				"'k8s.node~^(.*)-[a-z0-9]{5}$=$1'. Rules are applied in order, after the node and cluster names.",
+			TypeHint: api.TypeString,
+		},
+	}
+}
+
+func (i *identityOperator) InstanceParams() api.Params {
+	return nil
+}
+
+func (i *identityOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	if i.nodeName == "" && i.clusterName == "" && len(i.rules) == 0 {
+		return nil, nil
+	}
+
+	instance := &identityOperatorInstance{
+		op:     i,
+		fields: make(map[datasource.DataSource][]datasource.DataFunc),
+	}
+	err := instance.init(gadgetCtx)
+	if err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+func (i *identityOperator) Priority() int {
+	return Priority
+}
+
+type identityOperatorInstance struct {
+	op     *identityOperator
+	fields map[datasource.DataSource][]datasource.DataFunc
+}
+
+func (i *identityOperatorInstance) Name() string {
+	return Name
+}
+
+func (i *identityOperatorInstance) init(gadgetCtx operators.GadgetContext) error {
+	for _, ds := range gadgetCtx.GetDataSources() {
+		if i.op.nodeName != "" {
+			for _, f := range ds.Accessors(false) {
+				if f.Type() != api.Kind_String || f.Annotations()[metadatav1.TemplateAnnotation] != nodeTemplate {
+					continue
+				}
+				gadgetCtx.Logger().Debugf("overriding node name in field %s of %s", f.FullName(), ds.Name())
+				i.fields[ds] = append(i.fields[ds], setFunc(f, i.op.nodeName))
+			}
+		}
+
+		if i.op.clusterName != "" {
+			f, err := addClusterField(ds)
+			if err != nil {
+				return fmt.Errorf("adding cluster field to %s: %w", ds.Name(), err)
+			}
+			i.fields[ds] = append(i.fields[ds], setFunc(f, i.op.clusterName))
+		}
+
+		for _, r := range i.op.rules {
+			f := ds.GetField(r.field)
+			if f == nil {
+				// Not all data sources have the same fields
+				continue
+			}
+			if f.Type() != api.Kind_String {
+				return fmt.Errorf("relabeling field %s of %s: only string fields are supported", r.field, ds.Name())
+			}
+			i.fields[ds] = append(i.fields[ds], relabelFunc(f, r))
+		}
+	}
+	return nil
+}
+
+// addClusterField adds the field holding the cluster name next to the other
+// Kubernetes metadata if there is any, at the top level otherwise
+func addClusterField(ds datasource.DataSource) (datasource.FieldAccessor, error) {
+	opts := []datasource.FieldOption{
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation: "Name of the cluster",
+		}),
+		datasource.WithFlags(datasource.FieldFlagHidden),
+	}
+	if k8s := ds.GetField(k8sField); k8s != nil {
+		return k8s.AddSubField(clusterField, api.Kind_String, opts...)
+	}
+	return ds.AddField(clusterField, api.Kind_String, opts...)
+}
+
+func setFunc(f datasource.FieldAccessor, value string) datasource.DataFunc {
+	return func(ds datasource.DataSource, data datasource.Data) error {
+		return f.PutString(data, value)
+	}
+}
+
+func relabelFunc(f datasource.FieldAccessor, r rule) datasource.DataFunc {
+	return func(ds datasource.DataSource, data datasource.Data) error {
+		value, err := f.String(data)
+		if err != nil {
+			return err
+		}
+		return f.PutString(data, r.apply(value))
+	}
+}
+
+func (i *identityOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, funcs := range i.fields {
+		for _, f := range funcs {
+			err := ds.Subscribe(f, Priority)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (i *identityOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (i *identityOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (i *identityOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func init() {
+	operators.RegisterDataOperator(&identityOperator{})
+}
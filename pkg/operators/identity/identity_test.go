@@ -0,0 +1,207 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    string
+		value    string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			rules:    "",
+			expected: nil,
+		},
+		{
+			name:     "static",
+			rules:    "k8s.node=worker",
+			value:    "aks-nodepool1-12345-vmss000001",
+			expected: []string{"worker"},
+		},
+		{
+			name:     "regex",
+			rules:    `k8s.node~^(.*)-vmss[0-9a-z]+$=$1`,
+			value:    "aks-nodepool1-12345-vmss000001",
+			expected: []string{"aks-nodepool1-12345"},
+		},
+		{
+			name:     "regex no match",
+			rules:    `k8s.node~^ip-(.*)$=$1`,
+			value:    "aks-nodepool1-12345-vmss000001",
+			expected: []string{"aks-nodepool1-12345-vmss000001"},
+		},
+		{
+			name:     "multiple",
+			rules:    `k8s.node=worker, k8s.namespace~^kube-.*$=system`,
+			value:    "kube-system",
+			expected: []string{"worker", "system"},
+		},
+		{
+			name:     "escaped comma",
+			rules:    `k8s.node=a\,b`,
+			expected: []string{"a,b"},
+		},
+		{
+			name:    "missing operation",
+			rules:   "k8s.node",
+			wantErr: true,
+		},
+		{
+			name:    "missing field",
+			rules:   "=worker",
+			wantErr: true,
+		},
+		{
+			name:    "missing replacement",
+			rules:   "k8s.node~^worker",
+			wantErr: true,
+		},
+		{
+			name:    "invalid regex",
+			rules:   "k8s.node~(=worker",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := parseRules(test.rules)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, rules, len(test.expected))
+			for i, r := range rules {
+				assert.Equal(t, test.expected[i], r.apply(test.value))
+			}
+		})
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	o := &identityOperator{}
+	globalParams := apihelpers.ToParamDescs(o.GlobalParams()).ToParams()
+	globalParams.Set(ParamNodeName, "worker")
+	globalParams.Set(ParamClusterName, "prod")
+	globalParams.Set(ParamRelabel, `k8s.namespace~^team-(.*)$=$1,missing=foo`)
+	err := o.Init(globalParams)
+	require.NoError(t, err)
+
+	var ds datasource.DataSource
+	var nodeField, namespaceField datasource.FieldAccessor
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "test")
+		require.NoError(t, err)
+		k8s, err := ds.AddField("k8s", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+		require.NoError(t, err)
+		nodeField, err = k8s.AddSubField("node", api.Kind_String,
+			datasource.WithAnnotations(map[string]string{
+				metadatav1.TemplateAnnotation: "node",
+			}),
+		)
+		require.NoError(t, err)
+		namespaceField, err = k8s.AddSubField("namespace", api.Kind_String)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		data, err := ds.NewPacketSingle()
+		assert.NoError(t, err)
+		assert.NoError(t, nodeField.PutString(data, "aks-nodepool1-12345-vmss000001"))
+		assert.NoError(t, namespaceField.PutString(data, "team-payments"))
+		err = ds.EmitAndRelease(data)
+		assert.NoError(t, err)
+		cancel()
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	success := false
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		ds := gadgetCtx.GetDataSources()["test"]
+		require.NotNil(t, ds)
+
+		clusterField := ds.GetField("k8s.cluster")
+		require.NotNil(t, clusterField)
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			node, err := nodeField.String(data)
+			require.NoError(t, err)
+			assert.Equal(t, "worker", node)
+
+			namespace, err := namespaceField.String(data)
+			require.NoError(t, err)
+			assert.Equal(t, "payments", namespace)
+
+			cluster, err := clusterField.String(data)
+			require.NoError(t, err)
+			assert.Equal(t, "prod", cluster)
+
+			success = true
+			return nil
+		}, Priority+1)
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(o, producer, consumer))
+	err = gadgetCtx.Run(nil)
+	require.NoError(t, err)
+
+	assert.True(t, success)
+}
+
+func TestIdentityDisabled(t *testing.T) {
+	o := &identityOperator{}
+	err := o.Init(apihelpers.ToParamDescs(o.GlobalParams()).ToParams())
+	require.NoError(t, err)
+
+	instance, err := o.InstantiateDataOperator(nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, instance)
+}
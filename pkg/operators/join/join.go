@@ -0,0 +1,418 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package join is a data operator that enriches the events of a data source
+// with the fields of the last event of another data source with the same key,
+// like the parent of the process of a TCP connection from the processes of
+// snapshot_process. Events are stored in named tables shared by all the gadget
+// instances, so the data sources can belong to different gadgets.
+package join
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	Name     = "join"
+	Priority = 8920 // after the identity operator, before the transform and filter operators so they can use the joined fields
+
+	ParamMaxEntries = "join-max-entries"
+	ParamTTL        = "join-ttl"
+	ParamStore      = "join-store"
+	ParamLookup     = "join-lookup"
+
+	keyFieldsSep = ","
+	keyValuesSep = "/"
+)
+
+// spec is a store or lookup rule like "[DATASOURCE:]TABLE:KEY[,KEY]", with
+// "=FIELD[,FIELD]" for lookups
+type spec struct {
+	dataSource string
+	table      string
+	keys       []string
+	fields     []string
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, keyFieldsSep) {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// parseSpecs parses rules separated by semicolons or new lines. Lookup rules
+// need the fields to copy from the table.
+func parseSpecs(s string, lookup bool) ([]spec, error) {
+	var specs []spec
+	for _, r := range strings.FieldsFunc(s, func(c rune) bool { return c == ';' || c == '\n' }) {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+
+		target, fields, hasFields := strings.Cut(r, "=")
+		if hasFields != lookup {
+			if lookup {
+				return nil, fmt.Errorf("invalid lookup %q: expected [DATASOURCE:]TABLE:KEY[,KEY]=FIELD[,FIELD]", r)
+			}
+			return nil, fmt.Errorf("invalid store %q: expected [DATASOURCE:]TABLE:KEY[,KEY]", r)
+		}
+
+		var sp spec
+		parts := strings.Split(target, ":")
+		switch len(parts) {
+		case 2:
+			sp.table, sp.keys = strings.TrimSpace(parts[0]), splitList(parts[1])
+		case 3:
+			sp.dataSource = strings.TrimSpace(parts[0])
+			sp.table, sp.keys = strings.TrimSpace(parts[1]), splitList(parts[2])
+		default:
+			return nil, fmt.Errorf("invalid rule %q: expected [DATASOURCE:]TABLE:KEY[,KEY]", r)
+		}
+		if sp.table == "" || len(sp.keys) == 0 {
+			return nil, fmt.Errorf("invalid rule %q: missing table or keys", r)
+		}
+		if lookup {
+			sp.fields = splitList(fields)
+			if len(sp.fields) == 0 {
+				return nil, fmt.Errorf("invalid lookup %q: missing fields", r)
+			}
+		}
+		specs = append(specs, sp)
+	}
+	return specs, nil
+}
+
+type joinOperator struct {
+	maxEntries int
+	ttl        time.Duration
+
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+func (j *joinOperator) Name() string {
+	return Name
+}
+
+func (j *joinOperator) Init(params *params.Params) error {
+	j.maxEntries = int(params.Get(ParamMaxEntries).AsUint64())
+	if j.maxEntries == 0 {
+		return fmt.Errorf("invalid value for %s: must be greater than 0", ParamMaxEntries)
+	}
+	j.ttl = params.Get(ParamTTL).AsDuration()
+	return nil
+}
+
+func (j *joinOperator) GlobalParams() api.Params {
+	return api.Params{
+		{
+			Key:          ParamMaxEntries,
+			Title:        "Max Entries",
+			Description:  "Maximum number of events kept in each table; the least recently updated ones are evicted first",
+			DefaultValue: "10000",
+			TypeHint:     api.TypeUint,
+		},
+		{
+			Key:          ParamTTL,
+			Title:        "TTL",
+			Description:  "Time after which an event stored in a table expires if it isn't updated. Use 0 to keep events until evicted.",
+			DefaultValue: "5m",
+			TypeHint:     api.TypeDuration,
+		},
+	}
+}
+
+func (j *joinOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamStore,
+			Title: "Store",
+			Description: "Store the events in tables, separated by semicolons, like 'procs:pid'. " +
+				"Each one is [DATASOURCE:]TABLE:KEY[,KEY]; the last event with the same values for the KEY fields is kept.",
+			TypeHint: api.TypeString,
+		},
+		{
+			Key:   ParamLookup,
+			Title: "Lookup",
+			Description: "Add fields of the events stored in tables, separated by semicolons, like 'procs:proc.pid=parent.comm'. " +
+				"Each one is [DATASOURCE:]TABLE:KEY[,KEY]=FIELD[,FIELD], with the KEY fields in the same order as when storing. " +
+				"The fields are added as TABLE.FIELD",
+			TypeHint: api.TypeString,
+		},
+	}
+}
+
+func (j *joinOperator) getTable(name string) *table {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.tables == nil {
+		j.tables = make(map[string]*table)
+	}
+	t, ok := j.tables[name]
+	if !ok {
+		t = newTable(j.maxEntries, j.ttl)
+		j.tables[name] = t
+	}
+	return t
+}
+
+func (j *joinOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	stores, err := parseSpecs(instanceParamValues[ParamStore], false)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ParamStore, err)
+	}
+	lookups, err := parseSpecs(instanceParamValues[ParamLookup], true)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ParamLookup, err)
+	}
+	if len(stores) == 0 && len(lookups) == 0 {
+		return nil, nil
+	}
+
+	instance := &joinOperatorInstance{
+		funcs: make(map[datasource.DataSource][]datasource.DataFunc),
+	}
+	for _, sp := range stores {
+		if err := instance.forEachDataSource(gadgetCtx, sp, j.addStore); err != nil {
+			return nil, fmt.Errorf("storing in table %q: %w", sp.table, err)
+		}
+	}
+	for _, sp := range lookups {
+		if err := instance.forEachDataSource(gadgetCtx, sp, j.addLookup); err != nil {
+			return nil, fmt.Errorf("looking up table %q: %w", sp.table, err)
+		}
+	}
+	return instance, nil
+}
+
+func (j *joinOperator) Priority() int {
+	return Priority
+}
+
+type keyFunc func(datasource.Data) (string, string)
+
+func formatInt(v int64) string     { return strconv.FormatInt(v, 10) }
+func formatFloat(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+func formatString(v string) string { return v }
+
+func valueFunc(f datasource.FieldAccessor) (keyFunc, error) {
+	return datasource.GetKeyValueFunc[string, string](f, f.FullName(), formatInt, formatFloat, formatString)
+}
+
+func eventKey(keyFuncs []keyFunc, data datasource.Data) string {
+	vals := make([]string, 0, len(keyFuncs))
+	for _, kf := range keyFuncs {
+		_, val := kf(data)
+		vals = append(vals, val)
+	}
+	return strings.Join(vals, keyValuesSep)
+}
+
+type joinOperatorInstance struct {
+	funcs map[datasource.DataSource][]datasource.DataFunc
+}
+
+func (j *joinOperatorInstance) Name() string {
+	return Name
+}
+
+// forEachDataSource calls fn with the data source of a rule and the functions
+// returning its keys. Rules without data source apply to all the data sources
+// with the key fields.
+func (j *joinOperatorInstance) forEachDataSource(
+	gadgetCtx operators.GadgetContext,
+	sp spec,
+	fn func(gadgetCtx operators.GadgetContext, ds datasource.DataSource, sp spec, keyFuncs []keyFunc) (datasource.DataFunc, error),
+) error {
+	found := false
+	for _, ds := range gadgetCtx.GetDataSources() {
+		if sp.dataSource != "" && ds.Name() != sp.dataSource {
+			continue
+		}
+
+		var keyFuncs []keyFunc
+		for _, k := range sp.keys {
+			f := ds.GetField(k)
+			if f == nil {
+				break
+			}
+			kf, err := valueFunc(f)
+			if err != nil {
+				return fmt.Errorf("using %q of %s as key: %w", k, ds.Name(), err)
+			}
+			keyFuncs = append(keyFuncs, kf)
+		}
+		if len(keyFuncs) != len(sp.keys) {
+			if sp.dataSource != "" {
+				return fmt.Errorf("data source %q doesn't have the fields %v", ds.Name(), sp.keys)
+			}
+			continue
+		}
+
+		dataFn, err := fn(gadgetCtx, ds, sp, keyFuncs)
+		if err != nil {
+			return fmt.Errorf("data source %q: %w", ds.Name(), err)
+		}
+		j.funcs[ds] = append(j.funcs[ds], dataFn)
+		found = true
+	}
+	if !found {
+		if sp.dataSource != "" {
+			return fmt.Errorf("data source %q not found", sp.dataSource)
+		}
+		return fmt.Errorf("no data source has the fields %v", sp.keys)
+	}
+	return nil
+}
+
+// addStore stores all the fields of the events that can be used as keys
+func (j *joinOperator) addStore(gadgetCtx operators.GadgetContext, ds datasource.DataSource, sp spec, keyFuncs []keyFunc) (datasource.DataFunc, error) {
+	var valueFuncs []keyFunc
+	for _, f := range ds.Accessors(false) {
+		if len(f.SubFields()) > 0 {
+			continue
+		}
+		vf, err := valueFunc(f)
+		if err != nil {
+			// Only strings and numbers are stored
+			continue
+		}
+		valueFuncs = append(valueFuncs, vf)
+	}
+
+	gadgetCtx.Logger().Debugf("join: storing %s in table %q by %v", ds.Name(), sp.table, sp.keys)
+
+	t := j.getTable(sp.table)
+	return func(ds datasource.DataSource, data datasource.Data) error {
+		r := make(row, len(valueFuncs))
+		for _, vf := range valueFuncs {
+			name, val := vf(data)
+			r[name] = val
+		}
+		t.set(eventKey(keyFuncs, data), r, time.Now())
+		return nil
+	}, nil
+}
+
+// addLookup adds the fields of the lookup as TABLE.FIELD. Fields are strings,
+// as the table can be filled by a different gadget.
+func (j *joinOperator) addLookup(gadgetCtx operators.GadgetContext, ds datasource.DataSource, sp spec, keyFuncs []keyFunc) (datasource.DataFunc, error) {
+	type target struct {
+		name  string
+		field datasource.FieldAccessor
+	}
+	var targets []target
+	for _, name := range sp.fields {
+		f, err := addNestedField(ds, sp.table+"."+name, map[string]string{
+			metadatav1.DescriptionAnnotation: fmt.Sprintf("%s of the event of table %s with the same %s", name, sp.table, strings.Join(sp.keys, keyFieldsSep)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("adding field %q: %w", name, err)
+		}
+		targets = append(targets, target{name: name, field: f})
+	}
+
+	gadgetCtx.Logger().Debugf("join: looking up %v in table %q by %v of %s", sp.fields, sp.table, sp.keys, ds.Name())
+
+	t := j.getTable(sp.table)
+	return func(ds datasource.DataSource, data datasource.Data) error {
+		r, ok := t.get(eventKey(keyFuncs, data), time.Now())
+		if !ok {
+			return nil
+		}
+		for _, tg := range targets {
+			if err := tg.field.PutString(data, r[tg.name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// addNestedField adds a string field with its parents, like a.b.c, reusing the
+// parents that already exist
+func addNestedField(ds datasource.DataSource, name string, annotations map[string]string) (datasource.FieldAccessor, error) {
+	parts := strings.Split(name, ".")
+
+	var parent datasource.FieldAccessor
+	for i, part := range parts[:len(parts)-1] {
+		f := ds.GetField(strings.Join(parts[:i+1], "."))
+		if f == nil {
+			var err error
+			if parent == nil {
+				f, err = ds.AddField(part, api.Kind_Invalid)
+			} else {
+				f, err = parent.AddSubField(part, api.Kind_Invalid)
+			}
+			if err != nil {
+				return nil, err
+			}
+		} else if f.Type() != api.Kind_Invalid {
+			return nil, fmt.Errorf("field %q already exists and isn't a structure", f.FullName())
+		}
+		parent = f
+	}
+
+	opts := []datasource.FieldOption{datasource.WithAnnotations(annotations)}
+	if parent == nil {
+		return ds.AddField(name, api.Kind_String, opts...)
+	}
+	return parent.AddSubField(parts[len(parts)-1], api.Kind_String, opts...)
+}
+
+func (j *joinOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, funcs := range j.funcs {
+		for _, fn := range funcs {
+			err := ds.Subscribe(fn, Priority)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (j *joinOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (j *joinOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (j *joinOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &joinOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
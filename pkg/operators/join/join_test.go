@@ -0,0 +1,212 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package join
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func TestParseSpecs(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		lookup   bool
+		expected []spec
+		wantErr  bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "store",
+			value: "procs:pid; sockets:netns,port",
+			expected: []spec{
+				{table: "procs", keys: []string{"pid"}},
+				{table: "sockets", keys: []string{"netns", "port"}},
+			},
+		},
+		{
+			name:     "store with data source",
+			value:    "processes:procs:pid",
+			expected: []spec{{dataSource: "processes", table: "procs", keys: []string{"pid"}}},
+		},
+		{
+			name:     "lookup",
+			value:    "procs:proc.pid=parent.pid,parent.comm",
+			lookup:   true,
+			expected: []spec{{table: "procs", keys: []string{"proc.pid"}, fields: []string{"parent.pid", "parent.comm"}}},
+		},
+		{
+			name:    "store with fields",
+			value:   "procs:pid=comm",
+			wantErr: true,
+		},
+		{
+			name:    "lookup without fields",
+			value:   "procs:pid",
+			lookup:  true,
+			wantErr: true,
+		},
+		{
+			name:    "missing keys",
+			value:   "procs",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseSpecs(test.value, test.lookup)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	var procs, tcp datasource.DataSource
+	var procPid, procParentComm, tcpPid, tcpAddr datasource.FieldAccessor
+	var got []string
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		procs, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "processes")
+		require.NoError(t, err)
+		procPid, err = procs.AddField("pid", api.Kind_Uint32)
+		require.NoError(t, err)
+		parent, err := procs.AddField("parent", api.Kind_Invalid)
+		require.NoError(t, err)
+		procParentComm, err = parent.AddSubField("comm", api.Kind_String)
+		require.NoError(t, err)
+
+		tcp, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "tcp")
+		require.NoError(t, err)
+		proc, err := tcp.AddField("proc", api.Kind_Invalid)
+		require.NoError(t, err)
+		tcpPid, err = proc.AddSubField("pid", api.Kind_Uint32)
+		require.NoError(t, err)
+		tcpAddr, err = tcp.AddField("addr", api.Kind_String)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for pid, parentComm := range map[uint32]string{10: "bash", 20: "containerd-shim"} {
+			data, err := procs.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, procPid.PutUint32(data, pid))
+			require.NoError(t, procParentComm.PutString(data, parentComm))
+			require.NoError(t, procs.EmitAndRelease(data))
+		}
+		for _, pid := range []uint32{20, 10, 30} {
+			data, err := tcp.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, tcpPid.PutUint32(data, pid))
+			require.NoError(t, tcpAddr.PutString(data, "1.1.1.1"))
+			require.NoError(t, tcp.EmitAndRelease(data))
+		}
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		parentComm := tcp.GetField("procs.parent.comm")
+		require.NotNil(t, parentComm)
+		tcp.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			v, err := parentComm.String(data)
+			require.NoError(t, err)
+			got = append(got, v)
+			if len(got) == 3 {
+				cancel()
+			}
+			return nil
+		}, Priority+1)
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	op := &joinOperator{maxEntries: 100, ttl: time.Minute}
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(op, producer, consumer))
+	err := gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamStore:  "processes:procs:pid",
+		"operator." + Name + "." + ParamLookup: "procs:proc.pid=parent.comm",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"containerd-shim", "bash", ""}, got)
+	assert.Equal(t, 2, op.getTable("procs").len())
+}
+
+func TestJoinInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		paramValues api.ParamValues
+	}{
+		{
+			name:        "unknown data source",
+			paramValues: api.ParamValues{"operator." + Name + "." + ParamStore: "exec:procs:pid"},
+		},
+		{
+			name:        "unknown key",
+			paramValues: api.ParamValues{"operator." + Name + "." + ParamLookup: "procs:tid=comm"},
+		},
+		{
+			name:        "existing field",
+			paramValues: api.ParamValues{"operator." + Name + "." + ParamLookup: "pid:pid=comm"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			producer := simple.New("producer",
+				simple.WithPriority(Priority-1),
+				simple.OnInit(func(gadgetCtx operators.GadgetContext) error {
+					ds, err := gadgetCtx.RegisterDataSource(datasource.TypeSingle, "processes")
+					require.NoError(t, err)
+					_, err = ds.AddField("pid", api.Kind_Uint32)
+					require.NoError(t, err)
+					return nil
+				}),
+			)
+			op := &joinOperator{maxEntries: 100}
+			gadgetCtx := gadgetcontext.New(context.Background(), "", gadgetcontext.WithDataOperators(op, producer))
+			require.Error(t, gadgetCtx.Run(test.paramValues))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package join
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// row holds the values of the fields of a stored event, by full field name
+type row map[string]string
+
+type entry struct {
+	key     string
+	row     row
+	updated time.Time
+}
+
+// table holds the last row stored for each key. It keeps at most maxEntries
+// rows, evicting the least recently updated ones, and rows expire ttl after
+// they were last updated.
+type table struct {
+	mu sync.Mutex
+
+	maxEntries int
+	ttl        time.Duration
+
+	entries map[string]*list.Element
+	// order holds the entries from the most to the least recently updated
+	order *list.List
+}
+
+func newTable(maxEntries int, ttl time.Duration) *table {
+	return &table{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (t *table) set(key string, r row, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if el, ok := t.entries[key]; ok {
+		e := el.Value.(*entry)
+		e.row = r
+		e.updated = now
+		t.order.MoveToFront(el)
+	} else {
+		t.entries[key] = t.order.PushFront(&entry{key: key, row: r, updated: now})
+	}
+
+	for t.order.Len() > 0 {
+		el := t.order.Back()
+		if t.order.Len() <= t.maxEntries && !t.expired(el.Value.(*entry), now) {
+			break
+		}
+		t.remove(el)
+	}
+}
+
+func (t *table) get(key string, now time.Time) (row, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	el, ok := t.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if t.expired(e, now) {
+		t.remove(el)
+		return nil, false
+	}
+	return e.row, true
+}
+
+func (t *table) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.order.Len()
+}
+
+func (t *table) expired(e *entry, now time.Time) bool {
+	return t.ttl > 0 && now.Sub(e.updated) >= t.ttl
+}
+
+func (t *table) remove(el *list.Element) {
+	t.order.Remove(el)
+	delete(t.entries, el.Value.(*entry).key)
+}
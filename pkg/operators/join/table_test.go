@@ -0,0 +1,59 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package join
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableEviction(t *testing.T) {
+	now := time.Now()
+	tb := newTable(2, 0)
+
+	tb.set("a", row{"v": "1"}, now)
+	tb.set("b", row{"v": "2"}, now)
+	// Updating a moves it to the front, so b is evicted
+	tb.set("a", row{"v": "3"}, now)
+	tb.set("c", row{"v": "4"}, now)
+
+	assert.Equal(t, 2, tb.len())
+	r, ok := tb.get("a", now)
+	assert.True(t, ok)
+	assert.Equal(t, row{"v": "3"}, r)
+	_, ok = tb.get("b", now)
+	assert.False(t, ok)
+	_, ok = tb.get("c", now)
+	assert.True(t, ok)
+}
+
+func TestTableTTL(t *testing.T) {
+	now := time.Now()
+	tb := newTable(10, time.Minute)
+
+	tb.set("a", row{"v": "1"}, now)
+	tb.set("b", row{"v": "2"}, now.Add(30*time.Second))
+
+	_, ok := tb.get("a", now.Add(59*time.Second))
+	assert.True(t, ok)
+	_, ok = tb.get("a", now.Add(time.Minute))
+	assert.False(t, ok)
+
+	// Expired entries are also removed when storing new ones
+	tb.set("c", row{"v": "3"}, now.Add(2*time.Minute))
+	assert.Equal(t, 1, tb.len())
+}
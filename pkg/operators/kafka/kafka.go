@@ -0,0 +1,359 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kafka is a data operator that publishes the events of data sources
+// to Kafka topics, so that gadget instances can feed pipelines like SIEMs
+// directly from the nodes.
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	igjson "github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/formatters/json"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/common"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/routing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	name     = "kafka"
+	Priority = 9999
+
+	ParamKafkaExporter = "kafka-exporter"
+
+	EncodingJSON     = "json"
+	EncodingProtobuf = "protobuf"
+
+	DefaultTopic = "{gadget}.{datasource}"
+
+	HeaderGadget     = "gadget"
+	HeaderDataSource = "datasource"
+	HeaderNode       = "node"
+)
+
+// invalidTopicChars matches the characters that can't be used in the names of
+// Kafka topics
+var invalidTopicChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+type exporterConfig struct {
+	Brokers      []string      `json:"brokers" yaml:"brokers"`
+	Topic        string        `json:"topic" yaml:"topic"`
+	Encoding     string        `json:"encoding" yaml:"encoding"`
+	Compression  string        `json:"compression" yaml:"compression"`
+	BatchSize    int           `json:"batchSize" yaml:"batchSize"`
+	BatchTimeout time.Duration `json:"batchTimeout" yaml:"batchTimeout"`
+}
+
+// messageWriter is implemented by kafka.Writer; it can be replaced for
+// testing
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+type exporter struct {
+	topic    string
+	encoding string
+	writer   messageWriter
+}
+
+func newExporter(exporterName string, cfg *exporterConfig) (*exporter, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("exporter %q: no brokers set", exporterName)
+	}
+
+	e := &exporter{topic: cfg.Topic, encoding: cfg.Encoding}
+	if e.topic == "" {
+		e.topic = DefaultTopic
+	}
+	switch e.encoding {
+	case "":
+		e.encoding = EncodingJSON
+	case EncodingJSON, EncodingProtobuf:
+	default:
+		return nil, fmt.Errorf("exporter %q: unsupported encoding %q; expected %q or %q",
+			exporterName, e.encoding, EncodingJSON, EncodingProtobuf)
+	}
+
+	var compression kafka.Compression
+	if cfg.Compression != "" {
+		if err := compression.UnmarshalText([]byte(cfg.Compression)); err != nil {
+			return nil, fmt.Errorf("exporter %q: unsupported compression %q", exporterName, cfg.Compression)
+		}
+	}
+
+	e.writer = &kafka.Writer{
+		Addr:                   kafka.TCP(cfg.Brokers...),
+		Balancer:               &kafka.Hash{},
+		BatchSize:              cfg.BatchSize,
+		BatchTimeout:           cfg.BatchTimeout,
+		Compression:            compression,
+		AllowAutoTopicCreation: true,
+		// Don't block the data sources while messages are sent
+		Async: true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				log.Errorf("kafka exporter %q: writing %d messages: %v", exporterName, len(messages), err)
+			}
+		},
+	}
+	return e, nil
+}
+
+// expandTopic fills in the placeholders of a topic template; characters that
+// aren't allowed in topic names are replaced with underscores
+func expandTopic(template, gadget, dataSource, node string) string {
+	topic := strings.NewReplacer(
+		"{gadget}", gadget,
+		"{datasource}", dataSource,
+		"{node}", node,
+	).Replace(template)
+	return invalidTopicChars.ReplaceAllString(topic, "_")
+}
+
+type kafkaOperator struct {
+	exporters map[string]*exporter
+	node      string
+}
+
+func (k *kafkaOperator) Name() string {
+	return name
+}
+
+func (k *kafkaOperator) Init(params *params.Params) error {
+	k.exporters = make(map[string]*exporter)
+
+	k.node = os.Getenv("NODE_NAME")
+	if k.node == "" {
+		k.node, _ = os.Hostname()
+	}
+
+	if config.Config == nil {
+		return nil
+	}
+
+	configs := make(map[string]*exporterConfig)
+	log.Debugf("loading kafka exporters")
+	err := config.Config.UnmarshalKey("operator.kafka.exporters", &configs)
+	if err != nil {
+		log.Warnf("failed to load operator.kafka.exporters: %v", err)
+	}
+	for exporterName, cfg := range configs {
+		e, err := newExporter(exporterName, cfg)
+		if err != nil {
+			return err
+		}
+		k.exporters[exporterName] = e
+		log.Debugf("> kafka exporter %q with brokers %v loaded", exporterName, cfg.Brokers)
+	}
+	return nil
+}
+
+func (k *kafkaOperator) GlobalParams() api.Params {
+	return api.Params{}
+}
+
+func (k *kafkaOperator) InstanceParams() api.Params {
+	return api.Params{
+		&api.Param{
+			Key:          ParamKafkaExporter,
+			Description:  "Kafka exporter to publish the events to, as DATASOURCE:EXPORTER or EXPORTER for all data sources",
+			DefaultValue: "",
+		},
+	}
+}
+
+func (k *kafkaOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	if len(k.exporters) == 0 {
+		return nil, nil
+	}
+	mappings, err := apihelpers.GetStringValuesPerDataSource(instanceParamValues[ParamKafkaExporter])
+	if err != nil {
+		return nil, fmt.Errorf("parsing exporter mappings: %w", err)
+	}
+
+	inst := &kafkaOperatorInstance{
+		k:       k,
+		outputs: make(map[datasource.DataSource]*dsOutputs),
+	}
+	gadget := common.GadgetName(gadgetCtx.ImageName())
+	for _, ds := range gadgetCtx.GetDataSources() {
+		exporterName, ok := mappings[ds.Name()]
+		if !ok {
+			exporterName = mappings[""]
+		}
+		if _, ok := k.exporters[exporterName]; exporterName != "" && !ok {
+			return nil, fmt.Errorf("kafka exporter not found: %q", exporterName)
+		}
+		dispatcher := routing.NewDispatcher(gadgetCtx, ds, name, exporterName, func(name string) bool {
+			_, ok := k.exporters[name]
+			return ok
+		})
+		if dispatcher == nil {
+			continue
+		}
+		outs := &dsOutputs{dispatcher: dispatcher, outputs: make(map[string]*output)}
+		for _, exporterName := range dispatcher.Exporters() {
+			e := k.exporters[exporterName]
+			if e.encoding == EncodingProtobuf && exporterName != dispatcher.Mapped() && ds.Type() != datasource.TypeSingle {
+				return nil, fmt.Errorf("route to %q: routing packets of data source %s with the protobuf encoding isn't supported", exporterName, ds.Name())
+			}
+			out := &output{
+				exporter: e,
+				topic:    expandTopic(e.topic, gadget, ds.Name(), k.node),
+				headers: []kafka.Header{
+					{Key: HeaderGadget, Value: []byte(gadgetCtx.ImageName())},
+					{Key: HeaderDataSource, Value: []byte(ds.Name())},
+					{Key: HeaderNode, Value: []byte(k.node)},
+				},
+			}
+			gadgetCtx.Logger().Debugf("publishing %q to kafka topic %q of exporter %q", ds.Name(), out.topic, exporterName)
+			outs.outputs[exporterName] = out
+		}
+		inst.outputs[ds] = outs
+	}
+	if len(inst.outputs) == 0 {
+		return nil, nil
+	}
+	return inst, nil
+}
+
+func (k *kafkaOperator) Priority() int {
+	return Priority
+}
+
+// output is where the events of a data source are published
+type output struct {
+	exporter *exporter
+	topic    string
+	headers  []kafka.Header
+}
+
+// dsOutputs holds the outputs of the exporters the events of a data source
+// are sent to
+type dsOutputs struct {
+	dispatcher *routing.Dispatcher
+	outputs    map[string]*output
+}
+
+type kafkaOperatorInstance struct {
+	k       *kafkaOperator
+	outputs map[datasource.DataSource]*dsOutputs
+}
+
+func (k *kafkaOperatorInstance) Name() string {
+	return name
+}
+
+func (k *kafkaOperatorInstance) write(gadgetCtx operators.GadgetContext, out *output, value []byte) {
+	err := out.exporter.writer.WriteMessages(gadgetCtx.Context(), kafka.Message{
+		Topic:   out.topic,
+		Key:     []byte(k.k.node),
+		Value:   value,
+		Headers: out.headers,
+	})
+	if err != nil {
+		gadgetCtx.Logger().Warnf("publishing to kafka topic %q: %v", out.topic, err)
+	}
+}
+
+func (k *kafkaOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, outs := range k.outputs {
+		encodings := make(map[string]bool)
+		for _, out := range outs.outputs {
+			encodings[out.exporter.encoding] = true
+		}
+		if encodings[EncodingJSON] {
+			formatter, err := igjson.New(ds, igjson.WithShowAll(true))
+			if err != nil {
+				return fmt.Errorf("creating json formatter for %s: %w", ds.Name(), err)
+			}
+			// One message per event; the formatter reuses its buffer
+			ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+				var value []byte
+				for _, exporterName := range outs.dispatcher.Route(data) {
+					out := outs.outputs[exporterName]
+					if out.exporter.encoding != EncodingJSON {
+						continue
+					}
+					if value == nil {
+						value = bytes.Clone(formatter.Marshal(data))
+					}
+					k.write(gadgetCtx, out, value)
+				}
+				return nil
+			}, Priority)
+		}
+		if encodings[EncodingProtobuf] {
+			// One message per packet, as sent over the gRPC API; only packets
+			// of single events are routed
+			ds.SubscribePacket(func(ds datasource.DataSource, packet datasource.Packet) error {
+				exporterNames := []string{outs.dispatcher.Mapped()}
+				if single, ok := packet.(datasource.PacketSingle); ok {
+					exporterNames = outs.dispatcher.Route(single)
+				}
+				var value []byte
+				for _, exporterName := range exporterNames {
+					out, ok := outs.outputs[exporterName]
+					if !ok || out.exporter.encoding != EncodingProtobuf {
+						continue
+					}
+					if value == nil {
+						var err error
+						value, err = proto.Marshal(packet.Raw())
+						if err != nil {
+							gadgetCtx.Logger().Warnf("marshaling packet of %s: %v", ds.Name(), err)
+							return nil
+						}
+					}
+					k.write(gadgetCtx, out, value)
+				}
+				return nil
+			}, Priority)
+		}
+	}
+	return nil
+}
+
+func (k *kafkaOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (k *kafkaOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (k *kafkaOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &kafkaOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+type fakeWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+}
+
+func (f *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, msgs...)
+	return nil
+}
+
+func TestExpandTopic(t *testing.T) {
+	assert.Equal(t, "trace_exec.exec", expandTopic(DefaultTopic, "trace_exec", "exec", "node-1"))
+	assert.Equal(t, "ig-node-1-trace_exec", expandTopic("ig-{node}-{gadget}", "trace_exec", "exec", "node-1"))
+	assert.Equal(t, "ig_node_1.exec", expandTopic("ig/{node}.{datasource}", "trace_exec", "exec", "node:1"))
+}
+
+func TestNewExporter(t *testing.T) {
+	_, err := newExporter("test", &exporterConfig{})
+	require.Error(t, err)
+
+	_, err = newExporter("test", &exporterConfig{Brokers: []string{"127.0.0.1:9092"}, Encoding: "xml"})
+	require.Error(t, err)
+
+	_, err = newExporter("test", &exporterConfig{Brokers: []string{"127.0.0.1:9092"}, Compression: "rar"})
+	require.Error(t, err)
+
+	e, err := newExporter("test", &exporterConfig{Brokers: []string{"127.0.0.1:9092"}, Compression: "zstd"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTopic, e.topic)
+	assert.Equal(t, EncodingJSON, e.encoding)
+	assert.Equal(t, kafka.Zstd, e.writer.(*kafka.Writer).Compression)
+}
+
+func runProducer(t *testing.T, o *kafkaOperator, paramValues api.ParamValues) {
+	var ds datasource.DataSource
+	var comm datasource.FieldAccessor
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "exec")
+		require.NoError(t, err)
+		comm, err = ds.AddField("comm", api.Kind_String)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for _, c := range []string{"bash", "sh"} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, comm.PutString(data, c))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		cancel()
+		return nil
+	}
+
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "ghcr.io/inspektor-gadget/gadget/trace_exec:latest",
+		gadgetcontext.WithDataOperators(o, producer))
+	require.NoError(t, gadgetCtx.Run(paramValues))
+}
+
+func TestPublishJSON(t *testing.T) {
+	writer := &fakeWriter{}
+	o := &kafkaOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"siem": {topic: "ig.{node}.{gadget}", encoding: EncodingJSON, writer: writer},
+		},
+	}
+	runProducer(t, o, api.ParamValues{
+		"operator.kafka." + ParamKafkaExporter: "siem",
+	})
+
+	require.Len(t, writer.messages, 2)
+	for i, c := range []string{"bash", "sh"} {
+		msg := writer.messages[i]
+		assert.Equal(t, "ig.node-1.trace_exec", msg.Topic)
+		assert.Equal(t, []byte("node-1"), msg.Key)
+		assert.JSONEq(t, `{"comm":"`+c+`"}`, string(msg.Value))
+		assert.Contains(t, msg.Headers, kafka.Header{Key: HeaderDataSource, Value: []byte("exec")})
+		assert.Contains(t, msg.Headers, kafka.Header{Key: HeaderNode, Value: []byte("node-1")})
+	}
+}
+
+func TestPublishProtobuf(t *testing.T) {
+	writer := &fakeWriter{}
+	o := &kafkaOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"siem": {topic: DefaultTopic, encoding: EncodingProtobuf, writer: writer},
+		},
+	}
+	runProducer(t, o, api.ParamValues{
+		"operator.kafka." + ParamKafkaExporter: "exec:siem",
+	})
+
+	require.Len(t, writer.messages, 2)
+	for i, c := range []string{"bash", "sh"} {
+		msg := writer.messages[i]
+		assert.Equal(t, "trace_exec.exec", msg.Topic)
+		gadgetData := &api.GadgetData{}
+		require.NoError(t, proto.Unmarshal(msg.Value, gadgetData))
+		assert.Equal(t, []byte(c), gadgetData.Data.Payload[0])
+	}
+}
+
+func TestNoExporter(t *testing.T) {
+	writer := &fakeWriter{}
+	o := &kafkaOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"siem": {topic: DefaultTopic, encoding: EncodingJSON, writer: writer},
+		},
+	}
+	runProducer(t, o, api.ParamValues{})
+	assert.Empty(t, writer.messages)
+}
@@ -13,15 +13,21 @@
 // limitations under the License.
 
 // Package kubeipresolver provides an operator that enriches events by looking
-// up IP addresses in Kubernetes resources such as pods and services.
+// up IP addresses in Kubernetes resources such as pods and services. It can
+// also tell whether the NetworkPolicies allow the flows between endpoints.
 package kubeipresolver
 
 import (
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 
+	"github.com/expr-lang/expr/vm"
+	v1 "k8s.io/api/core/v1"
+
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/expr"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
@@ -33,6 +39,13 @@ import (
 const (
 	OperatorName = "KubeIPResolver"
 	Priority     = 10
+
+	ParamNetworkPolicies = "kubeipresolver-network-policies"
+
+	// AnnotationIncoming is the data source annotation with a filter
+	// expression telling whether the connection of an event was initiated by
+	// the dst endpoint, like `type == "accept"`
+	AnnotationIncoming = "kubeipresolver.incoming"
 )
 
 const (
@@ -44,7 +57,9 @@ type KubeIPResolverInterface interface {
 	GetEndpoints() []*types.L3Endpoint
 }
 
-type KubeIPResolver struct{}
+type KubeIPResolver struct {
+	networkPolicies bool
+}
 
 func (k *KubeIPResolver) Name() string {
 	return OperatorName
@@ -55,6 +70,9 @@ func (k *KubeIPResolver) Description() string {
 }
 
 func (k *KubeIPResolver) Init(params *params.Params) error {
+	if params != nil {
+		k.networkPolicies = params.Get(ParamNetworkPolicies).AsBool()
+	}
 	return nil
 }
 
@@ -67,6 +85,9 @@ type KubeIPResolverInstance struct {
 	k8sInventory       common.K8sInventoryCache
 	gadgetInstance     any
 	endpointsAccessors map[datasource.DataSource][]endpointAccessors
+
+	netpolCache     common.NetworkPolicyCache
+	netpolAccessors map[datasource.DataSource]*netpolAccessors
 }
 
 func (m *KubeIPResolverInstance) Name() string {
@@ -116,7 +137,16 @@ func (m *KubeIPResolverInstance) EnrichEvent(ev any) error {
 }
 
 func (k *KubeIPResolver) GlobalParams() api.Params {
-	return nil
+	return api.Params{
+		{
+			Key:   ParamNetworkPolicies,
+			Title: "Network Policies",
+			Description: "Tell whether the NetworkPolicies allow the flows from the src to the dst endpoints of the events. " +
+				"It requires permissions to list and watch NetworkPolicies and namespaces.",
+			DefaultValue: "false",
+			TypeHint:     api.TypeBool,
+		},
+	}
 }
 
 func (k *KubeIPResolver) InstanceParams() api.Params {
@@ -125,6 +155,9 @@ func (k *KubeIPResolver) InstanceParams() api.Params {
 
 type endpointAccessors struct {
 	root            datasource.FieldAccessor
+	ip              datasource.FieldAccessor
+	version         datasource.FieldAccessor
+	proto           datasource.FieldAccessor
 	subK8sKind      datasource.FieldAccessor
 	subK8sName      datasource.FieldAccessor
 	subK8sNamespace datasource.FieldAccessor
@@ -212,8 +245,16 @@ func (k *KubeIPResolver) InstantiateDataOperator(gadgetCtx operators.GadgetConte
 				portAcc = p[0]
 			}
 
+			var protoAcc datasource.FieldAccessor
+			if p := ep.GetSubFieldsWithTag("name:proto_raw"); len(p) == 1 && p[0].Size() == 2 {
+				protoAcc = p[0]
+			}
+
 			ea := endpointAccessors{
 				root:            ep,
+				ip:              ips[0],
+				version:         version[0],
+				proto:           protoAcc,
 				subK8sKind:      k8sKindAcc,
 				subK8sName:      k8sNameAcc,
 				subK8sNamespace: k8sNamespaceAcc,
@@ -235,10 +276,93 @@ func (k *KubeIPResolver) InstantiateDataOperator(gadgetCtx operators.GadgetConte
 		return nil, fmt.Errorf("creating k8s inventory cache: %w", err)
 	}
 
-	return &KubeIPResolverInstance{
+	instance := &KubeIPResolverInstance{
 		k8sInventory:       k8sInventory,
 		endpointsAccessors: epAccessors,
-	}, nil
+	}
+
+	if k.networkPolicies {
+		instance.netpolAccessors = make(map[datasource.DataSource]*netpolAccessors)
+		for ds, acc := range epAccessors {
+			na, err := newNetpolAccessors(ds, acc)
+			if err != nil {
+				return nil, fmt.Errorf("datasource %s: %w", ds.Name(), err)
+			}
+			if na == nil {
+				logger.Debugf("> no src and dst endpoints to evaluate network policies")
+				continue
+			}
+			instance.netpolAccessors[ds] = na
+		}
+		if len(instance.netpolAccessors) > 0 {
+			instance.netpolCache, err = common.GetNetworkPolicyCache()
+			if err != nil {
+				return nil, fmt.Errorf("creating network policy cache: %w", err)
+			}
+		}
+	}
+
+	return instance, nil
+}
+
+// netpolAccessors holds the endpoints of a flow, as indexes of the endpoint
+// accessors of a data source, and the fields with the verdict of the
+// NetworkPolicies
+type netpolAccessors struct {
+	src, dst int
+	incoming *vm.Program
+
+	verdict  datasource.FieldAccessor
+	policies datasource.FieldAccessor
+}
+
+// newNetpolAccessors adds the netpol fields to a data source with src and dst
+// endpoints, or returns nil if it doesn't have them
+func newNetpolAccessors(ds datasource.DataSource, acc []endpointAccessors) (*netpolAccessors, error) {
+	na := &netpolAccessors{src: -1, dst: -1}
+	for i, a := range acc {
+		switch a.root.FullName() {
+		case "src":
+			na.src = i
+		case "dst":
+			na.dst = i
+		}
+	}
+	if na.src < 0 || na.dst < 0 {
+		return nil, nil
+	}
+
+	if incoming := strings.TrimSpace(ds.Annotations()[AnnotationIncoming]); incoming != "" {
+		prog, err := expr.CompileFilterProgram(ds, incoming)
+		if err != nil {
+			return nil, fmt.Errorf("compiling %s annotation: %w", AnnotationIncoming, err)
+		}
+		na.incoming = prog
+	}
+
+	netpol, err := ds.AddField("netpol", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+	if err != nil {
+		return nil, fmt.Errorf("adding field %q: %w", "netpol", err)
+	}
+	na.verdict, err = netpol.AddSubField("verdict", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation:  "Whether the NetworkPolicies allow the flow: allowed or denied",
+			metadatav1.ColumnsWidthAnnotation: "7",
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("adding field %q: %w", "verdict", err)
+	}
+	na.policies, err = netpol.AddSubField("policies", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation: "NetworkPolicies allowing the flow, or isolating the pods if it's denied",
+		}),
+		datasource.WithFlags(datasource.FieldFlagHidden),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("adding field %q: %w", "policies", err)
+	}
+	return na, nil
 }
 
 func (k *KubeIPResolver) Priority() int {
@@ -247,27 +371,32 @@ func (k *KubeIPResolver) Priority() int {
 
 func (m *KubeIPResolverInstance) PreStart(gadgetCtx operators.GadgetContext) error {
 	m.k8sInventory.Start()
+	if m.netpolCache != nil {
+		m.netpolCache.Start()
+	}
 	return nil
 }
 
 func (m *KubeIPResolverInstance) Start(gadgetCtx operators.GadgetContext) error {
+	evaluator := &netpolEvaluator{cache: m.netpolCache}
 	for ds, acc := range m.endpointsAccessors {
+		na := m.netpolAccessors[ds]
 		ds.Subscribe(func(source datasource.DataSource, data datasource.Data) error {
 			var errs error
-			for _, a := range acc {
-				ip := a.root.GetSubFieldsWithTag("type:" + ipAddrType)[0]
-				version := a.root.GetSubFieldsWithTag("name:version")[0]
-				addrStr, err := common.GetIPForVersion(data, version, ip)
+			peers := make([]peer, len(acc))
+			for i, a := range acc {
+				addrStr, err := common.GetIPForVersion(data, a.version, a.ip)
 				if err != nil {
 					errors.Join(errs, fmt.Errorf("%s: getting IP: %w", a.root.Name(), err))
 					continue
 				}
+				peers[i].ip = net.ParseIP(addrStr)
 
 				pod := m.k8sInventory.GetPodByIp(addrStr)
 				if pod != nil {
-					if pod.Spec.HostNetwork {
-						continue
-					}
+					peers[i].pod = pod
+				}
+				if pod != nil && !pod.Spec.HostNetwork {
 					a.subK8sName.Set(data, []byte(pod.Name))
 					a.subK8sKind.Set(data, []byte("pod"))
 					a.subK8sNamespace.Set(data, []byte(pod.Namespace))
@@ -285,8 +414,15 @@ func (m *KubeIPResolverInstance) Start(gadgetCtx operators.GadgetContext) error
 					continue
 				}
 
+				// Services are reachable at their cluster, external and load
+				// balancer IPs, and at their node ports on the IPs of the nodes
 				svc := m.k8sInventory.GetSvcByIp(addrStr)
+				if svc == nil && a.port != nil {
+					p, _ := a.port.Uint16(data)
+					svc = m.k8sInventory.GetSvcByNodePort(addrStr, p)
+				}
 				if svc != nil {
+					peers[i].service = true
 					a.subK8sKind.Set(data, []byte("svc"))
 					a.subK8sName.Set(data, []byte(svc.Name))
 					a.subK8sNamespace.Set(data, []byte(svc.Namespace))
@@ -312,14 +448,47 @@ func (m *KubeIPResolverInstance) Start(gadgetCtx operators.GadgetContext) error
 					a.column.Set(data, []byte(v))
 				}
 			}
+
+			if na != nil {
+				verdict, policies := evaluator.evaluate(na.flow(data, acc, peers))
+				na.verdict.PutString(data, verdict)
+				na.policies.PutString(data, formatPolicies(policies))
+			}
 			return errs
 		}, Priority)
 	}
 	return nil
 }
 
+// flow returns the flow of an event, from the client to the server
+func (na *netpolAccessors) flow(data datasource.Data, acc []endpointAccessors, peers []peer) *flow {
+	client, server := na.src, na.dst
+	if na.incoming != nil {
+		if ret, err := expr.Run(na.incoming, data); err == nil && ret == true {
+			client, server = server, client
+		}
+	}
+
+	f := &flow{
+		from:     peers[client],
+		to:       peers[server],
+		protocol: v1.ProtocolTCP,
+	}
+	if a := acc[server]; a.port != nil {
+		f.port, _ = a.port.Uint16(data)
+	}
+	if a := acc[server]; a.proto != nil {
+		proto, _ := a.proto.Uint16(data)
+		f.protocol = protocolFromNumber(proto)
+	}
+	return f
+}
+
 func (m *KubeIPResolverInstance) PostStop(gadgetCtx operators.GadgetContext) error {
 	m.k8sInventory.Stop()
+	if m.netpolCache != nil {
+		m.netpolCache.Stop()
+	}
 	return nil
 }
 
@@ -0,0 +1,216 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeipresolver
+
+import (
+	"net"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/common"
+)
+
+const (
+	verdictAllowed = "allowed"
+	verdictDenied  = "denied"
+)
+
+// peer is one of the endpoints of a flow. pod is nil if the IP address isn't
+// the one of a pod and service is true if it's the one of a service.
+type peer struct {
+	ip      net.IP
+	pod     *common.SlimPod
+	service bool
+}
+
+// isolatable returns whether NetworkPolicies can apply to the peer, which is
+// only the case for pods not using the host network
+func (p *peer) isolatable() bool {
+	return p.pod != nil && !p.pod.Spec.HostNetwork
+}
+
+// flow is a connection from a client to a server on a port of the server
+type flow struct {
+	from, to peer
+	protocol v1.Protocol
+	port     uint16
+}
+
+// protocolFromNumber returns the protocol of NetworkPolicies for an IP
+// protocol number
+func protocolFromNumber(n uint16) v1.Protocol {
+	switch n {
+	case 17:
+		return v1.ProtocolUDP
+	case 132:
+		return v1.ProtocolSCTP
+	default:
+		return v1.ProtocolTCP
+	}
+}
+
+// sideResult is the result of the policies isolating one side of a flow
+type sideResult struct {
+	isolated  bool
+	allowedBy []string
+	isolating []string
+}
+
+func (r *sideResult) allowed() bool {
+	return !r.isolated || len(r.allowedBy) > 0
+}
+
+type netpolEvaluator struct {
+	cache common.NetworkPolicyCache
+}
+
+// evaluate returns whether the NetworkPolicies allow a flow and which
+// policies allow it, or which ones isolate the pods and deny it. The verdict
+// is empty if no pod that can be isolated is part of the flow, or if the
+// server is a service, as the policies apply to the pods backing it.
+func (e *netpolEvaluator) evaluate(f *flow) (string, []string) {
+	if !f.from.isolatable() && !f.to.isolatable() || f.to.service {
+		return "", nil
+	}
+
+	var egress, ingress sideResult
+	if f.from.isolatable() {
+		egress = e.evaluateSide(f.from.pod, &f.to, f, true)
+	}
+	if f.to.isolatable() {
+		ingress = e.evaluateSide(f.to.pod, &f.from, f, false)
+	}
+
+	if egress.allowed() && ingress.allowed() {
+		return verdictAllowed, union(egress.allowedBy, ingress.allowedBy)
+	}
+	var policies []string
+	if !egress.allowed() {
+		policies = append(policies, egress.isolating...)
+	}
+	if !ingress.allowed() {
+		policies = append(policies, ingress.isolating...)
+	}
+	return verdictDenied, union(policies)
+}
+
+// evaluateSide checks the egress policies of the client pod or the ingress
+// policies of the server pod against the other peer of the flow
+func (e *netpolEvaluator) evaluateSide(pod *common.SlimPod, other *peer, f *flow, egress bool) sideResult {
+	var res sideResult
+	podLabels := labels.Set(pod.Labels)
+	for _, np := range e.cache.GetNetworkPolicies(pod.Namespace) {
+		rules := np.IngressRules
+		if egress {
+			rules = np.EgressRules
+		}
+		if egress && !np.Egress || !egress && !np.Ingress || !np.PodSelector.Matches(podLabels) {
+			continue
+		}
+		res.isolated = true
+		res.isolating = append(res.isolating, np.Key())
+		for _, rule := range rules {
+			if e.ruleMatches(np, &rule, other, f) {
+				res.allowedBy = append(res.allowedBy, np.Key())
+				break
+			}
+		}
+	}
+	return res
+}
+
+func (e *netpolEvaluator) ruleMatches(np *common.NetworkPolicy, rule *common.NetworkPolicyRule, other *peer, f *flow) bool {
+	portMatches := len(rule.Ports) == 0
+	for _, port := range rule.Ports {
+		if portMatchesFlow(&port, f) {
+			portMatches = true
+			break
+		}
+	}
+	if !portMatches {
+		return false
+	}
+
+	if len(rule.Peers) == 0 {
+		return true
+	}
+	for _, p := range rule.Peers {
+		if e.peerMatches(np, &p, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// portMatchesFlow returns whether a port of a rule matches the port of the
+// server of a flow. Named ports never match, as the ports of the containers
+// aren't known.
+func portMatchesFlow(port *common.NetworkPolicyPort, f *flow) bool {
+	if port.Protocol != f.protocol || port.NamedPort != "" {
+		return false
+	}
+	if port.Port == 0 {
+		return true
+	}
+	if port.EndPort == 0 {
+		return int32(f.port) == port.Port
+	}
+	return int32(f.port) >= port.Port && int32(f.port) <= port.EndPort
+}
+
+func (e *netpolEvaluator) peerMatches(np *common.NetworkPolicy, p *common.NetworkPolicyPeer, other *peer) bool {
+	if p.IPBlock != nil {
+		if other.ip == nil || !p.IPBlock.CIDR.Contains(other.ip) {
+			return false
+		}
+		for _, except := range p.IPBlock.Except {
+			if except.Contains(other.ip) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !other.isolatable() {
+		return false
+	}
+	if p.NamespaceSelector == nil {
+		// Only pods of the namespace of the policy
+		return other.pod.Namespace == np.Namespace && p.PodSelector.Matches(labels.Set(other.pod.Labels))
+	}
+	nsLabels := labels.Set(e.cache.GetNamespaceLabels(other.pod.Namespace))
+	if !p.NamespaceSelector.Matches(nsLabels) {
+		return false
+	}
+	return p.PodSelector == nil || p.PodSelector.Matches(labels.Set(other.pod.Labels))
+}
+
+// union returns the sorted unique policy names of the given lists
+func union(lists ...[]string) []string {
+	var ret []string
+	for _, l := range lists {
+		ret = append(ret, l...)
+	}
+	slices.Sort(ret)
+	return slices.Compact(ret)
+}
+
+// formatPolicies returns the policy names separated by commas
+func formatPolicies(policies []string) string {
+	return strings.Join(policies, ",")
+}
@@ -0,0 +1,225 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeipresolver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/common"
+)
+
+type fakeNetworkPolicyCache struct {
+	policies        map[string][]*common.NetworkPolicy
+	namespaceLabels map[string]map[string]string
+}
+
+func (f *fakeNetworkPolicyCache) Start() {}
+func (f *fakeNetworkPolicyCache) Stop()  {}
+
+func (f *fakeNetworkPolicyCache) GetNetworkPolicies(namespace string) []*common.NetworkPolicy {
+	return f.policies[namespace]
+}
+
+func (f *fakeNetworkPolicyCache) GetNamespaceLabels(namespace string) map[string]string {
+	return f.namespaceLabels[namespace]
+}
+
+func newFakeCache(t *testing.T, policies ...*networkingv1.NetworkPolicy) *fakeNetworkPolicyCache {
+	cache := &fakeNetworkPolicyCache{
+		policies: make(map[string][]*common.NetworkPolicy),
+		namespaceLabels: map[string]map[string]string{
+			"frontend": {"team": "web"},
+			"backend":  {"team": "api"},
+		},
+	}
+	for _, p := range policies {
+		np, err := common.NewNetworkPolicy(p)
+		require.NoError(t, err)
+		cache.policies[p.Namespace] = append(cache.policies[p.Namespace], np)
+	}
+	return cache
+}
+
+func pod(namespace, name, ip string, labels map[string]string) peer {
+	p := &common.SlimPod{}
+	p.Namespace = namespace
+	p.Name = name
+	p.Labels = labels
+	p.Status.PodIP = ip
+	return peer{ip: net.ParseIP(ip), pod: p}
+}
+
+func port(p int32) *intstr.IntOrString {
+	v := intstr.FromInt32(p)
+	return &v
+}
+
+func TestNetpolEvaluator(t *testing.T) {
+	t.Parallel()
+
+	web := pod("frontend", "web", "10.0.0.1", map[string]string{"app": "web"})
+	api := pod("backend", "api", "10.0.0.2", map[string]string{"app": "api"})
+	db := pod("backend", "db", "10.0.0.3", map[string]string{"app": "db"})
+	external := peer{ip: net.ParseIP("1.1.1.1")}
+
+	denyAllIngress := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "backend", Name: "deny-all"},
+	}
+	allowWebToAPI := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "backend", Name: "allow-web"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+				}},
+				Ports: []networkingv1.NetworkPolicyPort{{Port: port(8080)}},
+			}},
+		},
+	}
+	allowAPIToDB := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "backend", Name: "allow-api"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{
+					PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				}},
+			}},
+		},
+	}
+	egressToInternet := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "frontend", Name: "egress"},
+		Spec: networkingv1.NetworkPolicySpec{
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{{
+				To: []networkingv1.NetworkPolicyPeer{{
+					IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0", Except: []string{"10.0.0.0/8"}},
+				}},
+			}},
+		},
+	}
+
+	type testCase struct {
+		policies         []*networkingv1.NetworkPolicy
+		flow             flow
+		expectedVerdict  string
+		expectedPolicies []string
+	}
+
+	tests := map[string]testCase{
+		"no_policies": {
+			flow:            flow{from: web, to: api, protocol: v1.ProtocolTCP, port: 8080},
+			expectedVerdict: verdictAllowed,
+		},
+		"no_pods": {
+			policies: []*networkingv1.NetworkPolicy{denyAllIngress},
+			flow:     flow{from: external, to: peer{ip: net.ParseIP("8.8.8.8")}, protocol: v1.ProtocolTCP, port: 53},
+		},
+		"service": {
+			policies: []*networkingv1.NetworkPolicy{denyAllIngress},
+			flow:     flow{from: web, to: peer{ip: net.ParseIP("10.96.0.1"), service: true}, protocol: v1.ProtocolTCP, port: 443},
+		},
+		"denied_by_default_deny": {
+			policies:         []*networkingv1.NetworkPolicy{denyAllIngress},
+			flow:             flow{from: web, to: api, protocol: v1.ProtocolTCP, port: 8080},
+			expectedVerdict:  verdictDenied,
+			expectedPolicies: []string{"backend/deny-all"},
+		},
+		"allowed_by_namespace_selector": {
+			policies:         []*networkingv1.NetworkPolicy{denyAllIngress, allowWebToAPI},
+			flow:             flow{from: web, to: api, protocol: v1.ProtocolTCP, port: 8080},
+			expectedVerdict:  verdictAllowed,
+			expectedPolicies: []string{"backend/allow-web"},
+		},
+		"denied_other_port": {
+			policies:         []*networkingv1.NetworkPolicy{denyAllIngress, allowWebToAPI},
+			flow:             flow{from: web, to: api, protocol: v1.ProtocolTCP, port: 22},
+			expectedVerdict:  verdictDenied,
+			expectedPolicies: []string{"backend/allow-web", "backend/deny-all"},
+		},
+		"denied_other_protocol": {
+			policies:         []*networkingv1.NetworkPolicy{allowWebToAPI},
+			flow:             flow{from: web, to: api, protocol: v1.ProtocolUDP, port: 8080},
+			expectedVerdict:  verdictDenied,
+			expectedPolicies: []string{"backend/allow-web"},
+		},
+		"allowed_by_pod_selector": {
+			policies:         []*networkingv1.NetworkPolicy{allowAPIToDB},
+			flow:             flow{from: api, to: db, protocol: v1.ProtocolTCP, port: 5432},
+			expectedVerdict:  verdictAllowed,
+			expectedPolicies: []string{"backend/allow-api"},
+		},
+		"pod_selector_other_namespace": {
+			policies: []*networkingv1.NetworkPolicy{allowAPIToDB},
+			flow: flow{
+				from:     pod("frontend", "api", "10.0.0.4", map[string]string{"app": "api"}),
+				to:       db,
+				protocol: v1.ProtocolTCP,
+				port:     5432,
+			},
+			expectedVerdict:  verdictDenied,
+			expectedPolicies: []string{"backend/allow-api"},
+		},
+		"egress_allowed_by_ip_block": {
+			policies:         []*networkingv1.NetworkPolicy{egressToInternet},
+			flow:             flow{from: web, to: external, protocol: v1.ProtocolTCP, port: 443},
+			expectedVerdict:  verdictAllowed,
+			expectedPolicies: []string{"frontend/egress"},
+		},
+		"egress_denied_by_except": {
+			policies:         []*networkingv1.NetworkPolicy{egressToInternet},
+			flow:             flow{from: web, to: api, protocol: v1.ProtocolTCP, port: 8080},
+			expectedVerdict:  verdictDenied,
+			expectedPolicies: []string{"frontend/egress"},
+		},
+		"ingress_not_isolated_by_egress_policy": {
+			policies:        []*networkingv1.NetworkPolicy{egressToInternet},
+			flow:            flow{from: external, to: web, protocol: v1.ProtocolTCP, port: 80},
+			expectedVerdict: verdictAllowed,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			e := &netpolEvaluator{cache: newFakeCache(t, test.policies...)}
+			verdict, policies := e.evaluate(&test.flow)
+			require.Equal(t, test.expectedVerdict, verdict)
+			require.Equal(t, test.expectedPolicies, policies)
+		})
+	}
+}
+
+func TestPortMatchesFlow(t *testing.T) {
+	t.Parallel()
+
+	f := &flow{protocol: v1.ProtocolTCP, port: 8080}
+	require.True(t, portMatchesFlow(&common.NetworkPolicyPort{Protocol: v1.ProtocolTCP}, f))
+	require.True(t, portMatchesFlow(&common.NetworkPolicyPort{Protocol: v1.ProtocolTCP, Port: 8080}, f))
+	require.True(t, portMatchesFlow(&common.NetworkPolicyPort{Protocol: v1.ProtocolTCP, Port: 8000, EndPort: 9000}, f))
+	require.False(t, portMatchesFlow(&common.NetworkPolicyPort{Protocol: v1.ProtocolTCP, Port: 8000, EndPort: 8079}, f))
+	require.False(t, portMatchesFlow(&common.NetworkPolicyPort{Protocol: v1.ProtocolUDP, Port: 8080}, f))
+	require.False(t, portMatchesFlow(&common.NetworkPolicyPort{Protocol: v1.ProtocolTCP, NamedPort: "http"}, f))
+}
@@ -201,6 +201,12 @@ func (k *KubeManager) Close() error {
 	return nil
 }
 
+// ContainerCollection returns the containers of the node; it's nil until the
+// operator is initialized
+func (k *KubeManager) ContainerCollection() *containercollection.ContainerCollection {
+	return k.containerCollection
+}
+
 type KubeManagerInstance struct {
 	id           string
 	manager      *KubeManager
@@ -308,6 +308,12 @@ func (l *localManager) Close() error {
 	return nil
 }
 
+// ContainerCollection returns the containers of the host; it's nil until the
+// operator is initialized or if no container runtime was found
+func (l *localManager) ContainerCollection() *containercollection.ContainerCollection {
+	return l.containerCollection
+}
+
 type localManagerTrace struct {
 	manager         *localManager
 	mountnsmap      *ebpf.Map
@@ -0,0 +1,416 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package loki is a data operator that pushes the events of data sources to
+// Loki as log lines, so that they can be queried in Grafana alongside the logs
+// of applications.
+package loki
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	igjson "github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/formatters/json"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/common"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/routing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	name     = "loki"
+	Priority = 9999
+
+	ParamLokiExporter = "loki-exporter"
+	ParamLokiLabels   = "loki-labels"
+
+	LabelGadget     = "gadget"
+	LabelDataSource = "datasource"
+	LabelNode       = "node"
+
+	PushPath = "/loki/api/v1/push"
+
+	DefaultBatchSize  = 1000
+	DefaultBatchWait  = time.Second
+	DefaultMaxRetries = 5
+	DefaultTimeout    = 10 * time.Second
+)
+
+// DefaultLabels are the labels taken from the fields of events when an
+// exporter doesn't configure any
+var DefaultLabels = map[string]string{
+	"namespace": "k8s.namespace",
+	"pod":       "k8s.podName",
+	"container": "k8s.containerName",
+}
+
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type exporterConfig struct {
+	URL        string            `json:"url" yaml:"url"`
+	TenantID   string            `json:"tenantID" yaml:"tenantID"`
+	Username   string            `json:"username" yaml:"username"`
+	Password   string            `json:"password" yaml:"password"`
+	Labels     map[string]string `json:"labels" yaml:"labels"`
+	BatchSize  int               `json:"batchSize" yaml:"batchSize"`
+	BatchWait  time.Duration     `json:"batchWait" yaml:"batchWait"`
+	MaxRetries *int              `json:"maxRetries" yaml:"maxRetries"`
+	Timeout    time.Duration     `json:"timeout" yaml:"timeout"`
+}
+
+type exporter struct {
+	// labels maps label names to the fields they are taken from
+	labels map[string]string
+	sender entrySender
+}
+
+// pushURL returns the URL of the push API; the path is added if the URL
+// doesn't have one
+func pushURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing url %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid url %q: expected http:// or https://", rawURL)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid url %q: missing host", rawURL)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = PushPath
+	}
+	return u.String(), nil
+}
+
+func validateLabels(labels map[string]string) error {
+	for label, field := range labels {
+		if !labelNameRegex.MatchString(label) {
+			return fmt.Errorf("invalid label name %q", label)
+		}
+		switch label {
+		case LabelGadget, LabelDataSource, LabelNode:
+			return fmt.Errorf("label %q is reserved", label)
+		}
+		if field == "" {
+			return fmt.Errorf("label %q: missing field", label)
+		}
+	}
+	return nil
+}
+
+func newExporter(exporterName string, cfg *exporterConfig) (*exporter, error) {
+	u, err := pushURL(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("exporter %q: %w", exporterName, err)
+	}
+	e := &exporter{labels: cfg.Labels}
+	if e.labels == nil {
+		e.labels = DefaultLabels
+	}
+	if err := validateLabels(e.labels); err != nil {
+		return nil, fmt.Errorf("exporter %q: %w", exporterName, err)
+	}
+	if cfg.BatchSize < 0 || cfg.BatchWait < 0 || cfg.Timeout < 0 || (cfg.MaxRetries != nil && *cfg.MaxRetries < 0) {
+		return nil, fmt.Errorf("exporter %q: batchSize, batchWait, maxRetries and timeout can't be negative", exporterName)
+	}
+
+	p := &pusher{
+		name:       exporterName,
+		url:        u,
+		tenantID:   cfg.TenantID,
+		username:   cfg.Username,
+		password:   cfg.Password,
+		batchSize:  cfg.BatchSize,
+		batchWait:  cfg.BatchWait,
+		maxRetries: DefaultMaxRetries,
+	}
+	if p.batchSize == 0 {
+		p.batchSize = DefaultBatchSize
+	}
+	if p.batchWait == 0 {
+		p.batchWait = DefaultBatchWait
+	}
+	if cfg.MaxRetries != nil {
+		p.maxRetries = *cfg.MaxRetries
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	p.client = &http.Client{Timeout: timeout}
+	p.start()
+	e.sender = p
+	return e, nil
+}
+
+// parseLabels parses labels like "label=field,label2=field2"
+func parseLabels(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	labels := make(map[string]string)
+	for _, v := range strings.Split(s, ",") {
+		label, field, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label %q: expected LABEL=FIELD", v)
+		}
+		labels[strings.TrimSpace(label)] = strings.TrimSpace(field)
+	}
+	if err := validateLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+type lokiOperator struct {
+	exporters map[string]*exporter
+	node      string
+}
+
+func (l *lokiOperator) Name() string {
+	return name
+}
+
+func (l *lokiOperator) Init(params *params.Params) error {
+	l.exporters = make(map[string]*exporter)
+
+	l.node = os.Getenv("NODE_NAME")
+	if l.node == "" {
+		l.node, _ = os.Hostname()
+	}
+
+	if config.Config == nil {
+		return nil
+	}
+
+	configs := make(map[string]*exporterConfig)
+	log.Debugf("loading loki exporters")
+	err := config.Config.UnmarshalKey("operator.loki.exporters", &configs)
+	if err != nil {
+		log.Warnf("failed to load operator.loki.exporters: %v", err)
+	}
+	for exporterName, cfg := range configs {
+		e, err := newExporter(exporterName, cfg)
+		if err != nil {
+			return err
+		}
+		l.exporters[exporterName] = e
+		log.Debugf("> loki exporter %q pushing to %q loaded", exporterName, cfg.URL)
+	}
+	return nil
+}
+
+func (l *lokiOperator) GlobalParams() api.Params {
+	return api.Params{}
+}
+
+func (l *lokiOperator) InstanceParams() api.Params {
+	return api.Params{
+		&api.Param{
+			Key:          ParamLokiExporter,
+			Description:  "Loki exporter to push the events to, as DATASOURCE:EXPORTER or EXPORTER for all data sources",
+			DefaultValue: "",
+		},
+		&api.Param{
+			Key: ParamLokiLabels,
+			Description: "Labels to take from the fields of the events, like namespace=k8s.namespace,pod=k8s.podName, " +
+				"overriding the ones of the exporter",
+			DefaultValue: "",
+		},
+	}
+}
+
+func (l *lokiOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	if len(l.exporters) == 0 {
+		return nil, nil
+	}
+	mappings, err := apihelpers.GetStringValuesPerDataSource(instanceParamValues[ParamLokiExporter])
+	if err != nil {
+		return nil, fmt.Errorf("parsing exporter mappings: %w", err)
+	}
+	labels, err := parseLabels(instanceParamValues[ParamLokiLabels])
+	if err != nil {
+		return nil, fmt.Errorf("parsing labels: %w", err)
+	}
+
+	inst := &lokiOperatorInstance{
+		outputs: make(map[datasource.DataSource]*dsOutputs),
+	}
+	gadget := common.GadgetName(gadgetCtx.ImageName())
+	for _, ds := range gadgetCtx.GetDataSources() {
+		exporterName, ok := mappings[ds.Name()]
+		if !ok {
+			exporterName = mappings[""]
+		}
+		if _, ok := l.exporters[exporterName]; exporterName != "" && !ok {
+			return nil, fmt.Errorf("loki exporter not found: %q", exporterName)
+		}
+		dispatcher := routing.NewDispatcher(gadgetCtx, ds, name, exporterName, func(name string) bool {
+			_, ok := l.exporters[name]
+			return ok
+		})
+		if dispatcher == nil {
+			continue
+		}
+		outs := &dsOutputs{dispatcher: dispatcher, outputs: make(map[string]*output)}
+		for _, exporterName := range dispatcher.Exporters() {
+			e := l.exporters[exporterName]
+			out := &output{
+				exporter: e,
+				labels:   e.labels,
+				static: map[string]string{
+					LabelGadget:     gadget,
+					LabelDataSource: ds.Name(),
+					LabelNode:       l.node,
+				},
+			}
+			if labels != nil {
+				out.labels = labels
+			}
+			gadgetCtx.Logger().Debugf("pushing %q to loki exporter %q", ds.Name(), exporterName)
+			outs.outputs[exporterName] = out
+		}
+		inst.outputs[ds] = outs
+	}
+	if len(inst.outputs) == 0 {
+		return nil, nil
+	}
+	return inst, nil
+}
+
+func (l *lokiOperator) Priority() int {
+	return Priority
+}
+
+// output is where the events of a data source are pushed to
+type output struct {
+	exporter *exporter
+
+	// labels maps label names to the fields they are taken from
+	labels map[string]string
+
+	// static holds the labels that are the same for all events
+	static map[string]string
+}
+
+// dsOutputs holds the outputs of the exporters the events of a data source
+// are pushed to
+type dsOutputs struct {
+	dispatcher *routing.Dispatcher
+	outputs    map[string]*output
+}
+
+type lokiOperatorInstance struct {
+	outputs map[datasource.DataSource]*dsOutputs
+}
+
+func (l *lokiOperatorInstance) Name() string {
+	return name
+}
+
+// labelFuncs returns the functions getting the labels of the events of a data
+// source; labels of fields the data source doesn't have are skipped
+func labelFuncs(ds datasource.DataSource, labels map[string]string) ([]func(datasource.Data) (string, string), error) {
+	var fns []func(datasource.Data) (string, string)
+	for label, fieldName := range labels {
+		f := ds.GetField(fieldName)
+		if f == nil {
+			continue
+		}
+		fn, err := datasource.GetKeyValueFunc[string, string](f, label,
+			func(i int64) string { return strconv.FormatInt(i, 10) },
+			func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) },
+			func(s string) string { return s },
+		)
+		if err != nil {
+			return nil, fmt.Errorf("label %q: field %q: %w", label, fieldName, err)
+		}
+		fns = append(fns, fn)
+	}
+	return fns, nil
+}
+
+func (l *lokiOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, outs := range l.outputs {
+		formatter, err := igjson.New(ds, igjson.WithShowAll(true))
+		if err != nil {
+			return fmt.Errorf("creating json formatter for %s: %w", ds.Name(), err)
+		}
+		fns := make(map[string][]func(datasource.Data) (string, string), len(outs.outputs))
+		for exporterName, out := range outs.outputs {
+			fns[exporterName], err = labelFuncs(ds, out.labels)
+			if err != nil {
+				return fmt.Errorf("data source %s: %w", ds.Name(), err)
+			}
+		}
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			var line string
+			for _, exporterName := range outs.dispatcher.Route(data) {
+				out := outs.outputs[exporterName]
+				if line == "" {
+					line = string(formatter.Marshal(data))
+				}
+				labels := make(map[string]string, len(out.static)+len(fns[exporterName]))
+				for k, v := range out.static {
+					labels[k] = v
+				}
+				for _, fn := range fns[exporterName] {
+					// Empty values are the same as missing labels in Loki
+					if k, v := fn(data); v != "" {
+						labels[k] = v
+					}
+				}
+				out.exporter.sender.send(entry{
+					labels: labels,
+					ts:     time.Now(),
+					line:   line,
+				})
+			}
+			return nil
+		}, Priority)
+	}
+	return nil
+}
+
+func (l *lokiOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (l *lokiOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (l *lokiOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &lokiOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
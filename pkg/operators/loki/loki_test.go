@@ -0,0 +1,205 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+type fakeSender struct {
+	mu      sync.Mutex
+	entries []entry
+}
+
+func (f *fakeSender) send(e entry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries = append(f.entries, e)
+}
+
+func TestPushURL(t *testing.T) {
+	u, err := pushURL("http://loki:3100")
+	require.NoError(t, err)
+	assert.Equal(t, "http://loki:3100"+PushPath, u)
+
+	u, err = pushURL("https://logs.example.com/api/prom/push")
+	require.NoError(t, err)
+	assert.Equal(t, "https://logs.example.com/api/prom/push", u)
+
+	for _, rawURL := range []string{"", "loki:3100", "ftp://loki", "http://"} {
+		_, err = pushURL(rawURL)
+		require.Error(t, err, rawURL)
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	_, err := newExporter("test", &exporterConfig{})
+	require.Error(t, err)
+
+	_, err = newExporter("test", &exporterConfig{URL: "http://loki:3100", Labels: map[string]string{"k8s.namespace": "k8s.namespace"}})
+	require.Error(t, err)
+
+	_, err = newExporter("test", &exporterConfig{URL: "http://loki:3100", Labels: map[string]string{"node": "k8s.node"}})
+	require.Error(t, err)
+
+	_, err = newExporter("test", &exporterConfig{URL: "http://loki:3100", BatchSize: -1})
+	require.Error(t, err)
+
+	e, err := newExporter("test", &exporterConfig{URL: "http://loki:3100"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLabels, e.labels)
+	p := e.sender.(*pusher)
+	assert.Equal(t, "http://loki:3100"+PushPath, p.url)
+	assert.Equal(t, DefaultBatchSize, p.batchSize)
+	assert.Equal(t, DefaultBatchWait, p.batchWait)
+	assert.Equal(t, DefaultMaxRetries, p.maxRetries)
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := parseLabels("ns=k8s.namespace, pod=k8s.podName")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"ns": "k8s.namespace", "pod": "k8s.podName"}, labels)
+
+	labels, err = parseLabels("")
+	require.NoError(t, err)
+	assert.Nil(t, labels)
+
+	for _, s := range []string{"k8s.namespace", "ns=", "gadget=comm", "1ns=k8s.namespace"} {
+		_, err = parseLabels(s)
+		require.Error(t, err, s)
+	}
+}
+
+func runProducer(t *testing.T, o *lokiOperator, paramValues api.ParamValues) {
+	var ds datasource.DataSource
+	var comm, namespace, pid datasource.FieldAccessor
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "exec")
+		require.NoError(t, err)
+		comm, err = ds.AddField("comm", api.Kind_String)
+		require.NoError(t, err)
+		pid, err = ds.AddField("pid", api.Kind_Uint32)
+		require.NoError(t, err)
+		k8s, err := ds.AddField("k8s", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+		require.NoError(t, err)
+		namespace, err = k8s.AddSubField("namespace", api.Kind_String)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for i, c := range []string{"bash", "sh"} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, comm.PutString(data, c))
+			require.NoError(t, pid.PutUint32(data, uint32(i+1)))
+			if c == "bash" {
+				require.NoError(t, namespace.PutString(data, "default"))
+			}
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		cancel()
+		return nil
+	}
+
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "ghcr.io/inspektor-gadget/gadget/trace_exec:latest",
+		gadgetcontext.WithDataOperators(o, producer))
+	require.NoError(t, gadgetCtx.Run(paramValues))
+}
+
+func TestPushEvents(t *testing.T) {
+	sender := &fakeSender{}
+	o := &lokiOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"grafana": {labels: DefaultLabels, sender: sender},
+		},
+	}
+	runProducer(t, o, api.ParamValues{
+		"operator.loki." + ParamLokiExporter: "grafana",
+	})
+
+	require.Len(t, sender.entries, 2)
+	assert.Equal(t, map[string]string{
+		LabelGadget:     "trace_exec",
+		LabelDataSource: "exec",
+		LabelNode:       "node-1",
+		"namespace":     "default",
+	}, sender.entries[0].labels)
+	assert.JSONEq(t, `{"comm":"bash","pid":1,"k8s":{"namespace":"default"}}`, sender.entries[0].line)
+
+	// Labels of empty fields are left out
+	assert.Equal(t, map[string]string{
+		LabelGadget:     "trace_exec",
+		LabelDataSource: "exec",
+		LabelNode:       "node-1",
+	}, sender.entries[1].labels)
+	assert.JSONEq(t, `{"comm":"sh","pid":2,"k8s":{"namespace":""}}`, sender.entries[1].line)
+}
+
+func TestPushEventsWithLabels(t *testing.T) {
+	sender := &fakeSender{}
+	o := &lokiOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"grafana": {labels: DefaultLabels, sender: sender},
+		},
+	}
+	runProducer(t, o, api.ParamValues{
+		"operator.loki." + ParamLokiExporter: "exec:grafana",
+		"operator.loki." + ParamLokiLabels:   "comm=comm,pid=pid",
+	})
+
+	require.Len(t, sender.entries, 2)
+	for i, c := range []string{"bash", "sh"} {
+		assert.Equal(t, c, sender.entries[i].labels["comm"])
+		assert.NotContains(t, sender.entries[i].labels, "namespace")
+	}
+	assert.Equal(t, "2", sender.entries[1].labels["pid"])
+}
+
+func TestNoExporter(t *testing.T) {
+	sender := &fakeSender{}
+	o := &lokiOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"grafana": {labels: DefaultLabels, sender: sender},
+		},
+	}
+	runProducer(t, o, api.ParamValues{})
+	assert.Empty(t, sender.entries)
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// queueLength is the number of log lines waiting to be pushed; further
+	// ones are dropped
+	queueLength = 10000
+
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// entry is a log line with the labels of its stream
+type entry struct {
+	labels map[string]string
+	ts     time.Time
+	line   string
+}
+
+// entrySender sends log lines to Loki; it can be replaced for testing
+type entrySender interface {
+	send(e entry)
+}
+
+// stream is a stream of the push API
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type pushRequest struct {
+	Streams []*stream `json:"streams"`
+}
+
+// batch groups log lines by stream
+type batch struct {
+	streams map[string]*stream
+	order   []string
+	size    int
+}
+
+func newBatch() *batch {
+	return &batch{streams: make(map[string]*stream)}
+}
+
+// streamKey identifies the stream of a set of labels
+func streamKey(labels map[string]string) string {
+	var sb strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(strconv.Quote(labels[k]))
+		sb.WriteString(",")
+	}
+	return sb.String()
+}
+
+func (b *batch) add(e entry) {
+	key := streamKey(e.labels)
+	s, ok := b.streams[key]
+	if !ok {
+		s = &stream{Stream: e.labels}
+		b.streams[key] = s
+		b.order = append(b.order, key)
+	}
+	s.Values = append(s.Values, [2]string{strconv.FormatInt(e.ts.UnixNano(), 10), e.line})
+	b.size++
+}
+
+func (b *batch) encode() ([]byte, error) {
+	req := pushRequest{Streams: make([]*stream, 0, len(b.order))}
+	for _, key := range b.order {
+		req.Streams = append(req.Streams, b.streams[key])
+	}
+	return json.Marshal(req)
+}
+
+// pusher batches log lines and pushes them to the push API of Loki in the
+// background, retrying with exponential backoff when Loki is unavailable or
+// rate-limits. Lines are queued so that the data sources aren't blocked by
+// Loki.
+type pusher struct {
+	name       string
+	url        string
+	tenantID   string
+	username   string
+	password   string
+	client     *http.Client
+	batchSize  int
+	batchWait  time.Duration
+	maxRetries int
+
+	// backoff is the first delay between retries; it can be lowered for
+	// testing
+	backoff time.Duration
+
+	queue   chan entry
+	dropped atomic.Uint64
+}
+
+func (p *pusher) start() {
+	p.queue = make(chan entry, queueLength)
+	if p.backoff == 0 {
+		p.backoff = minBackoff
+	}
+	go p.run()
+}
+
+func (p *pusher) send(e entry) {
+	select {
+	case p.queue <- e:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// run pushes a batch once it holds batchSize lines, or every batchWait
+func (p *pusher) run() {
+	ticker := time.NewTicker(p.batchWait)
+	defer ticker.Stop()
+
+	b := newBatch()
+	flush := func() {
+		if b.size == 0 {
+			return
+		}
+		if dropped := p.dropped.Swap(0); dropped > 0 {
+			log.Warnf("loki exporter %q: dropped %d log lines because %s didn't keep up", p.name, dropped, p.url)
+		}
+		if err := p.push(b); err != nil {
+			log.Errorf("loki exporter %q: pushing %d log lines to %s: %v", p.name, b.size, p.url, err)
+		}
+		b = newBatch()
+	}
+
+	for {
+		select {
+		case e := <-p.queue:
+			b.add(e)
+			if b.size >= p.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// push sends a batch, retrying on network errors, rate limiting and server
+// errors
+func (p *pusher) push(b *batch) error {
+	body, err := b.encode()
+	if err != nil {
+		return fmt.Errorf("encoding batch: %w", err)
+	}
+	backoff := p.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := p.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= p.maxRetries {
+			return err
+		}
+		log.Debugf("loki exporter %q: retrying in %s: %v", p.name, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// post sends a push request; it returns whether it should be retried on error
+func (p *pusher) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", p.tenantID)
+	}
+	if p.username != "" {
+		req.SetBasicAuth(p.username, p.password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5, err
+}
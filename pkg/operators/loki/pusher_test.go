@@ -0,0 +1,117 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package loki
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	b := newBatch()
+	ts := time.Unix(1, 5)
+	b.add(entry{labels: map[string]string{"a": "1", "b": "2"}, ts: ts, line: "one"})
+	b.add(entry{labels: map[string]string{"a": "2"}, ts: ts, line: "two"})
+	b.add(entry{labels: map[string]string{"b": "2", "a": "1"}, ts: ts, line: "three"})
+	assert.Equal(t, 3, b.size)
+
+	body, err := b.encode()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"streams":[
+		{"stream":{"a":"1","b":"2"},"values":[["1000000005","one"],["1000000005","three"]]},
+		{"stream":{"a":"2"},"values":[["1000000005","two"]]}
+	]}`, string(body))
+}
+
+func TestPusher(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan pushRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first request to exercise the retries
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, PushPath, r.URL.Path)
+		assert.Equal(t, "tenant-1", r.Header.Get("X-Scope-OrgID"))
+		user, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "secret", password)
+
+		var req pushRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		w.WriteHeader(http.StatusNoContent)
+		received <- req
+	}))
+	defer server.Close()
+
+	p := &pusher{
+		name:       "test",
+		url:        server.URL + PushPath,
+		tenantID:   "tenant-1",
+		username:   "user",
+		password:   "secret",
+		client:     server.Client(),
+		batchSize:  2,
+		batchWait:  time.Hour,
+		maxRetries: 1,
+		backoff:    time.Millisecond,
+	}
+	p.start()
+	p.send(entry{labels: map[string]string{"gadget": "trace_exec"}, ts: time.Now(), line: "one"})
+	p.send(entry{labels: map[string]string{"gadget": "trace_exec"}, ts: time.Now(), line: "two"})
+
+	select {
+	case req := <-received:
+		require.Len(t, req.Streams, 1)
+		assert.Equal(t, map[string]string{"gadget": "trace_exec"}, req.Streams[0].Stream)
+		require.Len(t, req.Streams[0].Values, 2)
+		assert.Equal(t, "one", req.Streams[0].Values[0][1])
+		assert.Equal(t, "two", req.Streams[0].Values[1][1])
+	case <-time.After(5 * time.Second):
+		t.Fatal("no push received")
+	}
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestPushNoRetry(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "entry too far behind", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	p := &pusher{
+		url:        server.URL + PushPath,
+		client:     server.Client(),
+		maxRetries: 3,
+		backoff:    time.Millisecond,
+	}
+	b := newBatch()
+	b.add(entry{labels: map[string]string{"gadget": "trace_exec"}, ts: time.Now(), line: "one"})
+	err := p.push(b)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "entry too far behind")
+	assert.EqualValues(t, 1, calls.Load())
+}
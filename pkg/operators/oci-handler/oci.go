@@ -21,10 +21,15 @@ import (
 	"io"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/blang/semver"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -39,24 +44,28 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/resources"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/selftest"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/signature"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/signature/cosign"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/signature/notation"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
 )
 
 const (
-	validateMetadataParam   = "validate-metadata"
-	authfileParam           = "authfile"
-	insecureRegistriesParam = "insecure-registries"
-	disallowPulling         = "disallow-pulling"
-	pullParam               = "pull"
-	pullSecret              = "pull-secret"
-	annotate                = "annotate"
-	verifyImage             = "verify-image"
-	publicKeys              = "public-keys"
-	certificates            = "notation-certificates"
-	policyDocument          = "notation-policy-document"
-	allowedGadgets          = "allowed-gadgets"
+	validateMetadataParam    = "validate-metadata"
+	authfileParam            = "authfile"
+	insecureRegistriesParam  = "insecure-registries"
+	disallowPulling          = "disallow-pulling"
+	pullParam                = "pull"
+	pullSecret               = "pull-secret"
+	annotate                 = "annotate"
+	verifyImage              = "verify-image"
+	publicKeys               = "public-keys"
+	certificates             = "notation-certificates"
+	policyDocument           = "notation-policy-document"
+	allowedGadgets           = "allowed-gadgets"
+	gadgetPolicy             = "gadget-policy"
+	gadgetPolicySyncInterval = "gadget-policy-sync-interval"
 )
 
 const (
@@ -66,6 +75,10 @@ const (
 type ociHandler struct {
 	globalParams *params.Params
 	verifyOpts   oci.VerifyOptions
+	policy       *oci.PolicySyncer
+
+	// stopPolicySync stops syncing the gadget policy
+	stopPolicySync context.CancelFunc
 }
 
 func New() *ociHandler {
@@ -106,6 +119,34 @@ func (o *ociHandler) Init(params *params.Params) error {
 
 	o.verifyOpts = verifyOptions
 
+	if policyRef := o.globalParams.Get(gadgetPolicy).AsString(); policyRef != "" {
+		// The policy is signed like the gadgets it allows
+		o.policy = oci.NewPolicySyncer(policyRef, o.globalParams.Get(gadgetPolicySyncInterval).AsDuration(),
+			&oci.AuthOptions{
+				AuthFile:           o.globalParams.Get(authfileParam).AsString(),
+				InsecureRegistries: o.globalParams.Get(insecureRegistriesParam).AsStringSlice(),
+			}, &o.verifyOpts)
+
+		// Sync once before running any gadget; gadgets are denied until the
+		// policy is synced
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := o.policy.Sync(ctx); err != nil {
+			log.Warnf("syncing gadget policy %q: %v", policyRef, err)
+		}
+		syncCtx, stop := context.WithCancel(context.Background())
+		o.stopPolicySync = stop
+		go o.policy.Run(syncCtx)
+	}
+
+	return nil
+}
+
+// Close stops the background tasks of the operator
+func (o *ociHandler) Close() error {
+	if o.stopPolicySync != nil {
+		o.stopPolicySync()
+	}
 	return nil
 }
 
@@ -143,6 +184,20 @@ func (o *ociHandler) GlobalParams() api.Params {
 			Description: "List of allowed gadgets, if gadget is not part of it, execution will be denied. By default, all digests are allowed",
 			TypeHint:    api.TypeStringSlice,
 		},
+		{
+			Key:   gadgetPolicy,
+			Title: "Gadget policy",
+			Description: "OCI reference of a gadget policy artifact listing the allowed and denied gadgets, " +
+				"synced periodically. Gadgets are denied until the policy could be pulled",
+			TypeHint: api.TypeString,
+		},
+		{
+			Key:          gadgetPolicySyncInterval,
+			Title:        "Gadget policy sync interval",
+			Description:  "Interval between the syncs of the gadget policy",
+			DefaultValue: "5m",
+			TypeHint:     api.TypeDuration,
+		},
 		{
 			Key:         insecureRegistriesParam,
 			Title:       "Insecure registries",
@@ -386,6 +441,7 @@ func (o *OciHandlerInstance) init(gadgetCtx operators.GadgetContext) error {
 		VerifyOptions: o.ociHandler.verifyOpts,
 		AllowedGadgetsOptions: oci.AllowedGadgetsOptions{
 			AllowedGadgets: o.globalParams.Get(allowedGadgets).AsStringSlice(),
+			Policy:         o.ociHandler.policy,
 		},
 		Logger: gadgetCtx.Logger(),
 	}
@@ -393,16 +449,36 @@ func (o *OciHandlerInstance) init(gadgetCtx operators.GadgetContext) error {
 	gadgetCtx.Logger().Debugf("image options: %+v", imgOpts)
 
 	target := gadgetCtx.OrasTarget()
+	if target == nil && gadgetCtx.ImageName() == selftest.Image {
+		// The self-test gadget is built into the binary, so it isn't pulled
+		// nor verified, but it still needs to be allowed
+		builtin, err := selftest.Target(gadgetCtx.Context())
+		if err != nil {
+			return err
+		}
+		target = builtin
+		err = oci.CheckAllowedGadget(gadgetCtx.Context(), target, gadgetCtx.ImageName(), &imgOpts.AllowedGadgetsOptions)
+		if err != nil {
+			return fmt.Errorf("ensuring image: %w", err)
+		}
+	}
 	// If the target wasn't explicitly set, use the local store. In this case we
 	// need to be sure the image is available.
 	if target == nil {
 		// Make sure the image is available, either through pulling or by just accessing a local copy
 		// TODO: add security constraints (e.g. don't allow pulling - add GlobalParams for that)
-		err := oci.EnsureImage(gadgetCtx.Context(), gadgetCtx.ImageName(),
+		ctx, span := tracing.Tracer().Start(gadgetCtx.Context(), "pull image", trace.WithAttributes(
+			attribute.String("gadget_image", gadgetCtx.ImageName()),
+		))
+		err := oci.EnsureImage(ctx, gadgetCtx.ImageName(),
 			imgOpts, o.instanceParams.Get(pullParam).AsString())
 		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.End()
 			return fmt.Errorf("ensuring image: %w", err)
 		}
+		span.End()
 	}
 
 	manifest, err := oci.GetManifestForHost(gadgetCtx.Context(), target, gadgetCtx.ImageName())
@@ -518,6 +594,16 @@ func (o *OciHandlerInstance) PreStart(gadgetCtx operators.GadgetContext) error {
 	return nil
 }
 
+func (o *OciHandlerInstance) Validate(gadgetCtx operators.GadgetContext) []*api.ValidationCheck {
+	var checks []*api.ValidationCheck
+	for _, opInst := range o.imageOperatorInstances {
+		if validator, ok := opInst.(operators.Validator); ok {
+			checks = append(checks, validator.Validate(gadgetCtx)...)
+		}
+	}
+	return checks
+}
+
 func (o *OciHandlerInstance) Start(gadgetCtx operators.GadgetContext) error {
 	started := []operators.ImageOperatorInstance{}
 
@@ -55,8 +55,29 @@ const (
 	MapPrefix string = "map/"
 
 	MapSpecPrefix string = "mapspec/"
+
+	// StateReporterVarName is the name of the gadget context variable holding
+	// a StateReporter, if the gadget is run in a way its state can be queried
+	// later on, e.g. as a gadget instance.
+	StateReporterVarName string = "stateReporter"
 )
 
+// StateReporter can be used by operators to report problems that don't stop
+// the gadget but should be visible in its state.
+type StateReporter interface {
+	ReportWarning(msg string)
+}
+
+// GetStateReporter returns the StateReporter of the gadget context, if any
+func GetStateReporter(gadgetCtx GadgetContext) (StateReporter, bool) {
+	v, ok := gadgetCtx.GetVar(StateReporterVarName)
+	if !ok {
+		return nil, false
+	}
+	reporter, ok := v.(StateReporter)
+	return reporter, ok
+}
+
 type ImageOperator interface {
 	Name() string
 
@@ -122,6 +143,20 @@ type PostStop interface {
 	PostStop(gadgetCtx GadgetContext) error
 }
 
+// ParamUpdater is implemented by operator instances that can apply new values
+// of their params while the gadget is running. UpdateParams only checks the
+// values and returns a function applying them, so that nothing is changed if
+// the values of any operator are invalid.
+type ParamUpdater interface {
+	UpdateParams(gadgetCtx GadgetContext, instanceParamValues api.ParamValues) (func(), error)
+}
+
+// Validator is implemented by operator instances that can check whether the
+// gadget would run on the current node without starting it
+type Validator interface {
+	Validate(gadgetCtx GadgetContext) []*api.ValidationCheck
+}
+
 // ContainerInfoFromMountNSID is a typical kubernetes operator interface that adds node, pod, namespace and container
 // information given the MountNSID
 type ContainerInfoFromMountNSID interface {
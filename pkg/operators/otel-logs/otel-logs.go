@@ -16,17 +16,22 @@ package otellogs
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	otellog "go.opentelemetry.io/otel/log"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/inspektor-gadget/inspektor-gadget/internal/version"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
@@ -35,6 +40,7 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/routing"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 )
 
@@ -46,6 +52,10 @@ const (
 	AnnotationLogsBody     = "logs.body"
 	AnnotationLogsSeverity = "logs.severity"
 
+	// AnnotationLogsEnable set to false skips a data source when an exporter
+	// is set for all data sources
+	AnnotationLogsEnable = "logs.enable"
+
 	FieldNameBody      = "body"
 	FieldNameTimestamp = "timestamp"
 	FieldNameSeverity  = "severity"
@@ -58,11 +68,94 @@ const (
 
 var supportedExporters = []string{ExporterOTLPGRPC}
 
+// metadataAttributes maps the fields holding the Kubernetes and container
+// metadata of events to the semantic convention attributes they are exported
+// as
+var metadataAttributes = []struct {
+	field string
+	key   string
+}{
+	{"k8s.cluster", string(semconv.K8SClusterNameKey)},
+	{"cluster", string(semconv.K8SClusterNameKey)},
+	{"k8s.node", string(semconv.K8SNodeNameKey)},
+	{"k8s.namespace", string(semconv.K8SNamespaceNameKey)},
+	{"k8s.podName", string(semconv.K8SPodNameKey)},
+	{"k8s.containerName", string(semconv.K8SContainerNameKey)},
+	{"runtime.containerName", string(semconv.ContainerNameKey)},
+	{"runtime.containerId", string(semconv.ContainerIDKey)},
+	{"runtime.containerImageName", string(semconv.ContainerImageNameKey)},
+}
+
 type logConfig struct {
-	Exporter    string `json:"exporter" yaml:"exporter"`
-	Endpoint    string `json:"endpoint" yaml:"endpoint"`
-	Insecure    bool   `json:"insecure" yaml:"insecure"`
-	Compression string `json:"compression" yaml:"compression"`
+	Exporter           string            `json:"exporter" yaml:"exporter"`
+	Endpoint           string            `json:"endpoint" yaml:"endpoint"`
+	Insecure           bool              `json:"insecure" yaml:"insecure"`
+	Compression        string            `json:"compression" yaml:"compression"`
+	Headers            map[string]string `json:"headers" yaml:"headers"`
+	CAFile             string            `json:"caFile" yaml:"caFile"`
+	CertFile           string            `json:"certFile" yaml:"certFile"`
+	KeyFile            string            `json:"keyFile" yaml:"keyFile"`
+	InsecureSkipVerify bool              `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+}
+
+// tlsConfig returns the TLS configuration of an exporter, or nil if it uses
+// the defaults
+func tlsConfig(v *logConfig) (*tls.Config, error) {
+	if v.CAFile == "" && v.CertFile == "" && v.KeyFile == "" && !v.InsecureSkipVerify {
+		return nil, nil
+	}
+	cfg := &tls.Config{InsecureSkipVerify: v.InsecureSkipVerify}
+	if v.CAFile != "" {
+		pem, err := os.ReadFile(v.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %q", v.CAFile)
+		}
+	}
+	if v.CertFile != "" || v.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(v.CertFile, v.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// exporterOptions returns the options of the otlp exporter of a config
+func exporterOptions(v *logConfig) ([]otlploggrpc.Option, error) {
+	if v.Exporter != ExporterOTLPGRPC {
+		return nil, fmt.Errorf("unsupported log exporter %q; expected one of %s", v.Exporter,
+			strings.Join(supportedExporters, ", "))
+	}
+	var options []otlploggrpc.Option
+
+	options = append(options, otlploggrpc.WithEndpoint(v.Endpoint))
+	if v.Insecure {
+		options = append(options, otlploggrpc.WithInsecure())
+	} else {
+		cfg, err := tlsConfig(v)
+		if err != nil {
+			return nil, err
+		}
+		if cfg != nil {
+			options = append(options, otlploggrpc.WithTLSCredentials(credentials.NewTLS(cfg)))
+		}
+	}
+	switch v.Compression {
+	default:
+		return nil, fmt.Errorf("unsupported log compression %q", v.Compression)
+	case "", CompressionNone:
+	case CompressionGZIP:
+		options = append(options, otlploggrpc.WithCompressor("gzip"))
+	}
+	if len(v.Headers) > 0 {
+		options = append(options, otlploggrpc.WithHeaders(v.Headers))
+	}
+	return options, nil
 }
 
 type otelLogsOperator struct {
@@ -76,10 +169,7 @@ func (o *otelLogsOperator) Name() string {
 func (o *otelLogsOperator) Init(params *params.Params) error {
 	o.providers = make(map[string]*sdklog.LoggerProvider)
 
-	res, _ := resource.New(context.Background(), resource.WithAttributes(
-		semconv.ServiceNameKey.String("inspektor-gadget"),
-		semconv.ServiceVersionKey.String(version.Version().String()),
-	))
+	res, _ := resource.New(context.Background(), resource.WithAttributes(resourceAttributes()...))
 
 	if config.Config == nil {
 		return nil
@@ -92,22 +182,9 @@ func (o *otelLogsOperator) Init(params *params.Params) error {
 		log.Warnf("failed to load operator.otel-logs.exporters: %v", err)
 	}
 	for k, v := range configs {
-		if v.Exporter != ExporterOTLPGRPC {
-			return fmt.Errorf("unsupported log exporter %q; expected one of %s", v.Exporter,
-				strings.Join(supportedExporters, ", "))
-		}
-		var options []otlploggrpc.Option
-
-		options = append(options, otlploggrpc.WithEndpoint(v.Endpoint))
-		if v.Insecure {
-			options = append(options, otlploggrpc.WithInsecure())
-		}
-		switch v.Compression {
-		default:
-			return fmt.Errorf("unsupported log compression %q", v.Compression)
-		case "", CompressionNone:
-		case CompressionGZIP:
-			options = append(options, otlploggrpc.WithCompressor("gzip"))
+		options, err := exporterOptions(v)
+		if err != nil {
+			return fmt.Errorf("log exporter %q: %w", k, err)
 		}
 
 		exp, err := otlploggrpc.New(context.Background(), options...)
@@ -123,6 +200,22 @@ func (o *otelLogsOperator) Init(params *params.Params) error {
 	return nil
 }
 
+// resourceAttributes returns the attributes of the resource logging the events:
+// Inspektor Gadget on the node it runs on
+func resourceAttributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String("inspektor-gadget"),
+		semconv.ServiceVersionKey.String(version.Version().String()),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		attrs = append(attrs, semconv.HostNameKey.String(hostname))
+	}
+	if node := os.Getenv("NODE_NAME"); node != "" {
+		attrs = append(attrs, semconv.K8SNodeNameKey.String(node))
+	}
+	return attrs
+}
+
 func (o *otelLogsOperator) GlobalParams() api.Params {
 	return api.Params{}
 }
@@ -148,7 +241,7 @@ func (o *otelLogsOperator) InstantiateDataOperator(gadgetCtx operators.GadgetCon
 	inst := &otelLogsOperatorInstance{
 		o:        o,
 		mappings: mappings,
-		loggers:  make(map[datasource.DataSource]otellog.Logger),
+		loggers:  make(map[datasource.DataSource]*dsLoggers),
 	}
 	err = inst.init(gadgetCtx)
 	if err != nil {
@@ -164,34 +257,57 @@ func (o *otelLogsOperator) Priority() int {
 type otelLogsOperatorInstance struct {
 	o        *otelLogsOperator
 	mappings map[string]string
-	loggers  map[datasource.DataSource]otellog.Logger
+	loggers  map[datasource.DataSource]*dsLoggers
+}
+
+// dsLoggers holds the loggers the events of a data source are sent to
+type dsLoggers struct {
+	// dispatcher selects the exporters of each event: the one set with
+	// otel-logs-exporter and the ones of the routes of the routing operator
+	dispatcher *routing.Dispatcher
+	loggers    map[string]otellog.Logger
+}
+
+func (l *dsLoggers) emit(ctx context.Context, data datasource.Data, rec otellog.Record) {
+	for _, exporter := range l.dispatcher.Route(data) {
+		l.loggers[exporter].Emit(ctx, rec)
+	}
 }
 
 func (o *otelLogsOperatorInstance) init(gadgetCtx operators.GadgetContext) error {
 	for _, ds := range gadgetCtx.GetDataSources() {
 		annotations := ds.Annotations()
 
-		// Find mapping
+		loggerName := annotations[AnnotationLogsName]
+		if loggerName == "" {
+			loggerName = gadgetCtx.ImageName()
+		}
+
+		// Find mapping; data sources with logs.enable set to false are only
+		// logged when mapped explicitly
 		exporterName, ok := o.mappings[ds.Name()]
-		if !ok {
-			exporterName, ok = o.mappings[""]
-			if !ok {
-				continue
+		if !ok && annotations[AnnotationLogsEnable] != "false" {
+			exporterName = o.mappings[""]
+		}
+		if exporterName != "" {
+			if _, ok := o.o.providers[exporterName]; !ok {
+				return fmt.Errorf("exporter not found: %q", exporterName)
 			}
 		}
 
-		exporter, ok := o.o.providers[exporterName]
-		if !ok {
-			return fmt.Errorf("exporter not found: %q", exporterName)
+		dispatcher := routing.NewDispatcher(gadgetCtx, ds, "otel-logs", exporterName, func(name string) bool {
+			_, ok := o.o.providers[name]
+			return ok
+		})
+		if dispatcher == nil {
+			continue
 		}
-
-		loggerName := annotations[AnnotationLogsName]
-		if loggerName == "" {
-			loggerName = gadgetCtx.ImageName()
+		loggers := &dsLoggers{dispatcher: dispatcher, loggers: make(map[string]otellog.Logger)}
+		for _, name := range dispatcher.Exporters() {
+			gadgetCtx.Logger().Debugf("logging %q to exporter %q", ds.Name(), name)
+			loggers.loggers[name] = o.o.providers[name].Logger(loggerName)
 		}
-
-		gadgetCtx.Logger().Debugf("logging %q to exporter %q", ds.Name(), exporterName)
-		o.loggers[ds] = exporter.Logger(loggerName)
+		o.loggers[ds] = loggers
 	}
 	return nil
 }
@@ -201,13 +317,16 @@ func (o *otelLogsOperatorInstance) Name() string {
 }
 
 func (o *otelLogsOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
-	for ds, logger := range o.loggers {
+	for ds, loggers := range o.loggers {
 		fields := ds.Accessors(false)
 		annotations := ds.Annotations()
 
 		prep := make([]func(data datasource.Data) otellog.KeyValue, 0)
 		kvCount := 0
 
+		// meta returns the metadata attributes and whether they are set
+		meta := make([]func(data datasource.Data) (otellog.KeyValue, bool), 0)
+
 		fns := make([]func(datasource.Data, *otellog.Record), 0)
 
 		// fixed severity by annotation
@@ -282,14 +401,35 @@ func (o *otelLogsOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) e
 			kvCount++
 		}
 
+		// Kubernetes and container metadata, unless exported already
+		for _, m := range metadataAttributes {
+			f := ds.GetField(m.field)
+			if f == nil || f.Type() != api.Kind_String {
+				continue
+			}
+			if _, ok := f.Annotations()[AnnotationLogsName]; ok {
+				continue
+			}
+			key := m.key
+			meta = append(meta, func(data datasource.Data) (otellog.KeyValue, bool) {
+				val, _ := f.String(data)
+				return otellog.String(key, val), val != ""
+			})
+		}
+
 		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
 			var rec otellog.Record
 
 			// Collect attributes
-			attribs := make([]otellog.KeyValue, 0, kvCount)
+			attribs := make([]otellog.KeyValue, 0, kvCount+len(meta))
 			for _, p := range prep {
 				attribs = append(attribs, p(data))
 			}
+			for _, m := range meta {
+				if kv, ok := m(data); ok {
+					attribs = append(attribs, kv)
+				}
+			}
 			rec.AddAttributes(attribs...)
 
 			// Set other values
@@ -298,7 +438,7 @@ func (o *otelLogsOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) e
 			}
 			rec.SetTimestamp(time.Now())
 
-			logger.Emit(gadgetCtx.Context(), rec)
+			loggers.emit(gadgetCtx.Context(), data, rec)
 			return nil
 		}, 10000)
 	}
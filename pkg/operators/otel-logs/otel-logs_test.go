@@ -0,0 +1,192 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otellogs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+type fakeExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (f *fakeExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range records {
+		f.records = append(f.records, r.Clone())
+	}
+	return nil
+}
+
+func (f *fakeExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeExporter) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+func attributes(r sdklog.Record) map[string]string {
+	attrs := make(map[string]string)
+	r.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = kv.Value.String()
+		return true
+	})
+	return attrs
+}
+
+func TestExporterOptions(t *testing.T) {
+	_, err := exporterOptions(&logConfig{Exporter: "otlp-http"})
+	require.Error(t, err)
+
+	_, err = exporterOptions(&logConfig{Exporter: ExporterOTLPGRPC, Compression: "zstd"})
+	require.Error(t, err)
+
+	_, err = exporterOptions(&logConfig{Exporter: ExporterOTLPGRPC, CAFile: "/nonexistent/ca.pem"})
+	require.Error(t, err)
+
+	_, err = exporterOptions(&logConfig{Exporter: ExporterOTLPGRPC, CertFile: "/nonexistent/cert.pem"})
+	require.Error(t, err)
+
+	options, err := exporterOptions(&logConfig{
+		Exporter:           ExporterOTLPGRPC,
+		Endpoint:           "collector:4317",
+		Compression:        CompressionGZIP,
+		Headers:            map[string]string{"Authorization": "Bearer token"},
+		InsecureSkipVerify: true,
+	})
+	require.NoError(t, err)
+	assert.Len(t, options, 4)
+}
+
+func runProducer(t *testing.T, o *otelLogsOperator, annotations map[string]string, paramValues api.ParamValues) {
+	var exec, debug datasource.DataSource
+	var comm, namespace, podName datasource.FieldAccessor
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		exec, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "exec")
+		require.NoError(t, err)
+		comm, err = exec.AddField("comm", api.Kind_String, datasource.WithAnnotations(map[string]string{
+			AnnotationLogsName: "comm",
+		}))
+		require.NoError(t, err)
+		k8s, err := exec.AddField("k8s", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+		require.NoError(t, err)
+		namespace, err = k8s.AddSubField("namespace", api.Kind_String)
+		require.NoError(t, err)
+		podName, err = k8s.AddSubField("podName", api.Kind_String)
+		require.NoError(t, err)
+
+		debug, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "debug")
+		require.NoError(t, err)
+		for k, v := range annotations {
+			debug.AddAnnotation(k, v)
+		}
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for _, c := range []string{"bash", "sh"} {
+			data, err := exec.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, comm.PutString(data, c))
+			require.NoError(t, namespace.PutString(data, "default"))
+			if c == "bash" {
+				require.NoError(t, podName.PutString(data, "shell"))
+			}
+			require.NoError(t, exec.EmitAndRelease(data))
+
+			data, err = debug.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, debug.EmitAndRelease(data))
+		}
+		cancel()
+		return nil
+	}
+
+	producer := simple.New("producer",
+		simple.WithPriority(o.Priority()-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "ghcr.io/inspektor-gadget/gadget/trace_exec:latest",
+		gadgetcontext.WithDataOperators(o, producer))
+	require.NoError(t, gadgetCtx.Run(paramValues))
+}
+
+func newTestOperator(exporter sdklog.Exporter) *otelLogsOperator {
+	return &otelLogsOperator{
+		providers: map[string]*sdklog.LoggerProvider{
+			"collector": sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter))),
+		},
+	}
+}
+
+func TestMetadataAttributes(t *testing.T) {
+	exporter := &fakeExporter{}
+	runProducer(t, newTestOperator(exporter), map[string]string{
+		AnnotationLogsEnable: "false",
+	}, api.ParamValues{
+		"operator.otel-logs." + ParamOtelLogsExporter: "collector",
+	})
+
+	// Events of debug aren't logged
+	require.Len(t, exporter.records, 2)
+	assert.Equal(t, map[string]string{
+		"comm":               "bash",
+		"k8s.namespace.name": "default",
+		"k8s.pod.name":       "shell",
+	}, attributes(exporter.records[0]))
+
+	// Empty metadata is left out
+	assert.Equal(t, map[string]string{
+		"comm":               "sh",
+		"k8s.namespace.name": "default",
+	}, attributes(exporter.records[1]))
+}
+
+func TestEnableExplicitly(t *testing.T) {
+	exporter := &fakeExporter{}
+	runProducer(t, newTestOperator(exporter), map[string]string{
+		AnnotationLogsEnable: "false",
+	}, api.ParamValues{
+		"operator.otel-logs." + ParamOtelLogsExporter: "debug:collector",
+	})
+
+	require.Len(t, exporter.records, 2)
+	for _, r := range exporter.records {
+		assert.Empty(t, attributes(r))
+	}
+}
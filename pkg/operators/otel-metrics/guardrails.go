@@ -0,0 +1,103 @@
+// Copyright 2024-2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmetrics
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
+)
+
+// guardrails limit the series and the rate of measurements of a gadget
+// instance. They're shared by the collectors of all its data sources, so the
+// limits apply to the instance as a whole, whatever its number of data
+// sources.
+type guardrails struct {
+	// maxSeries limits the number of distinct attribute sets; 0 means no limit
+	maxSeries uint
+	// onSeriesOverflow is called once, when the first measurement exceeds
+	// maxSeries
+	onSeriesOverflow func()
+
+	seriesMu   sync.Mutex
+	series     map[seriesKey]struct{}
+	overflowed bool
+
+	// limiter limits the number of events turned into measurements per
+	// second; nil means no limit
+	limiter *rate.Limiter
+	// onRateExceeded is called once, when the first event exceeds the rate
+	onRateExceeded func()
+	rateExceeded   atomic.Bool
+	dropped        atomic.Uint64
+}
+
+type seriesKey struct {
+	collector *metricsCollector
+	set       attribute.Distinct
+}
+
+func newGuardrails(maxSeries uint, maxRate uint) *guardrails {
+	g := &guardrails{
+		maxSeries: maxSeries,
+		series:    make(map[seriesKey]struct{}),
+	}
+	if maxRate > 0 {
+		g.limiter = rate.NewLimiter(rate.Limit(maxRate), int(maxRate))
+	}
+	return g
+}
+
+// allow returns whether an event can be turned into measurements according to
+// the rate limit
+func (g *guardrails) allow() bool {
+	if g.limiter == nil || g.limiter.Allow() {
+		return true
+	}
+	g.dropped.Add(1)
+	if !g.rateExceeded.Swap(true) && g.onRateExceeded != nil {
+		g.onRateExceeded()
+	}
+	return false
+}
+
+// limitSeries returns the given set or, if it'd exceed the maximum number of
+// series of the instance, the overflow set
+func (g *guardrails) limitSeries(mc *metricsCollector, kset attribute.Set) attribute.Set {
+	if g.maxSeries == 0 {
+		return kset
+	}
+
+	g.seriesMu.Lock()
+	defer g.seriesMu.Unlock()
+
+	key := seriesKey{collector: mc, set: kset.Equivalent()}
+	if _, ok := g.series[key]; ok {
+		return kset
+	}
+	if uint(len(g.series)) < g.maxSeries {
+		g.series[key] = struct{}{}
+		return kset
+	}
+	if !g.overflowed {
+		g.overflowed = true
+		if g.onSeriesOverflow != nil {
+			g.onSeriesOverflow()
+		}
+	}
+	return overflowSet
+}
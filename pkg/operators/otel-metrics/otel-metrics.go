@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -34,7 +35,9 @@ import (
 	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
 	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
@@ -58,17 +61,28 @@ const (
 	ParamOtelMetricsExporter        = "otel-metrics-exporter"
 	ParamOtelMetricsPrintInterval   = "otel-metrics-print-interval"
 
+	ParamOtelMetricsMaxSeries                = "otel-metrics-max-series"
+	ParamOtelMetricsMaxRate                  = "otel-metrics-max-rate"
+	ParamOtelMetricsLabels                   = "otel-metrics-labels"
+	ParamOtelMetricsAggregateHighCardinality = "otel-metrics-aggregate-high-cardinality"
+	ParamOtelMetricsExemplars                = "otel-metrics-exemplars"
+
 	MetricTypeKey       = "key"
 	MetricTypeCounter   = "counter"
 	MetricTypeGauge     = "gauge"
 	MetricTypeHistogram = "histogram"
 
+	// MetricTypeLatency is a histogram of durations in nanoseconds, exported
+	// in seconds
+	MetricTypeLatency = "latency"
+
 	AnnotationMetricsCollect     = "metrics.collect"
 	AnnotationMetricsPrint       = "metrics.print"
 	AnnotationMetricsType        = "metrics.type"
 	AnnotationMetricsDescription = "metrics.description"
 	AnnotationMetricsUnit        = "metrics.unit"
 	AnnotationMetricsBoundaries  = "metrics.boundaries"
+	AnnotationMetricsExemplars   = "metrics.exemplars"
 
 	AnnotationImplicitCounterName        = "metrics.implicit-counter.name"
 	AnnotationImplicitCounterDescription = "metrics.implicit-counter.description"
@@ -79,8 +93,94 @@ const (
 	HistogramOutputMode = "histogram"
 
 	MinPrintInterval = time.Millisecond * 25
+
+	// OverflowAttribute is the only attribute of the series that aggregates all
+	// measurements exceeding the max series limit, following the OpenTelemetry
+	// conventions
+	OverflowAttribute = "otel.metric.overflow"
+
+	HistogramAggregationExplicit    = "explicit"
+	HistogramAggregationExponential = "exponential"
 )
 
+// latencyBoundaries are the default bucket boundaries of latency histograms in
+// seconds, from 10µs to 10s
+var latencyBoundaries = []float64{
+	0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005,
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05,
+	0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// exemplarAttributes maps the fields holding the Kubernetes and container
+// metadata of events to the semantic convention attributes that are attached
+// to exemplars. These attributes are dropped from the series by
+// exemplarView, so they don't increase their cardinality.
+var exemplarAttributes = []struct {
+	field string
+	key   attribute.Key
+}{
+	{"k8s.namespace", semconv.K8SNamespaceNameKey},
+	{"k8s.podName", semconv.K8SPodNameKey},
+	{"k8s.containerName", semconv.K8SContainerNameKey},
+	{"runtime.containerName", semconv.ContainerNameKey},
+	{"runtime.containerId", semconv.ContainerIDKey},
+}
+
+// exemplarContextKey marks measurements whose exemplars should be sampled
+type exemplarContextKey struct{}
+
+// exemplarFilter samples exemplars of measurements of data sources with
+// exemplars enabled, as well as the ones of sampled traces
+func exemplarFilter(ctx context.Context) bool {
+	return ctx.Value(exemplarContextKey{}) != nil || exemplar.TraceBasedFilter(ctx)
+}
+
+// exemplarView keeps the exemplar attributes out of the series; the SDK adds
+// them to the exemplars as filtered attributes instead
+func exemplarView(i sdkmetric.Instrument) (sdkmetric.Stream, bool) {
+	return sdkmetric.Stream{
+		Name:        i.Name,
+		Description: i.Description,
+		Unit:        i.Unit,
+		AttributeFilter: func(kv attribute.KeyValue) bool {
+			for _, a := range exemplarAttributes {
+				if kv.Key == a.key {
+					return false
+				}
+			}
+			return true
+		},
+	}, true
+}
+
+// meterProviderOptions returns the options of all meter providers
+func meterProviderOptions(opts ...sdkmetric.Option) []sdkmetric.Option {
+	return append(opts,
+		sdkmetric.WithView(exemplarView),
+		sdkmetric.WithExemplarFilter(exemplarFilter),
+	)
+}
+
+// exponentialHistogramSelector aggregates histograms as base-2 exponential
+// histograms, the native histograms of OTLP
+func exponentialHistogramSelector(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	if kind == sdkmetric.InstrumentKindHistogram {
+		return sdkmetric.AggregationBase2ExponentialHistogram{MaxSize: 160, MaxScale: 20}
+	}
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
+
+// highCardinalityTypes are the types of key fields that are aggregated (dropped
+// as labels) by default, as they'd create a new series for almost every event
+var highCardinalityTypes = []string{
+	"type:" + ebpftypes.PidTypeName,
+	"type:" + ebpftypes.PpidTypeName,
+	"type:" + ebpftypes.TidTypeName,
+	"type:" + ebpftypes.TimestampTypeName,
+}
+
+var overflowSet = attribute.NewSet(attribute.Bool(OverflowAttribute, true))
+
 var renderedDsCliAnnotations = map[string]string{
 	"cli.supported-output-modes": HistogramOutputMode,
 	"cli.default-output-mode":    HistogramOutputMode,
@@ -95,6 +195,9 @@ type metricsConfig struct {
 	Interval         time.Duration `json:"interval" yaml:"interval"`
 	CollectGoMetrics bool          `json:"collectGoMetrics" yaml:"collectGoMetrics"`
 	CollectIGMetrics bool          `json:"collectIGMetrics" yaml:"collectIGMetrics"`
+
+	// HistogramAggregation is either explicit (default) or exponential
+	HistogramAggregation string `json:"histogramAggregation" yaml:"histogramAggregation"`
 }
 
 func deltaSelector(kind sdkmetric.InstrumentKind) metricdata.Temporality {
@@ -158,6 +261,14 @@ func (m *otelMetricsOperator) Init(globalParams *params.Params) error {
 				case "delta":
 					options = append(options, otlpmetricgrpc.WithTemporalitySelector(deltaSelector))
 				}
+				switch v.HistogramAggregation {
+				case "", HistogramAggregationExplicit:
+				case HistogramAggregationExponential:
+					options = append(options, otlpmetricgrpc.WithAggregationSelector(exponentialHistogramSelector))
+				default:
+					return fmt.Errorf("invalid histogram aggregation %q for metric exporter %q; expected %q or %q",
+						v.HistogramAggregation, k, HistogramAggregationExplicit, HistogramAggregationExponential)
+				}
 				otlpcollector, err := otlpmetricgrpc.New(
 					context.Background(),
 					options...,
@@ -169,11 +280,11 @@ func (m *otelMetricsOperator) Init(globalParams *params.Params) error {
 				if v.Interval > 0 {
 					periodicReaderOptions = append(periodicReaderOptions, sdkmetric.WithInterval(v.Interval))
 				}
-				m.providers[k] = sdkmetric.NewMeterProvider(
+				m.providers[k] = sdkmetric.NewMeterProvider(meterProviderOptions(
 					sdkmetric.WithReader(
 						sdkmetric.NewPeriodicReader(otlpcollector, periodicReaderOptions...),
 					),
-				)
+				)...)
 
 				if v.CollectIGMetrics {
 					// Register with internal metrics
@@ -212,7 +323,7 @@ func (m *otelMetricsOperator) Init(globalParams *params.Params) error {
 		return fmt.Errorf("initializing otel metrics exporter: %w", err)
 	}
 	m.exporter = exporter
-	m.meterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(exporter))
+	m.meterProvider = sdkmetric.NewMeterProvider(meterProviderOptions(sdkmetric.WithReader(exporter))...)
 
 	if globalParams.Get(ParamOtelMetricsExportInternals).AsBool() {
 		log.Debug("enabled exporting internal metrics to global provider")
@@ -280,6 +391,35 @@ func (m *otelMetricsOperator) InstanceParams() api.Params {
 			Description:  "name of the configured metric provider to use; leave empty to use the default exporter",
 			DefaultValue: "",
 		},
+		{
+			Key:          ParamOtelMetricsMaxSeries,
+			TypeHint:     api.TypeUint,
+			Description:  "maximum number of series (distinct label sets) of the gadget instance, across all its data sources; measurements for further series are aggregated into a single overflow series; 0 disables the limit",
+			DefaultValue: "10000",
+		},
+		{
+			Key:          ParamOtelMetricsMaxRate,
+			TypeHint:     api.TypeUint,
+			Description:  "maximum number of events per second of the gadget instance, across all its data sources, turned into measurements; further events are dropped from the metrics; 0 disables the limit",
+			DefaultValue: "0",
+		},
+		{
+			Key:         ParamOtelMetricsLabels,
+			TypeHint:    api.TypeStringSlice,
+			Description: "key fields to export as labels; other key fields are aggregated. Leave empty to use all key fields",
+		},
+		{
+			Key:          ParamOtelMetricsAggregateHighCardinality,
+			TypeHint:     api.TypeBool,
+			Description:  "aggregate key fields with a high cardinality, like pids or timestamps, unless they're part of " + ParamOtelMetricsLabels,
+			DefaultValue: "true",
+		},
+		{
+			Key:          ParamOtelMetricsExemplars,
+			TypeHint:     api.TypeBool,
+			Description:  "attach exemplars with the pod and container of the events to the metrics",
+			DefaultValue: "false",
+		},
 	}
 }
 
@@ -301,11 +441,19 @@ func (m *otelMetricsOperator) InstantiateDataOperator(gadgetCtx operators.Gadget
 	}
 
 	instance := &otelMetricsOperatorInstance{
-		op:            m,
-		collectors:    make(map[datasource.DataSource]*metricsCollector),
-		nameMappings:  mappings,
-		printInterval: printInterval,
-		done:          make(chan struct{}),
+		op:                       m,
+		collectors:               make(map[datasource.DataSource]*metricsCollector),
+		nameMappings:             mappings,
+		printInterval:            printInterval,
+		maxSeries:                params.Get(ParamOtelMetricsMaxSeries).AsUint(),
+		maxRate:                  params.Get(ParamOtelMetricsMaxRate).AsUint(),
+		labels:                   params.Get(ParamOtelMetricsLabels).AsStringSlice(),
+		aggregateHighCardinality: params.Get(ParamOtelMetricsAggregateHighCardinality).AsBool(),
+		exemplars:                params.Get(ParamOtelMetricsExemplars).AsBool(),
+		done:                     make(chan struct{}),
+	}
+	if reporter, ok := operators.GetStateReporter(gadgetCtx); ok {
+		instance.reporter = reporter
 	}
 
 	// named metric providers are only evaluated on the server side for now
@@ -346,6 +494,39 @@ type otelMetricsOperatorInstance struct {
 	provider      metric.MeterProvider
 	done          chan struct{}
 	wg            sync.WaitGroup
+
+	// guardrails
+	maxSeries                uint
+	maxRate                  uint
+	guardrails               *guardrails
+	labels                   []string
+	aggregateHighCardinality bool
+	reporter                 operators.StateReporter
+
+	// exemplars enables exemplars for all data sources
+	exemplars bool
+}
+
+// reportWarning logs the guardrail violation and, if possible, adds it to the
+// state of the instance
+func (m *otelMetricsOperatorInstance) reportWarning(gadgetCtx operators.GadgetContext, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	gadgetCtx.Logger().Warn(msg)
+	if m.reporter != nil {
+		m.reporter.ReportWarning(msg)
+	}
+}
+
+// useAsLabel returns whether the key field should be exported as label or
+// aggregated according to the configured guardrails
+func (m *otelMetricsOperatorInstance) useAsLabel(f datasource.FieldAccessor) bool {
+	if slices.Contains(m.labels, f.Name()) || slices.Contains(m.labels, f.FullName()) {
+		return true
+	}
+	if len(m.labels) > 0 {
+		return false
+	}
+	return !m.aggregateHighCardinality || !f.HasAnyTagsOf(highCardinalityTypes...)
 }
 
 func (m *otelMetricsOperatorInstance) Name() string {
@@ -361,6 +542,29 @@ type metricsCollector struct {
 	exporter          *otelprometheus.Exporter
 	meterProvider     *sdkmetric.MeterProvider
 	useGlobalProvider bool
+
+	// guardrails are shared by all the collectors of the instance
+	guardrails *guardrails
+
+	// exemplars return the attributes attached to exemplars and whether
+	// they're set
+	exemplars []func(datasource.Data) (attribute.KeyValue, bool)
+}
+
+// addExemplarFuncs attaches the Kubernetes and container metadata the data
+// source has to exemplars
+func (mc *metricsCollector) addExemplarFuncs(ds datasource.DataSource) {
+	for _, a := range exemplarAttributes {
+		f := ds.GetField(a.field)
+		if f == nil || f.Type() != api.Kind_String {
+			continue
+		}
+		key := a.key
+		mc.exemplars = append(mc.exemplars, func(data datasource.Data) (attribute.KeyValue, bool) {
+			val, _ := f.String(data)
+			return key.String(val), val != ""
+		})
+	}
 }
 
 func (mc *metricsCollector) addKeyFunc(f datasource.FieldAccessor) error {
@@ -579,18 +783,75 @@ func (mc *metricsCollector) addValHistFunc(ds datasource.DataSource, f datasourc
 	}
 }
 
+// addValLatencyFunc adds a histogram of the durations in nanoseconds of a
+// field, recorded in seconds
+func (mc *metricsCollector) addValLatencyFunc(f datasource.FieldAccessor) error {
+	boundaries := latencyBoundaries
+	if buckets := f.Annotations()[AnnotationMetricsBoundaries]; buckets != "" {
+		var err error
+		boundaries, err = listToVals[float64](buckets, toFloat64)
+		if err != nil {
+			return fmt.Errorf("adding latency histogram for %q: %w", f.Name(), err)
+		}
+	}
+	options := []metric.Float64HistogramOption{
+		metric.WithExplicitBucketBoundaries(boundaries...),
+		metric.WithUnit("s"),
+	}
+	if description := f.Annotations()[AnnotationMetricsDescription]; description != "" {
+		options = append(options, metric.WithDescription(description))
+	}
+
+	asIntFn, err := datasource.AsInt64(f)
+	if err != nil {
+		return fmt.Errorf("unsupported field type for latency %q: %s", f.Name(), f.Type())
+	}
+	hist, err := mc.meter.Float64Histogram(f.Name(), options...)
+	if err != nil {
+		return fmt.Errorf("adding latency histogram for %q: %w", f.Name(), err)
+	}
+	mc.values = append(mc.values, func(ctx context.Context, data datasource.Data, set attribute.Set) {
+		hist.Record(ctx, time.Duration(asIntFn(data)).Seconds(), metric.WithAttributeSet(set))
+	})
+	return nil
+}
+
 func (mc *metricsCollector) Collect(ctx context.Context, data datasource.Data) {
-	kvs := make([]attribute.KeyValue, 0, len(mc.keys))
+	if !mc.guardrails.allow() {
+		return
+	}
+	kvs := make([]attribute.KeyValue, 0, len(mc.keys)+len(mc.exemplars))
 	for _, kf := range mc.keys {
 		kvs = append(kvs, kf(data))
 	}
-	kset := attribute.NewSet(kvs...)
+	kset := mc.guardrails.limitSeries(mc, attribute.NewSet(kvs...))
+	if len(mc.exemplars) > 0 {
+		// The exemplar attributes are dropped from the series by exemplarView
+		kvs = kset.ToSlice()
+		for _, ef := range mc.exemplars {
+			if kv, ok := ef(data); ok {
+				kvs = append(kvs, kv)
+			}
+		}
+		kset = attribute.NewSet(kvs...)
+		ctx = context.WithValue(ctx, exemplarContextKey{}, true)
+	}
 	for _, vf := range mc.values {
 		vf(ctx, data, kset)
 	}
 }
 
 func (m *otelMetricsOperatorInstance) init(gadgetCtx operators.GadgetContext) error {
+	m.guardrails = newGuardrails(m.maxSeries, m.maxRate)
+	m.guardrails.onSeriesOverflow = func() {
+		m.reportWarning(gadgetCtx, "metrics exceeded the limit of %d series; further series are aggregated with %s=true",
+			m.maxSeries, OverflowAttribute)
+	}
+	m.guardrails.onRateExceeded = func() {
+		m.reportWarning(gadgetCtx, "metrics exceeded the limit of %d events per second; further events are dropped from the metrics",
+			m.maxRate)
+	}
+
 	for _, ds := range gadgetCtx.GetDataSources() {
 		annotations := ds.Annotations()
 		metricsCollect := annotations[AnnotationMetricsCollect] == "true"
@@ -652,11 +913,13 @@ func (m *otelMetricsOperatorInstance) init(gadgetCtx operators.GadgetContext) er
 
 		gadgetCtx.Logger().Debugf("collecting metrics for data source %q as %q", ds.Name(), mappedName)
 
-		m.collectors[ds] = &metricsCollector{
+		collector := &metricsCollector{
 			output:            metricsPrint,
 			mappedName:        mappedName,
 			useGlobalProvider: useGlobal,
+			guardrails:        m.guardrails,
 		}
+		m.collectors[ds] = collector
 	}
 	return nil
 }
@@ -682,7 +945,7 @@ func (m *otelMetricsOperatorInstance) PreStart(gadgetCtx operators.GadgetContext
 				return fmt.Errorf("creating prometheus registry: %w", err)
 			}
 			collector.exporter = exporter
-			collector.meterProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(exporter))
+			collector.meterProvider = sdkmetric.NewMeterProvider(meterProviderOptions(sdkmetric.WithReader(exporter))...)
 			collector.meter = collector.meterProvider.Meter(collector.mappedName)
 		}
 
@@ -725,6 +988,15 @@ func (m *otelMetricsOperatorInstance) PreStart(gadgetCtx operators.GadgetContext
 			default:
 				continue
 			case MetricTypeKey:
+				if !m.useAsLabel(f) {
+					if len(m.labels) == 0 {
+						m.reportWarning(gadgetCtx, "aggregating high-cardinality label %q of metrics %q; add it to %s to keep it",
+							fieldName, collector.mappedName, ParamOtelMetricsLabels)
+					} else {
+						gadgetCtx.Logger().Debugf("aggregating label %q of metrics %q", fieldName, collector.mappedName)
+					}
+					continue
+				}
 				err := collector.addKeyFunc(f)
 				if err != nil {
 					return fmt.Errorf("adding key for %q: %w", fieldName, err)
@@ -741,6 +1013,12 @@ func (m *otelMetricsOperatorInstance) PreStart(gadgetCtx operators.GadgetContext
 					return fmt.Errorf("adding histogram for %q: %w", fieldName, err)
 				}
 				hasValueFields = true
+			case MetricTypeLatency:
+				err := collector.addValLatencyFunc(f)
+				if err != nil {
+					return fmt.Errorf("adding latency for %q: %w", fieldName, err)
+				}
+				hasValueFields = true
 			}
 			gadgetCtx.Logger().Debugf("registered field %q as type %q", fieldName, metricsType)
 		}
@@ -749,6 +1027,11 @@ func (m *otelMetricsOperatorInstance) PreStart(gadgetCtx operators.GadgetContext
 			continue
 		}
 
+		if m.exemplars || ds.Annotations()[AnnotationMetricsExemplars] == "true" {
+			collector.addExemplarFuncs(ds)
+			gadgetCtx.Logger().Debugf("attaching %d attributes to exemplars of metrics %q", len(collector.exemplars), collector.mappedName)
+		}
+
 		err := ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
 			collector.Collect(gadgetCtx.Context(), data)
 			return nil
@@ -767,6 +1050,20 @@ func (m *otelMetricsOperatorInstance) PreStart(gadgetCtx operators.GadgetContext
 	return nil
 }
 
+// formatLabels returns the non-empty labels of a data point as "key=value"
+// pairs separated by spaces
+func formatLabels(set attribute.Set) string {
+	labels := make([]string, 0, set.Len())
+	iter := set.Iter()
+	for iter.Next() {
+		kv := iter.Attribute()
+		if v := kv.Value.Emit(); v != "" {
+			labels = append(labels, fmt.Sprintf("%s=%s", kv.Key, v))
+		}
+	}
+	return strings.Join(labels, " ")
+}
+
 func (m *otelMetricsOperatorInstance) PrintMetrics(gadgetCtx operators.GadgetContext) {
 	defer m.wg.Done()
 	// Periodically print using the fetch interval
@@ -813,6 +1110,11 @@ func (m *otelMetricsOperatorInstance) PrintMetrics(gadgetCtx operators.GadgetCon
 						switch t := metric.Data.(type) {
 						case metricdata.Histogram[int64]:
 							for _, dp := range t.DataPoints {
+								// Histograms with labels, like per container, are
+								// preceded by their labels
+								if labels := formatLabels(dp.Attributes); labels != "" {
+									fmt.Fprintln(&out, labels)
+								}
 								last := uint64(0)
 								v := make([]histogram.Interval, 0, len(dp.Bounds))
 								for bucket, high := range dp.Bounds {
@@ -851,6 +1153,9 @@ func (m *otelMetricsOperatorInstance) Start(gadgetCtx operators.GadgetContext) e
 }
 
 func (m *otelMetricsOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	if dropped := m.guardrails.dropped.Load(); dropped > 0 {
+		gadgetCtx.Logger().Warnf("%d events were dropped from the metrics by the limit of %d events per second", dropped, m.maxRate)
+	}
 	return nil
 }
 
@@ -16,18 +16,24 @@ package otelmetrics
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
 	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
 )
 
@@ -194,3 +200,370 @@ func TestMetricsHistogram(t *testing.T) {
 		assert.True(t, found)
 	}
 }
+
+type testStateReporter struct {
+	warnings []string
+}
+
+func (r *testStateReporter) ReportWarning(msg string) {
+	r.warnings = append(r.warnings, msg)
+}
+
+func TestMetricsGuardrails(t *testing.T) {
+	o := &otelMetricsOperator{skipListen: true}
+	globalParams := apihelpers.ToParamDescs(o.GlobalParams()).ToParams()
+	globalParams.Set(ParamOtelMetricsListen, "true")
+	err := o.Init(globalParams)
+	require.NoError(t, err)
+
+	var ds datasource.DataSource
+	var pid datasource.FieldAccessor
+	var comm datasource.FieldAccessor
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "guarded")
+		require.NoError(t, err)
+		ds.AddAnnotation(AnnotationMetricsCollect, "true")
+		ds.AddAnnotation(AnnotationImplicitCounterName, "events")
+
+		pid, err = ds.AddField("pid", api.Kind_Uint32,
+			datasource.WithTags("type:"+ebpftypes.PidTypeName),
+			datasource.WithAnnotations(map[string]string{
+				AnnotationMetricsType: MetricTypeKey,
+			}))
+		require.NoError(t, err)
+
+		comm, err = ds.AddField("comm", api.Kind_String, datasource.WithAnnotations(map[string]string{
+			AnnotationMetricsType: MetricTypeKey,
+		}))
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for i := range 10 {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			err = pid.PutUint32(data, uint32(i))
+			assert.NoError(t, err)
+			err = comm.PutString(data, fmt.Sprintf("comm%d", i))
+			assert.NoError(t, err)
+			err = ds.EmitAndRelease(data)
+			assert.NoError(t, err)
+		}
+		cancel()
+		return nil
+	}
+
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	reporter := &testStateReporter{}
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(o, producer))
+	gadgetCtx.SetVar(operators.StateReporterVarName, reporter)
+
+	err = gadgetCtx.Run(api.ParamValues{
+		"operator.otel-metrics.otel-metrics-name":       "guarded:guarded",
+		"operator.otel-metrics.otel-metrics-max-series": "3",
+	})
+	require.NoError(t, err)
+
+	md := &metricdata.ResourceMetrics{}
+	err = o.exporter.Collect(context.Background(), md)
+	require.NoError(t, err)
+
+	found := false
+	for _, sm := range md.ScopeMetrics {
+		if sm.Scope.Name != "guarded" {
+			continue
+		}
+		for _, m := range sm.Metrics {
+			if m.Name != "events" {
+				continue
+			}
+			found = true
+			data, ok := (m.Data).(metricdata.Sum[int64])
+			require.True(t, ok)
+			// 3 series plus the overflow series
+			require.Len(t, data.DataPoints, 4)
+			for _, dp := range data.DataPoints {
+				_, hasPid := dp.Attributes.Value("pid")
+				assert.False(t, hasPid)
+				if dp.Attributes.Equals(&overflowSet) {
+					assert.Equal(t, int64(7), dp.Value)
+				} else {
+					assert.Equal(t, int64(1), dp.Value)
+				}
+			}
+		}
+	}
+	assert.True(t, found)
+	assert.Len(t, reporter.warnings, 2)
+}
+
+func TestUseAsLabel(t *testing.T) {
+	ds, err := datasource.New(datasource.TypeSingle, "test")
+	require.NoError(t, err)
+	pid, err := ds.AddField("pid", api.Kind_Uint32, datasource.WithTags("type:"+ebpftypes.PidTypeName))
+	require.NoError(t, err)
+	comm, err := ds.AddField("comm", api.Kind_String)
+	require.NoError(t, err)
+
+	type testCase struct {
+		labels    []string
+		aggregate bool
+		expected  map[datasource.FieldAccessor]bool
+	}
+
+	tests := map[string]testCase{
+		"default": {
+			aggregate: true,
+			expected:  map[datasource.FieldAccessor]bool{pid: false, comm: true},
+		},
+		"no_aggregation": {
+			aggregate: false,
+			expected:  map[datasource.FieldAccessor]bool{pid: true, comm: true},
+		},
+		"allowlist": {
+			labels:    []string{"comm"},
+			aggregate: true,
+			expected:  map[datasource.FieldAccessor]bool{pid: false, comm: true},
+		},
+		"allowlist_high_cardinality": {
+			labels:    []string{"pid"},
+			aggregate: true,
+			expected:  map[datasource.FieldAccessor]bool{pid: true, comm: false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := &otelMetricsOperatorInstance{
+				labels:                   test.labels,
+				aggregateHighCardinality: test.aggregate,
+			}
+			for f, expected := range test.expected {
+				assert.Equal(t, expected, m.useAsLabel(f), f.Name())
+			}
+		})
+	}
+}
+
+func runLatencyProducer(t *testing.T, o *otelMetricsOperator, paramValues api.ParamValues) {
+	var ds datasource.DataSource
+	var comm, podName, latency datasource.FieldAccessor
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "latency")
+		require.NoError(t, err)
+		ds.AddAnnotation(AnnotationMetricsCollect, "true")
+
+		comm, err = ds.AddField("comm", api.Kind_String, datasource.WithAnnotations(map[string]string{
+			AnnotationMetricsType: MetricTypeKey,
+		}))
+		require.NoError(t, err)
+		k8s, err := ds.AddField("k8s", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+		require.NoError(t, err)
+		podName, err = k8s.AddSubField("podName", api.Kind_String)
+		require.NoError(t, err)
+		latency, err = ds.AddField("latency_ns", api.Kind_Uint64, datasource.WithAnnotations(map[string]string{
+			AnnotationMetricsType: MetricTypeLatency,
+		}))
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for i := range 4 {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			assert.NoError(t, comm.PutString(data, "curl"))
+			assert.NoError(t, podName.PutString(data, fmt.Sprintf("pod-%d", i%2)))
+			// 20µs, 2ms, 20µs, 2ms
+			l := 20 * time.Microsecond
+			if i%2 == 1 {
+				l = 2 * time.Millisecond
+			}
+			assert.NoError(t, latency.PutUint64(data, uint64(l)))
+			assert.NoError(t, ds.EmitAndRelease(data))
+		}
+		cancel()
+		return nil
+	}
+
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(o, producer))
+	require.NoError(t, gadgetCtx.Run(paramValues))
+}
+
+func collectLatency(t *testing.T, o *otelMetricsOperator) metricdata.Metrics {
+	md := &metricdata.ResourceMetrics{}
+	require.NoError(t, o.exporter.Collect(context.Background(), md))
+	for _, sm := range md.ScopeMetrics {
+		if sm.Scope.Name != "latency" {
+			continue
+		}
+		for _, m := range sm.Metrics {
+			if m.Name == "latency_ns" {
+				return m
+			}
+		}
+	}
+	require.Fail(t, "latency_ns not found")
+	return metricdata.Metrics{}
+}
+
+func TestMetricsLatency(t *testing.T) {
+	o := &otelMetricsOperator{skipListen: true}
+	globalParams := apihelpers.ToParamDescs(o.GlobalParams()).ToParams()
+	globalParams.Set(ParamOtelMetricsListen, "true")
+	require.NoError(t, o.Init(globalParams))
+
+	runLatencyProducer(t, o, api.ParamValues{
+		"operator.otel-metrics.otel-metrics-name": "latency:latency",
+	})
+
+	m := collectLatency(t, o)
+	assert.Equal(t, "s", m.Unit)
+	data, ok := (m.Data).(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, data.DataPoints, 1)
+	dp := data.DataPoints[0]
+	assert.Equal(t, latencyBoundaries, dp.Bounds)
+	assert.Equal(t, uint64(4), dp.Count)
+	// 20µs falls into (10µs, 25µs], 2ms into (1ms, 2.5ms]
+	assert.Equal(t, uint64(2), dp.BucketCounts[1])
+	assert.Equal(t, uint64(2), dp.BucketCounts[7])
+	assert.Empty(t, dp.Exemplars)
+}
+
+func TestMetricsExemplars(t *testing.T) {
+	o := &otelMetricsOperator{skipListen: true}
+	globalParams := apihelpers.ToParamDescs(o.GlobalParams()).ToParams()
+	globalParams.Set(ParamOtelMetricsListen, "true")
+	require.NoError(t, o.Init(globalParams))
+
+	runLatencyProducer(t, o, api.ParamValues{
+		"operator.otel-metrics.otel-metrics-name":      "latency:latency",
+		"operator.otel-metrics.otel-metrics-exemplars": "true",
+	})
+
+	m := collectLatency(t, o)
+	data, ok := (m.Data).(metricdata.Histogram[float64])
+	require.True(t, ok)
+
+	// The pod isn't part of the series
+	require.Len(t, data.DataPoints, 1)
+	dp := data.DataPoints[0]
+	assert.Equal(t, 1, dp.Attributes.Len())
+	_, hasPod := dp.Attributes.Value(semconv.K8SPodNameKey)
+	assert.False(t, hasPod)
+
+	require.NotEmpty(t, dp.Exemplars)
+	for _, e := range dp.Exemplars {
+		require.Len(t, e.FilteredAttributes, 1)
+		assert.Equal(t, semconv.K8SPodNameKey, e.FilteredAttributes[0].Key)
+		if e.Value < 0.001 {
+			assert.Equal(t, "pod-0", e.FilteredAttributes[0].Value.AsString())
+		} else {
+			assert.Equal(t, "pod-1", e.FilteredAttributes[0].Value.AsString())
+		}
+	}
+}
+
+func TestExponentialHistogramSelector(t *testing.T) {
+	reader := sdkmetric.NewManualReader(sdkmetric.WithAggregationSelector(exponentialHistogramSelector))
+	provider := sdkmetric.NewMeterProvider(meterProviderOptions(sdkmetric.WithReader(reader))...)
+
+	hist, err := provider.Meter("test").Float64Histogram("latency", metric.WithExplicitBucketBoundaries(latencyBoundaries...))
+	require.NoError(t, err)
+	hist.Record(context.Background(), 0.002)
+	ctr, err := provider.Meter("test").Int64Counter("events")
+	require.NoError(t, err)
+	ctr.Add(context.Background(), 1)
+
+	md := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &md))
+	require.Len(t, md.ScopeMetrics, 1)
+	for _, m := range md.ScopeMetrics[0].Metrics {
+		switch m.Name {
+		case "latency":
+			data, ok := (m.Data).(metricdata.ExponentialHistogram[float64])
+			require.True(t, ok)
+			assert.Equal(t, uint64(1), data.DataPoints[0].Count)
+		case "events":
+			_, ok := (m.Data).(metricdata.Sum[int64])
+			require.True(t, ok)
+		}
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	assert.Equal(t, "", formatLabels(attribute.NewSet()))
+
+	set := attribute.NewSet(
+		attribute.String("k8s.podName", "mypod"),
+		attribute.String("k8s.namespace", ""),
+		attribute.String("disk", "sda"),
+	)
+	assert.Equal(t, "disk=sda k8s.podName=mypod", formatLabels(set))
+}
+
+func TestGuardrailsSeriesPerInstance(t *testing.T) {
+	g := newGuardrails(3, 0)
+	overflows := 0
+	g.onSeriesOverflow = func() { overflows++ }
+
+	// Collectors of different data sources share the limit
+	mc1 := &metricsCollector{guardrails: g}
+	mc2 := &metricsCollector{guardrails: g}
+	sets := []attribute.Set{
+		attribute.NewSet(attribute.String("comm", "a")),
+		attribute.NewSet(attribute.String("comm", "b")),
+	}
+
+	require.Equal(t, sets[0], g.limitSeries(mc1, sets[0]))
+	require.Equal(t, sets[1], g.limitSeries(mc1, sets[1]))
+	require.Equal(t, sets[0], g.limitSeries(mc2, sets[0]))
+	require.Equal(t, overflowSet, g.limitSeries(mc2, sets[1]))
+	require.Equal(t, overflowSet, g.limitSeries(mc2, sets[1]))
+
+	// Known series are still accepted
+	require.Equal(t, sets[1], g.limitSeries(mc1, sets[1]))
+	require.Equal(t, 1, overflows)
+}
+
+func TestGuardrailsRate(t *testing.T) {
+	g := newGuardrails(0, 5)
+	exceeded := 0
+	g.onRateExceeded = func() { exceeded++ }
+
+	allowed := 0
+	for range 10 {
+		if g.allow() {
+			allowed++
+		}
+	}
+	require.Equal(t, 5, allowed)
+	require.Equal(t, uint64(5), g.dropped.Load())
+	require.Equal(t, 1, exceeded)
+
+	unlimited := newGuardrails(0, 0)
+	for range 10 {
+		require.True(t, unlimited.allow())
+	}
+}
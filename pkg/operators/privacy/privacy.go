@@ -0,0 +1,254 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package privacy is a data operator applying privacy profiles to the events of
+// gadgets: vetted combinations of field redactions, field drops and sampling,
+// so users don't need to review every field of every gadget to limit the
+// personal or sensitive data they collect. The profiles are applied by the
+// redact and sample operators.
+package privacy
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/redact"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/sample"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	Name     = "privacy"
+	Priority = 40 // before the sample and redact operators, which apply the profile
+
+	ParamProfile        = "privacy-profile"
+	ParamDefaultProfile = "privacy-default-profile"
+	ParamEnforce        = "privacy-enforce"
+
+	ProfileOff      = "off"
+	ProfileBalanced = "balanced"
+	ProfileStrict   = "strict"
+)
+
+// Profile is a vetted combination of settings limiting the data collected by
+// gadgets
+type Profile struct {
+	// Redact lists the fields whose values are masked by the redact operator;
+	// the fields are kept to show that there was a value
+	Redact []string
+	// Drop lists the fields removed from the events
+	Drop []string
+	// SampleRate keeps one event of every SampleRate events of data sources
+	// emitting single events; 0 and 1 keep all of them
+	SampleRate uint64
+}
+
+type namedProfile struct {
+	name    string
+	profile Profile
+}
+
+// profiles are ordered from the least to the most strict. Fields are given by
+// their full names and the ones not present in a data source are ignored.
+var profiles = []namedProfile{
+	{
+		name: ProfileOff,
+	},
+	{
+		name: ProfileBalanced,
+		profile: Profile{
+			// Command lines often contain credentials
+			Redact: []string{"args"},
+			// Raw packets and login sessions identify users
+			Drop: []string{"data", "loginuid", "sessionid"},
+		},
+	},
+	{
+		name: ProfileStrict,
+		profile: Profile{
+			Redact: []string{
+				"args",
+				"cwd",
+				"exepath",
+				"parent_exepath",
+				"file",
+				"fname",
+				"addresses",
+			},
+			Drop: []string{
+				"data",
+				"loginuid",
+				"sessionid",
+				"tty",
+				"k8s.podLabels",
+			},
+			SampleRate: 10,
+		},
+	},
+}
+
+// ProfileNames returns the names of the profiles, from the least to the most
+// strict
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// GetProfile returns the profile with the given name
+func GetProfile(name string) (Profile, bool) {
+	idx := profileIndex(name)
+	if idx < 0 {
+		return Profile{}, false
+	}
+	return profiles[idx].profile, true
+}
+
+func profileIndex(name string) int {
+	return slices.IndexFunc(profiles, func(p namedProfile) bool {
+		return p.name == name
+	})
+}
+
+type privacyOperator struct {
+	defaultProfile string
+	enforce        bool
+}
+
+func (p *privacyOperator) Name() string {
+	return Name
+}
+
+func (p *privacyOperator) Init(params *params.Params) error {
+	p.defaultProfile = params.Get(ParamDefaultProfile).AsString()
+	if profileIndex(p.defaultProfile) < 0 {
+		return fmt.Errorf("invalid %s %q, expected one of %v", ParamDefaultProfile, p.defaultProfile, ProfileNames())
+	}
+	p.enforce = params.Get(ParamEnforce).AsBool()
+	return nil
+}
+
+func (p *privacyOperator) GlobalParams() api.Params {
+	return api.Params{
+		{
+			Key:            ParamDefaultProfile,
+			Title:          "Default Privacy Profile",
+			Description:    "Privacy profile of the gadgets not setting " + ParamProfile,
+			DefaultValue:   ProfileOff,
+			PossibleValues: ProfileNames(),
+		},
+		{
+			Key:   ParamEnforce,
+			Title: "Enforce Privacy Profile",
+			Description: "Reject gadgets setting a " + ParamProfile + " less strict than " +
+				ParamDefaultProfile,
+			DefaultValue: "false",
+			TypeHint:     api.TypeBool,
+		},
+	}
+}
+
+func (p *privacyOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamProfile,
+			Title: "Privacy Profile",
+			Description: "Privacy profile to apply to the events, one of off, balanced and strict. " +
+				"balanced redacts command lines and drops raw packets and login sessions. " +
+				"strict also redacts paths and resolved addresses, drops more fields and keeps " +
+				"one event out of ten. Defaults to the profile configured on the server.",
+			TypeHint: api.TypeString,
+		},
+	}
+}
+
+func (p *privacyOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	params := apihelpers.ToParamDescs(p.InstanceParams()).ToParams()
+	if err := params.CopyFromMap(instanceParamValues, ""); err != nil {
+		return nil, err
+	}
+
+	name, err := p.resolveProfile(params.Get(ParamProfile).AsString())
+	if err != nil {
+		return nil, err
+	}
+	profile, _ := GetProfile(name)
+	if len(profile.Redact) == 0 && len(profile.Drop) == 0 && profile.SampleRate <= 1 {
+		return nil, nil
+	}
+
+	gadgetCtx.Logger().Debugf("privacy: applying profile %q", name)
+
+	rules := make([]string, 0, len(profile.Redact)+len(profile.Drop))
+	for _, field := range profile.Redact {
+		rules = append(rules, field+"="+redact.ActionMask)
+	}
+	for _, field := range profile.Drop {
+		rules = append(rules, field+"="+redact.ActionDrop)
+	}
+	if len(rules) > 0 {
+		gadgetCtx.SetVar(redact.RulesVarName, rules)
+	}
+	if profile.SampleRate > 1 {
+		gadgetCtx.SetVar(sample.MinRateVarName, profile.SampleRate)
+	}
+	return &privacyOperatorInstance{}, nil
+}
+
+// resolveProfile returns the profile to apply given the one requested by the
+// gadget, if any
+func (p *privacyOperator) resolveProfile(requested string) (string, error) {
+	if requested == "" {
+		return p.defaultProfile, nil
+	}
+	idx := profileIndex(requested)
+	if idx < 0 {
+		return "", fmt.Errorf("invalid %s %q, expected one of %v", ParamProfile, requested, ProfileNames())
+	}
+	if p.enforce && idx < profileIndex(p.defaultProfile) {
+		return "", fmt.Errorf("%s %q is less strict than %q enforced by the server", ParamProfile, requested, p.defaultProfile)
+	}
+	return requested, nil
+}
+
+func (p *privacyOperator) Priority() int {
+	return Priority
+}
+
+type privacyOperatorInstance struct{}
+
+func (p *privacyOperatorInstance) Name() string {
+	return Name
+}
+
+func (p *privacyOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (p *privacyOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (p *privacyOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func init() {
+	operators.RegisterDataOperator(&privacyOperator{})
+}
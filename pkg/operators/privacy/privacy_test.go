@@ -0,0 +1,230 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package privacy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/redact"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/sample"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func TestResolveProfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultProfile string
+		enforce        bool
+		requested      string
+		expected       string
+		wantErr        bool
+	}{
+		{
+			name:           "default",
+			defaultProfile: ProfileBalanced,
+			expected:       ProfileBalanced,
+		},
+		{
+			name:           "requested",
+			defaultProfile: ProfileBalanced,
+			requested:      ProfileOff,
+			expected:       ProfileOff,
+		},
+		{
+			name:           "enforced stricter",
+			defaultProfile: ProfileBalanced,
+			enforce:        true,
+			requested:      ProfileStrict,
+			expected:       ProfileStrict,
+		},
+		{
+			name:           "enforced same",
+			defaultProfile: ProfileBalanced,
+			enforce:        true,
+			requested:      ProfileBalanced,
+			expected:       ProfileBalanced,
+		},
+		{
+			name:           "enforced less strict",
+			defaultProfile: ProfileBalanced,
+			enforce:        true,
+			requested:      ProfileOff,
+			wantErr:        true,
+		},
+		{
+			name:           "invalid",
+			defaultProfile: ProfileOff,
+			requested:      "paranoid",
+			wantErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := &privacyOperator{defaultProfile: test.defaultProfile, enforce: test.enforce}
+			profile, err := o.resolveProfile(test.requested)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, profile)
+		})
+	}
+}
+
+func TestInitInvalidDefault(t *testing.T) {
+	o := &privacyOperator{}
+	globalParams := apihelpers.ToParamDescs(o.GlobalParams()).ToParams()
+	require.Error(t, globalParams.Set(ParamDefaultProfile, "paranoid"))
+}
+
+func runProfile(t *testing.T, profile string, events int) ([]map[string]string, datasource.DataSource) {
+	t.Helper()
+
+	o := &privacyOperator{}
+	globalParams := apihelpers.ToParamDescs(o.GlobalParams()).ToParams()
+	require.NoError(t, o.Init(globalParams))
+
+	var ds datasource.DataSource
+	var argsField, commField, loginuidField datasource.FieldAccessor
+	var got []map[string]string
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "test")
+		require.NoError(t, err)
+		argsField, err = ds.AddField("args", api.Kind_String)
+		require.NoError(t, err)
+		commField, err = ds.AddField("comm", api.Kind_String)
+		require.NoError(t, err)
+		loginuidField, err = ds.AddField("loginuid", api.Kind_Uint32)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for range events {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, argsField.PutString(data, "--password=secret"))
+			require.NoError(t, commField.PutString(data, "curl"))
+			require.NoError(t, loginuidField.PutUint32(data, 1000))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		cancel()
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		ds := gadgetCtx.GetDataSources()["test"]
+		require.NotNil(t, ds)
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			args, err := argsField.String(data)
+			require.NoError(t, err)
+			comm, err := commField.String(data)
+			require.NoError(t, err)
+			loginuid, err := loginuidField.Uint32(data)
+			require.NoError(t, err)
+			got = append(got, map[string]string{
+				"args":     args,
+				"comm":     comm,
+				"loginuid": map[bool]string{true: "set", false: "cleared"}[loginuid != 0],
+			})
+			return nil
+		}, redact.Priority+1)
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(redact.Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	// The profiles are applied by the redact and sample operators
+	redactOp := operators.GetDataOperators()[redact.Name]
+	sampleOp := operators.GetDataOperators()["sample"]
+	require.NotNil(t, redactOp)
+	require.NotNil(t, sampleOp)
+	redactParams := apihelpers.ToParamDescs(redactOp.GlobalParams()).ToParams()
+	require.NoError(t, redactOp.Init(redactParams))
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(o, redactOp, sampleOp, producer, consumer))
+	err := gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamProfile: profile,
+	})
+	require.NoError(t, err)
+
+	return got, ds
+}
+
+func TestProfiles(t *testing.T) {
+	tests := []struct {
+		profile  string
+		events   int
+		expected map[string]string
+		count    int
+		dropped  bool
+	}{
+		{
+			profile:  ProfileOff,
+			events:   3,
+			expected: map[string]string{"args": "--password=secret", "comm": "curl", "loginuid": "set"},
+			count:    3,
+		},
+		{
+			profile:  ProfileBalanced,
+			events:   3,
+			expected: map[string]string{"args": redact.Masked, "comm": "curl", "loginuid": "cleared"},
+			count:    3,
+			dropped:  true,
+		},
+		{
+			profile:  ProfileStrict,
+			events:   25,
+			expected: map[string]string{"args": redact.Masked, "comm": "curl", "loginuid": "cleared"},
+			count:    3,
+			dropped:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.profile, func(t *testing.T) {
+			got, ds := runProfile(t, test.profile, test.events)
+			require.Len(t, got, test.count)
+			for _, event := range got {
+				assert.Equal(t, test.expected, event)
+			}
+			assert.Equal(t, test.dropped, ds.GetField("loginuid") == nil)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package privileges is a data operator that enriches the processes of the
+// events with their effective capabilities and their user namespace, read from
+// /proc. It tells whether the traced operations come from privileged contexts,
+// as capabilities only grant privileges over the host in its user namespace.
+package privileges
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
+)
+
+const (
+	Name     = "privileges"
+	Priority = 48 // with the other operators enriching events, before sampling them
+
+	ParamEnable = "privileges-enable"
+
+	cacheTTL        = 2 * time.Second
+	cacheMaxEntries = 16384
+)
+
+type privilegesOperator struct {
+	cache *privCache
+
+	// hostUserns is the inode of the user namespace of the host, 0 if it
+	// couldn't be read
+	hostUserns uint64
+}
+
+func (p *privilegesOperator) Name() string {
+	return Name
+}
+
+func (p *privilegesOperator) Init(params *params.Params) error {
+	p.cache = newPrivCache(host.HostProcFs, cacheTTL, cacheMaxEntries)
+
+	userns, err := readUserns(host.HostProcFs, 1)
+	if err != nil {
+		log.Warnf("privileges operator: reading user namespace of the host: %v", err)
+	}
+	p.hostUserns = userns
+	return nil
+}
+
+func (p *privilegesOperator) GlobalParams() api.Params {
+	return nil
+}
+
+func (p *privilegesOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:          ParamEnable,
+			Title:        "Process Privileges",
+			Description:  "Add the effective capabilities and the user namespace of the processes, read from /proc",
+			DefaultValue: "false",
+			TypeHint:     api.TypeBool,
+		},
+	}
+}
+
+// processAccessors holds the pid of a process struct and the fields added to
+// it. hostUserns is nil if the user namespace of the host is unknown.
+type processAccessors struct {
+	pid datasource.FieldAccessor
+
+	caps       datasource.FieldAccessor
+	capsRaw    datasource.FieldAccessor
+	userns     datasource.FieldAccessor
+	hostUserns datasource.FieldAccessor
+	uidMap     datasource.FieldAccessor
+}
+
+func (p *privilegesOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	params := apihelpers.ToParamDescs(p.InstanceParams()).ToParams()
+	if err := params.CopyFromMap(instanceParamValues, ""); err != nil {
+		return nil, err
+	}
+	if !params.Get(ParamEnable).AsBool() {
+		return nil, nil
+	}
+
+	procAccessors := make(map[datasource.DataSource][]processAccessors)
+	for _, ds := range gadgetCtx.GetDataSources() {
+		for _, proc := range ds.GetFieldsWithTag("type:" + ebpftypes.ProcessTypeName) {
+			pa, err := newProcessAccessors(proc, p.hostUserns != 0)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", proc.FullName(), err)
+			}
+			procAccessors[ds] = append(procAccessors[ds], pa)
+		}
+	}
+	if len(procAccessors) == 0 {
+		return nil, nil
+	}
+
+	return &privilegesOperatorInstance{
+		cache:          p.cache,
+		hostUserns:     p.hostUserns,
+		procsAccessors: procAccessors,
+	}, nil
+}
+
+// newProcessAccessors adds the caps and userns fields to a process
+func newProcessAccessors(proc datasource.FieldAccessor, withHostUserns bool) (processAccessors, error) {
+	var pa processAccessors
+
+	pid := proc.GetSubFieldsWithTag("type:" + ebpftypes.PidTypeName)
+	if len(pid) != 1 {
+		return pa, fmt.Errorf("expected one %q field", ebpftypes.PidTypeName)
+	}
+	pa.pid = pid[0]
+
+	caps, err := proc.AddSubField("caps", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "caps", err)
+	}
+	pa.caps, err = caps.AddSubField("effective", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation:     "Effective capabilities of the process, like CAP_NET_RAW,CAP_SYS_ADMIN, or ALL",
+			metadatav1.ColumnsMaxWidthAnnotation: "40",
+		}),
+	)
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "effective", err)
+	}
+	pa.capsRaw, err = caps.AddSubField("effective_raw", api.Kind_Uint64,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation: "Bitmask of the effective capabilities of the process",
+			metadatav1.ColumnsHexAnnotation:  "true",
+		}),
+		datasource.WithFlags(datasource.FieldFlagHidden),
+	)
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "effective_raw", err)
+	}
+
+	userns, err := proc.AddSubField("userns", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "userns", err)
+	}
+	pa.userns, err = userns.AddSubField("id", api.Kind_Uint64,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation: "Inode of the user namespace of the process",
+		}),
+		datasource.WithFlags(datasource.FieldFlagHidden),
+	)
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "id", err)
+	}
+	if withHostUserns {
+		pa.hostUserns, err = userns.AddSubField("host", api.Kind_Bool,
+			datasource.WithAnnotations(map[string]string{
+				metadatav1.DescriptionAnnotation: "Whether the process is in the user namespace of the host, where its capabilities apply to the host",
+			}),
+		)
+		if err != nil {
+			return pa, fmt.Errorf("adding field %q: %w", "host", err)
+		}
+	}
+	pa.uidMap, err = userns.AddSubField("uid_map", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation: "Uid mapping of the user namespace of the process, like \"0 100000 65536\"",
+		}),
+		datasource.WithFlags(datasource.FieldFlagHidden),
+	)
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "uid_map", err)
+	}
+	return pa, nil
+}
+
+func (p *privilegesOperator) Priority() int {
+	return Priority
+}
+
+type privilegesOperatorInstance struct {
+	cache          *privCache
+	hostUserns     uint64
+	procsAccessors map[datasource.DataSource][]processAccessors
+}
+
+func (p *privilegesOperatorInstance) Name() string {
+	return Name
+}
+
+func (p *privilegesOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, accessors := range p.procsAccessors {
+		err := ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			now := time.Now()
+			for _, pa := range accessors {
+				pid, err := pa.pid.Uint32(data)
+				if err != nil || pid == 0 {
+					continue
+				}
+				// Processes that already exited can't be resolved
+				priv, err := p.cache.get(pid, now)
+				if err != nil {
+					continue
+				}
+				pa.caps.PutString(data, formatCaps(priv.capEff))
+				pa.capsRaw.PutUint64(data, priv.capEff)
+				pa.userns.PutUint64(data, priv.userns)
+				if pa.hostUserns != nil {
+					pa.hostUserns.PutBool(data, priv.userns == p.hostUserns)
+				}
+				pa.uidMap.PutString(data, priv.uidMap)
+			}
+			return nil
+		}, Priority)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *privilegesOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (p *privilegesOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (p *privilegesOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &privilegesOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
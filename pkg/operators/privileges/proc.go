@@ -0,0 +1,178 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package privileges
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moby/sys/capability"
+)
+
+// privInfo holds the privileges of a process read from /proc
+type privInfo struct {
+	capEff  uint64
+	userns  uint64
+	uidMap  string
+	expires time.Time
+}
+
+// readCapEff returns the effective capabilities set of a process, read from
+// /proc/<pid>/status
+func readCapEff(procFs string, pid uint32) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(procFs, strconv.FormatUint(uint64(pid), 10), "status"))
+	if err != nil {
+		return 0, err
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		value, ok := strings.CutPrefix(s.Text(), "CapEff:")
+		if !ok {
+			continue
+		}
+		capEff, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing effective capabilities of pid %d: %w", pid, err)
+		}
+		return capEff, nil
+	}
+	return 0, fmt.Errorf("no effective capabilities for pid %d", pid)
+}
+
+// readUserns returns the inode of the user namespace of a process, from the
+// "user:[4026531837]" link of /proc/<pid>/ns/user
+func readUserns(procFs string, pid uint32) (uint64, error) {
+	link, err := os.Readlink(filepath.Join(procFs, strconv.FormatUint(uint64(pid), 10), "ns", "user"))
+	if err != nil {
+		return 0, err
+	}
+	inode, ok := strings.CutPrefix(link, "user:[")
+	if !ok || !strings.HasSuffix(inode, "]") {
+		return 0, fmt.Errorf("invalid user namespace %q of pid %d", link, pid)
+	}
+	return strconv.ParseUint(strings.TrimSuffix(inode, "]"), 10, 64)
+}
+
+// readUIDMap returns the uid mapping of the user namespace of a process, with
+// one "<inside> <outside> <count>" range per line of /proc/<pid>/uid_map,
+// separated by commas
+func readUIDMap(procFs string, pid uint32) (string, error) {
+	b, err := os.ReadFile(filepath.Join(procFs, strconv.FormatUint(uint64(pid), 10), "uid_map"))
+	if err != nil {
+		return "", err
+	}
+	var ranges []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			ranges = append(ranges, strings.Join(fields, " "))
+		}
+	}
+	return strings.Join(ranges, ","), nil
+}
+
+// knownCaps is the mask of all the capabilities known by name
+var knownCaps = func() uint64 {
+	var mask uint64
+	for _, c := range capability.ListKnown() {
+		mask |= 1 << uint(c)
+	}
+	return mask
+}()
+
+// formatCaps returns the names of the capabilities of a set, like
+// "CAP_NET_RAW,CAP_SYS_ADMIN", or "ALL" if it has all the known ones
+func formatCaps(caps uint64) string {
+	if caps&knownCaps == knownCaps {
+		return "ALL"
+	}
+	var names []string
+	for i := range 64 {
+		if caps&(1<<i) == 0 {
+			continue
+		}
+		name := capability.Cap(i).String()
+		if name == "unknown" {
+			name = strconv.Itoa(i)
+		}
+		names = append(names, "CAP_"+strings.ToUpper(name))
+	}
+	return strings.Join(names, ",")
+}
+
+// privCache caches the privileges of the processes read from /proc for a short
+// time, as events of the same processes come in bursts. It's short to avoid
+// mixing up processes when pids are reused and to catch processes changing
+// their capabilities.
+type privCache struct {
+	procFs     string
+	ttl        time.Duration
+	maxEntries int
+
+	mu    sync.Mutex
+	privs map[uint32]privInfo
+}
+
+func newPrivCache(procFs string, ttl time.Duration, maxEntries int) *privCache {
+	return &privCache{
+		procFs:     procFs,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		privs:      make(map[uint32]privInfo),
+	}
+}
+
+// get returns the privileges of a process
+func (c *privCache) get(pid uint32, now time.Time) (privInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.privs[pid]; ok && now.Before(p.expires) {
+		return p, nil
+	}
+
+	var p privInfo
+	var err error
+	if p.capEff, err = readCapEff(c.procFs, pid); err != nil {
+		return privInfo{}, err
+	}
+	if p.userns, err = readUserns(c.procFs, pid); err != nil {
+		return privInfo{}, err
+	}
+	if p.uidMap, err = readUIDMap(c.procFs, pid); err != nil {
+		return privInfo{}, err
+	}
+
+	if len(c.privs) >= c.maxEntries {
+		for pid, p := range c.privs {
+			if !now.Before(p.expires) {
+				delete(c.privs, pid)
+			}
+		}
+		// All the entries are recent, start over
+		if len(c.privs) >= c.maxEntries {
+			clear(c.privs)
+		}
+	}
+	p.expires = now.Add(c.ttl)
+	c.privs[pid] = p
+	return p, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package privileges
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type proc struct {
+	capEff string
+	userns uint64
+	uidMap string
+}
+
+// writeProcFs writes the status, ns/user and uid_map files of fake processes,
+// by pid
+func writeProcFs(t *testing.T, procs map[uint32]proc) string {
+	dir := t.TempDir()
+	for pid, p := range procs {
+		pidDir := filepath.Join(dir, fmt.Sprint(pid))
+		require.NoError(t, os.MkdirAll(filepath.Join(pidDir, "ns"), 0o755))
+		status := fmt.Sprintf("Name:\tfoo\nCapInh:\t0000000000000000\nCapPrm:\t%s\nCapEff:\t%s\n", p.capEff, p.capEff)
+		require.NoError(t, os.WriteFile(filepath.Join(pidDir, "status"), []byte(status), 0o644))
+		require.NoError(t, os.Symlink(fmt.Sprintf("user:[%d]", p.userns), filepath.Join(pidDir, "ns", "user")))
+		require.NoError(t, os.WriteFile(filepath.Join(pidDir, "uid_map"), []byte(p.uidMap), 0o644))
+	}
+	return dir
+}
+
+func TestFormatCaps(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "", formatCaps(0))
+	assert.Equal(t, "CAP_NET_RAW,CAP_SYS_ADMIN", formatCaps(1<<13|1<<21))
+	assert.Equal(t, "ALL", formatCaps(knownCaps))
+	assert.Equal(t, "ALL", formatCaps(^uint64(0)))
+	assert.Equal(t, "CAP_CHOWN,CAP_63", formatCaps(1|1<<63))
+}
+
+func TestPrivCache(t *testing.T) {
+	t.Parallel()
+
+	dir := writeProcFs(t, map[uint32]proc{
+		100: {
+			capEff: "00000000a80425fb",
+			userns: 4026532000,
+			uidMap: "         0     100000      65536\n     65536    1000000          1\n",
+		},
+	})
+	c := newPrivCache(dir, time.Minute, 10)
+
+	p, err := c.get(100, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0xa80425fb), p.capEff)
+	assert.Equal(t, uint64(4026532000), p.userns)
+	assert.Equal(t, "0 100000 65536,65536 1000000 1", p.uidMap)
+
+	_, err = c.get(200, time.Now())
+	require.Error(t, err)
+}
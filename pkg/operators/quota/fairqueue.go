@@ -0,0 +1,205 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxDroppedKeys is the number of keys whose dropped events are counted
+// separately; the events of further keys are counted together
+const maxDroppedKeys = 1024
+
+// fairQueue decides which events are let through when there are more events
+// than the capacity per window. At the beginning of each window, the capacity
+// is shared between the keys seen in the previous one using weighted max-min
+// fairness: keys asking for less than their fair share get what they asked
+// for and the rest is split between the others according to their weights.
+// Capacity that is not reserved this way is given to whoever asks first, which
+// is also all new keys get during their first window.
+type fairQueue struct {
+	mu sync.Mutex
+
+	capacity uint64
+	window   time.Duration
+	weights  map[string]uint64
+
+	windowStart time.Time
+	// demand is the number of events seen per key in the current window
+	demand map[string]uint64
+	// used is the number of events let through per key in the current window
+	used map[string]uint64
+	// shares is the capacity reserved per key for the current window
+	shares map[string]uint64
+	// unreserved is the capacity left that isn't reserved for any key
+	unreserved uint64
+
+	// drops is the number of dropped events per key, for up to maxDroppedKeys
+	// keys; otherDrops counts the ones of the keys beyond
+	drops      map[string]uint64
+	otherDrops uint64
+
+	// onFirstDrop is called, while holding the lock, the first time an event
+	// of a key is dropped, if its drops are counted separately
+	onFirstDrop func(key string)
+
+	now func() time.Time
+}
+
+func newFairQueue(capacity uint64, window time.Duration, weights map[string]uint64) *fairQueue {
+	return &fairQueue{
+		capacity:   capacity,
+		window:     window,
+		weights:    weights,
+		demand:     make(map[string]uint64),
+		used:       make(map[string]uint64),
+		shares:     make(map[string]uint64),
+		unreserved: capacity,
+		drops:      make(map[string]uint64),
+		now:        time.Now,
+	}
+}
+
+func (q *fairQueue) weight(key string) uint64 {
+	if w, ok := q.weights[key]; ok {
+		return w
+	}
+	return 1
+}
+
+// allow returns whether an event of the given key can be let through
+func (q *fairQueue) allow(key string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	if now.Sub(q.windowStart) >= q.window {
+		q.rotate(now)
+	}
+
+	q.demand[key]++
+	if q.used[key] < q.shares[key] {
+		q.used[key]++
+		return true
+	}
+	if q.unreserved > 0 {
+		q.unreserved--
+		q.used[key]++
+		return true
+	}
+
+	if _, ok := q.drops[key]; !ok && len(q.drops) >= maxDroppedKeys {
+		q.otherDrops++
+		return false
+	}
+	if q.drops[key] == 0 && q.onFirstDrop != nil {
+		q.onFirstDrop(key)
+	}
+	q.drops[key]++
+	return false
+}
+
+// rotate starts a new window, computing the shares from the demand of the
+// previous one
+func (q *fairQueue) rotate(now time.Time) {
+	// Demand from a window older than the previous one isn't meaningful
+	// anymore
+	demand := q.demand
+	if now.Sub(q.windowStart) >= 2*q.window {
+		demand = nil
+	}
+
+	q.shares = fairShares(q.capacity, demand, q.weight)
+	q.unreserved = q.capacity
+	for _, share := range q.shares {
+		q.unreserved -= share
+	}
+	q.windowStart = now
+	q.demand = make(map[string]uint64, len(q.shares))
+	q.used = make(map[string]uint64, len(q.shares))
+}
+
+// fairShares splits the capacity between the keys using weighted max-min
+// fairness (water-filling)
+func fairShares(capacity uint64, demand map[string]uint64, weight func(string) uint64) map[string]uint64 {
+	shares := make(map[string]uint64, len(demand))
+	pending := slices.Sorted(maps.Keys(demand))
+	remaining := capacity
+
+	for len(pending) > 0 && remaining > 0 {
+		var totalWeight uint64
+		for _, key := range pending {
+			totalWeight += weight(key)
+		}
+
+		// Keys asking for less than their share get what they asked for
+		var unsatisfied []string
+		var granted uint64
+		for _, key := range pending {
+			if demand[key] <= remaining*weight(key)/totalWeight {
+				shares[key] = demand[key]
+				granted += demand[key]
+			} else {
+				unsatisfied = append(unsatisfied, key)
+			}
+		}
+
+		if len(unsatisfied) == len(pending) {
+			// Everybody wants more than their share, split what's left
+			for _, key := range pending {
+				shares[key] = remaining * weight(key) / totalWeight
+			}
+			break
+		}
+		remaining -= granted
+		pending = unsatisfied
+	}
+	return shares
+}
+
+type keyDrops struct {
+	key     string
+	dropped uint64
+}
+
+// topDropped returns the n keys with the most dropped events, in decreasing
+// order, and the number of events dropped for all other keys
+func (q *fairQueue) topDropped(n int) (top []keyDrops, others uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for key, dropped := range q.drops {
+		top = append(top, keyDrops{key: key, dropped: dropped})
+	}
+	slices.SortFunc(top, func(a, b keyDrops) int {
+		if c := cmp.Compare(b.dropped, a.dropped); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.key, b.key)
+	})
+
+	others = q.otherDrops
+	if len(top) > n {
+		for _, kd := range top[n:] {
+			others += kd.dropped
+		}
+		top = top[:n]
+	}
+	return top, others
+}
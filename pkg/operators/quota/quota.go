@@ -0,0 +1,283 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quota is a data operator that limits the number of events per second
+// of a gadget and shares this capacity fairly between the namespaces,
+// workloads or any other key the events belong to. Without it, a single chatty
+// pod could fill the event pipeline under sustained load and hide the events
+// of everything else.
+package quota
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/metrics"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	name             = "quota"
+	ParamMaxEvents   = "quota-max-events"
+	ParamKey         = "quota-key"
+	ParamWeights     = "quota-weights"
+	Priority         = 9100 // after the filter operator, filtered events don't use the quota
+	DefaultKey       = "k8s.namespace"
+	quotaWindow      = time.Second
+	keyFieldsSep     = ","
+	keyValuesSep     = "/"
+	droppedMetricKey = "ig_quota_dropped_events"
+
+	// topDroppedKeys is the number of keys whose dropped events are logged
+	// when the gadget stops
+	topDroppedKeys = 10
+)
+
+type quotaOperator struct {
+	ctrDropped metric.Int64Counter
+}
+
+func (q *quotaOperator) Name() string {
+	return name
+}
+
+func (q *quotaOperator) Init(params *params.Params) error {
+	q.ctrDropped, _ = metrics.Int64Counter(droppedMetricKey,
+		metric.WithUnit("{event}"),
+		metric.WithDescription("Number of events dropped because they exceeded the fair share of their key"),
+	)
+	return nil
+}
+
+func (q *quotaOperator) GlobalParams() api.Params {
+	return nil
+}
+
+func (q *quotaOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamMaxEvents,
+			Title: "Max Events",
+			Description: "The maximum number of events per second for each data source. " +
+				"The capacity is shared fairly between the keys given by " + ParamKey + ". " +
+				"Use 0 to disable the quota.",
+			DefaultValue: "0",
+			TypeHint:     api.TypeUint,
+		},
+		{
+			Key:   ParamKey,
+			Title: "Quota Key",
+			Description: "Comma-separated list of fields identifying who the events belong to, " +
+				"like k8s.namespace or k8s.namespace,k8s.podName.",
+			DefaultValue: DefaultKey,
+			TypeHint:     api.TypeString,
+		},
+		{
+			Key:   ParamWeights,
+			Title: "Quota Weights",
+			Description: "Weights of the keys when sharing the capacity, like 'kube-system:2,default:1'. " +
+				"Keys not listed have a weight of 1.",
+			TypeHint: api.TypeString,
+		},
+	}
+}
+
+func (q *quotaOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	params := apihelpers.ToParamDescs(q.InstanceParams()).ToParams()
+	if err := params.CopyFromMap(instanceParamValues, ""); err != nil {
+		return nil, err
+	}
+
+	maxEvents := params.Get(ParamMaxEvents).AsUint64()
+	if maxEvents == 0 {
+		return nil, nil
+	}
+
+	weights, err := parseWeights(params.Get(ParamWeights).AsString())
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ParamWeights, err)
+	}
+
+	var keyFields []string
+	for _, f := range strings.Split(params.Get(ParamKey).AsString(), keyFieldsSep) {
+		if f = strings.TrimSpace(f); f != "" {
+			keyFields = append(keyFields, f)
+		}
+	}
+	if len(keyFields) == 0 {
+		return nil, fmt.Errorf("%s can't be empty", ParamKey)
+	}
+
+	instance := &quotaOperatorInstance{
+		op:        q,
+		maxEvents: maxEvents,
+		keyFields: keyFields,
+		weights:   weights,
+	}
+	if reporter, ok := operators.GetStateReporter(gadgetCtx); ok {
+		instance.reporter = reporter
+	}
+	return instance, nil
+}
+
+func (q *quotaOperator) Priority() int {
+	return Priority
+}
+
+// parseWeights parses a list like "key1:2,key2:3"
+func parseWeights(s string) (map[string]uint64, error) {
+	weights := make(map[string]uint64)
+	if s == "" {
+		return weights, nil
+	}
+	values, err := apihelpers.GetIntValuesPerDataSource(s)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range values {
+		if k == "" {
+			return nil, fmt.Errorf("missing key for weight %d", v)
+		}
+		if v <= 0 {
+			return nil, fmt.Errorf("invalid weight for %q: %d", k, v)
+		}
+		weights[k] = uint64(v)
+	}
+	return weights, nil
+}
+
+type quotaOperatorInstance struct {
+	op        *quotaOperator
+	maxEvents uint64
+	keyFields []string
+	weights   map[string]uint64
+	reporter  operators.StateReporter
+	queues    []*fairQueue
+}
+
+func (q *quotaOperatorInstance) Name() string {
+	return name
+}
+
+func (q *quotaOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for _, ds := range gadgetCtx.GetDataSources() {
+		// Array data sources are emitted all at once, sort or limit them
+		// instead
+		if ds.Type() != datasource.TypeSingle {
+			continue
+		}
+
+		var keyFuncs []func(datasource.Data) (string, string)
+		for _, fieldName := range q.keyFields {
+			f := ds.GetField(fieldName)
+			if f == nil {
+				break
+			}
+			kf, err := datasource.GetKeyValueFunc[string, string](f, "", formatInt, formatFloat, formatString)
+			if err != nil {
+				return fmt.Errorf("using %q as %s: %w", fieldName, ParamKey, err)
+			}
+			keyFuncs = append(keyFuncs, kf)
+		}
+		if len(keyFuncs) != len(q.keyFields) {
+			gadgetCtx.Logger().Debugf("quota: data source %q doesn't have the fields %v, skipping", ds.Name(), q.keyFields)
+			continue
+		}
+
+		gadgetCtx.Logger().Debugf("quota: data source %q limited to %d events/s per %v", ds.Name(), q.maxEvents, q.keyFields)
+
+		fq := newFairQueue(q.maxEvents, quotaWindow, q.weights)
+		fq.onFirstDrop = func(key string) {
+			msg := fmt.Sprintf("dropping events of data source %q for %s=%q exceeding their fair share of %d events/s",
+				ds.Name(), strings.Join(q.keyFields, keyFieldsSep), key, q.maxEvents)
+			gadgetCtx.Logger().Warn(msg)
+			if q.reporter != nil {
+				q.reporter.ReportWarning(msg)
+			}
+		}
+		q.queues = append(q.queues, fq)
+
+		// The values of the key aren't bounded, so only the fields they're
+		// taken from are used as attribute
+		ctx := gadgetCtx.Context()
+		attrs := metric.WithAttributes(
+			attribute.String("datasource", ds.Name()),
+			attribute.String("key_fields", strings.Join(q.keyFields, keyFieldsSep)),
+		)
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			key := eventKey(keyFuncs, data)
+			if fq.allow(key) {
+				return nil
+			}
+			if q.op.ctrDropped != nil {
+				q.op.ctrDropped.Add(ctx, 1, attrs)
+			}
+			return datasource.ErrDiscard
+		}, Priority)
+	}
+	return nil
+}
+
+func formatInt(v int64) string     { return strconv.FormatInt(v, 10) }
+func formatFloat(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+func formatString(v string) string { return v }
+
+func eventKey(keyFuncs []func(datasource.Data) (string, string), data datasource.Data) string {
+	if len(keyFuncs) == 1 {
+		_, val := keyFuncs[0](data)
+		return val
+	}
+	vals := make([]string, 0, len(keyFuncs))
+	for _, kf := range keyFuncs {
+		_, val := kf(data)
+		vals = append(vals, val)
+	}
+	return strings.Join(vals, keyValuesSep)
+}
+
+func (q *quotaOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (q *quotaOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	for _, fq := range q.queues {
+		top, others := fq.topDropped(topDroppedKeys)
+		for _, kd := range top {
+			gadgetCtx.Logger().Infof("quota: dropped %d events for %q", kd.dropped, kd.key)
+		}
+		if others > 0 {
+			gadgetCtx.Logger().Infof("quota: dropped %d events for other keys", others)
+		}
+	}
+	return nil
+}
+
+func (q *quotaOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &quotaOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFairShares(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		capacity uint64
+		demand   map[string]uint64
+		weights  map[string]uint64
+		expected map[string]uint64
+	}
+
+	tests := map[string]testCase{
+		"no_demand": {
+			capacity: 100,
+			expected: map[string]uint64{},
+		},
+		"below_capacity": {
+			capacity: 100,
+			demand:   map[string]uint64{"a": 10, "b": 20},
+			expected: map[string]uint64{"a": 10, "b": 20},
+		},
+		"equal_split": {
+			capacity: 100,
+			demand:   map[string]uint64{"a": 1000, "b": 1000},
+			expected: map[string]uint64{"a": 50, "b": 50},
+		},
+		"chatty_key": {
+			capacity: 100,
+			demand:   map[string]uint64{"chatty": 10000, "a": 10, "b": 20},
+			expected: map[string]uint64{"chatty": 70, "a": 10, "b": 20},
+		},
+		"water_filling": {
+			capacity: 90,
+			demand:   map[string]uint64{"a": 20, "b": 40, "c": 1000},
+			expected: map[string]uint64{"a": 20, "b": 35, "c": 35},
+		},
+		"weights": {
+			capacity: 90,
+			demand:   map[string]uint64{"a": 1000, "b": 1000},
+			weights:  map[string]uint64{"a": 2},
+			expected: map[string]uint64{"a": 60, "b": 30},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			q := newFairQueue(test.capacity, time.Second, test.weights)
+			shares := fairShares(test.capacity, test.demand, q.weight)
+			require.Equal(t, test.expected, shares)
+		})
+	}
+}
+
+func TestFairQueue(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	q := newFairQueue(10, time.Second, nil)
+	q.now = func() time.Time { return now }
+
+	var firstDrops []string
+	q.onFirstDrop = func(key string) {
+		firstDrops = append(firstDrops, key)
+	}
+
+	emit := func(key string, n int) (allowed int) {
+		for range n {
+			if q.allow(key) {
+				allowed++
+			}
+		}
+		return allowed
+	}
+
+	// First window: no shares yet, the chatty key takes everything
+	require.Equal(t, 10, emit("chatty", 100))
+	require.Equal(t, 0, emit("quiet", 2))
+
+	// Next window: the quiet key gets its share, even if the chatty key
+	// comes first
+	now = now.Add(time.Second)
+	require.Equal(t, 8, emit("chatty", 100))
+	require.Equal(t, 2, emit("quiet", 2))
+
+	require.Equal(t, []string{"chatty", "quiet"}, firstDrops)
+	top, others := q.topDropped(10)
+	require.Equal(t, []keyDrops{{"chatty", 182}, {"quiet", 2}}, top)
+	require.Zero(t, others)
+
+	// After an idle period, the previous demand is forgotten
+	now = now.Add(5 * time.Second)
+	require.Equal(t, 10, emit("quiet", 10))
+}
+
+func TestTopDropped(t *testing.T) {
+	t.Parallel()
+
+	q := newFairQueue(0, time.Second, nil)
+	dropped := 0
+	q.onFirstDrop = func(key string) {
+		dropped++
+	}
+
+	for i := range maxDroppedKeys + 10 {
+		for range i%3 + 1 {
+			require.False(t, q.allow(fmt.Sprintf("key-%d", i)))
+		}
+	}
+	require.Equal(t, maxDroppedKeys, dropped)
+	require.Len(t, q.drops, maxDroppedKeys)
+
+	top, others := q.topDropped(2)
+	require.Equal(t, []keyDrops{{"key-1001", 3}, {"key-1004", 3}}, top)
+
+	total := uint64(0)
+	for i := range maxDroppedKeys + 10 {
+		total += uint64(i%3 + 1)
+	}
+	require.Equal(t, total-6, others)
+}
+
+func TestParseWeights(t *testing.T) {
+	t.Parallel()
+
+	weights, err := parseWeights("")
+	require.NoError(t, err)
+	require.Empty(t, weights)
+
+	weights, err = parseWeights("kube-system:2,default:1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{"kube-system": 2, "default": 1}, weights)
+
+	_, err = parseWeights("2")
+	require.Error(t, err)
+
+	_, err = parseWeights("kube-system:0")
+	require.Error(t, err)
+
+	_, err = parseWeights("kube-system:foo")
+	require.Error(t, err)
+}
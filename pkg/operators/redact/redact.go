@@ -0,0 +1,430 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redact is a data operator hashing, truncating or dropping single
+// fields of events before they leave the node, as configured by a policy of
+// the server, the parameters of the gadget or annotations of the fields.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	Name     = "redact"
+	Priority = 9310 // after the privacy operator, before sorting and exporting
+
+	ParamPolicy  = "redact-policy"
+	ParamHashKey = "redact-hash-key"
+	ParamFields  = "redact-fields"
+
+	// AnnotationRedact sets the action applied to a field, like `hash` or
+	// `truncate:8`
+	AnnotationRedact = "redact"
+
+	// RulesVarName is the name of the variable of the gadget context other
+	// operators can set to a []string of rules like the ones of ParamFields
+	// to apply them as well
+	RulesVarName = "redact.rules"
+
+	ActionTruncate = "truncate"
+	ActionHash     = "hash"
+	ActionMask     = "mask"
+	ActionDrop     = "drop"
+
+	// HashLength is the number of hex characters kept of hashes
+	HashLength = 16
+
+	// Masked is the value of the string fields masked by ActionMask
+	Masked = "[redacted]"
+)
+
+// actions are ordered from the least to the most strict
+var actions = []string{ActionTruncate, ActionHash, ActionMask, ActionDrop}
+
+// rule is the redaction applied to a field
+type rule struct {
+	// dataSource is the name of the data source of the field; empty for all
+	// data sources
+	dataSource string
+	field      string
+	action     string
+	// length is the number of bytes kept by ActionTruncate
+	length int
+}
+
+// stricter returns whether r hides more of the field than o
+func (r rule) stricter(o rule) bool {
+	ri, oi := slices.Index(actions, r.action), slices.Index(actions, o.action)
+	if ri != oi {
+		return ri > oi
+	}
+	return r.action == ActionTruncate && r.length < o.length
+}
+
+// parseAction parses an action like `hash`, `drop` or `truncate:8`
+func parseAction(s string) (string, int, error) {
+	action, arg, hasArg := strings.Cut(s, ":")
+	switch action {
+	case ActionHash, ActionMask, ActionDrop:
+		if hasArg {
+			return "", 0, fmt.Errorf("action %q doesn't take an argument", action)
+		}
+		return action, 0, nil
+	case ActionTruncate:
+		length, err := strconv.Atoi(arg)
+		if err != nil || length < 0 {
+			return "", 0, fmt.Errorf("action %q expects the number of bytes to keep, like %s:8", action, action)
+		}
+		return action, length, nil
+	}
+	return "", 0, fmt.Errorf("invalid action %q, expected one of %v", action, actions)
+}
+
+// parseRule parses a rule like `args=hash` or `dns:name=truncate:8`
+func parseRule(s string) (rule, error) {
+	field, action, ok := strings.Cut(s, "=")
+	if !ok || field == "" {
+		return rule{}, fmt.Errorf("invalid rule %q, expected [DATASOURCE:]FIELD=ACTION", s)
+	}
+	r := rule{field: field}
+	if dsName, fieldName, ok := strings.Cut(field, ":"); ok {
+		r.dataSource, r.field = dsName, fieldName
+	}
+	var err error
+	r.action, r.length, err = parseAction(action)
+	if err != nil {
+		return rule{}, fmt.Errorf("invalid rule %q: %w", s, err)
+	}
+	return r, nil
+}
+
+func parseRules(entries []string) ([]rule, error) {
+	rules := make([]rule, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		r, err := parseRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+type redactOperator struct {
+	policy  []rule
+	hashKey []byte
+}
+
+func (r *redactOperator) Name() string {
+	return Name
+}
+
+func (r *redactOperator) Init(params *params.Params) error {
+	policy, err := parseRules(params.Get(ParamPolicy).AsStringSlice())
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", ParamPolicy, err)
+	}
+	r.policy = policy
+	r.hashKey = []byte(params.Get(ParamHashKey).AsString())
+	return nil
+}
+
+func (r *redactOperator) GlobalParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamPolicy,
+			Title: "Redaction Policy",
+			Description: "Redaction applied to the fields of all gadgets, as a comma-separated list of " +
+				"[DATASOURCE:]FIELD=ACTION with the actions hash, truncate:LENGTH, mask and drop. Gadgets can only " +
+				"make it stricter",
+			TypeHint: api.TypeStringSlice,
+		},
+		{
+			Key:   ParamHashKey,
+			Title: "Redaction Hash Key",
+			Description: "Key of the HMAC-SHA256 used to hash fields, so hashed values can't be guessed " +
+				"by hashing likely values. Plain SHA-256 is used if empty",
+			TypeHint: api.TypeString,
+		},
+	}
+}
+
+func (r *redactOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamFields,
+			Title: "Redact Fields",
+			Description: "Fields to redact in addition to the policy of the server, as a comma-separated list of " +
+				"[DATASOURCE:]FIELD=ACTION. hash replaces the value by its hash, truncate:LENGTH keeps the " +
+				"first LENGTH bytes, mask replaces the value by " + Masked + " and drop removes the field",
+			TypeHint: api.TypeStringSlice,
+		},
+	}
+}
+
+func (r *redactOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	params := apihelpers.ToParamDescs(r.InstanceParams()).ToParams()
+	if err := params.CopyFromMap(instanceParamValues, ""); err != nil {
+		return nil, err
+	}
+	fields, err := parseRules(params.Get(ParamFields).AsStringSlice())
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ParamFields, err)
+	}
+	rules := append(slices.Clone(r.policy), fields...)
+	if v, ok := gadgetCtx.GetVar(RulesVarName); ok {
+		entries, ok := v.([]string)
+		if !ok {
+			return nil, fmt.Errorf("invalid type %T of %s", v, RulesVarName)
+		}
+		extra, err := parseRules(entries)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", RulesVarName, err)
+		}
+		rules = append(rules, extra...)
+	}
+
+	instance := &redactOperatorInstance{
+		redactions: make(map[datasource.DataSource][]*redaction),
+	}
+	for _, ds := range gadgetCtx.GetDataSources() {
+		dsRules, err := dataSourceRules(ds, rules)
+		if err != nil {
+			return nil, err
+		}
+		for _, rl := range dsRules {
+			f := ds.GetField(rl.field)
+			red, err := newRedaction(f, rl, r.hashKey)
+			if err != nil {
+				return nil, fmt.Errorf("redacting field %q of %q: %w", rl.field, ds.Name(), err)
+			}
+			gadgetCtx.Logger().Debugf("redact: applying %s to field %s of %s", rl.action, rl.field, ds.Name())
+			if rl.action == ActionDrop {
+				// Removing the reference hides the field from the output and
+				// clearing it in the subscription keeps its value from being
+				// sent to remote clients
+				f.RemoveReference(true)
+			}
+			instance.redactions[ds] = append(instance.redactions[ds], red)
+		}
+	}
+	if len(instance.redactions) == 0 {
+		return nil, nil
+	}
+	return instance, nil
+}
+
+// dataSourceRules returns the rules applying to the fields of a data source,
+// from the annotations of its fields and the given rules. The strictest rule
+// is kept for fields with more than one; rules of fields the data source
+// doesn't have are ignored.
+func dataSourceRules(ds datasource.DataSource, rules []rule) ([]rule, error) {
+	byField := make(map[string]rule)
+	var order []string
+	add := func(r rule) {
+		prev, ok := byField[r.field]
+		if !ok {
+			order = append(order, r.field)
+		}
+		if !ok || r.stricter(prev) {
+			byField[r.field] = r
+		}
+	}
+
+	for _, f := range ds.Accessors(false) {
+		annotation, ok := f.Annotations()[AnnotationRedact]
+		if !ok {
+			continue
+		}
+		action, length, err := parseAction(annotation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation of field %q of %q: %w", AnnotationRedact, f.FullName(), ds.Name(), err)
+		}
+		add(rule{field: f.FullName(), action: action, length: length})
+	}
+	for _, r := range rules {
+		if r.dataSource != "" && r.dataSource != ds.Name() {
+			continue
+		}
+		if ds.GetField(r.field) == nil {
+			continue
+		}
+		add(r)
+	}
+
+	res := make([]rule, 0, len(order))
+	for _, field := range order {
+		res = append(res, byField[field])
+	}
+	return res, nil
+}
+
+// redaction applies a rule to a field of the events
+type redaction struct {
+	field datasource.FieldAccessor
+	rule  rule
+	// newHash returns the hash used by ActionHash
+	newHash func() hash.Hash
+}
+
+func newRedaction(f datasource.FieldAccessor, r rule, hashKey []byte) (*redaction, error) {
+	red := &redaction{field: f, rule: r}
+	switch r.action {
+	case ActionHash:
+		if !isString(f) {
+			return nil, fmt.Errorf("can't hash fields of type %s", f.Type())
+		}
+		red.newHash = sha256.New
+		if len(hashKey) > 0 {
+			red.newHash = func() hash.Hash {
+				return hmac.New(sha256.New, hashKey)
+			}
+		}
+	case ActionTruncate:
+		if !isString(f) && f.Type() != api.Kind_Bytes {
+			return nil, fmt.Errorf("can't truncate fields of type %s", f.Type())
+		}
+	}
+	return red, nil
+}
+
+func isString(f datasource.FieldAccessor) bool {
+	return f.Type() == api.Kind_String || f.Type() == api.Kind_CString
+}
+
+// putString sets the value of a string field, shortened to fit statically
+// sized ones, like members of eBPF structs, with their null terminator
+func putString(f datasource.FieldAccessor, data datasource.Data, s string) error {
+	if size := int(f.Size()); size > 0 && len(s) >= size {
+		s = s[:size-1]
+	}
+	return f.PutString(data, s)
+}
+
+func (r *redaction) apply(data datasource.Data) error {
+	f := r.field
+	switch r.rule.action {
+	case ActionDrop:
+		return clearField(f, data)
+	case ActionMask:
+		if !isString(f) {
+			return clearField(f, data)
+		}
+		s, err := f.String(data)
+		if err != nil || s == "" {
+			return err
+		}
+		return putString(f, data, Masked)
+	case ActionHash:
+		s, err := f.String(data)
+		if err != nil || s == "" {
+			return err
+		}
+		h := r.newHash()
+		h.Write([]byte(s))
+		return putString(f, data, hex.EncodeToString(h.Sum(nil))[:HashLength])
+	case ActionTruncate:
+		if isString(f) {
+			s, err := f.String(data)
+			if err != nil || len(s) <= r.rule.length {
+				return err
+			}
+			return putString(f, data, s[:r.rule.length])
+		}
+		b := f.Get(data)
+		if len(b) <= r.rule.length {
+			return nil
+		}
+		if f.Size() == 0 {
+			return f.Set(data, slices.Clone(b[:r.rule.length]))
+		}
+		clear(b[r.rule.length:])
+	}
+	return nil
+}
+
+func clearField(f datasource.FieldAccessor, data datasource.Data) error {
+	switch f.Type() {
+	case api.Kind_String, api.Kind_CString, api.Kind_Bytes:
+		if f.Size() == 0 {
+			return f.Set(data, nil)
+		}
+	}
+	// Fixed size values are zeroed in place
+	clear(f.Get(data))
+	return nil
+}
+
+func (r *redactOperator) Priority() int {
+	return Priority
+}
+
+type redactOperatorInstance struct {
+	redactions map[datasource.DataSource][]*redaction
+}
+
+func (r *redactOperatorInstance) Name() string {
+	return Name
+}
+
+func (r *redactOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, redactions := range r.redactions {
+		err := ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			for _, red := range redactions {
+				if err := red.apply(data); err != nil {
+					return fmt.Errorf("redacting %s: %w", red.field.FullName(), err)
+				}
+			}
+			return nil
+		}, Priority)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *redactOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (r *redactOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (r *redactOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func init() {
+	operators.RegisterDataOperator(&redactOperator{})
+}
@@ -0,0 +1,253 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redact
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rule     string
+		expected rule
+		wantErr  bool
+	}{
+		{
+			rule:     "args=hash",
+			expected: rule{field: "args", action: ActionHash},
+		},
+		{
+			rule:     "dns:name=truncate:8",
+			expected: rule{dataSource: "dns", field: "name", action: ActionTruncate, length: 8},
+		},
+		{
+			rule:     "k8s.podLabels=drop",
+			expected: rule{field: "k8s.podLabels", action: ActionDrop},
+		},
+		{rule: "args", wantErr: true},
+		{rule: "=hash", wantErr: true},
+		{rule: "args=encrypt", wantErr: true},
+		{rule: "args=truncate", wantErr: true},
+		{rule: "args=truncate:-1", wantErr: true},
+		{rule: "args=hash:8", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule, func(t *testing.T) {
+			r, err := parseRule(test.rule)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, r)
+		})
+	}
+}
+
+func TestStricter(t *testing.T) {
+	drop := rule{action: ActionDrop}
+	mask := rule{action: ActionMask}
+	hash := rule{action: ActionHash}
+	truncate4 := rule{action: ActionTruncate, length: 4}
+	truncate8 := rule{action: ActionTruncate, length: 8}
+
+	assert.True(t, drop.stricter(mask))
+	assert.True(t, mask.stricter(hash))
+	assert.True(t, hash.stricter(truncate4))
+	assert.True(t, truncate4.stricter(truncate8))
+	assert.False(t, truncate8.stricter(truncate4))
+	assert.False(t, hash.stricter(hash))
+}
+
+func TestInitInvalidPolicy(t *testing.T) {
+	o := &redactOperator{}
+	globalParams := apihelpers.ToParamDescs(o.GlobalParams()).ToParams()
+	require.NoError(t, globalParams.Set(ParamPolicy, "args=encrypt"))
+	require.Error(t, o.Init(globalParams))
+}
+
+type event struct {
+	args     string
+	name     string
+	data     []byte
+	loginuid uint32
+}
+
+func run(t *testing.T, policy, hashKey string, paramValues api.ParamValues) ([]event, datasource.DataSource) {
+	t.Helper()
+
+	o := &redactOperator{}
+	globalParams := apihelpers.ToParamDescs(o.GlobalParams()).ToParams()
+	require.NoError(t, globalParams.Set(ParamPolicy, policy))
+	require.NoError(t, globalParams.Set(ParamHashKey, hashKey))
+	require.NoError(t, o.Init(globalParams))
+
+	var ds datasource.DataSource
+	var argsField, nameField, dataField, loginuidField datasource.FieldAccessor
+	var got []event
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "dns")
+		require.NoError(t, err)
+		argsField, err = ds.AddField("args", api.Kind_String)
+		require.NoError(t, err)
+		nameField, err = ds.AddField("name", api.Kind_String, datasource.WithAnnotations(map[string]string{
+			AnnotationRedact: "truncate:8",
+		}))
+		require.NoError(t, err)
+		dataField, err = ds.AddField("data", api.Kind_Bytes)
+		require.NoError(t, err)
+		loginuidField, err = ds.AddField("loginuid", api.Kind_Uint32)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for _, args := range []string{"--password=secret", ""} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, argsField.PutString(data, args))
+			require.NoError(t, nameField.PutString(data, "internal.example.com."))
+			require.NoError(t, dataField.PutBytes(data, []byte{1, 2, 3, 4, 5, 6}))
+			require.NoError(t, loginuidField.PutUint32(data, 1000))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		cancel()
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		ds := gadgetCtx.GetDataSources()["dns"]
+		require.NotNil(t, ds)
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			var e event
+			var err error
+			e.args, err = argsField.String(data)
+			require.NoError(t, err)
+			e.name, err = nameField.String(data)
+			require.NoError(t, err)
+			e.data, err = dataField.Bytes(data)
+			require.NoError(t, err)
+			e.loginuid, err = loginuidField.Uint32(data)
+			require.NoError(t, err)
+			got = append(got, e)
+			return nil
+		}, Priority+1)
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(o, producer, consumer))
+	require.NoError(t, gadgetCtx.Run(paramValues))
+
+	return got, ds
+}
+
+func TestRedact(t *testing.T) {
+	sum := sha256.Sum256([]byte("--password=secret"))
+	hashed := hex.EncodeToString(sum[:])[:HashLength]
+
+	got, ds := run(t, "args=hash,dns:data=truncate:2", "", api.ParamValues{
+		"operator." + Name + "." + ParamFields: "loginuid=drop,name=truncate:12",
+	})
+	require.Len(t, got, 2)
+
+	// The annotation is stricter than the parameter
+	assert.Equal(t, event{args: hashed, name: "internal", data: []byte{1, 2}}, got[0])
+	// Empty values aren't hashed
+	assert.Equal(t, event{name: "internal", data: []byte{1, 2}}, got[1])
+	assert.Nil(t, ds.GetField("loginuid"))
+}
+
+func TestRedactHashKey(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte("--password=secret"))
+	hashed := hex.EncodeToString(mac.Sum(nil))[:HashLength]
+
+	got, _ := run(t, "", "key", api.ParamValues{
+		"operator." + Name + "." + ParamFields: "args=hash",
+	})
+	require.Len(t, got, 2)
+	assert.Equal(t, hashed, got[0].args)
+}
+
+func TestRedactMask(t *testing.T) {
+	got, _ := run(t, "", "", api.ParamValues{
+		"operator." + Name + "." + ParamFields: "args=mask,data=mask,loginuid=mask",
+	})
+	require.Len(t, got, 2)
+	assert.Equal(t, event{args: Masked, name: "internal"}, got[0])
+	// Empty values aren't masked
+	assert.Equal(t, event{name: "internal"}, got[1])
+}
+
+func TestRedactPolicyOfOtherDataSource(t *testing.T) {
+	got, ds := run(t, "exec:args=drop", "", api.ParamValues{})
+	require.Len(t, got, 2)
+	assert.Equal(t, "--password=secret", got[0].args)
+	assert.NotNil(t, ds.GetField("args"))
+}
+
+func TestRedactInvalidType(t *testing.T) {
+	o := &redactOperator{}
+	require.NoError(t, o.Init(apihelpers.ToParamDescs(o.GlobalParams()).ToParams()))
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(func(gadgetCtx operators.GadgetContext) error {
+			ds, err := gadgetCtx.RegisterDataSource(datasource.TypeSingle, "dns")
+			require.NoError(t, err)
+			_, err = ds.AddField("loginuid", api.Kind_Uint32)
+			require.NoError(t, err)
+			return nil
+		}),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(o, producer))
+	require.Error(t, gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamFields: "loginuid=hash",
+	}))
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operators
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ResourceLimitsVarName is the name of the gadget context variable holding
+// the ResourceLimits of the gadget, if any
+const ResourceLimitsVarName string = "resourceLimits"
+
+// ResourceUsage holds the eBPF resources used by a gadget
+type ResourceUsage struct {
+	Programs int
+	Maps     int
+
+	// MapMemory is the memory locked by the maps, ring buffers apart
+	MapMemory uint64
+
+	// BufferMemory is the memory of the ring buffers and perf buffers
+	// gadgets send their events with
+	BufferMemory uint64
+}
+
+// ResourceLimits limits the eBPF resources each gadget can use, so a gadget
+// can't exhaust the locked memory of a node; zero values disable a limit
+type ResourceLimits struct {
+	MaxPrograms     int
+	MaxMapMemory    uint64
+	MaxBufferMemory uint64
+}
+
+// Enabled returns whether any of the limits is set
+func (l ResourceLimits) Enabled() bool {
+	return l != ResourceLimits{}
+}
+
+// Check returns an error describing the limits exceeded by usage, if any
+func (l ResourceLimits) Check(usage ResourceUsage) error {
+	var errs []error
+	if l.MaxPrograms > 0 && usage.Programs > l.MaxPrograms {
+		errs = append(errs, fmt.Errorf("%d eBPF programs exceed the limit of %d", usage.Programs, l.MaxPrograms))
+	}
+	if l.MaxMapMemory > 0 && usage.MapMemory > l.MaxMapMemory {
+		errs = append(errs, fmt.Errorf("%d bytes of map memory exceed the limit of %d", usage.MapMemory, l.MaxMapMemory))
+	}
+	if l.MaxBufferMemory > 0 && usage.BufferMemory > l.MaxBufferMemory {
+		errs = append(errs, fmt.Errorf("%d bytes of buffer memory exceed the limit of %d", usage.BufferMemory, l.MaxBufferMemory))
+	}
+	return errors.Join(errs...)
+}
+
+// GetResourceLimits returns the ResourceLimits of the gadget context, if any
+func GetResourceLimits(gadgetCtx GadgetContext) (ResourceLimits, bool) {
+	v, ok := gadgetCtx.GetVar(ResourceLimitsVarName)
+	if !ok {
+		return ResourceLimits{}, false
+	}
+	limits, ok := v.(ResourceLimits)
+	return limits, ok
+}
+
+// ResourceReporter can be implemented by the StateReporter of a gadget
+// context to make the resources used by the gadget visible in its state
+type ResourceReporter interface {
+	ReportResources(usage ResourceUsage)
+}
+
+// GetResourceReporter returns the ResourceReporter of the gadget context, if
+// any
+func GetResourceReporter(gadgetCtx GadgetContext) (ResourceReporter, bool) {
+	v, ok := gadgetCtx.GetVar(StateReporterVarName)
+	if !ok {
+		return nil, false
+	}
+	reporter, ok := v.(ResourceReporter)
+	return reporter, ok
+}
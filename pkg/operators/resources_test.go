@@ -0,0 +1,36 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operators
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceLimitsCheck(t *testing.T) {
+	usage := ResourceUsage{Programs: 4, Maps: 2, MapMemory: 1024, BufferMemory: 4096}
+
+	assert.False(t, ResourceLimits{}.Enabled())
+	require.NoError(t, ResourceLimits{}.Check(usage))
+	require.NoError(t, ResourceLimits{MaxPrograms: 4, MaxMapMemory: 1024, MaxBufferMemory: 4096}.Check(usage))
+
+	err := ResourceLimits{MaxPrograms: 3, MaxBufferMemory: 1024}.Check(usage)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "4 eBPF programs exceed the limit of 3")
+	assert.Contains(t, err.Error(), "4096 bytes of buffer memory exceed the limit of 1024")
+	assert.NotContains(t, err.Error(), "map memory")
+}
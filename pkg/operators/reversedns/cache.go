@@ -0,0 +1,105 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reversedns
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	ip      string
+	name    string
+	expires time.Time
+}
+
+// cache holds the names of the IP addresses, with an empty name for the ones
+// without any. It keeps at most maxEntries names, evicting the least recently
+// used ones. Names expire ttl after they were looked up, and missing names
+// negativeTTL after.
+type cache struct {
+	mu sync.Mutex
+
+	maxEntries  int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	entries map[string]*list.Element
+	// order holds the entries from the most to the least recently used
+	order *list.List
+}
+
+func newCache(maxEntries int, ttl, negativeTTL time.Duration) *cache {
+	return &cache{
+		maxEntries:  maxEntries,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+	}
+}
+
+func (c *cache) set(ip, name string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ttl := c.ttl
+	if name == "" {
+		ttl = c.negativeTTL
+	}
+	e := &entry{ip: ip, name: name, expires: now.Add(ttl)}
+
+	if el, ok := c.entries[ip]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+	} else {
+		c.entries[ip] = c.order.PushFront(e)
+	}
+
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// get returns the name of an IP address and whether it's in the cache, which
+// can be the case with an empty name
+func (c *cache) get(ip string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[ip]
+	if !ok {
+		return "", false
+	}
+	e := el.Value.(*entry)
+	if !now.Before(e.expires) {
+		c.remove(el)
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return e.name, true
+}
+
+func (c *cache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *cache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*entry).ip)
+}
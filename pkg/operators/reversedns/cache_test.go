@@ -0,0 +1,71 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reversedns
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	c := newCache(2, time.Minute, 10*time.Second)
+
+	c.set("1.1.1.1", "one.one.one.one", now)
+	c.set("10.0.0.1", "", now)
+
+	name, ok := c.get("1.1.1.1", now)
+	require.True(t, ok)
+	assert.Equal(t, "one.one.one.one", name)
+
+	// Negative entries are cached too, with a shorter TTL
+	name, ok = c.get("10.0.0.1", now.Add(5*time.Second))
+	require.True(t, ok)
+	assert.Empty(t, name)
+	_, ok = c.get("10.0.0.1", now.Add(10*time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.len())
+
+	_, ok = c.get("1.1.1.1", now.Add(time.Minute))
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.len())
+}
+
+func TestCacheEviction(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	c := newCache(2, time.Minute, time.Minute)
+
+	c.set("1.1.1.1", "a", now)
+	c.set("2.2.2.2", "b", now)
+	// Using 1.1.1.1 makes 2.2.2.2 the least recently used one
+	_, ok := c.get("1.1.1.1", now)
+	require.True(t, ok)
+	c.set("3.3.3.3", "c", now)
+
+	assert.Equal(t, 2, c.len())
+	_, ok = c.get("2.2.2.2", now)
+	assert.False(t, ok)
+	_, ok = c.get("1.1.1.1", now)
+	assert.True(t, ok)
+	_, ok = c.get("3.3.3.3", now)
+	assert.True(t, ok)
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reversedns
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// lookupAddrFunc returns the names of an IP address, like
+// net.Resolver.LookupAddr
+type lookupAddrFunc func(ctx context.Context, addr string) ([]string, error)
+
+// resolver looks up the names of IP addresses in the background, so events
+// aren't delayed by slow DNS servers, and caches them
+type resolver struct {
+	cache      *cache
+	lookupAddr lookupAddrFunc
+	timeout    time.Duration
+
+	mu          sync.Mutex
+	inflight    map[string]struct{}
+	maxInflight int
+}
+
+func newResolver(c *cache, lookupAddr lookupAddrFunc, timeout time.Duration, maxInflight int) *resolver {
+	return &resolver{
+		cache:       c,
+		lookupAddr:  lookupAddr,
+		timeout:     timeout,
+		inflight:    make(map[string]struct{}),
+		maxInflight: maxInflight,
+	}
+}
+
+// resolve returns the name of an IP address if it's cached. Otherwise, it
+// starts looking it up, unless it's already being looked up or too many
+// lookups are in flight, and returns false.
+func (r *resolver) resolve(ip string) (string, bool) {
+	if name, ok := r.cache.get(ip, time.Now()); ok {
+		return name, true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.inflight[ip]; ok || len(r.inflight) >= r.maxInflight {
+		return "", false
+	}
+	r.inflight[ip] = struct{}{}
+	go r.lookup(ip)
+	return "", false
+}
+
+func (r *resolver) lookup(ip string) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	// Errors, like addresses without names or timeouts, are cached as well,
+	// so they aren't looked up for every event
+	var name string
+	names, err := r.lookupAddr(ctx, ip)
+	if err != nil {
+		log.Debugf("reversedns: looking up %s: %v", ip, err)
+	} else if len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	}
+	r.cache.set(ip, name, time.Now())
+
+	r.mu.Lock()
+	delete(r.inflight, ip)
+	r.mu.Unlock()
+}
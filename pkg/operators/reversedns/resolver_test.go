@@ -0,0 +1,97 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reversedns
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver(t *testing.T) {
+	t.Parallel()
+
+	var lookups atomic.Int32
+	lookupAddr := func(ctx context.Context, addr string) ([]string, error) {
+		lookups.Add(1)
+		if addr == "1.1.1.1" {
+			return []string{"one.one.one.one.", "other.example."}, nil
+		}
+		return nil, errors.New("not found")
+	}
+	r := newResolver(newCache(10, time.Minute, time.Minute), lookupAddr, time.Second, 10)
+
+	// Names are looked up in the background
+	_, ok := r.resolve("1.1.1.1")
+	assert.False(t, ok)
+	require.Eventually(t, func() bool {
+		_, ok := r.resolve("1.1.1.1")
+		return ok
+	}, time.Second, time.Millisecond)
+	name, _ := r.resolve("1.1.1.1")
+	assert.Equal(t, "one.one.one.one", name)
+
+	// Failures are cached as empty names
+	r.resolve("10.0.0.1")
+	require.Eventually(t, func() bool {
+		_, ok := r.resolve("10.0.0.1")
+		return ok
+	}, time.Second, time.Millisecond)
+	name, _ = r.resolve("10.0.0.1")
+	assert.Empty(t, name)
+
+	assert.Equal(t, int32(2), lookups.Load())
+}
+
+func TestResolverMaxInflight(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	var lookups atomic.Int32
+	lookupAddr := func(ctx context.Context, addr string) ([]string, error) {
+		lookups.Add(1)
+		<-release
+		return []string{addr + ".example."}, nil
+	}
+	r := newResolver(newCache(10, time.Minute, time.Minute), lookupAddr, time.Second, 2)
+
+	for _, ip := range []string{"1.1.1.1", "1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		_, ok := r.resolve(ip)
+		assert.False(t, ok)
+	}
+	close(release)
+
+	// The lookup of 1.1.1.1 was started once and the one of 3.3.3.3 wasn't
+	// as two lookups were already in flight
+	require.Eventually(t, func() bool {
+		_, ok1 := r.resolve("1.1.1.1")
+		_, ok2 := r.resolve("2.2.2.2")
+		return ok1 && ok2
+	}, time.Second, time.Millisecond)
+	name, _ := r.resolve("1.1.1.1")
+	assert.Equal(t, "1.1.1.1.example", name)
+	assert.Equal(t, int32(2), lookups.Load())
+
+	// Once they finished, it's looked up
+	require.Eventually(t, func() bool {
+		_, ok := r.resolve("3.3.3.3")
+		return ok
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,229 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reversedns is a data operator that enriches the endpoints of the
+// events with the names of their IP addresses, looked up with the resolver of
+// the node. Lookups happen in the background and their results are cached, so
+// the first events of an address don't have its name.
+package reversedns
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/common"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	Name     = "reversedns"
+	Priority = 30 // after the KubeIPResolver and geoip operators enriching endpoints too
+
+	ParamEnable      = "reversedns-enable"
+	ParamCacheSize   = "reversedns-cache-size"
+	ParamTTL         = "reversedns-ttl"
+	ParamNegativeTTL = "reversedns-negative-ttl"
+	ParamMaxInflight = "reversedns-max-inflight"
+	ParamTimeout     = "reversedns-timeout"
+)
+
+type reverseDNSOperator struct {
+	resolver *resolver
+}
+
+func (r *reverseDNSOperator) Name() string {
+	return Name
+}
+
+func (r *reverseDNSOperator) Init(params *params.Params) error {
+	cacheSize := params.Get(ParamCacheSize).AsInt()
+	if cacheSize <= 0 {
+		return fmt.Errorf("%s must be positive", ParamCacheSize)
+	}
+	maxInflight := params.Get(ParamMaxInflight).AsInt()
+	if maxInflight <= 0 {
+		return fmt.Errorf("%s must be positive", ParamMaxInflight)
+	}
+
+	// The cache is shared by all the gadgets, so names are looked up once
+	c := newCache(cacheSize, params.Get(ParamTTL).AsDuration(), params.Get(ParamNegativeTTL).AsDuration())
+	r.resolver = newResolver(c, net.DefaultResolver.LookupAddr, params.Get(ParamTimeout).AsDuration(), maxInflight)
+	return nil
+}
+
+func (r *reverseDNSOperator) GlobalParams() api.Params {
+	return api.Params{
+		{
+			Key:          ParamCacheSize,
+			Title:        "Cache Size",
+			Description:  "Maximum number of IP addresses whose names are cached",
+			DefaultValue: "10000",
+			TypeHint:     api.TypeInt,
+		},
+		{
+			Key:          ParamTTL,
+			Title:        "TTL",
+			Description:  "Time names are cached for",
+			DefaultValue: "5m",
+			TypeHint:     api.TypeDuration,
+		},
+		{
+			Key:          ParamNegativeTTL,
+			Title:        "Negative TTL",
+			Description:  "Time failed lookups, like the ones of IP addresses without names, are cached for",
+			DefaultValue: "1m",
+			TypeHint:     api.TypeDuration,
+		},
+		{
+			Key:          ParamMaxInflight,
+			Title:        "Maximum In-flight Lookups",
+			Description:  "Maximum number of lookups running at the same time. Addresses aren't looked up while it's reached.",
+			DefaultValue: "32",
+			TypeHint:     api.TypeInt,
+		},
+		{
+			Key:          ParamTimeout,
+			Title:        "Timeout",
+			Description:  "Timeout of the lookups",
+			DefaultValue: "2s",
+			TypeHint:     api.TypeDuration,
+		},
+	}
+}
+
+func (r *reverseDNSOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:          ParamEnable,
+			Title:        "Reverse DNS",
+			Description:  "Add the names of the IP addresses of the endpoints, looked up with the resolver of the node",
+			DefaultValue: "false",
+			TypeHint:     api.TypeBool,
+		},
+	}
+}
+
+// endpointAccessors holds the fields of an endpoint and the one added to it
+type endpointAccessors struct {
+	ip      datasource.FieldAccessor
+	version datasource.FieldAccessor
+
+	dnsName datasource.FieldAccessor
+}
+
+func (r *reverseDNSOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	params := apihelpers.ToParamDescs(r.InstanceParams()).ToParams()
+	if err := params.CopyFromMap(instanceParamValues, ""); err != nil {
+		return nil, err
+	}
+	if !params.Get(ParamEnable).AsBool() {
+		return nil, nil
+	}
+
+	epAccessors := make(map[datasource.DataSource][]endpointAccessors)
+	for _, ds := range gadgetCtx.GetDataSources() {
+		endpoints := ds.GetFieldsWithTag("type:"+ebpftypes.L3EndpointTypeName, "type:"+ebpftypes.L4EndpointTypeName)
+		for _, ep := range endpoints {
+			ips := ep.GetSubFieldsWithTag("type:" + ebpftypes.IPAddrTypeName)
+			if len(ips) != 1 {
+				return nil, fmt.Errorf("%s: expected one %q field", ep.FullName(), ebpftypes.IPAddrTypeName)
+			}
+			version := ep.GetSubFieldsWithTag("name:version")
+			if len(version) != 1 {
+				return nil, fmt.Errorf("%s: expected one %q field", ep.FullName(), "version")
+			}
+
+			dnsName, err := ep.AddSubField("dns_name", api.Kind_String,
+				datasource.WithAnnotations(map[string]string{
+					metadatav1.DescriptionAnnotation:     "Name of the IP address, from a reverse DNS lookup",
+					metadatav1.ColumnsMaxWidthAnnotation: "40",
+				}),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("%s: adding field %q: %w", ep.FullName(), "dns_name", err)
+			}
+			epAccessors[ds] = append(epAccessors[ds], endpointAccessors{
+				ip:      ips[0],
+				version: version[0],
+				dnsName: dnsName,
+			})
+		}
+	}
+	if len(epAccessors) == 0 {
+		return nil, nil
+	}
+
+	return &reverseDNSOperatorInstance{
+		resolver:           r.resolver,
+		endpointsAccessors: epAccessors,
+	}, nil
+}
+
+func (r *reverseDNSOperator) Priority() int {
+	return Priority
+}
+
+type reverseDNSOperatorInstance struct {
+	resolver           *resolver
+	endpointsAccessors map[datasource.DataSource][]endpointAccessors
+}
+
+func (r *reverseDNSOperatorInstance) Name() string {
+	return Name
+}
+
+func (r *reverseDNSOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, accessors := range r.endpointsAccessors {
+		err := ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			for _, ea := range accessors {
+				addr, err := common.GetIPForVersion(data, ea.version, ea.ip)
+				if err != nil || net.ParseIP(addr).IsUnspecified() {
+					continue
+				}
+				if name, ok := r.resolver.resolve(addr); ok {
+					ea.dnsName.PutString(data, name)
+				}
+			}
+			return nil
+		}, Priority)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *reverseDNSOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (r *reverseDNSOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (r *reverseDNSOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &reverseDNSOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reversedns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func runReverseDNS(t *testing.T, op *reverseDNSOperator, paramValues api.ParamValues) (datasource.DataSource, []string) {
+	var ds datasource.DataSource
+	var ipField, versionField datasource.FieldAccessor
+	var got []string
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	addrs := []string{"1.1.1.1", "10.0.0.1", "0.0.0.0"}
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "tcp")
+		require.NoError(t, err)
+		dst, err := ds.AddField("dst", api.Kind_Invalid, datasource.WithTags("type:"+ebpftypes.L4EndpointTypeName))
+		require.NoError(t, err)
+		ipField, err = dst.AddSubField("addr_raw", api.Kind_Bytes, datasource.WithTags("type:"+ebpftypes.IPAddrTypeName))
+		require.NoError(t, err)
+		versionField, err = dst.AddSubField("version", api.Kind_Uint8, datasource.WithTags("name:version"))
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for _, addr := range addrs {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			ip := make([]byte, 16)
+			copy(ip, net.ParseIP(addr).To4())
+			require.NoError(t, ipField.PutBytes(data, ip))
+			require.NoError(t, versionField.PutUint8(data, 4))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		dnsName := ds.GetField("dst.dns_name")
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			var name string
+			if dnsName != nil {
+				var err error
+				name, err = dnsName.String(data)
+				require.NoError(t, err)
+			}
+			got = append(got, name)
+			if len(got) == len(addrs) {
+				cancel()
+			}
+			return nil
+		}, Priority+1)
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(op, producer, consumer))
+	require.NoError(t, gadgetCtx.Run(paramValues))
+	return ds, got
+}
+
+func TestReverseDNS(t *testing.T) {
+	lookedUp := make(chan string, 10)
+	lookupAddr := func(ctx context.Context, addr string) ([]string, error) {
+		lookedUp <- addr
+		return nil, errors.New("not found")
+	}
+	c := newCache(10, time.Minute, time.Minute)
+	c.set("1.1.1.1", "one.one.one.one", time.Now())
+	op := &reverseDNSOperator{resolver: newResolver(c, lookupAddr, time.Second, 10)}
+
+	_, got := runReverseDNS(t, op, api.ParamValues{
+		"operator." + Name + "." + ParamEnable: "true",
+	})
+	// 10.0.0.1 isn't cached yet and unspecified addresses aren't looked up
+	assert.Equal(t, []string{"one.one.one.one", "", ""}, got)
+
+	select {
+	case addr := <-lookedUp:
+		assert.Equal(t, "10.0.0.1", addr)
+	case <-time.After(time.Second):
+		t.Fatal("10.0.0.1 wasn't looked up")
+	}
+	assert.Empty(t, lookedUp)
+}
+
+func TestReverseDNSDisabled(t *testing.T) {
+	c := newCache(10, time.Minute, time.Minute)
+	op := &reverseDNSOperator{resolver: newResolver(c, nil, time.Second, 10)}
+
+	ds, got := runReverseDNS(t, op, api.ParamValues{})
+	assert.Nil(t, ds.GetField("dst.dns_name"))
+	assert.Equal(t, []string{"", "", ""}, got)
+}
@@ -0,0 +1,337 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package routing is a data operator that decides which exporters the events
+// of a gadget instance are sent to, based on their fields. This way a single
+// instance can send, for instance, security-relevant events to a SIEM and all
+// of them to long-term storage. The exporters use GetRouter to get the routes
+// of a data source.
+package routing
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/expr-lang/expr/vm"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/expr"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	Name     = "routing"
+	Priority = 9990 // before the exporters
+
+	ParamRoutes = "routing-routes"
+
+	// routerVarPrefix is the prefix of the gadget context variables holding
+	// the Router of each data source
+	routerVarPrefix = "routing.router."
+)
+
+// route sends the events of a data source matching an expression to an
+// exporter
+type route struct {
+	dataSource string
+	exporter   string
+	expression string
+}
+
+// parseRoutes parses routes separated by semicolons or new lines like
+// "EXPORTER=EXPRESSION" or "DATASOURCE:EXPORTER=EXPRESSION". Events are sent to
+// EXPORTER if EXPRESSION, using the same syntax as filter expressions, is true
+// for them. Routes without expression match all the events.
+func parseRoutes(s string) ([]route, error) {
+	var routes []route
+	for _, r := range strings.FieldsFunc(s, func(c rune) bool { return c == ';' || c == '\n' }) {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+
+		target, expression, _ := strings.Cut(r, "=")
+		dataSource, exporter, ok := strings.Cut(target, ":")
+		if !ok {
+			dataSource, exporter = "", target
+		}
+		exporter = strings.TrimSpace(exporter)
+		if exporter == "" {
+			return nil, fmt.Errorf("invalid route %q: expected [DATASOURCE:]EXPORTER=EXPRESSION", r)
+		}
+		routes = append(routes, route{
+			dataSource: strings.TrimSpace(dataSource),
+			exporter:   exporter,
+			expression: strings.TrimSpace(expression),
+		})
+	}
+	return routes, nil
+}
+
+type compiledRoute struct {
+	exporter   string
+	expression string
+	prog       *vm.Program
+}
+
+// Router holds the routes of a data source
+type Router struct {
+	ds     datasource.DataSource
+	logger logger.Logger
+	routes []compiledRoute
+
+	// claims is shared by the routers of a gadget and maps the exporters of
+	// the routes to the operators providing them
+	claims map[string][]string
+}
+
+// Exporters returns the names of all the exporters events can be routed to
+func (r *Router) Exporters() []string {
+	var exporters []string
+	for _, rt := range r.routes {
+		if !slices.Contains(exporters, rt.exporter) {
+			exporters = append(exporters, rt.exporter)
+		}
+	}
+	return exporters
+}
+
+// Route returns the names of the exporters the event needs to be sent to
+func (r *Router) Route(data datasource.Data) []string {
+	var exporters []string
+	for _, rt := range r.routes {
+		if slices.Contains(exporters, rt.exporter) {
+			continue
+		}
+		if rt.prog != nil {
+			ret, err := expr.Run(rt.prog, data)
+			if err != nil {
+				r.logger.Errorf("running route expression %q for datasource %s: %v", rt.expression, r.ds.Name(), err)
+				continue
+			}
+			if match, _ := ret.(bool); !match {
+				continue
+			}
+		}
+		exporters = append(exporters, rt.exporter)
+	}
+	return exporters
+}
+
+// GetRouter returns the Router of the data source, if events of the gadget
+// context are routed
+func GetRouter(gadgetCtx operators.GadgetContext, ds datasource.DataSource) (*Router, bool) {
+	v, ok := gadgetCtx.GetVar(routerVarPrefix + ds.Name())
+	if !ok {
+		return nil, false
+	}
+	router, ok := v.(*Router)
+	return router, ok
+}
+
+// claim records that the exporter is provided by the operator
+func (r *Router) claim(operator, exporter string) {
+	if !slices.Contains(r.claims[exporter], operator) {
+		r.claims[exporter] = append(r.claims[exporter], operator)
+	}
+}
+
+// Dispatcher selects the exporters of an exporter operator each event of a
+// data source is sent to
+type Dispatcher struct {
+	mapped string
+	router *Router
+	routed []string
+}
+
+// NewDispatcher returns the Dispatcher of the events of ds for an exporter
+// operator. mapped is the exporter receiving all the events, set with the
+// params of the operator, if any, and exists tells whether the operator has an
+// exporter with the given name; the exporters of the routes it has are claimed
+// for the operator. It returns nil if the operator sends no events of ds.
+func NewDispatcher(gadgetCtx operators.GadgetContext, ds datasource.DataSource, operator, mapped string, exists func(string) bool) *Dispatcher {
+	d := &Dispatcher{mapped: mapped}
+	if router, ok := GetRouter(gadgetCtx, ds); ok {
+		for _, exporter := range router.Exporters() {
+			if !exists(exporter) {
+				continue
+			}
+			router.claim(operator, exporter)
+			if exporter != mapped {
+				d.routed = append(d.routed, exporter)
+			}
+		}
+		if len(d.routed) > 0 {
+			d.router = router
+		}
+	}
+	if d.mapped == "" && d.router == nil {
+		return nil
+	}
+	return d
+}
+
+// Mapped returns the exporter receiving all the events, if any
+func (d *Dispatcher) Mapped() string {
+	return d.mapped
+}
+
+// Exporters returns the exporters the events can be sent to
+func (d *Dispatcher) Exporters() []string {
+	if d.mapped == "" {
+		return d.routed
+	}
+	return append([]string{d.mapped}, d.routed...)
+}
+
+// Route returns the exporters an event is sent to, each one once
+func (d *Dispatcher) Route(data datasource.Data) []string {
+	if d.router == nil {
+		return []string{d.mapped}
+	}
+	var exporters []string
+	if d.mapped != "" {
+		exporters = append(exporters, d.mapped)
+	}
+	for _, exporter := range d.router.Route(data) {
+		if exporter != d.mapped && slices.Contains(d.routed, exporter) {
+			exporters = append(exporters, exporter)
+		}
+	}
+	return exporters
+}
+
+type routingOperator struct{}
+
+func (r *routingOperator) Name() string {
+	return Name
+}
+
+func (r *routingOperator) Init(params *params.Params) error {
+	return nil
+}
+
+func (r *routingOperator) GlobalParams() api.Params {
+	return nil
+}
+
+func (r *routingOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamRoutes,
+			Title: "Routes",
+			Description: "Routes sending events to exporters, separated by semicolons, like " +
+				"'siem=proc.comm in [\"sh\", \"bash\"]; archive='. Each route is [DATASOURCE:]EXPORTER=EXPRESSION, " +
+				"where EXPRESSION uses the syntax of filter expressions and an empty one matches all events",
+			TypeHint: api.TypeString,
+		},
+	}
+}
+
+func (r *routingOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	routes, err := parseRoutes(instanceParamValues[ParamRoutes])
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ParamRoutes, err)
+	}
+	if len(routes) == 0 {
+		return nil, nil
+	}
+
+	dataSources := gadgetCtx.GetDataSources()
+	for _, rt := range routes {
+		if rt.dataSource == "" {
+			continue
+		}
+		if _, ok := dataSources[rt.dataSource]; !ok {
+			return nil, fmt.Errorf("route to %q: data source %q not found", rt.exporter, rt.dataSource)
+		}
+	}
+
+	inst := &routingOperatorInstance{routes: routes, claims: make(map[string][]string)}
+	for _, ds := range dataSources {
+		router := &Router{ds: ds, logger: gadgetCtx.Logger(), claims: inst.claims}
+		for _, rt := range routes {
+			if rt.dataSource != "" && rt.dataSource != ds.Name() {
+				continue
+			}
+			compiled := compiledRoute{exporter: rt.exporter, expression: rt.expression}
+			if rt.expression != "" {
+				compiled.prog, err = expr.CompileFilterProgram(ds, rt.expression)
+				if err != nil {
+					return nil, fmt.Errorf("compiling route expression %q for datasource %s: %w", rt.expression, ds.Name(), err)
+				}
+			}
+			router.routes = append(router.routes, compiled)
+		}
+		if len(router.routes) == 0 {
+			continue
+		}
+		gadgetCtx.Logger().Debugf("routing %s to %s", ds.Name(), strings.Join(router.Exporters(), ", "))
+		gadgetCtx.SetVar(routerVarPrefix+ds.Name(), router)
+	}
+
+	return inst, nil
+}
+
+func (r *routingOperator) Priority() int {
+	return Priority
+}
+
+// routingOperatorInstance doesn't process events itself, the exporters
+// evaluate the routes when sending them
+type routingOperatorInstance struct {
+	routes []route
+	claims map[string][]string
+}
+
+func (r *routingOperatorInstance) Name() string {
+	return Name
+}
+
+// PreStart checks that every exporter of the routes was claimed by exactly
+// one exporter operator; all of them were instantiated by now
+func (r *routingOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for _, rt := range r.routes {
+		switch claims := r.claims[rt.exporter]; len(claims) {
+		case 0:
+			return fmt.Errorf("route to %q: exporter not found", rt.exporter)
+		case 1:
+		default:
+			return fmt.Errorf("route to %q: ambiguous exporter configured in the %s operators", rt.exporter, strings.Join(claims, " and "))
+		}
+	}
+	return nil
+}
+
+func (r *routingOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (r *routingOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (r *routingOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &routingOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,245 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routing
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func TestParseRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		routes   string
+		expected []route
+		wantErr  bool
+	}{
+		{
+			name:   "empty",
+			routes: "",
+		},
+		{
+			name:   "all events",
+			routes: "archive=",
+			expected: []route{
+				{exporter: "archive"},
+			},
+		},
+		{
+			name:   "no expression",
+			routes: "archive",
+			expected: []route{
+				{exporter: "archive"},
+			},
+		},
+		{
+			name:   "multiple",
+			routes: `siem=proc.comm in ["sh", "bash"]; archive=`,
+			expected: []route{
+				{exporter: "siem", expression: `proc.comm in ["sh", "bash"]`},
+				{exporter: "archive"},
+			},
+		},
+		{
+			name:   "data source and new lines",
+			routes: "exec:siem = uid == 0\nopen:archive=fname startsWith '/etc/'\n",
+			expected: []route{
+				{dataSource: "exec", exporter: "siem", expression: "uid == 0"},
+				{dataSource: "open", exporter: "archive", expression: "fname startsWith '/etc/'"},
+			},
+		},
+		{
+			name:    "missing exporter",
+			routes:  "=uid == 0",
+			wantErr: true,
+		},
+		{
+			name:    "missing exporter with data source",
+			routes:  "exec:=uid == 0",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			routes, err := parseRoutes(test.routes)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, routes)
+		})
+	}
+}
+
+func runRoutes(t *testing.T, routes string) (map[string][]string, error) {
+	t.Helper()
+
+	var ds datasource.DataSource
+	var commField datasource.FieldAccessor
+	got := make(map[string][]string)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "exec")
+		require.NoError(t, err)
+		commField, err = ds.AddField("comm", api.Kind_String)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for _, comm := range []string{"sh", "curl"} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, commField.PutString(data, comm))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		cancel()
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	// Like the exporters, the consumers get their dispatcher once instantiated
+	newConsumer := func(name string, exporters ...string) operators.DataOperator {
+		consume := func(gadgetCtx operators.GadgetContext) error {
+			dispatcher := NewDispatcher(gadgetCtx, ds, name, "", func(exporter string) bool {
+				return slices.Contains(exporters, exporter)
+			})
+			if dispatcher == nil {
+				return nil
+			}
+			ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+				comm, err := commField.String(data)
+				require.NoError(t, err)
+				got[comm] = append(got[comm], dispatcher.Route(data)...)
+				return nil
+			}, Priority+1)
+			return nil
+		}
+		return simple.New(name,
+			simple.WithPriority(Priority+1),
+			simple.OnInit(consume),
+		)
+	}
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(
+		Operator,
+		producer,
+		newConsumer("logs", "siem", "archive", "shared"),
+		newConsumer("kafka", "stream", "shared"),
+	))
+	err := gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamRoutes: routes,
+	})
+	return got, err
+}
+
+func TestRouting(t *testing.T) {
+	tests := []struct {
+		name     string
+		routes   string
+		expected map[string][]string
+		wantErr  bool
+	}{
+		{
+			name:     "no routes",
+			routes:   "",
+			expected: map[string][]string{},
+		},
+		{
+			name:   "fan out",
+			routes: `siem=comm == "sh"; archive=`,
+			expected: map[string][]string{
+				"sh":   {"siem", "archive"},
+				"curl": {"archive"},
+			},
+		},
+		{
+			name:   "same exporter",
+			routes: `siem=comm == "sh"; siem=comm startsWith "s"`,
+			expected: map[string][]string{
+				"sh":   {"siem"},
+				"curl": nil,
+			},
+		},
+		{
+			name:   "data source",
+			routes: `exec:siem=comm == "curl"`,
+			expected: map[string][]string{
+				"sh":   nil,
+				"curl": {"siem"},
+			},
+		},
+		{
+			name:   "several operators",
+			routes: `siem=comm == "sh"; stream=`,
+			expected: map[string][]string{
+				"sh":   {"stream", "siem"},
+				"curl": {"stream"},
+			},
+		},
+		{
+			name:    "unknown data source",
+			routes:  `open:siem=`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown exporter",
+			routes:  `s3=`,
+			wantErr: true,
+		},
+		{
+			name:    "ambiguous exporter",
+			routes:  `shared=`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid expression",
+			routes:  `siem=missing == "sh"`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := runRoutes(t, test.routes)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
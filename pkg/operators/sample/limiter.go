@@ -0,0 +1,75 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sample
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// keyedLimiter limits the number of events per second of each key with a
+// token bucket per key. Buckets of keys without events for idleTimeout are
+// removed, as they'd be full again anyway, so keys of pods that are gone
+// don't pile up.
+type keyedLimiter struct {
+	mu sync.Mutex
+
+	limit rate.Limit
+	burst int
+
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newKeyedLimiter(eventsPerSecond uint64, burst uint64) *keyedLimiter {
+	if burst == 0 {
+		burst = eventsPerSecond
+	}
+	return &keyedLimiter{
+		limit:   rate.Limit(eventsPerSecond),
+		burst:   int(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow returns whether an event of the given key can be let through
+func (k *keyedLimiter) allow(key string, now time.Time) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if now.Sub(k.lastSweep) >= idleTimeout {
+		for key, b := range k.buckets {
+			if now.Sub(b.lastSeen) >= idleTimeout {
+				delete(k.buckets, key)
+			}
+		}
+		k.lastSweep = now
+	}
+
+	b, ok := k.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(k.limit, k.burst)}
+		k.buckets[key] = b
+	}
+	b.lastSeen = now
+	return b.limiter.AllowN(now, 1)
+}
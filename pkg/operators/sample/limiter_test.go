@@ -0,0 +1,61 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sample
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	l := newKeyedLimiter(2, 0)
+	now := time.Now()
+
+	// Each key gets its own bucket
+	for _, key := range []string{"pod-a", "pod-b"} {
+		assert.True(t, l.allow(key, now))
+		assert.True(t, l.allow(key, now))
+		assert.False(t, l.allow(key, now))
+	}
+
+	// Tokens are refilled at 2 per second
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, l.allow("pod-a", now))
+	assert.False(t, l.allow("pod-a", now))
+}
+
+func TestKeyedLimiterBurst(t *testing.T) {
+	l := newKeyedLimiter(1, 3)
+	now := time.Now()
+	for range 3 {
+		assert.True(t, l.allow("", now))
+	}
+	assert.False(t, l.allow("", now))
+}
+
+func TestKeyedLimiterSweep(t *testing.T) {
+	l := newKeyedLimiter(1, 0)
+	now := time.Now()
+	l.allow("pod-a", now)
+	l.allow("pod-b", now.Add(idleTimeout/2))
+	assert.Len(t, l.buckets, 2)
+
+	// Only the bucket of pod-a was idle for long enough
+	l.allow("pod-c", now.Add(idleTimeout))
+	assert.Len(t, l.buckets, 2)
+	assert.NotContains(t, l.buckets, "pod-a")
+}
@@ -0,0 +1,291 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sample is a data operator that samples the events of noisy gadgets
+// right after they're enriched, so the other operators only handle the events
+// that are kept. Events can be sampled at a fixed rate, keeping one event out of
+// N, and limited to a number of events per second, for the whole data source
+// or for each pod, namespace or any other key.
+package sample
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	name           = "sample"
+	ParamRate      = "sample-rate"
+	ParamMaxEvents = "sample-max-events"
+	ParamBurst     = "sample-burst"
+	ParamKey       = "sample-key"
+	Priority       = 50 // after the enrichment with container and Kubernetes metadata, before the other operators
+	// MinRateVarName is the name of the variable of the gadget context other
+	// operators can set to a uint64 to keep at most one event out of that
+	// many of every data source, whatever ParamRate is
+	MinRateVarName = "sample.minRate"
+	keyFieldsSep   = ","
+	keyValuesSep   = "/"
+
+	// idleTimeout is the time after which the token bucket of a key without
+	// events is removed
+	idleTimeout = time.Minute
+)
+
+type sampleOperator struct{}
+
+func (s *sampleOperator) Name() string {
+	return name
+}
+
+func (s *sampleOperator) Init(params *params.Params) error {
+	return nil
+}
+
+func (s *sampleOperator) GlobalParams() api.Params {
+	return nil
+}
+
+func (s *sampleOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamRate,
+			Title: "Sample Rate",
+			Description: "Keep one event out of N. " +
+				"If using multiple data sources, prefix the value with 'datasourcename:' and separate with ','. " +
+				"Use 0 or 1 to keep all events.",
+			TypeHint: api.TypeString,
+		},
+		{
+			Key:   ParamMaxEvents,
+			Title: "Sample Max Events",
+			Description: "The maximum number of events per second, for each key given by " + ParamKey + " if set. " +
+				"If using multiple data sources, prefix the value with 'datasourcename:' and separate with ','. " +
+				"Use 0 to disable the limit.",
+			TypeHint: api.TypeString,
+		},
+		{
+			Key:          ParamBurst,
+			Title:        "Sample Burst",
+			Description:  "The number of events let through at once before " + ParamMaxEvents + " applies. Defaults to " + ParamMaxEvents + ".",
+			DefaultValue: "0",
+			TypeHint:     api.TypeUint,
+		},
+		{
+			Key:   ParamKey,
+			Title: "Sample Key",
+			Description: "Comma-separated list of fields whose values get their own " + ParamMaxEvents + ", " +
+				"like k8s.podName or k8s.namespace,k8s.podName.",
+			TypeHint: api.TypeString,
+		},
+	}
+}
+
+func (s *sampleOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	params := apihelpers.ToParamDescs(s.InstanceParams()).ToParams()
+	if err := params.CopyFromMap(instanceParamValues, ""); err != nil {
+		return nil, err
+	}
+
+	rates, err := parseValuesPerDataSource(params.Get(ParamRate).AsString())
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ParamRate, err)
+	}
+	maxEvents, err := parseValuesPerDataSource(params.Get(ParamMaxEvents).AsString())
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ParamMaxEvents, err)
+	}
+	if v, ok := gadgetCtx.GetVar(MinRateVarName); ok {
+		minRate, ok := v.(uint64)
+		if !ok {
+			return nil, fmt.Errorf("invalid type %T of %s", v, MinRateVarName)
+		}
+		if minRate > 1 {
+			for k, rate := range rates {
+				rates[k] = max(rate, minRate)
+			}
+			rates[""] = max(rates[""], minRate)
+		}
+	}
+	if len(rates) == 0 && len(maxEvents) == 0 {
+		return nil, nil
+	}
+
+	var keyFields []string
+	for _, f := range strings.Split(params.Get(ParamKey).AsString(), keyFieldsSep) {
+		if f = strings.TrimSpace(f); f != "" {
+			keyFields = append(keyFields, f)
+		}
+	}
+
+	return &sampleOperatorInstance{
+		rates:     rates,
+		maxEvents: maxEvents,
+		burst:     params.Get(ParamBurst).AsUint64(),
+		keyFields: keyFields,
+	}, nil
+}
+
+// parseValuesPerDataSource parses a list of non-negative values like
+// "exec:10,open:100" or "10"
+func parseValuesPerDataSource(s string) (map[string]uint64, error) {
+	values, err := apihelpers.GetIntValuesPerDataSource(s)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]uint64, len(values))
+	for k, v := range values {
+		if v < 0 {
+			return nil, fmt.Errorf("invalid value for %q: %d", k, v)
+		}
+		res[k] = uint64(v)
+	}
+	return res, nil
+}
+
+// valueFor returns the value for a data source, or the one for all of them
+func valueFor(values map[string]uint64, ds datasource.DataSource) uint64 {
+	if v, ok := values[ds.Name()]; ok {
+		return v
+	}
+	return values[""]
+}
+
+func (s *sampleOperator) Priority() int {
+	return Priority
+}
+
+type sampleOperatorInstance struct {
+	rates     map[string]uint64
+	maxEvents map[string]uint64
+	burst     uint64
+	keyFields []string
+	samplers  []*sampler
+}
+
+// sampler holds the state of the sampling of a data source
+type sampler struct {
+	ds      datasource.DataSource
+	count   atomic.Uint64
+	dropped atomic.Uint64
+}
+
+func (s *sampleOperatorInstance) Name() string {
+	return name
+}
+
+func (s *sampleOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for _, ds := range gadgetCtx.GetDataSources() {
+		// Array data sources are emitted all at once, sampling them would drop
+		// whole snapshots
+		if ds.Type() != datasource.TypeSingle {
+			continue
+		}
+
+		rate := valueFor(s.rates, ds)
+		if rate == 1 {
+			rate = 0
+		}
+		maxEvents := valueFor(s.maxEvents, ds)
+
+		var limiter *keyedLimiter
+		var keyFuncs []func(datasource.Data) (string, string)
+		if maxEvents > 0 {
+			for _, fieldName := range s.keyFields {
+				f := ds.GetField(fieldName)
+				if f == nil {
+					break
+				}
+				kf, err := datasource.GetKeyValueFunc[string, string](f, "", formatInt, formatFloat, formatString)
+				if err != nil {
+					return fmt.Errorf("using %q as %s: %w", fieldName, ParamKey, err)
+				}
+				keyFuncs = append(keyFuncs, kf)
+			}
+			if len(keyFuncs) == len(s.keyFields) {
+				limiter = newKeyedLimiter(maxEvents, s.burst)
+			} else {
+				gadgetCtx.Logger().Debugf("sample: data source %q doesn't have the fields %v, not limiting it", ds.Name(), s.keyFields)
+			}
+		}
+
+		if rate == 0 && limiter == nil {
+			continue
+		}
+
+		gadgetCtx.Logger().Debugf("sample: data source %q with rate %d and max events %d/s per %v",
+			ds.Name(), rate, maxEvents, s.keyFields)
+
+		smp := &sampler{ds: ds}
+		s.samplers = append(s.samplers, smp)
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			if rate > 0 && (smp.count.Add(1)-1)%rate != 0 {
+				smp.dropped.Add(1)
+				return datasource.ErrDiscard
+			}
+			if limiter != nil && !limiter.allow(eventKey(keyFuncs, data), time.Now()) {
+				smp.dropped.Add(1)
+				return datasource.ErrDiscard
+			}
+			return nil
+		}, Priority)
+	}
+	return nil
+}
+
+func formatInt(v int64) string     { return strconv.FormatInt(v, 10) }
+func formatFloat(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+func formatString(v string) string { return v }
+
+func eventKey(keyFuncs []func(datasource.Data) (string, string), data datasource.Data) string {
+	vals := make([]string, 0, len(keyFuncs))
+	for _, kf := range keyFuncs {
+		_, val := kf(data)
+		vals = append(vals, val)
+	}
+	return strings.Join(vals, keyValuesSep)
+}
+
+func (s *sampleOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (s *sampleOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	for _, smp := range s.samplers {
+		if dropped := smp.dropped.Load(); dropped > 0 {
+			gadgetCtx.Logger().Infof("sample: dropped %d events of data source %q", dropped, smp.ds.Name())
+		}
+	}
+	return nil
+}
+
+func (s *sampleOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &sampleOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sample
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+// run emits 10 events for each of 2 pods to the data sources exec and open
+// and returns the number of events received per data source and pod
+func run(t *testing.T, paramValues api.ParamValues) map[string]map[string]int {
+	t.Helper()
+
+	var dataSources []datasource.DataSource
+	var podFields []datasource.FieldAccessor
+	got := make(map[string]map[string]int)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		for _, name := range []string{"exec", "open"} {
+			ds, err := gadgetCtx.RegisterDataSource(datasource.TypeSingle, name)
+			require.NoError(t, err)
+			k8s, err := ds.AddField("k8s", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+			require.NoError(t, err)
+			podName, err := k8s.AddSubField("podName", api.Kind_String)
+			require.NoError(t, err)
+			dataSources = append(dataSources, ds)
+			podFields = append(podFields, podName)
+			got[name] = make(map[string]int)
+		}
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for i, ds := range dataSources {
+			for j := range 20 {
+				data, err := ds.NewPacketSingle()
+				require.NoError(t, err)
+				require.NoError(t, podFields[i].PutString(data, fmt.Sprintf("pod-%d", j%2)))
+				require.NoError(t, ds.EmitAndRelease(data))
+			}
+		}
+		cancel()
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		for i, ds := range dataSources {
+			podName := podFields[i]
+			ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+				pod, err := podName.String(data)
+				require.NoError(t, err)
+				got[ds.Name()][pod]++
+				return nil
+			}, Priority+1)
+		}
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(Operator, producer, consumer))
+	require.NoError(t, gadgetCtx.Run(paramValues))
+	return got
+}
+
+func TestSample(t *testing.T) {
+	tests := []struct {
+		name        string
+		paramValues api.ParamValues
+		expected    map[string]map[string]int
+	}{
+		{
+			name:        "disabled",
+			paramValues: api.ParamValues{},
+			expected: map[string]map[string]int{
+				"exec": {"pod-0": 10, "pod-1": 10},
+				"open": {"pod-0": 10, "pod-1": 10},
+			},
+		},
+		{
+			name: "rate",
+			paramValues: api.ParamValues{
+				"operator.sample." + ParamRate: "4",
+			},
+			expected: map[string]map[string]int{
+				"exec": {"pod-0": 5},
+				"open": {"pod-0": 5},
+			},
+		},
+		{
+			name: "rate per data source",
+			paramValues: api.ParamValues{
+				"operator.sample." + ParamRate: "exec:5",
+			},
+			expected: map[string]map[string]int{
+				"exec": {"pod-0": 2, "pod-1": 2},
+				"open": {"pod-0": 10, "pod-1": 10},
+			},
+		},
+		{
+			name: "max events",
+			paramValues: api.ParamValues{
+				"operator.sample." + ParamMaxEvents: "3",
+			},
+			expected: map[string]map[string]int{
+				"exec": {"pod-0": 2, "pod-1": 1},
+				"open": {"pod-0": 2, "pod-1": 1},
+			},
+		},
+		{
+			name: "max events per pod",
+			paramValues: api.ParamValues{
+				"operator.sample." + ParamMaxEvents: "open:3",
+				"operator.sample." + ParamKey:       "k8s.podName",
+			},
+			expected: map[string]map[string]int{
+				"exec": {"pod-0": 10, "pod-1": 10},
+				"open": {"pod-0": 3, "pod-1": 3},
+			},
+		},
+		{
+			name: "max events with burst",
+			paramValues: api.ParamValues{
+				"operator.sample." + ParamMaxEvents: "1",
+				"operator.sample." + ParamBurst:     "4",
+				"operator.sample." + ParamKey:       "k8s.podName",
+			},
+			expected: map[string]map[string]int{
+				"exec": {"pod-0": 4, "pod-1": 4},
+				"open": {"pod-0": 4, "pod-1": 4},
+			},
+		},
+		{
+			name: "missing key",
+			paramValues: api.ParamValues{
+				"operator.sample." + ParamMaxEvents: "1",
+				"operator.sample." + ParamKey:       "k8s.namespace",
+			},
+			expected: map[string]map[string]int{
+				"exec": {"pod-0": 10, "pod-1": 10},
+				"open": {"pod-0": 10, "pod-1": 10},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, run(t, test.paramValues))
+		})
+	}
+}
+
+func TestInvalidParams(t *testing.T) {
+	for _, paramValues := range []api.ParamValues{
+		{"operator.sample." + ParamRate: "-1"},
+		{"operator.sample." + ParamRate: "exec:2,3"},
+		{"operator.sample." + ParamMaxEvents: "many"},
+	} {
+		gadgetCtx := gadgetcontext.New(context.Background(), "", gadgetcontext.WithDataOperators(Operator))
+		require.Error(t, gadgetCtx.Run(paramValues), paramValues)
+	}
+}
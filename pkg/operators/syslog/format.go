@@ -0,0 +1,191 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatRFC5424 = "rfc5424"
+	FormatCEF     = "cef"
+
+	// maxMsgIDLength is the maximum length of the MSGID of RFC5424
+	maxMsgIDLength = 32
+
+	// rfc5424Timestamp has at most 6 digits for the fraction of the second, as
+	// required by RFC5424
+	rfc5424Timestamp = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+var severities = map[string]int{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"error":   3,
+	"warning": 4,
+	"warn":    4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+// cefSeverities maps the severities of syslog to the ones of CEF, that go from
+// 0 (lowest) to 10 (highest)
+var cefSeverities = [...]int{10, 9, 8, 7, 5, 3, 1, 0}
+
+// parseFacility parses the name or the number of a syslog facility
+func parseFacility(s string) (int, error) {
+	if f, ok := facilities[strings.ToLower(s)]; ok {
+		return f, nil
+	}
+	if f, err := strconv.Atoi(s); err == nil && f >= 0 && f <= 23 {
+		return f, nil
+	}
+	return 0, fmt.Errorf("invalid syslog facility %q", s)
+}
+
+// parseSeverity parses the name or the number of a syslog severity
+func parseSeverity(s string) (int, error) {
+	if sv, ok := severities[strings.ToLower(s)]; ok {
+		return sv, nil
+	}
+	if sv, err := strconv.Atoi(s); err == nil && sv >= 0 && sv <= 7 {
+		return sv, nil
+	}
+	return 0, fmt.Errorf("invalid syslog severity %q", s)
+}
+
+// header holds what's needed to format a message besides the event itself
+type header struct {
+	facility int
+	severity int
+	time     time.Time
+	hostname string
+	appName  string
+	msgID    string
+}
+
+// formatRFC5424 formats a message as described in RFC5424, without structured
+// data
+func formatRFC5424(h header, msg string) string {
+	return fmt.Sprintf("<%d>1 %s %s %s - %s - %s",
+		h.facility*8+h.severity,
+		h.time.UTC().Format(rfc5424Timestamp),
+		headerValue(h.hostname, 255),
+		headerValue(h.appName, 48),
+		headerValue(h.msgID, maxMsgIDLength),
+		msg,
+	)
+}
+
+// headerValue makes s usable in the header of a RFC5424 message: only
+// printable ASCII characters without spaces are allowed, and "-" is used for
+// empty values
+func headerValue(s string, maxLength int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > maxLength {
+		s = s[:maxLength]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// cefEvent is an event in the Common Event Format
+type cefEvent struct {
+	product     string
+	version     string
+	signatureID string
+	name        string
+	severity    int
+	extension   []cefField
+}
+
+type cefField struct {
+	key   string
+	value string
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+// formatCEF formats an event in the Common Event Format, like
+// CEF:0|Inspektor Gadget|trace_exec|v0.40.0|exec|exec|1|comm=bash pid=1234
+func formatCEF(e cefEvent) string {
+	var sb strings.Builder
+	sb.WriteString("CEF:0|Inspektor Gadget|")
+	for _, s := range []string{e.product, e.version, e.signatureID, e.name} {
+		sb.WriteString(cefHeaderEscaper.Replace(s))
+		sb.WriteByte('|')
+	}
+	sb.WriteString(strconv.Itoa(cefSeverities[e.severity]))
+	sb.WriteByte('|')
+	for i, f := range e.extension {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(cefKey(f.key))
+		sb.WriteByte('=')
+		sb.WriteString(cefExtensionEscaper.Replace(f.value))
+	}
+	return sb.String()
+}
+
+// cefKey replaces the characters that can't be used in the keys of the
+// extension of a CEF event
+func cefKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, s)
+}
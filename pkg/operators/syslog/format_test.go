@@ -0,0 +1,77 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFacilityAndSeverity(t *testing.T) {
+	f, err := parseFacility("local0")
+	require.NoError(t, err)
+	assert.Equal(t, 16, f)
+	f, err = parseFacility("4")
+	require.NoError(t, err)
+	assert.Equal(t, 4, f)
+	_, err = parseFacility("local8")
+	require.Error(t, err)
+
+	sv, err := parseSeverity("Warning")
+	require.NoError(t, err)
+	assert.Equal(t, 4, sv)
+	_, err = parseSeverity("8")
+	require.Error(t, err)
+}
+
+func TestFormatRFC5424(t *testing.T) {
+	h := header{
+		facility: 16,
+		severity: 4,
+		time:     time.Date(2025, 3, 4, 5, 6, 7, 123456789, time.UTC),
+		hostname: "node-1",
+		appName:  "inspektor-gadget",
+		msgID:    "exec events",
+	}
+	assert.Equal(t,
+		`<132>1 2025-03-04T05:06:07.123456Z node-1 inspektor-gadget - exec_events - {"comm":"bash"}`,
+		formatRFC5424(h, `{"comm":"bash"}`))
+
+	h.hostname = ""
+	assert.Equal(t,
+		`<132>1 2025-03-04T05:06:07.123456Z - inspektor-gadget - exec_events - hello`,
+		formatRFC5424(h, "hello"))
+}
+
+func TestFormatCEF(t *testing.T) {
+	e := cefEvent{
+		product:     "trace_exec",
+		version:     "v0.40.0",
+		signatureID: "exec",
+		name:        "bash|sh",
+		severity:    4,
+		extension: []cefField{
+			{key: "proc.comm", value: "bash"},
+			{key: "args", value: "a=b\\c\nd"},
+			{key: "k8s pod", value: "x"},
+		},
+	}
+	assert.Equal(t,
+		`CEF:0|Inspektor Gadget|trace_exec|v0.40.0|exec|bash\|sh|5|proc.comm=bash args=a\=b\\c\nd k8s_pod=x`,
+		formatCEF(e))
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// queueLength is the number of messages waiting to be sent before new
+	// ones are dropped
+	queueLength = 10000
+
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 5 * time.Second
+)
+
+// messageSender sends formatted messages to a syslog endpoint; it can be
+// replaced for testing
+type messageSender interface {
+	send(msg string)
+}
+
+// sender sends messages over UDP, one per datagram, or over TCP or TLS with
+// octet-counting framing as described in RFC6587. Messages are queued so that
+// the data sources aren't blocked by the endpoint.
+type sender struct {
+	name      string
+	network   string
+	address   string
+	tlsConfig *tls.Config
+
+	queue   chan string
+	dropped atomic.Uint64
+	conn    net.Conn
+}
+
+// parseAddress parses addresses like udp://host:port, tcp://host:port or
+// tls://host:port; the default port is 514, or 6514 for TLS
+func parseAddress(address string) (network string, hostPort string, err error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing address %q: %w", address, err)
+	}
+	port := "514"
+	switch u.Scheme {
+	case "udp", "tcp":
+	case "tls":
+		port = "6514"
+	default:
+		return "", "", fmt.Errorf("invalid address %q: expected udp://, tcp:// or tls://", address)
+	}
+	if u.Hostname() == "" {
+		return "", "", fmt.Errorf("invalid address %q: missing host", address)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", "", fmt.Errorf("invalid address %q: invalid port", address)
+	}
+	return u.Scheme, net.JoinHostPort(u.Hostname(), port), nil
+}
+
+func newSender(name, address string, tlsConfig *tls.Config) (*sender, error) {
+	network, hostPort, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	s := &sender{
+		name:      name,
+		network:   network,
+		address:   hostPort,
+		tlsConfig: tlsConfig,
+		queue:     make(chan string, queueLength),
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *sender) send(msg string) {
+	select {
+	case s.queue <- msg:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *sender) run() {
+	for msg := range s.queue {
+		if dropped := s.dropped.Swap(0); dropped > 0 {
+			log.Warnf("syslog exporter %q: dropped %d messages because %s didn't keep up", s.name, dropped, s.address)
+		}
+		if err := s.write(msg); err != nil {
+			log.Errorf("syslog exporter %q: sending message to %s: %v", s.name, s.address, err)
+		}
+	}
+}
+
+func (s *sender) dial() (net.Conn, error) {
+	switch s.network {
+	case "tls":
+		dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: dialTimeout}, Config: s.tlsConfig}
+		return dialer.Dial("tcp", s.address)
+	default:
+		return net.DialTimeout(s.network, s.address, dialTimeout)
+	}
+}
+
+// write sends a message, reconnecting once if the connection was lost
+func (s *sender) write(msg string) error {
+	frame := msg
+	if s.network != "udp" {
+		frame = strconv.Itoa(len(msg)) + " " + msg
+	}
+
+	var err error
+	for range 2 {
+		if s.conn == nil {
+			s.conn, err = s.dial()
+			if err != nil {
+				return fmt.Errorf("connecting: %w", err)
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err = s.conn.Write([]byte(frame)); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
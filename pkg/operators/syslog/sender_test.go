@@ -0,0 +1,71 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	for _, tc := range []struct {
+		address  string
+		network  string
+		hostPort string
+	}{
+		{"udp://10.0.0.1", "udp", "10.0.0.1:514"},
+		{"tcp://siem.example.com:1514", "tcp", "siem.example.com:1514"},
+		{"tls://siem.example.com", "tls", "siem.example.com:6514"},
+		{"tls://[::1]:6515", "tls", "[::1]:6515"},
+	} {
+		network, hostPort, err := parseAddress(tc.address)
+		require.NoError(t, err, tc.address)
+		assert.Equal(t, tc.network, network, tc.address)
+		assert.Equal(t, tc.hostPort, hostPort, tc.address)
+	}
+
+	for _, address := range []string{"", "10.0.0.1:514", "http://10.0.0.1", "tcp://:514", "udp://10.0.0.1:99999"} {
+		_, _, err := parseAddress(address)
+		require.Error(t, err, address)
+	}
+}
+
+func TestSenderTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s, err := newSender("test", "tcp://"+listener.Addr().String(), nil)
+	require.NoError(t, err)
+	s.send("<14>1 hello")
+	s.send("<14>1 world")
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(conn)
+	buf := make([]byte, len("11 <14>1 hello11 <14>1 world"))
+	_, err = io.ReadFull(r, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "11 <14>1 hello11 <14>1 world", string(buf))
+}
@@ -0,0 +1,562 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package syslog is a data operator that forwards the events of data sources
+// to syslog endpoints as RFC5424 messages, optionally in the Common Event
+// Format (CEF), since many SOCs can only ingest syslog.
+package syslog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr/vm"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/inspektor-gadget/inspektor-gadget/internal/version"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/config"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/expr"
+	igjson "github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/formatters/json"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/common"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/routing"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	name     = "syslog"
+	Priority = 9999
+
+	ParamSyslogExporter = "syslog-exporter"
+	ParamSyslogFacility = "syslog-facility"
+	ParamSyslogSeverity = "syslog-severity"
+	ParamSyslogFields   = "syslog-fields"
+
+	AnnotationSyslogFacility = "syslog.facility"
+	AnnotationSyslogSeverity = "syslog.severity"
+	AnnotationSyslogMessage  = "syslog.message"
+
+	DefaultAppName  = "inspektor-gadget"
+	DefaultFacility = "user"
+	DefaultSeverity = "info"
+)
+
+type exporterConfig struct {
+	Address            string `json:"address" yaml:"address"`
+	Format             string `json:"format" yaml:"format"`
+	Facility           string `json:"facility" yaml:"facility"`
+	AppName            string `json:"appName" yaml:"appName"`
+	CAFile             string `json:"caFile" yaml:"caFile"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+}
+
+type exporter struct {
+	format   string
+	facility int
+	appName  string
+	sender   messageSender
+}
+
+func newExporter(exporterName string, cfg *exporterConfig) (*exporter, error) {
+	e := &exporter{format: cfg.Format, appName: cfg.AppName}
+	switch e.format {
+	case "":
+		e.format = FormatRFC5424
+	case FormatRFC5424, FormatCEF:
+	default:
+		return nil, fmt.Errorf("exporter %q: unsupported format %q; expected %q or %q",
+			exporterName, e.format, FormatRFC5424, FormatCEF)
+	}
+	if e.appName == "" {
+		e.appName = DefaultAppName
+	}
+	facility := cfg.Facility
+	if facility == "" {
+		facility = DefaultFacility
+	}
+	var err error
+	e.facility, err = parseFacility(facility)
+	if err != nil {
+		return nil, fmt.Errorf("exporter %q: %w", exporterName, err)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("exporter %q: reading CA file: %w", exporterName, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("exporter %q: no certificates found in %q", exporterName, cfg.CAFile)
+		}
+	}
+	e.sender, err = newSender(exporterName, cfg.Address, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("exporter %q: %w", exporterName, err)
+	}
+	return e, nil
+}
+
+// parseFields parses the fields selected per data source, like
+// "datasource:field1,field2;datasource2:field3" or "field1,field2" for all
+// data sources
+func parseFields(s string) map[string][]string {
+	res := make(map[string][]string)
+	for _, v := range strings.Split(s, ";") {
+		if v == "" {
+			continue
+		}
+		dsName, fields, ok := strings.Cut(v, ":")
+		if !ok {
+			dsName, fields = "", v
+		}
+		res[dsName] = strings.Split(fields, ",")
+	}
+	return res
+}
+
+type syslogOperator struct {
+	exporters map[string]*exporter
+	node      string
+}
+
+func (s *syslogOperator) Name() string {
+	return name
+}
+
+func (s *syslogOperator) Init(params *params.Params) error {
+	s.exporters = make(map[string]*exporter)
+
+	s.node = os.Getenv("NODE_NAME")
+	if s.node == "" {
+		s.node, _ = os.Hostname()
+	}
+
+	if config.Config == nil {
+		return nil
+	}
+
+	configs := make(map[string]*exporterConfig)
+	log.Debugf("loading syslog exporters")
+	err := config.Config.UnmarshalKey("operator.syslog.exporters", &configs)
+	if err != nil {
+		log.Warnf("failed to load operator.syslog.exporters: %v", err)
+	}
+	for exporterName, cfg := range configs {
+		e, err := newExporter(exporterName, cfg)
+		if err != nil {
+			return err
+		}
+		s.exporters[exporterName] = e
+		log.Debugf("> syslog exporter %q with address %q loaded", exporterName, cfg.Address)
+	}
+	return nil
+}
+
+func (s *syslogOperator) GlobalParams() api.Params {
+	return api.Params{}
+}
+
+func (s *syslogOperator) InstanceParams() api.Params {
+	return api.Params{
+		&api.Param{
+			Key:          ParamSyslogExporter,
+			Description:  "Syslog exporter to forward the events to, as DATASOURCE:EXPORTER or EXPORTER for all data sources",
+			DefaultValue: "",
+		},
+		&api.Param{
+			Key:          ParamSyslogFacility,
+			Description:  "Syslog facility of the events, like local0, as DATASOURCE:FACILITY or FACILITY for all data sources",
+			DefaultValue: "",
+		},
+		&api.Param{
+			Key:          ParamSyslogSeverity,
+			Description:  "Syslog severity of the events, like warning, as DATASOURCE:SEVERITY or SEVERITY for all data sources",
+			DefaultValue: "",
+		},
+		&api.Param{
+			Key: ParamSyslogFields,
+			Description: "Fields to forward, like DATASOURCE:FIELD1,FIELD2;DATASOURCE2:FIELD3 or FIELD1,FIELD2 for all data sources. " +
+				"Fields can be prefixed with +/- to add them to or remove them from the default fields",
+			DefaultValue: "",
+		},
+	}
+}
+
+func (s *syslogOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	if len(s.exporters) == 0 {
+		return nil, nil
+	}
+	mappings, err := apihelpers.GetStringValuesPerDataSource(instanceParamValues[ParamSyslogExporter])
+	if err != nil {
+		return nil, fmt.Errorf("parsing exporter mappings: %w", err)
+	}
+	facilities, err := apihelpers.GetStringValuesPerDataSource(instanceParamValues[ParamSyslogFacility])
+	if err != nil {
+		return nil, fmt.Errorf("parsing facilities: %w", err)
+	}
+	severities, err := apihelpers.GetStringValuesPerDataSource(instanceParamValues[ParamSyslogSeverity])
+	if err != nil {
+		return nil, fmt.Errorf("parsing severities: %w", err)
+	}
+	fields := parseFields(instanceParamValues[ParamSyslogFields])
+
+	inst := &syslogOperatorInstance{
+		s:       s,
+		outputs: make(map[datasource.DataSource]*dsOutputs),
+	}
+	for _, ds := range gadgetCtx.GetDataSources() {
+		exporterName, _ := perDataSource(mappings, ds.Name())
+		if _, ok := s.exporters[exporterName]; exporterName != "" && !ok {
+			return nil, fmt.Errorf("syslog exporter not found: %q", exporterName)
+		}
+		dispatcher := routing.NewDispatcher(gadgetCtx, ds, name, exporterName, func(name string) bool {
+			_, ok := s.exporters[name]
+			return ok
+		})
+		if dispatcher == nil {
+			continue
+		}
+
+		facility, ok := perDataSource(facilities, ds.Name())
+		if !ok {
+			facility = ds.Annotations()[AnnotationSyslogFacility]
+		}
+		facilityValue := -1
+		if facility != "" {
+			facilityValue, err = parseFacility(facility)
+			if err != nil {
+				return nil, fmt.Errorf("data source %s: %w", ds.Name(), err)
+			}
+		}
+
+		severity, ok := perDataSource(severities, ds.Name())
+		if ok {
+			if _, err := parseSeverity(severity); err != nil {
+				return nil, fmt.Errorf("data source %s: %w", ds.Name(), err)
+			}
+		}
+		dsFields, ok := fields[ds.Name()]
+		if !ok {
+			dsFields = fields[""]
+		}
+
+		outs := &dsOutputs{dispatcher: dispatcher, outputs: make(map[string]*output)}
+		for _, exporterName := range dispatcher.Exporters() {
+			e := s.exporters[exporterName]
+			out := &output{exporter: e, facility: e.facility, severity: severity, fields: dsFields}
+			if facilityValue >= 0 {
+				out.facility = facilityValue
+			}
+			gadgetCtx.Logger().Debugf("forwarding %q to syslog exporter %q", ds.Name(), exporterName)
+			outs.outputs[exporterName] = out
+		}
+		inst.outputs[ds] = outs
+	}
+	if len(inst.outputs) == 0 {
+		return nil, nil
+	}
+	return inst, nil
+}
+
+// perDataSource returns the value of a data source, or the one for all data
+// sources
+func perDataSource(values map[string]string, dsName string) (string, bool) {
+	if v, ok := values[dsName]; ok {
+		return v, true
+	}
+	v, ok := values[""]
+	return v, ok
+}
+
+func (s *syslogOperator) Priority() int {
+	return Priority
+}
+
+// output is where and how the events of a data source are forwarded
+type output struct {
+	exporter *exporter
+	facility int
+
+	// severity set with the instance params, if any
+	severity string
+
+	// fields selected with the instance params, if any
+	fields []string
+}
+
+// dsOutputs holds the outputs of the exporters the events of a data source
+// are forwarded to
+type dsOutputs struct {
+	dispatcher *routing.Dispatcher
+	outputs    map[string]*output
+}
+
+type syslogOperatorInstance struct {
+	s       *syslogOperator
+	outputs map[datasource.DataSource]*dsOutputs
+}
+
+func (s *syslogOperatorInstance) Name() string {
+	return name
+}
+
+// severityFunc returns the severity of the events of a data source: the one
+// of the instance params, or the one of the syslog.severity annotation, that's
+// either a severity or an expression evaluating to one, like
+// 'proc.creds.uid == 0 ? "warning" : "info"'
+func severityFunc(gadgetCtx operators.GadgetContext, ds datasource.DataSource, out *output) (func(datasource.Data) int, error) {
+	severity := out.severity
+	if severity == "" {
+		severity = ds.Annotations()[AnnotationSyslogSeverity]
+	}
+	if severity == "" {
+		severity = DefaultSeverity
+	}
+	if sv, err := parseSeverity(severity); err == nil {
+		return func(datasource.Data) int { return sv }, nil
+	}
+
+	prog, err := expr.CompileStringProgram(ds, severity)
+	if err != nil {
+		return nil, fmt.Errorf("compiling severity expression %q: %w", severity, err)
+	}
+	defaultSeverity, _ := parseSeverity(DefaultSeverity)
+	return func(data datasource.Data) int {
+		res, err := expr.Run(prog, data)
+		if err != nil {
+			gadgetCtx.Logger().Debugf("running severity expression of %s: %v", ds.Name(), err)
+			return defaultSeverity
+		}
+		sv, err := parseSeverity(res.(string))
+		if err != nil {
+			gadgetCtx.Logger().Debugf("severity expression of %s: %v", ds.Name(), err)
+			return defaultSeverity
+		}
+		return sv
+	}, nil
+}
+
+// cefFields returns the functions getting the extension fields of CEF events,
+// honoring the selected fields like the json formatter does
+func cefFields(ds datasource.DataSource, selected []string) ([]func(datasource.Data) (string, string), error) {
+	var defaultFields []datasource.FieldAccessor
+	for _, f := range ds.Accessors(false) {
+		flags := f.Flags()
+		if len(f.SubFields()) > 0 || datasource.FieldFlagUnreferenced.In(flags) || datasource.FieldFlagEmpty.In(flags) {
+			continue
+		}
+		if !datasource.FieldFlagHidden.In(flags) {
+			defaultFields = append(defaultFields, f)
+		}
+	}
+
+	fields := defaultFields
+	if len(selected) > 0 {
+		relative := true
+		for _, s := range selected {
+			if !strings.HasPrefix(s, "+") && !strings.HasPrefix(s, "-") {
+				relative = false
+			}
+		}
+		if !relative {
+			fields = nil
+		}
+		for _, s := range selected {
+			fieldName := strings.TrimLeft(s, "+-")
+			f := ds.GetField(fieldName)
+			if f == nil {
+				return nil, fmt.Errorf("field %q not found", fieldName)
+			}
+			if strings.HasPrefix(s, "-") {
+				fields = deleteField(fields, fieldName)
+			} else if !containsField(fields, fieldName) {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	var fns []func(datasource.Data) (string, string)
+	for _, f := range fields {
+		kvf, err := datasource.GetKeyValueFunc[string, string](f, f.FullName(),
+			func(i int64) string { return strconv.FormatInt(i, 10) },
+			func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) },
+			func(s string) string { return s },
+		)
+		if err != nil {
+			if len(selected) > 0 {
+				return nil, fmt.Errorf("field %q: %w", f.FullName(), err)
+			}
+			continue
+		}
+		fns = append(fns, kvf)
+	}
+	return fns, nil
+}
+
+func containsField(fields []datasource.FieldAccessor, fullName string) bool {
+	for _, f := range fields {
+		if f.FullName() == fullName {
+			return true
+		}
+	}
+	return false
+}
+
+func deleteField(fields []datasource.FieldAccessor, fullName string) []datasource.FieldAccessor {
+	res := fields[:0:0]
+	for _, f := range fields {
+		if f.FullName() != fullName {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+func (s *syslogOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, outs := range s.outputs {
+		senders := make(map[string]func(datasource.Data), len(outs.outputs))
+		for exporterName, out := range outs.outputs {
+			send, err := s.sender(gadgetCtx, ds, out)
+			if err != nil {
+				return err
+			}
+			senders[exporterName] = send
+		}
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			for _, exporterName := range outs.dispatcher.Route(data) {
+				senders[exporterName](data)
+			}
+			return nil
+		}, Priority)
+	}
+	return nil
+}
+
+// sender returns the function forwarding the events of a data source to an
+// output
+func (s *syslogOperatorInstance) sender(gadgetCtx operators.GadgetContext, ds datasource.DataSource, out *output) (func(datasource.Data), error) {
+	gadget := common.GadgetName(gadgetCtx.ImageName())
+	igVersion := version.Version().String()
+
+	severity, err := severityFunc(gadgetCtx, ds, out)
+	if err != nil {
+		return nil, fmt.Errorf("data source %s: %w", ds.Name(), err)
+	}
+
+	var message *vm.Program
+	if msg, ok := ds.Annotations()[AnnotationSyslogMessage]; ok {
+		message, err = expr.CompileStringProgram(ds, msg)
+		if err != nil {
+			return nil, fmt.Errorf("compiling message expression %q of %s: %w", msg, ds.Name(), err)
+		}
+	}
+	messageFunc := func(data datasource.Data) (string, bool) {
+		if message == nil {
+			return "", false
+		}
+		res, err := expr.Run(message, data)
+		if err != nil {
+			return "", false
+		}
+		return res.(string), true
+	}
+
+	var format func(data datasource.Data, severity int) string
+	switch out.exporter.format {
+	case FormatRFC5424:
+		var options []igjson.Option
+		if len(out.fields) > 0 {
+			options = append(options, igjson.WithFields(out.fields))
+		}
+		formatter, err := igjson.New(ds, options...)
+		if err != nil {
+			return nil, fmt.Errorf("creating json formatter for %s: %w", ds.Name(), err)
+		}
+		format = func(data datasource.Data, severity int) string {
+			if msg, ok := messageFunc(data); ok {
+				return msg
+			}
+			return string(formatter.Marshal(data))
+		}
+	case FormatCEF:
+		fields, err := cefFields(ds, out.fields)
+		if err != nil {
+			return nil, fmt.Errorf("data source %s: %w", ds.Name(), err)
+		}
+		format = func(data datasource.Data, severity int) string {
+			e := cefEvent{
+				product:     gadget,
+				version:     igVersion,
+				signatureID: ds.Name(),
+				name:        ds.Name(),
+				severity:    severity,
+				extension:   make([]cefField, 0, len(fields)+2),
+			}
+			if msg, ok := messageFunc(data); ok {
+				e.name = msg
+			}
+			e.extension = append(e.extension,
+				cefField{key: "dvchost", value: s.s.node},
+				cefField{key: "rt", value: strconv.FormatInt(time.Now().UnixMilli(), 10)},
+			)
+			for _, f := range fields {
+				key, value := f(data)
+				e.extension = append(e.extension, cefField{key: key, value: value})
+			}
+			return formatCEF(e)
+		}
+	}
+
+	return func(data datasource.Data) {
+		sv := severity(data)
+		h := header{
+			facility: out.facility,
+			severity: sv,
+			time:     time.Now(),
+			hostname: s.s.node,
+			appName:  out.exporter.appName,
+			msgID:    ds.Name(),
+		}
+		out.exporter.sender.send(formatRFC5424(h, format(data, sv)))
+	}, nil
+}
+
+func (s *syslogOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (s *syslogOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (s *syslogOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &syslogOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syslog
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+type fakeSender struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (f *fakeSender) send(msg string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, msg)
+}
+
+// timestamp matches the variable parts of the messages
+var timestamp = regexp.MustCompile(`\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}Z|rt=\d+`)
+
+func runProducer(t *testing.T, o *syslogOperator, annotations map[string]string, paramValues api.ParamValues) {
+	var ds datasource.DataSource
+	var comm, uid datasource.FieldAccessor
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "exec")
+		require.NoError(t, err)
+		for k, v := range annotations {
+			ds.AddAnnotation(k, v)
+		}
+		comm, err = ds.AddField("comm", api.Kind_String)
+		require.NoError(t, err)
+		uid, err = ds.AddField("uid", api.Kind_Uint32)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		for _, c := range []string{"bash", "sh"} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, comm.PutString(data, c))
+			if c == "sh" {
+				require.NoError(t, uid.PutUint32(data, 1000))
+			}
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		cancel()
+		return nil
+	}
+
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "ghcr.io/inspektor-gadget/gadget/trace_exec:latest",
+		gadgetcontext.WithDataOperators(o, producer))
+	require.NoError(t, gadgetCtx.Run(paramValues))
+}
+
+func TestForwardRFC5424(t *testing.T) {
+	sender := &fakeSender{}
+	o := &syslogOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"soc": {format: FormatRFC5424, facility: 16, appName: DefaultAppName, sender: sender},
+		},
+	}
+	runProducer(t, o, map[string]string{
+		AnnotationSyslogSeverity: `uid == 0 ? "warning" : "info"`,
+	}, api.ParamValues{
+		"operator.syslog." + ParamSyslogExporter: "soc",
+		"operator.syslog." + ParamSyslogFields:   "exec:comm",
+	})
+
+	require.Len(t, sender.messages, 2)
+	assert.Equal(t, `<132>1 TS node-1 inspektor-gadget - exec - {"comm":"bash"}`,
+		timestamp.ReplaceAllString(sender.messages[0], "TS"))
+	assert.Equal(t, `<134>1 TS node-1 inspektor-gadget - exec - {"comm":"sh"}`,
+		timestamp.ReplaceAllString(sender.messages[1], "TS"))
+}
+
+func TestForwardCEF(t *testing.T) {
+	sender := &fakeSender{}
+	o := &syslogOperator{
+		node: "node-1",
+		exporters: map[string]*exporter{
+			"soc": {format: FormatCEF, facility: 16, appName: DefaultAppName, sender: sender},
+		},
+	}
+	runProducer(t, o, map[string]string{
+		AnnotationSyslogMessage: `"execution of " + comm`,
+	}, api.ParamValues{
+		"operator.syslog." + ParamSyslogExporter: "exec:soc",
+		"operator.syslog." + ParamSyslogFacility: "auth",
+		"operator.syslog." + ParamSyslogSeverity: "notice",
+	})
+
+	require.Len(t, sender.messages, 2)
+	assert.Regexp(t, `^<37>1 TS node-1 inspektor-gadget - exec - CEF:0\|Inspektor Gadget\|trace_exec\|[^|]*\|exec\|execution of bash\|3\|dvchost=node-1 TS comm=bash uid=0$`,
+		timestamp.ReplaceAllString(sender.messages[0], "TS"))
+	assert.Regexp(t, `\|execution of sh\|3\|dvchost=node-1 TS comm=sh uid=1000$`,
+		timestamp.ReplaceAllString(sender.messages[1], "TS"))
+}
+
+func TestInvalidParams(t *testing.T) {
+	o := &syslogOperator{
+		exporters: map[string]*exporter{
+			"soc": {format: FormatRFC5424, sender: &fakeSender{}},
+		},
+	}
+	gadgetCtx := gadgetcontext.New(context.Background(), "trace_exec", gadgetcontext.WithDataOperators(o))
+	_, err := gadgetCtx.RegisterDataSource(datasource.TypeSingle, "exec")
+	require.NoError(t, err)
+
+	for _, paramValues := range []api.ParamValues{
+		{ParamSyslogExporter: "other"},
+		{ParamSyslogExporter: "soc", ParamSyslogFacility: "local9"},
+		{ParamSyslogExporter: "soc", ParamSyslogSeverity: "loud"},
+	} {
+		_, err = o.InstantiateDataOperator(gadgetCtx, paramValues)
+		require.Error(t, err, paramValues)
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	assert.Equal(t, map[string][]string{"": {"comm", "pid"}}, parseFields("comm,pid"))
+	assert.Equal(t, map[string][]string{"exec": {"comm"}, "open": {"-fname"}}, parseFields("exec:comm;open:-fname"))
+}
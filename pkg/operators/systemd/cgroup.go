@@ -0,0 +1,131 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unitSuffixes are the suffixes of the systemd units that have cgroups, slices
+// apart
+var unitSuffixes = []string{".service", ".scope", ".socket", ".mount", ".swap"}
+
+// parseCgroupFile returns the path of the systemd cgroup in the content of
+// /proc/<pid>/cgroup: the one of the name=systemd hierarchy with cgroup v1,
+// otherwise the one of the unified hierarchy.
+func parseCgroupFile(content string) string {
+	var unified string
+	for _, line := range strings.Split(content, "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[1] == "name=systemd":
+			return parts[2]
+		case parts[0] == "0" && parts[1] == "":
+			unified = parts[2]
+		}
+	}
+	return unified
+}
+
+// unitFromPath returns the most specific unit of a cgroup path and the slice
+// containing it, like "ssh.service" and "system.slice" for
+// "/system.slice/ssh.service". Sub-cgroups of units, like the ones created by
+// services managing their own cgroups, are attributed to the units.
+func unitFromPath(path string) (string, string) {
+	var unit, slice, lastSlice string
+	for _, c := range strings.Split(strings.Trim(path, "/"), "/") {
+		if strings.HasSuffix(c, ".slice") {
+			lastSlice = c
+			continue
+		}
+		for _, suffix := range unitSuffixes {
+			if strings.HasSuffix(c, suffix) {
+				unit = c
+				slice = lastSlice
+				break
+			}
+		}
+	}
+	if unit == "" {
+		slice = lastSlice
+	}
+	return unit, slice
+}
+
+type unitInfo struct {
+	unit    string
+	slice   string
+	expires time.Time
+}
+
+// unitCache caches the units of the processes read from /proc for a short
+// time, as events of the same processes come in bursts. It's short to avoid
+// mixing up processes when pids are reused.
+type unitCache struct {
+	procFs     string
+	ttl        time.Duration
+	maxEntries int
+
+	mu    sync.Mutex
+	units map[uint32]unitInfo
+}
+
+func newUnitCache(procFs string, ttl time.Duration, maxEntries int) *unitCache {
+	return &unitCache{
+		procFs:     procFs,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		units:      make(map[uint32]unitInfo),
+	}
+}
+
+// get returns the unit and the slice of a process
+func (c *unitCache) get(pid uint32, now time.Time) (unitInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if u, ok := c.units[pid]; ok && now.Before(u.expires) {
+		return u, nil
+	}
+
+	b, err := os.ReadFile(filepath.Join(c.procFs, strconv.FormatUint(uint64(pid), 10), "cgroup"))
+	if err != nil {
+		return unitInfo{}, err
+	}
+	unit, slice := unitFromPath(parseCgroupFile(string(b)))
+
+	if len(c.units) >= c.maxEntries {
+		for pid, u := range c.units {
+			if !now.Before(u.expires) {
+				delete(c.units, pid)
+			}
+		}
+		// All the entries are recent, start over
+		if len(c.units) >= c.maxEntries {
+			clear(c.units)
+		}
+	}
+	u := unitInfo{unit: unit, slice: slice, expires: now.Add(c.ttl)}
+	c.units[pid] = u
+	return u, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProcFs writes the cgroup files of fake processes, by pid
+func writeProcFs(t *testing.T, cgroups map[uint32]string) string {
+	dir := t.TempDir()
+	for pid, cgroup := range cgroups {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, fmt.Sprint(pid)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprint(pid), "cgroup"), []byte(cgroup), 0o644))
+	}
+	return dir
+}
+
+func TestParseCgroupFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "v2",
+			content:  "0::/system.slice/ssh.service\n",
+			expected: "/system.slice/ssh.service",
+		},
+		{
+			name: "v1",
+			content: "12:memory:/system.slice/ssh.service\n" +
+				"1:name=systemd:/system.slice/ssh.service\n",
+			expected: "/system.slice/ssh.service",
+		},
+		{
+			name: "hybrid",
+			content: "1:name=systemd:/system.slice/ssh.service\n" +
+				"0::/\n",
+			expected: "/system.slice/ssh.service",
+		},
+		{
+			name:     "no systemd hierarchy",
+			content:  "12:memory:/foo\n",
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, parseCgroupFile(test.content))
+		})
+	}
+}
+
+func TestUnitFromPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		path  string
+		unit  string
+		slice string
+	}{
+		{"/system.slice/ssh.service", "ssh.service", "system.slice"},
+		{"/user.slice/user-1000.slice/session-2.scope", "session-2.scope", "user-1000.slice"},
+		{"/user.slice/user-1000.slice/user@1000.service/app.slice/app-firefox-1234.scope", "app-firefox-1234.scope", "app.slice"},
+		{"/system.slice/containerd.service/subgroup", "containerd.service", "system.slice"},
+		{"/kubepods.slice/kubepods-burstable.slice", "", "kubepods-burstable.slice"},
+		{"/init.scope", "init.scope", ""},
+		{"/", "", ""},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			t.Parallel()
+			unit, slice := unitFromPath(test.path)
+			assert.Equal(t, test.unit, unit)
+			assert.Equal(t, test.slice, slice)
+		})
+	}
+}
+
+func TestUnitCache(t *testing.T) {
+	t.Parallel()
+
+	dir := writeProcFs(t, map[uint32]string{
+		100: "0::/system.slice/ssh.service\n",
+	})
+	c := newUnitCache(dir, time.Minute, 10)
+	now := time.Now()
+
+	u, err := c.get(100, now)
+	require.NoError(t, err)
+	assert.Equal(t, "ssh.service", u.unit)
+	assert.Equal(t, "system.slice", u.slice)
+
+	// Cached until it expires
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "100", "cgroup"), []byte("0::/system.slice/cron.service\n"), 0o644))
+	u, err = c.get(100, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, "ssh.service", u.unit)
+	u, err = c.get(100, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "cron.service", u.unit)
+
+	_, err = c.get(200, now)
+	require.Error(t, err)
+}
@@ -0,0 +1,203 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package systemd is a data operator that enriches the processes of the events
+// with the systemd unit and slice they belong to, resolved from their cgroup.
+// It attributes the events of host processes, which don't have container
+// information, to services like ssh.service.
+package systemd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	apihelpers "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api-helpers"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
+)
+
+const (
+	Name     = "systemd"
+	Priority = 45 // with the other operators enriching events, before sampling them
+
+	ParamEnable = "systemd-enable"
+
+	cacheTTL        = 2 * time.Second
+	cacheMaxEntries = 16384
+)
+
+type systemdOperator struct {
+	cache *unitCache
+}
+
+func (s *systemdOperator) Name() string {
+	return Name
+}
+
+func (s *systemdOperator) Init(params *params.Params) error {
+	s.cache = newUnitCache(host.HostProcFs, cacheTTL, cacheMaxEntries)
+	return nil
+}
+
+func (s *systemdOperator) GlobalParams() api.Params {
+	return nil
+}
+
+func (s *systemdOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:          ParamEnable,
+			Title:        "Systemd Units",
+			Description:  "Add the systemd unit and slice of the processes, resolved from their cgroup",
+			DefaultValue: "false",
+			TypeHint:     api.TypeBool,
+		},
+	}
+}
+
+// processAccessors holds the pid of a process struct and the fields added to
+// it
+type processAccessors struct {
+	pid datasource.FieldAccessor
+
+	unit  datasource.FieldAccessor
+	slice datasource.FieldAccessor
+}
+
+func (s *systemdOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	params := apihelpers.ToParamDescs(s.InstanceParams()).ToParams()
+	if err := params.CopyFromMap(instanceParamValues, ""); err != nil {
+		return nil, err
+	}
+	if !params.Get(ParamEnable).AsBool() {
+		return nil, nil
+	}
+
+	procAccessors := make(map[datasource.DataSource][]processAccessors)
+	for _, ds := range gadgetCtx.GetDataSources() {
+		for _, proc := range ds.GetFieldsWithTag("type:" + ebpftypes.ProcessTypeName) {
+			pa, err := newProcessAccessors(proc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", proc.FullName(), err)
+			}
+			procAccessors[ds] = append(procAccessors[ds], pa)
+		}
+	}
+	if len(procAccessors) == 0 {
+		return nil, nil
+	}
+
+	return &systemdOperatorInstance{
+		cache:          s.cache,
+		procsAccessors: procAccessors,
+	}, nil
+}
+
+// newProcessAccessors adds the systemd.unit and systemd.slice fields to a
+// process
+func newProcessAccessors(proc datasource.FieldAccessor) (processAccessors, error) {
+	var pa processAccessors
+
+	pid := proc.GetSubFieldsWithTag("type:" + ebpftypes.PidTypeName)
+	if len(pid) != 1 {
+		return pa, fmt.Errorf("expected one %q field", ebpftypes.PidTypeName)
+	}
+	pa.pid = pid[0]
+
+	systemd, err := proc.AddSubField("systemd", api.Kind_Invalid, datasource.WithFlags(datasource.FieldFlagEmpty))
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "systemd", err)
+	}
+	pa.unit, err = systemd.AddSubField("unit", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation:     "Systemd unit of the process, like ssh.service",
+			metadatav1.ColumnsMaxWidthAnnotation: "40",
+		}),
+	)
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "unit", err)
+	}
+	pa.slice, err = systemd.AddSubField("slice", api.Kind_String,
+		datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation: "Systemd slice containing the unit of the process, like system.slice",
+		}),
+		datasource.WithFlags(datasource.FieldFlagHidden),
+	)
+	if err != nil {
+		return pa, fmt.Errorf("adding field %q: %w", "slice", err)
+	}
+	return pa, nil
+}
+
+func (s *systemdOperator) Priority() int {
+	return Priority
+}
+
+type systemdOperatorInstance struct {
+	cache          *unitCache
+	procsAccessors map[datasource.DataSource][]processAccessors
+}
+
+func (s *systemdOperatorInstance) Name() string {
+	return Name
+}
+
+func (s *systemdOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, accessors := range s.procsAccessors {
+		err := ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			now := time.Now()
+			for _, pa := range accessors {
+				pid, err := pa.pid.Uint32(data)
+				if err != nil || pid == 0 {
+					continue
+				}
+				// Processes that already exited can't be resolved
+				u, err := s.cache.get(pid, now)
+				if err != nil {
+					continue
+				}
+				pa.unit.PutString(data, u.unit)
+				pa.slice.PutString(data, u.slice)
+			}
+			return nil
+		}, Priority)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *systemdOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (s *systemdOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (s *systemdOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &systemdOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	ebpftypes "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/ebpf/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+type result struct {
+	unit  string
+	slice string
+}
+
+func TestSystemd(t *testing.T) {
+	dir := writeProcFs(t, map[uint32]string{
+		100: "0::/system.slice/ssh.service\n",
+		200: "0::/user.slice/user-1000.slice/session-2.scope\n",
+	})
+
+	var ds datasource.DataSource
+	var pidField datasource.FieldAccessor
+	var got []result
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "exec")
+		require.NoError(t, err)
+		proc, err := ds.AddField("proc", api.Kind_Invalid, datasource.WithTags("type:"+ebpftypes.ProcessTypeName))
+		require.NoError(t, err)
+		pidField, err = proc.AddSubField("pid", api.Kind_Uint32, datasource.WithTags("type:"+ebpftypes.PidTypeName))
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		// 300 already exited
+		for _, pid := range []uint32{100, 200, 300} {
+			data, err := ds.NewPacketSingle()
+			require.NoError(t, err)
+			require.NoError(t, pidField.PutUint32(data, pid))
+			require.NoError(t, ds.EmitAndRelease(data))
+		}
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		unit := ds.GetField("proc.systemd.unit")
+		require.NotNil(t, unit)
+		slice := ds.GetField("proc.systemd.slice")
+		require.NotNil(t, slice)
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			var r result
+			var err error
+			r.unit, err = unit.String(data)
+			require.NoError(t, err)
+			r.slice, err = slice.String(data)
+			require.NoError(t, err)
+			got = append(got, r)
+			if len(got) == 3 {
+				cancel()
+			}
+			return nil
+		}, Priority+1)
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	op := &systemdOperator{cache: newUnitCache(dir, time.Minute, 10)}
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(op, producer, consumer))
+	require.NoError(t, gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamEnable: "true",
+	}))
+
+	assert.Equal(t, []result{
+		{unit: "ssh.service", slice: "system.slice"},
+		{unit: "session-2.scope", slice: "user-1000.slice"},
+		{},
+	}, got)
+}
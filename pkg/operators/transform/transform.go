@@ -0,0 +1,376 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package transform is a data operator that adds fields computed from the
+// other fields of the events with expressions, like the throughput from a
+// number of bytes and an interval or the domain of a DNS name. Gadgets define
+// them with annotations of their data sources and users with a parameter, so
+// simple transformations don't need WASM.
+package transform
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/expr-lang/expr/vm"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/expr"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	Name     = "transform"
+	Priority = 8950 // after the identity operator, before the filter operator so filters can use the new fields
+
+	ParamFields = "transform-fields"
+
+	// AnnotationPrefix is the prefix of the data source annotations defining
+	// fields, like "transform.bytes_per_sec: bytes / interval"
+	AnnotationPrefix = "transform."
+)
+
+// transformation sets a field to the result of an expression
+type transformation struct {
+	dataSource string
+	field      string
+	expression string
+}
+
+// parseTransformations parses transformations separated by semicolons or new
+// lines like "FIELD=EXPRESSION" or "DATASOURCE:FIELD=EXPRESSION"
+func parseTransformations(s string) ([]transformation, error) {
+	var transformations []transformation
+	for _, t := range strings.FieldsFunc(s, func(c rune) bool { return c == ';' || c == '\n' }) {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+
+		target, expression, ok := strings.Cut(t, "=")
+		expression = strings.TrimSpace(expression)
+		if !ok || expression == "" {
+			return nil, fmt.Errorf("invalid transformation %q: expected [DATASOURCE:]FIELD=EXPRESSION", t)
+		}
+		dataSource, field, ok := strings.Cut(target, ":")
+		if !ok {
+			dataSource, field = "", target
+		}
+		field = strings.TrimSpace(field)
+		if field == "" {
+			return nil, fmt.Errorf("invalid transformation %q: missing field", t)
+		}
+		transformations = append(transformations, transformation{
+			dataSource: strings.TrimSpace(dataSource),
+			field:      field,
+			expression: expression,
+		})
+	}
+	return transformations, nil
+}
+
+// annotatedTransformations returns the transformations defined by the
+// annotations of a data source, sorted by field name
+func annotatedTransformations(ds datasource.DataSource) []transformation {
+	var transformations []transformation
+	for k, v := range ds.Annotations() {
+		field, ok := strings.CutPrefix(k, AnnotationPrefix)
+		if !ok || field == "" || strings.TrimSpace(v) == "" {
+			continue
+		}
+		transformations = append(transformations, transformation{
+			dataSource: ds.Name(),
+			field:      field,
+			expression: strings.TrimSpace(v),
+		})
+	}
+	slices.SortFunc(transformations, func(a, b transformation) int {
+		return strings.Compare(a.field, b.field)
+	})
+	return transformations
+}
+
+type transformOperator struct{}
+
+func (t *transformOperator) Name() string {
+	return Name
+}
+
+func (t *transformOperator) Init(params *params.Params) error {
+	return nil
+}
+
+func (t *transformOperator) GlobalParams() api.Params {
+	return nil
+}
+
+func (t *transformOperator) InstanceParams() api.Params {
+	return api.Params{
+		{
+			Key:   ParamFields,
+			Title: "Computed Fields",
+			Description: "Fields computed from other fields, separated by semicolons, like " +
+				"'bytes_per_sec=bytes / interval; domain=join(split(name, \".\")[-3:], \".\")'. " +
+				"Each one is [DATASOURCE:]FIELD=EXPRESSION, where EXPRESSION uses the syntax of filter expressions. " +
+				"Existing fields are overwritten.",
+			TypeHint: api.TypeString,
+		},
+	}
+}
+
+func (t *transformOperator) InstantiateDataOperator(gadgetCtx operators.GadgetContext, instanceParamValues api.ParamValues) (operators.DataOperatorInstance, error) {
+	transformations, err := parseTransformations(instanceParamValues[ParamFields])
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ParamFields, err)
+	}
+
+	dataSources := gadgetCtx.GetDataSources()
+	for _, tr := range transformations {
+		if tr.dataSource == "" {
+			continue
+		}
+		if _, ok := dataSources[tr.dataSource]; !ok {
+			return nil, fmt.Errorf("computing field %q: data source %q not found", tr.field, tr.dataSource)
+		}
+	}
+
+	instance := &transformOperatorInstance{
+		fields: make(map[datasource.DataSource][]*computedField),
+	}
+	for _, ds := range dataSources {
+		// Transformations of the gadget come first, so users can build on them
+		for _, tr := range append(annotatedTransformations(ds), transformations...) {
+			if tr.dataSource != "" && tr.dataSource != ds.Name() {
+				continue
+			}
+			cf, err := newComputedField(ds, tr)
+			if err != nil {
+				return nil, fmt.Errorf("computing field %q of datasource %s: %w", tr.field, ds.Name(), err)
+			}
+			gadgetCtx.Logger().Debugf("transform: computing field %q of %s as %s", tr.field, ds.Name(), tr.expression)
+			instance.fields[ds] = append(instance.fields[ds], cf)
+		}
+	}
+	if len(instance.fields) == 0 {
+		return nil, nil
+	}
+	return instance, nil
+}
+
+func (t *transformOperator) Priority() int {
+	return Priority
+}
+
+// computedField holds the field set by a transformation and the compiled
+// expression computing it
+type computedField struct {
+	field      datasource.FieldAccessor
+	expression string
+	prog       *vm.Program
+}
+
+// newComputedField compiles the expression of a transformation and adds the
+// field holding its result, with a type depending on the one of the result. It
+// reuses the field if it already exists.
+func newComputedField(ds datasource.DataSource, tr transformation) (*computedField, error) {
+	prog, err := expr.CompileProgram(ds, tr.expression)
+	if err != nil {
+		return nil, err
+	}
+
+	f := ds.GetField(tr.field)
+	if f == nil {
+		f, err = addField(ds, tr.field, kindOf(prog.Node().Type()),
+			datasource.WithAnnotations(map[string]string{
+				metadatav1.DescriptionAnnotation: "Computed as " + tr.expression,
+			}),
+		)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(f.SubFields()) > 0 {
+		return nil, fmt.Errorf("field has subfields")
+	}
+
+	return &computedField{
+		field:      f,
+		expression: tr.expression,
+		prog:       prog,
+	}, nil
+}
+
+// addField adds a field, as a subfield if its name contains dots, like
+// k8s.team
+func addField(ds datasource.DataSource, name string, kind api.Kind, opts ...datasource.FieldOption) (datasource.FieldAccessor, error) {
+	idx := strings.LastIndex(name, ".")
+	if idx < 0 {
+		return ds.AddField(name, kind, opts...)
+	}
+	parent := ds.GetField(name[:idx])
+	if parent == nil {
+		return nil, fmt.Errorf("parent field %q not found", name[:idx])
+	}
+	return parent.AddSubField(name[idx+1:], kind, opts...)
+}
+
+// kindOf returns the kind of the field holding values of the given type.
+// Values of unknown types are stored as strings.
+func kindOf(t reflect.Type) api.Kind {
+	if t == nil {
+		return api.Kind_String
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return api.Kind_Bool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return api.Kind_Int64
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return api.Kind_Uint64
+	case reflect.Float32, reflect.Float64:
+		return api.Kind_Float64
+	default:
+		return api.Kind_String
+	}
+}
+
+// putValue stores the result of an expression in a field, converting it to
+// the type of the field
+func putValue(f datasource.FieldAccessor, data datasource.Data, value any) error {
+	if f.Type() == api.Kind_String || f.Type() == api.Kind_CString {
+		if s, ok := value.(string); ok {
+			return f.PutString(data, s)
+		}
+		return f.PutString(data, fmt.Sprint(value))
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.CanInt(), v.CanUint(), v.CanFloat():
+	case v.Kind() == reflect.Bool && f.Type() == api.Kind_Bool:
+		return f.PutBool(data, v.Bool())
+	default:
+		return fmt.Errorf("can't store %T in %s field", value, f.Type())
+	}
+
+	switch f.Type() {
+	case api.Kind_Int8:
+		return f.PutInt8(data, int8(toInt64(v)))
+	case api.Kind_Int16:
+		return f.PutInt16(data, int16(toInt64(v)))
+	case api.Kind_Int32:
+		return f.PutInt32(data, int32(toInt64(v)))
+	case api.Kind_Int64:
+		return f.PutInt64(data, toInt64(v))
+	case api.Kind_Uint8:
+		return f.PutUint8(data, uint8(toUint64(v)))
+	case api.Kind_Uint16:
+		return f.PutUint16(data, uint16(toUint64(v)))
+	case api.Kind_Uint32:
+		return f.PutUint32(data, uint32(toUint64(v)))
+	case api.Kind_Uint64:
+		return f.PutUint64(data, toUint64(v))
+	case api.Kind_Float32:
+		return f.PutFloat32(data, float32(toFloat64(v)))
+	case api.Kind_Float64:
+		return f.PutFloat64(data, toFloat64(v))
+	default:
+		return fmt.Errorf("can't store %T in %s field", value, f.Type())
+	}
+}
+
+func toInt64(v reflect.Value) int64 {
+	switch {
+	case v.CanUint():
+		return int64(v.Uint())
+	case v.CanFloat():
+		return int64(v.Float())
+	default:
+		return v.Int()
+	}
+}
+
+func toUint64(v reflect.Value) uint64 {
+	switch {
+	case v.CanInt():
+		return uint64(v.Int())
+	case v.CanFloat():
+		return uint64(v.Float())
+	default:
+		return v.Uint()
+	}
+}
+
+func toFloat64(v reflect.Value) float64 {
+	switch {
+	case v.CanInt():
+		return float64(v.Int())
+	case v.CanUint():
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
+
+type transformOperatorInstance struct {
+	fields map[datasource.DataSource][]*computedField
+}
+
+func (t *transformOperatorInstance) Name() string {
+	return Name
+}
+
+func (t *transformOperatorInstance) PreStart(gadgetCtx operators.GadgetContext) error {
+	for ds, fields := range t.fields {
+		err := ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			for _, cf := range fields {
+				ret, err := expr.Run(cf.prog, data)
+				if err != nil {
+					gadgetCtx.Logger().Errorf("running expression %q for field %s of datasource %s: %v", cf.expression, cf.field.FullName(), ds.Name(), err)
+					continue
+				}
+				if err := putValue(cf.field, data, ret); err != nil {
+					gadgetCtx.Logger().Errorf("setting field %s of datasource %s: %v", cf.field.FullName(), ds.Name(), err)
+				}
+			}
+			return nil
+		}, Priority)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *transformOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (t *transformOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+func (t *transformOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
+	return nil
+}
+
+var Operator = &transformOperator{}
+
+func init() {
+	operators.RegisterDataOperator(Operator)
+}
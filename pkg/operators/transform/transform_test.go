@@ -0,0 +1,214 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+func TestParseTransformations(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []transformation
+		wantErr  bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "multiple",
+			value: "rate=bytes / interval; dns:domain = lower(name)\nup=upper(comm)",
+			expected: []transformation{
+				{field: "rate", expression: "bytes / interval"},
+				{dataSource: "dns", field: "domain", expression: "lower(name)"},
+				{field: "up", expression: "upper(comm)"},
+			},
+		},
+		{
+			name:     "expression with equals",
+			value:    "local=dst.port == 443",
+			expected: []transformation{{field: "local", expression: "dst.port == 443"}},
+		},
+		{
+			name:    "no expression",
+			value:   "rate=",
+			wantErr: true,
+		},
+		{
+			name:    "no field",
+			value:   "dns:=name",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseTransformations(test.value)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
+
+type result struct {
+	rate   float64
+	double uint64
+	domain string
+	comm   string
+	slow   bool
+}
+
+func TestTransform(t *testing.T) {
+	var ds datasource.DataSource
+	var nameField, commField, bytesField, intervalField datasource.FieldAccessor
+	var got []result
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "dns")
+		require.NoError(t, err)
+		nameField, err = ds.AddField("name", api.Kind_String)
+		require.NoError(t, err)
+		commField, err = ds.AddField("comm", api.Kind_String)
+		require.NoError(t, err)
+		bytesField, err = ds.AddField("bytes", api.Kind_Uint64)
+		require.NoError(t, err)
+		intervalField, err = ds.AddField("interval", api.Kind_Uint32)
+		require.NoError(t, err)
+		ds.AddAnnotation(AnnotationPrefix+"rate", "bytes / interval")
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		data, err := ds.NewPacketSingle()
+		require.NoError(t, err)
+		require.NoError(t, nameField.PutString(data, "www.example.com."))
+		require.NoError(t, commField.PutString(data, "curl"))
+		require.NoError(t, bytesField.PutUint64(data, 300))
+		require.NoError(t, intervalField.PutUint32(data, 4))
+		require.NoError(t, ds.EmitAndRelease(data))
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	consume := func(gadgetCtx operators.GadgetContext) error {
+		rate := ds.GetField("rate")
+		require.NotNil(t, rate)
+		assert.Equal(t, api.Kind_Float64, rate.Type())
+		double := ds.GetField("double")
+		require.NotNil(t, double)
+		assert.Equal(t, api.Kind_Int64, double.Type())
+		domain := ds.GetField("domain")
+		require.NotNil(t, domain)
+		assert.Equal(t, api.Kind_String, domain.Type())
+		slow := ds.GetField("slow")
+		require.NotNil(t, slow)
+		assert.Equal(t, api.Kind_Bool, slow.Type())
+
+		ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+			var r result
+			var err error
+			r.rate, err = rate.Float64(data)
+			require.NoError(t, err)
+			d, err := double.Int64(data)
+			require.NoError(t, err)
+			r.double = uint64(d)
+			r.domain, err = domain.String(data)
+			require.NoError(t, err)
+			r.comm, err = commField.String(data)
+			require.NoError(t, err)
+			r.slow, err = slow.Bool(data)
+			require.NoError(t, err)
+			got = append(got, r)
+			cancel()
+			return nil
+		}, Priority+1)
+		return nil
+	}
+	consumer := simple.New("consumer",
+		simple.WithPriority(Priority+1),
+		simple.OnPreStart(consume),
+	)
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(Operator, producer, consumer))
+	err := gadgetCtx.Run(api.ParamValues{
+		"operator." + Name + "." + ParamFields: `double=bytes * 2; dns:domain=join(split(trimSuffix(name, "."), ".")[-2:], ".");` +
+			`comm=upper(comm); slow=rate < 100`,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []result{
+		{rate: 75, double: 600, domain: "example.com", comm: "CURL", slow: true},
+	}, got)
+}
+
+func TestTransformInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields string
+	}{
+		{
+			name:   "unknown field",
+			fields: "rate=bytes / time",
+		},
+		{
+			name:   "unknown data source",
+			fields: "exec:rate=bytes",
+		},
+		{
+			name:   "unknown parent",
+			fields: "k8s.rate=bytes",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			producer := simple.New("producer",
+				simple.WithPriority(Priority-1),
+				simple.OnInit(func(gadgetCtx operators.GadgetContext) error {
+					ds, err := gadgetCtx.RegisterDataSource(datasource.TypeSingle, "dns")
+					require.NoError(t, err)
+					_, err = ds.AddField("bytes", api.Kind_Uint64)
+					require.NoError(t, err)
+					return nil
+				}),
+			)
+			gadgetCtx := gadgetcontext.New(context.Background(), "", gadgetcontext.WithDataOperators(Operator, producer))
+			require.Error(t, gadgetCtx.Run(api.ParamValues{"operator." + Name + "." + ParamFields: test.fields}))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	wapi "github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// The ABI between the host and the wasm modules is versioned by the value
+// returned by their gadgetAPIVersion function. New host functions can be added
+// without changing it: modules using them fail to load on older versions of
+// Inspektor Gadget with an error listing them, before any of their code runs.
+// Changing or removing host functions requires a new version.
+
+// checkGuestABI checks that a wasm module can be instantiated with the host
+// module, i.e. it exports gadgetAPIVersion and only imports host functions
+// provided with the same signature, so it doesn't trap at runtime.
+func checkGuestABI(host, guest wazero.CompiledModule) error {
+	versionF, ok := guest.ExportedFunctions()["gadgetAPIVersion"]
+	if !ok {
+		return errors.New("wasm module doesn't export gadgetAPIVersion")
+	}
+	if len(versionF.ParamTypes()) != 0 || !slices.Equal(versionF.ResultTypes(), []wapi.ValueType{wapi.ValueTypeI64}) {
+		return fmt.Errorf("gadgetAPIVersion has signature %s, expected () -> (i64)", signature(versionF))
+	}
+
+	hostFuncs := host.ExportedFunctions()
+	var missing []string
+	var errs []error
+	for _, fn := range guest.ImportedFunctions() {
+		moduleName, name, _ := fn.Import()
+		switch moduleName {
+		case wasi_snapshot_preview1.ModuleName:
+			continue
+		case host.Name():
+		default:
+			errs = append(errs, fmt.Errorf("wasm module imports %s.%s from unknown module %q", moduleName, name, moduleName))
+			continue
+		}
+
+		hostF, ok := hostFuncs[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		if !slices.Equal(fn.ParamTypes(), hostF.ParamTypes()) || !slices.Equal(fn.ResultTypes(), hostF.ResultTypes()) {
+			errs = append(errs, fmt.Errorf("wasm module imports %s with signature %s, expected %s",
+				name, signature(fn), signature(hostF)))
+		}
+	}
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("wasm module uses host functions not provided by this version of Inspektor Gadget (API version %d): %s",
+			apiVersion, strings.Join(missing, ", ")))
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkAPIVersion checks that the version returned by the gadgetAPIVersion
+// function of a wasm module is supported
+func checkAPIVersion(version uint64) error {
+	switch {
+	case version < minAPIVersion:
+		return fmt.Errorf("gadget API version %d is no longer supported, oldest supported: %d: rebuild the gadget with a newer wasmapi",
+			version, minAPIVersion)
+	case version > apiVersion:
+		return fmt.Errorf("gadget API version %d is newer than the supported one: %d: update Inspektor Gadget",
+			version, apiVersion)
+	}
+	return nil
+}
+
+// signature returns the signature of a function, like "(i64, i32) -> (i32)"
+func signature(fn wapi.FunctionDefinition) string {
+	names := func(types []wapi.ValueType) string {
+		s := make([]string, len(types))
+		for i, t := range types {
+			s[i] = wapi.ValueTypeName(t)
+		}
+		return strings.Join(s, ", ")
+	}
+	return fmt.Sprintf("(%s) -> (%s)", names(fn.ParamTypes()), names(fn.ResultTypes()))
+}
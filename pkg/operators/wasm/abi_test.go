@@ -0,0 +1,350 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tetratelabs/wazero"
+	wapi "github.com/tetratelabs/wazero/api"
+)
+
+// testFunc is a function of a wasm module built by buildTestModule
+type testFunc struct {
+	module  string // Module of imported functions
+	name    string
+	params  []wapi.ValueType
+	results []wapi.ValueType
+	// Value returned by exported functions with a result
+	ret int64
+}
+
+func appendULEB(b []byte, v uint64) []byte {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func appendSLEB(b []byte, v int64) []byte {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func appendName(b []byte, name string) []byte {
+	return append(appendULEB(b, uint64(len(name))), name...)
+}
+
+func appendSection(b []byte, id byte, count int, content []byte) []byte {
+	content = append(appendULEB(nil, uint64(count)), content...)
+	b = append(b, id)
+	b = appendULEB(b, uint64(len(content)))
+	return append(b, content...)
+}
+
+// buildTestModule returns the binary of a wasm module importing and exporting
+// the given functions. Exported functions don't have params and return ret.
+func buildTestModule(imports, exports []testFunc) []byte {
+	var types, importSec, funcSec, exportSec, codeSec []byte
+
+	funcs := append(append([]testFunc{}, imports...), exports...)
+	for _, fn := range funcs {
+		types = append(types, 0x60)
+		types = appendULEB(types, uint64(len(fn.params)))
+		types = append(types, fn.params...)
+		types = appendULEB(types, uint64(len(fn.results)))
+		types = append(types, fn.results...)
+	}
+
+	for idx, fn := range imports {
+		importSec = appendName(importSec, fn.module)
+		importSec = appendName(importSec, fn.name)
+		importSec = append(importSec, 0x00) // function
+		importSec = appendULEB(importSec, uint64(idx))
+	}
+
+	for idx, fn := range exports {
+		funcIdx := uint64(len(imports) + idx)
+		funcSec = appendULEB(funcSec, funcIdx)
+
+		exportSec = appendName(exportSec, fn.name)
+		exportSec = append(exportSec, 0x00) // function
+		exportSec = appendULEB(exportSec, funcIdx)
+
+		body := []byte{0x00} // no locals
+		switch {
+		case len(fn.results) == 0:
+		case fn.results[0] == wapi.ValueTypeI64:
+			body = appendSLEB(append(body, 0x42), fn.ret) // i64.const
+		default:
+			body = appendSLEB(append(body, 0x41), fn.ret) // i32.const
+		}
+		body = append(body, 0x0b) // end
+		codeSec = appendULEB(codeSec, uint64(len(body)))
+		codeSec = append(codeSec, body...)
+	}
+
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	b = appendSection(b, 1, len(funcs), types)
+	b = appendSection(b, 2, len(imports), importSec)
+	b = appendSection(b, 3, len(exports), funcSec)
+	b = appendSection(b, 7, len(exports), exportSec)
+	b = appendSection(b, 10, len(exports), codeSec)
+	return b
+}
+
+func compileHostModule(t *testing.T) (wazero.Runtime, wazero.CompiledModule) {
+	ctx := context.Background()
+	i := &wasmOperatorInstance{rt: wazero.NewRuntime(ctx)}
+	t.Cleanup(func() { i.rt.Close(ctx) })
+
+	host, err := i.newHostModuleBuilder().Compile(ctx)
+	require.NoError(t, err)
+	return i.rt, host
+}
+
+func TestCheckGuestABI(t *testing.T) {
+	t.Parallel()
+
+	rt, host := compileHostModule(t)
+
+	i32 := wapi.ValueTypeI32
+	i64 := wapi.ValueTypeI64
+	version := testFunc{name: "gadgetAPIVersion", results: []wapi.ValueType{i64}, ret: apiVersion}
+
+	tests := []struct {
+		name    string
+		imports []testFunc
+		exports []testFunc
+		err     string
+	}{
+		{
+			name: "valid",
+			imports: []testFunc{
+				{module: "ig", name: "gadgetLog", params: []wapi.ValueType{i32, i64}},
+				{module: "wasi_snapshot_preview1", name: "proc_exit", params: []wapi.ValueType{i32}},
+			},
+			exports: []testFunc{version},
+		},
+		{
+			name: "no_version",
+			err:  "doesn't export gadgetAPIVersion",
+		},
+		{
+			name:    "version_wrong_signature",
+			exports: []testFunc{{name: "gadgetAPIVersion", results: []wapi.ValueType{i32}, ret: apiVersion}},
+			err:     "gadgetAPIVersion has signature () -> (i32)",
+		},
+		{
+			name: "missing_funcs",
+			imports: []testFunc{
+				{module: "ig", name: "gadgetLog", params: []wapi.ValueType{i32, i64}},
+				{module: "ig", name: "fromTheFuture"},
+				{module: "ig", name: "fromTheFuture2", results: []wapi.ValueType{i32}},
+			},
+			exports: []testFunc{version},
+			err:     "not provided by this version of Inspektor Gadget (API version 1): fromTheFuture, fromTheFuture2",
+		},
+		{
+			name: "wrong_signature",
+			imports: []testFunc{
+				{module: "ig", name: "gadgetLog", params: []wapi.ValueType{i32, i32}},
+			},
+			exports: []testFunc{version},
+			err:     "imports gadgetLog with signature (i32, i32) -> (), expected (i32, i64) -> ()",
+		},
+		{
+			name: "unknown_module",
+			imports: []testFunc{
+				{module: "env", name: "foo"},
+			},
+			exports: []testFunc{version},
+			err:     `imports env.foo from unknown module "env"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			guest, err := rt.CompileModule(context.Background(), buildTestModule(test.imports, test.exports))
+			require.NoError(t, err)
+
+			err = checkGuestABI(host, guest)
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+func TestCheckAPIVersion(t *testing.T) {
+	t.Parallel()
+
+	for v := uint64(minAPIVersion); v <= apiVersion; v++ {
+		assert.NoError(t, checkAPIVersion(v), "version %d", v)
+	}
+	assert.ErrorContains(t, checkAPIVersion(minAPIVersion-1), "no longer supported")
+	assert.ErrorContains(t, checkAPIVersion(apiVersion+1), "newer than the supported one")
+}
+
+// valueTypes maps the types used by the declarations of the host functions in
+// the Go and Rust APIs to wasm types
+var valueTypes = map[string]wapi.ValueType{
+	"int32":  wapi.ValueTypeI32,
+	"uint32": wapi.ValueTypeI32,
+	"int64":  wapi.ValueTypeI64,
+	"uint64": wapi.ValueTypeI64,
+	"i32":    wapi.ValueTypeI32,
+	"u32":    wapi.ValueTypeI32,
+	"i64":    wapi.ValueTypeI64,
+	"u64":    wapi.ValueTypeI64,
+}
+
+func toValueTypes(t *testing.T, names []string) []wapi.ValueType {
+	ret := []wapi.ValueType{}
+	for _, name := range names {
+		typ, ok := valueTypes[name]
+		require.True(t, ok, "unknown type %q", name)
+		ret = append(ret, typ)
+	}
+	return ret
+}
+
+// goImports returns the host functions declared by the Go API
+func goImports(t *testing.T) []testFunc {
+	files, err := filepath.Glob("../../../wasmapi/go/*.go")
+	require.NoError(t, err)
+
+	var ret []testFunc
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		require.NoError(t, err)
+
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Doc == nil {
+				continue
+			}
+			for _, c := range fn.Doc.List {
+				fields := strings.Fields(c.Text)
+				if len(fields) != 3 || fields[0] != "//go:wasmimport" {
+					continue
+				}
+
+				typeNames := func(fl *ast.FieldList) []string {
+					var names []string
+					if fl == nil {
+						return names
+					}
+					for _, field := range fl.List {
+						n := max(len(field.Names), 1)
+						for range n {
+							names = append(names, field.Type.(*ast.Ident).Name)
+						}
+					}
+					return names
+				}
+				ret = append(ret, testFunc{
+					module:  fields[1],
+					name:    fields[2],
+					params:  toValueTypes(t, typeNames(fn.Type.Params)),
+					results: toValueTypes(t, typeNames(fn.Type.Results)),
+				})
+			}
+		}
+	}
+	return ret
+}
+
+var rustImportRe = regexp.MustCompile(`#\[link_name = "(\w+)"\]\s*fn \w+\(([^)]*)\)(?:\s*->\s*(\w+))?;`)
+
+// rustImports returns the host functions declared by the Rust API
+func rustImports(t *testing.T) []testFunc {
+	files, err := filepath.Glob("../../../wasmapi/rust/src/*.rs")
+	require.NoError(t, err)
+
+	var ret []testFunc
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+
+		for _, m := range rustImportRe.FindAllStringSubmatch(string(content), -1) {
+			var params, results []string
+			for _, param := range strings.Split(m[2], ",") {
+				if _, typ, ok := strings.Cut(param, ":"); ok {
+					params = append(params, strings.TrimSpace(typ))
+				}
+			}
+			if m[3] != "" {
+				results = append(results, m[3])
+			}
+			ret = append(ret, testFunc{
+				module:  "ig",
+				name:    m[1],
+				params:  toValueTypes(t, params),
+				results: toValueTypes(t, results),
+			})
+		}
+	}
+	return ret
+}
+
+// TestAPIConformance checks that the host functions declared by the Go and
+// Rust APIs are provided by the host with the same signature
+func TestAPIConformance(t *testing.T) {
+	t.Parallel()
+
+	rt, host := compileHostModule(t)
+	version := testFunc{name: "gadgetAPIVersion", results: []wapi.ValueType{wapi.ValueTypeI64}, ret: apiVersion}
+
+	for lang, imports := range map[string][]testFunc{
+		"go":   goImports(t),
+		"rust": rustImports(t),
+	} {
+		t.Run(lang, func(t *testing.T) {
+			t.Parallel()
+
+			require.NotEmpty(t, imports)
+
+			guest, err := rt.CompileModule(context.Background(), buildTestModule(imports, []testFunc{version}))
+			require.NoError(t, err)
+			require.NoError(t, checkGuestABI(host, guest))
+		})
+	}
+}
@@ -47,6 +47,8 @@ func TestConsts(t *testing.T) {
 	assert.EqualValues(t, wasmapi.DebugLevel, debugLevel)
 	assert.EqualValues(t, wasmapi.TraceLevel, traceLevel)
 
+	assert.EqualValues(t, wasmapi.APIVersion, apiVersion)
+
 	// FieldKind
 	assert.EqualValues(t, wasmapi.Kind_Invalid, api.Kind_Invalid)
 	assert.EqualValues(t, wasmapi.Kind_Bool, api.Kind_Bool)
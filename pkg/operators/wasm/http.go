@@ -0,0 +1,300 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	wapi "github.com/tetratelabs/wazero/api"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+const (
+	// Params added to gadgets sending HTTP requests
+	httpAllowlistParam = "http-allowlist"
+	httpTimeoutParam   = "http-timeout"
+
+	defaultHTTPTimeout = 5 * time.Second
+	maxHTTPRedirects   = 10
+)
+
+// httpFuncs are the host functions sending HTTP requests. The params to
+// configure them are only added to the gadgets importing them.
+var httpFuncs = []string{"httpGet", "httpPost"}
+
+func httpParams() api.Params {
+	return api.Params{
+		{
+			Key:         httpAllowlistParam,
+			Title:       "HTTP Allowlist",
+			Description: "Comma-separated list of URL prefixes, like https://example.com/api/, the gadget is allowed to send HTTP requests to. HTTP requests are denied if empty",
+		},
+		{
+			Key:          httpTimeoutParam,
+			Title:        "HTTP Timeout",
+			Description:  "Timeout of the HTTP requests sent by the gadget",
+			DefaultValue: defaultHTTPTimeout.String(),
+			TypeHint:     api.TypeDuration,
+		},
+	}
+}
+
+// httpAllowlist is a list of URL prefixes HTTP requests are allowed to
+type httpAllowlist []*url.URL
+
+func parseHTTPAllowlist(s string) (httpAllowlist, error) {
+	var allowlist httpAllowlist
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		u, err := url.Parse(entry)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", entry, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("%q: scheme must be http or https", entry)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("%q: missing host", entry)
+		}
+		allowlist = append(allowlist, u)
+	}
+	return allowlist, nil
+}
+
+// allows returns whether a URL matches any of the prefixes: it must have the
+// same scheme and host, and its path must be under the one of the prefix.
+// URLs with dot segments are never allowed, as servers could resolve them to
+// paths out of the prefixes.
+func (a httpAllowlist) allows(u *url.URL) bool {
+	p := "/" + u.Path + "/"
+	if strings.Contains(p, "/../") || strings.Contains(p, "/./") {
+		return false
+	}
+	for _, prefix := range a {
+		if u.Scheme != prefix.Scheme || !strings.EqualFold(u.Host, prefix.Host) {
+			continue
+		}
+		prefixPath := strings.TrimSuffix(prefix.Path, "/")
+		if prefixPath == "" || u.Path == prefixPath || strings.HasPrefix(u.Path, prefixPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// initHTTP creates the HTTP client of the gadget from its params. The client
+// is nil, denying all the requests, if the allowlist is empty.
+func (i *wasmOperatorInstance) initHTTP() error {
+	allowlist, err := parseHTTPAllowlist(i.paramValues[httpAllowlistParam])
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", httpAllowlistParam, err)
+	}
+	if len(allowlist) == 0 {
+		return nil
+	}
+
+	timeout := defaultHTTPTimeout
+	if s := i.paramValues[httpTimeoutParam]; s != "" {
+		timeout, err = time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", httpTimeoutParam, err)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("%s must be positive", httpTimeoutParam)
+		}
+	}
+
+	i.httpAllowlist = allowlist
+	i.httpClient = &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxHTTPRedirects {
+				return errors.New("too many redirects")
+			}
+			if !allowlist.allows(req.URL) {
+				return fmt.Errorf("redirect to %q not allowed", req.URL.Redacted())
+			}
+			return nil
+		},
+	}
+	return nil
+}
+
+func (i *wasmOperatorInstance) addHTTPFuncs(env wazero.HostModuleBuilder) {
+	exportFunction(env, "httpGet", i.httpGet,
+		[]wapi.ValueType{
+			wapi.ValueTypeI64, // URL
+			wapi.ValueTypeI64, // Dest buffer
+			wapi.ValueTypeI32, // Status code pointer
+		},
+		[]wapi.ValueType{wapi.ValueTypeI32}, // N bytes or Error
+	)
+
+	exportFunction(env, "httpPost", i.httpPost,
+		[]wapi.ValueType{
+			wapi.ValueTypeI64, // URL
+			wapi.ValueTypeI64, // Content type
+			wapi.ValueTypeI64, // Body
+			wapi.ValueTypeI64, // Dest buffer
+			wapi.ValueTypeI32, // Status code pointer
+		},
+		[]wapi.ValueType{wapi.ValueTypeI32}, // N bytes or Error
+	)
+}
+
+// httpGet sends a GET request and returns the body of the response.
+// Params:
+// - stack[0]: URL
+// - stack[1]: Destination buffer for the body of the response
+// - stack[2]: Pointer where the status code of the response is written
+// Return value:
+// - The number of bytes of the body or -1 in case of error
+func (i *wasmOperatorInstance) httpGet(ctx context.Context, m wapi.Module, stack []uint64) {
+	urlPtr := stack[0]
+	dst := stack[1]
+	statusPtr := wapi.DecodeU32(stack[2])
+
+	rawURL, err := stringFromStack(m, urlPtr)
+	if err != nil {
+		i.logger.Warnf("httpGet: reading string from stack: %v", err)
+		stack[0] = wapi.EncodeI32(-1)
+		return
+	}
+
+	n, err := i.doHTTPRequest(ctx, m, http.MethodGet, rawURL, "", nil, dst, statusPtr)
+	if err != nil {
+		i.logger.Warnf("httpGet: %v", err)
+		stack[0] = wapi.EncodeI32(-1)
+		return
+	}
+	stack[0] = wapi.EncodeI32(n)
+}
+
+// httpPost sends a POST request and returns the body of the response.
+// Params:
+// - stack[0]: URL
+// - stack[1]: Content type of the body
+// - stack[2]: Body
+// - stack[3]: Destination buffer for the body of the response
+// - stack[4]: Pointer where the status code of the response is written
+// Return value:
+// - The number of bytes of the body or -1 in case of error
+func (i *wasmOperatorInstance) httpPost(ctx context.Context, m wapi.Module, stack []uint64) {
+	urlPtr := stack[0]
+	contentTypePtr := stack[1]
+	bodyPtr := stack[2]
+	dst := stack[3]
+	statusPtr := wapi.DecodeU32(stack[4])
+
+	rawURL, err := stringFromStack(m, urlPtr)
+	if err != nil {
+		i.logger.Warnf("httpPost: reading string from stack: %v", err)
+		stack[0] = wapi.EncodeI32(-1)
+		return
+	}
+	contentType, err := stringFromStack(m, contentTypePtr)
+	if err != nil {
+		i.logger.Warnf("httpPost: reading string from stack: %v", err)
+		stack[0] = wapi.EncodeI32(-1)
+		return
+	}
+	var body []byte
+	if bodyPtr != 0 {
+		body, err = bufFromStack(m, bodyPtr)
+		if err != nil {
+			i.logger.Warnf("httpPost: reading body from stack: %v", err)
+			stack[0] = wapi.EncodeI32(-1)
+			return
+		}
+	}
+
+	n, err := i.doHTTPRequest(ctx, m, http.MethodPost, rawURL, contentType, body, dst, statusPtr)
+	if err != nil {
+		i.logger.Warnf("httpPost: %v", err)
+		stack[0] = wapi.EncodeI32(-1)
+		return
+	}
+	stack[0] = wapi.EncodeI32(n)
+}
+
+// doHTTPRequest sends a request to an allowed URL and copies the body of the
+// response to dst, which must be big enough to hold it
+func (i *wasmOperatorInstance) doHTTPRequest(
+	ctx context.Context,
+	m wapi.Module,
+	method, rawURL, contentType string,
+	body []byte,
+	dst uint64,
+	statusPtr uint32,
+) (int32, error) {
+	if i.httpClient == nil {
+		return 0, fmt.Errorf("HTTP requests are denied, see the %s param", httpAllowlistParam)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, fmt.Errorf("parsing URL: %w", err)
+	}
+	if !i.httpAllowlist.allows(u) {
+		return 0, fmt.Errorf("URL %q not allowed", u.Redacted())
+	}
+
+	// The body is copied, as the guest memory can't be accessed after
+	// returning, e.g. if a redirect needs to send it again
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(bytes.Clone(body)))
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read one byte more than the buffer to detect bodies not fitting in it
+	size := getLength(dst)
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, int64(size)+1))
+	if err != nil {
+		return 0, fmt.Errorf("reading response: %w", err)
+	}
+	if uint32(len(respBody)) > size {
+		return 0, fmt.Errorf("response body bigger than buffer of %d bytes", size)
+	}
+
+	if err := i.writeToDstBuffer(respBody, dst); err != nil {
+		return 0, err
+	}
+	if statusPtr != 0 && !m.Memory().WriteUint32Le(statusPtr, uint32(resp.StatusCode)) {
+		return 0, errors.New("writing status code to guest memory: out of memory write")
+	}
+	return int32(len(respBody)), nil
+}
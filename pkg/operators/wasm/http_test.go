@@ -0,0 +1,77 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHTTPAllowlist(t *testing.T) {
+	t.Parallel()
+
+	allowlist, err := parseHTTPAllowlist("")
+	require.NoError(t, err)
+	assert.Empty(t, allowlist)
+
+	allowlist, err = parseHTTPAllowlist("https://example.com/api/, http://localhost:8080")
+	require.NoError(t, err)
+	require.Len(t, allowlist, 2)
+	assert.Equal(t, "example.com", allowlist[0].Host)
+	assert.Equal(t, "localhost:8080", allowlist[1].Host)
+
+	for _, invalid := range []string{"ftp://example.com", "example.com/api", "https://", "https://example.com/%zz"} {
+		_, err := parseHTTPAllowlist(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestHTTPAllowlistAllows(t *testing.T) {
+	t.Parallel()
+
+	allowlist, err := parseHTTPAllowlist("https://example.com/api/,http://localhost:8080")
+	require.NoError(t, err)
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/api/", true},
+		{"https://example.com/api", true},
+		{"https://example.com/api/v1/ip?addr=1.2.3.4", true},
+		{"https://EXAMPLE.com/api/v1", true},
+		{"https://example.com/apis", false},
+		{"https://example.com/", false},
+		{"https://example.com/api/../admin", false},
+		{"https://example.com/api/%2e%2e/admin", false},
+		{"https://example.com/api/./v1", false},
+		{"http://example.com/api/v1", false},
+		{"https://example.com:8443/api/v1", false},
+		{"https://example.com.evil.com/api/v1", false},
+		{"http://localhost:8080/anything", true},
+		{"http://localhost/anything", false},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			t.Parallel()
+			u, err := url.Parse(test.url)
+			require.NoError(t, err)
+			assert.Equal(t, test.allowed, allowlist.allows(u))
+		})
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/tetratelabs/wazero"
+	wapi "github.com/tetratelabs/wazero/api"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	// Param added to gadgets using the key-value store
+	kvMaxSizeParam = "kv-max-size"
+
+	defaultKVMaxSize = "16MiB"
+
+	// Directory of the key-value stores, with a file per image
+	kvStoreDir = "/var/lib/ig/wasm-kv"
+)
+
+// kvFuncs are the host functions using the key-value store. The store is only
+// opened for the gadgets importing them.
+var kvFuncs = []string{"kvGet", "kvSet", "kvDelete"}
+
+func kvParams() api.Params {
+	return api.Params{
+		{
+			Key:          kvMaxSizeParam,
+			Title:        "Key-Value Store Maximum Size",
+			Description:  "Maximum size of the keys and values the gadget stores in its persistent key-value store, like 16MiB",
+			DefaultValue: defaultKVMaxSize,
+			TypeHint:     api.TypeSize,
+		},
+	}
+}
+
+// initKV opens the key-value store of the gadget. It's named after a hash of
+// the fully qualified name of the image, so its content survives restarts of
+// the gadget while gadgets of different images, like images with the same
+// name in different registries, don't share it.
+func (i *wasmOperatorInstance) initKV(gadgetCtx operators.GadgetContext) error {
+	maxSize := i.paramValues[kvMaxSizeParam]
+	if maxSize == "" {
+		maxSize = defaultKVMaxSize
+	}
+	size, err := params.ParseSize(maxSize)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", kvMaxSizeParam, err)
+	}
+	i.kvMaxSize = size
+
+	name, err := kvStoreName(gadgetCtx.ImageName())
+	if err != nil {
+		return err
+	}
+
+	store, err := openKVStore(filepath.Join(kvStoreDir, name))
+	if err != nil {
+		return fmt.Errorf("opening key-value store: %w", err)
+	}
+	i.kvStore = store
+	return nil
+}
+
+// kvStoreName returns the name of the file of the key-value store of an image
+func kvStoreName(imageName string) (string, error) {
+	ref, err := oci.NormalizeImageName(imageName)
+	if err != nil {
+		return "", fmt.Errorf("normalizing image name for key-value store: %w", err)
+	}
+	sum := sha256.Sum256([]byte(ref))
+	return hex.EncodeToString(sum[:]) + ".db", nil
+}
+
+func (i *wasmOperatorInstance) addKVFuncs(env wazero.HostModuleBuilder) {
+	exportFunction(env, "kvGet", i.kvGet,
+		[]wapi.ValueType{
+			wapi.ValueTypeI64, // Key
+			wapi.ValueTypeI64, // Dest buffer
+		},
+		[]wapi.ValueType{wapi.ValueTypeI32}, // N bytes or Error
+	)
+
+	exportFunction(env, "kvSet", i.kvSet,
+		[]wapi.ValueType{
+			wapi.ValueTypeI64, // Key
+			wapi.ValueTypeI64, // Value
+		},
+		[]wapi.ValueType{wapi.ValueTypeI32}, // Error
+	)
+
+	exportFunction(env, "kvDelete", i.kvDelete,
+		[]wapi.ValueType{
+			wapi.ValueTypeI64, // Key
+		},
+		[]wapi.ValueType{wapi.ValueTypeI32}, // Error
+	)
+}
+
+// kvGet returns the value of a key of the key-value store.
+// Params:
+// - stack[0]: Key
+// - stack[1]: Destination buffer
+// Return value:
+// - The number of bytes copied, -1 if the key isn't stored or -2 in case of
+// error
+func (i *wasmOperatorInstance) kvGet(ctx context.Context, m wapi.Module, stack []uint64) {
+	keyPtr := stack[0]
+	dst := stack[1]
+
+	if i.kvStore == nil {
+		i.logger.Warnf("kvGet: key-value store not opened")
+		stack[0] = wapi.EncodeI32(-2)
+		return
+	}
+
+	key, err := bufFromStack(m, keyPtr)
+	if err != nil {
+		i.logger.Warnf("kvGet: reading key from stack: %v", err)
+		stack[0] = wapi.EncodeI32(-2)
+		return
+	}
+
+	value, ok, err := i.kvStore.get(key)
+	if err != nil {
+		i.logger.Warnf("kvGet: %v", err)
+		stack[0] = wapi.EncodeI32(-2)
+		return
+	}
+	if !ok {
+		stack[0] = wapi.EncodeI32(-1)
+		return
+	}
+
+	if err := i.writeToDstBuffer(value, dst); err != nil {
+		i.logger.Warnf("kvGet: %v", err)
+		stack[0] = wapi.EncodeI32(-2)
+		return
+	}
+	stack[0] = wapi.EncodeI32(int32(len(value)))
+}
+
+// kvSet stores the value of a key in the key-value store.
+// Params:
+// - stack[0]: Key
+// - stack[1]: Value
+// Return value:
+// - 0 on success, 1 on error
+func (i *wasmOperatorInstance) kvSet(ctx context.Context, m wapi.Module, stack []uint64) {
+	keyPtr := stack[0]
+	valuePtr := stack[1]
+
+	if i.kvStore == nil {
+		i.logger.Warnf("kvSet: key-value store not opened")
+		stack[0] = 1
+		return
+	}
+
+	key, err := bufFromStack(m, keyPtr)
+	if err != nil {
+		i.logger.Warnf("kvSet: reading key from stack: %v", err)
+		stack[0] = 1
+		return
+	}
+	var value []byte
+	if valuePtr != 0 {
+		value, err = bufFromStack(m, valuePtr)
+		if err != nil {
+			i.logger.Warnf("kvSet: reading value from stack: %v", err)
+			stack[0] = 1
+			return
+		}
+	}
+
+	if err := i.kvStore.set(key, value, i.kvMaxSize); err != nil {
+		if errors.Is(err, errKVStoreFull) {
+			err = fmt.Errorf("%w, see the %s param", err, kvMaxSizeParam)
+		}
+		i.logger.Warnf("kvSet: %v", err)
+		stack[0] = 1
+		return
+	}
+	stack[0] = 0
+}
+
+// kvDelete removes a key from the key-value store. It doesn't fail if the key
+// isn't stored.
+// Params:
+// - stack[0]: Key
+// Return value:
+// - 0 on success, 1 on error
+func (i *wasmOperatorInstance) kvDelete(ctx context.Context, m wapi.Module, stack []uint64) {
+	keyPtr := stack[0]
+
+	if i.kvStore == nil {
+		i.logger.Warnf("kvDelete: key-value store not opened")
+		stack[0] = 1
+		return
+	}
+
+	key, err := bufFromStack(m, keyPtr)
+	if err != nil {
+		i.logger.Warnf("kvDelete: reading key from stack: %v", err)
+		stack[0] = 1
+		return
+	}
+
+	if err := i.kvStore.delete(key); err != nil {
+		i.logger.Warnf("kvDelete: %v", err)
+		stack[0] = 1
+		return
+	}
+	stack[0] = 0
+}
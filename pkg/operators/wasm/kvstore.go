@@ -0,0 +1,170 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var kvBucket = []byte("kv")
+
+var errKVStoreFull = errors.New("key-value store full")
+
+// kvStore is a persistent key-value store backed by a bolt file. It's shared by
+// all the instances of a gadget, as bolt files can only be opened once.
+type kvStore struct {
+	path string
+	db   *bolt.DB
+	refs int
+
+	// size is the size of the keys and the values stored
+	mu   sync.Mutex
+	size uint64
+}
+
+var (
+	kvStoresLock sync.Mutex
+	kvStores     = map[string]*kvStore{}
+)
+
+// openKVStore opens the store of a path, or returns it if it's already opened
+func openKVStore(path string) (*kvStore, error) {
+	kvStoresLock.Lock()
+	defer kvStoresLock.Unlock()
+
+	if s, ok := kvStores[path]; ok {
+		s.refs++
+		return s, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating directory: %w", err)
+	}
+	// The timeout avoids blocking forever if another process opened it
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening %q: %w", path, err)
+	}
+
+	s := &kvStore{path: path, db: db, refs: 1}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(kvBucket)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			s.size += uint64(len(k) + len(v))
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing %q: %w", path, err)
+	}
+
+	kvStores[path] = s
+	return s, nil
+}
+
+// close closes the store once all the instances using it closed it
+func (s *kvStore) close() error {
+	kvStoresLock.Lock()
+	defer kvStoresLock.Unlock()
+
+	s.refs--
+	if s.refs > 0 {
+		return nil
+	}
+	delete(kvStores, s.path)
+	return s.db.Close()
+}
+
+// get returns a copy of the value of a key, or false if it isn't stored
+func (s *kvStore) get(key []byte) ([]byte, bool, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// Values are only valid during the transaction
+		if v := tx.Bucket(kvBucket).Get(key); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return value, value != nil, nil
+}
+
+// set stores the value of a key, as long as the size of all the keys and
+// values stored doesn't exceed maxSize
+func (s *kvStore) set(key, value []byte, maxSize uint64) error {
+	if len(key) == 0 {
+		return errors.New("empty key")
+	}
+	if len(key) > bolt.MaxKeySize {
+		return fmt.Errorf("key bigger than %d bytes", bolt.MaxKeySize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The size is only updated once the transaction is committed
+	size := s.size
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(kvBucket)
+		size = s.size + uint64(len(key)+len(value))
+		if old := b.Get(key); old != nil {
+			size -= uint64(len(key) + len(old))
+		}
+		if size > maxSize {
+			return errKVStoreFull
+		}
+		return b.Put(key, value)
+	})
+	if err != nil {
+		return err
+	}
+	s.size = size
+	return nil
+}
+
+// delete removes a key, it doesn't fail if it isn't stored
+func (s *kvStore) delete(key []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(kvBucket)
+		old := b.Get(key)
+		if old == nil {
+			return nil
+		}
+		deleted = uint64(len(key) + len(old))
+		return b.Delete(key)
+	})
+	if err != nil {
+		return err
+	}
+	s.size -= deleted
+	return nil
+}
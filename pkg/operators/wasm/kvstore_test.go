@@ -0,0 +1,111 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sub", "gadget.db")
+	s, err := openKVStore(path)
+	require.NoError(t, err)
+
+	_, ok, err := s.get([]byte("foo"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, s.set([]byte("foo"), []byte("bar"), 100))
+	value, ok, err := s.get([]byte("foo"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("bar"), value)
+
+	// Empty values are stored too
+	require.NoError(t, s.set([]byte("empty"), nil, 100))
+	value, ok, err = s.get([]byte("empty"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, value)
+
+	require.Error(t, s.set(nil, []byte("bar"), 100))
+
+	require.NoError(t, s.delete([]byte("empty")))
+	require.NoError(t, s.delete([]byte("missing")))
+	_, ok, err = s.get([]byte("empty"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Stores are shared
+	s2, err := openKVStore(path)
+	require.NoError(t, err)
+	assert.Same(t, s, s2)
+	require.NoError(t, s2.close())
+
+	require.NoError(t, s.close())
+
+	// Values survive reopening the store
+	s, err = openKVStore(path)
+	require.NoError(t, err)
+	defer s.close()
+	value, ok, err = s.get([]byte("foo"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("bar"), value)
+	assert.Equal(t, uint64(len("foo")+len("bar")), s.size)
+}
+
+func TestKVStoreMaxSize(t *testing.T) {
+	t.Parallel()
+
+	s, err := openKVStore(filepath.Join(t.TempDir(), "gadget.db"))
+	require.NoError(t, err)
+	defer s.close()
+
+	require.NoError(t, s.set([]byte("k1"), []byte("12345678"), 20))
+	require.ErrorIs(t, s.set([]byte("k2"), []byte("123456789"), 20), errKVStoreFull)
+
+	// Replacing a value only accounts for the difference
+	require.NoError(t, s.set([]byte("k1"), []byte("123456789012345678"), 20))
+	require.ErrorIs(t, s.set([]byte("k1"), []byte("1234567890123456789"), 20), errKVStoreFull)
+
+	// Deleting values frees space
+	require.NoError(t, s.delete([]byte("k1")))
+	require.NoError(t, s.set([]byte("k2"), []byte("123456789"), 20))
+	assert.Equal(t, uint64(11), s.size)
+}
+
+func TestKVStoreName(t *testing.T) {
+	t.Parallel()
+
+	short, err := kvStoreName("trace_exec")
+	require.NoError(t, err)
+	full, err := kvStoreName("ghcr.io/inspektor-gadget/gadget/trace_exec:latest")
+	require.NoError(t, err)
+	assert.Equal(t, short, full)
+
+	other, err := kvStoreName("example.com/gadget/trace_exec:latest")
+	require.NoError(t, err)
+	assert.NotEqual(t, full, other)
+
+	// The name can't be used to escape the directory of the stores
+	assert.Equal(t, full, filepath.Base(full))
+}
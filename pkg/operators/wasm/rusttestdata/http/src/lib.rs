@@ -0,0 +1,77 @@
+use api::{
+    errorf,
+    http::{http_get, http_post},
+    params::get_param_value,
+};
+
+#[no_mangle]
+#[allow(non_snake_case)]
+pub fn gadgetStart() -> i32 {
+    let Ok(server) = get_param_value("server".to_string(), 256) else {
+        errorf!("failed to get param");
+        return 1;
+    };
+
+    match http_get(&format!("{}/allowed/ping", server), 64) {
+        Ok((200, body)) if body == b"pong" => {}
+        Ok((status, body)) => {
+            errorf!(
+                "http_get returned {} {:?}, expected 200 \"pong\"",
+                status,
+                body
+            );
+            return 1;
+        }
+        Err(e) => {
+            errorf!("http_get failed: {}", e);
+            return 1;
+        }
+    }
+
+    match http_post(
+        &format!("{}/allowed/echo", server),
+        "text/plain",
+        b"hello",
+        64,
+    ) {
+        Ok((200, body)) if body == b"hello" => {}
+        Ok((status, body)) => {
+            errorf!(
+                "http_post returned {} {:?}, expected 200 \"hello\"",
+                status,
+                body
+            );
+            return 1;
+        }
+        Err(e) => {
+            errorf!("http_post failed: {}", e);
+            return 1;
+        }
+    }
+
+    // Status codes of errors are returned too
+    match http_get(&format!("{}/allowed/missing", server), 64) {
+        Ok((404, _)) => {}
+        _ => {
+            errorf!("http_get didn't return 404");
+            return 1;
+        }
+    }
+
+    if http_get(&format!("{}/denied", server), 64).is_ok() {
+        errorf!("http_get to a URL out of the allowlist succeeded");
+        return 1;
+    }
+
+    if http_get(&format!("{}/allowed/redirect", server), 64).is_ok() {
+        errorf!("http_get redirected to a URL out of the allowlist succeeded");
+        return 1;
+    }
+
+    if http_get(&format!("{}/allowed/ping", server), 2).is_ok() {
+        errorf!("http_get with a response bigger than the buffer succeeded");
+        return 1;
+    }
+
+    0
+}
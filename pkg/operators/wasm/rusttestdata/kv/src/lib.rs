@@ -0,0 +1,62 @@
+use api::{
+    errorf,
+    kv::{kv_delete, kv_get, kv_set},
+    params::get_param_value,
+};
+
+#[no_mangle]
+#[allow(non_snake_case)]
+pub fn gadgetStart() -> i32 {
+    let Ok(key) = get_param_value("key".to_string(), 256) else {
+        errorf!("failed to get param");
+        return 1;
+    };
+    let Ok(expected) = get_param_value("expected".to_string(), 32) else {
+        errorf!("failed to get param");
+        return 1;
+    };
+
+    // The counter is stored by the previous runs of the gadget
+    let counter = match kv_get(&key, 32) {
+        Ok(None) => 0,
+        Ok(Some(val)) => match String::from_utf8_lossy(&val).parse::<u32>() {
+            Ok(counter) => counter,
+            Err(e) => {
+                errorf!("invalid counter {:?}: {}", val, e);
+                return 1;
+            }
+        },
+        Err(e) => {
+            errorf!("kv_get failed: {}", e);
+            return 1;
+        }
+    } + 1;
+
+    if counter.to_string() != expected {
+        errorf!("counter should be {}, got {}", expected, counter);
+        return 1;
+    }
+
+    if let Err(e) = kv_set(&key, counter.to_string().as_bytes()) {
+        errorf!("kv_set failed: {}", e);
+        return 1;
+    }
+
+    if kv_set(&format!("{}-big", key), &[0u8; 2048]).is_ok() {
+        errorf!("kv_set exceeding kv-max-size succeeded");
+        return 1;
+    }
+
+    if counter == 2 {
+        if let Err(e) = kv_delete(&key) {
+            errorf!("kv_delete failed: {}", e);
+            return 1;
+        }
+        if !matches!(kv_get(&key, 32), Ok(None)) {
+            errorf!("kv_get of a deleted key didn't fail");
+            return 1;
+        }
+    }
+
+    0
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+use std::{
+    sync::{
+        atomic::{AtomicU32, Ordering},
+        OnceLock,
+    },
+    time::Duration,
+};
+
+use api::{
+    datasources::{Data, DataSource, DataSourceType, Field, FieldKind, Packet},
+    errorf,
+    timer::{new_timer, Timer},
+};
+
+// Keep in sync with testWasmTimer in wasm_test.go
+const TICKS: u32 = 3;
+
+static DS: OnceLock<(DataSource, Field)> = OnceLock::new();
+static COUNT: AtomicU32 = AtomicU32::new(0);
+static TIMER: OnceLock<Timer> = OnceLock::new();
+
+#[no_mangle]
+#[allow(non_snake_case)]
+fn gadgetInit() -> i32 {
+    let Ok(ds) = DataSource::new_datasource("timer".to_string(), DataSourceType::Single) else {
+        errorf!("failed to create datasource");
+        return 1;
+    };
+    let count_field = match ds.add_field("count", FieldKind::Uint32) {
+        Ok(field) => field,
+        Err(e) => {
+            errorf!("failed to add field: {:?}", e);
+            return 1;
+        }
+    };
+    _ = DS.set((ds, count_field));
+    0
+}
+
+fn tick() {
+    let Some((ds, count_field)) = DS.get() else {
+        return;
+    };
+    let count = COUNT.fetch_add(1, Ordering::SeqCst) + 1;
+
+    let packet = match ds.new_packet_single() {
+        Ok(p) => p,
+        Err(e) => {
+            errorf!("failed to create packet: {:?}", e);
+            return;
+        }
+    };
+    _ = count_field.set_data(Data(packet.0), &count);
+    _ = ds.emit_and_release(Packet(packet.0));
+
+    if count == TICKS {
+        if let Some(timer) = TIMER.get() {
+            if let Err(e) = timer.stop() {
+                errorf!("failed to stop timer: {}", e);
+            }
+        }
+    }
+}
+
+#[no_mangle]
+#[allow(non_snake_case)]
+fn gadgetStart() -> i32 {
+    if new_timer(Duration::from_millis(1), || {}).is_ok() {
+        errorf!("creating timer with a too short interval succeeded");
+        return 1;
+    }
+
+    // A stopped timer never fires
+    let stopped = match new_timer(Duration::from_millis(100), || panic!("stopped timer fired")) {
+        Ok(timer) => timer,
+        Err(e) => {
+            errorf!("failed to create timer: {}", e);
+            return 1;
+        }
+    };
+    if let Err(e) = stopped.stop() {
+        errorf!("failed to stop timer: {}", e);
+        return 1;
+    }
+
+    match new_timer(Duration::from_millis(100), tick) {
+        Ok(timer) => {
+            _ = TIMER.set(timer);
+            0
+        }
+        Err(e) => {
+            errorf!("failed to create timer: {}", e);
+            1
+        }
+    }
+}
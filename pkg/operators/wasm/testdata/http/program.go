@@ -0,0 +1,74 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	api "github.com/inspektor-gadget/inspektor-gadget/wasmapi/go"
+)
+
+//go:wasmexport gadgetStart
+func gadgetStart() int32 {
+	server, err := api.GetParamValue("server", 256)
+	if err != nil {
+		api.Errorf("failed to get param: %v", err)
+		return 1
+	}
+
+	status, body, err := api.HTTPGet(server+"/allowed/ping", 64)
+	if err != nil {
+		api.Errorf("HTTPGet failed: %v", err)
+		return 1
+	}
+	if status != 200 || string(body) != "pong" {
+		api.Errorf("HTTPGet returned %d %q, expected 200 \"pong\"", status, body)
+		return 1
+	}
+
+	status, body, err = api.HTTPPost(server+"/allowed/echo", "text/plain", []byte("hello"), 64)
+	if err != nil {
+		api.Errorf("HTTPPost failed: %v", err)
+		return 1
+	}
+	if status != 200 || string(body) != "hello" {
+		api.Errorf("HTTPPost returned %d %q, expected 200 \"hello\"", status, body)
+		return 1
+	}
+
+	// Status codes of errors are returned too
+	status, _, err = api.HTTPGet(server+"/allowed/missing", 64)
+	if err != nil || status != 404 {
+		api.Errorf("HTTPGet returned %d, %v, expected 404", status, err)
+		return 1
+	}
+
+	if _, _, err := api.HTTPGet(server+"/denied", 64); err == nil {
+		api.Errorf("HTTPGet to a URL out of the allowlist succeeded")
+		return 1
+	}
+
+	if _, _, err := api.HTTPGet(server+"/allowed/redirect", 64); err == nil {
+		api.Errorf("HTTPGet redirected to a URL out of the allowlist succeeded")
+		return 1
+	}
+
+	if _, _, err := api.HTTPGet(server+"/allowed/ping", 2); err == nil {
+		api.Errorf("HTTPGet with a response bigger than the buffer succeeded")
+		return 1
+	}
+
+	return 0
+}
+
+func main() {}
@@ -0,0 +1,83 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	api "github.com/inspektor-gadget/inspektor-gadget/wasmapi/go"
+)
+
+//go:wasmexport gadgetStart
+func gadgetStart() int32 {
+	key, err := api.GetParamValue("key", 256)
+	if err != nil {
+		api.Errorf("failed to get param: %v", err)
+		return 1
+	}
+	expected, err := api.GetParamValue("expected", 32)
+	if err != nil {
+		api.Errorf("failed to get param: %v", err)
+		return 1
+	}
+
+	// The counter is stored by the previous runs of the gadget
+	counter := 0
+	val, err := api.KVGet(key, 32)
+	switch {
+	case errors.Is(err, api.ErrKVNotFound):
+	case err != nil:
+		api.Errorf("KVGet failed: %v", err)
+		return 1
+	default:
+		counter, err = strconv.Atoi(string(val))
+		if err != nil {
+			api.Errorf("invalid counter %q: %v", val, err)
+			return 1
+		}
+	}
+	counter++
+
+	if strconv.Itoa(counter) != expected {
+		api.Errorf("counter should be %s, got %d", expected, counter)
+		return 1
+	}
+
+	if err := api.KVSet(key, []byte(strconv.Itoa(counter))); err != nil {
+		api.Errorf("KVSet failed: %v", err)
+		return 1
+	}
+
+	if err := api.KVSet(key+"-big", make([]byte, 2048)); err == nil {
+		api.Errorf("KVSet exceeding kv-max-size succeeded")
+		return 1
+	}
+
+	if counter == 2 {
+		if err := api.KVDelete(key); err != nil {
+			api.Errorf("KVDelete failed: %v", err)
+			return 1
+		}
+		if _, err := api.KVGet(key, 32); !errors.Is(err, api.ErrKVNotFound) {
+			api.Errorf("KVGet of a deleted key returned %v", err)
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func main() {}
@@ -0,0 +1,92 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	api "github.com/inspektor-gadget/inspektor-gadget/wasmapi/go"
+)
+
+// Keep in sync with testWasmTimer in wasm_test.go
+const ticks = 3
+
+var (
+	ds     api.DataSource
+	countF api.Field
+	count  uint32
+	timer  api.Timer
+)
+
+//go:wasmexport gadgetInit
+func gadgetInit() int32 {
+	var err error
+	ds, err = api.NewDataSource("timer", api.DataSourceTypeSingle)
+	if err != nil {
+		api.Errorf("failed to create datasource: %v", err)
+		return 1
+	}
+	countF, err = ds.AddField("count", api.Kind_Uint32)
+	if err != nil {
+		api.Errorf("failed to add field: %v", err)
+		return 1
+	}
+	return 0
+}
+
+//go:wasmexport gadgetStart
+func gadgetStart() int32 {
+	if _, err := api.NewTimer(time.Millisecond, func() {}); err == nil {
+		api.Errorf("creating timer with a too short interval succeeded")
+		return 1
+	}
+
+	// A stopped timer never fires
+	stopped, err := api.NewTimer(100*time.Millisecond, func() {
+		panic("stopped timer fired")
+	})
+	if err != nil {
+		api.Errorf("failed to create timer: %v", err)
+		return 1
+	}
+	if err := stopped.Stop(); err != nil {
+		api.Errorf("failed to stop timer: %v", err)
+		return 1
+	}
+
+	timer, err = api.NewTimer(100*time.Millisecond, func() {
+		count++
+		packet, err := ds.NewPacketSingle()
+		if err != nil {
+			api.Errorf("failed to create packet: %v", err)
+			return
+		}
+		countF.SetUint32(api.Data(packet), count)
+		ds.EmitAndRelease(api.Packet(packet))
+
+		if count == ticks {
+			if err := timer.Stop(); err != nil {
+				api.Errorf("failed to stop timer: %v", err)
+			}
+		}
+	})
+	if err != nil {
+		api.Errorf("failed to create timer: %v", err)
+		return 1
+	}
+	return 0
+}
+
+func main() {}
@@ -0,0 +1,182 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	wapi "github.com/tetratelabs/wazero/api"
+)
+
+// minTimerInterval avoids timers keeping the guest busy
+const minTimerInterval = 10 * time.Millisecond
+
+// timer calls a guest callback periodically. Timers are started with the
+// gadget, or when they're created if it already started.
+type timer struct {
+	interval time.Duration
+	cbID     uint64
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (t *timer) stop() {
+	t.stopOnce.Do(func() { close(t.done) })
+}
+
+func (i *wasmOperatorInstance) addTimerFuncs(env wazero.HostModuleBuilder) {
+	exportFunction(env, "newTimer", i.newTimer,
+		[]wapi.ValueType{
+			wapi.ValueTypeI64, // Interval in nanoseconds
+			wapi.ValueTypeI64, // Callback ID
+		},
+		[]wapi.ValueType{wapi.ValueTypeI32}, // Timer handle
+	)
+
+	exportFunction(env, "timerStop", i.timerStop,
+		[]wapi.ValueType{
+			wapi.ValueTypeI32, // Timer handle
+		},
+		[]wapi.ValueType{wapi.ValueTypeI32}, // Error
+	)
+}
+
+// newTimer creates a timer calling the timerCallback function of the guest
+// with the given callback ID periodically, until it's stopped or the gadget
+// stops.
+// Params:
+// - stack[0]: Interval in nanoseconds
+// - stack[1]: Callback ID
+// Return value:
+// - Timer handle on success, 0 on error
+func (i *wasmOperatorInstance) newTimer(ctx context.Context, m wapi.Module, stack []uint64) {
+	interval := time.Duration(stack[0])
+	cbID := stack[1]
+
+	if i.timerCallback == nil {
+		i.logger.Warnf("wasm module doesn't export timerCallback")
+		stack[0] = 0
+		return
+	}
+	if interval < minTimerInterval {
+		i.logger.Warnf("newTimer: interval %s shorter than %s", interval, minTimerInterval)
+		stack[0] = 0
+		return
+	}
+
+	t := &timer{
+		interval: interval,
+		cbID:     cbID,
+		done:     make(chan struct{}),
+	}
+	handle := i.addHandle(t)
+	if handle == 0 {
+		stack[0] = 0
+		return
+	}
+
+	i.timersLock.Lock()
+	if i.timersStarted {
+		i.startTimer(t)
+	} else {
+		i.pendingTimers = append(i.pendingTimers, t)
+	}
+	i.timersLock.Unlock()
+
+	stack[0] = wapi.EncodeU32(handle)
+}
+
+// timerStop stops a timer and releases its handle.
+// Params:
+// - stack[0]: Timer handle
+// Return value:
+// - 0 on success, 1 on error
+func (i *wasmOperatorInstance) timerStop(ctx context.Context, m wapi.Module, stack []uint64) {
+	handle := wapi.DecodeU32(stack[0])
+
+	t, ok := getHandle[*timer](i, handle)
+	if !ok {
+		stack[0] = 1
+		return
+	}
+	t.stop()
+	i.delHandle(handle)
+
+	stack[0] = 0
+}
+
+// startTimers starts the timers created before the gadget started. timersLock
+// must not be held.
+func (i *wasmOperatorInstance) startTimers() {
+	i.timersLock.Lock()
+	defer i.timersLock.Unlock()
+
+	i.timersStarted = true
+	for _, t := range i.pendingTimers {
+		i.startTimer(t)
+	}
+	i.pendingTimers = nil
+}
+
+// startTimer runs a timer until it's stopped or i.ctx is cancelled. timersLock
+// must be held.
+func (i *wasmOperatorInstance) startTimer(t *timer) {
+	i.timersWg.Add(1)
+	go func() {
+		defer i.timersWg.Done()
+
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+
+		// The guest is closed if the context of a call is cancelled during
+		// it, so don't use i.ctx that is cancelled when the gadget stops
+		callCtx := context.WithoutCancel(i.ctx)
+		for {
+			select {
+			case <-i.ctx.Done():
+				return
+			case <-t.done:
+				return
+			case <-ticker.C:
+				if err := i.callTimerCallbackWithLock(callCtx, t.cbID); err != nil {
+					i.logger.Warnf("calling timer callback: %v", err)
+					return
+				}
+			}
+		}
+	}()
+}
+
+// stopTimers stops all the timers and waits for their callbacks to return
+func (i *wasmOperatorInstance) stopTimers() {
+	i.timersLock.Lock()
+	i.timersStarted = false
+	i.pendingTimers = nil
+	i.timersLock.Unlock()
+
+	// Timers stop once i.ctx is cancelled
+	i.timersWg.Wait()
+}
+
+func (i *wasmOperatorInstance) callTimerCallbackWithLock(ctx context.Context, cbID uint64) error {
+	i.dataSourceCallbackLock.Lock()
+	_, err := i.timerCallback.Call(ctx, cbID)
+	i.dataSourceCallbackLock.Unlock()
+	return err
+}
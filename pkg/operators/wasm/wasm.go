@@ -20,7 +20,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"slices"
 	"sync"
 	"time"
 
@@ -46,9 +48,10 @@ const (
 	// Maximum number of handles a gadget can have opened at the same time
 	maxHandles = 4 * 1024
 
-	// Current version of this API. This is used to check that the wasm module
-	// uses the same version.
-	apiVersion = 1
+	// Current version of this API and the oldest one still supported. They're
+	// used to check the version of the API the wasm module uses, see abi.go.
+	apiVersion    = 1
+	minAPIVersion = 1
 
 	// Indicates the handle encodes a member of a data array as index << 16 | arrayHandle
 	dataArrayHandleFlag = uint32(1 << 31)
@@ -131,10 +134,18 @@ type wasmOperatorInstance struct {
 
 	logger logger.Logger
 
-	// This mutex ensures dataSourceCallback() is never called in parallel, see:
+	// This mutex ensures dataSourceCallback() and timerCallback() are never
+	// called in parallel, see:
 	// https://github.com/tetratelabs/wazero/blob/610c202ec48f3a7c729f2bf11707330127ab3689/api/wasm.go#L378-L381
 	dataSourceCallbackLock sync.Mutex
 	dataSourceCallback     wapi.Function
+	timerCallback          wapi.Function
+
+	// Timers created before the gadget starts are pending until it does
+	timersLock    sync.Mutex
+	timersStarted bool
+	pendingTimers []*timer
+	timersWg      sync.WaitGroup
 
 	// Golang objects are exposed to the wasm module by using a handleID
 	handleMap       map[uint32]any
@@ -152,6 +163,14 @@ type wasmOperatorInstance struct {
 	syscallsDeclarations map[string]syscalls.SyscallDeclaration
 
 	mntNsIDMap *ebpf.Map
+
+	// httpClient is nil if the gadget isn't allowed to send HTTP requests
+	httpClient    *http.Client
+	httpAllowlist httpAllowlist
+
+	// kvStore is nil if the gadget doesn't use the key-value store
+	kvStore   *kvStore
+	kvMaxSize uint64
 }
 
 func (i *wasmOperatorInstance) Name() string {
@@ -224,19 +243,9 @@ func (i *wasmOperatorInstance) delHandle(handleID uint32) {
 	delete(i.handleMap, handleID)
 }
 
-func (i *wasmOperatorInstance) init(
-	gadgetCtx operators.GadgetContext,
-	target oras.ReadOnlyTarget,
-	desc ocispec.Descriptor,
-	cache wazero.CompilationCache,
-) error {
-	ctx := gadgetCtx.Context()
-	rtConfig := wazero.NewRuntimeConfig().
-		WithCloseOnContextDone(true).
-		WithMemoryLimitPages(256). // 16MB (64KB per page)
-		WithCompilationCache(cache)
-	i.rt = wazero.NewRuntimeWithConfig(ctx, rtConfig)
-
+// newHostModuleBuilder returns a builder of the "ig" module with the host
+// functions wasm modules can import
+func (i *wasmOperatorInstance) newHostModuleBuilder() wazero.HostModuleBuilder {
 	igModuleBuilder := i.rt.NewHostModuleBuilder("ig")
 
 	i.addLogFuncs(igModuleBuilder)
@@ -250,8 +259,31 @@ func (i *wasmOperatorInstance) init(
 	i.addPerfFuncs(igModuleBuilder)
 	i.addKallsymsFuncs(igModuleBuilder)
 	i.addFilterFuncs(igModuleBuilder)
+	i.addHTTPFuncs(igModuleBuilder)
+	i.addKVFuncs(igModuleBuilder)
+	i.addTimerFuncs(igModuleBuilder)
+
+	return igModuleBuilder
+}
+
+func (i *wasmOperatorInstance) init(
+	gadgetCtx operators.GadgetContext,
+	target oras.ReadOnlyTarget,
+	desc ocispec.Descriptor,
+	cache wazero.CompilationCache,
+) error {
+	ctx := gadgetCtx.Context()
+	rtConfig := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(256). // 16MB (64KB per page)
+		WithCompilationCache(cache)
+	i.rt = wazero.NewRuntimeWithConfig(ctx, rtConfig)
 
-	if _, err := igModuleBuilder.Instantiate(ctx); err != nil {
+	hostModule, err := i.newHostModuleBuilder().Compile(ctx)
+	if err != nil {
+		return fmt.Errorf("compiling host module: %w", err)
+	}
+	if _, err := i.rt.InstantiateModule(ctx, hostModule, wazero.NewModuleConfig()); err != nil {
 		return fmt.Errorf("instantiating host module: %w", err)
 	}
 
@@ -270,29 +302,41 @@ func (i *wasmOperatorInstance) init(
 		return fmt.Errorf("reading wasm program: %w", err)
 	}
 
-	config := wazero.NewModuleConfig().WithStartFunctions("_initialize")
-	mod, err := i.rt.InstantiateWithConfig(ctx, wasmProgram, config)
+	compiled, err := i.rt.CompileModule(ctx, wasmProgram)
 	if err != nil {
-		return fmt.Errorf("instantiating wasm: %w", err)
+		return fmt.Errorf("compiling wasm: %w", err)
+	}
+	if err := checkGuestABI(hostModule, compiled); err != nil {
+		return err
 	}
-	i.mod = mod
 
-	versionF := mod.ExportedFunction("gadgetAPIVersion")
-	if versionF == nil {
-		return errors.New("wasm module doesn't export gadgetAPIVersion")
+	if importsAny(compiled, httpFuncs) {
+		i.extraParams = append(i.extraParams, httpParams()...)
+		if err := i.initHTTP(); err != nil {
+			return err
+		}
+	}
+	if importsAny(compiled, kvFuncs) {
+		i.extraParams = append(i.extraParams, kvParams()...)
+		if err := i.initKV(gadgetCtx); err != nil {
+			return err
+		}
 	}
 
-	ret, err := versionF.Call(ctx)
+	config := wazero.NewModuleConfig().WithStartFunctions("_initialize")
+	mod, err := i.rt.InstantiateModule(ctx, compiled, config)
 	if err != nil {
-		return fmt.Errorf("calling version: %w", err)
+		return fmt.Errorf("instantiating wasm: %w", err)
 	}
+	i.mod = mod
 
-	if len(ret) != 1 {
-		return errors.New("version returned wrong number of values")
+	// checkGuestABI() already checked its signature
+	ret, err := mod.ExportedFunction("gadgetAPIVersion").Call(ctx)
+	if err != nil {
+		return fmt.Errorf("calling version: %w", err)
 	}
-
-	if ret[0] != apiVersion {
-		return fmt.Errorf("unsupported gadget API version: %d, expected: %d", ret[0], apiVersion)
+	if err := checkAPIVersion(ret[0]); err != nil {
+		return err
 	}
 
 	// add extra info to gadgetcontext if requested
@@ -304,6 +348,7 @@ func (i *wasmOperatorInstance) init(
 	}
 
 	i.dataSourceCallback = mod.ExportedFunction("dataSourceCallback")
+	i.timerCallback = mod.ExportedFunction("timerCallback")
 
 	if err := i.callGuestFunction(gadgetCtx.Context(), "gadgetInit"); err != nil {
 		return fmt.Errorf("initializing wasm guest: %w", err)
@@ -312,6 +357,18 @@ func (i *wasmOperatorInstance) init(
 	return err
 }
 
+// importsAny returns whether a module imports any of the given functions of
+// the ig module
+func importsAny(compiled wazero.CompiledModule, names []string) bool {
+	for _, fn := range compiled.ImportedFunctions() {
+		moduleName, name, _ := fn.Import()
+		if moduleName == "ig" && slices.Contains(names, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func (i *wasmOperatorInstance) callGuestFunction(ctx context.Context, name string) error {
 	fn := i.mod.ExportedFunction(name)
 	if fn == nil {
@@ -344,11 +401,17 @@ func (i *wasmOperatorInstance) Start(gadgetCtx operators.GadgetContext) error {
 		i.mntNsIDMap, _ = mntnsVar.(*ebpf.Map)
 	}
 
-	return i.callGuestFunction(i.ctx, "gadgetStart")
+	if err := i.callGuestFunction(i.ctx, "gadgetStart"); err != nil {
+		return err
+	}
+	i.startTimers()
+	return nil
 }
 
 func (i *wasmOperatorInstance) Stop(gadgetCtx operators.GadgetContext) error {
 	i.cancel()
+	// gadgetStop can't be called in parallel with timer callbacks
+	i.stopTimers()
 	defer func() {
 		i.handleLock.Lock()
 		i.handleMap = nil
@@ -379,6 +442,10 @@ func (i *wasmOperatorInstance) Close(gadgetCtx operators.GadgetContext) error {
 	if i.mod != nil {
 		errs = append(errs, i.mod.Close(gadgetCtx.Context()))
 	}
+	if i.kvStore != nil {
+		errs = append(errs, i.kvStore.close())
+		i.kvStore = nil
+	}
 
 	return errors.Join(errs...)
 }
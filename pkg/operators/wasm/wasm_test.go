@@ -17,6 +17,9 @@ package wasm_test
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -524,3 +527,108 @@ func testFiltering(t *testing.T, path string) {
 	err := runGadget(t, gadgetCtx, nil)
 	require.NoError(t, err, "running gadget")
 }
+
+func TestWasmHTTP(t *testing.T) {
+	runTestForLanguages(t, testWasmHTTP)
+}
+
+func testWasmHTTP(t *testing.T, path string) {
+	utils.RequireRoot(t)
+
+	t.Parallel()
+
+	// Keep in sync with testdata/http/program.go
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /allowed/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	})
+	mux.HandleFunc("POST /allowed/echo", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	})
+	mux.HandleFunc("GET /allowed/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/denied", http.StatusFound)
+	})
+	mux.HandleFunc("GET /denied", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("denied"))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	gadgetCtx := createGadgetCtx(t, path, "http")
+	params := map[string]string{
+		"operator.oci.wasm.server":         srv.URL,
+		"operator.oci.wasm.http-allowlist": srv.URL + "/allowed/",
+		"operator.oci.wasm.http-timeout":   "2s",
+	}
+
+	err := runGadget(t, gadgetCtx, params)
+	require.NoError(t, err, "running gadget")
+}
+
+func TestWasmKV(t *testing.T) {
+	runTestForLanguages(t, testWasmKV)
+}
+
+func testWasmKV(t *testing.T, path string) {
+	utils.RequireRoot(t)
+
+	t.Parallel()
+
+	// Use a key of its own, as the store persists across runs. Keep in sync
+	// with testdata/kv/program.go
+	key := fmt.Sprintf("counter-%s-%d", path, time.Now().UnixNano())
+
+	for _, expected := range []string{"1", "2"} {
+		gadgetCtx := createGadgetCtx(t, path, "kv")
+		params := map[string]string{
+			"operator.oci.wasm.key":         key,
+			"operator.oci.wasm.expected":    expected,
+			"operator.oci.wasm.kv-max-size": "1024",
+		}
+
+		err := runGadget(t, gadgetCtx, params)
+		require.NoError(t, err, "running gadget")
+	}
+}
+
+func TestWasmTimer(t *testing.T) {
+	runTestForLanguages(t, testWasmTimer)
+}
+
+func testWasmTimer(t *testing.T, path string) {
+	utils.RequireRoot(t)
+
+	t.Parallel()
+
+	var counts []uint32
+
+	const opPriority = 50000
+	myOperator := simple.New("myHandler",
+		simple.OnInit(func(gadgetCtx operators.GadgetContext) error {
+			ds, ok := gadgetCtx.GetDataSources()["timer"]
+			require.True(t, ok, "datasource not found")
+
+			acc := ds.GetField("count")
+			ds.Subscribe(func(source datasource.DataSource, data datasource.Data) error {
+				val, err := acc.Uint32(data)
+				require.NoError(t, err)
+				counts = append(counts, val)
+				return nil
+			}, opPriority)
+			return nil
+		}),
+		simple.OnStart(func(gadgetCtx operators.GadgetContext) error {
+			// Leave time for the timer to fire more than it should
+			time.AfterFunc(time.Second, gadgetCtx.Cancel)
+			return nil
+		}),
+	)
+
+	gadgetCtx := createGadgetCtx(t, path, "timer", myOperator)
+
+	err := runGadget(t, gadgetCtx, nil)
+	require.NoError(t, err, "running gadget")
+
+	// Keep in sync with testdata/timer/program.go
+	require.Equal(t, []uint32{1, 2, 3}, counts)
+}
@@ -0,0 +1,279 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package overhead measures the resources used by Inspektor Gadget itself on
+// the current node: the CPU and memory of its process, the run time of the
+// eBPF programs and the memory of the eBPF maps it holds and the rate of
+// events of its gadget instances.
+package overhead
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/bpfstats"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+const DefaultInterval = time.Second
+
+var (
+	fdInfoPath = "/proc/self/fdinfo"
+	statmPath  = "/proc/self/statm"
+)
+
+// InstanceEvents is the number of events emitted by a gadget instance since
+// it started
+type InstanceEvents struct {
+	ID        string
+	Name      string
+	ImageName string
+	Events    uint64
+}
+
+type sample struct {
+	at         time.Time
+	cpuTime    time.Duration
+	bpfRunTime time.Duration
+	instances  map[string]InstanceEvents
+}
+
+// bpfObjects holds the eBPF programs and maps referenced by the file
+// descriptors of the process
+type bpfObjects struct {
+	progRunTime  map[uint32]uint64
+	mapsMemlock  map[uint32]uint64
+	hasRunTimeNs bool
+}
+
+// fdInfo holds the fields of /proc/<pid>/fdinfo/<fd> relevant for eBPF objects
+type fdInfo struct {
+	progID    uint32
+	mapID     uint32
+	memlock   uint64
+	runTimeNs uint64
+	// hasRunTimeNs is false if bpf stats are not enabled
+	hasRunTimeNs bool
+}
+
+// Collect measures the overhead over the given interval. instances is called
+// at the beginning and at the end of the interval to compute the event rates.
+func Collect(ctx context.Context, interval time.Duration, instances func() []InstanceEvents) (*api.NodeOverhead, error) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	// The run time of the programs is only accounted while stats are enabled
+	statsEnabled := true
+	if err := bpfstats.EnableBPFStats(); err != nil {
+		log.Debugf("enabling bpf stats: %v", err)
+		statsEnabled = false
+	} else {
+		defer bpfstats.DisableBPFStats()
+	}
+
+	start, _, err := takeSample(instances)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(interval):
+	}
+
+	end, objs, err := takeSample(instances)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &api.NodeOverhead{
+		BpfProgramCount: uint32(len(objs.progRunTime)),
+		BpfMapCount:     uint32(len(objs.mapsMemlock)),
+	}
+	for _, memlock := range objs.mapsMemlock {
+		res.BpfMapMemoryBytes += memlock
+	}
+
+	elapsed := end.at.Sub(start.at)
+	res.CpuPercent = percent(end.cpuTime-start.cpuTime, elapsed)
+	if statsEnabled && objs.hasRunTimeNs {
+		res.BpfRunTimePercent = percent(end.bpfRunTime-start.bpfRunTime, elapsed)
+	}
+
+	if mem, err := residentMemory(); err != nil {
+		log.Debugf("getting resident memory: %v", err)
+	} else {
+		res.MemoryBytes = mem
+	}
+
+	for id, inst := range end.instances {
+		var rate float64
+		// Instances created during the interval don't have a rate yet
+		if prev, ok := start.instances[id]; ok && inst.Events >= prev.Events {
+			rate = float64(inst.Events-prev.Events) / elapsed.Seconds()
+		}
+		res.Instances = append(res.Instances, &api.InstanceOverhead{
+			Id:              inst.ID,
+			Name:            inst.Name,
+			ImageName:       inst.ImageName,
+			EventsPerSecond: rate,
+		})
+	}
+
+	return res, nil
+}
+
+// BPFMaps returns the number of eBPF maps the process holds and the memory
+// they use
+func BPFMaps() (int, uint64, error) {
+	objs, err := getBPFObjects()
+	if err != nil {
+		return 0, 0, fmt.Errorf("getting eBPF objects: %w", err)
+	}
+	var memory uint64
+	for _, memlock := range objs.mapsMemlock {
+		memory += memlock
+	}
+	return len(objs.mapsMemlock), memory, nil
+}
+
+func takeSample(instances func() []InstanceEvents) (*sample, *bpfObjects, error) {
+	s := &sample{
+		at:        time.Now(),
+		instances: make(map[string]InstanceEvents),
+	}
+
+	var rusage unix.Rusage
+	if err := unix.Getrusage(unix.RUSAGE_SELF, &rusage); err != nil {
+		return nil, nil, fmt.Errorf("getting resource usage: %w", err)
+	}
+	s.cpuTime = time.Duration(rusage.Utime.Nano() + rusage.Stime.Nano())
+
+	objs, err := getBPFObjects()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting eBPF objects: %w", err)
+	}
+	for _, runTime := range objs.progRunTime {
+		s.bpfRunTime += time.Duration(runTime)
+	}
+
+	if instances != nil {
+		for _, inst := range instances() {
+			s.instances[inst.ID] = inst
+		}
+	}
+	return s, objs, nil
+}
+
+func percent(d, elapsed time.Duration) float64 {
+	if elapsed <= 0 || d < 0 {
+		return 0
+	}
+	return float64(d) / float64(elapsed) * 100
+}
+
+// getBPFObjects returns the eBPF programs and maps the process holds a file
+// descriptor of. Programs and maps referenced by several descriptors are
+// counted once.
+func getBPFObjects() (*bpfObjects, error) {
+	entries, err := os.ReadDir(fdInfoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := &bpfObjects{
+		progRunTime: make(map[uint32]uint64),
+		mapsMemlock: make(map[uint32]uint64),
+	}
+	for _, entry := range entries {
+		f, err := os.Open(filepath.Join(fdInfoPath, entry.Name()))
+		if err != nil {
+			// The file descriptor could have been closed in the meantime
+			continue
+		}
+		info := parseFdInfo(f)
+		f.Close()
+
+		switch {
+		case info.progID != 0:
+			objs.progRunTime[info.progID] = info.runTimeNs
+			objs.hasRunTimeNs = objs.hasRunTimeNs || info.hasRunTimeNs
+		case info.mapID != 0:
+			objs.mapsMemlock[info.mapID] = info.memlock
+		}
+	}
+	return objs, nil
+}
+
+func parseFdInfo(r io.Reader) fdInfo {
+	var info fdInfo
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "prog_id":
+			info.progID = uint32(n)
+		case "map_id":
+			info.mapID = uint32(n)
+		case "memlock":
+			info.memlock = n
+		case "run_time_ns":
+			info.runTimeNs = n
+			info.hasRunTimeNs = true
+		}
+	}
+	return info
+}
+
+// residentMemory returns the resident set size of the process
+func residentMemory() (uint64, error) {
+	content, err := os.ReadFile(statmPath)
+	if err != nil {
+		return 0, err
+	}
+	return parseStatm(string(content), uint64(os.Getpagesize()))
+}
+
+// parseStatm parses /proc/<pid>/statm, whose second field is the number of
+// resident pages
+func parseStatm(content string, pageSize uint64) (uint64, error) {
+	fields := strings.Fields(content)
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected statm content %q", content)
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing resident pages: %w", err)
+	}
+	return pages * pageSize, nil
+}
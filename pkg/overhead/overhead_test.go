@@ -0,0 +1,106 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package overhead
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFdInfo(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		content  string
+		expected fdInfo
+	}
+
+	tests := map[string]testCase{
+		"regular_file": {
+			content:  "pos:\t0\nflags:\t02000002\nmnt_id:\t26\nino:\t1234\n",
+			expected: fdInfo{},
+		},
+		"map": {
+			content:  "pos:\t0\nflags:\t02000002\nmap_type:\t1\nkey_size:\t4\nvalue_size:\t8\nmax_entries:\t1024\nmemlock:\t86016\nmap_id:\t42\n",
+			expected: fdInfo{mapID: 42, memlock: 86016},
+		},
+		"program_without_stats": {
+			content:  "pos:\t0\nflags:\t02000002\nprog_type:\t2\nprog_jited:\t1\nprog_tag:\t3b185187f1855c4c\nmemlock:\t4096\nprog_id:\t7\n",
+			expected: fdInfo{progID: 7, memlock: 4096},
+		},
+		"program_with_stats": {
+			content:  "prog_type:\t2\nmemlock:\t4096\nprog_id:\t7\nrun_time_ns:\t123456\nrun_cnt:\t10\n",
+			expected: fdInfo{progID: 7, memlock: 4096, runTimeNs: 123456, hasRunTimeNs: true},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, test.expected, parseFdInfo(strings.NewReader(test.content)))
+		})
+	}
+}
+
+func TestBPFMaps(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"3": "map_type:\t1\nmemlock:\t86016\nmap_id:\t42\n",
+		// Another descriptor of the same map
+		"4": "map_type:\t1\nmemlock:\t86016\nmap_id:\t42\n",
+		"5": "map_type:\t2\nmemlock:\t4096\nmap_id:\t43\n",
+		"6": "prog_type:\t2\nmemlock:\t4096\nprog_id:\t7\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	oldPath := fdInfoPath
+	fdInfoPath = dir
+	t.Cleanup(func() { fdInfoPath = oldPath })
+
+	maps, memory, err := BPFMaps()
+	require.NoError(t, err)
+	require.Equal(t, 2, maps)
+	require.Equal(t, uint64(86016+4096), memory)
+}
+
+func TestParseStatm(t *testing.T) {
+	t.Parallel()
+
+	mem, err := parseStatm("340251 12345 5678 3012 0 50000 0\n", 4096)
+	require.NoError(t, err)
+	require.Equal(t, uint64(12345*4096), mem)
+
+	_, err = parseStatm("", 4096)
+	require.Error(t, err)
+
+	_, err = parseStatm("1 foo", 4096)
+	require.Error(t, err)
+}
+
+func TestPercent(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 50.0, percent(500*time.Millisecond, time.Second))
+	require.Equal(t, 200.0, percent(2*time.Second, time.Second))
+	require.Equal(t, 0.0, percent(time.Second, 0))
+	require.Equal(t, 0.0, percent(-time.Second, time.Second))
+}
@@ -420,6 +420,8 @@ func (p *Param) AsAny() any {
 		return p.AsFloat64()
 	case TypeDuration:
 		return p.AsDuration()
+	case TypeSize:
+		return p.AsSize()
 	case TypeIP:
 		return p.AsIP()
 	default:
@@ -540,11 +542,20 @@ func (p *Param) AsInt64Slice() []int64 {
 	return out
 }
 
+// AsDuration returns the value of the parameter as a duration, see
+// ParseDuration for the accepted formats
 func (p *Param) AsDuration() time.Duration {
-	d, _ := time.ParseDuration(p.value)
+	d, _ := ParseDuration(p.value)
 	return d
 }
 
+// AsSize returns the value of the parameter as a number of bytes, see
+// ParseSize for the accepted formats
+func (p *Param) AsSize() uint64 {
+	s, _ := ParseSize(p.value)
+	return s
+}
+
 func (p *Param) AsIP() net.IP {
 	return net.ParseIP(p.value)
 }
@@ -213,6 +213,34 @@ func TestParamAs(t *testing.T) {
 			expected: time.Duration(30 * time.Minute),
 			getter:   func(p *Param) any { return p.AsDuration() },
 		},
+		{
+			name:     "Duration()_days",
+			value:    "1d12h",
+			typeHint: TypeDuration,
+			expected: time.Duration(36 * time.Hour),
+			getter:   func(p *Param) any { return p.AsDuration() },
+		},
+		{
+			name:     "Duration()_plain_seconds",
+			value:    "30",
+			typeHint: TypeDuration,
+			expected: time.Duration(30 * time.Second),
+			getter:   func(p *Param) any { return p.AsDuration() },
+		},
+		{
+			name:     "Size()_MiB",
+			value:    "64MiB",
+			typeHint: TypeSize,
+			expected: uint64(64 * 1024 * 1024),
+			getter:   func(p *Param) any { return p.AsSize() },
+		},
+		{
+			name:     "Size()_plain_bytes",
+			value:    "512",
+			typeHint: TypeSize,
+			expected: uint64(512),
+			getter:   func(p *Param) any { return p.AsSize() },
+		},
 		{
 			name:     "IPv4",
 			value:    "127.0.0.1",
@@ -0,0 +1,74 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package params
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+const day = 24 * time.Hour
+
+// ParseDuration parses a duration like "500ms", "2h30m" or "1d12h". On top of
+// the units supported by time.ParseDuration, "d" can be used for days. Plain
+// numbers like "30" are interpreted as seconds, as that was the unit of the
+// params that used to take integers.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q: empty value", s)
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+
+	days, rest, ok := strings.Cut(s, "d")
+	if !ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: expected a number followed by a unit like ms, s, m, h or d", s)
+		}
+		return d, nil
+	}
+
+	n, err := strconv.ParseFloat(days, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration %q: expected a number of days before \"d\"", s)
+	}
+	d := time.Duration(n * float64(day))
+	if rest != "" {
+		r, err := time.ParseDuration(rest)
+		if err != nil || r < 0 {
+			return 0, fmt.Errorf("invalid duration %q: expected a number followed by a unit like ms, s, m or h after the days", s)
+		}
+		d += r
+	}
+	return d, nil
+}
+
+// ParseSize parses a size in bytes like "512", "64KiB", "1.5MB" or "2 GiB".
+// Both SI (kB, MB, GB, ...) and IEC (KiB, MiB, GiB, ...) units are accepted;
+// plain numbers are interpreted as bytes.
+func ParseSize(s string) (uint64, error) {
+	size, err := humanize.ParseBytes(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: expected a number optionally followed by a unit like KiB, MiB, MB or GB", s)
+	}
+	return size, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package params
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{name: "milliseconds", value: "500ms", expected: 500 * time.Millisecond},
+		{name: "hours_minutes", value: "2h30m", expected: 150 * time.Minute},
+		{name: "zero", value: "0", expected: 0},
+		{name: "plain_seconds", value: "5", expected: 5 * time.Second},
+		{name: "spaces", value: " 10s ", expected: 10 * time.Second},
+		{name: "days", value: "2d", expected: 48 * time.Hour},
+		{name: "fractional_days", value: "0.5d", expected: 12 * time.Hour},
+		{name: "days_and_hours", value: "1d6h", expected: 30 * time.Hour},
+		{name: "empty", value: "", wantErr: true},
+		{name: "no_number", value: "ms", wantErr: true},
+		{name: "unknown_unit", value: "5w", wantErr: true},
+		{name: "bad_after_days", value: "1d6", wantErr: true},
+		{name: "negative_days", value: "-1d", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := ParseDuration(test.value)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, d)
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected uint64
+		wantErr  bool
+	}{
+		{name: "plain_bytes", value: "4096", expected: 4096},
+		{name: "kib", value: "4KiB", expected: 4 * 1024},
+		{name: "mib", value: "64MiB", expected: 64 * 1024 * 1024},
+		{name: "mb", value: "64MB", expected: 64 * 1000 * 1000},
+		{name: "fractional_gib", value: "1.5GiB", expected: 1536 * 1024 * 1024},
+		{name: "space_before_unit", value: "2 GiB", expected: 2 * 1024 * 1024 * 1024},
+		{name: "empty", value: "", wantErr: true},
+		{name: "negative", value: "-1MiB", wantErr: true},
+		{name: "unknown_unit", value: "1XiB", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := ParseSize(test.value)
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, s)
+		})
+	}
+}
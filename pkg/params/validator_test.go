@@ -502,11 +502,64 @@ func TestValidateDuration(t *testing.T) {
 				value:         "1sad",
 				expectedError: true,
 			},
+			{
+				name:          "days_no_error",
+				value:         "2d",
+				expectedError: false,
+			},
+			{
+				name:          "plain_seconds_no_error",
+				value:         "30",
+				expectedError: false,
+			},
+			{
+				name:          "bad_days",
+				value:         "xd1h",
+				expectedError: true,
+			},
 		},
 		ValidateDuration,
 	)
 }
 
+func TestValidateSize(t *testing.T) {
+	testValidate(t,
+		[]validateTest{
+			{
+				name:          "plain_bytes_no_error",
+				value:         "512",
+				expectedError: false,
+			},
+			{
+				name:          "iec_no_error",
+				value:         "64MiB",
+				expectedError: false,
+			},
+			{
+				name:          "si_no_error",
+				value:         "1.5 GB",
+				expectedError: false,
+			},
+			{
+				name:          "empty_error",
+				value:         "",
+				expectedError: true,
+			},
+			{
+				name:          "negative_error",
+				value:         "-1",
+				expectedError: true,
+			},
+			{
+				name:          "bad_unit",
+				value:         "10XB",
+				expectedError: true,
+			},
+		},
+		ValidateSize,
+	)
+}
+
 func TestValidateIP(t *testing.T) {
 	testValidate(t,
 		[]validateTest{
@@ -19,7 +19,6 @@ import (
 	"net"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type TypeHint string
@@ -42,6 +41,7 @@ const (
 	TypeFloat32     TypeHint = "float32"
 	TypeFloat64     TypeHint = "float64"
 	TypeDuration    TypeHint = "duration"
+	TypeSize        TypeHint = "size"
 	TypeIP          TypeHint = "ip"
 	TypeStringSlice TypeHint = "[]string"
 )
@@ -61,6 +61,7 @@ var typeHintValidators = map[TypeHint]ParamValidator{
 	TypeFloat32:  ValidateFloat(32),
 	TypeFloat64:  ValidateFloat(64),
 	TypeDuration: ValidateDuration,
+	TypeSize:     ValidateSize,
 	TypeIP:       ValidateIP,
 }
 
@@ -148,7 +149,12 @@ func ValidateSlice(validator ParamValidator) func(value string) error {
 }
 
 func ValidateDuration(value string) error {
-	_, err := time.ParseDuration(value)
+	_, err := ParseDuration(value)
+	return err
+}
+
+func ValidateSize(value string) error {
+	_, err := ParseSize(value)
 	return err
 }
 
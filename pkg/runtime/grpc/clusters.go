@@ -0,0 +1,224 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+)
+
+// ClusterField is the field added to the events of all data sources when
+// running gadgets on several clusters
+const ClusterField = "cluster"
+
+// RestConfigLoader returns the REST config to use for the given kubeconfig
+// context
+type RestConfigLoader func(kubeContext string) (*rest.Config, error)
+
+// cluster is a Kubernetes cluster gadgets are run on
+type cluster struct {
+	// name is the kubeconfig context of the cluster; it's empty if only the
+	// cluster of the REST config set with SetRestConfig is used
+	name       string
+	restConfig *rest.Config
+}
+
+// SetRestConfigLoader sets the function used to get the REST configs of the
+// contexts given with --clusters
+func (r *Runtime) SetRestConfigLoader(loader RestConfigLoader) {
+	r.restConfigLoader = loader
+}
+
+// getClusters returns the clusters selected by --clusters, or the one of the
+// REST config if it's not set
+func (r *Runtime) getClusters() ([]cluster, error) {
+	var contexts []string
+	if p := r.globalParams.Get(ParamClusters); p != nil {
+		contexts = p.AsStringSlice()
+	}
+	if len(contexts) == 0 {
+		return []cluster{{restConfig: r.restConfig}}, nil
+	}
+	if r.restConfigLoader == nil {
+		return nil, errors.New("running on several clusters is not supported by this client")
+	}
+
+	r.clustersLock.Lock()
+	defer r.clustersLock.Unlock()
+	if r.restConfigs == nil {
+		r.restConfigs = make(map[string]*rest.Config)
+	}
+	clusters := make([]cluster, 0, len(contexts))
+	for _, name := range contexts {
+		config, ok := r.restConfigs[name]
+		if !ok {
+			var err error
+			config, err = r.restConfigLoader(name)
+			if err != nil {
+				return nil, fmt.Errorf("loading kubeconfig context %q: %w", name, err)
+			}
+			r.restConfigs[name] = config
+		}
+		clusters = append(clusters, cluster{name: name, restConfig: config})
+	}
+	return clusters, nil
+}
+
+// getClusterTargets returns the gadget pods of all clusters; with a single
+// cluster, only the ones running on the given nodes are returned, if any are
+// given. With several clusters, nodes are picked by resolveTargets.
+func (r *Runtime) getClusterTargets(ctx context.Context, nodes []string, gadgetNamespace string) ([]target, error) {
+	clusters, err := r.getClusters()
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters) == 1 && clusters[0].name == "" {
+		return getGadgetPods(ctx, clusters[0].restConfig, nodes, gadgetNamespace)
+	}
+
+	results := make([][]target, len(clusters))
+	errs := make([]error, len(clusters))
+	var wg sync.WaitGroup
+	for i, c := range clusters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pods, err := getGadgetPods(ctx, c.restConfig, nil, gadgetNamespace)
+			if err != nil {
+				errs[i] = fmt.Errorf("cluster %q: %w", c.name, err)
+				return
+			}
+			for j := range pods {
+				pods[j].cluster = c.name
+				pods[j].restConfig = c.restConfig
+			}
+			results[i] = pods
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var all []target
+	for _, pods := range results {
+		all = append(all, pods...)
+	}
+	return all, nil
+}
+
+// clusterRestConfig returns the REST config of a cluster loaded by getClusters
+func (r *Runtime) clusterRestConfig(name string) *rest.Config {
+	r.clustersLock.Lock()
+	defer r.clustersLock.Unlock()
+	return r.restConfigs[name]
+}
+
+// clusterTagger adds the cluster field to the data sources of a gadget and
+// sets it on their events
+type clusterTagger struct {
+	cluster string
+
+	// payloadIndex holds the payload index of the cluster field per data
+	// source ID
+	payloadIndex map[uint32]uint32
+}
+
+func newClusterTagger(cluster string) *clusterTagger {
+	return &clusterTagger{
+		cluster:      cluster,
+		payloadIndex: make(map[uint32]uint32),
+	}
+}
+
+// addFields adds the cluster field to the data sources of gi; the field comes
+// after the payloads sent by the server
+func (c *clusterTagger) addFields(gi *api.GadgetInfo) {
+	for _, ds := range gi.DataSources {
+		idx := uint32(0)
+		for _, f := range ds.Fields {
+			if datasource.FieldFlagEmpty.In(f.Flags) {
+				continue
+			}
+			idx = max(idx, f.PayloadIndex+1)
+		}
+		c.payloadIndex[ds.Id] = idx
+		ds.Fields = append(ds.Fields, &api.Field{
+			Name:         ClusterField,
+			FullName:     ClusterField,
+			Index:        uint32(len(ds.Fields)),
+			PayloadIndex: idx,
+			Kind:         api.Kind_String,
+			Order:        -1,
+			Annotations: map[string]string{
+				metadatav1.DescriptionAnnotation:      "Kubernetes context of the cluster the event comes from",
+				metadatav1.ColumnsWidthAnnotation:     "16",
+				metadatav1.ColumnsEllipsisAnnotation:  string(metadatav1.EllipsisEnd),
+				metadatav1.ColumnsAlignmentAnnotation: string(metadatav1.AlignmentLeft),
+			},
+		})
+	}
+}
+
+// tag sets the cluster field on the events of the packet
+func (c *clusterTagger) tag(dataSourceID uint32, p datasource.Packet) {
+	idx, ok := c.payloadIndex[dataSourceID]
+	if !ok {
+		return
+	}
+	switch raw := p.Raw().(type) {
+	case *api.GadgetData:
+		if raw.Data == nil {
+			raw.Data = &api.DataElement{}
+		}
+		raw.Data.Payload = setPayload(raw.Data.Payload, idx, c.cluster)
+	case *api.GadgetDataArray:
+		for _, el := range raw.DataArray {
+			el.Payload = setPayload(el.Payload, idx, c.cluster)
+		}
+	}
+}
+
+func setPayload(payload [][]byte, idx uint32, value string) [][]byte {
+	for uint32(len(payload)) <= idx {
+		payload = append(payload, nil)
+	}
+	payload[idx] = []byte(value)
+	return payload
+}
+
+// name returns the name of the target to show to users
+func (t target) name() string {
+	if t.cluster == "" {
+		return t.node
+	}
+	return t.cluster + "/" + t.node
+}
+
+// key identifies the target among the ones of all clusters
+func (t target) key() string {
+	if t.cluster == "" {
+		return t.addressOrPod
+	}
+	return t.cluster + "/" + t.addressOrPod
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func TestClusterTagger(t *testing.T) {
+	gi := &api.GadgetInfo{
+		DataSources: []*api.DataSource{
+			{
+				Id:   0,
+				Name: "events",
+				Type: uint32(datasource.TypeSingle),
+				Fields: []*api.Field{
+					{Name: "comm", FullName: "comm", Kind: api.Kind_String, PayloadIndex: 0},
+					{Name: "pid", FullName: "pid", Kind: api.Kind_Uint32, PayloadIndex: 1},
+					{Name: "k8s", FullName: "k8s", Flags: uint32(datasource.FieldFlagEmpty), PayloadIndex: 7},
+				},
+			},
+			{
+				Id:   1,
+				Name: "snapshot",
+				Type: uint32(datasource.TypeArray),
+				Fields: []*api.Field{
+					{Name: "comm", FullName: "comm", Kind: api.Kind_String, PayloadIndex: 0},
+				},
+			},
+		},
+	}
+
+	tagger := newClusterTagger("prod")
+	tagger.addFields(gi)
+
+	single, err := datasource.NewFromAPI(gi.DataSources[0])
+	require.NoError(t, err)
+	clusterField := single.GetField(ClusterField)
+	require.NotNil(t, clusterField)
+
+	raw, err := proto.Marshal(&api.GadgetData{Data: &api.DataElement{
+		Payload: [][]byte{[]byte("bash"), {1, 0, 0, 0}},
+	}})
+	require.NoError(t, err)
+	p, err := single.NewPacketSingleFromRaw(raw)
+	require.NoError(t, err)
+	tagger.tag(0, p)
+	cluster, err := clusterField.String(p)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", cluster)
+
+	array, err := datasource.NewFromAPI(gi.DataSources[1])
+	require.NoError(t, err)
+	clusterField = array.GetField(ClusterField)
+	require.NotNil(t, clusterField)
+
+	raw, err = proto.Marshal(&api.GadgetDataArray{DataArray: []*api.DataElement{
+		{Payload: [][]byte{[]byte("bash")}},
+		{Payload: [][]byte{[]byte("sh")}},
+	}})
+	require.NoError(t, err)
+	pa, err := array.NewPacketArrayFromRaw(raw)
+	require.NoError(t, err)
+	tagger.tag(1, pa)
+	for i := range pa.Len() {
+		cluster, err := clusterField.String(pa.Get(i))
+		require.NoError(t, err)
+		assert.Equal(t, "prod", cluster)
+	}
+
+	// Fields added on the client don't reuse the payload of the cluster field
+	_, err = single.AddField("extra", api.Kind_Uint8)
+	require.NoError(t, err)
+	fields := single.Fields()
+	assert.Equal(t, uint32(2), fields[3].PayloadIndex)
+	assert.Equal(t, uint32(3), fields[4].PayloadIndex)
+}
+
+func TestTargetName(t *testing.T) {
+	tg := target{addressOrPod: "gadget-abc", node: "node-1"}
+	assert.Equal(t, "node-1", tg.name())
+	assert.Equal(t, "gadget-abc", tg.key())
+
+	tg.cluster = "prod"
+	assert.Equal(t, "prod/node-1", tg.name())
+	assert.Equal(t, "prod/gadget-abc", tg.key())
+}
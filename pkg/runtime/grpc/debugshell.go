@@ -0,0 +1,119 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+// DebugShell opens a debug shell in a container; the targets are tried in turn
+// until the one running the container is found
+func (r *Runtime) DebugShell(ctx context.Context, runtimeParams *params.Params, req *api.DebugShellStartRequest,
+	stdin io.Reader, output io.Writer, onStarted func(*api.DebugShellStarted),
+) (*api.DebugShellExit, error) {
+	targets, err := r.getTargets(ctx, runtimeParams)
+	if err != nil {
+		return nil, fmt.Errorf("getting target nodes: %w", err)
+	}
+
+	var errs []error
+	for _, t := range targets {
+		exit, err := r.debugShell(ctx, t, req, stdin, output, onStarted)
+		if status.Code(err) == codes.NotFound {
+			errs = append(errs, fmt.Errorf("%s: %w", t.name(), err))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("node %q: %w", t.name(), err)
+		}
+		return exit, nil
+	}
+	return nil, fmt.Errorf("container not found: %w", errors.Join(errs...))
+}
+
+func (r *Runtime) debugShell(ctx context.Context, target target, req *api.DebugShellStartRequest,
+	stdin io.Reader, output io.Writer, onStarted func(*api.DebugShellStarted),
+) (*api.DebugShellExit, error) {
+	timeout := r.globalParams.Get(ParamConnectionTimeout).AsDuration()
+	dialCtx, cancelDial := context.WithTimeout(ctx, timeout)
+	defer cancelDial()
+
+	conn, err := r.dialContext(dialCtx, target, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("dialing target: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client, err := api.NewGadgetManagerClient(conn).DebugShell(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = client.Send(&api.DebugShellRequest{Event: &api.DebugShellRequest_StartRequest{StartRequest: req}})
+	if err != nil {
+		return nil, err
+	}
+
+	ev, err := client.Recv()
+	if err != nil {
+		return nil, err
+	}
+	started := ev.GetStarted()
+	if started == nil {
+		return nil, fmt.Errorf("expected the session to start, got %T", ev.Event)
+	}
+	onStarted(started)
+
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := stdin.Read(buf)
+			if n > 0 {
+				data := append([]byte(nil), buf[:n]...)
+				if client.Send(&api.DebugShellRequest{Event: &api.DebugShellRequest_Stdin{Stdin: data}}) != nil {
+					return
+				}
+			}
+			if err != nil {
+				client.CloseSend()
+				return
+			}
+		}
+	}()
+
+	for {
+		ev, err := client.Recv()
+		if err != nil {
+			return nil, err
+		}
+		switch e := ev.Event.(type) {
+		case *api.DebugShellEvent_Output:
+			output.Write(e.Output)
+		case *api.DebugShellEvent_Exit:
+			return e.Exit, nil
+		}
+	}
+}
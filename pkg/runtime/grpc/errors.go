@@ -0,0 +1,95 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"errors"
+	"maps"
+	"slices"
+)
+
+// MultiNodeError is returned by operations running on several nodes if some of
+// them failed. It holds the error of each failed node, so callers can tell a
+// single node being down from a request that failed everywhere. Use errors.As
+// to get it from the error returned.
+type MultiNodeError struct {
+	// nodes holds the failed nodes in the order they were run on
+	nodes []string
+	errs  map[string]error
+	total int
+}
+
+// newMultiNodeError returns a *MultiNodeError holding the errors of the nodes
+// that failed; errs must be in the same order as nodes, with nil for the nodes
+// that succeeded. nil is returned if no node failed.
+func newMultiNodeError(nodes []string, errs []error) error {
+	e := &MultiNodeError{
+		errs:  make(map[string]error),
+		total: len(nodes),
+	}
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		node := nodes[i]
+		if prev, ok := e.errs[node]; ok {
+			e.errs[node] = errors.Join(prev, err)
+			continue
+		}
+		e.nodes = append(e.nodes, node)
+		e.errs[node] = err
+	}
+	if len(e.nodes) == 0 {
+		return nil
+	}
+	return e
+}
+
+func targetNodes(targets []target) []string {
+	nodes := make([]string, 0, len(targets))
+	for _, t := range targets {
+		nodes = append(nodes, t.name())
+	}
+	return nodes
+}
+
+func (e *MultiNodeError) Error() string {
+	return errors.Join(e.Unwrap()...).Error()
+}
+
+// Errors returns the error of each failed node
+func (e *MultiNodeError) Errors() map[string]error {
+	return maps.Clone(e.errs)
+}
+
+// Nodes returns the names of the failed nodes
+func (e *MultiNodeError) Nodes() []string {
+	return slices.Clone(e.nodes)
+}
+
+// AllFailed returns whether the operation failed on all nodes it ran on
+func (e *MultiNodeError) AllFailed() bool {
+	return len(e.nodes) == e.total
+}
+
+// Unwrap returns the errors of the failed nodes, so that errors.Is and
+// errors.As consider them
+func (e *MultiNodeError) Unwrap() []error {
+	errs := make([]error, 0, len(e.nodes))
+	for _, node := range e.nodes {
+		errs = append(errs, e.errs[node])
+	}
+	return errs
+}
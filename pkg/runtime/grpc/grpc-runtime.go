@@ -16,12 +16,15 @@ package grpcruntime
 
 import (
 	"context"
-	"crypto/tls"
 	_ "embed"
 	"fmt"
 	"net"
 	"net/url"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -29,19 +32,21 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/compression"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
-	gadgettls "github.com/inspektor-gadget/inspektor-gadget/pkg/utils/tls"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
 )
 
 type ConnectionMode int
 
 const (
 	// ConnectionModeDirect will connect directly to the remote using the gRPC protocol; the remote side can either
-	// be a tcp or a unix socket endpoint
+	// be a tcp, a unix socket or a vsock endpoint
 	ConnectionModeDirect ConnectionMode = iota
 
 	// ConnectionModeKubernetesProxy will connect to a gRPC endpoint through a kubernetes API server by first looking
@@ -53,19 +58,44 @@ const (
 const (
 	ParamNode              = "node"
 	ParamRemoteAddress     = "remote-address"
+	ParamRemoteAddressFile = "remote-address-file"
+	ParamRemoteAddressSRV  = "remote-address-srv"
 	ParamConnectionMethod  = "connection-method"
 	ParamConnectionTimeout = "connection-timeout"
+	ParamMaxParallel       = "max-parallel-targets"
+	ParamTargetTimeout     = "target-timeout"
+	ParamTargetRetries     = "target-retries"
+	ParamTargetRetryDelay  = "target-retry-delay"
 	ParamID                = "id"
 	ParamDetach            = "detach"
 	ParamTags              = "tags"
 	ParamName              = "name"
+	ParamNodeSelector      = "node-selector"
+	ParamTolerations       = "tolerations"
+	ParamTTL               = "ttl"
 	ParamEventBufferLength = "event-buffer-length"
+	ParamOnNodeFailure     = "on-node-failure"
+	ParamCompression       = "compression"
+	ParamClusters          = "clusters"
+	ParamNoCache           = "no-cache"
+
+	ParamChecksumBatchSize = "checksum-batch-size"
+	ParamChecksumKeyFile   = "checksum-key-file"
 
 	ParamTLSKey        = "tls-key-file"
 	ParamTLSCert       = "tls-cert-file"
 	ParamTLSServerCA   = "tls-server-ca-file"
 	ParamTLSServerName = "tls-server-name"
 
+	// ParamGadgetTLS* are used instead of ParamTLS* in combination with ConnectionModeKubernetesProxy
+	ParamGadgetTLSKey        = "gadget-tls-key-file"
+	ParamGadgetTLSCert       = "gadget-tls-cert-file"
+	ParamGadgetTLSServerCA   = "gadget-tls-server-ca-file"
+	ParamGadgetTLSServerName = "gadget-tls-server-name"
+
+	ParamTokenFile       = "token-file"
+	ParamGadgetTokenFile = "gadget-token-file"
+
 	// ParamGadgetServiceTCPPort is only used in combination with KubernetesProxyConnectionMethodTCP
 	ParamGadgetServiceTCPPort = "tcp-port"
 
@@ -77,16 +107,39 @@ const (
 	// after sending a Stop command
 	ResultTimeout = 30
 
+	// MaxParallelTargets is the default number of targets contacted at the same time
+	// when an operation has to be run on several of them
+	MaxParallelTargets = 64
+
+	// MaxRetryDelay caps the exponential backoff between retries on a target
+	MaxRetryDelay = 10 * time.Second
+
 	ParamGadgetNamespace   string = "gadget-namespace"
 	DefaultGadgetNamespace string = "gadget"
 )
 
+// Policies for ParamOnNodeFailure
+const (
+	OnNodeFailureFail     = "fail"
+	OnNodeFailureRollback = "rollback"
+	OnNodeFailureContinue = "continue"
+)
+
+var onNodeFailurePolicies = []string{OnNodeFailureFail, OnNodeFailureRollback, OnNodeFailureContinue}
+
 type Runtime struct {
 	info           *Info
 	defaultValues  map[string]string
 	globalParams   *params.Params
 	restConfig     *rest.Config
 	connectionMode ConnectionMode
+	pool           *connPool
+
+	restConfigLoader RestConfigLoader
+	restConfigs      map[string]*rest.Config
+	clustersLock     sync.Mutex
+
+	resolver TargetResolver
 }
 
 type RunClient interface {
@@ -99,6 +152,7 @@ type RunClient interface {
 func New(options ...Option) *Runtime {
 	r := &Runtime{
 		defaultValues: map[string]string{},
+		pool:          newConnPool(),
 	}
 	for _, option := range options {
 		option(r)
@@ -124,6 +178,7 @@ func (r *Runtime) SetRestConfig(config *rest.Config) {
 }
 
 func (r *Runtime) Close() error {
+	r.pool.close()
 	return nil
 }
 
@@ -164,6 +219,33 @@ func (r *Runtime) ParamDescs() params.ParamDescs {
 			TypeHint:    params.TypeString,
 			Tags:        []string{"!attach"},
 		},
+		{
+			Key:         ParamNodeSelector,
+			Description: "Label selector of the nodes to run the gadget instance on, like node-role.kubernetes.io/worker=true; nodes matching it later also run it; used with --detach",
+			TypeHint:    params.TypeString,
+			Tags:        []string{"!attach"},
+			Validator: func(value string) error {
+				_, err := labels.Parse(value)
+				return err
+			},
+		},
+		{
+			Key:         ParamTolerations,
+			Description: "Comma-separated list of node taints to tolerate, as key[=value][:effect], or * to tolerate all taints; used with --detach",
+			TypeHint:    params.TypeString,
+			Tags:        []string{"!attach"},
+			Validator: func(value string) error {
+				_, err := parseTolerations(value)
+				return err
+			},
+		},
+		{
+			Key:          ParamTTL,
+			Description:  "Time after which the gadget instance is stopped and removed, like 2h or 7d; used with --detach; 0 = never",
+			DefaultValue: "0",
+			TypeHint:     params.TypeDuration,
+			Tags:         []string{"!attach"},
+		},
 		{
 			Key:         ParamID,
 			Description: "ID to assign to the gadget instance; if unset, it will be generated",
@@ -177,6 +259,43 @@ func (r *Runtime) ParamDescs() params.ParamDescs {
 			DefaultValue: "0",
 			Tags:         []string{"!attach"},
 		},
+		{
+			Key: ParamOnNodeFailure,
+			Description: "What to do if creating the gadget instance fails on some of the nodes: " +
+				"\"fail\" returns an error and keeps the instance on the other nodes, \"rollback\" also removes it from them " +
+				"and \"continue\" keeps it running on the other nodes without returning an error; used with --detach",
+			TypeHint:       params.TypeString,
+			DefaultValue:   OnNodeFailureFail,
+			PossibleValues: onNodeFailurePolicies,
+			Tags:           []string{"!attach"},
+		},
+		{
+			Key:            ParamCompression,
+			Description:    "Codec the nodes compress the events with before sending them; ignored by nodes not supporting it",
+			TypeHint:       params.TypeString,
+			DefaultValue:   compression.None,
+			PossibleValues: compression.Names(),
+		},
+		{
+			Key:          ParamNoCache,
+			Description:  "Run one-shot gadgets like snapshot_process even if the nodes have cached their events",
+			TypeHint:     params.TypeBool,
+			DefaultValue: "false",
+			Tags:         []string{"!attach"},
+		},
+		{
+			Key:          ParamChecksumBatchSize,
+			Description:  "Verify the integrity of the events by requesting a checksum every given number of events; 0 = disabled",
+			TypeHint:     params.TypeUint32,
+			DefaultValue: "0",
+			Tags:         []string{"!attach"},
+		},
+		{
+			Key:         ParamChecksumKeyFile,
+			Description: "Path to the key shared with the server to verify signed checksums; used with --checksum-batch-size",
+			TypeHint:    params.TypeString,
+			Tags:        []string{"!attach"},
+		},
 	}...)
 	switch r.connectionMode {
 	case ConnectionModeDirect:
@@ -198,9 +317,33 @@ func (r *Runtime) GlobalParamDescs() params.ParamDescs {
 	p := params.ParamDescs{
 		{
 			Key:          ParamConnectionTimeout,
-			Description:  "Maximum time to establish a connection to remote target in seconds",
-			DefaultValue: fmt.Sprintf("%d", ConnectTimeout),
-			TypeHint:     params.TypeUint16,
+			Description:  "Maximum time to establish a connection to remote target, like 5s or 1m; plain numbers are seconds",
+			DefaultValue: fmt.Sprintf("%ds", ConnectTimeout),
+			TypeHint:     params.TypeDuration,
+		},
+		{
+			Key:          ParamMaxParallel,
+			Description:  "Maximum number of targets contacted at the same time when managing gadget instances or collecting node information; 0 = no limit",
+			DefaultValue: fmt.Sprintf("%d", MaxParallelTargets),
+			TypeHint:     params.TypeUint32,
+		},
+		{
+			Key:          ParamTargetTimeout,
+			Description:  "Maximum time a single target has to connect and answer when managing gadget instances or collecting node information, like 30s; 0 = no limit",
+			DefaultValue: "0",
+			TypeHint:     params.TypeDuration,
+		},
+		{
+			Key:          ParamTargetRetries,
+			Description:  "Number of times a target is retried after a transient failure when managing gadget instances or collecting node information; 0 = no retries",
+			DefaultValue: "2",
+			TypeHint:     params.TypeUint32,
+		},
+		{
+			Key:          ParamTargetRetryDelay,
+			Description:  "Time to wait before the first retry on a target; it's doubled for every further retry, with some random jitter",
+			DefaultValue: "500ms",
+			TypeHint:     params.TypeDuration,
 		},
 	}
 	switch r.connectionMode {
@@ -208,31 +351,23 @@ func (r *Runtime) GlobalParamDescs() params.ParamDescs {
 		p.Add(params.ParamDescs{
 			{
 				Key:          ParamRemoteAddress,
-				Description:  "Comma-separated list of remote address (gRPC) to connect to",
+				Description:  "Comma-separated list of remote address (gRPC) to connect to: tcp://host:port, unix:///path or vsock://cid:port; append ?node=NAME to set the node name",
 				DefaultValue: api.DefaultDaemonPath,
 				Validator:    checkForDuplicates("address"),
 			},
 			{
-				Key:         ParamTLSKey,
-				Description: "TLS client key",
-				TypeHint:    params.TypeString,
-			},
-			{
-				Key:         ParamTLSCert,
-				Description: "TLS client certificate",
+				Key:         ParamRemoteAddressFile,
+				Description: "File listing the remote addresses (gRPC) to connect to, one per line; replaces --remote-address and is read again every time targets are looked up",
 				TypeHint:    params.TypeString,
 			},
 			{
-				Key:         ParamTLSServerCA,
-				Description: "TLS server CA certificate",
-				TypeHint:    params.TypeString,
-			},
-			{
-				Key:         ParamTLSServerName,
-				Description: "override TLS server name (if omitted, using target server name)",
+				Key:         ParamRemoteAddressSRV,
+				Description: "DNS name of the SRV records listing the remote hosts (gRPC) to connect to, like _ig._tcp.example.com; replaces --remote-address and is looked up again every time targets are looked up",
 				TypeHint:    params.TypeString,
 			},
 		}...)
+		p.Add(r.tlsParamDescs()...)
+		p.Add(r.tokenParamDesc())
 		return p
 	case ConnectionModeKubernetesProxy:
 		p.Add(params.ParamDescs{
@@ -248,7 +383,14 @@ func (r *Runtime) GlobalParamDescs() params.ParamDescs {
 				DefaultValue: DefaultGadgetNamespace,
 				TypeHint:     params.TypeString,
 			},
+			{
+				Key:         ParamClusters,
+				Description: "Comma-separated list of kubeconfig contexts of the clusters to run gadgets on at the same time; events get a cluster field. Defaults to the current context",
+				Validator:   checkForDuplicates("context"),
+			},
 		}...)
+		p.Add(r.tlsParamDescs()...)
+		p.Add(r.tokenParamDesc())
 		return p
 	}
 	panic("invalid connection mode set for grpc-runtime")
@@ -257,6 +399,11 @@ func (r *Runtime) GlobalParamDescs() params.ParamDescs {
 type target struct {
 	addressOrPod string
 	node         string
+
+	// cluster is the kubeconfig context of the cluster of the target when
+	// running on several clusters; restConfig is used to connect to it then
+	cluster    string
+	restConfig *rest.Config
 }
 
 func getGadgetPods(ctx context.Context, config *rest.Config, nodes []string, gadgetNamespace string) ([]target, error) {
@@ -300,44 +447,103 @@ nodesLoop:
 	return res, nil
 }
 
-// getTargets returns targets depending on the params given and the environment. The returned
-// bool is true, if the user explicitly selected the nodes using params.
+// getTargets returns the targets chosen by the params given and the resolver
+// of the runtime
 func (r *Runtime) getTargets(ctx context.Context, params *params.Params) ([]target, error) {
-	switch r.connectionMode {
-	case ConnectionModeKubernetesProxy:
-		// Get nodes to run on
-		nodes := params.Get(ParamNode).AsStringSlice()
-		gadgetNamespace := r.globalParams.Get(ParamGadgetNamespace).AsString()
-		pods, err := getGadgetPods(ctx, r.restConfig, nodes, gadgetNamespace)
+	var nodes []string
+	if params != nil {
+		if p := params.Get(ParamNode); p != nil {
+			nodes = p.AsStringSlice()
+		}
+	}
+	resolver, err := r.targetResolver()
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := resolver.Resolve(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveTargets(resolved, nodes)
+}
+
+// parseRemoteAddresses returns the targets for the given remote addresses. Supported are tcp://host:port,
+// unix:///path/to/socket and vsock://cid:port; the node name of each target can be overridden by adding a "node"
+// query parameter, like unix:///run/ig.sock?node=vm1.
+func parseRemoteAddresses(addresses []string) ([]target, error) {
+	unixSockets := 0
+	for _, address := range addresses {
+		if strings.HasPrefix(address, "unix:") {
+			unixSockets++
+		}
+	}
+
+	targets := make([]target, 0, len(addresses))
+	for _, t := range addresses {
+		purl, err := url.Parse(t)
 		if err != nil {
-			return nil, fmt.Errorf("get gadget pods: %w", err)
+			return nil, fmt.Errorf("invalid remote address %q: %w", t, err)
 		}
-		if len(pods) == 0 {
-			return nil, fmt.Errorf("get gadget pods: Inspektor Gadget is not running on the requested node(s): %v", nodes)
+		tg := target{
+			addressOrPod: purl.Host,
+			node:         purl.Hostname(),
 		}
-		return pods, nil
-	case ConnectionModeDirect:
-		inTargets := r.globalParams.Get(ParamRemoteAddress).AsStringSlice()
-		targets := make([]target, 0)
-		for _, t := range inTargets {
-			purl, err := url.Parse(t)
+		switch purl.Scheme {
+		case "unix":
+			// use the whole url in case of a unix socket and "local" as node, unless there are several sockets
+			tg.addressOrPod = "unix://" + purl.Path
+			tg.node = "local"
+			if unixSockets > 1 {
+				tg.node = purl.Path
+			}
+		case "vsock":
+			cid, port, err := parseVsockAddress(purl)
 			if err != nil {
 				return nil, fmt.Errorf("invalid remote address %q: %w", t, err)
 			}
-			tg := target{
-				addressOrPod: purl.Host,
-				node:         purl.Hostname(),
-			}
-			if purl.Scheme == "unix" {
-				// use the whole url in case of a unix socket and "local" as node
-				tg.addressOrPod = t
-				tg.node = "local"
-			}
-			targets = append(targets, tg)
+			tg.addressOrPod = fmt.Sprintf("vsock://%d:%d", cid, port)
+			tg.node = fmt.Sprintf("vsock-%d", cid)
+		}
+		if node := purl.Query().Get("node"); node != "" {
+			tg.node = node
+		}
+		if tg.addressOrPod == "" || tg.addressOrPod == "unix://" {
+			return nil, fmt.Errorf("invalid remote address %q: missing host or path", t)
 		}
-		return targets, nil
+		targets = append(targets, tg)
 	}
-	return nil, fmt.Errorf("unsupported connection mode")
+	return targets, nil
+}
+
+// parseVsockAddress returns the context id and port of a vsock://cid:port url
+func parseVsockAddress(purl *url.URL) (uint32, uint32, error) {
+	cid, err := strconv.ParseUint(purl.Hostname(), 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid vsock context id %q", purl.Hostname())
+	}
+	port, err := strconv.ParseUint(purl.Port(), 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid vsock port %q", purl.Port())
+	}
+	return uint32(cid), uint32(port), nil
+}
+
+// readRemoteAddressFile returns the addresses listed in the given file, one per line; empty lines, lines starting
+// with # and duplicates are skipped
+func readRemoteAddressFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading remote address file: %w", err)
+	}
+	var addresses []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || slices.Contains(addresses, line) {
+			continue
+		}
+		addresses = append(addresses, line)
+	}
+	return addresses, nil
 }
 
 func (r *Runtime) getConnToRandomTarget(ctx context.Context, runtimeParams *params.Params) (*grpc.ClientConn, error) {
@@ -349,23 +555,23 @@ func (r *Runtime) getConnToRandomTarget(ctx context.Context, runtimeParams *para
 		return nil, fmt.Errorf("no valid targets")
 	}
 	target := targets[0]
-	log.Debugf("using target %q (%q)", target.addressOrPod, target.node)
+	log.Debugf("using target %q (%q)", target.addressOrPod, target.name())
 
-	timeout := time.Second * time.Duration(r.globalParams.Get(ParamConnectionTimeout).AsUint16())
+	timeout := r.globalParams.Get(ParamConnectionTimeout).AsDuration()
 	conn, err := r.dialContext(ctx, target, timeout)
 	if err != nil {
-		return nil, fmt.Errorf("dialing %q (%q): %w", target.addressOrPod, target.node, err)
+		return nil, fmt.Errorf("dialing %q (%q): %w", target.addressOrPod, target.name(), err)
 	}
 	return conn, nil
 }
 
 func (r *Runtime) getConnFromTarget(ctx context.Context, runtimeParams *params.Params, target target) (*grpc.ClientConn, error) {
-	log.Debugf("using target %q (%q)", target.addressOrPod, target.node)
+	log.Debugf("using target %q (%q)", target.addressOrPod, target.name())
 
-	timeout := time.Second * time.Duration(r.globalParams.Get(ParamConnectionTimeout).AsUint16())
+	timeout := r.globalParams.Get(ParamConnectionTimeout).AsDuration()
 	conn, err := r.dialContext(ctx, target, timeout)
 	if err != nil {
-		return nil, fmt.Errorf("dialing %q (%q): %w", target.addressOrPod, target.node, err)
+		return nil, fmt.Errorf("dialing %q (%q): %w", target.addressOrPod, target.name(), err)
 	}
 	return conn, nil
 }
@@ -376,74 +582,54 @@ func (r *Runtime) dialContext(dialCtx context.Context, target target, timeout ti
 		grpc.WithBlock(),
 		//nolint:staticcheck
 		grpc.WithReturnConnectionError(),
+		grpc.WithChainUnaryInterceptor(quotaUnaryInterceptor),
+		grpc.WithChainStreamInterceptor(quotaStreamInterceptor),
+		tracing.DialOption(),
 	}
 
-	tlsKey := r.globalParams.Get(ParamTLSKey).String()
-	tlsCert := r.globalParams.Get(ParamTLSCert).String()
-	tlsCA := r.globalParams.Get(ParamTLSServerCA).String()
-
-	tlsOptionsSet := 0
-	for _, tlsOption := range []string{tlsKey, tlsCert, tlsCA} {
-		if len(tlsOption) != 0 {
-			tlsOptionsSet++
-		}
-	}
-
-	if tlsOptionsSet > 1 && tlsOptionsSet < 3 {
-		return nil, fmt.Errorf(`
-missing at least one the TLS related options:
-	* %s: %q
-	* %s: %q
-	* %s: %q
-All these options should be set at the same time to enable TLS connection`,
-			ParamTLSKey, tlsKey,
-			ParamTLSCert, tlsCert,
-			ParamTLSServerCA, tlsCA)
+	tlsConfig, err := r.tlsConfig(target)
+	if err != nil {
+		return nil, err
 	}
-
-	if tlsOptionsSet == 3 {
-		cert, err := gadgettls.LoadTLSCert(tlsCert, tlsKey)
-		if err != nil {
-			return nil, fmt.Errorf("creating TLS certificate: %w", err)
-		}
-
-		ca, err := gadgettls.LoadTLSCA(tlsCA)
-		if err != nil {
-			return nil, fmt.Errorf("creating TLS certificate authority: %w", err)
-		}
-
-		purl, err := url.Parse(target.addressOrPod)
-		if err != nil {
-			return nil, fmt.Errorf("parsing address %v: %w", target.addressOrPod, err)
-		}
-
-		tlsConfig := &tls.Config{
-			ServerName:   purl.Hostname(),
-			Certificates: []tls.Certificate{cert},
-			RootCAs:      ca,
-		}
-
-		if serverName := r.globalParams.Get(ParamTLSServerName).String(); serverName != "" {
-			tlsConfig.ServerName = serverName
-		}
-
-		if tlsConfig.ServerName == "" {
-			return nil, fmt.Errorf("invalid hostname, use %s to override", ParamTLSServerName)
-		}
-
+	if tlsConfig != nil {
 		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
 	} else {
 		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
 
+	tokenCreds, err := r.tokenCredentials()
+	if err != nil {
+		return nil, err
+	}
+	if tokenCreds != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCreds))
+	}
+
 	// If we're in Kubernetes connection mode, we need a custom dialer
 	if r.connectionMode == ConnectionModeKubernetesProxy {
 		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
 			port := r.globalParams.Get(ParamGadgetServiceTCPPort).AsUint16()
 			gadgetNamespace := r.globalParams.Get(ParamGadgetNamespace).AsString()
-			return NewK8SPortFwdConn(ctx, r.restConfig, gadgetNamespace, target, port, timeout)
+			restConfig := r.restConfig
+			if target.restConfig != nil {
+				restConfig = target.restConfig
+			}
+			return NewK8SPortFwdConn(ctx, restConfig, gadgetNamespace, target, port, timeout)
 		}))
 	} else {
+		if strings.HasPrefix(target.addressOrPod, "vsock://") {
+			opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+				purl, err := url.Parse(s)
+				if err != nil {
+					return nil, err
+				}
+				cid, port, err := parseVsockAddress(purl)
+				if err != nil {
+					return nil, err
+				}
+				return dialVsock(ctx, cid, port)
+			}))
+		}
 		newCtx, cancel := context.WithTimeout(dialCtx, timeout)
 		defer cancel()
 		dialCtx = newCtx
@@ -452,7 +638,7 @@ All these options should be set at the same time to enable TLS connection`,
 	//nolint:staticcheck
 	conn, err := grpc.DialContext(dialCtx, "passthrough:///"+target.addressOrPod, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("dialing %q (%q): %w", target.addressOrPod, target.node, err)
+		return nil, fmt.Errorf("dialing %q (%q): %w", target.addressOrPod, target.name(), err)
 	}
 	return conn, nil
 }
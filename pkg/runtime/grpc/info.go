@@ -17,10 +17,12 @@ package grpcruntime
 import (
 	"context"
 	"fmt"
-	"math"
-	"time"
+	"sync"
+
+	"google.golang.org/grpc"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 )
 
 type Info struct {
@@ -33,11 +35,7 @@ func (r *Runtime) GetInfo() (*Info, error) {
 		return r.info, nil
 	}
 
-	duration := r.globalParams.Get(ParamConnectionTimeout).AsUint()
-	if duration > math.MaxInt64 {
-		return nil, fmt.Errorf("duration (%d) exceeds math.MaxInt64 (%d)", duration, math.MaxInt64)
-	}
-	timeout := time.Second * time.Duration(duration)
+	timeout := r.globalParams.Get(ParamConnectionTimeout).AsDuration()
 	ctx, cancelDial := context.WithTimeout(context.Background(), timeout)
 	defer cancelDial()
 
@@ -61,3 +59,42 @@ func (r *Runtime) GetInfo() (*Info, error) {
 	}
 	return r.info, nil
 }
+
+// GetNodeInfo fetches the kernel feature inventory from all targets; the result is keyed by the node name
+func (r *Runtime) GetNodeInfo(ctx context.Context, runtimeParams *params.Params, req *api.NodeInfoRequest) (map[string]*api.NodeInfo, error) {
+	var mu sync.Mutex
+	res := make(map[string]*api.NodeInfo)
+	err := r.runForTargets(ctx, runtimeParams, true, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		client := api.NewBuiltInGadgetManagerClient(conn)
+		info, err := client.GetNodeInfo(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		res[target.name()] = info
+		mu.Unlock()
+		return nil
+	})
+	return res, err
+}
+
+// GetNodeOverhead fetches the resources used by Inspektor Gadget from all targets; the result is keyed by the node
+// name
+func (r *Runtime) GetNodeOverhead(ctx context.Context, runtimeParams *params.Params, req *api.NodeOverheadRequest) (map[string]*api.NodeOverhead, error) {
+	var mu sync.Mutex
+	res := make(map[string]*api.NodeOverhead)
+	err := r.runForTargets(ctx, runtimeParams, true, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		client := api.NewBuiltInGadgetManagerClient(conn)
+		overhead, err := client.GetNodeOverhead(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		res[target.name()] = overhead
+		mu.Unlock()
+		return nil
+	})
+	return res, err
+}
@@ -18,25 +18,117 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/blang/semver"
 	"github.com/moby/moby/pkg/namesgenerator"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/inspektor-gadget/inspektor-gadget/internal/version"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/environment"
+	gadgetmanifest "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-manifest"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
 )
 
 type NodeInstanceState struct {
 	State *api.GadgetInstanceState
 	Node  string
+	// Missing is set if the gadget instance is meant for the node, but isn't
+	// installed on it
+	Missing bool
+	// Error is set if the node couldn't be reached
+	Error error
 }
 
-func (r *Runtime) RemoveGadgetInstance(ctx context.Context, runtimeParams *params.Params, id string) error {
-	return r.runInstanceManagerClientForTargets(ctx, runtimeParams, false, func(target target, client api.GadgetInstanceManagerClient) error {
+var (
+	// ErrGadgetInstanceNotFound is returned if no gadget instance matches the
+	// given ID or name
+	ErrGadgetInstanceNotFound = errors.New("gadget instance not found")
+
+	// ErrAmbiguousGadgetInstance is returned if several gadget instances match
+	// the given ID or name prefix
+	ErrAmbiguousGadgetInstance = errors.New("gadget instance id or name is ambiguous")
+)
+
+// FindGadgetInstance returns the gadget instance identified by idOrName, which
+// can be the ID or the name of the instance or an unambiguous prefix of either.
+// A matching ID is preferred over a matching name, and both are preferred over
+// prefixes.
+func FindGadgetInstance(instances []*api.GadgetInstance, idOrName string) (*api.GadgetInstance, error) {
+	if idOrName == "" {
+		return nil, fmt.Errorf("%w: no id or name given", ErrGadgetInstanceNotFound)
+	}
+	var byID, byName, byPrefix []*api.GadgetInstance
+	for _, gi := range instances {
+		switch {
+		case gi.Id == idOrName:
+			byID = append(byID, gi)
+		case gi.Name == idOrName:
+			byName = append(byName, gi)
+		case strings.HasPrefix(gi.Id, idOrName) || strings.HasPrefix(gi.Name, idOrName):
+			byPrefix = append(byPrefix, gi)
+		}
+	}
+	for _, matches := range [][]*api.GadgetInstance{byID, byName, byPrefix} {
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		}
+		ids := make([]string, 0, len(matches))
+		for _, gi := range matches {
+			ids = append(ids, gi.Id)
+		}
+		return nil, fmt.Errorf("%w: %q matches %s", ErrAmbiguousGadgetInstance, idOrName, strings.Join(ids, ", "))
+	}
+	return nil, fmt.Errorf("%w: %q", ErrGadgetInstanceNotFound, idOrName)
+}
+
+// ResolveGadgetInstanceID returns the ID of the gadget instance identified by
+// idOrName, see FindGadgetInstance. Full IDs are returned as they are, without
+// asking the nodes.
+func (r *Runtime) ResolveGadgetInstanceID(ctx context.Context, runtimeParams *params.Params, idOrName string) (string, error) {
+	if api.IsValidInstanceID(idOrName) {
+		return idOrName, nil
+	}
+	instances, listErr := r.GetGadgetInstances(ctx, runtimeParams, nil)
+	if listErr != nil && !errors.As(listErr, new(*MultiNodeError)) {
+		return "", listErr
+	}
+	gi, err := FindGadgetInstance(instances, idOrName)
+	if err != nil {
+		if listErr != nil {
+			// The instance could be on the nodes that couldn't be reached
+			return "", fmt.Errorf("%w; not all nodes could be reached: %w", err, listErr)
+		}
+		return "", err
+	}
+	return gi.Id, nil
+}
+
+// RemoveGadgetInstance removes the gadget instance with the given ID or name,
+// see FindGadgetInstance; if that fails on some nodes, a *MultiNodeError holding
+// their errors is returned
+func (r *Runtime) RemoveGadgetInstance(ctx context.Context, runtimeParams *params.Params, idOrName string) error {
+	id, err := r.ResolveGadgetInstanceID(ctx, runtimeParams, idOrName)
+	if err != nil {
+		return err
+	}
+	return r.runInstanceManagerClientForTargets(ctx, runtimeParams, false, func(ctx context.Context, target target, client api.GadgetInstanceManagerClient) error {
 		res, err := client.RemoveGadgetInstance(ctx, &api.GadgetInstanceId{Id: id})
 		if err != nil {
 			return err
@@ -48,112 +140,553 @@ func (r *Runtime) RemoveGadgetInstance(ctx context.Context, runtimeParams *param
 	})
 }
 
-func (r *Runtime) GetGadgetInstances(ctx context.Context, runtimeParams *params.Params) (instances []*api.GadgetInstance, err error) {
+// PauseGadgetInstance stops the gadget instance with the given ID or name,
+// keeping its configuration and ID so it can be resumed with
+// ResumeGadgetInstance
+func (r *Runtime) PauseGadgetInstance(ctx context.Context, runtimeParams *params.Params, idOrName string) error {
+	id, err := r.ResolveGadgetInstanceID(ctx, runtimeParams, idOrName)
+	if err != nil {
+		return err
+	}
+	return r.runInstanceManagerClientForTargets(ctx, runtimeParams, false, func(ctx context.Context, target target, client api.GadgetInstanceManagerClient) error {
+		res, err := client.PauseGadgetInstance(ctx, &api.GadgetInstanceId{Id: id})
+		if err != nil {
+			return err
+		}
+		if res.Result != 0 {
+			return errors.New(res.Message)
+		}
+		return nil
+	})
+}
+
+// ResumeGadgetInstance runs the paused gadget instance with the given ID or name
+// again
+func (r *Runtime) ResumeGadgetInstance(ctx context.Context, runtimeParams *params.Params, idOrName string) error {
+	id, err := r.ResolveGadgetInstanceID(ctx, runtimeParams, idOrName)
+	if err != nil {
+		return err
+	}
+	return r.runInstanceManagerClientForTargets(ctx, runtimeParams, false, func(ctx context.Context, target target, client api.GadgetInstanceManagerClient) error {
+		res, err := client.ResumeGadgetInstance(ctx, &api.GadgetInstanceId{Id: id})
+		if err != nil {
+			return err
+		}
+		if res.Result != 0 {
+			return errors.New(res.Message)
+		}
+		return nil
+	})
+}
+
+// UpdateGadgetInstance merges the given param values into the gadget instance
+// with the given ID or name; the instance is restarted with the new values,
+// keeping its ID and buffered events
+func (r *Runtime) UpdateGadgetInstance(ctx context.Context, runtimeParams *params.Params, idOrName string, paramValues map[string]string) error {
+	id, err := r.ResolveGadgetInstanceID(ctx, runtimeParams, idOrName)
+	if err != nil {
+		return err
+	}
+	return r.runInstanceManagerClientForTargets(ctx, runtimeParams, false, func(ctx context.Context, target target, client api.GadgetInstanceManagerClient) error {
+		res, err := client.UpdateGadgetInstance(ctx, &api.UpdateGadgetInstanceRequest{
+			Id:          id,
+			ParamValues: paramValues,
+		})
+		if err != nil {
+			return err
+		}
+		if res.Result != 0 {
+			return fmt.Errorf("updating gadget instance %q: result %d", id, res.Result)
+		}
+		return nil
+	})
+}
+
+// NodeReport describes how a node answered when its gadget instances were
+// aggregated
+type NodeReport struct {
+	Node string
+
+	// Reachable is false if the node couldn't be asked for its instances; Error
+	// holds the reason
+	Reachable bool
+	Error     error
+
+	// ServerVersion is the version of the node, if it could be determined;
+	// VersionSkew is set if it differs from the version of the client
+	ServerVersion string
+	VersionSkew   bool
+
+	// StaleInstances holds the IDs of the instances the node knows about but
+	// has no state for yet, e.g. because it didn't start them yet
+	StaleInstances []string
+}
+
+// GadgetInstancesResult holds the gadget instances of all nodes together with a
+// report of how each node answered
+type GadgetInstancesResult struct {
+	Instances []*api.GadgetInstance
+	Nodes     []*NodeReport
+}
+
+// Unreachable returns the reports of the nodes that couldn't be reached
+func (res *GadgetInstancesResult) Unreachable() []*NodeReport {
+	var nodes []*NodeReport
+	for _, n := range res.Nodes {
+		if !n.Reachable {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// err returns a *MultiNodeError for the nodes that couldn't be reached, or nil
+// if all of them answered
+func (res *GadgetInstancesResult) err() error {
+	nodes := make([]string, 0, len(res.Nodes))
+	errs := make([]error, 0, len(res.Nodes))
+	for _, n := range res.Nodes {
+		nodes = append(nodes, n.Node)
+		errs = append(errs, n.Error)
+	}
+	return newMultiNodeError(nodes, errs)
+}
+
+// ListGadgetInstances returns the gadget instances of all targets that have all
+// the tags of the given selector, all instances are returned if it's empty.
+// Nodes that fail to answer don't make it fail, but are reported in the
+// result instead.
+func (r *Runtime) ListGadgetInstances(ctx context.Context, runtimeParams *params.Params, selector []string) (*GadgetInstancesResult, error) {
+	targets, err := r.selectTargets(ctx, runtimeParams, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GadgetInstancesResult{
+		Nodes: make([]*NodeReport, len(targets)),
+	}
+	for i, t := range targets {
+		res.Nodes[i] = &NodeReport{Node: t.name()}
+	}
+
 	var mu sync.Mutex
-	err = r.runInstanceManagerClientForTargets(ctx, runtimeParams, true, func(target target, client api.GadgetInstanceManagerClient) error {
-		res, err := client.ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{})
+	errs := r.runForEachTarget(ctx, runtimeParams, targets, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		list, err := api.NewGadgetInstanceManagerClient(conn).ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{Selector: selector})
 		if err != nil {
 			return err
 		}
 
-		// Merge results
+		// The version is only informational, so failing to get it is fine
+		var serverVersion string
+		if info, err := api.NewBuiltInGadgetManagerClient(conn).GetInfo(ctx, &api.InfoRequest{Version: "1.0"}); err == nil {
+			serverVersion = info.ServerVersion
+		}
+
+		var stale []string
+		for _, gi := range list.GadgetInstances {
+			if gi.State == nil && isMeantFor(gi, target.node) {
+				stale = append(stale, gi.Id)
+			}
+		}
+
 		mu.Lock()
-		instances = append(instances, res.GadgetInstances...)
-		mu.Unlock()
+		defer mu.Unlock()
+		res.Instances = append(res.Instances, list.GadgetInstances...)
+		for _, n := range res.Nodes {
+			if n.Node == target.name() {
+				n.ServerVersion = serverVersion
+				n.VersionSkew = hasVersionSkew(serverVersion)
+				n.StaleInstances = stale
+			}
+		}
 		return nil
 	})
-	slices.SortFunc(instances, func(i1 *api.GadgetInstance, i2 *api.GadgetInstance) int {
-		if i1.Id != i2.Id {
-			return strings.Compare(i1.Id, i2.Id)
-		}
-		if i1.State.Status == i2.State.Status {
+	for i, err := range errs {
+		res.Nodes[i].Reachable = err == nil
+		res.Nodes[i].Error = err
+	}
+
+	res.Instances = mergeGadgetInstances(res.Instances)
+	// Servers that don't know about selectors return all instances; make sure
+	// those are never picked, as the caller could e.g. delete them
+	res.Instances = slices.DeleteFunc(res.Instances, func(gi *api.GadgetInstance) bool {
+		return !gi.MatchesSelector(selector)
+	})
+	return res, nil
+}
+
+// GetGadgetInstances returns the gadget instances of all targets that have all
+// the tags of the given selector; all instances are returned if it's empty.
+// If targets couldn't be reached, a *MultiNodeError holding their errors is
+// returned together with the instances of the other ones.
+func (r *Runtime) GetGadgetInstances(ctx context.Context, runtimeParams *params.Params, selector []string) ([]*api.GadgetInstance, error) {
+	res, err := r.ListGadgetInstances(ctx, runtimeParams, selector)
+	if err != nil {
+		return nil, err
+	}
+	return res.Instances, res.err()
+}
+
+// ExportGadgetInstances returns the definitions of the gadget instances that
+// have all the tags of the given selector, so they can be stored and created
+// again later on, e.g. on another cluster. Runtime state like the status of the
+// instances isn't included. As a partial export would silently drop instances,
+// it fails if any target can't be reached.
+func (r *Runtime) ExportGadgetInstances(ctx context.Context, runtimeParams *params.Params, selector []string) ([]*gadgetmanifest.InstanceSpec, error) {
+	res, err := r.ListGadgetInstances(ctx, runtimeParams, selector)
+	if err != nil {
+		return nil, err
+	}
+	if err := res.err(); err != nil {
+		return nil, fmt.Errorf("exporting gadget instances: %w", err)
+	}
+
+	specs := make([]*gadgetmanifest.InstanceSpec, 0, len(res.Instances))
+	for _, gi := range res.Instances {
+		specs = append(specs, instanceToSpec(gi))
+	}
+	return specs, nil
+}
+
+func instanceToSpec(gi *api.GadgetInstance) *gadgetmanifest.InstanceSpec {
+	spec := &gadgetmanifest.InstanceSpec{
+		APIVersion:   gadgetmanifest.APIVersion,
+		Kind:         gadgetmanifest.KindInstanceSpec,
+		Image:        gi.GadgetConfig.GetImageName(),
+		ID:           gi.Id,
+		Name:         gi.Name,
+		Tags:         slices.DeleteFunc(slices.Clone(gi.Tags), func(tag string) bool { return tag == "" }),
+		Nodes:        gi.Nodes,
+		ParamValues:  gi.GadgetConfig.GetParamValues(),
+		NodeSelector: gi.NodeSelector,
+		Tolerations:  formatTolerations(gi.Tolerations),
+	}
+	if gi.Ttl > 0 {
+		spec.TTL = (time.Duration(gi.Ttl) * time.Second).String()
+	}
+	return spec
+}
+
+// mergeGadgetInstances merges the instances reported by several nodes into one
+// per ID; the state of an instance that isn't running on all nodes is
+// preferred, as it's the one needing attention, and instances without state
+// are only kept if no node has one for them
+func mergeGadgetInstances(instances []*api.GadgetInstance) []*api.GadgetInstance {
+	rank := func(gi *api.GadgetInstance) int {
+		switch {
+		case gi.State == nil:
+			return 2
+		case gi.State.Status == api.GadgetInstanceStatus_StatusRunning:
+			return 1
+		default:
 			return 0
 		}
-		// Move the instance to the front if it's not running
-		if i1.State.Status != api.GadgetInstanceStatus_StatusRunning {
-			return -1
+	}
+	slices.SortStableFunc(instances, func(i1 *api.GadgetInstance, i2 *api.GadgetInstance) int {
+		if i1.Id != i2.Id {
+			return strings.Compare(i1.Id, i2.Id)
 		}
-		return 1
+		return rank(i1) - rank(i2)
 	})
-	instances = slices.CompactFunc(instances, func(i1 *api.GadgetInstance, i2 *api.GadgetInstance) bool {
+	return slices.CompactFunc(instances, func(i1 *api.GadgetInstance, i2 *api.GadgetInstance) bool {
 		return i1.Id == i2.Id
 	})
-	return
 }
 
-func (r *Runtime) GetNodeInstanceStates(ctx context.Context, runtimeParams *params.Params, id string) ([]*NodeInstanceState, error) {
+// hasVersionSkew returns whether the given server version differs from the
+// version of the client; unknown versions are never considered skewed
+func hasVersionSkew(serverVersion string) bool {
+	if serverVersion == "" {
+		return false
+	}
+	serverSemver, err := semver.ParseTolerant(serverVersion)
+	if err != nil {
+		return false
+	}
+	return !serverSemver.EQ(version.Version())
+}
+
+// GetNodeInstanceStates returns the state of the gadget instance with the given ID or name, see FindGadgetInstance, on
+// each target. Targets the instance is meant for but that don't have it are reported as missing, and unreachable
+// targets with their error; an error is only returned if no target could be reached or the instance wasn't found.
+func (r *Runtime) GetNodeInstanceStates(ctx context.Context, runtimeParams *params.Params, idOrName string) ([]*NodeInstanceState, error) {
+	targets, err := r.selectTargets(ctx, runtimeParams, true)
+	if err != nil {
+		return nil, err
+	}
+
 	var mu sync.Mutex
-	var nStates []*NodeInstanceState
-	err := r.runInstanceManagerClientForTargets(ctx, runtimeParams, true, func(target target, client api.GadgetInstanceManagerClient) error {
-		res, err := client.ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{})
+	lists := make(map[target][]*api.GadgetInstance)
+	errs := r.runForEachTarget(ctx, runtimeParams, targets, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		res, err := api.NewGadgetInstanceManagerClient(conn).ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{})
 		if err != nil {
 			return err
 		}
 
 		mu.Lock()
-		for _, gi := range res.GadgetInstances {
-			if gi.Id == id {
-				nStates = append(nStates, &NodeInstanceState{
-					State: gi.GetState(),
-					Node:  target.node,
-				})
-				break
+		defer mu.Unlock()
+		lists[target] = res.GadgetInstances
+		return nil
+	})
+
+	var all []*api.GadgetInstance
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	instance, findErr := FindGadgetInstance(mergeGadgetInstances(all), idOrName)
+	found := make(map[target]*api.GadgetInstance)
+	if findErr == nil {
+		for t, list := range lists {
+			if i := slices.IndexFunc(list, func(gi *api.GadgetInstance) bool { return gi.Id == instance.Id }); i >= 0 {
+				found[t] = list[i]
 			}
 		}
-		mu.Unlock()
+	}
 
-		slices.SortFunc(nStates, func(i1 *NodeInstanceState, i2 *NodeInstanceState) int {
-			return strings.Compare(i1.Node, i2.Node)
-		})
-		return nil
+	var nStates []*NodeInstanceState
+	reachable := false
+	for i, err := range errs {
+		t := targets[i]
+		if err != nil {
+			nStates = append(nStates, &NodeInstanceState{Node: t.name(), Error: err})
+			continue
+		}
+		reachable = true
+		if gi, ok := found[t]; ok {
+			nStates = append(nStates, &NodeInstanceState{Node: t.name(), State: gi.GetState()})
+		} else if instance != nil && isMeantFor(instance, t.node) {
+			nStates = append(nStates, &NodeInstanceState{Node: t.name(), Missing: true})
+		}
+	}
+	if !reachable {
+		return nil, newMultiNodeError(targetNodes(targets), errs)
+	}
+	if findErr != nil {
+		return nil, findErr
+	}
+
+	slices.SortStableFunc(nStates, func(i1 *NodeInstanceState, i2 *NodeInstanceState) int {
+		return strings.Compare(i1.Node, i2.Node)
 	})
-	return nStates, err
+	return nStates, nil
+}
+
+// isMeantFor returns whether the gadget instance has to run on the given node; instances without a list of nodes run
+// on all of them
+func isMeantFor(gi *api.GadgetInstance, node string) bool {
+	return len(gi.Nodes) == 0 || slices.Contains(gi.Nodes, node)
+}
+
+func (r *Runtime) runInstanceManagerClientForTargets(ctx context.Context, runtimeParams *params.Params, allTargets bool, fn func(ctx context.Context, target target, client api.GadgetInstanceManagerClient) error) error {
+	return r.runForTargets(ctx, runtimeParams, allTargets, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		return fn(ctx, target, api.NewGadgetInstanceManagerClient(conn))
+	})
+}
+
+// runForTargets runs fn on the targets chosen by selectTargets; if it fails on
+// some of them, a *MultiNodeError holding their errors is returned
+func (r *Runtime) runForTargets(ctx context.Context, runtimeParams *params.Params, allTargets bool, fn func(ctx context.Context, target target, conn *grpc.ClientConn) error) error {
+	targets, err := r.selectTargets(ctx, runtimeParams, allTargets)
+	if err != nil {
+		return err
+	}
+	return newMultiNodeError(targetNodes(targets), r.runForEachTarget(ctx, runtimeParams, targets, fn))
 }
 
-func (r *Runtime) runInstanceManagerClientForTargets(ctx context.Context, runtimeParams *params.Params, allTargets bool, fn func(target target, client api.GadgetInstanceManagerClient) error) error {
-	// depending on the environment, we need to either connect to a single random target (k8s, where k8s/etcd handles
-	// synchronizing gadget configuration), or all possible targets (ig-daemon).
-	// if allTargets is true, we connect to all targets, otherwise we connect to one or more targets depending on the environment.
+// selectTargets returns the targets to connect to: depending on the environment, we need to either connect to a
+// single random target (k8s, where k8s/etcd handles synchronizing gadget configuration), or all possible targets
+// (ig-daemon). If allTargets is true, we connect to all targets, otherwise we connect to one or more targets
+// depending on the environment.
+func (r *Runtime) selectTargets(ctx context.Context, runtimeParams *params.Params, allTargets bool) ([]target, error) {
 	targets, err := r.getTargets(ctx, runtimeParams)
 	if err != nil {
-		return fmt.Errorf("getting targets: %w", err)
+		return nil, fmt.Errorf("getting targets: %w", err)
 	}
 
 	if len(targets) == 0 {
-		return fmt.Errorf("no targets found")
+		return nil, fmt.Errorf("no targets found")
 	}
 
 	if !allTargets && environment.Environment == environment.Kubernetes {
 		// We only need to connect to one target
 		targets = targets[:1]
 	}
+	return targets, nil
+}
 
-	var errs []error
-	var merrMutex sync.Mutex
+// runForEachTarget runs fn on all targets and returns the error of each of them, in the same order as targets; the
+// error of targets that succeeded is nil. The targets are run concurrently, limited by ParamMaxParallel, and each of
+// them gets a context limited by ParamTargetTimeout.
+func (r *Runtime) runForEachTarget(ctx context.Context, runtimeParams *params.Params, targets []target, fn func(ctx context.Context, target target, conn *grpc.ClientConn) error) []error {
+	errs := make([]error, len(targets))
 
+	workers := int(r.globalParams.Get(ParamMaxParallel).AsUint32())
+	if workers == 0 || workers > len(targets) {
+		workers = len(targets)
+	}
+	timeout := r.globalParams.Get(ParamTargetTimeout).AsDuration()
+
+	next := make(chan int)
 	wg := sync.WaitGroup{}
-	for _, t := range targets {
+	for range workers {
 		wg.Add(1)
-		go func(target target) {
+		go func() {
 			defer wg.Done()
-			conn, err := r.getConnFromTarget(ctx, runtimeParams, target)
-			if err != nil {
-				merrMutex.Lock()
-				errs = append(errs, fmt.Errorf("connecting to target %q: %w", target.node, err))
-				merrMutex.Unlock()
-				return
-			}
-			client := api.NewGadgetInstanceManagerClient(conn)
-			err = fn(target, client)
-			if err != nil {
-				merrMutex.Lock()
-				errs = append(errs, fmt.Errorf("executing on target %q: %w", target.node, err))
-				merrMutex.Unlock()
+			for i := range next {
+				errs[i] = r.runOnTarget(ctx, runtimeParams, targets[i], timeout, fn)
 			}
-		}(t)
+		}()
 	}
+	for i := range targets {
+		next <- i
+	}
+	close(next)
 	wg.Wait()
-	return errors.Join(errs...)
+	return errs
+}
+
+// runOnTarget connects to the target and runs fn on it, retrying transient failures with an exponential backoff as
+// configured by ParamTargetRetries and ParamTargetRetryDelay; if timeout is set, all attempts have to be done within
+// it
+func (r *Runtime) runOnTarget(ctx context.Context, runtimeParams *params.Params, target target, timeout time.Duration, fn func(ctx context.Context, target target, conn *grpc.ClientConn) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	retries := int(r.globalParams.Get(ParamTargetRetries).AsUint32())
+	delay := r.globalParams.Get(ParamTargetRetryDelay).AsDuration()
+	for attempt := 0; ; attempt++ {
+		transient, err := r.tryOnTarget(ctx, runtimeParams, target, fn)
+		if err == nil || !transient || attempt >= retries || ctx.Err() != nil {
+			return err
+		}
+
+		wait := retryDelay(delay, attempt)
+		log.Debugf("retrying target %q in %s: %v", target.name(), wait, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// tryOnTarget connects to the target and runs fn on it once; it also returns whether a failure is transient and thus
+// worth retrying
+func (r *Runtime) tryOnTarget(ctx context.Context, runtimeParams *params.Params, target target, fn func(ctx context.Context, target target, conn *grpc.ClientConn) error) (bool, error) {
+	conn, err := r.getConnFromTarget(ctx, runtimeParams, target)
+	if err != nil {
+		// Nothing was sent yet, so it's always safe to try again
+		return true, fmt.Errorf("connecting to target %q: %w", target.name(), err)
+	}
+	defer conn.Close()
+	err = fn(ctx, target, conn)
+	if err != nil {
+		return isTransient(err), fmt.Errorf("executing on target %q: %w", target.name(), err)
+	}
+	return false, nil
+}
+
+// permanentError marks an error that must not be retried, even if its code says it's transient; this is used for
+// calls that aren't idempotent, as the server could have handled them already
+type permanentError struct {
+	error
+}
+
+func (err permanentError) Unwrap() error {
+	return err.error
+}
+
+// isTransient returns whether err is a gRPC error that could go away by trying again
+func isTransient(err error) bool {
+	if errors.As(err, &permanentError{}) {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns the time to wait before the given retry attempt (starting at 0): the delay is doubled for each
+// attempt up to MaxRetryDelay, and a random jitter of up to half of it is subtracted so that retries on several
+// targets don't happen all at once
+func retryDelay(delay time.Duration, attempt int) time.Duration {
+	for range attempt {
+		if delay >= MaxRetryDelay/2 {
+			delay = MaxRetryDelay
+			break
+		}
+		delay *= 2
+	}
+	delay = min(delay, MaxRetryDelay)
+	if delay <= 0 {
+		return 0
+	}
+	return delay - rand.N(delay/2+1)
+}
+
+// parseTolerations parses a comma-separated list of tolerations given as
+// key[=value][:effect]; a toleration without a value tolerates all values of the
+// key, and "*" tolerates all taints
+func parseTolerations(value string) ([]*api.Toleration, error) {
+	var tolerations []*api.Toleration
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		toleration := &api.Toleration{}
+		spec, effect, hasEffect := strings.Cut(s, ":")
+		if hasEffect {
+			switch effect {
+			case "NoSchedule", "PreferNoSchedule", "NoExecute":
+				toleration.Effect = effect
+			default:
+				return nil, fmt.Errorf("invalid toleration %q: unknown effect %q", s, effect)
+			}
+		}
+		key, val, hasValue := strings.Cut(spec, "=")
+		switch {
+		case key == "*" && !hasValue:
+			toleration.Operator = "Exists"
+		case key == "":
+			return nil, fmt.Errorf("invalid toleration %q: missing key", s)
+		case hasValue:
+			toleration.Key = key
+			toleration.Operator = "Equal"
+			toleration.Value = val
+		default:
+			toleration.Key = key
+			toleration.Operator = "Exists"
+		}
+		tolerations = append(tolerations, toleration)
+	}
+	return tolerations, nil
+}
+
+// formatTolerations formats tolerations the way parseTolerations reads them
+func formatTolerations(tolerations []*api.Toleration) string {
+	res := make([]string, 0, len(tolerations))
+	for _, t := range tolerations {
+		var s string
+		switch {
+		case t.Key == "":
+			s = "*"
+		case t.Operator == "Exists":
+			s = t.Key
+		default:
+			s = t.Key + "=" + t.Value
+		}
+		if t.Effect != "" {
+			s += ":" + t.Effect
+		}
+		res = append(res, s)
+	}
+	return strings.Join(res, ",")
 }
 
 func (r *Runtime) createGadgetInstance(gadgetCtx runtime.GadgetContext, runtimeParams *params.Params, paramValues map[string]string) error {
@@ -173,10 +706,31 @@ func (r *Runtime) createGadgetInstance(gadgetCtx runtime.GadgetContext, runtimeP
 		}
 	}
 
-	if instanceName == "" {
+	generatedName := instanceName == ""
+	if generatedName {
 		instanceName = namesgenerator.GetRandomName(0)
 	}
 
+	if r.connectionMode == ConnectionModeDirect {
+		// Each ig daemon only checks the names of its own instances, so look
+		// at the ones of all daemons
+		instances, err := r.GetGadgetInstances(gadgetCtx.Context(), runtimeParams, nil)
+		if err != nil && !errors.As(err, new(*MultiNodeError)) {
+			return fmt.Errorf("checking gadget instance name: %w", err)
+		}
+		taken := func(name string) bool {
+			return slices.ContainsFunc(instances, func(gi *api.GadgetInstance) bool {
+				return gi.Name == name && gi.Id != instanceID
+			})
+		}
+		for retry := 1; taken(instanceName); retry++ {
+			if !generatedName {
+				return fmt.Errorf("gadget instance with name %q already exists", instanceName)
+			}
+			instanceName = namesgenerator.GetRandomName(retry)
+		}
+	}
+
 	instanceRequest := &api.CreateGadgetInstanceRequest{
 		GadgetInstance: &api.GadgetInstance{
 			Id:   instanceID,
@@ -191,31 +745,114 @@ func (r *Runtime) createGadgetInstance(gadgetCtx runtime.GadgetContext, runtimeP
 		EventBufferLength: runtimeParams.Get(ParamEventBufferLength).AsInt32(), // default for now
 	}
 
+	if p := runtimeParams.Get(ParamNodeSelector); p != nil {
+		instanceRequest.GadgetInstance.NodeSelector = p.AsString()
+	}
+	if p := runtimeParams.Get(ParamTolerations); p != nil {
+		instanceRequest.GadgetInstance.Tolerations, err = parseTolerations(p.AsString())
+		if err != nil {
+			return err
+		}
+	}
+
+	if p := runtimeParams.Get(ParamTTL); p != nil {
+		ttl := p.AsDuration()
+		if ttl < 0 {
+			return fmt.Errorf("ttl must not be negative")
+		}
+		// Round up so that TTLs below a second don't disable expiry
+		instanceRequest.GadgetInstance.Ttl = int64((ttl + time.Second - 1) / time.Second)
+	}
+
 	// if targets have explicitly been listed, add them to the `Nodes` list
 	if paramNode := runtimeParams.Get(ParamNode); paramNode != nil {
 		instanceRequest.GadgetInstance.Nodes = paramNode.AsStringSlice()
 	}
 
+	onNodeFailure := OnNodeFailureFail
+	if p := runtimeParams.Get(ParamOnNodeFailure); p != nil {
+		onNodeFailure = p.AsString()
+	}
+
+	targets, err := r.selectTargets(gadgetCtx.Context(), runtimeParams, false)
+	if err != nil {
+		return fmt.Errorf("creating gadget instance: %w", err)
+	}
+
 	var listMutex sync.Mutex
-	var nodeList []string
 	ids := make(map[string][]string)
+	targetIDs := make(map[target]string)
 	var lastID string
 
-	err = r.runInstanceManagerClientForTargets(gadgetCtx.Context(), runtimeParams, false, func(target target, client api.GadgetInstanceManagerClient) error {
-		gadgetCtx.Logger().Debugf("creating gadget on node %q", target.node)
-		res, err := client.CreateGadgetInstance(gadgetCtx.Context(), instanceRequest)
+	traceCtx, span := tracing.Tracer().Start(gadgetCtx.Context(), "create gadget instance", trace.WithAttributes(
+		attribute.String("gadget_image", gadgetCtx.ImageName()),
+		attribute.Int("targets", len(targets)),
+	))
+	defer span.End()
+
+	errs := r.runForEachTarget(traceCtx, runtimeParams, targets, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		gadgetCtx.Logger().Debugf("creating gadget on node %q", target.name())
+		ctx, nodeSpan := tracing.Tracer().Start(ctx, "create gadget instance on node", trace.WithAttributes(
+			attribute.String("node", target.name()),
+		))
+		defer nodeSpan.End()
+		res, err := api.NewGadgetInstanceManagerClient(conn).CreateGadgetInstance(ctx, instanceRequest)
 		if err != nil {
-			return fmt.Errorf("creating gadget on node %q: %w", target.node, err)
+			nodeSpan.RecordError(err)
+			nodeSpan.SetStatus(otelcodes.Error, err.Error())
+			// The instance could have been created even if we didn't get an answer
+			return permanentError{fmt.Errorf("creating gadget on node %q: %w", target.name(), err)}
 		}
 		listMutex.Lock()
-		nodeList = append(nodeList, target.node)
-		ids[res.GadgetInstance.Id] = append(ids[res.GadgetInstance.Id], target.node)
+		ids[res.GadgetInstance.Id] = append(ids[res.GadgetInstance.Id], target.name())
+		targetIDs[target] = res.GadgetInstance.Id
 		lastID = res.GadgetInstance.Id
 		listMutex.Unlock()
 		return nil
 	})
-	if err != nil {
-		return fmt.Errorf("creating gadget instance: %w", err)
+
+	var succeeded []target
+	for i, t := range targets {
+		if errs[i] == nil {
+			succeeded = append(succeeded, t)
+		}
+	}
+	if len(succeeded) < len(targets) {
+		err := newMultiNodeError(targetNodes(targets), errs)
+		if len(succeeded) == 0 {
+			return fmt.Errorf("creating gadget instance: %w", err)
+		}
+
+		for i, t := range targets {
+			if errs[i] != nil {
+				gadgetCtx.Logger().Warnf("%-20s | failed: %v", t.name(), errs[i])
+			} else {
+				gadgetCtx.Logger().Infof("%-20s | created", t.name())
+			}
+		}
+
+		switch onNodeFailure {
+		case OnNodeFailureRollback:
+			gadgetCtx.Logger().Infof("removing gadget instance from the %d node(s) it was created on", len(succeeded))
+			rollbackErrs := r.runForEachTarget(gadgetCtx.Context(), runtimeParams, succeeded, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+				res, err := api.NewGadgetInstanceManagerClient(conn).RemoveGadgetInstance(ctx, &api.GadgetInstanceId{Id: targetIDs[target]})
+				if err != nil {
+					return err
+				}
+				if res.Result != 0 {
+					return errors.New(res.Message)
+				}
+				return nil
+			})
+			if rollbackErr := newMultiNodeError(targetNodes(succeeded), rollbackErrs); rollbackErr != nil {
+				return fmt.Errorf("creating gadget instance: %w; rolling back: %w", err, rollbackErr)
+			}
+			return fmt.Errorf("creating gadget instance (rolled back): %w", err)
+		case OnNodeFailureContinue:
+			gadgetCtx.Logger().Warnf("gadget instance created on %d of %d nodes", len(succeeded), len(targets))
+		default:
+			return fmt.Errorf("creating gadget instance: %w", err)
+		}
 	}
 
 	if len(ids) > 1 {
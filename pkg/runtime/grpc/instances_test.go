@@ -0,0 +1,582 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/inspektor-gadget/inspektor-gadget/internal/version"
+	gadgetmanifest "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-manifest"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func newInstance(id string, status api.GadgetInstanceStatus, node string) *api.GadgetInstance {
+	return &api.GadgetInstance{
+		Id:    id,
+		Name:  node,
+		State: &api.GadgetInstanceState{Status: status},
+	}
+}
+
+func TestMergeGadgetInstances(t *testing.T) {
+	stale := &api.GadgetInstance{Id: "aaa", Name: "node3"}
+	instances := []*api.GadgetInstance{
+		newInstance("bbb", api.GadgetInstanceStatus_StatusRunning, "node1"),
+		stale,
+		newInstance("aaa", api.GadgetInstanceStatus_StatusRunning, "node1"),
+		newInstance("aaa", api.GadgetInstanceStatus_StatusError, "node2"),
+		newInstance("bbb", api.GadgetInstanceStatus_StatusRunning, "node2"),
+		{Id: "ccc", Name: "node1"},
+	}
+
+	merged := mergeGadgetInstances(instances)
+	require.Len(t, merged, 3)
+
+	// Errors are preferred over running instances and those over missing states
+	assert.Equal(t, "aaa", merged[0].Id)
+	assert.Equal(t, api.GadgetInstanceStatus_StatusError, merged[0].State.Status)
+	assert.Equal(t, "bbb", merged[1].Id)
+	assert.Equal(t, "node1", merged[1].Name)
+	assert.Equal(t, "ccc", merged[2].Id)
+	assert.Nil(t, merged[2].State)
+}
+
+func TestHasVersionSkew(t *testing.T) {
+	assert.False(t, hasVersionSkew(""))
+	assert.False(t, hasVersionSkew("not-a-version"))
+	assert.False(t, hasVersionSkew(version.Version().String()))
+	assert.False(t, hasVersionSkew("v"+version.Version().String()))
+	assert.True(t, hasVersionSkew("99.0.0"))
+}
+
+func TestGadgetInstancesResultUnreachable(t *testing.T) {
+	res := &GadgetInstancesResult{
+		Nodes: []*NodeReport{
+			{Node: "node1", Reachable: true},
+			{Node: "node2"},
+			{Node: "node3", Reachable: true},
+		},
+	}
+	unreachable := res.Unreachable()
+	require.Len(t, unreachable, 1)
+	assert.Equal(t, "node2", unreachable[0].Node)
+}
+
+type slowInstanceManager struct {
+	api.UnimplementedGadgetInstanceManagerServer
+	delay      time.Duration
+	running    atomic.Int32
+	maxRunning atomic.Int32
+}
+
+func (s *slowInstanceManager) ListGadgetInstances(ctx context.Context, _ *api.ListGadgetInstancesRequest) (*api.ListGadgetInstanceResponse, error) {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		m := s.maxRunning.Load()
+		if n <= m || s.maxRunning.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &api.ListGadgetInstanceResponse{}, nil
+}
+
+func startInstanceManager(t *testing.T, srv api.GadgetInstanceManagerServer) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	api.RegisterGadgetInstanceManagerServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func listOnTargets(r *Runtime, targets []target) []error {
+	return r.runForEachTarget(context.Background(), nil, targets, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		_, err := api.NewGadgetInstanceManagerClient(conn).ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{})
+		return err
+	})
+}
+
+func TestRunForEachTargetParallelism(t *testing.T) {
+	srv := &slowInstanceManager{delay: 50 * time.Millisecond}
+	addr := startInstanceManager(t, srv)
+
+	var targets []target
+	for i := range 6 {
+		targets = append(targets, target{addressOrPod: addr, node: fmt.Sprintf("node%d", i)})
+	}
+
+	r := New()
+	require.NoError(t, r.Init(nil))
+	require.NoError(t, r.globalParams.Set(ParamMaxParallel, "2"))
+
+	for _, err := range listOnTargets(r, targets) {
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), srv.maxRunning.Load())
+}
+
+func TestRunForEachTargetTimeout(t *testing.T) {
+	fast := startInstanceManager(t, &slowInstanceManager{})
+	slow := startInstanceManager(t, &slowInstanceManager{delay: 10 * time.Second})
+
+	r := New()
+	require.NoError(t, r.Init(nil))
+	require.NoError(t, r.globalParams.Set(ParamMaxParallel, "0"))
+	require.NoError(t, r.globalParams.Set(ParamTargetTimeout, "200ms"))
+
+	start := time.Now()
+	errs := listOnTargets(r, []target{
+		{addressOrPod: fast, node: "fast"},
+		{addressOrPod: slow, node: "slow"},
+	})
+	assert.Less(t, time.Since(start), 5*time.Second)
+	require.NoError(t, errs[0])
+	require.Error(t, errs[1])
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(errors.Unwrap(errs[1])))
+}
+
+type flakyInstanceManager struct {
+	api.UnimplementedGadgetInstanceManagerServer
+	failures int32
+	code     codes.Code
+	calls    atomic.Int32
+}
+
+func (s *flakyInstanceManager) ListGadgetInstances(context.Context, *api.ListGadgetInstancesRequest) (*api.ListGadgetInstanceResponse, error) {
+	if s.calls.Add(1) <= s.failures {
+		return nil, status.Error(s.code, "flaky")
+	}
+	return &api.ListGadgetInstanceResponse{}, nil
+}
+
+func TestRunForEachTargetRetries(t *testing.T) {
+	type testCase struct {
+		name          string
+		failures      int32
+		code          codes.Code
+		retries       string
+		permanent     bool
+		expectedCalls int32
+		expectedErr   bool
+	}
+
+	tests := []testCase{
+		{
+			name:          "recovers",
+			failures:      2,
+			code:          codes.Unavailable,
+			retries:       "2",
+			expectedCalls: 3,
+		},
+		{
+			name:          "deadline_exceeded",
+			failures:      1,
+			code:          codes.DeadlineExceeded,
+			retries:       "2",
+			expectedCalls: 2,
+		},
+		{
+			name:          "retries_exhausted",
+			failures:      5,
+			code:          codes.Unavailable,
+			retries:       "2",
+			expectedCalls: 3,
+			expectedErr:   true,
+		},
+		{
+			name:          "retries_disabled",
+			failures:      1,
+			code:          codes.Unavailable,
+			retries:       "0",
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+		{
+			name:          "not_transient",
+			failures:      1,
+			code:          codes.InvalidArgument,
+			retries:       "2",
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+		{
+			name:          "permanent",
+			failures:      1,
+			code:          codes.Unavailable,
+			retries:       "2",
+			permanent:     true,
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := &flakyInstanceManager{failures: tc.failures, code: tc.code}
+			addr := startInstanceManager(t, srv)
+
+			r := New()
+			require.NoError(t, r.Init(nil))
+			require.NoError(t, r.globalParams.Set(ParamTargetRetries, tc.retries))
+			require.NoError(t, r.globalParams.Set(ParamTargetRetryDelay, "1ms"))
+
+			errs := r.runForEachTarget(context.Background(), nil, []target{{addressOrPod: addr, node: "node"}}, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+				_, err := api.NewGadgetInstanceManagerClient(conn).ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{})
+				if err != nil && tc.permanent {
+					return permanentError{err}
+				}
+				return err
+			})
+			if tc.expectedErr {
+				require.Error(t, errs[0])
+				assert.Equal(t, tc.code, status.Code(errs[0]))
+			} else {
+				require.NoError(t, errs[0])
+			}
+			assert.Equal(t, tc.expectedCalls, srv.calls.Load())
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt, expected := range []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+	} {
+		for range 10 {
+			d := retryDelay(100*time.Millisecond, attempt)
+			assert.LessOrEqual(t, d, expected)
+			assert.GreaterOrEqual(t, d, expected/2)
+		}
+	}
+
+	// The delay is capped
+	d := retryDelay(time.Second, 20)
+	assert.LessOrEqual(t, d, MaxRetryDelay)
+	assert.GreaterOrEqual(t, d, MaxRetryDelay/2)
+
+	assert.Zero(t, retryDelay(0, 3))
+}
+
+func TestParseTolerations(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []*api.Toleration
+		err      bool
+	}{
+		{value: "", expected: nil},
+		{value: "*", expected: []*api.Toleration{{Operator: "Exists"}}},
+		{
+			value: "node-role.kubernetes.io/control-plane:NoSchedule, dedicated=gadgets",
+			expected: []*api.Toleration{
+				{Key: "node-role.kubernetes.io/control-plane", Operator: "Exists", Effect: "NoSchedule"},
+				{Key: "dedicated", Operator: "Equal", Value: "gadgets"},
+			},
+		},
+		{value: "dedicated=:NoExecute", expected: []*api.Toleration{{Key: "dedicated", Operator: "Equal", Effect: "NoExecute"}}},
+		{value: "dedicated:NoRun", err: true},
+		{value: "=gadgets", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			tolerations, err := parseTolerations(test.value)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, tolerations)
+		})
+	}
+}
+
+func TestFormatTolerations(t *testing.T) {
+	t.Parallel()
+
+	for _, value := range []string{"", "*", "*:NoExecute", "dedicated", "dedicated=gadgets:NoSchedule,node.kubernetes.io/disk-pressure"} {
+		t.Run(value, func(t *testing.T) {
+			tolerations, err := parseTolerations(value)
+			require.NoError(t, err)
+			assert.Equal(t, value, formatTolerations(tolerations))
+		})
+	}
+}
+
+func TestInstanceToSpec(t *testing.T) {
+	t.Parallel()
+
+	gi := &api.GadgetInstance{
+		Id:   "0123456789abcdef0123456789abcdef",
+		Name: "exec",
+		Tags: []string{""},
+		GadgetConfig: &api.GadgetRunRequest{
+			ImageName:   "trace_exec:latest",
+			ParamValues: map[string]string{"operator.filter.filter": "proc.comm==cat"},
+		},
+		Nodes:        []string{"node1"},
+		NodeSelector: "node-role.kubernetes.io/worker=true",
+		Tolerations:  []*api.Toleration{{Key: "dedicated", Operator: "Equal", Value: "gadgets", Effect: "NoSchedule"}},
+		Ttl:          7200,
+		TimeCreated:  1700000000,
+		State:        &api.GadgetInstanceState{Status: api.GadgetInstanceStatus_StatusRunning},
+	}
+	spec := instanceToSpec(gi)
+
+	var buf bytes.Buffer
+	require.NoError(t, gadgetmanifest.WriteInstanceSpecs(&buf, []*gadgetmanifest.InstanceSpec{spec}))
+	specs, err := gadgetmanifest.InstanceSpecsFromReader(&buf)
+	require.NoError(t, err)
+	require.Len(t, specs, 1)
+
+	assert.Equal(t, &gadgetmanifest.InstanceSpec{
+		APIVersion:   gadgetmanifest.APIVersion,
+		Kind:         gadgetmanifest.KindInstanceSpec,
+		Image:        "trace_exec:latest",
+		ID:           "0123456789abcdef0123456789abcdef",
+		Name:         "exec",
+		Tags:         []string{},
+		Nodes:        []string{"node1"},
+		ParamValues:  map[string]string{"operator.filter.filter": "proc.comm==cat"},
+		NodeSelector: "node-role.kubernetes.io/worker=true",
+		Tolerations:  "dedicated=gadgets:NoSchedule",
+		TTL:          "2h0m0s",
+	}, specs[0])
+}
+
+func TestMultiNodeError(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, newMultiNodeError([]string{"node1", "node2"}, []error{nil, nil}))
+
+	errNode2 := errors.New("node2 failed")
+	errNode3 := errors.New("node3 failed")
+	err := newMultiNodeError([]string{"node1", "node2", "node3"}, []error{nil, errNode2, errNode3})
+	require.Error(t, err)
+
+	var multiErr *MultiNodeError
+	require.ErrorAs(t, fmt.Errorf("wrapped: %w", err), &multiErr)
+	assert.Equal(t, []string{"node2", "node3"}, multiErr.Nodes())
+	assert.Equal(t, map[string]error{"node2": errNode2, "node3": errNode3}, multiErr.Errors())
+	assert.False(t, multiErr.AllFailed())
+	assert.ErrorIs(t, err, errNode3)
+	assert.Equal(t, errors.Join(errNode2, errNode3).Error(), err.Error())
+
+	err = newMultiNodeError([]string{"node1", "node2"}, []error{errNode2, errNode3})
+	require.ErrorAs(t, err, &multiErr)
+	assert.True(t, multiErr.AllFailed())
+}
+
+func TestRemoveGadgetInstanceMultiNodeError(t *testing.T) {
+	addr1 := startInstanceManager(t, newMemInstanceManager(&api.GadgetInstance{Id: "aaa"}))
+	addr2 := startInstanceManager(t, newMemInstanceManager())
+	// Use different host names, so both servers are different nodes
+	_, port2, _ := strings.Cut(addr2, ":")
+
+	r := New()
+	require.NoError(t, r.Init(nil))
+	require.NoError(t, r.globalParams.Set(ParamRemoteAddress, "tcp://"+addr1+",tcp://localhost:"+port2))
+	require.NoError(t, r.globalParams.Set(ParamTargetRetries, "0"))
+
+	err := r.RemoveGadgetInstance(context.Background(), nil, "aaa")
+	var multiErr *MultiNodeError
+	require.ErrorAs(t, err, &multiErr)
+	assert.Equal(t, []string{"localhost"}, multiErr.Nodes())
+	assert.False(t, multiErr.AllFailed())
+	assert.ErrorContains(t, multiErr.Errors()["localhost"], "not found")
+}
+
+func TestFindGadgetInstance(t *testing.T) {
+	t.Parallel()
+
+	instances := []*api.GadgetInstance{
+		{Id: "4f5ae12c54bd7c2058c0484ebd13dbc2", Name: "exec"},
+		{Id: "61c8fdd9b75e1aec3c242347f18cf854", Name: "exec-prod"},
+		{Id: "61c8fdd9b75e1aec3c242347f18cf855", Name: "open"},
+		{Id: "0123456789abcdef0123456789abcdef", Name: "61c8fdd9b75e1aec3c242347f18cf855"},
+	}
+
+	tests := []struct {
+		name        string
+		idOrName    string
+		expectedID  string
+		expectedErr error
+	}{
+		{name: "id_before_name", idOrName: "61c8fdd9b75e1aec3c242347f18cf855", expectedID: "61c8fdd9b75e1aec3c242347f18cf855"},
+		{name: "name", idOrName: "exec", expectedID: "4f5ae12c54bd7c2058c0484ebd13dbc2"},
+		{name: "id_prefix", idOrName: "4f5a", expectedID: "4f5ae12c54bd7c2058c0484ebd13dbc2"},
+		{name: "name_prefix", idOrName: "op", expectedID: "61c8fdd9b75e1aec3c242347f18cf855"},
+		{name: "ambiguous_id_prefix", idOrName: "61c8", expectedErr: ErrAmbiguousGadgetInstance},
+		{name: "ambiguous_name_prefix", idOrName: "exec-", expectedID: "61c8fdd9b75e1aec3c242347f18cf854"},
+		{name: "not_found", idOrName: "close", expectedErr: ErrGadgetInstanceNotFound},
+		{name: "empty", idOrName: "", expectedErr: ErrGadgetInstanceNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gi, err := FindGadgetInstance(instances, test.idOrName)
+			if test.expectedErr != nil {
+				require.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedID, gi.Id)
+		})
+	}
+
+	_, err := FindGadgetInstance(append(instances, &api.GadgetInstance{Id: "fedcba9876543210fedcba9876543210", Name: "exec"}), "exec")
+	require.ErrorIs(t, err, ErrAmbiguousGadgetInstance)
+}
+
+func TestGadgetInstanceByName(t *testing.T) {
+	node1 := newMemInstanceManager(&api.GadgetInstance{Id: "4f5ae12c54bd7c2058c0484ebd13dbc2", Name: "exec"})
+	node2 := newMemInstanceManager(&api.GadgetInstance{Id: "4f5ae12c54bd7c2058c0484ebd13dbc2", Name: "exec"})
+	addr1 := startInstanceManager(t, node1)
+	addr2 := startInstanceManager(t, node2)
+	_, port2, _ := strings.Cut(addr2, ":")
+
+	r := New()
+	require.NoError(t, r.Init(nil))
+	require.NoError(t, r.globalParams.Set(ParamRemoteAddress, "tcp://"+addr1+",tcp://localhost:"+port2))
+	require.NoError(t, r.globalParams.Set(ParamTargetRetries, "0"))
+
+	states, err := r.GetNodeInstanceStates(context.Background(), nil, "ex")
+	require.NoError(t, err)
+	assert.Len(t, states, 2)
+
+	_, err = r.GetNodeInstanceStates(context.Background(), nil, "open")
+	require.ErrorIs(t, err, ErrGadgetInstanceNotFound)
+
+	require.ErrorIs(t, r.RemoveGadgetInstance(context.Background(), nil, "open"), ErrGadgetInstanceNotFound)
+	require.NoError(t, r.RemoveGadgetInstance(context.Background(), nil, "exec"))
+	assert.Empty(t, node1.ids())
+	assert.Empty(t, node2.ids())
+}
+
+func TestParseRemoteAddresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		expected  []target
+		err       string
+	}{
+		{
+			name:      "tcp",
+			addresses: []string{"tcp://10.0.0.1:8888", "tcp://node2:8888?node=worker"},
+			expected: []target{
+				{addressOrPod: "10.0.0.1:8888", node: "10.0.0.1"},
+				{addressOrPod: "node2:8888", node: "worker"},
+			},
+		},
+		{
+			name:      "single unix socket",
+			addresses: []string{"unix:///run/ig.sock"},
+			expected:  []target{{addressOrPod: "unix:///run/ig.sock", node: "local"}},
+		},
+		{
+			name:      "several unix sockets",
+			addresses: []string{"unix:///run/vm1/ig.sock", "unix:///run/vm2/ig.sock?node=vm2"},
+			expected: []target{
+				{addressOrPod: "unix:///run/vm1/ig.sock", node: "/run/vm1/ig.sock"},
+				{addressOrPod: "unix:///run/vm2/ig.sock", node: "vm2"},
+			},
+		},
+		{
+			name:      "vsock",
+			addresses: []string{"vsock://3:8888", "vsock://4:8888?node=vm4"},
+			expected: []target{
+				{addressOrPod: "vsock://3:8888", node: "vsock-3"},
+				{addressOrPod: "vsock://4:8888", node: "vm4"},
+			},
+		},
+		{
+			name:      "invalid vsock context id",
+			addresses: []string{"vsock://vm:8888"},
+			err:       "invalid vsock context id",
+		},
+		{
+			name:      "missing vsock port",
+			addresses: []string{"vsock://3"},
+			err:       "invalid vsock port",
+		},
+		{
+			name:      "missing unix path",
+			addresses: []string{"unix://"},
+			err:       "missing host or path",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := parseRemoteAddresses(tt.addresses)
+			if tt.err != "" {
+				require.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, targets)
+		})
+	}
+}
+
+func TestUnixSocketTargets(t *testing.T) {
+	dir := t.TempDir()
+	var addresses []string
+	for i, srv := range []api.GadgetInstanceManagerServer{
+		newMemInstanceManager(&api.GadgetInstance{Id: "aaa"}),
+		newMemInstanceManager(&api.GadgetInstance{Id: "bbb"}),
+	} {
+		path := fmt.Sprintf("%s/ig%d.sock", dir, i)
+		lis, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		s := grpc.NewServer()
+		api.RegisterGadgetInstanceManagerServer(s, srv)
+		go s.Serve(lis)
+		t.Cleanup(s.Stop)
+		addresses = append(addresses, "unix://"+path)
+	}
+
+	r := New()
+	require.NoError(t, r.Init(nil))
+	require.NoError(t, r.globalParams.Set(ParamRemoteAddress, strings.Join(addresses, ",")))
+
+	instances, err := r.GetGadgetInstances(context.Background(), nil, nil)
+	require.NoError(t, err)
+	var ids []string
+	for _, instance := range instances {
+		ids = append(ids, instance.Id)
+	}
+	assert.ElementsMatch(t, []string{"aaa", "bbb"}, ids)
+}
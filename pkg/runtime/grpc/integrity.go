@@ -0,0 +1,106 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"fmt"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/integrity"
+	metadatav1 "github.com/inspektor-gadget/inspektor-gadget/pkg/metadata/v1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+)
+
+// IntegrityDataSource is the data source the verification errors are emitted
+// on when the checksums of the events are verified
+const IntegrityDataSource = "integrity_errors"
+
+var integrityFields = []struct {
+	name        string
+	kind        api.Kind
+	description string
+}{
+	{"node", api.Kind_String, "Node that sent the events"},
+	{"firstSeq", api.Kind_Uint32, "Sequence number of the first event of the batch"},
+	{"lastSeq", api.Kind_Uint32, "Sequence number of the last event of the batch"},
+	{"reason", api.Kind_String, "Why the verification failed"},
+}
+
+// addIntegrityDataSource adds the data source of the verification errors to
+// gi; it doesn't exist on the server, so no events are received for it
+func addIntegrityDataSource(gi *api.GadgetInfo) error {
+	id := uint32(0)
+	for _, ds := range gi.DataSources {
+		if ds.Name == IntegrityDataSource {
+			return fmt.Errorf("gadget already has a data source named %q", IntegrityDataSource)
+		}
+		id = max(id, ds.Id+1)
+	}
+
+	ds, err := datasource.New(datasource.TypeSingle, IntegrityDataSource)
+	if err != nil {
+		return err
+	}
+	for _, f := range integrityFields {
+		_, err := ds.AddField(f.name, f.kind, datasource.WithAnnotations(map[string]string{
+			metadatav1.DescriptionAnnotation: f.description,
+		}))
+		if err != nil {
+			return fmt.Errorf("adding field %q: %w", f.name, err)
+		}
+	}
+	gi.DataSources = append(gi.DataSources, &api.DataSource{
+		Id:     id,
+		Type:   uint32(ds.Type()),
+		Name:   ds.Name(),
+		Fields: ds.Fields(),
+	})
+	return nil
+}
+
+// dsReporter emits verification errors on the IntegrityDataSource of the
+// gadget
+type dsReporter struct {
+	gadgetCtx runtime.GadgetContext
+}
+
+func (r *dsReporter) ReportVerificationError(verr *integrity.VerificationError) {
+	if !r.emit(verr) {
+		r.gadgetCtx.Logger().Warnf("%-20s | integrity check failed: %s", verr.Node, verr.JSON())
+	}
+}
+
+func (r *dsReporter) emit(verr *integrity.VerificationError) bool {
+	ds, ok := r.gadgetCtx.GetDataSources()[IntegrityDataSource]
+	if !ok {
+		return false
+	}
+	node, firstSeq, lastSeq, reason := ds.GetField("node"), ds.GetField("firstSeq"), ds.GetField("lastSeq"), ds.GetField("reason")
+	if node == nil || firstSeq == nil || lastSeq == nil || reason == nil {
+		// The data source is the gadget's own one
+		return false
+	}
+	p, err := ds.NewPacketSingle()
+	if err != nil {
+		return false
+	}
+	node.PutString(p, verr.Node)
+	firstSeq.PutUint32(p, verr.FirstSeq)
+	lastSeq.PutUint32(p, verr.LastSeq)
+	reason.PutString(p, verr.Reason)
+	ds.EmitAndRelease(p)
+	return true
+}
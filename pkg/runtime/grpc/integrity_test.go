@@ -0,0 +1,64 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/integrity"
+)
+
+func TestIntegrityReporter(t *testing.T) {
+	gi := &api.GadgetInfo{
+		DataSources: []*api.DataSource{
+			{Id: 0, Name: "events", Type: uint32(datasource.TypeSingle)},
+			{Id: 1, Name: "snapshot", Type: uint32(datasource.TypeArray)},
+		},
+	}
+	require.NoError(t, addIntegrityDataSource(gi))
+	require.Len(t, gi.DataSources, 3)
+	assert.Equal(t, uint32(2), gi.DataSources[2].Id)
+
+	// A gadget can't have it already
+	require.Error(t, addIntegrityDataSource(gi))
+
+	gadgetCtx := gadgetcontext.New(context.Background(), "test")
+	require.NoError(t, gadgetCtx.LoadGadgetInfo(gi, nil, false, nil))
+
+	ds := gadgetCtx.GetDataSources()[IntegrityDataSource]
+	require.NotNil(t, ds)
+	var got []*integrity.VerificationError
+	ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+		verr := &integrity.VerificationError{}
+		verr.Node, _ = ds.GetField("node").String(data)
+		verr.FirstSeq, _ = ds.GetField("firstSeq").Uint32(data)
+		verr.LastSeq, _ = ds.GetField("lastSeq").Uint32(data)
+		verr.Reason, _ = ds.GetField("reason").String(data)
+		got = append(got, verr)
+		return nil
+	}, 0)
+
+	verr := &integrity.VerificationError{Node: "node1", FirstSeq: 1, LastSeq: 10, Reason: "checksum mismatch"}
+	reporter := &dsReporter{gadgetCtx: gadgetCtx}
+	reporter.ReportVerificationError(verr)
+	assert.Equal(t, []*integrity.VerificationError{verr}, got)
+}
@@ -19,16 +19,24 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/compression"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/integrity"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/tracing"
 )
 
 func (r *Runtime) GetGadgetInfo(gadgetCtx runtime.GadgetContext, runtimeParams *params.Params, paramValues api.ParamValues) (*api.GadgetInfo, error) {
@@ -36,13 +44,38 @@ func (r *Runtime) GetGadgetInfo(gadgetCtx runtime.GadgetContext, runtimeParams *
 		runtimeParams = r.ParamDescs().ToParams()
 	}
 
-	conn, err := r.getConnToRandomTarget(gadgetCtx.Context(), runtimeParams)
+	gadgetCtx, err := r.resolveInstanceContext(gadgetCtx, runtimeParams)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := r.getTargets(gadgetCtx.Context(), runtimeParams)
+	if err != nil {
+		return nil, fmt.Errorf("getting target nodes: %w", err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no valid targets")
+	}
+
+	target := targets[0]
+	conn, err := r.getConnFromTarget(gadgetCtx.Context(), runtimeParams, target)
 	if err != nil {
 		return nil, fmt.Errorf("dialing random target: %w", err)
 	}
-	defer conn.Close()
+	keepConn := false
+	defer func() {
+		if !keepConn {
+			conn.Close()
+		}
+	}()
 	client := api.NewGadgetManagerClient(conn)
 
+	// Most of the time, the gadget is run right after getting its information;
+	// prepare the connections to the other targets in the meantime
+	if p := runtimeParams.Get(ParamDetach); p == nil || !p.AsBool() {
+		r.prewarm(runtimeParams, targets[1:])
+	}
+
 	in := &api.GetGadgetInfoRequest{
 		ParamValues:      paramValues,
 		ImageName:        gadgetCtx.ImageName(),
@@ -59,6 +92,12 @@ func (r *Runtime) GetGadgetInfo(gadgetCtx runtime.GadgetContext, runtimeParams *
 	if err != nil {
 		return nil, fmt.Errorf("getting gadget info: %w", err)
 	}
+	if p := runtimeParams.Get(ParamChecksumBatchSize); p != nil && p.AsUint32() > 0 {
+		if err := addIntegrityDataSource(out.GadgetInfo); err != nil {
+			return nil, err
+		}
+	}
+
 	extraInfo := &api.ExtraInfo{}
 
 	if gadgetCtx.ExtraInfo() {
@@ -70,9 +109,44 @@ func (r *Runtime) GetGadgetInfo(gadgetCtx runtime.GadgetContext, runtimeParams *
 		return nil, fmt.Errorf("initializing local operators: %w", err)
 	}
 
+	if p := runtimeParams.Get(ParamDetach); p == nil || !p.AsBool() {
+		r.pool.setTargets(targetsKey(runtimeParams), targets)
+		r.pool.put(target, conn)
+		keepConn = true
+	}
+
 	return gadgetCtx.SerializeGadgetInfo(gadgetCtx.ExtraInfo())
 }
 
+// ValidateGadget checks on all targets whether the gadget would run there, without starting it; the result is keyed
+// by the node name
+func (r *Runtime) ValidateGadget(gadgetCtx runtime.GadgetContext, runtimeParams *params.Params, paramValues api.ParamValues) (map[string]*api.ValidateGadgetResponse, error) {
+	if runtimeParams == nil {
+		runtimeParams = r.ParamDescs().ToParams()
+	}
+
+	in := &api.ValidateGadgetRequest{
+		ParamValues: paramValues,
+		ImageName:   gadgetCtx.ImageName(),
+	}
+
+	var mu sync.Mutex
+	res := make(map[string]*api.ValidateGadgetResponse)
+	err := r.runForTargets(gadgetCtx.Context(), runtimeParams, true, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		client := api.NewGadgetManagerClient(conn)
+		out, err := client.ValidateGadget(ctx, in)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		res[target.name()] = out
+		mu.Unlock()
+		return nil
+	})
+	return res, err
+}
+
 func (r *Runtime) RunGadget(gadgetCtx runtime.GadgetContext, runtimeParams *params.Params, paramValues api.ParamValues) error {
 	if runtimeParams == nil {
 		runtimeParams = r.ParamDescs().ToParams()
@@ -87,33 +161,176 @@ func (r *Runtime) RunGadget(gadgetCtx runtime.GadgetContext, runtimeParams *para
 		return r.createGadgetInstance(gadgetCtx, runtimeParams, paramValues)
 	}
 
-	targets, err := r.getTargets(gadgetCtx.Context(), runtimeParams)
+	return r.runOnTargets(gadgetCtx, runtimeParams, paramValues, time.Time{})
+}
+
+// AttachGadgetInstance streams the events of the running gadget instance whose
+// ID or name, see FindGadgetInstance, is the image name of gadgetCtx from all
+// the nodes it's running on.
+// Several clients can be attached to the same instance at the same time. The
+// events buffered by the nodes are replayed first, starting with the ones
+// received at since; a zero since replays all of them.
+func (r *Runtime) AttachGadgetInstance(gadgetCtx runtime.GadgetContext, runtimeParams *params.Params, since time.Time) error {
+	if !gadgetCtx.UseInstance() {
+		return errors.New("attaching to a gadget instance requires a gadget context using an instance")
+	}
+	if runtimeParams == nil {
+		runtimeParams = r.ParamDescs().ToParams()
+	}
+	gadgetCtx, err := r.resolveInstanceContext(gadgetCtx, runtimeParams)
 	if err != nil {
-		return fmt.Errorf("getting target nodes: %w", err)
+		return err
+	}
+	return r.runOnTargets(gadgetCtx, runtimeParams, nil, since)
+}
+
+// instanceGadgetContext replaces the image name of a gadget context using an
+// instance with the ID of the instance
+type instanceGadgetContext struct {
+	runtime.GadgetContext
+	id string
+}
+
+func (c *instanceGadgetContext) ImageName() string {
+	return c.id
+}
+
+// resolveInstanceContext returns a gadget context using the ID of the gadget
+// instance as image name if gadgetCtx refers to the instance by its name or a
+// prefix
+func (r *Runtime) resolveInstanceContext(gadgetCtx runtime.GadgetContext, runtimeParams *params.Params) (runtime.GadgetContext, error) {
+	if !gadgetCtx.UseInstance() || api.IsValidInstanceID(gadgetCtx.ImageName()) {
+		return gadgetCtx, nil
+	}
+	id, err := r.ResolveGadgetInstanceID(gadgetCtx.Context(), runtimeParams, gadgetCtx.ImageName())
+	if err != nil {
+		return nil, err
+	}
+	return &instanceGadgetContext{GadgetContext: gadgetCtx, id: id}, nil
+}
+
+func (r *Runtime) runOnTargets(gadgetCtx runtime.GadgetContext, runtimeParams *params.Params, paramValues api.ParamValues, since time.Time) error {
+	targets, ok := r.pool.takeTargets(targetsKey(runtimeParams))
+	if !ok {
+		var err error
+		targets, err = r.getTargets(gadgetCtx.Context(), runtimeParams)
+		if err != nil {
+			return fmt.Errorf("getting target nodes: %w", err)
+		}
 	}
 
 	gadgetCtx.SetVar(runtime.NumRunTargets, len(targets))
 
-	_, err = r.runGadgetOnTargets(gadgetCtx, paramValues, targets)
+	checksums, err := checksumConfigFromParams(runtimeParams)
+	if err != nil {
+		return err
+	}
+
+	opts := &streamOptions{
+		checksums: checksums,
+		codec:     compression.None,
+		since:     since,
+		drops:     &dropStats{dropped: make(map[datasource.DataSource]uint64)},
+	}
+	if p := runtimeParams.Get(ParamCompression); p != nil {
+		opts.codec = p.AsString()
+	}
+	if p := runtimeParams.Get(ParamNoCache); p != nil {
+		opts.noCache = p.AsBool()
+	}
+
+	_, err = r.runGadgetOnTargets(gadgetCtx, paramValues, targets, opts)
 	return err
 }
 
+// streamOptions holds the settings of the event streams of all targets
+type streamOptions struct {
+	checksums *checksumConfig
+	codec     string
+	noCache   bool
+	since     time.Time
+	drops     *dropStats
+}
+
+// dropStats sums up the events dropped by the targets because the client was
+// too slow
+type dropStats struct {
+	mu      sync.Mutex
+	dropped map[datasource.DataSource]uint64
+}
+
+// add counts the dropped events of ds and updates its DroppedEventsAnnotation
+func (s *dropStats) add(ds datasource.DataSource, n uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped[ds] += n
+	ds.AddAnnotation(api.DroppedEventsAnnotation, strconv.FormatUint(s.dropped[ds], 10))
+}
+
+// checksumConfig holds the settings to verify the integrity of the events
+// received from the nodes
+type checksumConfig struct {
+	batchSize uint32
+	key       []byte
+}
+
+func checksumConfigFromParams(runtimeParams *params.Params) (*checksumConfig, error) {
+	p := runtimeParams.Get(ParamChecksumBatchSize)
+	if p == nil || p.AsUint32() == 0 {
+		return nil, nil
+	}
+	cfg := &checksumConfig{batchSize: p.AsUint32()}
+	if keyFile := runtimeParams.Get(ParamChecksumKeyFile); keyFile != nil && keyFile.AsString() != "" {
+		key, err := integrity.LoadKey(keyFile.AsString())
+		if err != nil {
+			return nil, err
+		}
+		cfg.key = key
+	}
+	return cfg, nil
+}
+
+func reportVerificationError(gadgetCtx runtime.GadgetContext, verr *integrity.VerificationError) {
+	if r, ok := gadgetCtx.GetVar(integrity.ReporterVarName); ok {
+		if reporter, ok := r.(integrity.Reporter); ok {
+			reporter.ReportVerificationError(verr)
+			return
+		}
+	}
+	gadgetCtx.Logger().Warnf("%-20s | integrity check failed: %s", verr.Node, verr.JSON())
+}
+
 func (r *Runtime) runGadgetOnTargets(
 	gadgetCtx runtime.GadgetContext,
 	paramMap map[string]string,
 	targets []target,
+	opts *streamOptions,
 ) (runtime.CombinedGadgetResult, error) {
 	results := make(runtime.CombinedGadgetResult, len(targets))
 	var resultsLock sync.Mutex
 
+	traceCtx, span := tracing.Tracer().Start(gadgetCtx.Context(), "run gadget", trace.WithAttributes(
+		attribute.String("gadget_image", gadgetCtx.ImageName()),
+		attribute.Int("targets", len(targets)),
+	))
+	defer span.End()
+
+	// Verification errors are emitted as events, unless a reporter was set by
+	// the caller
+	if opts.checksums != nil {
+		if _, ok := gadgetCtx.GetVar(integrity.ReporterVarName); !ok {
+			gadgetCtx.SetVar(integrity.ReporterVarName, &dsReporter{gadgetCtx: gadgetCtx})
+		}
+	}
+
 	wg := sync.WaitGroup{}
 	for _, t := range targets {
 		wg.Add(1)
 		go func(target target) {
-			gadgetCtx.Logger().Debugf("running gadget on node %q", target.node)
-			res, err := r.runGadget(gadgetCtx, target, paramMap)
+			gadgetCtx.Logger().Debugf("running gadget on node %q", target.name())
+			res, err := r.runGadget(traceCtx, gadgetCtx, target, paramMap, opts)
 			resultsLock.Lock()
-			results[target.node] = &runtime.GadgetResult{
+			results[target.name()] = &runtime.GadgetResult{
 				Payload: res,
 				Error:   err,
 			}
@@ -129,22 +346,48 @@ func (r *Runtime) runGadgetOnTargets(
 	return results, results.Err()
 }
 
-func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allParams map[string]string) ([]byte, error) {
+// runGadget runs the gadget on a single target; traceCtx holds the span the
+// one of the target is a child of
+func (r *Runtime) runGadget(
+	traceCtx context.Context,
+	gadgetCtx runtime.GadgetContext,
+	target target,
+	allParams map[string]string,
+	opts *streamOptions,
+) (_ []byte, err error) {
+	_, span := tracing.Tracer().Start(traceCtx, "run gadget on node", trace.WithAttributes(
+		attribute.String("node", target.name()),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	// Notice that we cannot use gadgetCtx.Context() here, as that would - when cancelled by the user - also cancel the
 	// underlying gRPC connection. That would then lead to results not being received anymore (mostly for profile
-	// gadgets.)
-	connCtx, cancel := context.WithCancel(context.Background())
+	// gadgets.) Only its span is kept to propagate it to the server.
+	connCtx, cancel := context.WithCancel(trace.ContextWithSpan(context.Background(), span))
 	defer cancel()
 
-	timeout := time.Second * time.Duration(r.globalParams.Get(ParamConnectionTimeout).AsUint16())
+	timeout := r.globalParams.Get(ParamConnectionTimeout).AsDuration()
 	dialCtx, cancelDial := context.WithTimeout(gadgetCtx.Context(), timeout)
 	defer cancelDial()
 
-	conn, err := r.dialContext(dialCtx, target, timeout)
-	if err != nil {
-		return nil, fmt.Errorf("dialing target on node %q: %w", target.node, err)
+	started := time.Now()
+	conn, ok := r.pool.take(dialCtx, target)
+	if !ok {
+		var err error
+		conn, err = r.dialContext(dialCtx, target, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("dialing target on node %q: %w", target.name(), err)
+		}
 	}
 	defer conn.Close()
+	gadgetCtx.Logger().Debugf("%-20s | connected after %s (pre-warmed: %t)", target.name(), time.Since(started), ok)
+	span.AddEvent("connected", trace.WithAttributes(attribute.Bool("pre_warmed", ok)))
 	client := api.NewGadgetManagerClient(conn)
 
 	runClient, err := client.RunGadget(connCtx)
@@ -154,17 +397,23 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 
 	var controlRequest *api.GadgetControlRequest
 
+	var verifier *integrity.Verifier
+
 	interactive := true
 	if gadgetCtx.UseInstance() {
 		gadgetCtx.Logger().Debugf("attaching to gadget instance %s", gadgetCtx.ImageName())
 		controlRequest = &api.GadgetControlRequest{
 			Event: &api.GadgetControlRequest_AttachRequest{
 				AttachRequest: &api.GadgetAttachRequest{
-					Id:      gadgetCtx.ImageName(),
-					Version: api.VersionGadgetRunProtocol,
+					Id:          gadgetCtx.ImageName(),
+					Version:     api.VersionGadgetRunProtocol,
+					Compression: opts.codec,
 				},
 			},
 		}
+		if !opts.since.IsZero() {
+			controlRequest.GetAttachRequest().Since = opts.since.UnixNano()
+		}
 		interactive = false
 	} else {
 		controlRequest = &api.GadgetControlRequest{
@@ -176,9 +425,15 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 					LogLevel:    uint32(gadgetCtx.Logger().GetLevel()),
 					Timeout:     int64(gadgetCtx.Timeout()),
 					Version:     api.VersionGadgetRunProtocol,
+					Compression: opts.codec,
+					NoCache:     opts.noCache,
 				},
 			},
 		}
+		if opts.checksums != nil {
+			controlRequest.GetRunRequest().ChecksumBatchSize = opts.checksums.batchSize
+			verifier = integrity.NewVerifier(target.name(), opts.checksums.batchSize, opts.checksums.key)
+		}
 	}
 
 	err = runClient.Send(controlRequest)
@@ -186,19 +441,25 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 		return nil, err
 	}
 
+	var tagger *clusterTagger
+	if target.cluster != "" {
+		tagger = newClusterTagger(target.cluster)
+	}
+
 	doneChan := make(chan error)
 
 	var result []byte
 	expectedSeq := uint32(1)
 
 	go func() {
+		gotPayload := false
 		dsMap := make(map[uint32]datasource.DataSource)
 		dsNameMap := make(map[string]uint32)
 		initialized := false
 		for {
 			ev, err := runClient.Recv()
 			if err != nil {
-				gadgetCtx.Logger().Debugf("%-20s | runClient returned with %v", target.node, err)
+				gadgetCtx.Logger().Debugf("%-20s | runClient returned with %v", target.name(), err)
 				if !errors.Is(err, io.EOF) {
 					doneChan <- err
 					return
@@ -208,14 +469,24 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 			}
 			switch ev.Type {
 			case api.EventTypeGadgetPayload:
+				if verifier != nil {
+					if verr := verifier.Add(ev.Seq, ev.DataSourceID, ev.Payload); verr != nil {
+						reportVerificationError(gadgetCtx, verr)
+					}
+				}
 				if !initialized {
-					gadgetCtx.Logger().Warnf("%-20s | received payload without being initialized", target.node)
+					gadgetCtx.Logger().Warnf("%-20s | received payload without being initialized", target.name())
 					continue
 				}
 				if expectedSeq != ev.Seq {
-					gadgetCtx.Logger().Warnf("%-20s | expected seq %d, got %d, %d messages dropped", target.node, expectedSeq, ev.Seq, ev.Seq-expectedSeq)
+					gadgetCtx.Logger().Warnf("%-20s | expected seq %d, got %d, %d messages dropped", target.name(), expectedSeq, ev.Seq, ev.Seq-expectedSeq)
 				}
 				expectedSeq = ev.Seq + 1
+				if !gotPayload {
+					gotPayload = true
+					gadgetCtx.Logger().Debugf("%-20s | first event after %s", target.name(), time.Since(started))
+					span.AddEvent("first event")
+				}
 				if ds, ok := dsMap[ev.DataSourceID]; ok && ds != nil {
 					var p datasource.Packet
 					switch ds.Type() {
@@ -231,12 +502,42 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 						gadgetCtx.Logger().Debugf("error unmarshaling payload: %v", err)
 						continue
 					}
+					if tagger != nil {
+						tagger.tag(ev.DataSourceID, p)
+					}
 					ds.EmitAndRelease(p)
 				}
 			case api.EventTypeGadgetResult:
-				gadgetCtx.Logger().Debugf("%-20s | got result from server", target.node)
+				gadgetCtx.Logger().Debugf("%-20s | got result from server", target.name())
 				result = ev.Payload
 			case api.EventTypeGadgetJobID: // not needed right now
+			case api.EventTypeGadgetChecksum:
+				if verifier == nil {
+					continue
+				}
+				c := &api.EventBatchChecksum{}
+				if err := proto.Unmarshal(ev.Payload, c); err != nil {
+					gadgetCtx.Logger().Warnf("%-20s | unmarshaling checksum: %v", target.name(), err)
+					continue
+				}
+				if verr := verifier.Verify(c); verr != nil {
+					reportVerificationError(gadgetCtx, verr)
+				}
+			case api.EventTypeGadgetDrops:
+				drops := &api.EventDrops{}
+				if err := proto.Unmarshal(ev.Payload, drops); err != nil {
+					gadgetCtx.Logger().Warnf("%-20s | unmarshaling drops: %v", target.name(), err)
+					continue
+				}
+				for dsID, n := range drops.Dropped {
+					ds, ok := dsMap[dsID]
+					if !ok || ds == nil {
+						continue
+					}
+					opts.drops.add(ds, n)
+					gadgetCtx.Logger().Warnf("%-20s | %d events of %s dropped in the last %s, the client doesn't keep up with them",
+						target.name(), n, ds.Name(), time.Duration(drops.Interval).Round(time.Millisecond))
+				}
 			case api.EventTypeGadgetInfo:
 				gi := &api.GadgetInfo{}
 				err = proto.Unmarshal(ev.Payload, gi)
@@ -247,6 +548,14 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 				for _, ds := range gi.DataSources {
 					dsNameMap[ds.Name] = ds.Id
 				}
+				if tagger != nil {
+					tagger.addFields(gi)
+				}
+				if verifier != nil {
+					if err := addIntegrityDataSource(gi); err != nil {
+						gadgetCtx.Logger().Warnf("%-20s | verification errors will be logged: %v", target.name(), err)
+					}
+				}
 
 				// Try to load gadget info; if gadget info has already been loaded and this one
 				// doesn't match, this will terminate this particular client session
@@ -255,7 +564,8 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 					gadgetCtx.Logger().Warnf("deserizalize gadget info: %v", err)
 					continue
 				}
-				gadgetCtx.Logger().Debugf("loaded gadget info")
+				gadgetCtx.Logger().Debugf("%-20s | loaded gadget info after %s", target.name(), time.Since(started))
+				span.AddEvent("loaded gadget info")
 				for _, ds := range gadgetCtx.GetAllDataSources() {
 					gadgetCtx.Logger().Debugf("registered ds %s", ds.Name())
 					if dsId, ok := dsNameMap[ds.Name()]; ok {
@@ -267,7 +577,7 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 				initialized = true
 			default:
 				if ev.Type >= 1<<api.EventLogShift {
-					gadgetCtx.Logger().Log(logger.Level(ev.Type>>api.EventLogShift), fmt.Sprintf("%-20s | %s", target.node, string(ev.Payload)))
+					gadgetCtx.Logger().Log(logger.Level(ev.Type>>api.EventLogShift), fmt.Sprintf("%-20s | %s", target.name(), string(ev.Payload)))
 					continue
 				}
 				gadgetCtx.Logger().Warnf("unknown payload type %d: %s", ev.Type, ev.Payload)
@@ -278,19 +588,19 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 	var runErr error
 	select {
 	case doneErr := <-doneChan:
-		gadgetCtx.Logger().Debugf("%-20s | done from server side (%v)", target.node, doneErr)
+		gadgetCtx.Logger().Debugf("%-20s | done from server side (%v)", target.name(), doneErr)
 		runErr = doneErr
 	case <-gadgetCtx.Context().Done():
 		if interactive {
 			// Send stop request
-			gadgetCtx.Logger().Debugf("%-20s | sending stop request", target.node)
+			gadgetCtx.Logger().Debugf("%-20s | sending stop request", target.name())
 			controlRequest := &api.GadgetControlRequest{Event: &api.GadgetControlRequest_StopRequest{StopRequest: &api.GadgetStopRequest{}}}
 			runClient.Send(controlRequest)
 
 			// Wait for done or timeout
 			select {
 			case doneErr := <-doneChan:
-				gadgetCtx.Logger().Debugf("%-20s | done after cancel request (%v)", target.node, doneErr)
+				gadgetCtx.Logger().Debugf("%-20s | done after cancel request (%v)", target.name(), doneErr)
 				runErr = doneErr
 			case <-time.After(ResultTimeout * time.Second):
 				return nil, fmt.Errorf("timed out while getting result")
@@ -300,6 +610,19 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 	return result, runErr
 }
 
+// prewarm dials the given targets in the background, so runGadget can pick up
+// the connections later on
+func (r *Runtime) prewarm(runtimeParams *params.Params, targets []target) {
+	timeout := r.globalParams.Get(ParamConnectionTimeout).AsDuration()
+	for _, t := range targets {
+		r.pool.prewarm(t, func() (*grpc.ClientConn, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return r.getConnFromTarget(ctx, runtimeParams, t)
+		})
+	}
+}
+
 func (r *Runtime) IsClient() bool {
 	return true
 }
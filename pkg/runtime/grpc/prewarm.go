@@ -0,0 +1,188 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+// prewarmTTL is how long targets and connections prepared while fetching the
+// gadget info are kept around for running the gadget afterwards
+const prewarmTTL = 30 * time.Second
+
+// warmConn is a connection to a target that is being or has been dialed ahead
+// of time
+type warmConn struct {
+	ready chan struct{}
+	conn  *grpc.ClientConn
+	err   error
+	timer *time.Timer
+}
+
+// connPool holds connections dialed ahead of time, so running a gadget right
+// after fetching its information doesn't need to look up the targets and to
+// connect to them again, which can take a while when going through the
+// Kubernetes API server. Each connection can only be taken once; connections
+// nobody took are closed after prewarmTTL.
+type connPool struct {
+	mu    sync.Mutex
+	conns map[string]*warmConn
+
+	targetsKey     string
+	targets        []target
+	targetsExpires time.Time
+}
+
+func newConnPool() *connPool {
+	return &connPool{
+		conns: make(map[string]*warmConn),
+	}
+}
+
+// targetsKey identifies the targets selected by the given params
+func targetsKey(runtimeParams *params.Params) string {
+	p := runtimeParams.Get(ParamNode)
+	if p == nil {
+		return ""
+	}
+	nodes := slices.Clone(p.AsStringSlice())
+	slices.Sort(nodes)
+	return strings.Join(nodes, ",")
+}
+
+func (p *connPool) setTargets(key string, targets []target) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.targetsKey = key
+	p.targets = targets
+	p.targetsExpires = time.Now().Add(prewarmTTL)
+}
+
+// takeTargets returns the targets previously stored for the given key, if they
+// didn't expire yet. They are only returned once.
+func (p *connPool) takeTargets(key string) ([]target, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	targets := p.targets
+	ok := targets != nil && p.targetsKey == key && time.Now().Before(p.targetsExpires)
+	p.targets = nil
+	return targets, ok
+}
+
+// put adds an already established connection to the pool
+func (p *connPool) put(t target, conn *grpc.ClientConn) {
+	wc := &warmConn{ready: make(chan struct{}), conn: conn}
+	close(wc.ready)
+	p.add(t, wc)
+}
+
+// prewarm dials the target in the background using dial
+func (p *connPool) prewarm(t target, dial func() (*grpc.ClientConn, error)) {
+	wc := &warmConn{ready: make(chan struct{})}
+	if !p.add(t, wc) {
+		return
+	}
+	go func() {
+		wc.conn, wc.err = dial()
+		if wc.err != nil {
+			log.Debugf("pre-warming connection to %q (%q): %v", t.addressOrPod, t.name(), wc.err)
+		}
+		close(wc.ready)
+	}()
+}
+
+func (p *connPool) add(t target, wc *warmConn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.conns[t.key()]; ok {
+		if wc.conn != nil {
+			wc.conn.Close()
+		}
+		return false
+	}
+	p.conns[t.key()] = wc
+	wc.timer = time.AfterFunc(prewarmTTL, func() {
+		if p.remove(t, wc) {
+			closeWarmConn(wc)
+		}
+	})
+	return true
+}
+
+func (p *connPool) remove(t target, wc *warmConn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conns[t.key()] != wc {
+		return false
+	}
+	delete(p.conns, t.addressOrPod)
+	return true
+}
+
+// take returns the connection to the target from the pool, waiting for it if
+// it's still being dialed. It returns false if there is no usable connection.
+func (p *connPool) take(ctx context.Context, t target) (*grpc.ClientConn, bool) {
+	p.mu.Lock()
+	wc, ok := p.conns[t.key()]
+	if ok {
+		delete(p.conns, t.addressOrPod)
+		wc.timer.Stop()
+	}
+	p.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	select {
+	case <-wc.ready:
+	case <-ctx.Done():
+		go closeWarmConn(wc)
+		return nil, false
+	}
+	if wc.err != nil {
+		return nil, false
+	}
+	return wc.conn, true
+}
+
+func closeWarmConn(wc *warmConn) {
+	<-wc.ready
+	if wc.conn != nil {
+		wc.conn.Close()
+	}
+}
+
+// close closes all connections nobody took
+func (p *connPool) close() {
+	p.mu.Lock()
+	conns := p.conns
+	p.conns = make(map[string]*warmConn)
+	p.targets = nil
+	p.mu.Unlock()
+
+	for _, wc := range conns {
+		wc.timer.Stop()
+		go closeWarmConn(wc)
+	}
+}
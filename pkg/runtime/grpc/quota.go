@@ -0,0 +1,114 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// QuotaViolation is a quota or rate limit of the gadget service a request
+// exceeded
+type QuotaViolation struct {
+	// Subject is what the limit applies to, like "global", "client:alice",
+	// "namespace:default" or "tag:ci"
+	Subject     string
+	Description string
+}
+
+// QuotaError is returned if the gadget service refused a request with
+// RESOURCE_EXHAUSTED because it exceeded a quota or rate limit. Use errors.As
+// to get it from the error returned.
+type QuotaError struct {
+	Violations []QuotaViolation
+
+	// RetryDelay is how long to wait before retrying, if the service told so
+	RetryDelay time.Duration
+
+	err error
+}
+
+// newQuotaError returns a *QuotaError describing err if it's a
+// RESOURCE_EXHAUSTED status error, otherwise err is returned unchanged
+func newQuotaError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.ResourceExhausted {
+		return err
+	}
+	e := &QuotaError{err: err}
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.QuotaFailure:
+			for _, v := range d.GetViolations() {
+				e.Violations = append(e.Violations, QuotaViolation{
+					Subject:     v.GetSubject(),
+					Description: v.GetDescription(),
+				})
+			}
+		case *errdetails.RetryInfo:
+			e.RetryDelay = d.GetRetryDelay().AsDuration()
+		}
+	}
+	if len(e.Violations) == 0 {
+		e.Violations = append(e.Violations, QuotaViolation{Description: st.Message()})
+	}
+	return e
+}
+
+func (e *QuotaError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		descriptions = append(descriptions, v.Description)
+	}
+	msg := "quota exceeded: " + strings.Join(descriptions, "; ")
+	if e.RetryDelay > 0 {
+		msg += fmt.Sprintf(" (retry in %s)", e.RetryDelay.Round(time.Millisecond))
+	}
+	return msg
+}
+
+// Unwrap returns the status error, so that status.Code still works on it
+func (e *QuotaError) Unwrap() error {
+	return e.err
+}
+
+func quotaUnaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return newQuotaError(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+func quotaStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, newQuotaError(err)
+	}
+	return &quotaClientStream{ClientStream: stream}, nil
+}
+
+// quotaClientStream turns the RESOURCE_EXHAUSTED errors the gadget service
+// ends streams with into *QuotaError
+type quotaClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *quotaClientStream) RecvMsg(m any) error {
+	return newQuotaError(s.ClientStream.RecvMsg(m))
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestNewQuotaError(t *testing.T) {
+	st, err := status.New(codes.ResourceExhausted, "rate limit exceeded").WithDetails(
+		&errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{{
+				Subject:     "client:alice",
+				Description: "rate limit of 5 requests per second exceeded for alice",
+			}},
+		},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(1500 * time.Millisecond)},
+	)
+	require.NoError(t, err)
+
+	var quotaErr *QuotaError
+	require.ErrorAs(t, newQuotaError(st.Err()), &quotaErr)
+	assert.Equal(t, []QuotaViolation{{
+		Subject:     "client:alice",
+		Description: "rate limit of 5 requests per second exceeded for alice",
+	}}, quotaErr.Violations)
+	assert.Equal(t, 1500*time.Millisecond, quotaErr.RetryDelay)
+	assert.Equal(t, "quota exceeded: rate limit of 5 requests per second exceeded for alice (retry in 1.5s)", quotaErr.Error())
+	assert.Equal(t, codes.ResourceExhausted, status.Code(quotaErr))
+
+	// Without details, the message of the status is used
+	require.ErrorAs(t, newQuotaError(status.Error(codes.ResourceExhausted, "too many gadgets")), &quotaErr)
+	assert.Equal(t, "quota exceeded: too many gadgets", quotaErr.Error())
+
+	// Other errors are kept as they are
+	assert.Nil(t, newQuotaError(nil))
+	assert.Equal(t, io.EOF, newQuotaError(io.EOF))
+	permissionErr := status.Error(codes.PermissionDenied, "denied")
+	assert.Equal(t, permissionErr, newQuotaError(permissionErr))
+	assert.False(t, errors.As(newQuotaError(permissionErr), &quotaErr))
+}
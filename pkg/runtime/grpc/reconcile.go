@@ -0,0 +1,244 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	ReconcileActionDeploy = "deploy"
+	ReconcileActionRemove = "remove"
+)
+
+// ReconcileAction is a change made by the InstanceReconciler on a node
+type ReconcileAction struct {
+	Node       string
+	InstanceID string
+	Action     string
+	Error      error
+}
+
+// InstanceReconciler keeps headless gadget instances installed on all the targets they're meant for. When connecting
+// to ig daemons directly, an instance is only created on the targets known at that time; the reconciler looks up the
+// targets again on every round, deploys the instances on targets that joined and removes them from targets that left.
+//
+// The instances are taken from the targets themselves: an instance is managed as long as at least one reachable
+// target has it, so deleting an instance from all targets also stops the reconciler from deploying it.
+type InstanceReconciler struct {
+	r             *Runtime
+	runtimeParams *params.Params
+	selector      []string
+
+	// known holds the targets and the instances managed in the previous round
+	known     []target
+	instances []string
+	// pending holds the instances still to be removed from targets that left
+	pending map[target][]string
+}
+
+// NewInstanceReconciler returns a reconciler for the gadget instances matching the given selector; all instances are
+// managed if it's empty
+func (r *Runtime) NewInstanceReconciler(runtimeParams *params.Params, selector []string) (*InstanceReconciler, error) {
+	if r.connectionMode != ConnectionModeDirect {
+		return nil, errors.New("gadget instances only need to be reconciled when connecting to ig daemons directly")
+	}
+	return &InstanceReconciler{
+		r:             r,
+		runtimeParams: runtimeParams,
+		selector:      selector,
+		pending:       make(map[target][]string),
+	}, nil
+}
+
+// Run reconciles the gadget instances every interval until ctx is done; report is called with the result of each
+// round
+func (rc *InstanceReconciler) Run(ctx context.Context, interval time.Duration, report func([]*ReconcileAction, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report(rc.Reconcile(ctx))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile runs a single round: it deploys the managed gadget instances on the reachable targets missing them and
+// removes them from the targets that aren't listed anymore. Unreachable targets are skipped and handled in a later
+// round.
+func (rc *InstanceReconciler) Reconcile(ctx context.Context) ([]*ReconcileAction, error) {
+	targets, err := rc.r.getTargets(ctx, rc.runtimeParams)
+	if err != nil {
+		return nil, fmt.Errorf("getting targets: %w", err)
+	}
+
+	var mu sync.Mutex
+	lists := make(map[target][]*api.GadgetInstance)
+	errs := rc.r.runForEachTarget(ctx, rc.runtimeParams, targets, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		res, err := api.NewGadgetInstanceManagerClient(conn).ListGadgetInstances(ctx, &api.ListGadgetInstancesRequest{Selector: rc.selector})
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		lists[target] = slices.DeleteFunc(res.GadgetInstances, func(gi *api.GadgetInstance) bool {
+			return !gi.MatchesSelector(rc.selector)
+		})
+		mu.Unlock()
+		return nil
+	})
+
+	// Collect the instances of all reachable targets, in the order of the targets
+	var instances []*api.GadgetInstance
+	for i, t := range targets {
+		if errs[i] != nil {
+			continue
+		}
+		for _, gi := range lists[t] {
+			if !slices.ContainsFunc(instances, func(i *api.GadgetInstance) bool { return i.Id == gi.Id }) {
+				instances = append(instances, gi)
+			}
+		}
+	}
+
+	missing := make(map[target][]*api.GadgetInstance)
+	var deployTargets []target
+	for i, t := range targets {
+		if errs[i] != nil {
+			continue
+		}
+		for _, gi := range instances {
+			if !isMeantFor(gi, t.node) || slices.ContainsFunc(lists[t], func(i *api.GadgetInstance) bool { return i.Id == gi.Id }) {
+				continue
+			}
+			if len(missing[t]) == 0 {
+				deployTargets = append(deployTargets, t)
+			}
+			missing[t] = append(missing[t], gi)
+		}
+	}
+
+	var actions []*ReconcileAction
+	results := make(map[target][]*ReconcileAction)
+	deployErrs := rc.r.runForEachTarget(ctx, rc.runtimeParams, deployTargets, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		client := api.NewGadgetInstanceManagerClient(conn)
+		var res []*ReconcileAction
+		for _, gi := range missing[target] {
+			instance := proto.Clone(gi).(*api.GadgetInstance)
+			instance.State = nil
+			_, err := client.CreateGadgetInstance(ctx, &api.CreateGadgetInstanceRequest{GadgetInstance: instance})
+			res = append(res, &ReconcileAction{Node: target.name(), InstanceID: gi.Id, Action: ReconcileActionDeploy, Error: err})
+		}
+		mu.Lock()
+		results[target] = res
+		mu.Unlock()
+		// The instances could have been created even if we didn't get an answer, don't retry
+		return nil
+	})
+	for i, t := range deployTargets {
+		if deployErrs[i] != nil {
+			for _, gi := range missing[t] {
+				actions = append(actions, &ReconcileAction{Node: t.name(), InstanceID: gi.Id, Action: ReconcileActionDeploy, Error: deployErrs[i]})
+			}
+			continue
+		}
+		actions = append(actions, results[t]...)
+	}
+
+	// Remove the instances of the previous round from the targets that left; targets that can't be reached are kept
+	// in pending, so removing is tried again in the next round
+	for _, t := range rc.known {
+		if !slices.Contains(targets, t) {
+			rc.pending[t] = rc.instances
+		}
+	}
+	var left []target
+	for t := range rc.pending {
+		if slices.Contains(targets, t) {
+			// The target is back, the instances are kept there
+			delete(rc.pending, t)
+			continue
+		}
+		left = append(left, t)
+	}
+	removed := make(map[target][]*ReconcileAction)
+	removeErrs := rc.r.runForEachTarget(ctx, rc.runtimeParams, left, func(ctx context.Context, target target, conn *grpc.ClientConn) error {
+		client := api.NewGadgetInstanceManagerClient(conn)
+		var res []*ReconcileAction
+		for _, id := range rc.pending[target] {
+			status, err := client.RemoveGadgetInstance(ctx, &api.GadgetInstanceId{Id: id})
+			if err != nil {
+				return err
+			}
+			if status.Result != 0 {
+				// The instance wasn't installed there
+				continue
+			}
+			res = append(res, &ReconcileAction{Node: target.name(), InstanceID: id, Action: ReconcileActionRemove})
+		}
+		mu.Lock()
+		removed[target] = res
+		mu.Unlock()
+		return nil
+	})
+	for i, t := range left {
+		if removeErrs[i] != nil {
+			for _, id := range rc.pending[t] {
+				actions = append(actions, &ReconcileAction{Node: t.name(), InstanceID: id, Action: ReconcileActionRemove, Error: removeErrs[i]})
+			}
+			continue
+		}
+		actions = append(actions, removed[t]...)
+		delete(rc.pending, t)
+	}
+
+	rc.known = targets
+
+	// Only fail the round if no target could be reached; the instances of the previous round are kept then
+	if len(targets) > 0 && !slices.ContainsFunc(errs, func(err error) bool { return err == nil }) {
+		return actions, fmt.Errorf("no target could be reached: %w", newMultiNodeError(targetNodes(targets), errs))
+	}
+	rc.instances = make([]string, 0, len(instances))
+	for _, gi := range instances {
+		rc.instances = append(rc.instances, gi.Id)
+	}
+	return actions, nil
+}
+
+func (a *ReconcileAction) String() string {
+	switch {
+	case a.Action == ReconcileActionDeploy && a.Error != nil:
+		return fmt.Sprintf("deploying gadget instance %q on node %q failed: %v", a.InstanceID, a.Node, a.Error)
+	case a.Action == ReconcileActionDeploy:
+		return fmt.Sprintf("deployed gadget instance %q on node %q", a.InstanceID, a.Node)
+	case a.Error != nil:
+		return fmt.Sprintf("removing gadget instance %q from node %q failed: %v", a.InstanceID, a.Node, a.Error)
+	default:
+		return fmt.Sprintf("removed gadget instance %q from node %q", a.InstanceID, a.Node)
+	}
+}
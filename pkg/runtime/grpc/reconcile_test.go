@@ -0,0 +1,170 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+type memInstanceManager struct {
+	api.UnimplementedGadgetInstanceManagerServer
+	mu        sync.Mutex
+	instances map[string]*api.GadgetInstance
+}
+
+func newMemInstanceManager(instances ...*api.GadgetInstance) *memInstanceManager {
+	m := &memInstanceManager{instances: map[string]*api.GadgetInstance{}}
+	for _, gi := range instances {
+		m.instances[gi.Id] = gi
+	}
+	return m
+}
+
+func (m *memInstanceManager) ids() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Sorted(maps.Keys(m.instances))
+}
+
+func (m *memInstanceManager) CreateGadgetInstance(_ context.Context, req *api.CreateGadgetInstanceRequest) (*api.CreateGadgetInstanceResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.instances[req.GadgetInstance.Id] = req.GadgetInstance
+	return &api.CreateGadgetInstanceResponse{GadgetInstance: req.GadgetInstance}, nil
+}
+
+func (m *memInstanceManager) ListGadgetInstances(_ context.Context, req *api.ListGadgetInstancesRequest) (*api.ListGadgetInstanceResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := &api.ListGadgetInstanceResponse{}
+	for _, id := range slices.Sorted(maps.Keys(m.instances)) {
+		gi := m.instances[id]
+		if gi.MatchesSelector(req.Selector) {
+			res.GadgetInstances = append(res.GadgetInstances, gi)
+		}
+	}
+	return res, nil
+}
+
+func (m *memInstanceManager) RemoveGadgetInstance(_ context.Context, id *api.GadgetInstanceId) (*api.StatusResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.instances[id.Id]; !ok {
+		return &api.StatusResponse{Result: 1, Message: "not found"}, nil
+	}
+	delete(m.instances, id.Id)
+	return &api.StatusResponse{}, nil
+}
+
+func TestInstanceReconciler(t *testing.T) {
+	node1 := newMemInstanceManager(
+		&api.GadgetInstance{Id: "aaa", Name: "exec", Tags: []string{"team-a"}},
+		&api.GadgetInstance{Id: "bbb", Name: "open", Tags: []string{"team-b"}},
+	)
+	node2 := newMemInstanceManager()
+	addr1 := startInstanceManager(t, node1)
+	addr2 := startInstanceManager(t, node2)
+	// Use different host names, so both servers are different nodes
+	_, port2, _ := strings.Cut(addr2, ":")
+
+	addressFile := filepath.Join(t.TempDir(), "targets")
+	writeTargets := func(addresses ...string) {
+		require.NoError(t, os.WriteFile(addressFile, []byte("# targets\n"+strings.Join(addresses, "\n")+"\n"), 0o644))
+	}
+	writeTargets("tcp://" + addr1)
+
+	r := New()
+	require.NoError(t, r.Init(nil))
+	require.NoError(t, r.globalParams.Set(ParamRemoteAddressFile, addressFile))
+	require.NoError(t, r.globalParams.Set(ParamTargetRetries, "0"))
+
+	rc, err := r.NewInstanceReconciler(nil, []string{"team-a"})
+	require.NoError(t, err)
+
+	actions, err := rc.Reconcile(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, actions)
+
+	// A node joining gets the instances matching the selector
+	writeTargets("tcp://"+addr1, "tcp://localhost:"+port2)
+	actions, err = rc.Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, &ReconcileAction{Node: "localhost", InstanceID: "aaa", Action: ReconcileActionDeploy}, actions[0])
+	assert.Equal(t, []string{"aaa"}, node2.ids())
+
+	states, err := r.GetNodeInstanceStates(context.Background(), nil, "aaa")
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	assert.False(t, states[0].Missing)
+	assert.False(t, states[1].Missing)
+
+	// Nothing to do once all nodes have the instances
+	actions, err = rc.Reconcile(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, actions)
+
+	// A node leaving gets its instances removed
+	writeTargets("tcp://" + addr1)
+	actions, err = rc.Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, &ReconcileAction{Node: "localhost", InstanceID: "aaa", Action: ReconcileActionRemove}, actions[0])
+	assert.Empty(t, node2.ids())
+	assert.Equal(t, []string{"aaa", "bbb"}, node1.ids())
+}
+
+func TestGetNodeInstanceStatesMissing(t *testing.T) {
+	addr1 := startInstanceManager(t, newMemInstanceManager(&api.GadgetInstance{
+		Id:    "aaa",
+		State: &api.GadgetInstanceState{Status: api.GadgetInstanceStatus_StatusRunning},
+	}))
+	addr2 := startInstanceManager(t, newMemInstanceManager())
+	_, port2, _ := strings.Cut(addr2, ":")
+
+	r := New()
+	require.NoError(t, r.Init(nil))
+	require.NoError(t, r.globalParams.Set(ParamRemoteAddress, "tcp://"+addr1+",tcp://localhost:"+port2+",tcp://localhost:1"))
+	require.NoError(t, r.globalParams.Set(ParamTargetRetries, "0"))
+	require.NoError(t, r.globalParams.Set(ParamConnectionTimeout, "200ms"))
+
+	states, err := r.GetNodeInstanceStates(context.Background(), nil, "aaa")
+	require.NoError(t, err)
+	require.Len(t, states, 3)
+	assert.Equal(t, "127.0.0.1", states[0].Node)
+	assert.Equal(t, api.GadgetInstanceStatus_StatusRunning, states[0].State.Status)
+	assert.Equal(t, "localhost", states[1].Node)
+	assert.True(t, states[1].Missing)
+	assert.Equal(t, "localhost", states[2].Node)
+	assert.Error(t, states[2].Error)
+}
+
+func TestNewInstanceReconcilerKubernetes(t *testing.T) {
+	r := New(WithConnectUsingK8SProxy)
+	_, err := r.NewInstanceReconciler(nil, nil)
+	require.Error(t, err)
+}
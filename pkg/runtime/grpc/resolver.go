@@ -0,0 +1,208 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Target is a gadget service gadgets can be run on
+type Target struct {
+	// Address is the remote address of the gadget service, in the format of
+	// --remote-address, or the name of its pod when connecting through the
+	// Kubernetes API
+	Address string
+
+	// Node is the name of the node of the target; if it's empty, it's derived
+	// from the address
+	Node string
+
+	// Cluster is the kubeconfig context of the cluster of the target when
+	// running on several clusters
+	Cluster string
+}
+
+// TargetResolver looks up the targets gadgets are run on. nodes holds the
+// nodes selected by the user, if any; resolvers can return targets on other
+// nodes as well, they're skipped by the runtime.
+type TargetResolver interface {
+	Resolve(ctx context.Context, nodes []string) ([]Target, error)
+}
+
+// SetTargetResolver replaces the resolver looking up the targets chosen with
+// the runtime params
+func (r *Runtime) SetTargetResolver(resolver TargetResolver) {
+	r.resolver = resolver
+}
+
+// StaticResolver resolves to the given remote addresses
+type StaticResolver []string
+
+func (s StaticResolver) Resolve(ctx context.Context, nodes []string) ([]Target, error) {
+	targets := make([]Target, 0, len(s))
+	for _, address := range s {
+		targets = append(targets, Target{Address: address})
+	}
+	return targets, nil
+}
+
+// FileResolver resolves to the remote addresses listed in a file, one per
+// line; the file is read again on every lookup
+type FileResolver string
+
+func (f FileResolver) Resolve(ctx context.Context, nodes []string) ([]Target, error) {
+	addresses, err := readRemoteAddressFile(string(f))
+	if err != nil {
+		return nil, err
+	}
+	return StaticResolver(addresses).Resolve(ctx, nodes)
+}
+
+// SRVResolver resolves to the hosts listed in the DNS SRV records of Name,
+// like _ig._tcp.example.com; the host names of the records are used as node
+// names
+type SRVResolver struct {
+	Name string
+
+	// lookupSRV can be replaced for testing
+	lookupSRV func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+func (s *SRVResolver) Resolve(ctx context.Context, nodes []string) ([]Target, error) {
+	lookupSRV := s.lookupSRV
+	if lookupSRV == nil {
+		lookupSRV = net.DefaultResolver.LookupSRV
+	}
+	_, records, err := lookupSRV(ctx, "", "", s.Name)
+	if err != nil {
+		return nil, fmt.Errorf("looking up SRV records of %q: %w", s.Name, err)
+	}
+	targets := make([]Target, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		targets = append(targets, Target{
+			Address: "tcp://" + net.JoinHostPort(host, strconv.Itoa(int(record.Port))),
+			Node:    host,
+		})
+	}
+	return targets, nil
+}
+
+// kubernetesResolver resolves to the gadget pods of the clusters chosen with
+// the global params
+type kubernetesResolver struct {
+	r *Runtime
+}
+
+func (k *kubernetesResolver) Resolve(ctx context.Context, nodes []string) ([]Target, error) {
+	gadgetNamespace := k.r.globalParams.Get(ParamGadgetNamespace).AsString()
+	pods, err := k.r.getClusterTargets(ctx, nodes, gadgetNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("get gadget pods: %w", err)
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("get gadget pods: Inspektor Gadget is not running on the requested node(s): %v", nodes)
+	}
+	targets := make([]Target, 0, len(pods))
+	for _, pod := range pods {
+		targets = append(targets, Target{Address: pod.addressOrPod, Node: pod.node, Cluster: pod.cluster})
+	}
+	return targets, nil
+}
+
+// targetResolver returns the resolver set with SetTargetResolver or the one
+// chosen by the global params
+func (r *Runtime) targetResolver() (TargetResolver, error) {
+	if r.resolver != nil {
+		return r.resolver, nil
+	}
+	switch r.connectionMode {
+	case ConnectionModeKubernetesProxy:
+		return &kubernetesResolver{r: r}, nil
+	case ConnectionModeDirect:
+		var file, srv string
+		if p := r.globalParams.Get(ParamRemoteAddressFile); p != nil {
+			file = p.AsString()
+		}
+		if p := r.globalParams.Get(ParamRemoteAddressSRV); p != nil {
+			srv = p.AsString()
+		}
+		switch {
+		case file != "" && srv != "":
+			return nil, fmt.Errorf("--%s and --%s can't be used together", ParamRemoteAddressFile, ParamRemoteAddressSRV)
+		case file != "":
+			return FileResolver(file), nil
+		case srv != "":
+			return &SRVResolver{Name: srv}, nil
+		}
+		return StaticResolver(r.globalParams.Get(ParamRemoteAddress).AsStringSlice()), nil
+	}
+	return nil, errors.New("unsupported connection mode")
+}
+
+// resolveTargets turns the targets of a resolver into the ones of the given
+// nodes, or of all nodes if none are given
+func (r *Runtime) resolveTargets(resolved []Target, nodes []string) ([]target, error) {
+	var targets []target
+	switch r.connectionMode {
+	case ConnectionModeKubernetesProxy:
+		targets = make([]target, 0, len(resolved))
+		for _, t := range resolved {
+			tg := target{addressOrPod: t.Address, node: t.Node, cluster: t.Cluster}
+			if t.Cluster != "" {
+				tg.restConfig = r.clusterRestConfig(t.Cluster)
+			}
+			targets = append(targets, tg)
+		}
+	default:
+		addresses := make([]string, 0, len(resolved))
+		for _, t := range resolved {
+			addresses = append(addresses, t.Address)
+		}
+		var err error
+		targets, err = parseRemoteAddresses(addresses)
+		if err != nil {
+			return nil, err
+		}
+		for i, t := range resolved {
+			if t.Node != "" {
+				targets[i].node = t.Node
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return targets, nil
+	}
+
+	var res []target
+	for _, node := range nodes {
+		found := false
+		for _, t := range targets {
+			if node == t.node || node == t.name() {
+				res = append(res, t)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("node %q does not have a target", node)
+		}
+	}
+	return res, nil
+}
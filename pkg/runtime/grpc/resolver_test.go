@@ -0,0 +1,109 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSRVResolver(t *testing.T) {
+	s := &SRVResolver{
+		Name: "_ig._tcp.example.com",
+		lookupSRV: func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			assert.Equal(t, "_ig._tcp.example.com", name)
+			return "", []*net.SRV{
+				{Target: "node-1.example.com.", Port: 1234},
+				{Target: "node-2.example.com.", Port: 1235},
+			}, nil
+		},
+	}
+	targets, err := s.Resolve(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Target{
+		{Address: "tcp://node-1.example.com:1234", Node: "node-1.example.com"},
+		{Address: "tcp://node-2.example.com:1235", Node: "node-2.example.com"},
+	}, targets)
+}
+
+func TestTargetResolver(t *testing.T) {
+	r := New()
+	require.NoError(t, r.Init(nil))
+
+	resolver, err := r.targetResolver()
+	require.NoError(t, err)
+	assert.IsType(t, StaticResolver{}, resolver)
+
+	require.NoError(t, r.globalParams.Set(ParamRemoteAddressSRV, "_ig._tcp.example.com"))
+	resolver, err = r.targetResolver()
+	require.NoError(t, err)
+	assert.Equal(t, &SRVResolver{Name: "_ig._tcp.example.com"}, resolver)
+
+	addressFile := filepath.Join(t.TempDir(), "targets")
+	require.NoError(t, os.WriteFile(addressFile, []byte("tcp://10.0.0.1:1234\n"), 0o644))
+	require.NoError(t, r.globalParams.Set(ParamRemoteAddressFile, addressFile))
+	_, err = r.targetResolver()
+	require.Error(t, err)
+
+	require.NoError(t, r.globalParams.Set(ParamRemoteAddressSRV, ""))
+	targets, err := r.getTargets(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []target{{addressOrPod: "10.0.0.1:1234", node: "10.0.0.1"}}, targets)
+}
+
+type fakeResolver []Target
+
+func (f fakeResolver) Resolve(ctx context.Context, nodes []string) ([]Target, error) {
+	return f, nil
+}
+
+func TestResolveTargets(t *testing.T) {
+	r := New()
+	require.NoError(t, r.Init(nil))
+	r.SetTargetResolver(fakeResolver{
+		{Address: "tcp://10.0.0.1:1234", Node: "worker-1"},
+		{Address: "tcp://10.0.0.2:1234"},
+		{Address: "unix:///run/ig.sock"},
+	})
+
+	resolver, err := r.targetResolver()
+	require.NoError(t, err)
+	resolved, err := resolver.Resolve(context.Background(), nil)
+	require.NoError(t, err)
+
+	targets, err := r.resolveTargets(resolved, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []target{
+		{addressOrPod: "10.0.0.1:1234", node: "worker-1"},
+		{addressOrPod: "10.0.0.2:1234", node: "10.0.0.2"},
+		{addressOrPod: "unix:///run/ig.sock", node: "local"},
+	}, targets)
+
+	targets, err = r.resolveTargets(resolved, []string{"worker-1", "local"})
+	require.NoError(t, err)
+	assert.Equal(t, []target{
+		{addressOrPod: "10.0.0.1:1234", node: "worker-1"},
+		{addressOrPod: "unix:///run/ig.sock", node: "local"},
+	}, targets)
+
+	_, err = r.resolveTargets(resolved, []string{"worker-2"})
+	require.Error(t, err)
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	gadgettls "github.com/inspektor-gadget/inspektor-gadget/pkg/utils/tls"
+)
+
+// TLSServerNameNode is replaced by the node name of the target in the server name given with
+// ParamTLSServerName, like {node}.gadget.svc
+const TLSServerNameNode = "{node}"
+
+// tlsParamKeys returns the keys of the params holding the client key, the client certificate, the server CA and
+// the server name. kubectl-gadget already uses tls-* flags for the connection to the Kubernetes API server, so they
+// are prefixed with "gadget-" when connecting through it.
+func (r *Runtime) tlsParamKeys() (string, string, string, string) {
+	if r.connectionMode == ConnectionModeKubernetesProxy {
+		return ParamGadgetTLSKey, ParamGadgetTLSCert, ParamGadgetTLSServerCA, ParamGadgetTLSServerName
+	}
+	return ParamTLSKey, ParamTLSCert, ParamTLSServerCA, ParamTLSServerName
+}
+
+func (r *Runtime) tlsParamDescs() params.ParamDescs {
+	keyParam, certParam, caParam, serverNameParam := r.tlsParamKeys()
+	return params.ParamDescs{
+		{
+			Key:         keyParam,
+			Description: "TLS client key",
+			TypeHint:    params.TypeString,
+		},
+		{
+			Key:         certParam,
+			Description: "TLS client certificate",
+			TypeHint:    params.TypeString,
+		},
+		{
+			Key:         caParam,
+			Description: "TLS server CA certificate",
+			TypeHint:    params.TypeString,
+		},
+		{
+			Key: serverNameParam,
+			Description: "override TLS server name (if omitted, using the host of tcp targets and the node name of other targets); " +
+				TLSServerNameNode + " is replaced by the node name of each target",
+			TypeHint: params.TypeString,
+		},
+	}
+}
+
+// tlsConfig returns the configuration to use to mutually authenticate with the given target, or nil if TLS
+// isn't configured
+func (r *Runtime) tlsConfig(target target) (*tls.Config, error) {
+	keyParam, certParam, caParam, serverNameParam := r.tlsParamKeys()
+
+	tlsKey := r.globalParams.Get(keyParam).String()
+	tlsCert := r.globalParams.Get(certParam).String()
+	tlsCA := r.globalParams.Get(caParam).String()
+
+	tlsOptionsSet := 0
+	for _, tlsOption := range []string{tlsKey, tlsCert, tlsCA} {
+		if len(tlsOption) != 0 {
+			tlsOptionsSet++
+		}
+	}
+
+	if tlsOptionsSet == 0 {
+		return nil, nil
+	}
+
+	if tlsOptionsSet < 3 {
+		return nil, fmt.Errorf(`
+missing at least one the TLS related options:
+	* %s: %q
+	* %s: %q
+	* %s: %q
+All these options should be set at the same time to enable TLS connection`,
+			keyParam, tlsKey,
+			certParam, tlsCert,
+			caParam, tlsCA)
+	}
+
+	cert, err := gadgettls.LoadTLSCert(tlsCert, tlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating TLS certificate: %w", err)
+	}
+
+	ca, err := gadgettls.LoadTLSCA(tlsCA)
+	if err != nil {
+		return nil, fmt.Errorf("creating TLS certificate authority: %w", err)
+	}
+
+	serverName := tlsServerName(target, r.connectionMode, r.globalParams.Get(serverNameParam).String())
+	if serverName == "" {
+		return nil, fmt.Errorf("invalid hostname for %q, use %s to override", target.addressOrPod, serverNameParam)
+	}
+
+	return &tls.Config{
+		ServerName:   serverName,
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      ca,
+	}, nil
+}
+
+// tlsServerName returns the name the certificate of the given target has to be valid for. If no override is given,
+// that's the host of tcp targets and the node name of unix socket, vsock and Kubernetes targets, as they don't
+// have a meaningful host name.
+func tlsServerName(target target, mode ConnectionMode, override string) string {
+	if override != "" {
+		return strings.ReplaceAll(override, TLSServerNameNode, target.node)
+	}
+	if mode == ConnectionModeKubernetesProxy ||
+		strings.HasPrefix(target.addressOrPod, "unix://") ||
+		strings.HasPrefix(target.addressOrPod, "vsock://") {
+		return target.node
+	}
+	host, _, err := net.SplitHostPort(target.addressOrPod)
+	if err != nil {
+		return target.addressOrPod
+	}
+	return host
+}
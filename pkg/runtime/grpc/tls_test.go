@@ -0,0 +1,209 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	gadgettls "github.com/inspektor-gadget/inspektor-gadget/pkg/utils/tls"
+)
+
+func TestTLSServerName(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   target
+		mode     ConnectionMode
+		override string
+		expected string
+	}{
+		{
+			name:     "tcp",
+			target:   target{addressOrPod: "node1.example.com:8888", node: "node1.example.com"},
+			expected: "node1.example.com",
+		},
+		{
+			name:     "unix",
+			target:   target{addressOrPod: "unix:///run/ig.sock", node: "vm1"},
+			expected: "vm1",
+		},
+		{
+			name:     "vsock",
+			target:   target{addressOrPod: "vsock://3:1234", node: "vsock-3"},
+			expected: "vsock-3",
+		},
+		{
+			name:     "kubernetes",
+			target:   target{addressOrPod: "gadget-abcde", node: "worker1"},
+			mode:     ConnectionModeKubernetesProxy,
+			expected: "worker1",
+		},
+		{
+			name:     "override",
+			target:   target{addressOrPod: "10.0.0.1:8888", node: "10.0.0.1"},
+			override: "ig.example.com",
+			expected: "ig.example.com",
+		},
+		{
+			name:     "override with node",
+			target:   target{addressOrPod: "gadget-abcde", node: "worker1"},
+			mode:     ConnectionModeKubernetesProxy,
+			override: "{node}.gadget.svc",
+			expected: "worker1.gadget.svc",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, tlsServerName(test.target, test.mode, test.override))
+		})
+	}
+}
+
+func TestTLSParamKeys(t *testing.T) {
+	r := New(WithConnectUsingK8SProxy)
+	p := r.GlobalParamDescs().ToParams()
+	assert.NotNil(t, p.Get(ParamGadgetTLSCert))
+	assert.Nil(t, p.Get(ParamTLSServerName))
+
+	r = New()
+	p = r.GlobalParamDescs().ToParams()
+	assert.NotNil(t, p.Get(ParamTLSCert))
+	assert.Nil(t, p.Get(ParamGadgetTLSServerName))
+}
+
+func TestTLSConfigMissingOption(t *testing.T) {
+	r := New()
+	require.NoError(t, r.Init(nil))
+	require.NoError(t, r.globalParams.Set(ParamTLSCert, "client.crt"))
+
+	_, err := r.tlsConfig(target{addressOrPod: "unix:///run/ig.sock", node: "local"})
+	require.ErrorContains(t, err, ParamTLSKey)
+}
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// writeTestCert creates a certificate for the given name signed by parent (self-signed if nil) and stores it
+// and its key as PEM files in dir
+func writeTestCert(t *testing.T, dir, name string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+
+	return &testCert{cert: cert, key: key}
+}
+
+func TestTLSConnection(t *testing.T) {
+	dir := t.TempDir()
+	ca := writeTestCert(t, dir, "ca", nil)
+	writeTestCert(t, dir, "vm1", ca)
+	writeTestCert(t, dir, "client", ca)
+	otherCA := writeTestCert(t, dir, "other-ca", nil)
+	writeTestCert(t, dir, "other-client", otherCA)
+
+	serverConfig, err := gadgettls.NewServerConfig(filepath.Join(dir, "vm1.crt"), filepath.Join(dir, "vm1.key"),
+		filepath.Join(dir, "ca.crt"))
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "ig.sock")
+	lis, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverConfig)))
+	api.RegisterGadgetInstanceManagerServer(s, newMemInstanceManager(&api.GadgetInstance{Id: "aaa"}))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	newRuntime := func(t *testing.T, node, client string) *Runtime {
+		r := New()
+		require.NoError(t, r.Init(nil))
+		require.NoError(t, r.globalParams.Set(ParamRemoteAddress, "unix://"+path+"?node="+node))
+		require.NoError(t, r.globalParams.Set(ParamTLSCert, filepath.Join(dir, client+".crt")))
+		require.NoError(t, r.globalParams.Set(ParamTLSKey, filepath.Join(dir, client+".key")))
+		require.NoError(t, r.globalParams.Set(ParamTLSServerCA, filepath.Join(dir, "ca.crt")))
+		require.NoError(t, r.globalParams.Set(ParamConnectionTimeout, "2s"))
+		require.NoError(t, r.globalParams.Set(ParamTargetRetries, "0"))
+		t.Cleanup(func() { r.Close() })
+		return r
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		r := newRuntime(t, "vm1", "client")
+		instances, err := r.GetGadgetInstances(context.Background(), nil, nil)
+		require.NoError(t, err)
+		require.Len(t, instances, 1)
+		assert.Equal(t, "aaa", instances[0].Id)
+	})
+
+	t.Run("wrong server identity", func(t *testing.T) {
+		r := newRuntime(t, "vm2", "client")
+		_, err := r.GetGadgetInstances(context.Background(), nil, nil)
+		require.Error(t, err)
+	})
+
+	t.Run("untrusted client", func(t *testing.T) {
+		r := newRuntime(t, "vm1", "other-client")
+		_, err := r.GetGadgetInstances(context.Background(), nil, nil)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/authz"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+// tokenParamKey returns the key of the param holding the path to the bearer token file; like the TLS params, it's
+// prefixed with "gadget-" when connecting through the Kubernetes API server
+func (r *Runtime) tokenParamKey() string {
+	if r.connectionMode == ConnectionModeKubernetesProxy {
+		return ParamGadgetTokenFile
+	}
+	return ParamTokenFile
+}
+
+func (r *Runtime) tokenParamDesc() *params.ParamDesc {
+	return &params.ParamDesc{
+		Key:         r.tokenParamKey(),
+		Description: "File holding the bearer token to authenticate with at the gadget service",
+		TypeHint:    params.TypeString,
+	}
+}
+
+// tokenCredentials adds a bearer token to all requests
+type tokenCredentials struct {
+	token string
+}
+
+func (c *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{authz.AuthorizationHeader: "Bearer " + c.token}, nil
+}
+
+// RequireTransportSecurity returns false, as the connection through the Kubernetes API server is secured even if
+// the gRPC one isn't
+func (c *tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// tokenCredentials returns the credentials sending the configured bearer token, or nil if none is configured
+func (r *Runtime) tokenCredentials() (credentials.PerRPCCredentials, error) {
+	p := r.globalParams.Get(r.tokenParamKey())
+	if p == nil || p.AsString() == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(p.AsString())
+	if err != nil {
+		return nil, fmt.Errorf("reading token file: %w", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return nil, fmt.Errorf("token file %q is empty", p.AsString())
+	}
+	return &tokenCredentials{token: token}, nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+type vsockAddr struct {
+	cid  uint32
+	port uint32
+}
+
+func (a *vsockAddr) Network() string { return "vsock" }
+func (a *vsockAddr) String() string  { return fmt.Sprintf("%d:%d", a.cid, a.port) }
+
+// vsockConn is a net.Conn on top of a vsock socket; the net package can't wrap AF_VSOCK sockets itself, so the
+// file of the (non-blocking) socket is used, which takes care of polling and deadlines
+type vsockConn struct {
+	*os.File
+	local  net.Addr
+	remote net.Addr
+}
+
+func (c *vsockConn) LocalAddr() net.Addr  { return c.local }
+func (c *vsockConn) RemoteAddr() net.Addr { return c.remote }
+
+// dialVsock connects to the given port of the virtual machine (or host) with the given context id
+func dialVsock(ctx context.Context, cid uint32, port uint32) (net.Conn, error) {
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("creating vsock socket: %w", err)
+	}
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("vsock:%d:%d", cid, port))
+
+	if err := connectVsock(ctx, f, &unix.SockaddrVM{CID: cid, Port: port}); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("connecting to vsock %d:%d: %w", cid, port, err)
+	}
+
+	conn := &vsockConn{
+		File:   f,
+		remote: &vsockAddr{cid: cid, port: port},
+	}
+	if sa, err := unix.Getsockname(fd); err == nil {
+		if vm, ok := sa.(*unix.SockaddrVM); ok {
+			conn.local = &vsockAddr{cid: vm.CID, port: vm.Port}
+		}
+	}
+	if conn.local == nil {
+		conn.local = &vsockAddr{}
+	}
+	return conn, nil
+}
+
+func connectVsock(ctx context.Context, f *os.File, sa unix.Sockaddr) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var connectErr error
+	if err := rc.Control(func(fd uintptr) {
+		connectErr = unix.Connect(int(fd), sa)
+	}); err != nil {
+		return err
+	}
+	if !errors.Is(connectErr, unix.EINPROGRESS) {
+		return connectErr
+	}
+
+	// Wait for the socket to become writable, or the context to be done
+	if deadline, ok := ctx.Deadline(); ok {
+		f.SetWriteDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		f.SetWriteDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	err = rc.Write(func(fd uintptr) bool {
+		soErr, err := unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_ERROR)
+		if err != nil {
+			connectErr = err
+			return true
+		}
+		if soErr != 0 {
+			connectErr = unix.Errno(soErr)
+			return true
+		}
+		if _, err := unix.Getpeername(int(fd)); errors.Is(err, unix.ENOTCONN) {
+			// still connecting
+			return false
+		}
+		connectErr = nil
+		return true
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	if connectErr != nil {
+		return connectErr
+	}
+	return f.SetWriteDeadline(time.Time{})
+}
@@ -15,10 +15,13 @@
 package local
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/nodeinfo"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
 )
@@ -61,3 +64,8 @@ func (r *Runtime) SetDefaultValue(key params.ValueHint, value string) {
 func (r *Runtime) GetDefaultValue(key params.ValueHint) (string, bool) {
 	return "", false
 }
+
+// GetNodeInfo returns the kernel feature inventory of the local node
+func (r *Runtime) GetNodeInfo(ctx context.Context, runtimeParams *params.Params, req *api.NodeInfoRequest) (map[string]*api.NodeInfo, error) {
+	return map[string]*api.NodeInfo{"local": nodeinfo.Collect(req)}, nil
+}
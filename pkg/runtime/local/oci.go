@@ -30,6 +30,27 @@ func (r *Runtime) GetGadgetInfo(gadgetCtx runtime.GadgetContext, runtimeParams *
 	return gadgetCtx.SerializeGadgetInfo(gadgetCtx.ExtraInfo())
 }
 
+// ValidateGadget checks whether the gadget would run on the local node, without starting it
+func (r *Runtime) ValidateGadget(gadgetCtx runtime.GadgetContext, runtimeParams *params.Params, paramValues api.ParamValues) (map[string]*api.ValidateGadgetResponse, error) {
+	res := &api.ValidateGadgetResponse{}
+	checks, err := gadgetCtx.ValidateGadget(paramValues)
+	if err != nil {
+		res.Checks = []*api.ValidationCheck{{
+			Name:    "gadget",
+			Result:  api.ValidationResult_ValidationFailed,
+			Message: err.Error(),
+		}}
+		return map[string]*api.ValidateGadgetResponse{"local": res}, nil
+	}
+	res.Checks = checks
+
+	res.GadgetInfo, err = gadgetCtx.SerializeGadgetInfo(false)
+	if err != nil {
+		return nil, fmt.Errorf("serializing gadget info: %w", err)
+	}
+	return map[string]*api.ValidateGadgetResponse{"local": res}, nil
+}
+
 func (r *Runtime) RunGadget(gadgetCtx runtime.GadgetContext, runtimeParams *params.Params, paramValues api.ParamValues) error {
 	return gadgetCtx.Run(paramValues)
 }
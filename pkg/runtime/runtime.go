@@ -16,6 +16,7 @@ package runtime
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"oras.land/oras-go/v2"
@@ -64,6 +65,7 @@ type GadgetContext interface {
 
 	Run(paramValues api.ParamValues) error
 	PrepareGadgetInfo(paramValues api.ParamValues) error
+	ValidateGadget(paramValues api.ParamValues) ([]*api.ValidationCheck, error)
 }
 
 // GadgetResult contains the (optional) payload and error of a gadget run for a node
@@ -124,3 +126,29 @@ type Runtime interface {
 
 	IsClient() bool
 }
+
+// NodeInfoProvider is implemented by runtimes that can report the kernel feature inventory of the nodes they
+// are able to run gadgets on
+type NodeInfoProvider interface {
+	GetNodeInfo(ctx context.Context, runtimeParams *params.Params, req *api.NodeInfoRequest) (map[string]*api.NodeInfo, error)
+}
+
+// GadgetValidator is implemented by runtimes that can check whether a gadget would run on the nodes they are able
+// to run gadgets on, without starting it
+type GadgetValidator interface {
+	ValidateGadget(gadgetCtx GadgetContext, runtimeParams *params.Params, paramValueMap api.ParamValues) (map[string]*api.ValidateGadgetResponse, error)
+}
+
+// NodeOverheadProvider is implemented by runtimes that can report the resources used by Inspektor Gadget itself
+// on the nodes they are able to run gadgets on
+type NodeOverheadProvider interface {
+	GetNodeOverhead(ctx context.Context, runtimeParams *params.Params, req *api.NodeOverheadRequest) (map[string]*api.NodeOverhead, error)
+}
+
+// DebugShellProvider is implemented by runtimes that can open debug shells in the containers of the nodes they are
+// able to run gadgets on. onStarted is called once the session started; the input is then read from stdin and the
+// output written to output until the session ends.
+type DebugShellProvider interface {
+	DebugShell(ctx context.Context, runtimeParams *params.Params, req *api.DebugShellStartRequest,
+		stdin io.Reader, output io.Writer, onStarted func(*api.DebugShellStarted)) (*api.DebugShellExit, error)
+}
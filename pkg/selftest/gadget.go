@@ -0,0 +1,63 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selftest
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"oras.land/oras-go/v2"
+	orasoci "oras.land/oras-go/v2/content/oci"
+)
+
+// Image is the reference of the gadget built into the binaries to verify the
+// pipeline, so it doesn't depend on pulling one. It traces the files opened;
+// the activity generated is opening a file whose path contains the marker.
+// Its source is in the gadget directory; "make selftest-gadget" builds it into
+// the layout directory before the binaries are built.
+const Image = "ghcr.io/inspektor-gadget/gadget/selftest:builtin"
+
+// ImageTimestampField is the field of the built-in gadget holding the time
+// since boot of the events
+const ImageTimestampField = "timestamp_raw"
+
+// errNotBuilt is returned by Target if the binary was built without the
+// built-in gadget
+var errNotBuilt = errors.New("the self-test gadget isn't built into this binary, build it with \"make selftest-gadget\"")
+
+// layout holds the OCI layout of the built-in gadget; it only holds a
+// .gitignore file if the gadget wasn't built
+//
+//go:embed all:layout
+var layout embed.FS
+
+// Target returns a read-only store holding the built-in gadget
+func Target(ctx context.Context) (oras.ReadOnlyTarget, error) {
+	sub, err := fs.Sub(layout, "layout")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fs.Stat(sub, "index.json"); err != nil {
+		return nil, errNotBuilt
+	}
+	target, err := orasoci.NewFromFS(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("opening built-in gadget: %w", err)
+	}
+	return target, nil
+}
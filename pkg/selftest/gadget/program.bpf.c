@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2025 The Inspektor Gadget authors */
+
+// Gadget built into the binaries to verify the pipeline with `ig selftest`:
+// it traces the files opened with openat, including failed attempts, as the
+// activity generated by the self-test opens a file that doesn't exist.
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+
+#include <gadget/buffer.h>
+#include <gadget/common.h>
+#include <gadget/filter.h>
+#include <gadget/macros.h>
+#include <gadget/types.h>
+
+#define NAME_MAX 255
+
+struct event {
+	gadget_timestamp timestamp_raw;
+	struct gadget_process proc;
+
+	gadget_errno error_raw;
+	char fname[NAME_MAX];
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 10240);
+	__type(key, u32);
+	__type(value, const char *);
+} start SEC(".maps");
+
+GADGET_TRACER_MAP(events, 1024 * 256);
+
+GADGET_TRACER(open, events, event);
+
+SEC("tracepoint/syscalls/sys_enter_openat")
+int ig_selftest_e(struct syscall_trace_enter *ctx)
+{
+	u32 tid = (u32)bpf_get_current_pid_tgid();
+	const char *fname = (const char *)ctx->args[1];
+
+	if (gadget_should_discard_data_current())
+		return 0;
+
+	bpf_map_update_elem(&start, &tid, &fname, BPF_ANY);
+	return 0;
+}
+
+SEC("tracepoint/syscalls/sys_exit_openat")
+int ig_selftest_x(struct syscall_trace_exit *ctx)
+{
+	u32 tid = (u32)bpf_get_current_pid_tgid();
+	struct event *event;
+	const char **fname;
+
+	fname = bpf_map_lookup_elem(&start, &tid);
+	if (!fname)
+		return 0;
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		goto cleanup;
+
+	gadget_process_populate(&event->proc);
+	event->error_raw = ctx->ret < 0 ? -ctx->ret : 0;
+	bpf_probe_read_user_str(&event->fname, sizeof(event->fname), *fname);
+	event->timestamp_raw = bpf_ktime_get_boot_ns();
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+
+cleanup:
+	bpf_map_delete_elem(&start, &tid);
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,345 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package selftest verifies that the whole event pipeline works on a node: a
+// gadget is loaded, known activity is generated and the events it causes have
+// to go through the enrichment and the other operators to the output within
+// the expected latency.
+package selftest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	igjson "github.com/inspektor-gadget/inspektor-gadget/pkg/datasource/formatters/json"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+const (
+	// DefaultTimestampField is the field holding the time of the events
+	DefaultTimestampField = "timestamp_raw"
+
+	// Priority is the priority of the operator checking the events, after all
+	// the other operators
+	Priority = 50000
+
+	markerPrefix = "ig-selftest-"
+)
+
+// Check names, in the order they are run
+const (
+	CheckGadget     = "gadget"
+	CheckActivity   = "activity"
+	CheckEvents     = "events"
+	CheckEnrichment = "enrichment"
+	CheckLatency    = "latency"
+)
+
+// Status of a check
+type Status string
+
+const (
+	StatusPass Status = "PASS"
+	StatusFail Status = "FAIL"
+	StatusSkip Status = "SKIP"
+)
+
+// Result is the result of a check
+type Result struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report holds the results of all the checks
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Passed returns whether none of the checks failed
+func (r *Report) Passed() bool {
+	for _, res := range r.Results {
+		if res.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Print writes the results as a table
+func (r *Report) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+	for _, res := range r.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", res.Name, res.Status, res.Detail)
+	}
+	tw.Flush()
+
+	if r.Passed() {
+		fmt.Fprintln(w, "\nSelf-test passed")
+	} else {
+		fmt.Fprintln(w, "\nSelf-test failed")
+	}
+}
+
+// NewMarker returns a random string identifying the activity generated by a
+// self-test
+func NewMarker() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return markerPrefix + hex.EncodeToString(b)
+}
+
+// Options configures the checks
+type Options struct {
+	// Marker identifies the events caused by the activity generated for the
+	// self-test
+	Marker string
+	// EnrichmentFields are the fields that need to be set in the events, like
+	// "proc.comm" or "k8s.podName"
+	EnrichmentFields []string
+	// TimestampField is the field holding the time of the events, used to
+	// measure the latency. DefaultTimestampField is used if empty.
+	TimestampField string
+	// BootTime is whether TimestampField holds the time since boot instead
+	// of the wall time, like in the built-in gadget
+	BootTime bool
+	// MaxLatency is the maximum time between an event happening and being
+	// received
+	MaxLatency time.Duration
+}
+
+// Checker verifies the events of a gadget. Its operator has to be added to the
+// data operators of the gadget context.
+type Checker struct {
+	opts Options
+	now  func() time.Time
+
+	mu          sync.Mutex
+	loaded      bool
+	dataSources []string
+	activityErr error
+	event       map[string]any
+	received    time.Time
+
+	loadedCh chan struct{}
+	found    chan struct{}
+}
+
+func NewChecker(opts Options) *Checker {
+	if opts.TimestampField == "" {
+		opts.TimestampField = DefaultTimestampField
+	}
+	return &Checker{
+		opts:     opts,
+		now:      time.Now,
+		loadedCh: make(chan struct{}),
+		found:    make(chan struct{}),
+	}
+}
+
+// Marker returns the string identifying the events caused by the activity
+func (c *Checker) Marker() string {
+	return c.opts.Marker
+}
+
+// Loaded is closed once the gadget is running
+func (c *Checker) Loaded() <-chan struct{} {
+	return c.loadedCh
+}
+
+// Found is closed once an event containing the marker is received
+func (c *Checker) Found() <-chan struct{} {
+	return c.found
+}
+
+// SetActivityError records that generating the activity failed
+func (c *Checker) SetActivityError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.activityErr = err
+}
+
+// Operator returns the data operator looking for the events containing the
+// marker
+func (c *Checker) Operator() operators.DataOperator {
+	return simple.New("selftest",
+		simple.WithPriority(Priority),
+		simple.OnInit(func(gadgetCtx operators.GadgetContext) error {
+			for _, ds := range gadgetCtx.GetDataSources() {
+				c.mu.Lock()
+				c.dataSources = append(c.dataSources, ds.Name())
+				c.mu.Unlock()
+
+				formatter, err := igjson.New(ds, igjson.WithShowAll(true))
+				if err != nil {
+					return fmt.Errorf("creating json formatter for %q: %w", ds.Name(), err)
+				}
+				ds.Subscribe(func(ds datasource.DataSource, data datasource.Data) error {
+					c.onEvent(formatter.Marshal(data))
+					return nil
+				}, Priority)
+			}
+			return nil
+		}),
+		simple.OnStart(func(gadgetCtx operators.GadgetContext) error {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if !c.loaded {
+				c.loaded = true
+				close(c.loadedCh)
+			}
+			return nil
+		}),
+	)
+}
+
+func (c *Checker) onEvent(b []byte) {
+	if !bytes.Contains(b, []byte(c.opts.Marker)) {
+		return
+	}
+	received := c.now()
+
+	var event map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.event != nil {
+		return
+	}
+	c.event = event
+	c.received = received
+	close(c.found)
+}
+
+// Report returns the results of the checks; runErr is the error returned by
+// running the gadget, if any
+func (c *Checker) Report(runErr error) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := &Report{}
+	add := func(name string, status Status, format string, args ...any) {
+		r.Results = append(r.Results, Result{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case c.loaded:
+		add(CheckGadget, StatusPass, "data sources: %s", strings.Join(c.dataSources, ", "))
+	case runErr != nil:
+		add(CheckGadget, StatusFail, "running gadget: %v", runErr)
+	default:
+		add(CheckGadget, StatusFail, "gadget didn't start")
+	}
+
+	switch {
+	case c.activityErr != nil:
+		add(CheckActivity, StatusFail, "generating activity: %v", c.activityErr)
+	case !c.loaded:
+		add(CheckActivity, StatusSkip, "gadget didn't start")
+	default:
+		add(CheckActivity, StatusPass, "marker %s", c.opts.Marker)
+	}
+
+	if c.event == nil {
+		add(CheckEvents, StatusFail, "no event containing the marker %s received", c.opts.Marker)
+		add(CheckEnrichment, StatusSkip, "no event")
+		add(CheckLatency, StatusSkip, "no event")
+		return r
+	}
+	add(CheckEvents, StatusPass, "event containing the marker received")
+
+	var missing []string
+	for _, field := range c.opts.EnrichmentFields {
+		if value, ok := lookupField(c.event, field); !ok || value == "" {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		add(CheckEnrichment, StatusFail, "missing fields: %s", strings.Join(missing, ", "))
+	} else {
+		add(CheckEnrichment, StatusPass, "fields set: %s", strings.Join(c.opts.EnrichmentFields, ", "))
+	}
+
+	ts, err := c.timestamp()
+	if err != nil {
+		add(CheckLatency, StatusFail, "%v", err)
+		return r
+	}
+	latency := c.received.Sub(ts)
+	if c.opts.MaxLatency > 0 && latency > c.opts.MaxLatency {
+		add(CheckLatency, StatusFail, "%s, more than %s", latency, c.opts.MaxLatency)
+	} else {
+		add(CheckLatency, StatusPass, "%s", latency)
+	}
+	return r
+}
+
+func (c *Checker) timestamp() (time.Time, error) {
+	value, ok := lookupField(c.event, c.opts.TimestampField)
+	if !ok {
+		return time.Time{}, fmt.Errorf("field %q not found", c.opts.TimestampField)
+	}
+	ns, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing %q: %w", c.opts.TimestampField, err)
+	}
+	if c.opts.BootTime {
+		ns = int64(gadgets.WallTimeFromBootTime(uint64(ns)))
+	}
+	return time.Unix(0, ns), nil
+}
+
+// lookupField returns the value of the field at path, like "proc.comm",
+// formatted as a string
+func lookupField(event map[string]any, path string) (string, bool) {
+	parts := strings.Split(path, ".")
+	m := event
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part].(map[string]any)
+		if !ok {
+			return "", false
+		}
+		m = next
+	}
+	value, ok := m[parts[len(parts)-1]]
+	if !ok || value == nil {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
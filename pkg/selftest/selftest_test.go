@@ -0,0 +1,266 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package selftest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/datasource"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/simple"
+)
+
+const testMarker = "ig-selftest-0123456789ab"
+
+var testNow = time.Unix(1700000000, 0)
+
+func statuses(r *Report) map[string]Status {
+	res := make(map[string]Status)
+	for _, result := range r.Results {
+		res[result.Name] = result.Status
+	}
+	return res
+}
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name     string
+		loaded   bool
+		runErr   error
+		event    string
+		expected map[string]Status
+		passed   bool
+	}{
+		{
+			name:   "passed",
+			loaded: true,
+			event: fmt.Sprintf(`{"args":"/bin/sh -c exit 0 %s","proc":{"comm":"sh","pid":42},"timestamp_raw":%d}`,
+				testMarker, testNow.Add(-100*time.Millisecond).UnixNano()),
+			expected: map[string]Status{
+				CheckGadget:     StatusPass,
+				CheckActivity:   StatusPass,
+				CheckEvents:     StatusPass,
+				CheckEnrichment: StatusPass,
+				CheckLatency:    StatusPass,
+			},
+			passed: true,
+		},
+		{
+			name:   "gadget failed",
+			runErr: errors.New("pulling image"),
+			expected: map[string]Status{
+				CheckGadget:     StatusFail,
+				CheckActivity:   StatusSkip,
+				CheckEvents:     StatusFail,
+				CheckEnrichment: StatusSkip,
+				CheckLatency:    StatusSkip,
+			},
+		},
+		{
+			name:   "other events",
+			loaded: true,
+			event:  `{"args":"/bin/ls","proc":{"comm":"ls","pid":42},"timestamp_raw":1}`,
+			expected: map[string]Status{
+				CheckGadget:     StatusPass,
+				CheckActivity:   StatusPass,
+				CheckEvents:     StatusFail,
+				CheckEnrichment: StatusSkip,
+				CheckLatency:    StatusSkip,
+			},
+		},
+		{
+			name:   "not enriched",
+			loaded: true,
+			event: fmt.Sprintf(`{"args":"%s","proc":{"comm":"","pid":42},"timestamp_raw":%d}`,
+				testMarker, testNow.UnixNano()),
+			expected: map[string]Status{
+				CheckGadget:     StatusPass,
+				CheckActivity:   StatusPass,
+				CheckEvents:     StatusPass,
+				CheckEnrichment: StatusFail,
+				CheckLatency:    StatusPass,
+			},
+		},
+		{
+			name:   "too slow",
+			loaded: true,
+			event: fmt.Sprintf(`{"args":"%s","proc":{"comm":"sh","pid":42},"timestamp_raw":%d}`,
+				testMarker, testNow.Add(-5*time.Second).UnixNano()),
+			expected: map[string]Status{
+				CheckGadget:     StatusPass,
+				CheckActivity:   StatusPass,
+				CheckEvents:     StatusPass,
+				CheckEnrichment: StatusPass,
+				CheckLatency:    StatusFail,
+			},
+		},
+		{
+			name:   "no timestamp",
+			loaded: true,
+			event:  fmt.Sprintf(`{"args":"%s","proc":{"comm":"sh","pid":42}}`, testMarker),
+			expected: map[string]Status{
+				CheckGadget:     StatusPass,
+				CheckActivity:   StatusPass,
+				CheckEvents:     StatusPass,
+				CheckEnrichment: StatusPass,
+				CheckLatency:    StatusFail,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewChecker(Options{
+				Marker:           testMarker,
+				EnrichmentFields: []string{"proc.comm", "proc.pid"},
+				MaxLatency:       time.Second,
+			})
+			c.now = func() time.Time { return testNow }
+			c.loaded = test.loaded
+			if test.event != "" {
+				c.onEvent([]byte(test.event))
+			}
+
+			r := c.Report(test.runErr)
+			assert.Equal(t, test.expected, statuses(r))
+			assert.Equal(t, test.passed, r.Passed())
+		})
+	}
+}
+
+func TestReportActivityError(t *testing.T) {
+	c := NewChecker(Options{Marker: testMarker})
+	c.loaded = true
+	c.SetActivityError(errors.New("creating pod"))
+
+	r := c.Report(nil)
+	assert.Equal(t, StatusFail, statuses(r)[CheckActivity])
+	assert.False(t, r.Passed())
+}
+
+func TestPrint(t *testing.T) {
+	r := &Report{Results: []Result{
+		{Name: CheckGadget, Status: StatusPass, Detail: "data sources: exec"},
+		{Name: CheckEvents, Status: StatusFail, Detail: "no event"},
+	}}
+
+	var sb strings.Builder
+	r.Print(&sb)
+	assert.Contains(t, sb.String(), "gadget  PASS    data sources: exec")
+	assert.Contains(t, sb.String(), "Self-test failed")
+}
+
+func TestChecker(t *testing.T) {
+	c := NewChecker(Options{
+		Marker:           NewMarker(),
+		EnrichmentFields: []string{"comm"},
+		MaxLatency:       time.Minute,
+	})
+	require.True(t, strings.HasPrefix(c.Marker(), markerPrefix))
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+
+	var ds datasource.DataSource
+	var argsField, commField, tsField datasource.FieldAccessor
+
+	prepare := func(gadgetCtx operators.GadgetContext) error {
+		var err error
+		ds, err = gadgetCtx.RegisterDataSource(datasource.TypeSingle, "exec")
+		require.NoError(t, err)
+		argsField, err = ds.AddField("args", api.Kind_String)
+		require.NoError(t, err)
+		commField, err = ds.AddField("comm", api.Kind_String)
+		require.NoError(t, err)
+		tsField, err = ds.AddField(DefaultTimestampField, api.Kind_Uint64)
+		require.NoError(t, err)
+		return nil
+	}
+	produce := func(operators.GadgetContext) error {
+		go func() {
+			<-c.Loaded()
+			for _, args := range []string{"/bin/ls", "/bin/sh -c exit 0 " + c.Marker()} {
+				data, err := ds.NewPacketSingle()
+				require.NoError(t, err)
+				require.NoError(t, argsField.PutString(data, args))
+				require.NoError(t, commField.PutString(data, "sh"))
+				require.NoError(t, tsField.PutUint64(data, uint64(time.Now().UnixNano())))
+				require.NoError(t, ds.EmitAndRelease(data))
+			}
+		}()
+		return nil
+	}
+	producer := simple.New("producer",
+		simple.WithPriority(Priority-1),
+		simple.OnInit(prepare),
+		simple.OnStart(produce),
+	)
+
+	go func() {
+		select {
+		case <-c.Found():
+		case <-ctx.Done():
+		}
+		cancel()
+	}()
+
+	gadgetCtx := gadgetcontext.New(ctx, "", gadgetcontext.WithDataOperators(producer, c.Operator()))
+	require.NoError(t, gadgetCtx.Run(api.ParamValues{}))
+
+	r := c.Report(nil)
+	assert.True(t, r.Passed(), "results: %+v", r.Results)
+	assert.Equal(t, "data sources: exec", r.Results[0].Detail)
+}
+
+func TestReportBootTime(t *testing.T) {
+	var ts unix.Timespec
+	require.NoError(t, unix.ClockGettime(unix.CLOCK_BOOTTIME, &ts))
+
+	c := NewChecker(Options{
+		Marker:         testMarker,
+		TimestampField: ImageTimestampField,
+		BootTime:       true,
+		MaxLatency:     time.Minute,
+	})
+	c.loaded = true
+	c.onEvent([]byte(fmt.Sprintf(`{"fname":"/tmp/%s","timestamp_raw":%d}`, testMarker, ts.Nano())))
+
+	r := c.Report(nil)
+	assert.Equal(t, StatusPass, statuses(r)[CheckLatency], "results: %+v", r.Results)
+}
+
+func TestTarget(t *testing.T) {
+	target, err := Target(context.TODO())
+	if errors.Is(err, errNotBuilt) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+
+	manifest, err := oci.GetManifestForHost(context.TODO(), target, Image)
+	require.NoError(t, err)
+	assert.NotEmpty(t, manifest.Layers)
+}
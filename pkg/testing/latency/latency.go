@@ -0,0 +1,275 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package latency measures the time between the moment events are generated in
+// the kernel and the moment they are received by the client running a gadget.
+package latency
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// DefaultTimestampField is the field holding the time at which the events were
+// generated, in nanoseconds since the epoch
+const DefaultTimestampField = "timestamp_raw"
+
+// Environment variables overriding the thresholds, as durations like "500ms"
+const (
+	EnvP50 = "IG_TEST_LATENCY_P50"
+	EnvP99 = "IG_TEST_LATENCY_P99"
+	EnvMax = "IG_TEST_LATENCY_MAX"
+)
+
+// Recorder is an io.Writer receiving the output of a gadget in the JSON
+// output mode, one event per line, and recording the latency of each event
+// as it's received. It can be used with igrunner.WithStdOutWriter().
+type Recorder struct {
+	timestampField []string
+	keepOutput     bool
+
+	mu        sync.Mutex
+	partial   []byte
+	output    bytes.Buffer
+	lines     uint64
+	latencies []time.Duration
+	errors    uint64
+	now       func() time.Time
+}
+
+type Option func(*Recorder)
+
+// WithTimestampField sets the path of the field holding the time of the
+// events, like "timestamp_raw" or "data.timestamp_raw". DefaultTimestampField
+// is used by default.
+func WithTimestampField(field string) Option {
+	return func(r *Recorder) {
+		r.timestampField = strings.Split(field, ".")
+	}
+}
+
+// WithKeepOutput keeps the output so it can be validated once the gadget is
+// done, since igrunner.WithValidateOutput() doesn't get it when a writer is
+// set.
+func WithKeepOutput() Option {
+	return func(r *Recorder) {
+		r.keepOutput = true
+	}
+}
+
+func NewRecorder(opts ...Option) *Recorder {
+	r := &Recorder{
+		timestampField: []string{DefaultTimestampField},
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	received := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.keepOutput {
+		r.output.Write(p)
+	}
+
+	r.partial = append(r.partial, p...)
+	for {
+		i := bytes.IndexByte(r.partial, '\n')
+		if i < 0 {
+			break
+		}
+		r.record(r.partial[:i], received)
+		r.partial = r.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+func (r *Recorder) record(line []byte, received time.Time) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	r.lines++
+
+	ts, err := r.timestamp(line)
+	if err != nil {
+		r.errors++
+		return
+	}
+	r.latencies = append(r.latencies, received.Sub(time.Unix(0, ts)))
+}
+
+func (r *Recorder) timestamp(line []byte) (int64, error) {
+	var event map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		return 0, err
+	}
+
+	path := r.timestampField
+	for _, part := range path[:len(path)-1] {
+		next, ok := event[part].(map[string]any)
+		if !ok {
+			return 0, fmt.Errorf("field %q not found", part)
+		}
+		event = next
+	}
+	n, ok := event[path[len(path)-1]].(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("field %q not found or not a number", strings.Join(path, "."))
+	}
+	return strconv.ParseInt(n.String(), 10, 64)
+}
+
+// Lines returns the number of events received
+func (r *Recorder) Lines() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lines
+}
+
+// Output returns the output received, if WithKeepOutput() was used
+func (r *Recorder) Output() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.output.String()
+}
+
+// Stats summarizes the latencies recorded
+type Stats struct {
+	// Count is the number of events whose latency was measured
+	Count int
+	// Errors is the number of events without a valid timestamp
+	Errors uint64
+
+	Min time.Duration
+	P50 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("count=%d errors=%d min=%s p50=%s p99=%s max=%s",
+		s.Count, s.Errors, s.Min, s.P50, s.P99, s.Max)
+}
+
+// Stats returns the statistics of the latencies recorded so far
+func (r *Recorder) Stats() Stats {
+	r.mu.Lock()
+	latencies := slices.Clone(r.latencies)
+	errors := r.errors
+	r.mu.Unlock()
+
+	stats := Stats{Count: len(latencies), Errors: errors}
+	if len(latencies) == 0 {
+		return stats
+	}
+	slices.Sort(latencies)
+	stats.Min = latencies[0]
+	stats.P50 = percentile(latencies, 50)
+	stats.P99 = percentile(latencies, 99)
+	stats.Max = latencies[len(latencies)-1]
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Thresholds are the maximum latencies accepted; zero values aren't checked
+type Thresholds struct {
+	P50 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// ThresholdsFromEnv returns defaults overridden by the EnvP50, EnvP99 and
+// EnvMax environment variables, so slower environments can use higher
+// thresholds than the ones of the tests.
+func ThresholdsFromEnv(defaults Thresholds) (Thresholds, error) {
+	thresholds := defaults
+	for env, d := range map[string]*time.Duration{
+		EnvP50: &thresholds.P50,
+		EnvP99: &thresholds.P99,
+		EnvMax: &thresholds.Max,
+	} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return Thresholds{}, fmt.Errorf("parsing %s: %w", env, err)
+		}
+		*d = parsed
+	}
+	return thresholds, nil
+}
+
+// Check returns an error if stats exceed the thresholds
+func (t Thresholds) Check(stats Stats) error {
+	if stats.Count == 0 {
+		return fmt.Errorf("no latency measured")
+	}
+	for _, c := range []struct {
+		name      string
+		value     time.Duration
+		threshold time.Duration
+	}{
+		{"p50", stats.P50, t.P50},
+		{"p99", stats.P99, t.P99},
+		{"max", stats.Max, t.Max},
+	} {
+		if c.threshold != 0 && c.value > c.threshold {
+			return fmt.Errorf("%s latency %s exceeds %s", c.name, c.value, c.threshold)
+		}
+	}
+	return nil
+}
+
+// RequireLatency logs the latencies recorded by r and verifies them against
+// defaults, overridden by the environment as in ThresholdsFromEnv().
+func RequireLatency(t *testing.T, r *Recorder, defaults Thresholds) Stats {
+	t.Helper()
+
+	thresholds, err := ThresholdsFromEnv(defaults)
+	require.NoError(t, err, "reading latency thresholds")
+
+	stats := r.Stats()
+	t.Logf("end-to-end latency: %s", stats)
+	require.NoError(t, thresholds.Check(stats), "latency regression")
+	return stats
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package latency
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	base := time.Unix(1000, 0)
+	now := base
+	r := NewRecorder(WithTimestampField("data.timestamp_raw"), WithKeepOutput())
+	r.now = func() time.Time { return now }
+
+	event := func(ts time.Time) string {
+		return fmt.Sprintf("{\"data\":{\"timestamp_raw\":%d}}\n", ts.UnixNano())
+	}
+
+	// Received in two writes
+	now = base.Add(10 * time.Millisecond)
+	line := event(base)
+	_, err := r.Write([]byte(line[:5]))
+	require.NoError(t, err)
+	_, err = r.Write([]byte(line[5:]))
+	require.NoError(t, err)
+
+	// Two events in one write
+	now = base.Add(50 * time.Millisecond)
+	_, err = r.Write([]byte(event(base.Add(20*time.Millisecond)) + event(base.Add(40*time.Millisecond))))
+	require.NoError(t, err)
+
+	// Invalid events
+	_, err = r.Write([]byte("{\"data\":{}}\nnot json\n\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(5), r.Lines())
+	stats := r.Stats()
+	assert.Equal(t, Stats{
+		Count:  3,
+		Errors: 2,
+		Min:    10 * time.Millisecond,
+		P50:    10 * time.Millisecond,
+		P99:    30 * time.Millisecond,
+		Max:    30 * time.Millisecond,
+	}, stats)
+	assert.Contains(t, r.Output(), "not json")
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	assert.Equal(t, time.Duration(50), percentile(sorted, 50))
+	assert.Equal(t, time.Duration(99), percentile(sorted, 99))
+	assert.Equal(t, time.Duration(1), percentile(sorted[:1], 99))
+}
+
+func TestThresholds(t *testing.T) {
+	stats := Stats{Count: 10, P50: time.Millisecond, P99: 20 * time.Millisecond, Max: time.Second}
+
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		wantErr    string
+	}{
+		{"unchecked", Thresholds{}, ""},
+		{"within", Thresholds{P50: 2 * time.Millisecond, P99: 50 * time.Millisecond, Max: 2 * time.Second}, ""},
+		{"p99 exceeded", Thresholds{P99: 10 * time.Millisecond}, "p99 latency"},
+		{"max exceeded", Thresholds{Max: 500 * time.Millisecond}, "max latency"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.thresholds.Check(stats)
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	require.ErrorContains(t, Thresholds{}.Check(Stats{}), "no latency measured")
+}
+
+func TestThresholdsFromEnv(t *testing.T) {
+	t.Setenv(EnvP99, "250ms")
+	thresholds, err := ThresholdsFromEnv(Thresholds{P50: time.Millisecond, P99: time.Millisecond})
+	require.NoError(t, err)
+	assert.Equal(t, Thresholds{P50: time.Millisecond, P99: 250 * time.Millisecond}, thresholds)
+
+	t.Setenv(EnvMax, "soon")
+	_, err = ThresholdsFromEnv(Thresholds{})
+	require.ErrorContains(t, err, EnvMax)
+}
@@ -0,0 +1,291 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// FieldMatcher verifies the value of a single field of an entry. The value is
+// the field as decoded from JSON: nil if the field is missing, a string, a
+// json.Number, a bool, a []any or a map[string]any.
+type FieldMatcher interface {
+	Match(value any) error
+	String() string
+}
+
+type fieldMatcher struct {
+	desc  string
+	match func(value any) error
+}
+
+func (m *fieldMatcher) Match(value any) error {
+	return m.match(value)
+}
+
+func (m *fieldMatcher) String() string {
+	return m.desc
+}
+
+// Regexp matches string and number fields whose value matches expr.
+func Regexp(expr string) FieldMatcher {
+	re := regexp.MustCompile(expr)
+	return &fieldMatcher{
+		desc: fmt.Sprintf("matches %q", expr),
+		match: func(value any) error {
+			var s string
+			switch v := value.(type) {
+			case string:
+				s = v
+			case json.Number:
+				s = v.String()
+			default:
+				return fmt.Errorf("expected a string, got %v", value)
+			}
+			if !re.MatchString(s) {
+				return fmt.Errorf("%q doesn't match %q", s, expr)
+			}
+			return nil
+		},
+	}
+}
+
+// Range matches number fields whose value is between low and high, both
+// included.
+func Range(low, high float64) FieldMatcher {
+	return &fieldMatcher{
+		desc: fmt.Sprintf("in [%v, %v]", low, high),
+		match: func(value any) error {
+			n, ok := value.(json.Number)
+			if !ok {
+				return fmt.Errorf("expected a number, got %v", value)
+			}
+			f, err := strconv.ParseFloat(n.String(), 64)
+			if err != nil {
+				return fmt.Errorf("parsing %q: %w", n, err)
+			}
+			if f < low || f > high {
+				return fmt.Errorf("%s not in [%v, %v]", n, low, high)
+			}
+			return nil
+		},
+	}
+}
+
+// NonEmpty matches fields that are present and not set to the zero value of
+// their type: "", 0, false, an empty array or an empty object.
+func NonEmpty() FieldMatcher {
+	return &fieldMatcher{
+		desc: "non-empty",
+		match: func(value any) error {
+			empty := false
+			switch v := value.(type) {
+			case nil:
+				empty = true
+			case string:
+				empty = v == ""
+			case json.Number:
+				f, err := strconv.ParseFloat(v.String(), 64)
+				empty = err == nil && f == 0
+			case bool:
+				empty = !v
+			case []any:
+				empty = len(v) == 0
+			case map[string]any:
+				empty = len(v) == 0
+			}
+			if empty {
+				return fmt.Errorf("expected a non-empty value, got %v", value)
+			}
+			return nil
+		},
+	}
+}
+
+// OneOf matches fields whose value is equal to one of values, compared by
+// their JSON encoding; OneOf("curl", "nginx") or OneOf(80, 443) for example.
+func OneOf(values ...any) FieldMatcher {
+	encoded := make([][]byte, 0, len(values))
+	descs := make([]string, 0, len(values))
+	for _, v := range values {
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(fmt.Sprintf("encoding %v: %v", v, err))
+		}
+		encoded = append(encoded, b)
+		descs = append(descs, string(b))
+	}
+	desc := fmt.Sprintf("one of [%s]", strings.Join(descs, ", "))
+	return &fieldMatcher{
+		desc: desc,
+		match: func(value any) error {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("encoding %v: %w", value, err)
+			}
+			for _, e := range encoded {
+				if bytes.Equal(b, e) {
+					return nil
+				}
+			}
+			return fmt.Errorf("%s is not %s", b, desc)
+		},
+	}
+}
+
+// Fields maps the path of fields to the matchers verifying them. The path is
+// made of the JSON names of the fields separated by dots, like "src.port".
+type Fields map[string]FieldMatcher
+
+// ExpectedEntry is an entry whose fields must all be equal to the ones of the
+// output entry, except for the fields in Fields that are verified by their
+// matcher instead.
+type ExpectedEntry[T any] struct {
+	Entry  *T
+	Fields Fields
+}
+
+// WithFields returns an expected entry verifying the given fields with their
+// matchers instead of comparing them to the ones of entry.
+func WithFields[T any](entry *T, fields Fields) *ExpectedEntry[T] {
+	return &ExpectedEntry[T]{Entry: entry, Fields: fields}
+}
+
+// MatchEntriesWithFields verifies that all the entries in expectedEntries are
+// matched by at least one entry in the output. The output is parsed according
+// to outputMode.
+func MatchEntriesWithFields[T any](t *testing.T, outputMode OutputMode, output string, normalize func(*T), expectedEntries ...*ExpectedEntry[T]) {
+	entries := decodeJSONOutput(t, outputMode, output, normalize)
+
+out:
+	for _, expectedEntry := range expectedEntries {
+		for _, entry := range entries {
+			if matchEntry(t, expectedEntry, entry) == nil {
+				continue out
+			}
+		}
+
+		var str strings.Builder
+
+		str.WriteString("output doesn't contain the expected entry\n")
+		str.WriteString("captured:\n")
+		for _, entry := range entries {
+			entryJson, _ := json.Marshal(entry)
+			str.WriteString(string(entryJson))
+			str.WriteString("\n")
+		}
+		str.WriteString("expected:\n")
+		str.WriteString(expectedEntry.String())
+		t.Fatal(str.String())
+	}
+}
+
+// MatchAllEntriesWithFields verifies that expectedEntry is matched by all
+// entries in the output. The output is parsed according to outputMode.
+func MatchAllEntriesWithFields[T any](t *testing.T, outputMode OutputMode, output string, normalize func(*T), expectedEntry *ExpectedEntry[T]) {
+	entries := decodeJSONOutput(t, outputMode, output, normalize)
+
+	require.NotEmpty(t, entries, "no output entries to match")
+
+	for _, entry := range entries {
+		err := matchEntry(t, expectedEntry, entry)
+		require.NoError(t, err, "unexpected output entry\nexpected:\n%s", expectedEntry.String())
+	}
+}
+
+func (e *ExpectedEntry[T]) String() string {
+	var str strings.Builder
+
+	entryJson, _ := json.Marshal(e.Entry)
+	str.WriteString(string(entryJson))
+	str.WriteString("\n")
+
+	paths := make([]string, 0, len(e.Fields))
+	for path := range e.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(&str, "  %s: %s\n", path, e.Fields[path])
+	}
+	return str.String()
+}
+
+// matchEntry returns an error describing the first difference between
+// expectedEntry and entry.
+func matchEntry[T any](t *testing.T, expectedEntry *ExpectedEntry[T], entry *T) error {
+	expected := toJSONMap(t, expectedEntry.Entry)
+	actual := toJSONMap(t, entry)
+
+	for path, matcher := range expectedEntry.Fields {
+		value, _ := lookupField(actual, path)
+		if err := matcher.Match(value); err != nil {
+			return fmt.Errorf("field %q: %w", path, err)
+		}
+		deleteField(expected, path)
+		deleteField(actual, path)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		return fmt.Errorf("entries differ")
+	}
+	return nil
+}
+
+func toJSONMap(t *testing.T, v any) map[string]any {
+	b, err := json.Marshal(v)
+	require.NoError(t, err, "encoding json")
+
+	m := map[string]any{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&m), "decoding json")
+	return m
+}
+
+func lookupField(m map[string]any, path string) (any, bool) {
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		m = next
+	}
+	value, ok := m[parts[len(parts)-1]]
+	return value, ok
+}
+
+func deleteField(m map[string]any, path string) {
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part].(map[string]any)
+		if !ok {
+			return
+		}
+		m = next
+	}
+	delete(m, parts[len(parts)-1])
+}
@@ -0,0 +1,177 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testNestedEvent struct {
+	Comm string    `json:"comm"`
+	Port int       `json:"port"`
+	Tags []string  `json:"tags"`
+	Sub  testEvent `json:"sub"`
+}
+
+func TestFieldMatchers(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher FieldMatcher
+		value   any
+		wantErr bool
+	}{
+		{"regexp string", Regexp("^cu"), "curl", false},
+		{"regexp number", Regexp("^80$"), json.Number("80"), false},
+		{"regexp no match", Regexp("^cu"), "nginx", true},
+		{"regexp missing", Regexp(".*"), nil, true},
+		{"range", Range(1, 65535), json.Number("8080"), false},
+		{"range bounds", Range(1, 65535), json.Number("65535"), false},
+		{"range out", Range(1, 65535), json.Number("0"), true},
+		{"range string", Range(1, 65535), "80", true},
+		{"non-empty string", NonEmpty(), "foo", false},
+		{"non-empty number", NonEmpty(), json.Number("-1"), false},
+		{"empty string", NonEmpty(), "", true},
+		{"empty number", NonEmpty(), json.Number("0"), true},
+		{"empty array", NonEmpty(), []any{}, true},
+		{"missing", NonEmpty(), nil, true},
+		{"one of string", OneOf("curl", "nginx"), "nginx", false},
+		{"one of number", OneOf(80, 443), json.Number("443"), false},
+		{"one of mismatch", OneOf(80, 443), json.Number("8080"), true},
+		{"one of type mismatch", OneOf(80), "80", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.matcher.Match(test.value)
+			if test.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMatchEntry(t *testing.T) {
+	entry := &testNestedEvent{
+		Comm: "curl",
+		Port: 43210,
+		Tags: []string{"a"},
+		Sub:  testEvent{Foo: 1, Bar: "baz"},
+	}
+
+	tests := []struct {
+		name     string
+		expected *ExpectedEntry[testNestedEvent]
+		wantErr  string
+	}{
+		{
+			name:     "equal",
+			expected: WithFields(&testNestedEvent{Comm: "curl", Port: 43210, Tags: []string{"a"}, Sub: testEvent{Foo: 1, Bar: "baz"}}, nil),
+		},
+		{
+			name:     "not equal",
+			expected: WithFields(&testNestedEvent{Comm: "curl"}, nil),
+			wantErr:  "entries differ",
+		},
+		{
+			name: "fields ignored",
+			expected: WithFields(&testNestedEvent{Comm: "curl", Tags: []string{"a"}}, Fields{
+				"port":    Range(1024, 65535),
+				"sub.foo": OneOf(1, 2),
+				"sub.bar": Regexp("^b"),
+			}),
+		},
+		{
+			name: "field mismatch",
+			expected: WithFields(&testNestedEvent{Comm: "curl", Tags: []string{"a"}, Sub: testEvent{Foo: 1, Bar: "baz"}}, Fields{
+				"port": Range(1, 1023),
+			}),
+			wantErr: `field "port"`,
+		},
+		{
+			name: "missing field",
+			expected: WithFields(&testNestedEvent{Comm: "curl", Port: 43210, Tags: []string{"a"}, Sub: testEvent{Foo: 1, Bar: "baz"}}, Fields{
+				"sub.qux": NonEmpty(),
+			}),
+			wantErr: `field "sub.qux"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := matchEntry(t, test.expected, entry)
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMatchEntriesWithFields(t *testing.T) {
+	output := `{"foo": 1, "bar": "connect"}
+	{"foo": 2, "bar": "accept"}`
+
+	MatchEntriesWithFields(t, JSONMultiObjectMode, output, nil,
+		WithFields(&testEvent{Bar: "accept"}, Fields{"foo": NonEmpty()}),
+		WithFields(&testEvent{Foo: 1}, Fields{"bar": Regexp("^conn")}),
+	)
+	MatchAllEntriesWithFields(t, JSONMultiObjectMode, output, nil,
+		WithFields(&testEvent{}, Fields{"foo": Range(1, 2), "bar": OneOf("connect", "accept")}),
+	)
+}
+
+func TestCheckOrder(t *testing.T) {
+	output := `{"ts": 3, "type": "close"}
+	{"ts": 1, "type": "connect"}
+	{"ts": 2, "type": "accept"}
+	{"ts": 4, "type": "connect"}`
+
+	connect := Fields{"type": OneOf("connect")}
+	accept := Fields{"type": OneOf("accept")}
+	closeStep := Fields{"type": OneOf("close")}
+
+	raw := decodeJSONOutput[json.RawMessage](t, JSONMultiObjectMode, output, nil)
+	var entries []map[string]any
+	for _, r := range raw {
+		entries = append(entries, toJSONMap(t, r))
+	}
+
+	// In the order of the output
+	require.NoError(t, checkOrder(entries, []Fields{connect, accept}))
+	require.NoError(t, checkOrder(entries, []Fields{closeStep, connect}))
+	require.ErrorContains(t, checkOrder(entries, []Fields{accept, closeStep}), "step 1")
+
+	// In the order of the timestamps
+	require.NoError(t, sortByTimestamp(entries, "ts"))
+	require.NoError(t, checkOrder(entries, []Fields{connect, accept, closeStep, connect}))
+	require.ErrorContains(t, checkOrder(entries, []Fields{closeStep, accept}), "step 1")
+
+	require.ErrorContains(t, sortByTimestamp(entries, "missing"), `field "missing"`)
+}
+
+func TestRequireOrder(t *testing.T) {
+	output := `[{"ts": 2, "type": "close"}, {"ts": 1, "type": "connect"}]`
+
+	RequireOrder(t, JSONSingleArrayMode, output, "ts",
+		Fields{"type": OneOf("connect")},
+		Fields{"type": OneOf("close")},
+	)
+}
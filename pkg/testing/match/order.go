@@ -0,0 +1,146 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package match
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// RequireOrder verifies that the output contains one entry matching each of
+// steps, in the order of steps; other entries can be interleaved. The output
+// is parsed according to outputMode.
+//
+// If timestampField is empty, the entries are considered in the order they
+// were printed. Otherwise they're ordered by the value of that field, like
+// "timestamp_raw", to verify that the events happened in that order even if
+// they were received in a different one, as it happens for events generated on
+// different CPUs.
+//
+// For instance, to verify that a connection was closed after being opened:
+//
+//	RequireOrder(t, JSONMultiObjectMode, output, "timestamp_raw",
+//		Fields{"type": OneOf("connect")},
+//		Fields{"type": OneOf("close")},
+//	)
+func RequireOrder(t *testing.T, outputMode OutputMode, output string, timestampField string, steps ...Fields) {
+	t.Helper()
+
+	raw := decodeJSONOutput[json.RawMessage](t, outputMode, output, nil)
+	entries := make([]map[string]any, 0, len(raw))
+	for _, r := range raw {
+		entries = append(entries, toJSONMap(t, r))
+	}
+
+	if timestampField != "" {
+		if err := sortByTimestamp(entries, timestampField); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := checkOrder(entries, steps); err != nil {
+		var str strings.Builder
+
+		str.WriteString(err.Error())
+		str.WriteString("\ncaptured:\n")
+		for _, entry := range entries {
+			entryJson, _ := json.Marshal(entry)
+			str.WriteString(string(entryJson))
+			str.WriteString("\n")
+		}
+		t.Fatal(str.String())
+	}
+}
+
+func sortByTimestamp(entries []map[string]any, timestampField string) error {
+	timestamps := make([]int64, len(entries))
+	for i, entry := range entries {
+		value, _ := lookupField(entry, timestampField)
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("entry %d: field %q not found or not a number", i, timestampField)
+		}
+		ts, err := strconv.ParseInt(n.String(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("entry %d: parsing %q: %w", i, timestampField, err)
+		}
+		timestamps[i] = ts
+	}
+
+	sort.Stable(byTimestamp{entries: entries, timestamps: timestamps})
+	return nil
+}
+
+type byTimestamp struct {
+	entries    []map[string]any
+	timestamps []int64
+}
+
+func (b byTimestamp) Len() int { return len(b.entries) }
+
+func (b byTimestamp) Less(i, j int) bool { return b.timestamps[i] < b.timestamps[j] }
+
+func (b byTimestamp) Swap(i, j int) {
+	b.entries[i], b.entries[j] = b.entries[j], b.entries[i]
+	b.timestamps[i], b.timestamps[j] = b.timestamps[j], b.timestamps[i]
+}
+
+// checkOrder looks for the earliest entry matching each step after the entry
+// matching the previous one, which finds an ordering if there is one.
+func checkOrder(entries []map[string]any, steps []Fields) error {
+	next := 0
+	for i, step := range steps {
+		found := false
+		for ; next < len(entries); next++ {
+			if matchFields(entries[next], step) {
+				found = true
+				next++
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no entry matching step %d (%s) after the ones matching the previous steps", i, step)
+		}
+	}
+	return nil
+}
+
+func matchFields(entry map[string]any, fields Fields) bool {
+	for path, matcher := range fields {
+		value, _ := lookupField(entry, path)
+		if matcher.Match(value) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (f Fields) String() string {
+	paths := make([]string, 0, len(f))
+	for path := range f {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	descs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		descs = append(descs, fmt.Sprintf("%s %s", path, f[path]))
+	}
+	return strings.Join(descs, ", ")
+}
@@ -0,0 +1,103 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing records OpenTelemetry spans of the lifecycle of gadgets, like
+// pulling their image, loading their eBPF objects or creating gadget
+// instances, so that slow startups can be diagnosed. The trace context is
+// propagated over the gRPC calls between clients and daemons, so the spans of
+// both sides end up in the same trace.
+//
+// Spans are only exported if an OTLP endpoint is configured with the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT
+// environment variables; the other OTEL_EXPORTER_OTLP_* variables are honored
+// as well.
+package tracing
+
+import (
+	"context"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+
+	"github.com/inspektor-gadget/inspektor-gadget/internal/version"
+)
+
+const tracerName = "github.com/inspektor-gadget/inspektor-gadget"
+
+// shutdownTimeout is the time given to export the remaining spans on exit
+const shutdownTimeout = 5 * time.Second
+
+// Tracer returns the tracer used for all spans of Inspektor Gadget
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Enabled returns whether an OTLP endpoint to export spans to is configured
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" ||
+		os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Init sets up exporting the spans of the given service, if enabled. The
+// returned function exports the remaining spans and has to be called before
+// exiting.
+func Init(serviceName string) func() {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if !Enabled() {
+		return func() {}
+	}
+
+	exporter, err := otlptracegrpc.New(context.Background())
+	if err != nil {
+		log.Warnf("creating trace exporter: %v", err)
+		return func() {}
+	}
+	res, _ := resource.New(context.Background(), resource.WithAttributes(
+		semconv.ServiceNameKey.String(serviceName),
+		semconv.ServiceVersionKey.String(version.Version().String()),
+	))
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Warnf("exporting spans: %v", err)
+		}
+	}
+}
+
+// DialOption propagates the trace context of the calls of a gRPC client
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
+
+// ServerOption continues the traces of the callers of a gRPC server
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
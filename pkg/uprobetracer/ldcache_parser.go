@@ -18,7 +18,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"strings"
 	"unsafe"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/secureopen"
@@ -175,7 +174,7 @@ func parseLdCache(containerPid uint32, ldCachePath string, libraryName string) (
 
 	// filter library entries with given library name
 	for _, entry := range ldEntries {
-		if strings.HasPrefix(entry.Key, libraryName+".so") {
+		if isLibrary(entry.Key, libraryName) {
 			filteredLibraries = append(filteredLibraries, entry.Value)
 		}
 	}
@@ -0,0 +1,107 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uprobetracer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/secureopen"
+)
+
+// muslArch is the architecture in the name of the musl loader path file, like
+// /etc/ld-musl-x86_64.path
+var muslArch = map[string]string{
+	"amd64": "x86_64",
+	"arm64": "aarch64",
+}
+
+// libDirs returns the directories searched for libraries in containers where
+// they can't be found with ld.so.cache, like the ones of Alpine or distroless
+// images: the ones of the musl loader path file, then the default ones.
+func libDirs(containerPid uint32) []string {
+	var dirs []string
+
+	if arch, ok := muslArch[runtime.GOARCH]; ok {
+		content, err := secureopen.ReadFileInContainer(containerPid, "/etc/ld-musl-"+arch+".path", ldCacheMaxSize)
+		if err == nil {
+			// Directories are separated by new lines or colons
+			dirs = strings.FieldsFunc(string(content), func(r rune) bool {
+				return r == '\n' || r == ':'
+			})
+		}
+	}
+
+	dirs = append(dirs, "/lib", "/usr/lib", "/usr/local/lib", "/lib64", "/usr/lib64")
+	if arch, ok := muslArch[runtime.GOARCH]; ok {
+		dirs = append(dirs, "/lib/"+arch+"-linux-gnu", "/usr/lib/"+arch+"-linux-gnu")
+	}
+	return dirs
+}
+
+// isLibrary returns whether the file name is the one of the library, like
+// libssl.so.3 for libssl
+func isLibrary(fileName, libraryName string) bool {
+	return strings.HasPrefix(fileName, libraryName+".so")
+}
+
+// searchLibDirs returns the paths of the library in the library directories
+// of the container
+func searchLibDirs(containerPid uint32, libraryName string) []string {
+	var paths []string
+	for _, dir := range libDirs(containerPid) {
+		names, err := secureopen.ReadDirInContainer(containerPid, dir)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if isLibrary(name, libraryName) {
+				paths = append(paths, path.Join(dir, name))
+			}
+		}
+	}
+	return paths
+}
+
+// mappedLibraries returns the paths of the library mapped by the process, like
+// the ones bundled with applications and loaded from their own directories. The
+// paths are the ones in the mount namespace of the process.
+func mappedLibraries(procFs string, pid uint32, libraryName string) ([]string, error) {
+	f, err := os.Open(filepath.Join(procFs, fmt.Sprint(pid), "maps"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// address perms offset dev inode path
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 6 || !filepath.IsAbs(fields[5]) {
+			continue
+		}
+		if isLibrary(path.Base(fields[5]), libraryName) && !slices.Contains(paths, fields[5]) {
+			paths = append(paths, fields[5])
+		}
+	}
+	return paths, scanner.Err()
+}
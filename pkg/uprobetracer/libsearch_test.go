@@ -0,0 +1,57 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uprobetracer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsLibrary(t *testing.T) {
+	assert.True(t, isLibrary("libssl.so", "libssl"))
+	assert.True(t, isLibrary("libssl.so.3", "libssl"))
+	assert.False(t, isLibrary("libssl3.so", "libssl"))
+	assert.False(t, isLibrary("libcrypto.so.3", "libssl"))
+}
+
+func TestMappedLibraries(t *testing.T) {
+	procFs := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procFs, "42"), 0o755))
+
+	maps := `55d0c0a00000-55d0c0a28000 r--p 00000000 00:2f 1234 /usr/bin/node
+7f1c2a000000-7f1c2a080000 r--p 00000000 00:2f 5678 /app/lib/libssl.so.1.1
+7f1c2a080000-7f1c2a200000 r-xp 00080000 00:2f 5678 /app/lib/libssl.so.1.1
+7f1c2a400000-7f1c2a600000 r-xp 00000000 00:2f 9012 /usr/lib/libssl.so.3
+7f1c2a800000-7f1c2a900000 r-xp 00000000 00:2f 3456 /usr/lib/libcrypto.so.3
+7f1c2aa00000-7f1c2aa21000 rw-p 00000000 00:00 0 
+7ffd5a000000-7ffd5a021000 rw-p 00000000 00:00 0 [stack]
+`
+	require.NoError(t, os.WriteFile(filepath.Join(procFs, "42", "maps"), []byte(maps), 0o644))
+
+	paths, err := mappedLibraries(procFs, 42, "libssl")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/app/lib/libssl.so.1.1", "/usr/lib/libssl.so.3"}, paths)
+
+	paths, err = mappedLibraries(procFs, 42, "libz")
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+
+	_, err = mappedLibraries(procFs, 43, "libssl")
+	require.Error(t, err)
+}
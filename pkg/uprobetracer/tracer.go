@@ -37,8 +37,11 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
@@ -59,18 +62,23 @@ const (
 )
 
 // inodeKeeper holds a file object, with the counter representing its
-// reference count. The link is not nil only when the file is attached.
+// reference count. The links are not empty only when the file is attached.
+// USDT probes can have several links, one for each location of the probe,
+// and the cookies of their arguments.
 type inodeKeeper struct {
 	counter int
 	file    *os.File
-	link    link.Link
+	links   []link.Link
+	cookies []uint64
 }
 
-func (t *inodeKeeper) close() {
-	if t.link != nil {
-		t.link.Close()
-	}
-	t.file.Close()
+// usdtCookieCtr generates the cookies of the USDT probes, the keys of their
+// arguments in the ig_usdt_specs map
+var usdtCookieCtr atomic.Uint64
+
+func (t *Tracer[Event]) closeInode(keeper *inodeKeeper) {
+	t.detachUprobe(keeper.links, keeper.cookies)
+	keeper.file.Close()
 }
 
 type Tracer[Event any] struct {
@@ -79,6 +87,9 @@ type Tracer[Event any] struct {
 	attachFilePath string
 	attachSymbol   string
 	prog           *ebpf.Program
+	// optional map where the arguments of USDT probes are stored, see
+	// include/gadget/usdt.h
+	usdtSpecs *ebpf.Map
 
 	// keeps the inodes for each attached container
 	// when users write library names in ebpf section names, it's possible to
@@ -120,6 +131,15 @@ func NewTracer[Event any](logger logger.Logger) (*Tracer[Event], error) {
 	return t, nil
 }
 
+// SetUSDTSpecsMap sets the map where the arguments of the USDT probes are
+// stored for the program to read them. It must be called before AttachProg.
+func (t *Tracer[Event]) SetUSDTSpecsMap(m *ebpf.Map) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.usdtSpecs = m
+}
+
 // AttachProg loads the ebpf program, and try attaching if there are pending containers
 func (t *Tracer[Event]) AttachProg(progName string, progType ProgType, attachTo string, prog *ebpf.Program) error {
 	if progType != ProgUprobe && progType != ProgUretprobe && progType != ProgUSDT {
@@ -166,6 +186,9 @@ func (t *Tracer[Event]) AttachProg(progName string, progType ProgType, attachTo
 	return nil
 }
 
+// searchForLibrary returns the paths of the file to attach to in the mount
+// namespace of the container. Library names are resolved with the ld cache of
+// the container, the libraries its process mapped and its library directories.
 func (t *Tracer[Event]) searchForLibrary(containerPid uint32) ([]string, error) {
 	filePath := t.attachFilePath
 	if filepath.IsAbs(filePath) {
@@ -173,37 +196,99 @@ func (t *Tracer[Event]) searchForLibrary(containerPid uint32) ([]string, error)
 	}
 
 	ldCachePath := "/etc/ld.so.cache"
-	ldCachePaths, err := parseLdCache(containerPid, ldCachePath, filePath)
+	paths, err := parseLdCache(containerPid, ldCachePath, filePath)
 	if err != nil {
-		return nil, fmt.Errorf("parsing ld cache: %w", err)
+		t.logger.Debugf("parsing ld cache of container %d: %s", containerPid, err)
 	}
-	return ldCachePaths, nil
+
+	// Libraries can also be loaded from directories not in the ld cache, like
+	// the ones bundled with applications
+	mapped, err := mappedLibraries(host.HostProcFs, containerPid, filePath)
+	if err != nil {
+		t.logger.Debugf("reading libraries mapped by container %d: %s", containerPid, err)
+	}
+	for _, p := range mapped {
+		if !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+
+	// Not all the images have an ld cache, like the ones based on musl
+	if len(paths) == 0 {
+		paths = searchLibDirs(containerPid, filePath)
+	}
+	return paths, nil
 }
 
 // attach uprobe program to the inode of the file passed in parameter
-func (t *Tracer[Event]) attachUprobe(file *os.File) (link.Link, error) {
+func (t *Tracer[Event]) attachUprobe(file *os.File) ([]link.Link, []uint64, error) {
 	attachPath := path.Join(host.HostProcFs, "self/fd/", fmt.Sprint(file.Fd()))
 	ex, err := link.OpenExecutable(attachPath)
 	if err != nil {
-		return nil, fmt.Errorf("opening %q: %w", attachPath, err)
+		return nil, nil, fmt.Errorf("opening %q: %w", attachPath, err)
 	}
 	switch t.progType {
 	case ProgUprobe:
-		return ex.Uprobe(t.attachSymbol, t.prog, nil)
+		l, err := ex.Uprobe(t.attachSymbol, t.prog, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []link.Link{l}, nil, nil
 	case ProgUretprobe:
-		return ex.Uretprobe(t.attachSymbol, t.prog, nil)
+		l, err := ex.Uretprobe(t.attachSymbol, t.prog, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []link.Link{l}, nil, nil
 	case ProgUSDT:
-		attachInfo, err := getUsdtInfo(attachPath, t.attachSymbol)
+		attachInfos, err := getUsdtInfo(attachPath, t.attachSymbol)
 		if err != nil {
-			return nil, fmt.Errorf("reading USDT metadata: %w", err)
+			return nil, nil, fmt.Errorf("reading USDT metadata: %w", err)
 		}
-		return ex.Uprobe(t.attachSymbol, t.prog,
-			&link.UprobeOptions{
+
+		var links []link.Link
+		var cookies []uint64
+		for _, attachInfo := range attachInfos {
+			opts := &link.UprobeOptions{
 				Address:      attachInfo.attachAddress,
 				RefCtrOffset: attachInfo.semaphoreAddress,
-			})
+			}
+			if t.usdtSpecs != nil {
+				// Programs can still read the arguments that could be parsed
+				spec, err := parseUsdtArgs(runtime.GOARCH, attachInfo.args)
+				if err != nil {
+					t.logger.Debugf("parsing arguments %q of USDT %q: %s", attachInfo.args, t.attachSymbol, err)
+				}
+				cookie := usdtCookieCtr.Add(1)
+				if err := t.usdtSpecs.Put(cookie, &spec); err != nil {
+					t.detachUprobe(links, cookies)
+					return nil, nil, fmt.Errorf("storing arguments of USDT %q: %w", t.attachSymbol, err)
+				}
+				cookies = append(cookies, cookie)
+				opts.Cookie = cookie
+			}
+
+			l, err := ex.Uprobe(t.attachSymbol, t.prog, opts)
+			if err != nil {
+				t.detachUprobe(links, cookies)
+				return nil, nil, err
+			}
+			links = append(links, l)
+		}
+		return links, cookies, nil
 	default:
-		return nil, fmt.Errorf("attaching to inode: unsupported prog type: %q", t.progType)
+		return nil, nil, fmt.Errorf("attaching to inode: unsupported prog type: %q", t.progType)
+	}
+}
+
+// detachUprobe closes the links of an inode and removes the arguments of its
+// USDT probes
+func (t *Tracer[Event]) detachUprobe(links []link.Link, cookies []uint64) {
+	for _, l := range links {
+		l.Close()
+	}
+	for _, cookie := range cookies {
+		t.usdtSpecs.Delete(cookie)
 	}
 }
 
@@ -242,11 +327,11 @@ func (t *Tracer[Event]) attach(containerPid uint32) {
 
 		inode, exists := t.inodeRefCount[realInodePtr]
 		if !exists {
-			progLink, err := t.attachUprobe(file)
+			links, cookies, err := t.attachUprobe(file)
 			if err != nil {
 				t.logger.Debugf("failed to attach uprobe %q: %s", t.progName, err.Error())
 			}
-			t.inodeRefCount[realInodePtr] = &inodeKeeper{1, file, progLink}
+			t.inodeRefCount[realInodePtr] = &inodeKeeper{1, file, links, cookies}
 		} else {
 			inode.counter++
 			file.Close()
@@ -313,7 +398,7 @@ func (t *Tracer[Event]) DetachContainer(container *containercollection.Container
 			}
 			keeper.counter--
 			if keeper.counter == 0 {
-				keeper.close()
+				t.closeInode(keeper)
 				delete(t.inodeRefCount, realInodePtr)
 			}
 		}
@@ -331,7 +416,7 @@ func (t *Tracer[Event]) Close() {
 	}
 
 	for _, keeper := range t.inodeRefCount {
-		keeper.close()
+		t.closeInode(keeper)
 	}
 
 	t.containerPid2Inodes = nil
@@ -40,6 +40,8 @@ type noteHeader struct {
 type usdtAttachInfo struct {
 	attachAddress    uint64
 	semaphoreAddress uint64
+	// Arguments as written in the note, like "-4@%edi 8@-8(%rbp)"
+	args string
 }
 
 func vaddr2ElfOffset(f *elf.File, addr uint64) (uint64, error) {
@@ -55,7 +57,9 @@ func alignUp[T int | int32 | int64 | uint | uint32 | uint64](n T, align T) T {
 	return (n + align - 1) / align * align
 }
 
-func getUsdtInfo(filepath string, attachSymbol string) (*usdtAttachInfo, error) {
+// getUsdtInfo returns the locations of a USDT probe. The same probe can be in
+// several locations, like when the function using it is inlined.
+func getUsdtInfo(filepath string, attachSymbol string) ([]usdtAttachInfo, error) {
 	parts := strings.Split(attachSymbol, ":")
 	if len(parts) != 2 {
 		return nil, fmt.Errorf("invalid USDT section name: %q", attachSymbol)
@@ -108,6 +112,7 @@ func getUsdtInfo(filepath string, attachSymbol string) (*usdtAttachInfo, error)
 	// walk through USDT notes, and match with providerName and probeName
 	// For details of the structure of ELF notes, please refer to
 	// https://man7.org/linux/man-pages/man5/elf.5.html, the `Notes (Nhdr)` section
+	var infos []usdtAttachInfo
 	for {
 		var header noteHeader
 		err = binary.Read(notesReader, elfReader.ByteOrder, &header)
@@ -154,8 +159,12 @@ func getUsdtInfo(filepath string, attachSymbol string) (*usdtAttachInfo, error)
 		provider := readStringFromBytes(desc, uint32(3*wordSize))
 		probe := readStringFromBytes(desc, uint32(3*wordSize+len(provider)+1))
 		if provider == providerName && probe == probeName {
-			return &usdtAttachInfo{location, elfSemaphore}, nil
+			args := readStringFromBytes(desc, uint32(3*wordSize+len(provider)+1+len(probe)+1))
+			infos = append(infos, usdtAttachInfo{location, elfSemaphore, args})
 		}
 	}
-	return nil, errors.New("no matching USDT metadata")
+	if len(infos) == 0 {
+		return nil, errors.New("no matching USDT metadata")
+	}
+	return infos, nil
 }
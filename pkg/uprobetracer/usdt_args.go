@@ -0,0 +1,193 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uprobetracer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// usdtMaxArgs is the maximum number of arguments of USDT probes that can be
+// read. Keep in sync with include/gadget/usdt.h
+const usdtMaxArgs = 12
+
+type usdtArgType uint32
+
+const (
+	usdtArgConst usdtArgType = iota
+	usdtArgReg
+	usdtArgRegDeref
+)
+
+// usdtArgSpec is the Go representation of struct gadget_usdt_arg_spec
+type usdtArgSpec struct {
+	// Value of constants or offset of dereferences
+	ValOff  uint64
+	ArgType usdtArgType
+	// Offset of the register in struct pt_regs
+	RegOff      int16
+	ArgSigned   uint8
+	ArgBitshift int8
+}
+
+// usdtSpec is the Go representation of struct gadget_usdt_spec
+type usdtSpec struct {
+	Args   [usdtMaxArgs]usdtArgSpec
+	ArgCnt uint64
+}
+
+// ptRegsOffsets are the offsets of the registers in struct pt_regs of each
+// architecture, by the names used in the arguments of the USDT notes
+var ptRegsOffsets = map[string]map[string]int16{
+	"amd64": {
+		"rip": 128,
+		"rax": 80, "eax": 80, "ax": 80, "al": 80,
+		"rbx": 40, "ebx": 40, "bx": 40, "bl": 40,
+		"rcx": 88, "ecx": 88, "cx": 88, "cl": 88,
+		"rdx": 96, "edx": 96, "dx": 96, "dl": 96,
+		"rsi": 104, "esi": 104, "si": 104, "sil": 104,
+		"rdi": 112, "edi": 112, "di": 112, "dil": 112,
+		"rbp": 32, "ebp": 32, "bp": 32, "bpl": 32,
+		"rsp": 152, "esp": 152, "sp": 152, "spl": 152,
+		"r8": 72, "r8d": 72, "r8w": 72, "r8b": 72,
+		"r9": 64, "r9d": 64, "r9w": 64, "r9b": 64,
+		"r10": 56, "r10d": 56, "r10w": 56, "r10b": 56,
+		"r11": 48, "r11d": 48, "r11w": 48, "r11b": 48,
+		"r12": 24, "r12d": 24, "r12w": 24, "r12b": 24,
+		"r13": 16, "r13d": 16, "r13w": 16, "r13b": 16,
+		"r14": 8, "r14d": 8, "r14w": 8, "r14b": 8,
+		"r15": 0, "r15d": 0, "r15w": 0, "r15b": 0,
+	},
+	"arm64": arm64PtRegsOffsets(),
+}
+
+func arm64PtRegsOffsets() map[string]int16 {
+	offsets := map[string]int16{"sp": 31 * 8}
+	for i := int16(0); i < 31; i++ {
+		offsets[fmt.Sprintf("x%d", i)] = i * 8
+		offsets[fmt.Sprintf("w%d", i)] = i * 8
+	}
+	return offsets
+}
+
+var (
+	// Arguments are separated by spaces, but the locations of arm64 can contain
+	// spaces, like "8@[sp, 16]"
+	usdtArgRe = regexp.MustCompile(`(-?\d+)@(\[[^\]]*\]|\S+)`)
+
+	// Locations of amd64, like "$5", "%rdi" and "-8(%rbp)"
+	amd64ConstRe = regexp.MustCompile(`^\$(-?\w+)$`)
+	amd64RegRe   = regexp.MustCompile(`^%(\w+)$`)
+	amd64DerefRe = regexp.MustCompile(`^(-?\w+)?\(%(\w+)\)$`)
+
+	// Locations of arm64, like "5", "x0" and "[sp, 16]"
+	arm64ConstRe = regexp.MustCompile(`^(-?\d+)$`)
+	arm64RegRe   = regexp.MustCompile(`^(\w+)$`)
+	arm64DerefRe = regexp.MustCompile(`^\[(\w+)(?:\s*,\s*(-?\w+))?\]$`)
+)
+
+// parseUsdtArgs parses the arguments of a USDT note, like "-4@%edi 8@-8(%rbp)"
+func parseUsdtArgs(arch string, args string) (usdtSpec, error) {
+	var spec usdtSpec
+
+	matches := usdtArgRe.FindAllStringSubmatch(args, -1)
+	if len(matches) > usdtMaxArgs {
+		return spec, fmt.Errorf("too many arguments: %d, maximum: %d", len(matches), usdtMaxArgs)
+	}
+	for i, m := range matches {
+		argSpec, err := parseUsdtArg(arch, m[1], m[2])
+		if err != nil {
+			return usdtSpec{}, fmt.Errorf("argument %d %q: %w", i, m[0], err)
+		}
+		spec.Args[i] = argSpec
+	}
+	spec.ArgCnt = uint64(len(matches))
+	return spec, nil
+}
+
+func parseUsdtArg(arch string, sizeStr string, location string) (usdtArgSpec, error) {
+	var spec usdtArgSpec
+
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil {
+		return spec, fmt.Errorf("invalid size %q: %w", sizeStr, err)
+	}
+	if size < 0 {
+		spec.ArgSigned = 1
+		size = -size
+	}
+	switch size {
+	case 1, 2, 4, 8:
+	default:
+		return spec, fmt.Errorf("invalid size %d", size)
+	}
+	spec.ArgBitshift = int8(64 - size*8)
+
+	offsets, ok := ptRegsOffsets[arch]
+	if !ok {
+		return spec, fmt.Errorf("unsupported architecture %q", arch)
+	}
+	regOffset := func(name string) (int16, error) {
+		off, ok := offsets[name]
+		if !ok {
+			return 0, fmt.Errorf("unsupported register %q", name)
+		}
+		return off, nil
+	}
+	parseInt := func(s string) (uint64, error) {
+		if s == "" {
+			return 0, nil
+		}
+		v, err := strconv.ParseInt(s, 0, 64)
+		return uint64(v), err
+	}
+
+	var constRe, regRe, derefRe *regexp.Regexp
+	var derefReg, derefOff int
+	switch arch {
+	case "amd64":
+		constRe, regRe, derefRe = amd64ConstRe, amd64RegRe, amd64DerefRe
+		derefOff, derefReg = 1, 2
+	case "arm64":
+		constRe, regRe, derefRe = arm64ConstRe, arm64RegRe, arm64DerefRe
+		derefReg, derefOff = 1, 2
+	}
+
+	if m := constRe.FindStringSubmatch(location); m != nil {
+		spec.ArgType = usdtArgConst
+		spec.ValOff, err = parseInt(m[1])
+		if err != nil {
+			return spec, fmt.Errorf("invalid constant %q: %w", m[1], err)
+		}
+		return spec, nil
+	}
+	if m := regRe.FindStringSubmatch(location); m != nil {
+		spec.ArgType = usdtArgReg
+		spec.RegOff, err = regOffset(strings.ToLower(m[1]))
+		return spec, err
+	}
+	if m := derefRe.FindStringSubmatch(location); m != nil {
+		spec.ArgType = usdtArgRegDeref
+		spec.ValOff, err = parseInt(m[derefOff])
+		if err != nil {
+			return spec, fmt.Errorf("invalid offset %q: %w", m[derefOff], err)
+		}
+		spec.RegOff, err = regOffset(strings.ToLower(m[derefReg]))
+		return spec, err
+	}
+	return spec, fmt.Errorf("unsupported location %q", location)
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uprobetracer
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsdtSpecSize(t *testing.T) {
+	// Must match the layout of struct gadget_usdt_spec in include/gadget/usdt.h
+	assert.Equal(t, uintptr(16), unsafe.Sizeof(usdtArgSpec{}))
+	assert.Equal(t, uintptr(usdtMaxArgs*16+8), unsafe.Sizeof(usdtSpec{}))
+}
+
+func TestParseUsdtArg(t *testing.T) {
+	type testDefinition struct {
+		arch     string
+		arg      string
+		expected usdtArgSpec
+		err      bool
+	}
+
+	tests := map[string]testDefinition{
+		"amd64_const": {
+			arch:     "amd64",
+			arg:      "-4@$-5",
+			expected: usdtArgSpec{ValOff: uint64(0xfffffffffffffffb), ArgType: usdtArgConst, ArgSigned: 1, ArgBitshift: 32},
+		},
+		"amd64_reg": {
+			arch:     "amd64",
+			arg:      "8@%rdi",
+			expected: usdtArgSpec{ArgType: usdtArgReg, RegOff: 112},
+		},
+		"amd64_subreg": {
+			arch:     "amd64",
+			arg:      "-4@%edx",
+			expected: usdtArgSpec{ArgType: usdtArgReg, RegOff: 96, ArgSigned: 1, ArgBitshift: 32},
+		},
+		"amd64_deref": {
+			arch:     "amd64",
+			arg:      "2@-8(%rbp)",
+			expected: usdtArgSpec{ValOff: uint64(0xfffffffffffffff8), ArgType: usdtArgRegDeref, RegOff: 32, ArgBitshift: 48},
+		},
+		"amd64_deref_no_offset": {
+			arch:     "amd64",
+			arg:      "1@(%r15)",
+			expected: usdtArgSpec{ArgType: usdtArgRegDeref, RegOff: 0, ArgBitshift: 56},
+		},
+		"amd64_unknown_reg": {
+			arch: "amd64",
+			arg:  "8@%xmm0",
+			err:  true,
+		},
+		"arm64_const": {
+			arch:     "arm64",
+			arg:      "4@16",
+			expected: usdtArgSpec{ValOff: 16, ArgType: usdtArgConst, ArgBitshift: 32},
+		},
+		"arm64_reg": {
+			arch:     "arm64",
+			arg:      "-8@x3",
+			expected: usdtArgSpec{ArgType: usdtArgReg, RegOff: 24, ArgSigned: 1},
+		},
+		"arm64_deref": {
+			arch:     "arm64",
+			arg:      "8@[sp, 16]",
+			expected: usdtArgSpec{ValOff: 16, ArgType: usdtArgRegDeref, RegOff: 248},
+		},
+		"arm64_deref_no_offset": {
+			arch:     "arm64",
+			arg:      "4@[x1]",
+			expected: usdtArgSpec{ArgType: usdtArgRegDeref, RegOff: 8, ArgBitshift: 32},
+		},
+		"invalid_size": {
+			arch: "amd64",
+			arg:  "3@%rdi",
+			err:  true,
+		},
+		"unsupported_arch": {
+			arch: "riscv64",
+			arg:  "8@a0",
+			err:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			spec, err := parseUsdtArgs(test.arch, test.arg)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, uint64(1), spec.ArgCnt)
+			assert.Equal(t, test.expected, spec.Args[0])
+		})
+	}
+}
+
+func TestParseUsdtArgs(t *testing.T) {
+	spec, err := parseUsdtArgs("arm64", "-4@x0 8@[sp, 16] 8@x1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), spec.ArgCnt)
+	assert.Equal(t, usdtArgReg, spec.Args[0].ArgType)
+	assert.Equal(t, usdtArgRegDeref, spec.Args[1].ArgType)
+	assert.Equal(t, int16(8), spec.Args[2].RegOff)
+
+	spec, err = parseUsdtArgs("amd64", "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), spec.ArgCnt)
+
+	_, err = parseUsdtArgs("amd64", "8@%rax 8@%rax 8@%rax 8@%rax 8@%rax 8@%rax 8@%rax 8@%rax 8@%rax 8@%rax 8@%rax 8@%rax 8@%rax")
+	require.Error(t, err)
+}
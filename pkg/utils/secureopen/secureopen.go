@@ -92,3 +92,31 @@ func ReadFileInContainer(containerPid uint32, unsafePath string, limitBytes int6
 	}
 	return io.ReadAll(fh)
 }
+
+// ReadDirInContainer returns the names of the entries of the given directory
+// in the given container referenced by containerPid.
+//
+// The directory is resolved like in OpenInContainer(), so it's guaranteed to
+// be inside the provided container.
+func ReadDirInContainer(containerPid uint32, unsafePath string) ([]string, error) {
+	root := filepath.Join(host.HostProcFs, fmt.Sprint(containerPid), "root")
+	rootDir, err := os.OpenFile(root, unix.O_PATH, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open o_path %q: %w", root, err)
+	}
+	defer rootDir.Close()
+
+	how := unix.OpenHow{
+		Flags:   unix.O_RDONLY | unix.O_DIRECTORY | unix.O_CLOEXEC,
+		Mode:    0,
+		Resolve: unix.RESOLVE_IN_ROOT | unix.RESOLVE_NO_MAGICLINKS,
+	}
+	fd, err := unix.Openat2(int(rootDir.Fd()), unsafePath, &how)
+	if err != nil {
+		return nil, fmt.Errorf("openat2 %q in %q: %w", unsafePath, root, err)
+	}
+	dir := os.NewFile(uintptr(fd), unsafePath)
+	defer dir.Close()
+
+	return dir.Readdirnames(-1)
+}
@@ -44,3 +44,23 @@ func LoadTLSCA(clientCA string) (*x509.CertPool, error) {
 
 	return ca, nil
 }
+
+// NewServerConfig returns a configuration for a server presenting the given certificate and requiring clients to
+// present a certificate signed by clientCA
+func NewServerConfig(serverCert, serverKey, clientCA string) (*tls.Config, error) {
+	cert, err := LoadTLSCert(serverCert, serverKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating TLS certificate: %w", err)
+	}
+
+	ca, err := LoadTLSCA(clientCA)
+	if err != nil {
+		return nil, fmt.Errorf("creating TLS certificate authority: %w", err)
+	}
+
+	return &tls.Config{
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    ca,
+	}, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"runtime"
+	"unsafe"
+)
+
+//go:wasmimport ig httpGet
+//go:linkname httpGet httpGet
+func httpGet(url uint64, dst uint64, statusPtr uint32) int32
+
+//go:wasmimport ig httpPost
+//go:linkname httpPost httpPost
+func httpPost(url uint64, contentType uint64, body uint64, dst uint64, statusPtr uint32) int32
+
+var errHTTPRequest = errors.New("error sending HTTP request")
+
+// HTTPGet sends a GET request to url and returns the status code and the body
+// of the response, which can't be bigger than maxSize. The URL must be allowed
+// by the http-allowlist param of the gadget. It blocks until the response is
+// received or the http-timeout param expires.
+func HTTPGet(url string, maxSize uint32) (uint32, []byte, error) {
+	dst := make([]byte, maxSize)
+	var status uint32
+	statusPtr := uintptr(unsafe.Pointer(&status))
+
+	ret := httpGet(uint64(stringToBufPtr(url)), uint64(bytesToBufPtr(dst)), uint32(statusPtr))
+	runtime.KeepAlive(url)
+	runtime.KeepAlive(dst)
+	if ret == -1 {
+		return 0, nil, errHTTPRequest
+	}
+	return status, dst[:ret], nil
+}
+
+// HTTPPost sends a POST request with the given body to url and returns the
+// status code and the body of the response, which can't be bigger than maxSize.
+// The URL must be allowed by the http-allowlist param of the gadget. It blocks
+// until the response is received or the http-timeout param expires.
+func HTTPPost(url string, contentType string, body []byte, maxSize uint32) (uint32, []byte, error) {
+	dst := make([]byte, maxSize)
+	var status uint32
+	statusPtr := uintptr(unsafe.Pointer(&status))
+
+	var bodyPtr bufPtr
+	if len(body) > 0 {
+		bodyPtr = bytesToBufPtr(body)
+	}
+
+	ret := httpPost(uint64(stringToBufPtr(url)), uint64(stringToBufPtr(contentType)), uint64(bodyPtr),
+		uint64(bytesToBufPtr(dst)), uint32(statusPtr))
+	runtime.KeepAlive(url)
+	runtime.KeepAlive(contentType)
+	runtime.KeepAlive(body)
+	runtime.KeepAlive(dst)
+	if ret == -1 {
+		return 0, nil, errHTTPRequest
+	}
+	return status, dst[:ret], nil
+}
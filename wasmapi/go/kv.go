@@ -0,0 +1,79 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"runtime"
+	_ "unsafe"
+)
+
+//go:wasmimport ig kvGet
+//go:linkname kvGet kvGet
+func kvGet(key uint64, dst uint64) int32
+
+//go:wasmimport ig kvSet
+//go:linkname kvSet kvSet
+func kvSet(key uint64, value uint64) uint32
+
+//go:wasmimport ig kvDelete
+//go:linkname kvDelete kvDelete
+func kvDelete(key uint64) uint32
+
+// ErrKVNotFound is returned by KVGet for keys not stored
+var ErrKVNotFound = errors.New("key not found")
+
+// KVGet returns the value of a key of the persistent key-value store of the
+// gadget. The value can't be bigger than maxSize.
+func KVGet(key string, maxSize uint32) ([]byte, error) {
+	dst := make([]byte, maxSize)
+	ret := kvGet(uint64(stringToBufPtr(key)), uint64(bytesToBufPtr(dst)))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(dst)
+	switch {
+	case ret == -1:
+		return nil, ErrKVNotFound
+	case ret < 0:
+		return nil, errors.New("error getting value")
+	}
+	return dst[:ret], nil
+}
+
+// KVSet stores the value of a key in the persistent key-value store of the
+// gadget. It fails if the store would exceed the kv-max-size param.
+func KVSet(key string, value []byte) error {
+	var valuePtr bufPtr
+	if len(value) > 0 {
+		valuePtr = bytesToBufPtr(value)
+	}
+	ret := kvSet(uint64(stringToBufPtr(key)), uint64(valuePtr))
+	runtime.KeepAlive(key)
+	runtime.KeepAlive(value)
+	if ret != 0 {
+		return errors.New("error setting value")
+	}
+	return nil
+}
+
+// KVDelete removes a key from the persistent key-value store of the gadget. It
+// doesn't fail if the key isn't stored.
+func KVDelete(key string) error {
+	ret := kvDelete(uint64(stringToBufPtr(key)))
+	runtime.KeepAlive(key)
+	if ret != 0 {
+		return errors.New("error deleting value")
+	}
+	return nil
+}
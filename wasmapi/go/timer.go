@@ -0,0 +1,77 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"time"
+	_ "unsafe"
+)
+
+//go:wasmimport ig newTimer
+//go:linkname newTimer newTimer
+func newTimer(interval uint64, cbID uint64) uint32
+
+//go:wasmimport ig timerStop
+//go:linkname timerStop timerStop
+func timerStop(timer uint32) uint32
+
+// TimerFunc is called each time a timer fires
+type TimerFunc func()
+
+// Timer calls a TimerFunc periodically
+type Timer struct {
+	handle uint32
+	cbID   uint64
+}
+
+var (
+	timerCallbackCtr = uint64(0)
+	timerCallbacks   = map[uint64]TimerFunc{}
+)
+
+//go:wasmexport timerCallback
+func timerCallback(cbID uint64) {
+	cb, ok := timerCallbacks[cbID]
+	if !ok {
+		return
+	}
+	cb()
+}
+
+// NewTimer creates a timer calling cb every interval, until it's stopped or
+// the gadget stops. Timers created before the gadget starts fire once it does.
+// Callbacks aren't called in parallel with the ones of data sources.
+func NewTimer(interval time.Duration, cb TimerFunc) (Timer, error) {
+	timerCallbackCtr++
+	cbID := timerCallbackCtr
+	timerCallbacks[cbID] = cb
+
+	handle := newTimer(uint64(interval), cbID)
+	if handle == 0 {
+		delete(timerCallbacks, cbID)
+		return Timer{}, errors.New("creating timer")
+	}
+	return Timer{handle: handle, cbID: cbID}, nil
+}
+
+// Stop stops the timer. It can be called from its own callback.
+func (t Timer) Stop() error {
+	delete(timerCallbacks, t.cbID)
+	if timerStop(t.handle) != 0 {
+		return errors.New("stopping timer")
+	}
+	return nil
+}
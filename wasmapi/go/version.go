@@ -14,7 +14,13 @@
 
 package api
 
+// APIVersion is the version of the API between Inspektor Gadget and the wasm
+// modules implemented by this package. Gadgets using it fail to load on
+// versions of Inspektor Gadget not supporting it, with an error telling which
+// side needs to be updated.
+const APIVersion = 1
+
 //go:wasmexport gadgetAPIVersion
 func gadgetAPIVersion() uint64 {
-	return 1
+	return APIVersion
 }
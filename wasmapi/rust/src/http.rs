@@ -0,0 +1,83 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+use crate::helpers::{bytes_to_buf_ptr, string_to_buf_ptr};
+
+#[link(wasm_import_module = "ig")]
+extern "C" {
+    #[link_name = "httpGet"]
+    fn _http_get(url: u64, dst: u64, status_ptr: u32) -> i32;
+
+    #[link_name = "httpPost"]
+    fn _http_post(url: u64, content_type: u64, body: u64, dst: u64, status_ptr: u32) -> i32;
+}
+
+/// Sends a GET request to url and returns the status code and the body of the
+/// response, which can't be bigger than max_size. The URL must be allowed by
+/// the http-allowlist param of the gadget.
+pub fn http_get(url: &str, max_size: u32) -> Result<(u32, Vec<u8>), String> {
+    let mut dst = vec![0u8; max_size as usize];
+    let mut status: u32 = 0;
+    let status_ptr = &mut status as *mut u32 as u32;
+
+    let ret = unsafe {
+        _http_get(
+            string_to_buf_ptr(url).0,
+            bytes_to_buf_ptr(&dst).0,
+            status_ptr,
+        )
+    };
+    if ret == -1 {
+        return Err("error sending HTTP request".to_string());
+    }
+
+    dst.truncate(ret as usize);
+    Ok((status, dst))
+}
+
+/// Sends a POST request with the given body to url and returns the status code
+/// and the body of the response, which can't be bigger than max_size. The URL
+/// must be allowed by the http-allowlist param of the gadget.
+pub fn http_post(
+    url: &str,
+    content_type: &str,
+    body: &[u8],
+    max_size: u32,
+) -> Result<(u32, Vec<u8>), String> {
+    let mut dst = vec![0u8; max_size as usize];
+    let mut status: u32 = 0;
+    let status_ptr = &mut status as *mut u32 as u32;
+
+    let body_ptr = if body.is_empty() {
+        0
+    } else {
+        bytes_to_buf_ptr(body).0
+    };
+
+    let ret = unsafe {
+        _http_post(
+            string_to_buf_ptr(url).0,
+            string_to_buf_ptr(content_type).0,
+            body_ptr,
+            bytes_to_buf_ptr(&dst).0,
+            status_ptr,
+        )
+    };
+    if ret == -1 {
+        return Err("error sending HTTP request".to_string());
+    }
+
+    dst.truncate(ret as usize);
+    Ok((status, dst))
+}
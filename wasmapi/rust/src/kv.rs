@@ -0,0 +1,67 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+use crate::helpers::{bytes_to_buf_ptr, string_to_buf_ptr};
+
+#[link(wasm_import_module = "ig")]
+extern "C" {
+    #[link_name = "kvGet"]
+    fn _kv_get(key: u64, dst: u64) -> i32;
+
+    #[link_name = "kvSet"]
+    fn _kv_set(key: u64, value: u64) -> u32;
+
+    #[link_name = "kvDelete"]
+    fn _kv_delete(key: u64) -> u32;
+}
+
+/// Returns the value of a key of the persistent key-value store of the gadget,
+/// or None if it isn't stored. The value can't be bigger than max_size.
+pub fn kv_get(key: &str, max_size: u32) -> Result<Option<Vec<u8>>, String> {
+    let mut dst = vec![0u8; max_size as usize];
+    let ret = unsafe { _kv_get(string_to_buf_ptr(key).0, bytes_to_buf_ptr(&dst).0) };
+    match ret {
+        -1 => Ok(None),
+        ret if ret < 0 => Err("error getting value".to_string()),
+        ret => {
+            dst.truncate(ret as usize);
+            Ok(Some(dst))
+        }
+    }
+}
+
+/// Stores the value of a key in the persistent key-value store of the gadget.
+/// It fails if the store would exceed the kv-max-size param.
+pub fn kv_set(key: &str, value: &[u8]) -> Result<(), String> {
+    let value_ptr = if value.is_empty() {
+        0
+    } else {
+        bytes_to_buf_ptr(value).0
+    };
+    let ret = unsafe { _kv_set(string_to_buf_ptr(key).0, value_ptr) };
+    if ret != 0 {
+        return Err("error setting value".to_string());
+    }
+    Ok(())
+}
+
+/// Removes a key from the persistent key-value store of the gadget. It doesn't
+/// fail if the key isn't stored.
+pub fn kv_delete(key: &str) -> Result<(), String> {
+    let ret = unsafe { _kv_delete(string_to_buf_ptr(key).0) };
+    if ret != 0 {
+        return Err("error deleting value".to_string());
+    }
+    Ok(())
+}
@@ -26,10 +26,13 @@ pub mod fields;
 pub mod filter;
 pub mod handle;
 pub mod helpers;
+pub mod http;
 pub mod kallsyms;
+pub mod kv;
 pub mod log;
 pub mod map;
 pub mod params;
 pub mod perf;
 pub mod syscall;
+pub mod timer;
 pub mod version;
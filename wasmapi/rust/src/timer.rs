@@ -0,0 +1,84 @@
+// Copyright 2025 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+use std::{
+    collections::HashMap,
+    sync::{
+        atomic::{AtomicU64, Ordering},
+        Arc, LazyLock, Mutex,
+    },
+    time::Duration,
+};
+
+#[link(wasm_import_module = "ig")]
+extern "C" {
+    #[link_name = "newTimer"]
+    fn _new_timer(interval: u64, cb_id: u64) -> u32;
+
+    #[link_name = "timerStop"]
+    fn _timer_stop(timer: u32) -> u32;
+}
+
+type TimerCallBack = Arc<dyn Fn() + Send + Sync>;
+
+static TIMER_CALLBACK_CTR: AtomicU64 = AtomicU64::new(0);
+static TIMER_CALLBACKS: LazyLock<Mutex<HashMap<u64, TimerCallBack>>> =
+    LazyLock::new(|| Mutex::new(HashMap::new()));
+
+/// Timer calls a callback periodically.
+#[derive(Clone, Copy, Debug)]
+pub struct Timer {
+    handle: u32,
+    cb_id: u64,
+}
+
+#[no_mangle]
+#[allow(non_snake_case)]
+fn timerCallback(cb_id: u64) {
+    // The callback can stop its own timer, don't keep the lock while calling it
+    let Some(cb) = TIMER_CALLBACKS.lock().unwrap().get(&cb_id).cloned() else {
+        return;
+    };
+    cb();
+}
+
+/// Creates a timer calling cb every interval, until it's stopped or the gadget
+/// stops. Timers created before the gadget starts fire once it does. Callbacks
+/// aren't called in parallel with the ones of data sources.
+pub fn new_timer<F>(interval: Duration, cb: F) -> Result<Timer, String>
+where
+    F: Fn() + Send + Sync + 'static,
+{
+    let cb_id = TIMER_CALLBACK_CTR.fetch_add(1, Ordering::SeqCst) + 1;
+    TIMER_CALLBACKS.lock().unwrap().insert(cb_id, Arc::new(cb));
+
+    let handle = unsafe { _new_timer(interval.as_nanos() as u64, cb_id) };
+    if handle == 0 {
+        TIMER_CALLBACKS.lock().unwrap().remove(&cb_id);
+        return Err("creating timer".to_string());
+    }
+    Ok(Timer { handle, cb_id })
+}
+
+impl Timer {
+    /// Stops the timer. It can be called from its own callback.
+    pub fn stop(&self) -> Result<(), String> {
+        TIMER_CALLBACKS.lock().unwrap().remove(&self.cb_id);
+        let ret = unsafe { _timer_stop(self.handle) };
+        if ret != 0 {
+            return Err("stopping timer".to_string());
+        }
+        Ok(())
+    }
+}
@@ -12,8 +12,13 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+/// Version of the API between Inspektor Gadget and the wasm modules implemented
+/// by this crate. Gadgets using it fail to load on versions of Inspektor Gadget
+/// not supporting it, with an error telling which side needs to be updated.
+pub const API_VERSION: u64 = 1;
+
 #[no_mangle]
 #[allow(non_snake_case)]
 pub fn gadgetAPIVersion() -> u64 {
-    1
+    API_VERSION
 }